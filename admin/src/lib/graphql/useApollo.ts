@@ -2,11 +2,17 @@ import { ApolloClient, HttpLink, InMemoryCache, NormalizedCacheObject } from "@a
 
 let apolloClient: ApolloClient<NormalizedCacheObject>;
 
+// Base path that Hetty serves the admin interface under, e.g. `/hetty`.
+function basePath(): string {
+  if (typeof document === "undefined") return "";
+  return document.querySelector<HTMLMetaElement>('meta[name="hetty-base-path"]')?.content ?? "";
+}
+
 function createApolloClient() {
   return new ApolloClient({
     ssrMode: typeof window === "undefined",
     link: new HttpLink({
-      uri: "/api/graphql/",
+      uri: basePath() + "/api/graphql/",
     }),
     cache: new InMemoryCache(),
   });
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+// unixAddrPrefix is the `-admin-addr` prefix for a Unix socket path.
+const unixAddrPrefix = "unix:"
+
+// listenAdmin listens on the address of the admin interface: a TCP address,
+// or a Unix socket path prefixed with `unix:`. Unix sockets are only
+// accessible by the current user.
+func listenAdmin(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, unixAddrPrefix) {
+		return net.Listen("tcp", addr)
+	}
+
+	path, err := homedir.Expand(strings.TrimPrefix(addr, unixAddrPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse socket path: %w", err)
+	}
+
+	// Remove a socket file left behind by a process that didn't exit cleanly.
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("could not remove existing socket file: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("could not set socket file permissions: %w", err)
+	}
+
+	return ln, nil
+}
+
+// newAdminTLSConfig returns the TLS config of the admin interface. Without a
+// certificate and key file, certificates are issued by the CA of Hetty on the
+// fly, for the hostname the admin interface is requested with.
+func newAdminTLSConfig(certFile, keyFile string, caCert *x509.Certificate, caKey *rsa.PrivateKey) (*tls.Config, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("both -admin-tls-cert and -admin-tls-key must be set")
+	}
+
+	if certFile != "" {
+		certFile, err := homedir.Expand(certFile)
+		if err != nil {
+			return nil, err
+		}
+
+		keyFile, err := homedir.Expand(keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load admin TLS certificate: %w", err)
+		}
+
+		return &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}, nil
+	}
+
+	certConfig, err := proxy.NewCertConfig(caCert, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := certConfig.TLSConfig()
+	getCertificate := tlsConfig.GetCertificate
+
+	// Clients don't send a server name for IP addresses, e.g. `127.0.0.1`.
+	tlsConfig.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if hello.ServerName == "" {
+			hello.ServerName = "localhost"
+
+			if host, _, err := net.SplitHostPort(hello.Conn.LocalAddr().String()); err == nil {
+				hello.ServerName = host
+			}
+		}
+
+		return getCertificate(hello)
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/dstotijn/hetty/pkg/api/client"
+	"github.com/dstotijn/hetty/pkg/db/badger"
+)
+
+// runBackup writes a backup of the database to a file. By default, the backup
+// is made by a running Hetty instance via its admin API, so that capture
+// doesn't need to be stopped. With `-db`, the database is opened directly.
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+
+	var outPath, adminAPIURL, backupDBPath string
+
+	fs.StringVar(&outPath, "o", fmt.Sprintf("hetty-%v.bak", time.Now().UTC().Format("20060102-150405")),
+		"Backup filepath")
+	fs.StringVar(&adminAPIURL, "url", client.DefaultURL, "URL of the admin interface of a running Hetty instance")
+	fs.StringVar(&backupDBPath, "db", "",
+		"Database directory path, to back up a database that isn't in use instead of a running instance")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	backup := func(w io.Writer) error {
+		c, err := client.New(client.Config{URL: adminAPIURL})
+		if err != nil {
+			return err
+		}
+
+		return c.Backup(context.Background(), w)
+	}
+
+	if backupDBPath != "" {
+		path, err := homedir.Expand(backupDBPath)
+		if err != nil {
+			return fmt.Errorf("could not parse database filepath: %w", err)
+		}
+
+		database, err := badger.OpenDatabase(badgerdb.DefaultOptions(path).WithLogger(nil))
+		if err != nil {
+			return fmt.Errorf("could not open database: %w", err)
+		}
+		defer database.Close()
+
+		backup = database.Backup
+	}
+
+	// Write to a temporary file, so that a failed backup doesn't leave a
+	// truncated file behind.
+	tmpPath := outPath + ".tmp"
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("could not create backup file: %w", err)
+	}
+
+	if err := backup(f); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+
+		return fmt.Errorf("could not back up database: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not write backup file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		return fmt.Errorf("could not write backup file: %w", err)
+	}
+
+	log.Printf("[INFO] Wrote backup to %v.", outPath)
+
+	return nil
+}
+
+// runRestore restores a backup to a new database directory.
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+
+	var inPath string
+
+	fs.StringVar(&inPath, "i", "", "Backup filepath")
+	fs.StringVar(&dbPath, "db", "~/.hetty/db", "Database directory path to restore to. Must not contain a database yet")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if inPath == "" {
+		return errors.New("backup filepath must be set with `-i`")
+	}
+
+	dbPath, err := homedir.Expand(dbPath)
+	if err != nil {
+		return fmt.Errorf("could not parse database filepath: %w", err)
+	}
+
+	f, err := os.Open(inPath)
+	if err != nil {
+		return fmt.Errorf("could not open backup file: %w", err)
+	}
+	defer f.Close()
+
+	database, err := badger.OpenDatabase(badgerdb.DefaultOptions(dbPath).WithLogger(nil))
+	if err != nil {
+		return fmt.Errorf("could not open database: %w", err)
+	}
+
+	if err := database.Restore(f); err != nil {
+		database.Close()
+		return fmt.Errorf("could not restore backup: %w", err)
+	}
+
+	if err := database.Close(); err != nil {
+		return fmt.Errorf("could not close database: %w", err)
+	}
+
+	log.Printf("[INFO] Restored backup to %v.", dbPath)
+
+	return nil
+}
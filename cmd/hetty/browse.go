@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/dstotijn/hetty/pkg/browser"
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+// runBrowse launches a browser that is configured to use a running Hetty
+// instance as its proxy.
+func runBrowse(args []string) error {
+	fs := flag.NewFlagSet("browse", flag.ExitOnError)
+
+	fs.StringVar(&caCertFile, "cert", "~/.hetty/hetty_cert.pem",
+		"CA certificate filepath. Creates a new CA certificate if file doesn't exist")
+	fs.StringVar(&caKeyFile, "key", "~/.hetty/hetty_key.pem",
+		"CA private key filepath. Creates a new CA private key if file doesn't exist")
+	fs.StringVar(&addr, "addr", ":8080", "TCP address of the proxy, in the form \"host:port\"")
+
+	var execPath string
+
+	fs.StringVar(&execPath, "chrome", "", "Chromium or Google Chrome executable path. Searched for when empty")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	caCertFile, err := homedir.Expand(caCertFile)
+	if err != nil {
+		return fmt.Errorf("could not parse CA certificate filepath: %w", err)
+	}
+
+	caKeyFile, err := homedir.Expand(caKeyFile)
+	if err != nil {
+		return fmt.Errorf("could not parse CA private key filepath: %w", err)
+	}
+
+	caCert, _, err := proxy.LoadOrCreateCA(caKeyFile, caCertFile)
+	if err != nil {
+		return fmt.Errorf("could not create/load CA key pair: %w", err)
+	}
+
+	launcher := browser.NewLauncher(browser.Config{
+		ProxyAddr: addr,
+		CACert:    caCert,
+		ExecPath:  execPath,
+	})
+
+	cmd, err := launcher.Launch(adminURL)
+	if err != nil {
+		return fmt.Errorf("could not launch browser: %w", err)
+	}
+
+	log.Printf("[INFO] Launched browser (pid: %v), using proxy on %v ...", cmd.Process.Pid, addr)
+
+	_, err = cmd.Process.Wait()
+
+	return err
+}
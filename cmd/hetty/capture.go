@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/dstotijn/hetty/pkg/api/client"
+	"github.com/dstotijn/hetty/pkg/api/rest"
+)
+
+// runCapture pauses or resumes capture of a running Hetty instance via its
+// admin API, e.g. before browsing personal sites mid-engagement. Traffic is
+// still proxied while capture is paused, but it isn't logged.
+func runCapture(args []string) error {
+	fs := flag.NewFlagSet("capture", flag.ExitOnError)
+
+	var adminAPIURL string
+	var project bool
+
+	fs.StringVar(&adminAPIURL, "url", client.DefaultURL, "URL of the admin interface of a running Hetty instance")
+	fs.BoolVar(&project, "project", false,
+		"Pause or resume capture for the active project only, instead of globally. The setting is stored with the project")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: hetty capture [flags] pause|resume|status\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one command, got: %v", fs.NArg())
+	}
+
+	c, err := client.New(client.Config{URL: adminAPIURL})
+	if err != nil {
+		return err
+	}
+
+	var status rest.CaptureStatus
+
+	switch cmd := fs.Arg(0); cmd {
+	case "pause", "resume":
+		paused := cmd == "pause"
+		input := rest.CaptureStatusInput{Paused: &paused}
+
+		if project {
+			input = rest.CaptureStatusInput{ProjectPaused: &paused}
+		}
+
+		if status, err = c.SetCaptureStatus(context.Background(), input); err != nil {
+			return fmt.Errorf("could not set capture status: %w", err)
+		}
+	case "status":
+		if status, err = c.CaptureStatus(context.Background()); err != nil {
+			return fmt.Errorf("could not get capture status: %w", err)
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command: %q", cmd)
+	}
+
+	log.Printf("[INFO] Capture is %v globally, and %v for the active project.",
+		captureState(status.Paused), captureState(status.ProjectPaused))
+
+	return nil
+}
+
+func captureState(paused bool) string {
+	if paused {
+		return "paused"
+	}
+
+	return "active"
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+// runEvidence verifies the hashes of an evidence document, which is exported
+// with the `exportHttpRequestLogEvidence` query. It doesn't need a running
+// Hetty instance, e.g. for recipients of a report.
+func runEvidence(args []string) error {
+	fs := flag.NewFlagSet("evidence", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: hetty evidence verify FILE\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 || fs.Arg(0) != "verify" {
+		fs.Usage()
+		return fmt.Errorf("expected verify command and file")
+	}
+
+	data, err := ioutil.ReadFile(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("could not read evidence: %w", err)
+	}
+
+	n, err := reqlog.VerifyEvidence(data)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Verified %v evidence entries.", n)
+
+	return nil
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"embed"
+	"encoding/pem"
 	"errors"
 	"flag"
 	"fmt"
@@ -11,31 +13,139 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
 	badgerdb "github.com/dgraph-io/badger/v3"
 	"github.com/gorilla/mux"
 	"github.com/mitchellh/go-homedir"
+	"github.com/oklog/ulid"
 
+	"github.com/dstotijn/hetty/pkg/activescan"
 	"github.com/dstotijn/hetty/pkg/api"
+	"github.com/dstotijn/hetty/pkg/api/rest"
+	"github.com/dstotijn/hetty/pkg/browser"
+	"github.com/dstotijn/hetty/pkg/config"
+	"github.com/dstotijn/hetty/pkg/crawler"
+	"github.com/dstotijn/hetty/pkg/db"
 	"github.com/dstotijn/hetty/pkg/db/badger"
-	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/discovery"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/hetty"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/idor"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/logging"
+	"github.com/dstotijn/hetty/pkg/mdns"
+	"github.com/dstotijn/hetty/pkg/nuclei"
+	"github.com/dstotijn/hetty/pkg/oast"
+	"github.com/dstotijn/hetty/pkg/origin"
+	"github.com/dstotijn/hetty/pkg/pac"
 	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/ratelimit"
+	"github.com/dstotijn/hetty/pkg/replay"
 	"github.com/dstotijn/hetty/pkg/reqlog"
 	"github.com/dstotijn/hetty/pkg/scope"
-	"github.com/dstotijn/hetty/pkg/sender"
+	"github.com/dstotijn/hetty/pkg/smuggle"
+	"github.com/dstotijn/hetty/pkg/sysproxy"
+	"github.com/dstotijn/hetty/pkg/tracing"
+	"github.com/dstotijn/hetty/pkg/webui"
 )
 
 var version = "0.0.0"
 
+// adminURL is the URL of the admin interface, for clients that use the proxy.
+const adminURL = "http://hetty.proxy/"
+
+// caCertPath is the path the CA certificate can be downloaded from, on any
+// host that Hetty can be reached on.
+const caCertPath = "/hetty_cert.pem"
+
+// memoryDBPath is the `-db` flag value for an in-memory database.
+const memoryDBPath = "memory"
+
 // Flag variables.
 var (
 	caCertFile string
 	caKeyFile  string
 	dbPath     string
 	addr       string
+
+	oastDomain   string
+	oastIP       string
+	oastHTTPAddr string
+	oastDNSAddr  string
+
+	systemProxy bool
+	mdnsEnabled bool
+
+	upstreamFingerprint string
+
+	upstreamMaxIdleConnsPerHost   int
+	upstreamMaxConnsPerHost       int
+	upstreamDialTimeout           time.Duration
+	upstreamTLSHandshakeTimeout   time.Duration
+	upstreamResponseHeaderTimeout time.Duration
+	upstreamRequestTimeout        time.Duration
+	upstreamDisableKeepAlives     bool
+	upstreamHostTimeouts          hostTimeoutsFlag
+	upstreamRetries               int
+	upstreamResolver              string
+	upstreamIPFamily              string
+	upstreamFallbackDelay         time.Duration
+	upstreamRetryBackoff          time.Duration
+	upstreamRateLimits            rateLimitsFlag
+
+	rawCapture        bool
+	capturePaused     bool
+	blockRules        blockRulesFlag
+	proxyAuthRequired bool
+
+	shutdownTimeout time.Duration
+
+	reqLogStoreWorkers   int
+	reqLogStoreQueueSize int
+
+	maxConcurrentJobs int
+
+	dbBatchSize     int
+	dbBatchInterval time.Duration
+
+	dbCompactionInterval time.Duration
+	dbCompactionWindow   string
+
+	certCacheTTL    time.Duration
+	certCacheSize   int
+	certPregenerate bool
+	certWildcard    bool
+
+	idStrategy      string
+	idCryptoEntropy bool
+
+	otlpEndpoint string
+
+	logLevel          string
+	logFormat         string
+	logFile           string
+	logFileMaxSize    int64
+	logFileMaxBackups int
+	logSyslog         string
+
+	configFile string
+
+	adminAddr     string
+	adminBasePath string
+	adminTLS      bool
+	adminTLSCert  string
+	adminTLSKey   string
+
+	adminAllowedOrigins originsFlag
 )
 
 //go:embed admin
@@ -51,14 +161,187 @@ func main() {
 }
 
 func run() error {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "browse":
+			return runBrowse(os.Args[2:])
+		case "reindex":
+			return runReindex(os.Args[2:])
+		case "backup":
+			return runBackup(os.Args[2:])
+		case "restore":
+			return runRestore(os.Args[2:])
+		case "capture":
+			return runCapture(os.Args[2:])
+		case "evidence":
+			return runEvidence(os.Args[2:])
+		}
+	}
+
 	flag.StringVar(&caCertFile, "cert", "~/.hetty/hetty_cert.pem",
 		"CA certificate filepath. Creates a new CA certificate if file doesn't exist")
 	flag.StringVar(&caKeyFile, "key", "~/.hetty/hetty_key.pem",
 		"CA private key filepath. Creates a new CA private key if file doesn't exist")
-	flag.StringVar(&dbPath, "db", "~/.hetty/db", "Database directory path")
+	flag.StringVar(&dbPath, "db", "~/.hetty/db",
+		"Database directory path, or \"memory\" for an in-memory database that's discarded on exit")
 	flag.StringVar(&addr, "addr", ":8080", "TCP address to listen on, in the form \"host:port\"")
+	flag.StringVar(&oastDomain, "oast-domain", "",
+		"Domain delegated to Hetty for out-of-band callbacks, e.g. \"oast.example.com\"")
+	flag.StringVar(&oastIP, "oast-ip", "", "Public IP address returned for DNS queries of out-of-band callback hostnames")
+	flag.StringVar(&oastHTTPAddr, "oast-http-addr", "",
+		"TCP address to listen on for out-of-band HTTP callbacks, in the form \"host:port\"")
+	flag.StringVar(&oastDNSAddr, "oast-dns-addr", "",
+		"UDP address to listen on for out-of-band DNS callbacks, in the form \"host:port\"")
+	flag.BoolVar(&systemProxy, "system-proxy", false,
+		"Configure the system proxy settings to use Hetty while it's running (macOS, Windows and GNOME)")
+	flag.BoolVar(&mdnsEnabled, "mdns", false, "Advertise the proxy and CA certificate download URL via mDNS on the local network")
+	flag.StringVar(&upstreamFingerprint, "upstream-fingerprint", "go",
+		"TLS fingerprint for upstream connections: \"go\", \"chrome\", \"firefox\" or \"client\" (replays the client's ClientHello)")
+	flag.IntVar(&upstreamMaxIdleConnsPerHost, "upstream-max-idle-conns-per-host", 2,
+		"Maximum number of idle (keep-alive) upstream connections per host")
+	flag.IntVar(&upstreamMaxConnsPerHost, "upstream-max-conns-per-host", 0,
+		"Maximum number of upstream connections per host; 0 means no limit")
+	flag.DurationVar(&upstreamDialTimeout, "upstream-dial-timeout", 30*time.Second,
+		"Time to wait for TCP connections with upstream servers")
+	flag.DurationVar(&upstreamTLSHandshakeTimeout, "upstream-tls-handshake-timeout", 10*time.Second,
+		"Time to wait for TLS handshakes with upstream servers")
+	flag.DurationVar(&upstreamResponseHeaderTimeout, "upstream-response-header-timeout", 0,
+		"Time to wait for upstream response headers after a request is sent; 0 means no timeout")
+	flag.DurationVar(&upstreamRequestTimeout, "upstream-request-timeout", 0,
+		"Total time of an upstream request, until the response body is read; 0 means no timeout")
+	flag.Var(&upstreamHostTimeouts, "upstream-host-timeout",
+		"Timeouts for a host, in the form \"host:dial=5s,tls=5s,header=10s,request=30s\"; "+
+			"a leading \"*.\" matches subdomains. Can be repeated")
+	flag.Var(&upstreamRateLimits, "upstream-rate-limit",
+		"Rate limit of outbound requests to a host, in the form \"host:10/s,burst=20\" (or \"/m\", \"/h\"); "+
+			"a leading \"*.\" matches subdomains, and \"*\" all hosts. Can be repeated")
+	flag.StringVar(&upstreamIPFamily, "upstream-ip-family", "any",
+		"IP versions of upstream connections: \"any\", \"ipv4\", \"ipv6\", \"prefer-ipv4\" or \"prefer-ipv6\"")
+	flag.DurationVar(&upstreamFallbackDelay, "upstream-fallback-delay", 300*time.Millisecond,
+		"Time to wait before racing an upstream connection with the other IP version (happy eyeballs); a negative value disables racing")
+	flag.StringVar(&upstreamResolver, "upstream-resolver", "",
+		"DNS server (e.g. \"10.0.0.1:53\") or DNS-over-HTTPS URL (e.g. \"https://1.1.1.1/dns-query\") for resolving upstream hostnames; defaults to the OS resolver")
+	flag.IntVar(&upstreamRetries, "upstream-retries", 0,
+		"Maximum number of retries of GET and HEAD requests whose upstream connection was reset; 0 disables retries")
+	flag.DurationVar(&upstreamRetryBackoff, "upstream-retry-backoff", 100*time.Millisecond,
+		"Time to wait before the first retry of an upstream request, doubled for every next retry")
+	flag.BoolVar(&upstreamDisableKeepAlives, "upstream-disable-keep-alives", false,
+		"Use a new upstream connection for every request")
+	flag.BoolVar(&rawCapture, "raw-capture", false,
+		"Store the raw bytes of proxied requests and responses, e.g. for request smuggling research; "+
+			"disables keep-alive for proxied connections")
+	flag.BoolVar(&capturePaused, "capture-paused", false,
+		"Start with capture paused, so that proxied traffic isn't logged until it's resumed (see `hetty capture`)")
+	flag.Var(&blockRules, "block",
+		"Refuse to proxy a host (e.g. \"*.example.com\"), or URLs matching a regular expression prefixed with \"url:\"; "+
+			"the proxy responds with 403 Forbidden without contacting upstream. Can be repeated")
+	flag.BoolVar(&proxyAuthRequired, "proxy-auth-required", false,
+		"Require clients to send proxy credentials (any password), so that their username can be used for client routes")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second,
+		"Time to wait for active connections to close on shutdown or restart (SIGHUP)")
+	flag.IntVar(&reqLogStoreWorkers, "reqlog-workers", 8, "Number of workers that store response logs")
+	flag.IntVar(&reqLogStoreQueueSize, "reqlog-queue-size", 1024,
+		"Number of response logs that can be queued for storage before proxied responses are delayed")
+	flag.IntVar(&maxConcurrentJobs, "max-concurrent-jobs", job.DefaultMaxConcurrent,
+		"Maximum number of background jobs (e.g. crawls, scans and replays) that run at once; others are queued")
+	flag.IntVar(&dbBatchSize, "db-batch-size", 0,
+		"Number of request and response log writes to buffer and commit as a batch; 0 disables batching")
+	flag.DurationVar(&dbBatchInterval, "db-batch-interval", time.Second,
+		"Maximum time that batched writes are buffered before they're committed")
+	flag.DurationVar(&dbCompactionInterval, "db-compaction-interval", 24*time.Hour,
+		"Minimum time between background database compactions; 0 disables background compaction")
+	flag.StringVar(&dbCompactionWindow, "db-compaction-window", "",
+		"Hours of the day in which background compactions run, in the form \"start-end\" (e.g. \"2-6\"). Any time when empty")
+	flag.DurationVar(&certCacheTTL, "cert-cache-ttl", 12*time.Hour, "Duration that generated leaf certificates are cached for")
+	flag.IntVar(&certCacheSize, "cert-cache-size", 1000, "Maximum number of cached leaf certificates")
+	flag.BoolVar(&certPregenerate, "cert-pregenerate", false,
+		"Generate leaf certificates for in-scope HTTPS hosts of a project's request log when the project is opened")
+	flag.BoolVar(&certWildcard, "cert-wildcard", true,
+		"Generate wildcard leaf certificates (e.g. \"*.example.com\"), shared by subdomains")
+	flag.StringVar(&idStrategy, "id-strategy", string(idgen.StrategyULID),
+		"Strategy for IDs of stored entities, e.g. request logs (\"ulid\", \"monotonic\" or \"uuidv7\")")
+	flag.BoolVar(&idCryptoEntropy, "id-crypto-entropy", false,
+		"Use cryptographically secure random bits for IDs, so that they are hard to guess")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		"Base URL of an OTLP/HTTP receiver to export traces of proxied requests to, e.g. \"http://localhost:4318\"")
+	flag.StringVar(&logLevel, "log-level", "info",
+		"Minimum level of log messages: \"debug\", \"info\", \"warn\" or \"error\"; can be changed at runtime via the admin API")
+	flag.StringVar(&logFormat, "log-format", string(logging.FormatText), "Format of log messages: \"text\" or \"json\"")
+	flag.StringVar(&logFile, "log-file", "", "File that log messages are written to, in addition to the console")
+	flag.Int64Var(&logFileMaxSize, "log-file-max-size", 100,
+		"Size in megabytes after which the log file is rotated; 0 disables rotation")
+	flag.IntVar(&logFileMaxBackups, "log-file-max-backups", 5, "Number of rotated log files to keep")
+	flag.StringVar(&logSyslog, "log-syslog", "",
+		"Syslog daemon that log messages are sent to, in addition to the console: \"local\", or e.g. \"udp://host:514\"")
+	flag.StringVar(&adminAddr, "admin-addr", "",
+		"Address to serve the admin interface and APIs on, instead of -addr: \"host:port\", or \"unix:/path/to/hetty.sock\"")
+	flag.StringVar(&adminBasePath, "admin-base-path", "",
+		"Path prefix that the admin interface and APIs are served under, e.g. \"/hetty\"")
+	flag.BoolVar(&adminTLS, "admin-tls", false,
+		"Serve the admin interface over TLS on -admin-addr, with certificates issued by the Hetty CA unless -admin-tls-cert is set")
+	flag.StringVar(&adminTLSCert, "admin-tls-cert", "", "TLS certificate file of the admin interface; implies -admin-tls")
+	flag.StringVar(&adminTLSKey, "admin-tls-key", "", "TLS private key file of the admin interface")
+	flag.Var(&adminAllowedOrigins, "admin-allowed-origin",
+		"Origin that can use the admin APIs and embed the admin interface, e.g. \"https://*.example.com\". "+
+			"Repeatable, or comma-separated")
+	flag.StringVar(&configFile, "config", os.Getenv("HETTY_CONFIG"),
+		"Config file with one flag name and value per line. Flags and HETTY_* environment variables take precedence")
 	flag.Parse()
 
+	if err := config.Load(flag.CommandLine, config.Options{
+		EnvPrefix: "HETTY",
+		File:      configFile,
+		Skip:      []string{"config"},
+	}); err != nil {
+		return err
+	}
+
+	logger, err := newLogger()
+	if err != nil {
+		return err
+	}
+	defer logger.Close()
+
+	log.SetOutput(logger)
+	log.SetFlags(0)
+
+	fingerprint, err := proxy.ParseFingerprint(upstreamFingerprint)
+	if err != nil {
+		return fmt.Errorf("could not parse upstream fingerprint: %w", err)
+	}
+
+	ipFamily, err := proxy.ParseIPFamily(upstreamIPFamily)
+	if err != nil {
+		return fmt.Errorf("could not parse upstream IP family: %w", err)
+	}
+
+	idCfg := idgen.Config{Strategy: idgen.Strategy(idStrategy)}
+	if idCryptoEntropy {
+		idCfg.Entropy = idgen.CryptoEntropy
+	}
+
+	idGenerator, err := idgen.NewGenerator(idCfg)
+	if err != nil {
+		return fmt.Errorf("could not create ID generator: %w", err)
+	}
+
+	var tracer *tracing.Tracer
+
+	if otlpEndpoint != "" {
+		serviceName := os.Getenv("OTEL_SERVICE_NAME")
+		tracer = tracing.NewTracer(tracing.Config{
+			Exporter: tracing.NewOTLPExporter(tracing.OTLPConfig{Endpoint: otlpEndpoint, ServiceName: serviceName}),
+		})
+	}
+
+	var resolver *net.Resolver
+
+	if upstreamResolver != "" {
+		if resolver, err = proxy.NewResolver(upstreamResolver); err != nil {
+			return fmt.Errorf("could not create upstream resolver: %w", err)
+		}
+	}
+
 	// Expand `~` in filepaths.
 	caCertFile, err := homedir.Expand(caCertFile)
 	if err != nil {
@@ -70,9 +353,11 @@ func run() error {
 		return fmt.Errorf("could not parse CA private key filepath: %w", err)
 	}
 
-	dbPath, err := homedir.Expand(dbPath)
-	if err != nil {
-		return fmt.Errorf("could not parse projects filepath: %w", err)
+	if dbPath != memoryDBPath {
+		dbPath, err = homedir.Expand(dbPath)
+		if err != nil {
+			return fmt.Errorf("could not parse projects filepath: %w", err)
+		}
 	}
 
 	// Load existing CA certificate and key from disk, or generate and write
@@ -82,66 +367,339 @@ func run() error {
 		return fmt.Errorf("could not create/load CA key pair: %w", err)
 	}
 
-	badger, err := badger.OpenDatabase(badgerdb.DefaultOptions(dbPath))
+	basePath, err := webui.ParseBasePath(adminBasePath)
 	if err != nil {
-		return fmt.Errorf("could not open badger database: %w", err)
+		return err
 	}
-	defer badger.Close()
 
-	scope := &scope.Scope{}
+	if adminTLSCert != "" || adminTLSKey != "" {
+		adminTLS = true
+	}
 
-	reqLogService := reqlog.NewService(reqlog.Config{
-		Scope:      scope,
-		Repository: badger,
-	})
+	if adminTLS && adminAddr == "" {
+		return errors.New("-admin-tls requires -admin-addr")
+	}
 
-	senderService := sender.NewService(sender.Config{
-		Repository:    badger,
-		ReqLogService: reqLogService,
-	})
+	var adminTLSConfig *tls.Config
+	if adminTLS {
+		adminTLSConfig, err = newAdminTLSConfig(adminTLSCert, adminTLSKey, caCert, caKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	ln, adminLn, err := inheritedListeners()
+	if err != nil {
+		return err
+	}
 
-	projService, err := proj.NewService(proj.Config{
-		Repository:    badger,
-		ReqLogService: reqLogService,
-		SenderService: senderService,
-		Scope:         scope,
+	// After a restart, the previous process holds the database lock until its
+	// connections are drained.
+	var dbWait time.Duration
+	if ln != nil {
+		dbWait = shutdownTimeout + 10*time.Second
+	}
+
+	database, err := openDatabase(dbPath, dbWait, badger.BatchConfig{
+		Size:     dbBatchSize,
+		Interval: dbBatchInterval,
 	})
 	if err != nil {
-		return fmt.Errorf("could not create new project service: %w", err)
+		return fmt.Errorf("could not open database: %w", err)
+	}
+	defer database.Close()
+
+	// Memory databases don't need compaction.
+	maintainer, _ := database.(db.Maintainer)
+
+	if maintainer != nil {
+		schedule := db.CompactionSchedule{Interval: dbCompactionInterval}
+
+		if dbCompactionWindow != "" {
+			if schedule.WindowStart, schedule.WindowEnd, err = db.ParseCompactionWindow(dbCompactionWindow); err != nil {
+				return err
+			}
+		}
+
+		if err := maintainer.SetCompactionSchedule(schedule); err != nil {
+			return fmt.Errorf("could not set database compaction schedule: %w", err)
+		}
 	}
 
-	p, err := proxy.NewProxy(caCert, caKey)
+	h, err := hetty.New(hetty.Config{
+		CACert:   caCert,
+		CAKey:    caKey,
+		Database: database,
+		Transport: proxy.TransportConfig{
+			MaxIdleConnsPerHost:   upstreamMaxIdleConnsPerHost,
+			MaxConnsPerHost:       upstreamMaxConnsPerHost,
+			DialTimeout:           upstreamDialTimeout,
+			TLSHandshakeTimeout:   upstreamTLSHandshakeTimeout,
+			ResponseHeaderTimeout: upstreamResponseHeaderTimeout,
+			RequestTimeout:        upstreamRequestTimeout,
+			DisableKeepAlives:     upstreamDisableKeepAlives,
+			HostTimeouts:          upstreamHostTimeouts,
+			IPFamily:              ipFamily,
+			FallbackDelay:         upstreamFallbackDelay,
+			Resolver:              resolver,
+			Retry: proxy.RetryConfig{
+				MaxRetries: upstreamRetries,
+				Backoff:    upstreamRetryBackoff,
+			},
+		},
+		RateLimits:           upstreamRateLimits,
+		ReqLogStoreWorkers:   reqLogStoreWorkers,
+		ReqLogStoreQueueSize: reqLogStoreQueueSize,
+		MaxConcurrentJobs:    maxConcurrentJobs,
+		IDGenerator:          idGenerator,
+		Tracer:               tracer,
+	})
 	if err != nil {
-		return fmt.Errorf("could not create proxy: %w", err)
+		return fmt.Errorf("could not set up services: %w", err)
+	}
+
+	p := h.Proxy
+	scope := h.Scope
+	reqLogService := h.RequestLogService
+	senderService := h.SenderService
+	projService := h.ProjectService
+	findingService := h.FindingService
+	connLogService := h.ConnLogService
+	oauth2Service := h.OAuth2Service
+
+	reqLogService.SetCapturePaused(capturePaused)
+	p.SetUpstreamFingerprint(fingerprint)
+
+	if err := p.SetBlockRules(blockRules); err != nil {
+		return fmt.Errorf("could not set block rules: %w", err)
+	}
+
+	p.SetCertCache(proxy.CertCacheConfig{
+		TTL:     certCacheTTL,
+		MaxSize: certCacheSize,
+	})
+	p.SetWildcardCerts(certWildcard)
+	p.SetRawCapture(rawCapture)
+	p.SetProxyAuthRequired(proxyAuthRequired)
+
+	var oastIPAddr net.IP
+	if oastIP != "" {
+		if oastIPAddr = net.ParseIP(oastIP); oastIPAddr == nil {
+			return fmt.Errorf("could not parse OAST IP address: %q", oastIP)
+		}
 	}
 
-	p.UseRequestModifier(reqLogService.RequestModifier)
-	p.UseResponseModifier(reqLogService.ResponseModifier)
+	oastService := oast.NewService(oast.Config{
+		Repository:  database,
+		Domain:      oastDomain,
+		IP:          oastIPAddr,
+		IDGenerator: h.IDGenerator,
+	})
+
+	h.Events.Subscribe(func(e event.Event) {
+		if e.Type == event.TypeProjectClosed {
+			oastService.SetActiveProjectID(ulid.ULID{})
+			oastService.SetReadOnly(false)
+			return
+		}
+
+		oastService.SetActiveProjectID(e.ProjectID)
+		oastService.SetReadOnly(projService.IsReadOnly())
+
+		if certPregenerate {
+			go pregenerateCerts(p, database, scope, e.ProjectID, certCacheSize)
+		}
+	}, event.TypeProjectOpened, event.TypeProjectClosed)
+
+	if oastDNSAddr != "" {
+		conn, err := net.ListenPacket("udp", oastDNSAddr)
+		if err != nil {
+			return fmt.Errorf("could not listen for OAST DNS callbacks: %w", err)
+		}
+		defer conn.Close()
+
+		go func() {
+			if err := oastService.ServeDNS(conn); err != nil {
+				log.Printf("[ERROR] OAST DNS server stopped: %v", err)
+			}
+		}()
+	}
+
+	if oastHTTPAddr != "" {
+		oastServer := &http.Server{Addr: oastHTTPAddr, Handler: oastService}
+		defer oastServer.Close()
+
+		go func() {
+			if err := oastServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("[ERROR] OAST HTTP server stopped: %v", err)
+			}
+		}()
+	}
+
+	discoveryService := discovery.NewService(discovery.Config{
+		Scope:       scope,
+		Transport:   p,
+		RequestLogs: reqLogService,
+		IDGenerator: h.IDGenerator,
+		Jobs:        h.JobService,
+	})
+
+	smuggleService := smuggle.NewService(smuggle.Config{
+		Scope:             scope,
+		RequestLogService: reqLogService,
+		FindingRepository: database,
+		Events:            h.Events,
+		IDGenerator:       h.IDGenerator,
+		Jobs:              h.JobService,
+	})
+
+	idorService := idor.NewService(idor.Config{
+		Scope:             scope,
+		RequestLogService: reqLogService,
+		FindingRepository: database,
+		// Mutated requests are sent via the proxy, so that they are logged.
+		Transport:   p,
+		Events:      h.Events,
+		IDGenerator: h.IDGenerator,
+		Jobs:        h.JobService,
+	})
+
+	activeScanService := activescan.NewService(activescan.Config{
+		Scope:             scope,
+		RequestLogService: reqLogService,
+		FindingRepository: database,
+		// Requests with payloads are sent via the proxy, so that the evidence
+		// of detections is logged.
+		Transport:   p,
+		Events:      h.Events,
+		IDGenerator: h.IDGenerator,
+		Jobs:        h.JobService,
+	})
+
+	nucleiService := nuclei.NewService(nuclei.Config{
+		Scope:             scope,
+		RequestLogService: reqLogService,
+		FindingRepository: database,
+		// Template requests are sent via the proxy, so that matches can be
+		// linked to request logs.
+		Transport:   p,
+		Events:      h.Events,
+		IDGenerator: h.IDGenerator,
+		Jobs:        h.JobService,
+	})
+
+	replayService := replay.NewService(replay.Config{
+		RequestLogService: reqLogService,
+		Transport:         p,
+		IDGenerator:       h.IDGenerator,
+		Rewriter:          h.Rewriter,
+		Signer:            senderService,
+		Jobs:              h.JobService,
+	})
+
+	browserLauncher := browser.NewLauncher(browser.Config{
+		ProxyAddr: addr,
+		CACert:    caCert,
+	})
+
+	crawlerService := crawler.NewService(crawler.Config{
+		Scope:       scope,
+		Transport:   p,
+		IDGenerator: h.IDGenerator,
+		Jobs:        h.JobService,
+	})
 
 	fsSub, err := fs.Sub(adminContent, "admin")
 	if err != nil {
 		return fmt.Errorf("could not prepare subtree file system: %w", err)
 	}
 
-	adminHandler := http.FileServer(http.FS(fsSub))
 	router := mux.NewRouter().SkipClean(true)
-	adminRouter := router.MatcherFunc(func(req *http.Request, match *mux.RouteMatch) bool {
-		hostname, _ := os.Hostname()
-		host, _, _ := net.SplitHostPort(req.Host)
-		return strings.EqualFold(host, hostname) || (req.Host == "hetty.proxy" || req.Host == "localhost:8080")
-	}).Subrouter().StrictSlash(true)
+
+	// Proxy auto-config file. It's served for any host (unlike the admin
+	// interface), so that other devices on the network can use it. Requests
+	// with an absolute URL are proxied requests, and are left to the proxy.
+	_, port, _ := net.SplitHostPort(addr)
+	router.MatcherFunc(func(req *http.Request, match *mux.RouteMatch) bool {
+		return req.URL.Host == "" && req.URL.Path == "/proxy.pac"
+	}).Handler(pac.Handler(scope, port))
+
+	// CA certificate download, for configuring other devices on the network.
+	caCertPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})
+	router.MatcherFunc(func(req *http.Request, match *mux.RouteMatch) bool {
+		return req.URL.Host == "" && req.URL.Path == caCertPath
+	}).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-x509-ca-cert")
+		w.Header().Set("Content-Disposition", `attachment; filename="hetty_cert.pem"`)
+		w.Write(caCertPEM)
+	})
+
+	adminRouter := mux.NewRouter().SkipClean(true).StrictSlash(true)
 
 	// GraphQL server.
-	adminRouter.Path("/api/playground/").Handler(playground.Handler("GraphQL Playground", "/api/graphql/"))
-	adminRouter.Path("/api/graphql/").Handler(
-		handler.NewDefaultServer(api.NewExecutableSchema(api.Config{Resolvers: &api.Resolver{
-			ProjectService:    projService,
-			RequestLogService: reqLogService,
-			SenderService:     senderService,
-		}})))
+	adminRouter.Path("/api/playground/").Handler(playground.Handler("GraphQL Playground", basePath+"/api/graphql/"))
+	gqlServer := handler.NewDefaultServer(api.NewExecutableSchema(api.Config{Resolvers: &api.Resolver{
+		ProjectService:    projService,
+		RequestLogService: reqLogService,
+		SenderService:     senderService,
+		OASTService:       oastService,
+		DiscoveryService:  discoveryService,
+		CrawlerService:    crawlerService,
+		FindingService:    findingService,
+		AuthzService:      h.AuthzService,
+		SmugglingService:  smuggleService,
+		IDORService:       idorService,
+		ActiveScanService: activeScanService,
+		NucleiService:     nucleiService,
+		ReplayService:     replayService,
+		ConnLogService:    connLogService,
+		OAuth2Service:     oauth2Service,
+		JobService:        h.JobService,
+		InterceptService:  h.InterceptService,
+		BrowserLauncher:   browserLauncher,
+		Proxy:             p,
+		Logger:            logger,
+	}}))
+	gqlServer.SetErrorPresenter(api.ErrorPresenter)
+	adminRouter.Path("/api/graphql/").Handler(gqlServer)
+
+	// REST API.
+	adminRouter.PathPrefix(rest.PathPrefix + "/").Handler(rest.NewHandler(rest.Config{
+		ProjectService:    projService,
+		RequestLogService: reqLogService,
+		SenderService:     senderService,
+		Database:          maintainer,
+	}))
+
+	// QR code for mobile device setup.
+	adminRouter.Path("/api/qrcode.png").Handler(qrCodeHandler(port))
 
 	// Admin interface.
-	adminRouter.PathPrefix("").Handler(adminHandler)
+	adminRouter.PathPrefix("").Handler(webui.Handler(fsSub, basePath))
+
+	adminHandler := origin.Handler(origin.Config{AllowedOrigins: adminAllowedOrigins},
+		webui.BasePathHandler(basePath, adminRouter))
+
+	// With a separate admin address, the admin interface isn't served on the
+	// proxy listener.
+	if adminAddr == "" {
+		router.MatcherFunc(func(req *http.Request, match *mux.RouteMatch) bool {
+			hostname, _ := os.Hostname()
+			host, _, _ := net.SplitHostPort(req.Host)
+			return strings.EqualFold(host, hostname) || (req.Host == "hetty.proxy" || req.Host == "localhost:8080")
+		}).Handler(adminHandler)
+	}
+
+	// Out-of-band callbacks, for when the OAST domain resolves to the main listener.
+	if oastDomain != "" {
+		router.MatcherFunc(func(req *http.Request, match *mux.RouteMatch) bool {
+			host := req.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			return strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(strings.Trim(oastDomain, ".")))
+		}).Handler(oastService)
+	}
 
 	// Fallback (default) is the Proxy handler.
 	router.PathPrefix("").Handler(p)
@@ -152,12 +710,389 @@ func run() error {
 		TLSNextProto: map[string]func(*http.Server, *tls.Conn, http.Handler){}, // Disable HTTP/2
 	}
 
+	if ln == nil {
+		ln, err = net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("could not listen on %v: %w", addr, err)
+		}
+	}
+
+	var adminServer *http.Server
+
+	if adminAddr != "" {
+		if adminLn == nil {
+			adminLn, err = listenAdmin(adminAddr)
+			if err != nil {
+				return fmt.Errorf("could not listen on %v: %w", adminAddr, err)
+			}
+		}
+
+		adminServer = &http.Server{
+			Handler:   adminHandler,
+			TLSConfig: adminTLSConfig,
+		}
+
+		go func() {
+			serve := adminServer.Serve
+			if adminTLSConfig != nil {
+				serve = func(ln net.Listener) error { return adminServer.ServeTLS(ln, "", "") }
+			}
+
+			if err := serve(adminLn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("[ERROR] Admin server closed unexpected: %v", err)
+			}
+		}()
+
+		log.Printf("[INFO] Admin interface is available on %v ...", adminAddr)
+	}
+
+	if systemProxy {
+		restore, err := sysproxy.Enable(addr)
+		if err != nil {
+			return fmt.Errorf("could not configure system proxy: %w", err)
+		}
+
+		defer func() {
+			if err := restore(); err != nil {
+				log.Printf("[ERROR] Could not restore system proxy settings: %v", err)
+			}
+		}()
+
+		log.Printf("[INFO] System proxy settings configured to use Hetty.")
+	}
+
+	if mdnsEnabled {
+		if err := advertiseMDNS(port); err != nil {
+			log.Printf("[ERROR] Could not advertise proxy via mDNS: %v", err)
+		}
+	}
+
+	// Shut down gracefully on interrupt, so that pending logs are stored and
+	// deferred cleanup (e.g. restoring system proxy settings) is done before
+	// exiting.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	restart := make(chan os.Signal, 1)
+	if len(restartSignals) > 0 {
+		signal.Notify(restart, restartSignals...)
+		defer signal.Stop(restart)
+	}
+
+	shutdownDone := make(chan struct{})
+
+	go func() {
+		defer close(shutdownDone)
+
+		waitForShutdown(ctx, restart, ln, adminLn)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			log.Printf("[ERROR] Could not gracefully shut down HTTP server: %v", err)
+		}
+
+		if adminServer != nil {
+			if err := adminServer.Shutdown(shutdownCtx); err != nil {
+				log.Printf("[ERROR] Could not gracefully shut down admin server: %v", err)
+			}
+		}
+
+		if err := h.Shutdown(shutdownCtx); err != nil {
+			log.Printf("[ERROR] Could not shut down gracefully: %v", err)
+		}
+
+		if err := tracer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("[ERROR] Could not export remaining traces: %v", err)
+		}
+	}()
+
 	log.Printf("[INFO] Hetty (v%v) is running on %v ...", version, addr)
 
-	err = s.ListenAndServe()
-	if err != nil && errors.Is(err, http.ErrServerClosed) {
+	err = s.Serve(ln)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return fmt.Errorf("http server closed unexpected: %w", err)
 	}
 
+	<-shutdownDone
+
+	return nil
+}
+
+// waitForShutdown blocks until ctx is done, or a restart is requested and the
+// new process was started. Restarts that fail are logged, and Hetty keeps
+// running.
+func waitForShutdown(ctx context.Context, restart <-chan os.Signal, ln, adminLn net.Listener) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[INFO] Shutting down ...")
+			return
+		case <-restart:
+			if err := startProcess(ln, adminLn); err != nil {
+				log.Printf("[ERROR] Could not restart: %v", err)
+				continue
+			}
+
+			log.Printf("[INFO] Restarting; draining connections ...")
+
+			return
+		}
+	}
+}
+
+// openDatabase opens the Badger database, or an in-memory database when path
+// is `memory`. Opening the Badger database is retried for the duration of
+// wait, e.g. while another process releases the database.
+func openDatabase(path string, wait time.Duration, batch badger.BatchConfig) (db.Database, error) {
+	if path == memoryDBPath {
+		return memory.OpenDatabase(), nil
+	}
+
+	deadline := time.Now().Add(wait)
+
+	for {
+		database, err := badger.OpenDatabase(badgerdb.DefaultOptions(path))
+		if err == nil {
+			database.BatchWrites(batch)
+			return database, nil
+		}
+
+		// A database of a newer version won't become openable by waiting.
+		if errors.Is(err, badger.ErrSchemaTooNew) || time.Now().After(deadline) {
+			return nil, err
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// pregenerateCerts generates leaf certificates for the HTTPS hosts of in-scope
+// request logs of a project, up to max hosts.
+func pregenerateCerts(p *proxy.Proxy, repo reqlog.Repository, scope *scope.Scope, projectID ulid.ULID, max int) {
+	reqLogs, err := repo.FindRequestLogs(context.Background(), reqlog.FindRequestsFilter{
+		ProjectID:   projectID,
+		OnlyInScope: true,
+	}, scope)
+	if err != nil {
+		log.Printf("[ERROR] Could not find request logs to pregenerate certificates: %v", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	hostnames := make([]string, 0)
+
+	for _, reqLog := range reqLogs {
+		if reqLog.URL == nil || reqLog.URL.Scheme != "https" {
+			continue
+		}
+
+		hostname := reqLog.URL.Hostname()
+		if hostname == "" || seen[hostname] {
+			continue
+		}
+
+		seen[hostname] = true
+		hostnames = append(hostnames, hostname)
+
+		if len(hostnames) == max {
+			break
+		}
+	}
+
+	if err := p.PregenerateCerts(hostnames); err != nil {
+		log.Printf("[ERROR] Could not pregenerate certificates: %v", err)
+		return
+	}
+
+	log.Printf("[INFO] Generated certificates for %v in-scope hosts.", len(hostnames))
+}
+
+// advertiseMDNS answers mDNS queries for the proxy in the background. The TXT
+// record contains the proxy address, and the PAC file and CA certificate URLs.
+func advertiseMDNS(port string) error {
+	portNum, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port: %w", err)
+	}
+
+	ips, err := mdns.InterfaceIPs()
+	if err != nil {
+		return err
+	}
+
+	if len(ips) == 0 {
+		return mdns.ErrNoAddrs
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("could not get hostname: %w", err)
+	}
+
+	hostname = strings.SplitN(hostname, ".", 2)[0]
+	hostPort := net.JoinHostPort(ips[0].String(), port)
+
+	responder, err := mdns.NewResponder(mdns.Config{
+		Instance: "Hetty on " + hostname,
+		Hostname: hostname,
+		Port:     uint16(portNum),
+		TXT: []string{
+			"version=" + version,
+			"proxy=" + hostPort,
+			"pac=http://" + hostPort + "/proxy.pac",
+			"ca=http://" + hostPort + caCertPath,
+		},
+		IPs: ips,
+	})
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdns.GroupAddr)
+	if err != nil {
+		return fmt.Errorf("could not listen for mDNS queries: %w", err)
+	}
+
+	if err := responder.Announce(conn); err != nil {
+		log.Printf("[ERROR] Could not announce mDNS records: %v", err)
+	}
+
+	go func() {
+		if err := responder.Serve(conn); err != nil {
+			log.Printf("[ERROR] mDNS responder stopped: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// hostTimeoutsFlag is a repeatable flag of upstream host timeouts.
+type hostTimeoutsFlag []proxy.HostTimeouts
+
+func (f *hostTimeoutsFlag) String() string {
+	hosts := make([]string, len(*f))
+	for i, ht := range *f {
+		hosts[i] = ht.Host
+	}
+
+	return strings.Join(hosts, ", ")
+}
+
+func (f *hostTimeoutsFlag) Set(s string) error {
+	ht, err := proxy.ParseHostTimeouts(s)
+	if err != nil {
+		return err
+	}
+
+	*f = append(*f, ht)
+
 	return nil
 }
+
+// originsFlag is a repeatable flag of origin patterns. Values can be
+// comma-separated, e.g. for setting multiple origins via an environment
+// variable.
+type originsFlag []origin.Pattern
+
+func (f *originsFlag) String() string {
+	origins := make([]string, len(*f))
+	for i, p := range *f {
+		origins[i] = p.String()
+	}
+
+	return strings.Join(origins, ", ")
+}
+
+func (f *originsFlag) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		p, err := origin.ParsePattern(strings.TrimSpace(v))
+		if err != nil {
+			return err
+		}
+
+		*f = append(*f, p)
+	}
+
+	return nil
+}
+
+// rateLimitsFlag is a repeatable flag of upstream rate limits.
+type rateLimitsFlag []ratelimit.Rule
+
+func (f *rateLimitsFlag) String() string {
+	rules := make([]string, len(*f))
+	for i, rule := range *f {
+		rules[i] = rule.String()
+	}
+
+	return strings.Join(rules, ", ")
+}
+
+func (f *rateLimitsFlag) Set(s string) error {
+	rule, err := ratelimit.ParseRule(s)
+	if err != nil {
+		return err
+	}
+
+	*f = append(*f, rule)
+
+	return nil
+}
+
+// blockRulesFlag is a repeatable flag of proxy block rules.
+type blockRulesFlag []proxy.BlockRule
+
+func (f *blockRulesFlag) String() string {
+	rules := make([]string, len(*f))
+	for i, rule := range *f {
+		rules[i] = rule.Host
+		if rule.URL != nil {
+			rules[i] = "url:" + rule.URL.String()
+		}
+	}
+
+	return strings.Join(rules, ", ")
+}
+
+func (f *blockRulesFlag) Set(s string) error {
+	rule, err := proxy.ParseBlockRule(s)
+	if err != nil {
+		return err
+	}
+
+	*f = append(*f, rule)
+
+	return nil
+}
+
+// newLogger returns the output of the standard logger, configured by the log
+// flags.
+func newLogger() (*logging.Logger, error) {
+	level, err := logging.ParseLevel(logLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := logging.ParseFormat(logFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := logging.Config{
+		Level:       level,
+		Format:      format,
+		MaxFileSize: logFileMaxSize * 1024 * 1024,
+		MaxBackups:  logFileMaxBackups,
+		Syslog:      logSyslog,
+	}
+
+	if logFile != "" {
+		if cfg.File, err = homedir.Expand(logFile); err != nil {
+			return nil, fmt.Errorf("could not parse log filepath: %w", err)
+		}
+	}
+
+	return logging.New(cfg)
+}
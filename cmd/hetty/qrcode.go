@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+
+	"github.com/dstotijn/hetty/pkg/mdns"
+	"github.com/dstotijn/hetty/pkg/qrcode"
+)
+
+// qrCodeHandler serves a QR code PNG image for configuring mobile devices. The
+// `content` query parameter selects what is encoded:
+//
+//   - `ca` (default): CA certificate download URL.
+//   - `pac`: Proxy auto-config file URL.
+//   - `proxy`: Proxy address, in the form "host:port".
+//
+// The host defaults to the first non-loopback IP address of the machine, and
+// can be set with the `host` query parameter.
+func qrCodeHandler(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.URL.Query().Get("host")
+
+		if host == "" {
+			if ips, err := mdns.InterfaceIPs(); err == nil && len(ips) > 0 {
+				host = ips[0].String()
+			} else {
+				host, _, _ = net.SplitHostPort(r.Host)
+			}
+		}
+
+		hostPort := net.JoinHostPort(host, port)
+
+		var content string
+
+		switch r.URL.Query().Get("content") {
+		case "", "ca":
+			content = "http://" + hostPort + caCertPath
+		case "pac":
+			content = "http://" + hostPort + "/proxy.pac"
+		case "proxy":
+			content = hostPort
+		default:
+			http.Error(w, "Invalid content, must be one of: ca, pac, proxy.", http.StatusBadRequest)
+			return
+		}
+
+		code, err := qrcode.Encode([]byte(content))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		buf := &bytes.Buffer{}
+
+		if err := code.PNG(buf, 8); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/mitchellh/go-homedir"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/badger"
+)
+
+// runReindex rebuilds the request log indexes of projects in the database, e.g.
+// for projects that were created with an older version of Hetty. Hetty must
+// not be running, because it holds the database lock.
+func runReindex(args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+
+	fs.StringVar(&dbPath, "db", "~/.hetty/db", "Database directory path")
+
+	var projectID string
+
+	fs.StringVar(&projectID, "project", "", "ID of the project to reindex. All projects are reindexed when empty")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	dbPath, err := homedir.Expand(dbPath)
+	if err != nil {
+		return fmt.Errorf("could not parse projects filepath: %w", err)
+	}
+
+	database, err := badger.OpenDatabase(badgerdb.DefaultOptions(dbPath).WithLogger(nil))
+	if err != nil {
+		return fmt.Errorf("could not open database: %w", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+
+	var projectIDs []ulid.ULID
+
+	if projectID != "" {
+		id, err := ulid.Parse(projectID)
+		if err != nil {
+			return fmt.Errorf("invalid project ID: %w", err)
+		}
+
+		projectIDs = append(projectIDs, id)
+	} else {
+		projects, err := database.Projects(ctx)
+		if err != nil {
+			return fmt.Errorf("could not get projects: %w", err)
+		}
+
+		for _, project := range projects {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	}
+
+	for _, id := range projectIDs {
+		n, err := database.RebuildRequestLogIndexes(ctx, id)
+		if err != nil {
+			return fmt.Errorf("could not reindex project (id: %v): %w", id, err)
+		}
+
+		log.Printf("[INFO] Reindexed %v request logs of project (id: %v).", n, id)
+	}
+
+	return nil
+}
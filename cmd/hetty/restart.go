@@ -0,0 +1,126 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// listenerFDEnv is set for a process started by a restart. The listener of the
+// previous process is inherited as file descriptor 3, and a separate admin
+// listener as file descriptor 4.
+const (
+	listenerFDEnv      = "HETTY_LISTENER_FD"
+	adminListenerFDEnv = "HETTY_ADMIN_LISTENER_FD"
+)
+
+// restartSignals trigger a live restart.
+var restartSignals = []os.Signal{syscall.SIGHUP}
+
+// inheritedListeners returns the listener and admin listener passed on by the
+// previous process, or nil if this process wasn't started by a restart (or the
+// previous process had no admin listener).
+func inheritedListeners() (ln, adminLn net.Listener, err error) {
+	if os.Getenv(listenerFDEnv) == "" {
+		return nil, nil, nil
+	}
+
+	os.Unsetenv(listenerFDEnv)
+
+	ln, err = fileListener(3, "listener")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if os.Getenv(adminListenerFDEnv) == "" {
+		return ln, nil, nil
+	}
+
+	os.Unsetenv(adminListenerFDEnv)
+
+	adminLn, err = fileListener(4, "admin listener")
+	if err != nil {
+		ln.Close()
+		return nil, nil, err
+	}
+
+	return ln, adminLn, nil
+}
+
+func fileListener(fd uintptr, name string) (net.Listener, error) {
+	f := os.NewFile(fd, name)
+	defer f.Close()
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not use inherited %v: %w", name, err)
+	}
+
+	return ln, nil
+}
+
+// startProcess starts a new Hetty process with the same arguments, that
+// accepts connections on ln, and on adminLn if it isn't nil.
+func startProcess(ln, adminLn net.Listener) error {
+	if _, ok := ln.(*net.TCPListener); !ok {
+		return errors.New("listener is not a TCP listener")
+	}
+
+	f, err := listenerFile(ln)
+	if err != nil {
+		return fmt.Errorf("could not get listener file: %w", err)
+	}
+	defer f.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("could not get executable path: %w", err)
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), listenerFDEnv+"=3")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{f}
+
+	if adminLn != nil {
+		adminFile, err := listenerFile(adminLn)
+		if err != nil {
+			return fmt.Errorf("could not get admin listener file: %w", err)
+		}
+		defer adminFile.Close()
+
+		cmd.Env = append(cmd.Env, adminListenerFDEnv+"=4")
+		cmd.ExtraFiles = append(cmd.ExtraFiles, adminFile)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("could not start process: %w", err)
+	}
+
+	// The socket file of a Unix listener is removed when the listener is
+	// closed, but the new process still uses it.
+	if unixLn, ok := adminLn.(*net.UnixListener); ok {
+		unixLn.SetUnlinkOnClose(false)
+	}
+
+	return nil
+}
+
+func listenerFile(ln net.Listener) (*os.File, error) {
+	switch ln := ln.(type) {
+	case *net.TCPListener:
+		return ln.File()
+	case *net.UnixListener:
+		return ln.File()
+	default:
+		return nil, fmt.Errorf("unsupported listener type %T", ln)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// Live restarts rely on passing the listener to a new process, which isn't
+// supported on Windows.
+var restartSignals []os.Signal
+
+func inheritedListeners() (net.Listener, net.Listener, error) {
+	return nil, nil, nil
+}
+
+func startProcess(_, _ net.Listener) error {
+	return errors.New("restart is not supported on Windows")
+}
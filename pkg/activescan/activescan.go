@@ -0,0 +1,496 @@
+// Package activescan injects payloads into the parameters of logged requests,
+// and evaluates the responses with the detection rules of each payload, e.g. to
+// find SQL injection, path traversal and server-side template injection. Scans
+// are opt-in: they only run for selected, in-scope requests.
+package activescan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+	// Maximum number of requests of a scan.
+	maxScanRequests = 10000
+)
+
+// JobType is the type of the jobs that run scans, see `job.Params`.
+const JobType = "activeScan"
+
+var (
+	ErrScanNotFound    = errcode.New(errcode.NotFound, "activescan: scan not found")
+	ErrNoRequests      = errcode.New(errcode.Invalid, "activescan: no in-scope request logs with parameters selected")
+	ErrTooManyRequests = errcode.New(errcode.Invalid, fmt.Sprintf("activescan: scan exceeds %v requests", maxScanRequests))
+	ErrInvalidPayload  = errcode.New(errcode.Invalid, "activescan: invalid payload")
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusFinished  Status = "finished"
+	StatusCancelled Status = "cancelled"
+)
+
+// Service runs active scans of logged requests. Detections are stored as
+// findings.
+type Service interface {
+	StartScan(ctx context.Context, params ScanParams) (Scan, error)
+	FindScanByID(id ulid.ULID) (Scan, error)
+	FindScans() []Scan
+	CancelScan(id ulid.ULID) error
+}
+
+type service struct {
+	scope       *scope.Scope
+	ids         idgen.Generator
+	jobs        job.Service
+	reqLogSvc   reqlog.Service
+	findingRepo finding.Repository
+	events      *event.Bus
+	httpClient  *http.Client
+	scans       map[ulid.ULID]*scanState
+	mu          sync.RWMutex
+}
+
+type Config struct {
+	Scope             *scope.Scope
+	RequestLogService reqlog.Service
+	FindingRepository finding.Repository
+	// Transport that requests with payloads are sent with, e.g. the proxy, so
+	// that they are logged. Defaults to `http.DefaultTransport`.
+	Transport http.RoundTripper
+	// Bus for publishing created findings. Optional.
+	Events *event.Bus
+	// Generates the IDs of scans and findings. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+	// Runs scans as jobs, so that they're queued, report progress and can be
+	// paused. Optional; without it, scans start right away.
+	Jobs job.Service
+}
+
+type ScanParams struct {
+	Selection reqlog.Selection
+	// Payloads to inject. Defaults to `DefaultPayloads()`.
+	Payloads []Payload
+	// Names of the parameters to inject into. Defaults to all parameters.
+	Parameters []string
+}
+
+type Scan struct {
+	ID        ulid.ULID
+	Status    Status
+	Total     int
+	Completed int
+	// Number of requests that couldn't be sent.
+	Errors     int
+	Detections []Detection
+}
+
+// Detection is a response to a request with a payload that matched a rule of
+// the payload.
+type Detection struct {
+	RequestLogID ulid.ULID
+	Method       string
+	URL          *url.URL
+	Parameter    Parameter
+	PayloadName  string
+	Category     Category
+	Payload      string
+	// Request log of the request with the payload, if it was logged.
+	ProbeRequestLogID ulid.ULID
+	StatusCode        int
+	// Part of the response that matched, e.g. a database error message.
+	Evidence string
+}
+
+type probe struct {
+	reqLog reqlog.RequestLog
+	params []Parameter
+}
+
+type scanState struct {
+	scan   Scan
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &service{
+		ids:         cfg.IDGenerator,
+		jobs:        cfg.Jobs,
+		scope:       cfg.Scope,
+		reqLogSvc:   cfg.RequestLogService,
+		findingRepo: cfg.FindingRepository,
+		events:      cfg.Events,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   defaultTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		scans: make(map[ulid.ULID]*scanState),
+	}
+}
+
+// StartScan looks up the selected request logs, and injects the payloads into
+// the parameters of those that match the project scope and have a response,
+// in the background.
+func (svc *service) StartScan(ctx context.Context, params ScanParams) (Scan, error) {
+	if len(params.Payloads) == 0 {
+		params.Payloads = DefaultPayloads()
+	}
+
+	payloads, err := compilePayloads(params.Payloads)
+	if err != nil {
+		return Scan{}, err
+	}
+
+	selected, err := svc.reqLogSvc.FindSelectedRequests(ctx, params.Selection)
+	if err != nil {
+		return Scan{}, fmt.Errorf("activescan: failed to find request logs: %w", err)
+	}
+
+	names := make(map[string]bool, len(params.Parameters))
+	for _, name := range params.Parameters {
+		names[name] = true
+	}
+
+	var probes []probe
+
+	total := 0
+
+	for _, reqLog := range selected {
+		if reqLog.Response == nil || reqLog.URL == nil || reqLog.URL.Host == "" {
+			continue
+		}
+
+		if svc.scope != nil && !svc.scope.Match(&http.Request{URL: reqLog.URL, Header: reqLog.Header}, reqLog.Body) {
+			continue
+		}
+
+		var reqParams []Parameter
+
+		for _, param := range parameters(reqLog) {
+			if len(names) == 0 || names[param.Name] {
+				reqParams = append(reqParams, param)
+			}
+		}
+
+		if len(reqParams) == 0 {
+			continue
+		}
+
+		probes = append(probes, probe{reqLog: reqLog, params: reqParams})
+		total += len(reqParams) * len(payloads)
+	}
+
+	if len(probes) == 0 {
+		return Scan{}, ErrNoRequests
+	}
+
+	if total > maxScanRequests {
+		return Scan{}, ErrTooManyRequests
+	}
+
+	sort.Slice(probes, func(i, j int) bool {
+		return probes[i].reqLog.ID.Compare(probes[j].reqLog.ID) < 0
+	})
+
+	scanCtx, cancel := context.WithCancel(context.Background())
+
+	state := &scanState{
+		scan: Scan{
+			ID:     svc.ids.New(time.Now()),
+			Status: StatusRunning,
+			Total:  total,
+		},
+		cancel: cancel,
+	}
+
+	svc.mu.Lock()
+	svc.scans[state.scan.ID] = state
+	svc.mu.Unlock()
+
+	if svc.jobs == nil {
+		go svc.run(scanCtx, state, probes, payloads, nil)
+		return state.snapshot(), nil
+	}
+
+	jobParams := job.Params{
+		ID:          state.scan.ID,
+		Type:        JobType,
+		Description: fmt.Sprintf("%v requests, %v payloads", len(probes), len(payloads)),
+		Total:       total,
+	}
+
+	svc.jobs.StartJob(scanCtx, jobParams, func(ctx context.Context, progress *job.Progress) error {
+		return svc.run(ctx, state, probes, payloads, progress)
+	})
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindScanByID(id ulid.ULID) (Scan, error) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	state, ok := svc.scans[id]
+	if !ok {
+		return Scan{}, ErrScanNotFound
+	}
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindScans() []Scan {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	scans := make([]Scan, 0, len(svc.scans))
+	for _, state := range svc.scans {
+		scans = append(scans, state.snapshot())
+	}
+
+	// Most recent scans first.
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].ID.Compare(scans[j].ID) > 0
+	})
+
+	return scans
+}
+
+func (svc *service) CancelScan(id ulid.ULID) error {
+	svc.mu.RLock()
+	state, ok := svc.scans[id]
+	svc.mu.RUnlock()
+
+	if !ok {
+		return ErrScanNotFound
+	}
+
+	state.mu.Lock()
+	if state.scan.Status == StatusRunning {
+		state.scan.Status = StatusCancelled
+	}
+	state.mu.Unlock()
+
+	state.cancel()
+
+	return nil
+}
+
+// run sends the requests of a scan. Once a payload category is detected for a
+// parameter, its remaining payloads are skipped.
+func (svc *service) run(
+	ctx context.Context,
+	state *scanState,
+	probes []probe,
+	payloads []compiledPayload,
+	progress *job.Progress,
+) error {
+	defer state.cancel()
+
+loop:
+	for _, p := range probes {
+		for _, param := range p.params {
+			detected := make(map[Category]bool)
+
+			for _, payload := range payloads {
+				if err := progress.Wait(ctx); err != nil {
+					break loop
+				}
+
+				if detected[payload.Category] {
+					state.mu.Lock()
+					state.scan.Completed++
+					state.mu.Unlock()
+					progress.Add(1)
+
+					continue
+				}
+
+				detection, ok, err := svc.send(ctx, p.reqLog, param, payload)
+				if ctx.Err() != nil {
+					break loop
+				}
+
+				if ok {
+					detected[payload.Category] = true
+
+					if err := svc.storeFinding(p.reqLog, detection); err != nil {
+						log.Printf("[ERROR] Could not store active scan finding: %v", err)
+					}
+				}
+
+				state.mu.Lock()
+				state.scan.Completed++
+				if err != nil {
+					state.scan.Errors++
+				}
+				if ok {
+					state.scan.Detections = append(state.scan.Detections, detection)
+				}
+				state.mu.Unlock()
+				progress.Add(1)
+			}
+		}
+	}
+
+	state.mu.Lock()
+	if state.scan.Status == StatusRunning {
+		state.scan.Status = StatusFinished
+		if ctx.Err() != nil {
+			state.scan.Status = StatusCancelled
+		}
+	}
+	state.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// send sends a request log with a payload injected into a parameter, and
+// returns a detection if the response matches a rule of the payload.
+func (svc *service) send(
+	ctx context.Context,
+	reqLog reqlog.RequestLog,
+	param Parameter,
+	payload compiledPayload,
+) (Detection, bool, error) {
+	value := payload.Value
+	if payload.Append {
+		value = param.Value + payload.Value
+	}
+
+	injected, ok := inject(reqLog, param, value)
+	if !ok {
+		return Detection{}, false, fmt.Errorf("parameter %q not found in request", param.Name)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, injected.Method, injected.URL.String(), bytes.NewReader(injected.Body))
+	if err != nil {
+		return Detection{}, false, err
+	}
+
+	header := injected.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	// Set by the HTTP client, for the request with the payload.
+	header.Del("Content-Length")
+	header.Del("Host")
+
+	req.Header = header
+
+	res, err := svc.httpClient.Do(req)
+	if err != nil {
+		return Detection{}, false, err
+	}
+	defer res.Body.Close()
+
+	resLog, err := reqlog.ParseHTTPResponse(res)
+	if err != nil {
+		return Detection{}, false, err
+	}
+
+	evidence, ok := payload.match(resLog.StatusCode, resLog.Body, reqLog.Response.StatusCode, reqLog.Response.Body)
+	if !ok {
+		return Detection{}, false, nil
+	}
+
+	detection := Detection{
+		RequestLogID: reqLog.ID,
+		Method:       reqLog.Method,
+		URL:          reqLog.URL,
+		Parameter:    param,
+		PayloadName:  payload.Name,
+		Category:     payload.Category,
+		Payload:      value,
+		StatusCode:   resLog.StatusCode,
+		Evidence:     evidence,
+	}
+
+	if res.Request != nil {
+		if reqLogID, ok := res.Request.Context().Value(proxy.ReqLogIDKey).(ulid.ULID); ok {
+			detection.ProbeRequestLogID = reqLogID
+		}
+	}
+
+	return detection, true, nil
+}
+
+// storeFinding stores a finding for a detection. It refers to the request log
+// with the payload if it was logged, because that has the evidence.
+func (svc *service) storeFinding(reqLog reqlog.RequestLog, detection Detection) error {
+	f := finding.Finding{
+		ID:           svc.ids.New(time.Now()),
+		ProjectID:    reqLog.ProjectID,
+		RequestLogID: reqLog.ID,
+		Check:        detection.Category.check(),
+		Severity:     detection.Category.severity(),
+		Description: fmt.Sprintf(
+			"Injecting payload %q (%v) into %v parameter `%v` of request log %v returned a response "+
+				"that matches its detection rules. Evidence: %q",
+			detection.Payload, detection.PayloadName, detection.Parameter.Location, detection.Parameter.Name,
+			reqLog.ID, detection.Evidence,
+		),
+	}
+
+	if detection.ProbeRequestLogID.Compare(ulid.ULID{}) != 0 {
+		f.RequestLogID = detection.ProbeRequestLogID
+	}
+
+	if err := svc.findingRepo.StoreFinding(context.Background(), f); err != nil {
+		return err
+	}
+
+	svc.events.Publish(event.Event{
+		Type:      event.TypeFindingCreated,
+		ProjectID: f.ProjectID,
+		ID:        f.ID,
+		Data:      f,
+	})
+
+	return nil
+}
+
+func (state *scanState) snapshot() Scan {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	scan := state.scan
+	scan.Detections = make([]Detection, len(state.scan.Detections))
+	copy(scan.Detections, state.scan.Detections)
+
+	return scan
+}
@@ -0,0 +1,184 @@
+package activescan_test
+
+//go:generate go run github.com/matryer/moq -out reqlog_mock_test.go -pkg activescan_test ../reqlog Service:ReqLogServiceMock
+//go:generate go run github.com/matryer/moq -out finding_repo_mock_test.go -pkg activescan_test ../finding Repository:FindingRepoMock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/activescan"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+//nolint:gosec
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func TestStartScan(t *testing.T) {
+	t.Parallel()
+
+	// The `id` query parameter is vulnerable to SQL injection, and the `name`
+	// JSON field to template injection.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("id"), "'") {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, "You have an error in your SQL syntax; check the manual")
+
+			return
+		}
+
+		var body struct {
+			Name string `json:"name"`
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		fmt.Fprintf(w, "Hello, %v", strings.ReplaceAll(body.Name, "{{7*191}}", "1337"))
+	}))
+	t.Cleanup(ts.Close)
+
+	newReqLog := func(rawURL, body string) reqlog.RequestLog {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			Method:    http.MethodPost,
+			URL:       u,
+			Header:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:      []byte(body),
+			Response: &reqlog.ResponseLog{
+				StatusCode: http.StatusOK,
+				Body:       []byte("Hello, foo"),
+			},
+		}
+	}
+
+	reqLogs := []reqlog.RequestLog{
+		newReqLog(ts.URL+"/users?id=1", `{"name":"foo"}`),
+		// Without a response, so it's skipped.
+		{ID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy), URL: &url.URL{Scheme: "http", Host: "example.com"}},
+	}
+
+	reqLogSvc := &ReqLogServiceMock{
+		FindSelectedRequestsFunc: func(_ context.Context, _ reqlog.Selection) ([]reqlog.RequestLog, error) {
+			return reqLogs, nil
+		},
+	}
+
+	findingRepo := &FindingRepoMock{
+		StoreFindingFunc: func(_ context.Context, _ finding.Finding) error {
+			return nil
+		},
+	}
+
+	svc := activescan.NewService(activescan.Config{
+		RequestLogService: reqLogSvc,
+		FindingRepository: findingRepo,
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := svc.StartScan(context.Background(), activescan.ScanParams{
+			Payloads: []activescan.Payload{{
+				Name:     "foo",
+				Category: activescan.CategoryOther,
+				Value:    "bar",
+				Rules:    []activescan.Rule{{Type: activescan.RuleBodyRegexp, Value: "("}},
+			}},
+		})
+		if !errors.Is(err, activescan.ErrInvalidPayload) {
+			t.Fatalf("expected `activescan.ErrInvalidPayload`, got: %v", err)
+		}
+	})
+
+	t.Run("no parameters", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := svc.StartScan(context.Background(), activescan.ScanParams{Parameters: []string{"foo"}})
+		if !errors.Is(err, activescan.ErrNoRequests) {
+			t.Fatalf("expected `activescan.ErrNoRequests`, got: %v", err)
+		}
+	})
+
+	t.Run("detections", func(t *testing.T) {
+		t.Parallel()
+
+		scan, err := svc.StartScan(context.Background(), activescan.ScanParams{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		payloads := len(activescan.DefaultPayloads())
+		if exp := 2 * payloads; scan.Total != exp {
+			t.Fatalf("expected %v requests, got: %v", exp, scan.Total)
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+
+		for scan.Status == activescan.StatusRunning && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+
+			scan, err = svc.FindScanByID(scan.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if scan.Status != activescan.StatusFinished || scan.Completed != scan.Total || scan.Errors != 0 {
+			t.Fatalf("expected scan to finish without errors, got: %+v", scan)
+		}
+
+		var got []string
+
+		for _, detection := range scan.Detections {
+			got = append(got, fmt.Sprintf("%v %v %v", detection.Parameter.Name, detection.PayloadName, detection.StatusCode))
+
+			if !strings.Contains(detection.Evidence, "SQL syntax") && !strings.Contains(detection.Evidence, "1337") {
+				t.Errorf("unexpected evidence: %q", detection.Evidence)
+			}
+		}
+
+		sort.Strings(got)
+
+		// The double quote payload is skipped, once SQL injection is detected.
+		exp := []string{"id sqli-single-quote 500", "name ssti-curly-braces 200"}
+		if diff := cmp.Diff(exp, got); diff != "" {
+			t.Fatalf("detections not equal (-exp, +got):\n%v", diff)
+		}
+
+		var checks []string
+
+		for _, call := range findingRepo.StoreFindingCalls() {
+			checks = append(checks, string(call.FindingMoqParam.Check))
+
+			if call.FindingMoqParam.RequestLogID != reqLogs[0].ID {
+				t.Errorf("expected finding for request log %v, got: %v", reqLogs[0].ID, call.FindingMoqParam.RequestLogID)
+			}
+		}
+
+		sort.Strings(checks)
+
+		if exp := []string{"sql_injection", "template_injection"}; !cmp.Equal(exp, checks) {
+			t.Fatalf("expected findings %v, got: %v", exp, checks)
+		}
+	})
+}
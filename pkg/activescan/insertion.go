@@ -0,0 +1,201 @@
+package activescan
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+// Location is the part of a request a parameter is in.
+type Location string
+
+const (
+	LocationQuery Location = "query"
+	LocationForm  Location = "form"
+	LocationJSON  Location = "json"
+)
+
+// Parameter is a value of a request that payloads are injected into.
+type Parameter struct {
+	Location Location
+	// Name of the query or form parameter, or dot separated path of the JSON
+	// field (e.g. `user.name`).
+	Name  string
+	Value string
+}
+
+// parameters returns the query parameters, and form or JSON body fields with a
+// string or number value, of a request log.
+func parameters(reqLog reqlog.RequestLog) []Parameter {
+	var params []Parameter
+
+	if reqLog.URL != nil {
+		params = append(params, valuesParameters(LocationQuery, reqLog.URL.Query())...)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(reqLog.Header.Get("Content-Type"))
+
+	switch {
+	case len(reqLog.Body) == 0:
+	case mediaType == "application/x-www-form-urlencoded":
+		if values, err := url.ParseQuery(string(reqLog.Body)); err == nil {
+			params = append(params, valuesParameters(LocationForm, values)...)
+		}
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if v, ok := decodeJSON(reqLog.Body); ok {
+			params = append(params, jsonParameters(nil, v)...)
+		}
+	}
+
+	return params
+}
+
+func valuesParameters(location Location, values url.Values) []Parameter {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	params := make([]Parameter, len(names))
+	for i, name := range names {
+		params[i] = Parameter{Location: location, Name: name, Value: values.Get(name)}
+	}
+
+	return params
+}
+
+func jsonParameters(path []string, v interface{}) []Parameter {
+	var params []Parameter
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+
+		for key := range t {
+			// Keys with dots can't be addressed by a path.
+			if !strings.Contains(key, ".") {
+				keys = append(keys, key)
+			}
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			params = append(params, jsonParameters(append(path[:len(path):len(path)], key), t[key])...)
+		}
+	case []interface{}:
+		for i, elem := range t {
+			params = append(params, jsonParameters(append(path[:len(path):len(path)], strconv.Itoa(i)), elem)...)
+		}
+	case json.Number:
+		if len(path) > 0 {
+			params = append(params, Parameter{Location: LocationJSON, Name: strings.Join(path, "."), Value: t.String()})
+		}
+	case string:
+		if len(path) > 0 {
+			params = append(params, Parameter{Location: LocationJSON, Name: strings.Join(path, "."), Value: t})
+		}
+	}
+
+	return params
+}
+
+// inject returns a copy of reqLog, with the value of the parameter set to
+// value. It returns false if the request doesn't have the parameter.
+func inject(reqLog reqlog.RequestLog, param Parameter, value string) (reqlog.RequestLog, bool) {
+	u := *reqLog.URL
+
+	switch param.Location {
+	case LocationQuery:
+		query := u.Query()
+		if _, ok := query[param.Name]; !ok {
+			return reqlog.RequestLog{}, false
+		}
+
+		query.Set(param.Name, value)
+		u.RawQuery = query.Encode()
+	case LocationForm:
+		values, err := url.ParseQuery(string(reqLog.Body))
+		if _, ok := values[param.Name]; err != nil || !ok {
+			return reqlog.RequestLog{}, false
+		}
+
+		values.Set(param.Name, value)
+		reqLog.Body = []byte(values.Encode())
+	case LocationJSON:
+		v, ok := decodeJSON(reqLog.Body)
+		if !ok || !setJSONPath(v, strings.Split(param.Name, "."), value) {
+			return reqlog.RequestLog{}, false
+		}
+
+		body, err := json.Marshal(v)
+		if err != nil {
+			return reqlog.RequestLog{}, false
+		}
+
+		reqLog.Body = body
+	default:
+		return reqlog.RequestLog{}, false
+	}
+
+	reqLog.URL = &u
+
+	return reqLog, true
+}
+
+// setJSONPath sets the value at a path of a decoded JSON document to a string.
+func setJSONPath(v interface{}, keys []string, value string) bool {
+	key, rest := keys[0], keys[1:]
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		old, ok := t[key]
+		if !ok {
+			return false
+		}
+
+		if len(rest) > 0 {
+			return setJSONPath(old, rest, value)
+		}
+
+		t[key] = value
+
+		return true
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(t) {
+			return false
+		}
+
+		if len(rest) > 0 {
+			return setJSONPath(t[i], rest, value)
+		}
+
+		t[i] = value
+
+		return true
+	default:
+		return false
+	}
+}
+
+func decodeJSON(body []byte) (interface{}, bool) {
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return nil, false
+	}
+
+	return v, true
+}
@@ -0,0 +1,274 @@
+package activescan
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/finding"
+)
+
+// Maximum length of the evidence of a detection.
+const maxEvidenceLen = 200
+
+// Category is the kind of vulnerability a payload probes for.
+type Category string
+
+const (
+	CategorySQLInjection      Category = "sql_injection"
+	CategoryPathTraversal     Category = "path_traversal"
+	CategoryTemplateInjection Category = "template_injection"
+	CategoryOther             Category = "other"
+)
+
+type RuleType string
+
+const (
+	// RuleBodyContains matches if the response body contains the value.
+	RuleBodyContains RuleType = "body_contains"
+	// RuleBodyRegexp matches if the response body matches the regular
+	// expression of the value.
+	RuleBodyRegexp RuleType = "body_regexp"
+	// RuleStatusCode matches if the response has the status code of the value.
+	RuleStatusCode RuleType = "status_code"
+)
+
+// Payload is a value that is injected into parameters. A response that matches
+// one of its rules, while the original response didn't, is a detection.
+type Payload struct {
+	Name     string
+	Category Category
+	Value    string
+	// Append the value to that of the parameter, instead of replacing it.
+	Append bool
+	Rules  []Rule
+}
+
+type Rule struct {
+	Type  RuleType
+	Value string
+}
+
+type compiledPayload struct {
+	Payload
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	re         *regexp.Regexp
+	statusCode int
+}
+
+// sqlErrorRegexp matches error messages of common databases.
+const sqlErrorRegexp = `(?i)(you have an error in your sql syntax|unclosed quotation mark after the character string|` +
+	`quoted string not properly terminated|SQLSTATE\[|pg_query\(\)|unterminated quoted string at or near|` +
+	`syntax error at or near|ORA-[0-9]{5}|sqlite3?\.OperationalError|SQLite3::SQLException|` +
+	`Microsoft OLE DB Provider for SQL Server)`
+
+// DefaultPayloads returns the payloads that scans use by default, which probe
+// for SQL injection (error messages), path traversal and server-side template
+// injection.
+func DefaultPayloads() []Payload {
+	return []Payload{
+		{
+			Name:     "sqli-single-quote",
+			Category: CategorySQLInjection,
+			Value:    "'",
+			Append:   true,
+			Rules:    []Rule{{Type: RuleBodyRegexp, Value: sqlErrorRegexp}},
+		},
+		{
+			Name:     "sqli-double-quote",
+			Category: CategorySQLInjection,
+			Value:    `"`,
+			Append:   true,
+			Rules:    []Rule{{Type: RuleBodyRegexp, Value: sqlErrorRegexp}},
+		},
+		{
+			Name:     "path-traversal-unix",
+			Category: CategoryPathTraversal,
+			Value:    "../../../../../../../../../../etc/passwd",
+			Rules:    []Rule{{Type: RuleBodyRegexp, Value: `root:[^:\r\n]*:0:0:`}},
+		},
+		{
+			Name:     "path-traversal-windows",
+			Category: CategoryPathTraversal,
+			Value:    `..\..\..\..\..\..\..\..\..\..\windows\win.ini`,
+			Rules:    []Rule{{Type: RuleBodyRegexp, Value: `(?i)\[(fonts|extensions)\]`}},
+		},
+		{
+			Name:     "ssti-curly-braces",
+			Category: CategoryTemplateInjection,
+			Value:    "{{7*191}}",
+			Rules:    []Rule{{Type: RuleBodyContains, Value: "1337"}},
+		},
+		{
+			Name:     "ssti-dollar",
+			Category: CategoryTemplateInjection,
+			Value:    "${7*191}",
+			Rules:    []Rule{{Type: RuleBodyContains, Value: "1337"}},
+		},
+		{
+			Name:     "ssti-erb",
+			Category: CategoryTemplateInjection,
+			Value:    "<%= 7*191 %>",
+			Rules:    []Rule{{Type: RuleBodyContains, Value: "1337"}},
+		},
+	}
+}
+
+func compilePayloads(payloads []Payload) ([]compiledPayload, error) {
+	compiled := make([]compiledPayload, len(payloads))
+
+	for i, payload := range payloads {
+		if payload.Name == "" {
+			return nil, fmt.Errorf("%w: name must not be empty", ErrInvalidPayload)
+		}
+
+		switch payload.Category {
+		case CategorySQLInjection, CategoryPathTraversal, CategoryTemplateInjection, CategoryOther:
+		default:
+			return nil, fmt.Errorf("%w: %q has invalid category %q", ErrInvalidPayload, payload.Name, payload.Category)
+		}
+
+		if payload.Value == "" {
+			return nil, fmt.Errorf("%w: %q has no value", ErrInvalidPayload, payload.Name)
+		}
+
+		if len(payload.Rules) == 0 {
+			return nil, fmt.Errorf("%w: %q has no rules", ErrInvalidPayload, payload.Name)
+		}
+
+		compiled[i] = compiledPayload{Payload: payload, rules: make([]compiledRule, len(payload.Rules))}
+
+		for j, rule := range payload.Rules {
+			cr, err := compileRule(rule)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: %v", ErrInvalidPayload, payload.Name, err)
+			}
+
+			compiled[i].rules[j] = cr
+		}
+	}
+
+	return compiled, nil
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	cr := compiledRule{Rule: rule}
+
+	switch rule.Type {
+	case RuleBodyContains:
+		if rule.Value == "" {
+			return compiledRule{}, errors.New("rule value must not be empty")
+		}
+	case RuleBodyRegexp:
+		re, err := regexp.Compile(rule.Value)
+		if err != nil {
+			return compiledRule{}, fmt.Errorf("invalid regular expression: %w", err)
+		}
+
+		cr.re = re
+	case RuleStatusCode:
+		statusCode, err := strconv.Atoi(rule.Value)
+		if err != nil || statusCode < 100 || statusCode > 999 {
+			return compiledRule{}, fmt.Errorf("invalid status code %q", rule.Value)
+		}
+
+		cr.statusCode = statusCode
+	default:
+		return compiledRule{}, fmt.Errorf("invalid rule type %q", rule.Type)
+	}
+
+	return cr, nil
+}
+
+// match returns the evidence of the first rule that matches the response, but
+// not the original response.
+func (payload compiledPayload) match(statusCode int, body []byte, origStatusCode int, origBody []byte) (string, bool) {
+	for _, rule := range payload.rules {
+		if _, ok := rule.match(origStatusCode, origBody); ok {
+			continue
+		}
+
+		if evidence, ok := rule.match(statusCode, body); ok {
+			return evidence, true
+		}
+	}
+
+	return "", false
+}
+
+func (rule compiledRule) match(statusCode int, body []byte) (string, bool) {
+	switch rule.Type {
+	case RuleBodyContains:
+		i := bytes.Index(body, []byte(rule.Value))
+		if i == -1 {
+			return "", false
+		}
+
+		return snippet(body, i, i+len(rule.Value)), true
+	case RuleBodyRegexp:
+		loc := rule.re.FindIndex(body)
+		if loc == nil {
+			return "", false
+		}
+
+		return snippet(body, loc[0], loc[1]), true
+	case RuleStatusCode:
+		if statusCode != rule.statusCode {
+			return "", false
+		}
+
+		return fmt.Sprintf("%v %v", statusCode, http.StatusText(statusCode)), true
+	default:
+		return "", false
+	}
+}
+
+// snippet returns the match of body between start and end, with some
+// surrounding context.
+func snippet(body []byte, start, end int) string {
+	const context = 40
+
+	if end-start > maxEvidenceLen {
+		end = start + maxEvidenceLen
+	}
+
+	from, to := start-context, end+context
+	if from < 0 {
+		from = 0
+	}
+
+	if to > len(body) {
+		to = len(body)
+	}
+
+	return strings.ToValidUTF8(string(body[from:to]), "")
+}
+
+func (c Category) check() finding.Check {
+	switch c {
+	case CategorySQLInjection:
+		return finding.CheckSQLInjection
+	case CategoryPathTraversal:
+		return finding.CheckPathTraversal
+	case CategoryTemplateInjection:
+		return finding.CheckTemplateInjection
+	default:
+		return finding.CheckActiveScanMatch
+	}
+}
+
+func (c Category) severity() finding.Severity {
+	if c == CategoryOther {
+		return finding.SeverityMedium
+	}
+
+	return finding.SeverityHigh
+}
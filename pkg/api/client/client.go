@@ -0,0 +1,394 @@
+// Package client is a Go client for the REST API of a running Hetty instance,
+// see package `rest`.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/api/rest"
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+// DefaultURL is the URL of the admin interface of Hetty with default settings.
+// The admin interface is only served for known hostnames, so use `localhost`
+// rather than `127.0.0.1`.
+const DefaultURL = "http://localhost:8080"
+
+var (
+	ErrNotFound        = errors.New("client: not found")
+	ErrNoActiveProject = errors.New("client: no active project")
+	ErrReadOnly        = errors.New("client: project is opened read-only")
+	ErrStorage         = errors.New("client: storage error")
+	ErrUpstream        = errors.New("client: upstream error")
+)
+
+// Error is an error response of the API. It matches `ErrNotFound`,
+// `ErrNoActiveProject`, `ErrReadOnly`, `ErrStorage` and `ErrUpstream` with
+// `errors.Is`, and its code is returned by `errcode.Of`.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("client: API error (status: %v, code: %v): %v", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrNoActiveProject:
+		return e.Code == "no_active_project"
+	case ErrReadOnly:
+		return e.Code == "read_only"
+	case ErrStorage:
+		return e.Code == string(errcode.Storage)
+	case ErrUpstream:
+		return e.Code == string(errcode.Upstream) || e.Code == "send_request_failed"
+	default:
+		return false
+	}
+}
+
+func (e *Error) ErrorCode() errcode.Code {
+	return errcode.Code(e.Code)
+}
+
+type Config struct {
+	// URL of the admin interface, e.g. `http://localhost:8080`. Defaults to
+	// `DefaultURL`.
+	URL string
+	// Defaults to `http.DefaultClient`.
+	HTTPClient *http.Client
+}
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+}
+
+// RequestLogFilter filters request logs, see `Client.RequestLogs`.
+type RequestLogFilter struct {
+	// Search expression, e.g. `req.method = POST`.
+	Search            string
+	OnlyInScope       bool
+	CollapseRedirects bool
+	CollapsePageLoads bool
+	// Built-in filters, see `reqlog.FilterPresets`.
+	HideStaticAssets   bool
+	HideTracking       bool
+	HideCertRevocation bool
+	// Indexed fields, see `reqlog.FindRequestsFilter`.
+	Host        string
+	StatusCode  int
+	ContentType string
+}
+
+// New returns a new Client.
+func New(cfg Config) (*Client, error) {
+	rawURL := cfg.URL
+	if rawURL == "" {
+		rawURL = DefaultURL
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(rawURL, "/") + rest.PathPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid URL: %w", err)
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{baseURL: u, httpClient: httpClient}, nil
+}
+
+func (c *Client) Projects(ctx context.Context) ([]rest.Project, error) {
+	var projects []rest.Project
+	err := c.do(ctx, http.MethodGet, "/projects", nil, nil, &projects)
+
+	return projects, err
+}
+
+func (c *Client) CreateProject(ctx context.Context, name string) (rest.Project, error) {
+	var project rest.Project
+	err := c.do(ctx, http.MethodPost, "/projects", nil, rest.CreateProjectInput{Name: name}, &project)
+
+	return project, err
+}
+
+func (c *Client) ActiveProject(ctx context.Context) (rest.Project, error) {
+	var project rest.Project
+	err := c.do(ctx, http.MethodGet, "/projects/active", nil, nil, &project)
+
+	return project, err
+}
+
+func (c *Client) OpenProject(ctx context.Context, id ulid.ULID) (rest.Project, error) {
+	var project rest.Project
+	err := c.do(ctx, http.MethodPost, "/projects/"+id.String()+"/open", nil, nil, &project)
+
+	return project, err
+}
+
+// OpenProjectReadOnly opens a project without allowing changes to it; mutating
+// calls return `ErrReadOnly` until the project is closed.
+func (c *Client) OpenProjectReadOnly(ctx context.Context, id ulid.ULID) (rest.Project, error) {
+	var project rest.Project
+	query := url.Values{"readOnly": []string{strconv.FormatBool(true)}}
+	err := c.do(ctx, http.MethodPost, "/projects/"+id.String()+"/open", query, nil, &project)
+
+	return project, err
+}
+
+func (c *Client) CloseProject(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/projects/active", nil, nil, nil)
+}
+
+func (c *Client) DeleteProject(ctx context.Context, id ulid.ULID) error {
+	return c.do(ctx, http.MethodDelete, "/projects/"+id.String(), nil, nil, nil)
+}
+
+// RequestLogs returns the request logs of the active project.
+func (c *Client) RequestLogs(ctx context.Context, filter RequestLogFilter) ([]rest.RequestLog, error) {
+	query := url.Values{}
+
+	if filter.Search != "" {
+		query.Set("q", filter.Search)
+	}
+
+	if filter.OnlyInScope {
+		query.Set("inScope", strconv.FormatBool(true))
+	}
+
+	if filter.CollapseRedirects {
+		query.Set("collapseRedirects", strconv.FormatBool(true))
+	}
+
+	if filter.CollapsePageLoads {
+		query.Set("collapsePageLoads", strconv.FormatBool(true))
+	}
+
+	for key, enabled := range map[string]bool{
+		"hideStaticAssets":   filter.HideStaticAssets,
+		"hideTracking":       filter.HideTracking,
+		"hideCertRevocation": filter.HideCertRevocation,
+	} {
+		if enabled {
+			query.Set(key, strconv.FormatBool(true))
+		}
+	}
+
+	if filter.Host != "" {
+		query.Set("host", filter.Host)
+	}
+
+	if filter.StatusCode != 0 {
+		query.Set("statusCode", strconv.Itoa(filter.StatusCode))
+	}
+
+	if filter.ContentType != "" {
+		query.Set("contentType", filter.ContentType)
+	}
+
+	var reqLogs []rest.RequestLog
+	err := c.do(ctx, http.MethodGet, "/request-logs", query, nil, &reqLogs)
+
+	return reqLogs, err
+}
+
+// CaptureStatus returns whether capture is paused, globally or for the active
+// project.
+func (c *Client) CaptureStatus(ctx context.Context) (rest.CaptureStatus, error) {
+	var status rest.CaptureStatus
+	err := c.do(ctx, http.MethodGet, "/capture", nil, nil, &status)
+
+	return status, err
+}
+
+// SetCaptureStatus pauses or resumes capture. Setting `ProjectPaused` returns
+// `ErrNoActiveProject` if no project is open.
+func (c *Client) SetCaptureStatus(ctx context.Context, input rest.CaptureStatusInput) (rest.CaptureStatus, error) {
+	var status rest.CaptureStatus
+	err := c.do(ctx, http.MethodPut, "/capture", nil, input, &status)
+
+	return status, err
+}
+
+func (c *Client) RequestLog(ctx context.Context, id ulid.ULID) (rest.RequestLog, error) {
+	var reqLog rest.RequestLog
+	err := c.do(ctx, http.MethodGet, "/request-logs/"+id.String(), nil, nil, &reqLog)
+
+	return reqLog, err
+}
+
+func (c *Client) SenderRequests(ctx context.Context) ([]rest.SenderRequest, error) {
+	var reqs []rest.SenderRequest
+	err := c.do(ctx, http.MethodGet, "/sender-requests", nil, nil, &reqs)
+
+	return reqs, err
+}
+
+func (c *Client) SenderRequest(ctx context.Context, id ulid.ULID) (rest.SenderRequest, error) {
+	var req rest.SenderRequest
+	err := c.do(ctx, http.MethodGet, "/sender-requests/"+id.String(), nil, nil, &req)
+
+	return req, err
+}
+
+// CreateSenderRequest creates a sender request, without sending it.
+func (c *Client) CreateSenderRequest(ctx context.Context, input rest.SenderRequestInput) (rest.SenderRequest, error) {
+	var req rest.SenderRequest
+	err := c.do(ctx, http.MethodPost, "/sender-requests", nil, input, &req)
+
+	return req, err
+}
+
+// CloneRequestLog creates a sender request from a request log.
+func (c *Client) CloneRequestLog(ctx context.Context, reqLogID ulid.ULID) (rest.SenderRequest, error) {
+	return c.CreateSenderRequest(ctx, rest.SenderRequestInput{RequestLogID: &reqLogID})
+}
+
+// SendRequest sends a sender request, and returns it with its response.
+func (c *Client) SendRequest(ctx context.Context, id ulid.ULID) (rest.SenderRequest, error) {
+	var req rest.SenderRequest
+	err := c.do(ctx, http.MethodPost, "/sender-requests/"+id.String()+"/send", nil, nil, &req)
+
+	return req, err
+}
+
+// DatabaseStats returns statistics of the database. It returns `ErrNotFound`
+// for databases that don't need maintenance, e.g. in-memory databases.
+func (c *Client) DatabaseStats(ctx context.Context) (rest.DatabaseStats, error) {
+	var stats rest.DatabaseStats
+	err := c.do(ctx, http.MethodGet, "/database/stats", nil, nil, &stats)
+
+	return stats, err
+}
+
+// CompactDatabase starts a compaction of the database, without waiting for it
+// to finish; see `DatabaseStats` for its result.
+func (c *Client) CompactDatabase(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/database/compact", nil, nil, nil)
+}
+
+// Backup writes a backup of the database to w, see `hetty restore`.
+func (c *Client) Backup(ctx context.Context, w io.Writer) error {
+	req, err := c.newRequest(ctx, http.MethodGet, "/database/backup", nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("client: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errorFromResponse(resp)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("client: failed to read backup: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) CompactionSchedule(ctx context.Context) (rest.CompactionSchedule, error) {
+	var schedule rest.CompactionSchedule
+	err := c.do(ctx, http.MethodGet, "/database/compaction-schedule", nil, nil, &schedule)
+
+	return schedule, err
+}
+
+func (c *Client) SetCompactionSchedule(ctx context.Context, schedule rest.CompactionSchedule) (rest.CompactionSchedule, error) {
+	err := c.do(ctx, http.MethodPut, "/database/compaction-schedule", nil, schedule, &schedule)
+
+	return schedule, err
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	var reqBody io.Reader
+
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("client: failed to encode request body: %w", err)
+		}
+
+		reqBody = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
+
+func errorFromResponse(resp *http.Response) error {
+	var errResp rest.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+		errResp.Error.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Code:       errResp.Error.Code,
+		Message:    errResp.Error.Message,
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, v interface{}) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("client: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errorFromResponse(resp)
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("client: failed to decode response body: %w", err)
+	}
+
+	return nil
+}
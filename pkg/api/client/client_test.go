@@ -0,0 +1,129 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/api/client"
+	"github.com/dstotijn/hetty/pkg/api/rest"
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
+	"github.com/dstotijn/hetty/pkg/scope"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func newTestClient(t *testing.T) *client.Client {
+	t.Helper()
+
+	database := memory.OpenDatabase()
+	scope := &scope.Scope{}
+
+	reqLogSvc := reqlog.NewService(reqlog.Config{
+		Scope:      scope,
+		Repository: database,
+	})
+	senderSvc := sender.NewService(sender.Config{
+		Scope:         scope,
+		Repository:    database,
+		ReqLogService: reqLogSvc,
+		HTTPClient:    &http.Client{},
+	})
+
+	projSvc, err := proj.NewService(proj.Config{
+		Repository:    database,
+		ReqLogService: reqLogSvc,
+		SenderService: senderSvc,
+		Scope:         scope,
+		Rewriter:      &rewrite.Rewriter{},
+	})
+	if err != nil {
+		t.Fatalf("failed to create project service: %v", err)
+	}
+
+	ts := httptest.NewServer(rest.NewHandler(rest.Config{
+		ProjectService:    projSvc,
+		RequestLogService: reqLogSvc,
+		SenderService:     senderSvc,
+	}))
+	t.Cleanup(ts.Close)
+
+	c, err := client.New(client.Config{URL: ts.URL + "/"})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	return c
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Foo")))
+	}))
+	t.Cleanup(upstream.Close)
+
+	if _, err := c.RequestLogs(ctx, client.RequestLogFilter{}); !errors.Is(err, client.ErrNoActiveProject) {
+		t.Fatalf("expected `client.ErrNoActiveProject`, got: %v", err)
+	}
+
+	project, err := c.CreateProject(ctx, "foobar")
+	if err != nil {
+		t.Fatalf("unexpected error creating project: %v", err)
+	}
+
+	if _, err := c.OpenProject(ctx, project.ID); err != nil {
+		t.Fatalf("unexpected error opening project: %v", err)
+	}
+
+	active, err := c.ActiveProject(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error getting active project: %v", err)
+	}
+
+	if active.ID != project.ID || !active.IsActive {
+		t.Fatalf("unexpected active project: %+v", active)
+	}
+
+	req, err := c.CreateSenderRequest(ctx, rest.SenderRequestInput{
+		URL:     upstream.URL,
+		Proto:   sender.HTTPProto1,
+		Headers: http.Header{"X-Foo": []string{"bar"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating sender request: %v", err)
+	}
+
+	req, err = c.SendRequest(ctx, req.ID)
+	if err != nil {
+		t.Fatalf("unexpected error sending request: %v", err)
+	}
+
+	if req.Response == nil || req.Response.Body != "bar" {
+		t.Fatalf("unexpected sender response: %+v", req.Response)
+	}
+
+	if _, err := c.RequestLog(ctx, req.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected `client.ErrNotFound`, got: %v", err)
+	}
+
+	if _, err := c.OpenProjectReadOnly(ctx, project.ID); err != nil {
+		t.Fatalf("unexpected error opening project read-only: %v", err)
+	}
+
+	if _, err := c.SendRequest(ctx, req.ID); !errors.Is(err, client.ErrReadOnly) {
+		t.Fatalf("expected `client.ErrReadOnly`, got: %v", err)
+	}
+
+	if err := c.CloseProject(ctx); err != nil {
+		t.Fatalf("unexpected error closing project: %v", err)
+	}
+}
@@ -12,22 +12,388 @@ import (
 	"github.com/oklog/ulid"
 )
 
+// Injection of payloads into the parameters of selected request logs.
+type ActiveScan struct {
+	ID        ulid.ULID        `json:"id"`
+	Status    ActiveScanStatus `json:"status"`
+	Total     int              `json:"total"`
+	Completed int              `json:"completed"`
+	// Number of requests that couldn't be sent.
+	Errors     int                   `json:"errors"`
+	Detections []ActiveScanDetection `json:"detections"`
+	Timestamp  time.Time             `json:"timestamp"`
+}
+
+type ActiveScanDetection struct {
+	RequestLogID ulid.ULID            `json:"requestLogID"`
+	Method       HTTPMethod           `json:"method"`
+	URL          *url.URL             `json:"url"`
+	Parameter    *ActiveScanParameter `json:"parameter"`
+	PayloadName  string               `json:"payloadName"`
+	Category     ActiveScanCategory   `json:"category"`
+	// Value the parameter was set to.
+	Payload string `json:"payload"`
+	// Request log of the request with the payload, if it was logged.
+	ProbeRequestLogID *ulid.ULID `json:"probeRequestLogID"`
+	StatusCode        int        `json:"statusCode"`
+	// Part of the response that matched, e.g. a database error message.
+	Evidence string `json:"evidence"`
+}
+
+type ActiveScanParameter struct {
+	Location ActiveScanLocation `json:"location"`
+	// Name of the query or form parameter, or dot separated path of the JSON
+	// field (e.g. `user.name`).
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Value that an active scan injects into parameters. A response that matches one
+// of its rules, while the original response didn't, is a detection.
+type ActiveScanPayload struct {
+	Name     string             `json:"name"`
+	Category ActiveScanCategory `json:"category"`
+	Value    string             `json:"value"`
+	// Append the value to that of the parameter, instead of replacing it.
+	Append bool             `json:"append"`
+	Rules  []ActiveScanRule `json:"rules"`
+}
+
+type ActiveScanPayloadInput struct {
+	Name     string                `json:"name"`
+	Category ActiveScanCategory    `json:"category"`
+	Value    string                `json:"value"`
+	Append   *bool                 `json:"append"`
+	Rules    []ActiveScanRuleInput `json:"rules"`
+}
+
+type ActiveScanRule struct {
+	Type ActiveScanRuleType `json:"type"`
+	// Substring or regular expression of the response body, or status code.
+	Value string `json:"value"`
+}
+
+type ActiveScanRuleInput struct {
+	Type  ActiveScanRuleType `json:"type"`
+	Value string             `json:"value"`
+}
+
+// Settings of the authorization check, which replays successful in-scope proxied
+// requests with the credentials of a second user (e.g. a low privileged one), and
+// stores an `AUTHORIZATION_BYPASS` finding if the response is identical.
+type AuthzCheckSettings struct {
+	Enabled bool `json:"enabled"`
+	// Headers that replace those of proxied requests, e.g. the `Cookie` or
+	// `Authorization` header of the second user. Requests that have none of them
+	// aren't replayed.
+	Headers []HTTPHeader `json:"headers"`
+}
+
+type AuthzCheckSettingsInput struct {
+	Enabled bool              `json:"enabled"`
+	Headers []HTTPHeaderInput `json:"headers"`
+}
+
+type AwsSigV4Signing struct {
+	Region          string  `json:"region"`
+	Service         string  `json:"service"`
+	AccessKeyID     string  `json:"accessKeyID"`
+	SecretAccessKey string  `json:"secretAccessKey"`
+	SessionToken    *string `json:"sessionToken"`
+}
+
+type AwsSigV4SigningInput struct {
+	Region          string  `json:"region"`
+	Service         string  `json:"service"`
+	AccessKeyID     string  `json:"accessKeyID"`
+	SecretAccessKey string  `json:"secretAccessKey"`
+	SessionToken    *string `json:"sessionToken"`
+}
+
+type BulkHTTPRequestLogsResult struct {
+	// Number of request logs that were updated or deleted.
+	Count int `json:"count"`
+}
+
+type CancelActiveScanResult struct {
+	Success bool `json:"success"`
+}
+
+type CancelContentDiscoveryResult struct {
+	Success bool `json:"success"`
+}
+
+type CancelCrawlResult struct {
+	Success bool `json:"success"`
+}
+
+type CancelIdorTestResult struct {
+	Success bool `json:"success"`
+}
+
+type CancelNucleiRunResult struct {
+	Success bool `json:"success"`
+}
+
+type CancelReplayResult struct {
+	Success bool `json:"success"`
+}
+
+type CancelSmugglingTestResult struct {
+	Success bool `json:"success"`
+}
+
+type CancelUnauthCheckResult struct {
+	Success bool `json:"success"`
+}
+
+type CancelledJob struct {
+	Module ActiveModule `json:"module"`
+	// ID of the crawl, scan, etc., which is also the ID of its job.
+	ID ulid.ULID `json:"id"`
+}
+
+// Whether proxied requests are logged. While capture is paused, traffic is passed
+// through without storing request logs, connection logs or findings.
+type CaptureStatus struct {
+	// Paused globally, for all projects and routed clients.
+	Paused bool `json:"paused"`
+	// Paused for the active project; stored with the project. Projects that clients
+	// are routed to can be paused separately, see `setProjectCapturePaused`.
+	ProjectPaused bool `json:"projectPaused"`
+}
+
+type ClearConnectionLogsResult struct {
+	Success bool `json:"success"`
+}
+
 type ClearHTTPRequestLogResult struct {
 	Success bool `json:"success"`
 }
 
+// Logs the requests of matching clients to a project other than the active one,
+// so that multiple testers can share the proxy. A route matches if all of its
+// set criteria match; the first matching route is used.
+type ClientRoute struct {
+	// Client IP address or CIDR range, e.g. `10.0.0.0/24`.
+	ClientIP *string `json:"clientIP"`
+	// Username of the client's proxy credentials (`Proxy-Authorization` header).
+	Username  *string   `json:"username"`
+	ProjectID ulid.ULID `json:"projectID"`
+}
+
+type ClientRouteInput struct {
+	ClientIP  *string   `json:"clientIP"`
+	Username  *string   `json:"username"`
+	ProjectID ulid.ULID `json:"projectID"`
+}
+
 type CloseProjectResult struct {
 	Success bool `json:"success"`
 }
 
+type CompareSenderRequestInput struct {
+	RequestID ulid.ULID `json:"requestID"`
+	// Exactly two base URLs. Their scheme and host replace those of the request
+	// URL, and their path (if any) is prepended to its path.
+	BaseURLs []*url.URL `json:"baseURLs"`
+	// Headers that aren't compared, in addition to `Age`, `Content-Length`, `Date`
+	// and `Expires`.
+	IgnoreHeaders []string `json:"ignoreHeaders"`
+	// Volatile fields of bodies, see `SenderScheduleInput`.
+	IgnoreJSONPaths []string `json:"ignoreJSONPaths"`
+	IgnorePatterns  []string `json:"ignorePatterns"`
+}
+
+type ConnectionCapture struct {
+	// Hex encoded bytes received from the client.
+	Up string `json:"up"`
+	// Hex encoded bytes sent to the client.
+	Down string `json:"down"`
+	// True if the bytes in either direction exceeded the capture size limit.
+	Truncated bool `json:"truncated"`
+}
+
+// CONNECT tunnel handled by the proxy.
+type ConnectionLog struct {
+	ID         ulid.ULID `json:"id"`
+	ClientAddr string    `json:"clientAddr"`
+	// CONNECT target, in the form "host:port".
+	Host string `json:"host"`
+	// Server name (SNI) sent by the client, for MITM tunnels.
+	ServerName *string        `json:"serverName"`
+	Mode       ConnectionMode `json:"mode"`
+	// Number of bytes received from the client.
+	BytesUp int `json:"bytesUp"`
+	// Number of bytes sent to the client.
+	BytesDown int       `json:"bytesDown"`
+	StartedAt time.Time `json:"startedAt"`
+	// Duration of the tunnel, in milliseconds.
+	Duration int `json:"duration"`
+	// Raw bytes of tunnels that weren't handled as HTTP.
+	Capture *ConnectionCapture `json:"capture"`
+	// Error that ended the tunnel, if any.
+	Error *string `json:"error"`
+}
+
+type ContentDiscoveryResult struct {
+	URL        *url.URL `json:"url"`
+	StatusCode int      `json:"statusCode"`
+	Size       int      `json:"size"`
+	// Request log of the path, which is tagged `discovered`, so that results are
+	// stored with the project.
+	RequestLogID *ulid.ULID `json:"requestLogID"`
+}
+
+type ContentDiscoveryScan struct {
+	ID        ulid.ULID                `json:"id"`
+	BaseURL   *url.URL                 `json:"baseURL"`
+	Status    ContentDiscoveryStatus   `json:"status"`
+	Total     int                      `json:"total"`
+	Completed int                      `json:"completed"`
+	Results   []ContentDiscoveryResult `json:"results"`
+	Timestamp time.Time                `json:"timestamp"`
+}
+
+type ContinueInterceptedRequestResult struct {
+	Success bool `json:"success"`
+}
+
+// Traffic related to a sender request: the request itself, requests it triggered
+// that were proxied (e.g. followed redirects) and out-of-band interactions with
+// payloads created for it.
+type CorrelatedTraffic struct {
+	CorrelationID    ulid.ULID         `json:"correlationID"`
+	SenderRequest    *SenderRequest    `json:"senderRequest"`
+	HTTPRequestLogs  []HTTPRequestLog  `json:"httpRequestLogs"`
+	OastInteractions []OASTInteraction `json:"oastInteractions"`
+}
+
+type Crawl struct {
+	ID        ulid.ULID     `json:"id"`
+	StartURL  *url.URL      `json:"startURL"`
+	Status    CrawlStatus   `json:"status"`
+	Results   []CrawlResult `json:"results"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+type CrawlResult struct {
+	Method       HTTPMethod `json:"method"`
+	URL          *url.URL   `json:"url"`
+	Depth        int        `json:"depth"`
+	StatusCode   int        `json:"statusCode"`
+	RequestLogID *ulid.ULID `json:"requestLogID"`
+}
+
+type DeleteHTTPBodyViewResult struct {
+	Success bool `json:"success"`
+}
+
 type DeleteProjectResult struct {
 	Success bool `json:"success"`
 }
 
+type DeleteSenderCollectionResult struct {
+	Success bool `json:"success"`
+}
+
 type DeleteSenderRequestsResult struct {
 	Success bool `json:"success"`
 }
 
+type DropInterceptedRequestResult struct {
+	Success bool `json:"success"`
+}
+
+type ExportHTTPRequestLogEvidenceResult struct {
+	// Evidence document (JSON), with a canonical serialization, SHA-256 hashes and
+	// the capture timestamp of each request and response. Verify it with
+	// `hetty evidence verify`.
+	Evidence string `json:"evidence"`
+	Count    int    `json:"count"`
+}
+
+type ExportHTTPRequestLogsResult struct {
+	// HAR (HTTP Archive) 1.2 document.
+	Har   string `json:"har"`
+	Count int    `json:"count"`
+}
+
+type ExportSenderRequestsResult struct {
+	// Postman collection v2.1 document.
+	Postman string `json:"postman"`
+	Count   int    `json:"count"`
+}
+
+// Issue found by a passive check on a logged response.
+type Finding struct {
+	ID           ulid.ULID       `json:"id"`
+	RequestLogID ulid.ULID       `json:"requestLogID"`
+	Check        FindingCheck    `json:"check"`
+	Severity     FindingSeverity `json:"severity"`
+	Description  string          `json:"description"`
+	// Offending header. For missing headers, only the key is set.
+	HeaderKey   *string   `json:"headerKey"`
+	HeaderValue *string   `json:"headerValue"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+type HmacSigning struct {
+	// One of `md5`, `sha1`, `sha256` or `sha512`.
+	Algorithm string `json:"algorithm"`
+	Key       string `json:"key"`
+	// Template of the signed message, using the built-in variables of sender
+	// scripts, e.g. `{{request.method}}\n{{request.path}}\n{{timestamp}}`.
+	Message string `json:"message"`
+	// One of `hex` or `base64`.
+	Encoding string `json:"encoding"`
+	Header   string `json:"header"`
+	// Template of the header value; `{{signature}}` is replaced by the signature.
+	HeaderValue string `json:"headerValue"`
+	// Header that is set to the Unix time used in the message, if any.
+	TimestampHeader *string `json:"timestampHeader"`
+}
+
+type HmacSigningInput struct {
+	Algorithm       *string `json:"algorithm"`
+	Key             string  `json:"key"`
+	Message         string  `json:"message"`
+	Encoding        *string `json:"encoding"`
+	Header          string  `json:"header"`
+	HeaderValue     *string `json:"headerValue"`
+	TimestampHeader *string `json:"timestampHeader"`
+}
+
+// Named transformation of (part of) the request or response body of a request
+// log, e.g. a decoded JWT. Views are stored with the request log.
+type HTTPBodyView struct {
+	Name   string         `json:"name"`
+	Source HTTPBodySource `json:"source"`
+	// Byte range of the body that is transformed. An end of 0 means the end of the
+	// body.
+	Start      int             `json:"start"`
+	End        int             `json:"end"`
+	Transforms []TransformType `json:"transforms"`
+	Output     string          `json:"output"`
+	// Output encoded as base64, for when the output contains binary data.
+	OutputBase64 string    `json:"outputBase64"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type HTTPBodyViewInput struct {
+	Name       string          `json:"name"`
+	Source     HTTPBodySource  `json:"source"`
+	Start      *int            `json:"start"`
+	End        *int            `json:"end"`
+	Transforms []TransformType `json:"transforms"`
+}
+
+// Client classification, based on the `User-Agent` header.
+type HTTPClientDevice struct {
+	Type HTTPClientDeviceType `json:"type"`
+	Os   *string              `json:"os"`
+}
+
 type HTTPHeader struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
@@ -38,25 +404,196 @@ type HTTPHeaderInput struct {
 	Value string `json:"value"`
 }
 
+type HTTPRawExchange struct {
+	// Hex encoded request, as received from the client. Only captured for requests
+	// in CONNECT tunnels.
+	ClientRequest *string `json:"clientRequest"`
+	// Hex encoded bytes sent to the upstream server.
+	UpstreamRequest string `json:"upstreamRequest"`
+	// Hex encoded bytes received from the upstream server.
+	UpstreamResponse string `json:"upstreamResponse"`
+	// True if the bytes in either direction exceeded the capture size limit.
+	Truncated bool `json:"truncated"`
+}
+
 type HTTPRequestLog struct {
-	ID        ulid.ULID        `json:"id"`
-	URL       string           `json:"url"`
-	Method    HTTPMethod       `json:"method"`
-	Proto     string           `json:"proto"`
-	Headers   []HTTPHeader     `json:"headers"`
-	Body      *string          `json:"body"`
+	ID  ulid.ULID `json:"id"`
+	URL string    `json:"url"`
+	// URL of the request before it was rewritten by a remote mapping, if any. `url`
+	// is the URL that the request was sent to.
+	OriginalURL *string      `json:"originalURL"`
+	Method      HTTPMethod   `json:"method"`
+	Proto       string       `json:"proto"`
+	Headers     []HTTPHeader `json:"headers"`
+	Body        *string      `json:"body"`
+	// Size of the body in bytes. Large bodies can be fetched in parts, with range
+	// requests to `/api/v1/request-logs/{id}/request-body`, instead of `body`.
+	BodySize  int              `json:"bodySize"`
 	Timestamp time.Time        `json:"timestamp"`
 	Response  *HTTPResponseLog `json:"response"`
+	// ID of the request log whose redirect response led to this request.
+	RedirectFromID *ulid.ULID `json:"redirectFromID"`
+	// ID of the sender request that triggered this request, if any.
+	CorrelationID *ulid.ULID `json:"correlationID"`
+	// ID of the request log of the navigation that triggered this request, e.g. for
+	// images of a page. Equal to `id` for the navigation itself.
+	PageLoadID *ulid.ULID `json:"pageLoadID"`
+	// Error of the upstream request (e.g. a DNS error, timeout or TLS failure),
+	// in which case there's no response.
+	Error *string `json:"error"`
+	// Raw bytes of the request and response, when raw capture is enabled.
+	Raw *HTTPRawExchange `json:"raw"`
+	// Number of times the upstream request was retried, after the connection was
+	// reset.
+	Retries int `json:"retries"`
+	// IP address of the upstream server (or HTTP proxy) that the request was sent
+	// to, if a connection was established.
+	RemoteIP *string `json:"remoteIP"`
+	// Address (`ip:port`) of the client that sent the request to the proxy. Empty
+	// for requests sent by Hetty itself.
+	ClientAddr *string           `json:"clientAddr"`
+	Device     *HTTPClientDevice `json:"device"`
+	Tags       []string          `json:"tags"`
+	BodyViews  []HTTPBodyView    `json:"bodyViews"`
+	// Values of the custom columns of the active project, in order. Only computed
+	// for `httpRequestLogs` and `httpRequestLog`, empty otherwise.
+	Columns []HTTPRequestLogColumnValue `json:"columns"`
+}
+
+// Custom column of the request log table, whose values are computed from a search
+// expression: the value of a key (e.g. `req.header.Authorization` or
+// `res.json.user.id`), the first submatch of a regular expression (e.g.
+// `req.url =~ "/users/([0-9]+)"`), or otherwise `true` or `false`.
+type HTTPRequestLogColumn struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+type HTTPRequestLogColumnInput struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+type HTTPRequestLogColumnValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 type HTTPRequestLogFilter struct {
-	OnlyInScope      bool    `json:"onlyInScope"`
-	SearchExpression *string `json:"searchExpression"`
+	OnlyInScope       bool                         `json:"onlyInScope"`
+	SearchExpression  *string                      `json:"searchExpression"`
+	CollapseRedirects bool                         `json:"collapseRedirects"`
+	CollapsePageLoads bool                         `json:"collapsePageLoads"`
+	Presets           []HTTPRequestLogFilterPreset `json:"presets"`
 }
 
 type HTTPRequestLogFilterInput struct {
 	OnlyInScope      *bool   `json:"onlyInScope"`
 	SearchExpression *string `json:"searchExpression"`
+	// Only return the first request of each redirect chain.
+	CollapseRedirects *bool `json:"collapseRedirects"`
+	// Only return the first request of each page load.
+	CollapsePageLoads *bool `json:"collapsePageLoads"`
+	// Built-in filters that hide noise, combined with `searchExpression`.
+	Presets []HTTPRequestLogFilterPreset `json:"presets"`
+}
+
+// Triage actions for a request log, applied with a single mutation, e.g. for
+// hotkeys. Settings of the active project are updated at once.
+type HTTPRequestLogQuickActionsInput struct {
+	// Add a scope rule that matches URLs of the host of the request log, unless
+	// there already is one.
+	AddHostToScope *bool `json:"addHostToScope"`
+	// Hide request logs of the host, by adding a condition to the search expression
+	// of the request log filter. Can't be combined with `addHostToScope`.
+	ExcludeHost *bool `json:"excludeHost"`
+	// Create a sender request from the request log.
+	SendToSender *bool `json:"sendToSender"`
+}
+
+type HTTPRequestLogQuickActionsResult struct {
+	Scope  []ScopeRule           `json:"scope"`
+	Filter *HTTPRequestLogFilter `json:"filter"`
+	// Set if `sendToSender` was set.
+	SenderRequest *SenderRequest `json:"senderRequest"`
+}
+
+// Determines which proxied requests are logged, e.g. for load tests where logging
+// every request isn't needed. When both are set, a request is logged if it passes
+// both.
+type HTTPRequestLogSampling struct {
+	Rate        int `json:"rate"`
+	PerEndpoint int `json:"perEndpoint"`
+}
+
+type HTTPRequestLogSamplingInput struct {
+	// Log 1 in N requests. Zero or one logs every request.
+	Rate *int `json:"rate"`
+	// Log only the first K requests per endpoint (method, host and path). Zero
+	// means no limit.
+	PerEndpoint *int `json:"perEndpoint"`
+}
+
+type HTTPRequestLogSearchKeyValues struct {
+	Key string `json:"key"`
+	// Most common values first.
+	Values []HTTPRequestLogSearchValue `json:"values"`
+}
+
+// Search keys, operators and observed values of request logs, for autocompletion
+// of search expressions.
+type HTTPRequestLogSearchMetadata struct {
+	Keys []string `json:"keys"`
+	// Prefixes of keys that are followed by a header name or JSON path, e.g.
+	// `req.header.`.
+	KeyPrefixes []string `json:"keyPrefixes"`
+	Operators   []string `json:"operators"`
+	// Observed values of the request logs of the active project, by key.
+	Values []HTTPRequestLogSearchKeyValues `json:"values"`
+}
+
+type HTTPRequestLogSearchValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Request logs of the active project, for bulk operations. Either `ids` or
+// `filter` must be set.
+type HTTPRequestLogSelectionInput struct {
+	Ids    []ulid.ULID                `json:"ids"`
+	Filter *HTTPRequestLogFilterInput `json:"filter"`
+}
+
+// Metrics of the queue of response logs that are stored in the background.
+type HTTPRequestLogStoreStats struct {
+	Queued    int `json:"queued"`
+	QueueSize int `json:"queueSize"`
+	Workers   int `json:"workers"`
+	Stored    int `json:"stored"`
+	Failed    int `json:"failed"`
+	// Number of responses that were delayed because the queue was full.
+	Blocked int `json:"blocked"`
+}
+
+// Rules that determine which response bodies are stored, for the active project.
+// For other responses, only the status, headers and metadata are stored.
+type HTTPResponseBodyRules struct {
+	OmitOutOfScope   bool     `json:"omitOutOfScope"`
+	OmitStatic       bool     `json:"omitStatic"`
+	OmitContentTypes []string `json:"omitContentTypes"`
+	MaxSize          int      `json:"maxSize"`
+}
+
+type HTTPResponseBodyRulesInput struct {
+	// Omit bodies of responses to requests that don't match the scope rules.
+	OmitOutOfScope bool `json:"omitOutOfScope"`
+	// Omit bodies of images, fonts, video and audio.
+	OmitStatic bool `json:"omitStatic"`
+	// Omit bodies of responses with these media types. A type ending with `/*`
+	// matches all subtypes, e.g. `image/*`.
+	OmitContentTypes []string `json:"omitContentTypes"`
+	// Omit bodies larger than this number of bytes. Zero means no limit.
+	MaxSize *int `json:"maxSize"`
 }
 
 type HTTPResponseLog struct {
@@ -66,13 +603,411 @@ type HTTPResponseLog struct {
 	StatusCode   int          `json:"statusCode"`
 	StatusReason string       `json:"statusReason"`
 	Body         *string      `json:"body"`
-	Headers      []HTTPHeader `json:"headers"`
+	// Size of the body in bytes. Large bodies of request logs and sender requests
+	// can be fetched in parts, with range requests to
+	// `/api/v1/request-logs/{id}/response-body` and
+	// `/api/v1/sender-requests/{id}/response-body`, instead of `body`.
+	BodySize int `json:"bodySize"`
+	// True when the body wasn't stored, because of the body storage rules.
+	BodyOmitted bool         `json:"bodyOmitted"`
+	Headers     []HTTPHeader `json:"headers"`
+	// TLS connection metadata, for responses received over TLS.
+	TLS *TLSInfo `json:"tls"`
+}
+
+// A match of a search expression in the request or response body of a request
+// log. Offsets are in bytes.
+type HTTPSearchHit struct {
+	// Body of the match: `req.body` or `res.body`.
+	Key   string `json:"key"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	// Part of the body around the match.
+	Snippet string `json:"snippet"`
+	// Offset of the match in `snippet`.
+	SnippetOffset int `json:"snippetOffset"`
+}
+
+// Value of a request that looks like the ID of an object.
+type IdorIdentifier struct {
+	Location IdorLocation `json:"location"`
+	// Index of the path segment (starting at 1), name of the query or form
+	// parameter, or dot separated path of the JSON field (e.g. `items.0.id`).
+	Name  string             `json:"name"`
+	Value string             `json:"value"`
+	Kind  IdorIdentifierKind `json:"kind"`
+}
+
+type IdorIdentifierInput struct {
+	Location IdorLocation `json:"location"`
+	Name     string       `json:"name"`
+}
+
+// Replay of a request log with its identifiers replaced, to find insecure direct
+// object references.
+type IdorTest struct {
+	ID           ulid.ULID        `json:"id"`
+	RequestLogID ulid.ULID        `json:"requestLogID"`
+	URL          *url.URL         `json:"url"`
+	Status       IdorTestStatus   `json:"status"`
+	Total        int              `json:"total"`
+	Completed    int              `json:"completed"`
+	Results      []IdorTestResult `json:"results"`
+	Timestamp    time.Time        `json:"timestamp"`
+}
+
+type IdorTestResult struct {
+	Identifier *IdorIdentifier `json:"identifier"`
+	// Value that the identifier was replaced with.
+	Value string `json:"value"`
+	// Request log of the mutated request, if it was logged.
+	RequestLogID *ulid.ULID `json:"requestLogID"`
+	StatusCode   *int       `json:"statusCode"`
+	BodySize     int        `json:"bodySize"`
+	// True if the response is successful, and its body differs from that of the
+	// original response.
+	Flagged bool `json:"flagged"`
+	// Error of sending the request, if any.
+	Error *string `json:"error"`
+}
+
+// Holds proxied requests that match a search expression of request logs, e.g.
+// `req.method = POST AND req.url =~ "/admin"`, until they're continued or
+// dropped. Requests are continued if they're held for 5 minutes. Response fields
+// (`res.`) can't be matched.
+type InterceptBreakpoint struct {
+	Expression string `json:"expression"`
+}
+
+type InterceptBreakpointInput struct {
+	Expression string `json:"expression"`
+}
+
+type InterceptedRequest struct {
+	// ID of the request log of the request, if it's logged.
+	ID      ulid.ULID    `json:"id"`
+	Method  HTTPMethod   `json:"method"`
+	URL     *url.URL     `json:"url"`
+	Proto   string       `json:"proto"`
+	Headers []HTTPHeader `json:"headers"`
+	Body    *string      `json:"body"`
+	// Expression of the breakpoint that the request matched.
+	Breakpoint    string    `json:"breakpoint"`
+	InterceptedAt time.Time `json:"interceptedAt"`
+}
+
+type Jwt struct {
+	Raw      string      `json:"raw"`
+	Location JWTLocation `json:"location"`
+	// Header as a JSON encoded object.
+	Header string `json:"header"`
+	// Claims as a JSON encoded object.
+	Claims     string        `json:"claims"`
+	Algorithm  string        `json:"algorithm"`
+	Weaknesses []JWTWeakness `json:"weaknesses"`
+}
+
+type JWTWeakness struct {
+	Type        JWTWeaknessType `json:"type"`
+	Description string          `json:"description"`
+	// Guessed secret, set for weaknesses of type `WEAK_SECRET`.
+	Secret *string `json:"secret"`
+}
+
+// Background job of a module, e.g. a crawl or an active scan. Jobs are queued
+// while the maximum number of concurrent jobs is running.
+type Job struct {
+	ID ulid.ULID `json:"id"`
+	// Kind of work, e.g. "crawl" or "activeScan".
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	Status      JobStatus `json:"status"`
+	Completed   int       `json:"completed"`
+	// Total units of work, or 0 if it isn't known.
+	Total int `json:"total"`
+	// Completed work, as a percentage.
+	Progress   float64    `json:"progress"`
+	Error      *string    `json:"error"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt"`
+}
+
+type LaunchBrowserResult struct {
+	Success bool `json:"success"`
+}
+
+// Response that matched the matchers of a template. Once a template matches a
+// target, its remaining requests for the target are skipped.
+type NucleiMatch struct {
+	TemplateID   string          `json:"templateID"`
+	TemplateName string          `json:"templateName"`
+	Severity     FindingSeverity `json:"severity"`
+	URL          *url.URL        `json:"url"`
+	// Request log of the matching request, if it was logged.
+	RequestLogID *ulid.ULID `json:"requestLogID"`
+	StatusCode   int        `json:"statusCode"`
+}
+
+// Run of nuclei templates against in-scope hosts of the site map.
+type NucleiRun struct {
+	ID     ulid.ULID       `json:"id"`
+	Status NucleiRunStatus `json:"status"`
+	// IDs of the templates.
+	Templates []string   `json:"templates"`
+	Targets   []*url.URL `json:"targets"`
+	Total     int        `json:"total"`
+	Completed int        `json:"completed"`
+	// Number of requests that couldn't be sent.
+	Errors    int           `json:"errors"`
+	Matches   []NucleiMatch `json:"matches"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+type OASTInteraction struct {
+	ID            ulid.ULID    `json:"id"`
+	PayloadID     ulid.ULID    `json:"payloadID"`
+	RequestLogID  *ulid.ULID   `json:"requestLogID"`
+	CorrelationID *ulid.ULID   `json:"correlationID"`
+	Protocol      OASTProtocol `json:"protocol"`
+	RemoteAddr    string       `json:"remoteAddr"`
+	Timestamp     time.Time    `json:"timestamp"`
+	Raw           string       `json:"raw"`
+}
+
+type OASTPayload struct {
+	ID            ulid.ULID  `json:"id"`
+	Hostname      string     `json:"hostname"`
+	URL           string     `json:"url"`
+	RequestLogID  *ulid.ULID `json:"requestLogID"`
+	CorrelationID *ulid.ULID `json:"correlationID"`
+	Timestamp     time.Time  `json:"timestamp"`
+}
+
+type OAuth2Token struct {
+	Source      string     `json:"source"`
+	Variable    string     `json:"variable"`
+	AccessToken *string    `json:"accessToken"`
+	TokenType   *string    `json:"tokenType"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	FetchedAt   *time.Time `json:"fetchedAt"`
+	// Error of the last fetch, if it failed. The previous access token is kept.
+	Error *string `json:"error"`
+}
+
+// Fetches OAuth 2.0 access tokens for the active project. The last token is the
+// value of variable `variable` in sender requests, e.g. `Bearer {{accessToken}}`.
+type OAuth2TokenSource struct {
+	Name         string          `json:"name"`
+	Variable     string          `json:"variable"`
+	TokenURL     *url.URL        `json:"tokenURL"`
+	GrantType    OAuth2GrantType `json:"grantType"`
+	ClientID     string          `json:"clientID"`
+	ClientSecret string          `json:"clientSecret"`
+	// Send client credentials as form parameters, instead of with HTTP Basic
+	// authentication.
+	ClientCredentialsInBody bool     `json:"clientCredentialsInBody"`
+	Username                string   `json:"username"`
+	Password                string   `json:"password"`
+	Scopes                  []string `json:"scopes"`
+	// Seconds between fetches. If not set, tokens are fetched again shortly before
+	// they expire.
+	RefreshInterval *int `json:"refreshInterval"`
+	// Tokens aren't fetched automatically for paused sources, only with
+	// `fetchOAuth2Token`.
+	Paused bool `json:"paused"`
+}
+
+type OAuth2TokenSourceInput struct {
+	Name                    string          `json:"name"`
+	Variable                string          `json:"variable"`
+	TokenURL                *url.URL        `json:"tokenURL"`
+	GrantType               OAuth2GrantType `json:"grantType"`
+	ClientID                *string         `json:"clientID"`
+	ClientSecret            *string         `json:"clientSecret"`
+	ClientCredentialsInBody *bool           `json:"clientCredentialsInBody"`
+	Username                *string         `json:"username"`
+	Password                *string         `json:"password"`
+	Scopes                  []string        `json:"scopes"`
+	RefreshInterval         *int            `json:"refreshInterval"`
+	Paused                  *bool           `json:"paused"`
 }
 
 type Project struct {
-	ID       ulid.ULID `json:"id"`
-	Name     string    `json:"name"`
-	IsActive bool      `json:"isActive"`
+	ID         ulid.ULID `json:"id"`
+	Name       string    `json:"name"`
+	IsActive   bool      `json:"isActive"`
+	IsReadOnly bool      `json:"isReadOnly"`
+}
+
+// Refuses to proxy matching requests: the proxy responds with 403 Forbidden,
+// without contacting the upstream server. A rule matches if all of its set
+// criteria match.
+type ProxyBlockRule struct {
+	// Hostname, without port. A leading `*.` matches all subdomains. Rules with only
+	// a host also refuse CONNECT tunnels to the host.
+	Host *string `json:"host"`
+	// Matched against the full URL of requests.
+	URL *string `json:"url"`
+}
+
+type ProxyBlockRuleInput struct {
+	Host *string `json:"host"`
+	URL  *string `json:"url"`
+}
+
+type Replay struct {
+	ID        ulid.ULID      `json:"id"`
+	Status    ReplayStatus   `json:"status"`
+	Timing    ReplayTiming   `json:"timing"`
+	Speed     float64        `json:"speed"`
+	Total     int            `json:"total"`
+	Completed int            `json:"completed"`
+	Results   []ReplayResult `json:"results"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// A replayed request, with its new response next to the original one.
+type ReplayResult struct {
+	OriginalRequestLogID ulid.ULID `json:"originalRequestLogID"`
+	// Request log of the replayed request, if it was logged.
+	RequestLogID *ulid.ULID `json:"requestLogID"`
+	Method       HTTPMethod `json:"method"`
+	// URL the request was sent to, after host rewrites.
+	URL              *url.URL         `json:"url"`
+	OriginalResponse *HTTPResponseLog `json:"originalResponse"`
+	Response         *HTTPResponseLog `json:"response"`
+	// Milliseconds between sending the request and receiving its response.
+	Duration int `json:"duration"`
+	// Error of sending the request, if any.
+	Error             *string `json:"error"`
+	StatusCodeChanged bool    `json:"statusCodeChanged"`
+	BodyChanged       bool    `json:"bodyChanged"`
+}
+
+type ResignJWTInput struct {
+	Token string `json:"token"`
+	// JSON encoded object. Defaults to the header of `token`.
+	Header *string `json:"header"`
+	// JSON encoded object. Defaults to the claims of `token`.
+	Claims    *string `json:"claims"`
+	Algorithm string  `json:"algorithm"`
+	// Secret for HMAC algorithms, or a PEM encoded private key for RSA algorithms.
+	Key string `json:"key"`
+	// When set, occurrences of `token` in the headers and body of this sender
+	// request are replaced with the re-signed token.
+	SenderRequestID *ulid.ULID `json:"senderRequestID"`
+}
+
+type ResignJWTResult struct {
+	Token         string         `json:"token"`
+	SenderRequest *SenderRequest `json:"senderRequest"`
+}
+
+// Built-in rewrites of proxied responses, for the active project.
+type ResponseRewritePresets struct {
+	InjectScript           *string `json:"injectScript"`
+	StripCsp               bool    `json:"stripCSP"`
+	StripHsts              bool    `json:"stripHSTS"`
+	RemoveSecureCookieFlag bool    `json:"removeSecureCookieFlag"`
+}
+
+type ResponseRewritePresetsInput struct {
+	// JavaScript that is injected in HTML responses.
+	InjectScript *string `json:"injectScript"`
+	// Remove `Content-Security-Policy` headers.
+	StripCsp bool `json:"stripCSP"`
+	// Remove `Strict-Transport-Security` headers.
+	StripHsts bool `json:"stripHSTS"`
+	// Remove the `Secure` attribute of cookies.
+	RemoveSecureCookieFlag bool `json:"removeSecureCookieFlag"`
+}
+
+type RewriteHostRule struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Scheme *string `json:"scheme"`
+}
+
+type RewriteHostRuleInput struct {
+	// Host (with port, if not the default port) to rewrite, e.g. `example.com`.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Scheme of rewritten requests, `http` or `https`. Defaults to the original
+	// scheme.
+	Scheme *string `json:"scheme"`
+}
+
+// Serves a local file or inline content as the response to matching proxied
+// requests ("map local"), instead of the upstream server. The first matching
+// mapping is used.
+type RewriteLocalMapping struct {
+	URL         string  `json:"url"`
+	File        *string `json:"file"`
+	Content     *string `json:"content"`
+	ContentType *string `json:"contentType"`
+	StatusCode  int     `json:"statusCode"`
+}
+
+type RewriteLocalMappingInput struct {
+	// Matched against the full URL of requests.
+	URL string `json:"url"`
+	// Path of the file to serve. It's read for every request.
+	File *string `json:"file"`
+	// Content to serve, if `file` isn't set.
+	Content *string `json:"content"`
+	// Media type of the response. Defaults to the type of the file extension of
+	// `file` or of the URL path, or else it's detected from the content.
+	ContentType *string `json:"contentType"`
+	// Defaults to 200.
+	StatusCode *int `json:"statusCode"`
+}
+
+type RewriteProfile struct {
+	Name      string            `json:"name"`
+	HostRules []RewriteHostRule `json:"hostRules"`
+	// Headers that are set on each request, replacing existing values.
+	Headers []HTTPHeader `json:"headers"`
+}
+
+type RewriteProfileInput struct {
+	Name      string                 `json:"name"`
+	HostRules []RewriteHostRuleInput `json:"hostRules"`
+	Headers   []HTTPHeaderInput      `json:"headers"`
+}
+
+// Rewrites of requests of the sender and of replays, e.g. to send requests
+// captured in production to staging, with the credentials of staging.
+type RewriteProfiles struct {
+	Profiles []RewriteProfile `json:"profiles"`
+	// Name of the profile that sender requests are rewritten with, if any.
+	Active *string `json:"active"`
+}
+
+// Sends matching proxied requests to another origin or path ("map remote"). Both
+// the original and the rewritten URL are logged. The first matching mapping is
+// used.
+type RewriteRemoteMapping struct {
+	URL    string  `json:"url"`
+	Scheme *string `json:"scheme"`
+	Host   *string `json:"host"`
+	Port   *int    `json:"port"`
+	Path   *string `json:"path"`
+}
+
+type RewriteRemoteMappingInput struct {
+	// Matched against the full URL of requests.
+	URL string `json:"url"`
+	// Scheme of rewritten requests: `http` or `https`. Defaults to the scheme of
+	// the original request.
+	Scheme *string `json:"scheme"`
+	// Hostname of rewritten requests, without port. Defaults to the hostname of
+	// the original request.
+	Host *string `json:"host"`
+	// Port of rewritten requests. Defaults to the port of the original request.
+	Port *int `json:"port"`
+	// Path of rewritten requests, replacing the original path. The query is kept.
+	Path *string `json:"path"`
 }
 
 type ScopeHeader struct {
@@ -97,16 +1032,120 @@ type ScopeRuleInput struct {
 	Body   *string           `json:"body"`
 }
 
+type SenderAssertion struct {
+	Type  SenderAssertionType `json:"type"`
+	Path  *string             `json:"path"`
+	Value string              `json:"value"`
+}
+
+type SenderAssertionInput struct {
+	Type SenderAssertionType `json:"type"`
+	// Dot separated path in the JSON response body, e.g. `$.user.roles.0`, for
+	// `JSON_PATH_EQUALS`.
+	Path  *string `json:"path"`
+	Value string  `json:"value"`
+}
+
+type SenderAssertionResult struct {
+	Assertion *SenderAssertion `json:"assertion"`
+	Passed    bool             `json:"passed"`
+	// Value found in the response, if any.
+	Actual *string `json:"actual"`
+	// Reason the assertion failed, if it did.
+	Message *string `json:"message"`
+}
+
+type SenderAssertionSuiteResult struct {
+	Results []SenderCollectionRunResult `json:"results"`
+	Passed  int                         `json:"passed"`
+	Failed  int                         `json:"failed"`
+}
+
+// Named, ordered group of sender requests, e.g. the requests of an
+// authentication flow. A request is in at most one collection.
+type SenderCollection struct {
+	ID       ulid.ULID       `json:"id"`
+	Name     string          `json:"name"`
+	Requests []SenderRequest `json:"requests"`
+}
+
+type SenderCollectionRunResult struct {
+	// Not set if the request no longer exists.
+	Request *SenderRequest `json:"request"`
+	// Set if the request couldn't be sent; other requests of the collection are
+	// still sent.
+	Error *string `json:"error"`
+	// Results of the assertions of the request, if it was sent.
+	AssertionResults []SenderAssertionResult `json:"assertionResults"`
+	// True if the request was sent and all its assertions passed.
+	Passed bool `json:"passed"`
+}
+
+// Responses of a sender request sent to two base URLs, e.g. an old and a new API
+// version, and their differences.
+type SenderComparison struct {
+	ID        ulid.ULID                `json:"id"`
+	RequestID ulid.ULID                `json:"requestID"`
+	Timestamp time.Time                `json:"timestamp"`
+	Targets   []SenderComparisonTarget `json:"targets"`
+	Diff      *SenderResponseDiff      `json:"diff"`
+}
+
+type SenderComparisonTarget struct {
+	BaseURL *url.URL `json:"baseURL"`
+	// URL the request was sent to.
+	URL      *url.URL         `json:"url"`
+	Response *HTTPResponseLog `json:"response"`
+	// Error of sending the request, if any.
+	Error *string `json:"error"`
+}
+
+type SenderEnvironment struct {
+	Name      string           `json:"name"`
+	Variables []SenderVariable `json:"variables"`
+}
+
+type SenderEnvironmentInput struct {
+	Name      string                `json:"name"`
+	Variables []SenderVariableInput `json:"variables"`
+}
+
+// Sets of variables of the active project, e.g. for its dev, staging and prod
+// targets. References like `{{baseURL}}` in the URL, header values and body of a
+// sender request are replaced by the variables of the active environment when the
+// request is sent. References to undefined variables are left as is.
+type SenderEnvironments struct {
+	Environments []SenderEnvironment `json:"environments"`
+	// Name of the environment whose variables are used, if any.
+	Active *string `json:"active"`
+}
+
+// Header whose values differ. Values are empty if a response doesn't have it.
+type SenderHeaderDiff struct {
+	Key     string   `json:"key"`
+	AValues []string `json:"aValues"`
+	BValues []string `json:"bValues"`
+}
+
 type SenderRequest struct {
-	ID                 ulid.ULID        `json:"id"`
-	SourceRequestLogID *ulid.ULID       `json:"sourceRequestLogID"`
-	URL                *url.URL         `json:"url"`
-	Method             HTTPMethod       `json:"method"`
-	Proto              HTTPProtocol     `json:"proto"`
-	Headers            []HTTPHeader     `json:"headers"`
-	Body               *string          `json:"body"`
-	Timestamp          time.Time        `json:"timestamp"`
-	Response           *HTTPResponseLog `json:"response"`
+	ID                 ulid.ULID    `json:"id"`
+	SourceRequestLogID *ulid.ULID   `json:"sourceRequestLogID"`
+	URL                *url.URL     `json:"url"`
+	Method             HTTPMethod   `json:"method"`
+	Proto              HTTPProtocol `json:"proto"`
+	Headers            []HTTPHeader `json:"headers"`
+	Body               *string      `json:"body"`
+	Raw                *string      `json:"raw"`
+	// Bytes received for the last raw request that was sent.
+	RawResponse        *string           `json:"rawResponse"`
+	PreRequestScript   *string           `json:"preRequestScript"`
+	PostResponseScript *string           `json:"postResponseScript"`
+	Assertions         []SenderAssertion `json:"assertions"`
+	// Results of the assertions, checked against the last response. Not set if the
+	// request hasn't been sent.
+	AssertionResults []SenderAssertionResult `json:"assertionResults"`
+	Timestamp        time.Time               `json:"timestamp"`
+	Response         *HTTPResponseLog        `json:"response"`
 }
 
 type SenderRequestFilter struct {
@@ -126,100 +1165,1890 @@ type SenderRequestInput struct {
 	Proto   *HTTPProtocol     `json:"proto"`
 	Headers []HTTPHeaderInput `json:"headers"`
 	Body    *string           `json:"body"`
+	// Raw request, sent as is to the host of `url` instead of a request built from
+	// the fields above. Allows malformed requests, e.g. duplicate headers, obs-fold
+	// or conflicting `Content-Length` and `Transfer-Encoding` headers.
+	Raw *string `json:"raw"`
+	// Script that runs before the request is sent, e.g. to set a signature header.
+	// Not supported for raw requests.
+	PreRequestScript *string `json:"preRequestScript"`
+	// Script that runs after the response is received, e.g. to extract a token into
+	// a variable of the active environment.
+	PostResponseScript *string `json:"postResponseScript"`
+	// Checked against the response when the request is run in a collection or
+	// assertion suite.
+	Assertions []SenderAssertionInput `json:"assertions"`
 }
 
-type HTTPMethod string
+// Differences of the responses of a comparison. Empty if either request failed.
+type SenderResponseDiff struct {
+	// True if the status codes, headers and bodies are equal, ignoring volatile
+	// fields.
+	Equal             bool               `json:"equal"`
+	StatusCodeChanged bool               `json:"statusCodeChanged"`
+	Headers           []SenderHeaderDiff `json:"headers"`
+	// Unified diff of the normalized bodies (indented, if JSON), if they differ.
+	Body *string `json:"body"`
+}
 
-const (
-	HTTPMethodGet     HTTPMethod = "GET"
-	HTTPMethodHead    HTTPMethod = "HEAD"
-	HTTPMethodPost    HTTPMethod = "POST"
-	HTTPMethodPut     HTTPMethod = "PUT"
-	HTTPMethodDelete  HTTPMethod = "DELETE"
-	HTTPMethodConnect HTTPMethod = "CONNECT"
-	HTTPMethodOptions HTTPMethod = "OPTIONS"
-	HTTPMethodTrace   HTTPMethod = "TRACE"
-	HTTPMethodPatch   HTTPMethod = "PATCH"
-)
+// Sends requests on an interval and checks their assertions. Requests are
+// selected by ID, by collection, or else all requests with assertions are sent.
+type SenderSchedule struct {
+	Name         string      `json:"name"`
+	RequestIDs   []ulid.ULID `json:"requestIDs"`
+	CollectionID *ulid.ULID  `json:"collectionID"`
+	// Seconds between runs.
+	Interval int `json:"interval"`
+	// URL that a JSON summary of a run is posted to when an assertion fails, or, if
+	// `alertOnChange` is true, when a response changed since the previous run.
+	WebhookURL    *url.URL `json:"webhookURL"`
+	AlertOnChange bool     `json:"alertOnChange"`
+	// JSON paths (e.g. `$.items.*.updatedAt`) of volatile fields that are ignored
+	// when responses are compared.
+	IgnoreJSONPaths []string `json:"ignoreJSONPaths"`
+	// Regular expressions of volatile values in response bodies (e.g.
+	// `csrf_token=\w+`) that are ignored when responses are compared.
+	IgnorePatterns []string `json:"ignorePatterns"`
+	// Paused schedules aren't run, but are kept so that they can be resumed.
+	Paused bool `json:"paused"`
+}
+
+type SenderScheduleInput struct {
+	Name            string      `json:"name"`
+	RequestIDs      []ulid.ULID `json:"requestIDs"`
+	CollectionID    *ulid.ULID  `json:"collectionID"`
+	Interval        int         `json:"interval"`
+	WebhookURL      *url.URL    `json:"webhookURL"`
+	AlertOnChange   *bool       `json:"alertOnChange"`
+	IgnoreJSONPaths []string    `json:"ignoreJSONPaths"`
+	IgnorePatterns  []string    `json:"ignorePatterns"`
+	Paused          *bool       `json:"paused"`
+}
+
+type SenderScheduleRun struct {
+	ID        ulid.ULID                 `json:"id"`
+	Schedule  string                    `json:"schedule"`
+	Timestamp time.Time                 `json:"timestamp"`
+	Passed    int                       `json:"passed"`
+	Failed    int                       `json:"failed"`
+	Changed   int                       `json:"changed"`
+	Results   []SenderScheduleRunResult `json:"results"`
+}
+
+type SenderScheduleRunResult struct {
+	RequestID        ulid.ULID               `json:"requestID"`
+	StatusCode       *int                    `json:"statusCode"`
+	Error            *string                 `json:"error"`
+	AssertionResults []SenderAssertionResult `json:"assertionResults"`
+	Passed           bool                    `json:"passed"`
+	// True if the status code or body differ from the previous response, ignoring
+	// volatile fields.
+	Changed bool `json:"changed"`
+	// Status and body of the response, without volatile fields. Only set on the
+	// first run, and when the response changed.
+	Snapshot *string `json:"snapshot"`
+	// Unified diff of the previous and current snapshot, if changed.
+	Diff *string `json:"diff"`
+}
+
+// Signs sender requests to hosts matching `hostPattern` (a regular expression)
+// when they're sent. Exactly one of `awsSigV4` and `hmac` is set. References like
+// `{{secret}}` in values are replaced by variables of the active environment and
+// of the pre-request script.
+type SenderSigningProfile struct {
+	Name        string           `json:"name"`
+	HostPattern string           `json:"hostPattern"`
+	AwsSigV4    *AwsSigV4Signing `json:"awsSigV4"`
+	Hmac        *HmacSigning     `json:"hmac"`
+}
+
+type SenderSigningProfileInput struct {
+	Name        string                `json:"name"`
+	HostPattern string                `json:"hostPattern"`
+	AwsSigV4    *AwsSigV4SigningInput `json:"awsSigV4"`
+	Hmac        *HmacSigningInput     `json:"hmac"`
+}
+
+type SenderVariable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type SenderVariableInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type SmugglingProbeResult struct {
+	Technique SmugglingTechnique `json:"technique"`
+	// Variant of the `Transfer-Encoding` header, e.g. `tab separator`.
+	Variant string `json:"variant"`
+	// Time until a response was received, or the timeout elapsed, in milliseconds.
+	Duration   int  `json:"duration"`
+	TimedOut   bool `json:"timedOut"`
+	StatusCode *int `json:"statusCode"`
+	// True if the probe timed out twice, while the baseline request didn't.
+	Vulnerable bool `json:"vulnerable"`
+}
+
+// Test of a logged request for HTTP request smuggling, using CL.TE and TE.CL
+// probes with variants of the `Transfer-Encoding` header.
+type SmugglingTest struct {
+	ID           ulid.ULID           `json:"id"`
+	RequestLogID ulid.ULID           `json:"requestLogID"`
+	URL          *url.URL            `json:"url"`
+	Status       SmugglingTestStatus `json:"status"`
+	// Duration of a well-formed request, in milliseconds.
+	BaselineDuration int                    `json:"baselineDuration"`
+	Error            *string                `json:"error"`
+	Results          []SmugglingProbeResult `json:"results"`
+	Timestamp        time.Time              `json:"timestamp"`
+}
+
+type StartActiveScanInput struct {
+	Selection *HTTPRequestLogSelectionInput `json:"selection"`
+	// Payloads to inject. Defaults to `activeScanPayloads`.
+	Payloads []ActiveScanPayloadInput `json:"payloads"`
+	// Names of the parameters to inject into. Defaults to all parameters.
+	Parameters []string `json:"parameters"`
+}
+
+type StartContentDiscoveryInput struct {
+	BaseURL *url.URL `json:"baseURL"`
+	// Paths to request, relative to `baseURL`. Defaults to a small built-in list.
+	Wordlist []string `json:"wordlist"`
+	// File extensions that are appended to each path, e.g. `php`.
+	Extensions []string `json:"extensions"`
+	// Defaults to 10, and can be at most 1000.
+	RequestsPerSecond *int `json:"requestsPerSecond"`
+	Concurrency       *int `json:"concurrency"`
+	// Status codes of responses that are not considered a result. Defaults to `[404]`.
+	IgnoreStatusCodes []int `json:"ignoreStatusCodes"`
+}
+
+type StartCrawlInput struct {
+	StartURL    *url.URL `json:"startURL"`
+	MaxDepth    *int     `json:"maxDepth"`
+	MaxRequests *int     `json:"maxRequests"`
+	// Defaults to 10, and can be at most 1000.
+	RequestsPerSecond *int `json:"requestsPerSecond"`
+	Concurrency       *int `json:"concurrency"`
+	// Submit forms found in responses, with placeholder values for empty fields.
+	SubmitForms *bool `json:"submitForms"`
+}
+
+type StartIdorTestInput struct {
+	RequestLogID ulid.ULID `json:"requestLogID"`
+	// Identifiers to replace. Defaults to all identifiers of the request.
+	Identifiers []IdorIdentifierInput `json:"identifiers"`
+	// Values that identifiers are replaced with, e.g. IDs of objects of another
+	// user. Numeric identifiers are also replaced with the adjacent numbers.
+	Values []string `json:"values"`
+}
+
+type StartNucleiRunInput struct {
+	// YAML documents of the templates. HTTP requests with `method`, `path`,
+	// `headers` and `body`, and `word`, `regex`, `status` and `size` matchers are
+	// supported.
+	Templates []string `json:"templates"`
+	// Hosts (e.g. `example.com:8443`) of the site map to run the templates against.
+	// Defaults to all in-scope hosts.
+	Hosts []string `json:"hosts"`
+}
+
+type StartReplayInput struct {
+	// Request logs to replay, in the order they were logged.
+	Selection *HTTPRequestLogSelectionInput `json:"selection"`
+	// Defaults to `ORIGINAL`.
+	Timing *ReplayTiming `json:"timing"`
+	// Factor that delays between requests are divided by with `ORIGINAL` timing,
+	// e.g. `2` to replay twice as fast. Defaults to `1`.
+	Speed *float64 `json:"speed"`
+	// Name of a rewrite profile that requests are rewritten with.
+	RewriteProfile *string `json:"rewriteProfile"`
+	// Host rewrites, applied after those of `rewriteProfile`.
+	HostRewrites []RewriteHostRuleInput `json:"hostRewrites"`
+	// Headers that are set on each request, replacing existing values, e.g.
+	// `Authorization` with credentials of the target environment.
+	Headers []HTTPHeaderInput `json:"headers"`
+	// Name of a sender signing profile that requests are signed with, after
+	// rewrites. Its host pattern is ignored.
+	SigningProfile *string `json:"signingProfile"`
+}
+
+type StartSmugglingTestInput struct {
+	RequestLogID ulid.ULID `json:"requestLogID"`
+	// Time to wait for a response to a probe, in milliseconds. Defaults to 5000.
+	Timeout *int `json:"timeout"`
+}
+
+type StartUnauthCheckInput struct {
+	// Request logs to replay. Those without credentials or a response are skipped.
+	Selection *HTTPRequestLogSelectionInput `json:"selection"`
+	// Headers that are removed, in addition to common credential headers (e.g.
+	// `Authorization` and `Cookie`) and the headers of the authorization check
+	// settings.
+	StripHeaders []string `json:"stripHeaders"`
+	// Query parameters that are removed, e.g. `access_token`.
+	StripQueryParams []string `json:"stripQueryParams"`
+	// Minimum similarity of a response body to the original one, for an endpoint to
+	// be marked as a bypass. Defaults to `0.95`.
+	MinSimilarity *float64 `json:"minSimilarity"`
+}
+
+type StopActiveModulesResult struct {
+	// Jobs that were queued, running or paused, and are cancelled.
+	CancelledJobs []CancelledJob `json:"cancelledJobs"`
+	// Number of sender schedules that are paused. Paused schedules are kept, so
+	// that they can be resumed with `setSenderSchedules`.
+	StoppedSchedules int `json:"stoppedSchedules"`
+	// Number of OAuth 2.0 token sources whose scheduled fetches are paused.
+	PausedTokenSources int `json:"pausedTokenSources"`
+	// True if the replays of the authorization check are disabled. Pending replays
+	// are aborted.
+	DisabledAuthzReplays bool `json:"disabledAuthzReplays"`
+}
+
+type TLSInfo struct {
+	// Protocol version, e.g. `TLS 1.3`.
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipherSuite"`
+	// Negotiated application protocol (ALPN), e.g. `h2`.
+	Alpn       *string `json:"alpn"`
+	ServerName *string `json:"serverName"`
+	// Hex encoded SHA-256 fingerprints of the server's certificate chain, leaf
+	// certificate first.
+	CertificateFingerprints []string `json:"certificateFingerprints"`
+	// True for protocol versions older than TLS 1.2, or insecure cipher suites.
+	Weak bool `json:"weak"`
+}
+
+// An entry of the timeline of a project. Request logs of paths found by content
+// discovery, and of requests made by crawls, have the source of the scan instead
+// of `PROXY`.
+type TimelineEntry struct {
+	ID             ulid.ULID       `json:"id"`
+	Source         TimelineSource  `json:"source"`
+	Timestamp      time.Time       `json:"timestamp"`
+	HTTPRequestLog *HTTPRequestLog `json:"httpRequestLog"`
+	SenderRequest  *SenderRequest  `json:"senderRequest"`
+}
+
+type TransformResult struct {
+	Output string `json:"output"`
+	// Output encoded as base64, for when the output contains binary data.
+	OutputBase64 string `json:"outputBase64"`
+}
+
+// Replay of request logs without their credentials, to find endpoints that don't
+// require authentication.
+type UnauthCheck struct {
+	ID            ulid.ULID           `json:"id"`
+	Status        UnauthCheckStatus   `json:"status"`
+	MinSimilarity float64             `json:"minSimilarity"`
+	Total         int                 `json:"total"`
+	Completed     int                 `json:"completed"`
+	Results       []UnauthCheckResult `json:"results"`
+	Timestamp     time.Time           `json:"timestamp"`
+}
+
+type UnauthCheckResult struct {
+	RequestLogID ulid.ULID  `json:"requestLogID"`
+	Method       HTTPMethod `json:"method"`
+	// URL the request was sent to, without stripped query parameters.
+	URL *url.URL `json:"url"`
+	// Headers and query parameters that were removed.
+	Stripped           []string `json:"stripped"`
+	OriginalStatusCode int      `json:"originalStatusCode"`
+	StatusCode         *int     `json:"statusCode"`
+	// Similarity of the response body to the original one, from 0 (no overlap) to
+	// 1 (identical).
+	Similarity float64 `json:"similarity"`
+	// True if the original response was successful, and the response has the same
+	// status code and a similar body. An `AUTHENTICATION_BYPASS` finding is stored
+	// for it.
+	Bypass bool `json:"bypass"`
+	// Error of sending the request, if any.
+	Error *string `json:"error"`
+}
+
+type UpstreamHostTimeouts struct {
+	// Hostname, without port. A leading `*.` matches all subdomains.
+	Host           string `json:"host"`
+	Dial           int    `json:"dial"`
+	TLSHandshake   int    `json:"tlsHandshake"`
+	ResponseHeader int    `json:"responseHeader"`
+	Request        int    `json:"request"`
+}
+
+type UpstreamHostTimeoutsInput struct {
+	Host           string `json:"host"`
+	Dial           int    `json:"dial"`
+	TLSHandshake   int    `json:"tlsHandshake"`
+	ResponseHeader int    `json:"responseHeader"`
+	Request        int    `json:"request"`
+}
+
+// Limits the rate of outbound requests to matching hosts, of the proxy, the
+// sender and modules that send requests via the proxy (e.g. the crawler and the
+// active scanner) combined. The first matching rule is used.
+type UpstreamRateLimit struct {
+	// Hostname, without port. A leading `*.` matches all subdomains, and `*`
+	// matches all hosts. Requests to all matching hosts share the limit.
+	Host string `json:"host"`
+	// Requests per second.
+	Rate float64 `json:"rate"`
+	// Number of requests that can be sent at once, before the rate applies.
+	Burst int `json:"burst"`
+}
+
+type UpstreamRateLimitInput struct {
+	Host  string  `json:"host"`
+	Rate  float64 `json:"rate"`
+	Burst *int    `json:"burst"`
+}
+
+// Timeouts of upstream requests, in milliseconds. A zero value uses the default
+// for dial (30s) and TLS handshake (10s) timeouts, and means no timeout for the
+// others. For host overrides, it means the global timeout is used.
+type UpstreamTimeouts struct {
+	Dial           int `json:"dial"`
+	TLSHandshake   int `json:"tlsHandshake"`
+	ResponseHeader int `json:"responseHeader"`
+	// Total time of a request, until the response body is read.
+	Request int                    `json:"request"`
+	Hosts   []UpstreamHostTimeouts `json:"hosts"`
+}
+
+type UpstreamTimeoutsInput struct {
+	Dial           int                         `json:"dial"`
+	TLSHandshake   int                         `json:"tlsHandshake"`
+	ResponseHeader int                         `json:"responseHeader"`
+	Request        int                         `json:"request"`
+	Hosts          []UpstreamHostTimeoutsInput `json:"hosts"`
+}
+
+// Module that generates traffic of its own, as opposed to passive proxying.
+type ActiveModule string
+
+const (
+	ActiveModuleCrawler          ActiveModule = "CRAWLER"
+	ActiveModuleContentDiscovery ActiveModule = "CONTENT_DISCOVERY"
+	ActiveModuleActiveScan       ActiveModule = "ACTIVE_SCAN"
+	ActiveModuleNuclei           ActiveModule = "NUCLEI"
+	ActiveModuleReplay           ActiveModule = "REPLAY"
+	ActiveModuleSmugglingTest    ActiveModule = "SMUGGLING_TEST"
+	ActiveModuleIDOrTest         ActiveModule = "IDOR_TEST"
+	ActiveModuleUnauthCheck      ActiveModule = "UNAUTH_CHECK"
+)
+
+var AllActiveModule = []ActiveModule{
+	ActiveModuleCrawler,
+	ActiveModuleContentDiscovery,
+	ActiveModuleActiveScan,
+	ActiveModuleNuclei,
+	ActiveModuleReplay,
+	ActiveModuleSmugglingTest,
+	ActiveModuleIDOrTest,
+	ActiveModuleUnauthCheck,
+}
+
+func (e ActiveModule) IsValid() bool {
+	switch e {
+	case ActiveModuleCrawler, ActiveModuleContentDiscovery, ActiveModuleActiveScan, ActiveModuleNuclei, ActiveModuleReplay, ActiveModuleSmugglingTest, ActiveModuleIDOrTest, ActiveModuleUnauthCheck:
+		return true
+	}
+	return false
+}
+
+func (e ActiveModule) String() string {
+	return string(e)
+}
+
+func (e *ActiveModule) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ActiveModule(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ActiveModule", str)
+	}
+	return nil
+}
+
+func (e ActiveModule) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ActiveScanCategory string
+
+const (
+	ActiveScanCategorySQLInjection      ActiveScanCategory = "SQL_INJECTION"
+	ActiveScanCategoryPathTraversal     ActiveScanCategory = "PATH_TRAVERSAL"
+	ActiveScanCategoryTemplateInjection ActiveScanCategory = "TEMPLATE_INJECTION"
+	ActiveScanCategoryOther             ActiveScanCategory = "OTHER"
+)
+
+var AllActiveScanCategory = []ActiveScanCategory{
+	ActiveScanCategorySQLInjection,
+	ActiveScanCategoryPathTraversal,
+	ActiveScanCategoryTemplateInjection,
+	ActiveScanCategoryOther,
+}
+
+func (e ActiveScanCategory) IsValid() bool {
+	switch e {
+	case ActiveScanCategorySQLInjection, ActiveScanCategoryPathTraversal, ActiveScanCategoryTemplateInjection, ActiveScanCategoryOther:
+		return true
+	}
+	return false
+}
+
+func (e ActiveScanCategory) String() string {
+	return string(e)
+}
+
+func (e *ActiveScanCategory) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ActiveScanCategory(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ActiveScanCategory", str)
+	}
+	return nil
+}
+
+func (e ActiveScanCategory) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ActiveScanLocation string
+
+const (
+	ActiveScanLocationQuery ActiveScanLocation = "QUERY"
+	ActiveScanLocationForm  ActiveScanLocation = "FORM"
+	ActiveScanLocationJSON  ActiveScanLocation = "JSON"
+)
+
+var AllActiveScanLocation = []ActiveScanLocation{
+	ActiveScanLocationQuery,
+	ActiveScanLocationForm,
+	ActiveScanLocationJSON,
+}
+
+func (e ActiveScanLocation) IsValid() bool {
+	switch e {
+	case ActiveScanLocationQuery, ActiveScanLocationForm, ActiveScanLocationJSON:
+		return true
+	}
+	return false
+}
+
+func (e ActiveScanLocation) String() string {
+	return string(e)
+}
+
+func (e *ActiveScanLocation) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ActiveScanLocation(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ActiveScanLocation", str)
+	}
+	return nil
+}
+
+func (e ActiveScanLocation) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ActiveScanRuleType string
+
+const (
+	ActiveScanRuleTypeBodyContains ActiveScanRuleType = "BODY_CONTAINS"
+	ActiveScanRuleTypeBodyRegexp   ActiveScanRuleType = "BODY_REGEXP"
+	ActiveScanRuleTypeStatusCode   ActiveScanRuleType = "STATUS_CODE"
+)
+
+var AllActiveScanRuleType = []ActiveScanRuleType{
+	ActiveScanRuleTypeBodyContains,
+	ActiveScanRuleTypeBodyRegexp,
+	ActiveScanRuleTypeStatusCode,
+}
+
+func (e ActiveScanRuleType) IsValid() bool {
+	switch e {
+	case ActiveScanRuleTypeBodyContains, ActiveScanRuleTypeBodyRegexp, ActiveScanRuleTypeStatusCode:
+		return true
+	}
+	return false
+}
+
+func (e ActiveScanRuleType) String() string {
+	return string(e)
+}
+
+func (e *ActiveScanRuleType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ActiveScanRuleType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ActiveScanRuleType", str)
+	}
+	return nil
+}
+
+func (e ActiveScanRuleType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ActiveScanStatus string
+
+const (
+	ActiveScanStatusRunning   ActiveScanStatus = "RUNNING"
+	ActiveScanStatusFinished  ActiveScanStatus = "FINISHED"
+	ActiveScanStatusCancelled ActiveScanStatus = "CANCELLED"
+)
+
+var AllActiveScanStatus = []ActiveScanStatus{
+	ActiveScanStatusRunning,
+	ActiveScanStatusFinished,
+	ActiveScanStatusCancelled,
+}
+
+func (e ActiveScanStatus) IsValid() bool {
+	switch e {
+	case ActiveScanStatusRunning, ActiveScanStatusFinished, ActiveScanStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e ActiveScanStatus) String() string {
+	return string(e)
+}
+
+func (e *ActiveScanStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ActiveScanStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ActiveScanStatus", str)
+	}
+	return nil
+}
+
+func (e ActiveScanStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ConnectionMode string
+
+const (
+	ConnectionModeMitm        ConnectionMode = "MITM"
+	ConnectionModePassthrough ConnectionMode = "PASSTHROUGH"
+)
+
+var AllConnectionMode = []ConnectionMode{
+	ConnectionModeMitm,
+	ConnectionModePassthrough,
+}
+
+func (e ConnectionMode) IsValid() bool {
+	switch e {
+	case ConnectionModeMitm, ConnectionModePassthrough:
+		return true
+	}
+	return false
+}
+
+func (e ConnectionMode) String() string {
+	return string(e)
+}
+
+func (e *ConnectionMode) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ConnectionMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ConnectionMode", str)
+	}
+	return nil
+}
+
+func (e ConnectionMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ContentDiscoveryStatus string
+
+const (
+	ContentDiscoveryStatusRunning   ContentDiscoveryStatus = "RUNNING"
+	ContentDiscoveryStatusFinished  ContentDiscoveryStatus = "FINISHED"
+	ContentDiscoveryStatusCancelled ContentDiscoveryStatus = "CANCELLED"
+)
+
+var AllContentDiscoveryStatus = []ContentDiscoveryStatus{
+	ContentDiscoveryStatusRunning,
+	ContentDiscoveryStatusFinished,
+	ContentDiscoveryStatusCancelled,
+}
+
+func (e ContentDiscoveryStatus) IsValid() bool {
+	switch e {
+	case ContentDiscoveryStatusRunning, ContentDiscoveryStatusFinished, ContentDiscoveryStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e ContentDiscoveryStatus) String() string {
+	return string(e)
+}
+
+func (e *ContentDiscoveryStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ContentDiscoveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ContentDiscoveryStatus", str)
+	}
+	return nil
+}
+
+func (e ContentDiscoveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type CrawlStatus string
+
+const (
+	CrawlStatusRunning   CrawlStatus = "RUNNING"
+	CrawlStatusFinished  CrawlStatus = "FINISHED"
+	CrawlStatusCancelled CrawlStatus = "CANCELLED"
+)
+
+var AllCrawlStatus = []CrawlStatus{
+	CrawlStatusRunning,
+	CrawlStatusFinished,
+	CrawlStatusCancelled,
+}
+
+func (e CrawlStatus) IsValid() bool {
+	switch e {
+	case CrawlStatusRunning, CrawlStatusFinished, CrawlStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e CrawlStatus) String() string {
+	return string(e)
+}
+
+func (e *CrawlStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CrawlStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CrawlStatus", str)
+	}
+	return nil
+}
+
+func (e CrawlStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type FindingCheck string
+
+const (
+	FindingCheckCorsWildcardCredentials   FindingCheck = "CORS_WILDCARD_CREDENTIALS"
+	FindingCheckCorsReflectedOrigin       FindingCheck = "CORS_REFLECTED_ORIGIN"
+	FindingCheckCorsNullOrigin            FindingCheck = "CORS_NULL_ORIGIN"
+	FindingCheckMissingCsp                FindingCheck = "MISSING_CSP"
+	FindingCheckMissingFrameOptions       FindingCheck = "MISSING_FRAME_OPTIONS"
+	FindingCheckMissingContentTypeOptions FindingCheck = "MISSING_CONTENT_TYPE_OPTIONS"
+	FindingCheckMissingHsts               FindingCheck = "MISSING_HSTS"
+	FindingCheckCookieMissingSecure       FindingCheck = "COOKIE_MISSING_SECURE"
+	FindingCheckCookieMissingHTTPOnly     FindingCheck = "COOKIE_MISSING_HTTP_ONLY"
+	FindingCheckCookieMissingSameSite     FindingCheck = "COOKIE_MISSING_SAME_SITE"
+	FindingCheckReflectedInput            FindingCheck = "REFLECTED_INPUT"
+	FindingCheckStoredReflectedInput      FindingCheck = "STORED_REFLECTED_INPUT"
+	FindingCheckRequestSmugglingClTe      FindingCheck = "REQUEST_SMUGGLING_CL_TE"
+	FindingCheckRequestSmugglingTeCl      FindingCheck = "REQUEST_SMUGGLING_TE_CL"
+	FindingCheckAuthorizationBypass       FindingCheck = "AUTHORIZATION_BYPASS"
+	FindingCheckAuthenticationBypass      FindingCheck = "AUTHENTICATION_BYPASS"
+	FindingCheckIDOrCandidate             FindingCheck = "IDOR_CANDIDATE"
+	FindingCheckSQLInjection              FindingCheck = "SQL_INJECTION"
+	FindingCheckPathTraversal             FindingCheck = "PATH_TRAVERSAL"
+	FindingCheckTemplateInjection         FindingCheck = "TEMPLATE_INJECTION"
+	FindingCheckActiveScanMatch           FindingCheck = "ACTIVE_SCAN_MATCH"
+	FindingCheckNucleiTemplate            FindingCheck = "NUCLEI_TEMPLATE"
+)
+
+var AllFindingCheck = []FindingCheck{
+	FindingCheckCorsWildcardCredentials,
+	FindingCheckCorsReflectedOrigin,
+	FindingCheckCorsNullOrigin,
+	FindingCheckMissingCsp,
+	FindingCheckMissingFrameOptions,
+	FindingCheckMissingContentTypeOptions,
+	FindingCheckMissingHsts,
+	FindingCheckCookieMissingSecure,
+	FindingCheckCookieMissingHTTPOnly,
+	FindingCheckCookieMissingSameSite,
+	FindingCheckReflectedInput,
+	FindingCheckStoredReflectedInput,
+	FindingCheckRequestSmugglingClTe,
+	FindingCheckRequestSmugglingTeCl,
+	FindingCheckAuthorizationBypass,
+	FindingCheckAuthenticationBypass,
+	FindingCheckIDOrCandidate,
+	FindingCheckSQLInjection,
+	FindingCheckPathTraversal,
+	FindingCheckTemplateInjection,
+	FindingCheckActiveScanMatch,
+	FindingCheckNucleiTemplate,
+}
+
+func (e FindingCheck) IsValid() bool {
+	switch e {
+	case FindingCheckCorsWildcardCredentials, FindingCheckCorsReflectedOrigin, FindingCheckCorsNullOrigin, FindingCheckMissingCsp, FindingCheckMissingFrameOptions, FindingCheckMissingContentTypeOptions, FindingCheckMissingHsts, FindingCheckCookieMissingSecure, FindingCheckCookieMissingHTTPOnly, FindingCheckCookieMissingSameSite, FindingCheckReflectedInput, FindingCheckStoredReflectedInput, FindingCheckRequestSmugglingClTe, FindingCheckRequestSmugglingTeCl, FindingCheckAuthorizationBypass, FindingCheckAuthenticationBypass, FindingCheckIDOrCandidate, FindingCheckSQLInjection, FindingCheckPathTraversal, FindingCheckTemplateInjection, FindingCheckActiveScanMatch, FindingCheckNucleiTemplate:
+		return true
+	}
+	return false
+}
+
+func (e FindingCheck) String() string {
+	return string(e)
+}
+
+func (e *FindingCheck) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = FindingCheck(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid FindingCheck", str)
+	}
+	return nil
+}
+
+func (e FindingCheck) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type FindingSeverity string
+
+const (
+	FindingSeverityInfo   FindingSeverity = "INFO"
+	FindingSeverityLow    FindingSeverity = "LOW"
+	FindingSeverityMedium FindingSeverity = "MEDIUM"
+	FindingSeverityHigh   FindingSeverity = "HIGH"
+)
+
+var AllFindingSeverity = []FindingSeverity{
+	FindingSeverityInfo,
+	FindingSeverityLow,
+	FindingSeverityMedium,
+	FindingSeverityHigh,
+}
+
+func (e FindingSeverity) IsValid() bool {
+	switch e {
+	case FindingSeverityInfo, FindingSeverityLow, FindingSeverityMedium, FindingSeverityHigh:
+		return true
+	}
+	return false
+}
+
+func (e FindingSeverity) String() string {
+	return string(e)
+}
+
+func (e *FindingSeverity) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = FindingSeverity(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid FindingSeverity", str)
+	}
+	return nil
+}
+
+func (e FindingSeverity) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type HTTPBodySource string
+
+const (
+	HTTPBodySourceRequest  HTTPBodySource = "REQUEST"
+	HTTPBodySourceResponse HTTPBodySource = "RESPONSE"
+)
+
+var AllHTTPBodySource = []HTTPBodySource{
+	HTTPBodySourceRequest,
+	HTTPBodySourceResponse,
+}
+
+func (e HTTPBodySource) IsValid() bool {
+	switch e {
+	case HTTPBodySourceRequest, HTTPBodySourceResponse:
+		return true
+	}
+	return false
+}
+
+func (e HTTPBodySource) String() string {
+	return string(e)
+}
+
+func (e *HTTPBodySource) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = HTTPBodySource(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid HttpBodySource", str)
+	}
+	return nil
+}
+
+func (e HTTPBodySource) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type HTTPClientDeviceType string
+
+const (
+	HTTPClientDeviceTypeDesktop HTTPClientDeviceType = "DESKTOP"
+	HTTPClientDeviceTypeMobile  HTTPClientDeviceType = "MOBILE"
+	HTTPClientDeviceTypeTablet  HTTPClientDeviceType = "TABLET"
+	HTTPClientDeviceTypeBot     HTTPClientDeviceType = "BOT"
+	HTTPClientDeviceTypeCli     HTTPClientDeviceType = "CLI"
+	HTTPClientDeviceTypeUnknown HTTPClientDeviceType = "UNKNOWN"
+)
+
+var AllHTTPClientDeviceType = []HTTPClientDeviceType{
+	HTTPClientDeviceTypeDesktop,
+	HTTPClientDeviceTypeMobile,
+	HTTPClientDeviceTypeTablet,
+	HTTPClientDeviceTypeBot,
+	HTTPClientDeviceTypeCli,
+	HTTPClientDeviceTypeUnknown,
+}
+
+func (e HTTPClientDeviceType) IsValid() bool {
+	switch e {
+	case HTTPClientDeviceTypeDesktop, HTTPClientDeviceTypeMobile, HTTPClientDeviceTypeTablet, HTTPClientDeviceTypeBot, HTTPClientDeviceTypeCli, HTTPClientDeviceTypeUnknown:
+		return true
+	}
+	return false
+}
+
+func (e HTTPClientDeviceType) String() string {
+	return string(e)
+}
+
+func (e *HTTPClientDeviceType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = HTTPClientDeviceType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid HttpClientDeviceType", str)
+	}
+	return nil
+}
+
+func (e HTTPClientDeviceType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type HTTPMethod string
+
+const (
+	HTTPMethodGet     HTTPMethod = "GET"
+	HTTPMethodHead    HTTPMethod = "HEAD"
+	HTTPMethodPost    HTTPMethod = "POST"
+	HTTPMethodPut     HTTPMethod = "PUT"
+	HTTPMethodDelete  HTTPMethod = "DELETE"
+	HTTPMethodConnect HTTPMethod = "CONNECT"
+	HTTPMethodOptions HTTPMethod = "OPTIONS"
+	HTTPMethodTrace   HTTPMethod = "TRACE"
+	HTTPMethodPatch   HTTPMethod = "PATCH"
+)
+
+var AllHTTPMethod = []HTTPMethod{
+	HTTPMethodGet,
+	HTTPMethodHead,
+	HTTPMethodPost,
+	HTTPMethodPut,
+	HTTPMethodDelete,
+	HTTPMethodConnect,
+	HTTPMethodOptions,
+	HTTPMethodTrace,
+	HTTPMethodPatch,
+}
+
+func (e HTTPMethod) IsValid() bool {
+	switch e {
+	case HTTPMethodGet, HTTPMethodHead, HTTPMethodPost, HTTPMethodPut, HTTPMethodDelete, HTTPMethodConnect, HTTPMethodOptions, HTTPMethodTrace, HTTPMethodPatch:
+		return true
+	}
+	return false
+}
+
+func (e HTTPMethod) String() string {
+	return string(e)
+}
+
+func (e *HTTPMethod) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = HTTPMethod(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid HttpMethod", str)
+	}
+	return nil
+}
+
+func (e HTTPMethod) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type HTTPProtocol string
+
+const (
+	HTTPProtocolHTTP1 HTTPProtocol = "HTTP1"
+	HTTPProtocolHTTP2 HTTPProtocol = "HTTP2"
+)
+
+var AllHTTPProtocol = []HTTPProtocol{
+	HTTPProtocolHTTP1,
+	HTTPProtocolHTTP2,
+}
+
+func (e HTTPProtocol) IsValid() bool {
+	switch e {
+	case HTTPProtocolHTTP1, HTTPProtocolHTTP2:
+		return true
+	}
+	return false
+}
+
+func (e HTTPProtocol) String() string {
+	return string(e)
+}
+
+func (e *HTTPProtocol) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = HTTPProtocol(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid HttpProtocol", str)
+	}
+	return nil
+}
+
+func (e HTTPProtocol) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type HTTPRequestLogFilterPreset string
+
+const (
+	// Images, fonts, stylesheets and media, by extension of the URL path.
+	HTTPRequestLogFilterPresetHideStaticAssets HTTPRequestLogFilterPreset = "HIDE_STATIC_ASSETS"
+	// Requests to common analytics, advertising and tracking domains.
+	HTTPRequestLogFilterPresetHideTracking HTTPRequestLogFilterPreset = "HIDE_TRACKING"
+	// OCSP and CRL requests, which clients send to check certificates.
+	HTTPRequestLogFilterPresetHideCertRevocation HTTPRequestLogFilterPreset = "HIDE_CERT_REVOCATION"
+)
+
+var AllHTTPRequestLogFilterPreset = []HTTPRequestLogFilterPreset{
+	HTTPRequestLogFilterPresetHideStaticAssets,
+	HTTPRequestLogFilterPresetHideTracking,
+	HTTPRequestLogFilterPresetHideCertRevocation,
+}
+
+func (e HTTPRequestLogFilterPreset) IsValid() bool {
+	switch e {
+	case HTTPRequestLogFilterPresetHideStaticAssets, HTTPRequestLogFilterPresetHideTracking, HTTPRequestLogFilterPresetHideCertRevocation:
+		return true
+	}
+	return false
+}
+
+func (e HTTPRequestLogFilterPreset) String() string {
+	return string(e)
+}
+
+func (e *HTTPRequestLogFilterPreset) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = HTTPRequestLogFilterPreset(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid HttpRequestLogFilterPreset", str)
+	}
+	return nil
+}
+
+func (e HTTPRequestLogFilterPreset) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type IdorIdentifierKind string
+
+const (
+	IdorIdentifierKindNumeric IdorIdentifierKind = "NUMERIC"
+	IdorIdentifierKindUUID    IdorIdentifierKind = "UUID"
+)
+
+var AllIdorIdentifierKind = []IdorIdentifierKind{
+	IdorIdentifierKindNumeric,
+	IdorIdentifierKindUUID,
+}
+
+func (e IdorIdentifierKind) IsValid() bool {
+	switch e {
+	case IdorIdentifierKindNumeric, IdorIdentifierKindUUID:
+		return true
+	}
+	return false
+}
+
+func (e IdorIdentifierKind) String() string {
+	return string(e)
+}
+
+func (e *IdorIdentifierKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = IdorIdentifierKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid IdorIdentifierKind", str)
+	}
+	return nil
+}
+
+func (e IdorIdentifierKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type IdorLocation string
+
+const (
+	IdorLocationPath  IdorLocation = "PATH"
+	IdorLocationQuery IdorLocation = "QUERY"
+	IdorLocationForm  IdorLocation = "FORM"
+	IdorLocationJSON  IdorLocation = "JSON"
+)
+
+var AllIdorLocation = []IdorLocation{
+	IdorLocationPath,
+	IdorLocationQuery,
+	IdorLocationForm,
+	IdorLocationJSON,
+}
+
+func (e IdorLocation) IsValid() bool {
+	switch e {
+	case IdorLocationPath, IdorLocationQuery, IdorLocationForm, IdorLocationJSON:
+		return true
+	}
+	return false
+}
+
+func (e IdorLocation) String() string {
+	return string(e)
+}
+
+func (e *IdorLocation) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = IdorLocation(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid IdorLocation", str)
+	}
+	return nil
+}
+
+func (e IdorLocation) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type IdorTestStatus string
+
+const (
+	IdorTestStatusRunning   IdorTestStatus = "RUNNING"
+	IdorTestStatusFinished  IdorTestStatus = "FINISHED"
+	IdorTestStatusCancelled IdorTestStatus = "CANCELLED"
+)
+
+var AllIdorTestStatus = []IdorTestStatus{
+	IdorTestStatusRunning,
+	IdorTestStatusFinished,
+	IdorTestStatusCancelled,
+}
+
+func (e IdorTestStatus) IsValid() bool {
+	switch e {
+	case IdorTestStatusRunning, IdorTestStatusFinished, IdorTestStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e IdorTestStatus) String() string {
+	return string(e)
+}
+
+func (e *IdorTestStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = IdorTestStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid IdorTestStatus", str)
+	}
+	return nil
+}
+
+func (e IdorTestStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type JWTLocation string
+
+const (
+	JWTLocationRequestHeader  JWTLocation = "REQUEST_HEADER"
+	JWTLocationRequestBody    JWTLocation = "REQUEST_BODY"
+	JWTLocationResponseHeader JWTLocation = "RESPONSE_HEADER"
+	JWTLocationResponseBody   JWTLocation = "RESPONSE_BODY"
+)
+
+var AllJWTLocation = []JWTLocation{
+	JWTLocationRequestHeader,
+	JWTLocationRequestBody,
+	JWTLocationResponseHeader,
+	JWTLocationResponseBody,
+}
+
+func (e JWTLocation) IsValid() bool {
+	switch e {
+	case JWTLocationRequestHeader, JWTLocationRequestBody, JWTLocationResponseHeader, JWTLocationResponseBody:
+		return true
+	}
+	return false
+}
+
+func (e JWTLocation) String() string {
+	return string(e)
+}
+
+func (e *JWTLocation) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = JWTLocation(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid JWTLocation", str)
+	}
+	return nil
+}
+
+func (e JWTLocation) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type JWTWeaknessType string
+
+const (
+	JWTWeaknessTypeAlgNone    JWTWeaknessType = "ALG_NONE"
+	JWTWeaknessTypeWeakSecret JWTWeaknessType = "WEAK_SECRET"
+)
+
+var AllJWTWeaknessType = []JWTWeaknessType{
+	JWTWeaknessTypeAlgNone,
+	JWTWeaknessTypeWeakSecret,
+}
+
+func (e JWTWeaknessType) IsValid() bool {
+	switch e {
+	case JWTWeaknessTypeAlgNone, JWTWeaknessTypeWeakSecret:
+		return true
+	}
+	return false
+}
+
+func (e JWTWeaknessType) String() string {
+	return string(e)
+}
+
+func (e *JWTWeaknessType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = JWTWeaknessType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid JWTWeaknessType", str)
+	}
+	return nil
+}
+
+func (e JWTWeaknessType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "QUEUED"
+	JobStatusRunning   JobStatus = "RUNNING"
+	JobStatusPaused    JobStatus = "PAUSED"
+	JobStatusFinished  JobStatus = "FINISHED"
+	JobStatusFailed    JobStatus = "FAILED"
+	JobStatusCancelled JobStatus = "CANCELLED"
+	// The job was active when Hetty was stopped.
+	JobStatusInterrupted JobStatus = "INTERRUPTED"
+)
+
+var AllJobStatus = []JobStatus{
+	JobStatusQueued,
+	JobStatusRunning,
+	JobStatusPaused,
+	JobStatusFinished,
+	JobStatusFailed,
+	JobStatusCancelled,
+	JobStatusInterrupted,
+}
+
+func (e JobStatus) IsValid() bool {
+	switch e {
+	case JobStatusQueued, JobStatusRunning, JobStatusPaused, JobStatusFinished, JobStatusFailed, JobStatusCancelled, JobStatusInterrupted:
+		return true
+	}
+	return false
+}
+
+func (e JobStatus) String() string {
+	return string(e)
+}
+
+func (e *JobStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = JobStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid JobStatus", str)
+	}
+	return nil
+}
+
+func (e JobStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Minimum level of log messages that are written.
+type LogLevel string
+
+const (
+	LogLevelDebug LogLevel = "DEBUG"
+	LogLevelInfo  LogLevel = "INFO"
+	LogLevelWarn  LogLevel = "WARN"
+	LogLevelError LogLevel = "ERROR"
+)
+
+var AllLogLevel = []LogLevel{
+	LogLevelDebug,
+	LogLevelInfo,
+	LogLevelWarn,
+	LogLevelError,
+}
+
+func (e LogLevel) IsValid() bool {
+	switch e {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+		return true
+	}
+	return false
+}
+
+func (e LogLevel) String() string {
+	return string(e)
+}
+
+func (e *LogLevel) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = LogLevel(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid LogLevel", str)
+	}
+	return nil
+}
+
+func (e LogLevel) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type NucleiRunStatus string
+
+const (
+	NucleiRunStatusRunning   NucleiRunStatus = "RUNNING"
+	NucleiRunStatusFinished  NucleiRunStatus = "FINISHED"
+	NucleiRunStatusCancelled NucleiRunStatus = "CANCELLED"
+)
+
+var AllNucleiRunStatus = []NucleiRunStatus{
+	NucleiRunStatusRunning,
+	NucleiRunStatusFinished,
+	NucleiRunStatusCancelled,
+}
+
+func (e NucleiRunStatus) IsValid() bool {
+	switch e {
+	case NucleiRunStatusRunning, NucleiRunStatusFinished, NucleiRunStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e NucleiRunStatus) String() string {
+	return string(e)
+}
+
+func (e *NucleiRunStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = NucleiRunStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid NucleiRunStatus", str)
+	}
+	return nil
+}
+
+func (e NucleiRunStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OASTProtocol string
+
+const (
+	OASTProtocolDNS  OASTProtocol = "DNS"
+	OASTProtocolHTTP OASTProtocol = "HTTP"
+)
 
-var AllHTTPMethod = []HTTPMethod{
-	HTTPMethodGet,
-	HTTPMethodHead,
-	HTTPMethodPost,
-	HTTPMethodPut,
-	HTTPMethodDelete,
-	HTTPMethodConnect,
-	HTTPMethodOptions,
-	HTTPMethodTrace,
-	HTTPMethodPatch,
+var AllOASTProtocol = []OASTProtocol{
+	OASTProtocolDNS,
+	OASTProtocolHTTP,
 }
 
-func (e HTTPMethod) IsValid() bool {
+func (e OASTProtocol) IsValid() bool {
 	switch e {
-	case HTTPMethodGet, HTTPMethodHead, HTTPMethodPost, HTTPMethodPut, HTTPMethodDelete, HTTPMethodConnect, HTTPMethodOptions, HTTPMethodTrace, HTTPMethodPatch:
+	case OASTProtocolDNS, OASTProtocolHTTP:
 		return true
 	}
 	return false
 }
 
-func (e HTTPMethod) String() string {
+func (e OASTProtocol) String() string {
 	return string(e)
 }
 
-func (e *HTTPMethod) UnmarshalGQL(v interface{}) error {
+func (e *OASTProtocol) UnmarshalGQL(v interface{}) error {
 	str, ok := v.(string)
 	if !ok {
 		return fmt.Errorf("enums must be strings")
 	}
 
-	*e = HTTPMethod(str)
+	*e = OASTProtocol(str)
 	if !e.IsValid() {
-		return fmt.Errorf("%s is not a valid HttpMethod", str)
+		return fmt.Errorf("%s is not a valid OASTProtocol", str)
 	}
 	return nil
 }
 
-func (e HTTPMethod) MarshalGQL(w io.Writer) {
+func (e OASTProtocol) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
 
-type HTTPProtocol string
+type OAuth2GrantType string
 
 const (
-	HTTPProtocolHTTP1 HTTPProtocol = "HTTP1"
-	HTTPProtocolHTTP2 HTTPProtocol = "HTTP2"
+	OAuth2GrantTypeClientCredentials OAuth2GrantType = "CLIENT_CREDENTIALS"
+	OAuth2GrantTypePassword          OAuth2GrantType = "PASSWORD"
 )
 
-var AllHTTPProtocol = []HTTPProtocol{
-	HTTPProtocolHTTP1,
-	HTTPProtocolHTTP2,
+var AllOAuth2GrantType = []OAuth2GrantType{
+	OAuth2GrantTypeClientCredentials,
+	OAuth2GrantTypePassword,
 }
 
-func (e HTTPProtocol) IsValid() bool {
+func (e OAuth2GrantType) IsValid() bool {
 	switch e {
-	case HTTPProtocolHTTP1, HTTPProtocolHTTP2:
+	case OAuth2GrantTypeClientCredentials, OAuth2GrantTypePassword:
 		return true
 	}
 	return false
 }
 
-func (e HTTPProtocol) String() string {
+func (e OAuth2GrantType) String() string {
 	return string(e)
 }
 
-func (e *HTTPProtocol) UnmarshalGQL(v interface{}) error {
+func (e *OAuth2GrantType) UnmarshalGQL(v interface{}) error {
 	str, ok := v.(string)
 	if !ok {
 		return fmt.Errorf("enums must be strings")
 	}
 
-	*e = HTTPProtocol(str)
+	*e = OAuth2GrantType(str)
 	if !e.IsValid() {
-		return fmt.Errorf("%s is not a valid HttpProtocol", str)
+		return fmt.Errorf("%s is not a valid OAuth2GrantType", str)
 	}
 	return nil
 }
 
-func (e HTTPProtocol) MarshalGQL(w io.Writer) {
+func (e OAuth2GrantType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReplayStatus string
+
+const (
+	ReplayStatusRunning   ReplayStatus = "RUNNING"
+	ReplayStatusFinished  ReplayStatus = "FINISHED"
+	ReplayStatusCancelled ReplayStatus = "CANCELLED"
+)
+
+var AllReplayStatus = []ReplayStatus{
+	ReplayStatusRunning,
+	ReplayStatusFinished,
+	ReplayStatusCancelled,
+}
+
+func (e ReplayStatus) IsValid() bool {
+	switch e {
+	case ReplayStatusRunning, ReplayStatusFinished, ReplayStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e ReplayStatus) String() string {
+	return string(e)
+}
+
+func (e *ReplayStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReplayStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReplayStatus", str)
+	}
+	return nil
+}
+
+func (e ReplayStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReplayTiming string
+
+const (
+	// Requests are sent one after the other, as fast as possible.
+	ReplayTimingNone ReplayTiming = "NONE"
+	// Requests are spaced like the original requests, divided by the speed.
+	ReplayTimingOriginal ReplayTiming = "ORIGINAL"
+)
+
+var AllReplayTiming = []ReplayTiming{
+	ReplayTimingNone,
+	ReplayTimingOriginal,
+}
+
+func (e ReplayTiming) IsValid() bool {
+	switch e {
+	case ReplayTimingNone, ReplayTimingOriginal:
+		return true
+	}
+	return false
+}
+
+func (e ReplayTiming) String() string {
+	return string(e)
+}
+
+func (e *ReplayTiming) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReplayTiming(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReplayTiming", str)
+	}
+	return nil
+}
+
+func (e ReplayTiming) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SenderAssertionType string
+
+const (
+	SenderAssertionTypeStatusEquals   SenderAssertionType = "STATUS_EQUALS"
+	SenderAssertionTypeBodyContains   SenderAssertionType = "BODY_CONTAINS"
+	SenderAssertionTypeJSONPathEquals SenderAssertionType = "JSON_PATH_EQUALS"
+)
+
+var AllSenderAssertionType = []SenderAssertionType{
+	SenderAssertionTypeStatusEquals,
+	SenderAssertionTypeBodyContains,
+	SenderAssertionTypeJSONPathEquals,
+}
+
+func (e SenderAssertionType) IsValid() bool {
+	switch e {
+	case SenderAssertionTypeStatusEquals, SenderAssertionTypeBodyContains, SenderAssertionTypeJSONPathEquals:
+		return true
+	}
+	return false
+}
+
+func (e SenderAssertionType) String() string {
+	return string(e)
+}
+
+func (e *SenderAssertionType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SenderAssertionType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SenderAssertionType", str)
+	}
+	return nil
+}
+
+func (e SenderAssertionType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SmugglingTechnique string
+
+const (
+	SmugglingTechniqueClTe SmugglingTechnique = "CL_TE"
+	SmugglingTechniqueTeCl SmugglingTechnique = "TE_CL"
+)
+
+var AllSmugglingTechnique = []SmugglingTechnique{
+	SmugglingTechniqueClTe,
+	SmugglingTechniqueTeCl,
+}
+
+func (e SmugglingTechnique) IsValid() bool {
+	switch e {
+	case SmugglingTechniqueClTe, SmugglingTechniqueTeCl:
+		return true
+	}
+	return false
+}
+
+func (e SmugglingTechnique) String() string {
+	return string(e)
+}
+
+func (e *SmugglingTechnique) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SmugglingTechnique(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SmugglingTechnique", str)
+	}
+	return nil
+}
+
+func (e SmugglingTechnique) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SmugglingTestStatus string
+
+const (
+	SmugglingTestStatusRunning   SmugglingTestStatus = "RUNNING"
+	SmugglingTestStatusFinished  SmugglingTestStatus = "FINISHED"
+	SmugglingTestStatusCancelled SmugglingTestStatus = "CANCELLED"
+	SmugglingTestStatusFailed    SmugglingTestStatus = "FAILED"
+)
+
+var AllSmugglingTestStatus = []SmugglingTestStatus{
+	SmugglingTestStatusRunning,
+	SmugglingTestStatusFinished,
+	SmugglingTestStatusCancelled,
+	SmugglingTestStatusFailed,
+}
+
+func (e SmugglingTestStatus) IsValid() bool {
+	switch e {
+	case SmugglingTestStatusRunning, SmugglingTestStatusFinished, SmugglingTestStatusCancelled, SmugglingTestStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e SmugglingTestStatus) String() string {
+	return string(e)
+}
+
+func (e *SmugglingTestStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SmugglingTestStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SmugglingTestStatus", str)
+	}
+	return nil
+}
+
+func (e SmugglingTestStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type TimelineSource string
+
+const (
+	TimelineSourceProxy            TimelineSource = "PROXY"
+	TimelineSourceSender           TimelineSource = "SENDER"
+	TimelineSourceContentDiscovery TimelineSource = "CONTENT_DISCOVERY"
+	TimelineSourceCrawler          TimelineSource = "CRAWLER"
+)
+
+var AllTimelineSource = []TimelineSource{
+	TimelineSourceProxy,
+	TimelineSourceSender,
+	TimelineSourceContentDiscovery,
+	TimelineSourceCrawler,
+}
+
+func (e TimelineSource) IsValid() bool {
+	switch e {
+	case TimelineSourceProxy, TimelineSourceSender, TimelineSourceContentDiscovery, TimelineSourceCrawler:
+		return true
+	}
+	return false
+}
+
+func (e TimelineSource) String() string {
+	return string(e)
+}
+
+func (e *TimelineSource) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TimelineSource(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TimelineSource", str)
+	}
+	return nil
+}
+
+func (e TimelineSource) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type TransformType string
+
+const (
+	TransformTypeBase64Encode    TransformType = "BASE64_ENCODE"
+	TransformTypeBase64Decode    TransformType = "BASE64_DECODE"
+	TransformTypeBase64urlEncode TransformType = "BASE64URL_ENCODE"
+	TransformTypeBase64urlDecode TransformType = "BASE64URL_DECODE"
+	TransformTypeURLEncode       TransformType = "URL_ENCODE"
+	TransformTypeURLDecode       TransformType = "URL_DECODE"
+	TransformTypeHexEncode       TransformType = "HEX_ENCODE"
+	TransformTypeHexDecode       TransformType = "HEX_DECODE"
+	TransformTypeGzipCompress    TransformType = "GZIP_COMPRESS"
+	TransformTypeGzipDecompress  TransformType = "GZIP_DECOMPRESS"
+	TransformTypeHTMLEncode      TransformType = "HTML_ENCODE"
+	TransformTypeHTMLDecode      TransformType = "HTML_DECODE"
+	TransformTypeJwtDecode       TransformType = "JWT_DECODE"
+	TransformTypeMd5             TransformType = "MD5"
+	TransformTypeSha1            TransformType = "SHA1"
+	TransformTypeSha256          TransformType = "SHA256"
+	TransformTypeSha512          TransformType = "SHA512"
+)
+
+var AllTransformType = []TransformType{
+	TransformTypeBase64Encode,
+	TransformTypeBase64Decode,
+	TransformTypeBase64urlEncode,
+	TransformTypeBase64urlDecode,
+	TransformTypeURLEncode,
+	TransformTypeURLDecode,
+	TransformTypeHexEncode,
+	TransformTypeHexDecode,
+	TransformTypeGzipCompress,
+	TransformTypeGzipDecompress,
+	TransformTypeHTMLEncode,
+	TransformTypeHTMLDecode,
+	TransformTypeJwtDecode,
+	TransformTypeMd5,
+	TransformTypeSha1,
+	TransformTypeSha256,
+	TransformTypeSha512,
+}
+
+func (e TransformType) IsValid() bool {
+	switch e {
+	case TransformTypeBase64Encode, TransformTypeBase64Decode, TransformTypeBase64urlEncode, TransformTypeBase64urlDecode, TransformTypeURLEncode, TransformTypeURLDecode, TransformTypeHexEncode, TransformTypeHexDecode, TransformTypeGzipCompress, TransformTypeGzipDecompress, TransformTypeHTMLEncode, TransformTypeHTMLDecode, TransformTypeJwtDecode, TransformTypeMd5, TransformTypeSha1, TransformTypeSha256, TransformTypeSha512:
+		return true
+	}
+	return false
+}
+
+func (e TransformType) String() string {
+	return string(e)
+}
+
+func (e *TransformType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TransformType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TransformType", str)
+	}
+	return nil
+}
+
+func (e TransformType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type UnauthCheckStatus string
+
+const (
+	UnauthCheckStatusRunning   UnauthCheckStatus = "RUNNING"
+	UnauthCheckStatusFinished  UnauthCheckStatus = "FINISHED"
+	UnauthCheckStatusCancelled UnauthCheckStatus = "CANCELLED"
+)
+
+var AllUnauthCheckStatus = []UnauthCheckStatus{
+	UnauthCheckStatusRunning,
+	UnauthCheckStatusFinished,
+	UnauthCheckStatusCancelled,
+}
+
+func (e UnauthCheckStatus) IsValid() bool {
+	switch e {
+	case UnauthCheckStatusRunning, UnauthCheckStatusFinished, UnauthCheckStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e UnauthCheckStatus) String() string {
+	return string(e)
+}
+
+func (e *UnauthCheckStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = UnauthCheckStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid UnauthCheckStatus", str)
+	}
+	return nil
+}
+
+func (e UnauthCheckStatus) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
@@ -3,22 +3,51 @@ package api
 //go:generate go run github.com/99designs/gqlgen
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/oklog/ulid"
 	"github.com/vektah/gqlparser/v2/gqlerror"
 
+	"github.com/dstotijn/hetty/pkg/activescan"
+	"github.com/dstotijn/hetty/pkg/authz"
+	"github.com/dstotijn/hetty/pkg/browser"
+	"github.com/dstotijn/hetty/pkg/connlog"
+	"github.com/dstotijn/hetty/pkg/crawler"
+	"github.com/dstotijn/hetty/pkg/discovery"
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/idor"
+	"github.com/dstotijn/hetty/pkg/intercept"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/jwt"
+	"github.com/dstotijn/hetty/pkg/logging"
+	"github.com/dstotijn/hetty/pkg/nuclei"
+	"github.com/dstotijn/hetty/pkg/oast"
+	"github.com/dstotijn/hetty/pkg/oauth2"
 	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/ratelimit"
+	"github.com/dstotijn/hetty/pkg/replay"
 	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
 	"github.com/dstotijn/hetty/pkg/scope"
 	"github.com/dstotijn/hetty/pkg/search"
 	"github.com/dstotijn/hetty/pkg/sender"
+	"github.com/dstotijn/hetty/pkg/smuggle"
+	"github.com/dstotijn/hetty/pkg/transform"
 )
 
 var httpProtocolMap = map[string]HTTPProtocol{
@@ -35,6 +64,25 @@ type Resolver struct {
 	ProjectService    proj.Service
 	RequestLogService reqlog.Service
 	SenderService     sender.Service
+	OASTService       oast.Service
+	DiscoveryService  discovery.Service
+	CrawlerService    crawler.Service
+	FindingService    finding.Service
+	AuthzService      authz.Service
+	SmugglingService  smuggle.Service
+	IDORService       idor.Service
+	ActiveScanService activescan.Service
+	NucleiService     nuclei.Service
+	ReplayService     replay.Service
+	ConnLogService    connlog.Service
+	OAuth2Service     oauth2.Service
+	JobService        job.Service
+	InterceptService  intercept.Service
+	BrowserLauncher   *browser.Launcher
+	Proxy             *proxy.Proxy
+	// Output of the standard logger, if it's configured with package
+	// `pkg/logging`.
+	Logger *logging.Logger
 }
 
 type (
@@ -54,14 +102,16 @@ func (r *queryResolver) HTTPRequestLogs(ctx context.Context) ([]HTTPRequestLog,
 	}
 
 	logs := make([]HTTPRequestLog, len(reqs))
+	columns := r.RequestLogService.Columns()
 
 	for i, req := range reqs {
-		req, err := parseRequestLog(req)
+		log, err := parseRequestLog(req)
 		if err != nil {
 			return nil, err
 		}
 
-		logs[i] = req
+		log.Columns = parseColumnValues(req, columns)
+		logs[i] = log
 	}
 
 	return logs, nil
@@ -80,9 +130,200 @@ func (r *queryResolver) HTTPRequestLog(ctx context.Context, id ulid.ULID) (*HTTP
 		return nil, err
 	}
 
+	req.Columns = parseColumnValues(log, r.RequestLogService.Columns())
+
 	return &req, nil
 }
 
+func (r *queryResolver) HTTPRequestLogRedirectChain(ctx context.Context, id ulid.ULID) ([]HTTPRequestLog, error) {
+	chain, err := r.RequestLogService.FindRedirectChain(ctx, id)
+	if errors.Is(err, reqlog.ErrRequestNotFound) {
+		return nil, gqlerror.Errorf("Request log not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find redirect chain: %w", err)
+	}
+
+	logs := make([]HTTPRequestLog, len(chain))
+
+	for i, reqLog := range chain {
+		log, err := parseRequestLog(reqLog)
+		if err != nil {
+			return nil, err
+		}
+
+		logs[i] = log
+	}
+
+	return logs, nil
+}
+
+func (r *queryResolver) HTTPRequestLogPageLoad(ctx context.Context, id ulid.ULID) ([]HTTPRequestLog, error) {
+	pageLoad, err := r.RequestLogService.FindPageLoad(ctx, id)
+	if errors.Is(err, reqlog.ErrRequestNotFound) {
+		return nil, gqlerror.Errorf("Request log not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find page load: %w", err)
+	}
+
+	logs := make([]HTTPRequestLog, len(pageLoad))
+
+	for i, reqLog := range pageLoad {
+		log, err := parseRequestLog(reqLog)
+		if err != nil {
+			return nil, err
+		}
+
+		logs[i] = log
+	}
+
+	return logs, nil
+}
+
+func (r *queryResolver) HTTPRequestLogSearchHits(
+	ctx context.Context,
+	id ulid.ULID,
+	searchExpression *string,
+) ([]HTTPSearchHit, error) {
+	expr := r.RequestLogService.FindReqsFilter().SearchExpr
+
+	if searchExpression != nil {
+		var err error
+
+		if expr, err = reqlog.ParseSearchExpr(*searchExpression); err != nil {
+			return nil, searchExprErr("Invalid search expression", err)
+		}
+	}
+
+	hits := make([]HTTPSearchHit, 0)
+
+	if expr == nil {
+		return hits, nil
+	}
+
+	reqLog, err := r.RequestLogService.FindRequestLogByID(ctx, id)
+	if errors.Is(err, reqlog.ErrRequestNotFound) {
+		return nil, gqlerror.Errorf("Request log not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get request by ID: %w", err)
+	}
+
+	for _, hit := range reqLog.SearchHits(expr) {
+		hits = append(hits, HTTPSearchHit{
+			Key:           hit.Key,
+			Start:         hit.Start,
+			End:           hit.End,
+			Snippet:       hit.Snippet,
+			SnippetOffset: hit.SnippetOffset,
+		})
+	}
+
+	return hits, nil
+}
+
+func (r *queryResolver) ExportHTTPRequestLogs(
+	ctx context.Context,
+	input HTTPRequestLogSelectionInput,
+) (*ExportHTTPRequestLogsResult, error) {
+	sel, err := selectionFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		reqLogs []reqlog.RequestLog
+		har     []byte
+	)
+
+	err = r.runExport(ctx, "HAR", func(ctx context.Context, progress *job.Progress) (err error) {
+		reqLogs, err = r.findExportedRequestLogs(ctx, sel, progress)
+		if err != nil {
+			return err
+		}
+
+		har, err = reqlog.ExportHAR(reqLogs)
+		if err != nil {
+			return fmt.Errorf("could not export request logs: %w", err)
+		}
+
+		progress.SetCompleted(len(reqLogs))
+
+		return nil
+	})
+	if errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &ExportHTTPRequestLogsResult{
+		Har:   string(har),
+		Count: len(reqLogs),
+	}, nil
+}
+
+func (r *queryResolver) ExportHTTPRequestLogEvidence(
+	ctx context.Context,
+	input HTTPRequestLogSelectionInput,
+	chained *bool,
+) (*ExportHTTPRequestLogEvidenceResult, error) {
+	sel, err := selectionFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		reqLogs  []reqlog.RequestLog
+		evidence []byte
+	)
+
+	err = r.runExport(ctx, "evidence", func(ctx context.Context, progress *job.Progress) (err error) {
+		reqLogs, err = r.findExportedRequestLogs(ctx, sel, progress)
+		if err != nil {
+			return err
+		}
+
+		evidence, err = reqlog.ExportEvidence(reqLogs, chained != nil && *chained)
+		if err != nil {
+			return fmt.Errorf("could not export evidence: %w", err)
+		}
+
+		progress.SetCompleted(len(reqLogs))
+
+		return nil
+	})
+	if errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &ExportHTTPRequestLogEvidenceResult{
+		Evidence: string(evidence),
+		Count:    len(reqLogs),
+	}, nil
+}
+
+// findExportedRequestLogs finds the selected request logs of an export, and
+// sets them as the total work of its job.
+func (r *queryResolver) findExportedRequestLogs(
+	ctx context.Context,
+	sel reqlog.Selection,
+	progress *job.Progress,
+) ([]reqlog.RequestLog, error) {
+	reqLogs, err := r.RequestLogService.FindSelectedRequests(ctx, sel)
+	if err != nil {
+		return nil, fmt.Errorf("could not find request logs: %w", err)
+	}
+
+	progress.SetTotal(len(reqLogs))
+
+	if err := progress.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return reqLogs, nil
+}
+
 func parseRequestLog(reqLog reqlog.RequestLog) (HTTPRequestLog, error) {
 	method := HTTPMethod(reqLog.Method)
 	if method != "" && !method.IsValid() {
@@ -93,18 +334,81 @@ func parseRequestLog(reqLog reqlog.RequestLog) (HTTPRequestLog, error) {
 		ID:        reqLog.ID,
 		Proto:     reqLog.Proto,
 		Method:    method,
+		BodySize:  len(reqLog.Body),
 		Timestamp: ulid.Time(reqLog.ID.Time()),
+		Retries:   reqLog.Retries,
 	}
 
 	if reqLog.URL != nil {
 		log.URL = reqLog.URL.String()
 	}
 
+	if reqLog.OriginalURL != nil {
+		originalURL := reqLog.OriginalURL.String()
+		log.OriginalURL = &originalURL
+	}
+
+	if reqLog.RedirectFromID.Compare(ulid.ULID{}) != 0 {
+		redirectFromID := reqLog.RedirectFromID
+		log.RedirectFromID = &redirectFromID
+	}
+
+	if reqLog.CorrelationID.Compare(ulid.ULID{}) != 0 {
+		correlationID := reqLog.CorrelationID
+		log.CorrelationID = &correlationID
+	}
+
+	if reqLog.PageLoadID.Compare(ulid.ULID{}) != 0 {
+		pageLoadID := reqLog.PageLoadID
+		log.PageLoadID = &pageLoadID
+	}
+
 	if len(reqLog.Body) > 0 {
 		bodyStr := string(reqLog.Body)
 		log.Body = &bodyStr
 	}
 
+	if reqLog.Error != "" {
+		reqErr := reqLog.Error
+		log.Error = &reqErr
+	}
+
+	if remoteIP := reqLog.ServerIP(); remoteIP != "" {
+		log.RemoteIP = &remoteIP
+	}
+
+	if reqLog.ClientAddr != "" {
+		clientAddr := reqLog.ClientAddr
+		log.ClientAddr = &clientAddr
+	}
+
+	log.Device = parseDevice(reqLog.Device)
+
+	log.Tags = reqLog.Tags
+	if log.Tags == nil {
+		log.Tags = []string{}
+	}
+
+	log.BodyViews = make([]HTTPBodyView, len(reqLog.BodyViews))
+	for i, view := range reqLog.BodyViews {
+		log.BodyViews[i] = parseBodyView(view)
+	}
+
+	log.Columns = []HTTPRequestLogColumnValue{}
+
+	if reqLog.Raw != nil {
+		log.Raw = &HTTPRawExchange{
+			UpstreamRequest:  hex.EncodeToString(reqLog.Raw.UpstreamRequest),
+			UpstreamResponse: hex.EncodeToString(reqLog.Raw.UpstreamResponse),
+			Truncated:        reqLog.Raw.Truncated,
+		}
+
+		if reqLog.Raw.ClientRequest != nil {
+			clientReq := hex.EncodeToString(reqLog.Raw.ClientRequest)
+			log.Raw.ClientRequest = &clientReq
+		}
+	}
+
 	if reqLog.Header != nil {
 		log.Headers = make([]HTTPHeader, 0)
 
@@ -132,6 +436,23 @@ func parseRequestLog(reqLog reqlog.RequestLog) (HTTPRequestLog, error) {
 	return log, nil
 }
 
+func parseDevice(device reqlog.Device) *HTTPClientDevice {
+	// Request logs stored before devices were classified have no type.
+	deviceType := HTTPClientDeviceTypeUnknown
+	if device.Type != "" {
+		deviceType = HTTPClientDeviceType(strings.ToUpper(string(device.Type)))
+	}
+
+	apiDevice := &HTTPClientDevice{Type: deviceType}
+
+	if device.OS != "" {
+		deviceOS := device.OS
+		apiDevice.Os = &deviceOS
+	}
+
+	return apiDevice
+}
+
 func parseResponseLog(resLog reqlog.ResponseLog) (HTTPResponseLog, error) {
 	proto := httpProtocolMap[resLog.Proto]
 	if !proto.IsValid() {
@@ -139,8 +460,10 @@ func parseResponseLog(resLog reqlog.ResponseLog) (HTTPResponseLog, error) {
 	}
 
 	httpResLog := HTTPResponseLog{
-		Proto:      proto,
-		StatusCode: resLog.StatusCode,
+		Proto:       proto,
+		StatusCode:  resLog.StatusCode,
+		BodySize:    len(resLog.Body),
+		BodyOmitted: resLog.BodyOmitted,
 	}
 	statusReasonSubs := strings.SplitN(resLog.Status, " ", 2)
 
@@ -166,6 +489,25 @@ func parseResponseLog(resLog reqlog.ResponseLog) (HTTPResponseLog, error) {
 		}
 	}
 
+	if resLog.TLS != nil {
+		httpResLog.TLS = &TLSInfo{
+			Version:                 resLog.TLS.VersionName(),
+			CipherSuite:             resLog.TLS.CipherSuiteName(),
+			CertificateFingerprints: make([]string, len(resLog.TLS.CertFingerprints)),
+			Weak:                    resLog.TLS.IsWeak(),
+		}
+
+		copy(httpResLog.TLS.CertificateFingerprints, resLog.TLS.CertFingerprints)
+
+		if resLog.TLS.ALPN != "" {
+			httpResLog.TLS.Alpn = &resLog.TLS.ALPN
+		}
+
+		if resLog.TLS.ServerName != "" {
+			httpResLog.TLS.ServerName = &resLog.TLS.ServerName
+		}
+	}
+
 	return httpResLog, nil
 }
 
@@ -178,14 +520,20 @@ func (r *mutationResolver) CreateProject(ctx context.Context, name string) (*Pro
 	}
 
 	return &Project{
-		ID:       p.ID,
-		Name:     p.Name,
-		IsActive: r.ProjectService.IsProjectActive(p.ID),
+		ID:         p.ID,
+		Name:       p.Name,
+		IsActive:   r.ProjectService.IsProjectActive(p.ID),
+		IsReadOnly: r.ProjectService.IsProjectActive(p.ID) && r.ProjectService.IsReadOnly(),
 	}, nil
 }
 
-func (r *mutationResolver) OpenProject(ctx context.Context, id ulid.ULID) (*Project, error) {
-	p, err := r.ProjectService.OpenProject(ctx, id)
+func (r *mutationResolver) OpenProject(ctx context.Context, id ulid.ULID, readOnly *bool) (*Project, error) {
+	openProject := r.ProjectService.OpenProject
+	if readOnly != nil && *readOnly {
+		openProject = r.ProjectService.OpenProjectReadOnly
+	}
+
+	p, err := openProject(ctx, id)
 	if errors.Is(err, proj.ErrInvalidName) {
 		return nil, gqlerror.Errorf("Project name must only contain alphanumeric or space chars.")
 	} else if err != nil {
@@ -193,12 +541,105 @@ func (r *mutationResolver) OpenProject(ctx context.Context, id ulid.ULID) (*Proj
 	}
 
 	return &Project{
-		ID:       p.ID,
-		Name:     p.Name,
-		IsActive: r.ProjectService.IsProjectActive(p.ID),
+		ID:         p.ID,
+		Name:       p.Name,
+		IsActive:   r.ProjectService.IsProjectActive(p.ID),
+		IsReadOnly: r.ProjectService.IsProjectActive(p.ID) && r.ProjectService.IsReadOnly(),
 	}, nil
 }
 
+func (r *queryResolver) ClientRoutes(ctx context.Context) ([]ClientRoute, error) {
+	return parseClientRoutes(r.ProjectService.ClientRoutes()), nil
+}
+
+func (r *mutationResolver) SetClientRoutes(ctx context.Context, input []ClientRouteInput) ([]ClientRoute, error) {
+	routes := make([]proj.ClientRoute, len(input))
+
+	for i, route := range input {
+		routes[i] = proj.ClientRoute{ProjectID: route.ProjectID}
+
+		if route.ClientIP != nil {
+			routes[i].ClientIP = *route.ClientIP
+		}
+
+		if route.Username != nil {
+			routes[i].Username = *route.Username
+		}
+	}
+
+	err := r.ProjectService.SetClientRoutes(ctx, routes)
+	switch {
+	case errors.Is(err, proj.ErrProjectNotFound):
+		return nil, gqlerror.Errorf("Project not found.")
+	case errors.Is(err, reqlog.ErrInvalidClientRoute):
+		return nil, gqlerror.Errorf("Invalid client route: a valid client IP or username must be set.")
+	case err != nil:
+		return nil, fmt.Errorf("could not set client routes: %w", err)
+	}
+
+	return parseClientRoutes(r.ProjectService.ClientRoutes()), nil
+}
+
+func parseClientRoutes(routes []proj.ClientRoute) []ClientRoute {
+	clientRoutes := make([]ClientRoute, len(routes))
+
+	for i, route := range routes {
+		clientRoutes[i] = ClientRoute{ProjectID: route.ProjectID}
+
+		if route.ClientIP != "" {
+			clientRoutes[i].ClientIP = &routes[i].ClientIP
+		}
+
+		if route.Username != "" {
+			clientRoutes[i].Username = &routes[i].Username
+		}
+	}
+
+	return clientRoutes
+}
+
+func (r *queryResolver) CaptureStatus(ctx context.Context) (*CaptureStatus, error) {
+	return parseCaptureStatus(r.RequestLogService), nil
+}
+
+func (r *mutationResolver) SetCapturePaused(ctx context.Context, paused bool) (*CaptureStatus, error) {
+	r.RequestLogService.SetCapturePaused(paused)
+
+	return parseCaptureStatus(r.RequestLogService), nil
+}
+
+func (r *mutationResolver) SetProjectCapturePaused(
+	ctx context.Context,
+	paused bool,
+	projectID *ulid.ULID,
+) (*CaptureStatus, error) {
+	var id ulid.ULID
+	if projectID != nil {
+		id = *projectID
+	}
+
+	err := r.ProjectService.SetCapturePaused(ctx, id, paused)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, proj.ErrProjectNotFound):
+		return nil, gqlerror.Errorf("Project not found.")
+	case errors.Is(err, proj.ErrReadOnly):
+		return nil, gqlerror.Errorf("Project is opened read-only.")
+	case err != nil:
+		return nil, fmt.Errorf("could not set capture status: %w", err)
+	}
+
+	return parseCaptureStatus(r.RequestLogService), nil
+}
+
+func parseCaptureStatus(svc reqlog.Service) *CaptureStatus {
+	return &CaptureStatus{
+		Paused:        svc.CapturePaused(),
+		ProjectPaused: svc.ProjectCapturePaused(svc.ActiveProjectID()),
+	}
+}
+
 func (r *queryResolver) ActiveProject(ctx context.Context) (*Project, error) {
 	p, err := r.ProjectService.ActiveProject(ctx)
 	if errors.Is(err, proj.ErrNoProject) {
@@ -208,9 +649,10 @@ func (r *queryResolver) ActiveProject(ctx context.Context) (*Project, error) {
 	}
 
 	return &Project{
-		ID:       p.ID,
-		Name:     p.Name,
-		IsActive: r.ProjectService.IsProjectActive(p.ID),
+		ID:         p.ID,
+		Name:       p.Name,
+		IsActive:   r.ProjectService.IsProjectActive(p.ID),
+		IsReadOnly: r.ProjectService.IsProjectActive(p.ID) && r.ProjectService.IsReadOnly(),
 	}, nil
 }
 
@@ -223,9 +665,10 @@ func (r *queryResolver) Projects(ctx context.Context) ([]Project, error) {
 	projects := make([]Project, len(p))
 	for i, proj := range p {
 		projects[i] = Project{
-			ID:       proj.ID,
-			Name:     proj.Name,
-			IsActive: r.ProjectService.IsProjectActive(proj.ID),
+			ID:         proj.ID,
+			Name:       proj.Name,
+			IsActive:   r.ProjectService.IsProjectActive(proj.ID),
+			IsReadOnly: r.ProjectService.IsProjectActive(proj.ID) && r.ProjectService.IsReadOnly(),
 		}
 	}
 
@@ -277,9 +720,141 @@ func (r *mutationResolver) ClearHTTPRequestLog(ctx context.Context) (*ClearHTTPR
 		return nil, fmt.Errorf("could not clear request log: %w", err)
 	}
 
+	// Findings relate to request logs, so they are cleared as well.
+	if err := r.FindingService.ClearFindings(ctx, project.ID); err != nil {
+		return nil, fmt.Errorf("could not clear findings: %w", err)
+	}
+
 	return &ClearHTTPRequestLogResult{true}, nil
 }
 
+func (r *mutationResolver) TagHTTPRequestLogs(
+	ctx context.Context,
+	input HTTPRequestLogSelectionInput,
+	add, remove []string,
+) (*BulkHTTPRequestLogsResult, error) {
+	sel, err := selectionFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := r.RequestLogService.TagRequests(ctx, sel, add, remove)
+	if errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not tag request logs: %w", err)
+	}
+
+	return &BulkHTTPRequestLogsResult{Count: n}, nil
+}
+
+func (r *mutationResolver) DeleteHTTPRequestLogs(
+	ctx context.Context,
+	input HTTPRequestLogSelectionInput,
+) (*BulkHTTPRequestLogsResult, error) {
+	sel, err := selectionFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := r.RequestLogService.DeleteRequests(ctx, sel)
+	if errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not delete request logs: %w", err)
+	}
+
+	return &BulkHTTPRequestLogsResult{Count: n}, nil
+}
+
+func (r *mutationResolver) SetHTTPBodyView(
+	ctx context.Context,
+	requestLogID ulid.ULID,
+	input HTTPBodyViewInput,
+) (*HTTPBodyView, error) {
+	view := reqlog.BodyView{
+		Name:       input.Name,
+		Source:     reqlog.BodySource(strings.ToLower(input.Source.String())),
+		Transforms: make([]transform.Transform, len(input.Transforms)),
+	}
+
+	if input.Start != nil {
+		view.Start = *input.Start
+	}
+
+	if input.End != nil {
+		view.End = *input.End
+	}
+
+	for i, transformType := range input.Transforms {
+		t, err := transform.Parse(transformType.String())
+		if err != nil {
+			return nil, fmt.Errorf("could not parse transform type: %w", err)
+		}
+
+		view.Transforms[i] = t
+	}
+
+	view, err := r.RequestLogService.SetBodyView(ctx, requestLogID, view)
+	switch {
+	case errors.Is(err, reqlog.ErrProjectIDMustBeSet):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, reqlog.ErrReadOnly):
+		return nil, gqlerror.Errorf("Project is opened read-only.")
+	case errors.Is(err, reqlog.ErrRequestNotFound):
+		return nil, gqlerror.Errorf("Request log not found.")
+	case errors.Is(err, reqlog.ErrInvalidBodyView):
+		return nil, gqlerror.Errorf("Could not set body view: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not set body view: %w", err)
+	}
+
+	result := parseBodyView(view)
+
+	return &result, nil
+}
+
+func (r *mutationResolver) DeleteHTTPBodyView(
+	ctx context.Context,
+	requestLogID ulid.ULID,
+	name string,
+) (*DeleteHTTPBodyViewResult, error) {
+	err := r.RequestLogService.DeleteBodyView(ctx, requestLogID, name)
+	switch {
+	case errors.Is(err, reqlog.ErrProjectIDMustBeSet):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, reqlog.ErrReadOnly):
+		return nil, gqlerror.Errorf("Project is opened read-only.")
+	case errors.Is(err, reqlog.ErrRequestNotFound):
+		return nil, gqlerror.Errorf("Request log not found.")
+	case errors.Is(err, reqlog.ErrBodyViewNotFound):
+		return nil, gqlerror.Errorf("Body view not found.")
+	case err != nil:
+		return nil, fmt.Errorf("could not delete body view: %w", err)
+	}
+
+	return &DeleteHTTPBodyViewResult{Success: true}, nil
+}
+
+func parseBodyView(view reqlog.BodyView) HTTPBodyView {
+	result := HTTPBodyView{
+		Name:         view.Name,
+		Source:       HTTPBodySource(strings.ToUpper(string(view.Source))),
+		Start:        view.Start,
+		End:          view.End,
+		Transforms:   make([]TransformType, len(view.Transforms)),
+		Output:       string(view.Output),
+		OutputBase64: base64.StdEncoding.EncodeToString(view.Output),
+		CreatedAt:    view.CreatedAt,
+	}
+
+	for i, t := range view.Transforms {
+		result.Transforms[i] = TransformType(strings.ToUpper(t.String()))
+	}
+
+	return result
+}
+
 func (r *mutationResolver) SetScope(ctx context.Context, input []ScopeRuleInput) ([]ScopeRule, error) {
 	rules := make([]scope.Rule, len(input))
 
@@ -326,242 +901,3978 @@ func (r *mutationResolver) SetScope(ctx context.Context, input []ScopeRuleInput)
 	return scopeToScopeRules(rules), nil
 }
 
-func (r *queryResolver) HTTPRequestLogFilter(ctx context.Context) (*HTTPRequestLogFilter, error) {
-	return findReqFilterToHTTPReqLogFilter(r.RequestLogService.FindReqsFilter()), nil
+func (r *queryResolver) ResponseRewritePresets(ctx context.Context) (*ResponseRewritePresets, error) {
+	return parseRewritePresets(r.ProjectService.Rewriter().Presets()), nil
 }
 
-func (r *mutationResolver) SetHTTPRequestLogFilter(
+func (r *mutationResolver) SetResponseRewritePresets(
 	ctx context.Context,
-	input *HTTPRequestLogFilterInput,
-) (*HTTPRequestLogFilter, error) {
-	filter, err := findRequestsFilterFromInput(input)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse request log filter: %w", err)
+	input ResponseRewritePresetsInput,
+) (*ResponseRewritePresets, error) {
+	presets := rewrite.Presets{
+		StripCSP:               input.StripCsp,
+		StripHSTS:              input.StripHsts,
+		RemoveSecureCookieFlag: input.RemoveSecureCookieFlag,
 	}
 
-	err = r.ProjectService.SetRequestLogFindFilter(ctx, filter)
+	if input.InjectScript != nil {
+		presets.InjectScript = *input.InjectScript
+	}
+
+	err := r.ProjectService.SetRewritePresets(ctx, presets)
 	if errors.Is(err, proj.ErrNoProject) {
 		return nil, noActiveProjectErr(ctx)
 	} else if err != nil {
-		return nil, fmt.Errorf("could not set request log filter: %w", err)
+		return nil, fmt.Errorf("could not set response rewrite presets: %w", err)
 	}
 
-	return findReqFilterToHTTPReqLogFilter(filter), nil
+	return parseRewritePresets(presets), nil
 }
 
-func (r *queryResolver) SenderRequest(ctx context.Context, id ulid.ULID) (*SenderRequest, error) {
-	senderReq, err := r.SenderService.FindRequestByID(ctx, id)
-	if errors.Is(err, sender.ErrRequestNotFound) {
-		return nil, nil
-	} else if err != nil {
-		return nil, fmt.Errorf("could not get request by ID: %w", err)
+func parseRewritePresets(presets rewrite.Presets) *ResponseRewritePresets {
+	rewritePresets := &ResponseRewritePresets{
+		StripCsp:               presets.StripCSP,
+		StripHsts:              presets.StripHSTS,
+		RemoveSecureCookieFlag: presets.RemoveSecureCookieFlag,
 	}
 
-	req, err := parseSenderRequest(senderReq)
-	if err != nil {
+	if presets.InjectScript != "" {
+		rewritePresets.InjectScript = &presets.InjectScript
+	}
+
+	return rewritePresets
+}
+
+func (r *queryResolver) RewriteProfiles(ctx context.Context) (*RewriteProfiles, error) {
+	return parseRewriteProfiles(r.ProjectService.Rewriter().Profiles()), nil
+}
+
+func (r *mutationResolver) SetRewriteProfiles(
+	ctx context.Context,
+	input []RewriteProfileInput,
+	active *string,
+) (*RewriteProfiles, error) {
+	profiles := rewrite.Profiles{
+		Profiles: make([]rewrite.Profile, len(input)),
+	}
+
+	if active != nil {
+		profiles.Active = *active
+	}
+
+	for i, profileInput := range input {
+		profiles.Profiles[i] = rewrite.Profile{
+			Name:    profileInput.Name,
+			Hosts:   hostRulesFromInput(profileInput.HostRules),
+			Headers: headerFromInput(profileInput.Headers),
+		}
+	}
+
+	err := r.ProjectService.SetRewriteProfiles(ctx, profiles)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, rewrite.ErrInvalidProfiles):
+		return nil, gqlerror.Errorf("Could not set rewrite profiles: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not set rewrite profiles: %w", err)
+	}
+
+	return parseRewriteProfiles(profiles), nil
+}
+
+func hostRulesFromInput(input []RewriteHostRuleInput) []rewrite.HostRule {
+	if len(input) == 0 {
+		return nil
+	}
+
+	rules := make([]rewrite.HostRule, len(input))
+
+	for i, ruleInput := range input {
+		rules[i] = rewrite.HostRule{
+			From: ruleInput.From,
+			To:   ruleInput.To,
+		}
+
+		if ruleInput.Scheme != nil {
+			rules[i].Scheme = *ruleInput.Scheme
+		}
+	}
+
+	return rules
+}
+
+func headerFromInput(input []HTTPHeaderInput) http.Header {
+	if len(input) == 0 {
+		return nil
+	}
+
+	header := make(http.Header)
+
+	for _, h := range input {
+		header.Add(h.Key, h.Value)
+	}
+
+	return header
+}
+
+func (r *queryResolver) RewriteLocalMappings(ctx context.Context) ([]RewriteLocalMapping, error) {
+	return parseLocalMappings(r.ProjectService.Rewriter().LocalMappings()), nil
+}
+
+func (r *mutationResolver) SetRewriteLocalMappings(
+	ctx context.Context,
+	input []RewriteLocalMappingInput,
+) ([]RewriteLocalMapping, error) {
+	mappings := make(rewrite.LocalMappings, len(input))
+
+	for i, mappingInput := range input {
+		mappings[i] = rewrite.LocalMapping{URL: mappingInput.URL}
+
+		if mappingInput.File != nil {
+			mappings[i].File = *mappingInput.File
+		}
+
+		if mappingInput.Content != nil {
+			mappings[i].Content = *mappingInput.Content
+		}
+
+		if mappingInput.ContentType != nil {
+			mappings[i].ContentType = *mappingInput.ContentType
+		}
+
+		if mappingInput.StatusCode != nil {
+			mappings[i].StatusCode = *mappingInput.StatusCode
+		}
+	}
+
+	err := r.ProjectService.SetRewriteLocalMappings(ctx, mappings)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, proj.ErrReadOnly):
+		return nil, gqlerror.Errorf("Project is opened read-only.")
+	case errors.Is(err, rewrite.ErrInvalidLocalMappings):
+		return nil, gqlerror.Errorf("Could not set local mappings: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not set local mappings: %w", err)
+	}
+
+	return parseLocalMappings(mappings), nil
+}
+
+func parseLocalMappings(mappings rewrite.LocalMappings) []RewriteLocalMapping {
+	localMappings := make([]RewriteLocalMapping, len(mappings))
+
+	for i, mapping := range mappings {
+		localMappings[i] = RewriteLocalMapping{
+			URL:        mapping.URL,
+			StatusCode: mapping.StatusCode,
+		}
+
+		if localMappings[i].StatusCode == 0 {
+			localMappings[i].StatusCode = http.StatusOK
+		}
+
+		if mapping.File != "" {
+			localMappings[i].File = &mappings[i].File
+		}
+
+		if mapping.Content != "" {
+			localMappings[i].Content = &mappings[i].Content
+		}
+
+		if mapping.ContentType != "" {
+			localMappings[i].ContentType = &mappings[i].ContentType
+		}
+	}
+
+	return localMappings
+}
+
+func (r *queryResolver) RewriteRemoteMappings(ctx context.Context) ([]RewriteRemoteMapping, error) {
+	return parseRemoteMappings(r.ProjectService.Rewriter().RemoteMappings()), nil
+}
+
+func (r *mutationResolver) SetRewriteRemoteMappings(
+	ctx context.Context,
+	input []RewriteRemoteMappingInput,
+) ([]RewriteRemoteMapping, error) {
+	mappings := make(rewrite.RemoteMappings, len(input))
+
+	for i, mappingInput := range input {
+		mappings[i] = rewrite.RemoteMapping{URL: mappingInput.URL}
+
+		if mappingInput.Scheme != nil {
+			mappings[i].Scheme = *mappingInput.Scheme
+		}
+
+		if mappingInput.Host != nil {
+			mappings[i].Host = *mappingInput.Host
+		}
+
+		if mappingInput.Port != nil {
+			mappings[i].Port = *mappingInput.Port
+		}
+
+		if mappingInput.Path != nil {
+			mappings[i].Path = *mappingInput.Path
+		}
+	}
+
+	err := r.ProjectService.SetRewriteRemoteMappings(ctx, mappings)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, proj.ErrReadOnly):
+		return nil, gqlerror.Errorf("Project is opened read-only.")
+	case errors.Is(err, rewrite.ErrInvalidRemoteMappings):
+		return nil, gqlerror.Errorf("Could not set remote mappings: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not set remote mappings: %w", err)
+	}
+
+	return parseRemoteMappings(mappings), nil
+}
+
+func parseRemoteMappings(mappings rewrite.RemoteMappings) []RewriteRemoteMapping {
+	remoteMappings := make([]RewriteRemoteMapping, len(mappings))
+
+	for i, mapping := range mappings {
+		remoteMappings[i] = RewriteRemoteMapping{URL: mapping.URL}
+
+		if mapping.Scheme != "" {
+			remoteMappings[i].Scheme = &mappings[i].Scheme
+		}
+
+		if mapping.Host != "" {
+			remoteMappings[i].Host = &mappings[i].Host
+		}
+
+		if mapping.Port != 0 {
+			remoteMappings[i].Port = &mappings[i].Port
+		}
+
+		if mapping.Path != "" {
+			remoteMappings[i].Path = &mappings[i].Path
+		}
+	}
+
+	return remoteMappings
+}
+
+func parseRewriteProfiles(profiles rewrite.Profiles) *RewriteProfiles {
+	rewriteProfiles := &RewriteProfiles{
+		Profiles: make([]RewriteProfile, len(profiles.Profiles)),
+	}
+
+	if profiles.Active != "" {
+		rewriteProfiles.Active = &profiles.Active
+	}
+
+	for i, profile := range profiles.Profiles {
+		rewriteProfile := RewriteProfile{
+			Name:      profile.Name,
+			HostRules: make([]RewriteHostRule, len(profile.Hosts)),
+			Headers:   make([]HTTPHeader, 0, len(profile.Headers)),
+		}
+
+		for j, rule := range profile.Hosts {
+			rewriteProfile.HostRules[j] = RewriteHostRule{From: rule.From, To: rule.To}
+
+			if rule.Scheme != "" {
+				rewriteProfile.HostRules[j].Scheme = &profiles.Profiles[i].Hosts[j].Scheme
+			}
+		}
+
+		for key, values := range profile.Headers {
+			for _, value := range values {
+				rewriteProfile.Headers = append(rewriteProfile.Headers, HTTPHeader{Key: key, Value: value})
+			}
+		}
+
+		rewriteProfiles.Profiles[i] = rewriteProfile
+	}
+
+	return rewriteProfiles
+}
+
+func (r *queryResolver) HTTPResponseBodyRules(ctx context.Context) (*HTTPResponseBodyRules, error) {
+	return parseBodyRules(r.RequestLogService.BodyRules()), nil
+}
+
+func (r *mutationResolver) SetHTTPResponseBodyRules(
+	ctx context.Context,
+	input HTTPResponseBodyRulesInput,
+) (*HTTPResponseBodyRules, error) {
+	rules := reqlog.BodyRules{
+		OmitOutOfScope:   input.OmitOutOfScope,
+		OmitStatic:       input.OmitStatic,
+		OmitContentTypes: input.OmitContentTypes,
+	}
+
+	if input.MaxSize != nil {
+		if *input.MaxSize < 0 {
+			return nil, gqlerror.Errorf("Maximum body size must not be negative.")
+		}
+
+		rules.MaxSize = *input.MaxSize
+	}
+
+	err := r.ProjectService.SetRequestLogBodyRules(ctx, rules)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not set response body rules: %w", err)
+	}
+
+	return parseBodyRules(rules), nil
+}
+
+func parseBodyRules(rules reqlog.BodyRules) *HTTPResponseBodyRules {
+	bodyRules := &HTTPResponseBodyRules{
+		OmitOutOfScope:   rules.OmitOutOfScope,
+		OmitStatic:       rules.OmitStatic,
+		OmitContentTypes: rules.OmitContentTypes,
+		MaxSize:          rules.MaxSize,
+	}
+
+	if bodyRules.OmitContentTypes == nil {
+		bodyRules.OmitContentTypes = []string{}
+	}
+
+	return bodyRules
+}
+
+func (r *queryResolver) HTTPRequestLogSampling(ctx context.Context) (*HTTPRequestLogSampling, error) {
+	return parseSampling(r.RequestLogService.Sampling()), nil
+}
+
+func (r *mutationResolver) SetHTTPRequestLogSampling(
+	ctx context.Context,
+	input HTTPRequestLogSamplingInput,
+) (*HTTPRequestLogSampling, error) {
+	var sampling reqlog.Sampling
+
+	if input.Rate != nil {
+		sampling.Rate = *input.Rate
+	}
+
+	if input.PerEndpoint != nil {
+		sampling.PerEndpoint = *input.PerEndpoint
+	}
+
+	err := r.ProjectService.SetRequestLogSampling(ctx, sampling)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, proj.ErrReadOnly):
+		return nil, gqlerror.Errorf("Project is opened read-only.")
+	case errors.Is(err, reqlog.ErrInvalidSampling):
+		return nil, gqlerror.Errorf("Sampling rate and requests per endpoint must not be negative.")
+	case err != nil:
+		return nil, fmt.Errorf("could not set request log sampling: %w", err)
+	}
+
+	return parseSampling(sampling), nil
+}
+
+func parseSampling(sampling reqlog.Sampling) *HTTPRequestLogSampling {
+	return &HTTPRequestLogSampling{
+		Rate:        sampling.Rate,
+		PerEndpoint: sampling.PerEndpoint,
+	}
+}
+
+func (r *queryResolver) HTTPRequestLogColumns(ctx context.Context) ([]HTTPRequestLogColumn, error) {
+	return parseColumns(r.RequestLogService.Columns()), nil
+}
+
+func (r *queryResolver) HTTPRequestLogSearchMetadata(ctx context.Context) (*HTTPRequestLogSearchMetadata, error) {
+	metadata, err := r.RequestLogService.SearchMetadata(ctx)
+	if errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get search metadata: %w", err)
+	}
+
+	keys := make([]string, 0, len(metadata.Values))
+	for key := range metadata.Values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	values := make([]HTTPRequestLogSearchKeyValues, len(keys))
+
+	for i, key := range keys {
+		keyValues := make([]HTTPRequestLogSearchValue, len(metadata.Values[key]))
+		for j, v := range metadata.Values[key] {
+			keyValues[j] = HTTPRequestLogSearchValue{Value: v.Value, Count: v.Count}
+		}
+
+		values[i] = HTTPRequestLogSearchKeyValues{Key: key, Values: keyValues}
+	}
+
+	return &HTTPRequestLogSearchMetadata{
+		Keys:        metadata.Keys,
+		KeyPrefixes: metadata.KeyPrefixes,
+		Operators:   metadata.Operators,
+		Values:      values,
+	}, nil
+}
+
+func (r *mutationResolver) SetHTTPRequestLogColumns(
+	ctx context.Context,
+	input []HTTPRequestLogColumnInput,
+) ([]HTTPRequestLogColumn, error) {
+	columns := make([]reqlog.Column, len(input))
+
+	for i, column := range input {
+		expr, err := reqlog.ParseSearchExpr(column.Expression)
+		if err != nil {
+			return nil, searchExprErr(fmt.Sprintf("Invalid expression of column %q", column.Name), err)
+		}
+
+		columns[i] = reqlog.Column{
+			Name:       strings.TrimSpace(column.Name),
+			Expression: expr,
+		}
+	}
+
+	err := r.ProjectService.SetRequestLogColumns(ctx, columns)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, proj.ErrReadOnly):
+		return nil, gqlerror.Errorf("Project is opened read-only.")
+	case errors.Is(err, reqlog.ErrInvalidColumn):
+		return nil, gqlerror.Errorf("Invalid columns: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not set request log columns: %w", err)
+	}
+
+	return parseColumns(columns), nil
+}
+
+func parseColumns(columns []reqlog.Column) []HTTPRequestLogColumn {
+	httpColumns := make([]HTTPRequestLogColumn, len(columns))
+
+	for i, column := range columns {
+		httpColumns[i] = HTTPRequestLogColumn{
+			Name:       column.Name,
+			Expression: column.Expression.String(),
+		}
+	}
+
+	return httpColumns
+}
+
+// parseColumnValues returns the values of the custom columns for a request log.
+func parseColumnValues(reqLog reqlog.RequestLog, columns []reqlog.Column) []HTTPRequestLogColumnValue {
+	values := reqLog.ColumnValues(columns)
+	httpValues := make([]HTTPRequestLogColumnValue, len(values))
+
+	for i, value := range values {
+		httpValues[i] = HTTPRequestLogColumnValue{Name: value.Name, Value: value.Value}
+	}
+
+	return httpValues
+}
+
+func (r *queryResolver) HTTPRequestLogStoreStats(ctx context.Context) (*HTTPRequestLogStoreStats, error) {
+	stats := r.RequestLogService.StoreStats()
+
+	return &HTTPRequestLogStoreStats{
+		Queued:    stats.Queued,
+		QueueSize: stats.QueueSize,
+		Workers:   stats.Workers,
+		Stored:    int(stats.Stored),
+		Failed:    int(stats.Failed),
+		Blocked:   int(stats.Blocked),
+	}, nil
+}
+
+func (r *queryResolver) HTTPRequestLogFilter(ctx context.Context) (*HTTPRequestLogFilter, error) {
+	return findReqFilterToHTTPReqLogFilter(r.RequestLogService.FindReqsFilter()), nil
+}
+
+func (r *mutationResolver) SetHTTPRequestLogFilter(
+	ctx context.Context,
+	input *HTTPRequestLogFilterInput,
+) (*HTTPRequestLogFilter, error) {
+	filter, err := findRequestsFilterFromInput(input)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse request log filter: %w", err)
+	}
+
+	err = r.ProjectService.SetRequestLogFindFilter(ctx, filter)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not set request log filter: %w", err)
+	}
+
+	return findReqFilterToHTTPReqLogFilter(filter), nil
+}
+
+func (r *queryResolver) SenderRequest(ctx context.Context, id ulid.ULID) (*SenderRequest, error) {
+	senderReq, err := r.SenderService.FindRequestByID(ctx, id)
+	if errors.Is(err, sender.ErrRequestNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get request by ID: %w", err)
+	}
+
+	req, err := parseSenderRequest(senderReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+func (r *queryResolver) SenderRequests(ctx context.Context) ([]SenderRequest, error) {
+	reqs, err := r.SenderService.FindRequests(ctx)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find sender requests: %w", err)
+	}
+
+	senderReqs := make([]SenderRequest, len(reqs))
+
+	for i, req := range reqs {
+		req, err := parseSenderRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		senderReqs[i] = req
+	}
+
+	return senderReqs, nil
+}
+
+func (r *mutationResolver) SetSenderRequestFilter(
+	ctx context.Context,
+	input *SenderRequestFilterInput,
+) (*SenderRequestFilter, error) {
+	filter, err := findSenderRequestsFilterFromInput(input)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse request log filter: %w", err)
+	}
+
+	err = r.ProjectService.SetSenderRequestFindFilter(ctx, filter)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not set request log filter: %w", err)
+	}
+
+	return findReqFilterToSenderReqFilter(filter), nil
+}
+
+func (r *mutationResolver) CreateOrUpdateSenderRequest(ctx context.Context, input SenderRequestInput) (*SenderRequest, error) {
+	req := sender.Request{
+		URL:    input.URL,
+		Header: make(http.Header),
+	}
+
+	if input.ID != nil {
+		req.ID = *input.ID
+	}
+
+	if input.Method != nil {
+		req.Method = input.Method.String()
+	}
+
+	if input.Proto != nil {
+		req.Proto = revHTTPProtocolMap[*input.Proto]
+	}
+
+	for _, header := range input.Headers {
+		req.Header.Add(header.Key, header.Value)
+	}
+
+	if input.Body != nil {
+		req.Body = []byte(*input.Body)
+	}
+
+	if input.Raw != nil {
+		req.Raw = []byte(*input.Raw)
+	}
+
+	if input.PreRequestScript != nil {
+		req.PreRequestScript = *input.PreRequestScript
+	}
+
+	if input.PostResponseScript != nil {
+		req.PostResponseScript = *input.PostResponseScript
+	}
+
+	for _, assertion := range input.Assertions {
+		senderAssertion := sender.Assertion{
+			Type:  revSenderAssertionTypeMap[assertion.Type],
+			Value: assertion.Value,
+		}
+
+		if assertion.Path != nil {
+			senderAssertion.Path = *assertion.Path
+		}
+
+		req.Assertions = append(req.Assertions, senderAssertion)
+	}
+
+	req, err := r.SenderService.CreateOrUpdateRequest(ctx, req)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if errors.Is(err, sender.ErrInvalidScript) || errors.Is(err, sender.ErrInvalidAssertions) {
+		return nil, gqlerror.Errorf("Could not create sender request: %v", err)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not create sender request: %w", err)
+	}
+
+	senderReq, err := parseSenderRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &senderReq, nil
+}
+
+func (r *mutationResolver) CreateSenderRequestFromHTTPRequestLog(ctx context.Context, id ulid.ULID) (*SenderRequest, error) {
+	req, err := r.SenderService.CloneFromRequestLog(ctx, id)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not create sender request from http request log: %w", err)
+	}
+
+	senderReq, err := parseSenderRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &senderReq, nil
+}
+
+func (r *mutationResolver) RunHTTPRequestLogQuickActions(
+	ctx context.Context,
+	id ulid.ULID,
+	input HTTPRequestLogQuickActionsInput,
+) (*HTTPRequestLogQuickActionsResult, error) {
+	reqLog, err := r.RequestLogService.FindRequestLogByID(ctx, id)
+	if errors.Is(err, reqlog.ErrRequestNotFound) {
+		return nil, gqlerror.Errorf("Request log not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get request log: %w", err)
+	}
+
+	actions := proj.HostActions{
+		AddToScope: input.AddHostToScope != nil && *input.AddHostToScope,
+		Exclude:    input.ExcludeHost != nil && *input.ExcludeHost,
+	}
+
+	if actions.AddToScope || actions.Exclude {
+		err = r.ProjectService.ApplyHostActions(ctx, reqLog.Hostname(), actions)
+		switch {
+		case errors.Is(err, proj.ErrNoProject):
+			return nil, noActiveProjectErr(ctx)
+		case errors.Is(err, proj.ErrReadOnly):
+			return nil, gqlerror.Errorf("Project is opened read-only.")
+		case errors.Is(err, proj.ErrInvalidHostActions):
+			return nil, gqlerror.Errorf("Could not run quick actions: %v", err)
+		case err != nil:
+			return nil, fmt.Errorf("could not apply host actions: %w", err)
+		}
+	}
+
+	result := &HTTPRequestLogQuickActionsResult{
+		Scope:  scopeToScopeRules(r.ProjectService.Scope().Rules()),
+		Filter: findReqFilterToHTTPReqLogFilter(r.RequestLogService.FindReqsFilter()),
+	}
+
+	if input.SendToSender != nil && *input.SendToSender {
+		req, err := r.SenderService.CloneFromRequestLog(ctx, reqLog.ID)
+		if errors.Is(err, proj.ErrNoProject) {
+			return nil, noActiveProjectErr(ctx)
+		} else if err != nil {
+			return nil, fmt.Errorf("could not create sender request from http request log: %w", err)
+		}
+
+		senderReq, err := parseSenderRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		result.SenderRequest = &senderReq
+	}
+
+	return result, nil
+}
+
+func (r *mutationResolver) CreateSenderRequestsFromHTTPRequestLogs(
+	ctx context.Context,
+	input HTTPRequestLogSelectionInput,
+) ([]SenderRequest, error) {
+	sel, err := selectionFromInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	reqLogs, err := r.RequestLogService.FindSelectedRequests(ctx, sel)
+	if errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find request logs: %w", err)
+	}
+
+	senderReqs := make([]SenderRequest, len(reqLogs))
+
+	for i, reqLog := range reqLogs {
+		req, err := r.SenderService.CloneFromRequestLog(ctx, reqLog.ID)
+		if errors.Is(err, proj.ErrNoProject) {
+			return nil, noActiveProjectErr(ctx)
+		} else if err != nil {
+			return nil, fmt.Errorf("could not create sender request from http request log: %w", err)
+		}
+
+		senderReqs[i], err = parseSenderRequest(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return senderReqs, nil
+}
+
+func (r *mutationResolver) SendRequest(ctx context.Context, id ulid.ULID) (*SenderRequest, error) {
+	// Use new context, because we don't want to risk interrupting sending the request
+	// or the subsequent storing of the response, e.g. if ctx gets cancelled or
+	// times out.
+	ctx2 := context.Background()
+
+	var sendErr *sender.SendError
+
+	req, err := r.SenderService.SendRequest(ctx2, id)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if errors.As(err, &sendErr) {
+		return nil, &gqlerror.Error{
+			Path:    graphql.GetPath(ctx),
+			Message: fmt.Sprintf("Sending request failed: %v", sendErr.Unwrap()),
+			Extensions: map[string]interface{}{
+				"code": "send_request_failed",
+			},
+		}
+	} else if errors.Is(err, sender.ErrScriptFailed) || errors.Is(err, sender.ErrInvalidScript) {
+		return nil, &gqlerror.Error{
+			Path:    graphql.GetPath(ctx),
+			Message: fmt.Sprintf("Script failed: %v", err),
+			Extensions: map[string]interface{}{
+				"code": "script_failed",
+			},
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("could not send request: %w", err)
+	}
+
+	senderReq, err := parseSenderRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &senderReq, nil
+}
+
+func (r *mutationResolver) DeleteSenderRequests(ctx context.Context) (*DeleteSenderRequestsResult, error) {
+	project, err := r.ProjectService.ActiveProject(ctx)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active project: %w", err)
+	}
+
+	if err := r.SenderService.DeleteRequests(ctx, project.ID); err != nil {
+		return nil, fmt.Errorf("could not clear request log: %w", err)
+	}
+
+	return &DeleteSenderRequestsResult{true}, nil
+}
+
+func (r *queryResolver) SenderCollections(ctx context.Context) ([]SenderCollection, error) {
+	return r.senderCollections(ctx)
+}
+
+func (r *mutationResolver) CreateSenderCollection(ctx context.Context, name string) (*SenderCollection, error) {
+	collection, err := r.SenderService.CreateCollection(ctx, name)
+	if err != nil {
+		return nil, senderCollectionErr(ctx, "could not create sender collection", err)
+	}
+
+	senderCollection, err := r.parseSenderCollection(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &senderCollection, nil
+}
+
+func (r *mutationResolver) RenameSenderCollection(ctx context.Context, id ulid.ULID, name string) (*SenderCollection, error) {
+	collection, err := r.SenderService.RenameCollection(ctx, id, name)
+	if err != nil {
+		return nil, senderCollectionErr(ctx, "could not rename sender collection", err)
+	}
+
+	senderCollection, err := r.parseSenderCollection(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &senderCollection, nil
+}
+
+func (r *mutationResolver) DeleteSenderCollection(ctx context.Context, id ulid.ULID) (*DeleteSenderCollectionResult, error) {
+	if err := r.SenderService.DeleteCollection(ctx, id); err != nil {
+		return nil, senderCollectionErr(ctx, "could not delete sender collection", err)
+	}
+
+	return &DeleteSenderCollectionResult{true}, nil
+}
+
+func (r *mutationResolver) ReorderSenderCollections(ctx context.Context, ids []ulid.ULID) ([]SenderCollection, error) {
+	if err := r.SenderService.ReorderCollections(ctx, ids); err != nil {
+		return nil, senderCollectionErr(ctx, "could not reorder sender collections", err)
+	}
+
+	return r.senderCollections(ctx)
+}
+
+func (r *mutationResolver) MoveSenderRequest(
+	ctx context.Context,
+	id ulid.ULID,
+	collectionID *ulid.ULID,
+	index *int,
+) ([]SenderCollection, error) {
+	var targetID ulid.ULID
+	if collectionID != nil {
+		targetID = *collectionID
+	}
+
+	// Append by default.
+	targetIndex := -1
+	if index != nil {
+		targetIndex = *index
+	}
+
+	err := r.SenderService.MoveRequest(ctx, id, targetID, targetIndex)
+	if errors.Is(err, sender.ErrRequestNotFound) {
+		return nil, gqlerror.Errorf("Sender request not found.")
+	} else if err != nil {
+		return nil, senderCollectionErr(ctx, "could not move sender request", err)
+	}
+
+	return r.senderCollections(ctx)
+}
+
+func (r *mutationResolver) RunSenderCollection(ctx context.Context, id ulid.ULID) ([]SenderCollectionRunResult, error) {
+	// Use new context, so that sending requests and storing their responses
+	// isn't interrupted, like with `SendRequest`.
+	results, err := r.SenderService.RunCollection(context.Background(), id)
+	if err != nil {
+		return nil, senderCollectionErr(ctx, "could not run sender collection", err)
+	}
+
+	return parseCollectionRunResults(results)
+}
+
+func (r *mutationResolver) RunSenderAssertionSuite(
+	ctx context.Context,
+	collectionID *ulid.ULID,
+) (*SenderAssertionSuiteResult, error) {
+	var (
+		results []sender.CollectionRunResult
+		err     error
+	)
+
+	// Use new context, like with `RunSenderCollection`.
+	if collectionID != nil {
+		results, err = r.SenderService.RunCollection(context.Background(), *collectionID)
+	} else {
+		results, err = r.SenderService.RunAssertionSuite(context.Background())
+	}
+
+	if err != nil {
+		return nil, senderCollectionErr(ctx, "could not run sender assertion suite", err)
+	}
+
+	runResults, err := parseCollectionRunResults(results)
+	if err != nil {
+		return nil, err
+	}
+
+	suiteResult := &SenderAssertionSuiteResult{Results: runResults}
+
+	for _, result := range results {
+		if result.Passed() {
+			suiteResult.Passed++
+		} else {
+			suiteResult.Failed++
+		}
+	}
+
+	return suiteResult, nil
+}
+
+func parseCollectionRunResults(results []sender.CollectionRunResult) ([]SenderCollectionRunResult, error) {
+	runResults := make([]SenderCollectionRunResult, len(results))
+
+	for i, result := range results {
+		if !errors.Is(result.Err, sender.ErrRequestNotFound) {
+			senderReq, err := parseSenderRequest(result.Request)
+			if err != nil {
+				return nil, err
+			}
+
+			runResults[i].Request = &senderReq
+		}
+
+		var sendErr *sender.SendError
+
+		switch {
+		case errors.As(result.Err, &sendErr):
+			msg := fmt.Sprintf("Sending request failed: %v", sendErr.Unwrap())
+			runResults[i].Error = &msg
+		case result.Err != nil:
+			msg := result.Err.Error()
+			runResults[i].Error = &msg
+		}
+
+		runResults[i].AssertionResults = parseAssertionResults(result.Assertions)
+		runResults[i].Passed = result.Passed()
+	}
+
+	return runResults, nil
+}
+
+var senderAssertionTypeMap = map[sender.AssertionType]SenderAssertionType{
+	sender.AssertionStatusEquals:   SenderAssertionTypeStatusEquals,
+	sender.AssertionBodyContains:   SenderAssertionTypeBodyContains,
+	sender.AssertionJSONPathEquals: SenderAssertionTypeJSONPathEquals,
+}
+
+var revSenderAssertionTypeMap = map[SenderAssertionType]sender.AssertionType{
+	SenderAssertionTypeStatusEquals:   sender.AssertionStatusEquals,
+	SenderAssertionTypeBodyContains:   sender.AssertionBodyContains,
+	SenderAssertionTypeJSONPathEquals: sender.AssertionJSONPathEquals,
+}
+
+func parseAssertion(assertion sender.Assertion) SenderAssertion {
+	senderAssertion := SenderAssertion{
+		Type:  senderAssertionTypeMap[assertion.Type],
+		Value: assertion.Value,
+	}
+
+	if assertion.Path != "" {
+		path := assertion.Path
+		senderAssertion.Path = &path
+	}
+
+	return senderAssertion
+}
+
+func parseAssertionResults(results []sender.AssertionResult) []SenderAssertionResult {
+	assertionResults := make([]SenderAssertionResult, len(results))
+
+	for i, result := range results {
+		assertion := parseAssertion(result.Assertion)
+		assertionResults[i] = SenderAssertionResult{
+			Assertion: &assertion,
+			Passed:    result.Passed,
+		}
+
+		if result.Actual != "" {
+			actual := result.Actual
+			assertionResults[i].Actual = &actual
+		}
+
+		if result.Message != "" {
+			msg := result.Message
+			assertionResults[i].Message = &msg
+		}
+	}
+
+	return assertionResults
+}
+
+func (r *mutationResolver) ImportPostmanCollection(ctx context.Context, data string) (*SenderCollection, error) {
+	collection, err := r.SenderService.ImportPostmanCollection(ctx, []byte(data))
+	if errors.Is(err, sender.ErrInvalidPostmanCollection) {
+		return nil, gqlerror.Errorf("Invalid Postman collection: %v", err)
+	} else if err != nil {
+		return nil, senderCollectionErr(ctx, "could not import Postman collection", err)
+	}
+
+	senderCollection, err := r.parseSenderCollection(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &senderCollection, nil
+}
+
+func (r *queryResolver) ExportSenderRequests(
+	ctx context.Context,
+	collectionID *ulid.ULID,
+) (*ExportSenderRequestsResult, error) {
+	project, err := r.ProjectService.ActiveProject(ctx)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active project: %w", err)
+	}
+
+	name := project.Name
+
+	var collection sender.Collection
+
+	if collectionID != nil {
+		collection, err = r.SenderService.FindCollectionByID(ctx, *collectionID)
+		if err != nil {
+			return nil, senderCollectionErr(ctx, "could not find sender collection", err)
+		}
+
+		name = collection.Name
+	}
+
+	var (
+		reqs []sender.Request
+		data []byte
+	)
+
+	err = r.runExport(ctx, "Postman collection", func(ctx context.Context, progress *job.Progress) (err error) {
+		if collectionID != nil {
+			progress.SetTotal(len(collection.RequestIDs))
+
+			for _, id := range collection.RequestIDs {
+				if err := progress.Wait(ctx); err != nil {
+					return err
+				}
+
+				req, err := r.SenderService.FindRequestByID(ctx, id)
+				progress.Add(1)
+
+				if errors.Is(err, sender.ErrRequestNotFound) {
+					continue
+				} else if err != nil {
+					return fmt.Errorf("could not find sender request: %w", err)
+				}
+
+				reqs = append(reqs, req)
+			}
+		} else {
+			reqs, err = r.SenderService.FindRequests(ctx)
+			if err != nil {
+				return fmt.Errorf("could not find sender requests: %w", err)
+			}
+		}
+
+		data, err = sender.ExportPostmanCollection(name, reqs)
+		if err != nil {
+			return fmt.Errorf("could not export sender requests: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+
+	for _, req := range reqs {
+		if req.Raw == nil && req.URL != nil {
+			count++
+		}
+	}
+
+	return &ExportSenderRequestsResult{
+		Postman: string(data),
+		Count:   count,
+	}, nil
+}
+
+func (r *queryResolver) SenderEnvironments(ctx context.Context) (*SenderEnvironments, error) {
+	return parseSenderEnvironments(r.SenderService.Environments()), nil
+}
+
+func (r *mutationResolver) SetSenderEnvironments(
+	ctx context.Context,
+	input []SenderEnvironmentInput,
+	active *string,
+) (*SenderEnvironments, error) {
+	envs := sender.Environments{
+		Environments: make([]sender.Environment, len(input)),
+	}
+
+	if active != nil {
+		envs.Active = *active
+	}
+
+	for i, envInput := range input {
+		env := sender.Environment{
+			Name:      envInput.Name,
+			Variables: make([]sender.Variable, len(envInput.Variables)),
+		}
+
+		for j, v := range envInput.Variables {
+			env.Variables[j] = sender.Variable{Name: v.Name, Value: v.Value}
+		}
+
+		envs.Environments[i] = env
+	}
+
+	err := r.ProjectService.SetSenderEnvironments(ctx, envs)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, sender.ErrInvalidEnvironments):
+		return nil, gqlerror.Errorf("Could not set environments: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not set sender environments: %w", err)
+	}
+
+	return parseSenderEnvironments(envs), nil
+}
+
+func parseSenderEnvironments(envs sender.Environments) *SenderEnvironments {
+	senderEnvs := &SenderEnvironments{
+		Environments: make([]SenderEnvironment, len(envs.Environments)),
+	}
+
+	if envs.Active != "" {
+		senderEnvs.Active = &envs.Active
+	}
+
+	for i, env := range envs.Environments {
+		senderEnv := SenderEnvironment{
+			Name:      env.Name,
+			Variables: make([]SenderVariable, len(env.Variables)),
+		}
+
+		for j, v := range env.Variables {
+			senderEnv.Variables[j] = SenderVariable{Name: v.Name, Value: v.Value}
+		}
+
+		senderEnvs.Environments[i] = senderEnv
+	}
+
+	return senderEnvs
+}
+
+func (r *queryResolver) SenderSigningProfiles(ctx context.Context) ([]SenderSigningProfile, error) {
+	return parseSenderSigningProfiles(r.SenderService.SigningProfiles()), nil
+}
+
+func (r *mutationResolver) SetSenderSigningProfiles(
+	ctx context.Context,
+	input []SenderSigningProfileInput,
+) ([]SenderSigningProfile, error) {
+	profiles := make(sender.SigningProfiles, len(input))
+
+	for i, profileInput := range input {
+		profile := sender.SigningProfile{
+			Name:        profileInput.Name,
+			HostPattern: profileInput.HostPattern,
+		}
+
+		if aws := profileInput.AwsSigV4; aws != nil {
+			profile.AWSV4 = &sender.AWSV4Signing{
+				Region:          aws.Region,
+				Service:         aws.Service,
+				AccessKeyID:     aws.AccessKeyID,
+				SecretAccessKey: aws.SecretAccessKey,
+			}
+
+			if aws.SessionToken != nil {
+				profile.AWSV4.SessionToken = *aws.SessionToken
+			}
+		}
+
+		if h := profileInput.Hmac; h != nil {
+			profile.HMAC = &sender.HMACSigning{
+				Key:     h.Key,
+				Message: h.Message,
+				Header:  h.Header,
+			}
+
+			if h.Algorithm != nil {
+				profile.HMAC.Algorithm = *h.Algorithm
+			}
+
+			if h.Encoding != nil {
+				profile.HMAC.Encoding = *h.Encoding
+			}
+
+			if h.HeaderValue != nil {
+				profile.HMAC.HeaderValue = *h.HeaderValue
+			}
+
+			if h.TimestampHeader != nil {
+				profile.HMAC.TimestampHeader = *h.TimestampHeader
+			}
+		}
+
+		profiles[i] = profile
+	}
+
+	err := r.ProjectService.SetSenderSigningProfiles(ctx, profiles)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, sender.ErrInvalidSigningProfiles):
+		return nil, gqlerror.Errorf("Could not set signing profiles: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not set sender signing profiles: %w", err)
+	}
+
+	return parseSenderSigningProfiles(profiles), nil
+}
+
+func parseSenderSigningProfiles(profiles sender.SigningProfiles) []SenderSigningProfile {
+	senderProfiles := make([]SenderSigningProfile, len(profiles))
+
+	for i, profile := range profiles {
+		senderProfile := SenderSigningProfile{
+			Name:        profile.Name,
+			HostPattern: profile.HostPattern,
+		}
+
+		if aws := profile.AWSV4; aws != nil {
+			senderProfile.AwsSigV4 = &AwsSigV4Signing{
+				Region:          aws.Region,
+				Service:         aws.Service,
+				AccessKeyID:     aws.AccessKeyID,
+				SecretAccessKey: aws.SecretAccessKey,
+			}
+
+			if aws.SessionToken != "" {
+				senderProfile.AwsSigV4.SessionToken = &aws.SessionToken
+			}
+		}
+
+		if h := profile.HMAC; h != nil {
+			senderProfile.Hmac = &HmacSigning{
+				Algorithm:   h.Algorithm,
+				Key:         h.Key,
+				Message:     h.Message,
+				Encoding:    h.Encoding,
+				Header:      h.Header,
+				HeaderValue: h.HeaderValue,
+			}
+
+			if senderProfile.Hmac.Algorithm == "" {
+				senderProfile.Hmac.Algorithm = "sha256"
+			}
+
+			if senderProfile.Hmac.Encoding == "" {
+				senderProfile.Hmac.Encoding = "hex"
+			}
+
+			if senderProfile.Hmac.HeaderValue == "" {
+				senderProfile.Hmac.HeaderValue = "{{signature}}"
+			}
+
+			if h.TimestampHeader != "" {
+				senderProfile.Hmac.TimestampHeader = &h.TimestampHeader
+			}
+		}
+
+		senderProfiles[i] = senderProfile
+	}
+
+	return senderProfiles
+}
+
+func (r *queryResolver) SenderSchedules(ctx context.Context) ([]SenderSchedule, error) {
+	return parseSenderSchedules(r.SenderService.Schedules())
+}
+
+func (r *mutationResolver) CompareSenderRequest(
+	ctx context.Context,
+	input CompareSenderRequestInput,
+) (*SenderComparison, error) {
+	if len(input.BaseURLs) != 2 {
+		return nil, gqlerror.Errorf("Exactly two base URLs must be set.")
+	}
+
+	params := sender.CompareParams{
+		RequestID:       input.RequestID,
+		BaseURLs:        [2]*url.URL{input.BaseURLs[0], input.BaseURLs[1]},
+		IgnoreHeaders:   input.IgnoreHeaders,
+		IgnoreJSONPaths: input.IgnoreJSONPaths,
+		IgnorePatterns:  input.IgnorePatterns,
+	}
+
+	// Use new context, so that storing the comparison isn't interrupted, like
+	// with `SendRequest`.
+	comparison, err := r.SenderService.CompareRequest(context.Background(), params)
+	switch {
+	case errors.Is(err, sender.ErrReadOnly):
+		return nil, gqlerror.Errorf("Project is opened read-only.")
+	case errors.Is(err, sender.ErrRequestNotFound):
+		return nil, gqlerror.Errorf("Sender request not found.")
+	case errors.Is(err, sender.ErrInvalidComparison):
+		return nil, gqlerror.Errorf("Could not compare request: %v", err)
+	case errors.Is(err, sender.ErrScriptFailed) || errors.Is(err, sender.ErrInvalidScript):
+		return nil, &gqlerror.Error{
+			Path:    graphql.GetPath(ctx),
+			Message: fmt.Sprintf("Script failed: %v", err),
+			Extensions: map[string]interface{}{
+				"code": "script_failed",
+			},
+		}
+	case err != nil:
+		return nil, fmt.Errorf("could not compare sender request: %w", err)
+	}
+
+	senderComparison, err := parseSenderComparison(comparison)
+	if err != nil {
+		return nil, err
+	}
+
+	return &senderComparison, nil
+}
+
+func (r *queryResolver) SenderComparisons(ctx context.Context, requestID *ulid.ULID) ([]SenderComparison, error) {
+	var id ulid.ULID
+	if requestID != nil {
+		id = *requestID
+	}
+
+	comparisons, err := r.SenderService.FindComparisons(ctx, id)
+	if errors.Is(err, sender.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find sender comparisons: %w", err)
+	}
+
+	senderComparisons := make([]SenderComparison, len(comparisons))
+
+	for i, comparison := range comparisons {
+		senderComparisons[i], err = parseSenderComparison(comparison)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return senderComparisons, nil
+}
+
+func parseSenderComparison(comparison sender.Comparison) (SenderComparison, error) {
+	senderComparison := SenderComparison{
+		ID:        comparison.ID,
+		RequestID: comparison.RequestID,
+		Timestamp: ulid.Time(comparison.ID.Time()),
+		Targets:   make([]SenderComparisonTarget, len(comparison.Targets)),
+		Diff: &SenderResponseDiff{
+			Equal:             comparison.Diff.Equal,
+			StatusCodeChanged: comparison.Diff.StatusCodeChanged,
+			Headers:           make([]SenderHeaderDiff, len(comparison.Diff.Headers)),
+		},
+	}
+
+	for i, target := range comparison.Targets {
+		comparisonTarget := SenderComparisonTarget{
+			BaseURL: target.BaseURL,
+			URL:     target.URL,
+		}
+
+		if target.Err != "" {
+			comparisonTarget.Error = &comparison.Targets[i].Err
+		}
+
+		if target.Response != nil {
+			resLog, err := parseResponseLog(*target.Response)
+			if err != nil {
+				return SenderComparison{}, err
+			}
+
+			resLog.ID = comparison.RequestID
+			comparisonTarget.Response = &resLog
+		}
+
+		senderComparison.Targets[i] = comparisonTarget
+	}
+
+	for i, header := range comparison.Diff.Headers {
+		senderComparison.Diff.Headers[i] = SenderHeaderDiff{
+			Key:     header.Key,
+			AValues: append([]string{}, header.AValues...),
+			BValues: append([]string{}, header.BValues...),
+		}
+	}
+
+	if comparison.Diff.Body != "" {
+		senderComparison.Diff.Body = &comparison.Diff.Body
+	}
+
+	return senderComparison, nil
+}
+
+func (r *queryResolver) SenderScheduleRuns(ctx context.Context, schedule *string) ([]SenderScheduleRun, error) {
+	var name string
+	if schedule != nil {
+		name = *schedule
+	}
+
+	runs, err := r.SenderService.FindScheduleRuns(ctx, name)
+	if errors.Is(err, sender.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find sender schedule runs: %w", err)
+	}
+
+	scheduleRuns := make([]SenderScheduleRun, len(runs))
+
+	for i, run := range runs {
+		scheduleRun := SenderScheduleRun{
+			ID:        run.ID,
+			Schedule:  run.Schedule,
+			Timestamp: ulid.Time(run.ID.Time()),
+			Passed:    run.Passed,
+			Failed:    run.Failed,
+			Changed:   run.Changed,
+			Results:   make([]SenderScheduleRunResult, len(run.Results)),
+		}
+
+		for j, result := range run.Results {
+			runResult := SenderScheduleRunResult{
+				RequestID:        result.RequestID,
+				AssertionResults: parseAssertionResults(result.Assertions),
+				Passed:           result.Passed,
+				Changed:          result.Changed,
+			}
+
+			if result.StatusCode != 0 {
+				statusCode := result.StatusCode
+				runResult.StatusCode = &statusCode
+			}
+
+			if result.Err != "" {
+				errMsg := result.Err
+				runResult.Error = &errMsg
+			}
+
+			if result.Snapshot != "" {
+				snapshot := result.Snapshot
+				runResult.Snapshot = &snapshot
+			}
+
+			if result.Diff != "" {
+				diff := result.Diff
+				runResult.Diff = &diff
+			}
+
+			scheduleRun.Results[j] = runResult
+		}
+
+		scheduleRuns[i] = scheduleRun
+	}
+
+	return scheduleRuns, nil
+}
+
+func (r *mutationResolver) SetSenderSchedules(
+	ctx context.Context,
+	input []SenderScheduleInput,
+) ([]SenderSchedule, error) {
+	schedules := make([]sender.Schedule, len(input))
+
+	for i, scheduleInput := range input {
+		schedule := sender.Schedule{
+			Name:            scheduleInput.Name,
+			RequestIDs:      scheduleInput.RequestIDs,
+			Interval:        time.Duration(scheduleInput.Interval) * time.Second,
+			IgnoreJSONPaths: scheduleInput.IgnoreJSONPaths,
+			IgnorePatterns:  scheduleInput.IgnorePatterns,
+		}
+
+		if scheduleInput.CollectionID != nil {
+			schedule.CollectionID = *scheduleInput.CollectionID
+		}
+
+		if scheduleInput.WebhookURL != nil {
+			schedule.WebhookURL = scheduleInput.WebhookURL.String()
+		}
+
+		if scheduleInput.AlertOnChange != nil {
+			schedule.AlertOnChange = *scheduleInput.AlertOnChange
+		}
+
+		if scheduleInput.Paused != nil {
+			schedule.Paused = *scheduleInput.Paused
+		}
+
+		schedules[i] = schedule
+	}
+
+	err := r.ProjectService.SetSenderSchedules(ctx, schedules)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, sender.ErrInvalidSchedules):
+		return nil, gqlerror.Errorf("Could not set sender schedules: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not set sender schedules: %w", err)
+	}
+
+	return parseSenderSchedules(schedules)
+}
+
+func parseSenderSchedules(schedules []sender.Schedule) ([]SenderSchedule, error) {
+	senderSchedules := make([]SenderSchedule, len(schedules))
+
+	for i, schedule := range schedules {
+		senderSchedule := SenderSchedule{
+			Name:            schedule.Name,
+			RequestIDs:      schedule.RequestIDs,
+			Interval:        int(schedule.Interval / time.Second),
+			AlertOnChange:   schedule.AlertOnChange,
+			IgnoreJSONPaths: schedule.IgnoreJSONPaths,
+			IgnorePatterns:  schedule.IgnorePatterns,
+			Paused:          schedule.Paused,
+		}
+
+		if senderSchedule.RequestIDs == nil {
+			senderSchedule.RequestIDs = []ulid.ULID{}
+		}
+
+		if senderSchedule.IgnoreJSONPaths == nil {
+			senderSchedule.IgnoreJSONPaths = []string{}
+		}
+
+		if senderSchedule.IgnorePatterns == nil {
+			senderSchedule.IgnorePatterns = []string{}
+		}
+
+		if schedule.CollectionID.Compare(ulid.ULID{}) != 0 {
+			collectionID := schedule.CollectionID
+			senderSchedule.CollectionID = &collectionID
+		}
+
+		if schedule.WebhookURL != "" {
+			webhookURL, err := url.Parse(schedule.WebhookURL)
+			if err != nil {
+				return nil, fmt.Errorf("sender schedule has invalid webhook URL: %w", err)
+			}
+
+			senderSchedule.WebhookURL = webhookURL
+		}
+
+		senderSchedules[i] = senderSchedule
+	}
+
+	return senderSchedules, nil
+}
+
+func (r *queryResolver) Oauth2TokenSources(ctx context.Context) ([]OAuth2TokenSource, error) {
+	return r.oauth2TokenSources()
+}
+
+func (r *Resolver) oauth2TokenSources() ([]OAuth2TokenSource, error) {
+	sources := r.OAuth2Service.Sources()
+	tokenSources := make([]OAuth2TokenSource, len(sources))
+
+	for i, source := range sources {
+		tokenSource, err := parseOAuth2TokenSource(source)
+		if err != nil {
+			return nil, err
+		}
+
+		tokenSources[i] = tokenSource
+	}
+
+	return tokenSources, nil
+}
+
+func (r *queryResolver) Oauth2Tokens(ctx context.Context) ([]OAuth2Token, error) {
+	tokens := r.OAuth2Service.Tokens()
+	oauth2Tokens := make([]OAuth2Token, len(tokens))
+
+	for i, token := range tokens {
+		oauth2Tokens[i] = parseOAuth2Token(token)
+	}
+
+	return oauth2Tokens, nil
+}
+
+func (r *mutationResolver) SetOAuth2TokenSources(
+	ctx context.Context,
+	input []OAuth2TokenSourceInput,
+) ([]OAuth2TokenSource, error) {
+	sources := make([]oauth2.Source, len(input))
+
+	for i, sourceInput := range input {
+		source := oauth2.Source{
+			Name:      sourceInput.Name,
+			Variable:  sourceInput.Variable,
+			TokenURL:  sourceInput.TokenURL.String(),
+			GrantType: revOAuth2GrantTypeMap[sourceInput.GrantType],
+			Scopes:    sourceInput.Scopes,
+		}
+
+		if sourceInput.ClientID != nil {
+			source.ClientID = *sourceInput.ClientID
+		}
+
+		if sourceInput.ClientSecret != nil {
+			source.ClientSecret = *sourceInput.ClientSecret
+		}
+
+		if sourceInput.ClientCredentialsInBody != nil {
+			source.ClientCredentialsInBody = *sourceInput.ClientCredentialsInBody
+		}
+
+		if sourceInput.Username != nil {
+			source.Username = *sourceInput.Username
+		}
+
+		if sourceInput.Password != nil {
+			source.Password = *sourceInput.Password
+		}
+
+		if sourceInput.RefreshInterval != nil {
+			source.RefreshInterval = time.Duration(*sourceInput.RefreshInterval) * time.Second
+		}
+
+		if sourceInput.Paused != nil {
+			source.Paused = *sourceInput.Paused
+		}
+
+		sources[i] = source
+	}
+
+	err := r.ProjectService.SetOAuth2Sources(ctx, sources)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, oauth2.ErrInvalidSources):
+		return nil, gqlerror.Errorf("Could not set OAuth 2.0 token sources: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not set OAuth 2.0 token sources: %w", err)
+	}
+
+	return r.oauth2TokenSources()
+}
+
+func (r *mutationResolver) FetchOAuth2Token(ctx context.Context, source string) (*OAuth2Token, error) {
+	token, err := r.OAuth2Service.FetchToken(ctx, source)
+	if errors.Is(err, oauth2.ErrSourceNotFound) {
+		return nil, gqlerror.Errorf("OAuth 2.0 token source not found.")
+	}
+
+	// Errors of the fetch itself are reported on the token.
+	oauth2Token := parseOAuth2Token(token)
+
+	return &oauth2Token, nil
+}
+
+var oauth2GrantTypeMap = map[oauth2.GrantType]OAuth2GrantType{
+	oauth2.GrantTypeClientCredentials: OAuth2GrantTypeClientCredentials,
+	oauth2.GrantTypePassword:          OAuth2GrantTypePassword,
+}
+
+var revOAuth2GrantTypeMap = map[OAuth2GrantType]oauth2.GrantType{
+	OAuth2GrantTypeClientCredentials: oauth2.GrantTypeClientCredentials,
+	OAuth2GrantTypePassword:          oauth2.GrantTypePassword,
+}
+
+func parseOAuth2TokenSource(source oauth2.Source) (OAuth2TokenSource, error) {
+	tokenURL, err := url.Parse(source.TokenURL)
+	if err != nil {
+		return OAuth2TokenSource{}, fmt.Errorf("OAuth 2.0 token source has invalid token URL: %w", err)
+	}
+
+	tokenSource := OAuth2TokenSource{
+		Name:                    source.Name,
+		Variable:                source.Variable,
+		TokenURL:                tokenURL,
+		GrantType:               oauth2GrantTypeMap[source.GrantType],
+		ClientID:                source.ClientID,
+		ClientSecret:            source.ClientSecret,
+		ClientCredentialsInBody: source.ClientCredentialsInBody,
+		Username:                source.Username,
+		Password:                source.Password,
+		Scopes:                  source.Scopes,
+		Paused:                  source.Paused,
+	}
+
+	if tokenSource.Scopes == nil {
+		tokenSource.Scopes = []string{}
+	}
+
+	if source.RefreshInterval > 0 {
+		refreshInterval := int(source.RefreshInterval / time.Second)
+		tokenSource.RefreshInterval = &refreshInterval
+	}
+
+	return tokenSource, nil
+}
+
+func parseOAuth2Token(token oauth2.Token) OAuth2Token {
+	oauth2Token := OAuth2Token{
+		Source:   token.Source,
+		Variable: token.Variable,
+	}
+
+	if token.AccessToken != "" {
+		oauth2Token.AccessToken = &token.AccessToken
+	}
+
+	if token.TokenType != "" {
+		oauth2Token.TokenType = &token.TokenType
+	}
+
+	if !token.ExpiresAt.IsZero() {
+		oauth2Token.ExpiresAt = &token.ExpiresAt
+	}
+
+	if !token.FetchedAt.IsZero() {
+		oauth2Token.FetchedAt = &token.FetchedAt
+	}
+
+	if token.Err != nil {
+		errStr := token.Err.Error()
+		oauth2Token.Error = &errStr
+	}
+
+	return oauth2Token
+}
+
+func (r *Resolver) senderCollections(ctx context.Context) ([]SenderCollection, error) {
+	collections, err := r.SenderService.FindCollections(ctx)
+	if err != nil {
+		return nil, senderCollectionErr(ctx, "could not find sender collections", err)
+	}
+
+	senderCollections := make([]SenderCollection, len(collections))
+
+	for i, collection := range collections {
+		if senderCollections[i], err = r.parseSenderCollection(ctx, collection); err != nil {
+			return nil, err
+		}
+	}
+
+	return senderCollections, nil
+}
+
+// parseSenderCollection returns a collection with its requests, in order.
+// Requests that no longer exist are skipped.
+func (r *Resolver) parseSenderCollection(ctx context.Context, collection sender.Collection) (SenderCollection, error) {
+	senderCollection := SenderCollection{
+		ID:       collection.ID,
+		Name:     collection.Name,
+		Requests: make([]SenderRequest, 0, len(collection.RequestIDs)),
+	}
+
+	for _, id := range collection.RequestIDs {
+		req, err := r.SenderService.FindRequestByID(ctx, id)
+		if errors.Is(err, sender.ErrRequestNotFound) {
+			continue
+		} else if err != nil {
+			return SenderCollection{}, fmt.Errorf("could not get sender request: %w", err)
+		}
+
+		senderReq, err := parseSenderRequest(req)
+		if err != nil {
+			return SenderCollection{}, err
+		}
+
+		senderCollection.Requests = append(senderCollection.Requests, senderReq)
+	}
+
+	return senderCollection, nil
+}
+
+func senderCollectionErr(ctx context.Context, msg string, err error) error {
+	switch {
+	case errors.Is(err, sender.ErrProjectIDMustBeSet):
+		return noActiveProjectErr(ctx)
+	case errors.Is(err, sender.ErrCollectionNotFound):
+		return gqlerror.Errorf("Sender collection not found.")
+	case errors.Is(err, sender.ErrInvalidCollectionName):
+		return gqlerror.Errorf("Sender collection name must not be empty.")
+	default:
+		return fmt.Errorf("%v: %w", msg, err)
+	}
+}
+
+func (r *queryResolver) HTTPRequestLogJWTs(ctx context.Context, id ulid.ULID) ([]Jwt, error) {
+	reqLog, err := r.RequestLogService.FindRequestLogByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not get request by ID: %w", err)
+	}
+
+	jwts := make([]Jwt, 0)
+	seen := make(map[string]bool)
+
+	findJWTs := func(location JWTLocation, b []byte) {
+		for _, token := range jwt.Find(b) {
+			if key := location.String() + token.Raw; !seen[key] {
+				seen[key] = true
+				jwts = append(jwts, parseJWT(token, location))
+			}
+		}
+	}
+
+	for _, value := range sortedHeaderValues(reqLog.Header) {
+		findJWTs(JWTLocationRequestHeader, []byte(value))
+	}
+
+	findJWTs(JWTLocationRequestBody, reqLog.Body)
+
+	if reqLog.Response != nil {
+		for _, value := range sortedHeaderValues(reqLog.Response.Header) {
+			findJWTs(JWTLocationResponseHeader, []byte(value))
+		}
+
+		findJWTs(JWTLocationResponseBody, reqLog.Response.Body)
+	}
+
+	return jwts, nil
+}
+
+func (r *mutationResolver) ResignJwt(ctx context.Context, input ResignJWTInput) (*ResignJWTResult, error) {
+	token, err := jwt.Parse(input.Token)
+	if err != nil {
+		return nil, gqlerror.Errorf("Invalid token: %v", err)
+	}
+
+	header := token.Header
+	claims := token.Claims
+
+	if input.Header != nil {
+		header = nil
+		if err := json.Unmarshal([]byte(*input.Header), &header); err != nil {
+			return nil, gqlerror.Errorf("Header must be a JSON object: %v", err)
+		}
+	}
+
+	if input.Claims != nil {
+		claims = nil
+		if err := json.Unmarshal([]byte(*input.Claims), &claims); err != nil {
+			return nil, gqlerror.Errorf("Claims must be a JSON object: %v", err)
+		}
+	}
+
+	signed, err := jwt.Sign(header, claims, input.Algorithm, []byte(input.Key))
+	if errors.Is(err, jwt.ErrUnsupportedAlgorithm) || errors.Is(err, jwt.ErrInvalidKey) {
+		return nil, gqlerror.Errorf("Could not sign token: %v", err)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not sign token: %w", err)
+	}
+
+	result := &ResignJWTResult{Token: signed}
+
+	if input.SenderRequestID == nil {
+		return result, nil
+	}
+
+	req, err := r.SenderService.FindRequestByID(ctx, *input.SenderRequestID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get sender request by ID: %w", err)
+	}
+
+	for key, values := range req.Header {
+		for i := range values {
+			req.Header[key][i] = strings.ReplaceAll(values[i], token.Raw, signed)
+		}
+	}
+
+	req.Body = bytes.ReplaceAll(req.Body, []byte(token.Raw), []byte(signed))
+
+	req, err = r.SenderService.CreateOrUpdateRequest(ctx, req)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not update sender request: %w", err)
+	}
+
+	senderReq, err := parseSenderRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result.SenderRequest = &senderReq
+
+	return result, nil
+}
+
+func parseJWT(token jwt.Token, location JWTLocation) Jwt {
+	parsed := Jwt{
+		Raw:        token.Raw,
+		Location:   location,
+		Header:     string(token.RawHeader),
+		Claims:     string(token.RawClaims),
+		Algorithm:  token.Algorithm(),
+		Weaknesses: make([]JWTWeakness, 0),
+	}
+
+	for _, weakness := range token.Weaknesses(nil) {
+		jwtWeakness := JWTWeakness{
+			Description: weakness.Description,
+		}
+
+		switch weakness.Type {
+		case jwt.WeaknessAlgNone:
+			jwtWeakness.Type = JWTWeaknessTypeAlgNone
+		case jwt.WeaknessWeakSecret:
+			secret := weakness.Secret
+			jwtWeakness.Type = JWTWeaknessTypeWeakSecret
+			jwtWeakness.Secret = &secret
+		}
+
+		parsed.Weaknesses = append(parsed.Weaknesses, jwtWeakness)
+	}
+
+	return parsed
+}
+
+// sortedHeaderValues returns all header values, ordered by header key.
+func sortedHeaderValues(header http.Header) []string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var values []string
+	for _, key := range keys {
+		values = append(values, header[key]...)
+	}
+
+	return values
+}
+
+func parseSenderRequest(req sender.Request) (SenderRequest, error) {
+	method := HTTPMethod(req.Method)
+	if method != "" && !method.IsValid() {
+		return SenderRequest{}, fmt.Errorf("sender request has invalid method: %v", method)
+	}
+
+	reqProto := httpProtocolMap[req.Proto]
+	if !reqProto.IsValid() {
+		return SenderRequest{}, fmt.Errorf("sender request has invalid protocol: %v", req.Proto)
+	}
+
+	senderReq := SenderRequest{
+		ID:        req.ID,
+		URL:       req.URL,
+		Method:    method,
+		Proto:     HTTPProtocol(req.Proto),
+		Timestamp: ulid.Time(req.ID.Time()),
+	}
+
+	if req.SourceRequestLogID.Compare(ulid.ULID{}) != 0 {
+		senderReq.SourceRequestLogID = &req.SourceRequestLogID
+	}
+
+	if req.Header != nil {
+		senderReq.Headers = make([]HTTPHeader, 0)
+
+		for key, values := range req.Header {
+			for _, value := range values {
+				senderReq.Headers = append(senderReq.Headers, HTTPHeader{
+					Key:   key,
+					Value: value,
+				})
+			}
+		}
+	}
+
+	if len(req.Body) > 0 {
+		bodyStr := string(req.Body)
+		senderReq.Body = &bodyStr
+	}
+
+	if req.Raw != nil {
+		raw := string(req.Raw)
+		senderReq.Raw = &raw
+	}
+
+	if req.PreRequestScript != "" {
+		senderReq.PreRequestScript = &req.PreRequestScript
+	}
+
+	if req.PostResponseScript != "" {
+		senderReq.PostResponseScript = &req.PostResponseScript
+	}
+
+	senderReq.Assertions = make([]SenderAssertion, len(req.Assertions))
+	for i, assertion := range req.Assertions {
+		senderReq.Assertions[i] = parseAssertion(assertion)
+	}
+
+	if results := req.CheckAssertions(); results != nil {
+		senderReq.AssertionResults = parseAssertionResults(results)
+	}
+
+	if req.RawResponse != nil {
+		rawRes := string(req.RawResponse)
+		senderReq.RawResponse = &rawRes
+	}
+
+	if req.Response != nil {
+		resLog, err := parseResponseLog(*req.Response)
+		if err != nil {
+			return SenderRequest{}, err
+		}
+
+		resLog.ID = req.ID
+
+		senderReq.Response = &resLog
+	}
+
+	return senderReq, nil
+}
+
+func (r *queryResolver) Transform(ctx context.Context, input string, transformTypes []TransformType) (*TransformResult, error) {
+	transforms := make([]transform.Transform, len(transformTypes))
+
+	for i, transformType := range transformTypes {
+		t, err := transform.Parse(transformType.String())
+		if err != nil {
+			return nil, fmt.Errorf("could not parse transform type: %w", err)
+		}
+
+		transforms[i] = t
+	}
+
+	output, err := transform.Chain([]byte(input), transforms...)
+	if err != nil {
+		return nil, &gqlerror.Error{
+			Path:    graphql.GetPath(ctx),
+			Message: fmt.Sprintf("Transform failed: %v", err),
+			Extensions: map[string]interface{}{
+				"code": "transform_failed",
+			},
+		}
+	}
+
+	return &TransformResult{
+		Output:       string(output),
+		OutputBase64: base64.StdEncoding.EncodeToString(output),
+	}, nil
+}
+
+func (r *mutationResolver) CreateOASTPayload(
+	ctx context.Context,
+	requestLogID *ulid.ULID,
+	correlationID *ulid.ULID,
+) (*OASTPayload, error) {
+	var params oast.CreatePayloadParams
+	if requestLogID != nil {
+		params.RequestLogID = *requestLogID
+	}
+
+	if correlationID != nil {
+		params.CorrelationID = *correlationID
+	}
+
+	payload, err := r.OASTService.CreatePayload(ctx, params)
+	switch {
+	case errors.Is(err, oast.ErrProjectIDMustBeSet):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, oast.ErrNotConfigured):
+		return nil, &gqlerror.Error{
+			Path:    graphql.GetPath(ctx),
+			Message: "Out-of-band callback server is not configured.",
+			Extensions: map[string]interface{}{
+				"code": "oast_not_configured",
+			},
+		}
+	case err != nil:
+		return nil, fmt.Errorf("could not create OAST payload: %w", err)
+	}
+
+	oastPayload := OASTPayload{
+		ID:        payload.ID,
+		Hostname:  r.OASTService.Hostname(payload),
+		URL:       r.OASTService.URL(payload),
+		Timestamp: ulid.Time(payload.ID.Time()),
+	}
+
+	if payload.RequestLogID.Compare(ulid.ULID{}) != 0 {
+		oastPayload.RequestLogID = &payload.RequestLogID
+	}
+
+	if payload.CorrelationID.Compare(ulid.ULID{}) != 0 {
+		oastPayload.CorrelationID = &payload.CorrelationID
+	}
+
+	return &oastPayload, nil
+}
+
+func (r *queryResolver) OastInteractions(
+	ctx context.Context,
+	requestLogID *ulid.ULID,
+	correlationID *ulid.ULID,
+) ([]OASTInteraction, error) {
+	filter := oast.FindInteractionsFilter{}
+	if requestLogID != nil {
+		filter.RequestLogID = *requestLogID
+	}
+
+	if correlationID != nil {
+		filter.CorrelationID = *correlationID
+	}
+
+	interactions, err := r.OASTService.FindInteractions(ctx, filter)
+	if errors.Is(err, oast.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find OAST interactions: %w", err)
+	}
+
+	return parseOASTInteractions(interactions), nil
+}
+
+func parseOASTInteractions(interactions []oast.Interaction) []OASTInteraction {
+	oastInteractions := make([]OASTInteraction, len(interactions))
+
+	for i, interaction := range interactions {
+		oastInteraction := OASTInteraction{
+			ID:         interaction.ID,
+			PayloadID:  interaction.PayloadID,
+			Protocol:   OASTProtocol(strings.ToUpper(string(interaction.Protocol))),
+			RemoteAddr: interaction.RemoteAddr,
+			Timestamp:  ulid.Time(interaction.ID.Time()),
+			Raw:        string(interaction.Raw),
+		}
+
+		if interaction.RequestLogID.Compare(ulid.ULID{}) != 0 {
+			oastInteraction.RequestLogID = &interactions[i].RequestLogID
+		}
+
+		if interaction.CorrelationID.Compare(ulid.ULID{}) != 0 {
+			oastInteraction.CorrelationID = &interactions[i].CorrelationID
+		}
+
+		oastInteractions[i] = oastInteraction
+	}
+
+	return oastInteractions
+}
+
+func (r *queryResolver) Findings(ctx context.Context, requestLogID *ulid.ULID) ([]Finding, error) {
+	filter := finding.FindFindingsFilter{}
+	if requestLogID != nil {
+		filter.RequestLogID = *requestLogID
+	}
+
+	findings, err := r.FindingService.FindFindings(ctx, filter)
+	if errors.Is(err, finding.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find findings: %w", err)
+	}
+
+	apiFindings := make([]Finding, len(findings))
+
+	for i, f := range findings {
+		apiFinding := Finding{
+			ID:           f.ID,
+			RequestLogID: f.RequestLogID,
+			Check:        FindingCheck(strings.ToUpper(string(f.Check))),
+			Severity:     FindingSeverity(strings.ToUpper(string(f.Severity))),
+			Description:  f.Description,
+			Timestamp:    ulid.Time(f.ID.Time()),
+		}
+
+		if f.HeaderKey != "" {
+			apiFinding.HeaderKey = &findings[i].HeaderKey
+		}
+
+		if f.HeaderValue != "" {
+			apiFinding.HeaderValue = &findings[i].HeaderValue
+		}
+
+		apiFindings[i] = apiFinding
+	}
+
+	return apiFindings, nil
+}
+
+func (r *queryResolver) AuthzCheckSettings(ctx context.Context) (*AuthzCheckSettings, error) {
+	return parseAuthzCheckSettings(r.AuthzService.Settings()), nil
+}
+
+func (r *mutationResolver) SetAuthzCheckSettings(
+	ctx context.Context,
+	input AuthzCheckSettingsInput,
+) (*AuthzCheckSettings, error) {
+	settings := authz.Settings{
+		Enabled: input.Enabled,
+		Headers: headerFromInput(input.Headers),
+	}
+
+	err := r.ProjectService.SetAuthzSettings(ctx, settings)
+	switch {
+	case errors.Is(err, proj.ErrNoProject):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, authz.ErrInvalidSettings):
+		return nil, gqlerror.Errorf("Could not set authorization check settings: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not set authorization check settings: %w", err)
+	}
+
+	return parseAuthzCheckSettings(settings), nil
+}
+
+func (r *mutationResolver) StartUnauthCheck(ctx context.Context, input StartUnauthCheckInput) (*UnauthCheck, error) {
+	sel, err := selectionFromInput(*input.Selection)
+	if err != nil {
+		return nil, err
+	}
+
+	params := authz.UnauthCheckParams{
+		Selection:        sel,
+		StripHeaders:     input.StripHeaders,
+		StripQueryParams: input.StripQueryParams,
+	}
+
+	if input.MinSimilarity != nil {
+		params.MinSimilarity = *input.MinSimilarity
+	}
+
+	check, err := r.AuthzService.StartUnauthCheck(ctx, params)
+	switch {
+	case errors.Is(err, reqlog.ErrProjectIDMustBeSet):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, authz.ErrNoRequests):
+		return nil, gqlerror.Errorf("No request logs with credentials and a response selected.")
+	case errors.Is(err, authz.ErrInvalidSimilarity):
+		return nil, gqlerror.Errorf("Minimum similarity must be between 0 and 1.")
+	case err != nil:
+		return nil, fmt.Errorf("could not start unauthenticated check: %w", err)
+	}
+
+	return parseUnauthCheck(check), nil
+}
+
+func (r *mutationResolver) CancelUnauthCheck(ctx context.Context, id ulid.ULID) (*CancelUnauthCheckResult, error) {
+	err := r.AuthzService.CancelUnauthCheck(id)
+	if errors.Is(err, authz.ErrCheckNotFound) {
+		return nil, gqlerror.Errorf("Unauthenticated check not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not cancel unauthenticated check: %w", err)
+	}
+
+	return &CancelUnauthCheckResult{Success: true}, nil
+}
+
+func (r *queryResolver) UnauthCheck(ctx context.Context, id ulid.ULID) (*UnauthCheck, error) {
+	check, err := r.AuthzService.FindUnauthCheckByID(id)
+	if errors.Is(err, authz.ErrCheckNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get unauthenticated check: %w", err)
+	}
+
+	return parseUnauthCheck(check), nil
+}
+
+func (r *queryResolver) UnauthChecks(ctx context.Context) ([]UnauthCheck, error) {
+	checks := r.AuthzService.FindUnauthChecks()
+	result := make([]UnauthCheck, len(checks))
+
+	for i, check := range checks {
+		result[i] = *parseUnauthCheck(check)
+	}
+
+	return result, nil
+}
+
+func parseUnauthCheck(check authz.UnauthCheck) *UnauthCheck {
+	result := &UnauthCheck{
+		ID:            check.ID,
+		Status:        UnauthCheckStatus(strings.ToUpper(string(check.Status))),
+		MinSimilarity: check.MinSimilarity,
+		Total:         check.Total,
+		Completed:     check.Completed,
+		Results:       make([]UnauthCheckResult, len(check.Results)),
+		Timestamp:     ulid.Time(check.ID.Time()),
+	}
+
+	for i, res := range check.Results {
+		checkResult := UnauthCheckResult{
+			RequestLogID:       res.RequestLogID,
+			Method:             HTTPMethod(res.Method),
+			URL:                res.URL,
+			Stripped:           res.Stripped,
+			OriginalStatusCode: res.OriginalStatusCode,
+			Similarity:         res.Similarity,
+			Bypass:             res.Bypass,
+		}
+
+		if res.StatusCode != 0 {
+			checkResult.StatusCode = &check.Results[i].StatusCode
+		}
+
+		if res.Error != "" {
+			checkResult.Error = &check.Results[i].Error
+		}
+
+		result.Results[i] = checkResult
+	}
+
+	return result
+}
+
+func parseAuthzCheckSettings(settings authz.Settings) *AuthzCheckSettings {
+	authzSettings := &AuthzCheckSettings{
+		Enabled: settings.Enabled,
+		Headers: make([]HTTPHeader, 0, len(settings.Headers)),
+	}
+
+	for key, values := range settings.Headers {
+		for _, value := range values {
+			authzSettings.Headers = append(authzSettings.Headers, HTTPHeader{Key: key, Value: value})
+		}
+	}
+
+	return authzSettings
+}
+
+func (r *queryResolver) ConnectionLogs(ctx context.Context) ([]ConnectionLog, error) {
+	connLogs, err := r.ConnLogService.FindConnectionLogs(ctx, connlog.FindConnectionLogsFilter{})
+	if errors.Is(err, connlog.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find connection logs: %w", err)
+	}
+
+	apiConnLogs := make([]ConnectionLog, len(connLogs))
+
+	for i, connLog := range connLogs {
+		apiConnLog := ConnectionLog{
+			ID:         connLog.ID,
+			ClientAddr: connLog.ClientAddr,
+			Host:       connLog.Host,
+			Mode:       ConnectionMode(strings.ToUpper(string(connLog.Mode))),
+			BytesUp:    int(connLog.BytesUp),
+			BytesDown:  int(connLog.BytesDown),
+			StartedAt:  connLog.StartedAt,
+			Duration:   int(connLog.Duration.Milliseconds()),
+		}
+
+		if connLog.ServerName != "" {
+			apiConnLog.ServerName = &connLogs[i].ServerName
+		}
+
+		if connLog.Capture != nil {
+			apiConnLog.Capture = &ConnectionCapture{
+				Up:        hex.EncodeToString(connLog.Capture.Up),
+				Down:      hex.EncodeToString(connLog.Capture.Down),
+				Truncated: connLog.Capture.Truncated,
+			}
+		}
+
+		if connLog.Error != "" {
+			apiConnLog.Error = &connLogs[i].Error
+		}
+
+		apiConnLogs[i] = apiConnLog
+	}
+
+	return apiConnLogs, nil
+}
+
+func (r *mutationResolver) ClearConnectionLogs(ctx context.Context) (*ClearConnectionLogsResult, error) {
+	project, err := r.ProjectService.ActiveProject(ctx)
+	if errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active project: %w", err)
+	}
+
+	if err := r.ConnLogService.ClearConnectionLogs(ctx, project.ID); err != nil {
+		return nil, fmt.Errorf("could not clear connection logs: %w", err)
+	}
+
+	return &ClearConnectionLogsResult{true}, nil
+}
+
+func (r *queryResolver) CorrelatedTraffic(ctx context.Context, correlationID ulid.ULID) (*CorrelatedTraffic, error) {
+	if _, err := r.ProjectService.ActiveProject(ctx); errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active project: %w", err)
+	}
+
+	traffic := CorrelatedTraffic{
+		CorrelationID:    correlationID,
+		HTTPRequestLogs:  make([]HTTPRequestLog, 0),
+		OastInteractions: make([]OASTInteraction, 0),
+	}
+
+	senderReq, err := r.SenderService.FindRequestByID(ctx, correlationID)
+	if err != nil && !errors.Is(err, sender.ErrRequestNotFound) {
+		return nil, fmt.Errorf("could not get sender request: %w", err)
+	}
+
+	if err == nil {
+		req, err := parseSenderRequest(senderReq)
+		if err != nil {
+			return nil, err
+		}
+
+		traffic.SenderRequest = &req
+	}
+
+	reqLogs, err := r.RequestLogService.FindCorrelatedRequests(ctx, correlationID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find correlated request logs: %w", err)
+	}
+
+	for _, reqLog := range reqLogs {
+		log, err := parseRequestLog(reqLog)
+		if err != nil {
+			return nil, err
+		}
+
+		traffic.HTTPRequestLogs = append(traffic.HTTPRequestLogs, log)
+	}
+
+	interactions, err := r.OASTService.FindInteractions(ctx, oast.FindInteractionsFilter{CorrelationID: correlationID})
+	if err != nil {
+		return nil, fmt.Errorf("could not find correlated OAST interactions: %w", err)
+	}
+
+	traffic.OastInteractions = parseOASTInteractions(interactions)
+
+	return &traffic, nil
+}
+
+func (r *queryResolver) Timeline(
+	ctx context.Context,
+	sources []TimelineSource,
+	limit *int,
+) ([]TimelineEntry, error) {
+	if limit != nil && *limit < 0 {
+		return nil, gqlerror.Errorf("limit must not be negative")
+	}
+
+	if _, err := r.ProjectService.ActiveProject(ctx); errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active project: %w", err)
+	}
+
+	include := make(map[TimelineSource]bool)
+
+	for _, source := range sources {
+		include[source] = true
+	}
+
+	if len(sources) == 0 {
+		for _, source := range AllTimelineSource {
+			include[source] = true
+		}
+	}
+
+	// Request logs of scans are proxied as well, so their source is looked up
+	// by ID.
+	scanSources := make(map[ulid.ULID]TimelineSource)
+
+	for _, scan := range r.DiscoveryService.FindScans() {
+		for _, result := range scan.Results {
+			scanSources[result.RequestLogID] = TimelineSourceContentDiscovery
+		}
+	}
+
+	for _, crawl := range r.CrawlerService.FindCrawls() {
+		for _, result := range crawl.Results {
+			scanSources[result.RequestLogID] = TimelineSourceCrawler
+		}
+	}
+
+	entries := make([]TimelineEntry, 0)
+
+	if include[TimelineSourceProxy] || include[TimelineSourceContentDiscovery] || include[TimelineSourceCrawler] {
+		reqLogs, err := r.RequestLogService.FindSelectedRequests(ctx, reqlog.Selection{Filter: &reqlog.FindRequestsFilter{}})
+		if err != nil {
+			return nil, fmt.Errorf("could not find request logs: %w", err)
+		}
+
+		for _, reqLog := range reqLogs {
+			source, ok := scanSources[reqLog.ID]
+			if !ok {
+				source = TimelineSourceProxy
+			}
+
+			if !include[source] {
+				continue
+			}
+
+			log, err := parseRequestLog(reqLog)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, TimelineEntry{
+				ID:             reqLog.ID,
+				Source:         source,
+				Timestamp:      log.Timestamp,
+				HTTPRequestLog: &log,
+			})
+		}
+	}
+
+	if include[TimelineSourceSender] {
+		senderReqs, err := r.SenderService.FindRequests(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not find sender requests: %w", err)
+		}
+
+		for _, senderReq := range senderReqs {
+			req, err := parseSenderRequest(senderReq)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, TimelineEntry{
+				ID:            senderReq.ID,
+				Source:        TimelineSourceSender,
+				Timestamp:     req.Timestamp,
+				SenderRequest: &req,
+			})
+		}
+	}
+
+	// ULIDs sort by time.
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID.Compare(entries[j].ID) < 0
+	})
+
+	if limit != nil && *limit < len(entries) {
+		entries = entries[len(entries)-*limit:]
+	}
+
+	return entries, nil
+}
+
+func (r *mutationResolver) StartContentDiscovery(
+	ctx context.Context,
+	input StartContentDiscoveryInput,
+) (*ContentDiscoveryScan, error) {
+	// Discovered paths are stored as request logs, which requires an active project.
+	if _, err := r.ProjectService.ActiveProject(ctx); errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active project: %w", err)
+	}
+
+	params := discovery.ScanParams{
+		BaseURL:           input.BaseURL,
+		Wordlist:          input.Wordlist,
+		Extensions:        input.Extensions,
+		IgnoreStatusCodes: input.IgnoreStatusCodes,
+	}
+
+	if input.RequestsPerSecond != nil {
+		params.RequestsPerSecond = *input.RequestsPerSecond
+	}
+
+	if input.Concurrency != nil {
+		params.Concurrency = *input.Concurrency
+	}
+
+	scan, err := r.DiscoveryService.StartScan(ctx, params)
+	switch {
+	case errors.Is(err, discovery.ErrOutOfScope):
+		return nil, &gqlerror.Error{
+			Path:    graphql.GetPath(ctx),
+			Message: "Base URL is out of scope.",
+			Extensions: map[string]interface{}{
+				"code": "out_of_scope",
+			},
+		}
+	case errors.Is(err, discovery.ErrEmptyWordlist):
+		return nil, gqlerror.Errorf("Wordlist is empty.")
+	case errors.Is(err, discovery.ErrInvalidRate):
+		return nil, gqlerror.Errorf("Requests per second must be at most 1000.")
+	case err != nil:
+		return nil, fmt.Errorf("could not start content discovery: %w", err)
+	}
+
+	contentDiscoveryScan := parseContentDiscoveryScan(scan)
+
+	return &contentDiscoveryScan, nil
+}
+
+func (r *mutationResolver) CancelContentDiscovery(ctx context.Context, id ulid.ULID) (*CancelContentDiscoveryResult, error) {
+	err := r.DiscoveryService.CancelScan(id)
+	if errors.Is(err, discovery.ErrScanNotFound) {
+		return nil, gqlerror.Errorf("Content discovery scan not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not cancel content discovery: %w", err)
+	}
+
+	return &CancelContentDiscoveryResult{Success: true}, nil
+}
+
+func (r *queryResolver) ContentDiscoveryScan(ctx context.Context, id ulid.ULID) (*ContentDiscoveryScan, error) {
+	scan, err := r.DiscoveryService.FindScanByID(id)
+	if errors.Is(err, discovery.ErrScanNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get content discovery scan: %w", err)
+	}
+
+	contentDiscoveryScan := parseContentDiscoveryScan(scan)
+
+	return &contentDiscoveryScan, nil
+}
+
+func (r *queryResolver) ContentDiscoveryScans(ctx context.Context) ([]ContentDiscoveryScan, error) {
+	scans := r.DiscoveryService.FindScans()
+	contentDiscoveryScans := make([]ContentDiscoveryScan, len(scans))
+
+	for i, scan := range scans {
+		contentDiscoveryScans[i] = parseContentDiscoveryScan(scan)
+	}
+
+	return contentDiscoveryScans, nil
+}
+
+func parseContentDiscoveryScan(scan discovery.Scan) ContentDiscoveryScan {
+	contentDiscoveryScan := ContentDiscoveryScan{
+		ID:        scan.ID,
+		BaseURL:   scan.BaseURL,
+		Status:    ContentDiscoveryStatus(strings.ToUpper(string(scan.Status))),
+		Total:     scan.Total,
+		Completed: scan.Completed,
+		Results:   make([]ContentDiscoveryResult, len(scan.Results)),
+		Timestamp: ulid.Time(scan.ID.Time()),
+	}
+
+	for i, result := range scan.Results {
+		contentDiscoveryScan.Results[i] = ContentDiscoveryResult{
+			URL:        result.URL,
+			StatusCode: result.StatusCode,
+			Size:       int(result.Size),
+		}
+
+		if result.RequestLogID.Compare(ulid.ULID{}) != 0 {
+			contentDiscoveryScan.Results[i].RequestLogID = &scan.Results[i].RequestLogID
+		}
+	}
+
+	return contentDiscoveryScan
+}
+
+func (r *mutationResolver) StartCrawl(ctx context.Context, input StartCrawlInput) (*Crawl, error) {
+	// Crawled requests are stored as request logs, which requires an active project.
+	if _, err := r.ProjectService.ActiveProject(ctx); errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active project: %w", err)
+	}
+
+	params := crawler.CrawlParams{
+		StartURL: input.StartURL,
+	}
+
+	if input.MaxDepth != nil {
+		params.MaxDepth = *input.MaxDepth
+	}
+
+	if input.MaxRequests != nil {
+		params.MaxRequests = *input.MaxRequests
+	}
+
+	if input.RequestsPerSecond != nil {
+		params.RequestsPerSecond = *input.RequestsPerSecond
+	}
+
+	if input.Concurrency != nil {
+		params.Concurrency = *input.Concurrency
+	}
+
+	if input.SubmitForms != nil {
+		params.SubmitForms = *input.SubmitForms
+	}
+
+	c, err := r.CrawlerService.StartCrawl(ctx, params)
+	if errors.Is(err, crawler.ErrOutOfScope) {
+		return nil, &gqlerror.Error{
+			Path:    graphql.GetPath(ctx),
+			Message: "Start URL is out of scope.",
+			Extensions: map[string]interface{}{
+				"code": "out_of_scope",
+			},
+		}
+	} else if errors.Is(err, crawler.ErrInvalidRate) {
+		return nil, gqlerror.Errorf("Requests per second must be at most 1000.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not start crawl: %w", err)
+	}
+
+	crawl := parseCrawl(c)
+
+	return &crawl, nil
+}
+
+func (r *mutationResolver) CancelCrawl(ctx context.Context, id ulid.ULID) (*CancelCrawlResult, error) {
+	err := r.CrawlerService.CancelCrawl(id)
+	if errors.Is(err, crawler.ErrCrawlNotFound) {
+		return nil, gqlerror.Errorf("Crawl not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not cancel crawl: %w", err)
+	}
+
+	return &CancelCrawlResult{Success: true}, nil
+}
+
+// exportJobType is the type of the jobs that run exports, see `job.Params`.
+const exportJobType = "export"
+
+// runExport runs an export as a job, so that it's listed with its progress and
+// can be cancelled, and waits until it's done. Without a job service, fn runs
+// right away.
+func (r *Resolver) runExport(ctx context.Context, format string, fn job.Func) error {
+	if r.JobService == nil {
+		return fn(ctx, nil)
+	}
+
+	_, err := r.JobService.RunJob(ctx, job.Params{Type: exportJobType, Description: format}, fn)
+
+	return err
+}
+
+func (r *queryResolver) Jobs(ctx context.Context) ([]Job, error) {
+	jobs, err := r.JobService.FindJobs(ctx, job.FindJobsFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get jobs: %w", err)
+	}
+
+	result := make([]Job, len(jobs))
+	for i, j := range jobs {
+		result[i] = parseJob(j)
+	}
+
+	return result, nil
+}
+
+func (r *queryResolver) Job(ctx context.Context, id ulid.ULID) (*Job, error) {
+	j, err := r.JobService.FindJobByID(ctx, id)
+	if errors.Is(err, job.ErrJobNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get job: %w", err)
+	}
+
+	result := parseJob(j)
+
+	return &result, nil
+}
+
+func (r *mutationResolver) PauseJob(ctx context.Context, id ulid.ULID) (*Job, error) {
+	return updateJob(r.JobService.PauseJob(ctx, id))
+}
+
+func (r *mutationResolver) ResumeJob(ctx context.Context, id ulid.ULID) (*Job, error) {
+	return updateJob(r.JobService.ResumeJob(ctx, id))
+}
+
+func (r *mutationResolver) CancelJob(ctx context.Context, id ulid.ULID) (*Job, error) {
+	return updateJob(r.JobService.CancelJob(ctx, id))
+}
+
+func updateJob(j job.Job, err error) (*Job, error) {
+	switch {
+	case errors.Is(err, job.ErrJobNotFound):
+		return nil, gqlerror.Errorf("Job not found.")
+	case errors.Is(err, job.ErrInvalidJobState):
+		return nil, gqlerror.Errorf("Could not update job: %v", err)
+	case err != nil:
+		return nil, fmt.Errorf("could not update job: %w", err)
+	}
+
+	result := parseJob(j)
+
+	return &result, nil
+}
+
+func parseJob(j job.Job) Job {
+	result := Job{
+		ID:          j.ID,
+		Type:        j.Type,
+		Description: j.Description,
+		Status:      JobStatus(strings.ToUpper(string(j.Status))),
+		Completed:   j.Completed,
+		Total:       j.Total,
+		Progress:    j.Progress(),
+		CreatedAt:   j.CreatedAt,
+	}
+
+	if j.Error != "" {
+		result.Error = &j.Error
+	}
+
+	if !j.StartedAt.IsZero() {
+		result.StartedAt = &j.StartedAt
+	}
+
+	if !j.FinishedAt.IsZero() {
+		result.FinishedAt = &j.FinishedAt
+	}
+
+	return result
+}
+
+// activeModuleJobTypes are the active modules that run their work as jobs, by
+// job type.
+var activeModuleJobTypes = map[string]ActiveModule{
+	crawler.JobType:    ActiveModuleCrawler,
+	discovery.JobType:  ActiveModuleContentDiscovery,
+	activescan.JobType: ActiveModuleActiveScan,
+	nuclei.JobType:     ActiveModuleNuclei,
+	replay.JobType:     ActiveModuleReplay,
+	smuggle.JobType:    ActiveModuleSmugglingTest,
+	idor.JobType:       ActiveModuleIDOrTest,
+}
+
+// StopActiveModules cancels the active jobs of all active modules, pauses the
+// sender schedules and OAuth 2.0 token fetches, and disables the replays of the
+// authorization check, even if stopping one of them fails, so that as much
+// traffic as possible is stopped. Queued and paused jobs are cancelled as
+// well, so that they don't start or resume later.
+func (r *mutationResolver) StopActiveModules(ctx context.Context) (*StopActiveModulesResult, error) {
+	result := &StopActiveModulesResult{CancelledJobs: []CancelledJob{}}
+
+	var errs []string
+
+	// Jobs have the ID of the crawl, scan, etc. they run.
+	if r.JobService != nil {
+		for _, j := range r.JobService.ActiveJobs() {
+			module, ok := activeModuleJobTypes[j.Type]
+			if !ok {
+				continue
+			}
+
+			_, err := r.JobService.CancelJob(ctx, j.ID)
+			switch {
+			case errors.Is(err, job.ErrInvalidJobState):
+				// The job is done in the meantime.
+			case err != nil:
+				errs = append(errs, fmt.Sprintf("%v %v: %v", module, j.ID, err))
+			default:
+				result.CancelledJobs = append(result.CancelledJobs, CancelledJob{Module: module, ID: j.ID})
+			}
+		}
+	}
+
+	if r.AuthzService != nil {
+		for _, check := range r.AuthzService.FindUnauthChecks() {
+			if check.Status != authz.StatusRunning {
+				continue
+			}
+
+			if err := r.AuthzService.CancelUnauthCheck(check.ID); err != nil {
+				errs = append(errs, fmt.Sprintf("%v %v: %v", ActiveModuleUnauthCheck, check.ID, err))
+				continue
+			}
+
+			result.CancelledJobs = append(result.CancelledJobs, CancelledJob{Module: ActiveModuleUnauthCheck, ID: check.ID})
+		}
+	}
+
+	// The paused and disabled settings are stored with the project, so that
+	// they don't resume when it's reopened. Settings of a project that is
+	// opened read-only only apply until it's reopened.
+	if r.SenderService != nil {
+		if schedules, n := pauseSenderSchedules(r.SenderService.Schedules()); n > 0 {
+			err := r.ProjectService.SetSenderSchedules(ctx, schedules)
+			if err != nil {
+				r.SenderService.SetSchedules(schedules)
+
+				if !errors.Is(err, proj.ErrReadOnly) {
+					errs = append(errs, fmt.Sprintf("sender schedules: %v", err))
+				}
+			}
+
+			result.StoppedSchedules = n
+		}
+	}
+
+	if r.OAuth2Service != nil {
+		if sources, n := pauseOAuth2Sources(r.OAuth2Service.Sources()); n > 0 {
+			err := r.ProjectService.SetOAuth2Sources(ctx, sources)
+			if err != nil {
+				r.OAuth2Service.SetSources(sources)
+
+				if !errors.Is(err, proj.ErrReadOnly) {
+					errs = append(errs, fmt.Sprintf("OAuth 2.0 token sources: %v", err))
+				}
+			}
+
+			result.PausedTokenSources = n
+		}
+	}
+
+	if r.AuthzService != nil {
+		if settings := r.AuthzService.Settings(); settings.Enabled {
+			settings.Enabled = false
+
+			err := r.ProjectService.SetAuthzSettings(ctx, settings)
+			if err != nil {
+				r.AuthzService.SetSettings(settings)
+
+				if !errors.Is(err, proj.ErrReadOnly) {
+					errs = append(errs, fmt.Sprintf("authorization check: %v", err))
+				}
+			}
+
+			result.DisabledAuthzReplays = true
+		}
+
+		r.AuthzService.CancelReplays()
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("could not cancel jobs: %v", strings.Join(errs, "; "))
+	}
+
+	return result, nil
+}
+
+// pauseSenderSchedules returns a copy of schedules with all of them paused, and
+// the number of schedules that weren't paused yet.
+func pauseSenderSchedules(schedules []sender.Schedule) ([]sender.Schedule, int) {
+	paused := make([]sender.Schedule, len(schedules))
+	n := 0
+
+	for i, schedule := range schedules {
+		if !schedule.Paused {
+			schedule.Paused = true
+			n++
+		}
+
+		paused[i] = schedule
+	}
+
+	return paused, n
+}
+
+// pauseOAuth2Sources returns a copy of sources with all of them paused, and the
+// number of sources that weren't paused yet.
+func pauseOAuth2Sources(sources []oauth2.Source) ([]oauth2.Source, int) {
+	paused := make([]oauth2.Source, len(sources))
+	n := 0
+
+	for i, source := range sources {
+		if !source.Paused {
+			source.Paused = true
+			n++
+		}
+
+		paused[i] = source
+	}
+
+	return paused, n
+}
+
+func (r *queryResolver) Crawl(ctx context.Context, id ulid.ULID) (*Crawl, error) {
+	c, err := r.CrawlerService.FindCrawlByID(id)
+	if errors.Is(err, crawler.ErrCrawlNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get crawl: %w", err)
+	}
+
+	crawl := parseCrawl(c)
+
+	return &crawl, nil
+}
+
+func (r *queryResolver) Crawls(ctx context.Context) ([]Crawl, error) {
+	crawls := r.CrawlerService.FindCrawls()
+	result := make([]Crawl, len(crawls))
+
+	for i, c := range crawls {
+		result[i] = parseCrawl(c)
+	}
+
+	return result, nil
+}
+
+func parseCrawl(c crawler.Crawl) Crawl {
+	crawl := Crawl{
+		ID:        c.ID,
+		StartURL:  c.StartURL,
+		Status:    CrawlStatus(strings.ToUpper(string(c.Status))),
+		Results:   make([]CrawlResult, len(c.Results)),
+		Timestamp: ulid.Time(c.ID.Time()),
+	}
+
+	for i, result := range c.Results {
+		crawl.Results[i] = CrawlResult{
+			Method:     HTTPMethod(result.Method),
+			URL:        result.URL,
+			Depth:      result.Depth,
+			StatusCode: result.StatusCode,
+		}
+
+		if result.RequestLogID.Compare(ulid.ULID{}) != 0 {
+			crawl.Results[i].RequestLogID = &c.Results[i].RequestLogID
+		}
+	}
+
+	return crawl
+}
+
+func (r *mutationResolver) StartReplay(ctx context.Context, input StartReplayInput) (*Replay, error) {
+	// Replayed requests are stored as request logs, which requires an active project.
+	if _, err := r.ProjectService.ActiveProject(ctx); errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active project: %w", err)
+	}
+
+	sel, err := selectionFromInput(*input.Selection)
+	if err != nil {
+		return nil, err
+	}
+
+	params := replay.ReplayParams{
+		Selection: sel,
+	}
+
+	if input.Timing != nil {
+		params.Timing = replay.Timing(strings.ToLower(input.Timing.String()))
+	}
+
+	if input.Speed != nil {
+		params.Speed = *input.Speed
+	}
+
+	if input.RewriteProfile != nil {
+		params.RewriteProfile = *input.RewriteProfile
+	}
+
+	if input.SigningProfile != nil {
+		params.SigningProfile = *input.SigningProfile
+	}
+
+	params.Rewrite.Hosts = hostRulesFromInput(input.HostRewrites)
+	params.Rewrite.Headers = headerFromInput(input.Headers)
+
+	rep, err := r.ReplayService.StartReplay(ctx, params)
+	switch {
+	case errors.Is(err, replay.ErrNoRequests):
+		return nil, gqlerror.Errorf("No request logs selected.")
+	case errors.Is(err, replay.ErrInvalidSpeed):
+		return nil, gqlerror.Errorf("Speed must not be negative.")
+	case errors.Is(err, replay.ErrProfileNotFound):
+		return nil, gqlerror.Errorf("Rewrite profile not found.")
+	case errors.Is(err, replay.ErrSigningProfileNotFound):
+		return nil, gqlerror.Errorf("Signing profile not found.")
+	case err != nil:
+		return nil, fmt.Errorf("could not start replay: %w", err)
+	}
+
+	result, err := parseReplay(rep)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *mutationResolver) CancelReplay(ctx context.Context, id ulid.ULID) (*CancelReplayResult, error) {
+	err := r.ReplayService.CancelReplay(id)
+	if errors.Is(err, replay.ErrReplayNotFound) {
+		return nil, gqlerror.Errorf("Replay not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not cancel replay: %w", err)
+	}
+
+	return &CancelReplayResult{Success: true}, nil
+}
+
+func (r *queryResolver) Replay(ctx context.Context, id ulid.ULID) (*Replay, error) {
+	rep, err := r.ReplayService.FindReplayByID(id)
+	if errors.Is(err, replay.ErrReplayNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get replay: %w", err)
+	}
+
+	result, err := parseReplay(rep)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *queryResolver) Replays(ctx context.Context) ([]Replay, error) {
+	replays := r.ReplayService.FindReplays()
+	result := make([]Replay, len(replays))
+
+	for i, rep := range replays {
+		var err error
+
+		result[i], err = parseReplay(rep)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func parseReplay(rep replay.Replay) (Replay, error) {
+	result := Replay{
+		ID:        rep.ID,
+		Status:    ReplayStatus(strings.ToUpper(string(rep.Status))),
+		Timing:    ReplayTiming(strings.ToUpper(string(rep.Timing))),
+		Speed:     rep.Speed,
+		Total:     rep.Total,
+		Completed: rep.Completed,
+		Results:   make([]ReplayResult, len(rep.Results)),
+		Timestamp: ulid.Time(rep.ID.Time()),
+	}
+
+	for i, res := range rep.Results {
+		replayResult := ReplayResult{
+			OriginalRequestLogID: res.OriginalRequestLogID,
+			Method:               HTTPMethod(res.Method),
+			URL:                  res.URL,
+			Duration:             int(res.Duration.Milliseconds()),
+			StatusCodeChanged:    res.StatusCodeChanged(),
+			BodyChanged:          res.BodyChanged(),
+		}
+
+		if res.RequestLogID.Compare(ulid.ULID{}) != 0 {
+			replayResult.RequestLogID = &rep.Results[i].RequestLogID
+		}
+
+		if res.Error != "" {
+			replayResult.Error = &rep.Results[i].Error
+		}
+
+		if res.OriginalResponse != nil {
+			resLog, err := parseResponseLog(*res.OriginalResponse)
+			if err != nil {
+				return Replay{}, err
+			}
+
+			resLog.ID = res.OriginalRequestLogID
+			replayResult.OriginalResponse = &resLog
+		}
+
+		if res.Response != nil {
+			resLog, err := parseResponseLog(*res.Response)
+			if err != nil {
+				return Replay{}, err
+			}
+
+			resLog.ID = res.RequestLogID
+			replayResult.Response = &resLog
+		}
+
+		result.Results[i] = replayResult
+	}
+
+	return result, nil
+}
+
+func (r *mutationResolver) StartSmugglingTest(ctx context.Context, input StartSmugglingTestInput) (*SmugglingTest, error) {
+	// Tests are run for request logs, and their findings are stored for the
+	// active project.
+	if _, err := r.ProjectService.ActiveProject(ctx); errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active project: %w", err)
+	}
+
+	params := smuggle.TestParams{
+		RequestLogID: input.RequestLogID,
+	}
+
+	if input.Timeout != nil {
+		params.Timeout = msToDuration(*input.Timeout)
+	}
+
+	test, err := r.SmugglingService.StartTest(ctx, params)
+	switch {
+	case errors.Is(err, reqlog.ErrRequestNotFound):
+		return nil, gqlerror.Errorf("Request log not found.")
+	case errors.Is(err, smuggle.ErrOutOfScope):
+		return nil, &gqlerror.Error{
+			Path:    graphql.GetPath(ctx),
+			Message: "Request is out of scope.",
+			Extensions: map[string]interface{}{
+				"code": "out_of_scope",
+			},
+		}
+	case err != nil:
+		return nil, fmt.Errorf("could not start smuggling test: %w", err)
+	}
+
+	smugglingTest := parseSmugglingTest(test)
+
+	return &smugglingTest, nil
+}
+
+func (r *mutationResolver) CancelSmugglingTest(ctx context.Context, id ulid.ULID) (*CancelSmugglingTestResult, error) {
+	err := r.SmugglingService.CancelTest(id)
+	if errors.Is(err, smuggle.ErrTestNotFound) {
+		return nil, gqlerror.Errorf("Smuggling test not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not cancel smuggling test: %w", err)
+	}
+
+	return &CancelSmugglingTestResult{Success: true}, nil
+}
+
+func (r *queryResolver) SmugglingTest(ctx context.Context, id ulid.ULID) (*SmugglingTest, error) {
+	test, err := r.SmugglingService.FindTestByID(id)
+	if errors.Is(err, smuggle.ErrTestNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get smuggling test: %w", err)
+	}
+
+	smugglingTest := parseSmugglingTest(test)
+
+	return &smugglingTest, nil
+}
+
+func (r *queryResolver) SmugglingTests(ctx context.Context) ([]SmugglingTest, error) {
+	tests := r.SmugglingService.FindTests()
+	smugglingTests := make([]SmugglingTest, len(tests))
+
+	for i, test := range tests {
+		smugglingTests[i] = parseSmugglingTest(test)
+	}
+
+	return smugglingTests, nil
+}
+
+func parseSmugglingTest(test smuggle.Test) SmugglingTest {
+	smugglingTest := SmugglingTest{
+		ID:               test.ID,
+		RequestLogID:     test.RequestLogID,
+		URL:              test.URL,
+		Status:           SmugglingTestStatus(strings.ToUpper(string(test.Status))),
+		BaselineDuration: int(test.BaselineDuration.Milliseconds()),
+		Results:          make([]SmugglingProbeResult, len(test.Results)),
+		Timestamp:        ulid.Time(test.ID.Time()),
+	}
+
+	if test.Error != "" {
+		smugglingTest.Error = &test.Error
+	}
+
+	for i, result := range test.Results {
+		smugglingTest.Results[i] = SmugglingProbeResult{
+			Technique:  SmugglingTechnique(strings.ToUpper(string(result.Technique))),
+			Variant:    result.Variant,
+			Duration:   int(result.Duration.Milliseconds()),
+			TimedOut:   result.TimedOut,
+			Vulnerable: result.Vulnerable,
+		}
+
+		if result.StatusCode != 0 {
+			smugglingTest.Results[i].StatusCode = &test.Results[i].StatusCode
+		}
+	}
+
+	return smugglingTest
+}
+
+func (r *queryResolver) IdorIdentifiers(ctx context.Context, requestLogID ulid.ULID) ([]IdorIdentifier, error) {
+	ids, err := r.IDORService.FindIdentifiers(ctx, requestLogID)
+	if errors.Is(err, reqlog.ErrRequestNotFound) {
+		return nil, gqlerror.Errorf("Request log not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find identifiers: %w", err)
+	}
+
+	idorIDs := make([]IdorIdentifier, len(ids))
+
+	for i, id := range ids {
+		idorIDs[i] = parseIdorIdentifier(id)
+	}
+
+	return idorIDs, nil
+}
+
+func (r *mutationResolver) StartIdorTest(ctx context.Context, input StartIdorTestInput) (*IdorTest, error) {
+	// Findings of tests are stored for the active project.
+	if _, err := r.ProjectService.ActiveProject(ctx); errors.Is(err, proj.ErrNoProject) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active project: %w", err)
+	}
+
+	params := idor.TestParams{
+		RequestLogID: input.RequestLogID,
+		Identifiers:  make([]idor.Identifier, len(input.Identifiers)),
+		Values:       input.Values,
+	}
+
+	for i, id := range input.Identifiers {
+		params.Identifiers[i] = idor.Identifier{
+			Location: idor.Location(strings.ToLower(id.Location.String())),
+			Name:     id.Name,
+		}
+	}
+
+	test, err := r.IDORService.StartTest(ctx, params)
+	switch {
+	case errors.Is(err, reqlog.ErrRequestNotFound):
+		return nil, gqlerror.Errorf("Request log not found.")
+	case errors.Is(err, idor.ErrNoMutations):
+		return nil, gqlerror.Errorf("Request has no identifiers to replace with the given values.")
+	case errors.Is(err, idor.ErrOutOfScope):
+		return nil, &gqlerror.Error{
+			Path:    graphql.GetPath(ctx),
+			Message: "Request is out of scope.",
+			Extensions: map[string]interface{}{
+				"code": "out_of_scope",
+			},
+		}
+	case err != nil:
+		return nil, fmt.Errorf("could not start IDOR test: %w", err)
+	}
+
+	return parseIdorTest(test), nil
+}
+
+func (r *mutationResolver) CancelIdorTest(ctx context.Context, id ulid.ULID) (*CancelIdorTestResult, error) {
+	err := r.IDORService.CancelTest(id)
+	if errors.Is(err, idor.ErrTestNotFound) {
+		return nil, gqlerror.Errorf("IDOR test not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not cancel IDOR test: %w", err)
+	}
+
+	return &CancelIdorTestResult{Success: true}, nil
+}
+
+func (r *queryResolver) IdorTest(ctx context.Context, id ulid.ULID) (*IdorTest, error) {
+	test, err := r.IDORService.FindTestByID(id)
+	if errors.Is(err, idor.ErrTestNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get IDOR test: %w", err)
+	}
+
+	return parseIdorTest(test), nil
+}
+
+func (r *queryResolver) IdorTests(ctx context.Context) ([]IdorTest, error) {
+	tests := r.IDORService.FindTests()
+	idorTests := make([]IdorTest, len(tests))
+
+	for i, test := range tests {
+		idorTests[i] = *parseIdorTest(test)
+	}
+
+	return idorTests, nil
+}
+
+func parseIdorIdentifier(id idor.Identifier) IdorIdentifier {
+	return IdorIdentifier{
+		Location: IdorLocation(strings.ToUpper(string(id.Location))),
+		Name:     id.Name,
+		Value:    id.Value,
+		Kind:     IdorIdentifierKind(strings.ToUpper(string(id.Kind))),
+	}
+}
+
+func parseIdorTest(test idor.Test) *IdorTest {
+	idorTest := &IdorTest{
+		ID:           test.ID,
+		RequestLogID: test.RequestLogID,
+		URL:          test.URL,
+		Status:       IdorTestStatus(strings.ToUpper(string(test.Status))),
+		Total:        test.Total,
+		Completed:    test.Completed,
+		Results:      make([]IdorTestResult, len(test.Results)),
+		Timestamp:    ulid.Time(test.ID.Time()),
+	}
+
+	for i, result := range test.Results {
+		id := parseIdorIdentifier(result.Identifier)
+
+		idorTest.Results[i] = IdorTestResult{
+			Identifier: &id,
+			Value:      result.Value,
+			BodySize:   result.BodySize,
+			Flagged:    result.Flagged,
+		}
+
+		if result.RequestLogID.Compare(ulid.ULID{}) != 0 {
+			idorTest.Results[i].RequestLogID = &test.Results[i].RequestLogID
+		}
+
+		if result.StatusCode != 0 {
+			idorTest.Results[i].StatusCode = &test.Results[i].StatusCode
+		}
+
+		if result.Error != "" {
+			idorTest.Results[i].Error = &test.Results[i].Error
+		}
+	}
+
+	return idorTest
+}
+
+func (r *queryResolver) ActiveScanPayloads(ctx context.Context) ([]ActiveScanPayload, error) {
+	payloads := activescan.DefaultPayloads()
+	activeScanPayloads := make([]ActiveScanPayload, len(payloads))
+
+	for i, payload := range payloads {
+		activeScanPayloads[i] = ActiveScanPayload{
+			Name:     payload.Name,
+			Category: ActiveScanCategory(strings.ToUpper(string(payload.Category))),
+			Value:    payload.Value,
+			Append:   payload.Append,
+			Rules:    make([]ActiveScanRule, len(payload.Rules)),
+		}
+
+		for j, rule := range payload.Rules {
+			activeScanPayloads[i].Rules[j] = ActiveScanRule{
+				Type:  ActiveScanRuleType(strings.ToUpper(string(rule.Type))),
+				Value: rule.Value,
+			}
+		}
+	}
+
+	return activeScanPayloads, nil
+}
+
+func (r *mutationResolver) StartActiveScan(ctx context.Context, input StartActiveScanInput) (*ActiveScan, error) {
+	sel, err := selectionFromInput(*input.Selection)
+	if err != nil {
 		return nil, err
 	}
 
-	return &req, nil
+	params := activescan.ScanParams{
+		Selection:  sel,
+		Payloads:   make([]activescan.Payload, len(input.Payloads)),
+		Parameters: input.Parameters,
+	}
+
+	for i, payload := range input.Payloads {
+		params.Payloads[i] = activescan.Payload{
+			Name:     payload.Name,
+			Category: activescan.Category(strings.ToLower(payload.Category.String())),
+			Value:    payload.Value,
+			Rules:    make([]activescan.Rule, len(payload.Rules)),
+		}
+
+		if payload.Append != nil {
+			params.Payloads[i].Append = *payload.Append
+		}
+
+		for j, rule := range payload.Rules {
+			params.Payloads[i].Rules[j] = activescan.Rule{
+				Type:  activescan.RuleType(strings.ToLower(rule.Type.String())),
+				Value: rule.Value,
+			}
+		}
+	}
+
+	scan, err := r.ActiveScanService.StartScan(ctx, params)
+	switch {
+	case errors.Is(err, reqlog.ErrProjectIDMustBeSet):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, activescan.ErrInvalidPayload):
+		return nil, gqlerror.Errorf("Could not start active scan: %v", err)
+	case errors.Is(err, activescan.ErrNoRequests):
+		return nil, gqlerror.Errorf("No in-scope request logs with parameters and a response selected.")
+	case errors.Is(err, activescan.ErrTooManyRequests):
+		return nil, gqlerror.Errorf("Active scan exceeds the maximum number of requests, select fewer request logs, " +
+			"parameters or payloads.")
+	case err != nil:
+		return nil, fmt.Errorf("could not start active scan: %w", err)
+	}
+
+	return parseActiveScan(scan), nil
+}
+
+func (r *mutationResolver) CancelActiveScan(ctx context.Context, id ulid.ULID) (*CancelActiveScanResult, error) {
+	err := r.ActiveScanService.CancelScan(id)
+	if errors.Is(err, activescan.ErrScanNotFound) {
+		return nil, gqlerror.Errorf("Active scan not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not cancel active scan: %w", err)
+	}
+
+	return &CancelActiveScanResult{Success: true}, nil
+}
+
+func (r *queryResolver) ActiveScan(ctx context.Context, id ulid.ULID) (*ActiveScan, error) {
+	scan, err := r.ActiveScanService.FindScanByID(id)
+	if errors.Is(err, activescan.ErrScanNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get active scan: %w", err)
+	}
+
+	return parseActiveScan(scan), nil
+}
+
+func (r *queryResolver) ActiveScans(ctx context.Context) ([]ActiveScan, error) {
+	scans := r.ActiveScanService.FindScans()
+	activeScans := make([]ActiveScan, len(scans))
+
+	for i, scan := range scans {
+		activeScans[i] = *parseActiveScan(scan)
+	}
+
+	return activeScans, nil
+}
+
+func parseActiveScan(scan activescan.Scan) *ActiveScan {
+	activeScan := &ActiveScan{
+		ID:         scan.ID,
+		Status:     ActiveScanStatus(strings.ToUpper(string(scan.Status))),
+		Total:      scan.Total,
+		Completed:  scan.Completed,
+		Errors:     scan.Errors,
+		Detections: make([]ActiveScanDetection, len(scan.Detections)),
+		Timestamp:  ulid.Time(scan.ID.Time()),
+	}
+
+	for i, detection := range scan.Detections {
+		activeScan.Detections[i] = ActiveScanDetection{
+			RequestLogID: detection.RequestLogID,
+			Method:       HTTPMethod(detection.Method),
+			URL:          detection.URL,
+			Parameter: &ActiveScanParameter{
+				Location: ActiveScanLocation(strings.ToUpper(string(detection.Parameter.Location))),
+				Name:     detection.Parameter.Name,
+				Value:    detection.Parameter.Value,
+			},
+			PayloadName: detection.PayloadName,
+			Category:    ActiveScanCategory(strings.ToUpper(string(detection.Category))),
+			Payload:     detection.Payload,
+			StatusCode:  detection.StatusCode,
+			Evidence:    detection.Evidence,
+		}
+
+		if detection.ProbeRequestLogID.Compare(ulid.ULID{}) != 0 {
+			activeScan.Detections[i].ProbeRequestLogID = &scan.Detections[i].ProbeRequestLogID
+		}
+	}
+
+	return activeScan
+}
+
+func (r *queryResolver) NucleiTargets(ctx context.Context) ([]*url.URL, error) {
+	targets, err := r.NucleiService.FindTargets(ctx)
+	if errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+		return nil, noActiveProjectErr(ctx)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not find nuclei targets: %w", err)
+	}
+
+	return targets, nil
+}
+
+func (r *mutationResolver) StartNucleiRun(ctx context.Context, input StartNucleiRunInput) (*NucleiRun, error) {
+	run, err := r.NucleiService.StartRun(ctx, nuclei.RunParams{
+		Templates: input.Templates,
+		Hosts:     input.Hosts,
+	})
+	switch {
+	case errors.Is(err, reqlog.ErrProjectIDMustBeSet):
+		return nil, noActiveProjectErr(ctx)
+	case errors.Is(err, nuclei.ErrInvalidTemplate):
+		return nil, gqlerror.Errorf("Could not start nuclei run: %v", err)
+	case errors.Is(err, nuclei.ErrNoTemplates):
+		return nil, gqlerror.Errorf("At least one template must be set.")
+	case errors.Is(err, nuclei.ErrNoTargets):
+		return nil, gqlerror.Errorf("No in-scope hosts in site map.")
+	case errors.Is(err, nuclei.ErrTooManyRequests):
+		return nil, gqlerror.Errorf("Nuclei run exceeds the maximum number of requests, use fewer templates or hosts.")
+	case err != nil:
+		return nil, fmt.Errorf("could not start nuclei run: %w", err)
+	}
+
+	return parseNucleiRun(run), nil
+}
+
+func (r *mutationResolver) CancelNucleiRun(ctx context.Context, id ulid.ULID) (*CancelNucleiRunResult, error) {
+	err := r.NucleiService.CancelRun(id)
+	if errors.Is(err, nuclei.ErrRunNotFound) {
+		return nil, gqlerror.Errorf("Nuclei run not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not cancel nuclei run: %w", err)
+	}
+
+	return &CancelNucleiRunResult{Success: true}, nil
+}
+
+func (r *queryResolver) NucleiRun(ctx context.Context, id ulid.ULID) (*NucleiRun, error) {
+	run, err := r.NucleiService.FindRunByID(id)
+	if errors.Is(err, nuclei.ErrRunNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get nuclei run: %w", err)
+	}
+
+	return parseNucleiRun(run), nil
+}
+
+func (r *queryResolver) NucleiRuns(ctx context.Context) ([]NucleiRun, error) {
+	runs := r.NucleiService.FindRuns()
+	nucleiRuns := make([]NucleiRun, len(runs))
+
+	for i, run := range runs {
+		nucleiRuns[i] = *parseNucleiRun(run)
+	}
+
+	return nucleiRuns, nil
 }
 
-func (r *queryResolver) SenderRequests(ctx context.Context) ([]SenderRequest, error) {
-	reqs, err := r.SenderService.FindRequests(ctx)
-	if errors.Is(err, proj.ErrNoProject) {
-		return nil, noActiveProjectErr(ctx)
-	} else if err != nil {
-		return nil, fmt.Errorf("failed to find sender requests: %w", err)
+func parseNucleiRun(run nuclei.Run) *NucleiRun {
+	nucleiRun := &NucleiRun{
+		ID:        run.ID,
+		Status:    NucleiRunStatus(strings.ToUpper(string(run.Status))),
+		Templates: run.Templates,
+		Targets:   run.Targets,
+		Total:     run.Total,
+		Completed: run.Completed,
+		Errors:    run.Errors,
+		Matches:   make([]NucleiMatch, len(run.Matches)),
+		Timestamp: ulid.Time(run.ID.Time()),
 	}
 
-	senderReqs := make([]SenderRequest, len(reqs))
+	for i, match := range run.Matches {
+		nucleiRun.Matches[i] = NucleiMatch{
+			TemplateID:   match.TemplateID,
+			TemplateName: match.TemplateName,
+			Severity:     FindingSeverity(strings.ToUpper(string(match.Severity))),
+			URL:          match.URL,
+			StatusCode:   match.StatusCode,
+		}
 
-	for i, req := range reqs {
-		req, err := parseSenderRequest(req)
-		if err != nil {
-			return nil, err
+		if match.RequestLogID.Compare(ulid.ULID{}) != 0 {
+			nucleiRun.Matches[i].RequestLogID = &run.Matches[i].RequestLogID
 		}
+	}
 
-		senderReqs[i] = req
+	return nucleiRun
+}
+
+func (r *mutationResolver) LaunchBrowser(ctx context.Context) (*LaunchBrowserResult, error) {
+	_, err := r.BrowserLauncher.Launch("http://hetty.proxy/")
+	if errors.Is(err, browser.ErrNotFound) {
+		return nil, &gqlerror.Error{
+			Path:    graphql.GetPath(ctx),
+			Message: "Could not find a Chromium or Google Chrome executable.",
+			Extensions: map[string]interface{}{
+				"code": "browser_not_found",
+			},
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("could not launch browser: %w", err)
 	}
 
-	return senderReqs, nil
+	return &LaunchBrowserResult{Success: true}, nil
 }
 
-func (r *mutationResolver) SetSenderRequestFilter(
+func (r *queryResolver) UpstreamTimeouts(ctx context.Context) (*UpstreamTimeouts, error) {
+	return parseUpstreamTimeouts(r.Proxy.TransportConfig()), nil
+}
+
+func (r *mutationResolver) SetUpstreamTimeouts(
 	ctx context.Context,
-	input *SenderRequestFilterInput,
-) (*SenderRequestFilter, error) {
-	filter, err := findSenderRequestsFilterFromInput(input)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse request log filter: %w", err)
+	input UpstreamTimeoutsInput,
+) (*UpstreamTimeouts, error) {
+	for _, timeout := range []int{input.Dial, input.TLSHandshake, input.ResponseHeader, input.Request} {
+		if timeout < 0 {
+			return nil, gqlerror.Errorf("Timeouts must not be negative.")
+		}
 	}
 
-	err = r.ProjectService.SetSenderRequestFindFilter(ctx, filter)
-	if errors.Is(err, proj.ErrNoProject) {
-		return nil, noActiveProjectErr(ctx)
-	} else if err != nil {
-		return nil, fmt.Errorf("could not set request log filter: %w", err)
+	cfg := r.Proxy.TransportConfig()
+	cfg.DialTimeout = msToDuration(input.Dial)
+	cfg.TLSHandshakeTimeout = msToDuration(input.TLSHandshake)
+	cfg.ResponseHeaderTimeout = msToDuration(input.ResponseHeader)
+	cfg.RequestTimeout = msToDuration(input.Request)
+	cfg.HostTimeouts = make([]proxy.HostTimeouts, 0, len(input.Hosts))
+
+	for _, host := range input.Hosts {
+		if strings.TrimSpace(host.Host) == "" {
+			return nil, gqlerror.Errorf("Host must not be empty.")
+		}
+
+		for _, timeout := range []int{host.Dial, host.TLSHandshake, host.ResponseHeader, host.Request} {
+			if timeout < 0 {
+				return nil, gqlerror.Errorf("Timeouts must not be negative.")
+			}
+		}
+
+		cfg.HostTimeouts = append(cfg.HostTimeouts, proxy.HostTimeouts{
+			Host:                  strings.TrimSpace(host.Host),
+			DialTimeout:           msToDuration(host.Dial),
+			TLSHandshakeTimeout:   msToDuration(host.TLSHandshake),
+			ResponseHeaderTimeout: msToDuration(host.ResponseHeader),
+			RequestTimeout:        msToDuration(host.Request),
+		})
 	}
 
-	return findReqFilterToSenderReqFilter(filter), nil
+	r.Proxy.SetTransportConfig(cfg)
+
+	return parseUpstreamTimeouts(cfg), nil
 }
 
-func (r *mutationResolver) CreateOrUpdateSenderRequest(ctx context.Context, input SenderRequestInput) (*SenderRequest, error) {
-	req := sender.Request{
-		URL:    input.URL,
-		Header: make(http.Header),
+func (r *queryResolver) UpstreamRateLimits(ctx context.Context) ([]UpstreamRateLimit, error) {
+	limiter := r.Proxy.TransportConfig().RateLimiter
+	if limiter == nil {
+		return nil, gqlerror.Errorf("Rate limiting isn't configured.")
 	}
 
-	if input.ID != nil {
-		req.ID = *input.ID
-	}
+	return parseRateLimitRules(limiter.Rules()), nil
+}
 
-	if input.Method != nil {
-		req.Method = input.Method.String()
+func (r *mutationResolver) SetUpstreamRateLimits(
+	ctx context.Context,
+	limits []UpstreamRateLimitInput,
+) ([]UpstreamRateLimit, error) {
+	limiter := r.Proxy.TransportConfig().RateLimiter
+	if limiter == nil {
+		return nil, gqlerror.Errorf("Rate limiting isn't configured.")
 	}
 
-	if input.Proto != nil {
-		req.Proto = revHTTPProtocolMap[*input.Proto]
-	}
+	rules := make([]ratelimit.Rule, len(limits))
 
-	for _, header := range input.Headers {
-		req.Header.Add(header.Key, header.Value)
-	}
+	for i, limit := range limits {
+		if strings.TrimSpace(limit.Host) == "" {
+			return nil, gqlerror.Errorf("Host must not be empty.")
+		}
 
-	if input.Body != nil {
-		req.Body = []byte(*input.Body)
-	}
+		if limit.Rate <= 0 {
+			return nil, gqlerror.Errorf("Rate must be positive.")
+		}
 
-	req, err := r.SenderService.CreateOrUpdateRequest(ctx, req)
-	if errors.Is(err, proj.ErrNoProject) {
-		return nil, noActiveProjectErr(ctx)
-	} else if err != nil {
-		return nil, fmt.Errorf("could not create sender request: %w", err)
+		rules[i] = ratelimit.Rule{Host: strings.TrimSpace(limit.Host), Rate: limit.Rate, Burst: 1}
+
+		if limit.Burst != nil {
+			if *limit.Burst < 1 {
+				return nil, gqlerror.Errorf("Burst must be at least 1.")
+			}
+
+			rules[i].Burst = *limit.Burst
+		}
 	}
 
-	senderReq, err := parseSenderRequest(req)
-	if err != nil {
-		return nil, err
+	limiter.SetRules(rules)
+
+	return parseRateLimitRules(limiter.Rules()), nil
+}
+
+func parseRateLimitRules(rules []ratelimit.Rule) []UpstreamRateLimit {
+	limits := make([]UpstreamRateLimit, len(rules))
+
+	for i, rule := range rules {
+		limits[i] = UpstreamRateLimit{Host: rule.Host, Rate: rule.Rate, Burst: rule.Burst}
 	}
 
-	return &senderReq, nil
+	return limits
 }
 
-func (r *mutationResolver) CreateSenderRequestFromHTTPRequestLog(ctx context.Context, id ulid.ULID) (*SenderRequest, error) {
-	req, err := r.SenderService.CloneFromRequestLog(ctx, id)
-	if errors.Is(err, proj.ErrNoProject) {
-		return nil, noActiveProjectErr(ctx)
+func (r *queryResolver) ProxyBlockRules(ctx context.Context) ([]ProxyBlockRule, error) {
+	return parseBlockRules(r.Proxy.BlockRules()), nil
+}
+
+func (r *mutationResolver) SetProxyBlockRules(ctx context.Context, input []ProxyBlockRuleInput) ([]ProxyBlockRule, error) {
+	rules := make([]proxy.BlockRule, len(input))
+
+	for i, rule := range input {
+		if rule.Host != nil {
+			rules[i].Host = strings.TrimSpace(*rule.Host)
+		}
+
+		if rule.URL != nil {
+			re, err := regexp.Compile(*rule.URL)
+			if err != nil {
+				return nil, gqlerror.Errorf("Invalid URL pattern: %v", err)
+			}
+
+			rules[i].URL = re
+		}
+	}
+
+	if err := r.Proxy.SetBlockRules(rules); errors.Is(err, proxy.ErrInvalidBlockRule) {
+		return nil, gqlerror.Errorf("Invalid block rule: a host or URL pattern must be set.")
 	} else if err != nil {
-		return nil, fmt.Errorf("could not create sender request from http request log: %w", err)
+		return nil, fmt.Errorf("could not set block rules: %w", err)
 	}
 
-	senderReq, err := parseSenderRequest(req)
-	if err != nil {
-		return nil, err
+	return parseBlockRules(rules), nil
+}
+
+func parseBlockRules(rules []proxy.BlockRule) []ProxyBlockRule {
+	blockRules := make([]ProxyBlockRule, len(rules))
+
+	for i, rule := range rules {
+		if rule.Host != "" {
+			blockRules[i].Host = &rules[i].Host
+		}
+
+		blockRules[i].URL = regexpToStringPtr(rule.URL)
 	}
 
-	return &senderReq, nil
+	return blockRules
 }
 
-func (r *mutationResolver) SendRequest(ctx context.Context, id ulid.ULID) (*SenderRequest, error) {
-	// Use new context, because we don't want to risk interrupting sending the request
-	// or the subsequent storing of the response, e.g. if ctx gets cancelled or
-	// times out.
-	ctx2 := context.Background()
+func (r *queryResolver) InterceptBreakpoints(ctx context.Context) ([]InterceptBreakpoint, error) {
+	return parseInterceptBreakpoints(r.InterceptService.Breakpoints()), nil
+}
 
-	var sendErr *sender.SendError
+func (r *queryResolver) InterceptedRequests(ctx context.Context) ([]InterceptedRequest, error) {
+	reqs := r.InterceptService.FindRequests()
+	result := make([]InterceptedRequest, len(reqs))
 
-	req, err := r.SenderService.SendRequest(ctx2, id)
-	if errors.Is(err, proj.ErrNoProject) {
-		return nil, noActiveProjectErr(ctx)
-	} else if errors.As(err, &sendErr) {
-		return nil, &gqlerror.Error{
-			Path:    graphql.GetPath(ctx),
-			Message: fmt.Sprintf("Sending request failed: %v", sendErr.Unwrap()),
-			Extensions: map[string]interface{}{
-				"code": "send_request_failed",
-			},
+	for i, req := range reqs {
+		interceptedReq, err := parseInterceptedRequest(req)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse intercepted request: %w", err)
 		}
-	} else if err != nil {
-		return nil, fmt.Errorf("could not send request: %w", err)
+
+		result[i] = interceptedReq
 	}
 
-	senderReq, err := parseSenderRequest(req)
-	if err != nil {
-		return nil, err
+	return result, nil
+}
+
+func (r *mutationResolver) SetInterceptBreakpoints(
+	ctx context.Context,
+	input []InterceptBreakpointInput,
+) ([]InterceptBreakpoint, error) {
+	breakpoints := make([]intercept.Breakpoint, len(input))
+
+	for i, bp := range input {
+		breakpoint, err := intercept.ParseBreakpoint(bp.Expression)
+		if errors.Is(err, intercept.ErrInvalidBreakpoint) {
+			return nil, gqlerror.Errorf("Invalid breakpoint: %v", err)
+		} else if err != nil {
+			return nil, fmt.Errorf("could not parse breakpoint: %w", err)
+		}
+
+		breakpoints[i] = breakpoint
 	}
 
-	return &senderReq, nil
+	r.InterceptService.SetBreakpoints(breakpoints)
+
+	return parseInterceptBreakpoints(breakpoints), nil
 }
 
-func (r *mutationResolver) DeleteSenderRequests(ctx context.Context) (*DeleteSenderRequestsResult, error) {
-	project, err := r.ProjectService.ActiveProject(ctx)
-	if errors.Is(err, proj.ErrNoProject) {
-		return nil, noActiveProjectErr(ctx)
+func (r *mutationResolver) ContinueInterceptedRequest(
+	ctx context.Context,
+	id ulid.ULID,
+) (*ContinueInterceptedRequestResult, error) {
+	err := r.InterceptService.ContinueRequest(id)
+	if errors.Is(err, intercept.ErrRequestNotFound) {
+		return nil, gqlerror.Errorf("Intercepted request not found.")
 	} else if err != nil {
-		return nil, fmt.Errorf("could not get active project: %w", err)
+		return nil, fmt.Errorf("could not continue intercepted request: %w", err)
 	}
 
-	if err := r.SenderService.DeleteRequests(ctx, project.ID); err != nil {
-		return nil, fmt.Errorf("could not clear request log: %w", err)
+	return &ContinueInterceptedRequestResult{Success: true}, nil
+}
+
+func (r *mutationResolver) DropInterceptedRequest(ctx context.Context, id ulid.ULID) (*DropInterceptedRequestResult, error) {
+	err := r.InterceptService.DropRequest(id)
+	if errors.Is(err, intercept.ErrRequestNotFound) {
+		return nil, gqlerror.Errorf("Intercepted request not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not drop intercepted request: %w", err)
 	}
 
-	return &DeleteSenderRequestsResult{true}, nil
+	return &DropInterceptedRequestResult{Success: true}, nil
 }
 
-func parseSenderRequest(req sender.Request) (SenderRequest, error) {
-	method := HTTPMethod(req.Method)
-	if method != "" && !method.IsValid() {
-		return SenderRequest{}, fmt.Errorf("sender request has invalid method: %v", method)
-	}
+func parseInterceptBreakpoints(breakpoints []intercept.Breakpoint) []InterceptBreakpoint {
+	result := make([]InterceptBreakpoint, len(breakpoints))
 
-	reqProto := httpProtocolMap[req.Proto]
-	if !reqProto.IsValid() {
-		return SenderRequest{}, fmt.Errorf("sender request has invalid protocol: %v", req.Proto)
+	for i, bp := range breakpoints {
+		result[i] = InterceptBreakpoint{Expression: bp.Expression}
 	}
 
-	senderReq := SenderRequest{
-		ID:        req.ID,
-		URL:       req.URL,
-		Method:    method,
-		Proto:     HTTPProtocol(req.Proto),
-		Timestamp: ulid.Time(req.ID.Time()),
-	}
+	return result
+}
 
-	if req.SourceRequestLogID.Compare(ulid.ULID{}) != 0 {
-		senderReq.SourceRequestLogID = &req.SourceRequestLogID
+func parseInterceptedRequest(req intercept.Request) (InterceptedRequest, error) {
+	method := HTTPMethod(req.Method)
+	if method != "" && !method.IsValid() {
+		return InterceptedRequest{}, fmt.Errorf("request has invalid method: %v", method)
 	}
 
-	if req.Header != nil {
-		senderReq.Headers = make([]HTTPHeader, 0)
+	result := InterceptedRequest{
+		ID:            req.ID,
+		Method:        method,
+		URL:           req.URL,
+		Proto:         req.Proto,
+		Headers:       make([]HTTPHeader, 0),
+		Breakpoint:    req.Breakpoint,
+		InterceptedAt: req.InterceptedAt,
+	}
 
-		for key, values := range req.Header {
-			for _, value := range values {
-				senderReq.Headers = append(senderReq.Headers, HTTPHeader{
-					Key:   key,
-					Value: value,
-				})
-			}
+	for key, values := range req.Header {
+		for _, value := range values {
+			result.Headers = append(result.Headers, HTTPHeader{Key: key, Value: value})
 		}
 	}
 
 	if len(req.Body) > 0 {
-		bodyStr := string(req.Body)
-		senderReq.Body = &bodyStr
+		body := string(req.Body)
+		result.Body = &body
 	}
 
-	if req.Response != nil {
-		resLog, err := parseResponseLog(*req.Response)
-		if err != nil {
-			return SenderRequest{}, err
-		}
+	return result, nil
+}
 
-		resLog.ID = req.ID
+func parseUpstreamTimeouts(cfg proxy.TransportConfig) *UpstreamTimeouts {
+	timeouts := &UpstreamTimeouts{
+		Dial:           int(cfg.DialTimeout.Milliseconds()),
+		TLSHandshake:   int(cfg.TLSHandshakeTimeout.Milliseconds()),
+		ResponseHeader: int(cfg.ResponseHeaderTimeout.Milliseconds()),
+		Request:        int(cfg.RequestTimeout.Milliseconds()),
+		Hosts:          make([]UpstreamHostTimeouts, len(cfg.HostTimeouts)),
+	}
 
-		senderReq.Response = &resLog
+	for i, host := range cfg.HostTimeouts {
+		timeouts.Hosts[i] = UpstreamHostTimeouts{
+			Host:           host.Host,
+			Dial:           int(host.DialTimeout.Milliseconds()),
+			TLSHandshake:   int(host.TLSHandshakeTimeout.Milliseconds()),
+			ResponseHeader: int(host.ResponseHeaderTimeout.Milliseconds()),
+			Request:        int(host.RequestTimeout.Milliseconds()),
+		}
 	}
 
-	return senderReq, nil
+	return timeouts
+}
+
+func msToDuration(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
 }
 
 func stringPtrToRegexp(s *string) (*regexp.Regexp, error) {
@@ -598,10 +4909,29 @@ func findRequestsFilterFromInput(input *HTTPRequestLogFilterInput) (filter reqlo
 		filter.OnlyInScope = *input.OnlyInScope
 	}
 
+	if input.CollapseRedirects != nil {
+		filter.CollapseRedirects = *input.CollapseRedirects
+	}
+
+	if input.CollapsePageLoads != nil {
+		filter.CollapsePageLoads = *input.CollapsePageLoads
+	}
+
+	for _, preset := range input.Presets {
+		switch preset {
+		case HTTPRequestLogFilterPresetHideStaticAssets:
+			filter.Presets.HideStaticAssets = true
+		case HTTPRequestLogFilterPresetHideTracking:
+			filter.Presets.HideTracking = true
+		case HTTPRequestLogFilterPresetHideCertRevocation:
+			filter.Presets.HideCertRevocation = true
+		}
+	}
+
 	if input.SearchExpression != nil && *input.SearchExpression != "" {
-		expr, err := search.ParseQuery(*input.SearchExpression)
+		expr, err := reqlog.ParseSearchExpr(*input.SearchExpression)
 		if err != nil {
-			return reqlog.FindRequestsFilter{}, fmt.Errorf("could not parse search query: %w", err)
+			return reqlog.FindRequestsFilter{}, searchExprErr("Invalid search expression", err)
 		}
 
 		filter.SearchExpr = expr
@@ -610,6 +4940,23 @@ func findRequestsFilterFromInput(input *HTTPRequestLogFilterInput) (filter reqlo
 	return
 }
 
+func selectionFromInput(input HTTPRequestLogSelectionInput) (reqlog.Selection, error) {
+	if len(input.Ids) > 0 {
+		return reqlog.Selection{IDs: input.Ids}, nil
+	}
+
+	if input.Filter == nil {
+		return reqlog.Selection{}, gqlerror.Errorf("Selection must have either IDs or a filter.")
+	}
+
+	filter, err := findRequestsFilterFromInput(input.Filter)
+	if err != nil {
+		return reqlog.Selection{}, err
+	}
+
+	return reqlog.Selection{Filter: &filter}, nil
+}
+
 func findSenderRequestsFilterFromInput(input *SenderRequestFilterInput) (filter sender.FindRequestsFilter, err error) {
 	if input == nil {
 		return
@@ -620,9 +4967,9 @@ func findSenderRequestsFilterFromInput(input *SenderRequestFilterInput) (filter
 	}
 
 	if input.SearchExpression != nil && *input.SearchExpression != "" {
-		expr, err := search.ParseQuery(*input.SearchExpression)
+		expr, err := sender.ParseSearchExpr(*input.SearchExpression)
 		if err != nil {
-			return sender.FindRequestsFilter{}, fmt.Errorf("could not parse search query: %w", err)
+			return sender.FindRequestsFilter{}, searchExprErr("Invalid search expression", err)
 		}
 
 		filter.SearchExpr = expr
@@ -631,6 +4978,33 @@ func findSenderRequestsFilterFromInput(input *SenderRequestFilterInput) (filter
 	return
 }
 
+// searchExprErr returns an error for a search expression that can't be parsed.
+// The position, expected tokens and suggested keys of a `search.ParseError`
+// are set as extensions, so that clients can point them out.
+func searchExprErr(msg string, err error) error {
+	gqlErr := &gqlerror.Error{
+		Message: fmt.Sprintf("%v: %v", msg, err),
+		Extensions: map[string]interface{}{
+			"code": "invalid_search_expression",
+		},
+	}
+
+	var parseErr *search.ParseError
+	if errors.As(err, &parseErr) {
+		gqlErr.Extensions["position"] = parseErr.Pos
+
+		if len(parseErr.Expected) > 0 {
+			gqlErr.Extensions["expected"] = parseErr.Expected
+		}
+
+		if len(parseErr.Suggestions) > 0 {
+			gqlErr.Extensions["suggestions"] = parseErr.Suggestions
+		}
+	}
+
+	return gqlErr
+}
+
 func findReqFilterToHTTPReqLogFilter(findReqFilter reqlog.FindRequestsFilter) *HTTPRequestLogFilter {
 	empty := reqlog.FindRequestsFilter{}
 	if findReqFilter == empty {
@@ -638,7 +5012,23 @@ func findReqFilterToHTTPReqLogFilter(findReqFilter reqlog.FindRequestsFilter) *H
 	}
 
 	httpReqLogFilter := &HTTPRequestLogFilter{
-		OnlyInScope: findReqFilter.OnlyInScope,
+		OnlyInScope:       findReqFilter.OnlyInScope,
+		CollapseRedirects: findReqFilter.CollapseRedirects,
+		CollapsePageLoads: findReqFilter.CollapsePageLoads,
+		Presets:           make([]HTTPRequestLogFilterPreset, 0),
+	}
+
+	for _, preset := range []struct {
+		enabled bool
+		preset  HTTPRequestLogFilterPreset
+	}{
+		{findReqFilter.Presets.HideStaticAssets, HTTPRequestLogFilterPresetHideStaticAssets},
+		{findReqFilter.Presets.HideTracking, HTTPRequestLogFilterPresetHideTracking},
+		{findReqFilter.Presets.HideCertRevocation, HTTPRequestLogFilterPresetHideCertRevocation},
+	} {
+		if preset.enabled {
+			httpReqLogFilter.Presets = append(httpReqLogFilter.Presets, preset.preset)
+		}
 	}
 
 	if findReqFilter.SearchExpr != nil {
@@ -667,6 +5057,30 @@ func findReqFilterToSenderReqFilter(findReqFilter sender.FindRequestsFilter) *Se
 	return senderReqFilter
 }
 
+// ErrorPresenter presents errors like `graphql.DefaultErrorPresenter`, with the
+// code of errors that have one (see package `errcode`) as `code` extension.
+// Errors that resolvers already set a code extension on are left as is.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	if _, ok := gqlErr.Extensions["code"]; ok {
+		return gqlErr
+	}
+
+	var coder errcode.Coder
+	if !errors.As(err, &coder) {
+		return gqlErr
+	}
+
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = make(map[string]interface{})
+	}
+
+	gqlErr.Extensions["code"] = string(coder.ErrorCode())
+
+	return gqlErr
+}
+
 func noActiveProjectErr(ctx context.Context) error {
 	return &gqlerror.Error{
 		Path:    graphql.GetPath(ctx),
@@ -676,3 +5090,26 @@ func noActiveProjectErr(ctx context.Context) error {
 		},
 	}
 }
+
+func (r *queryResolver) LogLevel(ctx context.Context) (LogLevel, error) {
+	if r.Logger == nil {
+		return "", gqlerror.Errorf("Logging isn't configured.")
+	}
+
+	return LogLevel(strings.ToUpper(r.Logger.Level().String())), nil
+}
+
+func (r *mutationResolver) SetLogLevel(ctx context.Context, input LogLevel) (LogLevel, error) {
+	if r.Logger == nil {
+		return "", gqlerror.Errorf("Logging isn't configured.")
+	}
+
+	level, err := logging.ParseLevel(input.String())
+	if err != nil {
+		return "", gqlerror.Errorf("Invalid log level: %v", input)
+	}
+
+	r.Logger.SetLevel(level)
+
+	return input, nil
+}
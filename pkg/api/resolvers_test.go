@@ -0,0 +1,212 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/api"
+	"github.com/dstotijn/hetty/pkg/authz"
+	"github.com/dstotijn/hetty/pkg/crawler"
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/oauth2"
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
+	"github.com/dstotijn/hetty/pkg/scope"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func TestStopActiveModules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var fetches int32
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&fetches, 1)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token-%v", "token_type": "Bearer"}`, n)
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	database := memory.OpenDatabase()
+	projScope := &scope.Scope{}
+	reqLogSvc := reqlog.NewService(reqlog.Config{Scope: projScope, Repository: database})
+	senderSvc := sender.NewService(sender.Config{
+		Scope:         projScope,
+		Repository:    database,
+		ReqLogService: reqLogSvc,
+		HTTPClient:    &http.Client{},
+	})
+	oauth2Svc := oauth2.NewService(oauth2.Config{})
+	authzSvc := authz.NewService(authz.Config{
+		Scope:             projScope,
+		RequestLogService: reqLogSvc,
+		FindingRepository: database,
+	})
+	t.Cleanup(authzSvc.Close)
+
+	projSvc, err := proj.NewService(proj.Config{
+		Repository:    database,
+		ReqLogService: reqLogSvc,
+		SenderService: senderSvc,
+		Scope:         projScope,
+		Rewriter:      &rewrite.Rewriter{},
+		OAuth2Service: oauth2Svc,
+		AuthzService:  authzSvc,
+	})
+	if err != nil {
+		t.Fatalf("failed to create project service: %v", err)
+	}
+
+	jobSvc := job.NewService(job.Config{})
+
+	resolver := &api.Resolver{
+		ProjectService:    projSvc,
+		RequestLogService: reqLogSvc,
+		SenderService:     senderSvc,
+		AuthzService:      authzSvc,
+		OAuth2Service:     oauth2Svc,
+		JobService:        jobSvc,
+	}
+
+	project, err := projSvc.CreateProject(ctx, "foobar")
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	if _, err := projSvc.OpenProject(ctx, project.ID); err != nil {
+		t.Fatalf("failed to open project: %v", err)
+	}
+
+	schedules := []sender.Schedule{{Name: "monitor", Interval: time.Hour}}
+	if err := projSvc.SetSenderSchedules(ctx, schedules); err != nil {
+		t.Fatalf("failed to set sender schedules: %v", err)
+	}
+
+	sources := []oauth2.Source{
+		{
+			Name:            "api",
+			Variable:        "accessToken",
+			TokenURL:        tokenServer.URL,
+			GrantType:       oauth2.GrantTypeClientCredentials,
+			ClientID:        "hetty",
+			RefreshInterval: 10 * time.Millisecond,
+		},
+	}
+	if err := projSvc.SetOAuth2Sources(ctx, sources); err != nil {
+		t.Fatalf("failed to set OAuth 2.0 token sources: %v", err)
+	}
+
+	authzSettings := authz.Settings{
+		Enabled: true,
+		Headers: http.Header{"Cookie": []string{"session=user"}},
+	}
+	if err := projSvc.SetAuthzSettings(ctx, authzSettings); err != nil {
+		t.Fatalf("failed to set authorization check settings: %v", err)
+	}
+
+	crawl := jobSvc.StartJob(context.Background(), job.Params{Type: crawler.JobType}, func(ctx context.Context, _ *job.Progress) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	result, err := resolver.Mutation().StopActiveModules(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.CancelledJobs) != 1 || result.CancelledJobs[0].ID != crawl.ID ||
+		result.CancelledJobs[0].Module != api.ActiveModuleCrawler {
+		t.Errorf("expected crawl to be cancelled, got: %+v", result.CancelledJobs)
+	}
+
+	if result.StoppedSchedules != 1 || result.PausedTokenSources != 1 || !result.DisabledAuthzReplays {
+		t.Errorf("expected schedule, token source and replays to be stopped, got: %+v", result)
+	}
+
+	// Token fetches stop once the sources are paused.
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := atomic.LoadInt32(&fetches)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&fetches); got != stopped {
+		t.Errorf("expected no token fetches after stop, got: %v", got-stopped)
+	}
+
+	if settings := authzSvc.Settings(); settings.Enabled {
+		t.Errorf("expected authorization check to be disabled, got: %+v", settings)
+	}
+
+	// The stopped modules are stored paused with the project, with their
+	// definitions kept, so that they can be resumed.
+	project, err = projSvc.ActiveProject(ctx)
+	if err != nil {
+		t.Fatalf("failed to get active project: %v", err)
+	}
+
+	if got := project.Settings.SenderSchedules; len(got) != 1 || got[0].Name != "monitor" || !got[0].Paused {
+		t.Errorf("expected paused sender schedule to be stored, got: %+v", got)
+	}
+
+	if got := project.Settings.OAuth2Sources; len(got) != 1 || got[0].Name != "api" || !got[0].Paused {
+		t.Errorf("expected paused OAuth 2.0 token source to be stored, got: %+v", got)
+	}
+
+	if got := project.Settings.AuthzSettings; got.Enabled || got.Headers.Get("Cookie") != "session=user" {
+		t.Errorf("expected disabled authorization check settings to be stored, got: %+v", got)
+	}
+
+	// Stopping again stops nothing new.
+	result, err = resolver.Mutation().StopActiveModules(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.CancelledJobs) != 0 || result.StoppedSchedules != 0 || result.PausedTokenSources != 0 ||
+		result.DisabledAuthzReplays {
+		t.Errorf("expected nothing to be stopped, got: %+v", result)
+	}
+
+	// Modules of a project that is opened read-only are only stopped until
+	// it's reopened.
+	if err := projSvc.SetSenderSchedules(ctx, schedules); err != nil {
+		t.Fatalf("failed to set sender schedules: %v", err)
+	}
+
+	if _, err := projSvc.OpenProjectReadOnly(ctx, project.ID); err != nil {
+		t.Fatalf("failed to open project read-only: %v", err)
+	}
+
+	result, err = resolver.Mutation().StopActiveModules(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.StoppedSchedules != 1 {
+		t.Errorf("expected 1 stopped schedule, got: %v", result.StoppedSchedules)
+	}
+
+	if got := senderSvc.Schedules(); len(got) != 1 || !got[0].Paused {
+		t.Errorf("expected sender schedule to be paused, got: %+v", got)
+	}
+
+	project, err = projSvc.ActiveProject(ctx)
+	if err != nil {
+		t.Fatalf("failed to get active project: %v", err)
+	}
+
+	if got := project.Settings.SenderSchedules; len(got) != 1 || got[0].Paused {
+		t.Errorf("expected stored sender schedule to be left as is, got: %+v", got)
+	}
+}
@@ -0,0 +1,424 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db"
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/search"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+type Project struct {
+	ID         ulid.ULID `json:"id"`
+	Name       string    `json:"name"`
+	IsActive   bool      `json:"isActive"`
+	IsReadOnly bool      `json:"isReadOnly"`
+}
+
+type CreateProjectInput struct {
+	Name string `json:"name"`
+}
+
+type RequestLog struct {
+	ID             ulid.ULID    `json:"id"`
+	URL            string       `json:"url"`
+	OriginalURL    string       `json:"originalURL,omitempty"`
+	Method         string       `json:"method"`
+	Proto          string       `json:"proto"`
+	Headers        http.Header  `json:"headers"`
+	Body           string       `json:"body,omitempty"`
+	Timestamp      time.Time    `json:"timestamp"`
+	Tags           []string     `json:"tags,omitempty"`
+	BodyViews      []BodyView   `json:"bodyViews,omitempty"`
+	RedirectFromID *ulid.ULID   `json:"redirectFromID,omitempty"`
+	CorrelationID  *ulid.ULID   `json:"correlationID,omitempty"`
+	PageLoadID     *ulid.ULID   `json:"pageLoadID,omitempty"`
+	Error          string       `json:"error,omitempty"`
+	RemoteIP       string       `json:"remoteIP,omitempty"`
+	ClientAddr     string       `json:"clientAddr,omitempty"`
+	Retries        int          `json:"retries"`
+	Response       *ResponseLog `json:"response,omitempty"`
+}
+
+// BodyView is a named transformation of a request or response body, see
+// `reqlog.BodyView`.
+type BodyView struct {
+	Name       string    `json:"name"`
+	Source     string    `json:"source"`
+	Start      int       `json:"start"`
+	End        int       `json:"end"`
+	Transforms []string  `json:"transforms"`
+	Output     string    `json:"output"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type ResponseLog struct {
+	Proto       string      `json:"proto"`
+	StatusCode  int         `json:"statusCode"`
+	Status      string      `json:"status"`
+	Headers     http.Header `json:"headers"`
+	Body        string      `json:"body,omitempty"`
+	BodyOmitted bool        `json:"bodyOmitted,omitempty"`
+}
+
+type SenderRequest struct {
+	ID                 ulid.ULID   `json:"id"`
+	SourceRequestLogID *ulid.ULID  `json:"sourceRequestLogID,omitempty"`
+	URL                string      `json:"url"`
+	Method             string      `json:"method"`
+	Proto              string      `json:"proto"`
+	Headers            http.Header `json:"headers"`
+	Body               string      `json:"body,omitempty"`
+	Raw                string      `json:"raw,omitempty"`
+	RawResponse        string      `json:"rawResponse,omitempty"`
+	PreRequestScript   string      `json:"preRequestScript,omitempty"`
+	PostResponseScript string      `json:"postResponseScript,omitempty"`
+	Assertions         []Assertion `json:"assertions,omitempty"`
+	// Results of the assertions, checked against the last response.
+	AssertionResults []AssertionResult `json:"assertionResults,omitempty"`
+	Timestamp        time.Time         `json:"timestamp"`
+	Response         *ResponseLog      `json:"response,omitempty"`
+}
+
+// Assertion is checked against the response of a sender request. Type is
+// `status_equals`, `body_contains` or `json_path_equals`.
+type Assertion struct {
+	Type  string `json:"type"`
+	Path  string `json:"path,omitempty"`
+	Value string `json:"value"`
+}
+
+type AssertionResult struct {
+	Assertion Assertion `json:"assertion"`
+	Passed    bool      `json:"passed"`
+	Actual    string    `json:"actual,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// SenderRequestInput creates a sender request. If RequestLogID is set, the
+// request log is cloned and the other fields are ignored.
+type SenderRequestInput struct {
+	RequestLogID *ulid.ULID `json:"requestLogID,omitempty"`
+	URL          string     `json:"url,omitempty"`
+	Method       string     `json:"method,omitempty"`
+	// HTTP protocol: `HTTP/1.1` or `HTTP/2.0` (default).
+	Proto              string      `json:"proto,omitempty"`
+	Headers            http.Header `json:"headers,omitempty"`
+	Body               string      `json:"body,omitempty"`
+	Raw                string      `json:"raw,omitempty"`
+	PreRequestScript   string      `json:"preRequestScript,omitempty"`
+	PostResponseScript string      `json:"postResponseScript,omitempty"`
+	Assertions         []Assertion `json:"assertions,omitempty"`
+}
+
+type DatabaseStats struct {
+	LSMSize             int64              `json:"lsmSize"`
+	ValueLogSize        int64              `json:"valueLogSize"`
+	KeyCounts           map[string]int     `json:"keyCounts"`
+	PendingCompactions  int                `json:"pendingCompactions"`
+	CompactionSchedule  CompactionSchedule `json:"compactionSchedule"`
+	LastCompactionAt    *time.Time         `json:"lastCompactionAt,omitempty"`
+	LastCompactionError string             `json:"lastCompactionError,omitempty"`
+	CompactionRunning   bool               `json:"compactionRunning"`
+}
+
+// CompactionSchedule configures background compactions, see
+// `db.CompactionSchedule`.
+type CompactionSchedule struct {
+	// Duration between compactions (e.g. `24h`). Empty when disabled.
+	Interval    string `json:"interval"`
+	WindowStart int    `json:"windowStart"`
+	WindowEnd   int    `json:"windowEnd"`
+}
+
+// CaptureStatus is whether proxied requests are logged, see
+// `reqlog.Service.SetCapturePaused`.
+type CaptureStatus struct {
+	// Paused globally, for all projects.
+	Paused bool `json:"paused"`
+	// Paused for the active project only.
+	ProjectPaused bool `json:"projectPaused"`
+}
+
+// CaptureStatusInput sets whether capture is paused. Unset fields are left
+// unchanged; `projectPaused` requires an active project.
+type CaptureStatusInput struct {
+	Paused        *bool `json:"paused,omitempty"`
+	ProjectPaused *bool `json:"projectPaused,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+type Error struct {
+	// Machine-readable code, e.g. `no_active_project`.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Set for search expressions that can't be parsed.
+	Search *SearchError `json:"search,omitempty"`
+}
+
+// SearchError is the position of an error in a search expression, see
+// `search.ParseError`.
+type SearchError struct {
+	// Byte offset in the search expression.
+	Position    int      `json:"position"`
+	Expected    []string `json:"expected,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// parseSearchError returns the search error of err, if it has one.
+func parseSearchError(err error) *SearchError {
+	var parseErr *search.ParseError
+	if !errors.As(err, &parseErr) {
+		return nil
+	}
+
+	return &SearchError{
+		Position:    parseErr.Pos,
+		Expected:    parseErr.Expected,
+		Suggestions: parseErr.Suggestions,
+	}
+}
+
+func parseProject(projSvc proj.Service, p proj.Project) Project {
+	return Project{
+		ID:         p.ID,
+		Name:       p.Name,
+		IsActive:   projSvc.IsProjectActive(p.ID),
+		IsReadOnly: projSvc.IsProjectActive(p.ID) && projSvc.IsReadOnly(),
+	}
+}
+
+func parseRequestLog(reqLog reqlog.RequestLog) RequestLog {
+	log := RequestLog{
+		ID:         reqLog.ID,
+		Method:     reqLog.Method,
+		Proto:      reqLog.Proto,
+		Headers:    reqLog.Header,
+		Body:       string(reqLog.Body),
+		Timestamp:  ulid.Time(reqLog.ID.Time()),
+		Tags:       reqLog.Tags,
+		Error:      reqLog.Error,
+		RemoteIP:   reqLog.ServerIP(),
+		ClientAddr: reqLog.ClientAddr,
+		Retries:    reqLog.Retries,
+	}
+
+	for _, view := range reqLog.BodyViews {
+		transforms := make([]string, len(view.Transforms))
+		for i, t := range view.Transforms {
+			transforms[i] = t.String()
+		}
+
+		log.BodyViews = append(log.BodyViews, BodyView{
+			Name:       view.Name,
+			Source:     string(view.Source),
+			Start:      view.Start,
+			End:        view.End,
+			Transforms: transforms,
+			Output:     string(view.Output),
+			CreatedAt:  view.CreatedAt,
+		})
+	}
+
+	if reqLog.URL != nil {
+		log.URL = reqLog.URL.String()
+	}
+
+	if reqLog.OriginalURL != nil {
+		log.OriginalURL = reqLog.OriginalURL.String()
+	}
+
+	if reqLog.RedirectFromID.Compare(ulid.ULID{}) != 0 {
+		redirectFromID := reqLog.RedirectFromID
+		log.RedirectFromID = &redirectFromID
+	}
+
+	if reqLog.CorrelationID.Compare(ulid.ULID{}) != 0 {
+		correlationID := reqLog.CorrelationID
+		log.CorrelationID = &correlationID
+	}
+
+	if reqLog.PageLoadID.Compare(ulid.ULID{}) != 0 {
+		pageLoadID := reqLog.PageLoadID
+		log.PageLoadID = &pageLoadID
+	}
+
+	if reqLog.Response != nil {
+		resLog := parseResponseLog(*reqLog.Response)
+		log.Response = &resLog
+	}
+
+	return log
+}
+
+func parseResponseLog(resLog reqlog.ResponseLog) ResponseLog {
+	return ResponseLog{
+		Proto:       resLog.Proto,
+		StatusCode:  resLog.StatusCode,
+		Status:      resLog.Status,
+		Headers:     resLog.Header,
+		Body:        string(resLog.Body),
+		BodyOmitted: resLog.BodyOmitted,
+	}
+}
+
+func parseSenderRequest(req sender.Request) SenderRequest {
+	senderReq := SenderRequest{
+		ID:                 req.ID,
+		Method:             req.Method,
+		Proto:              req.Proto,
+		Headers:            req.Header,
+		Body:               string(req.Body),
+		Raw:                string(req.Raw),
+		RawResponse:        string(req.RawResponse),
+		PreRequestScript:   req.PreRequestScript,
+		PostResponseScript: req.PostResponseScript,
+		Timestamp:          ulid.Time(req.ID.Time()),
+	}
+
+	if req.URL != nil {
+		senderReq.URL = req.URL.String()
+	}
+
+	if req.SourceRequestLogID.Compare(ulid.ULID{}) != 0 {
+		sourceRequestLogID := req.SourceRequestLogID
+		senderReq.SourceRequestLogID = &sourceRequestLogID
+	}
+
+	for _, assertion := range req.Assertions {
+		senderReq.Assertions = append(senderReq.Assertions, parseAssertion(assertion))
+	}
+
+	for _, result := range req.CheckAssertions() {
+		senderReq.AssertionResults = append(senderReq.AssertionResults, AssertionResult{
+			Assertion: parseAssertion(result.Assertion),
+			Passed:    result.Passed,
+			Actual:    result.Actual,
+			Message:   result.Message,
+		})
+	}
+
+	if req.Response != nil {
+		resLog := parseResponseLog(*req.Response)
+		senderReq.Response = &resLog
+	}
+
+	return senderReq
+}
+
+func parseAssertion(assertion sender.Assertion) Assertion {
+	return Assertion{
+		Type:  string(assertion.Type),
+		Path:  assertion.Path,
+		Value: assertion.Value,
+	}
+}
+
+func senderRequestFromInput(input SenderRequestInput) (sender.Request, error) {
+	if input.URL == "" {
+		return sender.Request{}, errors.New("URL must be set")
+	}
+
+	u, err := url.Parse(input.URL)
+	if err != nil {
+		return sender.Request{}, fmt.Errorf("invalid URL: %v", err)
+	}
+
+	switch input.Proto {
+	case "", sender.HTTPProto1, sender.HTTPProto2:
+	default:
+		return sender.Request{}, fmt.Errorf("unsupported HTTP protocol: %q", input.Proto)
+	}
+
+	req := sender.Request{
+		URL:                u,
+		Method:             input.Method,
+		Proto:              input.Proto,
+		Header:             input.Headers,
+		PreRequestScript:   input.PreRequestScript,
+		PostResponseScript: input.PostResponseScript,
+	}
+
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	for _, assertion := range input.Assertions {
+		req.Assertions = append(req.Assertions, sender.Assertion{
+			Type:  sender.AssertionType(assertion.Type),
+			Path:  assertion.Path,
+			Value: assertion.Value,
+		})
+	}
+
+	if input.Body != "" {
+		req.Body = []byte(input.Body)
+	}
+
+	if input.Raw != "" {
+		req.Raw = []byte(input.Raw)
+	}
+
+	return req, nil
+}
+
+func parseDatabaseStats(stats db.Stats) DatabaseStats {
+	dbStats := DatabaseStats{
+		LSMSize:             stats.LSMSize,
+		ValueLogSize:        stats.ValueLogSize,
+		KeyCounts:           stats.KeyCounts,
+		PendingCompactions:  stats.PendingCompactions,
+		CompactionSchedule:  parseCompactionSchedule(stats.CompactionSchedule),
+		LastCompactionError: stats.LastCompactionError,
+		CompactionRunning:   stats.CompactionRunning,
+	}
+
+	if !stats.LastCompactionAt.IsZero() {
+		lastCompactionAt := stats.LastCompactionAt
+		dbStats.LastCompactionAt = &lastCompactionAt
+	}
+
+	return dbStats
+}
+
+func parseCompactionSchedule(schedule db.CompactionSchedule) CompactionSchedule {
+	s := CompactionSchedule{
+		WindowStart: schedule.WindowStart,
+		WindowEnd:   schedule.WindowEnd,
+	}
+
+	if schedule.Interval > 0 {
+		s.Interval = schedule.Interval.String()
+	}
+
+	return s
+}
+
+func compactionScheduleFromInput(input CompactionSchedule) (db.CompactionSchedule, error) {
+	schedule := db.CompactionSchedule{
+		WindowStart: input.WindowStart,
+		WindowEnd:   input.WindowEnd,
+	}
+
+	if input.Interval != "" {
+		interval, err := time.ParseDuration(input.Interval)
+		if err != nil {
+			return db.CompactionSchedule{}, fmt.Errorf("invalid interval: %w", err)
+		}
+
+		schedule.Interval = interval
+	}
+
+	return schedule, nil
+}
@@ -0,0 +1,674 @@
+// Package rest implements a versioned JSON API for the core operations of the
+// admin API (request logs, the sender, projects and database maintenance), for
+// scripts and integrations that don't use GraphQL.
+package rest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db"
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+// PathPrefix is the path prefix of the current version of the API.
+const PathPrefix = "/api/v1"
+
+type Config struct {
+	ProjectService    proj.Service
+	RequestLogService reqlog.Service
+	SenderService     sender.Service
+	// Optional; the database endpoints are served when set.
+	Database db.Maintainer
+}
+
+type handler struct {
+	projSvc   proj.Service
+	reqLogSvc reqlog.Service
+	senderSvc sender.Service
+	database  db.Maintainer
+}
+
+// NewHandler returns an HTTP handler that serves the API on `PathPrefix`.
+func NewHandler(cfg Config) http.Handler {
+	h := &handler{
+		projSvc:   cfg.ProjectService,
+		reqLogSvc: cfg.RequestLogService,
+		senderSvc: cfg.SenderService,
+		database:  cfg.Database,
+	}
+
+	router := mux.NewRouter().PathPrefix(PathPrefix).Subrouter()
+
+	router.Path("/projects").Methods(http.MethodGet).HandlerFunc(h.listProjects)
+	router.Path("/projects").Methods(http.MethodPost).HandlerFunc(h.createProject)
+	router.Path("/projects/active").Methods(http.MethodGet).HandlerFunc(h.activeProject)
+	router.Path("/projects/active").Methods(http.MethodDelete).HandlerFunc(h.closeProject)
+	router.Path("/projects/{id}/open").Methods(http.MethodPost).HandlerFunc(h.openProject)
+	router.Path("/projects/{id}").Methods(http.MethodDelete).HandlerFunc(h.deleteProject)
+
+	router.Path("/capture").Methods(http.MethodGet).HandlerFunc(h.captureStatus)
+	router.Path("/capture").Methods(http.MethodPut).HandlerFunc(h.setCaptureStatus)
+
+	router.Path("/request-logs").Methods(http.MethodGet).HandlerFunc(h.listRequestLogs)
+	router.Path("/request-logs/{id}").Methods(http.MethodGet).HandlerFunc(h.getRequestLog)
+	router.Path("/request-logs/{id}/{part:request|response}-body").
+		Methods(http.MethodGet, http.MethodHead).HandlerFunc(h.getRequestLogBody)
+
+	router.Path("/sender-requests").Methods(http.MethodGet).HandlerFunc(h.listSenderRequests)
+	router.Path("/sender-requests").Methods(http.MethodPost).HandlerFunc(h.createSenderRequest)
+	router.Path("/sender-requests/{id}").Methods(http.MethodGet).HandlerFunc(h.getSenderRequest)
+	router.Path("/sender-requests/{id}/send").Methods(http.MethodPost).HandlerFunc(h.sendRequest)
+	router.Path("/sender-requests/{id}/{part:request|response}-body").
+		Methods(http.MethodGet, http.MethodHead).HandlerFunc(h.getSenderRequestBody)
+
+	if h.database != nil {
+		router.Path("/database/stats").Methods(http.MethodGet).HandlerFunc(h.databaseStats)
+		router.Path("/database/compact").Methods(http.MethodPost).HandlerFunc(h.compactDatabase)
+		router.Path("/database/backup").Methods(http.MethodGet).HandlerFunc(h.backupDatabase)
+		router.Path("/database/compaction-schedule").Methods(http.MethodGet).HandlerFunc(h.compactionSchedule)
+		router.Path("/database/compaction-schedule").Methods(http.MethodPut).HandlerFunc(h.setCompactionSchedule)
+	}
+
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "Not found.")
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed.")
+	})
+
+	return router
+}
+
+func (h *handler) listProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := h.projSvc.Projects(r.Context())
+	if err != nil {
+		writeServiceError(w, fmt.Errorf("could not get projects: %w", err))
+		return
+	}
+
+	resp := make([]Project, len(projects))
+	for i, p := range projects {
+		resp[i] = parseProject(h.projSvc, p)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) createProject(w http.ResponseWriter, r *http.Request) {
+	var input CreateProjectInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	p, err := h.projSvc.CreateProject(r.Context(), input.Name)
+	if errors.Is(err, proj.ErrInvalidName) {
+		writeError(w, http.StatusBadRequest, "invalid_name", "Project name must only contain alphanumeric or space chars.")
+		return
+	} else if errors.Is(err, proj.ErrReadOnly) {
+		writeReadOnlyError(w)
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not create project: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, parseProject(h.projSvc, p))
+}
+
+func (h *handler) activeProject(w http.ResponseWriter, r *http.Request) {
+	p, err := h.projSvc.ActiveProject(r.Context())
+	if errors.Is(err, proj.ErrNoProject) {
+		writeNoActiveProjectError(w)
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not get active project: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, parseProject(h.projSvc, p))
+}
+
+// openProject opens a project. With query parameter `readOnly=true`, the
+// project is opened read-only, see `proj.Service.OpenProjectReadOnly`.
+func (h *handler) openProject(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	openProject := h.projSvc.OpenProject
+
+	if v := r.URL.Query().Get("readOnly"); v != "" {
+		readOnly, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("Invalid `readOnly` parameter: %q", v))
+			return
+		}
+
+		if readOnly {
+			openProject = h.projSvc.OpenProjectReadOnly
+		}
+	}
+
+	p, err := openProject(r.Context(), id)
+	if errors.Is(err, proj.ErrProjectNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "Project not found.")
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not open project: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, parseProject(h.projSvc, p))
+}
+
+func (h *handler) closeProject(w http.ResponseWriter, _ *http.Request) {
+	if err := h.projSvc.CloseProject(); err != nil {
+		writeServiceError(w, fmt.Errorf("could not close project: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) deleteProject(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.projSvc.DeleteProject(r.Context(), id)
+	if errors.Is(err, proj.ErrReadOnly) {
+		writeReadOnlyError(w)
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not delete project: %w", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listRequestLogs returns the request logs of the active project. Query
+// parameters: `q` (search expression), `inScope`, `collapseRedirects`,
+// `collapsePageLoads` and the presets `hideStaticAssets`, `hideTracking` and
+// `hideCertRevocation`.
+func (h *handler) listRequestLogs(w http.ResponseWriter, r *http.Request) {
+	filter, err := findRequestsFilterFromQuery(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: Error{
+			Code:    "invalid_filter",
+			Message: err.Error(),
+			Search:  parseSearchError(err),
+		}})
+
+		return
+	}
+
+	reqLogs, err := h.reqLogSvc.FindSelectedRequests(r.Context(), reqlog.Selection{Filter: &filter})
+	if errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+		writeNoActiveProjectError(w)
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not find request logs: %w", err))
+		return
+	}
+
+	resp := make([]RequestLog, len(reqLogs))
+	for i, reqLog := range reqLogs {
+		resp[i] = parseRequestLog(reqLog)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) getRequestLog(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	reqLog, err := h.reqLogSvc.FindRequestLogByID(r.Context(), id)
+	if errors.Is(err, reqlog.ErrRequestNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "Request log not found.")
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not get request log: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, parseRequestLog(reqLog))
+}
+
+// getRequestLogBody serves the raw request or response body of a request log,
+// see `serveBody`.
+func (h *handler) getRequestLogBody(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	reqLog, err := h.reqLogSvc.FindRequestLogByID(r.Context(), id)
+	if errors.Is(err, reqlog.ErrRequestNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "Request log not found.")
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not get request log: %w", err))
+		return
+	}
+
+	serveBody(w, r, reqLog.Body, reqLog.Response)
+}
+
+// getSenderRequestBody serves the raw request or response body of a sender
+// request, see `serveBody`.
+func (h *handler) getSenderRequestBody(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	req, err := h.senderSvc.FindRequestByID(r.Context(), id)
+	if errors.Is(err, sender.ErrRequestNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "Sender request not found.")
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not get sender request: %w", err))
+		return
+	}
+
+	serveBody(w, r, req.Body, req.Response)
+}
+
+// serveBody serves the request body, or the body of the response, by the
+// `part` path variable. Unlike the JSON endpoints, bodies are served as is, and
+// support range requests, so that large bodies can be fetched in parts. Bodies
+// are always served as `application/octet-stream`, so that they aren't
+// rendered on the origin of the admin interface.
+func serveBody(w http.ResponseWriter, r *http.Request, reqBody []byte, resLog *reqlog.ResponseLog) {
+	body := reqBody
+
+	if mux.Vars(r)["part"] == "response" {
+		if resLog == nil {
+			writeError(w, http.StatusNotFound, "not_found", "Response not found.")
+			return
+		}
+
+		body = resLog.Body
+	}
+
+	// The ETag lets clients resume fetching a body with `If-Range`. Bodies of
+	// sender requests can change.
+	sum := sha256.Sum256(body)
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sum[:16]))
+
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
+
+func (h *handler) listSenderRequests(w http.ResponseWriter, r *http.Request) {
+	reqs, err := h.senderSvc.FindRequests(r.Context())
+	if errors.Is(err, sender.ErrProjectIDMustBeSet) {
+		writeNoActiveProjectError(w)
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not find sender requests: %w", err))
+		return
+	}
+
+	resp := make([]SenderRequest, len(reqs))
+	for i, req := range reqs {
+		resp[i] = parseSenderRequest(req)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// createSenderRequest creates a sender request, or clones a request log if
+// `requestLogID` is set.
+func (h *handler) createSenderRequest(w http.ResponseWriter, r *http.Request) {
+	var input SenderRequestInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	var (
+		req sender.Request
+		err error
+	)
+
+	if input.RequestLogID != nil {
+		req, err = h.senderSvc.CloneFromRequestLog(r.Context(), *input.RequestLogID)
+	} else {
+		req, err = senderRequestFromInput(input)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		req, err = h.senderSvc.CreateOrUpdateRequest(r.Context(), req)
+	}
+
+	switch {
+	case errors.Is(err, sender.ErrProjectIDMustBeSet):
+		writeNoActiveProjectError(w)
+		return
+	case errors.Is(err, sender.ErrReadOnly):
+		writeReadOnlyError(w)
+		return
+	case errors.Is(err, reqlog.ErrRequestNotFound):
+		writeError(w, http.StatusNotFound, "not_found", "Request log not found.")
+	case errors.Is(err, sender.ErrInvalidScript), errors.Is(err, sender.ErrInvalidAssertions):
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	case err != nil:
+		writeServiceError(w, fmt.Errorf("could not create sender request: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, parseSenderRequest(req))
+}
+
+func (h *handler) getSenderRequest(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	req, err := h.senderSvc.FindRequestByID(r.Context(), id)
+	if errors.Is(err, sender.ErrRequestNotFound) {
+		writeError(w, http.StatusNotFound, "not_found", "Sender request not found.")
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not get sender request: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, parseSenderRequest(req))
+}
+
+func (h *handler) sendRequest(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var sendErr *sender.SendError
+
+	// Don't use the request context, so that sending and storing the response
+	// isn't interrupted if the client disconnects.
+	req, err := h.senderSvc.SendRequest(context.Background(), id)
+
+	switch {
+	case errors.Is(err, sender.ErrProjectIDMustBeSet):
+		writeNoActiveProjectError(w)
+		return
+	case errors.Is(err, sender.ErrReadOnly):
+		writeReadOnlyError(w)
+		return
+	case errors.Is(err, sender.ErrRequestNotFound):
+		writeError(w, http.StatusNotFound, "not_found", "Sender request not found.")
+		return
+	case errors.As(err, &sendErr):
+		writeError(w, http.StatusBadGateway, "send_request_failed", fmt.Sprintf("Sending request failed: %v", sendErr.Unwrap()))
+	case errors.Is(err, sender.ErrScriptFailed), errors.Is(err, sender.ErrInvalidScript):
+		writeError(w, http.StatusUnprocessableEntity, "script_failed", err.Error())
+		return
+	case err != nil:
+		writeServiceError(w, fmt.Errorf("could not send request: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, parseSenderRequest(req))
+}
+
+func (h *handler) databaseStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.database.Stats(r.Context())
+	if err != nil {
+		writeServiceError(w, fmt.Errorf("could not get database stats: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, parseDatabaseStats(stats))
+}
+
+// compactDatabase starts a compaction in the background, because it can take
+// a while for large databases. Its result is reported in the stats.
+func (h *handler) compactDatabase(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.database.Stats(r.Context())
+	if err != nil {
+		writeServiceError(w, fmt.Errorf("could not get database stats: %w", err))
+		return
+	}
+
+	if stats.CompactionRunning {
+		writeError(w, http.StatusConflict, "compaction_running", "Compaction is already running.")
+		return
+	}
+
+	go func() {
+		if err := h.database.Compact(context.Background()); err != nil && !errors.Is(err, db.ErrCompactionRunning) {
+			log.Printf("[ERROR] Could not compact database: %v", err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// backupDatabase streams a backup of the database. Errors after the response
+// is started can only be logged, and result in a truncated response body.
+func (h *handler) backupDatabase(w http.ResponseWriter, _ *http.Request) {
+	filename := fmt.Sprintf("hetty-%v.bak", time.Now().UTC().Format("20060102-150405"))
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if err := h.database.Backup(w); err != nil {
+		log.Printf("[ERROR] REST API: could not write database backup: %v", err)
+	}
+}
+
+func (h *handler) compactionSchedule(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, parseCompactionSchedule(h.database.CompactionSchedule()))
+}
+
+func (h *handler) setCompactionSchedule(w http.ResponseWriter, r *http.Request) {
+	var input CompactionSchedule
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	schedule, err := compactionScheduleFromInput(input)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_compaction_schedule", err.Error())
+		return
+	}
+
+	err = h.database.SetCompactionSchedule(schedule)
+	if errors.Is(err, db.ErrInvalidCompactionWindow) {
+		writeError(w, http.StatusBadRequest, "invalid_compaction_schedule", "Window hours must be in range 0-23.")
+		return
+	} else if err != nil {
+		writeServiceError(w, fmt.Errorf("could not set compaction schedule: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, parseCompactionSchedule(schedule))
+}
+
+func (h *handler) captureStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, CaptureStatus{
+		Paused:        h.reqLogSvc.CapturePaused(),
+		ProjectPaused: h.reqLogSvc.ProjectCapturePaused(h.reqLogSvc.ActiveProjectID()),
+	})
+}
+
+func (h *handler) setCaptureStatus(w http.ResponseWriter, r *http.Request) {
+	var input CaptureStatusInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+
+	if input.ProjectPaused != nil {
+		err := h.projSvc.SetCapturePaused(r.Context(), ulid.ULID{}, *input.ProjectPaused)
+		switch {
+		case errors.Is(err, proj.ErrNoProject):
+			writeNoActiveProjectError(w)
+			return
+		case errors.Is(err, proj.ErrReadOnly):
+			writeReadOnlyError(w)
+			return
+		case err != nil:
+			writeServiceError(w, fmt.Errorf("could not set capture status: %w", err))
+			return
+		}
+	}
+
+	if input.Paused != nil {
+		h.reqLogSvc.SetCapturePaused(*input.Paused)
+	}
+
+	h.captureStatus(w, r)
+}
+
+func findRequestsFilterFromQuery(query url.Values) (filter reqlog.FindRequestsFilter, err error) {
+	for key, dst := range map[string]*bool{
+		"inScope":            &filter.OnlyInScope,
+		"collapseRedirects":  &filter.CollapseRedirects,
+		"collapsePageLoads":  &filter.CollapsePageLoads,
+		"hideStaticAssets":   &filter.Presets.HideStaticAssets,
+		"hideTracking":       &filter.Presets.HideTracking,
+		"hideCertRevocation": &filter.Presets.HideCertRevocation,
+	} {
+		if v := query.Get(key); v != "" {
+			if *dst, err = strconv.ParseBool(v); err != nil {
+				return reqlog.FindRequestsFilter{}, fmt.Errorf("invalid `%v` parameter: %q", key, v)
+			}
+		}
+	}
+
+	if q := query.Get("q"); q != "" {
+		expr, err := reqlog.ParseSearchExpr(q)
+		if err != nil {
+			return reqlog.FindRequestsFilter{}, fmt.Errorf("could not parse search query: %w", err)
+		}
+
+		filter.SearchExpr = expr
+	}
+
+	filter.Host = query.Get("host")
+	filter.ContentType = query.Get("contentType")
+
+	if v := query.Get("statusCode"); v != "" {
+		if filter.StatusCode, err = strconv.Atoi(v); err != nil {
+			return reqlog.FindRequestsFilter{}, fmt.Errorf("invalid `statusCode` parameter: %q", v)
+		}
+	}
+
+	for key, dst := range map[string]*time.Time{
+		"since": &filter.Since,
+		"until": &filter.Until,
+	} {
+		if v := query.Get(key); v != "" {
+			if *dst, err = time.Parse(time.RFC3339Nano, v); err != nil {
+				return reqlog.FindRequestsFilter{}, fmt.Errorf("invalid `%v` parameter: %q", key, v)
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (ulid.ULID, bool) {
+	id, err := ulid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Invalid ID.")
+		return ulid.ULID{}, false
+	}
+
+	return id, true
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", fmt.Sprintf("Invalid JSON request body: %v", err))
+		return false
+	}
+
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, code, message string) {
+	writeJSON(w, statusCode, ErrorResponse{Error: Error{Code: code, Message: message}})
+}
+
+func writeNoActiveProjectError(w http.ResponseWriter) {
+	writeError(w, http.StatusConflict, "no_active_project", "No active project.")
+}
+
+func writeReadOnlyError(w http.ResponseWriter) {
+	writeError(w, http.StatusConflict, "read_only", "Project is opened read-only.")
+}
+
+// errorStatusCodes are the HTTP status codes of error codes, see
+// `writeServiceError`.
+var errorStatusCodes = map[errcode.Code]int{
+	errcode.NotFound: http.StatusNotFound,
+	errcode.Invalid:  http.StatusBadRequest,
+	errcode.Storage:  http.StatusServiceUnavailable,
+	errcode.Upstream: http.StatusBadGateway,
+	errcode.Internal: http.StatusInternalServerError,
+}
+
+// writeServiceError writes an error returned by a service, with its code (see
+// package `errcode`). Storage and internal errors are logged, and their details
+// aren't exposed to clients.
+func writeServiceError(w http.ResponseWriter, err error) {
+	code := errcode.Of(err)
+
+	statusCode, ok := errorStatusCodes[code]
+	if !ok {
+		code, statusCode = errcode.Internal, http.StatusInternalServerError
+	}
+
+	message := err.Error()
+
+	switch code {
+	case errcode.Storage:
+		log.Printf("[ERROR] REST API: %v", err)
+		message = "Storage error."
+	case errcode.Internal:
+		log.Printf("[ERROR] REST API: %v", err)
+		message = "Internal server error."
+	}
+
+	writeError(w, statusCode, string(code), message)
+}
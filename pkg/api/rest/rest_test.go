@@ -0,0 +1,497 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/api/rest"
+	"github.com/dstotijn/hetty/pkg/db/badger"
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
+	"github.com/dstotijn/hetty/pkg/scope"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+//nolint:gosec
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func newTestServer(t *testing.T) (*httptest.Server, *memory.Database) {
+	t.Helper()
+
+	database := memory.OpenDatabase()
+	scope := &scope.Scope{}
+
+	reqLogSvc := reqlog.NewService(reqlog.Config{
+		Scope:      scope,
+		Repository: database,
+	})
+	senderSvc := sender.NewService(sender.Config{
+		Scope:         scope,
+		Repository:    database,
+		ReqLogService: reqLogSvc,
+		HTTPClient:    &http.Client{},
+	})
+
+	projSvc, err := proj.NewService(proj.Config{
+		Repository:    database,
+		ReqLogService: reqLogSvc,
+		SenderService: senderSvc,
+		Scope:         scope,
+		Rewriter:      &rewrite.Rewriter{},
+	})
+	if err != nil {
+		t.Fatalf("failed to create project service: %v", err)
+	}
+
+	ts := httptest.NewServer(rest.NewHandler(rest.Config{
+		ProjectService:    projSvc,
+		RequestLogService: reqLogSvc,
+		SenderService:     senderSvc,
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, database
+}
+
+func doJSON(t *testing.T, method, rawURL string, body, v interface{}) int {
+	t.Helper()
+
+	var reqBody io.Reader
+
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to encode request body: %v", err)
+		}
+
+		reqBody = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, rawURL, reqBody)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("failed to decode response body: %v", err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func TestProjects(t *testing.T) {
+	t.Parallel()
+
+	ts, _ := newTestServer(t)
+	baseURL := ts.URL + rest.PathPrefix
+
+	var errResp rest.ErrorResponse
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/projects/active", nil, &errResp); code != http.StatusConflict {
+		t.Fatalf("expected status code %v, got: %v", http.StatusConflict, code)
+	}
+
+	if errResp.Error.Code != "no_active_project" {
+		t.Fatalf("expected error code `no_active_project`, got: %q", errResp.Error.Code)
+	}
+
+	var project rest.Project
+
+	code := doJSON(t, http.MethodPost, baseURL+"/projects", rest.CreateProjectInput{Name: "foobar"}, &project)
+	if code != http.StatusCreated {
+		t.Fatalf("expected status code %v, got: %v", http.StatusCreated, code)
+	}
+
+	if code := doJSON(t, http.MethodPost, baseURL+"/projects/"+project.ID.String()+"/open", nil, &project); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if !project.IsActive {
+		t.Fatal("expected project to be active")
+	}
+
+	var projects []rest.Project
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/projects", nil, &projects); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if len(projects) != 1 || projects[0].ID != project.ID {
+		t.Fatalf("unexpected projects: %+v", projects)
+	}
+
+	if code := doJSON(t, http.MethodDelete, baseURL+"/projects/active", nil, nil); code != http.StatusNoContent {
+		t.Fatalf("expected status code %v, got: %v", http.StatusNoContent, code)
+	}
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/projects/foo/open", nil, &errResp); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status code %v, got: %v", http.StatusMethodNotAllowed, code)
+	}
+}
+
+func TestOpenProjectReadOnly(t *testing.T) {
+	t.Parallel()
+
+	ts, _ := newTestServer(t)
+	baseURL := ts.URL + rest.PathPrefix
+
+	var project rest.Project
+
+	doJSON(t, http.MethodPost, baseURL+"/projects", rest.CreateProjectInput{Name: "foobar"}, &project)
+
+	if code := doJSON(t, http.MethodPost, baseURL+"/projects/"+project.ID.String()+"/open?readOnly=maybe", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected status code %v, got: %v", http.StatusBadRequest, code)
+	}
+
+	if code := doJSON(t, http.MethodPost, baseURL+"/projects/"+project.ID.String()+"/open?readOnly=true", nil, &project); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if !project.IsActive || !project.IsReadOnly {
+		t.Fatalf("expected project to be active and read-only, got: %+v", project)
+	}
+
+	var errResp rest.ErrorResponse
+
+	code := doJSON(t, http.MethodPost, baseURL+"/sender-requests", rest.SenderRequestInput{URL: "https://example.com/"}, &errResp)
+	if code != http.StatusConflict {
+		t.Fatalf("expected status code %v, got: %v", http.StatusConflict, code)
+	}
+
+	if errResp.Error.Code != "read_only" {
+		t.Fatalf("expected error code `read_only`, got: %q", errResp.Error.Code)
+	}
+
+	if code := doJSON(t, http.MethodPost, baseURL+"/projects", rest.CreateProjectInput{Name: "baz"}, nil); code != http.StatusConflict {
+		t.Fatalf("expected status code %v, got: %v", http.StatusConflict, code)
+	}
+
+	// Reopening the project allows changes again.
+	if code := doJSON(t, http.MethodPost, baseURL+"/projects/"+project.ID.String()+"/open", nil, &project); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if project.IsReadOnly {
+		t.Fatal("expected project not to be read-only")
+	}
+
+	code = doJSON(t, http.MethodPost, baseURL+"/sender-requests", rest.SenderRequestInput{URL: "https://example.com/"}, nil)
+	if code != http.StatusCreated {
+		t.Fatalf("expected status code %v, got: %v", http.StatusCreated, code)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	t.Parallel()
+
+	ts, _ := newTestServer(t)
+	baseURL := ts.URL + rest.PathPrefix
+	paused := true
+
+	var errResp rest.ErrorResponse
+
+	code := doJSON(t, http.MethodPut, baseURL+"/capture", rest.CaptureStatusInput{ProjectPaused: &paused}, &errResp)
+	if code != http.StatusConflict || errResp.Error.Code != "no_active_project" {
+		t.Fatalf("expected `no_active_project` error, got: %v (%+v)", code, errResp)
+	}
+
+	var status rest.CaptureStatus
+
+	if code := doJSON(t, http.MethodPut, baseURL+"/capture", rest.CaptureStatusInput{Paused: &paused}, &status); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if !status.Paused || status.ProjectPaused {
+		t.Fatalf("expected capture to be paused globally, got: %+v", status)
+	}
+
+	var project rest.Project
+
+	doJSON(t, http.MethodPost, baseURL+"/projects", rest.CreateProjectInput{Name: "foobar"}, &project)
+	doJSON(t, http.MethodPost, baseURL+"/projects/"+project.ID.String()+"/open", nil, nil)
+
+	if code := doJSON(t, http.MethodPut, baseURL+"/capture", rest.CaptureStatusInput{ProjectPaused: &paused}, nil); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	// The project setting is restored when the project is reopened.
+	doJSON(t, http.MethodDelete, baseURL+"/projects/active", nil, nil)
+	doJSON(t, http.MethodPost, baseURL+"/projects/"+project.ID.String()+"/open", nil, nil)
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/capture", nil, &status); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if !status.Paused || !status.ProjectPaused {
+		t.Fatalf("expected capture to be paused globally and for the project, got: %+v", status)
+	}
+}
+
+func TestRequestLogsAndSender(t *testing.T) {
+	t.Parallel()
+
+	ts, database := newTestServer(t)
+	baseURL := ts.URL + rest.PathPrefix
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	t.Cleanup(upstream.Close)
+
+	var project rest.Project
+
+	doJSON(t, http.MethodPost, baseURL+"/projects", rest.CreateProjectInput{Name: "foobar"}, &project)
+	doJSON(t, http.MethodPost, baseURL+"/projects/"+project.ID.String()+"/open", nil, nil)
+
+	upstreamURL, _ := url.Parse(upstream.URL + "/login")
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: project.ID,
+		URL:       upstreamURL,
+		Method:    http.MethodPost,
+		Proto:     sender.HTTPProto1,
+		Header:    http.Header{"X-Foo": []string{"bar"}},
+		Body:      []byte("foo=bar"),
+	}
+
+	if err := database.StoreRequestLog(context.Background(), reqLog); err != nil {
+		t.Fatalf("failed to store request log: %v", err)
+	}
+
+	var reqLogs []rest.RequestLog
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/request-logs?q=req.body+%3D~+foo", nil, &reqLogs); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if len(reqLogs) != 1 || reqLogs[0].ID != reqLog.ID || reqLogs[0].Body != "foo=bar" {
+		t.Fatalf("unexpected request logs: %+v", reqLogs)
+	}
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/request-logs?host=example.com", nil, &reqLogs); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if len(reqLogs) != 0 {
+		t.Fatalf("expected no request logs for host, got: %+v", reqLogs)
+	}
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/request-logs?statusCode=ok", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected status code %v, got: %v", http.StatusBadRequest, code)
+	}
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/request-logs?since=yesterday", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected status code %v, got: %v", http.StatusBadRequest, code)
+	}
+
+	since := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/request-logs?since="+since, nil, &reqLogs); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if len(reqLogs) != 0 {
+		t.Fatalf("expected no request logs since an hour from now, got: %+v", reqLogs)
+	}
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/request-logs?inScope=maybe", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected status code %v, got: %v", http.StatusBadRequest, code)
+	}
+
+	var errResp rest.ErrorResponse
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/request-logs?q=req.metod+%3D+GET", nil, &errResp); code != http.StatusBadRequest {
+		t.Fatalf("expected status code %v, got: %v", http.StatusBadRequest, code)
+	}
+
+	if s := errResp.Error.Search; s == nil || s.Position != 0 || len(s.Suggestions) == 0 || s.Suggestions[0] != "req.method" {
+		t.Fatalf("expected search error with suggestion, got: %+v", errResp.Error)
+	}
+
+	var senderReq rest.SenderRequest
+
+	code := doJSON(t, http.MethodPost, baseURL+"/sender-requests", rest.SenderRequestInput{RequestLogID: &reqLog.ID}, &senderReq)
+	if code != http.StatusCreated {
+		t.Fatalf("expected status code %v, got: %v", http.StatusCreated, code)
+	}
+
+	if senderReq.SourceRequestLogID == nil || *senderReq.SourceRequestLogID != reqLog.ID {
+		t.Fatalf("unexpected source request log ID: %v", senderReq.SourceRequestLogID)
+	}
+
+	code = doJSON(t, http.MethodPost, baseURL+"/sender-requests/"+senderReq.ID.String()+"/send", nil, &senderReq)
+	if code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if senderReq.Response == nil || senderReq.Response.Body != "hello" {
+		t.Fatalf("unexpected sender response: %+v", senderReq.Response)
+	}
+}
+
+func TestRequestLogBody(t *testing.T) {
+	t.Parallel()
+
+	ts, database := newTestServer(t)
+	baseURL := ts.URL + rest.PathPrefix
+
+	var project rest.Project
+
+	doJSON(t, http.MethodPost, baseURL+"/projects", rest.CreateProjectInput{Name: "foobar"}, &project)
+	doJSON(t, http.MethodPost, baseURL+"/projects/"+project.ID.String()+"/open", nil, nil)
+
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: project.ID,
+		URL:       &url.URL{Scheme: "https", Host: "example.com"},
+		Method:    http.MethodGet,
+		Proto:     sender.HTTPProto1,
+		Body:      []byte("<script>foo</script>"),
+	}
+
+	if err := database.StoreRequestLog(context.Background(), reqLog); err != nil {
+		t.Fatalf("failed to store request log: %v", err)
+	}
+
+	getBody := func(path string, header http.Header) (*http.Response, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, baseURL+path, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+
+		req.Header = header
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+
+		return resp, string(body)
+	}
+
+	path := "/request-logs/" + reqLog.ID.String() + "/request-body"
+
+	resp, body := getBody(path, http.Header{})
+	if resp.StatusCode != http.StatusOK || body != string(reqLog.Body) {
+		t.Fatalf("unexpected response: %v %q", resp.StatusCode, body)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/octet-stream" {
+		t.Fatalf("expected binary content type, got: %q", contentType)
+	}
+
+	etag := resp.Header.Get("ETag")
+
+	resp, body = getBody(path, http.Header{"Range": []string{"bytes=8-10"}, "If-Range": []string{etag}})
+	if resp.StatusCode != http.StatusPartialContent || body != "foo" {
+		t.Fatalf("unexpected partial response: %v %q", resp.StatusCode, body)
+	}
+
+	// A stale ETag gets the whole body.
+	resp, body = getBody(path, http.Header{"Range": []string{"bytes=8-10"}, "If-Range": []string{`"stale"`}})
+	if resp.StatusCode != http.StatusOK || body != string(reqLog.Body) {
+		t.Fatalf("unexpected response for stale ETag: %v %q", resp.StatusCode, body)
+	}
+
+	resp, _ = getBody("/request-logs/"+reqLog.ID.String()+"/response-body", http.Header{})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status code %v for missing response, got: %v", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestDatabase(t *testing.T) {
+	t.Parallel()
+
+	database, err := badger.OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	ts := httptest.NewServer(rest.NewHandler(rest.Config{Database: database}))
+	t.Cleanup(ts.Close)
+
+	baseURL := ts.URL + rest.PathPrefix
+
+	var schedule rest.CompactionSchedule
+
+	code := doJSON(t, http.MethodPut, baseURL+"/database/compaction-schedule", rest.CompactionSchedule{
+		Interval:    "24h",
+		WindowStart: 2,
+		WindowEnd:   6,
+	}, &schedule)
+	if code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if schedule.Interval != "24h0m0s" {
+		t.Fatalf("unexpected compaction schedule: %+v", schedule)
+	}
+
+	code = doJSON(t, http.MethodPut, baseURL+"/database/compaction-schedule", rest.CompactionSchedule{WindowEnd: 25}, nil)
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected status code %v, got: %v", http.StatusBadRequest, code)
+	}
+
+	if code := doJSON(t, http.MethodPost, baseURL+"/database/compact", nil, nil); code != http.StatusAccepted {
+		t.Fatalf("expected status code %v, got: %v", http.StatusAccepted, code)
+	}
+
+	var stats rest.DatabaseStats
+
+	if code := doJSON(t, http.MethodGet, baseURL+"/database/stats", nil, &stats); code != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, code)
+	}
+
+	if stats.CompactionSchedule != schedule {
+		t.Fatalf("unexpected compaction schedule in stats: %+v", stats.CompactionSchedule)
+	}
+
+	if _, ok := stats.KeyCounts["requestLogs"]; !ok {
+		t.Fatalf("expected request log key count, got: %+v", stats.KeyCounts)
+	}
+
+	resp, err := http.Get(baseURL + "/database/backup")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	backup, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || len(backup) == 0 {
+		t.Fatalf("unexpected backup response (status code: %v, size: %v)", resp.StatusCode, len(backup))
+	}
+}
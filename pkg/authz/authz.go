@@ -0,0 +1,428 @@
+// Package authz tests the access control of a target, by replaying in-scope
+// proxied requests with the credentials of a second user (e.g. a low
+// privileged one). A response to a replay that is identical to the response
+// to the original request indicates that authorization isn't enforced, and is
+// stored as a finding. Selected request logs can also be replayed without
+// credentials, to find endpoints that don't require authentication, see
+// unauth.go.
+package authz
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/ratelimit"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+// Replays aren't sent via the proxy, so that they aren't logged or replayed
+// themselves.
+var defaultHTTPClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+const (
+	// Maximum number of replays that are sent concurrently.
+	maxConcurrentReplays = 4
+	// Maximum number of requests that are remembered as checked, see
+	// `service.checked`.
+	maxChecked = 10000
+)
+
+var ErrInvalidSettings = errcode.New(errcode.Invalid, "authz: invalid settings")
+
+// Settings of the check, per project.
+type Settings struct {
+	Enabled bool
+	// Headers that replace those of the original request, e.g. the `Cookie`
+	// or `Authorization` header of the second user. Requests that have none of
+	// them aren't replayed, as they don't carry credentials.
+	Headers http.Header
+}
+
+// Service replays proxied requests with the credentials of a second user, or
+// without credentials, see the package documentation.
+type Service interface {
+	ResponseModifier(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc
+	SetSettings(settings Settings)
+	Settings() Settings
+	StartUnauthCheck(ctx context.Context, params UnauthCheckParams) (UnauthCheck, error)
+	FindUnauthCheckByID(id ulid.ULID) (UnauthCheck, error)
+	FindUnauthChecks() []UnauthCheck
+	CancelUnauthCheck(id ulid.ULID) error
+	SetActiveProjectID(id ulid.ULID)
+	SetReadOnly(readOnly bool)
+	CancelReplays()
+	Flush(ctx context.Context) error
+	Close()
+}
+
+type service struct {
+	// mu guards the settings and active project, which are changed at
+	// runtime.
+	mu              sync.RWMutex
+	settings        Settings
+	activeProjectID ulid.ULID
+	readOnly        bool
+	// Method and URL of requests that were replayed, so that repeated
+	// requests aren't. Reset when the settings or active project change.
+	checked map[string]bool
+	// replayCtx is the context of pending replays, derived from ctx. It's
+	// replaced by `CancelReplays`.
+	replayCtx     context.Context
+	cancelReplays context.CancelFunc
+
+	unauthChecks map[ulid.ULID]*unauthCheckState
+	unauthMu     sync.RWMutex
+
+	scope       *scope.Scope
+	reqLogSvc   reqlog.Service
+	findingRepo finding.Repository
+	httpClient  *http.Client
+	rateLimiter *ratelimit.Limiter
+	ids         idgen.Generator
+	events      *event.Bus
+
+	// ctx is the parent of the contexts of replays and unauthenticated
+	// checks. It's canceled by `Close`.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	sem     chan struct{}
+	pending sync.WaitGroup
+}
+
+type Config struct {
+	Scope             *scope.Scope
+	RequestLogService reqlog.Service
+	FindingRepository finding.Repository
+	// Client that replays are sent with. It must not send requests via the
+	// proxy. Defaults to a client that doesn't follow redirects.
+	HTTPClient *http.Client
+	// Limits the rate of replays per host. Share it with the proxy, so that
+	// replays count towards the same limits as proxied requests. Optional.
+	RateLimiter *ratelimit.Limiter
+	// Bus for publishing created findings. Optional.
+	Events *event.Bus
+	// Generates the IDs of findings. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = defaultHTTPClient
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	replayCtx, cancelReplays := context.WithCancel(ctx)
+
+	return &service{
+		checked:       make(map[string]bool),
+		unauthChecks:  make(map[ulid.ULID]*unauthCheckState),
+		scope:         cfg.Scope,
+		reqLogSvc:     cfg.RequestLogService,
+		findingRepo:   cfg.FindingRepository,
+		httpClient:    cfg.HTTPClient,
+		rateLimiter:   cfg.RateLimiter,
+		ids:           cfg.IDGenerator,
+		events:        cfg.Events,
+		ctx:           ctx,
+		cancel:        cancel,
+		replayCtx:     replayCtx,
+		cancelReplays: cancelReplays,
+		sem:           make(chan struct{}, maxConcurrentReplays),
+	}
+}
+
+// Validate returns an error if the check can't be enabled with settings.
+func (settings Settings) Validate() error {
+	if settings.Enabled && len(settings.Headers) == 0 {
+		return fmt.Errorf("%w: headers with the credentials of the second user must be set", ErrInvalidSettings)
+	}
+
+	for key := range settings.Headers {
+		if key == "" {
+			return fmt.Errorf("%w: header key must not be empty", ErrInvalidSettings)
+		}
+	}
+
+	return nil
+}
+
+// ResponseModifier replays requests of successful responses in the
+// background, if the check is enabled. It's meant to run before other response
+// modifiers, on the original response.
+func (svc *service) ResponseModifier(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+	return func(res *http.Response) error {
+		if err := next(res); err != nil {
+			return err
+		}
+
+		if bypassed, _ := res.Request.Context().Value(reqlog.LogBypassedKey).(bool); bypassed {
+			return nil
+		}
+
+		reqLogID, ok := res.Request.Context().Value(proxy.ReqLogIDKey).(ulid.ULID)
+		if !ok {
+			return nil
+		}
+
+		settings, ctx := svc.replaySettings()
+		if !settings.Enabled || res.StatusCode < 200 || res.StatusCode > 299 {
+			return nil
+		}
+
+		if !hasAnyHeader(res.Request.Header, settings.Headers) {
+			return nil
+		}
+
+		projectID, ok := res.Request.Context().Value(reqlog.ProjectIDKey).(ulid.ULID)
+		if !ok || svc.isReadOnlyProject(projectID) {
+			return nil
+		}
+
+		if !svc.markChecked(res.Request) {
+			return nil
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("authz: could not read response body: %w", err)
+		}
+
+		res.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+		statusCode := res.StatusCode
+
+		svc.pending.Add(1)
+
+		go func() {
+			defer svc.pending.Done()
+
+			select {
+			case svc.sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-svc.sem }()
+
+			if err := svc.check(ctx, reqLogID, statusCode, body, settings); err != nil {
+				log.Printf("[ERROR] Could not check authorization of request log (id: %v): %v", reqLogID, err)
+			}
+		}()
+
+		return nil
+	}
+}
+
+// check replays a logged request with the headers of settings, and stores a
+// finding if the response is identical to the original one. Replays are
+// aborted when ctx is done.
+func (svc *service) check(ctx context.Context, reqLogID ulid.ULID, statusCode int, body []byte, settings Settings) error {
+	reqLog, err := svc.reqLogSvc.FindRequestLogByID(ctx, reqLogID)
+	if err != nil {
+		return fmt.Errorf("failed to find request log: %w", err)
+	}
+
+	if svc.scope != nil && !svc.scope.Match(&http.Request{URL: reqLog.URL, Header: reqLog.Header}, reqLog.Body) {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, reqLog.Method, reqLog.URL.String(), bytes.NewReader(reqLog.Body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header = reqLog.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	for key, values := range settings.Headers {
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+
+	if err := svc.rateLimiter.Wait(ctx, req.URL.Hostname()); err != nil {
+		return fmt.Errorf("failed to wait for rate limit: %w", err)
+	}
+
+	res, err := svc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send replay: %w", err)
+	}
+	defer res.Body.Close()
+
+	replayBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body of replay: %w", err)
+	}
+
+	if res.StatusCode != statusCode || !bytes.Equal(replayBody, body) {
+		return nil
+	}
+
+	return svc.storeFinding(reqLog, statusCode, len(body))
+}
+
+func (svc *service) storeFinding(reqLog reqlog.RequestLog, statusCode, bodySize int) error {
+	f := finding.Finding{
+		ID:           svc.ids.New(time.Now()),
+		ProjectID:    reqLog.ProjectID,
+		RequestLogID: reqLog.ID,
+		Check:        finding.CheckAuthorizationBypass,
+		Severity:     finding.SeverityMedium,
+		Description: fmt.Sprintf(
+			"The response to the request with the credentials of the second user is identical to the original "+
+				"response (status code: %v, body size: %v bytes). Access control may not be enforced.",
+			statusCode, bodySize,
+		),
+	}
+
+	if err := svc.findingRepo.StoreFinding(context.Background(), f); err != nil {
+		return fmt.Errorf("failed to store finding: %w", err)
+	}
+
+	svc.events.Publish(event.Event{
+		Type:      event.TypeFindingCreated,
+		ProjectID: f.ProjectID,
+		ID:        f.ID,
+		Data:      f,
+	})
+
+	return nil
+}
+
+// markChecked returns false if a request with the same method and URL was
+// replayed before.
+func (svc *service) markChecked(req *http.Request) bool {
+	key := req.Method + " " + req.URL.String()
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if svc.checked[key] {
+		return false
+	}
+
+	if len(svc.checked) >= maxChecked {
+		svc.checked = make(map[string]bool)
+	}
+
+	svc.checked[key] = true
+
+	return true
+}
+
+func hasAnyHeader(header, keys http.Header) bool {
+	for key := range keys {
+		if header.Get(key) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Flush waits until pending replays are checked, or ctx is done.
+func (svc *service) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		svc.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("authz: failed to flush checks: %w", ctx.Err())
+	}
+}
+
+// CancelReplays aborts pending replays of proxied requests. Requests that are
+// proxied afterwards are replayed, if the check is still enabled.
+func (svc *service) CancelReplays() {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.cancelReplays()
+	svc.replayCtx, svc.cancelReplays = context.WithCancel(svc.ctx)
+}
+
+// Close aborts pending replays and running unauthenticated checks. Replays of
+// requests that are proxied afterwards aren't sent.
+func (svc *service) Close() {
+	svc.cancel()
+}
+
+// SetSettings replaces the settings of the check. Requests that were checked
+// with previous settings are checked again.
+func (svc *service) SetSettings(settings Settings) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.settings = settings
+	svc.checked = make(map[string]bool)
+}
+
+func (svc *service) Settings() Settings {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.settings
+}
+
+// replaySettings returns the settings, and the context that replays with them
+// are sent with.
+func (svc *service) replaySettings() (Settings, context.Context) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.settings, svc.replayCtx
+}
+
+func (svc *service) SetActiveProjectID(id ulid.ULID) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.activeProjectID = id
+	svc.checked = make(map[string]bool)
+}
+
+// SetReadOnly sets whether the active project is opened read-only. Requests
+// aren't replayed for it.
+func (svc *service) SetReadOnly(readOnly bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.readOnly = readOnly
+}
+
+// isReadOnlyProject returns true if requests logged to the project must not be
+// replayed, i.e. if it's the active project and it's opened read-only.
+func (svc *service) isReadOnlyProject(projectID ulid.ULID) bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.readOnly && svc.activeProjectID.Compare(projectID) == 0
+}
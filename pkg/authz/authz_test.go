@@ -0,0 +1,414 @@
+package authz_test
+
+//go:generate go run github.com/matryer/moq -out reqlog_mock_test.go -pkg authz_test ../reqlog Service:ReqLogServiceMock
+//go:generate go run github.com/matryer/moq -out finding_repo_mock_test.go -pkg authz_test ../finding Repository:FindingRepoMock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/authz"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/ratelimit"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+//nolint:gosec
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func TestResponseModifier(t *testing.T) {
+	t.Parallel()
+
+	// `/admin` doesn't check the session, `/profile` does.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin":
+			fmt.Fprint(w, "admin panel")
+		case "/profile":
+			fmt.Fprintf(w, "profile of %v", r.Header.Get("Cookie"))
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	var (
+		reqLogs   = make(map[ulid.ULID]reqlog.RequestLog)
+		reqLogsMu sync.Mutex
+	)
+
+	reqLogSvc := &ReqLogServiceMock{
+		FindRequestLogByIDFunc: func(_ context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
+			reqLogsMu.Lock()
+			defer reqLogsMu.Unlock()
+
+			return reqLogs[id], nil
+		},
+	}
+
+	findingRepo := &FindingRepoMock{
+		StoreFindingFunc: func(_ context.Context, _ finding.Finding) error {
+			return nil
+		},
+	}
+
+	s := &scope.Scope{}
+	s.SetRules([]scope.Rule{{URL: regexp.MustCompile(regexp.QuoteMeta(ts.URL))}})
+
+	svc := authz.NewService(authz.Config{
+		Scope:             s,
+		RequestLogService: reqLogSvc,
+		FindingRepository: findingRepo,
+	})
+	svc.SetActiveProjectID(projectID)
+	svc.SetSettings(authz.Settings{
+		Enabled: true,
+		Headers: http.Header{"Cookie": []string{"session=user"}},
+	})
+
+	// proxyRequest passes the response of a proxied request through the
+	// modifier, and returns the request log ID and the (unmodified) body.
+	proxyRequest := func(t *testing.T, path string, header http.Header) (ulid.ULID, string) {
+		t.Helper()
+
+		reqLog := reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID: projectID,
+			Method:    http.MethodGet,
+			URL:       mustParseURL(t, ts.URL+path),
+			Header:    header,
+		}
+
+		reqLogsMu.Lock()
+		reqLogs[reqLog.ID] = reqLog
+		reqLogsMu.Unlock()
+
+		ctx := context.WithValue(context.Background(), proxy.ReqLogIDKey, reqLog.ID)
+		ctx = context.WithValue(ctx, reqlog.ProjectIDKey, projectID)
+
+		req, err := http.NewRequestWithContext(ctx, reqLog.Method, reqLog.URL.String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header = header
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		fn := svc.ResponseModifier(func(*http.Response) error { return nil })
+		if err := fn(res); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return reqLog.ID, string(body)
+	}
+
+	adminID, body := proxyRequest(t, "/admin", http.Header{"Cookie": []string{"session=admin"}})
+	if body != "admin panel" {
+		t.Fatalf("expected response body to be left as is, got: %q", body)
+	}
+
+	proxyRequest(t, "/profile", http.Header{"Cookie": []string{"session=admin"}})
+	// Requests without credentials aren't replayed.
+	proxyRequest(t, "/admin", http.Header{})
+
+	if err := svc.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	calls := findingRepo.StoreFindingCalls()
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 finding to be stored, got: %+v", calls)
+	}
+
+	got := calls[0].FindingMoqParam
+	if got.Check != finding.CheckAuthorizationBypass || got.RequestLogID != adminID || got.ProjectID != projectID {
+		t.Fatalf("expected authorization bypass finding for `/admin`, got: %+v", got)
+	}
+
+	if !strings.Contains(got.Description, "status code: 200") {
+		t.Errorf("expected description to contain status code, got: %q", got.Description)
+	}
+}
+
+func TestResponseModifierCancel(t *testing.T) {
+	t.Parallel()
+
+	var replays int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") == "session=user" {
+			atomic.AddInt32(&replays, 1)
+		}
+
+		fmt.Fprint(w, "admin panel")
+	}))
+	t.Cleanup(ts.Close)
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: projectID,
+		Method:    http.MethodGet,
+		URL:       mustParseURL(t, ts.URL+"/admin"),
+		Header:    http.Header{"Cookie": []string{"session=admin"}},
+	}
+
+	findingRepo := &FindingRepoMock{
+		StoreFindingFunc: func(_ context.Context, _ finding.Finding) error {
+			return nil
+		},
+	}
+
+	// The burst is used up, so that replays wait for the rate limit until
+	// they're aborted.
+	limiter := ratelimit.NewLimiter([]ratelimit.Rule{{Host: reqLog.URL.Hostname(), Rate: 0.001}})
+	if err := limiter.Wait(context.Background(), reqLog.URL.Hostname()); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := authz.NewService(authz.Config{
+		RequestLogService: &ReqLogServiceMock{
+			FindRequestLogByIDFunc: func(_ context.Context, _ ulid.ULID) (reqlog.RequestLog, error) {
+				return reqLog, nil
+			},
+		},
+		FindingRepository: findingRepo,
+		RateLimiter:       limiter,
+	})
+	svc.SetActiveProjectID(projectID)
+	svc.SetSettings(authz.Settings{
+		Enabled: true,
+		Headers: http.Header{"Cookie": []string{"session=user"}},
+	})
+
+	fn := svc.ResponseModifier(func(*http.Response) error { return nil })
+
+	// proxyRequest passes the response of a proxied request to path through
+	// the modifier.
+	proxyRequest := func(t *testing.T, path string) {
+		t.Helper()
+
+		ctx := context.WithValue(context.Background(), proxy.ReqLogIDKey, reqLog.ID)
+		ctx = context.WithValue(ctx, reqlog.ProjectIDKey, projectID)
+
+		req, err := http.NewRequestWithContext(ctx, reqLog.Method, ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header = reqLog.Header
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		if err := fn(res); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	proxyRequest(t, "/admin")
+	svc.CancelReplays()
+
+	if err := svc.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Requests proxied after replays were cancelled are replayed.
+	proxyRequest(t, "/admin?page=2")
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := svc.Flush(flushCtx); err == nil {
+		t.Fatal("expected flush to time out while replay waits for rate limit")
+	}
+
+	svc.Close()
+
+	if err := svc.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&replays); n != 0 {
+		t.Errorf("expected no replays to be sent after cancel and close, got: %v", n)
+	}
+
+	if calls := findingRepo.StoreFindingCalls(); len(calls) != 0 {
+		t.Errorf("expected no findings to be stored, got: %+v", calls)
+	}
+}
+
+func TestStartUnauthCheck(t *testing.T) {
+	t.Parallel()
+
+	// `/public` doesn't require a session, `/private` does.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/private" && r.Header.Get("Cookie") == "" && r.URL.Query().Get("token") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, "login required")
+
+			return
+		}
+
+		fmt.Fprint(w, "account details of alice")
+	}))
+	t.Cleanup(ts.Close)
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	newReqLog := func(path string, header http.Header) reqlog.RequestLog {
+		return reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID: projectID,
+			Method:    http.MethodGet,
+			URL:       mustParseURL(t, ts.URL+path),
+			Header:    header,
+			Response: &reqlog.ResponseLog{
+				StatusCode: http.StatusOK,
+				Body:       []byte("account details of bob"),
+			},
+		}
+	}
+
+	public := newReqLog("/public", http.Header{"Cookie": []string{"session=bob"}})
+	time.Sleep(time.Millisecond)
+	private := newReqLog("/private?token=secret", http.Header{"Authorization": []string{"Bearer secret"}})
+	// Requests without credentials are skipped.
+	anonymous := newReqLog("/public", http.Header{})
+
+	reqLogSvc := &ReqLogServiceMock{
+		FindSelectedRequestsFunc: func(_ context.Context, _ reqlog.Selection) ([]reqlog.RequestLog, error) {
+			return []reqlog.RequestLog{private, anonymous, public}, nil
+		},
+	}
+
+	findingRepo := &FindingRepoMock{
+		StoreFindingFunc: func(_ context.Context, _ finding.Finding) error {
+			return nil
+		},
+	}
+
+	svc := authz.NewService(authz.Config{
+		RequestLogService: reqLogSvc,
+		FindingRepository: findingRepo,
+	})
+
+	check, err := svc.StartUnauthCheck(context.Background(), authz.UnauthCheckParams{
+		StripQueryParams: []string{"token"},
+		MinSimilarity:    0.8,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if check.Total != 2 {
+		t.Fatalf("expected 2 request logs to be checked, got: %v", check.Total)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+
+	for check.Status == authz.StatusRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+
+		check, err = svc.FindUnauthCheckByID(check.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if check.Status != authz.StatusFinished || len(check.Results) != 2 {
+		t.Fatalf("expected check to finish with 2 results, got: %+v", check)
+	}
+
+	// Results are in the order the request logs were logged.
+	publicResult, privateResult := check.Results[0], check.Results[1]
+
+	if !publicResult.Bypass || publicResult.Similarity < 0.8 || publicResult.Similarity == 1 {
+		t.Errorf("expected `/public` to be a bypass with a similar body, got: %+v", publicResult)
+	}
+
+	if privateResult.Bypass || privateResult.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected `/private` to require authentication, got: %+v", privateResult)
+	}
+
+	if exp := []string{"Authorization", "token"}; !cmp.Equal(exp, privateResult.Stripped) {
+		t.Errorf("expected stripped %v, got: %v", exp, privateResult.Stripped)
+	}
+
+	calls := findingRepo.StoreFindingCalls()
+	if len(calls) != 1 || calls[0].FindingMoqParam.Check != finding.CheckAuthenticationBypass ||
+		calls[0].FindingMoqParam.RequestLogID != public.ID {
+		t.Fatalf("expected authentication bypass finding for `/public`, got: %+v", calls)
+	}
+}
+
+func TestStartUnauthCheckNoRequests(t *testing.T) {
+	t.Parallel()
+
+	svc := authz.NewService(authz.Config{
+		RequestLogService: &ReqLogServiceMock{
+			FindSelectedRequestsFunc: func(_ context.Context, _ reqlog.Selection) ([]reqlog.RequestLog, error) {
+				return nil, nil
+			},
+		},
+	})
+
+	_, err := svc.StartUnauthCheck(context.Background(), authz.UnauthCheckParams{})
+	if !errors.Is(err, authz.ErrNoRequests) {
+		t.Fatalf("expected `authz.ErrNoRequests`, got: %v", err)
+	}
+}
+
+func TestSettingsValidate(t *testing.T) {
+	t.Parallel()
+
+	err := authz.Settings{Enabled: true}.Validate()
+	if !errors.Is(err, authz.ErrInvalidSettings) {
+		t.Fatalf("expected `authz.ErrInvalidSettings`, got: %v", err)
+	}
+
+	if err := (authz.Settings{}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
@@ -0,0 +1,458 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+const (
+	// Default of `UnauthCheckParams.MinSimilarity`.
+	defaultMinSimilarity = 0.95
+	// Maximum size of the response bodies that are compared. Differences
+	// beyond it are ignored.
+	maxSimilarityBodySize = 256 << 10
+)
+
+var (
+	ErrCheckNotFound     = errcode.New(errcode.NotFound, "authz: check not found")
+	ErrNoRequests        = errcode.New(errcode.Invalid, "authz: no request logs with credentials and a response selected")
+	ErrInvalidSimilarity = errcode.New(errcode.Invalid, "authz: minimum similarity must be between 0 and 1")
+)
+
+// Headers that carry credentials, which are removed from requests of an
+// unauthenticated check.
+var authHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-Csrf-Token",
+	"X-Xsrf-Token",
+}
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusFinished  Status = "finished"
+	StatusCancelled Status = "cancelled"
+)
+
+type UnauthCheckParams struct {
+	Selection reqlog.Selection
+	// Headers that are removed from requests, in addition to common
+	// credential headers (e.g. `Authorization` and `Cookie`) and the headers
+	// of the authorization check settings.
+	StripHeaders []string
+	// Query parameters that are removed from requests, e.g. `access_token`.
+	StripQueryParams []string
+	// Minimum similarity (between 0 and 1) of the body of a response to the
+	// original one, for an endpoint to be marked as a candidate bypass.
+	// Defaults to 0.95.
+	MinSimilarity float64
+}
+
+// UnauthCheck replays request logs without their credentials.
+type UnauthCheck struct {
+	ID            ulid.ULID
+	Status        Status
+	MinSimilarity float64
+	Total         int
+	Completed     int
+	Results       []UnauthResult
+}
+
+type UnauthResult struct {
+	RequestLogID ulid.ULID
+	Method       string
+	URL          *url.URL
+	// Headers and query parameters that were removed.
+	Stripped           []string
+	OriginalStatusCode int
+	StatusCode         int
+	// Similarity of the response body to the original one, from 0 (no
+	// overlap) to 1 (identical).
+	Similarity float64
+	// Bypass is true if the original response was successful, and the
+	// response has the same status code and a similar body. A finding is
+	// stored for it.
+	Bypass bool
+	// Error of sending the request, if any.
+	Error string
+}
+
+type unauthCheckState struct {
+	check  UnauthCheck
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+// StartUnauthCheck looks up the selected request logs, and replays those with
+// credentials and a response without their credentials, in the background.
+func (svc *service) StartUnauthCheck(ctx context.Context, params UnauthCheckParams) (UnauthCheck, error) {
+	if params.MinSimilarity == 0 {
+		params.MinSimilarity = defaultMinSimilarity
+	}
+
+	if params.MinSimilarity < 0 || params.MinSimilarity > 1 {
+		return UnauthCheck{}, ErrInvalidSimilarity
+	}
+
+	selected, err := svc.reqLogSvc.FindSelectedRequests(ctx, params.Selection)
+	if err != nil {
+		return UnauthCheck{}, fmt.Errorf("authz: failed to find request logs: %w", err)
+	}
+
+	stripHeaders := svc.stripHeaders(params.StripHeaders)
+	reqLogs := make([]reqlog.RequestLog, 0, len(selected))
+
+	for _, reqLog := range selected {
+		if reqLog.Response == nil || reqLog.URL == nil {
+			continue
+		}
+
+		if _, stripped := stripCredentials(reqLog, stripHeaders, params.StripQueryParams); len(stripped) == 0 {
+			continue
+		}
+
+		reqLogs = append(reqLogs, reqLog)
+	}
+
+	if len(reqLogs) == 0 {
+		return UnauthCheck{}, ErrNoRequests
+	}
+
+	sort.Slice(reqLogs, func(i, j int) bool {
+		return reqLogs[i].ID.Compare(reqLogs[j].ID) < 0
+	})
+
+	checkCtx, cancel := context.WithCancel(svc.ctx)
+
+	state := &unauthCheckState{
+		check: UnauthCheck{
+			ID:            svc.ids.New(time.Now()),
+			Status:        StatusRunning,
+			MinSimilarity: params.MinSimilarity,
+			Total:         len(reqLogs),
+			Results:       make([]UnauthResult, 0, len(reqLogs)),
+		},
+		cancel: cancel,
+	}
+
+	svc.unauthMu.Lock()
+	svc.unauthChecks[state.check.ID] = state
+	svc.unauthMu.Unlock()
+
+	go svc.runUnauthCheck(checkCtx, state, reqLogs, stripHeaders, params)
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindUnauthCheckByID(id ulid.ULID) (UnauthCheck, error) {
+	svc.unauthMu.RLock()
+	defer svc.unauthMu.RUnlock()
+
+	state, ok := svc.unauthChecks[id]
+	if !ok {
+		return UnauthCheck{}, ErrCheckNotFound
+	}
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindUnauthChecks() []UnauthCheck {
+	svc.unauthMu.RLock()
+	defer svc.unauthMu.RUnlock()
+
+	checks := make([]UnauthCheck, 0, len(svc.unauthChecks))
+	for _, state := range svc.unauthChecks {
+		checks = append(checks, state.snapshot())
+	}
+
+	// Most recent checks first.
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].ID.Compare(checks[j].ID) > 0
+	})
+
+	return checks
+}
+
+func (svc *service) CancelUnauthCheck(id ulid.ULID) error {
+	svc.unauthMu.RLock()
+	state, ok := svc.unauthChecks[id]
+	svc.unauthMu.RUnlock()
+
+	if !ok {
+		return ErrCheckNotFound
+	}
+
+	state.mu.Lock()
+	if state.check.Status == StatusRunning {
+		state.check.Status = StatusCancelled
+	}
+	state.mu.Unlock()
+
+	state.cancel()
+
+	return nil
+}
+
+func (svc *service) runUnauthCheck(
+	ctx context.Context,
+	state *unauthCheckState,
+	reqLogs []reqlog.RequestLog,
+	stripHeaders []string,
+	params UnauthCheckParams,
+) {
+	defer state.cancel()
+
+	// Checks that are aborted by `Close` are cancelled as well.
+	defer func() {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+
+		if state.check.Status != StatusRunning {
+			return
+		}
+
+		state.check.Status = StatusFinished
+		if ctx.Err() != nil {
+			state.check.Status = StatusCancelled
+		}
+	}()
+
+	for _, reqLog := range reqLogs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		result := svc.checkUnauth(ctx, reqLog, stripHeaders, params)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if result.Bypass {
+			if err := svc.storeUnauthFinding(reqLog, result); err != nil {
+				log.Printf("[ERROR] Could not store authentication bypass finding: %v", err)
+			}
+		}
+
+		state.mu.Lock()
+		state.check.Results = append(state.check.Results, result)
+		state.check.Completed++
+		state.mu.Unlock()
+	}
+}
+
+func (svc *service) checkUnauth(
+	ctx context.Context,
+	reqLog reqlog.RequestLog,
+	stripHeaders []string,
+	params UnauthCheckParams,
+) UnauthResult {
+	stripped, names := stripCredentials(reqLog, stripHeaders, params.StripQueryParams)
+
+	result := UnauthResult{
+		RequestLogID:       reqLog.ID,
+		Method:             reqLog.Method,
+		URL:                stripped.URL,
+		Stripped:           names,
+		OriginalStatusCode: reqLog.Response.StatusCode,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, stripped.Method, stripped.URL.String(), bytes.NewReader(stripped.Body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	req.Header = stripped.Header
+
+	if err := svc.rateLimiter.Wait(ctx, req.URL.Hostname()); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	res, err := svc.httpClient.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read response body: %v", err)
+		return result
+	}
+
+	result.StatusCode = res.StatusCode
+	result.Similarity = similarity(reqLog.Response.Body, body)
+	result.Bypass = result.OriginalStatusCode >= 200 && result.OriginalStatusCode <= 299 &&
+		result.StatusCode == result.OriginalStatusCode &&
+		result.Similarity >= params.MinSimilarity
+
+	return result
+}
+
+func (svc *service) storeUnauthFinding(reqLog reqlog.RequestLog, result UnauthResult) error {
+	f := finding.Finding{
+		ID:           svc.ids.New(time.Now()),
+		ProjectID:    reqLog.ProjectID,
+		RequestLogID: reqLog.ID,
+		Check:        finding.CheckAuthenticationBypass,
+		Severity:     finding.SeverityMedium,
+		Description: fmt.Sprintf(
+			"Without credentials (removed: %v), the response has the same status code (%v) and a body that is "+
+				"%.0f%% similar to the original response. Authentication may not be required.",
+			strings.Join(result.Stripped, ", "), result.StatusCode, result.Similarity*100,
+		),
+	}
+
+	if err := svc.findingRepo.StoreFinding(context.Background(), f); err != nil {
+		return err
+	}
+
+	svc.events.Publish(event.Event{
+		Type:      event.TypeFindingCreated,
+		ProjectID: f.ProjectID,
+		ID:        f.ID,
+		Data:      f,
+	})
+
+	return nil
+}
+
+// stripHeaders returns the canonical keys of headers that are removed from
+// requests of an unauthenticated check.
+func (svc *service) stripHeaders(extra []string) []string {
+	seen := make(map[string]bool)
+
+	var keys []string
+
+	add := func(key string) {
+		key = http.CanonicalHeaderKey(key)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range authHeaders {
+		add(key)
+	}
+
+	for key := range svc.Settings().Headers {
+		add(key)
+	}
+
+	for _, key := range extra {
+		add(key)
+	}
+
+	return keys
+}
+
+// stripCredentials returns a copy of reqLog without the given headers and
+// query parameters, and the names of those it had.
+func stripCredentials(reqLog reqlog.RequestLog, headers, queryParams []string) (reqlog.RequestLog, []string) {
+	var stripped []string
+
+	header := reqLog.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	for _, key := range headers {
+		if len(header.Values(key)) > 0 {
+			header.Del(key)
+			stripped = append(stripped, key)
+		}
+	}
+
+	u := *reqLog.URL
+
+	if len(queryParams) > 0 {
+		query := u.Query()
+
+		for _, param := range queryParams {
+			if _, ok := query[param]; ok {
+				query.Del(param)
+				stripped = append(stripped, param)
+			}
+		}
+
+		u.RawQuery = query.Encode()
+	}
+
+	reqLog.Header = header
+	reqLog.URL = &u
+
+	return reqLog, stripped
+}
+
+// similarity returns the Sørensen–Dice coefficient of the byte pairs of a and
+// b, from 0 (no pairs in common) to 1 (identical).
+func similarity(a, b []byte) float64 {
+	if len(a) > maxSimilarityBodySize {
+		a = a[:maxSimilarityBodySize]
+	}
+
+	if len(b) > maxSimilarityBodySize {
+		b = b[:maxSimilarityBodySize]
+	}
+
+	if bytes.Equal(a, b) {
+		return 1
+	}
+
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+
+	pairs := make(map[uint16]int, len(a))
+
+	for i := 0; i < len(a)-1; i++ {
+		pairs[uint16(a[i])<<8|uint16(a[i+1])]++
+	}
+
+	common := 0
+
+	for i := 0; i < len(b)-1; i++ {
+		pair := uint16(b[i])<<8 | uint16(b[i+1])
+		if pairs[pair] > 0 {
+			pairs[pair]--
+			common++
+		}
+	}
+
+	return float64(2*common) / float64(len(a)-1+len(b)-1)
+}
+
+func (state *unauthCheckState) snapshot() UnauthCheck {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	check := state.check
+	check.Results = make([]UnauthResult, len(state.check.Results))
+	copy(check.Results, state.check.Results)
+
+	return check
+}
@@ -0,0 +1,167 @@
+package browser
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+var ErrNotFound = errors.New("browser: could not find Chromium or Google Chrome executable")
+
+// Executable names that are looked up in `PATH`, in order of preference.
+var execNames = []string{
+	"chromium",
+	"chromium-browser",
+	"google-chrome",
+	"google-chrome-stable",
+	"chrome",
+}
+
+// Well known executable paths per OS, for when none are found in `PATH`.
+var execPaths = map[string][]string{
+	"darwin": {
+		"/Applications/Chromium.app/Contents/MacOS/Chromium",
+		"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+	},
+	"windows": {
+		`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+		`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+		`C:\Program Files\Chromium\Application\chrome.exe`,
+	},
+}
+
+// Launcher starts Chromium based browsers that use the proxy, with a throwaway
+// profile.
+type Launcher struct {
+	proxyAddr string
+	caCert    *x509.Certificate
+	execPath  string
+}
+
+type Config struct {
+	// Address of the proxy, in the form "host:port". If the host is empty, the
+	// loopback address is used.
+	ProxyAddr string
+	// CA certificate of the proxy, which the browser trusts.
+	CACert *x509.Certificate
+	// Path of the browser executable. When empty, well known names and paths
+	// are searched.
+	ExecPath string
+}
+
+func NewLauncher(cfg Config) *Launcher {
+	return &Launcher{
+		proxyAddr: cfg.ProxyAddr,
+		caCert:    cfg.CACert,
+		execPath:  cfg.ExecPath,
+	}
+}
+
+// Launch starts a browser that opens startURL. The browser's profile directory
+// is removed after the process exits. Use the returned command to wait for exit.
+func (l *Launcher) Launch(startURL string) (*exec.Cmd, error) {
+	execPath := l.execPath
+	if execPath == "" {
+		var err error
+
+		execPath, err = FindExecutable()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	profileDir, err := ioutil.TempDir("", "hetty-browser-")
+	if err != nil {
+		return nil, fmt.Errorf("browser: failed to create profile directory: %w", err)
+	}
+
+	//nolint:gosec
+	cmd := exec.Command(execPath, Args(l.proxyAddr, l.caCert, profileDir, startURL)...)
+
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(profileDir)
+		return nil, fmt.Errorf("browser: failed to start browser: %w", err)
+	}
+
+	go func() {
+		// The error is irrelevant here; we only care about the process exiting.
+		_ = cmd.Wait()
+
+		if err := os.RemoveAll(profileDir); err != nil {
+			log.Printf("[ERROR] Could not remove browser profile directory: %v", err)
+		}
+	}()
+
+	return cmd, nil
+}
+
+// Args returns the command line arguments for a Chromium based browser. Instead
+// of installing the CA certificate in a certificate store, its public key hash
+// is passed via `--ignore-certificate-errors-spki-list`, which makes the browser
+// accept certificate chains that contain it.
+func Args(proxyAddr string, caCert *x509.Certificate, profileDir, startURL string) []string {
+	args := []string{
+		"--proxy-server=" + normalizeAddr(proxyAddr),
+		// By default, Chromium bypasses the proxy for loopback addresses.
+		"--proxy-bypass-list=<-loopback>",
+		"--user-data-dir=" + profileDir,
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--disable-background-networking",
+	}
+
+	if caCert != nil {
+		args = append(args, "--ignore-certificate-errors-spki-list="+SPKIHash(caCert))
+	}
+
+	if startURL != "" {
+		args = append(args, startURL)
+	}
+
+	return args
+}
+
+// SPKIHash returns the base64 encoded SHA-256 hash of the certificate's
+// SubjectPublicKeyInfo.
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// FindExecutable returns the path of a Chromium based browser.
+func FindExecutable() (string, error) {
+	for _, name := range execNames {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+
+	for _, path := range execPaths[runtime.GOOS] {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+func normalizeAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,52 @@
+package browser_test
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dstotijn/hetty/pkg/browser"
+)
+
+func TestArgs(t *testing.T) {
+	t.Parallel()
+
+	caCert := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("foobar")}
+
+	tests := []struct {
+		name      string
+		proxyAddr string
+		exp       string
+	}{
+		{name: "empty host", proxyAddr: ":8080", exp: "--proxy-server=127.0.0.1:8080"},
+		{name: "unspecified host", proxyAddr: "0.0.0.0:8080", exp: "--proxy-server=127.0.0.1:8080"},
+		{name: "hostname", proxyAddr: "hetty.local:1337", exp: "--proxy-server=hetty.local:1337"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := browser.Args(tt.proxyAddr, caCert, "/tmp/profile", "http://hetty.proxy/")
+
+			exp := []string{
+				tt.exp,
+				"--proxy-bypass-list=<-loopback>",
+				"--user-data-dir=/tmp/profile",
+				"--no-first-run",
+				"--no-default-browser-check",
+				"--disable-background-networking",
+				// SHA-256 of "foobar", base64 encoded.
+				"--ignore-certificate-errors-spki-list=w6uP8Tcg6K2QR905Rms8iXTlksL6OD1KOWBxTK7wxPI=",
+				"http://hetty.proxy/",
+			}
+
+			if diff := cmp.Diff(exp, got); diff != "" {
+				t.Fatalf("args not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package config sets flags from environment variables and a config file, e.g.
+// for Docker deployments where passing flags is inconvenient. Flags set on the
+// command line take precedence over environment variables, which take
+// precedence over the config file. Flags that aren't set anywhere keep their
+// default value.
+package config
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+var (
+	ErrUnknownSetting = errcode.New(errcode.Invalid, "config: unknown setting")
+	ErrInvalidValue   = errcode.New(errcode.Invalid, "config: invalid value")
+	ErrInvalidSyntax  = errcode.New(errcode.Invalid, "config: invalid syntax")
+)
+
+type Options struct {
+	// Prefix of environment variables, e.g. `HETTY` for `HETTY_ADDR`.
+	EnvPrefix string
+	// Path of a config file. Optional.
+	File string
+	// Flags that aren't set from the environment or config file, e.g. the
+	// flag of the config file itself.
+	Skip []string
+	// Defaults to `os.LookupEnv`.
+	LookupEnv func(key string) (string, bool)
+}
+
+// EnvName returns the name of the environment variable of a flag, e.g.
+// `HETTY_UPSTREAM_DIAL_TIMEOUT` for `upstream-dial-timeout`.
+func EnvName(prefix, flagName string) string {
+	name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(flagName))
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "_" + name
+}
+
+// Load sets the flags of fs that weren't set on the command line, from
+// environment variables and the config file. Call it after `fs.Parse`.
+//
+// Config files have one setting per line, as a flag name and value separated
+// by whitespace or `=`, e.g. `upstream-dial-timeout 5s`. Empty lines and lines
+// starting with `#` are ignored. Repeatable flags can be set on multiple
+// lines, and boolean flags without a value are set to true.
+func Load(fs *flag.FlagSet, opts Options) error {
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
+
+	skip := make(map[string]bool, len(opts.Skip))
+	for _, name := range opts.Skip {
+		skip[name] = true
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		skip[f.Name] = true
+	})
+
+	var envErr error
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if skip[f.Name] || envErr != nil {
+			return
+		}
+
+		key := EnvName(opts.EnvPrefix, f.Name)
+
+		value, ok := opts.LookupEnv(key)
+		if !ok {
+			return
+		}
+
+		if err := fs.Set(f.Name, value); err != nil {
+			envErr = fmt.Errorf("%w for %v: %v", ErrInvalidValue, key, err)
+			return
+		}
+
+		skip[f.Name] = true
+	})
+
+	if envErr != nil {
+		return envErr
+	}
+
+	if opts.File == "" {
+		return nil
+	}
+
+	file, err := os.Open(opts.File)
+	if err != nil {
+		return fmt.Errorf("config: could not open config file: %w", err)
+	}
+	defer file.Close()
+
+	return loadFile(fs, file, opts.File, skip)
+}
+
+func loadFile(fs *flag.FlagSet, r io.Reader, filename string, skip map[string]bool) error {
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, value := splitSetting(text)
+		if name == "" {
+			return fmt.Errorf("%w in %v:%v: expected a setting name", ErrInvalidSyntax, filename, line)
+		}
+
+		f := fs.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("%w in %v:%v: %q", ErrUnknownSetting, filename, line, name)
+		}
+
+		if skip[name] {
+			continue
+		}
+
+		if value == "" && isBoolFlag(f) {
+			value = "true"
+		}
+
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%w in %v:%v for %v: %v", ErrInvalidValue, filename, line, name, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("config: could not read config file: %w", err)
+	}
+
+	return nil
+}
+
+// splitSetting splits a line of a config file in a flag name and value. Values
+// can be quoted, e.g. for values with leading or trailing whitespace.
+func splitSetting(text string) (name, value string) {
+	i := strings.IndexAny(text, " \t=")
+	if i == -1 {
+		return text, ""
+	}
+
+	name = text[:i]
+	value = strings.TrimSpace(text[i:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+
+	if len(value) >= 2 && (value[0] == '"' && value[len(value)-1] == '"' ||
+		value[0] == '\'' && value[len(value)-1] == '\'') {
+		value = value[1 : len(value)-1]
+	}
+
+	return name, value
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+
+	return ok && bf.IsBoolFlag()
+}
@@ -0,0 +1,168 @@
+package config_test
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/config"
+)
+
+type testFlags struct {
+	fs      *flag.FlagSet
+	addr    string
+	db      string
+	timeout time.Duration
+	verbose bool
+	blocks  stringsFlag
+}
+
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func newTestFlags() *testFlags {
+	f := &testFlags{fs: flag.NewFlagSet("test", flag.ContinueOnError)}
+	f.fs.StringVar(&f.addr, "addr", ":8080", "")
+	f.fs.StringVar(&f.db, "db", "~/.hetty/db", "")
+	f.fs.DurationVar(&f.timeout, "upstream-dial-timeout", 30*time.Second, "")
+	f.fs.BoolVar(&f.verbose, "verbose", false, "")
+	f.fs.Var(&f.blocks, "block", "")
+
+	return f
+}
+
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "hetty.conf")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	f := newTestFlags()
+	if err := f.fs.Parse([]string{"-addr", ":9090"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file := writeFile(t, `# Hetty settings.
+addr :7070
+db = "/data/db"
+upstream-dial-timeout 10s
+verbose
+block host=ads.example.com
+block path=/telemetry
+`)
+
+	err := config.Load(f.fs, config.Options{
+		EnvPrefix: "HETTY",
+		File:      file,
+		LookupEnv: lookupEnv(map[string]string{
+			"HETTY_ADDR":                  ":6060",
+			"HETTY_UPSTREAM_DIAL_TIMEOUT": "5s",
+		}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Command line flags take precedence over environment variables, which take
+	// precedence over the config file.
+	if f.addr != ":9090" {
+		t.Errorf("expected addr from command line, got: %v", f.addr)
+	}
+
+	if f.timeout != 5*time.Second {
+		t.Errorf("expected timeout from environment, got: %v", f.timeout)
+	}
+
+	if f.db != "/data/db" {
+		t.Errorf("expected db from config file, got: %v", f.db)
+	}
+
+	if !f.verbose {
+		t.Error("expected verbose to be set from config file")
+	}
+
+	if got := f.blocks.String(); got != "host=ads.example.com,path=/telemetry" {
+		t.Errorf("expected repeated block settings, got: %v", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		file   string
+		expErr error
+		expMsg string
+	}{
+		{
+			name:   "invalid environment variable",
+			env:    map[string]string{"HETTY_UPSTREAM_DIAL_TIMEOUT": "soon"},
+			expErr: config.ErrInvalidValue,
+			expMsg: "HETTY_UPSTREAM_DIAL_TIMEOUT",
+		},
+		{
+			name:   "unknown setting",
+			file:   "addr :8080\nlisten :8080\n",
+			expErr: config.ErrUnknownSetting,
+			expMsg: `hetty.conf:2: "listen"`,
+		},
+		{
+			name:   "invalid value in config file",
+			file:   "verbose maybe\n",
+			expErr: config.ErrInvalidValue,
+			expMsg: "hetty.conf:1 for verbose",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f := newTestFlags()
+
+			opts := config.Options{EnvPrefix: "HETTY", LookupEnv: lookupEnv(tt.env)}
+			if tt.file != "" {
+				opts.File = writeFile(t, tt.file)
+			}
+
+			err := config.Load(f.fs, opts)
+			if !errors.Is(err, tt.expErr) {
+				t.Fatalf("expected error %v, got: %v", tt.expErr, err)
+			}
+
+			if !strings.Contains(err.Error(), tt.expMsg) {
+				t.Errorf("expected error to contain %q, got: %v", tt.expMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package connlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+var (
+	ErrProjectIDMustBeSet = errors.New("connlog: project ID must be set")
+	ErrReadOnly           = errors.New("connlog: project is opened read-only")
+)
+
+// Service logs CONNECT tunnels, so that traffic that isn't parsed as HTTP still
+// leaves a trace.
+type Service interface {
+	FindConnectionLogs(ctx context.Context, filter FindConnectionLogsFilter) ([]ConnectionLog, error)
+	ClearConnectionLogs(ctx context.Context, projectID ulid.ULID) error
+	ConnectionHandler(conn proxy.Connection)
+	SetActiveProjectID(id ulid.ULID)
+	ActiveProjectID() ulid.ULID
+	SetReadOnly(readOnly bool)
+}
+
+type service struct {
+	// mu guards the active project settings, which are changed at runtime.
+	mu              sync.RWMutex
+	activeProjectID ulid.ULID
+	readOnly        bool
+
+	repo Repository
+	ids  idgen.Generator
+}
+
+// ConnectionLog is a logged CONNECT tunnel.
+type ConnectionLog struct {
+	ID         ulid.ULID
+	ProjectID  ulid.ULID
+	ClientAddr string
+	Host       string
+	ServerName string
+	Mode       proxy.ConnectionMode
+	BytesUp    int64
+	BytesDown  int64
+	StartedAt  time.Time
+	Duration   time.Duration
+	// Raw bytes of tunnels that weren't handled as HTTP.
+	Capture *proxy.Capture
+	Error   string
+}
+
+type FindConnectionLogsFilter struct {
+	ProjectID ulid.ULID
+}
+
+type Config struct {
+	Repository Repository
+	// Generates the IDs of connection logs. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	return &service{
+		ids:  cfg.IDGenerator,
+		repo: cfg.Repository,
+	}
+}
+
+func (svc *service) FindConnectionLogs(ctx context.Context, filter FindConnectionLogsFilter) ([]ConnectionLog, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		filter.ProjectID = svc.ActiveProjectID()
+	}
+
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, ErrProjectIDMustBeSet
+	}
+
+	connLogs, err := svc.repo.FindConnectionLogs(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("connlog: failed to find connection logs: %w", err)
+	}
+
+	return connLogs, nil
+}
+
+func (svc *service) ClearConnectionLogs(ctx context.Context, projectID ulid.ULID) error {
+	if svc.isReadOnly() {
+		return ErrReadOnly
+	}
+
+	return svc.repo.ClearConnectionLogs(ctx, projectID)
+}
+
+// ConnectionHandler stores a closed CONNECT tunnel for the active project. It's
+// meant to be registered with `proxy.Proxy.OnConnectionClose`.
+func (svc *service) ConnectionHandler(conn proxy.Connection) {
+	projectID := svc.ActiveProjectID()
+	if projectID.Compare(ulid.ULID{}) == 0 || svc.isReadOnly() {
+		return
+	}
+
+	connLog := ConnectionLog{
+		// The ID timestamp is the start of the tunnel, so that logs are ordered
+		// by the time connections were opened.
+		ID:         svc.ids.New(conn.StartedAt),
+		ProjectID:  projectID,
+		ClientAddr: conn.ClientAddr,
+		Host:       conn.Host,
+		ServerName: conn.ServerName,
+		Mode:       conn.Mode,
+		BytesUp:    conn.BytesUp,
+		BytesDown:  conn.BytesDown,
+		StartedAt:  conn.StartedAt,
+		Duration:   conn.Duration,
+		Capture:    conn.Capture,
+	}
+
+	if conn.Err != nil {
+		connLog.Error = conn.Err.Error()
+	}
+
+	if err := svc.repo.StoreConnectionLog(context.Background(), connLog); err != nil {
+		log.Printf("[ERROR] Could not store connection log: %v", err)
+	}
+}
+
+func (svc *service) SetActiveProjectID(id ulid.ULID) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.activeProjectID = id
+}
+
+func (svc *service) ActiveProjectID() ulid.ULID {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.activeProjectID
+}
+
+// SetReadOnly sets whether the active project is opened read-only. No
+// connections are logged for it, and its logs can't be cleared.
+func (svc *service) SetReadOnly(readOnly bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.readOnly = readOnly
+}
+
+func (svc *service) isReadOnly() bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.readOnly
+}
@@ -0,0 +1,172 @@
+package connlog_test
+
+//go:generate go run github.com/matryer/moq -out repo_mock_test.go -pkg connlog_test . Repository:RepoMock
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/connlog"
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+//nolint:gosec
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func TestConnectionHandler(t *testing.T) {
+	t.Parallel()
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	startedAt := time.Now().Add(-time.Minute)
+
+	conn := proxy.Connection{
+		ClientAddr: "127.0.0.1:50000",
+		Host:       "example.com:443",
+		ServerName: "example.com",
+		Mode:       proxy.ConnectionModePassthrough,
+		BytesUp:    100,
+		BytesDown:  200,
+		StartedAt:  startedAt,
+		Duration:   time.Second,
+		Capture:    &proxy.Capture{},
+		Err:        errors.New("connection reset by peer"),
+	}
+
+	tests := []struct {
+		name            string
+		activeProjectID ulid.ULID
+		readOnly        bool
+		expStored       bool
+	}{
+		{
+			name:            "active project",
+			activeProjectID: projectID,
+			expStored:       true,
+		},
+		{
+			name:            "read-only project",
+			activeProjectID: projectID,
+			readOnly:        true,
+		},
+		{
+			name: "no active project",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repoMock := &RepoMock{
+				StoreConnectionLogFunc: func(_ context.Context, _ connlog.ConnectionLog) error {
+					return nil
+				},
+			}
+
+			svc := connlog.NewService(connlog.Config{Repository: repoMock})
+			svc.SetActiveProjectID(tt.activeProjectID)
+			svc.SetReadOnly(tt.readOnly)
+
+			svc.ConnectionHandler(conn)
+
+			calls := repoMock.StoreConnectionLogCalls()
+
+			if !tt.expStored {
+				if len(calls) != 0 {
+					t.Fatalf("expected no connection log to be stored, got: %+v", calls)
+				}
+
+				return
+			}
+
+			if len(calls) != 1 {
+				t.Fatalf("expected 1 connection log to be stored, got: %v", len(calls))
+			}
+
+			got := calls[0].ConnLog
+
+			// The ID timestamp is the start of the tunnel.
+			if got.ID.Time() != ulid.Timestamp(startedAt) {
+				t.Errorf("expected ID with timestamp of tunnel start, got: %v", got.ID.Time())
+			}
+
+			exp := connlog.ConnectionLog{
+				ID:         got.ID,
+				ProjectID:  projectID,
+				ClientAddr: "127.0.0.1:50000",
+				Host:       "example.com:443",
+				ServerName: "example.com",
+				Mode:       proxy.ConnectionModePassthrough,
+				BytesUp:    100,
+				BytesDown:  200,
+				StartedAt:  startedAt,
+				Duration:   time.Second,
+				Capture:    conn.Capture,
+				Error:      "connection reset by peer",
+			}
+
+			if diff := cmp.Diff(exp, got); diff != "" {
+				t.Fatalf("connection log not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestFindConnectionLogs(t *testing.T) {
+	t.Parallel()
+
+	activeProjectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	otherProjectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	repoMock := &RepoMock{
+		FindConnectionLogsFunc: func(_ context.Context, filter connlog.FindConnectionLogsFilter) ([]connlog.ConnectionLog, error) {
+			return []connlog.ConnectionLog{{ProjectID: filter.ProjectID}}, nil
+		},
+	}
+
+	svc := connlog.NewService(connlog.Config{Repository: repoMock})
+
+	// Without a project ID and an active project, no logs are found.
+	_, err := svc.FindConnectionLogs(context.Background(), connlog.FindConnectionLogsFilter{})
+	if !errors.Is(err, connlog.ErrProjectIDMustBeSet) {
+		t.Fatalf("expected `connlog.ErrProjectIDMustBeSet`, got: %v", err)
+	}
+
+	if calls := repoMock.FindConnectionLogsCalls(); len(calls) != 0 {
+		t.Fatalf("expected repository not to be called, got: %+v", calls)
+	}
+
+	svc.SetActiveProjectID(activeProjectID)
+
+	// The project ID defaults to the active project.
+	if _, err := svc.FindConnectionLogs(context.Background(), connlog.FindConnectionLogsFilter{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An explicit project ID is used as is.
+	filter := connlog.FindConnectionLogsFilter{ProjectID: otherProjectID}
+	if _, err := svc.FindConnectionLogs(context.Background(), filter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	calls := repoMock.FindConnectionLogsCalls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 repository calls, got: %v", len(calls))
+	}
+
+	if got := calls[0].Filter.ProjectID; got != activeProjectID {
+		t.Errorf("expected filter with active project ID %v, got: %v", activeProjectID, got)
+	}
+
+	if got := calls[1].Filter.ProjectID; got != otherProjectID {
+		t.Errorf("expected filter with project ID %v, got: %v", otherProjectID, got)
+	}
+}
@@ -0,0 +1,13 @@
+package connlog
+
+import (
+	"context"
+
+	"github.com/oklog/ulid"
+)
+
+type Repository interface {
+	StoreConnectionLog(ctx context.Context, connLog ConnectionLog) error
+	FindConnectionLogs(ctx context.Context, filter FindConnectionLogsFilter) ([]ConnectionLog, error)
+	ClearConnectionLogs(ctx context.Context, projectID ulid.ULID) error
+}
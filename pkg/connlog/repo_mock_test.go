@@ -0,0 +1,183 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package connlog_test
+
+import (
+	"context"
+	"github.com/dstotijn/hetty/pkg/connlog"
+	"github.com/oklog/ulid"
+	"sync"
+)
+
+// Ensure, that RepoMock does implement connlog.Repository.
+// If this is not the case, regenerate this file with moq.
+var _ connlog.Repository = &RepoMock{}
+
+// RepoMock is a mock implementation of connlog.Repository.
+//
+//	func TestSomethingThatUsesRepository(t *testing.T) {
+//
+//		// make and configure a mocked connlog.Repository
+//		mockedRepository := &RepoMock{
+//			ClearConnectionLogsFunc: func(ctx context.Context, projectID ulid.ULID) error {
+//				panic("mock out the ClearConnectionLogs method")
+//			},
+//			FindConnectionLogsFunc: func(ctx context.Context, filter connlog.FindConnectionLogsFilter) ([]connlog.ConnectionLog, error) {
+//				panic("mock out the FindConnectionLogs method")
+//			},
+//			StoreConnectionLogFunc: func(ctx context.Context, connLog connlog.ConnectionLog) error {
+//				panic("mock out the StoreConnectionLog method")
+//			},
+//		}
+//
+//		// use mockedRepository in code that requires connlog.Repository
+//		// and then make assertions.
+//
+//	}
+type RepoMock struct {
+	// ClearConnectionLogsFunc mocks the ClearConnectionLogs method.
+	ClearConnectionLogsFunc func(ctx context.Context, projectID ulid.ULID) error
+
+	// FindConnectionLogsFunc mocks the FindConnectionLogs method.
+	FindConnectionLogsFunc func(ctx context.Context, filter connlog.FindConnectionLogsFilter) ([]connlog.ConnectionLog, error)
+
+	// StoreConnectionLogFunc mocks the StoreConnectionLog method.
+	StoreConnectionLogFunc func(ctx context.Context, connLog connlog.ConnectionLog) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// ClearConnectionLogs holds details about calls to the ClearConnectionLogs method.
+		ClearConnectionLogs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+		}
+		// FindConnectionLogs holds details about calls to the FindConnectionLogs method.
+		FindConnectionLogs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter connlog.FindConnectionLogsFilter
+		}
+		// StoreConnectionLog holds details about calls to the StoreConnectionLog method.
+		StoreConnectionLog []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ConnLog is the connLog argument value.
+			ConnLog connlog.ConnectionLog
+		}
+	}
+	lockClearConnectionLogs sync.RWMutex
+	lockFindConnectionLogs  sync.RWMutex
+	lockStoreConnectionLog  sync.RWMutex
+}
+
+// ClearConnectionLogs calls ClearConnectionLogsFunc.
+func (mock *RepoMock) ClearConnectionLogs(ctx context.Context, projectID ulid.ULID) error {
+	if mock.ClearConnectionLogsFunc == nil {
+		panic("RepoMock.ClearConnectionLogsFunc: method is nil but Repository.ClearConnectionLogs was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+	}
+	mock.lockClearConnectionLogs.Lock()
+	mock.calls.ClearConnectionLogs = append(mock.calls.ClearConnectionLogs, callInfo)
+	mock.lockClearConnectionLogs.Unlock()
+	return mock.ClearConnectionLogsFunc(ctx, projectID)
+}
+
+// ClearConnectionLogsCalls gets all the calls that were made to ClearConnectionLogs.
+// Check the length with:
+//
+//	len(mockedRepository.ClearConnectionLogsCalls())
+func (mock *RepoMock) ClearConnectionLogsCalls() []struct {
+	Ctx       context.Context
+	ProjectID ulid.ULID
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}
+	mock.lockClearConnectionLogs.RLock()
+	calls = mock.calls.ClearConnectionLogs
+	mock.lockClearConnectionLogs.RUnlock()
+	return calls
+}
+
+// FindConnectionLogs calls FindConnectionLogsFunc.
+func (mock *RepoMock) FindConnectionLogs(ctx context.Context, filter connlog.FindConnectionLogsFilter) ([]connlog.ConnectionLog, error) {
+	if mock.FindConnectionLogsFunc == nil {
+		panic("RepoMock.FindConnectionLogsFunc: method is nil but Repository.FindConnectionLogs was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter connlog.FindConnectionLogsFilter
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockFindConnectionLogs.Lock()
+	mock.calls.FindConnectionLogs = append(mock.calls.FindConnectionLogs, callInfo)
+	mock.lockFindConnectionLogs.Unlock()
+	return mock.FindConnectionLogsFunc(ctx, filter)
+}
+
+// FindConnectionLogsCalls gets all the calls that were made to FindConnectionLogs.
+// Check the length with:
+//
+//	len(mockedRepository.FindConnectionLogsCalls())
+func (mock *RepoMock) FindConnectionLogsCalls() []struct {
+	Ctx    context.Context
+	Filter connlog.FindConnectionLogsFilter
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter connlog.FindConnectionLogsFilter
+	}
+	mock.lockFindConnectionLogs.RLock()
+	calls = mock.calls.FindConnectionLogs
+	mock.lockFindConnectionLogs.RUnlock()
+	return calls
+}
+
+// StoreConnectionLog calls StoreConnectionLogFunc.
+func (mock *RepoMock) StoreConnectionLog(ctx context.Context, connLog connlog.ConnectionLog) error {
+	if mock.StoreConnectionLogFunc == nil {
+		panic("RepoMock.StoreConnectionLogFunc: method is nil but Repository.StoreConnectionLog was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		ConnLog connlog.ConnectionLog
+	}{
+		Ctx:     ctx,
+		ConnLog: connLog,
+	}
+	mock.lockStoreConnectionLog.Lock()
+	mock.calls.StoreConnectionLog = append(mock.calls.StoreConnectionLog, callInfo)
+	mock.lockStoreConnectionLog.Unlock()
+	return mock.StoreConnectionLogFunc(ctx, connLog)
+}
+
+// StoreConnectionLogCalls gets all the calls that were made to StoreConnectionLog.
+// Check the length with:
+//
+//	len(mockedRepository.StoreConnectionLogCalls())
+func (mock *RepoMock) StoreConnectionLogCalls() []struct {
+	Ctx     context.Context
+	ConnLog connlog.ConnectionLog
+} {
+	var calls []struct {
+		Ctx     context.Context
+		ConnLog connlog.ConnectionLog
+	}
+	mock.lockStoreConnectionLog.RLock()
+	calls = mock.calls.StoreConnectionLog
+	mock.lockStoreConnectionLog.RUnlock()
+	return calls
+}
@@ -0,0 +1,459 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+const (
+	defaultMaxDepth          = 3
+	defaultMaxRequests       = 500
+	defaultRequestsPerSecond = 10
+	maxRequestsPerSecond     = 1000
+	defaultConcurrency       = 5
+
+	// Maximum size of a response body that is parsed for links.
+	maxBodySize = 5 << 20
+)
+
+// JobType is the type of the jobs that run crawls, see `job.Params`.
+const JobType = "crawl"
+
+var (
+	ErrCrawlNotFound = errcode.New(errcode.NotFound, "crawler: crawl not found")
+	ErrOutOfScope    = errcode.New(errcode.Invalid, "crawler: start URL is out of scope")
+	ErrInvalidRate   = errcode.New(errcode.Invalid, "crawler: requests per second must be at most 1000")
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusFinished  Status = "finished"
+	StatusCancelled Status = "cancelled"
+)
+
+// Service runs crawls, which follow links and forms in responses of in-scope
+// hosts.
+type Service interface {
+	StartCrawl(ctx context.Context, params CrawlParams) (Crawl, error)
+	FindCrawlByID(id ulid.ULID) (Crawl, error)
+	FindCrawls() []Crawl
+	CancelCrawl(id ulid.ULID) error
+}
+
+type service struct {
+	scope      *scope.Scope
+	ids        idgen.Generator
+	jobs       job.Service
+	httpClient *http.Client
+	crawls     map[ulid.ULID]*crawlState
+	mu         sync.RWMutex
+}
+
+type Config struct {
+	Scope *scope.Scope
+	// Transport used for outgoing requests. Typically the proxy itself, so
+	// that requests are logged like proxied traffic.
+	Transport http.RoundTripper
+	// Generates the IDs of crawls. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+	// Runs crawls as jobs, so that they're queued, report progress and can be
+	// paused. Optional; without it, crawls start right away.
+	Jobs job.Service
+}
+
+type CrawlParams struct {
+	StartURL *url.URL
+	// Maximum number of links followed from the start URL. Defaults to 3.
+	MaxDepth int
+	// Maximum number of requests for the crawl. Defaults to 500.
+	MaxRequests int
+	// Maximum number of requests per second. Defaults to 10, and can be at
+	// most 1000.
+	RequestsPerSecond int
+	// Maximum number of requests in flight. Defaults to 5.
+	Concurrency int
+	// Submit forms, with placeholder values for empty fields.
+	SubmitForms bool
+}
+
+type Crawl struct {
+	ID       ulid.ULID
+	StartURL *url.URL
+	Status   Status
+	Results  []Result
+}
+
+// Result is a request made by the crawler.
+type Result struct {
+	Method       string
+	URL          *url.URL
+	Depth        int
+	StatusCode   int
+	RequestLogID ulid.ULID
+}
+
+type crawlState struct {
+	crawl  Crawl
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &service{
+		ids:   cfg.IDGenerator,
+		scope: cfg.Scope,
+		jobs:  cfg.Jobs,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+			// Redirects are handled as links, so they are subject to scope
+			// and depth limits.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		crawls: make(map[ulid.ULID]*crawlState),
+	}
+}
+
+// StartCrawl starts a crawl in the background. The start URL must match the
+// project scope; links that don't match the scope are not followed.
+func (svc *service) StartCrawl(ctx context.Context, params CrawlParams) (Crawl, error) {
+	if params.StartURL == nil || params.StartURL.Host == "" {
+		return Crawl{}, errcode.New(errcode.Invalid, "crawler: start URL must be absolute")
+	}
+
+	startURL := *params.StartURL
+	startURL.Fragment = ""
+
+	if !svc.inScope(&startURL) {
+		return Crawl{}, ErrOutOfScope
+	}
+
+	if params.MaxDepth <= 0 {
+		params.MaxDepth = defaultMaxDepth
+	}
+
+	if params.MaxRequests <= 0 {
+		params.MaxRequests = defaultMaxRequests
+	}
+
+	if params.RequestsPerSecond <= 0 {
+		params.RequestsPerSecond = defaultRequestsPerSecond
+	}
+
+	if params.RequestsPerSecond > maxRequestsPerSecond {
+		return Crawl{}, ErrInvalidRate
+	}
+
+	if params.Concurrency <= 0 {
+		params.Concurrency = defaultConcurrency
+	}
+
+	crawlCtx, cancel := context.WithCancel(context.Background())
+
+	state := &crawlState{
+		crawl: Crawl{
+			ID:       svc.ids.New(time.Now()),
+			StartURL: &startURL,
+			Status:   StatusRunning,
+			Results:  make([]Result, 0),
+		},
+		cancel: cancel,
+	}
+
+	svc.mu.Lock()
+	svc.crawls[state.crawl.ID] = state
+	svc.mu.Unlock()
+
+	if svc.jobs == nil {
+		go svc.run(crawlCtx, state, params, nil)
+		return state.snapshot(), nil
+	}
+
+	jobParams := job.Params{
+		ID:          state.crawl.ID,
+		Type:        JobType,
+		Description: startURL.String(),
+		Total:       params.MaxRequests,
+	}
+
+	svc.jobs.StartJob(crawlCtx, jobParams, func(ctx context.Context, progress *job.Progress) error {
+		return svc.run(ctx, state, params, progress)
+	})
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindCrawlByID(id ulid.ULID) (Crawl, error) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	state, ok := svc.crawls[id]
+	if !ok {
+		return Crawl{}, ErrCrawlNotFound
+	}
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindCrawls() []Crawl {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	crawls := make([]Crawl, 0, len(svc.crawls))
+	for _, state := range svc.crawls {
+		crawls = append(crawls, state.snapshot())
+	}
+
+	// Most recent crawls first.
+	sort.Slice(crawls, func(i, j int) bool {
+		return crawls[i].ID.Compare(crawls[j].ID) > 0
+	})
+
+	return crawls
+}
+
+func (svc *service) CancelCrawl(id ulid.ULID) error {
+	svc.mu.RLock()
+	state, ok := svc.crawls[id]
+	svc.mu.RUnlock()
+
+	if !ok {
+		return ErrCrawlNotFound
+	}
+
+	state.mu.Lock()
+	if state.crawl.Status == StatusRunning {
+		state.crawl.Status = StatusCancelled
+	}
+	state.mu.Unlock()
+
+	state.cancel()
+
+	return nil
+}
+
+// run crawls breadth first: all targets of a depth are requested before the
+// targets found in their responses. Progress is reported as requests out of the
+// maximum number of requests.
+func (svc *service) run(ctx context.Context, state *crawlState, params CrawlParams, progress *job.Progress) error {
+	defer state.cancel()
+
+	ticker := time.NewTicker(time.Second / time.Duration(params.RequestsPerSecond))
+	defer ticker.Stop()
+
+	start := target{method: http.MethodGet, url: state.crawl.StartURL}
+	seen := map[string]struct{}{start.key(): {}}
+	queue := []target{start}
+	requests := 0
+
+	for depth := 0; depth <= params.MaxDepth && len(queue) > 0 && ctx.Err() == nil; depth++ {
+		if remaining := params.MaxRequests - requests; len(queue) > remaining {
+			queue = queue[:remaining]
+		}
+
+		requests += len(queue)
+		found := svc.crawlLevel(ctx, ticker, state, queue, depth, params, progress)
+
+		queue = nil
+
+		if depth == params.MaxDepth {
+			break
+		}
+
+		for _, t := range found {
+			if _, ok := seen[t.key()]; ok || !svc.inScope(t.url) {
+				continue
+			}
+
+			seen[t.key()] = struct{}{}
+			queue = append(queue, t)
+		}
+	}
+
+	state.mu.Lock()
+	if state.crawl.Status == StatusRunning {
+		state.crawl.Status = StatusFinished
+		if ctx.Err() != nil {
+			state.crawl.Status = StatusCancelled
+		}
+	}
+	state.mu.Unlock()
+
+	return ctx.Err()
+}
+
+func (svc *service) crawlLevel(
+	ctx context.Context,
+	ticker *time.Ticker,
+	state *crawlState,
+	targets []target,
+	depth int,
+	params CrawlParams,
+	progress *job.Progress,
+) []target {
+	targetCh := make(chan target)
+	wg := sync.WaitGroup{}
+	found := make([]target, 0)
+	foundMu := sync.Mutex{}
+
+	for i := 0; i < params.Concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for t := range targetCh {
+				result, links, ok := svc.fetch(ctx, t, params.SubmitForms)
+				progress.Add(1)
+
+				if !ok {
+					continue
+				}
+
+				result.Depth = depth
+				state.addResult(result)
+
+				foundMu.Lock()
+				found = append(found, links...)
+				foundMu.Unlock()
+			}
+		}()
+	}
+
+loop:
+	for _, t := range targets {
+		if err := progress.Wait(ctx); err != nil {
+			break loop
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case targetCh <- t:
+		}
+	}
+
+	close(targetCh)
+	wg.Wait()
+
+	// Sort found targets, so that crawl order doesn't depend on response timing.
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].key() < found[j].key()
+	})
+
+	return found
+}
+
+// fetch requests t, and returns the result and targets found in the response.
+func (svc *service) fetch(ctx context.Context, t target, submitForms bool) (Result, []target, bool) {
+	var body io.Reader
+	if t.body != "" {
+		body = strings.NewReader(t.body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, t.method, t.url.String(), body)
+	if err != nil {
+		return Result{}, nil, false
+	}
+
+	if t.body != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	res, err := svc.httpClient.Do(req)
+	if err != nil {
+		return Result{}, nil, false
+	}
+	defer res.Body.Close()
+
+	result := Result{
+		Method:     t.method,
+		URL:        t.url,
+		StatusCode: res.StatusCode,
+	}
+
+	if res.Request != nil {
+		if reqLogID, ok := res.Request.Context().Value(proxy.ReqLogIDKey).(ulid.ULID); ok {
+			result.RequestLogID = reqLogID
+		}
+	}
+
+	var targets []target
+
+	if location := res.Header.Get("Location"); location != "" {
+		if u := resolveLink(t.url, location); u != nil {
+			targets = append(targets, target{method: http.MethodGet, url: u})
+		}
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType == "text/html" {
+		targets = append(targets, extractTargets(io.LimitReader(res.Body, maxBodySize), t.url, submitForms)...)
+	}
+
+	// Drain the body, so that the full response is logged and the connection
+	// can be reused.
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	return result, targets, true
+}
+
+func (svc *service) inScope(u *url.URL) bool {
+	if svc.scope == nil {
+		return false
+	}
+
+	return svc.scope.Match(&http.Request{URL: u, Header: http.Header{}}, nil)
+}
+
+func (state *crawlState) addResult(result Result) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.crawl.Results = append(state.crawl.Results, result)
+}
+
+func (state *crawlState) snapshot() Crawl {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	crawl := state.crawl
+	crawl.Results = make([]Result, len(state.crawl.Results))
+	copy(crawl.Results, state.crawl.Results)
+
+	return crawl
+}
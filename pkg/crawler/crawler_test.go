@@ -0,0 +1,152 @@
+package crawler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dstotijn/hetty/pkg/crawler"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+const indexHTML = `<!DOCTYPE html>
+<html>
+<head><link rel="stylesheet" href="/style.css"></head>
+<body>
+	<a href="/about#team">About</a>
+	<a href="mailto:foo@example.com">Mail</a>
+	<a href="https://out-of-scope.example.com/">External</a>
+	<form action="/search">
+		<input name="q">
+		<input type="submit" value="Search">
+	</form>
+	<form action="/login" method="post">
+		<input name="username" value="admin">
+	</form>
+</body>
+</html>`
+
+const aboutHTML = `<a href="/">Home</a><a href="/team">Team</a>`
+
+func TestStartCrawl(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(indexHTML))
+		case "/about":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(aboutHTML))
+		case "/login":
+			http.Redirect(w, r, "/dashboard", http.StatusFound)
+		case "/style.css", "/search", "/dashboard", "/team":
+			w.Write([]byte("ok"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	startURL, err := url.Parse(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &scope.Scope{}
+	s.SetRules([]scope.Rule{{URL: regexp.MustCompile("^" + regexp.QuoteMeta(ts.URL))}})
+
+	t.Run("out of scope", func(t *testing.T) {
+		svc := crawler.NewService(crawler.Config{Scope: &scope.Scope{}})
+
+		_, err := svc.StartCrawl(context.Background(), crawler.CrawlParams{StartURL: startURL})
+		if !errors.Is(err, crawler.ErrOutOfScope) {
+			t.Fatalf("expected `crawler.ErrOutOfScope`, got: %v", err)
+		}
+	})
+
+	t.Run("too many requests per second", func(t *testing.T) {
+		svc := crawler.NewService(crawler.Config{Scope: s})
+
+		_, err := svc.StartCrawl(context.Background(), crawler.CrawlParams{StartURL: startURL, RequestsPerSecond: 2e9})
+		if !errors.Is(err, crawler.ErrInvalidRate) {
+			t.Fatalf("expected `crawler.ErrInvalidRate`, got: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		params crawler.CrawlParams
+		exp    []string
+	}{
+		{
+			name:   "depth limit",
+			params: crawler.CrawlParams{MaxDepth: 1},
+			exp:    []string{"GET /", "GET /about", "GET /style.css"},
+		},
+		{
+			name:   "request limit",
+			params: crawler.CrawlParams{MaxRequests: 2},
+			exp:    []string{"GET /", "GET /about"},
+		},
+		{
+			name:   "submit forms",
+			params: crawler.CrawlParams{SubmitForms: true},
+			exp: []string{
+				"GET /", "GET /about", "GET /dashboard", "GET /search?q=hetty", "GET /style.css", "GET /team",
+				"POST /login",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			svc := crawler.NewService(crawler.Config{Scope: s})
+
+			tt.params.StartURL = startURL
+			tt.params.RequestsPerSecond = 100
+
+			crawl, err := svc.StartCrawl(context.Background(), tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+
+			for crawl.Status == crawler.StatusRunning {
+				if time.Now().After(deadline) {
+					t.Fatal("crawl did not finish in time")
+				}
+
+				time.Sleep(10 * time.Millisecond)
+
+				crawl, err = svc.FindCrawlByID(crawl.ID)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			got := make([]string, len(crawl.Results))
+			for i, result := range crawl.Results {
+				got[i] = result.Method + " " + result.URL.RequestURI()
+			}
+
+			sort.Strings(got)
+
+			if diff := cmp.Diff(tt.exp, got); diff != "" {
+				t.Fatalf("crawled requests not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package crawler
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// target is a request the crawler can make.
+type target struct {
+	method string
+	url    *url.URL
+	// Form encoded body, for POST forms.
+	body string
+}
+
+func (t target) key() string {
+	return t.method + " " + t.url.String() + " " + t.body
+}
+
+// linkAttrs are the attributes per element that contain a URL to crawl.
+var linkAttrs = map[string]string{
+	"a":      "href",
+	"area":   "href",
+	"link":   "href",
+	"frame":  "src",
+	"iframe": "src",
+	"script": "src",
+}
+
+// extractTargets parses an HTML document, and returns its links and (when
+// submitForms is set) forms as targets, resolved against baseURL.
+func extractTargets(r io.Reader, baseURL *url.URL, submitForms bool) []target {
+	var (
+		targets []target
+		form    *formState
+	)
+
+	tokenizer := html.NewTokenizer(r)
+
+	for {
+		tt := tokenizer.Next()
+
+		switch tt {
+		case html.ErrorToken:
+			return targets
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			attrs := attrMap(token.Attr)
+
+			switch token.Data {
+			case "base":
+				if href, ok := attrs["href"]; ok {
+					if u, err := baseURL.Parse(href); err == nil {
+						baseURL = u
+					}
+				}
+			case "form":
+				if !submitForms {
+					continue
+				}
+
+				form = &formState{
+					method: strings.ToUpper(attrs["method"]),
+					action: attrs["action"],
+					values: url.Values{},
+				}
+			case "input", "select", "textarea", "button":
+				if form == nil || attrs["name"] == "" {
+					continue
+				}
+
+				if t := attrs["type"]; token.Data == "input" && (t == "submit" || t == "image" || t == "reset") {
+					continue
+				}
+
+				value, ok := attrs["value"]
+				if !ok || value == "" {
+					value = "hetty"
+				}
+
+				form.values.Add(attrs["name"], value)
+			}
+
+			if attr, ok := linkAttrs[token.Data]; ok {
+				if u := resolveLink(baseURL, attrs[attr]); u != nil {
+					targets = append(targets, target{method: http.MethodGet, url: u})
+				}
+			}
+		case html.EndTagToken:
+			token := tokenizer.Token()
+
+			if token.Data == "form" && form != nil {
+				if t, ok := form.target(baseURL); ok {
+					targets = append(targets, t)
+				}
+
+				form = nil
+			}
+		}
+	}
+}
+
+type formState struct {
+	method string
+	action string
+	values url.Values
+}
+
+func (f *formState) target(baseURL *url.URL) (target, bool) {
+	u := baseURL
+	if f.action != "" {
+		u = resolveLink(baseURL, f.action)
+		if u == nil {
+			return target{}, false
+		}
+	}
+
+	switch f.method {
+	case "", http.MethodGet:
+		withQuery := *u
+		withQuery.RawQuery = f.values.Encode()
+
+		return target{method: http.MethodGet, url: &withQuery}, true
+	case http.MethodPost:
+		return target{method: http.MethodPost, url: u, body: f.values.Encode()}, true
+	default:
+		return target{}, false
+	}
+}
+
+// resolveLink resolves a link against baseURL. Fragments are removed, and only
+// links with a HTTP(S) scheme are returned.
+func resolveLink(baseURL *url.URL, link string) *url.URL {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return nil
+	}
+
+	u, err := baseURL.Parse(link)
+	if err != nil {
+		return nil
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+
+	u.Fragment = ""
+
+	return u
+}
+
+func attrMap(attrs []html.Attribute) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		m[strings.ToLower(attr.Key)] = attr.Val
+	}
+
+	return m
+}
@@ -0,0 +1,50 @@
+package badger
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrRestoreNotEmpty = errors.New("badger: can only restore a backup to an empty database")
+
+// maxPendingRestoreWrites is the number of pending writes while restoring a
+// backup, as recommended by Badger.
+const maxPendingRestoreWrites = 256
+
+// Backup writes a snapshot of the database to w, e.g. while request logs are
+// being stored. Buffered writes are written first, so they're included.
+func (db *Database) Backup(w io.Writer) error {
+	if err := db.flushWrites(); err != nil {
+		return err
+	}
+
+	if _, err := db.badger.Backup(w, 0); err != nil {
+		return storageError("badger: failed to write backup: %w", err)
+	}
+
+	return nil
+}
+
+// Restore loads a backup that was written by `Backup`. The database must be
+// empty (apart from its schema version) and not in use. Backups of an older
+// schema version are migrated when the database is opened again.
+func (db *Database) Restore(r io.Reader) error {
+	empty, err := db.isEmptyExceptMeta()
+	if err != nil {
+		return err
+	}
+
+	if !empty {
+		return ErrRestoreNotEmpty
+	}
+
+	if err := db.badger.DropPrefix(entryKey(metaPrefix, 0, nil)); err != nil {
+		return storageError("badger: failed to drop schema version: %w", err)
+	}
+
+	if err := db.badger.Load(r, maxPendingRestoreWrites); err != nil {
+		return storageError("badger: failed to load backup: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	src, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer src.Close()
+
+	// Buffered writes are included in the backup.
+	src.BatchWrites(BatchConfig{Size: 100, Interval: time.Hour})
+
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		URL:       mustParseURL(t, "https://example.com/"),
+		Method:    http.MethodGet,
+		Body:      bytes.Repeat([]byte("foo"), minDedupBodySize),
+	}
+
+	if err := src.StoreRequestLog(ctx, reqLog); err != nil {
+		t.Fatalf("unexpected error creating request log fixture: %v", err)
+	}
+
+	var backup bytes.Buffer
+
+	if err := src.Backup(&backup); err != nil {
+		t.Fatalf("unexpected error writing backup: %v", err)
+	}
+
+	dst, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer dst.Close()
+
+	if err := dst.Restore(bytes.NewReader(backup.Bytes())); err != nil {
+		t.Fatalf("unexpected error restoring backup: %v", err)
+	}
+
+	got, err := dst.FindRequestLogByID(ctx, reqLog.ID)
+	if err != nil {
+		t.Fatalf("unexpected error finding request log: %v", err)
+	}
+
+	if diff := cmp.Diff(reqLog, got); diff != "" {
+		t.Fatalf("request log not equal (-exp, +got):\n%v", diff)
+	}
+
+	if version, _, _ := dst.schemaVersion(); version != SchemaVersion {
+		t.Fatalf("expected schema version %v, got: %v", SchemaVersion, version)
+	}
+
+	err = dst.Restore(bytes.NewReader(backup.Bytes()))
+	if !errors.Is(err, ErrRestoreNotEmpty) {
+		t.Fatalf("expected `badger.ErrRestoreNotEmpty`, got: %v", err)
+	}
+}
@@ -2,50 +2,121 @@ package badger
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/dgraph-io/badger/v3"
+
+	"github.com/dstotijn/hetty/pkg/db"
+	"github.com/dstotijn/hetty/pkg/errcode"
 )
 
 const (
 	// Key prefixes. Each prefix value should be unique.
-	projectPrefix   = 0x00
-	reqLogPrefix    = 0x01
-	resLogPrefix    = 0x02
-	senderReqPrefix = 0x03
+	projectPrefix         = 0x00
+	reqLogPrefix          = 0x01
+	resLogPrefix          = 0x02
+	senderReqPrefix       = 0x03
+	oastPayloadPrefix     = 0x04
+	oastInteractionPrefix = 0x05
+	findingPrefix         = 0x06
+	connLogPrefix         = 0x07
+	bodyPrefix            = 0x08
+	// Meta items, e.g. the schema version.
+	metaPrefix = 0x09
+	jobPrefix  = 0x0a
 
 	// Request log indices.
-	reqLogProjectIDIndex = 0x00
+	reqLogProjectIDIndex   = 0x00
+	reqLogHostIndex        = 0x01
+	reqLogStatusCodeIndex  = 0x02
+	reqLogContentTypeIndex = 0x03
+	reqLogMethodIndex      = 0x04
+	reqLogRedirectIndex    = 0x05
 
 	// Sender request indices.
-	senderReqProjectIDIndex = 0x00
+	senderReqProjectIDIndex        = 0x00
+	senderCollectionIndex          = 0x01
+	senderCollectionProjectIDIndex = 0x02
+	senderScheduleRunIndex         = 0x03
+	senderComparisonIndex          = 0x04
+
+	// OAST indices.
+	oastPayloadProjectIDIndex     = 0x01
+	oastInteractionProjectIDIndex = 0x01
+
+	// Finding indices.
+	findingProjectIDIndex = 0x01
+
+	// Connection log indices.
+	connLogProjectIDIndex = 0x01
+
+	// Job indices.
+	jobProjectIDIndex = 0x01
+
+	// Body indices.
+	bodyOwnerIndex = 0x01
+	bodyRefIndex   = 0x02
 )
 
+var _ db.Database = (*Database)(nil)
+
 // Database is used to store and retrieve data from an underlying Badger database.
 type Database struct {
 	badger *badger.DB
+
+	// Buffer for batched writes; nil when batching is disabled.
+	writeBuffer *writeBuffer
+
+	// Guards body reference counts, see `writeWithBody`.
+	bodyMu sync.RWMutex
+
+	compaction compaction
 }
 
-// OpenDatabase opens a new Badger database.
+// OpenDatabase opens a new Badger database. Databases of an older schema
+// version are migrated; databases of a newer version (i.e. created by a newer
+// version of Hetty) aren't opened, see `ErrSchemaTooNew`.
 func OpenDatabase(opts badger.Options) (*Database, error) {
-	db, err := badger.Open(opts)
+	bdb, err := badger.Open(opts)
 	if err != nil {
-		return nil, fmt.Errorf("badger: failed to open database: %w", err)
+		return nil, storageError("badger: failed to open database: %w", err)
 	}
 
-	return &Database{badger: db}, nil
+	db := &Database{badger: bdb}
+
+	if err := db.migrate(); err != nil {
+		bdb.Close()
+		return nil, err
+	}
+
+	return db, nil
 }
 
-// Close closes the underlying Badger database.
+// Close stops background compactions, writes buffered entries, and closes the
+// underlying Badger database.
 func (db *Database) Close() error {
+	db.stopCompactionScheduler()
+
+	if err := db.stopBatching(); err != nil {
+		db.badger.Close()
+		return err
+	}
+
 	return db.badger.Close()
 }
 
 // DatabaseFromBadgerDB returns a Database with `db` set as the underlying
-// Badger database.
+// Badger database. Unlike `OpenDatabase`, it doesn't migrate the database.
 func DatabaseFromBadgerDB(db *badger.DB) *Database {
 	return &Database{badger: db}
 }
 
+// storageError returns a formatted error with the `errcode.Storage` code,
+// unless it wraps an error that has a code, e.g. `reqlog.ErrRequestNotFound`.
+func storageError(format string, a ...interface{}) error {
+	return errcode.Wrap(errcode.Storage, fmt.Errorf(format, a...))
+}
+
 func entryKey(prefix, index byte, value []byte) []byte {
 	// Key consists of: | prefix (byte) | index (byte) | value
 	key := make([]byte, 2+len(value))
@@ -0,0 +1,195 @@
+package badger
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/oklog/ulid"
+)
+
+// BatchConfig configures batched writes of request and response logs.
+type BatchConfig struct {
+	// Number of buffered entries after which the buffer is written.
+	Size int
+	// Interval at which the buffer is written, regardless of its size.
+	Interval time.Duration
+}
+
+// writeBuffer buffers entries, which are written with a single
+// `badger.WriteBatch` instead of a transaction per log.
+type writeBuffer struct {
+	size int
+
+	// flushMu is held while buffered entries are written, so that a flush
+	// returns after writes of concurrent flushes are done.
+	flushMu sync.Mutex
+
+	mu      sync.Mutex
+	entries []*badger.Entry
+	// Project IDs of buffered request logs, by request log ID.
+	projectIDs map[ulid.ULID]ulid.ULID
+
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// BatchWrites enables buffering of request and response logs, which are
+// written in batches when the buffer is full, or when cfg.Interval has elapsed.
+// Pending writes are flushed before request logs or sender requests are read or
+// deleted, and when the database is closed. Buffered writes are lost when the
+// process crashes.
+//
+// BatchWrites must be called before the database is used.
+func (db *Database) BatchWrites(cfg BatchConfig) {
+	if cfg.Size <= 0 {
+		return
+	}
+
+	wb := &writeBuffer{
+		size:       cfg.Size,
+		projectIDs: make(map[ulid.ULID]ulid.ULID),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	db.writeBuffer = wb
+
+	go func() {
+		defer close(wb.stopped)
+
+		if cfg.Interval <= 0 {
+			<-wb.done
+			return
+		}
+
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := db.flushWrites(); err != nil {
+					log.Printf("[ERROR] Could not flush batched writes: %v", err)
+				}
+			case <-wb.done:
+				return
+			}
+		}
+	}()
+}
+
+// writeEntries writes entries in a single transaction, or appends them to the
+// write buffer when batching is enabled.
+func (db *Database) writeEntries(entries []*badger.Entry) error {
+	wb := db.writeBuffer
+	if wb == nil {
+		return db.badger.Update(func(txn *badger.Txn) error {
+			for i := range entries {
+				err := txn.SetEntry(entries[i])
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	wb.mu.Lock()
+	wb.entries = append(wb.entries, entries...)
+	full := len(wb.entries) >= wb.size
+	wb.mu.Unlock()
+
+	if full {
+		return db.flushWrites()
+	}
+
+	return nil
+}
+
+// flushWrites writes buffered entries. It's a no-op when batching isn't
+// enabled.
+func (db *Database) flushWrites() error {
+	wb := db.writeBuffer
+	if wb == nil {
+		return nil
+	}
+
+	wb.flushMu.Lock()
+	defer wb.flushMu.Unlock()
+
+	wb.mu.Lock()
+	entries := wb.entries
+	wb.entries = nil
+	wb.mu.Unlock()
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	defer wb.forgetProjectIDs(entries)
+
+	writeBatch := db.badger.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	for _, entry := range entries {
+		if err := writeBatch.SetEntry(entry); err != nil {
+			return storageError("badger: failed to set batch entry: %w", err)
+		}
+	}
+
+	if err := writeBatch.Flush(); err != nil {
+		return storageError("badger: failed to commit batch write: %w", err)
+	}
+
+	return nil
+}
+
+// stopBatching stops the periodic flush, and writes buffered entries.
+func (db *Database) stopBatching() error {
+	wb := db.writeBuffer
+	if wb == nil {
+		return nil
+	}
+
+	wb.stopOnce.Do(func() { close(wb.done) })
+	<-wb.stopped
+
+	return db.flushWrites()
+}
+
+func (wb *writeBuffer) setProjectID(reqLogID, projectID ulid.ULID) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	wb.projectIDs[reqLogID] = projectID
+}
+
+func (wb *writeBuffer) projectID(reqLogID ulid.ULID) (ulid.ULID, bool) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	projectID, ok := wb.projectIDs[reqLogID]
+
+	return projectID, ok
+}
+
+// forgetProjectIDs removes the project IDs of the request logs of written
+// entries.
+func (wb *writeBuffer) forgetProjectIDs(entries []*badger.Entry) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	for _, entry := range entries {
+		// Request log keys are | reqLogPrefix | 0 | request log ID |.
+		if len(entry.Key) != 18 || entry.Key[0] != reqLogPrefix || entry.Key[1] != 0 {
+			continue
+		}
+
+		var id ulid.ULID
+		copy(id[:], entry.Key[2:])
+		delete(wb.projectIDs, id)
+	}
+}
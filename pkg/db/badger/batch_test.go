@@ -0,0 +1,120 @@
+package badger
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+func TestBatchWrites(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	exp := []reqlog.RequestLog{
+		{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/foo"),
+			Method:    http.MethodGet,
+			Response: &reqlog.ResponseLog{
+				Proto:      "HTTP/1.1",
+				Status:     "200 OK",
+				StatusCode: 200,
+				Body:       []byte("foo"),
+			},
+		},
+		{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now())+100, ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/bar"),
+			Method:    http.MethodGet,
+		},
+	}
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+
+	// Neither the size, nor the interval are reached.
+	database.BatchWrites(BatchConfig{Size: 100, Interval: time.Hour})
+
+	for _, reqLog := range exp {
+		if err := database.StoreRequestLog(context.Background(), reqLog); err != nil {
+			t.Fatalf("unexpected error creating request log fixture: %v", err)
+		}
+
+		if reqLog.Response != nil {
+			if err := database.StoreResponseLog(context.Background(), reqLog.ID, *reqLog.Response); err != nil {
+				t.Fatalf("unexpected error creating response log fixture: %v", err)
+			}
+		}
+	}
+
+	// Request logs with a project ID, host and method index item, and a
+	// response log with a status code index item.
+	if n := len(database.writeBuffer.entries); n != 10 {
+		t.Fatalf("expected 10 buffered entries, got: %v", n)
+	}
+
+	// Buffered writes are flushed on close.
+	if err := database.Close(); err != nil {
+		t.Fatalf("unexpected error closing database: %v", err)
+	}
+
+	database, err = OpenDatabase(badgerdb.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	got, err := database.FindRequestLogs(context.Background(), reqlog.FindRequestsFilter{ProjectID: projectID}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error finding request logs: %v", err)
+	}
+
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("request logs not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestBatchWritesFlushOnRead(t *testing.T) {
+	t.Parallel()
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	database.BatchWrites(BatchConfig{Size: 100, Interval: time.Hour})
+
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		URL:       mustParseURL(t, "https://example.com/"),
+		Method:    http.MethodGet,
+	}
+
+	if err := database.StoreRequestLog(context.Background(), reqLog); err != nil {
+		t.Fatalf("unexpected error creating request log fixture: %v", err)
+	}
+
+	got, err := database.FindRequestLogByID(context.Background(), reqLog.ID)
+	if err != nil {
+		t.Fatalf("unexpected error finding request log: %v", err)
+	}
+
+	if diff := cmp.Diff(reqLog, got); diff != "" {
+		t.Fatalf("request log not equal (-exp, +got):\n%v", diff)
+	}
+}
@@ -0,0 +1,336 @@
+package badger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/oklog/ulid"
+)
+
+// Bodies of request and response logs are stored by their SHA-256 hash, so that
+// identical bodies (e.g. static assets, polling responses) are stored once. A
+// body is referenced by its owner: a request log ID (or sender request ID) and
+// the kind of body. The reference count of a body is the number of reference
+// index items for its hash; these are blind writes, so they can be batched.
+//
+// Keys:
+//   - | bodyPrefix | 0 | hash | -> body
+//   - | bodyPrefix | bodyOwnerIndex | owner ID | kind | -> hash
+//   - | bodyPrefix | bodyRefIndex | hash | owner ID | kind | -> nil
+
+type bodyKind byte
+
+const (
+	requestBody  bodyKind = 0x00
+	responseBody bodyKind = 0x01
+)
+
+// minDedupBodySize is the minimum size of a body that is stored by hash.
+// Smaller bodies are stored inline, because the index items would take more
+// space than the body itself.
+const minDedupBodySize = 256
+
+func bodyOwnerKey(ownerID ulid.ULID, kind bodyKind) []byte {
+	return entryKey(bodyPrefix, bodyOwnerIndex, append(ownerID[:], byte(kind)))
+}
+
+func bodyRefKey(hash []byte, ownerID ulid.ULID, kind bodyKind) []byte {
+	value := make([]byte, 0, len(hash)+len(ownerID)+1)
+	value = append(value, hash...)
+	value = append(value, ownerID[:]...)
+	value = append(value, byte(kind))
+
+	return entryKey(bodyPrefix, bodyRefIndex, value)
+}
+
+// writeWithBody writes the entries of an owner together with the entries that
+// store its body by hash. The entries of the owner are returned by entriesFn,
+// which is told whether the body must be stored inline.
+//
+// Body references are written under a read lock of `bodyMu`, so that a body
+// that was found to be stored isn't deleted by `releaseBodies` before the new
+// reference is written.
+func (db *Database) writeWithBody(
+	ownerID ulid.ULID,
+	kind bodyKind,
+	body []byte,
+	entriesFn func(inline bool) ([]*badger.Entry, error),
+) error {
+	db.bodyMu.RLock()
+
+	bodyEntries, staleHash, err := db.bodyEntries(ownerID, kind, body)
+	if err != nil {
+		db.bodyMu.RUnlock()
+		return fmt.Errorf("failed to store body: %w", err)
+	}
+
+	entries, err := entriesFn(bodyEntries == nil)
+	if err != nil {
+		db.bodyMu.RUnlock()
+		return err
+	}
+
+	err = db.writeEntries(append(bodyEntries, entries...))
+	db.bodyMu.RUnlock()
+
+	if err != nil {
+		return fmt.Errorf("failed to write entries: %w", err)
+	}
+
+	if staleHash != nil {
+		return db.releaseStaleBody(ownerID, kind, staleHash)
+	}
+
+	return nil
+}
+
+// bodyEntries returns the entries that store body by hash, for its owner. It
+// returns nil entries when body should be stored inline. The body itself is
+// only written if it isn't stored yet. If the owner referenced another body
+// before, its hash is returned, so that the reference can be released.
+func (db *Database) bodyEntries(ownerID ulid.ULID, kind bodyKind, body []byte) ([]*badger.Entry, []byte, error) {
+	var (
+		hash      []byte
+		staleHash []byte
+		stored    bool
+	)
+
+	if len(body) >= minDedupBodySize {
+		sum := sha256.Sum256(body)
+		hash = sum[:]
+	}
+
+	err := db.badger.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(bodyOwnerKey(ownerID, kind))
+
+		switch {
+		case errors.Is(err, badger.ErrKeyNotFound):
+		case err != nil:
+			return fmt.Errorf("failed to lookup body reference: %w", err)
+		default:
+			prevHash, err := item.ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("failed to retrieve body hash: %w", err)
+			}
+
+			if !bytes.Equal(prevHash, hash) {
+				staleHash = prevHash
+			}
+		}
+
+		if hash == nil {
+			return nil
+		}
+
+		_, err = txn.Get(entryKey(bodyPrefix, 0, hash))
+
+		switch {
+		case errors.Is(err, badger.ErrKeyNotFound):
+		case err != nil:
+			return fmt.Errorf("failed to lookup body: %w", err)
+		default:
+			stored = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if hash == nil {
+		return nil, staleHash, nil
+	}
+
+	entries := []*badger.Entry{
+		{
+			Key:   bodyOwnerKey(ownerID, kind),
+			Value: hash,
+		},
+		{
+			Key: bodyRefKey(hash, ownerID, kind),
+		},
+	}
+
+	if !stored {
+		entries = append(entries, &badger.Entry{
+			Key:   entryKey(bodyPrefix, 0, hash),
+			Value: body,
+		})
+	}
+
+	return entries, staleHash, nil
+}
+
+// releaseStaleBody deletes the reference of an owner to a body it no longer
+// references, and deletes the body if it's no longer referenced.
+func (db *Database) releaseStaleBody(ownerID ulid.ULID, kind bodyKind, hash []byte) error {
+	db.bodyMu.Lock()
+	defer db.bodyMu.Unlock()
+
+	if err := db.flushWrites(); err != nil {
+		return err
+	}
+
+	err := db.badger.Update(func(txn *badger.Txn) error {
+		if err := txn.Delete(bodyRefKey(hash, ownerID, kind)); err != nil {
+			return err
+		}
+
+		// The owner key is only left if the body is now stored inline.
+		item, err := txn.Get(bodyOwnerKey(ownerID, kind))
+
+		switch {
+		case errors.Is(err, badger.ErrKeyNotFound):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		ownerHash, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(ownerHash, hash) {
+			return txn.Delete(bodyOwnerKey(ownerID, kind))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete body reference: %w", err)
+	}
+
+	return db.deleteUnreferencedBodies(map[string]struct{}{string(hash): {}})
+}
+
+// getBody returns the body referenced by its owner, or nil if the owner doesn't
+// reference a body.
+func getBody(txn *badger.Txn, ownerID ulid.ULID, kind bodyKind) ([]byte, error) {
+	item, err := txn.Get(bodyOwnerKey(ownerID, kind))
+
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to lookup body reference: %w", err)
+	}
+
+	hash, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve body hash: %w", err)
+	}
+
+	item, err = txn.Get(entryKey(bodyPrefix, 0, hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup body (hash: %x): %w", hash, err)
+	}
+
+	body, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve body (hash: %x): %w", hash, err)
+	}
+
+	return body, nil
+}
+
+// releaseBodies deletes the body references of owners, and deletes bodies that
+// are no longer referenced.
+func (db *Database) releaseBodies(ownerIDs []ulid.ULID) error {
+	db.bodyMu.Lock()
+	defer db.bodyMu.Unlock()
+
+	// Body references that are buffered must be visible to
+	// `deleteUnreferencedBodies`.
+	if err := db.flushWrites(); err != nil {
+		return err
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	writeBatch := db.badger.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	hashes := make(map[string]struct{})
+
+	for _, ownerID := range ownerIDs {
+		for _, kind := range []bodyKind{requestBody, responseBody} {
+			item, err := txn.Get(bodyOwnerKey(ownerID, kind))
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				continue
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to lookup body reference: %w", err)
+			}
+
+			hash, err := item.ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("failed to retrieve body hash: %w", err)
+			}
+
+			hashes[string(hash)] = struct{}{}
+
+			if err := writeBatch.Delete(bodyOwnerKey(ownerID, kind)); err != nil {
+				return fmt.Errorf("failed to delete body reference: %w", err)
+			}
+
+			if err := writeBatch.Delete(bodyRefKey(hash, ownerID, kind)); err != nil {
+				return fmt.Errorf("failed to delete body reference: %w", err)
+			}
+		}
+	}
+
+	if err := writeBatch.Flush(); err != nil {
+		return fmt.Errorf("failed to commit batch write: %w", err)
+	}
+
+	return db.deleteUnreferencedBodies(hashes)
+}
+
+// deleteUnreferencedBodies deletes the bodies with the given hashes that are
+// no longer referenced. The caller must hold a write lock of `bodyMu`.
+func (db *Database) deleteUnreferencedBodies(hashes map[string]struct{}) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	writeBatch := db.badger.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	for hash := range hashes {
+		if bodyReferenced(txn, []byte(hash)) {
+			continue
+		}
+
+		if err := writeBatch.Delete(entryKey(bodyPrefix, 0, []byte(hash))); err != nil {
+			return fmt.Errorf("failed to delete body: %w", err)
+		}
+	}
+
+	if err := writeBatch.Flush(); err != nil {
+		return fmt.Errorf("failed to commit batch write: %w", err)
+	}
+
+	return nil
+}
+
+func bodyReferenced(txn *badger.Txn, hash []byte) bool {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	iterator := txn.NewIterator(opts)
+	defer iterator.Close()
+
+	prefix := entryKey(bodyPrefix, bodyRefIndex, hash)
+	iterator.Seek(prefix)
+
+	return iterator.ValidForPrefix(prefix)
+}
@@ -0,0 +1,229 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+func TestBodyDeduplication(t *testing.T) {
+	t.Parallel()
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	body := bytes.Repeat([]byte("foobar"), 100)
+	projectIDs := []ulid.ULID{
+		ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+	}
+
+	var exp []reqlog.RequestLog
+
+	for i, projectID := range projectIDs {
+		reqLog := reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now())+uint64(i), ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/"),
+			Method:    http.MethodPost,
+			Body:      body,
+			Response: &reqlog.ResponseLog{
+				Proto:      "HTTP/1.1",
+				Status:     "200 OK",
+				StatusCode: 200,
+				Body:       body,
+			},
+		}
+
+		if err := database.StoreRequestLog(ctx, reqLog); err != nil {
+			t.Fatalf("unexpected error creating request log fixture: %v", err)
+		}
+
+		if err := database.StoreResponseLog(ctx, reqLog.ID, *reqLog.Response); err != nil {
+			t.Fatalf("unexpected error creating response log fixture: %v", err)
+		}
+
+		exp = append(exp, reqLog)
+	}
+
+	if n := countBodies(t, database); n != 1 {
+		t.Fatalf("expected 1 stored body, got: %v", n)
+	}
+
+	got, err := database.FindRequestLogByID(ctx, exp[1].ID)
+	if err != nil {
+		t.Fatalf("unexpected error finding request log: %v", err)
+	}
+
+	if diff := cmp.Diff(exp[1], got); diff != "" {
+		t.Fatalf("request log not equal (-exp, +got):\n%v", diff)
+	}
+
+	// The body is still referenced by the request log of the other project.
+	if err := database.ClearRequestLogs(ctx, projectIDs[0]); err != nil {
+		t.Fatalf("unexpected error clearing request logs: %v", err)
+	}
+
+	if n := countBodies(t, database); n != 1 {
+		t.Fatalf("expected 1 stored body, got: %v", n)
+	}
+
+	if err := database.ClearRequestLogs(ctx, projectIDs[1]); err != nil {
+		t.Fatalf("unexpected error clearing request logs: %v", err)
+	}
+
+	if n := countBodies(t, database); n != 0 {
+		t.Fatalf("expected no stored bodies, got: %v", n)
+	}
+}
+
+func TestReplacedBody(t *testing.T) {
+	t.Parallel()
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		URL:       mustParseURL(t, "https://example.com/"),
+		Method:    http.MethodGet,
+	}
+
+	if err := database.StoreRequestLog(ctx, reqLog); err != nil {
+		t.Fatalf("unexpected error creating request log fixture: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		body      []byte
+		expBodies int
+	}{
+		{name: "deduplicated body", body: bytes.Repeat([]byte("foo"), 100), expBodies: 1},
+		{name: "other deduplicated body", body: bytes.Repeat([]byte("bar"), 100), expBodies: 1},
+		{name: "inline body", body: []byte("baz"), expBodies: 0},
+	} {
+		resLog := reqlog.ResponseLog{Proto: "HTTP/1.1", StatusCode: 200, Body: tt.body}
+
+		if err := database.StoreResponseLog(ctx, reqLog.ID, resLog); err != nil {
+			t.Fatalf("%v: unexpected error storing response log: %v", tt.name, err)
+		}
+
+		// The previous body is no longer referenced, so it's deleted.
+		if n := countBodies(t, database); n != tt.expBodies {
+			t.Fatalf("%v: expected %v stored bodies, got: %v", tt.name, tt.expBodies, n)
+		}
+
+		got, err := database.FindRequestLogByID(ctx, reqLog.ID)
+		if err != nil {
+			t.Fatalf("%v: unexpected error finding request log: %v", tt.name, err)
+		}
+
+		if !bytes.Equal(got.Response.Body, tt.body) {
+			t.Fatalf("%v: expected response body %q, got: %q", tt.name, tt.body, got.Response.Body)
+		}
+	}
+}
+
+func TestBodyReleasedWhileStored(t *testing.T) {
+	t.Parallel()
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	body := bytes.Repeat([]byte("foobar"), 100)
+	ids := make(chan ulid.ULID)
+	errc := make(chan error, 1)
+
+	// Each request log is deleted while the next one, with the same body, is
+	// stored. The body must never be deleted while it's referenced.
+	go func() {
+		defer close(errc)
+
+		for id := range ids {
+			if err := database.DeleteRequestLogs(ctx, projectID, []ulid.ULID{id}); err != nil {
+				errc <- err
+				return
+			}
+		}
+	}()
+
+	var last ulid.ULID
+
+	for i := 0; i < 50; i++ {
+		reqLog := reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now())+uint64(i), ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/"),
+			Method:    http.MethodPost,
+			Body:      body,
+		}
+
+		if err := database.StoreRequestLog(ctx, reqLog); err != nil {
+			t.Fatalf("unexpected error storing request log: %v", err)
+		}
+
+		if i > 0 {
+			ids <- last
+		}
+
+		last = reqLog.ID
+	}
+
+	close(ids)
+
+	if err := <-errc; err != nil {
+		t.Fatalf("unexpected error deleting request logs: %v", err)
+	}
+
+	got, err := database.FindRequestLogByID(ctx, last)
+	if err != nil {
+		t.Fatalf("unexpected error finding request log: %v", err)
+	}
+
+	if !bytes.Equal(got.Body, body) {
+		t.Fatalf("expected request body %q, got: %q", body, got.Body)
+	}
+}
+
+func countBodies(t *testing.T, db *Database) int {
+	t.Helper()
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	opts := badgerdb.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	iterator := txn.NewIterator(opts)
+	defer iterator.Close()
+
+	n := 0
+	prefix := entryKey(bodyPrefix, 0, nil)
+
+	for iterator.Seek(prefix); iterator.ValidForPrefix(prefix); iterator.Next() {
+		n++
+	}
+
+	return n
+}
@@ -0,0 +1,116 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/connlog"
+)
+
+func (db *Database) StoreConnectionLog(ctx context.Context, connLog connlog.ConnectionLog) error {
+	buf := bytes.Buffer{}
+
+	err := gob.NewEncoder(&buf).Encode(connLog)
+	if err != nil {
+		return storageError("badger: failed to encode connection log: %w", err)
+	}
+
+	entries := []*badger.Entry{
+		// Connection log itself.
+		{
+			Key:   entryKey(connLogPrefix, 0, connLog.ID[:]),
+			Value: buf.Bytes(),
+		},
+		// Index by project ID.
+		{
+			Key: entryKey(connLogPrefix, connLogProjectIDIndex, append(connLog.ProjectID[:], connLog.ID[:]...)),
+		},
+	}
+
+	err = db.badger.Update(func(txn *badger.Txn) error {
+		for i := range entries {
+			err := txn.SetEntry(entries[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return storageError("badger: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) FindConnectionLogs(ctx context.Context, filter connlog.FindConnectionLogsFilter) ([]connlog.ConnectionLog, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, connlog.ErrProjectIDMustBeSet
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	ids, err := findIDsByProjectID(txn, connLogPrefix, connLogProjectIDIndex, filter.ProjectID)
+	if err != nil {
+		return nil, storageError("badger: failed to find connection log IDs: %w", err)
+	}
+
+	connLogs := make([]connlog.ConnectionLog, 0, len(ids))
+
+	for _, id := range ids {
+		item, err := txn.Get(entryKey(connLogPrefix, 0, id[:]))
+		if err != nil {
+			return nil, storageError("badger: failed to get connection log (id: %v): %w", id.String(), err)
+		}
+
+		var connLog connlog.ConnectionLog
+
+		err = item.Value(func(rawConnLog []byte) error {
+			return gob.NewDecoder(bytes.NewReader(rawConnLog)).Decode(&connLog)
+		})
+		if err != nil {
+			return nil, storageError("badger: failed to retrieve or parse connection log value: %w", err)
+		}
+
+		connLogs = append(connLogs, connLog)
+	}
+
+	return connLogs, nil
+}
+
+func (db *Database) ClearConnectionLogs(ctx context.Context, projectID ulid.ULID) error {
+	// Note: this transaction is used just for reading; we use the `badger.WriteBatch`
+	// API to bulk delete items.
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	ids, err := findIDsByProjectID(txn, connLogPrefix, connLogProjectIDIndex, projectID)
+	if err != nil {
+		return storageError("badger: failed to find connection log IDs: %w", err)
+	}
+
+	writeBatch := db.badger.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	for _, id := range ids {
+		if err := writeBatch.Delete(entryKey(connLogPrefix, 0, id[:])); err != nil {
+			return storageError("badger: failed to delete connection log: %w", err)
+		}
+	}
+
+	if err := writeBatch.Flush(); err != nil {
+		return storageError("badger: failed to commit batch write: %w", err)
+	}
+
+	err = db.badger.DropPrefix(entryKey(connLogPrefix, connLogProjectIDIndex, projectID[:]))
+	if err != nil {
+		return storageError("badger: failed to drop connection log project ID index items: %w", err)
+	}
+
+	return nil
+}
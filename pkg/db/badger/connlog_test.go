@@ -0,0 +1,93 @@
+package badger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/connlog"
+	"github.com/dstotijn/hetty/pkg/db/badger"
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+func TestFindConnectionLogs(t *testing.T) {
+	t.Parallel()
+
+	database, err := badger.OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	_, err = database.FindConnectionLogs(context.Background(), connlog.FindConnectionLogsFilter{})
+	if !errors.Is(err, connlog.ErrProjectIDMustBeSet) {
+		t.Fatalf("expected `connlog.ErrProjectIDMustBeSet`, got: %v", err)
+	}
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	startedAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	connLogs := []connlog.ConnectionLog{
+		{
+			ID:         ulid.MustNew(ulid.Timestamp(startedAt), ulidEntropy),
+			ProjectID:  projectID,
+			ClientAddr: "127.0.0.1:50000",
+			Host:       "example.com:443",
+			ServerName: "example.com",
+			Mode:       proxy.ConnectionModeMITM,
+			BytesUp:    512,
+			BytesDown:  2048,
+			StartedAt:  startedAt,
+			Duration:   time.Second,
+		},
+		{
+			ID:         ulid.MustNew(ulid.Timestamp(startedAt)+1, ulidEntropy),
+			ProjectID:  projectID,
+			ClientAddr: "127.0.0.1:50001",
+			Host:       "example.com:5222",
+			Mode:       proxy.ConnectionModePassthrough,
+			BytesUp:    5,
+			BytesDown:  3,
+			StartedAt:  startedAt,
+			Capture: &proxy.Capture{
+				Up:   []byte("hello"),
+				Down: []byte{0x00, 0x01, 0x02},
+			},
+			Error: "connection reset by peer",
+		},
+	}
+
+	for _, connLog := range connLogs {
+		if err := database.StoreConnectionLog(context.Background(), connLog); err != nil {
+			t.Fatalf("unexpected error storing connection log: %v", err)
+		}
+	}
+
+	got, err := database.FindConnectionLogs(context.Background(), connlog.FindConnectionLogsFilter{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("unexpected error finding connection logs: %v", err)
+	}
+
+	// Connection logs are returned in reverse chronological order.
+	if diff := cmp.Diff([]connlog.ConnectionLog{connLogs[1], connLogs[0]}, got); diff != "" {
+		t.Fatalf("connection logs not equal (-exp, +got):\n%v", diff)
+	}
+
+	if err := database.ClearConnectionLogs(context.Background(), projectID); err != nil {
+		t.Fatalf("unexpected error clearing connection logs: %v", err)
+	}
+
+	got, err = database.FindConnectionLogs(context.Background(), connlog.FindConnectionLogsFilter{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("unexpected error finding connection logs: %v", err)
+	}
+
+	if len(got) != 0 {
+		t.Fatalf("expected no connection logs after clearing, got: %v", len(got))
+	}
+}
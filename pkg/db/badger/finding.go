@@ -0,0 +1,120 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/finding"
+)
+
+func (db *Database) StoreFinding(ctx context.Context, f finding.Finding) error {
+	buf := bytes.Buffer{}
+
+	err := gob.NewEncoder(&buf).Encode(f)
+	if err != nil {
+		return storageError("badger: failed to encode finding: %w", err)
+	}
+
+	entries := []*badger.Entry{
+		// Finding itself.
+		{
+			Key:   entryKey(findingPrefix, 0, f.ID[:]),
+			Value: buf.Bytes(),
+		},
+		// Index by project ID.
+		{
+			Key: entryKey(findingPrefix, findingProjectIDIndex, append(f.ProjectID[:], f.ID[:]...)),
+		},
+	}
+
+	err = db.badger.Update(func(txn *badger.Txn) error {
+		for i := range entries {
+			err := txn.SetEntry(entries[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return storageError("badger: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) FindFindings(ctx context.Context, filter finding.FindFindingsFilter) ([]finding.Finding, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, finding.ErrProjectIDMustBeSet
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	ids, err := findIDsByProjectID(txn, findingPrefix, findingProjectIDIndex, filter.ProjectID)
+	if err != nil {
+		return nil, storageError("badger: failed to find finding IDs: %w", err)
+	}
+
+	findings := make([]finding.Finding, 0, len(ids))
+
+	for _, id := range ids {
+		item, err := txn.Get(entryKey(findingPrefix, 0, id[:]))
+		if err != nil {
+			return nil, storageError("badger: failed to get finding (id: %v): %w", id.String(), err)
+		}
+
+		var f finding.Finding
+
+		err = item.Value(func(rawFinding []byte) error {
+			return gob.NewDecoder(bytes.NewReader(rawFinding)).Decode(&f)
+		})
+		if err != nil {
+			return nil, storageError("badger: failed to retrieve or parse finding value: %w", err)
+		}
+
+		if filter.RequestLogID.Compare(ulid.ULID{}) != 0 && filter.RequestLogID.Compare(f.RequestLogID) != 0 {
+			continue
+		}
+
+		findings = append(findings, f)
+	}
+
+	return findings, nil
+}
+
+func (db *Database) ClearFindings(ctx context.Context, projectID ulid.ULID) error {
+	// Note: this transaction is used just for reading; we use the `badger.WriteBatch`
+	// API to bulk delete items.
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	ids, err := findIDsByProjectID(txn, findingPrefix, findingProjectIDIndex, projectID)
+	if err != nil {
+		return storageError("badger: failed to find finding IDs: %w", err)
+	}
+
+	writeBatch := db.badger.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	for _, id := range ids {
+		if err := writeBatch.Delete(entryKey(findingPrefix, 0, id[:])); err != nil {
+			return storageError("badger: failed to delete finding: %w", err)
+		}
+	}
+
+	if err := writeBatch.Flush(); err != nil {
+		return storageError("badger: failed to commit batch write: %w", err)
+	}
+
+	err = db.badger.DropPrefix(entryKey(findingPrefix, findingProjectIDIndex, projectID[:]))
+	if err != nil {
+		return storageError("badger: failed to drop finding project ID index items: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package badger_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/badger"
+	"github.com/dstotijn/hetty/pkg/finding"
+)
+
+func TestFindFindings(t *testing.T) {
+	t.Parallel()
+
+	database, err := badger.OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	findings := []finding.Finding{
+		{
+			ID:           ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID:    projectID,
+			RequestLogID: reqLogID,
+			Check:        finding.CheckCORSNullOrigin,
+			Severity:     finding.SeverityMedium,
+			Description:  "foo",
+			HeaderKey:    "Access-Control-Allow-Origin",
+			HeaderValue:  "null",
+		},
+		{
+			ID:           ulid.MustNew(ulid.Timestamp(time.Now())+1, ulidEntropy),
+			ProjectID:    projectID,
+			RequestLogID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			Check:        finding.CheckMissingCSP,
+			Severity:     finding.SeverityLow,
+			Description:  "bar",
+			HeaderKey:    "Content-Security-Policy",
+		},
+	}
+
+	for _, f := range findings {
+		if err := database.StoreFinding(context.Background(), f); err != nil {
+			t.Fatalf("unexpected error storing finding: %v", err)
+		}
+	}
+
+	got, err := database.FindFindings(context.Background(), finding.FindFindingsFilter{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("unexpected error finding findings: %v", err)
+	}
+
+	// Findings are returned in reverse chronological order.
+	if diff := cmp.Diff([]finding.Finding{findings[1], findings[0]}, got); diff != "" {
+		t.Fatalf("findings not equal (-exp, +got):\n%v", diff)
+	}
+
+	got, err = database.FindFindings(context.Background(), finding.FindFindingsFilter{
+		ProjectID:    projectID,
+		RequestLogID: reqLogID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error finding findings: %v", err)
+	}
+
+	if diff := cmp.Diff([]finding.Finding{findings[0]}, got); diff != "" {
+		t.Fatalf("findings filtered by request log ID not equal (-exp, +got):\n%v", diff)
+	}
+
+	if err := database.ClearFindings(context.Background(), projectID); err != nil {
+		t.Fatalf("unexpected error clearing findings: %v", err)
+	}
+
+	got, err = database.FindFindings(context.Background(), finding.FindFindingsFilter{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("unexpected error finding findings: %v", err)
+	}
+
+	if len(got) != 0 {
+		t.Fatalf("expected no findings after clearing, got: %v", len(got))
+	}
+}
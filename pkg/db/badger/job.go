@@ -0,0 +1,116 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/job"
+)
+
+func (db *Database) StoreJob(ctx context.Context, j job.Job) error {
+	buf := bytes.Buffer{}
+
+	err := gob.NewEncoder(&buf).Encode(j)
+	if err != nil {
+		return storageError("badger: failed to encode job: %w", err)
+	}
+
+	entries := []*badger.Entry{
+		// Job itself.
+		{
+			Key:   entryKey(jobPrefix, 0, j.ID[:]),
+			Value: buf.Bytes(),
+		},
+		// Index by project ID.
+		{
+			Key: entryKey(jobPrefix, jobProjectIDIndex, append(j.ProjectID[:], j.ID[:]...)),
+		},
+	}
+
+	err = db.badger.Update(func(txn *badger.Txn) error {
+		for i := range entries {
+			err := txn.SetEntry(entries[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return storageError("badger: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) FindJobs(ctx context.Context, filter job.FindJobsFilter) ([]job.Job, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, job.ErrProjectIDMustBeSet
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	ids, err := findIDsByProjectID(txn, jobPrefix, jobProjectIDIndex, filter.ProjectID)
+	if err != nil {
+		return nil, storageError("badger: failed to find job IDs: %w", err)
+	}
+
+	jobs := make([]job.Job, 0, len(ids))
+
+	for _, id := range ids {
+		item, err := txn.Get(entryKey(jobPrefix, 0, id[:]))
+		if err != nil {
+			return nil, storageError("badger: failed to get job (id: %v): %w", id.String(), err)
+		}
+
+		var j job.Job
+
+		err = item.Value(func(rawJob []byte) error {
+			return gob.NewDecoder(bytes.NewReader(rawJob)).Decode(&j)
+		})
+		if err != nil {
+			return nil, storageError("badger: failed to retrieve or parse job value: %w", err)
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs, nil
+}
+
+func (db *Database) ClearJobs(ctx context.Context, projectID ulid.ULID) error {
+	// Note: this transaction is used just for reading; we use the `badger.WriteBatch`
+	// API to bulk delete items.
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	ids, err := findIDsByProjectID(txn, jobPrefix, jobProjectIDIndex, projectID)
+	if err != nil {
+		return storageError("badger: failed to find job IDs: %w", err)
+	}
+
+	writeBatch := db.badger.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	for _, id := range ids {
+		if err := writeBatch.Delete(entryKey(jobPrefix, 0, id[:])); err != nil {
+			return storageError("badger: failed to delete job: %w", err)
+		}
+	}
+
+	if err := writeBatch.Flush(); err != nil {
+		return storageError("badger: failed to commit batch write: %w", err)
+	}
+
+	err = db.badger.DropPrefix(entryKey(jobPrefix, jobProjectIDIndex, projectID[:]))
+	if err != nil {
+		return storageError("badger: failed to drop job project ID index items: %w", err)
+	}
+
+	return nil
+}
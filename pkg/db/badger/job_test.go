@@ -0,0 +1,96 @@
+package badger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/badger"
+	"github.com/dstotijn/hetty/pkg/job"
+)
+
+func TestFindJobs(t *testing.T) {
+	t.Parallel()
+
+	database, err := badger.OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	_, err = database.FindJobs(context.Background(), job.FindJobsFilter{})
+	if !errors.Is(err, job.ErrProjectIDMustBeSet) {
+		t.Fatalf("expected `job.ErrProjectIDMustBeSet`, got: %v", err)
+	}
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	jobs := []job.Job{
+		{
+			ID:          ulid.MustNew(ulid.Timestamp(createdAt), ulidEntropy),
+			ProjectID:   projectID,
+			Type:        "crawl",
+			Description: "https://example.com/",
+			Status:      job.StatusRunning,
+			Completed:   10,
+			Total:       100,
+			CreatedAt:   createdAt,
+			StartedAt:   createdAt,
+		},
+		{
+			ID:          ulid.MustNew(ulid.Timestamp(createdAt)+1, ulidEntropy),
+			ProjectID:   projectID,
+			Type:        "activeScan",
+			Description: "3 requests",
+			Status:      job.StatusFailed,
+			Error:       "connection refused",
+			CreatedAt:   createdAt,
+			StartedAt:   createdAt,
+			FinishedAt:  createdAt.Add(time.Second),
+		},
+	}
+
+	for _, j := range jobs {
+		if err := database.StoreJob(context.Background(), j); err != nil {
+			t.Fatalf("unexpected error storing job: %v", err)
+		}
+	}
+
+	// Storing a job again updates it.
+	jobs[0].Status = job.StatusFinished
+	jobs[0].Completed = 100
+	jobs[0].FinishedAt = createdAt.Add(time.Minute)
+
+	if err := database.StoreJob(context.Background(), jobs[0]); err != nil {
+		t.Fatalf("unexpected error storing job: %v", err)
+	}
+
+	got, err := database.FindJobs(context.Background(), job.FindJobsFilter{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("unexpected error finding jobs: %v", err)
+	}
+
+	// Jobs are returned in reverse chronological order.
+	if diff := cmp.Diff([]job.Job{jobs[1], jobs[0]}, got); diff != "" {
+		t.Fatalf("jobs not equal (-exp, +got):\n%v", diff)
+	}
+
+	if err := database.ClearJobs(context.Background(), projectID); err != nil {
+		t.Fatalf("unexpected error clearing jobs: %v", err)
+	}
+
+	got, err = database.FindJobs(context.Background(), job.FindJobsFilter{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("unexpected error finding jobs: %v", err)
+	}
+
+	if len(got) != 0 {
+		t.Fatalf("expected no jobs after clearing, got: %v", len(got))
+	}
+}
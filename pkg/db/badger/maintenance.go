@@ -0,0 +1,264 @@
+package badger
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+
+	hettydb "github.com/dstotijn/hetty/pkg/db"
+)
+
+var _ hettydb.Maintainer = (*Database)(nil)
+
+// keyPrefixNames are the names of key prefixes, for key counts in stats.
+var keyPrefixNames = map[byte]string{
+	projectPrefix:         "projects",
+	reqLogPrefix:          "requestLogs",
+	resLogPrefix:          "responseLogs",
+	senderReqPrefix:       "senderRequests",
+	oastPayloadPrefix:     "oastPayloads",
+	oastInteractionPrefix: "oastInteractions",
+	findingPrefix:         "findings",
+	connLogPrefix:         "connectionLogs",
+	bodyPrefix:            "bodies",
+	metaPrefix:            "meta",
+	jobPrefix:             "jobs",
+}
+
+// compactionCheckInterval is the interval at which the compaction schedule is
+// checked.
+var compactionCheckInterval = time.Minute
+
+// Value log files are rewritten when at least half of their space can be
+// discarded, as recommended by Badger.
+const valueLogGCDiscardRatio = 0.5
+
+type compaction struct {
+	mu       sync.Mutex
+	schedule hettydb.CompactionSchedule
+	// Time the schedule was set, so that the first compaction runs after the
+	// interval.
+	scheduledAt time.Time
+	running     bool
+	lastAt      time.Time
+	lastErr     error
+	// Running compactions, which are waited for on close.
+	wg sync.WaitGroup
+
+	// Set while the scheduler is running.
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// Stats returns the size on disk and key counts of the database. Keys are
+// counted by iterating over them (without values), so this is linear in the
+// number of keys.
+func (db *Database) Stats(ctx context.Context) (hettydb.Stats, error) {
+	if err := db.flushWrites(); err != nil {
+		return hettydb.Stats{}, err
+	}
+
+	lsm, vlog := db.badger.Size()
+
+	stats := hettydb.Stats{
+		LSMSize:      lsm,
+		ValueLogSize: vlog,
+		KeyCounts:    make(map[string]int, len(keyPrefixNames)),
+	}
+
+	for _, name := range keyPrefixNames {
+		stats.KeyCounts[name] = 0
+	}
+
+	err := db.badger.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		iterator := txn.NewIterator(opts)
+		defer iterator.Close()
+
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if name, ok := keyPrefixNames[iterator.Item().Key()[0]]; ok {
+				stats.KeyCounts[name]++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return hettydb.Stats{}, storageError("badger: failed to count keys: %w", err)
+	}
+
+	for _, level := range db.badger.Levels() {
+		// Badger compacts levels with a score of at least 1.
+		if level.Score >= 1 {
+			stats.PendingCompactions++
+		}
+	}
+
+	db.compaction.mu.Lock()
+	defer db.compaction.mu.Unlock()
+
+	stats.CompactionSchedule = db.compaction.schedule
+	stats.LastCompactionAt = db.compaction.lastAt
+	stats.CompactionRunning = db.compaction.running
+
+	if db.compaction.lastErr != nil {
+		stats.LastCompactionError = db.compaction.lastErr.Error()
+	}
+
+	return stats, nil
+}
+
+// Compact flattens the LSM tree, which drops deleted and overwritten items,
+// and rewrites value log files with mostly stale values. It's best run while
+// there are few writes, e.g. off-peak, see `SetCompactionSchedule`.
+func (db *Database) Compact(ctx context.Context) error {
+	db.compaction.mu.Lock()
+	if db.compaction.running {
+		db.compaction.mu.Unlock()
+		return hettydb.ErrCompactionRunning
+	}
+	db.compaction.running = true
+	db.compaction.wg.Add(1)
+	db.compaction.mu.Unlock()
+
+	defer db.compaction.wg.Done()
+
+	err := db.compact(ctx)
+
+	db.compaction.mu.Lock()
+	db.compaction.running = false
+	db.compaction.lastAt = time.Now()
+	db.compaction.lastErr = err
+	db.compaction.mu.Unlock()
+
+	return err
+}
+
+func (db *Database) compact(ctx context.Context) error {
+	if err := db.flushWrites(); err != nil {
+		return err
+	}
+
+	if err := db.badger.Flatten(1); err != nil {
+		return storageError("badger: failed to flatten LSM tree: %w", err)
+	}
+
+	// Each run rewrites at most one value log file.
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := db.badger.RunValueLogGC(valueLogGCDiscardRatio)
+
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, badger.ErrNoRewrite), errors.Is(err, badger.ErrGCInMemoryMode):
+			return nil
+		default:
+			return storageError("badger: failed to run value log GC: %w", err)
+		}
+	}
+}
+
+func (db *Database) CompactionSchedule() hettydb.CompactionSchedule {
+	db.compaction.mu.Lock()
+	defer db.compaction.mu.Unlock()
+
+	return db.compaction.schedule
+}
+
+// SetCompactionSchedule sets the schedule of background compactions, and
+// starts or stops the scheduler.
+func (db *Database) SetCompactionSchedule(schedule hettydb.CompactionSchedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+
+	db.compaction.mu.Lock()
+	defer db.compaction.mu.Unlock()
+
+	db.compaction.schedule = schedule
+	db.compaction.scheduledAt = time.Now()
+
+	switch {
+	case schedule.Interval > 0 && db.compaction.done == nil:
+		db.compaction.done = make(chan struct{})
+		db.compaction.stopped = make(chan struct{})
+
+		go db.runCompactionScheduler(db.compaction.done, db.compaction.stopped)
+	case schedule.Interval <= 0 && db.compaction.done != nil:
+		close(db.compaction.done)
+		db.compaction.done = nil
+	}
+
+	return nil
+}
+
+func (db *Database) runCompactionScheduler(done <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(compactionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if !db.compactionDue(now) {
+				continue
+			}
+
+			if err := db.Compact(context.Background()); err != nil && !errors.Is(err, hettydb.ErrCompactionRunning) {
+				log.Printf("[ERROR] Could not compact database: %v", err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (db *Database) compactionDue(now time.Time) bool {
+	db.compaction.mu.Lock()
+	defer db.compaction.mu.Unlock()
+
+	schedule := db.compaction.schedule
+
+	if schedule.Interval <= 0 || db.compaction.running || !schedule.InWindow(now) {
+		return false
+	}
+
+	last := db.compaction.scheduledAt
+	if db.compaction.lastAt.After(last) {
+		last = db.compaction.lastAt
+	}
+
+	return now.Sub(last) >= schedule.Interval
+}
+
+// stopCompactionScheduler stops the scheduler, and waits for running
+// compactions.
+func (db *Database) stopCompactionScheduler() {
+	db.compaction.mu.Lock()
+	done, stopped := db.compaction.done, db.compaction.stopped
+	if done != nil {
+		close(done)
+		db.compaction.done = nil
+	}
+	db.compaction.mu.Unlock()
+
+	if stopped != nil {
+		<-stopped
+	}
+
+	db.compaction.wg.Wait()
+}
@@ -0,0 +1,104 @@
+package badger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/oklog/ulid"
+
+	hettydb "github.com/dstotijn/hetty/pkg/db"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		URL:       mustParseURL(t, "https://example.com/"),
+		Method:    http.MethodGet,
+	}
+
+	if err := database.StoreRequestLog(context.Background(), reqLog); err != nil {
+		t.Fatalf("unexpected error creating request log fixture: %v", err)
+	}
+
+	if err := database.Compact(context.Background()); err != nil {
+		t.Fatalf("unexpected error compacting database: %v", err)
+	}
+
+	stats, err := database.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error getting stats: %v", err)
+	}
+
+	// The request log, and its project ID, host and method index items.
+	if n := stats.KeyCounts["requestLogs"]; n != 4 {
+		t.Fatalf("expected 4 request log keys, got: %v", n)
+	}
+
+	if n := stats.KeyCounts["findings"]; n != 0 {
+		t.Fatalf("expected no finding keys, got: %v", n)
+	}
+
+	if stats.LastCompactionAt.IsZero() || stats.LastCompactionError != "" {
+		t.Fatalf("unexpected last compaction: %v (error: %q)", stats.LastCompactionAt, stats.LastCompactionError)
+	}
+}
+
+func TestCompactionSchedule(t *testing.T) {
+	t.Parallel()
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	err = database.SetCompactionSchedule(hettydb.CompactionSchedule{WindowStart: 24})
+	if !errors.Is(err, hettydb.ErrInvalidCompactionWindow) {
+		t.Fatalf("expected `db.ErrInvalidCompactionWindow`, got: %v", err)
+	}
+
+	if err := database.SetCompactionSchedule(hettydb.CompactionSchedule{Interval: time.Hour}); err != nil {
+		t.Fatalf("unexpected error setting compaction schedule: %v", err)
+	}
+
+	now := time.Now()
+
+	if database.compactionDue(now) {
+		t.Fatal("expected compaction not to be due before the interval elapsed")
+	}
+
+	if !database.compactionDue(now.Add(time.Hour)) {
+		t.Fatal("expected compaction to be due after the interval elapsed")
+	}
+
+	if err := database.Compact(context.Background()); err != nil {
+		t.Fatalf("unexpected error compacting database: %v", err)
+	}
+
+	if database.compactionDue(now.Add(time.Hour)) {
+		t.Fatal("expected compaction not to be due after a compaction")
+	}
+
+	// Disabling the schedule stops the scheduler.
+	if err := database.SetCompactionSchedule(hettydb.CompactionSchedule{}); err != nil {
+		t.Fatalf("unexpected error setting compaction schedule: %v", err)
+	}
+
+	if database.compactionDue(now.Add(48 * time.Hour)) {
+		t.Fatal("expected compaction not to be due when disabled")
+	}
+}
@@ -0,0 +1,232 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/oast"
+)
+
+func (db *Database) StoreOASTPayload(ctx context.Context, payload oast.Payload) error {
+	buf := bytes.Buffer{}
+
+	err := gob.NewEncoder(&buf).Encode(payload)
+	if err != nil {
+		return storageError("badger: failed to encode OAST payload: %w", err)
+	}
+
+	entries := []*badger.Entry{
+		// OAST payload itself.
+		{
+			Key:   entryKey(oastPayloadPrefix, 0, payload.ID[:]),
+			Value: buf.Bytes(),
+		},
+		// Index by project ID.
+		{
+			Key: entryKey(oastPayloadPrefix, oastPayloadProjectIDIndex, append(payload.ProjectID[:], payload.ID[:]...)),
+		},
+	}
+
+	err = db.badger.Update(func(txn *badger.Txn) error {
+		for i := range entries {
+			err := txn.SetEntry(entries[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return storageError("badger: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) FindOASTPayloadByID(ctx context.Context, id ulid.ULID) (oast.Payload, error) {
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	item, err := txn.Get(entryKey(oastPayloadPrefix, 0, id[:]))
+
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return oast.Payload{}, oast.ErrPayloadNotFound
+	case err != nil:
+		return oast.Payload{}, storageError("badger: failed to lookup OAST payload item: %w", err)
+	}
+
+	var payload oast.Payload
+
+	err = item.Value(func(rawPayload []byte) error {
+		return gob.NewDecoder(bytes.NewReader(rawPayload)).Decode(&payload)
+	})
+	if err != nil {
+		return oast.Payload{}, storageError("badger: failed to retrieve or parse OAST payload value: %w", err)
+	}
+
+	return payload, nil
+}
+
+func (db *Database) StoreOASTInteraction(ctx context.Context, interaction oast.Interaction) error {
+	buf := bytes.Buffer{}
+
+	err := gob.NewEncoder(&buf).Encode(interaction)
+	if err != nil {
+		return storageError("badger: failed to encode OAST interaction: %w", err)
+	}
+
+	entries := []*badger.Entry{
+		// OAST interaction itself.
+		{
+			Key:   entryKey(oastInteractionPrefix, 0, interaction.ID[:]),
+			Value: buf.Bytes(),
+		},
+		// Index by project ID.
+		{
+			Key: entryKey(oastInteractionPrefix, oastInteractionProjectIDIndex,
+				append(interaction.ProjectID[:], interaction.ID[:]...)),
+		},
+	}
+
+	err = db.badger.Update(func(txn *badger.Txn) error {
+		for i := range entries {
+			err := txn.SetEntry(entries[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return storageError("badger: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) FindOASTInteractions(ctx context.Context, filter oast.FindInteractionsFilter) ([]oast.Interaction, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, oast.ErrProjectIDMustBeSet
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	ids, err := findIDsByProjectID(txn, oastInteractionPrefix, oastInteractionProjectIDIndex, filter.ProjectID)
+	if err != nil {
+		return nil, storageError("badger: failed to find OAST interaction IDs: %w", err)
+	}
+
+	interactions := make([]oast.Interaction, 0, len(ids))
+
+	for _, id := range ids {
+		item, err := txn.Get(entryKey(oastInteractionPrefix, 0, id[:]))
+		if err != nil {
+			return nil, storageError("badger: failed to get OAST interaction (id: %v): %w", id.String(), err)
+		}
+
+		var interaction oast.Interaction
+
+		err = item.Value(func(rawInteraction []byte) error {
+			return gob.NewDecoder(bytes.NewReader(rawInteraction)).Decode(&interaction)
+		})
+		if err != nil {
+			return nil, storageError("badger: failed to retrieve or parse OAST interaction value: %w", err)
+		}
+
+		if filter.RequestLogID.Compare(ulid.ULID{}) != 0 && filter.RequestLogID.Compare(interaction.RequestLogID) != 0 {
+			continue
+		}
+
+		if filter.CorrelationID.Compare(ulid.ULID{}) != 0 && filter.CorrelationID.Compare(interaction.CorrelationID) != 0 {
+			continue
+		}
+
+		interactions = append(interactions, interaction)
+	}
+
+	return interactions, nil
+}
+
+func (db *Database) ClearOASTData(ctx context.Context, projectID ulid.ULID) error {
+	// Note: this transaction is used just for reading; we use the `badger.WriteBatch`
+	// API to bulk delete items.
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	payloadIDs, err := findIDsByProjectID(txn, oastPayloadPrefix, oastPayloadProjectIDIndex, projectID)
+	if err != nil {
+		return storageError("badger: failed to find OAST payload IDs: %w", err)
+	}
+
+	interactionIDs, err := findIDsByProjectID(txn, oastInteractionPrefix, oastInteractionProjectIDIndex, projectID)
+	if err != nil {
+		return storageError("badger: failed to find OAST interaction IDs: %w", err)
+	}
+
+	writeBatch := db.badger.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	for _, id := range payloadIDs {
+		if err := writeBatch.Delete(entryKey(oastPayloadPrefix, 0, id[:])); err != nil {
+			return storageError("badger: failed to delete OAST payload: %w", err)
+		}
+	}
+
+	for _, id := range interactionIDs {
+		if err := writeBatch.Delete(entryKey(oastInteractionPrefix, 0, id[:])); err != nil {
+			return storageError("badger: failed to delete OAST interaction: %w", err)
+		}
+	}
+
+	if err := writeBatch.Flush(); err != nil {
+		return storageError("badger: failed to commit batch write: %w", err)
+	}
+
+	err = db.badger.DropPrefix(
+		entryKey(oastPayloadPrefix, oastPayloadProjectIDIndex, projectID[:]),
+		entryKey(oastInteractionPrefix, oastInteractionProjectIDIndex, projectID[:]),
+	)
+	if err != nil {
+		return storageError("badger: failed to drop OAST project ID index items: %w", err)
+	}
+
+	return nil
+}
+
+// findIDsByProjectID returns the IDs stored in a project ID index, in reverse
+// chronological order.
+func findIDsByProjectID(txn *badger.Txn, prefix, index byte, projectID ulid.ULID) ([]ulid.ULID, error) {
+	ids := make([]ulid.ULID, 0)
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Reverse = true
+	iterator := txn.NewIterator(opts)
+	defer iterator.Close()
+
+	var projectIndexKey []byte
+
+	keyPrefix := entryKey(prefix, index, projectID[:])
+
+	for iterator.Seek(append(keyPrefix, 255)); iterator.ValidForPrefix(keyPrefix); iterator.Next() {
+		projectIndexKey = iterator.Item().KeyCopy(projectIndexKey)
+
+		var id ulid.ULID
+		// The ID starts *after* the first 2 prefix and index bytes and the
+		// 16 byte project ID.
+		if err := id.UnmarshalBinary(projectIndexKey[18:]); err != nil {
+			return nil, fmt.Errorf("failed to parse ID: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
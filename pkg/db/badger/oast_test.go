@@ -0,0 +1,114 @@
+package badger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/badger"
+	"github.com/dstotijn/hetty/pkg/oast"
+)
+
+func TestFindOASTInteractions(t *testing.T) {
+	t.Parallel()
+
+	database, err := badger.OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	payload := oast.Payload{
+		ID:           ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID:    projectID,
+		RequestLogID: reqLogID,
+	}
+
+	if err := database.StoreOASTPayload(context.Background(), payload); err != nil {
+		t.Fatalf("unexpected error storing payload: %v", err)
+	}
+
+	gotPayload, err := database.FindOASTPayloadByID(context.Background(), payload.ID)
+	if err != nil {
+		t.Fatalf("unexpected error finding payload: %v", err)
+	}
+
+	if diff := cmp.Diff(payload, gotPayload); diff != "" {
+		t.Fatalf("payload not equal (-exp, +got):\n%v", diff)
+	}
+
+	interactions := []oast.Interaction{
+		{
+			ID:           ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID:    projectID,
+			PayloadID:    payload.ID,
+			RequestLogID: reqLogID,
+			Protocol:     oast.ProtocolDNS,
+			RemoteAddr:   "192.0.2.1:53",
+			Raw:          []byte("foo"),
+		},
+		{
+			ID:         ulid.MustNew(ulid.Timestamp(time.Now())+1, ulidEntropy),
+			ProjectID:  projectID,
+			PayloadID:  payload.ID,
+			Protocol:   oast.ProtocolHTTP,
+			RemoteAddr: "192.0.2.1:1337",
+			Raw:        []byte("bar"),
+		},
+	}
+
+	for _, interaction := range interactions {
+		if err := database.StoreOASTInteraction(context.Background(), interaction); err != nil {
+			t.Fatalf("unexpected error storing interaction: %v", err)
+		}
+	}
+
+	got, err := database.FindOASTInteractions(context.Background(), oast.FindInteractionsFilter{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("unexpected error finding interactions: %v", err)
+	}
+
+	// Interactions are returned in reverse chronological order.
+	exp := []oast.Interaction{interactions[1], interactions[0]}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("interactions not equal (-exp, +got):\n%v", diff)
+	}
+
+	got, err = database.FindOASTInteractions(context.Background(), oast.FindInteractionsFilter{
+		ProjectID:    projectID,
+		RequestLogID: reqLogID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error finding interactions: %v", err)
+	}
+
+	if diff := cmp.Diff(interactions[:1], got); diff != "" {
+		t.Fatalf("interactions not equal (-exp, +got):\n%v", diff)
+	}
+
+	if err := database.ClearOASTData(context.Background(), projectID); err != nil {
+		t.Fatalf("unexpected error clearing OAST data: %v", err)
+	}
+
+	_, err = database.FindOASTPayloadByID(context.Background(), payload.ID)
+	if !errors.Is(err, oast.ErrPayloadNotFound) {
+		t.Fatalf("expected `oast.ErrPayloadNotFound`, got: %v", err)
+	}
+
+	got, err = database.FindOASTInteractions(context.Background(), oast.FindInteractionsFilter{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("unexpected error finding interactions: %v", err)
+	}
+
+	if len(got) != 0 {
+		t.Fatalf("expected no interactions, got: %v", len(got))
+	}
+}
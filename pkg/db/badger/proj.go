@@ -18,14 +18,14 @@ func (db *Database) UpsertProject(ctx context.Context, project proj.Project) err
 
 	err := gob.NewEncoder(&buf).Encode(project)
 	if err != nil {
-		return fmt.Errorf("badger: failed to encode project: %w", err)
+		return storageError("badger: failed to encode project: %w", err)
 	}
 
 	err = db.badger.Update(func(txn *badger.Txn) error {
 		return txn.Set(entryKey(projectPrefix, 0, project.ID[:]), buf.Bytes())
 	})
 	if err != nil {
-		return fmt.Errorf("badger: failed to commit transaction: %w", err)
+		return storageError("badger: failed to commit transaction: %w", err)
 	}
 
 	return nil
@@ -53,7 +53,7 @@ func (db *Database) FindProjectByID(ctx context.Context, projectID ulid.ULID) (p
 	}
 
 	if err != nil {
-		return proj.Project{}, fmt.Errorf("badger: failed to commit transaction: %w", err)
+		return proj.Project{}, storageError("badger: failed to commit transaction: %w", err)
 	}
 
 	return project, nil
@@ -62,19 +62,39 @@ func (db *Database) FindProjectByID(ctx context.Context, projectID ulid.ULID) (p
 func (db *Database) DeleteProject(ctx context.Context, projectID ulid.ULID) error {
 	err := db.ClearRequestLogs(ctx, projectID)
 	if err != nil {
-		return fmt.Errorf("badger: failed to delete project request logs: %w", err)
+		return storageError("badger: failed to delete project request logs: %w", err)
 	}
 
 	err = db.DeleteSenderRequests(ctx, projectID)
 	if err != nil {
-		return fmt.Errorf("badger: failed to delete project sender requests: %w", err)
+		return storageError("badger: failed to delete project sender requests: %w", err)
+	}
+
+	err = db.ClearOASTData(ctx, projectID)
+	if err != nil {
+		return storageError("badger: failed to delete project OAST data: %w", err)
+	}
+
+	err = db.ClearFindings(ctx, projectID)
+	if err != nil {
+		return storageError("badger: failed to delete project findings: %w", err)
+	}
+
+	err = db.ClearConnectionLogs(ctx, projectID)
+	if err != nil {
+		return storageError("badger: failed to delete project connection logs: %w", err)
+	}
+
+	err = db.ClearJobs(ctx, projectID)
+	if err != nil {
+		return storageError("badger: failed to delete project jobs: %w", err)
 	}
 
 	err = db.badger.Update(func(txn *badger.Txn) error {
 		return txn.Delete(entryKey(projectPrefix, 0, projectID[:]))
 	})
 	if err != nil {
-		return fmt.Errorf("badger: failed to delete project item: %w", err)
+		return storageError("badger: failed to delete project item: %w", err)
 	}
 
 	return nil
@@ -108,7 +128,7 @@ func (db *Database) Projects(ctx context.Context) ([]proj.Project, error) {
 		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("badger: failed to commit transaction: %w", err)
+		return nil, storageError("badger: failed to commit transaction: %w", err)
 	}
 
 	return projects, nil
@@ -19,33 +19,85 @@ func (db *Database) FindRequestLogs(ctx context.Context, filter reqlog.FindReque
 		return nil, reqlog.ErrProjectIDMustBeSet
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, storageError("badger: failed to find request logs: %w", err)
+	}
+
+	if err := db.flushWrites(); err != nil {
+		return nil, err
+	}
+
 	txn := db.badger.NewTransaction(false)
 	defer txn.Discard()
 
-	reqLogIDs, err := findRequestLogIDsByProjectID(txn, filter.ProjectID)
+	reqLogIDs, err := findRequestLogIDs(txn, filter)
 	if err != nil {
-		return nil, fmt.Errorf("badger: failed to find request log IDs: %w", err)
+		return nil, storageError("badger: failed to find request log IDs: %w", err)
 	}
 
 	reqLogs := make([]reqlog.RequestLog, 0, len(reqLogIDs))
 
+	// Only load the bodies and response needed for filtering up front; the
+	// remaining parts are loaded for matching request logs.
+	var filterFields reqlog.SearchExprFields
+
+	searchExpr := filter.SearchExpression()
+	if searchExpr != nil {
+		filterFields = reqlog.FieldsForSearchExpr(searchExpr)
+	}
+
+	if filter.OnlyInScope {
+		// Scope rules can match on the request body.
+		filterFields.RequestBody = true
+	}
+
+	if filter.StatusCode != 0 || filter.ContentType != "" {
+		filterFields.Response = true
+	}
+
 	for _, reqLogID := range reqLogIDs {
-		reqLog, err := getRequestLogWithResponse(txn, reqLogID)
+		// Searching large projects can take long, so stop when ctx is done.
+		if err := ctx.Err(); err != nil {
+			return nil, storageError("badger: failed to find request logs: %w", err)
+		}
+
+		loader, err := newReqLogLoader(txn, reqLogID)
 		if err != nil {
-			return nil, fmt.Errorf("badger: failed to get request log (id: %v): %w", reqLogID.String(), err)
+			return nil, storageError("badger: failed to get request log (id: %v): %w", reqLogID.String(), err)
+		}
+
+		if filter.CollapseRedirects && loader.reqLog.RedirectFromID.Compare(ulid.ULID{}) != 0 {
+			continue
+		}
+
+		if filter.CorrelationID.Compare(ulid.ULID{}) != 0 && filter.CorrelationID.Compare(loader.reqLog.CorrelationID) != 0 {
+			continue
+		}
+
+		if filter.CollapsePageLoads && !loader.reqLog.StartsPageLoad() {
+			continue
+		}
+
+		if filter.PageLoadID.Compare(ulid.ULID{}) != 0 && filter.PageLoadID.Compare(loader.reqLog.PageLoadID) != 0 {
+			continue
+		}
+
+		if err := loader.load(filterFields); err != nil {
+			return nil, storageError("badger: failed to get request log (id: %v): %w", reqLogID.String(), err)
+		}
+
+		if !filter.MatchFields(loader.reqLog) {
+			continue
 		}
 
 		if filter.OnlyInScope {
-			if !reqLog.MatchScope(scope) {
+			if !loader.reqLog.MatchScope(scope) {
 				continue
 			}
 		}
 
-		// Filter by search expression.
-		// TODO: Once pagination is introduced, this filter logic should be done
-		// as items are retrieved (e.g. when using a `badger.Iterator`).
-		if filter.SearchExpr != nil {
-			match, err := reqLog.Matches(filter.SearchExpr)
+		if searchExpr != nil {
+			match, err := loader.reqLog.Matches(searchExpr)
 			if err != nil {
 				return nil, fmt.Errorf(
 					"badger: failed to match search expression for request log (id: %v): %w",
@@ -58,20 +110,35 @@ func (db *Database) FindRequestLogs(ctx context.Context, filter reqlog.FindReque
 			}
 		}
 
-		reqLogs = append(reqLogs, reqLog)
+		if err := loader.load(allReqLogFields); err != nil {
+			return nil, storageError("badger: failed to get request log (id: %v): %w", reqLogID.String(), err)
+		}
+
+		reqLogs = append(reqLogs, loader.reqLog)
 	}
 
 	return reqLogs, nil
 }
 
-func getRequestLogWithResponse(txn *badger.Txn, reqLogID ulid.ULID) (reqlog.RequestLog, error) {
+var allReqLogFields = reqlog.SearchExprFields{RequestBody: true, Response: true, ResponseBody: true}
+
+// reqLogLoader loads the separately stored parts of a request log (bodies and
+// the response log) on demand, so that request logs can be filtered before
+// these are read.
+type reqLogLoader struct {
+	txn    *badger.Txn
+	reqLog reqlog.RequestLog
+	loaded reqlog.SearchExprFields
+}
+
+func newReqLogLoader(txn *badger.Txn, reqLogID ulid.ULID) (*reqLogLoader, error) {
 	item, err := txn.Get(entryKey(reqLogPrefix, 0, reqLogID[:]))
 
 	switch {
 	case errors.Is(err, badger.ErrKeyNotFound):
-		return reqlog.RequestLog{}, reqlog.ErrRequestNotFound
+		return nil, reqlog.ErrRequestNotFound
 	case err != nil:
-		return reqlog.RequestLog{}, fmt.Errorf("failed to lookup request log item: %w", err)
+		return nil, fmt.Errorf("failed to lookup request log item: %w", err)
 	}
 
 	reqLog := reqlog.RequestLog{
@@ -87,17 +154,55 @@ func getRequestLogWithResponse(txn *badger.Txn, reqLogID ulid.ULID) (reqlog.Requ
 		return nil
 	})
 	if err != nil {
-		return reqlog.RequestLog{}, fmt.Errorf("failed to retrieve or parse request log value: %w", err)
+		return nil, fmt.Errorf("failed to retrieve or parse request log value: %w", err)
 	}
 
-	item, err = txn.Get(entryKey(resLogPrefix, 0, reqLogID[:]))
+	return &reqLogLoader{txn: txn, reqLog: reqLog}, nil
+}
+
+func (l *reqLogLoader) load(fields reqlog.SearchExprFields) error {
+	var err error
+
+	if fields.RequestBody && !l.loaded.RequestBody {
+		if len(l.reqLog.Body) == 0 {
+			if l.reqLog.Body, err = getBody(l.txn, l.reqLog.ID, requestBody); err != nil {
+				return fmt.Errorf("failed to get request body: %w", err)
+			}
+		}
+
+		l.loaded.RequestBody = true
+	}
+
+	if (fields.Response || fields.ResponseBody) && !l.loaded.Response {
+		if err := l.loadResponse(); err != nil {
+			return err
+		}
+
+		l.loaded.Response = true
+	}
+
+	if fields.ResponseBody && !l.loaded.ResponseBody {
+		if l.reqLog.Response != nil && len(l.reqLog.Response.Body) == 0 {
+			if l.reqLog.Response.Body, err = getBody(l.txn, l.reqLog.ID, responseBody); err != nil {
+				return fmt.Errorf("failed to get response body: %w", err)
+			}
+		}
+
+		l.loaded.ResponseBody = true
+	}
+
+	return nil
+}
+
+func (l *reqLogLoader) loadResponse() error {
+	item, err := l.txn.Get(entryKey(resLogPrefix, 0, l.reqLog.ID[:]))
 
 	if errors.Is(err, badger.ErrKeyNotFound) {
-		return reqLog, nil
+		return nil
 	}
 
 	if err != nil {
-		return reqlog.RequestLog{}, fmt.Errorf("failed to get response log: %w", err)
+		return fmt.Errorf("failed to get response log: %w", err)
 	}
 
 	err = item.Value(func(rawReslog []byte) error {
@@ -107,87 +212,143 @@ func getRequestLogWithResponse(txn *badger.Txn, reqLogID ulid.ULID) (reqlog.Requ
 			return fmt.Errorf("failed to decode response log: %w", err)
 		}
 
-		reqLog.Response = &resLog
+		l.reqLog.Response = &resLog
 
 		return nil
 	})
 	if err != nil {
-		return reqlog.RequestLog{}, fmt.Errorf("failed to retrieve or parse response log value: %w", err)
+		return fmt.Errorf("failed to retrieve or parse response log value: %w", err)
 	}
 
-	return reqLog, nil
+	return nil
+}
+
+func getRequestLogWithResponse(txn *badger.Txn, reqLogID ulid.ULID) (reqlog.RequestLog, error) {
+	loader, err := newReqLogLoader(txn, reqLogID)
+	if err != nil {
+		return reqlog.RequestLog{}, err
+	}
+
+	if err := loader.load(allReqLogFields); err != nil {
+		return reqlog.RequestLog{}, err
+	}
+
+	return loader.reqLog, nil
 }
 
 func (db *Database) FindRequestLogByID(ctx context.Context, reqLogID ulid.ULID) (reqLog reqlog.RequestLog, err error) {
+	if err := ctx.Err(); err != nil {
+		return reqlog.RequestLog{}, storageError("badger: failed to get request log: %w", err)
+	}
+
+	if err := db.flushWrites(); err != nil {
+		return reqlog.RequestLog{}, err
+	}
+
 	txn := db.badger.NewTransaction(false)
 	defer txn.Discard()
 
 	reqLog, err = getRequestLogWithResponse(txn, reqLogID)
 	if err != nil {
-		return reqlog.RequestLog{}, fmt.Errorf("badger: failed to get request log: %w", err)
+		return reqlog.RequestLog{}, storageError("badger: failed to get request log: %w", err)
 	}
 
 	return reqLog, nil
 }
 
 func (db *Database) StoreRequestLog(ctx context.Context, reqLog reqlog.RequestLog) error {
-	buf := bytes.Buffer{}
-
-	err := gob.NewEncoder(&buf).Encode(reqLog)
-	if err != nil {
-		return fmt.Errorf("badger: failed to encode request log: %w", err)
+	if err := ctx.Err(); err != nil {
+		return storageError("badger: failed to store request log: %w", err)
 	}
 
-	entries := []*badger.Entry{
-		// Request log itself.
-		{
-			Key:   entryKey(reqLogPrefix, 0, reqLog.ID[:]),
-			Value: buf.Bytes(),
-		},
-		// Index by project ID.
-		{
-			Key: entryKey(reqLogPrefix, reqLogProjectIDIndex, append(reqLog.ProjectID[:], reqLog.ID[:]...)),
-		},
-	}
+	err := db.writeWithBody(reqLog.ID, requestBody, reqLog.Body, func(inline bool) ([]*badger.Entry, error) {
+		if !inline {
+			reqLog.Body = nil
+		}
 
-	err = db.badger.Update(func(txn *badger.Txn) error {
-		for i := range entries {
-			err := txn.SetEntry(entries[i])
-			if err != nil {
-				return err
-			}
+		buf := bytes.Buffer{}
+
+		if err := gob.NewEncoder(&buf).Encode(reqLog); err != nil {
+			return nil, fmt.Errorf("failed to encode request log: %w", err)
 		}
-		return nil
+
+		entries := []*badger.Entry{
+			// Request log itself.
+			{
+				Key:   entryKey(reqLogPrefix, 0, reqLog.ID[:]),
+				Value: buf.Bytes(),
+			},
+			// Index by project ID.
+			{
+				Key: entryKey(reqLogPrefix, reqLogProjectIDIndex, append(reqLog.ProjectID[:], reqLog.ID[:]...)),
+			},
+		}
+
+		// The project ID is needed to index the response log, which can be
+		// stored before the request log is written.
+		if wb := db.writeBuffer; wb != nil {
+			wb.setProjectID(reqLog.ID, reqLog.ProjectID)
+		}
+
+		return append(entries, indexEntries(requestIndexKeys(reqLog))...), nil
 	})
 	if err != nil {
-		return fmt.Errorf("badger: failed to commit transaction: %w", err)
+		return storageError("badger: failed to store request log: %w", err)
 	}
 
 	return nil
 }
 
 func (db *Database) StoreResponseLog(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error {
-	buf := bytes.Buffer{}
+	if err := ctx.Err(); err != nil {
+		return storageError("badger: failed to store response log: %w", err)
+	}
 
-	err := gob.NewEncoder(&buf).Encode(resLog)
+	projectID, ok, err := db.requestLogProjectID(reqLogID)
 	if err != nil {
-		return fmt.Errorf("badger: failed to encode response log: %w", err)
+		return storageError("badger: failed to get project ID of request log: %w", err)
 	}
 
-	err = db.badger.Update(func(txn *badger.Txn) error {
-		return txn.SetEntry(&badger.Entry{
-			Key:   entryKey(resLogPrefix, 0, reqLogID[:]),
-			Value: buf.Bytes(),
-		})
+	err = db.writeWithBody(reqLogID, responseBody, resLog.Body, func(inline bool) ([]*badger.Entry, error) {
+		if !inline {
+			resLog.Body = nil
+		}
+
+		buf := bytes.Buffer{}
+
+		if err := gob.NewEncoder(&buf).Encode(resLog); err != nil {
+			return nil, fmt.Errorf("failed to encode response log: %w", err)
+		}
+
+		entries := []*badger.Entry{
+			{
+				Key:   entryKey(resLogPrefix, 0, reqLogID[:]),
+				Value: buf.Bytes(),
+			},
+		}
+
+		if ok {
+			entries = append(entries, indexEntries(resLogIndexKeys(projectID, reqLogID, resLog))...)
+		}
+
+		return entries, nil
 	})
 	if err != nil {
-		return fmt.Errorf("badger: failed to commit transaction: %w", err)
+		return storageError("badger: failed to store response log: %w", err)
 	}
 
 	return nil
 }
 
 func (db *Database) ClearRequestLogs(ctx context.Context, projectID ulid.ULID) error {
+	if err := ctx.Err(); err != nil {
+		return storageError("badger: failed to clear request logs: %w", err)
+	}
+
+	if err := db.flushWrites(); err != nil {
+		return err
+	}
+
 	// Note: this transaction is used just for reading; we use the `badger.WriteBatch`
 	// API to bulk delete items.
 	txn := db.badger.NewTransaction(false)
@@ -195,7 +356,7 @@ func (db *Database) ClearRequestLogs(ctx context.Context, projectID ulid.ULID) e
 
 	reqLogIDs, err := findRequestLogIDsByProjectID(txn, projectID)
 	if err != nil {
-		return fmt.Errorf("badger: failed to find request log IDs: %w", err)
+		return storageError("badger: failed to find request log IDs: %w", err)
 	}
 
 	writeBatch := db.badger.NewWriteBatch()
@@ -205,23 +366,82 @@ func (db *Database) ClearRequestLogs(ctx context.Context, projectID ulid.ULID) e
 		// Delete request logs.
 		err := writeBatch.Delete(entryKey(reqLogPrefix, 0, reqLogID[:]))
 		if err != nil {
-			return fmt.Errorf("badger: failed to delete request log: %w", err)
+			return storageError("badger: failed to delete request log: %w", err)
 		}
 
 		// Delete related response log.
 		err = writeBatch.Delete(entryKey(resLogPrefix, 0, reqLogID[:]))
 		if err != nil {
-			return fmt.Errorf("badger: failed to delete request log: %w", err)
+			return storageError("badger: failed to delete request log: %w", err)
 		}
 	}
 
 	if err := writeBatch.Flush(); err != nil {
-		return fmt.Errorf("badger: failed to commit batch write: %w", err)
+		return storageError("badger: failed to commit batch write: %w", err)
+	}
+
+	if err := db.releaseBodies(reqLogIDs); err != nil {
+		return storageError("badger: failed to release request log bodies: %w", err)
 	}
 
 	err = db.badger.DropPrefix(entryKey(reqLogPrefix, reqLogProjectIDIndex, projectID[:]))
 	if err != nil {
-		return fmt.Errorf("badger: failed to drop request log project ID index items: %w", err)
+		return storageError("badger: failed to drop request log project ID index items: %w", err)
+	}
+
+	return db.dropRequestLogIndexes(projectID)
+}
+
+// DeleteRequestLogs deletes request logs of a project, and their response logs.
+func (db *Database) DeleteRequestLogs(ctx context.Context, projectID ulid.ULID, ids []ulid.ULID) error {
+	if err := ctx.Err(); err != nil {
+		return storageError("badger: failed to delete request logs: %w", err)
+	}
+
+	if err := db.flushWrites(); err != nil {
+		return err
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	writeBatch := db.badger.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	for _, reqLogID := range ids {
+		keys := [][]byte{
+			entryKey(reqLogPrefix, 0, reqLogID[:]),
+			entryKey(reqLogPrefix, reqLogProjectIDIndex, append(projectID[:], reqLogID[:]...)),
+			entryKey(resLogPrefix, 0, reqLogID[:]),
+		}
+
+		// The field index keys are derived from the stored request log.
+		loader, err := newReqLogLoader(txn, reqLogID)
+		switch {
+		case errors.Is(err, reqlog.ErrRequestNotFound):
+		case err != nil:
+			return storageError("badger: failed to get request log (id: %v): %w", reqLogID.String(), err)
+		default:
+			if err := loader.load(reqlog.SearchExprFields{Response: true}); err != nil {
+				return storageError("badger: failed to get request log (id: %v): %w", reqLogID.String(), err)
+			}
+
+			keys = append(keys, reqLogIndexKeys(loader.reqLog)...)
+		}
+
+		for _, key := range keys {
+			if err := writeBatch.Delete(key); err != nil {
+				return storageError("badger: failed to delete request log: %w", err)
+			}
+		}
+	}
+
+	if err := writeBatch.Flush(); err != nil {
+		return storageError("badger: failed to commit batch write: %w", err)
+	}
+
+	if err := db.releaseBodies(ids); err != nil {
+		return storageError("badger: failed to release request log bodies: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,483 @@
+package badger
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+// Request logs are indexed by the fields of `reqlog.FindRequestsFilter` that
+// are commonly filtered on, so that finding request logs by these fields only
+// reads matching request logs. The method is indexed for listing observed
+// values only, see `FindRequestLogFieldValues`, and the redirect source for
+// following redirect chains, see `FindRedirectedRequestLogIDs`. Hostnames, media types and
+// methods don't contain NUL bytes, so these are used as separator before the
+// request log ID.
+//
+// Keys:
+//   - | reqLogPrefix | reqLogHostIndex | project ID | hostname | 0x00 | request log ID | -> nil
+//   - | reqLogPrefix | reqLogStatusCodeIndex | project ID | status code (uint16) | request log ID | -> nil
+//   - | reqLogPrefix | reqLogContentTypeIndex | project ID | media type | 0x00 | request log ID | -> nil
+//   - | reqLogPrefix | reqLogMethodIndex | project ID | method | 0x00 | request log ID | -> nil
+//   - | reqLogPrefix | reqLogRedirectIndex | project ID | redirect from ID | request log ID | -> nil
+//
+// The status code and content type index items are written when the response
+// log is stored. Index items can be stale (e.g. if a response is stored more
+// than once), so request logs are matched against the filter when found.
+
+// reqLogFieldIndexes are the indexes that are rebuilt and dropped per project.
+var reqLogFieldIndexes = []byte{reqLogHostIndex, reqLogStatusCodeIndex, reqLogContentTypeIndex, reqLogMethodIndex, reqLogRedirectIndex}
+
+func reqLogHostIndexPrefix(projectID ulid.ULID, hostname string) []byte {
+	value := make([]byte, 0, len(projectID)+len(hostname)+1)
+	value = append(value, projectID[:]...)
+	value = append(value, hostname...)
+	value = append(value, 0x00)
+
+	return entryKey(reqLogPrefix, reqLogHostIndex, value)
+}
+
+func reqLogMethodIndexPrefix(projectID ulid.ULID, method string) []byte {
+	value := make([]byte, 0, len(projectID)+len(method)+1)
+	value = append(value, projectID[:]...)
+	value = append(value, method...)
+	value = append(value, 0x00)
+
+	return entryKey(reqLogPrefix, reqLogMethodIndex, value)
+}
+
+func reqLogRedirectIndexPrefix(projectID, redirectFromID ulid.ULID) []byte {
+	value := make([]byte, 0, len(projectID)+len(redirectFromID))
+	value = append(value, projectID[:]...)
+	value = append(value, redirectFromID[:]...)
+
+	return entryKey(reqLogPrefix, reqLogRedirectIndex, value)
+}
+
+func reqLogStatusCodeIndexPrefix(projectID ulid.ULID, statusCode int) []byte {
+	value := make([]byte, len(projectID)+2)
+	copy(value, projectID[:])
+	binary.BigEndian.PutUint16(value[len(projectID):], uint16(statusCode))
+
+	return entryKey(reqLogPrefix, reqLogStatusCodeIndex, value)
+}
+
+func reqLogContentTypeIndexPrefix(projectID ulid.ULID, mediaType string) []byte {
+	value := make([]byte, 0, len(projectID)+len(mediaType)+1)
+	value = append(value, projectID[:]...)
+	value = append(value, mediaType...)
+	value = append(value, 0x00)
+
+	return entryKey(reqLogPrefix, reqLogContentTypeIndex, value)
+}
+
+func indexKey(prefix []byte, reqLogID ulid.ULID) []byte {
+	key := make([]byte, 0, len(prefix)+len(reqLogID))
+	key = append(key, prefix...)
+
+	return append(key, reqLogID[:]...)
+}
+
+// reqLogIndexKeys returns the field index keys of a request log, including
+// those of its response log.
+func reqLogIndexKeys(reqLog reqlog.RequestLog) [][]byte {
+	keys := requestIndexKeys(reqLog)
+
+	if reqLog.Response != nil {
+		keys = append(keys, resLogIndexKeys(reqLog.ProjectID, reqLog.ID, *reqLog.Response)...)
+	}
+
+	return keys
+}
+
+// requestIndexKeys returns the field index keys of a request log, without
+// those of its response log.
+func requestIndexKeys(reqLog reqlog.RequestLog) [][]byte {
+	keys := make([][]byte, 0, 3)
+
+	if hostname := reqLog.Hostname(); hostname != "" {
+		keys = append(keys, indexKey(reqLogHostIndexPrefix(reqLog.ProjectID, hostname), reqLog.ID))
+	}
+
+	if reqLog.Method != "" {
+		keys = append(keys, indexKey(reqLogMethodIndexPrefix(reqLog.ProjectID, reqLog.Method), reqLog.ID))
+	}
+
+	if reqLog.RedirectFromID.Compare(ulid.ULID{}) != 0 {
+		keys = append(keys, indexKey(reqLogRedirectIndexPrefix(reqLog.ProjectID, reqLog.RedirectFromID), reqLog.ID))
+	}
+
+	return keys
+}
+
+// resLogIndexKeys returns the field index keys of the response log of a
+// request log.
+func resLogIndexKeys(projectID, reqLogID ulid.ULID, resLog reqlog.ResponseLog) [][]byte {
+	keys := make([][]byte, 0, 2)
+
+	if resLog.StatusCode != 0 {
+		keys = append(keys, indexKey(reqLogStatusCodeIndexPrefix(projectID, resLog.StatusCode), reqLogID))
+	}
+
+	if mediaType := resLog.MediaType(); mediaType != "" {
+		keys = append(keys, indexKey(reqLogContentTypeIndexPrefix(projectID, mediaType), reqLogID))
+	}
+
+	return keys
+}
+
+func indexEntries(keys [][]byte) []*badger.Entry {
+	entries := make([]*badger.Entry, len(keys))
+	for i, key := range keys {
+		entries[i] = &badger.Entry{Key: key}
+	}
+
+	return entries
+}
+
+// requestLogProjectID returns the project ID of a request log, which can be
+// buffered for a batched write. It returns false if there's no request log
+// for the ID, e.g. for sender requests, which also have response logs.
+func (db *Database) requestLogProjectID(reqLogID ulid.ULID) (ulid.ULID, bool, error) {
+	if wb := db.writeBuffer; wb != nil {
+		if projectID, ok := wb.projectID(reqLogID); ok {
+			return projectID, true, nil
+		}
+	}
+
+	var projectID ulid.ULID
+
+	err := db.badger.View(func(txn *badger.Txn) error {
+		loader, err := newReqLogLoader(txn, reqLogID)
+		if err != nil {
+			return err
+		}
+
+		projectID = loader.reqLog.ProjectID
+
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, reqlog.ErrRequestNotFound):
+		return ulid.ULID{}, false, nil
+	case err != nil:
+		return ulid.ULID{}, false, err
+	}
+
+	return projectID, true, nil
+}
+
+// findRequestLogIDs returns the IDs of the request logs of the project of the
+// filter, by the field indexes for fields that are set. Only IDs in the time
+// range of the filter are read, because the IDs of both the project ID and
+// field index items are ordered by time.
+func findRequestLogIDs(txn *badger.Txn, filter reqlog.FindRequestsFilter) ([]ulid.ULID, error) {
+	since, until := filter.TimeRange(time.Now())
+
+	if !filter.HasFields() {
+		return findRequestLogIDsByIndexPrefix(txn, entryKey(reqLogPrefix, reqLogProjectIDIndex, filter.ProjectID[:]), since, until)
+	}
+
+	prefixes := make([][]byte, 0, 3)
+
+	if filter.Host != "" {
+		prefixes = append(prefixes, reqLogHostIndexPrefix(filter.ProjectID, strings.ToLower(filter.Host)))
+	}
+
+	if filter.StatusCode != 0 {
+		prefixes = append(prefixes, reqLogStatusCodeIndexPrefix(filter.ProjectID, filter.StatusCode))
+	}
+
+	if filter.ContentType != "" {
+		prefixes = append(prefixes, reqLogContentTypeIndexPrefix(filter.ProjectID, strings.ToLower(filter.ContentType)))
+	}
+
+	var reqLogIDs []ulid.ULID
+
+	for i, prefix := range prefixes {
+		ids, err := findRequestLogIDsByIndexPrefix(txn, prefix, since, until)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			reqLogIDs = ids
+			continue
+		}
+
+		reqLogIDs = intersectIDs(reqLogIDs, ids)
+	}
+
+	return reqLogIDs, nil
+}
+
+// findRequestLogIDsByIndexPrefix returns the IDs of the index items with
+// prefix, that are at or after since and before until. Zero times are
+// unbounded.
+func findRequestLogIDsByIndexPrefix(txn *badger.Txn, prefix []byte, since, until time.Time) ([]ulid.ULID, error) {
+	reqLogIDs := make([]ulid.ULID, 0)
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	iterator := txn.NewIterator(opts)
+	defer iterator.Close()
+
+	seekKey := prefix
+
+	if !since.IsZero() {
+		// The smallest ID of the millisecond of since.
+		var sinceID ulid.ULID
+		if err := sinceID.SetTime(ulid.Timestamp(since)); err != nil {
+			return nil, fmt.Errorf("failed to set time of ID: %w", err)
+		}
+
+		seekKey = indexKey(prefix, sinceID)
+	}
+
+	for iterator.Seek(seekKey); iterator.ValidForPrefix(prefix); iterator.Next() {
+		key := iterator.Item().Key()
+
+		var id ulid.ULID
+		// The request log ID is the last 16 bytes of the key.
+		if err := id.UnmarshalBinary(key[len(key)-len(id):]); err != nil {
+			return nil, fmt.Errorf("failed to parse request log ID: %w", err)
+		}
+
+		if !until.IsZero() && !ulid.Time(id.Time()).Before(until) {
+			break
+		}
+
+		if !reqlog.InTimeRange(id, since, until) {
+			continue
+		}
+
+		reqLogIDs = append(reqLogIDs, id)
+	}
+
+	return reqLogIDs, nil
+}
+
+// intersectIDs returns the IDs of a that are also in b, in the order of a.
+func intersectIDs(a, b []ulid.ULID) []ulid.ULID {
+	inB := make(map[ulid.ULID]struct{}, len(b))
+	for _, id := range b {
+		inB[id] = struct{}{}
+	}
+
+	ids := make([]ulid.ULID, 0, len(a))
+
+	for _, id := range a {
+		if _, ok := inB[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// RebuildRequestLogIndexes rewrites the host, status code, content type,
+// method and redirect indexes of the request logs of a project, e.g. for projects that were
+// created before these were indexed. It returns the number of request logs.
+func (db *Database) RebuildRequestLogIndexes(ctx context.Context, projectID ulid.ULID) (int, error) {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return 0, reqlog.ErrProjectIDMustBeSet
+	}
+
+	if err := db.flushWrites(); err != nil {
+		return 0, err
+	}
+
+	if err := db.dropRequestLogIndexes(projectID); err != nil {
+		return 0, err
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	reqLogIDs, err := findRequestLogIDsByProjectID(txn, projectID)
+	if err != nil {
+		return 0, storageError("badger: failed to find request log IDs: %w", err)
+	}
+
+	writeBatch := db.badger.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	for _, reqLogID := range reqLogIDs {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		loader, err := newReqLogLoader(txn, reqLogID)
+		if err != nil {
+			return 0, storageError("badger: failed to get request log (id: %v): %w", reqLogID.String(), err)
+		}
+
+		if err := loader.load(reqlog.SearchExprFields{Response: true}); err != nil {
+			return 0, storageError("badger: failed to get request log (id: %v): %w", reqLogID.String(), err)
+		}
+
+		for _, key := range reqLogIndexKeys(loader.reqLog) {
+			if err := writeBatch.Set(key, nil); err != nil {
+				return 0, storageError("badger: failed to set index item: %w", err)
+			}
+		}
+	}
+
+	if err := writeBatch.Flush(); err != nil {
+		return 0, storageError("badger: failed to commit batch write: %w", err)
+	}
+
+	return len(reqLogIDs), nil
+}
+
+func (db *Database) dropRequestLogIndexes(projectID ulid.ULID) error {
+	for _, index := range reqLogFieldIndexes {
+		if err := db.badger.DropPrefix(entryKey(reqLogPrefix, index, projectID[:])); err != nil {
+			return storageError("badger: failed to drop request log index items: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// FindRequestLogFieldValues returns the observed hostnames, methods, status
+// codes and media types of the request logs of a project, by reading the keys
+// of the field indexes.
+func (db *Database) FindRequestLogFieldValues(ctx context.Context, projectID ulid.ULID) (reqlog.FieldValues, error) {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return reqlog.FieldValues{}, reqlog.ErrProjectIDMustBeSet
+	}
+
+	if err := db.flushWrites(); err != nil {
+		return reqlog.FieldValues{}, err
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	var (
+		values reqlog.FieldValues
+		err    error
+	)
+
+	for _, index := range []struct {
+		index byte
+		dst   *[]reqlog.FieldValue
+	}{
+		{reqLogHostIndex, &values.Hosts},
+		{reqLogMethodIndex, &values.Methods},
+		{reqLogStatusCodeIndex, &values.StatusCodes},
+		{reqLogContentTypeIndex, &values.ContentTypes},
+	} {
+		if err := ctx.Err(); err != nil {
+			return reqlog.FieldValues{}, err
+		}
+
+		*index.dst, err = findIndexValues(txn, index.index, projectID)
+		if err != nil {
+			return reqlog.FieldValues{}, storageError("badger: failed to find request log field values: %w", err)
+		}
+	}
+
+	return values, nil
+}
+
+// findIndexValues returns the values of a field index of a project, with the
+// number of index items per value.
+func findIndexValues(txn *badger.Txn, index byte, projectID ulid.ULID) ([]reqlog.FieldValue, error) {
+	prefix := entryKey(reqLogPrefix, index, projectID[:])
+	counts := make(map[string]int)
+	values := make([]reqlog.FieldValue, 0)
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	iterator := txn.NewIterator(opts)
+	defer iterator.Close()
+
+	for iterator.Seek(prefix); iterator.ValidForPrefix(prefix); iterator.Next() {
+		key := iterator.Item().Key()
+
+		// The value is between the project ID and the request log ID.
+		if len(key) < len(prefix)+len(ulid.ULID{}) {
+			return nil, fmt.Errorf("invalid index key: %x", key)
+		}
+
+		value := key[len(prefix) : len(key)-len(ulid.ULID{})]
+
+		var v string
+
+		if index == reqLogStatusCodeIndex {
+			if len(value) != 2 {
+				return nil, fmt.Errorf("invalid status code index key: %x", key)
+			}
+
+			v = strconv.Itoa(int(binary.BigEndian.Uint16(value)))
+		} else {
+			v = strings.TrimSuffix(string(value), "\x00")
+		}
+
+		if _, ok := counts[v]; !ok {
+			values = append(values, reqlog.FieldValue{Value: v})
+		}
+
+		counts[v]++
+	}
+
+	for i := range values {
+		values[i].Count = counts[values[i].Value]
+	}
+
+	return values, nil
+}
+
+// FindRedirectedRequestLogIDs returns the IDs of the request logs of a project
+// that were redirected from the request log with the given ID, in ascending
+// order.
+func (db *Database) FindRedirectedRequestLogIDs(ctx context.Context, projectID, redirectFromID ulid.ULID) ([]ulid.ULID, error) {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, reqlog.ErrProjectIDMustBeSet
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, storageError("badger: failed to find redirected request logs: %w", err)
+	}
+
+	if err := db.flushWrites(); err != nil {
+		return nil, err
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	prefix := reqLogRedirectIndexPrefix(projectID, redirectFromID)
+	ids := make([]ulid.ULID, 0)
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	iterator := txn.NewIterator(opts)
+	defer iterator.Close()
+
+	for iterator.Seek(prefix); iterator.ValidForPrefix(prefix); iterator.Next() {
+		key := iterator.Item().Key()
+
+		if len(key) != len(prefix)+len(ulid.ULID{}) {
+			return nil, storageError("badger: invalid redirect index key: %x", key)
+		}
+
+		var id ulid.ULID
+
+		copy(id[:], key[len(prefix):])
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,346 @@
+package badger
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/search"
+)
+
+func indexFixtures(t *testing.T, projectID ulid.ULID) []reqlog.RequestLog {
+	t.Helper()
+
+	now := ulid.Timestamp(time.Now())
+
+	return []reqlog.RequestLog{
+		{
+			ID:        ulid.MustNew(now, ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/api"),
+			Method:    http.MethodGet,
+			Response: &reqlog.ResponseLog{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+			},
+		},
+		{
+			ID:        ulid.MustNew(now+1, ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://EXAMPLE.com:8443/"),
+			Method:    http.MethodGet,
+			Response: &reqlog.ResponseLog{
+				StatusCode: 404,
+				Header:     http.Header{"Content-Type": []string{"text/html"}},
+			},
+		},
+		{
+			ID:        ulid.MustNew(now+2, ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.org/api"),
+			Method:    http.MethodPost,
+			Response: &reqlog.ResponseLog{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+			},
+		},
+		{
+			ID:        ulid.MustNew(now+3, ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/pending"),
+			Method:    http.MethodGet,
+		},
+	}
+}
+
+func storeFixtures(t *testing.T, database *Database, reqLogs []reqlog.RequestLog) {
+	t.Helper()
+
+	for _, reqLog := range reqLogs {
+		if err := database.StoreRequestLog(context.Background(), reqLog); err != nil {
+			t.Fatalf("unexpected error creating request log fixture: %v", err)
+		}
+
+		if reqLog.Response != nil {
+			if err := database.StoreResponseLog(context.Background(), reqLog.ID, *reqLog.Response); err != nil {
+				t.Fatalf("unexpected error creating response log fixture: %v", err)
+			}
+		}
+	}
+}
+
+func assertFoundIDs(t *testing.T, database *Database, filter reqlog.FindRequestsFilter, exp []ulid.ULID) {
+	t.Helper()
+
+	got, err := database.FindRequestLogs(context.Background(), filter, nil)
+	if err != nil {
+		t.Fatalf("unexpected error finding request logs: %v", err)
+	}
+
+	gotIDs := make([]ulid.ULID, len(got))
+	for i, reqLog := range got {
+		gotIDs[i] = reqLog.ID
+	}
+
+	if diff := cmp.Diff(exp, gotIDs); diff != "" {
+		t.Fatalf("request log IDs not equal for filter %+v (-exp, +got):\n%v", filter, diff)
+	}
+}
+
+func TestFindRequestLogsByIndexedFields(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		batch bool
+	}{
+		{name: "without batched writes"},
+		{name: "with batched writes", batch: true},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+			if err != nil {
+				t.Fatalf("failed to open badger database: %v", err)
+			}
+			defer database.Close()
+
+			if tc.batch {
+				database.BatchWrites(BatchConfig{Size: 100, Interval: time.Hour})
+			}
+
+			projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+			reqLogs := indexFixtures(t, projectID)
+			storeFixtures(t, database, reqLogs)
+
+			tests := []struct {
+				filter reqlog.FindRequestsFilter
+				exp    []ulid.ULID
+			}{
+				{
+					filter: reqlog.FindRequestsFilter{Host: "Example.com"},
+					exp:    []ulid.ULID{reqLogs[0].ID, reqLogs[1].ID, reqLogs[3].ID},
+				},
+				{
+					filter: reqlog.FindRequestsFilter{StatusCode: 200},
+					exp:    []ulid.ULID{reqLogs[0].ID, reqLogs[2].ID},
+				},
+				{
+					filter: reqlog.FindRequestsFilter{ContentType: "application/json"},
+					exp:    []ulid.ULID{reqLogs[0].ID, reqLogs[2].ID},
+				},
+				{
+					filter: reqlog.FindRequestsFilter{Host: "example.com", StatusCode: 200, ContentType: "application/json"},
+					exp:    []ulid.ULID{reqLogs[0].ID},
+				},
+				{
+					filter: reqlog.FindRequestsFilter{Host: "example.net"},
+					exp:    []ulid.ULID{},
+				},
+				{
+					filter: reqlog.FindRequestsFilter{Since: idTime(reqLogs[1].ID), Until: idTime(reqLogs[3].ID)},
+					exp:    []ulid.ULID{reqLogs[1].ID, reqLogs[2].ID},
+				},
+				{
+					filter: reqlog.FindRequestsFilter{Host: "example.com", Since: idTime(reqLogs[1].ID)},
+					exp:    []ulid.ULID{reqLogs[1].ID, reqLogs[3].ID},
+				},
+				{
+					filter: reqlog.FindRequestsFilter{SearchExpr: search.LastExpression{Duration: time.Hour}},
+					exp:    []ulid.ULID{reqLogs[0].ID, reqLogs[1].ID, reqLogs[2].ID, reqLogs[3].ID},
+				},
+				{
+					filter: reqlog.FindRequestsFilter{Until: idTime(reqLogs[0].ID)},
+					exp:    []ulid.ULID{},
+				},
+			}
+
+			for _, tt := range tests {
+				tt.filter.ProjectID = projectID
+				assertFoundIDs(t, database, tt.filter, tt.exp)
+			}
+
+			// Index items are removed with the request logs.
+			if err := database.DeleteRequestLogs(context.Background(), projectID, []ulid.ULID{reqLogs[0].ID}); err != nil {
+				t.Fatalf("unexpected error deleting request logs: %v", err)
+			}
+
+			assertFoundIDs(t, database, reqlog.FindRequestsFilter{
+				ProjectID:  projectID,
+				StatusCode: 200,
+			}, []ulid.ULID{reqLogs[2].ID})
+
+			if err := database.ClearRequestLogs(context.Background(), projectID); err != nil {
+				t.Fatalf("unexpected error clearing request logs: %v", err)
+			}
+
+			for _, index := range reqLogFieldIndexes {
+				if n := countKeys(t, database, entryKey(reqLogPrefix, index, projectID[:])); n != 0 {
+					t.Fatalf("expected no index items for index %v, got: %v", index, n)
+				}
+			}
+		})
+	}
+}
+
+func idTime(id ulid.ULID) time.Time {
+	return ulid.Time(id.Time())
+}
+
+func TestFindRequestLogFieldValues(t *testing.T) {
+	t.Parallel()
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	storeFixtures(t, database, indexFixtures(t, projectID))
+
+	got, err := database.FindRequestLogFieldValues(context.Background(), projectID)
+	if err != nil {
+		t.Fatalf("unexpected error finding field values: %v", err)
+	}
+
+	exp := reqlog.FieldValues{
+		Hosts:        []reqlog.FieldValue{{Value: "example.com", Count: 3}, {Value: "example.org", Count: 1}},
+		Methods:      []reqlog.FieldValue{{Value: http.MethodGet, Count: 3}, {Value: http.MethodPost, Count: 1}},
+		StatusCodes:  []reqlog.FieldValue{{Value: "200", Count: 2}, {Value: "404", Count: 1}},
+		ContentTypes: []reqlog.FieldValue{{Value: "application/json", Count: 2}, {Value: "text/html", Count: 1}},
+	}
+
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("field values not equal (-exp, +got):\n%v", diff)
+	}
+
+	// Other projects have no values.
+	otherID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	got, err = database.FindRequestLogFieldValues(context.Background(), otherID)
+	if err != nil {
+		t.Fatalf("unexpected error finding field values: %v", err)
+	}
+
+	if len(got.Hosts) != 0 || len(got.Methods) != 0 || len(got.StatusCodes) != 0 || len(got.ContentTypes) != 0 {
+		t.Fatalf("expected no field values, got: %+v", got)
+	}
+}
+
+func TestFindRedirectedRequestLogIDs(t *testing.T) {
+	t.Parallel()
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	fixtures := indexFixtures(t, projectID)
+	fixtures[1].RedirectFromID = fixtures[0].ID
+	fixtures[3].RedirectFromID = fixtures[1].ID
+	storeFixtures(t, database, fixtures)
+
+	tests := []struct {
+		name           string
+		redirectFromID ulid.ULID
+		expIDs         []ulid.ULID
+	}{
+		{name: "redirected request log", redirectFromID: fixtures[0].ID, expIDs: []ulid.ULID{fixtures[1].ID}},
+		{name: "next request log in chain", redirectFromID: fixtures[1].ID, expIDs: []ulid.ULID{fixtures[3].ID}},
+		{name: "end of chain", redirectFromID: fixtures[3].ID, expIDs: []ulid.ULID{}},
+	}
+
+	for _, tt := range tests {
+		got, err := database.FindRedirectedRequestLogIDs(context.Background(), projectID, tt.redirectFromID)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.name, err)
+		}
+
+		if diff := cmp.Diff(tt.expIDs, got); diff != "" {
+			t.Fatalf("%v: IDs not equal (-exp, +got):\n%v", tt.name, diff)
+		}
+	}
+
+	// Deleted request logs are no longer found.
+	if err := database.DeleteRequestLogs(context.Background(), projectID, []ulid.ULID{fixtures[1].ID}); err != nil {
+		t.Fatalf("unexpected error deleting request logs: %v", err)
+	}
+
+	got, err := database.FindRedirectedRequestLogIDs(context.Background(), projectID, fixtures[0].ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 0 {
+		t.Fatalf("expected no IDs, got: %v", got)
+	}
+}
+
+func TestRebuildRequestLogIndexes(t *testing.T) {
+	t.Parallel()
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	reqLogs := indexFixtures(t, projectID)
+	storeFixtures(t, database, reqLogs)
+
+	// Projects created before the field indexes existed have no index items.
+	if err := database.dropRequestLogIndexes(projectID); err != nil {
+		t.Fatalf("unexpected error dropping indexes: %v", err)
+	}
+
+	filter := reqlog.FindRequestsFilter{ProjectID: projectID, StatusCode: 404}
+	assertFoundIDs(t, database, filter, []ulid.ULID{})
+
+	n, err := database.RebuildRequestLogIndexes(context.Background(), projectID)
+	if err != nil {
+		t.Fatalf("unexpected error rebuilding indexes: %v", err)
+	}
+
+	if n != len(reqLogs) {
+		t.Fatalf("expected %v indexed request logs, got: %v", len(reqLogs), n)
+	}
+
+	assertFoundIDs(t, database, filter, []ulid.ULID{reqLogs[1].ID})
+}
+
+func countKeys(t *testing.T, database *Database, prefix []byte) int {
+	t.Helper()
+
+	var n int
+
+	err := database.badger.View(func(txn *badgerdb.Txn) error {
+		opts := badgerdb.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		iterator := txn.NewIterator(opts)
+		defer iterator.Close()
+
+		for iterator.Seek(prefix); iterator.ValidForPrefix(prefix); iterator.Next() {
+			n++
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to count keys: %v", err)
+	}
+
+	return n
+}
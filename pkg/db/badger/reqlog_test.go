@@ -1,6 +1,7 @@
 package badger
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"net/http"
@@ -12,7 +13,9 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/oklog/ulid"
 
+	"github.com/dstotijn/hetty/pkg/errcode"
 	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/search"
 )
 
 func TestFindRequestLogs(t *testing.T) {
@@ -35,6 +38,39 @@ func TestFindRequestLogs(t *testing.T) {
 		}
 	})
 
+	t.Run("with done context", func(t *testing.T) {
+		t.Parallel()
+
+		database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+		if err != nil {
+			t.Fatalf("failed to open badger database: %v", err)
+		}
+		defer database.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		reqLog := reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		}
+
+		err = database.StoreRequestLog(ctx, reqLog)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected `context.Canceled` storing request log, got: %v", err)
+		}
+
+		if code := errcode.Of(err); code != errcode.Storage {
+			t.Fatalf("expected error code %q, got: %q", errcode.Storage, code)
+		}
+
+		filter := reqlog.FindRequestsFilter{ProjectID: reqLog.ProjectID}
+
+		if _, err := database.FindRequestLogs(ctx, filter, nil); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected `context.Canceled` finding request logs, got: %v", err)
+		}
+	})
+
 	t.Run("returns request logs and related response logs", func(t *testing.T) {
 		t.Parallel()
 
@@ -107,6 +143,255 @@ func TestFindRequestLogs(t *testing.T) {
 			t.Fatalf("request logs not equal (-exp, +got):\n%v", diff)
 		}
 	})
+
+	t.Run("collapses redirect chains", func(t *testing.T) {
+		t.Parallel()
+
+		database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+		if err != nil {
+			t.Fatalf("failed to open badger database: %v", err)
+		}
+		defer database.Close()
+
+		projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+		first := reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/login"),
+			Method:    http.MethodPost,
+		}
+		hop := reqlog.RequestLog{
+			ID:             ulid.MustNew(ulid.Timestamp(time.Now())+100, ulidEntropy),
+			ProjectID:      projectID,
+			URL:            mustParseURL(t, "https://example.com/dashboard"),
+			Method:         http.MethodGet,
+			RedirectFromID: first.ID,
+		}
+
+		for _, reqLog := range []reqlog.RequestLog{first, hop} {
+			err = database.StoreRequestLog(context.Background(), reqLog)
+			if err != nil {
+				t.Fatalf("unexpected error creating request log fixture: %v", err)
+			}
+		}
+
+		filter := reqlog.FindRequestsFilter{
+			ProjectID:         projectID,
+			CollapseRedirects: true,
+		}
+
+		got, err := database.FindRequestLogs(context.Background(), filter, nil)
+		if err != nil {
+			t.Fatalf("unexpected error finding request logs: %v", err)
+		}
+
+		if diff := cmp.Diff([]reqlog.RequestLog{first}, got); diff != "" {
+			t.Fatalf("request logs not equal (-exp, +got):\n%v", diff)
+		}
+	})
+
+	t.Run("collapses and filters by page loads", func(t *testing.T) {
+		t.Parallel()
+
+		database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+		if err != nil {
+			t.Fatalf("failed to open badger database: %v", err)
+		}
+		defer database.Close()
+
+		projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+		navID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+		nav := reqlog.RequestLog{
+			ID:         navID,
+			ProjectID:  projectID,
+			URL:        mustParseURL(t, "https://example.com/"),
+			Method:     http.MethodGet,
+			PageLoadID: navID,
+		}
+		image := reqlog.RequestLog{
+			ID:         ulid.MustNew(ulid.Timestamp(time.Now())+100, ulidEntropy),
+			ProjectID:  projectID,
+			URL:        mustParseURL(t, "https://example.com/logo.png"),
+			Method:     http.MethodGet,
+			PageLoadID: navID,
+		}
+		other := reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now())+200, ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/api"),
+			Method:    http.MethodGet,
+		}
+
+		for _, reqLog := range []reqlog.RequestLog{nav, image, other} {
+			err = database.StoreRequestLog(context.Background(), reqLog)
+			if err != nil {
+				t.Fatalf("unexpected error creating request log fixture: %v", err)
+			}
+		}
+
+		got, err := database.FindRequestLogs(context.Background(), reqlog.FindRequestsFilter{
+			ProjectID:         projectID,
+			CollapsePageLoads: true,
+		}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error finding request logs: %v", err)
+		}
+
+		if diff := cmp.Diff([]reqlog.RequestLog{nav, other}, got); diff != "" {
+			t.Fatalf("collapsed request logs not equal (-exp, +got):\n%v", diff)
+		}
+
+		got, err = database.FindRequestLogs(context.Background(), reqlog.FindRequestsFilter{
+			ProjectID:  projectID,
+			PageLoadID: navID,
+		}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error finding request logs: %v", err)
+		}
+
+		if diff := cmp.Diff([]reqlog.RequestLog{nav, image}, got); diff != "" {
+			t.Fatalf("request logs of page load not equal (-exp, +got):\n%v", diff)
+		}
+	})
+
+	t.Run("filters by search expression on stored bodies and responses", func(t *testing.T) {
+		t.Parallel()
+
+		database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+		if err != nil {
+			t.Fatalf("failed to open badger database: %v", err)
+		}
+		defer database.Close()
+
+		projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+		// Large enough to be stored by hash, rather than inline.
+		body := bytes.Repeat([]byte("foo"), minDedupBodySize)
+
+		match := reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/foo"),
+			Method:    http.MethodGet,
+			Body:      body,
+			Response: &reqlog.ResponseLog{
+				StatusCode: 200,
+				Body:       append(body, []byte("bar")...),
+			},
+		}
+		noMatch := reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now())+100, ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/bar"),
+			Method:    http.MethodGet,
+			Body:      body,
+			Response: &reqlog.ResponseLog{
+				StatusCode: 404,
+				Body:       body,
+			},
+		}
+
+		for _, reqLog := range []reqlog.RequestLog{match, noMatch} {
+			if err := database.StoreRequestLog(context.Background(), reqLog); err != nil {
+				t.Fatalf("unexpected error creating request log fixture: %v", err)
+			}
+
+			if err := database.StoreResponseLog(context.Background(), reqLog.ID, *reqLog.Response); err != nil {
+				t.Fatalf("unexpected error creating response log fixture: %v", err)
+			}
+		}
+
+		for _, query := range []string{"res.statusCode = 200", "res.body =~ bar$", "req.body =~ foo AND NOT (req.url =~ bar)"} {
+			searchExpr, err := search.ParseQuery(query)
+			if err != nil {
+				t.Fatalf("unexpected error parsing query: %v", err)
+			}
+
+			filter := reqlog.FindRequestsFilter{
+				ProjectID:  projectID,
+				SearchExpr: searchExpr,
+			}
+
+			got, err := database.FindRequestLogs(context.Background(), filter, nil)
+			if err != nil {
+				t.Fatalf("unexpected error finding request logs: %v", err)
+			}
+
+			if diff := cmp.Diff([]reqlog.RequestLog{match}, got); diff != "" {
+				t.Fatalf("request logs not equal for query %q (-exp, +got):\n%v", query, diff)
+			}
+		}
+	})
+}
+
+func TestDeleteRequestLogs(t *testing.T) {
+	t.Parallel()
+
+	database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	reqLogs := []reqlog.RequestLog{
+		{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/foo"),
+			Method:    http.MethodGet,
+			Response: &reqlog.ResponseLog{
+				Proto:      "HTTP/1.1",
+				Status:     "200 OK",
+				StatusCode: 200,
+				Body:       []byte("foo"),
+			},
+		},
+		{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now())+100, ulidEntropy),
+			ProjectID: projectID,
+			URL:       mustParseURL(t, "https://example.com/bar"),
+			Method:    http.MethodGet,
+		},
+	}
+
+	for _, reqLog := range reqLogs {
+		if err := database.StoreRequestLog(context.Background(), reqLog); err != nil {
+			t.Fatalf("unexpected error creating request log fixture: %v", err)
+		}
+
+		if reqLog.Response != nil {
+			err := database.StoreResponseLog(context.Background(), reqLog.ID, *reqLog.Response)
+			if err != nil {
+				t.Fatalf("unexpected error creating response log fixture: %v", err)
+			}
+		}
+	}
+
+	err = database.DeleteRequestLogs(context.Background(), projectID, []ulid.ULID{reqLogs[0].ID})
+	if err != nil {
+		t.Fatalf("unexpected error deleting request logs: %v", err)
+	}
+
+	_, err = database.FindRequestLogByID(context.Background(), reqLogs[0].ID)
+	if !errors.Is(err, reqlog.ErrRequestNotFound) {
+		t.Fatalf("expected `reqlog.ErrRequestNotFound`, got: %v", err)
+	}
+
+	if code := errcode.Of(err); code != errcode.NotFound {
+		t.Fatalf("expected error code %q, got: %q", errcode.NotFound, code)
+	}
+
+	got, err := database.FindRequestLogs(context.Background(), reqlog.FindRequestsFilter{ProjectID: projectID}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error finding request logs: %v", err)
+	}
+
+	if diff := cmp.Diff(reqLogs[1:], got); diff != "" {
+		t.Fatalf("request logs not equal (-exp, +got):\n%v", diff)
+	}
 }
 
 func mustParseURL(t *testing.T, s string) *url.URL {
@@ -0,0 +1,196 @@
+package badger
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// SchemaVersion is the version of the key layout and encoding of stored items.
+// Databases that were created before schema versioning have version 1. When
+// changing how items are stored, increment it and add a migration.
+const SchemaVersion = 3
+
+var (
+	ErrSchemaTooNew            = errors.New("badger: database was created by a newer version of Hetty")
+	ErrSchemaMigrationRequired = errors.New("badger: database must be migrated, which isn't possible in read-only mode")
+)
+
+var schemaVersionKey = entryKey(metaPrefix, 0, []byte("schemaVersion"))
+
+type migration struct {
+	// Schema version after the migration.
+	version     int
+	description string
+	// Migrations can be interrupted before the new schema version is stored,
+	// so they must be idempotent.
+	migrate func(db *Database) error
+}
+
+var migrations = []migration{
+	{
+		version:     2,
+		description: "index request logs by host, status code and content type",
+		migrate:     migrateRequestLogIndexes,
+	},
+	{
+		version:     3,
+		description: "index request logs by method and redirect source",
+		migrate:     migrateRequestLogIndexes,
+	},
+}
+
+// migrate runs the migrations for the schema version of the database. New
+// databases are created with the current version.
+func (db *Database) migrate() error {
+	version, ok, err := db.schemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		empty, err := db.isEmpty()
+		if err != nil {
+			return err
+		}
+
+		if empty {
+			if db.badger.Opts().ReadOnly {
+				return nil
+			}
+
+			return db.setSchemaVersion(SchemaVersion)
+		}
+
+		version = 1
+	}
+
+	if version > SchemaVersion {
+		return fmt.Errorf("%w (schema version: %v, supported: %v); upgrade Hetty to open it",
+			ErrSchemaTooNew, version, SchemaVersion)
+	}
+
+	if version < SchemaVersion && db.badger.Opts().ReadOnly {
+		return fmt.Errorf("%w (schema version: %v)", ErrSchemaMigrationRequired, version)
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+
+		log.Printf("[INFO] Migrating database to schema version %v: %v ...", m.version, m.description)
+
+		if err := m.migrate(db); err != nil {
+			return storageError("badger: failed to migrate database to schema version %v: %w", m.version, err)
+		}
+
+		if err := db.setSchemaVersion(m.version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *Database) schemaVersion() (version int, ok bool, err error) {
+	err = db.badger.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(schemaVersionKey)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			if len(val) != 4 {
+				return fmt.Errorf("invalid schema version value: %x", val)
+			}
+
+			version, ok = int(binary.BigEndian.Uint32(val)), true
+
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, false, storageError("badger: failed to get schema version: %w", err)
+	}
+
+	return version, ok, nil
+}
+
+func (db *Database) setSchemaVersion(version int) error {
+	val := make([]byte, 4)
+	binary.BigEndian.PutUint32(val, uint32(version))
+
+	err := db.badger.Update(func(txn *badger.Txn) error {
+		return txn.Set(schemaVersionKey, val)
+	})
+	if err != nil {
+		return storageError("badger: failed to set schema version: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) isEmpty() (bool, error) {
+	return db.isEmptyExcept(nil)
+}
+
+// isEmptyExceptMeta returns true if the database has no items other than meta
+// items, e.g. the schema version.
+func (db *Database) isEmptyExceptMeta() (bool, error) {
+	return db.isEmptyExcept([]byte{metaPrefix})
+}
+
+// isEmptyExcept returns true if there are no keys other than keys with the
+// given prefix, or no keys at all if prefix is nil.
+func (db *Database) isEmptyExcept(prefix []byte) (bool, error) {
+	empty := true
+
+	err := db.badger.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		iterator := txn.NewIterator(opts)
+		defer iterator.Close()
+
+		iterator.Rewind()
+
+		// Skip keys with the prefix, which can sort before other keys.
+		for prefix != nil && iterator.ValidForPrefix(prefix) {
+			iterator.Next()
+		}
+
+		empty = !iterator.Valid()
+
+		return nil
+	})
+	if err != nil {
+		return false, storageError("badger: failed to check if database is empty: %w", err)
+	}
+
+	return empty, nil
+}
+
+func migrateRequestLogIndexes(db *Database) error {
+	ctx := context.Background()
+
+	projects, err := db.Projects(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		if _, err := db.RebuildRequestLogIndexes(ctx, project.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package badger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v3"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sets current version for new database", func(t *testing.T) {
+		t.Parallel()
+
+		database, err := OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+		if err != nil {
+			t.Fatalf("failed to open badger database: %v", err)
+		}
+		defer database.Close()
+
+		version, ok, err := database.schemaVersion()
+		if err != nil {
+			t.Fatalf("unexpected error getting schema version: %v", err)
+		}
+
+		if !ok || version != SchemaVersion {
+			t.Fatalf("expected schema version %v, got: %v (ok: %v)", SchemaVersion, version, ok)
+		}
+	})
+
+	t.Run("refuses database of newer version", func(t *testing.T) {
+		t.Parallel()
+
+		opts := badgerdb.DefaultOptions(t.TempDir()).WithLogger(nil)
+
+		database, err := OpenDatabase(opts)
+		if err != nil {
+			t.Fatalf("failed to open badger database: %v", err)
+		}
+
+		if err := database.setSchemaVersion(SchemaVersion + 1); err != nil {
+			t.Fatalf("unexpected error setting schema version: %v", err)
+		}
+
+		database.Close()
+
+		_, err = OpenDatabase(opts)
+		if !errors.Is(err, ErrSchemaTooNew) {
+			t.Fatalf("expected `badger.ErrSchemaTooNew`, got: %v", err)
+		}
+	})
+
+	t.Run("migrates unversioned database", func(t *testing.T) {
+		t.Parallel()
+
+		opts := badgerdb.DefaultOptions(t.TempDir()).WithLogger(nil)
+		ctx := context.Background()
+
+		bdb, err := badgerdb.Open(opts)
+		if err != nil {
+			t.Fatalf("failed to open badger database: %v", err)
+		}
+
+		// A database of a version of Hetty without schema versioning and
+		// request log field indexes.
+		database := DatabaseFromBadgerDB(bdb)
+		project := proj.Project{ID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy), Name: "foobar"}
+		reqLog := reqlog.RequestLog{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID: project.ID,
+			URL:       mustParseURL(t, "https://example.com/"),
+			Method:    http.MethodGet,
+		}
+
+		if err := database.UpsertProject(ctx, project); err != nil {
+			t.Fatalf("unexpected error creating project fixture: %v", err)
+		}
+
+		if err := database.StoreRequestLog(ctx, reqLog); err != nil {
+			t.Fatalf("unexpected error creating request log fixture: %v", err)
+		}
+
+		if err := database.dropRequestLogIndexes(project.ID); err != nil {
+			t.Fatalf("unexpected error dropping indexes: %v", err)
+		}
+
+		bdb.Close()
+
+		database, err = OpenDatabase(opts)
+		if err != nil {
+			t.Fatalf("failed to open badger database: %v", err)
+		}
+		defer database.Close()
+
+		if version, _, _ := database.schemaVersion(); version != SchemaVersion {
+			t.Fatalf("expected schema version %v, got: %v", SchemaVersion, version)
+		}
+
+		assertFoundIDs(t, database, reqlog.FindRequestsFilter{
+			ProjectID: project.ID,
+			Host:      "example.com",
+		}, []ulid.ULID{reqLog.ID})
+	})
+}
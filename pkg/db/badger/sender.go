@@ -20,7 +20,7 @@ func (db *Database) StoreSenderRequest(ctx context.Context, req sender.Request)
 
 	err := gob.NewEncoder(&buf).Encode(req)
 	if err != nil {
-		return fmt.Errorf("badger: failed to encode sender request: %w", err)
+		return storageError("badger: failed to encode sender request: %w", err)
 	}
 
 	entries := []*badger.Entry{
@@ -45,19 +45,23 @@ func (db *Database) StoreSenderRequest(ctx context.Context, req sender.Request)
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("badger: failed to commit transaction: %w", err)
+		return storageError("badger: failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
 func (db *Database) FindSenderRequestByID(ctx context.Context, senderReqID ulid.ULID) (sender.Request, error) {
+	if err := db.flushWrites(); err != nil {
+		return sender.Request{}, err
+	}
+
 	txn := db.badger.NewTransaction(false)
 	defer txn.Discard()
 
 	req, err := getSenderRequestWithResponseLog(txn, senderReqID)
 	if err != nil {
-		return sender.Request{}, fmt.Errorf("badger: failed to get sender request: %w", err)
+		return sender.Request{}, storageError("badger: failed to get sender request: %w", err)
 	}
 
 	return req, nil
@@ -68,12 +72,16 @@ func (db *Database) FindSenderRequests(ctx context.Context, filter sender.FindRe
 		return nil, sender.ErrProjectIDMustBeSet
 	}
 
+	if err := db.flushWrites(); err != nil {
+		return nil, err
+	}
+
 	txn := db.badger.NewTransaction(false)
 	defer txn.Discard()
 
 	senderReqIDs, err := findSenderRequestIDsByProjectID(txn, filter.ProjectID)
 	if err != nil {
-		return nil, fmt.Errorf("badger: failed to find sender request IDs: %w", err)
+		return nil, storageError("badger: failed to find sender request IDs: %w", err)
 	}
 
 	senderReqs := make([]sender.Request, 0, len(senderReqIDs))
@@ -81,7 +89,7 @@ func (db *Database) FindSenderRequests(ctx context.Context, filter sender.FindRe
 	for _, id := range senderReqIDs {
 		senderReq, err := getSenderRequestWithResponseLog(txn, id)
 		if err != nil {
-			return nil, fmt.Errorf("badger: failed to get sender request (id: %v): %w", id.String(), err)
+			return nil, storageError("badger: failed to get sender request (id: %v): %w", id.String(), err)
 		}
 
 		if filter.OnlyInScope {
@@ -114,6 +122,10 @@ func (db *Database) FindSenderRequests(ctx context.Context, filter sender.FindRe
 }
 
 func (db *Database) DeleteSenderRequests(ctx context.Context, projectID ulid.ULID) error {
+	if err := db.flushWrites(); err != nil {
+		return err
+	}
+
 	// Note: this transaction is used just for reading; we use the `badger.WriteBatch`
 	// API to bulk delete items.
 	txn := db.badger.NewTransaction(false)
@@ -121,7 +133,7 @@ func (db *Database) DeleteSenderRequests(ctx context.Context, projectID ulid.ULI
 
 	senderReqIDs, err := findSenderRequestIDsByProjectID(txn, projectID)
 	if err != nil {
-		return fmt.Errorf("badger: failed to find sender request IDs: %w", err)
+		return storageError("badger: failed to find sender request IDs: %w", err)
 	}
 
 	writeBatch := db.badger.NewWriteBatch()
@@ -131,28 +143,325 @@ func (db *Database) DeleteSenderRequests(ctx context.Context, projectID ulid.ULI
 		// Delete sender requests.
 		err := writeBatch.Delete(entryKey(senderReqPrefix, 0, senderReqID[:]))
 		if err != nil {
-			return fmt.Errorf("badger: failed to delete sender requests: %w", err)
+			return storageError("badger: failed to delete sender requests: %w", err)
 		}
 
 		// Delete related response log.
 		err = writeBatch.Delete(entryKey(resLogPrefix, 0, senderReqID[:]))
 		if err != nil {
-			return fmt.Errorf("badger: failed to delete request log: %w", err)
+			return storageError("badger: failed to delete request log: %w", err)
 		}
 	}
 
 	if err := writeBatch.Flush(); err != nil {
-		return fmt.Errorf("badger: failed to commit batch write: %w", err)
+		return storageError("badger: failed to commit batch write: %w", err)
+	}
+
+	if err := db.releaseBodies(senderReqIDs); err != nil {
+		return storageError("badger: failed to release sender request response bodies: %w", err)
 	}
 
 	err = db.badger.DropPrefix(entryKey(senderReqPrefix, senderReqProjectIDIndex, projectID[:]))
 	if err != nil {
-		return fmt.Errorf("badger: failed to drop sender request project ID index items: %w", err)
+		return storageError("badger: failed to drop sender request project ID index items: %w", err)
+	}
+
+	if err := db.deleteSenderCollections(projectID); err != nil {
+		return storageError("badger: failed to delete sender collections: %w", err)
+	}
+
+	err = db.badger.DropPrefix(entryKey(senderReqPrefix, senderScheduleRunIndex, projectID[:]))
+	if err != nil {
+		return storageError("badger: failed to drop sender schedule runs: %w", err)
+	}
+
+	err = db.badger.DropPrefix(entryKey(senderReqPrefix, senderComparisonIndex, projectID[:]))
+	if err != nil {
+		return storageError("badger: failed to drop sender comparisons: %w", err)
 	}
 
 	return nil
 }
 
+func (db *Database) StoreSenderCollection(ctx context.Context, collection sender.Collection) error {
+	buf := bytes.Buffer{}
+
+	err := gob.NewEncoder(&buf).Encode(collection)
+	if err != nil {
+		return storageError("badger: failed to encode sender collection: %w", err)
+	}
+
+	err = db.badger.Update(func(txn *badger.Txn) error {
+		err := txn.Set(entryKey(senderReqPrefix, senderCollectionIndex, collection.ID[:]), buf.Bytes())
+		if err != nil {
+			return err
+		}
+
+		// Index by project ID.
+		return txn.Set(senderCollectionProjectIndexKey(collection.ProjectID, collection.ID), nil)
+	})
+	if err != nil {
+		return storageError("badger: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) FindSenderCollectionByID(ctx context.Context, id ulid.ULID) (sender.Collection, error) {
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	collection, err := getSenderCollection(txn, id)
+	if err != nil {
+		return sender.Collection{}, storageError("badger: failed to get sender collection: %w", err)
+	}
+
+	return collection, nil
+}
+
+func (db *Database) FindSenderCollections(ctx context.Context, projectID ulid.ULID) ([]sender.Collection, error) {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, sender.ErrProjectIDMustBeSet
+	}
+
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	ids, err := findSenderCollectionIDsByProjectID(txn, projectID)
+	if err != nil {
+		return nil, storageError("badger: failed to find sender collection IDs: %w", err)
+	}
+
+	collections := make([]sender.Collection, len(ids))
+
+	for i, id := range ids {
+		if collections[i], err = getSenderCollection(txn, id); err != nil {
+			return nil, storageError("badger: failed to get sender collection (id: %v): %w", id.String(), err)
+		}
+	}
+
+	return collections, nil
+}
+
+func (db *Database) DeleteSenderCollection(ctx context.Context, id ulid.ULID) error {
+	err := db.badger.Update(func(txn *badger.Txn) error {
+		collection, err := getSenderCollection(txn, id)
+		if err != nil {
+			return err
+		}
+
+		if err := txn.Delete(entryKey(senderReqPrefix, senderCollectionIndex, id[:])); err != nil {
+			return err
+		}
+
+		return txn.Delete(senderCollectionProjectIndexKey(collection.ProjectID, id))
+	})
+	if err != nil {
+		return storageError("badger: failed to delete sender collection: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) deleteSenderCollections(projectID ulid.ULID) error {
+	txn := db.badger.NewTransaction(false)
+	defer txn.Discard()
+
+	ids, err := findSenderCollectionIDsByProjectID(txn, projectID)
+	if err != nil {
+		return err
+	}
+
+	writeBatch := db.badger.NewWriteBatch()
+	defer writeBatch.Cancel()
+
+	for _, id := range ids {
+		if err := writeBatch.Delete(entryKey(senderReqPrefix, senderCollectionIndex, id[:])); err != nil {
+			return err
+		}
+
+		if err := writeBatch.Delete(senderCollectionProjectIndexKey(projectID, id)); err != nil {
+			return err
+		}
+	}
+
+	return writeBatch.Flush()
+}
+
+func senderCollectionProjectIndexKey(projectID, id ulid.ULID) []byte {
+	return entryKey(senderReqPrefix, senderCollectionProjectIDIndex, append(projectID[:], id[:]...))
+}
+
+func getSenderCollection(txn *badger.Txn, id ulid.ULID) (sender.Collection, error) {
+	item, err := txn.Get(entryKey(senderReqPrefix, senderCollectionIndex, id[:]))
+
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return sender.Collection{}, sender.ErrCollectionNotFound
+	case err != nil:
+		return sender.Collection{}, fmt.Errorf("failed to lookup sender collection item: %w", err)
+	}
+
+	var collection sender.Collection
+
+	err = item.Value(func(rawCollection []byte) error {
+		return gob.NewDecoder(bytes.NewReader(rawCollection)).Decode(&collection)
+	})
+	if err != nil {
+		return sender.Collection{}, fmt.Errorf("failed to decode sender collection: %w", err)
+	}
+
+	return collection, nil
+}
+
+// StoreSenderScheduleRun stores a schedule run. Runs are keyed by project ID
+// and run ID, so they're iterated in the order they ran.
+func (db *Database) StoreSenderScheduleRun(ctx context.Context, run sender.ScheduleRun) error {
+	buf := bytes.Buffer{}
+
+	err := gob.NewEncoder(&buf).Encode(run)
+	if err != nil {
+		return storageError("badger: failed to encode sender schedule run: %w", err)
+	}
+
+	err = db.badger.Update(func(txn *badger.Txn) error {
+		return txn.Set(senderScheduleRunKey(run.ProjectID, run.ID), buf.Bytes())
+	})
+	if err != nil {
+		return storageError("badger: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) FindSenderScheduleRuns(ctx context.Context, projectID ulid.ULID, name string) ([]sender.ScheduleRun, error) {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, sender.ErrProjectIDMustBeSet
+	}
+
+	runs := make([]sender.ScheduleRun, 0)
+
+	err := db.badger.View(func(txn *badger.Txn) error {
+		iterator := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iterator.Close()
+
+		prefix := entryKey(senderReqPrefix, senderScheduleRunIndex, projectID[:])
+
+		for iterator.Seek(prefix); iterator.ValidForPrefix(prefix); iterator.Next() {
+			var run sender.ScheduleRun
+
+			err := iterator.Item().Value(func(rawRun []byte) error {
+				return gob.NewDecoder(bytes.NewReader(rawRun)).Decode(&run)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to decode sender schedule run: %w", err)
+			}
+
+			if name == "" || run.Schedule == name {
+				runs = append(runs, run)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, storageError("badger: failed to find sender schedule runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+func senderScheduleRunKey(projectID, id ulid.ULID) []byte {
+	return entryKey(senderReqPrefix, senderScheduleRunIndex, append(projectID[:], id[:]...))
+}
+
+// StoreSenderComparison stores a comparison. Comparisons are keyed by project
+// ID and comparison ID, so they're iterated in the order they ran.
+func (db *Database) StoreSenderComparison(ctx context.Context, comparison sender.Comparison) error {
+	buf := bytes.Buffer{}
+
+	err := gob.NewEncoder(&buf).Encode(comparison)
+	if err != nil {
+		return storageError("badger: failed to encode sender comparison: %w", err)
+	}
+
+	err = db.badger.Update(func(txn *badger.Txn) error {
+		return txn.Set(senderComparisonKey(comparison.ProjectID, comparison.ID), buf.Bytes())
+	})
+	if err != nil {
+		return storageError("badger: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) FindSenderComparisons(
+	ctx context.Context,
+	projectID, requestID ulid.ULID,
+) ([]sender.Comparison, error) {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, sender.ErrProjectIDMustBeSet
+	}
+
+	comparisons := make([]sender.Comparison, 0)
+
+	err := db.badger.View(func(txn *badger.Txn) error {
+		iterator := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iterator.Close()
+
+		prefix := entryKey(senderReqPrefix, senderComparisonIndex, projectID[:])
+
+		for iterator.Seek(prefix); iterator.ValidForPrefix(prefix); iterator.Next() {
+			var comparison sender.Comparison
+
+			err := iterator.Item().Value(func(rawComparison []byte) error {
+				return gob.NewDecoder(bytes.NewReader(rawComparison)).Decode(&comparison)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to decode sender comparison: %w", err)
+			}
+
+			if requestID.Compare(ulid.ULID{}) == 0 || comparison.RequestID.Compare(requestID) == 0 {
+				comparisons = append(comparisons, comparison)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, storageError("badger: failed to find sender comparisons: %w", err)
+	}
+
+	return comparisons, nil
+}
+
+func senderComparisonKey(projectID, id ulid.ULID) []byte {
+	return entryKey(senderReqPrefix, senderComparisonIndex, append(projectID[:], id[:]...))
+}
+
+func findSenderCollectionIDsByProjectID(txn *badger.Txn, projectID ulid.ULID) ([]ulid.ULID, error) {
+	ids := make([]ulid.ULID, 0)
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	iterator := txn.NewIterator(opts)
+	defer iterator.Close()
+
+	prefix := entryKey(senderReqPrefix, senderCollectionProjectIDIndex, projectID[:])
+
+	for iterator.Seek(prefix); iterator.ValidForPrefix(prefix); iterator.Next() {
+		var id ulid.ULID
+		// The collection ID starts after the prefix and index bytes and the 16
+		// byte project ID.
+		if err := id.UnmarshalBinary(iterator.Item().Key()[18:]); err != nil {
+			return nil, fmt.Errorf("failed to parse sender collection ID: %w", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
 func getSenderRequestWithResponseLog(txn *badger.Txn, senderReqID ulid.ULID) (sender.Request, error) {
 	item, err := txn.Get(entryKey(senderReqPrefix, 0, senderReqID[:]))
 
@@ -204,6 +513,12 @@ func getSenderRequestWithResponseLog(txn *badger.Txn, senderReqID ulid.ULID) (se
 		return sender.Request{}, fmt.Errorf("failed to retrieve or parse response log value: %w", err)
 	}
 
+	if len(req.Response.Body) == 0 {
+		if req.Response.Body, err = getBody(txn, senderReqID, responseBody); err != nil {
+			return sender.Request{}, fmt.Errorf("failed to get response body: %w", err)
+		}
+	}
+
 	return req, nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
+	"sort"
 	"testing"
 	"time"
 
@@ -202,3 +203,77 @@ func TestFindSenderRequests(t *testing.T) {
 		}
 	})
 }
+
+func TestSenderCollections(t *testing.T) {
+	t.Parallel()
+
+	database, err := badger.OpenDatabase(badgerdb.DefaultOptions("").WithInMemory(true))
+	if err != nil {
+		t.Fatalf("failed to open badger database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	otherProjectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	exp := []sender.Collection{
+		{
+			ID:         ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID:  projectID,
+			Name:       "auth flows",
+			RequestIDs: []ulid.ULID{ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)},
+		},
+		{
+			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+			ProjectID: projectID,
+			Name:      "payments API",
+			Position:  1,
+		},
+	}
+	other := sender.Collection{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: otherProjectID,
+		Name:      "other",
+	}
+
+	for _, collection := range append(exp, other) {
+		if err := database.StoreSenderCollection(ctx, collection); err != nil {
+			t.Fatalf("unexpected error storing sender collection: %v", err)
+		}
+	}
+
+	got, err := database.FindSenderCollections(ctx, projectID)
+	if err != nil {
+		t.Fatalf("unexpected error finding sender collections: %v", err)
+	}
+
+	// Collections are returned by ID, which isn't monotonic within a
+	// millisecond; the service orders them by position.
+	sort.Slice(got, func(i, j int) bool { return got[i].Position < got[j].Position })
+
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("sender collections not equal (-exp, +got):\n%v", diff)
+	}
+
+	if err := database.DeleteSenderCollection(ctx, exp[1].ID); err != nil {
+		t.Fatalf("unexpected error deleting sender collection: %v", err)
+	}
+
+	if _, err := database.FindSenderCollectionByID(ctx, exp[1].ID); !errors.Is(err, sender.ErrCollectionNotFound) {
+		t.Fatalf("expected `sender.ErrCollectionNotFound`, got: %v", err)
+	}
+
+	// Deleting the sender requests of a project deletes its collections too.
+	if err := database.DeleteSenderRequests(ctx, projectID); err != nil {
+		t.Fatalf("unexpected error deleting sender requests: %v", err)
+	}
+
+	if got, err = database.FindSenderCollections(ctx, projectID); err != nil || len(got) != 0 {
+		t.Fatalf("expected no sender collections (error: %v), got: %+v", err, got)
+	}
+
+	if _, err := database.FindSenderCollectionByID(ctx, other.ID); err != nil {
+		t.Fatalf("unexpected error finding sender collection of other project: %v", err)
+	}
+}
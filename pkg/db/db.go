@@ -0,0 +1,25 @@
+// Package db defines the storage interface implemented by database backends.
+package db
+
+import (
+	"github.com/dstotijn/hetty/pkg/connlog"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/oast"
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+// Database is a storage backend for all services. Each service only depends
+// on its own repository interface; this interface combines them, so that a
+// backend can be selected at runtime.
+type Database interface {
+	proj.Repository
+	reqlog.Repository
+	sender.Repository
+	oast.Repository
+	finding.Repository
+	connlog.Repository
+	job.Repository
+}
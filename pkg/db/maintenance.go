@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+var (
+	ErrCompactionRunning       = errors.New("db: compaction is already running")
+	ErrInvalidCompactionWindow = errcode.New(errcode.Invalid, "db: compaction window hours must be in range 0-23")
+)
+
+// Maintainer is implemented by backends that need periodic maintenance, e.g.
+// the Badger backend, which reclaims space of deleted and overwritten items
+// with compactions.
+type Maintainer interface {
+	Stats(ctx context.Context) (Stats, error)
+	// Compact runs a compaction, and returns when it's done.
+	Compact(ctx context.Context) error
+	CompactionSchedule() CompactionSchedule
+	SetCompactionSchedule(schedule CompactionSchedule) error
+	// Backup writes a consistent snapshot of the database to w, while the
+	// database is in use.
+	Backup(w io.Writer) error
+}
+
+// Stats are statistics of a database.
+type Stats struct {
+	// Size on disk of the LSM tree and value log, in bytes.
+	LSMSize      int64
+	ValueLogSize int64
+	// Number of keys, including index items, by kind of item, e.g.
+	// `requestLogs`.
+	KeyCounts map[string]int
+	// Number of levels of the LSM tree that are due for compaction.
+	PendingCompactions int
+
+	CompactionSchedule CompactionSchedule
+	// Time and error of the last compaction, if any.
+	LastCompactionAt    time.Time
+	LastCompactionError string
+	CompactionRunning   bool
+}
+
+// CompactionSchedule configures background compactions.
+type CompactionSchedule struct {
+	// Minimum duration between compactions. Background compaction is
+	// disabled when zero.
+	Interval time.Duration
+	// Hours of the day (local time) in which compactions may run, from the
+	// start hour until the end hour, e.g. 2 and 6 for off-peak hours. When
+	// equal, compactions may run at any time.
+	WindowStart int
+	WindowEnd   int
+}
+
+// Validate returns an error if the window hours are out of range.
+func (s CompactionSchedule) Validate() error {
+	if s.WindowStart < 0 || s.WindowStart > 23 || s.WindowEnd < 0 || s.WindowEnd > 23 {
+		return ErrInvalidCompactionWindow
+	}
+
+	return nil
+}
+
+// ParseCompactionWindow parses a window of hours, in the form `start-end`, e.g.
+// `2-6`.
+func ParseCompactionWindow(s string) (start, end int, err error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("db: invalid compaction window %q, expected `start-end`", s)
+	}
+
+	if start, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+		return 0, 0, fmt.Errorf("db: invalid compaction window start hour: %w", err)
+	}
+
+	if end, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+		return 0, 0, fmt.Errorf("db: invalid compaction window end hour: %w", err)
+	}
+
+	if err := (CompactionSchedule{WindowStart: start, WindowEnd: end}).Validate(); err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+// InWindow returns true if t is within the window of the schedule. Windows
+// can wrap around midnight, e.g. from 22 until 4.
+func (s CompactionSchedule) InWindow(t time.Time) bool {
+	hour := t.Hour()
+
+	switch {
+	case s.WindowStart == s.WindowEnd:
+		return true
+	case s.WindowStart < s.WindowEnd:
+		return hour >= s.WindowStart && hour < s.WindowEnd
+	default:
+		return hour >= s.WindowStart || hour < s.WindowEnd
+	}
+}
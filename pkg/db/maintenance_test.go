@@ -0,0 +1,61 @@
+package db_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/db"
+)
+
+func TestCompactionScheduleInWindow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		schedule db.CompactionSchedule
+		hour     int
+		exp      bool
+	}{
+		{name: "any time", schedule: db.CompactionSchedule{}, hour: 13, exp: true},
+		{name: "in window", schedule: db.CompactionSchedule{WindowStart: 2, WindowEnd: 6}, hour: 2, exp: true},
+		{name: "window end is exclusive", schedule: db.CompactionSchedule{WindowStart: 2, WindowEnd: 6}, hour: 6, exp: false},
+		{name: "wraps around midnight", schedule: db.CompactionSchedule{WindowStart: 22, WindowEnd: 4}, hour: 1, exp: true},
+		{name: "outside wrapped window", schedule: db.CompactionSchedule{WindowStart: 22, WindowEnd: 4}, hour: 12, exp: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			now := time.Date(2022, 1, 1, tt.hour, 30, 0, 0, time.Local)
+
+			if got := tt.schedule.InWindow(now); got != tt.exp {
+				t.Fatalf("expected %v, got: %v", tt.exp, got)
+			}
+		})
+	}
+}
+
+func TestParseCompactionWindow(t *testing.T) {
+	t.Parallel()
+
+	start, end, err := db.ParseCompactionWindow("2-6")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if start != 2 || end != 6 {
+		t.Fatalf("expected window 2-6, got: %v-%v", start, end)
+	}
+
+	if _, _, err := db.ParseCompactionWindow("2-24"); !errors.Is(err, db.ErrInvalidCompactionWindow) {
+		t.Fatalf("expected `db.ErrInvalidCompactionWindow`, got: %v", err)
+	}
+
+	if _, _, err := db.ParseCompactionWindow("2"); err == nil {
+		t.Fatal("expected error for window without end hour")
+	}
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/connlog"
+)
+
+func (db *Database) StoreConnectionLog(ctx context.Context, connLog connlog.ConnectionLog) error {
+	var stored connlog.ConnectionLog
+
+	if err := copyValue(&stored, connLog); err != nil {
+		return fmt.Errorf("memory: failed to copy connection log: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.connLogs[connLog.ID] = stored
+
+	return nil
+}
+
+func (db *Database) FindConnectionLogs(ctx context.Context, filter connlog.FindConnectionLogsFilter) ([]connlog.ConnectionLog, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, connlog.ErrProjectIDMustBeSet
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]ulid.ULID, 0)
+
+	for id, connLog := range db.connLogs {
+		if connLog.ProjectID.Compare(filter.ProjectID) == 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	sortIDs(ids, true)
+
+	connLogs := make([]connlog.ConnectionLog, len(ids))
+
+	for i, id := range ids {
+		if err := copyValue(&connLogs[i], db.connLogs[id]); err != nil {
+			return nil, fmt.Errorf("memory: failed to copy connection log: %w", err)
+		}
+	}
+
+	return connLogs, nil
+}
+
+func (db *Database) ClearConnectionLogs(ctx context.Context, projectID ulid.ULID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for id, connLog := range db.connLogs {
+		if connLog.ProjectID.Compare(projectID) == 0 {
+			delete(db.connLogs, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/finding"
+)
+
+func (db *Database) StoreFinding(ctx context.Context, f finding.Finding) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// Findings only have value fields, so they don't need a deep copy.
+	db.findings[f.ID] = f
+
+	return nil
+}
+
+func (db *Database) FindFindings(ctx context.Context, filter finding.FindFindingsFilter) ([]finding.Finding, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, finding.ErrProjectIDMustBeSet
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]ulid.ULID, 0)
+
+	for id, f := range db.findings {
+		if f.ProjectID.Compare(filter.ProjectID) != 0 {
+			continue
+		}
+
+		if filter.RequestLogID.Compare(ulid.ULID{}) != 0 && filter.RequestLogID.Compare(f.RequestLogID) != 0 {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	sortIDs(ids, true)
+
+	findings := make([]finding.Finding, len(ids))
+
+	for i, id := range ids {
+		findings[i] = db.findings[id]
+	}
+
+	return findings, nil
+}
+
+func (db *Database) ClearFindings(ctx context.Context, projectID ulid.ULID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for id, f := range db.findings {
+		if f.ProjectID.Compare(projectID) == 0 {
+			delete(db.findings, id)
+		}
+	}
+
+	return nil
+}
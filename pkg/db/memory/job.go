@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/job"
+)
+
+func (db *Database) StoreJob(ctx context.Context, j job.Job) error {
+	var stored job.Job
+
+	if err := copyValue(&stored, j); err != nil {
+		return fmt.Errorf("memory: failed to copy job: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.jobs[j.ID] = stored
+
+	return nil
+}
+
+func (db *Database) FindJobs(ctx context.Context, filter job.FindJobsFilter) ([]job.Job, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, job.ErrProjectIDMustBeSet
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]ulid.ULID, 0)
+
+	for id, j := range db.jobs {
+		if j.ProjectID.Compare(filter.ProjectID) == 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	sortIDs(ids, true)
+
+	jobs := make([]job.Job, len(ids))
+
+	for i, id := range ids {
+		if err := copyValue(&jobs[i], db.jobs[id]); err != nil {
+			return nil, fmt.Errorf("memory: failed to copy job: %w", err)
+		}
+	}
+
+	return jobs, nil
+}
+
+func (db *Database) ClearJobs(ctx context.Context, projectID ulid.ULID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for id, j := range db.jobs {
+		if j.ProjectID.Compare(projectID) == 0 {
+			delete(db.jobs, id)
+		}
+	}
+
+	return nil
+}
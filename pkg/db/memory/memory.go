@@ -0,0 +1,93 @@
+// Package memory implements an in-memory database, for ephemeral sessions and
+// tests. Data is lost when the database is closed.
+package memory
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/connlog"
+	"github.com/dstotijn/hetty/pkg/db"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/oast"
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+var _ db.Database = (*Database)(nil)
+
+// Database stores data in memory. Values are copied when they are stored and
+// retrieved, like with a persistent database, so callers can't modify stored
+// data.
+type Database struct {
+	mu               sync.RWMutex
+	projects         map[ulid.ULID]proj.Project
+	reqLogs          map[ulid.ULID]reqlog.RequestLog
+	resLogs          map[ulid.ULID]reqlog.ResponseLog
+	senderReqs       map[ulid.ULID]sender.Request
+	senderColls      map[ulid.ULID]sender.Collection
+	senderRuns       map[ulid.ULID]sender.ScheduleRun
+	senderComps      map[ulid.ULID]sender.Comparison
+	oastPayloads     map[ulid.ULID]oast.Payload
+	oastInteractions map[ulid.ULID]oast.Interaction
+	findings         map[ulid.ULID]finding.Finding
+	connLogs         map[ulid.ULID]connlog.ConnectionLog
+	jobs             map[ulid.ULID]job.Job
+}
+
+// OpenDatabase returns a new, empty in-memory database.
+func OpenDatabase() *Database {
+	return &Database{
+		projects:         make(map[ulid.ULID]proj.Project),
+		reqLogs:          make(map[ulid.ULID]reqlog.RequestLog),
+		resLogs:          make(map[ulid.ULID]reqlog.ResponseLog),
+		senderReqs:       make(map[ulid.ULID]sender.Request),
+		senderColls:      make(map[ulid.ULID]sender.Collection),
+		senderRuns:       make(map[ulid.ULID]sender.ScheduleRun),
+		senderComps:      make(map[ulid.ULID]sender.Comparison),
+		oastPayloads:     make(map[ulid.ULID]oast.Payload),
+		oastInteractions: make(map[ulid.ULID]oast.Interaction),
+		findings:         make(map[ulid.ULID]finding.Finding),
+		connLogs:         make(map[ulid.ULID]connlog.ConnectionLog),
+		jobs:             make(map[ulid.ULID]job.Job),
+	}
+}
+
+// Close is a no-op; it's part of the `proj.Repository` interface.
+func (db *Database) Close() error {
+	return nil
+}
+
+// copyValue deep copies src into dst (a pointer) by gob encoding, so that
+// values are normalized the same way as in the Badger database.
+func copyValue(dst, src interface{}) error {
+	buf := bytes.Buffer{}
+
+	if err := gob.NewEncoder(&buf).Encode(src); err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	if err := gob.NewDecoder(&buf).Decode(dst); err != nil {
+		return fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	return nil
+}
+
+// sortIDs sorts ULIDs chronologically, or reverse chronologically.
+func sortIDs(ids []ulid.ULID, reverse bool) {
+	sort.Slice(ids, func(i, j int) bool {
+		if reverse {
+			return ids[i].Compare(ids[j]) > 0
+		}
+
+		return ids[i].Compare(ids[j]) < 0
+	})
+}
@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+//nolint:gosec
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func TestFindRequestLogs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("without project ID in filter", func(t *testing.T) {
+		t.Parallel()
+
+		database := OpenDatabase()
+
+		_, err := database.FindRequestLogs(context.Background(), reqlog.FindRequestsFilter{}, nil)
+		if !errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+			t.Fatalf("expected `reqlog.ErrProjectIDMustBeSet`, got: %v", err)
+		}
+	})
+
+	t.Run("returns request logs and related response logs", func(t *testing.T) {
+		t.Parallel()
+
+		database := OpenDatabase()
+		projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+		exp := []reqlog.RequestLog{
+			{
+				ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+				ProjectID: projectID,
+				URL:       mustParseURL(t, "https://example.com/foobar"),
+				Method:    http.MethodPost,
+				Proto:     "HTTP/1.1",
+				Header:    http.Header{"X-Foo": []string{"baz"}},
+				Body:      []byte("foo"),
+				Response: &reqlog.ResponseLog{
+					Proto:      "HTTP/1.1",
+					Status:     "200 OK",
+					StatusCode: 200,
+					Header:     http.Header{"X-Yolo": []string{"swag"}},
+					Body:       []byte("bar"),
+				},
+			},
+			{
+				ID:        ulid.MustNew(ulid.Timestamp(time.Now())+100, ulidEntropy),
+				ProjectID: projectID,
+				URL:       mustParseURL(t, "https://example.com/foo?bar=baz"),
+				Method:    http.MethodGet,
+				Proto:     "HTTP/1.1",
+				Header:    http.Header{"X-Foo": []string{"baz"}},
+			},
+		}
+
+		// Store in reverse order, to assert that results are sorted.
+		for i := len(exp) - 1; i >= 0; i-- {
+			reqLog := exp[i]
+
+			if err := database.StoreRequestLog(context.Background(), reqLog); err != nil {
+				t.Fatalf("unexpected error creating request log fixture: %v", err)
+			}
+
+			if reqLog.Response != nil {
+				err := database.StoreResponseLog(context.Background(), reqLog.ID, *reqLog.Response)
+				if err != nil {
+					t.Fatalf("unexpected error creating response log fixture: %v", err)
+				}
+			}
+		}
+
+		got, err := database.FindRequestLogs(context.Background(), reqlog.FindRequestsFilter{ProjectID: projectID}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error finding request logs: %v", err)
+		}
+
+		if diff := cmp.Diff(exp, got); diff != "" {
+			t.Fatalf("request logs not equal (-exp, +got):\n%v", diff)
+		}
+	})
+}
+
+func TestStoreRequestLogCopiesValue(t *testing.T) {
+	t.Parallel()
+
+	database := OpenDatabase()
+
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		URL:       mustParseURL(t, "https://example.com/"),
+		Method:    http.MethodGet,
+		Header:    http.Header{"X-Foo": []string{"bar"}},
+	}
+
+	if err := database.StoreRequestLog(context.Background(), reqLog); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Modifying the stored value, or a retrieved value, shouldn't affect the
+	// database.
+	reqLog.Header.Set("X-Foo", "baz")
+
+	got, err := database.FindRequestLogByID(context.Background(), reqLog.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got.URL.Path = "/modified"
+
+	got, err = database.FindRequestLogByID(context.Background(), reqLog.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := got.Header.Get("X-Foo"); v != "bar" {
+		t.Fatalf("expected header value `bar`, got: %v", v)
+	}
+
+	if got.URL.Path != "/" {
+		t.Fatalf("expected URL path `/`, got: %v", got.URL.Path)
+	}
+}
+
+func TestDeleteProject(t *testing.T) {
+	t.Parallel()
+
+	database := OpenDatabase()
+	ctx := context.Background()
+
+	project := proj.Project{
+		ID:   ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		Name: "foobar",
+	}
+
+	if err := database.UpsertProject(ctx, project); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	if err := database.StoreRequestLog(ctx, reqlog.RequestLog{ID: reqLogID, ProjectID: project.ID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	senderReq := sender.Request{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: project.ID,
+	}
+
+	if err := database.StoreSenderRequest(ctx, senderReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := database.DeleteProject(ctx, project.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := database.FindProjectByID(ctx, project.ID); !errors.Is(err, proj.ErrProjectNotFound) {
+		t.Fatalf("expected `proj.ErrProjectNotFound`, got: %v", err)
+	}
+
+	if _, err := database.FindRequestLogByID(ctx, reqLogID); !errors.Is(err, reqlog.ErrRequestNotFound) {
+		t.Fatalf("expected `reqlog.ErrRequestNotFound`, got: %v", err)
+	}
+
+	if _, err := database.FindSenderRequestByID(ctx, senderReq.ID); !errors.Is(err, sender.ErrRequestNotFound) {
+		t.Fatalf("expected `sender.ErrRequestNotFound`, got: %v", err)
+	}
+}
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return u
+}
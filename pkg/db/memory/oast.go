@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/oast"
+)
+
+func (db *Database) StoreOASTPayload(ctx context.Context, payload oast.Payload) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// Payloads only have value fields, so they don't need a deep copy.
+	db.oastPayloads[payload.ID] = payload
+
+	return nil
+}
+
+func (db *Database) FindOASTPayloadByID(ctx context.Context, id ulid.ULID) (oast.Payload, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	payload, ok := db.oastPayloads[id]
+	if !ok {
+		return oast.Payload{}, oast.ErrPayloadNotFound
+	}
+
+	return payload, nil
+}
+
+func (db *Database) StoreOASTInteraction(ctx context.Context, interaction oast.Interaction) error {
+	var stored oast.Interaction
+
+	if err := copyValue(&stored, interaction); err != nil {
+		return fmt.Errorf("memory: failed to copy OAST interaction: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.oastInteractions[interaction.ID] = stored
+
+	return nil
+}
+
+func (db *Database) FindOASTInteractions(ctx context.Context, filter oast.FindInteractionsFilter) ([]oast.Interaction, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, oast.ErrProjectIDMustBeSet
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]ulid.ULID, 0)
+
+	for id, interaction := range db.oastInteractions {
+		if interaction.ProjectID.Compare(filter.ProjectID) != 0 {
+			continue
+		}
+
+		if filter.RequestLogID.Compare(ulid.ULID{}) != 0 && filter.RequestLogID.Compare(interaction.RequestLogID) != 0 {
+			continue
+		}
+
+		if filter.CorrelationID.Compare(ulid.ULID{}) != 0 && filter.CorrelationID.Compare(interaction.CorrelationID) != 0 {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	sortIDs(ids, true)
+
+	interactions := make([]oast.Interaction, len(ids))
+
+	for i, id := range ids {
+		if err := copyValue(&interactions[i], db.oastInteractions[id]); err != nil {
+			return nil, fmt.Errorf("memory: failed to copy OAST interaction: %w", err)
+		}
+	}
+
+	return interactions, nil
+}
+
+func (db *Database) ClearOASTData(ctx context.Context, projectID ulid.ULID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for id, payload := range db.oastPayloads {
+		if payload.ProjectID.Compare(projectID) == 0 {
+			delete(db.oastPayloads, id)
+		}
+	}
+
+	for id, interaction := range db.oastInteractions {
+		if interaction.ProjectID.Compare(projectID) == 0 {
+			delete(db.oastInteractions, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/proj"
+)
+
+func (db *Database) UpsertProject(ctx context.Context, project proj.Project) error {
+	var stored proj.Project
+
+	if err := copyValue(&stored, project); err != nil {
+		return fmt.Errorf("memory: failed to copy project: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.projects[project.ID] = stored
+
+	return nil
+}
+
+func (db *Database) FindProjectByID(ctx context.Context, projectID ulid.ULID) (proj.Project, error) {
+	db.mu.RLock()
+	stored, ok := db.projects[projectID]
+	db.mu.RUnlock()
+
+	if !ok {
+		return proj.Project{}, proj.ErrProjectNotFound
+	}
+
+	var project proj.Project
+
+	if err := copyValue(&project, stored); err != nil {
+		return proj.Project{}, fmt.Errorf("memory: failed to copy project: %w", err)
+	}
+
+	return project, nil
+}
+
+func (db *Database) DeleteProject(ctx context.Context, projectID ulid.ULID) error {
+	if err := db.ClearRequestLogs(ctx, projectID); err != nil {
+		return fmt.Errorf("memory: failed to delete project request logs: %w", err)
+	}
+
+	if err := db.DeleteSenderRequests(ctx, projectID); err != nil {
+		return fmt.Errorf("memory: failed to delete project sender requests: %w", err)
+	}
+
+	if err := db.ClearOASTData(ctx, projectID); err != nil {
+		return fmt.Errorf("memory: failed to delete project OAST data: %w", err)
+	}
+
+	if err := db.ClearFindings(ctx, projectID); err != nil {
+		return fmt.Errorf("memory: failed to delete project findings: %w", err)
+	}
+
+	if err := db.ClearConnectionLogs(ctx, projectID); err != nil {
+		return fmt.Errorf("memory: failed to delete project connection logs: %w", err)
+	}
+
+	if err := db.ClearJobs(ctx, projectID); err != nil {
+		return fmt.Errorf("memory: failed to delete project jobs: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delete(db.projects, projectID)
+
+	return nil
+}
+
+func (db *Database) Projects(ctx context.Context) ([]proj.Project, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]ulid.ULID, 0, len(db.projects))
+	for id := range db.projects {
+		ids = append(ids, id)
+	}
+
+	sortIDs(ids, false)
+
+	projects := make([]proj.Project, len(ids))
+
+	for i, id := range ids {
+		if err := copyValue(&projects[i], db.projects[id]); err != nil {
+			return nil, fmt.Errorf("memory: failed to copy project: %w", err)
+		}
+	}
+
+	return projects, nil
+}
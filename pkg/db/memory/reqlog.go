@@ -0,0 +1,296 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+func (db *Database) FindRequestLogs(ctx context.Context, filter reqlog.FindRequestsFilter, scope *scope.Scope) ([]reqlog.RequestLog, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, reqlog.ErrProjectIDMustBeSet
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("memory: failed to find request logs: %w", err)
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	searchExpr := filter.SearchExpression()
+	since, until := filter.TimeRange(time.Now())
+	ids := make([]ulid.ULID, 0)
+
+	for id, reqLog := range db.reqLogs {
+		if reqLog.ProjectID.Compare(filter.ProjectID) == 0 && reqlog.InTimeRange(id, since, until) {
+			ids = append(ids, id)
+		}
+	}
+
+	sortIDs(ids, false)
+
+	reqLogs := make([]reqlog.RequestLog, 0, len(ids))
+
+	for _, id := range ids {
+		// Filter on the stored values, and only copy matching request logs.
+		reqLog := db.reqLogs[id]
+		if resLog, ok := db.resLogs[id]; ok {
+			reqLog.Response = &resLog
+		}
+
+		if !filter.MatchFields(reqLog) {
+			continue
+		}
+
+		if filter.OnlyInScope {
+			if !reqLog.MatchScope(scope) {
+				continue
+			}
+		}
+
+		if filter.CollapseRedirects && reqLog.RedirectFromID.Compare(ulid.ULID{}) != 0 {
+			continue
+		}
+
+		if filter.CorrelationID.Compare(ulid.ULID{}) != 0 && filter.CorrelationID.Compare(reqLog.CorrelationID) != 0 {
+			continue
+		}
+
+		if filter.CollapsePageLoads && !reqLog.StartsPageLoad() {
+			continue
+		}
+
+		if filter.PageLoadID.Compare(ulid.ULID{}) != 0 && filter.PageLoadID.Compare(reqLog.PageLoadID) != 0 {
+			continue
+		}
+
+		if searchExpr != nil {
+			match, err := reqLog.Matches(searchExpr)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"memory: failed to match search expression for request log (id: %v): %w",
+					id.String(), err,
+				)
+			}
+
+			if !match {
+				continue
+			}
+		}
+
+		reqLog, err := db.requestLogWithResponse(id)
+		if err != nil {
+			return nil, fmt.Errorf("memory: failed to get request log (id: %v): %w", id.String(), err)
+		}
+
+		reqLogs = append(reqLogs, reqLog)
+	}
+
+	return reqLogs, nil
+}
+
+// requestLogWithResponse returns a copy of a request log, with its response
+// log. The lock must be held.
+func (db *Database) requestLogWithResponse(id ulid.ULID) (reqlog.RequestLog, error) {
+	stored, ok := db.reqLogs[id]
+	if !ok {
+		return reqlog.RequestLog{}, reqlog.ErrRequestNotFound
+	}
+
+	var reqLog reqlog.RequestLog
+
+	if err := copyValue(&reqLog, stored); err != nil {
+		return reqlog.RequestLog{}, err
+	}
+
+	if resLog, ok := db.resLogs[id]; ok {
+		reqLog.Response = &reqlog.ResponseLog{}
+
+		if err := copyValue(reqLog.Response, resLog); err != nil {
+			return reqlog.RequestLog{}, err
+		}
+	}
+
+	return reqLog, nil
+}
+
+func (db *Database) FindRequestLogByID(ctx context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
+	if err := ctx.Err(); err != nil {
+		return reqlog.RequestLog{}, fmt.Errorf("memory: failed to get request log: %w", err)
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	reqLog, err := db.requestLogWithResponse(id)
+	if err != nil {
+		return reqlog.RequestLog{}, fmt.Errorf("memory: failed to get request log: %w", err)
+	}
+
+	return reqLog, nil
+}
+
+func (db *Database) StoreRequestLog(ctx context.Context, reqLog reqlog.RequestLog) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("memory: failed to store request log: %w", err)
+	}
+
+	var stored reqlog.RequestLog
+
+	if err := copyValue(&stored, reqLog); err != nil {
+		return fmt.Errorf("memory: failed to copy request log: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.reqLogs[reqLog.ID] = stored
+
+	return nil
+}
+
+// StoreResponseLog stores the response log for a request log or sender
+// request.
+func (db *Database) StoreResponseLog(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("memory: failed to store response log: %w", err)
+	}
+
+	var stored reqlog.ResponseLog
+
+	if err := copyValue(&stored, resLog); err != nil {
+		return fmt.Errorf("memory: failed to copy response log: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.resLogs[reqLogID] = stored
+
+	return nil
+}
+
+func (db *Database) DeleteRequestLogs(ctx context.Context, projectID ulid.ULID, ids []ulid.ULID) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("memory: failed to delete request logs: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, id := range ids {
+		if reqLog, ok := db.reqLogs[id]; ok && reqLog.ProjectID.Compare(projectID) == 0 {
+			delete(db.reqLogs, id)
+			delete(db.resLogs, id)
+		}
+	}
+
+	return nil
+}
+
+func (db *Database) ClearRequestLogs(ctx context.Context, projectID ulid.ULID) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("memory: failed to clear request logs: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for id, reqLog := range db.reqLogs {
+		if reqLog.ProjectID.Compare(projectID) == 0 {
+			delete(db.reqLogs, id)
+			delete(db.resLogs, id)
+		}
+	}
+
+	return nil
+}
+
+func (db *Database) FindRedirectedRequestLogIDs(ctx context.Context, projectID, redirectFromID ulid.ULID) ([]ulid.ULID, error) {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, reqlog.ErrProjectIDMustBeSet
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("memory: failed to find redirected request logs: %w", err)
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]ulid.ULID, 0)
+
+	for id, reqLog := range db.reqLogs {
+		if reqLog.ProjectID.Compare(projectID) == 0 && reqLog.RedirectFromID.Compare(redirectFromID) == 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	sortIDs(ids, false)
+
+	return ids, nil
+}
+
+func (db *Database) FindRequestLogFieldValues(ctx context.Context, projectID ulid.ULID) (reqlog.FieldValues, error) {
+	if err := ctx.Err(); err != nil {
+		return reqlog.FieldValues{}, fmt.Errorf("memory: failed to find request log field values: %w", err)
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	hosts := make(map[string]int)
+	methods := make(map[string]int)
+	statusCodes := make(map[string]int)
+	contentTypes := make(map[string]int)
+
+	for id, reqLog := range db.reqLogs {
+		if reqLog.ProjectID.Compare(projectID) != 0 {
+			continue
+		}
+
+		if hostname := reqLog.Hostname(); hostname != "" {
+			hosts[hostname]++
+		}
+
+		if reqLog.Method != "" {
+			methods[reqLog.Method]++
+		}
+
+		resLog, ok := db.resLogs[id]
+		if !ok {
+			continue
+		}
+
+		if resLog.StatusCode != 0 {
+			statusCodes[strconv.Itoa(resLog.StatusCode)]++
+		}
+
+		if mediaType := resLog.MediaType(); mediaType != "" {
+			contentTypes[mediaType]++
+		}
+	}
+
+	return reqlog.FieldValues{
+		Hosts:        fieldValues(hosts),
+		Methods:      fieldValues(methods),
+		StatusCodes:  fieldValues(statusCodes),
+		ContentTypes: fieldValues(contentTypes),
+	}, nil
+}
+
+func fieldValues(counts map[string]int) []reqlog.FieldValue {
+	values := make([]reqlog.FieldValue, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, reqlog.FieldValue{Value: value, Count: count})
+	}
+
+	return values
+}
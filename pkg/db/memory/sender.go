@@ -0,0 +1,318 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func (db *Database) StoreSenderRequest(ctx context.Context, req sender.Request) error {
+	var stored sender.Request
+
+	if err := copyValue(&stored, req); err != nil {
+		return fmt.Errorf("memory: failed to copy sender request: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.senderReqs[req.ID] = stored
+
+	return nil
+}
+
+func (db *Database) FindSenderRequestByID(ctx context.Context, senderReqID ulid.ULID) (sender.Request, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	req, err := db.senderRequestWithResponseLog(senderReqID)
+	if err != nil {
+		return sender.Request{}, fmt.Errorf("memory: failed to get sender request: %w", err)
+	}
+
+	return req, nil
+}
+
+func (db *Database) FindSenderRequests(ctx context.Context, filter sender.FindRequestsFilter, scope *scope.Scope) ([]sender.Request, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, sender.ErrProjectIDMustBeSet
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]ulid.ULID, 0)
+
+	for id, req := range db.senderReqs {
+		if req.ProjectID.Compare(filter.ProjectID) == 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	sortIDs(ids, true)
+
+	senderReqs := make([]sender.Request, 0, len(ids))
+
+	for _, id := range ids {
+		senderReq, err := db.senderRequestWithResponseLog(id)
+		if err != nil {
+			return nil, fmt.Errorf("memory: failed to get sender request (id: %v): %w", id.String(), err)
+		}
+
+		if filter.OnlyInScope {
+			if !senderReq.MatchScope(scope) {
+				continue
+			}
+		}
+
+		if filter.SearchExpr != nil {
+			match, err := senderReq.Matches(filter.SearchExpr)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"memory: failed to match search expression for sender request (id: %v): %w",
+					id.String(), err,
+				)
+			}
+
+			if !match {
+				continue
+			}
+		}
+
+		senderReqs = append(senderReqs, senderReq)
+	}
+
+	return senderReqs, nil
+}
+
+func (db *Database) DeleteSenderRequests(ctx context.Context, projectID ulid.ULID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for id, req := range db.senderReqs {
+		if req.ProjectID.Compare(projectID) == 0 {
+			delete(db.senderReqs, id)
+			delete(db.resLogs, id)
+		}
+	}
+
+	for id, collection := range db.senderColls {
+		if collection.ProjectID.Compare(projectID) == 0 {
+			delete(db.senderColls, id)
+		}
+	}
+
+	for id, run := range db.senderRuns {
+		if run.ProjectID.Compare(projectID) == 0 {
+			delete(db.senderRuns, id)
+		}
+	}
+
+	for id, comparison := range db.senderComps {
+		if comparison.ProjectID.Compare(projectID) == 0 {
+			delete(db.senderComps, id)
+		}
+	}
+
+	return nil
+}
+
+func (db *Database) StoreSenderCollection(ctx context.Context, collection sender.Collection) error {
+	var stored sender.Collection
+
+	if err := copyValue(&stored, collection); err != nil {
+		return fmt.Errorf("memory: failed to copy sender collection: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.senderColls[collection.ID] = stored
+
+	return nil
+}
+
+func (db *Database) FindSenderCollectionByID(ctx context.Context, id ulid.ULID) (sender.Collection, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	stored, ok := db.senderColls[id]
+	if !ok {
+		return sender.Collection{}, fmt.Errorf("memory: failed to get sender collection: %w", sender.ErrCollectionNotFound)
+	}
+
+	var collection sender.Collection
+
+	if err := copyValue(&collection, stored); err != nil {
+		return sender.Collection{}, fmt.Errorf("memory: failed to copy sender collection: %w", err)
+	}
+
+	return collection, nil
+}
+
+func (db *Database) FindSenderCollections(ctx context.Context, projectID ulid.ULID) ([]sender.Collection, error) {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, sender.ErrProjectIDMustBeSet
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]ulid.ULID, 0)
+
+	for id, collection := range db.senderColls {
+		if collection.ProjectID.Compare(projectID) == 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	sortIDs(ids, false)
+
+	collections := make([]sender.Collection, len(ids))
+
+	for i, id := range ids {
+		if err := copyValue(&collections[i], db.senderColls[id]); err != nil {
+			return nil, fmt.Errorf("memory: failed to copy sender collection: %w", err)
+		}
+	}
+
+	return collections, nil
+}
+
+func (db *Database) DeleteSenderCollection(ctx context.Context, id ulid.ULID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.senderColls[id]; !ok {
+		return fmt.Errorf("memory: failed to delete sender collection: %w", sender.ErrCollectionNotFound)
+	}
+
+	delete(db.senderColls, id)
+
+	return nil
+}
+
+func (db *Database) StoreSenderScheduleRun(ctx context.Context, run sender.ScheduleRun) error {
+	var stored sender.ScheduleRun
+
+	if err := copyValue(&stored, run); err != nil {
+		return fmt.Errorf("memory: failed to copy sender schedule run: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.senderRuns[run.ID] = stored
+
+	return nil
+}
+
+func (db *Database) FindSenderScheduleRuns(ctx context.Context, projectID ulid.ULID, name string) ([]sender.ScheduleRun, error) {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, sender.ErrProjectIDMustBeSet
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]ulid.ULID, 0)
+
+	for id, run := range db.senderRuns {
+		if run.ProjectID.Compare(projectID) == 0 && (name == "" || run.Schedule == name) {
+			ids = append(ids, id)
+		}
+	}
+
+	sortIDs(ids, false)
+
+	runs := make([]sender.ScheduleRun, len(ids))
+
+	for i, id := range ids {
+		if err := copyValue(&runs[i], db.senderRuns[id]); err != nil {
+			return nil, fmt.Errorf("memory: failed to copy sender schedule run: %w", err)
+		}
+	}
+
+	return runs, nil
+}
+
+func (db *Database) StoreSenderComparison(ctx context.Context, comparison sender.Comparison) error {
+	var stored sender.Comparison
+
+	if err := copyValue(&stored, comparison); err != nil {
+		return fmt.Errorf("memory: failed to copy sender comparison: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.senderComps[comparison.ID] = stored
+
+	return nil
+}
+
+func (db *Database) FindSenderComparisons(
+	ctx context.Context,
+	projectID, requestID ulid.ULID,
+) ([]sender.Comparison, error) {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, sender.ErrProjectIDMustBeSet
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ids := make([]ulid.ULID, 0)
+
+	for id, comparison := range db.senderComps {
+		if comparison.ProjectID.Compare(projectID) != 0 {
+			continue
+		}
+
+		if requestID.Compare(ulid.ULID{}) == 0 || comparison.RequestID.Compare(requestID) == 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	sortIDs(ids, false)
+
+	comparisons := make([]sender.Comparison, len(ids))
+
+	for i, id := range ids {
+		if err := copyValue(&comparisons[i], db.senderComps[id]); err != nil {
+			return nil, fmt.Errorf("memory: failed to copy sender comparison: %w", err)
+		}
+	}
+
+	return comparisons, nil
+}
+
+// senderRequestWithResponseLog returns a copy of a sender request, with its
+// response log. The lock must be held.
+func (db *Database) senderRequestWithResponseLog(id ulid.ULID) (sender.Request, error) {
+	stored, ok := db.senderReqs[id]
+	if !ok {
+		return sender.Request{}, sender.ErrRequestNotFound
+	}
+
+	var req sender.Request
+
+	if err := copyValue(&req, stored); err != nil {
+		return sender.Request{}, err
+	}
+
+	if resLog, ok := db.resLogs[id]; ok {
+		req.Response = &reqlog.ResponseLog{}
+
+		if err := copyValue(req.Response, resLog); err != nil {
+			return sender.Request{}, err
+		}
+	}
+
+	return req, nil
+}
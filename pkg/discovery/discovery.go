@@ -0,0 +1,465 @@
+package discovery
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+const (
+	defaultRequestsPerSecond = 10
+	maxRequestsPerSecond     = 1000
+	defaultConcurrency       = 5
+)
+
+// DiscoveredTag is the tag of the request logs of discovered paths, so that
+// these are marked in the site map.
+const DiscoveredTag = "discovered"
+
+// JobType is the type of the jobs that run scans, see `job.Params`.
+const JobType = "contentDiscovery"
+
+var (
+	ErrScanNotFound  = errcode.New(errcode.NotFound, "discovery: scan not found")
+	ErrOutOfScope    = errcode.New(errcode.Invalid, "discovery: base URL is out of scope")
+	ErrEmptyWordlist = errcode.New(errcode.Invalid, "discovery: wordlist is empty")
+	ErrInvalidRate   = errcode.New(errcode.Invalid, "discovery: requests per second must be at most 1000")
+)
+
+// DefaultWordlist is used when a scan is started without a wordlist.
+var DefaultWordlist = []string{
+	".env", ".git/HEAD", ".htaccess", ".well-known/security.txt", "admin", "api", "backup", "config",
+	"console", "debug", "login", "phpinfo.php", "robots.txt", "server-status", "sitemap.xml", "swagger.json",
+	"test", "upload", "uploads", "wp-admin",
+}
+
+// DefaultIgnoreStatusCodes are status codes of responses that aren't considered
+// a discovered path.
+var DefaultIgnoreStatusCodes = []int{http.StatusNotFound}
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusFinished  Status = "finished"
+	StatusCancelled Status = "cancelled"
+)
+
+// Service runs content discovery scans, which request paths from a wordlist
+// relative to a base URL.
+type Service interface {
+	StartScan(ctx context.Context, params ScanParams) (Scan, error)
+	FindScanByID(id ulid.ULID) (Scan, error)
+	FindScans() []Scan
+	CancelScan(id ulid.ULID) error
+}
+
+// RequestLogTagger tags request logs, e.g. `reqlog.Service`.
+type RequestLogTagger interface {
+	TagRequests(ctx context.Context, sel reqlog.Selection, add, remove []string) (int, error)
+}
+
+type service struct {
+	scope      *scope.Scope
+	ids        idgen.Generator
+	jobs       job.Service
+	reqLogs    RequestLogTagger
+	httpClient *http.Client
+	scans      map[ulid.ULID]*scanState
+	mu         sync.RWMutex
+}
+
+type Config struct {
+	Scope *scope.Scope
+	// Transport used for outgoing requests. Typically the proxy itself, so
+	// that requests are logged like proxied traffic.
+	Transport http.RoundTripper
+	// Tags the request logs of discovered paths with `DiscoveredTag`, so that
+	// results are stored in the site map of the project. Optional.
+	RequestLogs RequestLogTagger
+	// Generates the IDs of scans. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+	// Runs scans as jobs, so that they're queued, report progress and can be
+	// paused. Optional; without it, scans start right away.
+	Jobs job.Service
+}
+
+type ScanParams struct {
+	BaseURL    *url.URL
+	Wordlist   []string
+	Extensions []string
+	// Maximum number of requests per second. Defaults to 10, and can be at
+	// most 1000.
+	RequestsPerSecond int
+	// Maximum number of requests in flight. Defaults to 5.
+	Concurrency       int
+	IgnoreStatusCodes []int
+}
+
+type Scan struct {
+	ID        ulid.ULID
+	BaseURL   *url.URL
+	Status    Status
+	Total     int
+	Completed int
+	Results   []Result
+}
+
+// Result is a discovered path.
+type Result struct {
+	URL          *url.URL
+	StatusCode   int
+	Size         int64
+	RequestLogID ulid.ULID
+}
+
+type scanState struct {
+	scan   Scan
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &service{
+		ids:     cfg.IDGenerator,
+		scope:   cfg.Scope,
+		jobs:    cfg.Jobs,
+		reqLogs: cfg.RequestLogs,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   30 * time.Second,
+			// Redirects are not followed, as they are a result in themselves.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		scans: make(map[ulid.ULID]*scanState),
+	}
+}
+
+// StartScan starts a scan in the background. The base URL must match the
+// project scope.
+func (svc *service) StartScan(ctx context.Context, params ScanParams) (Scan, error) {
+	if params.BaseURL == nil || params.BaseURL.Host == "" {
+		return Scan{}, errcode.New(errcode.Invalid, "discovery: base URL must be absolute")
+	}
+
+	baseURL := *params.BaseURL
+	if !strings.HasSuffix(baseURL.Path, "/") {
+		baseURL.Path += "/"
+	}
+
+	if svc.scope != nil && !svc.scope.Match(&http.Request{URL: &baseURL, Header: http.Header{}}, nil) {
+		return Scan{}, ErrOutOfScope
+	}
+
+	wordlist := params.Wordlist
+	if wordlist == nil {
+		wordlist = DefaultWordlist
+	}
+
+	urls := buildURLs(&baseURL, wordlist, params.Extensions)
+	if len(urls) == 0 {
+		return Scan{}, ErrEmptyWordlist
+	}
+
+	if params.RequestsPerSecond <= 0 {
+		params.RequestsPerSecond = defaultRequestsPerSecond
+	}
+
+	if params.RequestsPerSecond > maxRequestsPerSecond {
+		return Scan{}, ErrInvalidRate
+	}
+
+	if params.Concurrency <= 0 {
+		params.Concurrency = defaultConcurrency
+	}
+
+	if params.IgnoreStatusCodes == nil {
+		params.IgnoreStatusCodes = DefaultIgnoreStatusCodes
+	}
+
+	scanCtx, cancel := context.WithCancel(context.Background())
+
+	state := &scanState{
+		scan: Scan{
+			ID:      svc.ids.New(time.Now()),
+			BaseURL: &baseURL,
+			Status:  StatusRunning,
+			Total:   len(urls),
+			Results: make([]Result, 0),
+		},
+		cancel: cancel,
+	}
+
+	svc.mu.Lock()
+	svc.scans[state.scan.ID] = state
+	svc.mu.Unlock()
+
+	if svc.jobs == nil {
+		go svc.run(scanCtx, state, urls, params, nil)
+		return state.snapshot(), nil
+	}
+
+	jobParams := job.Params{
+		ID:          state.scan.ID,
+		Type:        JobType,
+		Description: baseURL.String(),
+		Total:       len(urls),
+	}
+
+	svc.jobs.StartJob(scanCtx, jobParams, func(ctx context.Context, progress *job.Progress) error {
+		return svc.run(ctx, state, urls, params, progress)
+	})
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindScanByID(id ulid.ULID) (Scan, error) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	state, ok := svc.scans[id]
+	if !ok {
+		return Scan{}, ErrScanNotFound
+	}
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindScans() []Scan {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	scans := make([]Scan, 0, len(svc.scans))
+	for _, state := range svc.scans {
+		scans = append(scans, state.snapshot())
+	}
+
+	// Most recent scans first.
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].ID.Compare(scans[j].ID) > 0
+	})
+
+	return scans
+}
+
+func (svc *service) CancelScan(id ulid.ULID) error {
+	svc.mu.RLock()
+	state, ok := svc.scans[id]
+	svc.mu.RUnlock()
+
+	if !ok {
+		return ErrScanNotFound
+	}
+
+	state.mu.Lock()
+	if state.scan.Status == StatusRunning {
+		state.scan.Status = StatusCancelled
+	}
+	state.mu.Unlock()
+
+	state.cancel()
+
+	return nil
+}
+
+func (svc *service) run(
+	ctx context.Context,
+	state *scanState,
+	urls []*url.URL,
+	params ScanParams,
+	progress *job.Progress,
+) error {
+	defer state.cancel()
+
+	ticker := time.NewTicker(time.Second / time.Duration(params.RequestsPerSecond))
+	defer ticker.Stop()
+
+	urlCh := make(chan *url.URL)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < params.Concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for u := range urlCh {
+				result, ok := svc.probe(ctx, u, params.IgnoreStatusCodes)
+				if ok {
+					svc.tagResult(ctx, result)
+				}
+
+				state.addResult(result, ok)
+				progress.Add(1)
+			}
+		}()
+	}
+
+loop:
+	for _, u := range urls {
+		if err := progress.Wait(ctx); err != nil {
+			break loop
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			break loop
+		case urlCh <- u:
+		}
+	}
+
+	close(urlCh)
+	wg.Wait()
+
+	state.mu.Lock()
+	if state.scan.Status == StatusRunning {
+		state.scan.Status = StatusFinished
+		if ctx.Err() != nil {
+			state.scan.Status = StatusCancelled
+		}
+	}
+	state.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// probe requests u, and reports if the response indicates that the path exists.
+func (svc *service) probe(ctx context.Context, u *url.URL, ignoreStatusCodes []int) (Result, bool) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Result{}, false
+	}
+
+	res, err := svc.httpClient.Do(req)
+	if err != nil {
+		return Result{}, false
+	}
+	defer res.Body.Close()
+
+	size, err := io.Copy(ioutil.Discard, res.Body)
+	if err != nil {
+		return Result{}, false
+	}
+
+	for _, code := range ignoreStatusCodes {
+		if res.StatusCode == code {
+			return Result{}, false
+		}
+	}
+
+	result := Result{
+		URL:        u,
+		StatusCode: res.StatusCode,
+		Size:       size,
+	}
+
+	if res.Request != nil {
+		if reqLogID, ok := res.Request.Context().Value(proxy.ReqLogIDKey).(ulid.ULID); ok {
+			result.RequestLogID = reqLogID
+		}
+	}
+
+	return result, true
+}
+
+// tagResult tags the request log of a discovered path, if it was logged.
+func (svc *service) tagResult(ctx context.Context, result Result) {
+	if svc.reqLogs == nil || result.RequestLogID.Compare(ulid.ULID{}) == 0 {
+		return
+	}
+
+	sel := reqlog.Selection{IDs: []ulid.ULID{result.RequestLogID}}
+
+	if _, err := svc.reqLogs.TagRequests(ctx, sel, []string{DiscoveredTag}, nil); err != nil && ctx.Err() == nil {
+		log.Printf("[ERROR] Could not tag request log of discovered path (id: %v): %v", result.RequestLogID, err)
+	}
+}
+
+func (state *scanState) addResult(result Result, ok bool) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.scan.Completed++
+
+	if ok {
+		state.scan.Results = append(state.scan.Results, result)
+	}
+}
+
+func (state *scanState) snapshot() Scan {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	scan := state.scan
+	scan.Results = make([]Result, len(state.scan.Results))
+	copy(scan.Results, state.scan.Results)
+
+	return scan
+}
+
+func buildURLs(baseURL *url.URL, wordlist, extensions []string) []*url.URL {
+	urls := make([]*url.URL, 0, len(wordlist)*(len(extensions)+1))
+	seen := make(map[string]struct{})
+
+	for _, word := range wordlist {
+		word = strings.TrimLeft(strings.TrimSpace(word), "/")
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+
+		paths := []string{word}
+		for _, ext := range extensions {
+			paths = append(paths, word+"."+strings.TrimPrefix(ext, "."))
+		}
+
+		for _, path := range paths {
+			ref, err := url.Parse(path)
+			if err != nil {
+				continue
+			}
+
+			u := baseURL.ResolveReference(ref)
+			if _, ok := seen[u.String()]; ok {
+				continue
+			}
+
+			seen[u.String()] = struct{}{}
+			urls = append(urls, u)
+		}
+	}
+
+	return urls
+}
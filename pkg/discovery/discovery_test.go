@@ -0,0 +1,195 @@
+package discovery_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/discovery"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+func TestStartScan(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/admin":
+			w.Write([]byte("foobar"))
+		case "/app/login.php":
+			http.Redirect(w, r, "/app/admin", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	baseURL, err := url.Parse(ts.URL + "/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("out of scope", func(t *testing.T) {
+		svc := discovery.NewService(discovery.Config{Scope: &scope.Scope{}})
+
+		_, err := svc.StartScan(context.Background(), discovery.ScanParams{BaseURL: baseURL})
+		if !errors.Is(err, discovery.ErrOutOfScope) {
+			t.Fatalf("expected `discovery.ErrOutOfScope`, got: %v", err)
+		}
+	})
+
+	t.Run("too many requests per second", func(t *testing.T) {
+		svc := discovery.NewService(discovery.Config{})
+
+		_, err := svc.StartScan(context.Background(), discovery.ScanParams{BaseURL: baseURL, RequestsPerSecond: 2e9})
+		if !errors.Is(err, discovery.ErrInvalidRate) {
+			t.Fatalf("expected `discovery.ErrInvalidRate`, got: %v", err)
+		}
+	})
+
+	t.Run("finds paths", func(t *testing.T) {
+		s := &scope.Scope{}
+		s.SetRules([]scope.Rule{{URL: regexp.MustCompile(regexp.QuoteMeta(ts.URL))}})
+
+		tagger := &taggerMock{}
+		svc := discovery.NewService(discovery.Config{
+			Scope:       s,
+			Transport:   logTransport{},
+			RequestLogs: tagger,
+		})
+
+		scan, err := svc.StartScan(context.Background(), discovery.ScanParams{
+			BaseURL:           baseURL,
+			Wordlist:          []string{"admin", "/login", "# comment", "", "foo"},
+			Extensions:        []string{".php"},
+			RequestsPerSecond: 100,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if scan.Total != 6 {
+			t.Fatalf("incorrect total (expected: 6, got: %v)", scan.Total)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+
+		for scan.Status == discovery.StatusRunning {
+			if time.Now().After(deadline) {
+				t.Fatal("scan did not finish in time")
+			}
+
+			time.Sleep(10 * time.Millisecond)
+
+			scan, err = svc.FindScanByID(scan.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if scan.Status != discovery.StatusFinished {
+			t.Fatalf("incorrect status (expected: %v, got: %v)", discovery.StatusFinished, scan.Status)
+		}
+
+		got := make(map[string]discovery.Result)
+		for _, result := range scan.Results {
+			got[result.URL.Path] = result
+		}
+
+		if len(got) != 2 {
+			t.Fatalf("incorrect number of results (expected: 2, got: %v)", len(got))
+		}
+
+		if res := got["/app/admin"]; res.StatusCode != http.StatusOK || res.Size != 6 {
+			t.Errorf("incorrect result for `/app/admin` (got: %+v)", res)
+		}
+
+		if res := got["/app/login.php"]; res.StatusCode != http.StatusFound {
+			t.Errorf("incorrect result for `/app/login.php` (got: %+v)", res)
+		}
+
+		// Only the request logs of results are tagged.
+		tagged := tagger.taggedIDs()
+		if len(tagged) != 2 {
+			t.Fatalf("expected 2 tagged request logs, got: %v", tagged)
+		}
+
+		for _, result := range scan.Results {
+			if _, ok := tagged[result.RequestLogID]; !ok {
+				t.Errorf("expected request log of %v to be tagged", result.URL)
+			}
+		}
+	})
+}
+
+// logTransport sets a request log ID on requests, like the proxy does for
+// logged requests.
+type logTransport struct{}
+
+func (logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy())
+	req = req.WithContext(context.WithValue(req.Context(), proxy.ReqLogIDKey, reqLogID))
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+type taggerMock struct {
+	mu     sync.Mutex
+	tagged map[ulid.ULID]struct{}
+}
+
+func (m *taggerMock) TagRequests(_ context.Context, sel reqlog.Selection, add, _ []string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.tagged == nil {
+		m.tagged = make(map[ulid.ULID]struct{})
+	}
+
+	if len(add) != 1 || add[0] != discovery.DiscoveredTag {
+		return 0, errors.New("unexpected tags")
+	}
+
+	for _, id := range sel.IDs {
+		m.tagged[id] = struct{}{}
+	}
+
+	return len(sel.IDs), nil
+}
+
+func (m *taggerMock) taggedIDs() map[ulid.ULID]struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.tagged
+}
+
+var (
+	entropyMu sync.Mutex
+	entropy   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// ulidEntropy returns entropy for a single ID, because requests are sent
+// concurrently.
+func ulidEntropy() io.Reader {
+	entropyMu.Lock()
+	defer entropyMu.Unlock()
+
+	b := make([]byte, 10)
+	entropy.Read(b)
+
+	return bytes.NewReader(b)
+}
@@ -0,0 +1,327 @@
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Resource record types.
+const (
+	TypeA    uint16 = 1
+	TypeNS   uint16 = 2
+	TypePTR  uint16 = 12
+	TypeTXT  uint16 = 16
+	TypeAAAA uint16 = 28
+	TypeSRV  uint16 = 33
+	TypeANY  uint16 = 255
+)
+
+// ClassINET is the Internet class.
+const ClassINET uint16 = 1
+
+// Header flags.
+const (
+	FlagResponse      uint16 = 1 << 15
+	FlagAuthoritative uint16 = 1 << 10
+)
+
+const headerLen = 12
+
+var (
+	ErrShortMessage = errors.New("dns: message too short")
+	ErrInvalidName  = errors.New("dns: invalid name")
+)
+
+var typeStrings = map[uint16]string{
+	TypeA:    "A",
+	TypeNS:   "NS",
+	TypePTR:  "PTR",
+	TypeTXT:  "TXT",
+	TypeAAAA: "AAAA",
+	TypeSRV:  "SRV",
+	TypeANY:  "ANY",
+}
+
+// Message is a DNS message.
+type Message struct {
+	ID         uint16
+	Flags      uint16
+	Questions  []Question
+	Answers    []Resource
+	Additional []Resource
+}
+
+// Question is an entry in the question section of a message.
+type Question struct {
+	Name  string
+	Type  uint16
+	Class uint16
+}
+
+// Resource is a resource record. Data contains the (already encoded) RDATA.
+type Resource struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+	Data  []byte
+}
+
+// TypeString returns a human readable representation of a record type.
+func TypeString(t uint16) string {
+	if s, ok := typeStrings[t]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+// Parse decodes a DNS message. Only the header, question, answer and additional
+// sections are decoded; the authority section is skipped.
+func Parse(b []byte) (Message, error) {
+	if len(b) < headerLen {
+		return Message{}, ErrShortMessage
+	}
+
+	msg := Message{
+		ID:    binary.BigEndian.Uint16(b[0:2]),
+		Flags: binary.BigEndian.Uint16(b[2:4]),
+	}
+
+	qdCount := int(binary.BigEndian.Uint16(b[4:6]))
+	anCount := int(binary.BigEndian.Uint16(b[6:8]))
+	nsCount := int(binary.BigEndian.Uint16(b[8:10]))
+	arCount := int(binary.BigEndian.Uint16(b[10:12]))
+
+	off := headerLen
+
+	for i := 0; i < qdCount; i++ {
+		name, n, err := readName(b, off)
+		if err != nil {
+			return Message{}, err
+		}
+
+		off = n
+
+		if len(b) < off+4 {
+			return Message{}, ErrShortMessage
+		}
+
+		msg.Questions = append(msg.Questions, Question{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(b[off : off+2]),
+			Class: binary.BigEndian.Uint16(b[off+2 : off+4]),
+		})
+		off += 4
+	}
+
+	var err error
+
+	msg.Answers, off, err = readResources(b, off, anCount)
+	if err != nil {
+		return Message{}, err
+	}
+
+	_, off, err = readResources(b, off, nsCount)
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg.Additional, _, err = readResources(b, off, arCount)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return msg, nil
+}
+
+// Encode returns the wire format of the message. Names are not compressed.
+func (msg Message) Encode() ([]byte, error) {
+	b := make([]byte, headerLen, 512)
+	binary.BigEndian.PutUint16(b[0:2], msg.ID)
+	binary.BigEndian.PutUint16(b[2:4], msg.Flags)
+	binary.BigEndian.PutUint16(b[4:6], uint16(len(msg.Questions)))
+	binary.BigEndian.PutUint16(b[6:8], uint16(len(msg.Answers)))
+	binary.BigEndian.PutUint16(b[10:12], uint16(len(msg.Additional)))
+
+	var err error
+
+	for _, q := range msg.Questions {
+		b, err = appendName(b, q.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		b = appendUint16(b, q.Type)
+		b = appendUint16(b, q.Class)
+	}
+
+	for _, rrs := range [][]Resource{msg.Answers, msg.Additional} {
+		for _, rr := range rrs {
+			b, err = appendName(b, rr.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			b = appendUint16(b, rr.Type)
+			b = appendUint16(b, rr.Class)
+			b = appendUint16(b, uint16(rr.TTL>>16))
+			b = appendUint16(b, uint16(rr.TTL))
+			b = appendUint16(b, uint16(len(rr.Data)))
+			b = append(b, rr.Data...)
+		}
+	}
+
+	return b, nil
+}
+
+// Reply returns a response message for msg, with its questions copied.
+func (msg Message) Reply() Message {
+	return Message{
+		ID:        msg.ID,
+		Flags:     FlagResponse | FlagAuthoritative,
+		Questions: msg.Questions,
+	}
+}
+
+// AData returns the RDATA of an A or AAAA record for ip.
+func AData(ip net.IP) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+
+	return ip.To16()
+}
+
+// NameData returns the RDATA of a record containing a single name, e.g. PTR.
+func NameData(name string) ([]byte, error) {
+	return appendName(nil, name)
+}
+
+// TXTData returns the RDATA of a TXT record.
+func TXTData(txts ...string) []byte {
+	var b []byte
+
+	for _, txt := range txts {
+		if len(txt) > 255 {
+			txt = txt[:255]
+		}
+
+		b = append(b, byte(len(txt)))
+		b = append(b, txt...)
+	}
+
+	return b
+}
+
+// SRVData returns the RDATA of a SRV record.
+func SRVData(priority, weight, port uint16, target string) ([]byte, error) {
+	b := appendUint16(nil, priority)
+	b = appendUint16(b, weight)
+	b = appendUint16(b, port)
+
+	return appendName(b, target)
+}
+
+func readResources(b []byte, off, count int) ([]Resource, int, error) {
+	var rrs []Resource
+
+	for i := 0; i < count; i++ {
+		name, n, err := readName(b, off)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		off = n
+
+		if len(b) < off+10 {
+			return nil, 0, ErrShortMessage
+		}
+
+		rr := Resource{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(b[off : off+2]),
+			Class: binary.BigEndian.Uint16(b[off+2 : off+4]),
+			TTL:   binary.BigEndian.Uint32(b[off+4 : off+8]),
+		}
+		dataLen := int(binary.BigEndian.Uint16(b[off+8 : off+10]))
+		off += 10
+
+		if len(b) < off+dataLen {
+			return nil, 0, ErrShortMessage
+		}
+
+		rr.Data = b[off : off+dataLen]
+		off += dataLen
+
+		rrs = append(rrs, rr)
+	}
+
+	return rrs, off, nil
+}
+
+// readName reads a (possibly compressed) name at offset off, and returns the
+// name and the offset of the first byte after it.
+func readName(b []byte, off int) (string, int, error) {
+	var labels []string
+
+	next := -1
+
+	for hops := 0; ; hops++ {
+		if off >= len(b) || hops > 127 {
+			return "", 0, ErrInvalidName
+		}
+
+		l := int(b[off])
+
+		switch {
+		case l == 0:
+			if next == -1 {
+				next = off + 1
+			}
+
+			return strings.Join(labels, ".") + ".", next, nil
+		case l&0xC0 == 0xC0:
+			if off+1 >= len(b) {
+				return "", 0, ErrInvalidName
+			}
+
+			if next == -1 {
+				next = off + 2
+			}
+
+			off = int(binary.BigEndian.Uint16(b[off:off+2]) & 0x3FFF)
+		default:
+			if off+1+l > len(b) {
+				return "", 0, ErrInvalidName
+			}
+
+			labels = append(labels, string(b[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+}
+
+func appendName(b []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+			}
+
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+
+	return append(b, 0), nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
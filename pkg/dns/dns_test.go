@@ -0,0 +1,98 @@
+package dns_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dstotijn/hetty/pkg/dns"
+)
+
+func TestEncodeParse(t *testing.T) {
+	t.Parallel()
+
+	exp := dns.Message{
+		ID:    0xbeef,
+		Flags: dns.FlagResponse | dns.FlagAuthoritative,
+		Questions: []dns.Question{
+			{Name: "foo.example.com.", Type: dns.TypeA, Class: dns.ClassINET},
+		},
+		Answers: []dns.Resource{
+			{
+				Name:  "foo.example.com.",
+				Type:  dns.TypeA,
+				Class: dns.ClassINET,
+				TTL:   60,
+				Data:  dns.AData(net.ParseIP("192.0.2.1")),
+			},
+		},
+		Additional: []dns.Resource{
+			{
+				Name:  "foo.example.com.",
+				Type:  dns.TypeTXT,
+				Class: dns.ClassINET,
+				TTL:   3600,
+				Data:  dns.TXTData("foo=bar"),
+			},
+		},
+	}
+
+	b, err := exp.Encode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := dns.Parse(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("message not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestParseCompressedName(t *testing.T) {
+	t.Parallel()
+
+	b := []byte{
+		0x00, 0x01, 0x81, 0x80, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+		// Question: example.com. A IN
+		0x07, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0x03, 'c', 'o', 'm', 0x00, 0x00, 0x01, 0x00, 0x01,
+		// Answer with name pointer to offset 12.
+		0xc0, 0x0c, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x3c, 0x00, 0x04, 192, 0, 2, 1,
+	}
+
+	msg, err := dns.Parse(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(msg.Answers) != 1 {
+		t.Fatalf("incorrect number of answers (expected: 1, got: %v)", len(msg.Answers))
+	}
+
+	if exp := "example.com."; exp != msg.Answers[0].Name {
+		t.Errorf("incorrect answer name (expected: %v, got: %v)", exp, msg.Answers[0].Name)
+	}
+
+	if exp := net.ParseIP("192.0.2.1").To4(); !net.IP(msg.Answers[0].Data).Equal(exp) {
+		t.Errorf("incorrect answer data (expected: %v, got: %v)", exp, net.IP(msg.Answers[0].Data))
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, b := range [][]byte{
+		{0x00},
+		{0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07, 'e'},
+		// Name pointer loop.
+		{0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x0c, 0x00, 0x01, 0x00, 0x01},
+	} {
+		if _, err := dns.Parse(b); err == nil {
+			t.Errorf("expected error for message %x, got: nil", b)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package errcode classifies errors with machine-readable codes, so that API
+// clients can tell e.g. a missing request log apart from a broken database.
+package errcode
+
+import (
+	"errors"
+)
+
+// Code is the kind of an error, as surfaced by the GraphQL (`code` extension)
+// and REST (`error.code` field) APIs.
+type Code string
+
+const (
+	// A requested entity, e.g. a request log or project, doesn't exist.
+	NotFound Code = "not_found"
+	// Input of a request is invalid, e.g. a malformed schedule.
+	Invalid Code = "invalid_request"
+	// Reading from or writing to the database failed.
+	Storage Code = "storage_error"
+	// An upstream server, e.g. the target of a sender request, couldn't be
+	// reached.
+	Upstream Code = "upstream_error"
+	// Errors without a code.
+	Internal Code = "internal_error"
+)
+
+// Coder is implemented by errors that have a code.
+type Coder interface {
+	ErrorCode() Code
+}
+
+// Error is an error with a code.
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+// New returns an error with a code, e.g. for sentinel errors. Errors returned
+// by New are distinct, see `errors.New`.
+func New(code Code, text string) error {
+	return &Error{Code: code, Err: errors.New(text)}
+}
+
+// Wrap returns err with a code, unless an error in its chain already has one,
+// so that e.g. a not found error stays one when a repository wraps it as a
+// storage error. It returns nil if err is nil.
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var coder Coder
+	if errors.As(err, &coder) {
+		return err
+	}
+
+	return &Error{Code: code, Err: err}
+}
+
+// Of returns the code of the first error in the chain of err that has one, or
+// `Internal` if none has.
+func Of(err error) Code {
+	var coder Coder
+	if errors.As(err, &coder) {
+		return coder.ErrorCode()
+	}
+
+	return Internal
+}
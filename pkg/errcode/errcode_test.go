@@ -0,0 +1,51 @@
+package errcode_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+func TestOf(t *testing.T) {
+	t.Parallel()
+
+	errNotFound := errcode.New(errcode.NotFound, "foo: not found")
+
+	tests := []struct {
+		name    string
+		err     error
+		expCode errcode.Code
+	}{
+		{name: "without code", err: errors.New("foo"), expCode: errcode.Internal},
+		{name: "with code", err: errNotFound, expCode: errcode.NotFound},
+		{name: "wrapped", err: fmt.Errorf("could not get foo: %w", errNotFound), expCode: errcode.NotFound},
+		{name: "wrapped with code", err: errcode.Wrap(errcode.Storage, errors.New("disk full")), expCode: errcode.Storage},
+		{
+			name:    "code of wrapped error is kept",
+			err:     errcode.Wrap(errcode.Storage, fmt.Errorf("db: %w", errNotFound)),
+			expCode: errcode.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := errcode.Of(tt.err); got != tt.expCode {
+				t.Errorf("expected code: %q, got: %q", tt.expCode, got)
+			}
+		})
+	}
+
+	if !errors.Is(errcode.Wrap(errcode.Storage, fmt.Errorf("db: %w", errNotFound)), errNotFound) {
+		t.Error("expected wrapped error to match with `errors.Is`")
+	}
+
+	if errcode.Wrap(errcode.Storage, nil) != nil {
+		t.Error("expected nil error to stay nil")
+	}
+}
@@ -0,0 +1,148 @@
+// Package event implements an in-process bus for notifications between
+// services, e.g. for reacting to stored request logs without coupling to the
+// service that stores them.
+package event
+
+import (
+	"log"
+	"sync"
+
+	"github.com/oklog/ulid"
+)
+
+// Type identifies the kind of an event, and the type of its data.
+type Type string
+
+const (
+	// TypeRequestLogStored is published when a proxied request is logged.
+	// Data is a `reqlog.RequestLog` (without response).
+	TypeRequestLogStored Type = "request_log.stored"
+	// TypeResponseLogStored is published when the response of a logged
+	// request is stored. ID is the request log ID, and data is a
+	// `reqlog.ResponseLog`.
+	TypeResponseLogStored Type = "response_log.stored"
+	// TypeProjectOpened and TypeProjectClosed are published when the active
+	// project changes. Data is nil; see `proj.Service.IsReadOnly` for whether
+	// the opened project is read-only.
+	TypeProjectOpened Type = "project.opened"
+	TypeProjectClosed Type = "project.closed"
+	// TypeFindingCreated is published when a finding is stored. Data is a
+	// `finding.Finding`.
+	TypeFindingCreated Type = "finding.created"
+	// TypeSenderEnvironmentsChanged is published when a post-response script
+	// of a sender request extracts variables into the active environment.
+	// Data is the updated `sender.Environments`, to be stored with the project.
+	TypeSenderEnvironmentsChanged Type = "sender.environments_changed"
+	// TypeOAuth2TokensChanged is published when OAuth 2.0 tokens are fetched
+	// or discarded. Data is nil; see `oauth2.Service.Tokens`.
+	TypeOAuth2TokensChanged Type = "oauth2.tokens_changed"
+)
+
+type Event struct {
+	Type      Type
+	ProjectID ulid.ULID
+	// ID of the subject of the event, e.g. a request log ID.
+	ID   ulid.ULID
+	Data interface{}
+}
+
+// Handler handles an event. Handlers are called synchronously by the
+// publisher (e.g. while a request is proxied), so long running work should be
+// done in a goroutine.
+type Handler func(Event)
+
+type subscription struct {
+	id      uint64
+	types   map[Type]struct{}
+	handler Handler
+}
+
+// Bus delivers published events to subscribers. Publishing on a nil *Bus is
+// a no-op, so that services don't require one.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []subscription
+	nextID uint64
+}
+
+// NewBus returns a new Bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler for events of the given types, or for all
+// events if no types are given. The returned function unsubscribes.
+func (b *Bus) Subscribe(handler Handler, types ...Type) (unsubscribe func()) {
+	sub := subscription{handler: handler}
+
+	if len(types) > 0 {
+		sub.types = make(map[Type]struct{}, len(types))
+		for _, t := range types {
+			sub.types[t] = struct{}{}
+		}
+	}
+
+	b.mu.Lock()
+	b.nextID++
+	sub.id = b.nextID
+
+	// Copy on write, so that publishing doesn't hold the lock while calling
+	// handlers.
+	subs := make([]subscription, len(b.subs), len(b.subs)+1)
+	copy(subs, b.subs)
+	b.subs = append(subs, sub)
+	b.mu.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() { b.unsubscribe(sub.id) })
+	}
+}
+
+func (b *Bus) unsubscribe(id uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := make([]subscription, 0, len(b.subs))
+
+	for _, sub := range b.subs {
+		if sub.id != id {
+			subs = append(subs, sub)
+		}
+	}
+
+	b.subs = subs
+}
+
+// Publish calls the handlers that are subscribed to the type of e, in the
+// order they subscribed. A handler that panics doesn't affect the others.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.types != nil {
+			if _, ok := sub.types[e.Type]; !ok {
+				continue
+			}
+		}
+
+		callHandler(sub.handler, e)
+	}
+}
+
+func callHandler(handler Handler, e Event) {
+	defer func() {
+		if v := recover(); v != nil {
+			log.Printf("[ERROR] Event handler for %q panicked: %v", e.Type, v)
+		}
+	}()
+
+	handler(e)
+}
@@ -0,0 +1,50 @@
+package event_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dstotijn/hetty/pkg/event"
+)
+
+func TestBus(t *testing.T) {
+	t.Parallel()
+
+	bus := event.NewBus()
+
+	var got []string
+
+	bus.Subscribe(func(e event.Event) {
+		panic("boom")
+	})
+	unsubscribe := bus.Subscribe(func(e event.Event) {
+		got = append(got, "all: "+string(e.Type))
+	})
+	bus.Subscribe(func(e event.Event) {
+		got = append(got, "projects: "+string(e.Type))
+	}, event.TypeProjectOpened, event.TypeProjectClosed)
+
+	bus.Publish(event.Event{Type: event.TypeProjectOpened})
+	bus.Publish(event.Event{Type: event.TypeRequestLogStored})
+
+	unsubscribe()
+	unsubscribe()
+
+	bus.Publish(event.Event{Type: event.TypeProjectClosed})
+
+	exp := []string{
+		"all: project.opened",
+		"projects: project.opened",
+		"all: request_log.stored",
+		"projects: project.closed",
+	}
+
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("handled events not equal (-exp, +got):\n%v", diff)
+	}
+
+	// Publishing on a nil bus is a no-op.
+	var nilBus *event.Bus
+	nilBus.Publish(event.Event{Type: event.TypeProjectOpened})
+}
@@ -0,0 +1,212 @@
+package finding
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Check string
+
+const (
+	CheckCORSWildcardCredentials   Check = "cors_wildcard_credentials"
+	CheckCORSReflectedOrigin       Check = "cors_reflected_origin"
+	CheckCORSNullOrigin            Check = "cors_null_origin"
+	CheckMissingCSP                Check = "missing_csp"
+	CheckMissingFrameOptions       Check = "missing_frame_options"
+	CheckMissingContentTypeOptions Check = "missing_content_type_options"
+	CheckMissingHSTS               Check = "missing_hsts"
+	CheckCookieMissingSecure       Check = "cookie_missing_secure"
+	CheckCookieMissingHTTPOnly     Check = "cookie_missing_http_only"
+	CheckCookieMissingSameSite     Check = "cookie_missing_same_site"
+	CheckReflectedInput            Check = "reflected_input"
+	CheckStoredReflectedInput      Check = "stored_reflected_input"
+
+	// Active checks, see packages `smuggle`, `authz`, `idor`, `activescan` and
+	// `nuclei`.
+	CheckRequestSmugglingCLTE Check = "request_smuggling_cl_te"
+	CheckRequestSmugglingTECL Check = "request_smuggling_te_cl"
+	CheckAuthorizationBypass  Check = "authorization_bypass"
+	CheckAuthenticationBypass Check = "authentication_bypass"
+	CheckIDORCandidate        Check = "idor_candidate"
+	CheckSQLInjection         Check = "sql_injection"
+	CheckPathTraversal        Check = "path_traversal"
+	CheckTemplateInjection    Check = "template_injection"
+	CheckActiveScanMatch      Check = "active_scan_match"
+	CheckNucleiTemplate       Check = "nuclei_template"
+)
+
+// Analyze runs passive checks on the headers of a response, and returns the
+// findings. The ID, project ID and request log ID of findings aren't set.
+func Analyze(req *http.Request, res *http.Response) []Finding {
+	var findings []Finding
+
+	findings = append(findings, checkCORS(req, res.Header)...)
+	findings = append(findings, checkSecurityHeaders(req, res)...)
+	findings = append(findings, checkCookies(req, res.Header)...)
+
+	return findings
+}
+
+func checkCORS(req *http.Request, header http.Header) []Finding {
+	allowOrigin := header.Get("Access-Control-Allow-Origin")
+	if allowOrigin == "" {
+		return nil
+	}
+
+	credentials := strings.EqualFold(header.Get("Access-Control-Allow-Credentials"), "true")
+
+	switch {
+	case allowOrigin == "*" && credentials:
+		return []Finding{{
+			Check:    CheckCORSWildcardCredentials,
+			Severity: SeverityMedium,
+			Description: "Any origin is allowed, together with credentials. Browsers reject this combination, " +
+				"but it indicates the policy is likely to be relaxed in other ways.",
+			HeaderKey:   "Access-Control-Allow-Origin",
+			HeaderValue: allowOrigin,
+		}}
+	case allowOrigin == "null":
+		return []Finding{{
+			Check:    CheckCORSNullOrigin,
+			Severity: SeverityMedium,
+			Description: "The `null` origin is allowed. Sandboxed iframes and local files have this origin, " +
+				"so any site can make cross-origin requests.",
+			HeaderKey:   "Access-Control-Allow-Origin",
+			HeaderValue: allowOrigin,
+		}}
+	}
+
+	origin := req.Header.Get("Origin")
+	if origin == "" || origin != allowOrigin || isSameOrigin(req, origin) {
+		return nil
+	}
+
+	finding := Finding{
+		Check:       CheckCORSReflectedOrigin,
+		Severity:    SeverityLow,
+		Description: "The request origin is reflected as allowed origin.",
+		HeaderKey:   "Access-Control-Allow-Origin",
+		HeaderValue: allowOrigin,
+	}
+
+	if credentials {
+		finding.Severity = SeverityHigh
+		finding.Description = "The request origin is reflected as allowed origin, with credentials allowed. " +
+			"Any site can read responses of authenticated requests."
+	}
+
+	return []Finding{finding}
+}
+
+func isSameOrigin(req *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || req.URL == nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Scheme, req.URL.Scheme) && strings.EqualFold(u.Host, req.URL.Host)
+}
+
+func checkSecurityHeaders(req *http.Request, res *http.Response) []Finding {
+	var findings []Finding
+
+	isHTTPS := req.URL != nil && req.URL.Scheme == "https"
+
+	if isHTTPS && res.Header.Get("Strict-Transport-Security") == "" {
+		findings = append(findings, Finding{
+			Check:       CheckMissingHSTS,
+			Severity:    SeverityLow,
+			Description: "HTTPS response without `Strict-Transport-Security` header.",
+			HeaderKey:   "Strict-Transport-Security",
+		})
+	}
+
+	// Other security headers are only relevant for documents.
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "text/html" || res.StatusCode < 200 || res.StatusCode >= 300 {
+		return findings
+	}
+
+	csp := res.Header.Get("Content-Security-Policy")
+
+	if csp == "" {
+		findings = append(findings, Finding{
+			Check:       CheckMissingCSP,
+			Severity:    SeverityLow,
+			Description: "HTML response without `Content-Security-Policy` header.",
+			HeaderKey:   "Content-Security-Policy",
+		})
+	}
+
+	if res.Header.Get("X-Frame-Options") == "" && !strings.Contains(strings.ToLower(csp), "frame-ancestors") {
+		findings = append(findings, Finding{
+			Check:    CheckMissingFrameOptions,
+			Severity: SeverityLow,
+			Description: "HTML response without `X-Frame-Options` header or `frame-ancestors` directive, " +
+				"so it can be framed by other sites (clickjacking).",
+			HeaderKey: "X-Frame-Options",
+		})
+	}
+
+	if !strings.EqualFold(res.Header.Get("X-Content-Type-Options"), "nosniff") {
+		findings = append(findings, Finding{
+			Check:       CheckMissingContentTypeOptions,
+			Severity:    SeverityInfo,
+			Description: "HTML response without `X-Content-Type-Options: nosniff` header.",
+			HeaderKey:   "X-Content-Type-Options",
+			HeaderValue: res.Header.Get("X-Content-Type-Options"),
+		})
+	}
+
+	return findings
+}
+
+func checkCookies(req *http.Request, header http.Header) []Finding {
+	var findings []Finding
+
+	isHTTPS := req.URL != nil && req.URL.Scheme == "https"
+
+	for _, value := range header.Values("Set-Cookie") {
+		cookies := (&http.Response{Header: http.Header{"Set-Cookie": []string{value}}}).Cookies()
+		if len(cookies) == 0 {
+			continue
+		}
+
+		cookie := cookies[0]
+
+		if isHTTPS && !cookie.Secure {
+			findings = append(findings, Finding{
+				Check:       CheckCookieMissingSecure,
+				Severity:    SeverityLow,
+				Description: fmt.Sprintf("Cookie `%v` is set over HTTPS without the `Secure` attribute.", cookie.Name),
+				HeaderKey:   "Set-Cookie",
+				HeaderValue: value,
+			})
+		}
+
+		if !cookie.HttpOnly {
+			findings = append(findings, Finding{
+				Check:       CheckCookieMissingHTTPOnly,
+				Severity:    SeverityInfo,
+				Description: fmt.Sprintf("Cookie `%v` is readable by scripts, it has no `HttpOnly` attribute.", cookie.Name),
+				HeaderKey:   "Set-Cookie",
+				HeaderValue: value,
+			})
+		}
+
+		if cookie.SameSite == 0 {
+			findings = append(findings, Finding{
+				Check:       CheckCookieMissingSameSite,
+				Severity:    SeverityInfo,
+				Description: fmt.Sprintf("Cookie `%v` has no `SameSite` attribute.", cookie.Name),
+				HeaderKey:   "Set-Cookie",
+				HeaderValue: value,
+			})
+		}
+	}
+
+	return findings
+}
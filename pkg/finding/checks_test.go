@@ -0,0 +1,113 @@
+package finding_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dstotijn/hetty/pkg/finding"
+)
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		url       string
+		reqHeader http.Header
+		resHeader http.Header
+		exp       []finding.Check
+	}{
+		{
+			name:      "reflected origin with credentials",
+			url:       "http://example.com/api",
+			reqHeader: http.Header{"Origin": []string{"https://evil.example"}},
+			resHeader: http.Header{
+				"Access-Control-Allow-Origin":      []string{"https://evil.example"},
+				"Access-Control-Allow-Credentials": []string{"true"},
+			},
+			exp: []finding.Check{finding.CheckCORSReflectedOrigin},
+		},
+		{
+			name:      "same origin is not reflected",
+			url:       "http://example.com/api",
+			reqHeader: http.Header{"Origin": []string{"http://example.com"}},
+			resHeader: http.Header{"Access-Control-Allow-Origin": []string{"http://example.com"}},
+			exp:       nil,
+		},
+		{
+			name: "wildcard origin with credentials",
+			url:  "http://example.com/api",
+			resHeader: http.Header{
+				"Access-Control-Allow-Origin":      []string{"*"},
+				"Access-Control-Allow-Credentials": []string{"true"},
+			},
+			exp: []finding.Check{finding.CheckCORSWildcardCredentials},
+		},
+		{
+			name:      "null origin",
+			url:       "http://example.com/api",
+			resHeader: http.Header{"Access-Control-Allow-Origin": []string{"null"}},
+			exp:       []finding.Check{finding.CheckCORSNullOrigin},
+		},
+		{
+			name:      "HTML over HTTPS without security headers",
+			url:       "https://example.com/",
+			resHeader: http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+			exp: []finding.Check{
+				finding.CheckMissingHSTS,
+				finding.CheckMissingCSP,
+				finding.CheckMissingFrameOptions,
+				finding.CheckMissingContentTypeOptions,
+			},
+		},
+		{
+			name: "HTML with security headers",
+			url:  "http://example.com/",
+			resHeader: http.Header{
+				"Content-Type":            []string{"text/html"},
+				"Content-Security-Policy": []string{"default-src 'self'; frame-ancestors 'none'"},
+				"X-Content-Type-Options":  []string{"nosniff"},
+			},
+			exp: nil,
+		},
+		{
+			name: "cookies",
+			url:  "https://example.com/",
+			resHeader: http.Header{
+				"Strict-Transport-Security": []string{"max-age=31536000"},
+				"Set-Cookie": []string{
+					"session=foo; HttpOnly; SameSite=Lax",
+					"tracking=bar; Secure; HttpOnly; SameSite=None",
+				},
+			},
+			exp: []finding.Check{finding.CheckCookieMissingSecure},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			for key, values := range tt.reqHeader {
+				req.Header[key] = values
+			}
+
+			res := &http.Response{StatusCode: http.StatusOK, Header: tt.resHeader, Request: req}
+
+			var got []finding.Check
+			for _, f := range finding.Analyze(req, res) {
+				got = append(got, f.Check)
+			}
+
+			if diff := cmp.Diff(tt.exp, got); diff != "" {
+				t.Fatalf("checks not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package finding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+var (
+	ErrProjectIDMustBeSet = errors.New("finding: project ID must be set")
+	ErrReadOnly           = errors.New("finding: project is opened read-only")
+)
+
+type Severity string
+
+const (
+	SeverityInfo   Severity = "info"
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+// Service runs passive checks on logged responses, and stores the resulting
+// findings.
+type Service interface {
+	FindFindings(ctx context.Context, filter FindFindingsFilter) ([]Finding, error)
+	ClearFindings(ctx context.Context, projectID ulid.ULID) error
+	RequestModifier(next proxy.RequestModifyFunc) proxy.RequestModifyFunc
+	ResponseModifier(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc
+	SetActiveProjectID(id ulid.ULID)
+	ActiveProjectID() ulid.ULID
+	SetReadOnly(readOnly bool)
+	Flush(ctx context.Context) error
+}
+
+type service struct {
+	// mu guards the active project settings, which are changed at runtime.
+	mu              sync.RWMutex
+	activeProjectID ulid.ULID
+	readOnly        bool
+
+	// reflMu guards the parameter values that are tracked for reflections.
+	reflMu sync.Mutex
+	refl   reflections
+
+	repo   Repository
+	ids    idgen.Generator
+	events *event.Bus
+
+	// Findings that are being stored.
+	pending sync.WaitGroup
+}
+
+// Finding is an issue found by a passive check on a response.
+type Finding struct {
+	ID           ulid.ULID
+	ProjectID    ulid.ULID
+	RequestLogID ulid.ULID
+	Check        Check
+	Severity     Severity
+	Description  string
+	// Offending header, if any. For missing headers, only the key is set.
+	HeaderKey   string
+	HeaderValue string
+}
+
+type FindFindingsFilter struct {
+	ProjectID    ulid.ULID
+	RequestLogID ulid.ULID
+}
+
+type Config struct {
+	Repository Repository
+	// Bus for publishing created findings. Optional.
+	Events *event.Bus
+	// Generates the IDs of findings. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	return &service{
+		ids:    cfg.IDGenerator,
+		repo:   cfg.Repository,
+		events: cfg.Events,
+	}
+}
+
+func (svc *service) FindFindings(ctx context.Context, filter FindFindingsFilter) ([]Finding, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		filter.ProjectID = svc.ActiveProjectID()
+	}
+
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, ErrProjectIDMustBeSet
+	}
+
+	findings, err := svc.repo.FindFindings(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("finding: failed to find findings: %w", err)
+	}
+
+	return findings, nil
+}
+
+func (svc *service) ClearFindings(ctx context.Context, projectID ulid.ULID) error {
+	if svc.isReadOnly() {
+		return ErrReadOnly
+	}
+
+	return svc.repo.ClearFindings(ctx, projectID)
+}
+
+// ResponseModifier analyzes responses that are logged. Findings are stored in
+// the background, so that the response isn't delayed.
+func (svc *service) ResponseModifier(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+	return func(res *http.Response) error {
+		if err := next(res); err != nil {
+			return err
+		}
+
+		if bypassed, _ := res.Request.Context().Value(reqlog.LogBypassedKey).(bool); bypassed {
+			return nil
+		}
+
+		reqLogID, ok := res.Request.Context().Value(proxy.ReqLogIDKey).(ulid.ULID)
+		if !ok {
+			return nil
+		}
+
+		projectID, ok := res.Request.Context().Value(reqlog.ProjectIDKey).(ulid.ULID)
+		if !ok || svc.isReadOnlyProject(projectID) {
+			return nil
+		}
+
+		findings := Analyze(res.Request, res)
+
+		inputs, _ := res.Request.Context().Value(inputsKey).([]input)
+
+		body, err := svc.reflectionBody(res, len(inputs) > 0)
+		if err != nil {
+			return err
+		}
+
+		if len(findings) == 0 && body == nil {
+			return nil
+		}
+
+		req := res.Request
+		mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+
+		svc.pending.Add(1)
+
+		go func() {
+			defer svc.pending.Done()
+
+			if body != nil {
+				findings = append(findings, svc.checkReflections(req, reqLogID, inputs, mediaType, body)...)
+			}
+
+			for _, finding := range findings {
+				finding.ID = svc.ids.New(time.Now())
+				finding.ProjectID = projectID
+				finding.RequestLogID = reqLogID
+
+				if err := svc.repo.StoreFinding(context.Background(), finding); err != nil {
+					log.Printf("[ERROR] Could not store finding: %v", err)
+					continue
+				}
+
+				svc.events.Publish(event.Event{
+					Type:      event.TypeFindingCreated,
+					ProjectID: finding.ProjectID,
+					ID:        finding.ID,
+					Data:      finding,
+				})
+			}
+		}()
+
+		return nil
+	}
+}
+
+// Flush waits until pending findings are stored, or ctx is done.
+func (svc *service) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		svc.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("finding: failed to flush findings: %w", ctx.Err())
+	}
+}
+
+func (svc *service) SetActiveProjectID(id ulid.ULID) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if svc.activeProjectID.Compare(id) != 0 {
+		svc.resetReflections()
+	}
+
+	svc.activeProjectID = id
+}
+
+func (svc *service) ActiveProjectID() ulid.ULID {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.activeProjectID
+}
+
+// SetReadOnly sets whether the active project is opened read-only. No
+// findings are stored for it, and they can't be cleared.
+func (svc *service) SetReadOnly(readOnly bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.readOnly = readOnly
+}
+
+func (svc *service) isReadOnly() bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.readOnly
+}
+
+// isReadOnlyProject returns true if the project is the active project, opened
+// read-only. Projects that clients are routed to aren't opened, so these are
+// never read-only.
+func (svc *service) isReadOnlyProject(projectID ulid.ULID) bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.readOnly && svc.activeProjectID.Compare(projectID) == 0
+}
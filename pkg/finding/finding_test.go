@@ -0,0 +1,71 @@
+package finding_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+func TestResponseModifierProjectID(t *testing.T) {
+	t.Parallel()
+
+	repo := &RepoMock{
+		StoreFindingFunc: func(_ context.Context, _ finding.Finding) error {
+			return nil
+		},
+	}
+
+	activeID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	routedID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	svc := finding.NewService(finding.Config{Repository: repo})
+	svc.SetActiveProjectID(activeID)
+	svc.SetReadOnly(true)
+
+	resModifier := svc.ResponseModifier(func(_ *http.Response) error { return nil })
+
+	for _, projectID := range []ulid.ULID{activeID, routedID} {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		ctx := context.WithValue(req.Context(), proxy.ReqLogIDKey, ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+		req = req.WithContext(context.WithValue(ctx, reqlog.ProjectIDKey, projectID))
+
+		// The response lacks security headers, so it has findings.
+		res := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"text/html"}},
+			Body:       ioutil.NopCloser(strings.NewReader("<p>foobar</p>")),
+			Request:    req,
+		}
+
+		if err := resModifier(res); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := svc.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	calls := repo.StoreFindingCalls()
+	if len(calls) == 0 {
+		t.Fatal("expected findings for the routed project")
+	}
+
+	// Findings are stored for the project of the request log, and not for the
+	// active project, which is opened read-only.
+	for _, call := range calls {
+		if got := call.FindingMoqParam.ProjectID; got != routedID {
+			t.Fatalf("expected finding of project %v, got: %v", routedID, got)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package finding
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+const (
+	// Parameter values shorter than this are ignored, because they're likely
+	// to occur in responses by chance.
+	minReflectedValueLen = 4
+	// Maximum number of parameter values of earlier requests that responses
+	// are checked for.
+	maxTrackedInputs = 256
+	// Maximum number of response body bytes that are checked for reflections.
+	maxReflectionBodySize = 1 << 20
+	// Maximum number of reported reflections, after which they're reset.
+	maxReportedReflections = 10000
+)
+
+type contextKey int
+
+const inputsKey contextKey = 0
+
+// input is a parameter value of a request.
+type input struct {
+	location string
+	name     string
+	value    string
+	reqLogID ulid.ULID
+}
+
+// reflections tracks parameter values of requests, to find responses they are
+// reflected in.
+type reflections struct {
+	// Parameter values of earlier requests, oldest first.
+	inputs []input
+	// Reflections that are reported, by check, parameter and endpoint.
+	reported map[string]bool
+}
+
+// RequestModifier collects the query and form parameter values of requests,
+// which `ResponseModifier` checks responses for.
+func (svc *service) RequestModifier(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+	return func(req *http.Request) {
+		next(req)
+
+		inputs := requestInputs(req)
+		if len(inputs) == 0 {
+			return
+		}
+
+		*req = *req.WithContext(context.WithValue(req.Context(), inputsKey, inputs))
+	}
+}
+
+func requestInputs(req *http.Request) []input {
+	var inputs []input
+
+	if req.URL != nil {
+		inputs = append(inputs, valuesInputs("query", req.URL.Query())...)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if req.Body == nil || mediaType != "application/x-www-form-urlencoded" {
+		return inputs
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.Printf("[ERROR] Could not read request body for reflection check: %v", err)
+		return inputs
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+	if values, err := url.ParseQuery(string(body)); err == nil {
+		inputs = append(inputs, valuesInputs("form", values)...)
+	}
+
+	return inputs
+}
+
+func valuesInputs(location string, values url.Values) []input {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var inputs []input
+
+	for _, name := range names {
+		for _, value := range values[name] {
+			if isTrackableValue(value) {
+				inputs = append(inputs, input{location: location, name: name, value: value})
+			}
+		}
+	}
+
+	return inputs
+}
+
+func isTrackableValue(value string) bool {
+	if len(value) < minReflectedValueLen {
+		return false
+	}
+
+	switch strings.ToLower(value) {
+	case "true", "false", "null":
+		return false
+	}
+
+	// Numbers are likely to occur in responses by chance.
+	return strings.Trim(value, "0123456789.-") != ""
+}
+
+// reflectionBody returns the (decompressed) body of a textual response, if it
+// should be checked for reflections. The response body is left as is.
+func (svc *service) reflectionBody(res *http.Response, hasInputs bool) ([]byte, error) {
+	if !hasInputs && !svc.hasTrackedInputs() {
+		return nil, nil
+	}
+
+	if res.Body == nil || res.StatusCode == http.StatusNoContent || res.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if !isTextMediaType(mediaType) {
+		return nil, nil
+	}
+
+	encoding := strings.ToLower(res.Header.Get("Content-Encoding"))
+	if encoding != "" && encoding != "identity" && encoding != "gzip" {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("finding: could not read response body: %w", err)
+	}
+
+	res.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+	if encoding == "gzip" {
+		gzipReader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, nil
+		}
+		defer gzipReader.Close()
+
+		// Decompression errors are ignored, the decompressed part is checked.
+		body, _ = ioutil.ReadAll(io.LimitReader(gzipReader, maxReflectionBodySize))
+	}
+
+	if len(body) > maxReflectionBodySize {
+		body = body[:maxReflectionBodySize]
+	}
+
+	return body, nil
+}
+
+func isTextMediaType(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml":
+		return true
+	default:
+		return false
+	}
+}
+
+// checkReflections returns findings for parameter values of the request, and
+// of earlier requests, that are reflected verbatim in the response body.
+// Parameter values of requests that change state (i.e. not `GET`, `HEAD` or
+// `OPTIONS`) are tracked for later responses, to find stored reflections.
+func (svc *service) checkReflections(req *http.Request, reqLogID ulid.ULID, inputs []input, mediaType string, body []byte) []Finding {
+	endpoint := req.Method + " " + req.URL.Host + req.URL.Path
+
+	var findings []Finding
+
+	direct := make(map[string]bool, len(inputs))
+
+	for _, in := range inputs {
+		direct[in.value] = true
+
+		if !bytes.Contains(body, []byte(in.value)) || !svc.markReported(CheckReflectedInput, in, endpoint) {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Check:    CheckReflectedInput,
+			Severity: reflectionSeverity(in.value, mediaType),
+			Description: fmt.Sprintf("Value of %v parameter `%v` is reflected verbatim in the response body, "+
+				"which is a likely sink for XSS or injection.", in.location, in.name),
+		})
+	}
+
+	for _, in := range svc.trackedInputs() {
+		if direct[in.value] || !bytes.Contains(body, []byte(in.value)) ||
+			!svc.markReported(CheckStoredReflectedInput, in, endpoint) {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Check:    CheckStoredReflectedInput,
+			Severity: reflectionSeverity(in.value, mediaType),
+			Description: fmt.Sprintf("Value of %v parameter `%v` of request log %v is reflected verbatim in the "+
+				"response body of a later request, which is a likely sink for stored XSS or injection.",
+				in.location, in.name, in.reqLogID),
+		})
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		svc.trackInputs(inputs, reqLogID)
+	}
+
+	return findings
+}
+
+// reflectionSeverity is low for HTML responses, and medium if the value has
+// characters that are usually encoded to prevent XSS.
+func reflectionSeverity(value, mediaType string) Severity {
+	switch {
+	case mediaType != "text/html":
+		return SeverityInfo
+	case strings.ContainsAny(value, `<>"'`):
+		return SeverityMedium
+	default:
+		return SeverityLow
+	}
+}
+
+func (svc *service) trackInputs(inputs []input, reqLogID ulid.ULID) {
+	svc.reflMu.Lock()
+	defer svc.reflMu.Unlock()
+
+	for _, in := range inputs {
+		in.reqLogID = reqLogID
+		svc.refl.inputs = append(svc.refl.inputs, in)
+	}
+
+	if n := len(svc.refl.inputs) - maxTrackedInputs; n > 0 {
+		svc.refl.inputs = append([]input(nil), svc.refl.inputs[n:]...)
+	}
+}
+
+func (svc *service) trackedInputs() []input {
+	svc.reflMu.Lock()
+	defer svc.reflMu.Unlock()
+
+	return svc.refl.inputs
+}
+
+func (svc *service) hasTrackedInputs() bool {
+	svc.reflMu.Lock()
+	defer svc.reflMu.Unlock()
+
+	return len(svc.refl.inputs) > 0
+}
+
+// markReported returns false if the reflection of a parameter was already
+// reported for an endpoint.
+func (svc *service) markReported(check Check, in input, endpoint string) bool {
+	svc.reflMu.Lock()
+	defer svc.reflMu.Unlock()
+
+	key := string(check) + " " + in.location + " " + in.name + " " + endpoint
+
+	if svc.refl.reported[key] {
+		return false
+	}
+
+	if svc.refl.reported == nil || len(svc.refl.reported) >= maxReportedReflections {
+		svc.refl.reported = make(map[string]bool)
+	}
+
+	svc.refl.reported[key] = true
+
+	return true
+}
+
+// resetReflections forgets tracked parameter values and reported reflections,
+// e.g. when another project is opened.
+func (svc *service) resetReflections() {
+	svc.reflMu.Lock()
+	defer svc.reflMu.Unlock()
+
+	svc.refl = reflections{}
+}
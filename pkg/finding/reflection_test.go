@@ -0,0 +1,132 @@
+package finding_test
+
+//go:generate go run github.com/matryer/moq -out repo_mock_test.go -pkg finding_test . Repository:RepoMock
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+//nolint:gosec
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func TestReflections(t *testing.T) {
+	t.Parallel()
+
+	repo := &RepoMock{
+		StoreFindingFunc: func(_ context.Context, _ finding.Finding) error {
+			return nil
+		},
+	}
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	svc := finding.NewService(finding.Config{Repository: repo})
+	svc.SetActiveProjectID(projectID)
+
+	reqModifier := svc.RequestModifier(func(_ *http.Request) {})
+	resModifier := svc.ResponseModifier(func(_ *http.Response) error { return nil })
+
+	// roundTrip runs the modifiers for a request with a response body, and
+	// returns the checks of stored findings.
+	roundTrip := func(t *testing.T, req *http.Request, body string) []finding.Check {
+		t.Helper()
+
+		reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+		ctx := context.WithValue(req.Context(), proxy.ReqLogIDKey, reqLogID)
+		*req = *req.WithContext(context.WithValue(ctx, reqlog.ProjectIDKey, projectID))
+
+		reqModifier(req)
+
+		res := &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"Content-Type":              []string{"text/html; charset=utf-8"},
+				"Content-Security-Policy":   []string{"default-src 'self'"},
+				"X-Frame-Options":           []string{"DENY"},
+				"X-Content-Type-Options":    []string{"nosniff"},
+				"Strict-Transport-Security": []string{"max-age=31536000"},
+			},
+			Body:    ioutil.NopCloser(strings.NewReader(body)),
+			Request: req,
+		}
+
+		if err := resModifier(res); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		gotBody, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if string(gotBody) != body {
+			t.Fatalf("expected response body %q, got: %q", body, gotBody)
+		}
+
+		if err := svc.Flush(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var checks []finding.Check
+
+		for _, call := range repo.StoreFindingCalls() {
+			if call.FindingMoqParam.RequestLogID == reqLogID {
+				checks = append(checks, call.FindingMoqParam.Check)
+			}
+		}
+
+		return checks
+	}
+
+	// Reflected in the same response.
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/search?q=%3Cfoobar%3E&page=2", nil)
+
+	got := roundTrip(t, req, "<p>Results for <foobar>, page 2</p>")
+	if exp := []finding.Check{finding.CheckReflectedInput}; !cmp.Equal(exp, got) {
+		t.Fatalf("expected checks %v, got: %v", exp, got)
+	}
+
+	// Reported once per endpoint and parameter.
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/search?q=%3Cfoobar%3E", nil)
+
+	if got := roundTrip(t, req, "<p>Results for <foobar></p>"); len(got) != 0 {
+		t.Fatalf("expected no findings, got: %v", got)
+	}
+
+	// Form values of a state changing request are tracked for later responses.
+	req = httptest.NewRequest(http.MethodPost, "http://example.com/comments", strings.NewReader("comment=hello+world"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if got := roundTrip(t, req, "Saved."); len(got) != 0 {
+		t.Fatalf("expected no findings, got: %v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/comments", nil)
+
+	got = roundTrip(t, req, "<li>hello world</li>")
+	if exp := []finding.Check{finding.CheckStoredReflectedInput}; !cmp.Equal(exp, got) {
+		t.Fatalf("expected checks %v, got: %v", exp, got)
+	}
+
+	// Tracked values are forgotten when another project is opened.
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/comments/1", nil)
+
+	if got := roundTrip(t, req, "<li>hello world</li>"); len(got) != 0 {
+		t.Fatalf("expected no findings, got: %v", got)
+	}
+}
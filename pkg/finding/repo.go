@@ -0,0 +1,13 @@
+package finding
+
+import (
+	"context"
+
+	"github.com/oklog/ulid"
+)
+
+type Repository interface {
+	StoreFinding(ctx context.Context, finding Finding) error
+	FindFindings(ctx context.Context, filter FindFindingsFilter) ([]Finding, error)
+	ClearFindings(ctx context.Context, projectID ulid.ULID) error
+}
@@ -0,0 +1,326 @@
+// Package hetty embeds the core of Hetty (the proxy, request logs, the sender
+// and projects) in another Go program, e.g. a custom capture harness. It has
+// no dependency on the admin interface; serve `Hetty.Proxy` on a listener of
+// your own, and use the services directly.
+package hetty
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/authz"
+	"github.com/dstotijn/hetty/pkg/connlog"
+	"github.com/dstotijn/hetty/pkg/db"
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/intercept"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/oauth2"
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/ratelimit"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
+	"github.com/dstotijn/hetty/pkg/scope"
+	"github.com/dstotijn/hetty/pkg/sender"
+	"github.com/dstotijn/hetty/pkg/tracing"
+)
+
+type Config struct {
+	// CA certificate and private key, used to generate leaf certificates for
+	// intercepted HTTPS connections. When unset, an ephemeral CA is created,
+	// see `Hetty.CACert`.
+	CACert *x509.Certificate
+	CAKey  crypto.PrivateKey
+	// Storage backend, e.g. `badger.OpenDatabase(...)`. Defaults to an
+	// in-memory database. It isn't closed by `Hetty.Shutdown`.
+	Database db.Database
+	// Transport settings for upstream requests.
+	Transport proxy.TransportConfig
+	// Rate limits of outbound requests per host, of the proxy (and modules
+	// that send requests via the proxy) and the sender combined. Ignored when
+	// `Transport.RateLimiter` is set.
+	RateLimits []ratelimit.Rule
+	// Number of workers and queue size for storing response logs, see
+	// `reqlog.Config`.
+	ReqLogStoreWorkers   int
+	ReqLogStoreQueueSize int
+	// Maximum number of background jobs (e.g. crawls) that run at once, see
+	// `job.Config`.
+	MaxConcurrentJobs int
+	// Generates the IDs of stored entities, e.g. request logs. Defaults to
+	// `idgen.Default()`.
+	IDGenerator idgen.Generator
+	// Records spans of proxied requests, e.g. for export with OTLP. Optional;
+	// it isn't shut down by `Hetty.Shutdown`.
+	Tracer *tracing.Tracer
+}
+
+// Hetty is the wired up core of Hetty. The services are ready to use; e.g.
+// open a project with `ProjectService` to start logging proxied requests.
+type Hetty struct {
+	CACert *x509.Certificate
+	// Bus for events of the services, e.g. stored request logs.
+	Events *event.Bus
+	// Generator of IDs, for other services that store entities.
+	IDGenerator idgen.Generator
+	// Limits outbound requests per host; its rules can be changed at runtime.
+	RateLimiter *ratelimit.Limiter
+
+	Proxy             *proxy.Proxy
+	Scope             *scope.Scope
+	Rewriter          *rewrite.Rewriter
+	Database          db.Database
+	ProjectService    proj.Service
+	RequestLogService reqlog.Service
+	SenderService     sender.Service
+	FindingService    finding.Service
+	AuthzService      authz.Service
+	ConnLogService    connlog.Service
+	OAuth2Service     oauth2.Service
+	// Holds proxied requests that match a breakpoint, until they're continued
+	// or dropped.
+	InterceptService intercept.Service
+	// Runs long-running work of modules in the background, e.g. crawls.
+	JobService job.Service
+}
+
+// New returns a new Hetty.
+func New(cfg Config) (*Hetty, error) {
+	caCert, caKey := cfg.CACert, cfg.CAKey
+
+	if caCert == nil {
+		cert, key, err := proxy.NewCA("Hetty", "Hetty CA", 365*24*time.Hour)
+		if err != nil {
+			return nil, fmt.Errorf("hetty: could not create CA: %w", err)
+		}
+
+		caCert, caKey = cert, key
+	}
+
+	database := cfg.Database
+	if database == nil {
+		database = memory.OpenDatabase()
+	}
+
+	h := &Hetty{
+		CACert:   caCert,
+		Events:   event.NewBus(),
+		Scope:    &scope.Scope{},
+		Rewriter: &rewrite.Rewriter{},
+		Database: database,
+	}
+
+	h.IDGenerator = cfg.IDGenerator
+	if h.IDGenerator == nil {
+		h.IDGenerator = idgen.Default()
+	}
+
+	h.RequestLogService = reqlog.NewService(reqlog.Config{
+		Scope:          h.Scope,
+		Repository:     database,
+		StoreWorkers:   cfg.ReqLogStoreWorkers,
+		StoreQueueSize: cfg.ReqLogStoreQueueSize,
+		Events:         h.Events,
+		IDGenerator:    h.IDGenerator,
+	})
+
+	if cfg.Transport.RateLimiter == nil {
+		cfg.Transport.RateLimiter = ratelimit.NewLimiter(cfg.RateLimits)
+	}
+
+	h.RateLimiter = cfg.Transport.RateLimiter
+
+	p, err := proxy.NewProxy(proxy.Config{
+		CACert:    caCert,
+		CAKey:     caKey,
+		Transport: cfg.Transport,
+		Tracer:    cfg.Tracer,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("hetty: could not create proxy: %w", err)
+	}
+
+	h.Proxy = p
+
+	h.InterceptService = intercept.NewService(intercept.Config{
+		IDGenerator: h.IDGenerator,
+	})
+
+	// Breakpoints are matched after the other request modifiers, as the
+	// request is sent, so that held requests have their request log ID.
+	p.UseRequestModifier(h.InterceptService.RequestModifier)
+	p.UseRequestModifier(h.RequestLogService.RequestModifier)
+	// Remote mappings run after the request log modifier, so that the
+	// rewritten request is logged, with its original URL.
+	p.UseRequestModifier(h.Rewriter.RequestModifier)
+	// Response rewrites run after the request log modifier, so that the
+	// original response is logged.
+	p.UseResponseModifier(h.Rewriter.ResponseModifier, h.RequestLogService.ResponseModifier)
+	p.OnRequestError(h.RequestLogService.RequestErrorHandler)
+	p.OnRawCapture(h.RequestLogService.RawCaptureHandler)
+	p.OnRetry(h.RequestLogService.RetryHandler)
+	// Responses of local mappings are passed to the response modifiers, so that
+	// they're logged. Dropped requests aren't mapped.
+	p.UseResponder(h.InterceptService.Responder, h.Rewriter.Responder)
+
+	h.FindingService = finding.NewService(finding.Config{
+		Repository:  database,
+		Events:      h.Events,
+		IDGenerator: h.IDGenerator,
+	})
+
+	// Passive checks run before response rewrites, on the original response.
+	// Parameter values are collected for the reflected input checks.
+	p.UseRequestModifier(h.FindingService.RequestModifier)
+	p.UseResponseModifier(h.FindingService.ResponseModifier)
+
+	h.AuthzService = authz.NewService(authz.Config{
+		Scope:             h.Scope,
+		RequestLogService: h.RequestLogService,
+		FindingRepository: database,
+		// Replays count towards the rate limits of proxied requests.
+		RateLimiter: h.RateLimiter,
+		Events:      h.Events,
+		IDGenerator: h.IDGenerator,
+	})
+
+	p.UseResponseModifier(h.AuthzService.ResponseModifier)
+
+	h.ConnLogService = connlog.NewService(connlog.Config{
+		Repository:  database,
+		IDGenerator: h.IDGenerator,
+	})
+
+	// Tunnels aren't logged either while capture is paused.
+	p.OnConnectionClose(func(conn proxy.Connection) {
+		if h.RequestLogService.IsCapturePaused() {
+			return
+		}
+
+		h.ConnLogService.ConnectionHandler(conn)
+	})
+
+	h.JobService = job.NewService(job.Config{
+		Repository:    database,
+		MaxConcurrent: cfg.MaxConcurrentJobs,
+		IDGenerator:   h.IDGenerator,
+	})
+
+	h.SenderService = sender.NewService(sender.Config{
+		Repository:    database,
+		ReqLogService: h.RequestLogService,
+		// Redirects are followed via the proxy, so that they are logged.
+		RedirectTransport: p,
+		Events:            h.Events,
+		IDGenerator:       h.IDGenerator,
+		Rewriter:          h.Rewriter,
+		RateLimiter:       h.RateLimiter,
+	})
+
+	h.OAuth2Service = oauth2.NewService(oauth2.Config{
+		Events: h.Events,
+	})
+
+	h.ProjectService, err = proj.NewService(proj.Config{
+		Repository:    database,
+		ReqLogService: h.RequestLogService,
+		SenderService: h.SenderService,
+		Scope:         h.Scope,
+		Rewriter:      h.Rewriter,
+		OAuth2Service: h.OAuth2Service,
+		AuthzService:  h.AuthzService,
+		Events:        h.Events,
+		IDGenerator:   h.IDGenerator,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("hetty: could not create project service: %w", err)
+	}
+
+	h.Events.Subscribe(func(e event.Event) {
+		projectID := e.ProjectID
+		if e.Type == event.TypeProjectClosed {
+			projectID = ulid.ULID{}
+		}
+
+		readOnly := h.ProjectService.IsReadOnly()
+
+		h.FindingService.SetActiveProjectID(projectID)
+		h.FindingService.SetReadOnly(readOnly)
+		h.AuthzService.SetActiveProjectID(projectID)
+		h.AuthzService.SetReadOnly(readOnly)
+		h.ConnLogService.SetActiveProjectID(projectID)
+		h.ConnLogService.SetReadOnly(readOnly)
+		h.JobService.SetActiveProjectID(projectID)
+		h.JobService.SetReadOnly(readOnly)
+	}, event.TypeProjectOpened, event.TypeProjectClosed)
+
+	// Variables extracted by post-response scripts are stored with the project.
+	h.Events.Subscribe(func(e event.Event) {
+		envs, ok := e.Data.(sender.Environments)
+		if !ok || !h.ProjectService.IsProjectActive(e.ProjectID) {
+			return
+		}
+
+		if err := h.ProjectService.SetSenderEnvironments(context.Background(), envs); err != nil {
+			log.Printf("[ERROR] Could not store sender environments: %v", err)
+		}
+	}, event.TypeSenderEnvironmentsChanged)
+
+	// Access tokens are variables of sender requests, see `oauth2.Source`.
+	h.Events.Subscribe(func(event.Event) {
+		tokens := h.OAuth2Service.Tokens()
+		vars := make([]sender.Variable, 0, len(tokens))
+
+		for _, token := range tokens {
+			if token.AccessToken != "" {
+				vars = append(vars, sender.Variable{Name: token.Variable, Value: token.AccessToken})
+			}
+		}
+
+		h.SenderService.SetRuntimeVariables(vars)
+	}, event.TypeOAuth2TokensChanged)
+
+	return h, nil
+}
+
+// Shutdown waits for proxy tunnels to close and stores pending logs, until
+// ctx is done. Stop serving the proxy before calling it. Pending logs are
+// stored even if tunnels don't close in time; the first error is returned.
+// Request logs that aren't stored when ctx is done are discarded.
+func (h *Hetty) Shutdown(ctx context.Context) error {
+	var firstErr error
+
+	if err := h.Proxy.Shutdown(ctx); err != nil {
+		firstErr = fmt.Errorf("hetty: could not drain proxy tunnels: %w", err)
+	}
+
+	if err := h.RequestLogService.Flush(ctx); err != nil && firstErr == nil {
+		firstErr = fmt.Errorf("hetty: could not flush request logs: %w", err)
+	}
+
+	// Cancel writes that didn't finish in time, instead of leaving them to
+	// run against a closed database.
+	h.RequestLogService.Close()
+
+	if err := h.FindingService.Flush(ctx); err != nil && firstErr == nil {
+		firstErr = fmt.Errorf("hetty: could not flush findings: %w", err)
+	}
+
+	if err := h.AuthzService.Flush(ctx); err != nil && firstErr == nil {
+		firstErr = fmt.Errorf("hetty: could not flush authorization checks: %w", err)
+	}
+
+	// Abort replays that didn't finish in time.
+	h.AuthzService.Close()
+
+	return firstErr
+}
@@ -0,0 +1,93 @@
+package hetty_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/hetty"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	h, err := hetty.New(hetty.Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if h.CACert == nil {
+		t.Fatal("expected ephemeral CA certificate")
+	}
+
+	project, err := h.ProjectService.CreateProject(context.Background(), "foobar")
+	if err != nil {
+		t.Fatalf("unexpected error creating project: %v", err)
+	}
+
+	if _, err := h.ProjectService.OpenProject(context.Background(), project.ID); err != nil {
+		t.Fatalf("unexpected error opening project: %v", err)
+	}
+
+	var (
+		mu     sync.Mutex
+		events []event.Type
+	)
+
+	h.Events.Subscribe(func(e event.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		events = append(events, e.Type)
+	}, event.TypeRequestLogStored, event.TypeResponseLogStored)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	t.Cleanup(upstream.Close)
+
+	proxyServer := httptest.NewServer(h.Proxy)
+	t.Cleanup(proxyServer.Close)
+
+	proxyURL, _ := url.Parse(proxyServer.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	resp, err := client.Get(upstream.URL + "/foo")
+	if err != nil {
+		t.Fatalf("unexpected error sending request via proxy: %v", err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "hello" {
+		t.Fatalf("unexpected response body: %q", body)
+	}
+
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error shutting down: %v", err)
+	}
+
+	reqLogs, err := h.RequestLogService.FindRequests(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error finding request logs: %v", err)
+	}
+
+	if len(reqLogs) != 1 || reqLogs[0].Response == nil || string(reqLogs[0].Response.Body) != "hello" {
+		t.Fatalf("unexpected request logs: %+v", reqLogs)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if diff := cmp.Diff([]event.Type{event.TypeRequestLogStored, event.TypeResponseLogStored}, events); diff != "" {
+		t.Fatalf("events not equal (-exp, +got):\n%v", diff)
+	}
+}
@@ -0,0 +1,139 @@
+// Package idgen generates the IDs of stored entities, e.g. request logs and
+// projects.
+package idgen
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+)
+
+var ErrUnknownStrategy = errors.New("idgen: unknown strategy")
+
+// Generator generates IDs. IDs start with a 48 bit timestamp in milliseconds,
+// so that they sort by time, and are stored as ULIDs regardless of strategy.
+// Implementations must be safe for concurrent use.
+type Generator interface {
+	New(t time.Time) ulid.ULID
+}
+
+// Strategy is the layout of the bits after the timestamp of IDs.
+type Strategy string
+
+const (
+	// Random ULIDs.
+	StrategyULID Strategy = "ulid"
+	// ULIDs that are incremented within the same millisecond, so that IDs
+	// created by a generator sort in order of creation.
+	StrategyMonotonic Strategy = "monotonic"
+	// UUID version 7 (RFC 9562) bits, e.g. for interoperability with systems
+	// that store IDs as UUIDs. See `UUID`.
+	StrategyUUIDv7 Strategy = "uuidv7"
+)
+
+type Config struct {
+	// Defaults to `StrategyULID`.
+	Strategy Strategy
+	// Source of the random bits of IDs. Defaults to a `math/rand` source
+	// seeded with the current time. Use `CryptoEntropy` for IDs that are hard
+	// to guess, or a seeded source for deterministic IDs in tests.
+	Entropy io.Reader
+}
+
+// CryptoEntropy is a cryptographically secure source of entropy.
+var CryptoEntropy io.Reader = crand.Reader
+
+// defaultGenerator is shared by services that aren't configured with a generator.
+//
+//nolint:gosec
+var defaultGenerator = &generator{
+	strategy: StrategyULID,
+	entropy:  rand.New(rand.NewSource(time.Now().UnixNano())),
+}
+
+// Default returns the generator of services that aren't configured with one:
+// random ULIDs, with non-cryptographic entropy.
+func Default() Generator {
+	return defaultGenerator
+}
+
+type generator struct {
+	strategy Strategy
+	// mu guards entropy, which isn't safe for concurrent use.
+	mu      sync.Mutex
+	entropy io.Reader
+}
+
+// NewGenerator returns a Generator for a strategy.
+func NewGenerator(cfg Config) (Generator, error) {
+	if cfg.Strategy == "" {
+		cfg.Strategy = StrategyULID
+	}
+
+	entropy := cfg.Entropy
+	if entropy == nil {
+		//nolint:gosec
+		entropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	switch cfg.Strategy {
+	case StrategyULID, StrategyUUIDv7:
+	case StrategyMonotonic:
+		entropy = ulid.Monotonic(entropy, 0)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, cfg.Strategy)
+	}
+
+	return &generator{strategy: cfg.Strategy, entropy: entropy}, nil
+}
+
+func (g *generator) New(t time.Time) ulid.ULID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.strategy != StrategyUUIDv7 {
+		return ulid.MustNew(ulid.Timestamp(t), g.entropy)
+	}
+
+	var id ulid.ULID
+
+	if err := id.SetTime(ulid.Timestamp(t)); err != nil {
+		panic(err)
+	}
+
+	if _, err := io.ReadFull(g.entropy, id[6:]); err != nil {
+		panic(err)
+	}
+
+	// Version 7 in the high nibble of byte 6, variant `10` in the high bits
+	// of byte 8.
+	id[6] = 0x70 | id[6]&0x0f
+	id[8] = 0x80 | id[8]&0x3f
+
+	return id
+}
+
+// UUID returns the way UUIDs are usually written, for an ID, e.g.
+// `0190a4e6-2f1b-7c3d-9e4f-5a6b7c8d9e0f`.
+func UUID(id ulid.ULID) string {
+	var buf [36]byte
+
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+
+	return string(buf[:])
+}
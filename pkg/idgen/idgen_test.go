@@ -0,0 +1,92 @@
+package idgen_test
+
+import (
+	"errors"
+	"math/rand"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/idgen"
+)
+
+func TestNewGenerator(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("seeded entropy is deterministic", func(t *testing.T) {
+		t.Parallel()
+
+		var ids [2]ulid.ULID
+
+		for i := range ids {
+			gen, err := idgen.NewGenerator(idgen.Config{Entropy: rand.New(rand.NewSource(1))})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids[i] = gen.New(now)
+		}
+
+		if ids[0] != ids[1] {
+			t.Fatalf("expected equal IDs, got: %v and %v", ids[0], ids[1])
+		}
+
+		if got := ulid.Time(ids[0].Time()); !got.Equal(now) {
+			t.Fatalf("expected time: %v, got: %v", now, got)
+		}
+	})
+
+	t.Run("monotonic IDs increase within a millisecond", func(t *testing.T) {
+		t.Parallel()
+
+		gen, err := idgen.NewGenerator(idgen.Config{Strategy: idgen.StrategyMonotonic})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		prev := gen.New(now)
+
+		for i := 0; i < 100; i++ {
+			id := gen.New(now)
+			if id.Compare(prev) <= 0 {
+				t.Fatalf("expected %v to be greater than %v", id, prev)
+			}
+
+			prev = id
+		}
+	})
+
+	t.Run("UUIDv7 IDs", func(t *testing.T) {
+		t.Parallel()
+
+		gen, err := idgen.NewGenerator(idgen.Config{Strategy: idgen.StrategyUUIDv7, Entropy: idgen.CryptoEntropy})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		id := gen.New(now)
+
+		if got := ulid.Time(id.Time()); !got.Equal(now) {
+			t.Fatalf("expected time: %v, got: %v", now, got)
+		}
+
+		uuid := idgen.UUID(id)
+
+		if !regexp.MustCompile(`^0190[0-9a-f]{4}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uuid) {
+			t.Fatalf("expected UUIDv7, got: %v", uuid)
+		}
+	})
+
+	t.Run("unknown strategy", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := idgen.NewGenerator(idgen.Config{Strategy: "foo"})
+		if !errors.Is(err, idgen.ErrUnknownStrategy) {
+			t.Fatalf("expected `idgen.ErrUnknownStrategy`, got: %v", err)
+		}
+	})
+}
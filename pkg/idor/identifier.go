@@ -0,0 +1,274 @@
+package idor
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+// Location is the part of a request an identifier is in.
+type Location string
+
+const (
+	LocationPath  Location = "path"
+	LocationQuery Location = "query"
+	LocationForm  Location = "form"
+	LocationJSON  Location = "json"
+)
+
+type Kind string
+
+const (
+	KindNumeric Kind = "numeric"
+	KindUUID    Kind = "uuid"
+)
+
+var (
+	numericRegexp = regexp.MustCompile(`^[0-9]{1,18}$`)
+	uuidRegexp    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// Identifier is a value of a request that looks like the ID of an object.
+type Identifier struct {
+	Location Location
+	// Index of the path segment (starting at 1, after the leading slash),
+	// name of the query or form parameter, or dot separated path of the JSON
+	// field (e.g. `items.0.id`).
+	Name  string
+	Value string
+	Kind  Kind
+}
+
+func (id Identifier) key() string {
+	return string(id.Location) + ":" + id.Name
+}
+
+// FindIdentifiers returns the numeric and UUID identifiers in the path, query,
+// and form or JSON body of a request log, in that order.
+func FindIdentifiers(reqLog reqlog.RequestLog) []Identifier {
+	var ids []Identifier
+
+	if reqLog.URL != nil {
+		for i, segment := range strings.Split(reqLog.URL.Path, "/") {
+			if kind, ok := identifierKind(segment); ok {
+				ids = append(ids, Identifier{Location: LocationPath, Name: strconv.Itoa(i), Value: segment, Kind: kind})
+			}
+		}
+
+		ids = append(ids, findValuesIdentifiers(LocationQuery, reqLog.URL.Query())...)
+	}
+
+	switch bodyType(reqLog) {
+	case LocationForm:
+		if values, err := url.ParseQuery(string(reqLog.Body)); err == nil {
+			ids = append(ids, findValuesIdentifiers(LocationForm, values)...)
+		}
+	case LocationJSON:
+		if v, ok := decodeJSON(reqLog.Body); ok {
+			ids = append(ids, findJSONIdentifiers(nil, v)...)
+		}
+	}
+
+	return ids
+}
+
+func identifierKind(value string) (Kind, bool) {
+	switch {
+	case numericRegexp.MatchString(value):
+		return KindNumeric, true
+	case uuidRegexp.MatchString(value):
+		return KindUUID, true
+	default:
+		return "", false
+	}
+}
+
+func findValuesIdentifiers(location Location, values url.Values) []Identifier {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var ids []Identifier
+
+	for _, name := range names {
+		// Only the first value is mutated, so only that is considered.
+		value := values.Get(name)
+		if kind, ok := identifierKind(value); ok {
+			ids = append(ids, Identifier{Location: location, Name: name, Value: value, Kind: kind})
+		}
+	}
+
+	return ids
+}
+
+func findJSONIdentifiers(path []string, v interface{}) []Identifier {
+	var ids []Identifier
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+
+		for key := range t {
+			// Keys with dots can't be addressed by a path.
+			if !strings.Contains(key, ".") {
+				keys = append(keys, key)
+			}
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			ids = append(ids, findJSONIdentifiers(append(path[:len(path):len(path)], key), t[key])...)
+		}
+	case []interface{}:
+		for i, elem := range t {
+			ids = append(ids, findJSONIdentifiers(append(path[:len(path):len(path)], strconv.Itoa(i)), elem)...)
+		}
+	case json.Number:
+		if kind, ok := identifierKind(t.String()); ok && len(path) > 0 {
+			ids = append(ids, Identifier{Location: LocationJSON, Name: strings.Join(path, "."), Value: t.String(), Kind: kind})
+		}
+	case string:
+		if kind, ok := identifierKind(t); ok && len(path) > 0 {
+			ids = append(ids, Identifier{Location: LocationJSON, Name: strings.Join(path, "."), Value: t, Kind: kind})
+		}
+	}
+
+	return ids
+}
+
+// mutate returns a copy of reqLog, with the identifier replaced with value.
+// It returns false if the request doesn't have the identifier.
+func mutate(reqLog reqlog.RequestLog, id Identifier, value string) (reqlog.RequestLog, bool) {
+	u := *reqLog.URL
+
+	switch id.Location {
+	case LocationPath:
+		segments := strings.Split(u.Path, "/")
+
+		i, err := strconv.Atoi(id.Name)
+		if err != nil || i <= 0 || i >= len(segments) {
+			return reqlog.RequestLog{}, false
+		}
+
+		segments[i] = value
+		u.Path = strings.Join(segments, "/")
+		u.RawPath = ""
+	case LocationQuery:
+		query := u.Query()
+		if _, ok := query[id.Name]; !ok {
+			return reqlog.RequestLog{}, false
+		}
+
+		query.Set(id.Name, value)
+		u.RawQuery = query.Encode()
+	case LocationForm:
+		values, err := url.ParseQuery(string(reqLog.Body))
+		if _, ok := values[id.Name]; err != nil || !ok {
+			return reqlog.RequestLog{}, false
+		}
+
+		values.Set(id.Name, value)
+		reqLog.Body = []byte(values.Encode())
+	case LocationJSON:
+		v, ok := decodeJSON(reqLog.Body)
+		if !ok || !setJSONPath(v, strings.Split(id.Name, "."), value) {
+			return reqlog.RequestLog{}, false
+		}
+
+		body, err := json.Marshal(v)
+		if err != nil {
+			return reqlog.RequestLog{}, false
+		}
+
+		reqLog.Body = body
+	default:
+		return reqlog.RequestLog{}, false
+	}
+
+	reqLog.URL = &u
+
+	return reqLog, true
+}
+
+// setJSONPath sets the value at a path of a decoded JSON document. Numbers
+// stay numbers if value is numeric.
+func setJSONPath(v interface{}, keys []string, value string) bool {
+	key, rest := keys[0], keys[1:]
+
+	set := func(old interface{}) (interface{}, bool) {
+		if len(rest) > 0 {
+			return old, setJSONPath(old, rest, value)
+		}
+
+		if _, ok := old.(json.Number); ok && numericRegexp.MatchString(value) {
+			return json.Number(value), true
+		}
+
+		return value, true
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		old, ok := t[key]
+		if !ok {
+			return false
+		}
+
+		t[key], ok = set(old)
+
+		return ok
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(t) {
+			return false
+		}
+
+		var ok bool
+		t[i], ok = set(t[i])
+
+		return ok
+	default:
+		return false
+	}
+}
+
+func bodyType(reqLog reqlog.RequestLog) Location {
+	if len(reqLog.Body) == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(reqLog.Header.Get("Content-Type"))
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return LocationForm
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return LocationJSON
+	default:
+		return ""
+	}
+}
+
+func decodeJSON(body []byte) (interface{}, bool) {
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return nil, false
+	}
+
+	return v, true
+}
@@ -0,0 +1,91 @@
+package idor_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dstotijn/hetty/pkg/idor"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+func TestFindIdentifiers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		reqLog reqlog.RequestLog
+		exp    []idor.Identifier
+	}{
+		{
+			name: "path and query",
+			reqLog: reqlog.RequestLog{
+				URL: mustParseURL(t, "https://example.com/v1/users/42/orders/"+
+					"3f2504e0-4f89-11d3-9a0c-0305e82c3301?page=2&sort=name"),
+			},
+			exp: []idor.Identifier{
+				{Location: idor.LocationPath, Name: "3", Value: "42", Kind: idor.KindNumeric},
+				{Location: idor.LocationPath, Name: "5", Value: "3f2504e0-4f89-11d3-9a0c-0305e82c3301", Kind: idor.KindUUID},
+				{Location: idor.LocationQuery, Name: "page", Value: "2", Kind: idor.KindNumeric},
+			},
+		},
+		{
+			name: "form body",
+			reqLog: reqlog.RequestLog{
+				URL:    mustParseURL(t, "https://example.com/transfer"),
+				Header: http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}},
+				Body:   []byte("amount=10.5&to=1337"),
+			},
+			exp: []idor.Identifier{
+				{Location: idor.LocationForm, Name: "to", Value: "1337", Kind: idor.KindNumeric},
+			},
+		},
+		{
+			name: "JSON body",
+			reqLog: reqlog.RequestLog{
+				URL:    mustParseURL(t, "https://example.com/graphql"),
+				Header: http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+				Body:   []byte(`{"accountId":7,"items":[{"id":"12","qty":1.5}],"name":"foo"}`),
+			},
+			exp: []idor.Identifier{
+				{Location: idor.LocationJSON, Name: "accountId", Value: "7", Kind: idor.KindNumeric},
+				{Location: idor.LocationJSON, Name: "items.0.id", Value: "12", Kind: idor.KindNumeric},
+			},
+		},
+		{
+			name: "body of other content type",
+			reqLog: reqlog.RequestLog{
+				URL:    mustParseURL(t, "https://example.com/upload"),
+				Header: http.Header{"Content-Type": []string{"text/plain"}},
+				Body:   []byte("id=1"),
+			},
+			exp: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := idor.FindIdentifiers(tt.reqLog)
+			if diff := cmp.Diff(tt.exp, got); diff != "" {
+				t.Fatalf("identifiers not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
@@ -0,0 +1,480 @@
+// Package idor helps to find insecure direct object references. It replays a
+// logged request with the numeric and UUID identifiers in its URL and body
+// replaced, e.g. with the IDs of objects of another user, and flags successful
+// responses with content that differs from the original response for review.
+package idor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// JobType is the type of the jobs that run tests, see `job.Params`.
+const JobType = "idorTest"
+
+var (
+	ErrTestNotFound = errcode.New(errcode.NotFound, "idor: test not found")
+	ErrOutOfScope   = errcode.New(errcode.Invalid, "idor: request is out of scope")
+	ErrNoMutations  = errcode.New(errcode.Invalid, "idor: no identifiers to mutate")
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusFinished  Status = "finished"
+	StatusCancelled Status = "cancelled"
+)
+
+// Service runs IDOR tests of logged requests. Identifiers with a flagged
+// response are stored as findings.
+type Service interface {
+	FindIdentifiers(ctx context.Context, requestLogID ulid.ULID) ([]Identifier, error)
+	StartTest(ctx context.Context, params TestParams) (Test, error)
+	FindTestByID(id ulid.ULID) (Test, error)
+	FindTests() []Test
+	CancelTest(id ulid.ULID) error
+}
+
+type service struct {
+	scope       *scope.Scope
+	ids         idgen.Generator
+	jobs        job.Service
+	reqLogSvc   reqlog.Service
+	findingRepo finding.Repository
+	events      *event.Bus
+	httpClient  *http.Client
+	tests       map[ulid.ULID]*testState
+	mu          sync.RWMutex
+}
+
+type Config struct {
+	Scope             *scope.Scope
+	RequestLogService reqlog.Service
+	FindingRepository finding.Repository
+	// Transport that mutated requests are sent with, e.g. the proxy, so that
+	// they are logged. Defaults to `http.DefaultTransport`.
+	Transport http.RoundTripper
+	// Bus for publishing created findings. Optional.
+	Events *event.Bus
+	// Generates the IDs of tests and findings. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+	// Runs tests as jobs, so that they're queued, report progress and can be
+	// paused. Optional; without it, tests start right away.
+	Jobs job.Service
+}
+
+type TestParams struct {
+	RequestLogID ulid.ULID
+	// Identifiers of the request to mutate, by location and name. Defaults to
+	// all identifiers of the request.
+	Identifiers []Identifier
+	// Values that identifiers are replaced with, e.g. IDs of objects of
+	// another user. Numeric identifiers are also replaced with the adjacent
+	// numbers.
+	Values []string
+}
+
+type Test struct {
+	ID           ulid.ULID
+	RequestLogID ulid.ULID
+	URL          *url.URL
+	Status       Status
+	Total        int
+	Completed    int
+	Results      []Result
+}
+
+// Result is a request with a mutated identifier.
+type Result struct {
+	Identifier Identifier
+	// Value that the identifier was replaced with.
+	Value string
+	// Request log of the mutated request, if it was logged.
+	RequestLogID ulid.ULID
+	StatusCode   int
+	BodySize     int
+	// Flagged is true if the response is successful, and its body differs
+	// from that of the original response.
+	Flagged bool
+	// Error of sending the request, if any.
+	Error string
+}
+
+type mutation struct {
+	id    Identifier
+	value string
+}
+
+type testState struct {
+	test   Test
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &service{
+		ids:         cfg.IDGenerator,
+		scope:       cfg.Scope,
+		jobs:        cfg.Jobs,
+		reqLogSvc:   cfg.RequestLogService,
+		findingRepo: cfg.FindingRepository,
+		events:      cfg.Events,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   defaultTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		tests: make(map[ulid.ULID]*testState),
+	}
+}
+
+// FindIdentifiers returns the identifiers of a logged request, see
+// `FindIdentifiers`.
+func (svc *service) FindIdentifiers(ctx context.Context, requestLogID ulid.ULID) ([]Identifier, error) {
+	reqLog, err := svc.reqLogSvc.FindRequestLogByID(ctx, requestLogID)
+	if err != nil {
+		return nil, fmt.Errorf("idor: failed to find request log: %w", err)
+	}
+
+	return FindIdentifiers(reqLog), nil
+}
+
+// StartTest starts a test of a logged request in the background. The request
+// must match the project scope, and have a response to compare with.
+func (svc *service) StartTest(ctx context.Context, params TestParams) (Test, error) {
+	reqLog, err := svc.reqLogSvc.FindRequestLogByID(ctx, params.RequestLogID)
+	if err != nil {
+		return Test{}, fmt.Errorf("idor: failed to find request log: %w", err)
+	}
+
+	if reqLog.URL == nil || reqLog.URL.Host == "" {
+		return Test{}, errcode.New(errcode.Invalid, "idor: request log URL must be absolute")
+	}
+
+	if reqLog.Response == nil {
+		return Test{}, errcode.New(errcode.Invalid, "idor: request log has no response")
+	}
+
+	if svc.scope != nil && !svc.scope.Match(&http.Request{URL: reqLog.URL, Header: reqLog.Header}, reqLog.Body) {
+		return Test{}, ErrOutOfScope
+	}
+
+	mutations := mutations(FindIdentifiers(reqLog), params)
+	if len(mutations) == 0 {
+		return Test{}, ErrNoMutations
+	}
+
+	testCtx, cancel := context.WithCancel(context.Background())
+
+	state := &testState{
+		test: Test{
+			ID:           svc.ids.New(time.Now()),
+			RequestLogID: reqLog.ID,
+			URL:          reqLog.URL,
+			Status:       StatusRunning,
+			Total:        len(mutations),
+			Results:      make([]Result, 0, len(mutations)),
+		},
+		cancel: cancel,
+	}
+
+	svc.mu.Lock()
+	svc.tests[state.test.ID] = state
+	svc.mu.Unlock()
+
+	if svc.jobs == nil {
+		go svc.run(testCtx, state, reqLog, mutations, nil)
+		return state.snapshot(), nil
+	}
+
+	jobParams := job.Params{
+		ID:          state.test.ID,
+		Type:        JobType,
+		Description: reqLog.URL.String(),
+		Total:       len(mutations),
+	}
+
+	svc.jobs.StartJob(testCtx, jobParams, func(ctx context.Context, progress *job.Progress) error {
+		return svc.run(ctx, state, reqLog, mutations, progress)
+	})
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindTestByID(id ulid.ULID) (Test, error) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	state, ok := svc.tests[id]
+	if !ok {
+		return Test{}, ErrTestNotFound
+	}
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindTests() []Test {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	tests := make([]Test, 0, len(svc.tests))
+	for _, state := range svc.tests {
+		tests = append(tests, state.snapshot())
+	}
+
+	// Most recent tests first.
+	sort.Slice(tests, func(i, j int) bool {
+		return tests[i].ID.Compare(tests[j].ID) > 0
+	})
+
+	return tests
+}
+
+func (svc *service) CancelTest(id ulid.ULID) error {
+	svc.mu.RLock()
+	state, ok := svc.tests[id]
+	svc.mu.RUnlock()
+
+	if !ok {
+		return ErrTestNotFound
+	}
+
+	state.mu.Lock()
+	if state.test.Status == StatusRunning {
+		state.test.Status = StatusCancelled
+	}
+	state.mu.Unlock()
+
+	state.cancel()
+
+	return nil
+}
+
+// mutations returns the identifiers of params (or all identifiers) that the
+// request has, each with the values it's replaced with.
+func mutations(ids []Identifier, params TestParams) []mutation {
+	selected := make(map[string]bool, len(params.Identifiers))
+	for _, id := range params.Identifiers {
+		selected[id.key()] = true
+	}
+
+	var mutations []mutation
+
+	for _, id := range ids {
+		if len(selected) > 0 && !selected[id.key()] {
+			continue
+		}
+
+		seen := map[string]bool{id.Value: true}
+
+		add := func(value string) {
+			if value != "" && !seen[value] {
+				seen[value] = true
+				mutations = append(mutations, mutation{id: id, value: value})
+			}
+		}
+
+		if id.Kind == KindNumeric {
+			if n, err := strconv.ParseInt(id.Value, 10, 64); err == nil {
+				if n > 0 {
+					add(strconv.FormatInt(n-1, 10))
+				}
+
+				add(strconv.FormatInt(n+1, 10))
+			}
+		}
+
+		for _, value := range params.Values {
+			add(value)
+		}
+	}
+
+	return mutations
+}
+
+// run sends the mutated requests of a test. Findings are only stored if the
+// test isn't cancelled.
+func (svc *service) run(
+	ctx context.Context,
+	state *testState,
+	reqLog reqlog.RequestLog,
+	mutations []mutation,
+	progress *job.Progress,
+) error {
+	defer state.cancel()
+
+	flagged := make(map[string][]string)
+
+	var flaggedIDs []Identifier
+
+	for _, m := range mutations {
+		if err := progress.Wait(ctx); err != nil {
+			break
+		}
+
+		result := svc.send(ctx, reqLog, m)
+		if ctx.Err() != nil {
+			break
+		}
+
+		if result.Flagged {
+			if _, ok := flagged[m.id.key()]; !ok {
+				flaggedIDs = append(flaggedIDs, m.id)
+			}
+
+			flagged[m.id.key()] = append(flagged[m.id.key()], m.value)
+		}
+
+		state.mu.Lock()
+		state.test.Results = append(state.test.Results, result)
+		state.test.Completed++
+		state.mu.Unlock()
+		progress.Add(1)
+	}
+
+	if ctx.Err() == nil {
+		for _, id := range flaggedIDs {
+			svc.storeFinding(reqLog, id, flagged[id.key()])
+		}
+	}
+
+	state.mu.Lock()
+	if state.test.Status == StatusRunning {
+		state.test.Status = StatusFinished
+		if ctx.Err() != nil {
+			state.test.Status = StatusCancelled
+		}
+	}
+	state.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// send sends a request log with a mutated identifier.
+func (svc *service) send(ctx context.Context, reqLog reqlog.RequestLog, m mutation) Result {
+	result := Result{
+		Identifier: m.id,
+		Value:      m.value,
+	}
+
+	mutated, ok := mutate(reqLog, m.id, m.value)
+	if !ok {
+		result.Error = "identifier not found in request"
+		return result
+	}
+
+	req, err := http.NewRequestWithContext(ctx, mutated.Method, mutated.URL.String(), bytes.NewReader(mutated.Body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	header := mutated.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	// Set by the HTTP client, for the mutated request.
+	header.Del("Content-Length")
+	header.Del("Host")
+
+	req.Header = header
+
+	res, err := svc.httpClient.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer res.Body.Close()
+
+	resLog, err := reqlog.ParseHTTPResponse(res)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if res.Request != nil {
+		if reqLogID, ok := res.Request.Context().Value(proxy.ReqLogIDKey).(ulid.ULID); ok {
+			result.RequestLogID = reqLogID
+		}
+	}
+
+	result.StatusCode = resLog.StatusCode
+	result.BodySize = len(resLog.Body)
+	result.Flagged = resLog.StatusCode >= 200 && resLog.StatusCode <= 299 &&
+		!bytes.Equal(resLog.Body, reqLog.Response.Body)
+
+	return result
+}
+
+func (svc *service) storeFinding(reqLog reqlog.RequestLog, id Identifier, values []string) {
+	f := finding.Finding{
+		ID:           svc.ids.New(time.Now()),
+		ProjectID:    reqLog.ProjectID,
+		RequestLogID: reqLog.ID,
+		Check:        finding.CheckIDORCandidate,
+		Severity:     finding.SeverityLow,
+		Description: fmt.Sprintf(
+			"Replacing the %v identifier %q (%v: %v) with %v returned successful responses with different content. "+
+				"Review whether they contain objects the user isn't authorized to access.",
+			id.Kind, id.Value, id.Location, id.Name, strings.Join(values, ", "),
+		),
+	}
+
+	if err := svc.findingRepo.StoreFinding(context.Background(), f); err != nil {
+		log.Printf("[ERROR] Could not store IDOR finding: %v", err)
+		return
+	}
+
+	svc.events.Publish(event.Event{
+		Type:      event.TypeFindingCreated,
+		ProjectID: f.ProjectID,
+		ID:        f.ID,
+		Data:      f,
+	})
+}
+
+func (state *testState) snapshot() Test {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	test := state.test
+	test.Results = make([]Result, len(state.test.Results))
+	copy(test.Results, state.test.Results)
+
+	return test
+}
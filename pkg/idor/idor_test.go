@@ -0,0 +1,170 @@
+package idor_test
+
+//go:generate go run github.com/matryer/moq -out reqlog_mock_test.go -pkg idor_test ../reqlog Service:ReqLogServiceMock
+//go:generate go run github.com/matryer/moq -out finding_repo_mock_test.go -pkg idor_test ../finding Repository:FindingRepoMock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/idor"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+//nolint:gosec
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func TestStartTest(t *testing.T) {
+	t.Parallel()
+
+	// Orders 1 to 3 exist, and are returned to anyone.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			OrderID json.Number `json:"orderId"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch body.OrderID {
+		case "1", "2", "3":
+			fmt.Fprintf(w, "order %v", body.OrderID)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		URL:       mustParseURL(t, ts.URL+"/orders"),
+		Method:    http.MethodPost,
+		Header:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:      []byte(`{"orderId":2}`),
+		Response: &reqlog.ResponseLog{
+			StatusCode: http.StatusOK,
+			Body:       []byte("order 2"),
+		},
+	}
+
+	reqLogSvc := &ReqLogServiceMock{
+		FindRequestLogByIDFunc: func(_ context.Context, _ ulid.ULID) (reqlog.RequestLog, error) {
+			return reqLog, nil
+		},
+	}
+
+	findingRepo := &FindingRepoMock{
+		StoreFindingFunc: func(_ context.Context, _ finding.Finding) error {
+			return nil
+		},
+	}
+
+	t.Run("out of scope", func(t *testing.T) {
+		t.Parallel()
+
+		svc := idor.NewService(idor.Config{
+			Scope:             &scope.Scope{},
+			RequestLogService: reqLogSvc,
+			FindingRepository: findingRepo,
+		})
+
+		_, err := svc.StartTest(context.Background(), idor.TestParams{RequestLogID: reqLog.ID})
+		if !errors.Is(err, idor.ErrOutOfScope) {
+			t.Fatalf("expected `idor.ErrOutOfScope`, got: %v", err)
+		}
+	})
+
+	t.Run("no mutations", func(t *testing.T) {
+		t.Parallel()
+
+		svc := idor.NewService(idor.Config{
+			RequestLogService: reqLogSvc,
+			FindingRepository: findingRepo,
+		})
+
+		_, err := svc.StartTest(context.Background(), idor.TestParams{
+			RequestLogID: reqLog.ID,
+			Identifiers:  []idor.Identifier{{Location: idor.LocationQuery, Name: "id"}},
+		})
+		if !errors.Is(err, idor.ErrNoMutations) {
+			t.Fatalf("expected `idor.ErrNoMutations`, got: %v", err)
+		}
+	})
+
+	t.Run("flags other objects", func(t *testing.T) {
+		t.Parallel()
+
+		s := &scope.Scope{}
+		s.SetRules([]scope.Rule{{URL: regexp.MustCompile(regexp.QuoteMeta(ts.URL))}})
+
+		svc := idor.NewService(idor.Config{
+			Scope:             s,
+			RequestLogService: reqLogSvc,
+			FindingRepository: findingRepo,
+		})
+
+		test, err := svc.StartTest(context.Background(), idor.TestParams{
+			RequestLogID: reqLog.ID,
+			Values:       []string{"3", "999"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+
+		for test.Status == idor.StatusRunning && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+
+			test, err = svc.FindTestByID(test.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if test.Status != idor.StatusFinished {
+			t.Fatalf("expected test to finish, got status: %v", test.Status)
+		}
+
+		// Adjacent numbers (1, 3), then values (3 is a duplicate).
+		var got []string
+
+		for _, result := range test.Results {
+			got = append(got, fmt.Sprintf("%v %v %v", result.Value, result.StatusCode, result.Flagged))
+		}
+
+		sort.Strings(got)
+
+		if exp := []string{"1 200 true", "3 200 true", "999 404 false"}; !cmp.Equal(exp, got) {
+			t.Fatalf("expected results %v, got: %v", exp, got)
+		}
+
+		calls := findingRepo.StoreFindingCalls()
+		if len(calls) != 1 {
+			t.Fatalf("expected 1 finding to be stored, got: %+v", calls)
+		}
+
+		f := calls[0].FindingMoqParam
+		if f.Check != finding.CheckIDORCandidate || f.RequestLogID != reqLog.ID || !strings.Contains(f.Description, "1, 3") {
+			t.Fatalf("expected IDOR finding for request log, got: %+v", f)
+		}
+	})
+}
@@ -0,0 +1,1863 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package idor_test
+
+import (
+	"context"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/oklog/ulid"
+	"net/http"
+	"sync"
+)
+
+// Ensure, that ReqLogServiceMock does implement reqlog.Service.
+// If this is not the case, regenerate this file with moq.
+var _ reqlog.Service = &ReqLogServiceMock{}
+
+// ReqLogServiceMock is a mock implementation of reqlog.Service.
+//
+//	func TestSomethingThatUsesService(t *testing.T) {
+//
+//		// make and configure a mocked reqlog.Service
+//		mockedService := &ReqLogServiceMock{
+//			ActiveProjectIDFunc: func() ulid.ULID {
+//				panic("mock out the ActiveProjectID method")
+//			},
+//			BodyRulesFunc: func() reqlog.BodyRules {
+//				panic("mock out the BodyRules method")
+//			},
+//			BypassOutOfScopeRequestsFunc: func() bool {
+//				panic("mock out the BypassOutOfScopeRequests method")
+//			},
+//			CapturePausedFunc: func() bool {
+//				panic("mock out the CapturePaused method")
+//			},
+//			ClearRequestsFunc: func(ctx context.Context, projectID ulid.ULID) error {
+//				panic("mock out the ClearRequests method")
+//			},
+//			ClientRoutesFunc: func() []reqlog.ClientRoute {
+//				panic("mock out the ClientRoutes method")
+//			},
+//			CloseFunc: func()  {
+//				panic("mock out the Close method")
+//			},
+//			ColumnsFunc: func() []reqlog.Column {
+//				panic("mock out the Columns method")
+//			},
+//			DeleteBodyViewFunc: func(ctx context.Context, reqLogID ulid.ULID, name string) error {
+//				panic("mock out the DeleteBodyView method")
+//			},
+//			DeleteRequestsFunc: func(ctx context.Context, sel reqlog.Selection) (int, error) {
+//				panic("mock out the DeleteRequests method")
+//			},
+//			FindCorrelatedRequestsFunc: func(ctx context.Context, correlationID ulid.ULID) ([]reqlog.RequestLog, error) {
+//				panic("mock out the FindCorrelatedRequests method")
+//			},
+//			FindPageLoadFunc: func(ctx context.Context, id ulid.ULID) ([]reqlog.RequestLog, error) {
+//				panic("mock out the FindPageLoad method")
+//			},
+//			FindRedirectChainFunc: func(ctx context.Context, id ulid.ULID) ([]reqlog.RequestLog, error) {
+//				panic("mock out the FindRedirectChain method")
+//			},
+//			FindReqsFilterFunc: func() reqlog.FindRequestsFilter {
+//				panic("mock out the FindReqsFilter method")
+//			},
+//			FindRequestLogByIDFunc: func(ctx context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
+//				panic("mock out the FindRequestLogByID method")
+//			},
+//			FindRequestsFunc: func(ctx context.Context) ([]reqlog.RequestLog, error) {
+//				panic("mock out the FindRequests method")
+//			},
+//			FindSelectedRequestsFunc: func(ctx context.Context, sel reqlog.Selection) ([]reqlog.RequestLog, error) {
+//				panic("mock out the FindSelectedRequests method")
+//			},
+//			FlushFunc: func(ctx context.Context) error {
+//				panic("mock out the Flush method")
+//			},
+//			IsCapturePausedFunc: func() bool {
+//				panic("mock out the IsCapturePaused method")
+//			},
+//			ProjectCapturePausedFunc: func(projectID ulid.ULID) bool {
+//				panic("mock out the ProjectCapturePaused method")
+//			},
+//			RawCaptureHandlerFunc: func(req *http.Request, raw proxy.RawExchange)  {
+//				panic("mock out the RawCaptureHandler method")
+//			},
+//			ReadOnlyFunc: func() bool {
+//				panic("mock out the ReadOnly method")
+//			},
+//			RequestErrorHandlerFunc: func(req *http.Request, err error)  {
+//				panic("mock out the RequestErrorHandler method")
+//			},
+//			RequestModifierFunc: func(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+//				panic("mock out the RequestModifier method")
+//			},
+//			ResponseModifierFunc: func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+//				panic("mock out the ResponseModifier method")
+//			},
+//			RetryHandlerFunc: func(req *http.Request, retries int)  {
+//				panic("mock out the RetryHandler method")
+//			},
+//			SamplingFunc: func() reqlog.Sampling {
+//				panic("mock out the Sampling method")
+//			},
+//			SearchMetadataFunc: func(ctx context.Context) (reqlog.SearchMetadata, error) {
+//				panic("mock out the SearchMetadata method")
+//			},
+//			SetActiveProjectIDFunc: func(id ulid.ULID)  {
+//				panic("mock out the SetActiveProjectID method")
+//			},
+//			SetBodyRulesFunc: func(rules reqlog.BodyRules)  {
+//				panic("mock out the SetBodyRules method")
+//			},
+//			SetBodyViewFunc: func(ctx context.Context, reqLogID ulid.ULID, view reqlog.BodyView) (reqlog.BodyView, error) {
+//				panic("mock out the SetBodyView method")
+//			},
+//			SetBypassOutOfScopeRequestsFunc: func(b bool)  {
+//				panic("mock out the SetBypassOutOfScopeRequests method")
+//			},
+//			SetCapturePausedFunc: func(paused bool)  {
+//				panic("mock out the SetCapturePaused method")
+//			},
+//			SetClientRoutesFunc: func(routes []reqlog.ClientRoute) error {
+//				panic("mock out the SetClientRoutes method")
+//			},
+//			SetColumnsFunc: func(columns []reqlog.Column) error {
+//				panic("mock out the SetColumns method")
+//			},
+//			SetFindReqsFilterFunc: func(filter reqlog.FindRequestsFilter)  {
+//				panic("mock out the SetFindReqsFilter method")
+//			},
+//			SetProjectCapturePausedFunc: func(projectID ulid.ULID, paused bool)  {
+//				panic("mock out the SetProjectCapturePaused method")
+//			},
+//			SetReadOnlyFunc: func(readOnly bool)  {
+//				panic("mock out the SetReadOnly method")
+//			},
+//			SetSamplingFunc: func(sampling reqlog.Sampling) error {
+//				panic("mock out the SetSampling method")
+//			},
+//			StoreStatsFunc: func() reqlog.StoreStats {
+//				panic("mock out the StoreStats method")
+//			},
+//			TagRequestsFunc: func(ctx context.Context, sel reqlog.Selection, add []string, remove []string) (int, error) {
+//				panic("mock out the TagRequests method")
+//			},
+//		}
+//
+//		// use mockedService in code that requires reqlog.Service
+//		// and then make assertions.
+//
+//	}
+type ReqLogServiceMock struct {
+	// ActiveProjectIDFunc mocks the ActiveProjectID method.
+	ActiveProjectIDFunc func() ulid.ULID
+
+	// BodyRulesFunc mocks the BodyRules method.
+	BodyRulesFunc func() reqlog.BodyRules
+
+	// BypassOutOfScopeRequestsFunc mocks the BypassOutOfScopeRequests method.
+	BypassOutOfScopeRequestsFunc func() bool
+
+	// CapturePausedFunc mocks the CapturePaused method.
+	CapturePausedFunc func() bool
+
+	// ClearRequestsFunc mocks the ClearRequests method.
+	ClearRequestsFunc func(ctx context.Context, projectID ulid.ULID) error
+
+	// ClientRoutesFunc mocks the ClientRoutes method.
+	ClientRoutesFunc func() []reqlog.ClientRoute
+
+	// CloseFunc mocks the Close method.
+	CloseFunc func()
+
+	// ColumnsFunc mocks the Columns method.
+	ColumnsFunc func() []reqlog.Column
+
+	// DeleteBodyViewFunc mocks the DeleteBodyView method.
+	DeleteBodyViewFunc func(ctx context.Context, reqLogID ulid.ULID, name string) error
+
+	// DeleteRequestsFunc mocks the DeleteRequests method.
+	DeleteRequestsFunc func(ctx context.Context, sel reqlog.Selection) (int, error)
+
+	// FindCorrelatedRequestsFunc mocks the FindCorrelatedRequests method.
+	FindCorrelatedRequestsFunc func(ctx context.Context, correlationID ulid.ULID) ([]reqlog.RequestLog, error)
+
+	// FindPageLoadFunc mocks the FindPageLoad method.
+	FindPageLoadFunc func(ctx context.Context, id ulid.ULID) ([]reqlog.RequestLog, error)
+
+	// FindRedirectChainFunc mocks the FindRedirectChain method.
+	FindRedirectChainFunc func(ctx context.Context, id ulid.ULID) ([]reqlog.RequestLog, error)
+
+	// FindReqsFilterFunc mocks the FindReqsFilter method.
+	FindReqsFilterFunc func() reqlog.FindRequestsFilter
+
+	// FindRequestLogByIDFunc mocks the FindRequestLogByID method.
+	FindRequestLogByIDFunc func(ctx context.Context, id ulid.ULID) (reqlog.RequestLog, error)
+
+	// FindRequestsFunc mocks the FindRequests method.
+	FindRequestsFunc func(ctx context.Context) ([]reqlog.RequestLog, error)
+
+	// FindSelectedRequestsFunc mocks the FindSelectedRequests method.
+	FindSelectedRequestsFunc func(ctx context.Context, sel reqlog.Selection) ([]reqlog.RequestLog, error)
+
+	// FlushFunc mocks the Flush method.
+	FlushFunc func(ctx context.Context) error
+
+	// IsCapturePausedFunc mocks the IsCapturePaused method.
+	IsCapturePausedFunc func() bool
+
+	// ProjectCapturePausedFunc mocks the ProjectCapturePaused method.
+	ProjectCapturePausedFunc func(projectID ulid.ULID) bool
+
+	// RawCaptureHandlerFunc mocks the RawCaptureHandler method.
+	RawCaptureHandlerFunc func(req *http.Request, raw proxy.RawExchange)
+
+	// ReadOnlyFunc mocks the ReadOnly method.
+	ReadOnlyFunc func() bool
+
+	// RequestErrorHandlerFunc mocks the RequestErrorHandler method.
+	RequestErrorHandlerFunc func(req *http.Request, err error)
+
+	// RequestModifierFunc mocks the RequestModifier method.
+	RequestModifierFunc func(next proxy.RequestModifyFunc) proxy.RequestModifyFunc
+
+	// ResponseModifierFunc mocks the ResponseModifier method.
+	ResponseModifierFunc func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc
+
+	// RetryHandlerFunc mocks the RetryHandler method.
+	RetryHandlerFunc func(req *http.Request, retries int)
+
+	// SamplingFunc mocks the Sampling method.
+	SamplingFunc func() reqlog.Sampling
+
+	// SearchMetadataFunc mocks the SearchMetadata method.
+	SearchMetadataFunc func(ctx context.Context) (reqlog.SearchMetadata, error)
+
+	// SetActiveProjectIDFunc mocks the SetActiveProjectID method.
+	SetActiveProjectIDFunc func(id ulid.ULID)
+
+	// SetBodyRulesFunc mocks the SetBodyRules method.
+	SetBodyRulesFunc func(rules reqlog.BodyRules)
+
+	// SetBodyViewFunc mocks the SetBodyView method.
+	SetBodyViewFunc func(ctx context.Context, reqLogID ulid.ULID, view reqlog.BodyView) (reqlog.BodyView, error)
+
+	// SetBypassOutOfScopeRequestsFunc mocks the SetBypassOutOfScopeRequests method.
+	SetBypassOutOfScopeRequestsFunc func(b bool)
+
+	// SetCapturePausedFunc mocks the SetCapturePaused method.
+	SetCapturePausedFunc func(paused bool)
+
+	// SetClientRoutesFunc mocks the SetClientRoutes method.
+	SetClientRoutesFunc func(routes []reqlog.ClientRoute) error
+
+	// SetColumnsFunc mocks the SetColumns method.
+	SetColumnsFunc func(columns []reqlog.Column) error
+
+	// SetFindReqsFilterFunc mocks the SetFindReqsFilter method.
+	SetFindReqsFilterFunc func(filter reqlog.FindRequestsFilter)
+
+	// SetProjectCapturePausedFunc mocks the SetProjectCapturePaused method.
+	SetProjectCapturePausedFunc func(projectID ulid.ULID, paused bool)
+
+	// SetReadOnlyFunc mocks the SetReadOnly method.
+	SetReadOnlyFunc func(readOnly bool)
+
+	// SetSamplingFunc mocks the SetSampling method.
+	SetSamplingFunc func(sampling reqlog.Sampling) error
+
+	// StoreStatsFunc mocks the StoreStats method.
+	StoreStatsFunc func() reqlog.StoreStats
+
+	// TagRequestsFunc mocks the TagRequests method.
+	TagRequestsFunc func(ctx context.Context, sel reqlog.Selection, add []string, remove []string) (int, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// ActiveProjectID holds details about calls to the ActiveProjectID method.
+		ActiveProjectID []struct {
+		}
+		// BodyRules holds details about calls to the BodyRules method.
+		BodyRules []struct {
+		}
+		// BypassOutOfScopeRequests holds details about calls to the BypassOutOfScopeRequests method.
+		BypassOutOfScopeRequests []struct {
+		}
+		// CapturePaused holds details about calls to the CapturePaused method.
+		CapturePaused []struct {
+		}
+		// ClearRequests holds details about calls to the ClearRequests method.
+		ClearRequests []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+		}
+		// ClientRoutes holds details about calls to the ClientRoutes method.
+		ClientRoutes []struct {
+		}
+		// Close holds details about calls to the Close method.
+		Close []struct {
+		}
+		// Columns holds details about calls to the Columns method.
+		Columns []struct {
+		}
+		// DeleteBodyView holds details about calls to the DeleteBodyView method.
+		DeleteBodyView []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ReqLogID is the reqLogID argument value.
+			ReqLogID ulid.ULID
+			// Name is the name argument value.
+			Name string
+		}
+		// DeleteRequests holds details about calls to the DeleteRequests method.
+		DeleteRequests []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Sel is the sel argument value.
+			Sel reqlog.Selection
+		}
+		// FindCorrelatedRequests holds details about calls to the FindCorrelatedRequests method.
+		FindCorrelatedRequests []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// CorrelationID is the correlationID argument value.
+			CorrelationID ulid.ULID
+		}
+		// FindPageLoad holds details about calls to the FindPageLoad method.
+		FindPageLoad []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID ulid.ULID
+		}
+		// FindRedirectChain holds details about calls to the FindRedirectChain method.
+		FindRedirectChain []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID ulid.ULID
+		}
+		// FindReqsFilter holds details about calls to the FindReqsFilter method.
+		FindReqsFilter []struct {
+		}
+		// FindRequestLogByID holds details about calls to the FindRequestLogByID method.
+		FindRequestLogByID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID ulid.ULID
+		}
+		// FindRequests holds details about calls to the FindRequests method.
+		FindRequests []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// FindSelectedRequests holds details about calls to the FindSelectedRequests method.
+		FindSelectedRequests []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Sel is the sel argument value.
+			Sel reqlog.Selection
+		}
+		// Flush holds details about calls to the Flush method.
+		Flush []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// IsCapturePaused holds details about calls to the IsCapturePaused method.
+		IsCapturePaused []struct {
+		}
+		// ProjectCapturePaused holds details about calls to the ProjectCapturePaused method.
+		ProjectCapturePaused []struct {
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+		}
+		// RawCaptureHandler holds details about calls to the RawCaptureHandler method.
+		RawCaptureHandler []struct {
+			// Req is the req argument value.
+			Req *http.Request
+			// Raw is the raw argument value.
+			Raw proxy.RawExchange
+		}
+		// ReadOnly holds details about calls to the ReadOnly method.
+		ReadOnly []struct {
+		}
+		// RequestErrorHandler holds details about calls to the RequestErrorHandler method.
+		RequestErrorHandler []struct {
+			// Req is the req argument value.
+			Req *http.Request
+			// Err is the err argument value.
+			Err error
+		}
+		// RequestModifier holds details about calls to the RequestModifier method.
+		RequestModifier []struct {
+			// Next is the next argument value.
+			Next proxy.RequestModifyFunc
+		}
+		// ResponseModifier holds details about calls to the ResponseModifier method.
+		ResponseModifier []struct {
+			// Next is the next argument value.
+			Next proxy.ResponseModifyFunc
+		}
+		// RetryHandler holds details about calls to the RetryHandler method.
+		RetryHandler []struct {
+			// Req is the req argument value.
+			Req *http.Request
+			// Retries is the retries argument value.
+			Retries int
+		}
+		// Sampling holds details about calls to the Sampling method.
+		Sampling []struct {
+		}
+		// SearchMetadata holds details about calls to the SearchMetadata method.
+		SearchMetadata []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// SetActiveProjectID holds details about calls to the SetActiveProjectID method.
+		SetActiveProjectID []struct {
+			// ID is the id argument value.
+			ID ulid.ULID
+		}
+		// SetBodyRules holds details about calls to the SetBodyRules method.
+		SetBodyRules []struct {
+			// Rules is the rules argument value.
+			Rules reqlog.BodyRules
+		}
+		// SetBodyView holds details about calls to the SetBodyView method.
+		SetBodyView []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ReqLogID is the reqLogID argument value.
+			ReqLogID ulid.ULID
+			// View is the view argument value.
+			View reqlog.BodyView
+		}
+		// SetBypassOutOfScopeRequests holds details about calls to the SetBypassOutOfScopeRequests method.
+		SetBypassOutOfScopeRequests []struct {
+			// B is the b argument value.
+			B bool
+		}
+		// SetCapturePaused holds details about calls to the SetCapturePaused method.
+		SetCapturePaused []struct {
+			// Paused is the paused argument value.
+			Paused bool
+		}
+		// SetClientRoutes holds details about calls to the SetClientRoutes method.
+		SetClientRoutes []struct {
+			// Routes is the routes argument value.
+			Routes []reqlog.ClientRoute
+		}
+		// SetColumns holds details about calls to the SetColumns method.
+		SetColumns []struct {
+			// Columns is the columns argument value.
+			Columns []reqlog.Column
+		}
+		// SetFindReqsFilter holds details about calls to the SetFindReqsFilter method.
+		SetFindReqsFilter []struct {
+			// Filter is the filter argument value.
+			Filter reqlog.FindRequestsFilter
+		}
+		// SetProjectCapturePaused holds details about calls to the SetProjectCapturePaused method.
+		SetProjectCapturePaused []struct {
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+			// Paused is the paused argument value.
+			Paused bool
+		}
+		// SetReadOnly holds details about calls to the SetReadOnly method.
+		SetReadOnly []struct {
+			// ReadOnly is the readOnly argument value.
+			ReadOnly bool
+		}
+		// SetSampling holds details about calls to the SetSampling method.
+		SetSampling []struct {
+			// Sampling is the sampling argument value.
+			Sampling reqlog.Sampling
+		}
+		// StoreStats holds details about calls to the StoreStats method.
+		StoreStats []struct {
+		}
+		// TagRequests holds details about calls to the TagRequests method.
+		TagRequests []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Sel is the sel argument value.
+			Sel reqlog.Selection
+			// Add is the add argument value.
+			Add []string
+			// Remove is the remove argument value.
+			Remove []string
+		}
+	}
+	lockActiveProjectID             sync.RWMutex
+	lockBodyRules                   sync.RWMutex
+	lockBypassOutOfScopeRequests    sync.RWMutex
+	lockCapturePaused               sync.RWMutex
+	lockClearRequests               sync.RWMutex
+	lockClientRoutes                sync.RWMutex
+	lockClose                       sync.RWMutex
+	lockColumns                     sync.RWMutex
+	lockDeleteBodyView              sync.RWMutex
+	lockDeleteRequests              sync.RWMutex
+	lockFindCorrelatedRequests      sync.RWMutex
+	lockFindPageLoad                sync.RWMutex
+	lockFindRedirectChain           sync.RWMutex
+	lockFindReqsFilter              sync.RWMutex
+	lockFindRequestLogByID          sync.RWMutex
+	lockFindRequests                sync.RWMutex
+	lockFindSelectedRequests        sync.RWMutex
+	lockFlush                       sync.RWMutex
+	lockIsCapturePaused             sync.RWMutex
+	lockProjectCapturePaused        sync.RWMutex
+	lockRawCaptureHandler           sync.RWMutex
+	lockReadOnly                    sync.RWMutex
+	lockRequestErrorHandler         sync.RWMutex
+	lockRequestModifier             sync.RWMutex
+	lockResponseModifier            sync.RWMutex
+	lockRetryHandler                sync.RWMutex
+	lockSampling                    sync.RWMutex
+	lockSearchMetadata              sync.RWMutex
+	lockSetActiveProjectID          sync.RWMutex
+	lockSetBodyRules                sync.RWMutex
+	lockSetBodyView                 sync.RWMutex
+	lockSetBypassOutOfScopeRequests sync.RWMutex
+	lockSetCapturePaused            sync.RWMutex
+	lockSetClientRoutes             sync.RWMutex
+	lockSetColumns                  sync.RWMutex
+	lockSetFindReqsFilter           sync.RWMutex
+	lockSetProjectCapturePaused     sync.RWMutex
+	lockSetReadOnly                 sync.RWMutex
+	lockSetSampling                 sync.RWMutex
+	lockStoreStats                  sync.RWMutex
+	lockTagRequests                 sync.RWMutex
+}
+
+// ActiveProjectID calls ActiveProjectIDFunc.
+func (mock *ReqLogServiceMock) ActiveProjectID() ulid.ULID {
+	if mock.ActiveProjectIDFunc == nil {
+		panic("ReqLogServiceMock.ActiveProjectIDFunc: method is nil but Service.ActiveProjectID was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockActiveProjectID.Lock()
+	mock.calls.ActiveProjectID = append(mock.calls.ActiveProjectID, callInfo)
+	mock.lockActiveProjectID.Unlock()
+	return mock.ActiveProjectIDFunc()
+}
+
+// ActiveProjectIDCalls gets all the calls that were made to ActiveProjectID.
+// Check the length with:
+//
+//	len(mockedService.ActiveProjectIDCalls())
+func (mock *ReqLogServiceMock) ActiveProjectIDCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockActiveProjectID.RLock()
+	calls = mock.calls.ActiveProjectID
+	mock.lockActiveProjectID.RUnlock()
+	return calls
+}
+
+// BodyRules calls BodyRulesFunc.
+func (mock *ReqLogServiceMock) BodyRules() reqlog.BodyRules {
+	if mock.BodyRulesFunc == nil {
+		panic("ReqLogServiceMock.BodyRulesFunc: method is nil but Service.BodyRules was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockBodyRules.Lock()
+	mock.calls.BodyRules = append(mock.calls.BodyRules, callInfo)
+	mock.lockBodyRules.Unlock()
+	return mock.BodyRulesFunc()
+}
+
+// BodyRulesCalls gets all the calls that were made to BodyRules.
+// Check the length with:
+//
+//	len(mockedService.BodyRulesCalls())
+func (mock *ReqLogServiceMock) BodyRulesCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockBodyRules.RLock()
+	calls = mock.calls.BodyRules
+	mock.lockBodyRules.RUnlock()
+	return calls
+}
+
+// BypassOutOfScopeRequests calls BypassOutOfScopeRequestsFunc.
+func (mock *ReqLogServiceMock) BypassOutOfScopeRequests() bool {
+	if mock.BypassOutOfScopeRequestsFunc == nil {
+		panic("ReqLogServiceMock.BypassOutOfScopeRequestsFunc: method is nil but Service.BypassOutOfScopeRequests was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockBypassOutOfScopeRequests.Lock()
+	mock.calls.BypassOutOfScopeRequests = append(mock.calls.BypassOutOfScopeRequests, callInfo)
+	mock.lockBypassOutOfScopeRequests.Unlock()
+	return mock.BypassOutOfScopeRequestsFunc()
+}
+
+// BypassOutOfScopeRequestsCalls gets all the calls that were made to BypassOutOfScopeRequests.
+// Check the length with:
+//
+//	len(mockedService.BypassOutOfScopeRequestsCalls())
+func (mock *ReqLogServiceMock) BypassOutOfScopeRequestsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockBypassOutOfScopeRequests.RLock()
+	calls = mock.calls.BypassOutOfScopeRequests
+	mock.lockBypassOutOfScopeRequests.RUnlock()
+	return calls
+}
+
+// CapturePaused calls CapturePausedFunc.
+func (mock *ReqLogServiceMock) CapturePaused() bool {
+	if mock.CapturePausedFunc == nil {
+		panic("ReqLogServiceMock.CapturePausedFunc: method is nil but Service.CapturePaused was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockCapturePaused.Lock()
+	mock.calls.CapturePaused = append(mock.calls.CapturePaused, callInfo)
+	mock.lockCapturePaused.Unlock()
+	return mock.CapturePausedFunc()
+}
+
+// CapturePausedCalls gets all the calls that were made to CapturePaused.
+// Check the length with:
+//
+//	len(mockedService.CapturePausedCalls())
+func (mock *ReqLogServiceMock) CapturePausedCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockCapturePaused.RLock()
+	calls = mock.calls.CapturePaused
+	mock.lockCapturePaused.RUnlock()
+	return calls
+}
+
+// ClearRequests calls ClearRequestsFunc.
+func (mock *ReqLogServiceMock) ClearRequests(ctx context.Context, projectID ulid.ULID) error {
+	if mock.ClearRequestsFunc == nil {
+		panic("ReqLogServiceMock.ClearRequestsFunc: method is nil but Service.ClearRequests was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+	}
+	mock.lockClearRequests.Lock()
+	mock.calls.ClearRequests = append(mock.calls.ClearRequests, callInfo)
+	mock.lockClearRequests.Unlock()
+	return mock.ClearRequestsFunc(ctx, projectID)
+}
+
+// ClearRequestsCalls gets all the calls that were made to ClearRequests.
+// Check the length with:
+//
+//	len(mockedService.ClearRequestsCalls())
+func (mock *ReqLogServiceMock) ClearRequestsCalls() []struct {
+	Ctx       context.Context
+	ProjectID ulid.ULID
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}
+	mock.lockClearRequests.RLock()
+	calls = mock.calls.ClearRequests
+	mock.lockClearRequests.RUnlock()
+	return calls
+}
+
+// ClientRoutes calls ClientRoutesFunc.
+func (mock *ReqLogServiceMock) ClientRoutes() []reqlog.ClientRoute {
+	if mock.ClientRoutesFunc == nil {
+		panic("ReqLogServiceMock.ClientRoutesFunc: method is nil but Service.ClientRoutes was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockClientRoutes.Lock()
+	mock.calls.ClientRoutes = append(mock.calls.ClientRoutes, callInfo)
+	mock.lockClientRoutes.Unlock()
+	return mock.ClientRoutesFunc()
+}
+
+// ClientRoutesCalls gets all the calls that were made to ClientRoutes.
+// Check the length with:
+//
+//	len(mockedService.ClientRoutesCalls())
+func (mock *ReqLogServiceMock) ClientRoutesCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockClientRoutes.RLock()
+	calls = mock.calls.ClientRoutes
+	mock.lockClientRoutes.RUnlock()
+	return calls
+}
+
+// Close calls CloseFunc.
+func (mock *ReqLogServiceMock) Close() {
+	if mock.CloseFunc == nil {
+		panic("ReqLogServiceMock.CloseFunc: method is nil but Service.Close was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockClose.Lock()
+	mock.calls.Close = append(mock.calls.Close, callInfo)
+	mock.lockClose.Unlock()
+	mock.CloseFunc()
+}
+
+// CloseCalls gets all the calls that were made to Close.
+// Check the length with:
+//
+//	len(mockedService.CloseCalls())
+func (mock *ReqLogServiceMock) CloseCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockClose.RLock()
+	calls = mock.calls.Close
+	mock.lockClose.RUnlock()
+	return calls
+}
+
+// Columns calls ColumnsFunc.
+func (mock *ReqLogServiceMock) Columns() []reqlog.Column {
+	if mock.ColumnsFunc == nil {
+		panic("ReqLogServiceMock.ColumnsFunc: method is nil but Service.Columns was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockColumns.Lock()
+	mock.calls.Columns = append(mock.calls.Columns, callInfo)
+	mock.lockColumns.Unlock()
+	return mock.ColumnsFunc()
+}
+
+// ColumnsCalls gets all the calls that were made to Columns.
+// Check the length with:
+//
+//	len(mockedService.ColumnsCalls())
+func (mock *ReqLogServiceMock) ColumnsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockColumns.RLock()
+	calls = mock.calls.Columns
+	mock.lockColumns.RUnlock()
+	return calls
+}
+
+// DeleteBodyView calls DeleteBodyViewFunc.
+func (mock *ReqLogServiceMock) DeleteBodyView(ctx context.Context, reqLogID ulid.ULID, name string) error {
+	if mock.DeleteBodyViewFunc == nil {
+		panic("ReqLogServiceMock.DeleteBodyViewFunc: method is nil but Service.DeleteBodyView was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		ReqLogID ulid.ULID
+		Name     string
+	}{
+		Ctx:      ctx,
+		ReqLogID: reqLogID,
+		Name:     name,
+	}
+	mock.lockDeleteBodyView.Lock()
+	mock.calls.DeleteBodyView = append(mock.calls.DeleteBodyView, callInfo)
+	mock.lockDeleteBodyView.Unlock()
+	return mock.DeleteBodyViewFunc(ctx, reqLogID, name)
+}
+
+// DeleteBodyViewCalls gets all the calls that were made to DeleteBodyView.
+// Check the length with:
+//
+//	len(mockedService.DeleteBodyViewCalls())
+func (mock *ReqLogServiceMock) DeleteBodyViewCalls() []struct {
+	Ctx      context.Context
+	ReqLogID ulid.ULID
+	Name     string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		ReqLogID ulid.ULID
+		Name     string
+	}
+	mock.lockDeleteBodyView.RLock()
+	calls = mock.calls.DeleteBodyView
+	mock.lockDeleteBodyView.RUnlock()
+	return calls
+}
+
+// DeleteRequests calls DeleteRequestsFunc.
+func (mock *ReqLogServiceMock) DeleteRequests(ctx context.Context, sel reqlog.Selection) (int, error) {
+	if mock.DeleteRequestsFunc == nil {
+		panic("ReqLogServiceMock.DeleteRequestsFunc: method is nil but Service.DeleteRequests was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Sel reqlog.Selection
+	}{
+		Ctx: ctx,
+		Sel: sel,
+	}
+	mock.lockDeleteRequests.Lock()
+	mock.calls.DeleteRequests = append(mock.calls.DeleteRequests, callInfo)
+	mock.lockDeleteRequests.Unlock()
+	return mock.DeleteRequestsFunc(ctx, sel)
+}
+
+// DeleteRequestsCalls gets all the calls that were made to DeleteRequests.
+// Check the length with:
+//
+//	len(mockedService.DeleteRequestsCalls())
+func (mock *ReqLogServiceMock) DeleteRequestsCalls() []struct {
+	Ctx context.Context
+	Sel reqlog.Selection
+} {
+	var calls []struct {
+		Ctx context.Context
+		Sel reqlog.Selection
+	}
+	mock.lockDeleteRequests.RLock()
+	calls = mock.calls.DeleteRequests
+	mock.lockDeleteRequests.RUnlock()
+	return calls
+}
+
+// FindCorrelatedRequests calls FindCorrelatedRequestsFunc.
+func (mock *ReqLogServiceMock) FindCorrelatedRequests(ctx context.Context, correlationID ulid.ULID) ([]reqlog.RequestLog, error) {
+	if mock.FindCorrelatedRequestsFunc == nil {
+		panic("ReqLogServiceMock.FindCorrelatedRequestsFunc: method is nil but Service.FindCorrelatedRequests was just called")
+	}
+	callInfo := struct {
+		Ctx           context.Context
+		CorrelationID ulid.ULID
+	}{
+		Ctx:           ctx,
+		CorrelationID: correlationID,
+	}
+	mock.lockFindCorrelatedRequests.Lock()
+	mock.calls.FindCorrelatedRequests = append(mock.calls.FindCorrelatedRequests, callInfo)
+	mock.lockFindCorrelatedRequests.Unlock()
+	return mock.FindCorrelatedRequestsFunc(ctx, correlationID)
+}
+
+// FindCorrelatedRequestsCalls gets all the calls that were made to FindCorrelatedRequests.
+// Check the length with:
+//
+//	len(mockedService.FindCorrelatedRequestsCalls())
+func (mock *ReqLogServiceMock) FindCorrelatedRequestsCalls() []struct {
+	Ctx           context.Context
+	CorrelationID ulid.ULID
+} {
+	var calls []struct {
+		Ctx           context.Context
+		CorrelationID ulid.ULID
+	}
+	mock.lockFindCorrelatedRequests.RLock()
+	calls = mock.calls.FindCorrelatedRequests
+	mock.lockFindCorrelatedRequests.RUnlock()
+	return calls
+}
+
+// FindPageLoad calls FindPageLoadFunc.
+func (mock *ReqLogServiceMock) FindPageLoad(ctx context.Context, id ulid.ULID) ([]reqlog.RequestLog, error) {
+	if mock.FindPageLoadFunc == nil {
+		panic("ReqLogServiceMock.FindPageLoadFunc: method is nil but Service.FindPageLoad was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockFindPageLoad.Lock()
+	mock.calls.FindPageLoad = append(mock.calls.FindPageLoad, callInfo)
+	mock.lockFindPageLoad.Unlock()
+	return mock.FindPageLoadFunc(ctx, id)
+}
+
+// FindPageLoadCalls gets all the calls that were made to FindPageLoad.
+// Check the length with:
+//
+//	len(mockedService.FindPageLoadCalls())
+func (mock *ReqLogServiceMock) FindPageLoadCalls() []struct {
+	Ctx context.Context
+	ID  ulid.ULID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}
+	mock.lockFindPageLoad.RLock()
+	calls = mock.calls.FindPageLoad
+	mock.lockFindPageLoad.RUnlock()
+	return calls
+}
+
+// FindRedirectChain calls FindRedirectChainFunc.
+func (mock *ReqLogServiceMock) FindRedirectChain(ctx context.Context, id ulid.ULID) ([]reqlog.RequestLog, error) {
+	if mock.FindRedirectChainFunc == nil {
+		panic("ReqLogServiceMock.FindRedirectChainFunc: method is nil but Service.FindRedirectChain was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockFindRedirectChain.Lock()
+	mock.calls.FindRedirectChain = append(mock.calls.FindRedirectChain, callInfo)
+	mock.lockFindRedirectChain.Unlock()
+	return mock.FindRedirectChainFunc(ctx, id)
+}
+
+// FindRedirectChainCalls gets all the calls that were made to FindRedirectChain.
+// Check the length with:
+//
+//	len(mockedService.FindRedirectChainCalls())
+func (mock *ReqLogServiceMock) FindRedirectChainCalls() []struct {
+	Ctx context.Context
+	ID  ulid.ULID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}
+	mock.lockFindRedirectChain.RLock()
+	calls = mock.calls.FindRedirectChain
+	mock.lockFindRedirectChain.RUnlock()
+	return calls
+}
+
+// FindReqsFilter calls FindReqsFilterFunc.
+func (mock *ReqLogServiceMock) FindReqsFilter() reqlog.FindRequestsFilter {
+	if mock.FindReqsFilterFunc == nil {
+		panic("ReqLogServiceMock.FindReqsFilterFunc: method is nil but Service.FindReqsFilter was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockFindReqsFilter.Lock()
+	mock.calls.FindReqsFilter = append(mock.calls.FindReqsFilter, callInfo)
+	mock.lockFindReqsFilter.Unlock()
+	return mock.FindReqsFilterFunc()
+}
+
+// FindReqsFilterCalls gets all the calls that were made to FindReqsFilter.
+// Check the length with:
+//
+//	len(mockedService.FindReqsFilterCalls())
+func (mock *ReqLogServiceMock) FindReqsFilterCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockFindReqsFilter.RLock()
+	calls = mock.calls.FindReqsFilter
+	mock.lockFindReqsFilter.RUnlock()
+	return calls
+}
+
+// FindRequestLogByID calls FindRequestLogByIDFunc.
+func (mock *ReqLogServiceMock) FindRequestLogByID(ctx context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
+	if mock.FindRequestLogByIDFunc == nil {
+		panic("ReqLogServiceMock.FindRequestLogByIDFunc: method is nil but Service.FindRequestLogByID was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockFindRequestLogByID.Lock()
+	mock.calls.FindRequestLogByID = append(mock.calls.FindRequestLogByID, callInfo)
+	mock.lockFindRequestLogByID.Unlock()
+	return mock.FindRequestLogByIDFunc(ctx, id)
+}
+
+// FindRequestLogByIDCalls gets all the calls that were made to FindRequestLogByID.
+// Check the length with:
+//
+//	len(mockedService.FindRequestLogByIDCalls())
+func (mock *ReqLogServiceMock) FindRequestLogByIDCalls() []struct {
+	Ctx context.Context
+	ID  ulid.ULID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}
+	mock.lockFindRequestLogByID.RLock()
+	calls = mock.calls.FindRequestLogByID
+	mock.lockFindRequestLogByID.RUnlock()
+	return calls
+}
+
+// FindRequests calls FindRequestsFunc.
+func (mock *ReqLogServiceMock) FindRequests(ctx context.Context) ([]reqlog.RequestLog, error) {
+	if mock.FindRequestsFunc == nil {
+		panic("ReqLogServiceMock.FindRequestsFunc: method is nil but Service.FindRequests was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockFindRequests.Lock()
+	mock.calls.FindRequests = append(mock.calls.FindRequests, callInfo)
+	mock.lockFindRequests.Unlock()
+	return mock.FindRequestsFunc(ctx)
+}
+
+// FindRequestsCalls gets all the calls that were made to FindRequests.
+// Check the length with:
+//
+//	len(mockedService.FindRequestsCalls())
+func (mock *ReqLogServiceMock) FindRequestsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockFindRequests.RLock()
+	calls = mock.calls.FindRequests
+	mock.lockFindRequests.RUnlock()
+	return calls
+}
+
+// FindSelectedRequests calls FindSelectedRequestsFunc.
+func (mock *ReqLogServiceMock) FindSelectedRequests(ctx context.Context, sel reqlog.Selection) ([]reqlog.RequestLog, error) {
+	if mock.FindSelectedRequestsFunc == nil {
+		panic("ReqLogServiceMock.FindSelectedRequestsFunc: method is nil but Service.FindSelectedRequests was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Sel reqlog.Selection
+	}{
+		Ctx: ctx,
+		Sel: sel,
+	}
+	mock.lockFindSelectedRequests.Lock()
+	mock.calls.FindSelectedRequests = append(mock.calls.FindSelectedRequests, callInfo)
+	mock.lockFindSelectedRequests.Unlock()
+	return mock.FindSelectedRequestsFunc(ctx, sel)
+}
+
+// FindSelectedRequestsCalls gets all the calls that were made to FindSelectedRequests.
+// Check the length with:
+//
+//	len(mockedService.FindSelectedRequestsCalls())
+func (mock *ReqLogServiceMock) FindSelectedRequestsCalls() []struct {
+	Ctx context.Context
+	Sel reqlog.Selection
+} {
+	var calls []struct {
+		Ctx context.Context
+		Sel reqlog.Selection
+	}
+	mock.lockFindSelectedRequests.RLock()
+	calls = mock.calls.FindSelectedRequests
+	mock.lockFindSelectedRequests.RUnlock()
+	return calls
+}
+
+// Flush calls FlushFunc.
+func (mock *ReqLogServiceMock) Flush(ctx context.Context) error {
+	if mock.FlushFunc == nil {
+		panic("ReqLogServiceMock.FlushFunc: method is nil but Service.Flush was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockFlush.Lock()
+	mock.calls.Flush = append(mock.calls.Flush, callInfo)
+	mock.lockFlush.Unlock()
+	return mock.FlushFunc(ctx)
+}
+
+// FlushCalls gets all the calls that were made to Flush.
+// Check the length with:
+//
+//	len(mockedService.FlushCalls())
+func (mock *ReqLogServiceMock) FlushCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockFlush.RLock()
+	calls = mock.calls.Flush
+	mock.lockFlush.RUnlock()
+	return calls
+}
+
+// IsCapturePaused calls IsCapturePausedFunc.
+func (mock *ReqLogServiceMock) IsCapturePaused() bool {
+	if mock.IsCapturePausedFunc == nil {
+		panic("ReqLogServiceMock.IsCapturePausedFunc: method is nil but Service.IsCapturePaused was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockIsCapturePaused.Lock()
+	mock.calls.IsCapturePaused = append(mock.calls.IsCapturePaused, callInfo)
+	mock.lockIsCapturePaused.Unlock()
+	return mock.IsCapturePausedFunc()
+}
+
+// IsCapturePausedCalls gets all the calls that were made to IsCapturePaused.
+// Check the length with:
+//
+//	len(mockedService.IsCapturePausedCalls())
+func (mock *ReqLogServiceMock) IsCapturePausedCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockIsCapturePaused.RLock()
+	calls = mock.calls.IsCapturePaused
+	mock.lockIsCapturePaused.RUnlock()
+	return calls
+}
+
+// ProjectCapturePaused calls ProjectCapturePausedFunc.
+func (mock *ReqLogServiceMock) ProjectCapturePaused(projectID ulid.ULID) bool {
+	if mock.ProjectCapturePausedFunc == nil {
+		panic("ReqLogServiceMock.ProjectCapturePausedFunc: method is nil but Service.ProjectCapturePaused was just called")
+	}
+	callInfo := struct {
+		ProjectID ulid.ULID
+	}{
+		ProjectID: projectID,
+	}
+	mock.lockProjectCapturePaused.Lock()
+	mock.calls.ProjectCapturePaused = append(mock.calls.ProjectCapturePaused, callInfo)
+	mock.lockProjectCapturePaused.Unlock()
+	return mock.ProjectCapturePausedFunc(projectID)
+}
+
+// ProjectCapturePausedCalls gets all the calls that were made to ProjectCapturePaused.
+// Check the length with:
+//
+//	len(mockedService.ProjectCapturePausedCalls())
+func (mock *ReqLogServiceMock) ProjectCapturePausedCalls() []struct {
+	ProjectID ulid.ULID
+} {
+	var calls []struct {
+		ProjectID ulid.ULID
+	}
+	mock.lockProjectCapturePaused.RLock()
+	calls = mock.calls.ProjectCapturePaused
+	mock.lockProjectCapturePaused.RUnlock()
+	return calls
+}
+
+// RawCaptureHandler calls RawCaptureHandlerFunc.
+func (mock *ReqLogServiceMock) RawCaptureHandler(req *http.Request, raw proxy.RawExchange) {
+	if mock.RawCaptureHandlerFunc == nil {
+		panic("ReqLogServiceMock.RawCaptureHandlerFunc: method is nil but Service.RawCaptureHandler was just called")
+	}
+	callInfo := struct {
+		Req *http.Request
+		Raw proxy.RawExchange
+	}{
+		Req: req,
+		Raw: raw,
+	}
+	mock.lockRawCaptureHandler.Lock()
+	mock.calls.RawCaptureHandler = append(mock.calls.RawCaptureHandler, callInfo)
+	mock.lockRawCaptureHandler.Unlock()
+	mock.RawCaptureHandlerFunc(req, raw)
+}
+
+// RawCaptureHandlerCalls gets all the calls that were made to RawCaptureHandler.
+// Check the length with:
+//
+//	len(mockedService.RawCaptureHandlerCalls())
+func (mock *ReqLogServiceMock) RawCaptureHandlerCalls() []struct {
+	Req *http.Request
+	Raw proxy.RawExchange
+} {
+	var calls []struct {
+		Req *http.Request
+		Raw proxy.RawExchange
+	}
+	mock.lockRawCaptureHandler.RLock()
+	calls = mock.calls.RawCaptureHandler
+	mock.lockRawCaptureHandler.RUnlock()
+	return calls
+}
+
+// ReadOnly calls ReadOnlyFunc.
+func (mock *ReqLogServiceMock) ReadOnly() bool {
+	if mock.ReadOnlyFunc == nil {
+		panic("ReqLogServiceMock.ReadOnlyFunc: method is nil but Service.ReadOnly was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockReadOnly.Lock()
+	mock.calls.ReadOnly = append(mock.calls.ReadOnly, callInfo)
+	mock.lockReadOnly.Unlock()
+	return mock.ReadOnlyFunc()
+}
+
+// ReadOnlyCalls gets all the calls that were made to ReadOnly.
+// Check the length with:
+//
+//	len(mockedService.ReadOnlyCalls())
+func (mock *ReqLogServiceMock) ReadOnlyCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockReadOnly.RLock()
+	calls = mock.calls.ReadOnly
+	mock.lockReadOnly.RUnlock()
+	return calls
+}
+
+// RequestErrorHandler calls RequestErrorHandlerFunc.
+func (mock *ReqLogServiceMock) RequestErrorHandler(req *http.Request, err error) {
+	if mock.RequestErrorHandlerFunc == nil {
+		panic("ReqLogServiceMock.RequestErrorHandlerFunc: method is nil but Service.RequestErrorHandler was just called")
+	}
+	callInfo := struct {
+		Req *http.Request
+		Err error
+	}{
+		Req: req,
+		Err: err,
+	}
+	mock.lockRequestErrorHandler.Lock()
+	mock.calls.RequestErrorHandler = append(mock.calls.RequestErrorHandler, callInfo)
+	mock.lockRequestErrorHandler.Unlock()
+	mock.RequestErrorHandlerFunc(req, err)
+}
+
+// RequestErrorHandlerCalls gets all the calls that were made to RequestErrorHandler.
+// Check the length with:
+//
+//	len(mockedService.RequestErrorHandlerCalls())
+func (mock *ReqLogServiceMock) RequestErrorHandlerCalls() []struct {
+	Req *http.Request
+	Err error
+} {
+	var calls []struct {
+		Req *http.Request
+		Err error
+	}
+	mock.lockRequestErrorHandler.RLock()
+	calls = mock.calls.RequestErrorHandler
+	mock.lockRequestErrorHandler.RUnlock()
+	return calls
+}
+
+// RequestModifier calls RequestModifierFunc.
+func (mock *ReqLogServiceMock) RequestModifier(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+	if mock.RequestModifierFunc == nil {
+		panic("ReqLogServiceMock.RequestModifierFunc: method is nil but Service.RequestModifier was just called")
+	}
+	callInfo := struct {
+		Next proxy.RequestModifyFunc
+	}{
+		Next: next,
+	}
+	mock.lockRequestModifier.Lock()
+	mock.calls.RequestModifier = append(mock.calls.RequestModifier, callInfo)
+	mock.lockRequestModifier.Unlock()
+	return mock.RequestModifierFunc(next)
+}
+
+// RequestModifierCalls gets all the calls that were made to RequestModifier.
+// Check the length with:
+//
+//	len(mockedService.RequestModifierCalls())
+func (mock *ReqLogServiceMock) RequestModifierCalls() []struct {
+	Next proxy.RequestModifyFunc
+} {
+	var calls []struct {
+		Next proxy.RequestModifyFunc
+	}
+	mock.lockRequestModifier.RLock()
+	calls = mock.calls.RequestModifier
+	mock.lockRequestModifier.RUnlock()
+	return calls
+}
+
+// ResponseModifier calls ResponseModifierFunc.
+func (mock *ReqLogServiceMock) ResponseModifier(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+	if mock.ResponseModifierFunc == nil {
+		panic("ReqLogServiceMock.ResponseModifierFunc: method is nil but Service.ResponseModifier was just called")
+	}
+	callInfo := struct {
+		Next proxy.ResponseModifyFunc
+	}{
+		Next: next,
+	}
+	mock.lockResponseModifier.Lock()
+	mock.calls.ResponseModifier = append(mock.calls.ResponseModifier, callInfo)
+	mock.lockResponseModifier.Unlock()
+	return mock.ResponseModifierFunc(next)
+}
+
+// ResponseModifierCalls gets all the calls that were made to ResponseModifier.
+// Check the length with:
+//
+//	len(mockedService.ResponseModifierCalls())
+func (mock *ReqLogServiceMock) ResponseModifierCalls() []struct {
+	Next proxy.ResponseModifyFunc
+} {
+	var calls []struct {
+		Next proxy.ResponseModifyFunc
+	}
+	mock.lockResponseModifier.RLock()
+	calls = mock.calls.ResponseModifier
+	mock.lockResponseModifier.RUnlock()
+	return calls
+}
+
+// RetryHandler calls RetryHandlerFunc.
+func (mock *ReqLogServiceMock) RetryHandler(req *http.Request, retries int) {
+	if mock.RetryHandlerFunc == nil {
+		panic("ReqLogServiceMock.RetryHandlerFunc: method is nil but Service.RetryHandler was just called")
+	}
+	callInfo := struct {
+		Req     *http.Request
+		Retries int
+	}{
+		Req:     req,
+		Retries: retries,
+	}
+	mock.lockRetryHandler.Lock()
+	mock.calls.RetryHandler = append(mock.calls.RetryHandler, callInfo)
+	mock.lockRetryHandler.Unlock()
+	mock.RetryHandlerFunc(req, retries)
+}
+
+// RetryHandlerCalls gets all the calls that were made to RetryHandler.
+// Check the length with:
+//
+//	len(mockedService.RetryHandlerCalls())
+func (mock *ReqLogServiceMock) RetryHandlerCalls() []struct {
+	Req     *http.Request
+	Retries int
+} {
+	var calls []struct {
+		Req     *http.Request
+		Retries int
+	}
+	mock.lockRetryHandler.RLock()
+	calls = mock.calls.RetryHandler
+	mock.lockRetryHandler.RUnlock()
+	return calls
+}
+
+// Sampling calls SamplingFunc.
+func (mock *ReqLogServiceMock) Sampling() reqlog.Sampling {
+	if mock.SamplingFunc == nil {
+		panic("ReqLogServiceMock.SamplingFunc: method is nil but Service.Sampling was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockSampling.Lock()
+	mock.calls.Sampling = append(mock.calls.Sampling, callInfo)
+	mock.lockSampling.Unlock()
+	return mock.SamplingFunc()
+}
+
+// SamplingCalls gets all the calls that were made to Sampling.
+// Check the length with:
+//
+//	len(mockedService.SamplingCalls())
+func (mock *ReqLogServiceMock) SamplingCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockSampling.RLock()
+	calls = mock.calls.Sampling
+	mock.lockSampling.RUnlock()
+	return calls
+}
+
+// SearchMetadata calls SearchMetadataFunc.
+func (mock *ReqLogServiceMock) SearchMetadata(ctx context.Context) (reqlog.SearchMetadata, error) {
+	if mock.SearchMetadataFunc == nil {
+		panic("ReqLogServiceMock.SearchMetadataFunc: method is nil but Service.SearchMetadata was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockSearchMetadata.Lock()
+	mock.calls.SearchMetadata = append(mock.calls.SearchMetadata, callInfo)
+	mock.lockSearchMetadata.Unlock()
+	return mock.SearchMetadataFunc(ctx)
+}
+
+// SearchMetadataCalls gets all the calls that were made to SearchMetadata.
+// Check the length with:
+//
+//	len(mockedService.SearchMetadataCalls())
+func (mock *ReqLogServiceMock) SearchMetadataCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockSearchMetadata.RLock()
+	calls = mock.calls.SearchMetadata
+	mock.lockSearchMetadata.RUnlock()
+	return calls
+}
+
+// SetActiveProjectID calls SetActiveProjectIDFunc.
+func (mock *ReqLogServiceMock) SetActiveProjectID(id ulid.ULID) {
+	if mock.SetActiveProjectIDFunc == nil {
+		panic("ReqLogServiceMock.SetActiveProjectIDFunc: method is nil but Service.SetActiveProjectID was just called")
+	}
+	callInfo := struct {
+		ID ulid.ULID
+	}{
+		ID: id,
+	}
+	mock.lockSetActiveProjectID.Lock()
+	mock.calls.SetActiveProjectID = append(mock.calls.SetActiveProjectID, callInfo)
+	mock.lockSetActiveProjectID.Unlock()
+	mock.SetActiveProjectIDFunc(id)
+}
+
+// SetActiveProjectIDCalls gets all the calls that were made to SetActiveProjectID.
+// Check the length with:
+//
+//	len(mockedService.SetActiveProjectIDCalls())
+func (mock *ReqLogServiceMock) SetActiveProjectIDCalls() []struct {
+	ID ulid.ULID
+} {
+	var calls []struct {
+		ID ulid.ULID
+	}
+	mock.lockSetActiveProjectID.RLock()
+	calls = mock.calls.SetActiveProjectID
+	mock.lockSetActiveProjectID.RUnlock()
+	return calls
+}
+
+// SetBodyRules calls SetBodyRulesFunc.
+func (mock *ReqLogServiceMock) SetBodyRules(rules reqlog.BodyRules) {
+	if mock.SetBodyRulesFunc == nil {
+		panic("ReqLogServiceMock.SetBodyRulesFunc: method is nil but Service.SetBodyRules was just called")
+	}
+	callInfo := struct {
+		Rules reqlog.BodyRules
+	}{
+		Rules: rules,
+	}
+	mock.lockSetBodyRules.Lock()
+	mock.calls.SetBodyRules = append(mock.calls.SetBodyRules, callInfo)
+	mock.lockSetBodyRules.Unlock()
+	mock.SetBodyRulesFunc(rules)
+}
+
+// SetBodyRulesCalls gets all the calls that were made to SetBodyRules.
+// Check the length with:
+//
+//	len(mockedService.SetBodyRulesCalls())
+func (mock *ReqLogServiceMock) SetBodyRulesCalls() []struct {
+	Rules reqlog.BodyRules
+} {
+	var calls []struct {
+		Rules reqlog.BodyRules
+	}
+	mock.lockSetBodyRules.RLock()
+	calls = mock.calls.SetBodyRules
+	mock.lockSetBodyRules.RUnlock()
+	return calls
+}
+
+// SetBodyView calls SetBodyViewFunc.
+func (mock *ReqLogServiceMock) SetBodyView(ctx context.Context, reqLogID ulid.ULID, view reqlog.BodyView) (reqlog.BodyView, error) {
+	if mock.SetBodyViewFunc == nil {
+		panic("ReqLogServiceMock.SetBodyViewFunc: method is nil but Service.SetBodyView was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		ReqLogID ulid.ULID
+		View     reqlog.BodyView
+	}{
+		Ctx:      ctx,
+		ReqLogID: reqLogID,
+		View:     view,
+	}
+	mock.lockSetBodyView.Lock()
+	mock.calls.SetBodyView = append(mock.calls.SetBodyView, callInfo)
+	mock.lockSetBodyView.Unlock()
+	return mock.SetBodyViewFunc(ctx, reqLogID, view)
+}
+
+// SetBodyViewCalls gets all the calls that were made to SetBodyView.
+// Check the length with:
+//
+//	len(mockedService.SetBodyViewCalls())
+func (mock *ReqLogServiceMock) SetBodyViewCalls() []struct {
+	Ctx      context.Context
+	ReqLogID ulid.ULID
+	View     reqlog.BodyView
+} {
+	var calls []struct {
+		Ctx      context.Context
+		ReqLogID ulid.ULID
+		View     reqlog.BodyView
+	}
+	mock.lockSetBodyView.RLock()
+	calls = mock.calls.SetBodyView
+	mock.lockSetBodyView.RUnlock()
+	return calls
+}
+
+// SetBypassOutOfScopeRequests calls SetBypassOutOfScopeRequestsFunc.
+func (mock *ReqLogServiceMock) SetBypassOutOfScopeRequests(b bool) {
+	if mock.SetBypassOutOfScopeRequestsFunc == nil {
+		panic("ReqLogServiceMock.SetBypassOutOfScopeRequestsFunc: method is nil but Service.SetBypassOutOfScopeRequests was just called")
+	}
+	callInfo := struct {
+		B bool
+	}{
+		B: b,
+	}
+	mock.lockSetBypassOutOfScopeRequests.Lock()
+	mock.calls.SetBypassOutOfScopeRequests = append(mock.calls.SetBypassOutOfScopeRequests, callInfo)
+	mock.lockSetBypassOutOfScopeRequests.Unlock()
+	mock.SetBypassOutOfScopeRequestsFunc(b)
+}
+
+// SetBypassOutOfScopeRequestsCalls gets all the calls that were made to SetBypassOutOfScopeRequests.
+// Check the length with:
+//
+//	len(mockedService.SetBypassOutOfScopeRequestsCalls())
+func (mock *ReqLogServiceMock) SetBypassOutOfScopeRequestsCalls() []struct {
+	B bool
+} {
+	var calls []struct {
+		B bool
+	}
+	mock.lockSetBypassOutOfScopeRequests.RLock()
+	calls = mock.calls.SetBypassOutOfScopeRequests
+	mock.lockSetBypassOutOfScopeRequests.RUnlock()
+	return calls
+}
+
+// SetCapturePaused calls SetCapturePausedFunc.
+func (mock *ReqLogServiceMock) SetCapturePaused(paused bool) {
+	if mock.SetCapturePausedFunc == nil {
+		panic("ReqLogServiceMock.SetCapturePausedFunc: method is nil but Service.SetCapturePaused was just called")
+	}
+	callInfo := struct {
+		Paused bool
+	}{
+		Paused: paused,
+	}
+	mock.lockSetCapturePaused.Lock()
+	mock.calls.SetCapturePaused = append(mock.calls.SetCapturePaused, callInfo)
+	mock.lockSetCapturePaused.Unlock()
+	mock.SetCapturePausedFunc(paused)
+}
+
+// SetCapturePausedCalls gets all the calls that were made to SetCapturePaused.
+// Check the length with:
+//
+//	len(mockedService.SetCapturePausedCalls())
+func (mock *ReqLogServiceMock) SetCapturePausedCalls() []struct {
+	Paused bool
+} {
+	var calls []struct {
+		Paused bool
+	}
+	mock.lockSetCapturePaused.RLock()
+	calls = mock.calls.SetCapturePaused
+	mock.lockSetCapturePaused.RUnlock()
+	return calls
+}
+
+// SetClientRoutes calls SetClientRoutesFunc.
+func (mock *ReqLogServiceMock) SetClientRoutes(routes []reqlog.ClientRoute) error {
+	if mock.SetClientRoutesFunc == nil {
+		panic("ReqLogServiceMock.SetClientRoutesFunc: method is nil but Service.SetClientRoutes was just called")
+	}
+	callInfo := struct {
+		Routes []reqlog.ClientRoute
+	}{
+		Routes: routes,
+	}
+	mock.lockSetClientRoutes.Lock()
+	mock.calls.SetClientRoutes = append(mock.calls.SetClientRoutes, callInfo)
+	mock.lockSetClientRoutes.Unlock()
+	return mock.SetClientRoutesFunc(routes)
+}
+
+// SetClientRoutesCalls gets all the calls that were made to SetClientRoutes.
+// Check the length with:
+//
+//	len(mockedService.SetClientRoutesCalls())
+func (mock *ReqLogServiceMock) SetClientRoutesCalls() []struct {
+	Routes []reqlog.ClientRoute
+} {
+	var calls []struct {
+		Routes []reqlog.ClientRoute
+	}
+	mock.lockSetClientRoutes.RLock()
+	calls = mock.calls.SetClientRoutes
+	mock.lockSetClientRoutes.RUnlock()
+	return calls
+}
+
+// SetColumns calls SetColumnsFunc.
+func (mock *ReqLogServiceMock) SetColumns(columns []reqlog.Column) error {
+	if mock.SetColumnsFunc == nil {
+		panic("ReqLogServiceMock.SetColumnsFunc: method is nil but Service.SetColumns was just called")
+	}
+	callInfo := struct {
+		Columns []reqlog.Column
+	}{
+		Columns: columns,
+	}
+	mock.lockSetColumns.Lock()
+	mock.calls.SetColumns = append(mock.calls.SetColumns, callInfo)
+	mock.lockSetColumns.Unlock()
+	return mock.SetColumnsFunc(columns)
+}
+
+// SetColumnsCalls gets all the calls that were made to SetColumns.
+// Check the length with:
+//
+//	len(mockedService.SetColumnsCalls())
+func (mock *ReqLogServiceMock) SetColumnsCalls() []struct {
+	Columns []reqlog.Column
+} {
+	var calls []struct {
+		Columns []reqlog.Column
+	}
+	mock.lockSetColumns.RLock()
+	calls = mock.calls.SetColumns
+	mock.lockSetColumns.RUnlock()
+	return calls
+}
+
+// SetFindReqsFilter calls SetFindReqsFilterFunc.
+func (mock *ReqLogServiceMock) SetFindReqsFilter(filter reqlog.FindRequestsFilter) {
+	if mock.SetFindReqsFilterFunc == nil {
+		panic("ReqLogServiceMock.SetFindReqsFilterFunc: method is nil but Service.SetFindReqsFilter was just called")
+	}
+	callInfo := struct {
+		Filter reqlog.FindRequestsFilter
+	}{
+		Filter: filter,
+	}
+	mock.lockSetFindReqsFilter.Lock()
+	mock.calls.SetFindReqsFilter = append(mock.calls.SetFindReqsFilter, callInfo)
+	mock.lockSetFindReqsFilter.Unlock()
+	mock.SetFindReqsFilterFunc(filter)
+}
+
+// SetFindReqsFilterCalls gets all the calls that were made to SetFindReqsFilter.
+// Check the length with:
+//
+//	len(mockedService.SetFindReqsFilterCalls())
+func (mock *ReqLogServiceMock) SetFindReqsFilterCalls() []struct {
+	Filter reqlog.FindRequestsFilter
+} {
+	var calls []struct {
+		Filter reqlog.FindRequestsFilter
+	}
+	mock.lockSetFindReqsFilter.RLock()
+	calls = mock.calls.SetFindReqsFilter
+	mock.lockSetFindReqsFilter.RUnlock()
+	return calls
+}
+
+// SetProjectCapturePaused calls SetProjectCapturePausedFunc.
+func (mock *ReqLogServiceMock) SetProjectCapturePaused(projectID ulid.ULID, paused bool) {
+	if mock.SetProjectCapturePausedFunc == nil {
+		panic("ReqLogServiceMock.SetProjectCapturePausedFunc: method is nil but Service.SetProjectCapturePaused was just called")
+	}
+	callInfo := struct {
+		ProjectID ulid.ULID
+		Paused    bool
+	}{
+		ProjectID: projectID,
+		Paused:    paused,
+	}
+	mock.lockSetProjectCapturePaused.Lock()
+	mock.calls.SetProjectCapturePaused = append(mock.calls.SetProjectCapturePaused, callInfo)
+	mock.lockSetProjectCapturePaused.Unlock()
+	mock.SetProjectCapturePausedFunc(projectID, paused)
+}
+
+// SetProjectCapturePausedCalls gets all the calls that were made to SetProjectCapturePaused.
+// Check the length with:
+//
+//	len(mockedService.SetProjectCapturePausedCalls())
+func (mock *ReqLogServiceMock) SetProjectCapturePausedCalls() []struct {
+	ProjectID ulid.ULID
+	Paused    bool
+} {
+	var calls []struct {
+		ProjectID ulid.ULID
+		Paused    bool
+	}
+	mock.lockSetProjectCapturePaused.RLock()
+	calls = mock.calls.SetProjectCapturePaused
+	mock.lockSetProjectCapturePaused.RUnlock()
+	return calls
+}
+
+// SetReadOnly calls SetReadOnlyFunc.
+func (mock *ReqLogServiceMock) SetReadOnly(readOnly bool) {
+	if mock.SetReadOnlyFunc == nil {
+		panic("ReqLogServiceMock.SetReadOnlyFunc: method is nil but Service.SetReadOnly was just called")
+	}
+	callInfo := struct {
+		ReadOnly bool
+	}{
+		ReadOnly: readOnly,
+	}
+	mock.lockSetReadOnly.Lock()
+	mock.calls.SetReadOnly = append(mock.calls.SetReadOnly, callInfo)
+	mock.lockSetReadOnly.Unlock()
+	mock.SetReadOnlyFunc(readOnly)
+}
+
+// SetReadOnlyCalls gets all the calls that were made to SetReadOnly.
+// Check the length with:
+//
+//	len(mockedService.SetReadOnlyCalls())
+func (mock *ReqLogServiceMock) SetReadOnlyCalls() []struct {
+	ReadOnly bool
+} {
+	var calls []struct {
+		ReadOnly bool
+	}
+	mock.lockSetReadOnly.RLock()
+	calls = mock.calls.SetReadOnly
+	mock.lockSetReadOnly.RUnlock()
+	return calls
+}
+
+// SetSampling calls SetSamplingFunc.
+func (mock *ReqLogServiceMock) SetSampling(sampling reqlog.Sampling) error {
+	if mock.SetSamplingFunc == nil {
+		panic("ReqLogServiceMock.SetSamplingFunc: method is nil but Service.SetSampling was just called")
+	}
+	callInfo := struct {
+		Sampling reqlog.Sampling
+	}{
+		Sampling: sampling,
+	}
+	mock.lockSetSampling.Lock()
+	mock.calls.SetSampling = append(mock.calls.SetSampling, callInfo)
+	mock.lockSetSampling.Unlock()
+	return mock.SetSamplingFunc(sampling)
+}
+
+// SetSamplingCalls gets all the calls that were made to SetSampling.
+// Check the length with:
+//
+//	len(mockedService.SetSamplingCalls())
+func (mock *ReqLogServiceMock) SetSamplingCalls() []struct {
+	Sampling reqlog.Sampling
+} {
+	var calls []struct {
+		Sampling reqlog.Sampling
+	}
+	mock.lockSetSampling.RLock()
+	calls = mock.calls.SetSampling
+	mock.lockSetSampling.RUnlock()
+	return calls
+}
+
+// StoreStats calls StoreStatsFunc.
+func (mock *ReqLogServiceMock) StoreStats() reqlog.StoreStats {
+	if mock.StoreStatsFunc == nil {
+		panic("ReqLogServiceMock.StoreStatsFunc: method is nil but Service.StoreStats was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockStoreStats.Lock()
+	mock.calls.StoreStats = append(mock.calls.StoreStats, callInfo)
+	mock.lockStoreStats.Unlock()
+	return mock.StoreStatsFunc()
+}
+
+// StoreStatsCalls gets all the calls that were made to StoreStats.
+// Check the length with:
+//
+//	len(mockedService.StoreStatsCalls())
+func (mock *ReqLogServiceMock) StoreStatsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockStoreStats.RLock()
+	calls = mock.calls.StoreStats
+	mock.lockStoreStats.RUnlock()
+	return calls
+}
+
+// TagRequests calls TagRequestsFunc.
+func (mock *ReqLogServiceMock) TagRequests(ctx context.Context, sel reqlog.Selection, add []string, remove []string) (int, error) {
+	if mock.TagRequestsFunc == nil {
+		panic("ReqLogServiceMock.TagRequestsFunc: method is nil but Service.TagRequests was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Sel    reqlog.Selection
+		Add    []string
+		Remove []string
+	}{
+		Ctx:    ctx,
+		Sel:    sel,
+		Add:    add,
+		Remove: remove,
+	}
+	mock.lockTagRequests.Lock()
+	mock.calls.TagRequests = append(mock.calls.TagRequests, callInfo)
+	mock.lockTagRequests.Unlock()
+	return mock.TagRequestsFunc(ctx, sel, add, remove)
+}
+
+// TagRequestsCalls gets all the calls that were made to TagRequests.
+// Check the length with:
+//
+//	len(mockedService.TagRequestsCalls())
+func (mock *ReqLogServiceMock) TagRequestsCalls() []struct {
+	Ctx    context.Context
+	Sel    reqlog.Selection
+	Add    []string
+	Remove []string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Sel    reqlog.Selection
+		Add    []string
+		Remove []string
+	}
+	mock.lockTagRequests.RLock()
+	calls = mock.calls.TagRequests
+	mock.lockTagRequests.RUnlock()
+	return calls
+}
@@ -0,0 +1,355 @@
+// Package intercept holds proxied requests that match a breakpoint, until they
+// are continued or dropped. Breakpoints are search expressions for request
+// logs, e.g. `req.method = POST AND req.url =~ "/admin"`, so that requests are
+// matched like request log filters are.
+package intercept
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/search"
+)
+
+// DefaultTimeout is the time that a request is held for, if `Config.Timeout`
+// isn't set.
+const DefaultTimeout = 5 * time.Minute
+
+// droppedMessage is the body of responses to dropped requests.
+const droppedMessage = "Dropped by Hetty: the request was intercepted and dropped.\n"
+
+var (
+	ErrInvalidBreakpoint = errcode.New(errcode.Invalid, "intercept: invalid breakpoint")
+	ErrRequestNotFound   = errcode.New(errcode.NotFound, "intercept: request not found")
+)
+
+type droppedKey struct{}
+
+// Breakpoint holds proxied requests that match its search expression.
+type Breakpoint struct {
+	// Search expression for request logs. Response fields (`res.`) aren't
+	// allowed, as requests are held before they're sent.
+	Expression string
+
+	expr search.Expression
+}
+
+// Request is a request that is held by a breakpoint.
+type Request struct {
+	// ID of the request log of the request, or a new ID if it isn't logged.
+	ID            ulid.ULID
+	Method        string
+	URL           *url.URL
+	Proto         string
+	Header        http.Header
+	Body          []byte
+	Breakpoint    string
+	InterceptedAt time.Time
+}
+
+type Service interface {
+	RequestModifier(next proxy.RequestModifyFunc) proxy.RequestModifyFunc
+	Responder(req *http.Request) *http.Response
+	SetBreakpoints(breakpoints []Breakpoint)
+	Breakpoints() []Breakpoint
+	FindRequests() []Request
+	ContinueRequest(id ulid.ULID) error
+	DropRequest(id ulid.ULID) error
+}
+
+type service struct {
+	// mu guards the breakpoints and held requests, which are changed at
+	// runtime.
+	mu          sync.RWMutex
+	breakpoints []Breakpoint
+	held        map[ulid.ULID]*heldRequest
+
+	timeout time.Duration
+	ids     idgen.Generator
+}
+
+type heldRequest struct {
+	req Request
+	// Receives true if the request is dropped, and false if it's continued.
+	drop chan bool
+}
+
+type Config struct {
+	// Time that a request is held for, after which it's continued, so that
+	// clients don't hang if nobody continues or drops it. Defaults to
+	// `DefaultTimeout`.
+	Timeout time.Duration
+	// Generates the IDs of requests that aren't logged. Defaults to
+	// `idgen.Default()`.
+	IDGenerator idgen.Generator
+}
+
+func NewService(cfg Config) Service {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	return &service{
+		held:    make(map[ulid.ULID]*heldRequest),
+		timeout: cfg.Timeout,
+		ids:     cfg.IDGenerator,
+	}
+}
+
+// ParseBreakpoint parses a breakpoint with search expression s, see
+// `reqlog.ParseSearchExpr`.
+func ParseBreakpoint(s string) (Breakpoint, error) {
+	expr, err := reqlog.ParseSearchExpr(s)
+	if err != nil {
+		return Breakpoint{}, fmt.Errorf("%w: %v", ErrInvalidBreakpoint, err)
+	}
+
+	if keys, _ := search.Keys(expr); hasResponseKey(keys) {
+		return Breakpoint{}, fmt.Errorf("%w: response fields can't be matched, as requests are held before "+
+			"they're sent", ErrInvalidBreakpoint)
+	}
+
+	return Breakpoint{Expression: s, expr: expr}, nil
+}
+
+func hasResponseKey(keys []string) bool {
+	for _, key := range keys {
+		if reqlog.IsResponseSearchKey(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequestModifier holds requests that match a breakpoint, until they're
+// continued or dropped, the timeout passes, or the client goes away. It's meant
+// to be registered before other request modifiers, so that it runs after them:
+// requests are matched as they're sent, and held requests have the ID of their
+// request log. Requests that Hetty sends itself aren't held.
+func (svc *service) RequestModifier(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+	return func(req *http.Request) {
+		next(req)
+
+		if internal, _ := req.Context().Value(proxy.InternalRequestKey).(bool); internal {
+			return
+		}
+
+		breakpoints := svc.Breakpoints()
+		if len(breakpoints) == 0 {
+			return
+		}
+
+		var body []byte
+
+		if req.Body != nil {
+			var err error
+
+			body, err = ioutil.ReadAll(req.Body)
+			if err != nil {
+				log.Printf("[ERROR] Could not read request body for breakpoints: %v", err)
+				return
+			}
+
+			req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+		}
+
+		reqLog := reqlog.RequestLog{
+			Method:     req.Method,
+			URL:        req.URL,
+			Proto:      req.Proto,
+			Header:     req.Header,
+			Body:       body,
+			ClientAddr: req.RemoteAddr,
+			Device:     reqlog.ParseDevice(req.Header.Get("User-Agent")),
+		}
+
+		reqLog.ID, _ = req.Context().Value(proxy.ReqLogIDKey).(ulid.ULID)
+		if reqLog.ID.Compare(ulid.ULID{}) == 0 {
+			reqLog.ID = svc.ids.New(time.Now())
+		}
+
+		breakpoint, ok := matchBreakpoint(breakpoints, reqLog)
+		if !ok {
+			return
+		}
+
+		held := &heldRequest{
+			req: Request{
+				ID:            reqLog.ID,
+				Method:        req.Method,
+				URL:           req.URL,
+				Proto:         req.Proto,
+				Header:        req.Header.Clone(),
+				Body:          body,
+				Breakpoint:    breakpoint.Expression,
+				InterceptedAt: time.Now(),
+			},
+			drop: make(chan bool, 1),
+		}
+
+		if svc.hold(req.Context(), held) {
+			ctx := context.WithValue(req.Context(), droppedKey{}, true)
+			*req = *req.WithContext(ctx)
+		}
+	}
+}
+
+func matchBreakpoint(breakpoints []Breakpoint, reqLog reqlog.RequestLog) (Breakpoint, bool) {
+	for _, breakpoint := range breakpoints {
+		match, err := reqLog.Matches(breakpoint.expr)
+		if err != nil {
+			log.Printf("[ERROR] Could not match breakpoint %q: %v", breakpoint.Expression, err)
+			continue
+		}
+
+		if match {
+			return breakpoint, true
+		}
+	}
+
+	return Breakpoint{}, false
+}
+
+// hold blocks until a held request is continued or dropped, the timeout
+// passes, or ctx is done. It returns true if the request is dropped.
+func (svc *service) hold(ctx context.Context, held *heldRequest) bool {
+	svc.mu.Lock()
+	svc.held[held.req.ID] = held
+	svc.mu.Unlock()
+
+	defer func() {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+
+		if svc.held[held.req.ID] == held {
+			delete(svc.held, held.req.ID)
+		}
+	}()
+
+	timer := time.NewTimer(svc.timeout)
+	defer timer.Stop()
+
+	select {
+	case drop := <-held.drop:
+		return drop
+	case <-timer.C:
+		log.Printf("[INFO] Continued intercepted request after %v (id: %v)", svc.timeout, held.req.ID)
+		return false
+	case <-ctx.Done():
+		return true
+	}
+}
+
+// Responder responds to dropped requests, so that they aren't sent upstream.
+// It's meant to be registered with `proxy.Proxy.UseResponder`, before other
+// responders.
+func (svc *service) Responder(req *http.Request) *http.Response {
+	if dropped, _ := req.Context().Value(droppedKey{}).(bool); !dropped {
+		return nil
+	}
+
+	header := make(http.Header)
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(droppedMessage)))
+
+	return &http.Response{
+		Status:        "502 Bad Gateway",
+		StatusCode:    http.StatusBadGateway,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(droppedMessage)),
+		ContentLength: int64(len(droppedMessage)),
+		Request:       req,
+	}
+}
+
+// SetBreakpoints replaces the breakpoints, which must be parsed with
+// `ParseBreakpoint`. Held requests are continued if no breakpoints are left.
+func (svc *service) SetBreakpoints(breakpoints []Breakpoint) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.breakpoints = breakpoints
+
+	if len(breakpoints) > 0 {
+		return
+	}
+
+	for id, held := range svc.held {
+		held.drop <- false
+		delete(svc.held, id)
+	}
+}
+
+func (svc *service) Breakpoints() []Breakpoint {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.breakpoints
+}
+
+// FindRequests returns the held requests, oldest first.
+func (svc *service) FindRequests() []Request {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	reqs := make([]Request, 0, len(svc.held))
+	for _, held := range svc.held {
+		reqs = append(reqs, held.req)
+	}
+
+	sort.Slice(reqs, func(i, j int) bool {
+		return reqs[i].InterceptedAt.Before(reqs[j].InterceptedAt)
+	})
+
+	return reqs
+}
+
+// ContinueRequest sends a held request upstream.
+func (svc *service) ContinueRequest(id ulid.ULID) error {
+	return svc.release(id, false)
+}
+
+// DropRequest responds to a held request with `502 Bad Gateway`, without
+// sending it upstream.
+func (svc *service) DropRequest(id ulid.ULID) error {
+	return svc.release(id, true)
+}
+
+func (svc *service) release(id ulid.ULID, drop bool) error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	held, ok := svc.held[id]
+	if !ok {
+		return ErrRequestNotFound
+	}
+
+	held.drop <- drop
+	delete(svc.held, id)
+
+	return nil
+}
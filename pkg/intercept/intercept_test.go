@@ -0,0 +1,340 @@
+package intercept_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/intercept"
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+//nolint:gosec
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+type testProxy struct {
+	proxy    *proxy.Proxy
+	client   *http.Client
+	upstream string
+	hits     int32
+}
+
+// newTestProxy returns a proxy with svc registered like Hetty does, and a
+// request modifier that sets request log ID reqLogID, in place of the request
+// log service.
+func newTestProxy(t *testing.T, svc intercept.Service, reqLogID ulid.ULID) *testProxy {
+	t.Helper()
+
+	caCert, caKey, err := proxy.NewCA("Hetty", "Hetty test CA", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := proxy.NewProxy(proxy.Config{CACert: caCert, CAKey: caKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tp := &testProxy{proxy: p}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tp.hits, 1)
+
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body) //nolint:errcheck
+	}))
+	t.Cleanup(upstream.Close)
+
+	tp.upstream = upstream.URL
+
+	p.UseRequestModifier(svc.RequestModifier, func(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+		return func(req *http.Request) {
+			next(req)
+
+			ctx := context.WithValue(req.Context(), proxy.ReqLogIDKey, reqLogID)
+			*req = *req.WithContext(ctx)
+		}
+	})
+	p.UseResponder(svc.Responder)
+
+	proxySrv := httptest.NewServer(p)
+	t.Cleanup(proxySrv.Close)
+
+	proxyURL, err := url.Parse(proxySrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tp.client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	return tp
+}
+
+type result struct {
+	statusCode int
+	body       string
+	err        error
+}
+
+// send sends a request via the proxy in the background.
+func (tp *testProxy) send(method, body string) <-chan result {
+	results := make(chan result, 1)
+
+	go func() {
+		req, err := http.NewRequest(method, tp.upstream+"/admin", strings.NewReader(body))
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+
+		res, err := tp.client.Do(req)
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+
+		resBody, err := ioutil.ReadAll(res.Body)
+		results <- result{statusCode: res.StatusCode, body: string(resBody), err: err}
+	}()
+
+	return results
+}
+
+func waitForRequests(t *testing.T, svc intercept.Service, n int) []intercept.Request {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		if reqs := svc.FindRequests(); len(reqs) == n {
+			return reqs
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("expected %v held requests, got: %v", n, len(svc.FindRequests()))
+
+	return nil
+}
+
+func receive(t *testing.T, results <-chan result) result {
+	t.Helper()
+
+	select {
+	case res := <-results:
+		if res.err != nil {
+			t.Fatalf("unexpected error: %v", res.err)
+		}
+
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for response")
+	}
+
+	return result{}
+}
+
+func mustParseBreakpoints(t *testing.T, exprs ...string) []intercept.Breakpoint {
+	t.Helper()
+
+	breakpoints := make([]intercept.Breakpoint, len(exprs))
+
+	for i, expr := range exprs {
+		breakpoint, err := intercept.ParseBreakpoint(expr)
+		if err != nil {
+			t.Fatalf("failed to parse breakpoint: %v", err)
+		}
+
+		breakpoints[i] = breakpoint
+	}
+
+	return breakpoints
+}
+
+func TestParseBreakpoint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		input  string
+		expErr bool
+	}{
+		{name: "request fields", input: `req.method = POST AND req.body =~ "secret"`},
+		{name: "string literal", input: "admin"},
+		{name: "syntax error", input: "req.method =", expErr: true},
+		{name: "unknown key", input: "req.methd = POST", expErr: true},
+		{name: "response field", input: "res.statusCode = 200", expErr: true},
+		{name: "response header", input: "res.header.Server = nginx", expErr: true},
+	}
+
+	for _, tt := range tests {
+		breakpoint, err := intercept.ParseBreakpoint(tt.input)
+
+		if tt.expErr {
+			if !errors.Is(err, intercept.ErrInvalidBreakpoint) {
+				t.Errorf("%v: expected `intercept.ErrInvalidBreakpoint`, got: %v", tt.name, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.name, err)
+			continue
+		}
+
+		if breakpoint.Expression != tt.input {
+			t.Errorf("%v: expected expression %q, got: %q", tt.name, tt.input, breakpoint.Expression)
+		}
+	}
+}
+
+func TestRequestModifier(t *testing.T) {
+	t.Parallel()
+
+	t.Run("continued request is sent", func(t *testing.T) {
+		t.Parallel()
+
+		reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+		svc := intercept.NewService(intercept.Config{})
+		svc.SetBreakpoints(mustParseBreakpoints(t, "req.method = GET", `req.body =~ "secret"`))
+		tp := newTestProxy(t, svc, reqLogID)
+
+		results := tp.send(http.MethodPost, "secret=foo")
+
+		reqs := waitForRequests(t, svc, 1)
+		if got := reqs[0]; got.ID != reqLogID || got.Method != http.MethodPost ||
+			string(got.Body) != "secret=foo" || got.Breakpoint != `req.body =~ "secret"` {
+			t.Fatalf("unexpected held request: %+v", got)
+		}
+
+		if hits := atomic.LoadInt32(&tp.hits); hits != 0 {
+			t.Fatalf("expected held request not to be sent, got: %v upstream requests", hits)
+		}
+
+		if err := svc.ContinueRequest(reqLogID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The body of the held request is sent as is.
+		if res := receive(t, results); res.statusCode != http.StatusOK || res.body != "secret=foo" {
+			t.Fatalf("expected upstream response, got: %v (%q)", res.statusCode, res.body)
+		}
+
+		if reqs := svc.FindRequests(); len(reqs) != 0 {
+			t.Errorf("expected no held requests, got: %+v", reqs)
+		}
+
+		if err := svc.ContinueRequest(reqLogID); !errors.Is(err, intercept.ErrRequestNotFound) {
+			t.Errorf("expected `intercept.ErrRequestNotFound`, got: %v", err)
+		}
+	})
+
+	t.Run("dropped request isn't sent", func(t *testing.T) {
+		t.Parallel()
+
+		reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+		svc := intercept.NewService(intercept.Config{})
+		svc.SetBreakpoints(mustParseBreakpoints(t, `req.url =~ "/admin"`))
+		tp := newTestProxy(t, svc, reqLogID)
+
+		results := tp.send(http.MethodGet, "")
+
+		waitForRequests(t, svc, 1)
+
+		if err := svc.DropRequest(reqLogID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		res := receive(t, results)
+		if res.statusCode != http.StatusBadGateway || !strings.HasPrefix(res.body, "Dropped by Hetty") {
+			t.Fatalf("expected dropped response, got: %v (%q)", res.statusCode, res.body)
+		}
+
+		if hits := atomic.LoadInt32(&tp.hits); hits != 0 {
+			t.Errorf("expected dropped request not to be sent, got: %v upstream requests", hits)
+		}
+	})
+
+	t.Run("unmatched request isn't held", func(t *testing.T) {
+		t.Parallel()
+
+		svc := intercept.NewService(intercept.Config{})
+		svc.SetBreakpoints(mustParseBreakpoints(t, "req.method = DELETE"))
+		tp := newTestProxy(t, svc, ulid.ULID{})
+
+		if res := receive(t, tp.send(http.MethodGet, "")); res.statusCode != http.StatusOK {
+			t.Fatalf("expected upstream response, got: %v", res.statusCode)
+		}
+	})
+
+	t.Run("internal request isn't held", func(t *testing.T) {
+		t.Parallel()
+
+		svc := intercept.NewService(intercept.Config{})
+		svc.SetBreakpoints(mustParseBreakpoints(t, "req.method = GET"))
+		tp := newTestProxy(t, svc, ulid.ULID{})
+
+		req := httptest.NewRequest(http.MethodGet, tp.upstream+"/admin", nil)
+		req.RequestURI = ""
+
+		res, err := tp.proxy.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("expected upstream response, got: %v", res.StatusCode)
+		}
+	})
+
+	t.Run("request is continued after timeout", func(t *testing.T) {
+		t.Parallel()
+
+		svc := intercept.NewService(intercept.Config{Timeout: 50 * time.Millisecond})
+		svc.SetBreakpoints(mustParseBreakpoints(t, "req.method = GET"))
+		tp := newTestProxy(t, svc, ulid.ULID{})
+
+		if res := receive(t, tp.send(http.MethodGet, "")); res.statusCode != http.StatusOK {
+			t.Fatalf("expected upstream response, got: %v", res.statusCode)
+		}
+
+		if hits := atomic.LoadInt32(&tp.hits); hits != 1 {
+			t.Errorf("expected 1 upstream request, got: %v", hits)
+		}
+	})
+
+	t.Run("removing breakpoints continues held requests", func(t *testing.T) {
+		t.Parallel()
+
+		svc := intercept.NewService(intercept.Config{})
+		svc.SetBreakpoints(mustParseBreakpoints(t, "req.method = GET"))
+		tp := newTestProxy(t, svc, ulid.ULID{})
+
+		results := tp.send(http.MethodGet, "")
+
+		// Requests that aren't logged get a new ID.
+		if reqs := waitForRequests(t, svc, 1); reqs[0].ID.Compare(ulid.ULID{}) == 0 {
+			t.Fatalf("expected held request to have an ID, got: %+v", reqs[0])
+		}
+
+		svc.SetBreakpoints(nil)
+
+		if res := receive(t, results); res.statusCode != http.StatusOK {
+			t.Fatalf("expected upstream response, got: %v", res.statusCode)
+		}
+	})
+}
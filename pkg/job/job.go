@@ -0,0 +1,658 @@
+// Package job runs long-running work of modules, e.g. crawls, scans and
+// exports, as background jobs. Jobs are queued until one of a limited number of
+// slots is available, report their progress, and can be paused, resumed and
+// cancelled. Jobs of the active project are stored, so that past jobs can be
+// reviewed.
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/idgen"
+)
+
+var (
+	ErrJobNotFound        = errcode.New(errcode.NotFound, "job: job not found")
+	ErrInvalidJobState    = errcode.New(errcode.Invalid, "job: invalid job state")
+	ErrProjectIDMustBeSet = errors.New("job: project ID must be set")
+)
+
+// DefaultMaxConcurrent is the default number of jobs that run at once.
+const DefaultMaxConcurrent = 4
+
+// maxFinishedJobs is the number of finished jobs that aren't stored, e.g. jobs
+// that were started without an active project, that are kept in memory.
+// Finished jobs that are stored are only kept in the repository.
+const maxFinishedJobs = 100
+
+// progressStoreInterval is the minimum interval at which the progress of a
+// running job is stored. Status changes are always stored.
+const progressStoreInterval = time.Second
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusPaused    Status = "paused"
+	StatusFinished  Status = "finished"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+	// The job was queued, running or paused when Hetty was stopped. This status
+	// is never stored; it's reported for stored jobs that are no longer active.
+	StatusInterrupted Status = "interrupted"
+)
+
+// Done returns true if a job with status s no longer runs.
+func (s Status) Done() bool {
+	switch s {
+	case StatusFinished, StatusFailed, StatusCancelled, StatusInterrupted:
+		return true
+	default:
+		return false
+	}
+}
+
+type Job struct {
+	ID        ulid.ULID
+	ProjectID ulid.ULID
+	// Kind of work, e.g. `crawl` or `activeScan`.
+	Type        string
+	Description string
+	Status      Status
+	// Units of work that are completed, out of Total. Total is 0 if the amount
+	// of work isn't known (yet).
+	Completed int
+	Total     int
+	// Error of a failed job.
+	Error      string
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Progress returns the completed work of the job as a percentage. Finished jobs
+// are complete, even if they did less work than their total, e.g. a crawl that
+// found fewer links than its maximum number of requests.
+func (j Job) Progress() float64 {
+	if j.Status == StatusFinished {
+		return 100
+	}
+
+	if j.Total <= 0 {
+		return 0
+	}
+
+	if j.Completed >= j.Total {
+		return 100
+	}
+
+	return float64(j.Completed) * 100 / float64(j.Total)
+}
+
+type Params struct {
+	// ID of the job, e.g. the ID of the crawl it runs, so that the job can be
+	// found by it. Defaults to a new ID.
+	ID          ulid.ULID
+	Type        string
+	Description string
+	// Total units of work, if known upfront. See `Progress.SetTotal`.
+	Total int
+}
+
+// Func is the work of a job. It should return when ctx is done, and call
+// `Progress.Wait` between units of work, so that the job can be paused. The
+// returned error determines the final status of the job: nil means finished,
+// and `context.Canceled` means cancelled.
+type Func func(ctx context.Context, progress *Progress) error
+
+type FindJobsFilter struct {
+	ProjectID ulid.ULID
+}
+
+type Service interface {
+	// StartJob queues a job that runs fn. The context of the job is derived
+	// from ctx, so it should outlive the caller, e.g. a module's background
+	// context rather than the context of an API request.
+	StartJob(ctx context.Context, params Params, fn Func) Job
+	// RunJob runs fn as a job, like StartJob, and waits until the job is done,
+	// e.g. for exports whose result is returned to the caller. It returns the
+	// error of fn, or of ctx if the job was cancelled before it started.
+	RunJob(ctx context.Context, params Params, fn Func) (Job, error)
+	FindJobByID(ctx context.Context, id ulid.ULID) (Job, error)
+	FindJobs(ctx context.Context, filter FindJobsFilter) ([]Job, error)
+	ActiveJobs() []Job
+	PauseJob(ctx context.Context, id ulid.ULID) (Job, error)
+	ResumeJob(ctx context.Context, id ulid.ULID) (Job, error)
+	CancelJob(ctx context.Context, id ulid.ULID) (Job, error)
+	SetActiveProjectID(id ulid.ULID)
+	SetReadOnly(readOnly bool)
+}
+
+type Config struct {
+	// Stores jobs of the active project. Optional; jobs aren't stored without
+	// it.
+	Repository Repository
+	// Maximum number of jobs that run at once; other jobs are queued. Paused
+	// jobs keep their slot. Defaults to `DefaultMaxConcurrent`.
+	MaxConcurrent int
+	// Generates the IDs of jobs. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+}
+
+type service struct {
+	// mu guards the active project settings and jobs, which are changed at
+	// runtime.
+	mu              sync.RWMutex
+	activeProjectID ulid.ULID
+	readOnly        bool
+	// Jobs that were started by this process, until they're done and stored.
+	// Finished jobs that aren't stored are kept, up to `maxFinishedJobs`.
+	jobs     map[ulid.ULID]*jobState
+	finished []ulid.ULID
+	// Queued jobs, in order, and the number of jobs that hold a slot.
+	queue         []*jobState
+	running       int
+	maxConcurrent int
+
+	repo Repository
+	ids  idgen.Generator
+}
+
+type jobState struct {
+	// mu guards all fields below, except storeMu.
+	mu  sync.Mutex
+	job Job
+	// Closed when the job gets a slot.
+	start chan struct{}
+	// Closed when a paused job is resumed; nil if the job isn't paused.
+	resume chan struct{}
+	// Closed when the job is done, after err is set.
+	done       chan struct{}
+	err        error
+	cancel     context.CancelFunc
+	cancelled  bool
+	persist    bool
+	lastStored time.Time
+
+	// storeMu orders stores of the job, so that an older snapshot can't
+	// overwrite a newer one.
+	storeMu sync.Mutex
+}
+
+func (st *jobState) snapshot() Job {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.job
+}
+
+func NewService(cfg Config) Service {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = DefaultMaxConcurrent
+	}
+
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	return &service{
+		jobs:          make(map[ulid.ULID]*jobState),
+		maxConcurrent: cfg.MaxConcurrent,
+		repo:          cfg.Repository,
+		ids:           cfg.IDGenerator,
+	}
+}
+
+func (svc *service) StartJob(ctx context.Context, params Params, fn Func) Job {
+	return svc.startJob(ctx, params, fn).snapshot()
+}
+
+func (svc *service) RunJob(ctx context.Context, params Params, fn Func) (Job, error) {
+	st := svc.startJob(ctx, params, fn)
+	<-st.done
+
+	return st.snapshot(), st.err
+}
+
+func (svc *service) startJob(ctx context.Context, params Params, fn Func) *jobState {
+	now := time.Now()
+	jobCtx, cancel := context.WithCancel(ctx)
+
+	if params.ID.Compare(ulid.ULID{}) == 0 {
+		params.ID = svc.ids.New(now)
+	}
+
+	svc.mu.Lock()
+
+	st := &jobState{
+		job: Job{
+			ID:          params.ID,
+			ProjectID:   svc.activeProjectID,
+			Type:        params.Type,
+			Description: params.Description,
+			Status:      StatusQueued,
+			Total:       params.Total,
+			CreatedAt:   now,
+		},
+		start:   make(chan struct{}),
+		done:    make(chan struct{}),
+		cancel:  cancel,
+		persist: svc.repo != nil && svc.activeProjectID.Compare(ulid.ULID{}) != 0 && !svc.readOnly,
+	}
+	svc.jobs[st.job.ID] = st
+	svc.queue = append(svc.queue, st)
+	svc.dispatch()
+
+	svc.mu.Unlock()
+
+	svc.store(st)
+
+	go svc.run(jobCtx, st, fn)
+
+	return st
+}
+
+func (svc *service) run(ctx context.Context, st *jobState, fn Func) {
+	defer st.cancel()
+
+	select {
+	case <-st.start:
+	case <-ctx.Done():
+	}
+
+	// A job that is cancelled before it starts doesn't run, even if it got a
+	// slot.
+	if ctx.Err() != nil {
+		if !svc.dequeue(st) {
+			// The job got a slot in the meantime.
+			svc.release()
+		}
+
+		svc.finish(st, ctx.Err())
+
+		return
+	}
+
+	defer svc.release()
+
+	st.mu.Lock()
+	st.job.Status = StatusRunning
+	st.job.StartedAt = time.Now()
+	st.mu.Unlock()
+
+	svc.store(st)
+
+	err := fn(ctx, &Progress{svc: svc, state: st})
+
+	svc.finish(st, err)
+}
+
+// dispatch starts queued jobs in order, while slots are available. The caller
+// must hold svc.mu.
+func (svc *service) dispatch() {
+	for svc.running < svc.maxConcurrent && len(svc.queue) > 0 {
+		st := svc.queue[0]
+		svc.queue = svc.queue[1:]
+		svc.running++
+		close(st.start)
+	}
+}
+
+// dequeue removes a queued job without starting it. It returns false if the job
+// wasn't queued.
+func (svc *service) dequeue(st *jobState) bool {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	for i := range svc.queue {
+		if svc.queue[i] == st {
+			svc.queue = append(svc.queue[:i], svc.queue[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// release frees the slot of a job, for the next queued job.
+func (svc *service) release() {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.running--
+	svc.dispatch()
+}
+
+func (svc *service) finish(st *jobState, err error) {
+	st.mu.Lock()
+
+	switch {
+	case st.cancelled || errors.Is(err, context.Canceled):
+		st.job.Status = StatusCancelled
+	case err != nil:
+		st.job.Status = StatusFailed
+		st.job.Error = err.Error()
+	default:
+		st.job.Status = StatusFinished
+	}
+
+	if st.resume != nil {
+		close(st.resume)
+		st.resume = nil
+	}
+
+	st.job.FinishedAt = time.Now()
+	st.err = err
+	st.mu.Unlock()
+
+	svc.store(st)
+	svc.prune(st)
+
+	close(st.done)
+}
+
+// prune removes a done job from the jobs of this process. Stored jobs are found
+// in the repository from now on; other jobs are kept, up to `maxFinishedJobs`.
+func (svc *service) prune(st *jobState) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if st.persist {
+		delete(svc.jobs, st.job.ID)
+		return
+	}
+
+	svc.finished = append(svc.finished, st.job.ID)
+
+	for len(svc.finished) > maxFinishedJobs {
+		delete(svc.jobs, svc.finished[0])
+		svc.finished = svc.finished[1:]
+	}
+}
+
+func (svc *service) store(st *jobState) {
+	st.storeMu.Lock()
+	defer st.storeMu.Unlock()
+
+	st.mu.Lock()
+	job, persist := st.job, st.persist
+	st.lastStored = time.Now()
+	st.mu.Unlock()
+
+	if !persist {
+		return
+	}
+
+	if err := svc.repo.StoreJob(context.Background(), job); err != nil {
+		log.Printf("[ERROR] Could not store job (id: %v): %v", job.ID, err)
+	}
+}
+
+func (svc *service) FindJobByID(ctx context.Context, id ulid.ULID) (Job, error) {
+	if st, ok := svc.jobState(id); ok {
+		return st.snapshot(), nil
+	}
+
+	jobs, err := svc.FindJobs(ctx, FindJobsFilter{})
+	if err != nil {
+		return Job{}, err
+	}
+
+	for _, job := range jobs {
+		if job.ID.Compare(id) == 0 {
+			return job, nil
+		}
+	}
+
+	return Job{}, ErrJobNotFound
+}
+
+// FindJobs returns the jobs of a project (the active project by default),
+// newest first. Jobs that were started without an active project, and thus
+// aren't stored, are returned if no project is active.
+func (svc *service) FindJobs(ctx context.Context, filter FindJobsFilter) ([]Job, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		filter.ProjectID = svc.ActiveProjectID()
+	}
+
+	byID := make(map[ulid.ULID]Job)
+
+	// Jobs of this process are read first: a job that is pruned in the
+	// meantime has been stored when it was done, and is found below.
+	svc.mu.RLock()
+
+	for id, st := range svc.jobs {
+		if job := st.snapshot(); job.ProjectID.Compare(filter.ProjectID) == 0 {
+			byID[id] = job
+		}
+	}
+
+	svc.mu.RUnlock()
+
+	if filter.ProjectID.Compare(ulid.ULID{}) != 0 && svc.repo != nil {
+		stored, err := svc.repo.FindJobs(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("job: failed to find jobs: %w", err)
+		}
+
+		for _, job := range stored {
+			if _, ok := byID[job.ID]; ok {
+				continue
+			}
+
+			if !job.Status.Done() {
+				job.Status = StatusInterrupted
+			}
+
+			byID[job.ID] = job
+		}
+	}
+
+	jobs := make([]Job, 0, len(byID))
+	for _, job := range byID {
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID.Compare(jobs[j].ID) > 0
+	})
+
+	return jobs, nil
+}
+
+// ActiveJobs returns the queued, running and paused jobs of this process, of
+// all projects, newest first.
+func (svc *service) ActiveJobs() []Job {
+	svc.mu.RLock()
+
+	jobs := make([]Job, 0, len(svc.jobs))
+	for _, st := range svc.jobs {
+		if job := st.snapshot(); !job.Status.Done() {
+			jobs = append(jobs, job)
+		}
+	}
+
+	svc.mu.RUnlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID.Compare(jobs[j].ID) > 0
+	})
+
+	return jobs
+}
+
+// PauseJob pauses a running job, at the next call of `Progress.Wait` by the
+// job.
+func (svc *service) PauseJob(ctx context.Context, id ulid.ULID) (Job, error) {
+	return svc.update(ctx, id, func(st *jobState) error {
+		if st.job.Status != StatusRunning {
+			return fmt.Errorf("%w: can't pause %v job", ErrInvalidJobState, st.job.Status)
+		}
+
+		st.job.Status = StatusPaused
+		st.resume = make(chan struct{})
+
+		return nil
+	})
+}
+
+func (svc *service) ResumeJob(ctx context.Context, id ulid.ULID) (Job, error) {
+	return svc.update(ctx, id, func(st *jobState) error {
+		if st.job.Status != StatusPaused {
+			return fmt.Errorf("%w: can't resume %v job", ErrInvalidJobState, st.job.Status)
+		}
+
+		st.job.Status = StatusRunning
+		close(st.resume)
+		st.resume = nil
+
+		return nil
+	})
+}
+
+// CancelJob cancels a queued, running or paused job. The job is cancelled
+// once its func returns.
+func (svc *service) CancelJob(ctx context.Context, id ulid.ULID) (Job, error) {
+	return svc.update(ctx, id, func(st *jobState) error {
+		if st.job.Status.Done() {
+			return fmt.Errorf("%w: can't cancel %v job", ErrInvalidJobState, st.job.Status)
+		}
+
+		st.cancelled = true
+		st.cancel()
+
+		// Let a paused job return.
+		if st.resume != nil {
+			close(st.resume)
+			st.resume = nil
+		}
+
+		return nil
+	})
+}
+
+// update applies fn to an active job of this process, and stores the job.
+func (svc *service) update(ctx context.Context, id ulid.ULID, fn func(st *jobState) error) (Job, error) {
+	st, ok := svc.jobState(id)
+	if !ok {
+		job, err := svc.FindJobByID(ctx, id)
+		if err != nil {
+			return Job{}, err
+		}
+
+		return Job{}, fmt.Errorf("%w: job is %v", ErrInvalidJobState, job.Status)
+	}
+
+	st.mu.Lock()
+	err := fn(st)
+	st.mu.Unlock()
+
+	if err != nil {
+		return Job{}, err
+	}
+
+	svc.store(st)
+
+	return st.snapshot(), nil
+}
+
+func (svc *service) jobState(id ulid.ULID) (*jobState, bool) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	st, ok := svc.jobs[id]
+
+	return st, ok
+}
+
+func (svc *service) SetActiveProjectID(id ulid.ULID) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.activeProjectID = id
+}
+
+func (svc *service) ActiveProjectID() ulid.ULID {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.activeProjectID
+}
+
+// SetReadOnly sets whether the active project is opened read-only. Jobs that
+// are started for it aren't stored.
+func (svc *service) SetReadOnly(readOnly bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.readOnly = readOnly
+}
+
+// Progress reports the progress of a job, and pauses it. A nil *Progress does
+// nothing, so that work can run with or without a job.
+type Progress struct {
+	svc   *service
+	state *jobState
+}
+
+// SetTotal sets the total units of work of the job.
+func (p *Progress) SetTotal(total int) {
+	p.update(func(job *Job) { job.Total = total })
+}
+
+// SetCompleted sets the completed units of work of the job.
+func (p *Progress) SetCompleted(completed int) {
+	p.update(func(job *Job) { job.Completed = completed })
+}
+
+// Add adds n completed units of work.
+func (p *Progress) Add(n int) {
+	p.update(func(job *Job) { job.Completed += n })
+}
+
+func (p *Progress) update(fn func(job *Job)) {
+	if p == nil {
+		return
+	}
+
+	p.state.mu.Lock()
+	fn(&p.state.job)
+	due := time.Since(p.state.lastStored) >= progressStoreInterval
+	p.state.mu.Unlock()
+
+	if due {
+		p.svc.store(p.state)
+	}
+}
+
+// Wait blocks while the job is paused. It returns the error of ctx if it's
+// done.
+func (p *Progress) Wait(ctx context.Context) error {
+	for p != nil {
+		p.state.mu.Lock()
+		resume := p.state.resume
+		p.state.mu.Unlock()
+
+		if resume == nil {
+			break
+		}
+
+		select {
+		case <-resume:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,282 @@
+package job_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/job"
+)
+
+var projectID = ulid.MustParse("01FN5F8C3T8AQJN1CZ3VMD5QTX")
+
+func waitForStatus(t *testing.T, svc job.Service, id ulid.ULID, status job.Status) job.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		j, err := svc.FindJobByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if j.Status == status {
+			return j
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected job status %v, got: %v", status, j.Status)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	database := memory.OpenDatabase()
+	svc := job.NewService(job.Config{Repository: database, MaxConcurrent: 1})
+	svc.SetActiveProjectID(projectID)
+
+	release := make(chan struct{})
+
+	first := svc.StartJob(context.Background(), job.Params{Type: "test", Total: 2}, func(ctx context.Context, p *job.Progress) error {
+		p.Add(1)
+		<-release
+		p.Add(1)
+
+		return nil
+	})
+
+	second := svc.StartJob(context.Background(), job.Params{Type: "test"}, func(ctx context.Context, p *job.Progress) error {
+		return errors.New("boom")
+	})
+
+	waitForStatus(t, svc, first.ID, job.StatusRunning)
+
+	// The second job waits for the slot of the first.
+	time.Sleep(20 * time.Millisecond)
+
+	if j := waitForStatus(t, svc, second.ID, job.StatusQueued); !j.StartedAt.IsZero() {
+		t.Fatalf("expected queued job not to be started, got: %v", j.StartedAt)
+	}
+
+	close(release)
+
+	j := waitForStatus(t, svc, first.ID, job.StatusFinished)
+	if j.Completed != 2 || j.Progress() != 100 {
+		t.Fatalf("expected job to be completed, got: %v/%v (%v%%)", j.Completed, j.Total, j.Progress())
+	}
+
+	j = waitForStatus(t, svc, second.ID, job.StatusFailed)
+	if j.Error != "boom" {
+		t.Fatalf("expected error of failed job, got: %q", j.Error)
+	}
+
+	// Finished jobs are stored.
+	stored, err := database.FindJobs(context.Background(), job.FindJobsFilter{ProjectID: projectID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	statuses := make(map[ulid.ULID]job.Status)
+	for _, j := range stored {
+		statuses[j.ID] = j.Status
+	}
+
+	if len(stored) != 2 || statuses[first.ID] != job.StatusFinished || statuses[second.ID] != job.StatusFailed {
+		t.Fatalf("expected stored jobs to be finished and failed, got: %+v", stored)
+	}
+}
+
+func TestPauseResumeCancel(t *testing.T) {
+	t.Parallel()
+
+	svc := job.NewService(job.Config{Repository: memory.OpenDatabase()})
+	svc.SetActiveProjectID(projectID)
+	ctx := context.Background()
+
+	started := svc.StartJob(ctx, job.Params{Type: "test"}, func(ctx context.Context, p *job.Progress) error {
+		for {
+			if err := p.Wait(ctx); err != nil {
+				return err
+			}
+
+			p.Add(1)
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	waitForStatus(t, svc, started.ID, job.StatusRunning)
+
+	if _, err := svc.ResumeJob(ctx, started.ID); !errors.Is(err, job.ErrInvalidJobState) {
+		t.Fatalf("expected invalid job state error resuming running job, got: %v", err)
+	}
+
+	if _, err := svc.PauseJob(ctx, started.ID); err != nil {
+		t.Fatalf("unexpected error pausing job: %v", err)
+	}
+
+	// Wait for the job to reach `Progress.Wait`.
+	time.Sleep(20 * time.Millisecond)
+
+	paused := waitForStatus(t, svc, started.ID, job.StatusPaused)
+	time.Sleep(20 * time.Millisecond)
+
+	if j := waitForStatus(t, svc, started.ID, job.StatusPaused); j.Completed != paused.Completed {
+		t.Fatalf("expected no progress while paused, got: %v (was %v)", j.Completed, paused.Completed)
+	}
+
+	if _, err := svc.ResumeJob(ctx, started.ID); err != nil {
+		t.Fatalf("unexpected error resuming job: %v", err)
+	}
+
+	waitForStatus(t, svc, started.ID, job.StatusRunning)
+
+	if active := svc.ActiveJobs(); len(active) != 1 || active[0].ID != started.ID {
+		t.Fatalf("expected running job to be active, got: %+v", active)
+	}
+
+	if _, err := svc.CancelJob(ctx, started.ID); err != nil {
+		t.Fatalf("unexpected error cancelling job: %v", err)
+	}
+
+	waitForStatus(t, svc, started.ID, job.StatusCancelled)
+
+	if active := svc.ActiveJobs(); len(active) != 0 {
+		t.Fatalf("expected no active jobs, got: %+v", active)
+	}
+
+	if _, err := svc.CancelJob(ctx, started.ID); !errors.Is(err, job.ErrInvalidJobState) {
+		t.Fatalf("expected invalid job state error cancelling cancelled job, got: %v", err)
+	}
+
+	if _, err := svc.PauseJob(ctx, ulid.ULID{}); !errors.Is(err, job.ErrJobNotFound) {
+		t.Fatalf("expected job not found error, got: %v", err)
+	}
+}
+
+func TestFindJobsInterrupted(t *testing.T) {
+	t.Parallel()
+
+	database := memory.OpenDatabase()
+	id := ulid.MustParse("01FN5F8C3T8AQJN1CZ3VMD5QTY")
+
+	// A job of a previous process, that didn't finish.
+	err := database.StoreJob(context.Background(), job.Job{ID: id, ProjectID: projectID, Status: job.StatusRunning})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	svc := job.NewService(job.Config{Repository: database})
+	svc.SetActiveProjectID(projectID)
+
+	jobs, err := svc.FindJobs(context.Background(), job.FindJobsFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(jobs) != 1 || jobs[0].Status != job.StatusInterrupted {
+		t.Fatalf("expected interrupted job, got: %+v", jobs)
+	}
+
+	if _, err := svc.CancelJob(context.Background(), id); !errors.Is(err, job.ErrInvalidJobState) {
+		t.Fatalf("expected invalid job state error cancelling interrupted job, got: %v", err)
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	t.Parallel()
+
+	svc := job.NewService(job.Config{})
+	id := ulid.MustParse("01FN5F8C3T8AQJN1CZ3VMD5QTZ")
+
+	j, err := svc.RunJob(context.Background(), job.Params{ID: id, Type: "test"}, func(ctx context.Context, p *job.Progress) error {
+		p.SetTotal(1)
+		p.Add(1)
+
+		return errors.New("boom")
+	})
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("expected error of job func, got: %v", err)
+	}
+
+	if j.ID != id || j.Status != job.StatusFailed || j.Completed != 1 {
+		t.Fatalf("expected failed job with ID %v, got: %+v", id, j)
+	}
+
+	// A job that is cancelled while queued is done as well.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := svc.RunJob(ctx, job.Params{Type: "test"}, func(ctx context.Context, p *job.Progress) error {
+		return nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context cancelled error, got: %v", err)
+	}
+}
+
+func TestPruneFinishedJobs(t *testing.T) {
+	t.Parallel()
+
+	database := memory.OpenDatabase()
+	svc := job.NewService(job.Config{Repository: database})
+	svc.SetActiveProjectID(projectID)
+	ctx := context.Background()
+
+	noop := func(ctx context.Context, p *job.Progress) error { return nil }
+
+	stored, err := svc.RunJob(ctx, job.Params{Type: "test"}, noop)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Finished jobs that are stored are found in the repository, instead of
+	// being kept in memory.
+	if err := database.ClearJobs(ctx, projectID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := svc.FindJobByID(ctx, stored.ID); !errors.Is(err, job.ErrJobNotFound) {
+		t.Fatalf("expected job not found error for pruned job, got: %v", err)
+	}
+
+	// Other jobs are kept, up to a maximum.
+	svc.SetActiveProjectID(ulid.ULID{})
+
+	first, err := svc.RunJob(ctx, job.Params{Type: "test"}, noop)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if j, err := svc.FindJobByID(ctx, first.ID); err != nil || j.Status != job.StatusFinished {
+		t.Fatalf("expected finished job, got: %+v (error: %v)", j, err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if _, err := svc.RunJob(ctx, job.Params{Type: "test"}, noop); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	jobs, err := svc.FindJobs(ctx, job.FindJobsFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(jobs) != 100 {
+		t.Fatalf("expected 100 jobs to be kept, got: %v", len(jobs))
+	}
+
+	for _, j := range jobs {
+		if j.ID == first.ID {
+			t.Fatal("expected the oldest job to be pruned")
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package job
+
+import (
+	"context"
+
+	"github.com/oklog/ulid"
+)
+
+type Repository interface {
+	StoreJob(ctx context.Context, job Job) error
+	FindJobs(ctx context.Context, filter FindJobsFilter) ([]Job, error)
+	ClearJobs(ctx context.Context, projectID ulid.ULID) error
+}
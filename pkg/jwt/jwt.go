@@ -0,0 +1,305 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"regexp"
+	"strings"
+)
+
+// Algorithms.
+const (
+	AlgNone  = "none"
+	AlgHS256 = "HS256"
+	AlgHS384 = "HS384"
+	AlgHS512 = "HS512"
+	AlgRS256 = "RS256"
+	AlgRS384 = "RS384"
+	AlgRS512 = "RS512"
+)
+
+var (
+	ErrInvalidToken         = errors.New("jwt: invalid token")
+	ErrUnsupportedAlgorithm = errors.New("jwt: unsupported algorithm")
+	ErrInvalidKey           = errors.New("jwt: invalid key")
+)
+
+// tokenRegexp matches JWTs in arbitrary text. Because the header and payload
+// are JSON objects, both parts start with `eyJ` when base64url encoded.
+var tokenRegexp = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`)
+
+// DefaultSecrets is a list of commonly used (and thus guessable) HMAC secrets.
+var DefaultSecrets = []string{
+	"", "secret", "Secret", "SECRET", "secretkey", "secret_key", "secret-key", "mysecret", "my_secret",
+	"your-256-bit-secret", "your-384-bit-secret", "your-512-bit-secret", "jwt", "jwtsecret", "jwt_secret",
+	"jwt-secret", "key", "private", "password", "Password", "pass", "123456", "12345678", "1234567890",
+	"qwerty", "changeme", "changeit", "admin", "test", "default", "token", "hmac", "hs256", "example",
+	"supersecret", "s3cr3t", "letmein", "shhhhh", "keyboard cat",
+}
+
+// Token is a decoded JSON Web Token.
+type Token struct {
+	Raw       string
+	Header    map[string]interface{}
+	Claims    map[string]interface{}
+	Signature []byte
+
+	// RawHeader and RawClaims are the decoded JSON objects of the token, as
+	// they were encoded in the token.
+	RawHeader []byte
+	RawClaims []byte
+}
+
+// Weakness types.
+const (
+	WeaknessAlgNone    WeaknessType = "alg_none"
+	WeaknessWeakSecret WeaknessType = "weak_secret"
+)
+
+// WeaknessType defines a type for a class of token weaknesses.
+type WeaknessType string
+
+// Weakness describes an issue found for a token.
+type Weakness struct {
+	Type        WeaknessType
+	Description string
+	// Secret is set to the guessed secret for `WeaknessWeakSecret`.
+	Secret string
+}
+
+// Parse decodes a token. The signature is not verified.
+func Parse(raw string) (Token, error) {
+	raw = strings.TrimSpace(raw)
+
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return Token{}, fmt.Errorf("%w: must consist of three dot separated parts", ErrInvalidToken)
+	}
+
+	token := Token{Raw: raw}
+
+	var err error
+
+	token.RawHeader, err = decodeSegment(parts[0])
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: could not decode header: %v", ErrInvalidToken, err)
+	}
+
+	token.RawClaims, err = decodeSegment(parts[1])
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: could not decode claims: %v", ErrInvalidToken, err)
+	}
+
+	token.Signature, err = decodeSegment(parts[2])
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: could not decode signature: %v", ErrInvalidToken, err)
+	}
+
+	if err := json.Unmarshal(token.RawHeader, &token.Header); err != nil {
+		return Token{}, fmt.Errorf("%w: header is not a JSON object: %v", ErrInvalidToken, err)
+	}
+
+	if err := json.Unmarshal(token.RawClaims, &token.Claims); err != nil {
+		return Token{}, fmt.Errorf("%w: claims are not a JSON object: %v", ErrInvalidToken, err)
+	}
+
+	return token, nil
+}
+
+// Find returns all valid tokens found in b.
+func Find(b []byte) []Token {
+	var tokens []Token
+
+	for _, match := range tokenRegexp.FindAll(b, -1) {
+		token, err := Parse(string(match))
+		if err != nil {
+			continue
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+// Algorithm returns the value of the `alg` header parameter.
+func (t Token) Algorithm() string {
+	alg, _ := t.Header["alg"].(string)
+	return alg
+}
+
+// SigningInput returns the header and payload segments that are signed.
+func (t Token) SigningInput() string {
+	return t.Raw[:strings.LastIndex(t.Raw, ".")]
+}
+
+// Weaknesses checks the token for an insecure algorithm, and attempts to guess
+// the secret of HMAC signed tokens using `secrets`. When `secrets` is nil,
+// DefaultSecrets is used.
+func (t Token) Weaknesses(secrets []string) []Weakness {
+	var weaknesses []Weakness
+
+	alg := t.Algorithm()
+
+	if strings.EqualFold(alg, AlgNone) {
+		weaknesses = append(weaknesses, Weakness{
+			Type:        WeaknessAlgNone,
+			Description: "Token uses the `none` algorithm, so its signature is not verified.",
+		})
+	}
+
+	if secrets == nil {
+		secrets = DefaultSecrets
+	}
+
+	if hashFn := hmacHash(alg); hashFn != nil {
+		for _, secret := range secrets {
+			if hmac.Equal(t.Signature, hmacSign(hashFn, t.SigningInput(), []byte(secret))) {
+				weaknesses = append(weaknesses, Weakness{
+					Type:        WeaknessWeakSecret,
+					Description: fmt.Sprintf("Token is signed (%v) with a guessable secret.", alg),
+					Secret:      secret,
+				})
+
+				break
+			}
+		}
+	}
+
+	return weaknesses
+}
+
+// Sign encodes and signs a token with the given header and claims. The `alg`
+// header parameter is overwritten with `alg`. For HMAC algorithms, key is the
+// secret; for RSA algorithms, key must be a PEM encoded private key.
+func Sign(header, claims map[string]interface{}, alg string, key []byte) (string, error) {
+	if header == nil {
+		header = map[string]interface{}{"typ": "JWT"}
+	}
+
+	header["alg"] = alg
+
+	rawHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("jwt: could not encode header: %w", err)
+	}
+
+	if claims == nil {
+		claims = map[string]interface{}{}
+	}
+
+	rawClaims, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("jwt: could not encode claims: %w", err)
+	}
+
+	signingInput := encodeSegment(rawHeader) + "." + encodeSegment(rawClaims)
+
+	sig, err := sign(signingInput, alg, key)
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + encodeSegment(sig), nil
+}
+
+func sign(signingInput, alg string, key []byte) ([]byte, error) {
+	if strings.EqualFold(alg, AlgNone) {
+		return nil, nil
+	}
+
+	if hashFn := hmacHash(alg); hashFn != nil {
+		return hmacSign(hashFn, signingInput, key), nil
+	}
+
+	var hash crypto.Hash
+
+	switch alg {
+	case AlgRS256:
+		hash = crypto.SHA256
+	case AlgRS384:
+		hash = crypto.SHA384
+	case AlgRS512:
+		hash = crypto.SHA512
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedAlgorithm, alg)
+	}
+
+	privKey, err := parseRSAPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hash.New()
+	h.Write([]byte(signingInput))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, privKey, hash, h.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("jwt: could not sign token: %w", err)
+	}
+
+	return sig, nil
+}
+
+func parseRSAPrivateKey(key []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(key))
+	if block == nil {
+		return nil, fmt.Errorf("%w: expected PEM encoded RSA private key", ErrInvalidKey)
+	}
+
+	if privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return privKey, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	privKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: private key is not RSA", ErrInvalidKey)
+	}
+
+	return privKey, nil
+}
+
+func hmacHash(alg string) func() hash.Hash {
+	switch alg {
+	case AlgHS256:
+		return sha256.New
+	case AlgHS384:
+		return sha512.New384
+	case AlgHS512:
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+func hmacSign(hashFn func() hash.Hash, signingInput string, secret []byte) []byte {
+	mac := hmac.New(hashFn, secret)
+	mac.Write([]byte(signingInput))
+
+	return mac.Sum(nil)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
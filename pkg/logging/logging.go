@@ -0,0 +1,285 @@
+// Package logging writes the output of the standard logger to configurable
+// sinks, e.g. rotating files and syslog for long-running deployments. Messages
+// are filtered by the level of their prefix, e.g. `[ERROR]`.
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidLevel  = errors.New("logging: invalid level")
+	ErrInvalidFormat = errors.New("logging: invalid format")
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name: `debug`, `info`, `warn` or `error`.
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrInvalidLevel, s)
+}
+
+type Format string
+
+const (
+	// Lines in the format of the standard logger, e.g.
+	// `2006/01/02 15:04:05 [INFO] Running server on :8080 ...`.
+	FormatText Format = "text"
+	// One JSON object per line, with `time`, `level` and `msg` fields.
+	FormatJSON Format = "json"
+)
+
+// ParseFormat parses a format name: `text` or `json`.
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(strings.ToLower(s)); format {
+	case FormatText, FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidFormat, s)
+	}
+}
+
+type Config struct {
+	// Minimum level of messages that are written. Messages without a level
+	// prefix are info messages.
+	Level  Level
+	Format Format
+	// Defaults to `os.Stderr`.
+	Console io.Writer
+	// Path of a file that messages are written to as well, if set.
+	File string
+	// Size in bytes after which the file is rotated. Zero disables rotation.
+	MaxFileSize int64
+	// Number of rotated files to keep, e.g. `hetty.log.1`.
+	MaxBackups int
+	// Syslog daemon that messages are sent to as well, if set: `local`, or a
+	// network address, e.g. `udp://logs.example.com:514`.
+	Syslog string
+}
+
+// Logger writes messages to the console and the configured sinks. Use it as
+// the output of the standard logger, with `log.SetFlags(0)`, because it adds
+// timestamps itself.
+type Logger struct {
+	level   Level
+	format  Format
+	console io.Writer
+	file    *RotatingFile
+	syslog  syslogWriter
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// syslogWriter writes messages to syslog, with the severity of their level.
+type syslogWriter interface {
+	write(level Level, msg string) error
+	Close() error
+}
+
+func New(cfg Config) (*Logger, error) {
+	if cfg.Format == "" {
+		cfg.Format = FormatText
+	}
+
+	if _, err := ParseFormat(string(cfg.Format)); err != nil {
+		return nil, err
+	}
+
+	if cfg.Console == nil {
+		cfg.Console = os.Stderr
+	}
+
+	l := &Logger{
+		level:   cfg.Level,
+		format:  cfg.Format,
+		console: cfg.Console,
+		now:     time.Now,
+	}
+
+	if cfg.File != "" {
+		file, err := OpenRotatingFile(cfg.File, cfg.MaxFileSize, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+
+		l.file = file
+	}
+
+	if cfg.Syslog != "" {
+		w, err := dialSyslog(cfg.Syslog)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+
+		l.syslog = w
+	}
+
+	return l, nil
+}
+
+// SetLevel sets the minimum level of messages that are written, e.g. to
+// enable debug messages for a while, without a restart.
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.level = level
+}
+
+func (l *Logger) Level() Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.level
+}
+
+// Write writes a message of the standard logger. The standard logger calls
+// Write once per message, so p is a single message.
+func (l *Logger) Write(p []byte) (int, error) {
+	level, msg := parseMessage(strings.TrimSuffix(string(p), "\n"))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if level < l.level {
+		return len(p), nil
+	}
+
+	line := l.formatLine(level, msg)
+
+	if _, err := io.WriteString(l.console, line); err != nil {
+		return 0, err
+	}
+
+	// Errors of sinks are written to the console, because there's no other
+	// place to report them.
+	if l.file != nil {
+		if _, err := l.file.Write([]byte(line)); err != nil {
+			fmt.Fprintf(l.console, "logging: could not write to file: %v\n", err)
+		}
+	}
+
+	if l.syslog != nil {
+		syslogMsg := msg
+		if l.format == FormatJSON {
+			syslogMsg = strings.TrimSuffix(line, "\n")
+		}
+
+		if err := l.syslog.write(level, syslogMsg); err != nil {
+			fmt.Fprintf(l.console, "logging: could not write to syslog: %v\n", err)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (l *Logger) formatLine(level Level, msg string) string {
+	now := l.now()
+
+	if l.format == FormatJSON {
+		data, _ := json.Marshal(struct {
+			Time  string `json:"time"`
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}{
+			Time:  now.UTC().Format(time.RFC3339Nano),
+			Level: level.String(),
+			Msg:   msg,
+		})
+
+		return string(data) + "\n"
+	}
+
+	return fmt.Sprintf("%v [%v] %v\n", now.Format("2006/01/02 15:04:05"), strings.ToUpper(level.String()), msg)
+}
+
+// Close closes the file and syslog sinks. Messages that are written after
+// Close are only written to the console.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var errs []string
+
+	if l.file != nil {
+		if err := l.file.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		l.file = nil
+	}
+
+	if l.syslog != nil {
+		if err := l.syslog.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		l.syslog = nil
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("logging: could not close sinks: %v", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// parseMessage returns the level of the prefix of msg (e.g. `[ERROR]`), and
+// msg without it.
+func parseMessage(msg string) (Level, string) {
+	if !strings.HasPrefix(msg, "[") {
+		return LevelInfo, msg
+	}
+
+	end := strings.Index(msg, "]")
+	if end == -1 {
+		return LevelInfo, msg
+	}
+
+	name := msg[1:end]
+	if strings.EqualFold(name, "warning") {
+		name = "warn"
+	}
+
+	level, err := ParseLevel(name)
+	if err != nil {
+		return LevelInfo, msg
+	}
+
+	// Some messages are logged with a colon after the prefix, e.g. `[ERROR]:`.
+	rest := strings.TrimPrefix(msg[end+1:], ":")
+
+	return level, strings.TrimSpace(rest)
+}
@@ -0,0 +1,139 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/logging"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	var console bytes.Buffer
+
+	path := filepath.Join(t.TempDir(), "hetty.log")
+
+	logger, err := logging.New(logging.Config{
+		Level:   logging.LevelWarn,
+		Format:  logging.FormatJSON,
+		Console: &console,
+		File:    path,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l := log.New(logger, "", 0)
+	l.Printf("[INFO] Running server on :8080 ...")
+	l.Printf("[ERROR]: Could not open database: %v", "locked")
+	l.Printf("[WARNING] Disk almost full")
+
+	if err := logger.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if console.String() != string(file) {
+		t.Fatalf("expected console and file output to be equal (console: %q, file: %q)", console.String(), file)
+	}
+
+	lines := strings.Split(strings.TrimSpace(console.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected info message to be filtered, got: %q", lines)
+	}
+
+	var got struct {
+		Time  string `json:"time"`
+		Level string `json:"level"`
+		Msg   string `json:"msg"`
+	}
+
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Level != "error" || got.Msg != "Could not open database: locked" || got.Time == "" {
+		t.Errorf("unexpected message: %+v", got)
+	}
+
+	if !strings.Contains(lines[1], `"level":"warn"`) {
+		t.Errorf("expected warning level, got: %v", lines[1])
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "hetty.log")
+
+	file, err := logging.OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, s := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := file.Write([]byte(s)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, exp := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		got, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if string(got) != exp {
+			t.Errorf("unexpected content of %v (expected: %q, got: %q)", filepath.Base(name), exp, got)
+		}
+	}
+
+	// Backups beyond the maximum are removed.
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected third backup not to exist, got: %v", err)
+	}
+}
+
+func TestLoggerSetLevel(t *testing.T) {
+	t.Parallel()
+
+	var console bytes.Buffer
+
+	logger, err := logging.New(logging.Config{Level: logging.LevelInfo, Console: &console})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l := log.New(logger, "", 0)
+	l.Printf("[DEBUG] Hidden")
+
+	logger.SetLevel(logging.LevelDebug)
+	l.Printf("[DEBUG] Shown")
+
+	if got := console.String(); strings.Contains(got, "Hidden") || !strings.Contains(got, "[DEBUG] Shown") {
+		t.Fatalf("expected only debug message after level change, got: %q", got)
+	}
+
+	if level := logger.Level(); level != logging.LevelDebug {
+		t.Fatalf("expected level debug, got: %v", level)
+	}
+}
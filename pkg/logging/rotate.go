@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is a file that is rotated when it reaches a maximum size: the
+// file is renamed to `<path>.1`, existing backups are shifted (`<path>.1` to
+// `<path>.2`, etc.), and backups beyond the maximum number are removed.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+	mu         sync.Mutex
+}
+
+// OpenRotatingFile opens the file at path for appending, creating it if needed.
+// A maxSize of zero disables rotation.
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	if maxSize < 0 || maxBackups < 0 {
+		return nil, errors.New("logging: maximum file size and number of backups must not be negative")
+	}
+
+	file := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := file.open(); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (file *RotatingFile) open() error {
+	f, err := os.OpenFile(file.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("logging: could not open file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("logging: could not stat file: %w", err)
+	}
+
+	file.f = f
+	file.size = info.Size()
+
+	return nil
+}
+
+// Write writes p to the file, after rotating it if p doesn't fit. A single
+// write that's larger than the maximum size is written to an empty file.
+func (file *RotatingFile) Write(p []byte) (int, error) {
+	file.mu.Lock()
+	defer file.mu.Unlock()
+
+	if file.f == nil {
+		return 0, os.ErrClosed
+	}
+
+	if file.maxSize > 0 && file.size > 0 && file.size+int64(len(p)) > file.maxSize {
+		if err := file.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := file.f.Write(p)
+	file.size += int64(n)
+
+	return n, err
+}
+
+func (file *RotatingFile) rotate() error {
+	if err := file.f.Close(); err != nil {
+		return fmt.Errorf("logging: could not close file: %w", err)
+	}
+
+	file.f = nil
+
+	if file.maxBackups == 0 {
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("logging: could not remove file: %w", err)
+		}
+
+		return file.open()
+	}
+
+	for i := file.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(backupPath(file.path, i), backupPath(file.path, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("logging: could not rotate file: %w", err)
+		}
+	}
+
+	if err := os.Rename(file.path, backupPath(file.path, 1)); err != nil {
+		return fmt.Errorf("logging: could not rotate file: %w", err)
+	}
+
+	return file.open()
+}
+
+func (file *RotatingFile) Close() error {
+	file.mu.Lock()
+	defer file.mu.Unlock()
+
+	if file.f == nil {
+		return nil
+	}
+
+	err := file.f.Close()
+	file.f = nil
+
+	return err
+}
+
+func backupPath(path string, i int) string {
+	return fmt.Sprintf("%v.%v", path, i)
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package logging
+
+import "errors"
+
+func dialSyslog(addr string) (syslogWriter, error) {
+	return nil, errors.New("logging: syslog is not supported on this platform")
+}
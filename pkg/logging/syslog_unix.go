@@ -0,0 +1,49 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package logging
+
+import (
+	"fmt"
+	"log/syslog"
+	"net/url"
+)
+
+type syslogConn struct {
+	*syslog.Writer
+}
+
+// dialSyslog connects to the local syslog daemon if addr is `local`, or else
+// to the daemon at the network address, e.g. `udp://logs.example.com:514`.
+func dialSyslog(addr string) (syslogWriter, error) {
+	var network, raddr string
+
+	if addr != "local" {
+		u, err := url.Parse(addr)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("logging: invalid syslog address %q, expected \"local\" or e.g. \"udp://host:514\"", addr)
+		}
+
+		network, raddr = u.Scheme, u.Host
+	}
+
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_DAEMON, "hetty")
+	if err != nil {
+		return nil, fmt.Errorf("logging: could not connect to syslog: %w", err)
+	}
+
+	return syslogConn{w}, nil
+}
+
+func (conn syslogConn) write(level Level, msg string) error {
+	switch level {
+	case LevelDebug:
+		return conn.Debug(msg)
+	case LevelWarn:
+		return conn.Warning(msg)
+	case LevelError:
+		return conn.Err(msg)
+	default:
+		return conn.Info(msg)
+	}
+}
@@ -0,0 +1,262 @@
+package mdns
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/dns"
+)
+
+// ServiceType is the DNS-SD service type that Hetty is advertised as.
+const ServiceType = "_hetty._tcp.local."
+
+const (
+	servicesEnumName = "_services._dns-sd._udp.local."
+	mdnsPort         = 5353
+
+	// Records with a unique owner have the cache-flush bit set in their class.
+	classCacheFlush = 1 << 15
+
+	ttl = 120
+)
+
+// GroupAddr is the IPv4 mDNS multicast group address.
+var GroupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: mdnsPort}
+
+var ErrNoAddrs = errors.New("mdns: no IP addresses to advertise")
+
+// Responder answers mDNS queries for the Hetty service, so that it can be
+// discovered by other devices on the local network.
+type Responder struct {
+	instanceName string
+	hostName     string
+	port         uint16
+	txt          []string
+	ips          []net.IP
+}
+
+type Config struct {
+	// Instance name, e.g. "Hetty on foobar". Must not contain dots.
+	Instance string
+	// Hostname, without `.local` suffix.
+	Hostname string
+	Port     uint16
+	// TXT record values, in the form "key=value".
+	TXT []string
+	// IP addresses of the host. Defaults to the non-loopback addresses of
+	// all interfaces.
+	IPs []net.IP
+}
+
+func NewResponder(cfg Config) (*Responder, error) {
+	ips := cfg.IPs
+	if ips == nil {
+		var err error
+
+		ips, err = InterfaceIPs()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, ErrNoAddrs
+	}
+
+	label := func(s string) string {
+		return strings.ReplaceAll(s, ".", "-")
+	}
+
+	return &Responder{
+		instanceName: label(cfg.Instance) + "." + ServiceType,
+		hostName:     label(cfg.Hostname) + ".local.",
+		port:         cfg.Port,
+		txt:          cfg.TXT,
+		ips:          ips,
+	}, nil
+}
+
+// Serve answers queries received on conn. It blocks until reading from conn
+// fails. Replies are sent to the multicast group, unless the query was sent
+// from a port other than 5353 (a "legacy" unicast query).
+func (r *Responder) Serve(conn net.PacketConn) error {
+	buf := make([]byte, 9000)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return fmt.Errorf("mdns: failed to read packet: %w", err)
+		}
+
+		msg, err := dns.Parse(buf[:n])
+		if err != nil || msg.Flags&dns.FlagResponse != 0 {
+			continue
+		}
+
+		reply, ok := r.Reply(msg)
+		if !ok {
+			continue
+		}
+
+		dst := net.Addr(GroupAddr)
+
+		if udpAddr, ok := addr.(*net.UDPAddr); ok && udpAddr.Port != mdnsPort {
+			dst = addr
+		} else {
+			// Multicast responses don't have an ID, nor questions.
+			reply.ID = 0
+			reply.Questions = nil
+		}
+
+		if err := r.write(conn, reply, dst); err != nil {
+			log.Printf("[ERROR] Could not write mDNS reply: %v", err)
+		}
+	}
+}
+
+// Announce sends an unsolicited response with all records to the multicast group.
+func (r *Responder) Announce(conn net.PacketConn) error {
+	msg := dns.Message{
+		Flags:   dns.FlagResponse | dns.FlagAuthoritative,
+		Answers: append([]dns.Resource{r.ptrRecord()}, r.instanceRecords()...),
+	}
+	msg.Answers = append(msg.Answers, r.addrRecords()...)
+
+	return r.write(conn, msg, GroupAddr)
+}
+
+// Reply returns the reply to a query, and reports if any of its questions are
+// about the Hetty service.
+func (r *Responder) Reply(query dns.Message) (dns.Message, bool) {
+	reply := query.Reply()
+
+	for _, q := range query.Questions {
+		name := strings.ToLower(q.Name)
+		isAny := q.Type == dns.TypeANY
+
+		switch {
+		case name == servicesEnumName && (isAny || q.Type == dns.TypePTR):
+			if data, err := dns.NameData(ServiceType); err == nil {
+				reply.Answers = append(reply.Answers, dns.Resource{
+					Name:  servicesEnumName,
+					Type:  dns.TypePTR,
+					Class: dns.ClassINET,
+					TTL:   ttl,
+					Data:  data,
+				})
+			}
+		case name == ServiceType && (isAny || q.Type == dns.TypePTR):
+			reply.Answers = append(reply.Answers, r.ptrRecord())
+			reply.Additional = append(reply.Additional, r.instanceRecords()...)
+			reply.Additional = append(reply.Additional, r.addrRecords()...)
+		case name == strings.ToLower(r.instanceName):
+			for _, rr := range r.instanceRecords() {
+				if isAny || q.Type == rr.Type {
+					reply.Answers = append(reply.Answers, rr)
+				}
+			}
+
+			reply.Additional = append(reply.Additional, r.addrRecords()...)
+		case name == strings.ToLower(r.hostName):
+			for _, rr := range r.addrRecords() {
+				if isAny || q.Type == rr.Type {
+					reply.Answers = append(reply.Answers, rr)
+				}
+			}
+		}
+	}
+
+	return reply, len(reply.Answers) > 0
+}
+
+func (r *Responder) ptrRecord() dns.Resource {
+	data, _ := dns.NameData(r.instanceName)
+
+	return dns.Resource{
+		Name:  ServiceType,
+		Type:  dns.TypePTR,
+		Class: dns.ClassINET,
+		TTL:   ttl,
+		Data:  data,
+	}
+}
+
+func (r *Responder) instanceRecords() []dns.Resource {
+	srvData, _ := dns.SRVData(0, 0, r.port, r.hostName)
+
+	return []dns.Resource{
+		{
+			Name:  r.instanceName,
+			Type:  dns.TypeSRV,
+			Class: dns.ClassINET | classCacheFlush,
+			TTL:   ttl,
+			Data:  srvData,
+		},
+		{
+			Name:  r.instanceName,
+			Type:  dns.TypeTXT,
+			Class: dns.ClassINET | classCacheFlush,
+			TTL:   ttl,
+			Data:  dns.TXTData(r.txt...),
+		},
+	}
+}
+
+func (r *Responder) addrRecords() []dns.Resource {
+	rrs := make([]dns.Resource, 0, len(r.ips))
+
+	for _, ip := range r.ips {
+		rrType := dns.TypeAAAA
+		if ip.To4() != nil {
+			rrType = dns.TypeA
+		}
+
+		rrs = append(rrs, dns.Resource{
+			Name:  r.hostName,
+			Type:  rrType,
+			Class: dns.ClassINET | classCacheFlush,
+			TTL:   ttl,
+			Data:  dns.AData(ip),
+		})
+	}
+
+	return rrs
+}
+
+func (r *Responder) write(conn net.PacketConn, msg dns.Message, dst net.Addr) error {
+	b, err := msg.Encode()
+	if err != nil {
+		return fmt.Errorf("mdns: failed to encode message: %w", err)
+	}
+
+	if _, err := conn.WriteTo(b, dst); err != nil {
+		return fmt.Errorf("mdns: failed to write message: %w", err)
+	}
+
+	return nil
+}
+
+// InterfaceIPs returns the IPv4 addresses of all interfaces that are up, except
+// loopback addresses.
+func InterfaceIPs() ([]net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("mdns: failed to get interface addresses: %w", err)
+	}
+
+	var ips []net.IP
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+			continue
+		}
+
+		ips = append(ips, ipNet.IP.To4())
+	}
+
+	return ips, nil
+}
@@ -0,0 +1,111 @@
+package mdns_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/dns"
+	"github.com/dstotijn/hetty/pkg/mdns"
+)
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	responder, err := mdns.NewResponder(mdns.Config{
+		Instance: "Hetty on foo.lan",
+		Hostname: "foo",
+		Port:     8080,
+		TXT:      []string{"proxy=192.0.2.1:8080"},
+		IPs:      []net.IP{net.ParseIP("192.0.2.1")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go responder.Serve(conn)
+
+	client, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	query, err := dns.Message{
+		ID:        42,
+		Questions: []dns.Question{{Name: mdns.ServiceType, Type: dns.TypePTR, Class: dns.ClassINET}},
+	}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.WriteTo(query, conn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1500)
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	n, _, err := client.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read reply: %v", err)
+	}
+
+	reply, err := dns.Parse(buf[:n])
+	if err != nil {
+		t.Fatalf("failed to parse reply: %v", err)
+	}
+
+	// Legacy unicast replies must have the ID of the query.
+	if reply.ID != 42 {
+		t.Errorf("incorrect reply ID (expected: 42, got: %v)", reply.ID)
+	}
+
+	if len(reply.Answers) != 1 || reply.Answers[0].Type != dns.TypePTR {
+		t.Fatalf("expected a single PTR answer, got: %+v", reply.Answers)
+	}
+
+	expInstance, _ := dns.NameData("Hetty on foo-lan." + mdns.ServiceType)
+	if string(reply.Answers[0].Data) != string(expInstance) {
+		t.Errorf("incorrect PTR data (expected: %q, got: %q)", expInstance, reply.Answers[0].Data)
+	}
+
+	types := make(map[uint16]bool)
+	for _, rr := range reply.Additional {
+		types[rr.Type] = true
+	}
+
+	for _, typ := range []uint16{dns.TypeSRV, dns.TypeTXT, dns.TypeA} {
+		if !types[typ] {
+			t.Errorf("expected additional %v record", dns.TypeString(typ))
+		}
+	}
+}
+
+func TestReplyUnrelated(t *testing.T) {
+	t.Parallel()
+
+	responder, err := mdns.NewResponder(mdns.Config{
+		Instance: "Hetty",
+		Hostname: "foo",
+		Port:     8080,
+		IPs:      []net.IP{net.ParseIP("192.0.2.1")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, ok := responder.Reply(dns.Message{
+		Questions: []dns.Question{{Name: "_airplay._tcp.local.", Type: dns.TypePTR, Class: dns.ClassINET}},
+	})
+	if ok {
+		t.Fatal("expected no reply for unrelated query")
+	}
+}
@@ -0,0 +1,183 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package nuclei_test
+
+import (
+	"context"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/oklog/ulid"
+	"sync"
+)
+
+// Ensure, that FindingRepoMock does implement finding.Repository.
+// If this is not the case, regenerate this file with moq.
+var _ finding.Repository = &FindingRepoMock{}
+
+// FindingRepoMock is a mock implementation of finding.Repository.
+//
+//	func TestSomethingThatUsesRepository(t *testing.T) {
+//
+//		// make and configure a mocked finding.Repository
+//		mockedRepository := &FindingRepoMock{
+//			ClearFindingsFunc: func(ctx context.Context, projectID ulid.ULID) error {
+//				panic("mock out the ClearFindings method")
+//			},
+//			FindFindingsFunc: func(ctx context.Context, filter finding.FindFindingsFilter) ([]finding.Finding, error) {
+//				panic("mock out the FindFindings method")
+//			},
+//			StoreFindingFunc: func(ctx context.Context, findingMoqParam finding.Finding) error {
+//				panic("mock out the StoreFinding method")
+//			},
+//		}
+//
+//		// use mockedRepository in code that requires finding.Repository
+//		// and then make assertions.
+//
+//	}
+type FindingRepoMock struct {
+	// ClearFindingsFunc mocks the ClearFindings method.
+	ClearFindingsFunc func(ctx context.Context, projectID ulid.ULID) error
+
+	// FindFindingsFunc mocks the FindFindings method.
+	FindFindingsFunc func(ctx context.Context, filter finding.FindFindingsFilter) ([]finding.Finding, error)
+
+	// StoreFindingFunc mocks the StoreFinding method.
+	StoreFindingFunc func(ctx context.Context, findingMoqParam finding.Finding) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// ClearFindings holds details about calls to the ClearFindings method.
+		ClearFindings []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+		}
+		// FindFindings holds details about calls to the FindFindings method.
+		FindFindings []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter finding.FindFindingsFilter
+		}
+		// StoreFinding holds details about calls to the StoreFinding method.
+		StoreFinding []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// FindingMoqParam is the findingMoqParam argument value.
+			FindingMoqParam finding.Finding
+		}
+	}
+	lockClearFindings sync.RWMutex
+	lockFindFindings  sync.RWMutex
+	lockStoreFinding  sync.RWMutex
+}
+
+// ClearFindings calls ClearFindingsFunc.
+func (mock *FindingRepoMock) ClearFindings(ctx context.Context, projectID ulid.ULID) error {
+	if mock.ClearFindingsFunc == nil {
+		panic("FindingRepoMock.ClearFindingsFunc: method is nil but Repository.ClearFindings was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+	}
+	mock.lockClearFindings.Lock()
+	mock.calls.ClearFindings = append(mock.calls.ClearFindings, callInfo)
+	mock.lockClearFindings.Unlock()
+	return mock.ClearFindingsFunc(ctx, projectID)
+}
+
+// ClearFindingsCalls gets all the calls that were made to ClearFindings.
+// Check the length with:
+//
+//	len(mockedRepository.ClearFindingsCalls())
+func (mock *FindingRepoMock) ClearFindingsCalls() []struct {
+	Ctx       context.Context
+	ProjectID ulid.ULID
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}
+	mock.lockClearFindings.RLock()
+	calls = mock.calls.ClearFindings
+	mock.lockClearFindings.RUnlock()
+	return calls
+}
+
+// FindFindings calls FindFindingsFunc.
+func (mock *FindingRepoMock) FindFindings(ctx context.Context, filter finding.FindFindingsFilter) ([]finding.Finding, error) {
+	if mock.FindFindingsFunc == nil {
+		panic("FindingRepoMock.FindFindingsFunc: method is nil but Repository.FindFindings was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter finding.FindFindingsFilter
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockFindFindings.Lock()
+	mock.calls.FindFindings = append(mock.calls.FindFindings, callInfo)
+	mock.lockFindFindings.Unlock()
+	return mock.FindFindingsFunc(ctx, filter)
+}
+
+// FindFindingsCalls gets all the calls that were made to FindFindings.
+// Check the length with:
+//
+//	len(mockedRepository.FindFindingsCalls())
+func (mock *FindingRepoMock) FindFindingsCalls() []struct {
+	Ctx    context.Context
+	Filter finding.FindFindingsFilter
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter finding.FindFindingsFilter
+	}
+	mock.lockFindFindings.RLock()
+	calls = mock.calls.FindFindings
+	mock.lockFindFindings.RUnlock()
+	return calls
+}
+
+// StoreFinding calls StoreFindingFunc.
+func (mock *FindingRepoMock) StoreFinding(ctx context.Context, findingMoqParam finding.Finding) error {
+	if mock.StoreFindingFunc == nil {
+		panic("FindingRepoMock.StoreFindingFunc: method is nil but Repository.StoreFinding was just called")
+	}
+	callInfo := struct {
+		Ctx             context.Context
+		FindingMoqParam finding.Finding
+	}{
+		Ctx:             ctx,
+		FindingMoqParam: findingMoqParam,
+	}
+	mock.lockStoreFinding.Lock()
+	mock.calls.StoreFinding = append(mock.calls.StoreFinding, callInfo)
+	mock.lockStoreFinding.Unlock()
+	return mock.StoreFindingFunc(ctx, findingMoqParam)
+}
+
+// StoreFindingCalls gets all the calls that were made to StoreFinding.
+// Check the length with:
+//
+//	len(mockedRepository.StoreFindingCalls())
+func (mock *FindingRepoMock) StoreFindingCalls() []struct {
+	Ctx             context.Context
+	FindingMoqParam finding.Finding
+} {
+	var calls []struct {
+		Ctx             context.Context
+		FindingMoqParam finding.Finding
+	}
+	mock.lockStoreFinding.RLock()
+	calls = mock.calls.StoreFinding
+	mock.lockStoreFinding.RUnlock()
+	return calls
+}
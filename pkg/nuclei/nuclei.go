@@ -0,0 +1,526 @@
+// Package nuclei runs nuclei-style YAML templates against the in-scope hosts of
+// the site map, i.e. the origins of in-scope request logs of the active
+// project. Requests are sent with Hetty's transport, so that they are logged,
+// and matches are stored as findings of the generated request logs.
+package nuclei
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+	// Maximum number of requests of a run.
+	maxRunRequests = 10000
+)
+
+// JobType is the type of the jobs that run templates, see `job.Params`.
+const JobType = "nucleiRun"
+
+var (
+	ErrRunNotFound     = errcode.New(errcode.NotFound, "nuclei: run not found")
+	ErrInvalidTemplate = errcode.New(errcode.Invalid, "nuclei: invalid template")
+	ErrNoTemplates     = errcode.New(errcode.Invalid, "nuclei: no templates")
+	ErrNoTargets       = errcode.New(errcode.Invalid, "nuclei: no in-scope hosts in site map")
+	ErrTooManyRequests = errcode.New(errcode.Invalid, fmt.Sprintf("nuclei: run exceeds %v requests", maxRunRequests))
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusFinished  Status = "finished"
+	StatusCancelled Status = "cancelled"
+)
+
+// Service runs nuclei templates. Matches are stored as findings.
+type Service interface {
+	FindTargets(ctx context.Context) ([]*url.URL, error)
+	StartRun(ctx context.Context, params RunParams) (Run, error)
+	FindRunByID(id ulid.ULID) (Run, error)
+	FindRuns() []Run
+	CancelRun(id ulid.ULID) error
+}
+
+type service struct {
+	scope       *scope.Scope
+	ids         idgen.Generator
+	jobs        job.Service
+	reqLogSvc   reqlog.Service
+	findingRepo finding.Repository
+	events      *event.Bus
+	httpClient  *http.Client
+	runs        map[ulid.ULID]*runState
+	mu          sync.RWMutex
+}
+
+type Config struct {
+	Scope             *scope.Scope
+	RequestLogService reqlog.Service
+	FindingRepository finding.Repository
+	// Transport that template requests are sent with, e.g. the proxy, so that
+	// they are logged. Defaults to `http.DefaultTransport`.
+	Transport http.RoundTripper
+	// Bus for publishing created findings. Optional.
+	Events *event.Bus
+	// Generates the IDs of runs and findings. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+	// Runs templates as jobs, so that runs are queued, report progress and can
+	// be paused. Optional; without it, runs start right away.
+	Jobs job.Service
+}
+
+type RunParams struct {
+	// YAML documents of the templates.
+	Templates []string
+	// Hosts (e.g. `example.com:8443`) of the site map to run the templates
+	// against. Defaults to all in-scope hosts.
+	Hosts []string
+}
+
+type Run struct {
+	ID        ulid.ULID
+	Status    Status
+	Templates []string
+	Targets   []*url.URL
+	Total     int
+	Completed int
+	// Number of requests that couldn't be sent.
+	Errors  int
+	Matches []Match
+}
+
+// Match is a response that matched the matchers of a template. Once a template
+// matches a target, its remaining requests for the target are skipped.
+type Match struct {
+	TemplateID   string
+	TemplateName string
+	Severity     finding.Severity
+	URL          *url.URL
+	// Request log of the matching request, if it was logged.
+	RequestLogID ulid.ULID
+	StatusCode   int
+}
+
+type runState struct {
+	run    Run
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &service{
+		ids:         cfg.IDGenerator,
+		scope:       cfg.Scope,
+		jobs:        cfg.Jobs,
+		reqLogSvc:   cfg.RequestLogService,
+		findingRepo: cfg.FindingRepository,
+		events:      cfg.Events,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   defaultTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		runs: make(map[ulid.ULID]*runState),
+	}
+}
+
+// FindTargets returns the origins (scheme and host) of the in-scope request
+// logs of the active project, sorted by host.
+func (svc *service) FindTargets(ctx context.Context) ([]*url.URL, error) {
+	reqLogs, err := svc.reqLogSvc.FindSelectedRequests(ctx, reqlog.Selection{
+		Filter: &reqlog.FindRequestsFilter{OnlyInScope: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("nuclei: failed to find request logs: %w", err)
+	}
+
+	seen := make(map[string]bool)
+
+	var targets []*url.URL
+
+	for _, reqLog := range reqLogs {
+		if reqLog.URL == nil || reqLog.URL.Host == "" {
+			continue
+		}
+
+		if reqLog.URL.Scheme != "http" && reqLog.URL.Scheme != "https" {
+			continue
+		}
+
+		target := &url.URL{Scheme: reqLog.URL.Scheme, Host: strings.ToLower(reqLog.URL.Host)}
+		if seen[target.String()] {
+			continue
+		}
+
+		seen[target.String()] = true
+		targets = append(targets, target)
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Host != targets[j].Host {
+			return targets[i].Host < targets[j].Host
+		}
+
+		return targets[i].Scheme < targets[j].Scheme
+	})
+
+	return targets, nil
+}
+
+// StartRun parses the templates, and runs them against the in-scope hosts of
+// the site map in the background.
+func (svc *service) StartRun(ctx context.Context, params RunParams) (Run, error) {
+	if len(params.Templates) == 0 {
+		return Run{}, ErrNoTemplates
+	}
+
+	templates := make([]Template, len(params.Templates))
+
+	for i, data := range params.Templates {
+		tmpl, err := ParseTemplate([]byte(data))
+		if err != nil {
+			return Run{}, err
+		}
+
+		templates[i] = tmpl
+	}
+
+	targets, err := svc.FindTargets(ctx)
+	if err != nil {
+		return Run{}, err
+	}
+
+	if len(params.Hosts) > 0 {
+		hosts := make(map[string]bool, len(params.Hosts))
+		for _, host := range params.Hosts {
+			hosts[strings.ToLower(host)] = true
+		}
+
+		filtered := targets[:0]
+
+		for _, target := range targets {
+			if hosts[target.Host] {
+				filtered = append(filtered, target)
+			}
+		}
+
+		targets = filtered
+	}
+
+	if len(targets) == 0 {
+		return Run{}, ErrNoTargets
+	}
+
+	total := 0
+	ids := make([]string, len(templates))
+
+	for i, tmpl := range templates {
+		ids[i] = tmpl.ID
+
+		for _, req := range tmpl.Requests {
+			total += len(req.Path) * len(targets)
+		}
+	}
+
+	if total > maxRunRequests {
+		return Run{}, ErrTooManyRequests
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+
+	state := &runState{
+		run: Run{
+			ID:        svc.ids.New(time.Now()),
+			Status:    StatusRunning,
+			Templates: ids,
+			Targets:   targets,
+			Total:     total,
+		},
+		cancel: cancel,
+	}
+
+	svc.mu.Lock()
+	svc.runs[state.run.ID] = state
+	svc.mu.Unlock()
+
+	projectID := svc.reqLogSvc.ActiveProjectID()
+
+	if svc.jobs == nil {
+		go svc.run(runCtx, state, templates, targets, projectID, nil)
+		return state.snapshot(), nil
+	}
+
+	jobParams := job.Params{
+		ID:          state.run.ID,
+		Type:        JobType,
+		Description: fmt.Sprintf("%v templates, %v hosts", len(templates), len(targets)),
+		Total:       total,
+	}
+
+	svc.jobs.StartJob(runCtx, jobParams, func(ctx context.Context, progress *job.Progress) error {
+		return svc.run(ctx, state, templates, targets, projectID, progress)
+	})
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindRunByID(id ulid.ULID) (Run, error) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	state, ok := svc.runs[id]
+	if !ok {
+		return Run{}, ErrRunNotFound
+	}
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindRuns() []Run {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	runs := make([]Run, 0, len(svc.runs))
+	for _, state := range svc.runs {
+		runs = append(runs, state.snapshot())
+	}
+
+	// Most recent runs first.
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].ID.Compare(runs[j].ID) > 0
+	})
+
+	return runs
+}
+
+func (svc *service) CancelRun(id ulid.ULID) error {
+	svc.mu.RLock()
+	state, ok := svc.runs[id]
+	svc.mu.RUnlock()
+
+	if !ok {
+		return ErrRunNotFound
+	}
+
+	state.mu.Lock()
+	if state.run.Status == StatusRunning {
+		state.run.Status = StatusCancelled
+	}
+	state.mu.Unlock()
+
+	state.cancel()
+
+	return nil
+}
+
+func (svc *service) run(
+	ctx context.Context,
+	state *runState,
+	templates []Template,
+	targets []*url.URL,
+	projectID ulid.ULID,
+	progress *job.Progress,
+) error {
+	defer state.cancel()
+
+loop:
+	for _, tmpl := range templates {
+		for _, target := range targets {
+			matched := false
+
+			for _, req := range tmpl.Requests {
+				urls, err := req.requestURLs(target)
+				if err != nil {
+					state.mu.Lock()
+					state.run.Completed += len(req.Path)
+					state.run.Errors += len(req.Path)
+					state.mu.Unlock()
+					progress.Add(len(req.Path))
+
+					continue
+				}
+
+				for _, u := range urls {
+					if err := progress.Wait(ctx); err != nil {
+						break loop
+					}
+
+					if matched {
+						state.mu.Lock()
+						state.run.Completed++
+						state.mu.Unlock()
+						progress.Add(1)
+
+						continue
+					}
+
+					match, ok, err := svc.send(ctx, tmpl, req, u)
+					if ctx.Err() != nil {
+						break loop
+					}
+
+					if ok {
+						matched = true
+
+						if err := svc.storeFinding(projectID, tmpl, match); err != nil {
+							log.Printf("[ERROR] Could not store nuclei template finding: %v", err)
+						}
+					}
+
+					state.mu.Lock()
+					state.run.Completed++
+					if err != nil {
+						state.run.Errors++
+					}
+					if ok {
+						state.run.Matches = append(state.run.Matches, match)
+					}
+					state.mu.Unlock()
+					progress.Add(1)
+				}
+			}
+		}
+	}
+
+	state.mu.Lock()
+	if state.run.Status == StatusRunning {
+		state.run.Status = StatusFinished
+		if ctx.Err() != nil {
+			state.run.Status = StatusCancelled
+		}
+	}
+	state.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// send sends a request of a template, and returns a match if the response
+// matches its matchers.
+func (svc *service) send(ctx context.Context, tmpl Template, req HTTPTemplate, u *url.URL) (Match, bool, error) {
+	// Paths can be absolute URLs, which may be out of scope.
+	if svc.scope != nil && !svc.scope.Match(&http.Request{URL: u, Header: http.Header{}}, nil) {
+		return Match{}, false, fmt.Errorf("URL %v is out of scope", u)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, u.String(), strings.NewReader(req.Body))
+	if err != nil {
+		return Match{}, false, err
+	}
+
+	for key, value := range req.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	res, err := svc.httpClient.Do(httpReq)
+	if err != nil {
+		return Match{}, false, err
+	}
+	defer res.Body.Close()
+
+	resLog, err := reqlog.ParseHTTPResponse(res)
+	if err != nil {
+		return Match{}, false, err
+	}
+
+	if !req.match(resLog.StatusCode, resLog.Header, resLog.Body) {
+		return Match{}, false, nil
+	}
+
+	match := Match{
+		TemplateID:   tmpl.ID,
+		TemplateName: tmpl.Info.Name,
+		Severity:     tmpl.Info.severity(),
+		URL:          u,
+		StatusCode:   resLog.StatusCode,
+	}
+
+	if res.Request != nil {
+		if reqLogID, ok := res.Request.Context().Value(proxy.ReqLogIDKey).(ulid.ULID); ok {
+			match.RequestLogID = reqLogID
+		}
+	}
+
+	return match, true, nil
+}
+
+// storeFinding stores a finding for a match. Matches of requests that weren't
+// logged (e.g. because the project is opened read-only) aren't stored.
+func (svc *service) storeFinding(projectID ulid.ULID, tmpl Template, match Match) error {
+	if match.RequestLogID.Compare(ulid.ULID{}) == 0 {
+		return nil
+	}
+
+	name := tmpl.Info.Name
+	if name == "" {
+		name = tmpl.ID
+	}
+
+	description := fmt.Sprintf("Nuclei template `%v` (%v) matched %v.", tmpl.ID, name, match.URL)
+	if tmpl.Info.Description != "" {
+		description += " " + strings.TrimSpace(tmpl.Info.Description)
+	}
+
+	f := finding.Finding{
+		ID:           svc.ids.New(time.Now()),
+		ProjectID:    projectID,
+		RequestLogID: match.RequestLogID,
+		Check:        finding.CheckNucleiTemplate,
+		Severity:     match.Severity,
+		Description:  description,
+	}
+
+	if err := svc.findingRepo.StoreFinding(context.Background(), f); err != nil {
+		return err
+	}
+
+	svc.events.Publish(event.Event{
+		Type:      event.TypeFindingCreated,
+		ProjectID: f.ProjectID,
+		ID:        f.ID,
+		Data:      f,
+	})
+
+	return nil
+}
+
+func (state *runState) snapshot() Run {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	run := state.run
+	run.Matches = make([]Match, len(state.run.Matches))
+	copy(run.Matches, state.run.Matches)
+
+	return run
+}
@@ -0,0 +1,245 @@
+package nuclei_test
+
+//go:generate go run github.com/matryer/moq -out reqlog_mock_test.go -pkg nuclei_test ../reqlog Service:ReqLogServiceMock
+//go:generate go run github.com/matryer/moq -out finding_repo_mock_test.go -pkg nuclei_test ../finding Repository:FindingRepoMock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/nuclei"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+//nolint:gosec
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+const gitConfigTemplate = `
+id: git-config
+info:
+  name: Git Config File
+  severity: medium
+  description: Git configuration is exposed.
+requests:
+  - method: GET
+    path:
+      - "{{BaseURL}}/.git/config"
+      - "{{BaseURL}}/app/.git/config"
+    matchers-condition: and
+    matchers:
+      - type: word
+        words:
+          - "[core]"
+      - type: status
+        status:
+          - 200
+`
+
+const serverHeaderTemplate = `
+id: nginx-version
+info:
+  name: Nginx Version
+  severity: info
+http:
+  - path:
+      - "{{BaseURL}}/"
+    matchers:
+      - type: regex
+        part: header
+        regex:
+          - "(?i)server: nginx/[0-9.]+"
+`
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestParseTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{name: "invalid YAML", yaml: "id: [foo"},
+		{name: "no id", yaml: "requests:\n  - path: ['{{BaseURL}}']\n    matchers: [{type: status, status: [200]}]"},
+		{name: "no requests", yaml: "id: foo"},
+		{name: "raw request", yaml: "id: foo\nrequests:\n  - raw: ['GET / HTTP/1.1']"},
+		{name: "unsupported matcher", yaml: "id: foo\nrequests:\n  - path: ['{{BaseURL}}']\n    matchers: [{type: dsl}]"},
+		{name: "invalid regex", yaml: "id: foo\nrequests:\n  - path: ['{{BaseURL}}']\n    matchers: [{type: regex, regex: ['(']}]"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := nuclei.ParseTemplate([]byte(tt.yaml))
+			if !errors.Is(err, nuclei.ErrInvalidTemplate) {
+				t.Fatalf("expected `nuclei.ErrInvalidTemplate`, got: %v", err)
+			}
+		})
+	}
+
+	tmpl, err := nuclei.ParseTemplate([]byte(serverHeaderTemplate))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(tmpl.Requests) != 1 || tmpl.Requests[0].Method != http.MethodGet {
+		t.Fatalf("expected one `GET` request, got: %+v", tmpl.Requests)
+	}
+}
+
+func TestStartRun(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.18.0")
+
+		switch r.URL.Path {
+		case "/.git/config":
+			fmt.Fprint(w, "[core]\n\trepositoryformatversion = 0\n")
+		case "/app/.git/config":
+			t.Errorf("expected request to be skipped after a match")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	reqLogSvc := &ReqLogServiceMock{
+		FindSelectedRequestsFunc: func(_ context.Context, sel reqlog.Selection) ([]reqlog.RequestLog, error) {
+			if sel.Filter == nil || !sel.Filter.OnlyInScope {
+				t.Errorf("expected in-scope filter, got: %+v", sel)
+			}
+
+			return []reqlog.RequestLog{
+				{URL: &url.URL{Scheme: "http", Host: tsURL.Host, Path: "/foo"}},
+				{URL: &url.URL{Scheme: "http", Host: tsURL.Host, Path: "/bar"}},
+				{URL: &url.URL{Scheme: "http", Host: "other.example", Path: "/"}},
+			}, nil
+		},
+		ActiveProjectIDFunc: func() ulid.ULID {
+			return projectID
+		},
+	}
+
+	findingRepo := &FindingRepoMock{
+		StoreFindingFunc: func(_ context.Context, _ finding.Finding) error {
+			return nil
+		},
+	}
+
+	// Sets a request log ID on requests, like the proxy does.
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+		req = req.WithContext(context.WithValue(req.Context(), proxy.ReqLogIDKey, reqLogID))
+
+		res, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Request = req
+
+		return res, nil
+	})
+
+	svc := nuclei.NewService(nuclei.Config{
+		RequestLogService: reqLogSvc,
+		FindingRepository: findingRepo,
+		Transport:         transport,
+	})
+
+	targets, err := svc.FindTargets(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got: %v", targets)
+	}
+
+	_, err = svc.StartRun(context.Background(), nuclei.RunParams{
+		Templates: []string{serverHeaderTemplate},
+		Hosts:     []string{"unknown.example"},
+	})
+	if !errors.Is(err, nuclei.ErrNoTargets) {
+		t.Fatalf("expected `nuclei.ErrNoTargets`, got: %v", err)
+	}
+
+	run, err := svc.StartRun(context.Background(), nuclei.RunParams{
+		Templates: []string{gitConfigTemplate, serverHeaderTemplate},
+		Hosts:     []string{tsURL.Host},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if run.Total != 3 {
+		t.Fatalf("expected 3 requests, got: %v", run.Total)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+
+	for run.Status == nuclei.StatusRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+
+		run, err = svc.FindRunByID(run.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if run.Status != nuclei.StatusFinished || run.Completed != run.Total || run.Errors != 0 {
+		t.Fatalf("expected run to finish without errors, got: %+v", run)
+	}
+
+	if len(run.Matches) != 2 {
+		t.Fatalf("expected 2 matches, got: %+v", run.Matches)
+	}
+
+	calls := findingRepo.StoreFindingCalls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 findings to be stored, got: %+v", calls)
+	}
+
+	for i, call := range calls {
+		f, match := call.FindingMoqParam, run.Matches[i]
+
+		if f.Check != finding.CheckNucleiTemplate || f.ProjectID != projectID || f.RequestLogID != match.RequestLogID {
+			t.Errorf("expected finding of match %+v, got: %+v", match, f)
+		}
+
+		if !strings.Contains(f.Description, match.TemplateID) {
+			t.Errorf("expected finding description to contain template ID, got: %v", f.Description)
+		}
+	}
+
+	if run.Matches[0].Severity != finding.SeverityMedium || run.Matches[0].URL.Path != "/.git/config" {
+		t.Errorf("unexpected match: %+v", run.Matches[0])
+	}
+}
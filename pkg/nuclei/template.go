@@ -0,0 +1,317 @@
+package nuclei
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/dstotijn/hetty/pkg/finding"
+)
+
+// Template is a nuclei template, of which a subset is supported: HTTP requests
+// with `method`, `path`, `headers` and `body`, and `word`, `regex`, `status`
+// and `size` matchers. Raw requests, DSL matchers and extractors aren't.
+type Template struct {
+	ID       string         `yaml:"id"`
+	Info     TemplateInfo   `yaml:"info"`
+	Requests []HTTPTemplate `yaml:"requests"`
+	// Newer templates use `http` instead of `requests`.
+	HTTP []HTTPTemplate `yaml:"http"`
+}
+
+type TemplateInfo struct {
+	Name        string `yaml:"name"`
+	Severity    string `yaml:"severity"`
+	Description string `yaml:"description"`
+}
+
+type HTTPTemplate struct {
+	Method  string            `yaml:"method"`
+	Path    []string          `yaml:"path"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	Raw     []string          `yaml:"raw"`
+	// Either `or` (default) or `and`.
+	MatchersCondition string    `yaml:"matchers-condition"`
+	Matchers          []Matcher `yaml:"matchers"`
+}
+
+type Matcher struct {
+	Type string `yaml:"type"`
+	// Part of the response to match: `body` (default), `header` or `all`.
+	Part  string   `yaml:"part"`
+	Words []string `yaml:"words"`
+	Regex []string `yaml:"regex"`
+	// Status codes, for `status` matchers.
+	Status []int `yaml:"status"`
+	// Body sizes, for `size` matchers.
+	Size []int `yaml:"size"`
+	// Either `or` (default) or `and`, for the words or regexes.
+	Condition       string `yaml:"condition"`
+	Negative        bool   `yaml:"negative"`
+	CaseInsensitive bool   `yaml:"case-insensitive"`
+
+	regexps []*regexp.Regexp
+}
+
+// ParseTemplate parses and validates a YAML nuclei template.
+func ParseTemplate(data []byte) (Template, error) {
+	var tmpl Template
+
+	if err := yaml.Unmarshal(data, &tmpl); err != nil {
+		return Template{}, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	if tmpl.ID == "" {
+		return Template{}, fmt.Errorf("%w: id must be set", ErrInvalidTemplate)
+	}
+
+	tmpl.Requests = append(tmpl.Requests, tmpl.HTTP...)
+	tmpl.HTTP = nil
+
+	if len(tmpl.Requests) == 0 {
+		return Template{}, fmt.Errorf("%w: %v: no HTTP requests", ErrInvalidTemplate, tmpl.ID)
+	}
+
+	for i := range tmpl.Requests {
+		if err := tmpl.Requests[i].compile(); err != nil {
+			return Template{}, fmt.Errorf("%w: %v: %v", ErrInvalidTemplate, tmpl.ID, err)
+		}
+	}
+
+	return tmpl, nil
+}
+
+func (req *HTTPTemplate) compile() error {
+	if len(req.Raw) > 0 {
+		return fmt.Errorf("raw requests are not supported")
+	}
+
+	if len(req.Path) == 0 {
+		return fmt.Errorf("request has no paths")
+	}
+
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+
+	req.Method = strings.ToUpper(req.Method)
+
+	if err := checkCondition(req.MatchersCondition); err != nil {
+		return err
+	}
+
+	if len(req.Matchers) == 0 {
+		return fmt.Errorf("request has no matchers")
+	}
+
+	for i := range req.Matchers {
+		if err := req.Matchers[i].compile(); err != nil {
+			return fmt.Errorf("matcher %v: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (m *Matcher) compile() error {
+	if err := checkCondition(m.Condition); err != nil {
+		return err
+	}
+
+	switch m.Part {
+	case "", "body", "header", "all":
+	default:
+		return fmt.Errorf("part %q is not supported", m.Part)
+	}
+
+	switch m.Type {
+	case "word":
+		if len(m.Words) == 0 {
+			return fmt.Errorf("word matcher has no words")
+		}
+	case "regex":
+		if len(m.Regex) == 0 {
+			return fmt.Errorf("regex matcher has no regexes")
+		}
+
+		for _, expr := range m.Regex {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return fmt.Errorf("invalid regex: %w", err)
+			}
+
+			m.regexps = append(m.regexps, re)
+		}
+	case "status":
+		if len(m.Status) == 0 {
+			return fmt.Errorf("status matcher has no status codes")
+		}
+	case "size":
+		if len(m.Size) == 0 {
+			return fmt.Errorf("size matcher has no sizes")
+		}
+	default:
+		return fmt.Errorf("matcher type %q is not supported", m.Type)
+	}
+
+	return nil
+}
+
+func checkCondition(condition string) error {
+	switch condition {
+	case "", "or", "and":
+		return nil
+	default:
+		return fmt.Errorf("condition %q is not supported", condition)
+	}
+}
+
+// requestURLs returns the URLs of the paths of a request, with the variables
+// of nuclei (e.g. `{{BaseURL}}`) replaced for a target.
+func (req HTTPTemplate) requestURLs(target *url.URL) ([]*url.URL, error) {
+	base := strings.TrimSuffix(target.String(), "/")
+	root := target.Scheme + "://" + target.Host
+
+	replacer := strings.NewReplacer(
+		"{{BaseURL}}", base,
+		"{{RootURL}}", root,
+		"{{Hostname}}", target.Host,
+		"{{Host}}", target.Hostname(),
+		"{{Scheme}}", target.Scheme,
+	)
+
+	urls := make([]*url.URL, len(req.Path))
+
+	for i, path := range req.Path {
+		u, err := url.Parse(replacer.Replace(path))
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %q: %w", path, err)
+		}
+
+		urls[i] = u
+	}
+
+	return urls, nil
+}
+
+// match returns true if a response matches the matchers of a request.
+func (req HTTPTemplate) match(statusCode int, header http.Header, body []byte) bool {
+	and := req.MatchersCondition == "and"
+
+	for _, m := range req.Matchers {
+		matched := m.match(statusCode, header, body)
+
+		switch {
+		case and && !matched:
+			return false
+		case !and && matched:
+			return true
+		}
+	}
+
+	return and
+}
+
+func (m Matcher) match(statusCode int, header http.Header, body []byte) bool {
+	matched := false
+
+	switch m.Type {
+	case "word":
+		part := m.part(header, body)
+		if m.CaseInsensitive {
+			part = bytes.ToLower(part)
+		}
+
+		matched = matchAll(m.Condition, len(m.Words), func(i int) bool {
+			word := m.Words[i]
+			if m.CaseInsensitive {
+				word = strings.ToLower(word)
+			}
+
+			return bytes.Contains(part, []byte(word))
+		})
+	case "regex":
+		part := m.part(header, body)
+		matched = matchAll(m.Condition, len(m.regexps), func(i int) bool {
+			return m.regexps[i].Match(part)
+		})
+	case "status":
+		for _, status := range m.Status {
+			matched = matched || status == statusCode
+		}
+	case "size":
+		for _, size := range m.Size {
+			matched = matched || size == len(body)
+		}
+	}
+
+	return matched != m.Negative
+}
+
+func (m Matcher) part(header http.Header, body []byte) []byte {
+	if m.Part == "" || m.Part == "body" {
+		return body
+	}
+
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+
+	for _, key := range keys {
+		for _, value := range header[key] {
+			fmt.Fprintf(buf, "%v: %v\r\n", key, value)
+		}
+	}
+
+	if m.Part == "all" {
+		buf.WriteString("\r\n")
+		buf.Write(body)
+	}
+
+	return buf.Bytes()
+}
+
+// matchAll returns whether one (`or`) or all (`and`) of n items match.
+func matchAll(condition string, n int, match func(i int) bool) bool {
+	and := condition == "and"
+
+	for i := 0; i < n; i++ {
+		matched := match(i)
+
+		switch {
+		case and && !matched:
+			return false
+		case !and && matched:
+			return true
+		}
+	}
+
+	return and
+}
+
+// severity maps the severity of a template to that of a finding. Critical is
+// mapped to high, and unknown severities to info.
+func (info TemplateInfo) severity() finding.Severity {
+	switch strings.ToLower(info.Severity) {
+	case "low":
+		return finding.SeverityLow
+	case "medium":
+		return finding.SeverityMedium
+	case "high", "critical":
+		return finding.SeverityHigh
+	default:
+		return finding.SeverityInfo
+	}
+}
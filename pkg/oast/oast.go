@@ -0,0 +1,368 @@
+package oast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/dns"
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/idgen"
+)
+
+// Maximum size of a raw HTTP interaction that is stored.
+const maxRawHTTPSize = 64 << 10
+
+var (
+	ErrNotConfigured      = errors.New("oast: callback domain not configured")
+	ErrProjectIDMustBeSet = errors.New("oast: project ID must be set")
+	ErrPayloadNotFound    = errcode.New(errcode.NotFound, "oast: payload not found")
+	ErrReadOnly           = errors.New("oast: project is opened read-only")
+)
+
+type Protocol string
+
+const (
+	ProtocolDNS  Protocol = "dns"
+	ProtocolHTTP Protocol = "http"
+)
+
+// Service manages out-of-band payloads, and records the DNS and HTTP
+// interactions made with them.
+type Service interface {
+	CreatePayload(ctx context.Context, params CreatePayloadParams) (Payload, error)
+	FindInteractions(ctx context.Context, filter FindInteractionsFilter) ([]Interaction, error)
+	Hostname(payload Payload) string
+	URL(payload Payload) string
+	SetActiveProjectID(id ulid.ULID)
+	ActiveProjectID() ulid.ULID
+	SetReadOnly(readOnly bool)
+	ServeHTTP(w http.ResponseWriter, r *http.Request)
+	ServeDNS(conn net.PacketConn) error
+}
+
+type service struct {
+	// mu guards the active project settings, which are changed at runtime.
+	mu              sync.RWMutex
+	activeProjectID ulid.ULID
+	readOnly        bool
+
+	repo   Repository
+	ids    idgen.Generator
+	domain string
+	ip     net.IP
+}
+
+// Payload is a unique identifier that can be embedded in requests (as a
+// hostname or URL), so that interactions with it can be correlated to a
+// request log.
+type Payload struct {
+	ID           ulid.ULID
+	ProjectID    ulid.ULID
+	RequestLogID ulid.ULID
+	// ID that relates the payload to the source it was created for, e.g. a
+	// sender request.
+	CorrelationID ulid.ULID
+}
+
+// Interaction is a DNS query or HTTP request received for a payload.
+type Interaction struct {
+	ID            ulid.ULID
+	ProjectID     ulid.ULID
+	PayloadID     ulid.ULID
+	RequestLogID  ulid.ULID
+	CorrelationID ulid.ULID
+	Protocol      Protocol
+	RemoteAddr    string
+	Raw           []byte
+}
+
+type CreatePayloadParams struct {
+	RequestLogID  ulid.ULID
+	CorrelationID ulid.ULID
+}
+
+type FindInteractionsFilter struct {
+	ProjectID     ulid.ULID
+	RequestLogID  ulid.ULID
+	CorrelationID ulid.ULID
+}
+
+type Config struct {
+	Repository Repository
+	// Domain that is delegated to Hetty, e.g. `oast.example.com`. Payload
+	// hostnames are subdomains of it.
+	Domain string
+	// IP address returned for DNS queries of payload hostnames.
+	IP net.IP
+	// Generates the IDs of payloads and interactions. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	return &service{
+		ids:    cfg.IDGenerator,
+		repo:   cfg.Repository,
+		domain: strings.ToLower(strings.Trim(cfg.Domain, ".")),
+		ip:     cfg.IP,
+	}
+}
+
+func (svc *service) CreatePayload(ctx context.Context, params CreatePayloadParams) (Payload, error) {
+	if svc.domain == "" {
+		return Payload{}, ErrNotConfigured
+	}
+
+	projectID := svc.ActiveProjectID()
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return Payload{}, ErrProjectIDMustBeSet
+	}
+
+	if svc.isReadOnly() {
+		return Payload{}, ErrReadOnly
+	}
+
+	payload := Payload{
+		ID:            svc.ids.New(time.Now()),
+		ProjectID:     projectID,
+		RequestLogID:  params.RequestLogID,
+		CorrelationID: params.CorrelationID,
+	}
+
+	err := svc.repo.StoreOASTPayload(ctx, payload)
+	if err != nil {
+		return Payload{}, fmt.Errorf("oast: failed to store payload: %w", err)
+	}
+
+	return payload, nil
+}
+
+func (svc *service) FindInteractions(ctx context.Context, filter FindInteractionsFilter) ([]Interaction, error) {
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		filter.ProjectID = svc.ActiveProjectID()
+	}
+
+	if filter.ProjectID.Compare(ulid.ULID{}) == 0 {
+		return nil, ErrProjectIDMustBeSet
+	}
+
+	interactions, err := svc.repo.FindOASTInteractions(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("oast: failed to find interactions: %w", err)
+	}
+
+	return interactions, nil
+}
+
+// Hostname returns the hostname for a payload. Its first label is the payload
+// ID in lower case, because DNS names aren't case sensitive.
+func (svc *service) Hostname(payload Payload) string {
+	return strings.ToLower(payload.ID.String()) + "." + svc.domain
+}
+
+func (svc *service) URL(payload Payload) string {
+	return "http://" + svc.Hostname(payload) + "/"
+}
+
+func (svc *service) SetActiveProjectID(id ulid.ULID) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.activeProjectID = id
+}
+
+func (svc *service) ActiveProjectID() ulid.ULID {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.activeProjectID
+}
+
+// SetReadOnly sets whether the active project is opened read-only. No
+// payloads can be created for it, and interactions with its payloads aren't
+// stored.
+func (svc *service) SetReadOnly(readOnly bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.readOnly = readOnly
+}
+
+func (svc *service) isReadOnly() bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.readOnly
+}
+
+// ServeHTTP records an interaction for HTTP requests with a payload hostname
+// (or with a payload ID as the first path segment, for when the request is sent
+// to an IP address).
+func (svc *service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	payloadID, ok := svc.payloadIDFromName(host)
+	if !ok {
+		segment := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+		payloadID, ok = parsePayloadID(segment)
+	}
+
+	if ok {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRawHTTPSize)
+
+		raw, err := httputil.DumpRequest(r, true)
+		if err != nil {
+			// Fall back to storing the request without its body.
+			raw, _ = httputil.DumpRequest(r, false)
+		}
+
+		svc.recordInteraction(r.Context(), payloadID, ProtocolHTTP, r.RemoteAddr, raw)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, "hetty\n")
+}
+
+// ServeDNS answers DNS queries received on conn, and records an interaction for
+// queries of payload hostnames. It blocks until reading from conn fails.
+func (svc *service) ServeDNS(conn net.PacketConn) error {
+	buf := make([]byte, 512)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return fmt.Errorf("oast: failed to read DNS packet: %w", err)
+		}
+
+		msg, err := dns.Parse(buf[:n])
+		if err != nil || msg.Flags&dns.FlagResponse != 0 {
+			continue
+		}
+
+		reply := msg.Reply()
+
+		for _, q := range msg.Questions {
+			name := strings.ToLower(strings.TrimSuffix(q.Name, "."))
+			if name != svc.domain && !strings.HasSuffix(name, "."+svc.domain) {
+				continue
+			}
+
+			if payloadID, ok := svc.payloadIDFromName(name); ok {
+				// The query is stored in a human readable format, similar to
+				// the question section output of `dig`.
+				query := fmt.Sprintf("%v\tIN\t%v", q.Name, dns.TypeString(q.Type))
+				svc.recordInteraction(context.Background(), payloadID, ProtocolDNS, addr.String(), []byte(query))
+			}
+
+			if svc.ip == nil {
+				continue
+			}
+
+			ip4 := svc.ip.To4()
+
+			if q.Type == dns.TypeANY || (q.Type == dns.TypeA && ip4 != nil) || (q.Type == dns.TypeAAAA && ip4 == nil) {
+				rrType := dns.TypeA
+				if ip4 == nil {
+					rrType = dns.TypeAAAA
+				}
+
+				reply.Answers = append(reply.Answers, dns.Resource{
+					Name:  q.Name,
+					Type:  rrType,
+					Class: dns.ClassINET,
+					TTL:   0,
+					Data:  dns.AData(svc.ip),
+				})
+			}
+		}
+
+		b, err := reply.Encode()
+		if err != nil {
+			log.Printf("[ERROR] Could not encode OAST DNS reply: %v", err)
+			continue
+		}
+
+		if _, err := conn.WriteTo(b, addr); err != nil {
+			log.Printf("[ERROR] Could not write OAST DNS reply: %v", err)
+		}
+	}
+}
+
+func (svc *service) recordInteraction(ctx context.Context, payloadID ulid.ULID, protocol Protocol, remoteAddr string, raw []byte) {
+	payload, err := svc.repo.FindOASTPayloadByID(ctx, payloadID)
+	if errors.Is(err, ErrPayloadNotFound) {
+		return
+	}
+
+	if err != nil {
+		log.Printf("[ERROR] Could not find OAST payload (id: %v): %v", payloadID, err)
+		return
+	}
+
+	if svc.isReadOnly() && payload.ProjectID.Compare(svc.ActiveProjectID()) == 0 {
+		return
+	}
+
+	interaction := Interaction{
+		ID:            svc.ids.New(time.Now()),
+		ProjectID:     payload.ProjectID,
+		PayloadID:     payload.ID,
+		RequestLogID:  payload.RequestLogID,
+		CorrelationID: payload.CorrelationID,
+		Protocol:      protocol,
+		RemoteAddr:    remoteAddr,
+		Raw:           raw,
+	}
+
+	if err := svc.repo.StoreOASTInteraction(ctx, interaction); err != nil {
+		log.Printf("[ERROR] Could not store OAST interaction: %v", err)
+	}
+}
+
+// payloadIDFromName returns the payload ID of a hostname that is a subdomain of
+// the configured domain. The label directly preceding the domain is used, so
+// that arbitrary data can be prefixed, e.g. `data.<payload>.oast.example.com`.
+func (svc *service) payloadIDFromName(name string) (ulid.ULID, bool) {
+	if svc.domain == "" {
+		return ulid.ULID{}, false
+	}
+
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if !strings.HasSuffix(name, "."+svc.domain) {
+		return ulid.ULID{}, false
+	}
+
+	labels := strings.Split(strings.TrimSuffix(name, "."+svc.domain), ".")
+
+	return parsePayloadID(labels[len(labels)-1])
+}
+
+func parsePayloadID(s string) (ulid.ULID, bool) {
+	if len(s) != ulid.EncodedSize {
+		return ulid.ULID{}, false
+	}
+
+	id, err := ulid.ParseStrict(strings.ToUpper(s))
+	if err != nil {
+		return ulid.ULID{}, false
+	}
+
+	return id, true
+}
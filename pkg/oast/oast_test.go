@@ -0,0 +1,249 @@
+package oast_test
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/dns"
+	"github.com/dstotijn/hetty/pkg/oast"
+)
+
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// newRepoMock returns a repository mock with the payload, that records stored
+// interactions.
+func newRepoMock(payload oast.Payload) (*RepoMock, func() []oast.Interaction) {
+	var (
+		mu           sync.Mutex
+		interactions []oast.Interaction
+	)
+
+	repoMock := &RepoMock{
+		FindOASTPayloadByIDFunc: func(_ context.Context, id ulid.ULID) (oast.Payload, error) {
+			if id != payload.ID {
+				return oast.Payload{}, oast.ErrPayloadNotFound
+			}
+
+			return payload, nil
+		},
+		StoreOASTInteractionFunc: func(_ context.Context, interaction oast.Interaction) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			interactions = append(interactions, interaction)
+
+			return nil
+		},
+	}
+
+	return repoMock, func() []oast.Interaction {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]oast.Interaction(nil), interactions...)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	payload := oast.Payload{
+		ID:           ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID:    ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		RequestLogID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+	}
+	otherID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	label := strings.ToLower(payload.ID.String())
+
+	tests := []struct {
+		name           string
+		host           string
+		path           string
+		expInteraction bool
+	}{
+		{name: "payload hostname", host: label + ".oast.example.com", path: "/", expInteraction: true},
+		{name: "payload hostname with port", host: label + ".oast.example.com:8080", path: "/", expInteraction: true},
+		{name: "upper case payload hostname", host: strings.ToUpper(label) + ".OAST.example.com", path: "/", expInteraction: true},
+		{name: "prefixed data label", host: "secret." + label + ".oast.example.com", path: "/", expInteraction: true},
+		{name: "trailing dot", host: label + ".oast.example.com.", path: "/", expInteraction: true},
+		{name: "payload ID as path segment", host: "192.0.2.1", path: "/" + payload.ID.String() + "/foo", expInteraction: true},
+		{name: "unknown payload", host: strings.ToLower(otherID.String()) + ".oast.example.com", path: "/"},
+		{name: "domain itself", host: "oast.example.com", path: "/"},
+		{name: "other domain", host: label + ".example.org", path: "/"},
+		{name: "domain as suffix of label", host: label + "oast.example.com", path: "/"},
+		{name: "payload label not directly preceding domain", host: label + ".foo.oast.example.com", path: "/"},
+		{name: "too short label", host: label[1:] + ".oast.example.com", path: "/"},
+		{name: "invalid characters", host: "u" + label[1:] + ".oast.example.com", path: "/"},
+		{name: "overflowing timestamp", host: "8" + label[1:] + ".oast.example.com", path: "/"},
+		{name: "malformed path segment", host: "192.0.2.1", path: "/not-a-payload"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repoMock, interactions := newRepoMock(payload)
+			svc := oast.NewService(oast.Config{Repository: repoMock, Domain: "oast.example.com."})
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("foo=bar"))
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+
+			svc.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status code %v, got: %v", http.StatusOK, rec.Code)
+			}
+
+			got := interactions()
+
+			if !tt.expInteraction {
+				if len(got) != 0 {
+					t.Fatalf("expected no interactions, got: %+v", got)
+				}
+
+				return
+			}
+
+			if len(got) != 1 {
+				t.Fatalf("expected 1 interaction, got: %v", len(got))
+			}
+
+			interaction := got[0]
+
+			if interaction.PayloadID != payload.ID || interaction.ProjectID != payload.ProjectID ||
+				interaction.RequestLogID != payload.RequestLogID || interaction.Protocol != oast.ProtocolHTTP {
+				t.Errorf("unexpected interaction: %+v", interaction)
+			}
+
+			if raw := string(interaction.Raw); !strings.HasPrefix(raw, "POST "+tt.path) || !strings.HasSuffix(raw, "foo=bar") {
+				t.Errorf("unexpected raw request: %q", raw)
+			}
+		})
+	}
+}
+
+func TestServeDNS(t *testing.T) {
+	t.Parallel()
+
+	payload := oast.Payload{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+	}
+	otherID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	label := strings.ToLower(payload.ID.String())
+
+	repoMock, interactions := newRepoMock(payload)
+	svc := oast.NewService(oast.Config{
+		Repository: repoMock,
+		Domain:     "oast.example.com",
+		IP:         net.ParseIP("192.0.2.1"),
+	})
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	go func() {
+		_ = svc.ServeDNS(conn)
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		name           string
+		qName          string
+		qType          uint16
+		expAnswers     int
+		expInteraction bool
+	}{
+		{name: "A query of payload hostname", qName: "data." + label + ".oast.example.com.", qType: dns.TypeA, expAnswers: 1, expInteraction: true},
+		{name: "AAAA query of payload hostname", qName: label + ".oast.example.com.", qType: dns.TypeAAAA, expInteraction: true},
+		{name: "unknown payload", qName: strings.ToLower(otherID.String()) + ".oast.example.com.", qType: dns.TypeA, expAnswers: 1},
+		{name: "malformed payload label", qName: "foo.oast.example.com.", qType: dns.TypeA, expAnswers: 1},
+		{name: "domain itself", qName: "oast.example.com.", qType: dns.TypeA, expAnswers: 1},
+		{name: "other domain", qName: label + ".example.org.", qType: dns.TypeA},
+	}
+
+	// Queries are sent one after the other, so that the new interaction of
+	// each query can be told apart.
+	for i, tt := range tests {
+		query, err := dns.Message{
+			ID:        uint16(i + 1),
+			Questions: []dns.Question{{Name: tt.qName, Type: tt.qType, Class: dns.ClassINET}},
+		}.Encode()
+		if err != nil {
+			t.Fatalf("%v: failed to encode query: %v", tt.name, err)
+		}
+
+		before := len(interactions())
+
+		if _, err := client.Write(query); err != nil {
+			t.Fatalf("%v: failed to write query: %v", tt.name, err)
+		}
+
+		buf := make([]byte, 512)
+
+		if err := client.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+			t.Fatalf("%v: failed to set read deadline: %v", tt.name, err)
+		}
+
+		n, err := client.Read(buf)
+		if err != nil {
+			t.Fatalf("%v: failed to read reply: %v", tt.name, err)
+		}
+
+		reply, err := dns.Parse(buf[:n])
+		if err != nil {
+			t.Fatalf("%v: failed to parse reply: %v", tt.name, err)
+		}
+
+		if reply.ID != uint16(i+1) || reply.Flags&dns.FlagResponse == 0 {
+			t.Errorf("%v: unexpected reply header: %+v", tt.name, reply)
+		}
+
+		if len(reply.Answers) != tt.expAnswers {
+			t.Errorf("%v: expected %v answers, got: %+v", tt.name, tt.expAnswers, reply.Answers)
+		}
+
+		// The interaction is stored before the reply is written.
+		got := interactions()[before:]
+
+		if !tt.expInteraction {
+			if len(got) != 0 {
+				t.Errorf("%v: expected no interactions, got: %+v", tt.name, got)
+			}
+
+			continue
+		}
+
+		if len(got) != 1 {
+			t.Fatalf("%v: expected 1 interaction, got: %v", tt.name, len(got))
+		}
+
+		if got[0].PayloadID != payload.ID || got[0].Protocol != oast.ProtocolDNS {
+			t.Errorf("%v: unexpected interaction: %+v", tt.name, got[0])
+		}
+
+		if exp := tt.qName + "\tIN\t" + dns.TypeString(tt.qType); string(got[0].Raw) != exp {
+			t.Errorf("%v: expected raw query %q, got: %q", tt.name, exp, got[0].Raw)
+		}
+	}
+}
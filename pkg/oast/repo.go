@@ -0,0 +1,15 @@
+package oast
+
+import (
+	"context"
+
+	"github.com/oklog/ulid"
+)
+
+type Repository interface {
+	StoreOASTPayload(ctx context.Context, payload Payload) error
+	FindOASTPayloadByID(ctx context.Context, id ulid.ULID) (Payload, error)
+	StoreOASTInteraction(ctx context.Context, interaction Interaction) error
+	FindOASTInteractions(ctx context.Context, filter FindInteractionsFilter) ([]Interaction, error)
+	ClearOASTData(ctx context.Context, projectID ulid.ULID) error
+}
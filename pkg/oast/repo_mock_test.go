@@ -0,0 +1,283 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package oast_test
+
+import (
+	"context"
+	"github.com/dstotijn/hetty/pkg/oast"
+	"github.com/oklog/ulid"
+	"sync"
+)
+
+// Ensure, that RepoMock does implement oast.Repository.
+// If this is not the case, regenerate this file with moq.
+var _ oast.Repository = &RepoMock{}
+
+// RepoMock is a mock implementation of oast.Repository.
+//
+//	func TestSomethingThatUsesRepository(t *testing.T) {
+//
+//		// make and configure a mocked oast.Repository
+//		mockedRepository := &RepoMock{
+//			ClearOASTDataFunc: func(ctx context.Context, projectID ulid.ULID) error {
+//				panic("mock out the ClearOASTData method")
+//			},
+//			FindOASTInteractionsFunc: func(ctx context.Context, filter oast.FindInteractionsFilter) ([]oast.Interaction, error) {
+//				panic("mock out the FindOASTInteractions method")
+//			},
+//			FindOASTPayloadByIDFunc: func(ctx context.Context, id ulid.ULID) (oast.Payload, error) {
+//				panic("mock out the FindOASTPayloadByID method")
+//			},
+//			StoreOASTInteractionFunc: func(ctx context.Context, interaction oast.Interaction) error {
+//				panic("mock out the StoreOASTInteraction method")
+//			},
+//			StoreOASTPayloadFunc: func(ctx context.Context, payload oast.Payload) error {
+//				panic("mock out the StoreOASTPayload method")
+//			},
+//		}
+//
+//		// use mockedRepository in code that requires oast.Repository
+//		// and then make assertions.
+//
+//	}
+type RepoMock struct {
+	// ClearOASTDataFunc mocks the ClearOASTData method.
+	ClearOASTDataFunc func(ctx context.Context, projectID ulid.ULID) error
+
+	// FindOASTInteractionsFunc mocks the FindOASTInteractions method.
+	FindOASTInteractionsFunc func(ctx context.Context, filter oast.FindInteractionsFilter) ([]oast.Interaction, error)
+
+	// FindOASTPayloadByIDFunc mocks the FindOASTPayloadByID method.
+	FindOASTPayloadByIDFunc func(ctx context.Context, id ulid.ULID) (oast.Payload, error)
+
+	// StoreOASTInteractionFunc mocks the StoreOASTInteraction method.
+	StoreOASTInteractionFunc func(ctx context.Context, interaction oast.Interaction) error
+
+	// StoreOASTPayloadFunc mocks the StoreOASTPayload method.
+	StoreOASTPayloadFunc func(ctx context.Context, payload oast.Payload) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// ClearOASTData holds details about calls to the ClearOASTData method.
+		ClearOASTData []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+		}
+		// FindOASTInteractions holds details about calls to the FindOASTInteractions method.
+		FindOASTInteractions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter oast.FindInteractionsFilter
+		}
+		// FindOASTPayloadByID holds details about calls to the FindOASTPayloadByID method.
+		FindOASTPayloadByID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID ulid.ULID
+		}
+		// StoreOASTInteraction holds details about calls to the StoreOASTInteraction method.
+		StoreOASTInteraction []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Interaction is the interaction argument value.
+			Interaction oast.Interaction
+		}
+		// StoreOASTPayload holds details about calls to the StoreOASTPayload method.
+		StoreOASTPayload []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Payload is the payload argument value.
+			Payload oast.Payload
+		}
+	}
+	lockClearOASTData        sync.RWMutex
+	lockFindOASTInteractions sync.RWMutex
+	lockFindOASTPayloadByID  sync.RWMutex
+	lockStoreOASTInteraction sync.RWMutex
+	lockStoreOASTPayload     sync.RWMutex
+}
+
+// ClearOASTData calls ClearOASTDataFunc.
+func (mock *RepoMock) ClearOASTData(ctx context.Context, projectID ulid.ULID) error {
+	if mock.ClearOASTDataFunc == nil {
+		panic("RepoMock.ClearOASTDataFunc: method is nil but Repository.ClearOASTData was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+	}
+	mock.lockClearOASTData.Lock()
+	mock.calls.ClearOASTData = append(mock.calls.ClearOASTData, callInfo)
+	mock.lockClearOASTData.Unlock()
+	return mock.ClearOASTDataFunc(ctx, projectID)
+}
+
+// ClearOASTDataCalls gets all the calls that were made to ClearOASTData.
+// Check the length with:
+//
+//	len(mockedRepository.ClearOASTDataCalls())
+func (mock *RepoMock) ClearOASTDataCalls() []struct {
+	Ctx       context.Context
+	ProjectID ulid.ULID
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}
+	mock.lockClearOASTData.RLock()
+	calls = mock.calls.ClearOASTData
+	mock.lockClearOASTData.RUnlock()
+	return calls
+}
+
+// FindOASTInteractions calls FindOASTInteractionsFunc.
+func (mock *RepoMock) FindOASTInteractions(ctx context.Context, filter oast.FindInteractionsFilter) ([]oast.Interaction, error) {
+	if mock.FindOASTInteractionsFunc == nil {
+		panic("RepoMock.FindOASTInteractionsFunc: method is nil but Repository.FindOASTInteractions was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter oast.FindInteractionsFilter
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockFindOASTInteractions.Lock()
+	mock.calls.FindOASTInteractions = append(mock.calls.FindOASTInteractions, callInfo)
+	mock.lockFindOASTInteractions.Unlock()
+	return mock.FindOASTInteractionsFunc(ctx, filter)
+}
+
+// FindOASTInteractionsCalls gets all the calls that were made to FindOASTInteractions.
+// Check the length with:
+//
+//	len(mockedRepository.FindOASTInteractionsCalls())
+func (mock *RepoMock) FindOASTInteractionsCalls() []struct {
+	Ctx    context.Context
+	Filter oast.FindInteractionsFilter
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter oast.FindInteractionsFilter
+	}
+	mock.lockFindOASTInteractions.RLock()
+	calls = mock.calls.FindOASTInteractions
+	mock.lockFindOASTInteractions.RUnlock()
+	return calls
+}
+
+// FindOASTPayloadByID calls FindOASTPayloadByIDFunc.
+func (mock *RepoMock) FindOASTPayloadByID(ctx context.Context, id ulid.ULID) (oast.Payload, error) {
+	if mock.FindOASTPayloadByIDFunc == nil {
+		panic("RepoMock.FindOASTPayloadByIDFunc: method is nil but Repository.FindOASTPayloadByID was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockFindOASTPayloadByID.Lock()
+	mock.calls.FindOASTPayloadByID = append(mock.calls.FindOASTPayloadByID, callInfo)
+	mock.lockFindOASTPayloadByID.Unlock()
+	return mock.FindOASTPayloadByIDFunc(ctx, id)
+}
+
+// FindOASTPayloadByIDCalls gets all the calls that were made to FindOASTPayloadByID.
+// Check the length with:
+//
+//	len(mockedRepository.FindOASTPayloadByIDCalls())
+func (mock *RepoMock) FindOASTPayloadByIDCalls() []struct {
+	Ctx context.Context
+	ID  ulid.ULID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}
+	mock.lockFindOASTPayloadByID.RLock()
+	calls = mock.calls.FindOASTPayloadByID
+	mock.lockFindOASTPayloadByID.RUnlock()
+	return calls
+}
+
+// StoreOASTInteraction calls StoreOASTInteractionFunc.
+func (mock *RepoMock) StoreOASTInteraction(ctx context.Context, interaction oast.Interaction) error {
+	if mock.StoreOASTInteractionFunc == nil {
+		panic("RepoMock.StoreOASTInteractionFunc: method is nil but Repository.StoreOASTInteraction was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		Interaction oast.Interaction
+	}{
+		Ctx:         ctx,
+		Interaction: interaction,
+	}
+	mock.lockStoreOASTInteraction.Lock()
+	mock.calls.StoreOASTInteraction = append(mock.calls.StoreOASTInteraction, callInfo)
+	mock.lockStoreOASTInteraction.Unlock()
+	return mock.StoreOASTInteractionFunc(ctx, interaction)
+}
+
+// StoreOASTInteractionCalls gets all the calls that were made to StoreOASTInteraction.
+// Check the length with:
+//
+//	len(mockedRepository.StoreOASTInteractionCalls())
+func (mock *RepoMock) StoreOASTInteractionCalls() []struct {
+	Ctx         context.Context
+	Interaction oast.Interaction
+} {
+	var calls []struct {
+		Ctx         context.Context
+		Interaction oast.Interaction
+	}
+	mock.lockStoreOASTInteraction.RLock()
+	calls = mock.calls.StoreOASTInteraction
+	mock.lockStoreOASTInteraction.RUnlock()
+	return calls
+}
+
+// StoreOASTPayload calls StoreOASTPayloadFunc.
+func (mock *RepoMock) StoreOASTPayload(ctx context.Context, payload oast.Payload) error {
+	if mock.StoreOASTPayloadFunc == nil {
+		panic("RepoMock.StoreOASTPayloadFunc: method is nil but Repository.StoreOASTPayload was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Payload oast.Payload
+	}{
+		Ctx:     ctx,
+		Payload: payload,
+	}
+	mock.lockStoreOASTPayload.Lock()
+	mock.calls.StoreOASTPayload = append(mock.calls.StoreOASTPayload, callInfo)
+	mock.lockStoreOASTPayload.Unlock()
+	return mock.StoreOASTPayloadFunc(ctx, payload)
+}
+
+// StoreOASTPayloadCalls gets all the calls that were made to StoreOASTPayload.
+// Check the length with:
+//
+//	len(mockedRepository.StoreOASTPayloadCalls())
+func (mock *RepoMock) StoreOASTPayloadCalls() []struct {
+	Ctx     context.Context
+	Payload oast.Payload
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Payload oast.Payload
+	}
+	mock.lockStoreOASTPayload.RLock()
+	calls = mock.calls.StoreOASTPayload
+	mock.lockStoreOASTPayload.RUnlock()
+	return calls
+}
@@ -0,0 +1,410 @@
+// Package oauth2 fetches OAuth 2.0 access tokens on a schedule, with the client
+// credentials or resource owner password credentials grant, so that they can
+// be used as variables in sender requests.
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/event"
+)
+
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// Delay before a failed fetch is retried.
+const retryDelay = 30 * time.Second
+
+var (
+	ErrInvalidSources = errcode.New(errcode.Invalid, "oauth2: invalid token sources")
+	ErrSourceNotFound = errcode.New(errcode.NotFound, "oauth2: token source not found")
+)
+
+type GrantType string
+
+const (
+	GrantTypeClientCredentials GrantType = "client_credentials"
+	GrantTypePassword          GrantType = "password"
+)
+
+// Source fetches access tokens from the token endpoint of an authorization
+// server.
+type Source struct {
+	Name string
+	// Variable that is set to the access token, e.g. `accessToken`.
+	Variable  string
+	TokenURL  string
+	GrantType GrantType
+	// Client credentials are sent with HTTP Basic authentication, unless
+	// ClientCredentialsInBody is true.
+	ClientID                string
+	ClientSecret            string
+	ClientCredentialsInBody bool
+	// Resource owner credentials, for the password grant.
+	Username string
+	Password string
+	Scopes   []string
+	// Interval between fetches. If zero, tokens are fetched again shortly
+	// before they expire, or only once if the server doesn't return when.
+	RefreshInterval time.Duration
+	// Tokens aren't fetched automatically for paused sources, only with
+	// `Service.FetchToken`.
+	Paused bool
+}
+
+// Token is the last token fetched for a source.
+type Token struct {
+	Source      string
+	Variable    string
+	AccessToken string
+	TokenType   string
+	// Zero if the server didn't return when the token expires.
+	ExpiresAt time.Time
+	FetchedAt time.Time
+	// Error of the last fetch, if it failed. The access token of the previous
+	// fetch, if any, is kept.
+	Err error
+}
+
+type Service interface {
+	SetSources(sources []Source)
+	Sources() []Source
+	Tokens() []Token
+	FetchToken(ctx context.Context, source string) (Token, error)
+}
+
+type service struct {
+	// mu guards the sources and their tokens, which are changed at runtime.
+	mu      sync.RWMutex
+	sources []Source
+	tokens  map[string]Token
+	cancel  context.CancelFunc
+	// Incremented when the sources are replaced, so that tokens fetched for
+	// previous sources are discarded.
+	generation int
+
+	httpClient *http.Client
+	events     *event.Bus
+}
+
+type Config struct {
+	HTTPClient *http.Client
+	// Bus that `event.TypeOAuth2TokensChanged` is published on. Optional.
+	Events *event.Bus
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func NewService(cfg Config) Service {
+	svc := &service{
+		tokens:     make(map[string]Token),
+		httpClient: defaultHTTPClient,
+		events:     cfg.Events,
+	}
+
+	if cfg.HTTPClient != nil {
+		svc.httpClient = cfg.HTTPClient
+	}
+
+	return svc
+}
+
+// Validate returns an error if a source can't be used to fetch tokens.
+func (source Source) Validate() error {
+	if source.Name == "" {
+		return fmt.Errorf("%w: name must not be empty", ErrInvalidSources)
+	}
+
+	if source.Variable == "" {
+		return fmt.Errorf("%w: variable of source %q must not be empty", ErrInvalidSources, source.Name)
+	}
+
+	u, err := url.Parse(source.TokenURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: token URL of source %q must be an absolute HTTP(S) URL", ErrInvalidSources, source.Name)
+	}
+
+	switch source.GrantType {
+	case GrantTypeClientCredentials:
+	case GrantTypePassword:
+		if source.Username == "" {
+			return fmt.Errorf("%w: username of source %q must not be empty", ErrInvalidSources, source.Name)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported grant type %q", ErrInvalidSources, source.GrantType)
+	}
+
+	if source.RefreshInterval < 0 {
+		return fmt.Errorf("%w: refresh interval of source %q must not be negative", ErrInvalidSources, source.Name)
+	}
+
+	return nil
+}
+
+// ValidateSources validates sources, which must have unique names.
+func ValidateSources(sources []Source) error {
+	names := make(map[string]bool, len(sources))
+
+	for _, source := range sources {
+		if err := source.Validate(); err != nil {
+			return err
+		}
+
+		if names[source.Name] {
+			return fmt.Errorf("%w: duplicate name %q", ErrInvalidSources, source.Name)
+		}
+
+		names[source.Name] = true
+	}
+
+	return nil
+}
+
+// SetSources replaces the sources that tokens are fetched for. Tokens of the
+// previous sources are discarded, and a token is fetched for each source that
+// isn't paused.
+func (svc *service) SetSources(sources []Source) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	svc.mu.Lock()
+
+	if svc.cancel != nil {
+		svc.cancel()
+	}
+
+	svc.sources = sources
+	svc.tokens = make(map[string]Token, len(sources))
+	svc.cancel = cancel
+	svc.generation++
+	generation := svc.generation
+
+	svc.mu.Unlock()
+
+	for _, source := range sources {
+		if source.Paused {
+			continue
+		}
+
+		go svc.schedule(ctx, source, generation)
+	}
+
+	svc.publish()
+}
+
+func (svc *service) Sources() []Source {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.sources
+}
+
+// Tokens returns the last token fetched for each source, in the order of the
+// sources. Sources without a fetch are omitted.
+func (svc *service) Tokens() []Token {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	tokens := make([]Token, 0, len(svc.tokens))
+
+	for _, source := range svc.sources {
+		if token, ok := svc.tokens[source.Name]; ok {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// FetchToken fetches a token for a source now, e.g. after its credentials were
+// revoked.
+func (svc *service) FetchToken(ctx context.Context, name string) (Token, error) {
+	source, generation, ok := svc.findSource(name)
+	if !ok {
+		return Token{}, ErrSourceNotFound
+	}
+
+	token := svc.fetch(ctx, source, generation)
+
+	return token, token.Err
+}
+
+func (svc *service) findSource(name string) (Source, int, bool) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	for _, source := range svc.sources {
+		if source.Name == name {
+			return source, svc.generation, true
+		}
+	}
+
+	return Source{}, 0, false
+}
+
+// schedule fetches tokens for source until ctx is done.
+func (svc *service) schedule(ctx context.Context, source Source, generation int) {
+	for {
+		token := svc.fetch(ctx, source, generation)
+		if ctx.Err() != nil {
+			return
+		}
+
+		var delay time.Duration
+
+		switch {
+		case token.Err != nil:
+			log.Printf("[ERROR] Could not fetch OAuth 2.0 token (source: %v): %v", source.Name, token.Err)
+
+			delay = retryDelay
+			if source.RefreshInterval > 0 && source.RefreshInterval < delay {
+				delay = source.RefreshInterval
+			}
+		case source.RefreshInterval > 0:
+			delay = source.RefreshInterval
+		case !token.ExpiresAt.IsZero():
+			// Refresh a minute before the token expires, or halfway for
+			// short-lived tokens.
+			lifetime := token.ExpiresAt.Sub(token.FetchedAt)
+
+			delay = lifetime - time.Minute
+			if lifetime < 2*time.Minute {
+				delay = lifetime / 2
+			}
+		default:
+			return
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// fetch fetches a token for source and stores it, unless the sources were
+// replaced in the meantime.
+func (svc *service) fetch(ctx context.Context, source Source, generation int) Token {
+	token, err := svc.requestToken(ctx, source)
+
+	svc.mu.Lock()
+
+	prev, ok := svc.tokens[source.Name]
+	if err != nil {
+		token = prev
+		token.Source = source.Name
+		token.Variable = source.Variable
+		token.Err = err
+	}
+
+	current := generation == svc.generation
+	if current {
+		svc.tokens[source.Name] = token
+	}
+
+	svc.mu.Unlock()
+
+	if current && (!ok || prev.AccessToken != token.AccessToken || err != nil) {
+		svc.publish()
+	}
+
+	return token
+}
+
+func (svc *service) requestToken(ctx context.Context, source Source) (Token, error) {
+	form := url.Values{"grant_type": []string{string(source.GrantType)}}
+
+	if len(source.Scopes) > 0 {
+		form.Set("scope", strings.Join(source.Scopes, " "))
+	}
+
+	if source.GrantType == GrantTypePassword {
+		form.Set("username", source.Username)
+		form.Set("password", source.Password)
+	}
+
+	if source.ClientCredentialsInBody {
+		form.Set("client_id", source.ClientID)
+
+		if source.ClientSecret != "" {
+			form.Set("client_secret", source.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, source.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, fmt.Errorf("oauth2: failed to create token request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if !source.ClientCredentialsInBody && source.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(source.ClientID), url.QueryEscape(source.ClientSecret))
+	}
+
+	fetchedAt := time.Now()
+
+	res, err := svc.httpClient.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("oauth2: failed to send token request: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return Token{}, fmt.Errorf("oauth2: failed to read token response: %w", err)
+	}
+
+	var tokenRes tokenResponse
+
+	if err := json.Unmarshal(body, &tokenRes); err != nil {
+		return Token{}, fmt.Errorf("oauth2: invalid token response (status: %v): %w", res.StatusCode, err)
+	}
+
+	if res.StatusCode != http.StatusOK || tokenRes.Error != "" {
+		return Token{}, fmt.Errorf("oauth2: token request failed (status: %v): %v %v",
+			res.StatusCode, tokenRes.Error, tokenRes.ErrorDescription)
+	}
+
+	if tokenRes.AccessToken == "" {
+		return Token{}, errors.New("oauth2: token response has no access token")
+	}
+
+	token := Token{
+		Source:      source.Name,
+		Variable:    source.Variable,
+		AccessToken: tokenRes.AccessToken,
+		TokenType:   tokenRes.TokenType,
+		FetchedAt:   fetchedAt,
+	}
+
+	if tokenRes.ExpiresIn > 0 {
+		token.ExpiresAt = fetchedAt.Add(time.Duration(tokenRes.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+func (svc *service) publish() {
+	svc.events.Publish(event.Event{Type: event.TypeOAuth2TokensChanged})
+}
@@ -0,0 +1,282 @@
+package oauth2_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/oauth2"
+)
+
+func newTokenServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var fetches int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok {
+			clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+
+		valid := clientID == "hetty" && clientSecret == "s3cret"
+		if r.PostForm.Get("grant_type") == "password" {
+			valid = valid && r.PostForm.Get("username") == "alice" && r.PostForm.Get("password") == "p4ss"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if !valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "invalid_client"}`))
+
+			return
+		}
+
+		n := atomic.AddInt32(&fetches, 1)
+		fmt.Fprintf(w, `{"access_token": "token-%v-%v", "token_type": "Bearer", "expires_in": 3600}`,
+			r.PostForm.Get("scope"), n)
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts, &fetches
+}
+
+func TestFetchToken(t *testing.T) {
+	t.Parallel()
+
+	ts, _ := newTokenServer(t)
+
+	tests := []struct {
+		name     string
+		source   oauth2.Source
+		expScope string
+		expErr   bool
+	}{
+		{
+			name: "client credentials",
+			source: oauth2.Source{
+				GrantType:    oauth2.GrantTypeClientCredentials,
+				ClientID:     "hetty",
+				ClientSecret: "s3cret",
+				Scopes:       []string{"read", "write"},
+			},
+			expScope: "read write",
+		},
+		{
+			name: "password with client credentials in body",
+			source: oauth2.Source{
+				GrantType:               oauth2.GrantTypePassword,
+				ClientID:                "hetty",
+				ClientSecret:            "s3cret",
+				ClientCredentialsInBody: true,
+				Username:                "alice",
+				Password:                "p4ss",
+			},
+		},
+		{
+			name: "invalid credentials",
+			source: oauth2.Source{
+				GrantType: oauth2.GrantTypeClientCredentials,
+				ClientID:  "hetty",
+			},
+			expErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.source.Name = "api"
+			tt.source.Variable = "accessToken"
+			tt.source.TokenURL = ts.URL
+
+			if err := oauth2.ValidateSources([]oauth2.Source{tt.source}); err != nil {
+				t.Fatalf("unexpected error validating source: %v", err)
+			}
+
+			svc := oauth2.NewService(oauth2.Config{})
+			svc.SetSources([]oauth2.Source{tt.source})
+
+			token, err := svc.FetchToken(context.Background(), "api")
+			if tt.expErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if token.Variable != "accessToken" || token.TokenType != "Bearer" || token.ExpiresAt.IsZero() {
+				t.Fatalf("unexpected token: %+v", token)
+			}
+
+			if prefix := "token-" + tt.expScope + "-"; !strings.HasPrefix(token.AccessToken, prefix) {
+				t.Fatalf("expected access token with prefix %q, got: %q", prefix, token.AccessToken)
+			}
+		})
+	}
+}
+
+func TestScheduledFetches(t *testing.T) {
+	t.Parallel()
+
+	ts, fetches := newTokenServer(t)
+
+	events := event.NewBus()
+	changed := make(chan struct{}, 10)
+
+	events.Subscribe(func(event.Event) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}, event.TypeOAuth2TokensChanged)
+
+	svc := oauth2.NewService(oauth2.Config{Events: events})
+	svc.SetSources([]oauth2.Source{
+		{
+			Name:            "api",
+			Variable:        "accessToken",
+			TokenURL:        ts.URL,
+			GrantType:       oauth2.GrantTypeClientCredentials,
+			ClientID:        "hetty",
+			ClientSecret:    "s3cret",
+			RefreshInterval: 10 * time.Millisecond,
+		},
+	})
+
+	timeout := time.After(5 * time.Second)
+
+	for atomic.LoadInt32(fetches) < 3 {
+		select {
+		case <-changed:
+		case <-timeout:
+			t.Fatalf("expected at least 3 fetches, got: %v", atomic.LoadInt32(fetches))
+		}
+	}
+
+	tokens := svc.Tokens()
+	if len(tokens) != 1 || tokens[0].AccessToken == "" {
+		t.Fatalf("expected a token, got: %+v", tokens)
+	}
+
+	// Replacing the sources stops fetches and discards tokens.
+	svc.SetSources(nil)
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := atomic.LoadInt32(fetches)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if got := atomic.LoadInt32(fetches); got != stopped {
+		t.Fatalf("expected no fetches after replacing sources, got: %v", got-stopped)
+	}
+
+	if tokens := svc.Tokens(); len(tokens) != 0 {
+		t.Fatalf("expected no tokens, got: %+v", tokens)
+	}
+
+	if _, err := svc.FetchToken(context.Background(), "api"); !errors.Is(err, oauth2.ErrSourceNotFound) {
+		t.Fatalf("expected `oauth2.ErrSourceNotFound`, got: %v", err)
+	}
+
+	// Tokens of paused sources are only fetched on demand.
+	svc.SetSources([]oauth2.Source{
+		{
+			Name:            "api",
+			Variable:        "accessToken",
+			TokenURL:        ts.URL,
+			GrantType:       oauth2.GrantTypeClientCredentials,
+			ClientID:        "hetty",
+			ClientSecret:    "s3cret",
+			RefreshInterval: 10 * time.Millisecond,
+			Paused:          true,
+		},
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	if got := atomic.LoadInt32(fetches); got != stopped {
+		t.Fatalf("expected no fetches for paused source, got: %v", got-stopped)
+	}
+
+	if _, err := svc.FetchToken(context.Background(), "api"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateSources(t *testing.T) {
+	t.Parallel()
+
+	valid := oauth2.Source{
+		Name:      "api",
+		Variable:  "accessToken",
+		TokenURL:  "https://auth.example.com/token",
+		GrantType: oauth2.GrantTypeClientCredentials,
+	}
+
+	tests := []struct {
+		name    string
+		sources func() []oauth2.Source
+	}{
+		{
+			name:    "duplicate name",
+			sources: func() []oauth2.Source { return []oauth2.Source{valid, valid} },
+		},
+		{
+			name: "relative token URL",
+			sources: func() []oauth2.Source {
+				s := valid
+				s.TokenURL = "/token"
+				return []oauth2.Source{s}
+			},
+		},
+		{
+			name: "unsupported grant type",
+			sources: func() []oauth2.Source {
+				s := valid
+				s.GrantType = "implicit"
+				return []oauth2.Source{s}
+			},
+		},
+		{
+			name: "password grant without username",
+			sources: func() []oauth2.Source {
+				s := valid
+				s.GrantType = oauth2.GrantTypePassword
+				return []oauth2.Source{s}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := oauth2.ValidateSources(tt.sources()); !errors.Is(err, oauth2.ErrInvalidSources) {
+				t.Fatalf("expected `oauth2.ErrInvalidSources`, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// Package origin protects the admin interface and APIs against cross-origin
+// requests: requests from other origins than the admin interface itself are
+// rejected, unless their origin is allowed. Allowed origins can use the APIs
+// via CORS, and embed the admin interface in a frame.
+package origin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var ErrInvalidPattern = errors.New("origin: invalid pattern")
+
+// Pattern matches origins, e.g. `https://ui.example.com`. The host can start
+// with a wildcard label to match its subdomains, e.g. `https://*.example.com`.
+// Without a port, only the default port of the scheme matches.
+type Pattern struct {
+	Scheme string
+	Host   string
+	Port   string
+}
+
+// ParsePattern parses an origin pattern, e.g. `https://*.example.com:8443`.
+func ParsePattern(s string) (Pattern, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Pattern{}, fmt.Errorf("%w: %v", ErrInvalidPattern, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Pattern{}, fmt.Errorf("%w: scheme must be http or https, got %q", ErrInvalidPattern, s)
+	}
+
+	if u.Host == "" || u.User != nil || strings.TrimSuffix(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		return Pattern{}, fmt.Errorf("%w: must be a scheme and host only, got %q", ErrInvalidPattern, s)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+		return Pattern{}, fmt.Errorf("%w: wildcard must be the first label, got %q", ErrInvalidPattern, s)
+	}
+
+	return Pattern{Scheme: u.Scheme, Host: host, Port: u.Port()}, nil
+}
+
+func (p Pattern) String() string {
+	host := p.Host
+	if p.Port != "" {
+		host = net.JoinHostPort(host, p.Port)
+	}
+
+	return p.Scheme + "://" + host
+}
+
+// Match returns true if origin matches the pattern.
+func (p Pattern) Match(origin *url.URL) bool {
+	if !strings.EqualFold(origin.Scheme, p.Scheme) || origin.Port() != p.Port {
+		return false
+	}
+
+	host := strings.ToLower(origin.Hostname())
+
+	if strings.HasPrefix(p.Host, "*.") {
+		return strings.HasSuffix(host, p.Host[1:]) && len(host) > len(p.Host)-1
+	}
+
+	return host == p.Host
+}
+
+type Config struct {
+	// Origins that can use the APIs from a browser, and embed the admin
+	// interface. The origin of the admin interface itself is always allowed.
+	AllowedOrigins []Pattern
+}
+
+const (
+	allowedMethods = "GET, POST, PUT, PATCH, DELETE"
+	allowedHeaders = "Authorization, Content-Type"
+)
+
+// Handler rejects cross-origin requests to h that aren't allowed by cfg. To
+// protect against cross-site request forgery, state-changing requests without
+// an `Origin` header are rejected when the browser marks them as cross-site
+// (via `Sec-Fetch-Site`). Requests of other clients than browsers (e.g. curl),
+// which don't send these headers, are allowed.
+func Handler(cfg Config, h http.Handler) http.Handler {
+	frameAncestors := []string{"'self'"}
+	for _, p := range cfg.AllowedOrigins {
+		frameAncestors = append(frameAncestors, p.String())
+	}
+
+	csp := "frame-ancestors " + strings.Join(frameAncestors, " ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Content-Security-Policy", csp)
+
+		origin := r.Header.Get("Origin")
+
+		if origin == "" {
+			if !isSafeMethod(r.Method) && isCrossSite(r) {
+				http.Error(w, "Cross-site request not allowed.", http.StatusForbidden)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+
+			return
+		}
+
+		originURL, err := url.Parse(origin)
+		if err != nil || originURL.Host == "" {
+			http.Error(w, "Origin not allowed.", http.StatusForbidden)
+			return
+		}
+
+		if strings.EqualFold(originURL.Host, r.Host) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if !matchAny(cfg.AllowedOrigins, originURL) {
+			http.Error(w, "Origin not allowed.", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+
+		// Preflight request.
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func matchAny(patterns []Pattern, origin *url.URL) bool {
+	for _, p := range patterns {
+		if p.Match(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// isCrossSite returns true if the browser marks r as a request of another
+// site, or another origin of the same site.
+func isCrossSite(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "cross-site", "same-site":
+		return true
+	default:
+		return false
+	}
+}
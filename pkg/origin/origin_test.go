@@ -0,0 +1,154 @@
+package origin_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/origin"
+)
+
+func TestParsePattern(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input  string
+		exp    string
+		expErr error
+	}{
+		{input: "https://ui.example.com", exp: "https://ui.example.com"},
+		{input: "https://*.Example.com:8443/", exp: "https://*.example.com:8443"},
+		{input: "ftp://example.com", expErr: origin.ErrInvalidPattern},
+		{input: "https://example.com/admin", expErr: origin.ErrInvalidPattern},
+		{input: "https://ui.*.example.com", expErr: origin.ErrInvalidPattern},
+		{input: "example.com", expErr: origin.ErrInvalidPattern},
+	}
+
+	for _, tt := range tests {
+		p, err := origin.ParsePattern(tt.input)
+		if !errors.Is(err, tt.expErr) {
+			t.Errorf("%q: expected error %v, got: %v", tt.input, tt.expErr, err)
+			continue
+		}
+
+		if err == nil && p.String() != tt.exp {
+			t.Errorf("%q: expected %v, got: %v", tt.input, tt.exp, p)
+		}
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	t.Parallel()
+
+	p, err := origin.ParsePattern("https://*.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for s, exp := range map[string]bool{
+		"https://ui.example.com":      true,
+		"https://a.b.example.com":     true,
+		"https://example.com":         false,
+		"https://evilexample.com":     false,
+		"http://ui.example.com":       false,
+		"https://ui.example.com:8443": false,
+	} {
+		u, _ := url.Parse(s)
+		if got := p.Match(u); got != exp {
+			t.Errorf("%v: expected match to be %v, got: %v", s, exp, got)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	allowed, err := origin.ParsePattern("https://ui.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	h := origin.Handler(origin.Config{AllowedOrigins: []origin.Pattern{allowed}},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	tests := []struct {
+		name           string
+		method         string
+		header         http.Header
+		expStatus      int
+		expAllowOrigin string
+	}{
+		{
+			name:      "no origin",
+			method:    http.MethodPost,
+			expStatus: http.StatusOK,
+		},
+		{
+			name:      "same origin",
+			method:    http.MethodPost,
+			header:    http.Header{"Origin": {"http://hetty.proxy"}},
+			expStatus: http.StatusOK,
+		},
+		{
+			name:           "allowed origin",
+			method:         http.MethodPost,
+			header:         http.Header{"Origin": {"https://ui.example.com"}},
+			expStatus:      http.StatusOK,
+			expAllowOrigin: "https://ui.example.com",
+		},
+		{
+			name:   "preflight of allowed origin",
+			method: http.MethodOptions,
+			header: http.Header{
+				"Origin":                        {"https://ui.example.com"},
+				"Access-Control-Request-Method": {"POST"},
+			},
+			expStatus:      http.StatusNoContent,
+			expAllowOrigin: "https://ui.example.com",
+		},
+		{
+			name:      "other origin",
+			method:    http.MethodPost,
+			header:    http.Header{"Origin": {"https://evil.example.org"}},
+			expStatus: http.StatusForbidden,
+		},
+		{
+			name:      "cross-site request without origin",
+			method:    http.MethodPost,
+			header:    http.Header{"Sec-Fetch-Site": {"cross-site"}},
+			expStatus: http.StatusForbidden,
+		},
+		{
+			name:      "cross-site navigation",
+			method:    http.MethodGet,
+			header:    http.Header{"Sec-Fetch-Site": {"cross-site"}},
+			expStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "http://hetty.proxy/api/graphql/", nil)
+		for k, v := range tt.header {
+			req.Header[k] = v
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.expStatus {
+			t.Errorf("%v: expected status %v, got: %v", tt.name, tt.expStatus, rec.Code)
+		}
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.expAllowOrigin {
+			t.Errorf("%v: expected allowed origin %q, got: %q", tt.name, tt.expAllowOrigin, got)
+		}
+
+		if got := rec.Header().Get("Content-Security-Policy"); got != "frame-ancestors 'self' https://ui.example.com" {
+			t.Errorf("%v: unexpected content security policy: %v", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package pac
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+// ContentType is the media type of proxy auto-config files.
+const ContentType = "application/x-ns-proxy-autoconfig"
+
+// Generate returns a proxy auto-config (PAC) file that routes requests matching
+// the URL scope rules through the proxy at proxyAddr, and all other requests
+// directly.
+//
+// Rules that can't be evaluated by a PAC file (those with only header or body
+// conditions, or with a URL pattern that isn't compatible with JavaScript) could
+// match any request, so if any of these exist, all requests are routed through
+// the proxy. The same goes for when there are no scope rules at all.
+//
+// Note that browsers typically only pass the scheme and host of HTTPS URLs to
+// `FindProxyForURL`, so URL patterns that match on paths won't match these.
+func Generate(proxyAddr string, rules []scope.Rule) []byte {
+	patterns, ok := jsPatterns(rules)
+
+	proxyAddrJSON, _ := json.Marshal(proxyAddr)
+
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "// Generated by Hetty.\nfunction FindProxyForURL(url, host) {\n")
+	fmt.Fprintf(buf, "  var proxy = \"PROXY \" + %s;\n", proxyAddrJSON)
+
+	if !ok {
+		fmt.Fprintf(buf, "  return proxy;\n}\n")
+		return buf.Bytes()
+	}
+
+	fmt.Fprintf(buf, "  var patterns = [\n")
+
+	for _, p := range patterns {
+		fmt.Fprintf(buf, "    %s,\n", p)
+	}
+
+	fmt.Fprintf(buf, "  ];\n")
+	fmt.Fprintf(buf, "  for (var i = 0; i < patterns.length; i++) {\n")
+	fmt.Fprintf(buf, "    if (patterns[i].test(url)) {\n      return proxy;\n    }\n  }\n")
+	fmt.Fprintf(buf, "  return \"DIRECT\";\n}\n")
+
+	return buf.Bytes()
+}
+
+// Handler returns a handler that serves a PAC file for the current scope rules.
+// The proxy address in the file is the host that was used to fetch it, so that
+// it works for any client that can reach Hetty. If that host has no port,
+// defaultPort is used.
+func Handler(s *scope.Scope, defaultPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyAddr := r.Host
+		if _, _, err := net.SplitHostPort(proxyAddr); err != nil {
+			proxyAddr = net.JoinHostPort(strings.Trim(proxyAddr, "[]"), defaultPort)
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(Generate(proxyAddr, s.Rules()))
+	})
+}
+
+// jsPatterns returns JavaScript `RegExp` literals for the URL patterns of rules.
+// It reports false if any rule can't be converted.
+func jsPatterns(rules []scope.Rule) ([]string, bool) {
+	if len(rules) == 0 {
+		return nil, false
+	}
+
+	patterns := make([]string, 0, len(rules))
+
+	for _, rule := range rules {
+		if rule.URL == nil {
+			return nil, false
+		}
+
+		pattern := rule.URL.String()
+		flags := ""
+
+		if strings.HasPrefix(pattern, "(?i)") {
+			pattern = strings.TrimPrefix(pattern, "(?i)")
+			flags = "i"
+		}
+
+		// Other flag groups, named groups and `\z` are not supported by
+		// JavaScript regular expressions.
+		if strings.Contains(pattern, "(?") || strings.Contains(pattern, `\z`) {
+			return nil, false
+		}
+
+		patternJSON, _ := json.Marshal(pattern)
+		patterns = append(patterns, fmt.Sprintf("new RegExp(%s, %q)", patternJSON, flags))
+	}
+
+	return patterns, true
+}
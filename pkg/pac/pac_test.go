@@ -0,0 +1,84 @@
+package pac_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dstotijn/hetty/pkg/pac"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	proxyAll := `// Generated by Hetty.
+function FindProxyForURL(url, host) {
+  var proxy = "PROXY " + "127.0.0.1:8080";
+  return proxy;
+}
+`
+
+	tests := []struct {
+		name  string
+		rules []scope.Rule
+		exp   string
+	}{
+		{
+			name:  "no rules",
+			rules: nil,
+			exp:   proxyAll,
+		},
+		{
+			name: "url rules",
+			rules: []scope.Rule{
+				{URL: regexp.MustCompile(`^https?://(www\.)?example\.com`)},
+				{URL: regexp.MustCompile(`(?i)foo"bar`)},
+			},
+			exp: `// Generated by Hetty.
+function FindProxyForURL(url, host) {
+  var proxy = "PROXY " + "127.0.0.1:8080";
+  var patterns = [
+    new RegExp("^https?://(www\\.)?example\\.com", ""),
+    new RegExp("foo\"bar", "i"),
+  ];
+  for (var i = 0; i < patterns.length; i++) {
+    if (patterns[i].test(url)) {
+      return proxy;
+    }
+  }
+  return "DIRECT";
+}
+`,
+		},
+		{
+			name: "header rule",
+			rules: []scope.Rule{
+				{URL: regexp.MustCompile(`example\.com`)},
+				{Header: scope.Header{Key: regexp.MustCompile(`X-Foo`)}},
+			},
+			exp: proxyAll,
+		},
+		{
+			name: "unsupported url pattern",
+			rules: []scope.Rule{
+				{URL: regexp.MustCompile(`(?s)example\.com`)},
+			},
+			exp: proxyAll,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := string(pac.Generate("127.0.0.1:8080", tt.rules))
+			if diff := cmp.Diff(tt.exp, got); diff != "" {
+				t.Fatalf("PAC file not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -5,22 +5,24 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"regexp"
 	"sync"
 	"time"
 
 	"github.com/oklog/ulid"
 
+	"github.com/dstotijn/hetty/pkg/authz"
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/oauth2"
 	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
 	"github.com/dstotijn/hetty/pkg/scope"
 	"github.com/dstotijn/hetty/pkg/search"
 	"github.com/dstotijn/hetty/pkg/sender"
 )
 
-//nolint:gosec
-var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
-
 type (
 	OnProjectOpenFn  func(projectID ulid.ULID) error
 	OnProjectCloseFn func(projectID ulid.ULID) error
@@ -30,17 +32,36 @@ type (
 type Service interface {
 	CreateProject(ctx context.Context, name string) (Project, error)
 	OpenProject(ctx context.Context, projectID ulid.ULID) (Project, error)
+	OpenProjectReadOnly(ctx context.Context, projectID ulid.ULID) (Project, error)
 	CloseProject() error
 	DeleteProject(ctx context.Context, projectID ulid.ULID) error
 	ActiveProject(ctx context.Context) (Project, error)
 	IsProjectActive(projectID ulid.ULID) bool
+	IsReadOnly() bool
 	Projects(ctx context.Context) ([]Project, error)
 	Scope() *scope.Scope
 	SetScopeRules(ctx context.Context, rules []scope.Rule) error
+	ApplyHostActions(ctx context.Context, host string, actions HostActions) error
 	SetRequestLogFindFilter(ctx context.Context, filter reqlog.FindRequestsFilter) error
 	SetSenderRequestFindFilter(ctx context.Context, filter sender.FindRequestsFilter) error
+	SetSenderEnvironments(ctx context.Context, envs sender.Environments) error
+	SetSenderSigningProfiles(ctx context.Context, profiles sender.SigningProfiles) error
+	SetSenderSchedules(ctx context.Context, schedules []sender.Schedule) error
+	SetOAuth2Sources(ctx context.Context, sources []oauth2.Source) error
+	SetAuthzSettings(ctx context.Context, settings authz.Settings) error
+	SetRequestLogBodyRules(ctx context.Context, rules reqlog.BodyRules) error
+	SetCapturePaused(ctx context.Context, projectID ulid.ULID, paused bool) error
+	SetRequestLogSampling(ctx context.Context, sampling reqlog.Sampling) error
+	SetRequestLogColumns(ctx context.Context, columns []reqlog.Column) error
+	Rewriter() *rewrite.Rewriter
+	SetRewritePresets(ctx context.Context, presets rewrite.Presets) error
+	SetRewriteProfiles(ctx context.Context, profiles rewrite.Profiles) error
+	SetRewriteLocalMappings(ctx context.Context, mappings rewrite.LocalMappings) error
+	SetRewriteRemoteMappings(ctx context.Context, mappings rewrite.RemoteMappings) error
 	OnProjectOpen(fn OnProjectOpenFn)
 	OnProjectClose(fn OnProjectCloseFn)
+	SetClientRoutes(ctx context.Context, routes []ClientRoute) error
+	ClientRoutes() []ClientRoute
 }
 
 type service struct {
@@ -48,7 +69,13 @@ type service struct {
 	reqLogSvc         reqlog.Service
 	senderSvc         sender.Service
 	scope             *scope.Scope
+	ids               idgen.Generator
+	rewriter          *rewrite.Rewriter
+	oauth2Svc         oauth2.Service
+	authzSvc          authz.Service
+	events            *event.Bus
 	activeProjectID   ulid.ULID
+	readOnly          bool
 	onProjectOpenFns  []OnProjectOpenFn
 	onProjectCloseFns []OnProjectCloseFn
 	mu                sync.RWMutex
@@ -63,21 +90,53 @@ type Project struct {
 }
 
 type Settings struct {
-	ReqLogBypassOutOfScope bool
-	ReqLogOnlyFindInScope  bool
-	ReqLogSearchExpr       search.Expression
+	ReqLogBypassOutOfScope  bool
+	ReqLogOnlyFindInScope   bool
+	ReqLogSearchExpr        search.Expression
+	ReqLogCollapseRedirects bool
+	ReqLogCollapsePageLoads bool
+	ReqLogFilterPresets     reqlog.FilterPresets
+	ReqLogBodyRules         reqlog.BodyRules
+	ReqLogSampling          reqlog.Sampling
+	// Custom columns of the request log table.
+	ReqLogColumns []reqlog.Column
+	// Proxied requests are passed through without being logged.
+	CapturePaused bool
 
 	SenderOnlyFindInScope bool
 	SenderSearchExpr      search.Expression
+	SenderEnvironments    sender.Environments
+	SenderSigningProfiles sender.SigningProfiles
+	SenderSchedules       []sender.Schedule
+
+	OAuth2Sources []oauth2.Source
+
+	AuthzSettings authz.Settings
 
 	ScopeRules []scope.Rule
+
+	RewritePresets  rewrite.Presets
+	RewriteProfiles rewrite.Profiles
+	// Local files or content served for matching proxied requests.
+	RewriteLocalMappings rewrite.LocalMappings
+	// Other origins or paths that matching proxied requests are sent to.
+	RewriteRemoteMappings rewrite.RemoteMappings
+}
+
+// ClientRoute logs the requests of matching clients to a project other than
+// the active one, see `reqlog.ClientRoute`.
+type ClientRoute struct {
+	ClientIP  string
+	Username  string
+	ProjectID ulid.ULID
 }
 
 var (
-	ErrProjectNotFound = errors.New("proj: project not found")
+	ErrProjectNotFound = errcode.New(errcode.NotFound, "proj: project not found")
 	ErrNoProject       = errors.New("proj: no open project")
 	ErrNoSettings      = errors.New("proj: settings not found")
-	ErrInvalidName     = errors.New("proj: invalid name, must be alphanumeric or whitespace chars")
+	ErrInvalidName     = errcode.New(errcode.Invalid, "proj: invalid name, must be alphanumeric or whitespace chars")
+	ErrReadOnly        = errors.New("proj: project is opened read-only")
 )
 
 var nameRegexp = regexp.MustCompile(`^[\w\d\s]+$`)
@@ -87,25 +146,49 @@ type Config struct {
 	ReqLogService reqlog.Service
 	SenderService sender.Service
 	Scope         *scope.Scope
+	Rewriter      *rewrite.Rewriter
+	// Service that fetches the OAuth 2.0 tokens of the active project.
+	// Optional.
+	OAuth2Service oauth2.Service
+	// Service that replays proxied requests with the credentials of a second
+	// user. Optional.
+	AuthzService authz.Service
+	// Bus for publishing project opened and closed events. Optional.
+	Events *event.Bus
+	// Generates the IDs of projects. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
 }
 
 // NewService returns a new Service.
 func NewService(cfg Config) (Service, error) {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
 	return &service{
+		ids:       cfg.IDGenerator,
 		repo:      cfg.Repository,
 		reqLogSvc: cfg.ReqLogService,
 		senderSvc: cfg.SenderService,
 		scope:     cfg.Scope,
+		rewriter:  cfg.Rewriter,
+		oauth2Svc: cfg.OAuth2Service,
+		authzSvc:  cfg.AuthzService,
+		events:    cfg.Events,
 	}, nil
 }
 
 func (svc *service) CreateProject(ctx context.Context, name string) (Project, error) {
+	if svc.readOnly {
+		return Project{}, ErrReadOnly
+	}
+
 	if !nameRegexp.MatchString(name) {
 		return Project{}, ErrInvalidName
 	}
 
 	project := Project{
-		ID:   ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ID:   svc.ids.New(time.Now()),
 		Name: name,
 	}
 
@@ -129,12 +212,33 @@ func (svc *service) CloseProject() error {
 	closedProjectID := svc.activeProjectID
 
 	svc.activeProjectID = ulid.ULID{}
+	svc.readOnly = false
 	svc.reqLogSvc.SetActiveProjectID(ulid.ULID{})
+	svc.reqLogSvc.SetReadOnly(false)
 	svc.reqLogSvc.SetBypassOutOfScopeRequests(false)
 	svc.reqLogSvc.SetFindReqsFilter(reqlog.FindRequestsFilter{})
+	svc.reqLogSvc.SetBodyRules(reqlog.BodyRules{})
+	_ = svc.reqLogSvc.SetSampling(reqlog.Sampling{})
+	_ = svc.reqLogSvc.SetColumns(nil)
 	svc.senderSvc.SetActiveProjectID(ulid.ULID{})
+	svc.senderSvc.SetReadOnly(false)
 	svc.senderSvc.SetFindReqsFilter(sender.FindRequestsFilter{})
+	svc.senderSvc.SetEnvironments(sender.Environments{})
+	svc.senderSvc.SetSigningProfiles(nil)
+	svc.senderSvc.SetSchedules(nil)
 	svc.scope.SetRules(nil)
+	svc.rewriter.SetPresets(rewrite.Presets{})
+	svc.rewriter.SetProfiles(rewrite.Profiles{})
+	svc.rewriter.SetLocalMappings(nil)
+	svc.rewriter.SetRemoteMappings(nil)
+
+	if svc.oauth2Svc != nil {
+		svc.oauth2Svc.SetSources(nil)
+	}
+
+	if svc.authzSvc != nil {
+		svc.authzSvc.SetSettings(authz.Settings{})
+	}
 
 	svc.emitProjectClosed(closedProjectID)
 
@@ -143,6 +247,10 @@ func (svc *service) CloseProject() error {
 
 // DeleteProject removes a project from the repository.
 func (svc *service) DeleteProject(ctx context.Context, projectID ulid.ULID) error {
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
 	if svc.activeProjectID.Compare(projectID) == 0 {
 		return fmt.Errorf("proj: project (%v) is active", projectID.String())
 	}
@@ -151,11 +259,44 @@ func (svc *service) DeleteProject(ctx context.Context, projectID ulid.ULID) erro
 		return fmt.Errorf("proj: could not delete project: %w", err)
 	}
 
+	// Requests of clients that were routed to the project are logged to the
+	// active project again.
+	routes := svc.reqLogSvc.ClientRoutes()
+	remaining := make([]reqlog.ClientRoute, 0, len(routes))
+
+	for _, route := range routes {
+		if route.ProjectID.Compare(projectID) != 0 {
+			remaining = append(remaining, route)
+		}
+	}
+
+	if len(remaining) != len(routes) {
+		if err := svc.reqLogSvc.SetClientRoutes(remaining); err != nil {
+			return fmt.Errorf("proj: could not remove client routes of project: %w", err)
+		}
+	}
+
+	svc.reqLogSvc.SetProjectCapturePaused(projectID, false)
+
 	return nil
 }
 
 // OpenProject sets a project as the currently active project.
 func (svc *service) OpenProject(ctx context.Context, projectID ulid.ULID) (Project, error) {
+	return svc.openProject(ctx, projectID, false)
+}
+
+// OpenProjectReadOnly sets a project as the currently active project, without
+// allowing changes to it, e.g. for inspecting the evidence of a finished
+// engagement. Proxied requests aren't logged for the project, and mutating
+// methods of the services return their `ErrReadOnly`; until the project is
+// closed, other projects can't be created or deleted either. Request log and
+// sender filters can still be changed, but they aren't stored.
+func (svc *service) OpenProjectReadOnly(ctx context.Context, projectID ulid.ULID) (Project, error) {
+	return svc.openProject(ctx, projectID, true)
+}
+
+func (svc *service) openProject(ctx context.Context, projectID ulid.ULID, readOnly bool) (Project, error) {
 	svc.mu.Lock()
 	defer svc.mu.Unlock()
 
@@ -164,24 +305,55 @@ func (svc *service) OpenProject(ctx context.Context, projectID ulid.ULID) (Proje
 		return Project{}, fmt.Errorf("proj: failed to get project: %w", err)
 	}
 
+	if err := svc.reqLogSvc.SetSampling(project.Settings.ReqLogSampling); err != nil {
+		return Project{}, fmt.Errorf("proj: failed to set request log sampling: %w", err)
+	}
+
+	if err := svc.reqLogSvc.SetColumns(project.Settings.ReqLogColumns); err != nil {
+		return Project{}, fmt.Errorf("proj: failed to set request log columns: %w", err)
+	}
+
 	svc.activeProjectID = project.ID
+	svc.readOnly = readOnly
 
+	svc.reqLogSvc.SetReadOnly(readOnly)
 	svc.reqLogSvc.SetFindReqsFilter(reqlog.FindRequestsFilter{
-		ProjectID:   project.ID,
-		OnlyInScope: project.Settings.ReqLogOnlyFindInScope,
-		SearchExpr:  project.Settings.ReqLogSearchExpr,
+		ProjectID:         project.ID,
+		OnlyInScope:       project.Settings.ReqLogOnlyFindInScope,
+		SearchExpr:        project.Settings.ReqLogSearchExpr,
+		CollapseRedirects: project.Settings.ReqLogCollapseRedirects,
+		CollapsePageLoads: project.Settings.ReqLogCollapsePageLoads,
+		Presets:           project.Settings.ReqLogFilterPresets,
 	})
 	svc.reqLogSvc.SetBypassOutOfScopeRequests(project.Settings.ReqLogBypassOutOfScope)
+	svc.reqLogSvc.SetBodyRules(project.Settings.ReqLogBodyRules)
+	svc.reqLogSvc.SetProjectCapturePaused(project.ID, project.Settings.CapturePaused)
 	svc.reqLogSvc.SetActiveProjectID(project.ID)
 
 	svc.senderSvc.SetActiveProjectID(project.ID)
+	svc.senderSvc.SetReadOnly(readOnly)
 	svc.senderSvc.SetFindReqsFilter(sender.FindRequestsFilter{
 		ProjectID:   project.ID,
 		OnlyInScope: project.Settings.SenderOnlyFindInScope,
 		SearchExpr:  project.Settings.SenderSearchExpr,
 	})
+	svc.senderSvc.SetEnvironments(project.Settings.SenderEnvironments)
+	svc.senderSvc.SetSigningProfiles(project.Settings.SenderSigningProfiles)
+	svc.senderSvc.SetSchedules(project.Settings.SenderSchedules)
 
 	svc.scope.SetRules(project.Settings.ScopeRules)
+	svc.rewriter.SetPresets(project.Settings.RewritePresets)
+	svc.rewriter.SetProfiles(project.Settings.RewriteProfiles)
+	svc.rewriter.SetLocalMappings(project.Settings.RewriteLocalMappings)
+	svc.rewriter.SetRemoteMappings(project.Settings.RewriteRemoteMappings)
+
+	if svc.oauth2Svc != nil {
+		svc.oauth2Svc.SetSources(project.Settings.OAuth2Sources)
+	}
+
+	if svc.authzSvc != nil {
+		svc.authzSvc.SetSettings(project.Settings.AuthzSettings)
+	}
 
 	svc.emitProjectOpened()
 
@@ -217,6 +389,10 @@ func (svc *service) Scope() *scope.Scope {
 	return svc.scope
 }
 
+func (svc *service) Rewriter() *rewrite.Rewriter {
+	return svc.rewriter
+}
+
 func (svc *service) OnProjectOpen(fn OnProjectOpenFn) {
 	svc.mu.Lock()
 	defer svc.mu.Unlock()
@@ -232,6 +408,8 @@ func (svc *service) OnProjectClose(fn OnProjectCloseFn) {
 }
 
 func (svc *service) emitProjectOpened() {
+	svc.events.Publish(event.Event{Type: event.TypeProjectOpened, ProjectID: svc.activeProjectID})
+
 	for _, fn := range svc.onProjectOpenFns {
 		if err := fn(svc.activeProjectID); err != nil {
 			log.Printf("[ERROR] Could not execute onProjectOpen function: %v", err)
@@ -240,6 +418,8 @@ func (svc *service) emitProjectOpened() {
 }
 
 func (svc *service) emitProjectClosed(projectID ulid.ULID) {
+	svc.events.Publish(event.Event{Type: event.TypeProjectClosed, ProjectID: projectID})
+
 	for _, fn := range svc.onProjectCloseFns {
 		if err := fn(projectID); err != nil {
 			log.Printf("[ERROR] Could not execute onProjectClose function: %v", err)
@@ -253,6 +433,10 @@ func (svc *service) SetScopeRules(ctx context.Context, rules []scope.Rule) error
 		return err
 	}
 
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
 	project.Settings.ScopeRules = rules
 
 	err = svc.repo.UpsertProject(ctx, project)
@@ -262,25 +446,250 @@ func (svc *service) SetScopeRules(ctx context.Context, rules []scope.Rule) error
 
 	svc.scope.SetRules(rules)
 
+	for _, route := range svc.reqLogSvc.ClientRoutes() {
+		if route.ProjectID.Compare(project.ID) == 0 && route.Scope != nil {
+			route.Scope.SetRules(rules)
+		}
+	}
+
 	return nil
 }
 
-func (svc *service) SetRequestLogFindFilter(ctx context.Context, filter reqlog.FindRequestsFilter) error {
+func (svc *service) SetRewritePresets(ctx context.Context, presets rewrite.Presets) error {
 	project, err := svc.ActiveProject(ctx)
 	if err != nil {
 		return err
 	}
 
-	filter.ProjectID = project.ID
+	if svc.readOnly {
+		return ErrReadOnly
+	}
 
-	project.Settings.ReqLogOnlyFindInScope = filter.OnlyInScope
-	project.Settings.ReqLogSearchExpr = filter.SearchExpr
+	project.Settings.RewritePresets = presets
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	svc.rewriter.SetPresets(presets)
+
+	return nil
+}
+
+// SetRewriteProfiles sets the profiles that sender requests and replays are
+// rewritten with.
+func (svc *service) SetRewriteProfiles(ctx context.Context, profiles rewrite.Profiles) error {
+	if err := profiles.Validate(); err != nil {
+		return err
+	}
+
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	project.Settings.RewriteProfiles = profiles
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	svc.rewriter.SetProfiles(profiles)
+
+	return nil
+}
+
+// SetRewriteLocalMappings sets the local files or content that are served for
+// matching proxied requests, see `rewrite.LocalMapping`.
+func (svc *service) SetRewriteLocalMappings(ctx context.Context, mappings rewrite.LocalMappings) error {
+	if err := mappings.Validate(); err != nil {
+		return err
+	}
+
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	project.Settings.RewriteLocalMappings = mappings
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	svc.rewriter.SetLocalMappings(mappings)
+
+	return nil
+}
+
+// SetRewriteRemoteMappings sets the other origins or paths that matching
+// proxied requests are sent to, see `rewrite.RemoteMapping`.
+func (svc *service) SetRewriteRemoteMappings(ctx context.Context, mappings rewrite.RemoteMappings) error {
+	if err := mappings.Validate(); err != nil {
+		return err
+	}
+
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	project.Settings.RewriteRemoteMappings = mappings
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	svc.rewriter.SetRemoteMappings(mappings)
+
+	return nil
+}
+
+func (svc *service) SetRequestLogBodyRules(ctx context.Context, rules reqlog.BodyRules) error {
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	project.Settings.ReqLogBodyRules = rules
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	svc.reqLogSvc.SetBodyRules(rules)
+
+	return nil
+}
+
+// SetCapturePaused sets whether capture is paused for a project, see
+// `reqlog.Service.SetProjectCapturePaused`. A zero project ID means the active
+// project.
+func (svc *service) SetCapturePaused(ctx context.Context, projectID ulid.ULID, paused bool) error {
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		projectID = svc.activeProjectID
+	}
+
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return ErrNoProject
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	project, err := svc.repo.FindProjectByID(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("proj: failed to find project: %w", err)
+	}
+
+	project.Settings.CapturePaused = paused
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	svc.reqLogSvc.SetProjectCapturePaused(project.ID, paused)
+
+	return nil
+}
+
+// SetRequestLogSampling sets which proxied requests are logged for the active
+// project, see `reqlog.Sampling`.
+func (svc *service) SetRequestLogSampling(ctx context.Context, sampling reqlog.Sampling) error {
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	if err := sampling.Validate(); err != nil {
+		return err
+	}
+
+	project.Settings.ReqLogSampling = sampling
 
 	err = svc.repo.UpsertProject(ctx, project)
 	if err != nil {
 		return fmt.Errorf("proj: failed to update project: %w", err)
 	}
 
+	return svc.reqLogSvc.SetSampling(sampling)
+}
+
+// SetRequestLogColumns sets the custom columns of the request log table of the
+// active project, see `reqlog.Column`.
+func (svc *service) SetRequestLogColumns(ctx context.Context, columns []reqlog.Column) error {
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	if err := reqlog.ValidateColumns(columns); err != nil {
+		return err
+	}
+
+	project.Settings.ReqLogColumns = columns
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	return svc.reqLogSvc.SetColumns(columns)
+}
+
+func (svc *service) SetRequestLogFindFilter(ctx context.Context, filter reqlog.FindRequestsFilter) error {
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter.ProjectID = project.ID
+
+	project.Settings.ReqLogOnlyFindInScope = filter.OnlyInScope
+	project.Settings.ReqLogSearchExpr = filter.SearchExpr
+	project.Settings.ReqLogCollapseRedirects = filter.CollapseRedirects
+	project.Settings.ReqLogCollapsePageLoads = filter.CollapsePageLoads
+	project.Settings.ReqLogFilterPresets = filter.Presets
+
+	// The filter of a read-only project is only used for this session.
+	if !svc.readOnly {
+		err = svc.repo.UpsertProject(ctx, project)
+		if err != nil {
+			return fmt.Errorf("proj: failed to update project: %w", err)
+		}
+	}
+
 	svc.reqLogSvc.SetFindReqsFilter(filter)
 
 	return nil
@@ -297,12 +706,158 @@ func (svc *service) SetSenderRequestFindFilter(ctx context.Context, filter sende
 	project.Settings.SenderOnlyFindInScope = filter.OnlyInScope
 	project.Settings.SenderSearchExpr = filter.SearchExpr
 
+	if !svc.readOnly {
+		err = svc.repo.UpsertProject(ctx, project)
+		if err != nil {
+			return fmt.Errorf("proj: failed to update project: %w", err)
+		}
+	}
+
+	svc.senderSvc.SetFindReqsFilter(filter)
+
+	return nil
+}
+
+// SetSenderEnvironments sets the environments of the sender, whose variables
+// are replaced in requests when they're sent.
+func (svc *service) SetSenderEnvironments(ctx context.Context, envs sender.Environments) error {
+	if err := envs.Validate(); err != nil {
+		return err
+	}
+
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	project.Settings.SenderEnvironments = envs
+
 	err = svc.repo.UpsertProject(ctx, project)
 	if err != nil {
 		return fmt.Errorf("proj: failed to update project: %w", err)
 	}
 
-	svc.senderSvc.SetFindReqsFilter(filter)
+	svc.senderSvc.SetEnvironments(envs)
+
+	return nil
+}
+
+// SetSenderSigningProfiles sets the profiles that sender requests are signed
+// with when they're sent.
+func (svc *service) SetSenderSigningProfiles(ctx context.Context, profiles sender.SigningProfiles) error {
+	if err := profiles.Validate(); err != nil {
+		return err
+	}
+
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	project.Settings.SenderSigningProfiles = profiles
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	svc.senderSvc.SetSigningProfiles(profiles)
+
+	return nil
+}
+
+// SetSenderSchedules sets the schedules that sender requests are sent on,
+// which restarts their intervals.
+func (svc *service) SetSenderSchedules(ctx context.Context, schedules []sender.Schedule) error {
+	if err := sender.ValidateSchedules(schedules); err != nil {
+		return err
+	}
+
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	project.Settings.SenderSchedules = schedules
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	svc.senderSvc.SetSchedules(schedules)
+
+	return nil
+}
+
+// SetOAuth2Sources sets the sources that OAuth 2.0 tokens are fetched for,
+// which replaces the tokens fetched so far.
+func (svc *service) SetOAuth2Sources(ctx context.Context, sources []oauth2.Source) error {
+	if err := oauth2.ValidateSources(sources); err != nil {
+		return err
+	}
+
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	project.Settings.OAuth2Sources = sources
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	if svc.oauth2Svc != nil {
+		svc.oauth2Svc.SetSources(sources)
+	}
+
+	return nil
+}
+
+// SetAuthzSettings sets the settings of the authorization check, which replays
+// proxied requests with the credentials of a second user.
+func (svc *service) SetAuthzSettings(ctx context.Context, settings authz.Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	project.Settings.AuthzSettings = settings
+
+	err = svc.repo.UpsertProject(ctx, project)
+	if err != nil {
+		return fmt.Errorf("proj: failed to update project: %w", err)
+	}
+
+	if svc.authzSvc != nil {
+		svc.authzSvc.SetSettings(settings)
+	}
 
 	return nil
 }
@@ -310,3 +865,60 @@ func (svc *service) SetSenderRequestFindFilter(ctx context.Context, filter sende
 func (svc *service) IsProjectActive(projectID ulid.ULID) bool {
 	return projectID.Compare(svc.activeProjectID) == 0
 }
+
+// IsReadOnly returns whether the active project is opened read-only.
+func (svc *service) IsReadOnly() bool {
+	return svc.readOnly
+}
+
+// SetClientRoutes replaces the client routes, which log the requests of
+// matching clients to other projects than the active one. Requests of a routed
+// client are matched against the scope rules of its project.
+func (svc *service) SetClientRoutes(ctx context.Context, routes []ClientRoute) error {
+	if svc.readOnly {
+		return ErrReadOnly
+	}
+
+	reqLogRoutes := make([]reqlog.ClientRoute, len(routes))
+
+	for i, route := range routes {
+		project, err := svc.repo.FindProjectByID(ctx, route.ProjectID)
+		if err != nil {
+			return fmt.Errorf("proj: failed to find project of client route: %w", err)
+		}
+
+		routeScope := &scope.Scope{}
+		routeScope.SetRules(project.Settings.ScopeRules)
+
+		svc.reqLogSvc.SetProjectCapturePaused(project.ID, project.Settings.CapturePaused)
+
+		reqLogRoutes[i] = reqlog.ClientRoute{
+			ClientIP:  route.ClientIP,
+			Username:  route.Username,
+			ProjectID: project.ID,
+			Scope:     routeScope,
+		}
+	}
+
+	if err := svc.reqLogSvc.SetClientRoutes(reqLogRoutes); err != nil {
+		return fmt.Errorf("proj: failed to set client routes: %w", err)
+	}
+
+	return nil
+}
+
+// ClientRoutes returns the client routes.
+func (svc *service) ClientRoutes() []ClientRoute {
+	reqLogRoutes := svc.reqLogSvc.ClientRoutes()
+	routes := make([]ClientRoute, len(reqLogRoutes))
+
+	for i, route := range reqLogRoutes {
+		routes[i] = ClientRoute{
+			ClientIP:  route.ClientIP,
+			Username:  route.Username,
+			ProjectID: route.ProjectID,
+		}
+	}
+
+	return routes
+}
@@ -0,0 +1,118 @@
+package proj
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/scope"
+	"github.com/dstotijn/hetty/pkg/search"
+)
+
+var ErrInvalidHostActions = errcode.New(errcode.Invalid, "proj: invalid host actions")
+
+// HostActions are triage actions for a host, e.g. of a request log, that are
+// applied to the active project with a single update, see
+// `Service.ApplyHostActions`.
+type HostActions struct {
+	// Add a scope rule that matches URLs of the host, unless there already is
+	// one.
+	AddToScope bool
+	// Hide request logs of the host, by adding a condition to the search
+	// expression of the request log filter.
+	Exclude bool
+}
+
+// HostURLRegexp returns a regular expression that matches URLs with hostname
+// host, on any port.
+func HostURLRegexp(host string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)^[a-z]+://` + regexp.QuoteMeta(strings.ToLower(host)) + `(:[0-9]+)?([/?#]|$)`)
+}
+
+// ApplyHostActions applies actions for host to the active project. Its
+// settings are stored once, for all actions. Like `SetRequestLogFindFilter`,
+// an exclusion is only used for this session if the project is opened
+// read-only.
+func (svc *service) ApplyHostActions(ctx context.Context, host string, actions HostActions) error {
+	if host == "" {
+		return fmt.Errorf("%w: host must not be empty", ErrInvalidHostActions)
+	}
+
+	if actions.AddToScope && actions.Exclude {
+		return fmt.Errorf("%w: a host can't be both added to the scope and excluded", ErrInvalidHostActions)
+	}
+
+	project, err := svc.ActiveProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	if svc.readOnly && actions.AddToScope {
+		return ErrReadOnly
+	}
+
+	re := HostURLRegexp(host)
+	rules := project.Settings.ScopeRules
+	filter := svc.reqLogSvc.FindReqsFilter()
+
+	if actions.AddToScope && !hasScopeRule(rules, re) {
+		rules = append(append([]scope.Rule(nil), rules...), scope.Rule{URL: re})
+		project.Settings.ScopeRules = rules
+	}
+
+	if actions.Exclude {
+		exclusion := search.InfixExpression{
+			Operator: search.TokOpNotRe,
+			Left:     search.StringLiteral{Value: "req.url"},
+			Right:    search.RegexpLiteral{Regexp: re},
+		}
+
+		if filter.SearchExpr == nil {
+			filter.SearchExpr = exclusion
+		} else {
+			filter.SearchExpr = search.InfixExpression{
+				Operator: search.TokOpAnd,
+				Left:     filter.SearchExpr,
+				Right:    exclusion,
+			}
+		}
+
+		filter.ProjectID = project.ID
+		project.Settings.ReqLogSearchExpr = filter.SearchExpr
+	}
+
+	if !svc.readOnly {
+		err = svc.repo.UpsertProject(ctx, project)
+		if err != nil {
+			return fmt.Errorf("proj: failed to update project: %w", err)
+		}
+	}
+
+	if actions.AddToScope {
+		svc.scope.SetRules(rules)
+
+		for _, route := range svc.reqLogSvc.ClientRoutes() {
+			if route.ProjectID.Compare(project.ID) == 0 && route.Scope != nil {
+				route.Scope.SetRules(rules)
+			}
+		}
+	}
+
+	if actions.Exclude {
+		svc.reqLogSvc.SetFindReqsFilter(filter)
+	}
+
+	return nil
+}
+
+func hasScopeRule(rules []scope.Rule, re *regexp.Regexp) bool {
+	for _, rule := range rules {
+		if rule.URL != nil && rule.URL.String() == re.String() {
+			return true
+		}
+	}
+
+	return false
+}
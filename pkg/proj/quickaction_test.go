@@ -0,0 +1,109 @@
+package proj_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/proj"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
+	"github.com/dstotijn/hetty/pkg/scope"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func TestApplyHostActions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	database := memory.OpenDatabase()
+	projScope := &scope.Scope{}
+	reqLogSvc := reqlog.NewService(reqlog.Config{Scope: projScope, Repository: database})
+	senderSvc := sender.NewService(sender.Config{
+		Scope:         projScope,
+		Repository:    database,
+		ReqLogService: reqLogSvc,
+		HTTPClient:    &http.Client{},
+	})
+
+	svc, err := proj.NewService(proj.Config{
+		Repository:    database,
+		ReqLogService: reqLogSvc,
+		SenderService: senderSvc,
+		Scope:         projScope,
+		Rewriter:      &rewrite.Rewriter{},
+	})
+	if err != nil {
+		t.Fatalf("failed to create project service: %v", err)
+	}
+
+	project, err := svc.CreateProject(ctx, "foobar")
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	if _, err := svc.OpenProject(ctx, project.ID); err != nil {
+		t.Fatalf("failed to open project: %v", err)
+	}
+
+	err = svc.ApplyHostActions(ctx, "example.com", proj.HostActions{AddToScope: true, Exclude: true})
+	if !errors.Is(err, proj.ErrInvalidHostActions) {
+		t.Fatalf("expected `proj.ErrInvalidHostActions`, got: %v", err)
+	}
+
+	// Adding a host twice adds one scope rule.
+	for i := 0; i < 2; i++ {
+		if err := svc.ApplyHostActions(ctx, "Example.com", proj.HostActions{AddToScope: true}); err != nil {
+			t.Fatalf("failed to add host to scope: %v", err)
+		}
+	}
+
+	if n := len(projScope.Rules()); n != 1 {
+		t.Fatalf("expected 1 scope rule, got: %v", n)
+	}
+
+	for _, rawURL := range []string{"https://example.com/foo", "http://example.com:8080", "https://EXAMPLE.com?q"} {
+		if !projScope.Match(httptest.NewRequest(http.MethodGet, rawURL, nil), nil) {
+			t.Errorf("expected %q to be in scope", rawURL)
+		}
+	}
+
+	if projScope.Match(httptest.NewRequest(http.MethodGet, "https://example.com.evil.net/", nil), nil) {
+		t.Error("expected other host not to be in scope")
+	}
+
+	if err := svc.ApplyHostActions(ctx, "cdn.example.net", proj.HostActions{Exclude: true}); err != nil {
+		t.Fatalf("failed to exclude host: %v", err)
+	}
+
+	expr := reqLogSvc.FindReqsFilter().SearchExpr
+	if expr == nil {
+		t.Fatal("expected search expression to be set")
+	}
+
+	for rawURL, exp := range map[string]bool{"https://cdn.example.net/app.js": false, "https://example.com/": true} {
+		req := httptest.NewRequest(http.MethodGet, rawURL, nil)
+
+		got, err := reqlog.RequestLog{URL: req.URL}.Matches(expr)
+		if err != nil {
+			t.Fatalf("failed to match search expression: %v", err)
+		}
+
+		if got != exp {
+			t.Errorf("expected match of %q to be %v, got: %v", rawURL, exp, got)
+		}
+	}
+
+	// Settings are stored, e.g. for when the project is reopened.
+	project, err = svc.ActiveProject(ctx)
+	if err != nil {
+		t.Fatalf("failed to get active project: %v", err)
+	}
+
+	if len(project.Settings.ScopeRules) != 1 || project.Settings.ReqLogSearchExpr == nil {
+		t.Fatalf("expected scope rules and search expression to be stored, got: %+v", project.Settings)
+	}
+}
@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidBlockRule = errors.New("proxy: invalid block rule")
+
+// blockedMessage is the body of responses to blocked requests.
+const blockedMessage = "Blocked by Hetty: the request matches a block rule.\n"
+
+// BlockRule refuses to proxy matching requests, e.g. to keep test devices from
+// sending traffic to production or third party domains. The proxy responds with
+// `403 Forbidden`, without contacting the upstream server. A rule matches if
+// all of its set criteria match.
+type BlockRule struct {
+	// Hostname, where a leading `*.` matches subdomains, e.g. `*.example.com`.
+	// Rules with only a host also refuse CONNECT tunnels to the host, so that
+	// traffic that isn't HTTP is blocked as well.
+	Host string
+	// Matched against the full URL of requests, e.g. `^https://example\.com/`.
+	URL *regexp.Regexp
+}
+
+// ParseBlockRule parses a host pattern, or a URL regular expression prefixed
+// with `url:`, e.g. `url:^https://example\.com/admin`.
+func ParseBlockRule(s string) (BlockRule, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, "url:") {
+		re, err := regexp.Compile(strings.TrimPrefix(s, "url:"))
+		if err != nil {
+			return BlockRule{}, fmt.Errorf("%w: %v", ErrInvalidBlockRule, err)
+		}
+
+		return BlockRule{URL: re}, nil
+	}
+
+	if s == "" {
+		return BlockRule{}, fmt.Errorf("%w: host must not be empty", ErrInvalidBlockRule)
+	}
+
+	return BlockRule{Host: s}, nil
+}
+
+func (rule BlockRule) match(hostname, rawURL string) bool {
+	if rule.Host != "" && !matchHostPattern(rule.Host, hostname) {
+		return false
+	}
+
+	if rule.URL != nil && !rule.URL.MatchString(rawURL) {
+		return false
+	}
+
+	return true
+}
+
+// SetBlockRules replaces the block rules.
+func (p *Proxy) SetBlockRules(rules []BlockRule) error {
+	for _, rule := range rules {
+		if strings.TrimSpace(rule.Host) == "" && rule.URL == nil {
+			return fmt.Errorf("%w: host or URL must be set", ErrInvalidBlockRule)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.blockRules = append([]BlockRule(nil), rules...)
+
+	return nil
+}
+
+func (p *Proxy) BlockRules() []BlockRule {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return append([]BlockRule(nil), p.blockRules...)
+}
+
+// blocked returns true if r matches a block rule. Requests in CONNECT tunnels
+// don't have a scheme and host set on their URL yet, see `modifyRequest`.
+func (p *Proxy) blocked(r *http.Request) bool {
+	p.mu.RLock()
+	rules := p.blockRules
+	p.mu.RUnlock()
+
+	if len(rules) == 0 {
+		return false
+	}
+
+	u := *r.URL
+	if u.Scheme == "" {
+		u.Host = r.Host
+		u.Scheme = "https"
+	}
+
+	hostname, rawURL := strings.ToLower(u.Hostname()), u.String()
+
+	for _, rule := range rules {
+		if rule.match(hostname, rawURL) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// blockedTunnel returns true if a CONNECT tunnel to host is refused, because
+// a rule without a URL pattern matches it.
+func (p *Proxy) blockedTunnel(host string) bool {
+	p.mu.RLock()
+	rules := p.blockRules
+	p.mu.RUnlock()
+
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname = host
+	}
+
+	hostname = strings.ToLower(hostname)
+
+	for _, rule := range rules {
+		if rule.URL == nil && rule.match(hostname, "") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeBlocked(w http.ResponseWriter) {
+	http.Error(w, strings.TrimSuffix(blockedMessage, "\n"), http.StatusForbidden)
+}
+
+// blockedResponse returns the response to a blocked request, for requests that
+// are sent with `Proxy.RoundTrip`, or that match a block rule after the request
+// modifiers ran.
+func blockedResponse(req *http.Request) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(blockedMessage)))
+
+	return &http.Response{
+		Status:        "403 Forbidden",
+		StatusCode:    http.StatusForbidden,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(blockedMessage)),
+		ContentLength: int64(len(blockedMessage)),
+		Request:       req,
+	}
+}
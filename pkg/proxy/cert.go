@@ -16,7 +16,11 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
+
+	"golang.org/x/net/publicsuffix"
 )
 
 // MaxSerialNumber is the upper boundary that is used to create unique serial
@@ -31,6 +35,12 @@ type CertConfig struct {
 	caPriv crypto.PrivateKey
 	priv   *rsa.PrivateKey
 	keyID  []byte
+
+	// mu guards the cache and wildcard settings, which can be changed while
+	// certificates are generated.
+	mu       sync.RWMutex
+	cache    *certCache
+	wildcard bool
 }
 
 // NewCertConfig creates a MITM config using the CA certificate and
@@ -59,6 +69,7 @@ func NewCertConfig(ca *x509.Certificate, caPrivKey crypto.PrivateKey) (*CertConf
 		caPriv: caPrivKey,
 		priv:   priv,
 		keyID:  keyID,
+		cache:  newCertCache(CertCacheConfig{}),
 	}, nil
 }
 
@@ -210,13 +221,92 @@ func (c *CertConfig) TLSConfig() *tls.Config {
 	}
 }
 
+// SetCache replaces the certificate cache with an empty cache, configured with
+// cfg.
+func (c *CertConfig) SetCache(cfg CertCacheConfig) {
+	cache := newCertCache(cfg)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache = cache
+}
+
+func (c *CertConfig) certCache() *certCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cache
+}
+
+// SetWildcard enables or disables wildcard certificates. When enabled, a
+// certificate for `foo.example.com` is valid for `*.example.com` and
+// `example.com`, so that it's shared by other subdomains. Certificates for
+// registrable domains (e.g. `example.com`) also cover their subdomains.
+func (c *CertConfig) SetWildcard(wildcard bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.wildcard = wildcard
+}
+
+func (c *CertConfig) wildcardEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.wildcard
+}
+
+// Pregenerate generates and caches certificates for hostnames that aren't
+// cached yet, so that handshakes for these hosts don't wait for a certificate.
+func (c *CertConfig) Pregenerate(hostnames []string) error {
+	for _, hostname := range hostnames {
+		if _, err := c.cert(hostname); err != nil {
+			return fmt.Errorf("proxy: could not generate certificate for %q: %w", hostname, err)
+		}
+	}
+
+	return nil
+}
+
+// cert returns a cached certificate for hostname, or generates one.
 func (c *CertConfig) cert(hostname string) (*tls.Certificate, error) {
-	// Remove the port if it exists.
-	host, _, err := net.SplitHostPort(hostname)
-	if err == nil {
-		hostname = host
+	hostname = normalizeHostname(hostname)
+
+	if c.wildcardEnabled() {
+		if base, ok := wildcardBase(hostname); ok {
+			hostname = "*." + base
+		}
 	}
 
+	return c.certCache().get(hostname, c.generateCert)
+}
+
+// wildcardBase returns the domain whose wildcard certificate covers hostname:
+// hostname itself when it's a registrable domain (eTLD+1), or else its parent
+// domain. It returns false for IP addresses, and for hostnames that aren't
+// under a public suffix (e.g. `localhost`).
+func wildcardBase(hostname string) (string, bool) {
+	if net.ParseIP(hostname) != nil {
+		return "", false
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	if err != nil {
+		return "", false
+	}
+
+	if hostname == domain {
+		return domain, true
+	}
+
+	return hostname[strings.Index(hostname, ".")+1:], true
+}
+
+// generateCert generates a certificate for hostname, which can be a wildcard
+// name (e.g. `*.example.com`); the certificate is then also valid for the base
+// domain.
+func (c *CertConfig) generateCert(hostname string) (*tls.Certificate, error) {
 	serial, err := rand.Int(rand.Reader, MaxSerialNumber)
 	if err != nil {
 		return nil, err
@@ -233,12 +323,15 @@ func (c *CertConfig) cert(hostname string) (*tls.Certificate, error) {
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
 		NotBefore:             time.Now().Add(-24 * time.Hour),
-		NotAfter:              time.Now().Add(24 * time.Hour),
+		NotAfter:              time.Now().Add(certValidity),
 	}
 
-	if ip := net.ParseIP(hostname); ip != nil {
+	switch ip := net.ParseIP(hostname); {
+	case ip != nil:
 		tmpl.IPAddresses = []net.IP{ip}
-	} else {
+	case strings.HasPrefix(hostname, "*."):
+		tmpl.DNSNames = []string{hostname, strings.TrimPrefix(hostname, "*.")}
+	default:
 		tmpl.DNSNames = []string{hostname}
 	}
 
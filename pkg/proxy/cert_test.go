@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWildcardBase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		hostname string
+		expBase  string
+		expOK    bool
+	}{
+		{hostname: "example.com", expBase: "example.com", expOK: true},
+		{hostname: "foo.example.com", expBase: "example.com", expOK: true},
+		{hostname: "foo.bar.example.com", expBase: "bar.example.com", expOK: true},
+		{hostname: "foo.example.co.uk", expBase: "example.co.uk", expOK: true},
+		{hostname: "co.uk"},
+		{hostname: "localhost"},
+		{hostname: "127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		base, ok := wildcardBase(tt.hostname)
+		if base != tt.expBase || ok != tt.expOK {
+			t.Errorf("wildcardBase(%q) = (%q, %v), expected: (%q, %v)", tt.hostname, base, ok, tt.expBase, tt.expOK)
+		}
+	}
+}
+
+func TestWildcardCert(t *testing.T) {
+	t.Parallel()
+
+	ca, key, err := NewCA("Hetty", "Hetty CA", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certConfig, err := NewCertConfig(ca, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certConfig.SetWildcard(true)
+
+	cert, err := certConfig.cert("foo.example.com:443")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, hostname := range []string{"foo.example.com", "bar.example.com", "example.com"} {
+		if err := cert.Leaf.VerifyHostname(hostname); err != nil {
+			t.Errorf("expected certificate to be valid for %q, got: %v", hostname, err)
+		}
+
+		other, err := certConfig.cert(hostname)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if other != cert {
+			t.Errorf("expected cached certificate to be used for %q", hostname)
+		}
+	}
+}
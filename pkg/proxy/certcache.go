@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"container/list"
+	"crypto/tls"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// certValidity is how long generated leaf certificates are valid.
+	certValidity = 24 * time.Hour
+
+	defaultCertCacheTTL  = 12 * time.Hour
+	defaultCertCacheSize = 1000
+)
+
+// CertCacheConfig configures the cache of generated leaf certificates.
+type CertCacheConfig struct {
+	// Duration a certificate is cached for. Defaults to 12 hours, and can't
+	// exceed the validity of certificates (24 hours) minus an hour.
+	TTL time.Duration
+	// Maximum number of cached certificates. When the cache is full, the least
+	// recently used certificate is evicted. Defaults to 1000.
+	MaxSize int
+}
+
+// certCache caches leaf certificates by hostname. Concurrent lookups of a
+// hostname that isn't cached wait for a single certificate to be generated.
+type certCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	entries map[string]*list.Element
+	// Least recently used entries are at the back.
+	lru *list.List
+}
+
+type certCacheEntry struct {
+	hostname  string
+	cert      *tls.Certificate
+	err       error
+	ready     chan struct{}
+	expiresAt time.Time
+}
+
+func newCertCache(cfg CertCacheConfig) *certCache {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultCertCacheTTL
+	}
+
+	if maxTTL := certValidity - time.Hour; cfg.TTL > maxTTL {
+		cfg.TTL = maxTTL
+	}
+
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultCertCacheSize
+	}
+
+	return &certCache{
+		ttl:     cfg.TTL,
+		maxSize: cfg.MaxSize,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns the cached certificate for hostname, or generates one with gen.
+func (cc *certCache) get(hostname string, gen func(hostname string) (*tls.Certificate, error)) (*tls.Certificate, error) {
+	now := time.Now()
+
+	cc.mu.Lock()
+
+	if elem, ok := cc.entries[hostname]; ok {
+		entry := elem.Value.(*certCacheEntry)
+
+		select {
+		case <-entry.ready:
+			if now.Before(entry.expiresAt) {
+				cc.lru.MoveToFront(elem)
+				cc.mu.Unlock()
+
+				return entry.cert, nil
+			}
+
+			cc.remove(elem)
+		default:
+			// Another goroutine is generating the certificate.
+			cc.mu.Unlock()
+			<-entry.ready
+
+			return entry.cert, entry.err
+		}
+	}
+
+	entry := &certCacheEntry{
+		hostname: hostname,
+		ready:    make(chan struct{}),
+	}
+	elem := cc.lru.PushFront(entry)
+	cc.entries[hostname] = elem
+
+	for cc.lru.Len() > cc.maxSize {
+		cc.remove(cc.lru.Back())
+	}
+
+	cc.mu.Unlock()
+
+	entry.cert, entry.err = gen(hostname)
+	entry.expiresAt = time.Now().Add(cc.ttl)
+	close(entry.ready)
+
+	if entry.err != nil {
+		cc.mu.Lock()
+		if cc.entries[hostname] == elem {
+			cc.remove(elem)
+		}
+		cc.mu.Unlock()
+	}
+
+	return entry.cert, entry.err
+}
+
+// remove removes an entry. The lock must be held.
+func (cc *certCache) remove(elem *list.Element) {
+	entry := elem.Value.(*certCacheEntry)
+
+	cc.lru.Remove(elem)
+
+	if cc.entries[entry.hostname] == elem {
+		delete(cc.entries, entry.hostname)
+	}
+}
+
+func (cc *certCache) len() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	return cc.lru.Len()
+}
+
+// normalizeHostname removes the port, if any, and lowercases hostname.
+func normalizeHostname(hostname string) string {
+	if host, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = host
+	}
+
+	return strings.ToLower(hostname)
+}
@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCertCache(t *testing.T) {
+	t.Parallel()
+
+	var generated int32
+
+	gen := func(hostname string) (*tls.Certificate, error) {
+		atomic.AddInt32(&generated, 1)
+		return &tls.Certificate{}, nil
+	}
+
+	t.Run("generates a certificate once for concurrent lookups", func(t *testing.T) {
+		atomic.StoreInt32(&generated, 0)
+		cache := newCertCache(CertCacheConfig{})
+
+		var wg sync.WaitGroup
+
+		certs := make([]*tls.Certificate, 10)
+
+		for i := range certs {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				certs[i], _ = cache.get("example.com", gen)
+			}(i)
+		}
+
+		wg.Wait()
+
+		if n := atomic.LoadInt32(&generated); n != 1 {
+			t.Fatalf("expected 1 generated certificate, got: %v", n)
+		}
+
+		for _, cert := range certs {
+			if cert != certs[0] {
+				t.Fatal("expected the same certificate for all lookups")
+			}
+		}
+	})
+
+	t.Run("evicts least recently used certificate", func(t *testing.T) {
+		atomic.StoreInt32(&generated, 0)
+		cache := newCertCache(CertCacheConfig{MaxSize: 2})
+
+		for _, hostname := range []string{"a.example.com", "b.example.com", "a.example.com", "c.example.com"} {
+			if _, err := cache.get(hostname, gen); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if n := cache.len(); n != 2 {
+			t.Fatalf("expected 2 cached certificates, got: %v", n)
+		}
+
+		// `b.example.com` was evicted, `a.example.com` is still cached.
+		_, _ = cache.get("a.example.com", gen)
+		_, _ = cache.get("b.example.com", gen)
+
+		if n := atomic.LoadInt32(&generated); n != 4 {
+			t.Fatalf("expected 4 generated certificates, got: %v", n)
+		}
+	})
+
+	t.Run("regenerates expired certificate", func(t *testing.T) {
+		atomic.StoreInt32(&generated, 0)
+		cache := newCertCache(CertCacheConfig{TTL: time.Millisecond})
+
+		first, _ := cache.get("example.com", gen)
+
+		time.Sleep(5 * time.Millisecond)
+
+		second, _ := cache.get("example.com", gen)
+
+		if first == second {
+			t.Fatal("expected a new certificate after the TTL")
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+type clientUsernameKey struct{}
+
+// SetProxyAuthRequired enables or disables requiring clients to send proxy
+// credentials (`Proxy-Authorization` header, Basic scheme). Clients without
+// credentials get a `407 Proxy Authentication Required` response, which makes
+// browsers prompt for them. Credentials aren't verified; the username is only
+// used to tell clients apart, see `ClientUsername`.
+func (p *Proxy) SetProxyAuthRequired(required bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.proxyAuthRequired = required
+}
+
+// ProxyAuthRequired returns true if clients must send proxy credentials.
+func (p *Proxy) ProxyAuthRequired() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.proxyAuthRequired
+}
+
+// ClientUsername returns the username of the proxy credentials that the client
+// sent with a proxied request, or with the CONNECT request of its tunnel.
+func ClientUsername(ctx context.Context) string {
+	username, _ := ctx.Value(clientUsernameKey{}).(string)
+	return username
+}
+
+// withClientUsername returns r with the username of its proxy credentials on
+// its context. Requests without credentials keep the username of their tunnel,
+// if any. The `Proxy-Authorization` header is removed, so that the credentials
+// aren't logged (or exported, e.g. as HAR) by request modifiers.
+func withClientUsername(r *http.Request) *http.Request {
+	header := r.Header.Get("Proxy-Authorization")
+	r.Header.Del("Proxy-Authorization")
+
+	username, ok := proxyAuthUsername(header)
+	if !ok {
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), clientUsernameKey{}, username))
+}
+
+func proxyAuthUsername(header string) (string, bool) {
+	const prefix = "basic "
+
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(header[len(prefix):]))
+	if err != nil {
+		return "", false
+	}
+
+	username := string(decoded)
+	if i := strings.IndexByte(username, ':'); i >= 0 {
+		username = username[:i]
+	}
+
+	return username, username != ""
+}
+
+// requireProxyAuth writes a `407 Proxy Authentication Required` response and
+// returns false if proxy credentials are required, but r has none.
+func (p *Proxy) requireProxyAuth(w http.ResponseWriter, r *http.Request) bool {
+	if !p.ProxyAuthRequired() || ClientUsername(r.Context()) != "" {
+		return true
+	}
+
+	w.Header().Set("Proxy-Authenticate", `Basic realm="Hetty"`)
+	writeError(w, http.StatusProxyAuthRequired)
+
+	return false
+}
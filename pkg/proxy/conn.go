@@ -0,0 +1,305 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnectionMode is the way a CONNECT tunnel is handled by the proxy.
+type ConnectionMode string
+
+const (
+	// ConnectionModeMITM is used for tunnels with TLS traffic, which is
+	// decrypted and handled as HTTP requests.
+	ConnectionModeMITM ConnectionMode = "mitm"
+	// ConnectionModePassthrough is used for tunnels with non-TLS traffic, which
+	// is forwarded to the CONNECT target as is.
+	ConnectionModePassthrough ConnectionMode = "passthrough"
+)
+
+// tlsRecordTypeHandshake is the first byte of a TLS ClientHello.
+const tlsRecordTypeHandshake = 0x16
+
+// Connection describes a CONNECT tunnel that was closed.
+type Connection struct {
+	ClientAddr string
+	// Host is the CONNECT target, in the form "host:port".
+	Host string
+	// ServerName is the server name (SNI) sent by the client, for MITM tunnels.
+	ServerName string
+	Mode       ConnectionMode
+	// BytesUp and BytesDown are the number of bytes received from and sent to
+	// the client.
+	BytesUp   int64
+	BytesDown int64
+	StartedAt time.Time
+	Duration  time.Duration
+	// Capture is set for tunnels that aren't handled as HTTP.
+	Capture *Capture
+	// Err is the error that ended the tunnel, if any.
+	Err error
+}
+
+// ConnectionHandler is called when a CONNECT tunnel is closed.
+type ConnectionHandler func(conn Connection)
+
+// OnConnectionClose registers handlers that are called when a CONNECT tunnel
+// is closed.
+func (p *Proxy) OnConnectionClose(fn ...ConnectionHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	handlers := make([]ConnectionHandler, len(p.connHandlers), len(p.connHandlers)+len(fn))
+	copy(handlers, p.connHandlers)
+	p.connHandlers = append(handlers, fn...)
+}
+
+func (p *Proxy) handleConnectionClose(conn Connection) {
+	p.mu.RLock()
+	handlers := p.connHandlers
+	p.mu.RUnlock()
+
+	for _, fn := range handlers {
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					logPanic("connection handler", v)
+				}
+			}()
+
+			fn(conn)
+		}()
+	}
+}
+
+// countConn embeds net.Conn and counts the bytes read and written.
+type countConn struct {
+	net.Conn
+	bytesRead    int64
+	bytesWritten int64
+}
+
+func (c *countConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(&c.bytesRead, int64(n))
+
+	return n, err
+}
+
+func (c *countConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.bytesWritten, int64(n))
+
+	return n, err
+}
+
+func (c *countConn) BytesRead() int64 {
+	return atomic.LoadInt64(&c.bytesRead)
+}
+
+func (c *countConn) BytesWritten() int64 {
+	return atomic.LoadInt64(&c.bytesWritten)
+}
+
+// peekConn embeds net.Conn and reads via a buffered reader, so that the first
+// bytes of a connection can be inspected without consuming them.
+type peekConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func newPeekConn(conn net.Conn) *peekConn {
+	return &peekConn{Conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *peekConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// isTLS returns true if the client's first message is a TLS handshake record.
+func (c *peekConn) isTLS() (bool, error) {
+	b, err := c.r.Peek(1)
+	if err != nil {
+		return false, err
+	}
+
+	return b[0] == tlsRecordTypeHandshake, nil
+}
+
+// isHTTP returns true if the client's first message looks like an HTTP/1.x
+// request line, i.e. it starts with an uppercase method token and a space. It
+// blocks until the client sends data.
+func (c *peekConn) isHTTP() (bool, error) {
+	if _, err := c.r.Peek(1); err != nil {
+		return false, err
+	}
+
+	n := c.r.Buffered()
+	if n > maxMethodLen+1 {
+		n = maxMethodLen + 1
+	}
+
+	b, err := c.r.Peek(n)
+	if err != nil {
+		return false, err
+	}
+
+	for i, ch := range b {
+		switch {
+		case ch == ' ' && i > 0:
+			return true, nil
+		case ch < 'A' || ch > 'Z':
+			return false, nil
+		}
+	}
+
+	// Only (part of) a method token was received.
+	return len(b) <= maxMethodLen, nil
+}
+
+// maxMethodLen is the length of the longest HTTP method that's recognized,
+// e.g. `PROPFIND`.
+const maxMethodLen = 16
+
+// MaxCaptureSize is the maximum number of bytes per direction that's captured
+// for tunnels that aren't handled as HTTP.
+const MaxCaptureSize = 1 << 20
+
+// Capture holds the raw bytes of a tunnel that isn't handled as HTTP.
+type Capture struct {
+	// Up and Down are the bytes received from and sent to the client.
+	Up   []byte
+	Down []byte
+	// Truncated is true if more than `MaxCaptureSize` bytes were sent in
+	// either direction.
+	Truncated bool
+}
+
+// captureWriter stores the first `MaxCaptureSize` bytes written to it.
+type captureWriter struct {
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	n := len(b)
+
+	if room := MaxCaptureSize - w.buf.Len(); len(b) > room {
+		b = b[:room]
+		w.truncated = true
+	}
+
+	w.buf.Write(b)
+
+	return n, nil
+}
+
+// relay forwards traffic between the client and upstream, until either side
+// closes its connection. The forwarded bytes are captured.
+func relay(clientConn, upstreamConn net.Conn) (*Capture, error) {
+	var (
+		wg      sync.WaitGroup
+		copyErr error
+		once    sync.Once
+		up      captureWriter
+		down    captureWriter
+	)
+
+	copyConn := func(dst, src net.Conn, w *captureWriter) {
+		defer wg.Done()
+
+		// Deadline errors are caused by the copy in the other direction ending.
+		_, err := io.Copy(dst, io.TeeReader(src, w))
+		if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			once.Do(func() { copyErr = err })
+		}
+
+		// Unblock the copy in the other direction.
+		clientConn.SetDeadline(time.Now())
+		upstreamConn.SetDeadline(time.Now())
+	}
+
+	wg.Add(2)
+
+	go copyConn(upstreamConn, clientConn, &up)
+	go copyConn(clientConn, upstreamConn, &down)
+
+	wg.Wait()
+
+	capture := &Capture{
+		Up:        up.buf.Bytes(),
+		Down:      down.buf.Bytes(),
+		Truncated: up.truncated || down.truncated,
+	}
+
+	return capture, copyErr
+}
+
+// tunnelDialer returns the dialer for a CONNECT target, configured like the
+// upstream transport for its hostname.
+func tunnelDialer(cfg TransportConfig, host string) *upstreamDialer {
+	hostname, _, _ := net.SplitHostPort(host)
+	hostname = strings.ToLower(hostname)
+
+	for _, ht := range cfg.HostTimeouts {
+		if ht.match(hostname) {
+			cfg = ht.apply(cfg)
+			break
+		}
+	}
+
+	return newDialer(cfg)
+}
+
+// passthrough forwards traffic between the client and the CONNECT target as
+// is.
+func passthrough(clientConn net.Conn, host string, cfg TransportConfig) (*Capture, error) {
+	upstreamConn, err := tunnelDialer(cfg, host).DialContext(context.Background(), "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	defer upstreamConn.Close()
+
+	return relay(clientConn, upstreamConn)
+}
+
+// relayTLS forwards decrypted traffic from the client over a new TLS
+// connection to the CONNECT target. It's used for TLS tunnels that don't carry
+// HTTP.
+func relayTLS(clientConn net.Conn, host, serverName string, cfg TransportConfig) (*Capture, error) {
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(host)
+	}
+
+	conn, err := tunnelDialer(cfg, host).DialContext(context.Background(), "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	handshakeTimeout := 10 * time.Second
+	if cfg.TLSHandshakeTimeout > 0 {
+		handshakeTimeout = cfg.TLSHandshakeTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+
+	upstreamConn := tls.Client(conn, &tls.Config{ServerName: serverName})
+	if err := upstreamConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer upstreamConn.Close()
+
+	return relay(clientConn, upstreamConn)
+}
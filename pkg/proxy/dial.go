@@ -0,0 +1,204 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// IPFamily controls which IP versions are used for upstream connections.
+type IPFamily string
+
+const (
+	// IPFamilyAny connects to the first address that's resolved for a host,
+	// racing IPv4 and IPv6 connections (happy eyeballs).
+	IPFamilyAny IPFamily = ""
+	// IPFamilyIPv4 and IPFamilyIPv6 only use addresses of one IP version.
+	IPFamilyIPv4 IPFamily = "ipv4"
+	IPFamilyIPv6 IPFamily = "ipv6"
+	// IPFamilyPreferIPv4 and IPFamilyPreferIPv6 try addresses of one IP
+	// version first, and race the other IP version after the fallback delay.
+	IPFamilyPreferIPv4 IPFamily = "prefer-ipv4"
+	IPFamilyPreferIPv6 IPFamily = "prefer-ipv6"
+)
+
+// defaultFallbackDelay is the fallback delay of `net.Dialer`.
+const defaultFallbackDelay = 300 * time.Millisecond
+
+// ParseIPFamily parses an IP family; "any", "ipv4", "ipv6", "prefer-ipv4" or
+// "prefer-ipv6".
+func ParseIPFamily(s string) (IPFamily, error) {
+	switch family := IPFamily(s); family {
+	case "any":
+		return IPFamilyAny, nil
+	case IPFamilyAny, IPFamilyIPv4, IPFamilyIPv6, IPFamilyPreferIPv4, IPFamilyPreferIPv6:
+		return family, nil
+	default:
+		return "", fmt.Errorf("proxy: invalid IP family: %q", s)
+	}
+}
+
+// upstreamDialer is a net.Dialer that uses the IP family of the transport
+// config.
+type upstreamDialer struct {
+	net.Dialer
+	family IPFamily
+}
+
+func (d *upstreamDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" {
+		return d.Dialer.DialContext(ctx, network, addr)
+	}
+
+	switch d.family {
+	case IPFamilyIPv4:
+		return d.Dialer.DialContext(ctx, "tcp4", addr)
+	case IPFamilyIPv6:
+		return d.Dialer.DialContext(ctx, "tcp6", addr)
+	case IPFamilyPreferIPv4:
+		return d.dialPreferred(ctx, "tcp4", "tcp6", addr)
+	case IPFamilyPreferIPv6:
+		return d.dialPreferred(ctx, "tcp6", "tcp4", addr)
+	default:
+		return d.Dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// dialPreferred dials addr with the primary network, and races a connection
+// with the fallback network if the primary hasn't connected after the fallback
+// delay, or failed. The first connection that's established is used.
+func (d *upstreamDialer) dialPreferred(ctx context.Context, primary, fallback, addr string) (net.Conn, error) {
+	if d.FallbackDelay < 0 {
+		conn, err := d.Dialer.DialContext(ctx, primary, addr)
+		if err == nil {
+			return conn, nil
+		}
+
+		return d.Dialer.DialContext(ctx, fallback, addr)
+	}
+
+	delay := d.FallbackDelay
+	if delay == 0 {
+		delay = defaultFallbackDelay
+	}
+
+	type dialResult struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, 2)
+
+	dial := func(network string, primary bool) {
+		conn, err := d.Dialer.DialContext(ctx, network, addr)
+		results <- dialResult{conn: conn, err: err, primary: primary}
+	}
+
+	go dial(primary, true)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var (
+		primaryErr     error
+		fallbackDialed bool
+		pending        = 1
+	)
+
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if !fallbackDialed {
+				fallbackDialed = true
+				pending++
+
+				go dial(fallback, false)
+			}
+		case res := <-results:
+			pending--
+
+			if res.err == nil {
+				// Close a connection of the other network that's established
+				// after all.
+				if pending > 0 {
+					go func() {
+						if res := <-results; res.conn != nil {
+							res.conn.Close()
+						}
+					}()
+				}
+
+				return res.conn, nil
+			}
+
+			if res.primary {
+				primaryErr = res.err
+
+				if !fallbackDialed {
+					fallbackDialed = true
+					pending++
+
+					go dial(fallback, false)
+				}
+			}
+		}
+	}
+
+	return nil, primaryErr
+}
+
+type (
+	remoteAddrKey   struct{}
+	remoteAddrState struct {
+		mu   sync.Mutex
+		addr net.Addr
+	}
+)
+
+// traceRemoteAddr returns req with a client trace that records the remote
+// address of the upstream connection, see `RemoteIP`.
+func traceRemoteAddr(req *http.Request) *http.Request {
+	state := &remoteAddrState{}
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			state.mu.Lock()
+			state.addr = info.Conn.RemoteAddr()
+			state.mu.Unlock()
+		},
+	}
+
+	ctx := httptrace.WithClientTrace(req.Context(), trace)
+	ctx = context.WithValue(ctx, remoteAddrKey{}, state)
+
+	return req.WithContext(ctx)
+}
+
+// RemoteIP returns the IP address that a proxied request was sent to, or nil
+// if no connection was established. When an HTTP proxy is used for upstream
+// requests (e.g. via `HTTP_PROXY`), it's the IP address of that proxy. ctx is
+// the context of `http.Response.Request`, or of the request that's passed to
+// request error handlers.
+func RemoteIP(ctx context.Context) net.IP {
+	state, ok := ctx.Value(remoteAddrKey{}).(*remoteAddrState)
+	if !ok {
+		return nil
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if addr, ok := state.addr.(*net.TCPAddr); ok {
+		return addr.IP
+	}
+
+	return nil
+}
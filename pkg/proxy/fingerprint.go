@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+)
+
+// Fingerprint determines the TLS parameters offered to upstream servers, which
+// are used by servers to fingerprint clients (e.g. JA3).
+//
+// Note: `crypto/tls` doesn't allow control over the order of cipher suites and
+// extensions, nor GREASE values, so fingerprints are approximated: the offered
+// cipher suites, curves, ALPN protocols and versions match.
+type Fingerprint string
+
+const (
+	// FingerprintGo uses the Go defaults.
+	FingerprintGo Fingerprint = "go"
+	// FingerprintChrome mimics Chrome.
+	FingerprintChrome Fingerprint = "chrome"
+	// FingerprintFirefox mimics Firefox.
+	FingerprintFirefox Fingerprint = "firefox"
+	// FingerprintClient replays the parameters of the ClientHello that the
+	// proxy received from the client, for HTTPS requests.
+	FingerprintClient Fingerprint = "client"
+)
+
+var ErrUnknownFingerprint = errors.New("proxy: unknown fingerprint")
+
+type clientHelloKey struct{}
+
+// ClientHello holds the parameters of a TLS ClientHello.
+type ClientHello struct {
+	ServerName        string
+	CipherSuites      []uint16
+	SupportedCurves   []tls.CurveID
+	SupportedProtos   []string
+	SupportedVersions []uint16
+}
+
+var browserFingerprints = map[Fingerprint]ClientHello{
+	FingerprintChrome: {
+		CipherSuites: []uint16{
+			tls.TLS_AES_128_GCM_SHA256,
+			tls.TLS_AES_256_GCM_SHA384,
+			tls.TLS_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		},
+		SupportedCurves:   []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384},
+		SupportedProtos:   []string{"h2", "http/1.1"},
+		SupportedVersions: []uint16{tls.VersionTLS13, tls.VersionTLS12},
+	},
+	FingerprintFirefox: {
+		CipherSuites: []uint16{
+			tls.TLS_AES_128_GCM_SHA256,
+			tls.TLS_CHACHA20_POLY1305_SHA256,
+			tls.TLS_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+		},
+		SupportedCurves:   []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521},
+		SupportedProtos:   []string{"h2", "http/1.1"},
+		SupportedVersions: []uint16{tls.VersionTLS13, tls.VersionTLS12},
+	},
+}
+
+// ParseFingerprint parses a fingerprint name. An empty name is parsed as
+// FingerprintGo.
+func ParseFingerprint(s string) (Fingerprint, error) {
+	switch fp := Fingerprint(s); fp {
+	case "":
+		return FingerprintGo, nil
+	case FingerprintGo, FingerprintChrome, FingerprintFirefox, FingerprintClient:
+		return fp, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFingerprint, s)
+	}
+}
+
+func newClientHello(info *tls.ClientHelloInfo) *ClientHello {
+	return &ClientHello{
+		ServerName:        info.ServerName,
+		CipherSuites:      info.CipherSuites,
+		SupportedCurves:   info.SupportedCurves,
+		SupportedProtos:   info.SupportedProtos,
+		SupportedVersions: info.SupportedVersions,
+	}
+}
+
+// TLSConfig returns the TLS config for upstream connections. For
+// FingerprintClient, hello is the ClientHello received from the client; when
+// it's nil, the Go defaults are used.
+func (fp Fingerprint) TLSConfig(serverName string, hello *ClientHello) *tls.Config {
+	cfg := &tls.Config{
+		ServerName: serverName,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	if fp == FingerprintClient {
+		if hello == nil {
+			return cfg
+		}
+	} else {
+		browserHello, ok := browserFingerprints[fp]
+		if !ok {
+			return cfg
+		}
+
+		hello = &browserHello
+	}
+
+	cfg.CipherSuites = supportedCipherSuites(hello.CipherSuites)
+	cfg.CurvePreferences = supportedCurves(hello.SupportedCurves)
+
+	// Only offer protocols that the transport can speak.
+	cfg.NextProtos = nil
+
+	for _, proto := range hello.SupportedProtos {
+		if proto == "h2" || proto == "http/1.1" {
+			cfg.NextProtos = append(cfg.NextProtos, proto)
+		}
+	}
+
+	for _, version := range hello.SupportedVersions {
+		if version < tls.VersionTLS10 || version > tls.VersionTLS13 {
+			// E.g. GREASE values.
+			continue
+		}
+
+		if cfg.MinVersion == 0 || version < cfg.MinVersion {
+			cfg.MinVersion = version
+		}
+
+		if version > cfg.MaxVersion {
+			cfg.MaxVersion = version
+		}
+	}
+
+	return cfg
+}
+
+func supportedCipherSuites(ids []uint16) []uint16 {
+	known := make(map[uint16]bool)
+
+	for _, suite := range tls.CipherSuites() {
+		known[suite.ID] = true
+	}
+
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.ID] = true
+	}
+
+	var suites []uint16
+
+	for _, id := range ids {
+		if known[id] {
+			suites = append(suites, id)
+		}
+	}
+
+	return suites
+}
+
+func supportedCurves(ids []tls.CurveID) []tls.CurveID {
+	var curves []tls.CurveID
+
+	for _, id := range ids {
+		switch id {
+		case tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521:
+			curves = append(curves, id)
+		}
+	}
+
+	return curves
+}
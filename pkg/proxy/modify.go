@@ -1,6 +1,10 @@
 package proxy
 
-import "net/http"
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
 
 var (
 	nopReqModifier = func(req *http.Request) {}
@@ -22,3 +26,84 @@ type ResponseModifyFunc func(res *http.Response) error
 // ResponseModifyMiddleware defines a type for chaining response modifier
 // middleware.
 type ResponseModifyMiddleware func(ResponseModifyFunc) ResponseModifyFunc
+
+type reqModifier struct {
+	id int
+	fn RequestModifyMiddleware
+}
+
+type resModifier struct {
+	id int
+	fn ResponseModifyMiddleware
+}
+
+// recoverRequestModifier applies a request modifier middleware to next, so that
+// panics in the modifier are recovered and logged. If the modifier panics
+// before it calls next, next is called, so that other modifiers still run.
+func recoverRequestModifier(mw RequestModifyMiddleware, next RequestModifyFunc) (fn RequestModifyFunc) {
+	defer func() {
+		if v := recover(); v != nil {
+			logPanic("request modifier", v)
+			fn = next
+		}
+	}()
+
+	calledNext := false
+
+	modify := mw(func(req *http.Request) {
+		calledNext = true
+		next(req)
+	})
+
+	return func(req *http.Request) {
+		defer func() {
+			if v := recover(); v != nil {
+				logPanic("request modifier", v)
+
+				if !calledNext {
+					next(req)
+				}
+			}
+		}()
+
+		modify(req)
+	}
+}
+
+// recoverResponseModifier applies a response modifier middleware to next, so
+// that panics in the modifier are recovered and logged. If the modifier panics
+// before it calls next, next is called, so that other modifiers still run.
+func recoverResponseModifier(mw ResponseModifyMiddleware, next ResponseModifyFunc) (fn ResponseModifyFunc) {
+	defer func() {
+		if v := recover(); v != nil {
+			logPanic("response modifier", v)
+			fn = next
+		}
+	}()
+
+	calledNext := false
+
+	modify := mw(func(res *http.Response) error {
+		calledNext = true
+		return next(res)
+	})
+
+	return func(res *http.Response) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				logPanic("response modifier", v)
+
+				err = nil
+				if !calledNext {
+					err = next(res)
+				}
+			}
+		}()
+
+		return modify(res)
+	}
+}
+
+func logPanic(name string, v interface{}) {
+	log.Printf("[ERROR] Recovered from panic in %v: %v\n%s", name, v, debug.Stack())
+}
@@ -33,15 +33,3 @@ func (l *OnceAcceptListener) Close() error {
 func (l *OnceAcceptListener) Addr() net.Addr {
 	return l.c.LocalAddr()
 }
-
-// ConnNotify embeds net.Conn and adds a channel field for notifying
-// that the connection was closed.
-type ConnNotify struct {
-	net.Conn
-	closed chan struct{}
-}
-
-func (c *ConnNotify) Close() {
-	c.Conn.Close()
-	c.closed <- struct{}{}
-}
@@ -7,64 +7,351 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"sync"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/tracing"
 )
 
 type contextKey int
 
-const ReqLogIDKey contextKey = 0
+const (
+	ReqLogIDKey contextKey = iota
+	// CorrelationIDKey is the context key for the ID that relates requests
+	// triggered by the same source, e.g. a sender request and the redirects
+	// that followed.
+	CorrelationIDKey
+	// OriginalURLKey is the context key for the URL (`*url.URL`) of a request
+	// before it was rewritten by a request modifier, e.g. a remote mapping.
+	OriginalURLKey
+	// InternalRequestKey is the context key (bool) of requests that are sent
+	// with `Proxy.RoundTrip`, i.e. by Hetty itself rather than a proxy client.
+	InternalRequestKey
+)
 
 // Proxy implements http.Handler and offers MITM behaviour for modifying
 // HTTP requests and responses.
 type Proxy struct {
 	certConfig *CertConfig
 	handler    http.Handler
+	tracer     *tracing.Tracer
+
+	// mu guards the fields below, which can be changed while the proxy handles
+	// requests. Slices are replaced rather than modified in place, so that a
+	// snapshot can be used without holding the lock.
+	mu sync.RWMutex
+	// Transport for upstream requests, built from the fingerprint and
+	// transport config.
+	transport       http.RoundTripper
+	fingerprint     Fingerprint
+	transportConfig TransportConfig
+	reqModifiers    []reqModifier
+	resModifiers    []resModifier
+	connHandlers    []ConnectionHandler
+	errHandlers     []RequestErrorHandler
+	retryHandlers   []RetryHandler
+	responders      []Responder
+	nextModifierID  int
+
+	proxyAuthRequired bool
+
+	blockRules []BlockRule
+
+	rawCapture          bool
+	rawCaptureTransport http.RoundTripper
+	rawHandlers         []RawCaptureHandler
+
+	// Active CONNECT tunnels, by client connection.
+	tunnels   map[net.Conn]struct{}
+	tunnelsMu sync.Mutex
+	tunnelsWG sync.WaitGroup
+}
 
-	// TODO: Add mutex for modifier funcs.
-	reqModifiers []RequestModifyMiddleware
-	resModifiers []ResponseModifyMiddleware
+// Config is used to create a Proxy.
+type Config struct {
+	// CA certificate and private key, used to generate leaf certificates.
+	CACert *x509.Certificate
+	CAKey  crypto.PrivateKey
+	// Transport settings for upstream requests.
+	Transport TransportConfig
+	// Records spans of proxied requests and tunnels. Optional.
+	Tracer *tracing.Tracer
 }
 
 // NewProxy returns a new Proxy.
-func NewProxy(ca *x509.Certificate, key crypto.PrivateKey) (*Proxy, error) {
-	certConfig, err := NewCertConfig(ca, key)
+func NewProxy(cfg Config) (*Proxy, error) {
+	certConfig, err := NewCertConfig(cfg.CACert, cfg.CAKey)
 	if err != nil {
 		return nil, err
 	}
 
 	p := &Proxy{
-		certConfig:   certConfig,
-		reqModifiers: make([]RequestModifyMiddleware, 0),
-		resModifiers: make([]ResponseModifyMiddleware, 0),
+		certConfig:          certConfig,
+		tracer:              cfg.Tracer,
+		transport:           newTimeoutTransport(FingerprintGo, cfg.Transport),
+		rawCaptureTransport: newRawCaptureTransport(FingerprintGo, cfg.Transport),
+		fingerprint:         FingerprintGo,
+		transportConfig:     cfg.Transport,
+		reqModifiers:        make([]reqModifier, 0),
+		resModifiers:        make([]resModifier, 0),
+		tunnels:             make(map[net.Conn]struct{}),
 	}
 
 	p.handler = &httputil.ReverseProxy{
 		Director:       p.modifyRequest,
 		ModifyResponse: p.modifyResponse,
-		ErrorHandler:   errorHandler,
+		ErrorHandler:   p.errorHandler,
+		Transport: transportFunc(func(req *http.Request) (*http.Response, error) {
+			// Request modifiers (e.g. rewrite rules) can change the URL, so
+			// block rules are checked again before sending it upstream.
+			if p.blocked(req) {
+				return blockedResponse(req), nil
+			}
+
+			if res := p.respond(req); res != nil {
+				return res, nil
+			}
+
+			if state, ok := req.Context().Value(rawCaptureKey{}).(*rawCaptureState); ok {
+				return traceRoundTrip(req, func(req *http.Request) (*http.Response, error) {
+					return p.rawCaptureRoundTrip(req, state)
+				})
+			}
+
+			return traceRoundTrip(req, p.retryRoundTrip)
+		}),
 	}
 
 	return p, nil
 }
 
 func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = withClientUsername(r)
+
+	if !p.requireProxyAuth(w, r) {
+		return
+	}
+
+	spanName := "proxy.request"
 	if r.Method == http.MethodConnect {
-		p.handleConnect(w)
+		spanName = "proxy.tunnel"
+	}
+
+	ctx, span := p.tracer.Start(r.Context(), spanName,
+		tracing.String("http.request.method", r.Method),
+		tracing.String("server.address", r.Host),
+	)
+	defer span.End()
+
+	if span != nil {
+		r = r.WithContext(ctx)
+	}
+
+	if r.Method == http.MethodConnect {
+		if p.blockedTunnel(r.Host) {
+			writeBlocked(w)
+			return
+		}
+
+		p.handleConnect(w, r)
+
+		return
+	}
+
+	if p.blocked(r) {
+		writeBlocked(w)
+		return
+	}
+
+	if p.RawCapture() {
+		p.serveRawCapture(w, r)
 		return
 	}
 
 	p.handler.ServeHTTP(w, r)
 }
 
-func (p *Proxy) UseRequestModifier(fn ...RequestModifyMiddleware) {
-	p.reqModifiers = append(p.reqModifiers, fn...)
+// RoundTrip implements http.RoundTripper. It sends req upstream with the
+// request and response modifiers applied, so that requests made by Hetty itself
+// (e.g. content discovery) are handled like proxied traffic.
+func (p *Proxy) RoundTrip(req *http.Request) (*http.Response, error) {
+	outReq := req.Clone(context.WithValue(req.Context(), InternalRequestKey, true))
+	if req.ContentLength == 0 {
+		outReq.Body = nil
+	}
+
+	if outReq.Header == nil {
+		outReq.Header = make(http.Header)
+	}
+
+	if p.blocked(outReq) {
+		return blockedResponse(outReq), nil
+	}
+
+	p.modifyRequest(outReq)
+
+	var (
+		res *http.Response
+		err error
+	)
+
+	if p.blocked(outReq) {
+		res = blockedResponse(outReq)
+	} else {
+		res = p.respond(outReq)
+	}
+
+	if res == nil {
+		res, err = traceRoundTrip(outReq, p.retryRoundTrip)
+	}
+
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			p.handleRequestError(outReq, err)
+		}
+
+		return nil, err
+	}
+
+	if err := p.modifyResponse(res); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// SetUpstreamFingerprint sets the TLS fingerprint used for upstream requests.
+func (p *Proxy) SetUpstreamFingerprint(fp Fingerprint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.fingerprint = fp
+	p.replaceTransport()
+}
+
+// SetTransportConfig replaces the transport for upstream requests with one
+// that's configured with cfg.
+func (p *Proxy) SetTransportConfig(cfg TransportConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.transportConfig = cfg
+	p.replaceTransport()
+}
+
+// TransportConfig returns the transport settings for upstream requests.
+func (p *Proxy) TransportConfig() TransportConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.transportConfig
+}
+
+// replaceTransport builds new upstream transports. Idle connections of the
+// previous transport are closed; requests in flight aren't affected. The lock
+// must be held.
+func (p *Proxy) replaceTransport() {
+	if old, ok := p.transport.(*timeoutTransport); ok {
+		defer old.CloseIdleConnections()
+	}
+
+	p.transport = newTimeoutTransport(p.fingerprint, p.transportConfig)
+	p.rawCaptureTransport = newRawCaptureTransport(p.fingerprint, p.transportConfig)
+}
+
+// SetCertCache replaces the cache of generated leaf certificates.
+func (p *Proxy) SetCertCache(cfg CertCacheConfig) {
+	p.certConfig.SetCache(cfg)
+}
+
+// SetWildcardCerts enables or disables wildcard leaf certificates, see
+// `CertConfig.SetWildcard`.
+func (p *Proxy) SetWildcardCerts(wildcard bool) {
+	p.certConfig.SetWildcard(wildcard)
 }
 
-func (p *Proxy) UseResponseModifier(fn ...ResponseModifyMiddleware) {
-	p.resModifiers = append(p.resModifiers, fn...)
+// PregenerateCerts generates and caches leaf certificates for hostnames.
+func (p *Proxy) PregenerateCerts(hostnames []string) error {
+	return p.certConfig.Pregenerate(hostnames)
+}
+
+// UseRequestModifier adds request modifier middleware. It returns a function
+// that removes the added middleware again. Panics in middleware are recovered
+// and logged, and don't affect other middleware.
+func (p *Proxy) UseRequestModifier(fn ...RequestModifyMiddleware) (remove func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.newModifierID()
+	mods := make([]reqModifier, len(p.reqModifiers), len(p.reqModifiers)+len(fn))
+	copy(mods, p.reqModifiers)
+
+	for _, f := range fn {
+		mods = append(mods, reqModifier{id: id, fn: f})
+	}
+
+	p.reqModifiers = mods
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		mods := make([]reqModifier, 0, len(p.reqModifiers))
+
+		for _, mod := range p.reqModifiers {
+			if mod.id != id {
+				mods = append(mods, mod)
+			}
+		}
+
+		p.reqModifiers = mods
+	}
+}
+
+// UseResponseModifier adds response modifier middleware. It returns a
+// function that removes the added middleware again. Panics in middleware are
+// recovered and logged, and don't affect other middleware.
+func (p *Proxy) UseResponseModifier(fn ...ResponseModifyMiddleware) (remove func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.newModifierID()
+	mods := make([]resModifier, len(p.resModifiers), len(p.resModifiers)+len(fn))
+	copy(mods, p.resModifiers)
+
+	for _, f := range fn {
+		mods = append(mods, resModifier{id: id, fn: f})
+	}
+
+	p.resModifiers = mods
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		mods := make([]resModifier, 0, len(p.resModifiers))
+
+		for _, mod := range p.resModifiers {
+			if mod.id != id {
+				mods = append(mods, mod)
+			}
+		}
+
+		p.resModifiers = mods
+	}
+}
+
+// newModifierID returns an ID for modifiers added in a single call. The lock
+// must be held.
+func (p *Proxy) newModifierID() int {
+	p.nextModifierID++
+	return p.nextModifierID
 }
 
 func (p *Proxy) modifyRequest(r *http.Request) {
@@ -78,20 +365,42 @@ func (p *Proxy) modifyRequest(r *http.Request) {
 	// set this header.
 	r.Header["X-Forwarded-For"] = nil
 
+	p.mu.RLock()
+	mods := p.reqModifiers
+	p.mu.RUnlock()
+
 	fn := nopReqModifier
+	traced := tracing.SpanFromContext(r.Context()) != nil
+
+	for i := len(mods) - 1; i >= 0; i-- {
+		mw := mods[i].fn
+		if traced {
+			mw = traceRequestModifier(i, mw)
+		}
 
-	for i := len(p.reqModifiers) - 1; i >= 0; i-- {
-		fn = p.reqModifiers[i](fn)
+		fn = recoverRequestModifier(mw, fn)
 	}
 
 	fn(r)
+
+	*r = *traceRemoteAddr(r)
 }
 
 func (p *Proxy) modifyResponse(res *http.Response) error {
+	p.mu.RLock()
+	mods := p.resModifiers
+	p.mu.RUnlock()
+
 	fn := nopResModifier
+	traced := res.Request != nil && tracing.SpanFromContext(res.Request.Context()) != nil
 
-	for i := len(p.resModifiers) - 1; i >= 0; i-- {
-		fn = p.resModifiers[i](fn)
+	for i := len(mods) - 1; i >= 0; i-- {
+		mw := mods[i].fn
+		if traced {
+			mw = traceResponseModifier(i, mw)
+		}
+
+		fn = recoverResponseModifier(mw, fn)
 	}
 
 	return fn(res)
@@ -99,8 +408,10 @@ func (p *Proxy) modifyResponse(res *http.Response) error {
 
 // handleConnect hijacks the incoming HTTP request and sets up an HTTP tunnel.
 // During the TLS handshake with the client, we use the proxy's CA config to
-// create a certificate on-the-fly.
-func (p *Proxy) handleConnect(w http.ResponseWriter) {
+// create a certificate on-the-fly. Tunnels with non-TLS traffic are passed
+// through to the CONNECT target, and TLS tunnels that don't carry HTTP are
+// relayed; the raw bytes of both are captured.
+func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
 	hj, ok := w.(http.Hijacker)
 	if !ok {
 		log.Printf("[ERROR] handleConnect: ResponseWriter is not a http.Hijacker (type: %T)", w)
@@ -111,52 +422,280 @@ func (p *Proxy) handleConnect(w http.ResponseWriter) {
 
 	w.WriteHeader(http.StatusOK)
 
-	clientConn, _, err := hj.Hijack()
+	rawConn, _, err := hj.Hijack()
 	if err != nil {
 		log.Printf("[ERROR] Hijacking client connection failed: %v", err)
 		writeError(w, http.StatusServiceUnavailable)
 
 		return
 	}
-	defer clientConn.Close()
+	defer rawConn.Close()
+
+	p.trackTunnel(rawConn)
+	defer p.untrackTunnel(rawConn)
+
+	countConn := &countConn{Conn: rawConn}
+	conn := Connection{
+		ClientAddr: r.RemoteAddr,
+		Host:       r.Host,
+		Mode:       ConnectionModeMITM,
+		StartedAt:  time.Now(),
+	}
+
+	defer func() {
+		conn.BytesUp = countConn.BytesRead()
+		conn.BytesDown = countConn.BytesWritten()
+		conn.Duration = time.Since(conn.StartedAt)
+		p.handleConnectionClose(conn)
+
+		log.Printf("[DEBUG] Closed %v tunnel to %v for %v after %v (up: %v bytes, down: %v bytes, error: %v)",
+			conn.Mode, conn.Host, conn.ClientAddr, conn.Duration, conn.BytesUp, conn.BytesDown, conn.Err)
+	}()
+
+	// The setup of the tunnel, until it's known whether it carries HTTP.
+	// The span is ended once, either when the tunnel is known to carry HTTP,
+	// or when the tunnel is closed.
+	_, setupSpan := tracing.Start(r.Context(), "proxy.tunnel_setup")
+	setupEnded := false
+
+	defer func() {
+		if setupEnded {
+			return
+		}
+
+		setupSpan.SetAttributes(tracing.String("hetty.connection.mode", string(conn.Mode)))
+		setupSpan.RecordError(conn.Err)
+		setupSpan.End()
+	}()
+
+	peekConn := newPeekConn(countConn)
+
+	isTLS, err := peekConn.isTLS()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			conn.Err = err
+		}
+
+		return
+	}
+
+	if !isTLS {
+		conn.Mode = ConnectionModePassthrough
+		conn.Capture, conn.Err = passthrough(peekConn, r.Host, p.TransportConfig())
+
+		return
+	}
 
 	// Secure connection to client.
-	clientConn, err = p.clientTLSConn(clientConn)
+	clientConn, hello, err := p.clientTLSConn(peekConn)
 	if err != nil {
 		log.Printf("[ERROR] Securing client connection failed: %v", err)
+		conn.Err = err
+
 		return
 	}
 
-	clientConnNotify := ConnNotify{clientConn, make(chan struct{})}
-	l := &OnceAcceptListener{clientConnNotify.Conn}
+	if hello != nil {
+		conn.ServerName = hello.ServerName
+	}
+
+	tlsPeekConn := newPeekConn(clientConn)
+
+	isHTTP, err := tlsPeekConn.isHTTP()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			conn.Err = err
+		}
 
-	err = http.Serve(l, p)
+		return
+	}
+
+	// Other protocols are relayed as is, so that they can be inspected as raw
+	// bytes.
+	if !isHTTP {
+		conn.Capture, conn.Err = relayTLS(tlsPeekConn, r.Host, conn.ServerName, p.TransportConfig())
+		return
+	}
+
+	setupSpan.SetAttributes(
+		tracing.String("hetty.connection.mode", string(conn.Mode)),
+		tracing.String("tls.server_name", conn.ServerName),
+	)
+	setupSpan.End()
+	setupEnded = true
+
+	var tunnelConn net.Conn = tlsPeekConn
+
+	// With raw capture, the bytes received from the client are recorded, and
+	// the connection is closed after a single request.
+	rawCapture := p.RawCapture()
+	if rawCapture {
+		tunnelConn = &recordingConn{Conn: tlsPeekConn}
+	}
+
+	l := &OnceAcceptListener{tunnelConn}
+	closed := make(chan struct{})
+
+	srv := &http.Server{
+		Handler: p,
+		// The client's ClientHello is stored on the request context, so that it
+		// can be replayed for the upstream connection.
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			if recConn, ok := c.(*recordingConn); ok {
+				ctx = context.WithValue(ctx, clientConnKey{}, recConn)
+			}
+
+			// Requests in the tunnel are attributed to the client of the
+			// CONNECT request.
+			if username := ClientUsername(r.Context()); username != "" {
+				ctx = context.WithValue(ctx, clientUsernameKey{}, username)
+			}
+
+			// Spans of requests in the tunnel are children of the tunnel span.
+			ctx = tracing.ContextWithSpan(ctx, tracing.SpanFromContext(r.Context()))
+
+			return context.WithValue(ctx, clientHelloKey{}, hello)
+		},
+		// Serve returns once the connection is accepted, so the tunnel is
+		// tracked until the connection is closed.
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateClosed || state == http.StateHijacked {
+				close(closed)
+			}
+		},
+	}
+
+	srv.SetKeepAlivesEnabled(!rawCapture)
+
+	err = srv.Serve(l)
 	if err != nil && !errors.Is(err, ErrAlreadyAccepted) {
 		log.Printf("[ERROR] Serving HTTP request failed: %v", err)
 	}
 
-	<-clientConnNotify.closed
+	<-closed
+}
+
+// Shutdown waits for active CONNECT tunnels to be closed by clients. When ctx
+// is done first, the remaining tunnels are closed. The `http.Server` serving
+// the proxy should be shut down first, so that no new tunnels are opened.
+func (p *Proxy) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		p.tunnelsWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.tunnelsMu.Lock()
+	for conn := range p.tunnels {
+		conn.Close()
+	}
+	p.tunnelsMu.Unlock()
+
+	// Wait for tunnel handlers to return, so that connection handlers are done.
+	<-done
+
+	return ctx.Err()
+}
+
+func (p *Proxy) trackTunnel(conn net.Conn) {
+	p.tunnelsMu.Lock()
+	defer p.tunnelsMu.Unlock()
+
+	p.tunnels[conn] = struct{}{}
+	p.tunnelsWG.Add(1)
 }
 
-func (p *Proxy) clientTLSConn(conn net.Conn) (*tls.Conn, error) {
+func (p *Proxy) untrackTunnel(conn net.Conn) {
+	p.tunnelsMu.Lock()
+	defer p.tunnelsMu.Unlock()
+
+	delete(p.tunnels, conn)
+	p.tunnelsWG.Done()
+}
+
+func (p *Proxy) clientTLSConn(conn net.Conn) (*tls.Conn, *ClientHello, error) {
 	tlsConfig := p.certConfig.TLSConfig()
 
+	var hello *ClientHello
+
+	getCertificate := tlsConfig.GetCertificate
+	tlsConfig.GetCertificate = func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		hello = newClientHello(info)
+		return getCertificate(info)
+	}
+
 	tlsConn := tls.Server(conn, tlsConfig)
 	if err := tlsConn.Handshake(); err != nil {
 		tlsConn.Close()
-		return nil, fmt.Errorf("handshake error: %w", err)
+		return nil, nil, fmt.Errorf("handshake error: %w", err)
 	}
 
-	return tlsConn, nil
+	return tlsConn, hello, nil
+}
+
+type transportFunc func(req *http.Request) (*http.Response, error)
+
+func (fn transportFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
 }
 
-func errorHandler(w http.ResponseWriter, r *http.Request, err error) {
+// RequestErrorHandler is called when a proxied request fails upstream, e.g.
+// because of a DNS error, timeout or TLS failure. The request has the request
+// modifiers applied, so values set on its context (e.g. `ReqLogIDKey`) can be
+// used to correlate the error.
+type RequestErrorHandler func(req *http.Request, err error)
+
+// OnRequestError registers handlers that are called when a proxied request
+// fails upstream. Requests canceled by the client aren't reported.
+func (p *Proxy) OnRequestError(fn ...RequestErrorHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	handlers := make([]RequestErrorHandler, len(p.errHandlers), len(p.errHandlers)+len(fn))
+	copy(handlers, p.errHandlers)
+	p.errHandlers = append(handlers, fn...)
+}
+
+func (p *Proxy) handleRequestError(req *http.Request, err error) {
+	p.mu.RLock()
+	handlers := p.errHandlers
+	p.mu.RUnlock()
+
+	for _, fn := range handlers {
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					logPanic("request error handler", v)
+				}
+			}()
+
+			fn(req, err)
+		}()
+	}
+}
+
+func (p *Proxy) errorHandler(w http.ResponseWriter, r *http.Request, err error) {
 	if errors.Is(err, context.Canceled) {
 		return
 	}
 
+	p.handleRequestError(r, err)
+
 	log.Printf("[ERROR]: Proxy error: %v", err)
 
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		w.WriteHeader(http.StatusGatewayTimeout)
+		return
+	}
+
 	w.WriteHeader(http.StatusBadGateway)
 }
 
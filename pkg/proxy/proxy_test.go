@@ -0,0 +1,835 @@
+package proxy_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/ratelimit"
+	"github.com/dstotijn/hetty/pkg/tracing"
+)
+
+func newTestProxy(t *testing.T) *proxy.Proxy {
+	t.Helper()
+
+	return newTestProxyWithTracer(t, nil)
+}
+
+func newTestProxyWithTracer(t *testing.T, tracer *tracing.Tracer) *proxy.Proxy {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Hetty test CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := proxy.NewProxy(proxy.Config{CACert: ca, CAKey: key, Tracer: tracer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestUseModifierRemove(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Foo", r.Header.Get("X-Foo"))
+	}))
+	defer ts.Close()
+
+	p := newTestProxy(t)
+
+	removeReqMod := p.UseRequestModifier(func(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+		return func(req *http.Request) {
+			next(req)
+			req.Header.Set("X-Foo", "bar")
+		}
+	})
+
+	var resModCalls int
+
+	removeResMod := p.UseResponseModifier(func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+		return func(res *http.Response) error {
+			resModCalls++
+			return next(res)
+		}
+	})
+
+	roundTrip := func() *http.Response {
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := p.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+
+		return res
+	}
+
+	if got := roundTrip().Header.Get("X-Foo"); got != "bar" {
+		t.Fatalf("expected request modifier to set header (expected: %q, got: %q)", "bar", got)
+	}
+
+	removeReqMod()
+	removeResMod()
+
+	if got := roundTrip().Header.Get("X-Foo"); got != "" {
+		t.Fatalf("expected removed request modifier not to run, got header: %q", got)
+	}
+
+	if resModCalls != 1 {
+		t.Fatalf("expected removed response modifier not to run (expected calls: 1, got: %v)", resModCalls)
+	}
+}
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, spans...)
+
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer ts.Close()
+
+	exporter := &recordingExporter{}
+	tracer := tracing.NewTracer(tracing.Config{Exporter: exporter})
+	p := newTestProxyWithTracer(t, tracer)
+
+	p.UseRequestModifier(func(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+		return next
+	})
+	p.UseResponseModifier(func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+		return next
+	})
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ts.URL, nil))
+
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("expected status code %v, got: %v", http.StatusTeapot, rec.Code)
+	}
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error shutting down tracer: %v", err)
+	}
+
+	spans := make(map[string]tracing.SpanData)
+	for _, span := range exporter.spans {
+		spans[span.Name] = span
+	}
+
+	root, ok := spans["proxy.request"]
+	if !ok {
+		t.Fatalf("expected request span, got: %+v", exporter.spans)
+	}
+
+	for _, name := range []string{"proxy.request_modifier", "proxy.upstream_round_trip", "proxy.response_modifier"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("expected %v span, got: %+v", name, exporter.spans)
+			continue
+		}
+
+		if span.TraceID != root.TraceID || span.ParentSpanID != root.SpanID {
+			t.Errorf("expected %v span to be a child of the request span, got: %+v", name, span)
+		}
+	}
+
+	roundTrip := spans["proxy.upstream_round_trip"]
+	exp := tracing.Int("http.response.status_code", http.StatusTeapot)
+
+	if attrs := roundTrip.Attributes; len(attrs) == 0 || attrs[len(attrs)-1] != exp {
+		t.Errorf("expected status code attribute, got: %+v", attrs)
+	}
+}
+
+func TestModifierPanicRecovery(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Foo", r.Header.Get("X-Foo"))
+	}))
+	defer ts.Close()
+
+	p := newTestProxy(t)
+
+	p.UseRequestModifier(func(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+		return func(req *http.Request) {
+			panic("foobar")
+		}
+	}, func(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+		return func(req *http.Request) {
+			next(req)
+			req.Header.Set("X-Foo", "bar")
+		}
+	})
+
+	var resModCalls int
+
+	p.UseResponseModifier(func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+		panic("foobar")
+	}, func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+		return func(res *http.Response) error {
+			resModCalls++
+			return next(res)
+		}
+	})
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := p.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if got := res.Header.Get("X-Foo"); got != "bar" {
+		t.Fatalf("expected next request modifier to run (expected header: %q, got: %q)", "bar", got)
+	}
+
+	if resModCalls != 1 {
+		t.Fatalf("expected next response modifier to run (expected calls: 1, got: %v)", resModCalls)
+	}
+}
+
+func TestOnRequestError(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Close()
+
+	p := newTestProxy(t)
+
+	p.UseRequestModifier(func(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+		return func(req *http.Request) {
+			next(req)
+			req.Header.Set("X-Foo", "bar")
+		}
+	})
+
+	var (
+		gotReq *http.Request
+		gotErr error
+	)
+
+	p.OnRequestError(func(req *http.Request, err error) {
+		panic("foobar")
+	}, func(req *http.Request, err error) {
+		gotReq, gotErr = req, err
+	})
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.RoundTrip(req); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	if gotErr == nil {
+		t.Fatal("expected request error handler to be called")
+	}
+
+	if got := gotReq.Header.Get("X-Foo"); got != "bar" {
+		t.Fatalf("expected request with modifiers applied (expected header: %q, got: %q)", "bar", got)
+	}
+}
+
+func TestRawCapture(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Foo", "bar")
+		w.Write([]byte("foobar"))
+	}))
+	defer ts.Close()
+
+	p := newTestProxy(t)
+	p.SetRawCapture(true)
+
+	captures := make(chan proxy.RawExchange, 1)
+
+	p.OnRawCapture(func(req *http.Request, raw proxy.RawExchange) {
+		captures <- raw
+	})
+
+	proxySrv := httptest.NewServer(p)
+	defer proxySrv.Close()
+
+	proxyURL, err := url.Parse(proxySrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	res, err := client.Get(ts.URL + "/foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	var raw proxy.RawExchange
+
+	select {
+	case raw = <-captures:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected raw capture handler to be called")
+	}
+
+	if !bytes.HasPrefix(raw.UpstreamRequest, []byte("GET /foo HTTP/1.1\r\n")) {
+		t.Errorf("unexpected upstream request: %q", raw.UpstreamRequest)
+	}
+
+	if !bytes.HasPrefix(raw.UpstreamResponse, []byte("HTTP/1.1 200 OK\r\n")) ||
+		!bytes.HasSuffix(raw.UpstreamResponse, []byte("\r\n\r\nfoobar")) {
+		t.Errorf("unexpected upstream response: %q", raw.UpstreamResponse)
+	}
+
+	// Client requests are only captured in CONNECT tunnels.
+	if raw.ClientRequest != nil || raw.Truncated {
+		t.Errorf("unexpected client request capture: %+v", raw)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer ts.Close()
+
+	p := newTestProxy(t)
+	p.SetTransportConfig(proxy.TransportConfig{
+		RateLimiter: ratelimit.NewLimiter([]ratelimit.Rule{{Host: "127.0.0.1", Rate: 0.001, Burst: 1}}),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := p.RoundTrip(req)
+		if i == 0 {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			res.Body.Close()
+
+			continue
+		}
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected second request to wait for the rate limit, got: %v", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("expected 1 upstream request, got: %v", got)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+
+	var attempts int32
+
+	// The connection of the first two requests is closed without a response.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) > 2 {
+			w.Write([]byte("foobar"))
+			return
+		}
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		conn.(*net.TCPConn).SetLinger(0)
+		conn.Close()
+	}))
+	defer ts.Close()
+
+	p := newTestProxy(t)
+	p.SetTransportConfig(proxy.TransportConfig{
+		Retry: proxy.RetryConfig{MaxRetries: 3, Backoff: time.Millisecond},
+	})
+
+	var gotRetries int
+
+	p.OnRetry(func(req *http.Request, retries int) {
+		gotRetries = retries
+	})
+
+	t.Run("GET request is retried", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := p.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("expected status code 200, got: %v", res.StatusCode)
+		}
+
+		if gotRetries != 2 {
+			t.Fatalf("expected 2 retries, got: %v", gotRetries)
+		}
+	})
+
+	t.Run("POST request isn't retried", func(t *testing.T) {
+		atomic.StoreInt32(&attempts, 0)
+		gotRetries = 0
+
+		req, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader("foo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := p.RoundTrip(req); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+
+		if n := atomic.LoadInt32(&attempts); n != 1 {
+			t.Fatalf("expected 1 attempt, got: %v", n)
+		}
+
+		if gotRetries != 0 {
+			t.Fatalf("expected retry handler not to be called, got: %v retries", gotRetries)
+		}
+	})
+}
+
+func TestIPFamily(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(ts.Close)
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The test server only listens on IPv4, so IPv6 connections (if `localhost`
+	// resolves to `::1`) fail.
+	u.Host = net.JoinHostPort("localhost", u.Port())
+
+	for _, family := range []proxy.IPFamily{proxy.IPFamilyIPv4, proxy.IPFamilyPreferIPv4, proxy.IPFamilyPreferIPv6} {
+		family := family
+
+		t.Run(string(family), func(t *testing.T) {
+			t.Parallel()
+
+			p := newTestProxy(t)
+			p.SetTransportConfig(proxy.TransportConfig{IPFamily: family})
+
+			remoteIP := make(chan net.IP, 1)
+
+			p.UseResponseModifier(func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+				return func(res *http.Response) error {
+					remoteIP <- proxy.RemoteIP(res.Request.Context())
+					return next(res)
+				}
+			})
+
+			req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := p.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			res.Body.Close()
+
+			if ip := <-remoteIP; !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+				t.Fatalf("expected remote IP 127.0.0.1, got: %v", ip)
+			}
+		})
+	}
+
+	t.Run("invalid IP family", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := proxy.ParseIPFamily("ipv5"); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestPassthroughResolver(t *testing.T) {
+	t.Parallel()
+
+	// The upstream server listens on the address that `example.test` resolves
+	// to with the test DNS server, see `dnsAnswer`.
+	ln, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("could not listen on 127.0.0.2: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		conn.Write([]byte("echo: " + line))
+	}()
+
+	dnsConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dnsConn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+
+		for {
+			n, addr, err := dnsConn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			dnsConn.WriteTo(dnsAnswer(t, buf[:n]), addr)
+		}
+	}()
+
+	resolver, err := proxy.NewResolver(dnsConn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := newTestProxy(t)
+	p.SetTransportConfig(proxy.TransportConfig{Resolver: resolver})
+
+	proxySrv := httptest.NewServer(p)
+	t.Cleanup(proxySrv.Close)
+
+	conn, err := net.Dial("tcp", proxySrv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	target := net.JoinHostPort("example.test", port)
+
+	if _, err := conn.Write([]byte("CONNECT " + target + " HTTP/1.1\r\nHost: " + target + "\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, res.StatusCode)
+	}
+
+	// Traffic that isn't TLS is passed through to the resolved address.
+	if _, err := conn.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := br.ReadString('\n')
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if line != "echo: ping\n" {
+		t.Fatalf("expected echoed line, got: %q", line)
+	}
+}
+
+func TestProxyAuthRequired(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	p := newTestProxy(t)
+	p.SetProxyAuthRequired(true)
+
+	usernames := make(chan string, 1)
+	authHeaders := make(chan string, 1)
+
+	p.UseRequestModifier(func(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+		return func(req *http.Request) {
+			next(req)
+			usernames <- proxy.ClientUsername(req.Context())
+			authHeaders <- req.Header.Get("Proxy-Authorization")
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, ts.URL, nil)
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusProxyAuthRequired || rec.Header().Get("Proxy-Authenticate") == "" {
+		t.Fatalf("expected 407 response with challenge, got: %v (headers: %v)", rec.Code, rec.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Proxy-Authorization", "Basic YWxpY2U6c2VjcmV0") // alice:secret
+	rec = httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200, got: %v", rec.Code)
+	}
+
+	if got := <-usernames; got != "alice" {
+		t.Fatalf("expected client username %q, got: %q", "alice", got)
+	}
+
+	// The credentials aren't passed to request modifiers, e.g. for logging.
+	if got := <-authHeaders; got != "" {
+		t.Fatalf("expected no `Proxy-Authorization` header, got: %q", got)
+	}
+}
+
+func TestBlockRules(t *testing.T) {
+	t.Parallel()
+
+	var upstreamRequests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upstreamRequests, 1)
+	}))
+	defer ts.Close()
+
+	p := newTestProxy(t)
+
+	if err := p.SetBlockRules([]proxy.BlockRule{{}}); !errors.Is(err, proxy.ErrInvalidBlockRule) {
+		t.Fatalf("expected `proxy.ErrInvalidBlockRule`, got: %v", err)
+	}
+
+	urlRule, err := proxy.ParseBlockRule(`url:/admin\b`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hostRule, err := proxy.ParseBlockRule("*.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := p.SetBlockRules([]proxy.BlockRule{urlRule, hostRule}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		target string
+		exp    int
+	}{
+		{method: http.MethodGet, target: ts.URL + "/admin/users", exp: http.StatusForbidden},
+		{method: http.MethodGet, target: ts.URL + "/administrator", exp: http.StatusOK},
+		{method: http.MethodConnect, target: "api.example.com:443", exp: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		if tt.method == http.MethodConnect {
+			req.Host = tt.target
+		}
+
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+
+		if rec.Code != tt.exp {
+			t.Errorf("%v %v: expected status code %v, got: %v", tt.method, tt.target, tt.exp, rec.Code)
+		}
+	}
+
+	// Requests sent by Hetty itself are blocked too.
+	req := httptest.NewRequest(http.MethodGet, ts.URL+"/admin", nil)
+	req.RequestURI = ""
+
+	res, err := p.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected status code %v, got: %v", http.StatusForbidden, res.StatusCode)
+	}
+
+	// Block rules are checked again after request modifiers changed the URL.
+	p.UseRequestModifier(func(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+		return func(req *http.Request) {
+			next(req)
+
+			if req.URL.Path == "/rewritten" {
+				req.URL.Path = "/admin"
+			}
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ts.URL+"/rewritten", nil))
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status code %v for rewritten request, got: %v", http.StatusForbidden, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, ts.URL+"/rewritten", nil)
+	req.RequestURI = ""
+
+	res, err = p.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected status code %v for rewritten request, got: %v", http.StatusForbidden, res.StatusCode)
+	}
+
+	if n := atomic.LoadInt32(&upstreamRequests); n != 1 {
+		t.Fatalf("expected 1 upstream request, got: %v", n)
+	}
+}
+
+func TestUseResponder(t *testing.T) {
+	t.Parallel()
+
+	var upstreamRequests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upstreamRequests, 1)
+	}))
+	defer ts.Close()
+
+	p := newTestProxy(t)
+
+	p.UseResponder(func(req *http.Request) *http.Response {
+		if req.URL.Path != "/mapped" {
+			return nil
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusTeapot,
+			Header:     http.Header{},
+			Body:       http.NoBody,
+		}
+	})
+
+	modified := make(chan int, 1)
+
+	p.UseResponseModifier(func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+		return func(res *http.Response) error {
+			modified <- res.StatusCode
+			return next(res)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, ts.URL+"/mapped", nil)
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("expected status code %v, got: %v", http.StatusTeapot, rec.Code)
+	}
+
+	if got := <-modified; got != http.StatusTeapot {
+		t.Fatalf("expected response modifiers to be called with the response, got: %v", got)
+	}
+
+	if n := atomic.LoadInt32(&upstreamRequests); n != 0 {
+		t.Fatalf("expected no upstream requests, got: %v", n)
+	}
+}
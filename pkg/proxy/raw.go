@@ -0,0 +1,256 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+)
+
+// RawExchange holds the bytes of a proxied HTTP/1.x request and its response,
+// as they were sent on the wire, before headers are normalized by `net/http`.
+type RawExchange struct {
+	// ClientRequest is the request as received from the client. It's only
+	// captured for requests in CONNECT tunnels.
+	ClientRequest []byte
+	// UpstreamRequest and UpstreamResponse are the bytes sent to and received
+	// from the upstream server.
+	UpstreamRequest  []byte
+	UpstreamResponse []byte
+	// Truncated is true if more than `MaxCaptureSize` bytes were captured for
+	// either of the above.
+	Truncated bool
+}
+
+// RawCaptureHandler is called with the raw bytes of a proxied request, after
+// the response was written to the client. The request has the request
+// modifiers applied, so values set on its context (e.g. `ReqLogIDKey`) can be
+// used to correlate the capture.
+type RawCaptureHandler func(req *http.Request, raw RawExchange)
+
+type (
+	clientConnKey   struct{}
+	rawCaptureKey   struct{}
+	rawCaptureState struct {
+		mu       sync.Mutex
+		req      *http.Request
+		upstream *recordingConn
+	}
+)
+
+// SetRawCapture enables or disables capturing the raw bytes of proxied
+// requests, e.g. for request smuggling research. While enabled, requests in
+// CONNECT tunnels are handled on a connection of their own (keep-alive is
+// disabled), so that the bytes received from the client belong to a single
+// request. Upstream requests use a new connection for every request, and
+// always use HTTP/1.1. Only requests that are proxied (not those sent with
+// `RoundTrip`) are captured.
+func (p *Proxy) SetRawCapture(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rawCapture = enabled
+}
+
+// RawCapture returns true if raw bytes of proxied requests are captured.
+func (p *Proxy) RawCapture() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.rawCapture
+}
+
+// OnRawCapture registers handlers that are called with the raw bytes of
+// proxied requests, when raw capture is enabled.
+func (p *Proxy) OnRawCapture(fn ...RawCaptureHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	handlers := make([]RawCaptureHandler, len(p.rawHandlers), len(p.rawHandlers)+len(fn))
+	copy(handlers, p.rawHandlers)
+	p.rawHandlers = append(handlers, fn...)
+}
+
+func (p *Proxy) handleRawCapture(req *http.Request, raw RawExchange) {
+	p.mu.RLock()
+	handlers := p.rawHandlers
+	p.mu.RUnlock()
+
+	for _, fn := range handlers {
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					logPanic("raw capture handler", v)
+				}
+			}()
+
+			fn(req, raw)
+		}()
+	}
+}
+
+// serveRawCapture handles a proxied request, and calls the raw capture handlers
+// once the response is written.
+func (p *Proxy) serveRawCapture(w http.ResponseWriter, r *http.Request) {
+	state := &rawCaptureState{}
+	ctx := context.WithValue(r.Context(), rawCaptureKey{}, state)
+
+	p.handler.ServeHTTP(w, r.WithContext(ctx))
+
+	state.mu.Lock()
+	req, upstream := state.req, state.upstream
+	state.mu.Unlock()
+
+	// The request wasn't sent upstream, e.g. due to an invalid URL.
+	if req == nil {
+		return
+	}
+
+	var raw RawExchange
+
+	if client, ok := r.Context().Value(clientConnKey{}).(*recordingConn); ok {
+		var truncated bool
+		raw.ClientRequest, _, truncated = client.captured()
+		raw.Truncated = truncated
+	}
+
+	if upstream != nil {
+		var truncated bool
+		raw.UpstreamResponse, raw.UpstreamRequest, truncated = upstream.captured()
+		raw.Truncated = raw.Truncated || truncated
+	}
+
+	p.handleRawCapture(req, raw)
+}
+
+// rawCaptureRoundTrip sends an upstream request with the raw capture transport,
+// and records the request and its connection on the capture state of the
+// request context.
+func (p *Proxy) rawCaptureRoundTrip(req *http.Request, state *rawCaptureState) (*http.Response, error) {
+	state.mu.Lock()
+	state.req = req
+	state.mu.Unlock()
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if conn, ok := info.Conn.(*recordingConn); ok {
+				state.mu.Lock()
+				state.upstream = conn
+				state.mu.Unlock()
+			}
+		},
+	}
+
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	p.mu.RLock()
+	transport := p.rawCaptureTransport
+	p.mu.RUnlock()
+
+	return transport.RoundTrip(req)
+}
+
+// newRawCaptureTransport returns a transport like newTimeoutTransport, that
+// records the bytes of upstream connections.
+func newRawCaptureTransport(fp Fingerprint, cfg TransportConfig) *timeoutTransport {
+	t := newTimeoutTransport(fp, cfg)
+
+	enableRawCapture(t.defaultTransport, fp, cfg)
+
+	for _, host := range t.hosts {
+		enableRawCapture(host.transport, fp, host.timeouts.apply(cfg))
+	}
+
+	return t
+}
+
+// enableRawCapture configures transport to use a new, recorded connection for
+// every request. TLS connections are recorded after decryption, and only offer
+// HTTP/1.1, so that the raw bytes are HTTP/1.x messages.
+func enableRawCapture(transport *http.Transport, fp Fingerprint, cfg TransportConfig) {
+	dialer := newDialer(cfg)
+	handshakeTimeout := transport.TLSHandshakeTimeout
+
+	transport.DisableKeepAlives = true
+	transport.ForceAttemptHTTP2 = false
+
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &recordingConn{Conn: conn}, nil
+	}
+
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		hello, _ := ctx.Value(clientHelloKey{}).(*ClientHello)
+
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+		defer cancel()
+
+		tlsConfig := fp.TLSConfig(host, hello)
+		tlsConfig.NextProtos = []string{"http/1.1"}
+
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return &recordingConn{Conn: tlsConn}, nil
+	}
+}
+
+// recordingConn embeds net.Conn and captures the first `MaxCaptureSize` bytes
+// read from and written to it.
+type recordingConn struct {
+	net.Conn
+
+	mu      sync.Mutex
+	read    captureWriter
+	written captureWriter
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+
+	c.mu.Lock()
+	c.read.Write(b[:n])
+	c.mu.Unlock()
+
+	return n, err
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+
+	c.mu.Lock()
+	c.written.Write(b[:n])
+	c.mu.Unlock()
+
+	return n, err
+}
+
+// captured returns copies of the bytes that were read and written.
+func (c *recordingConn) captured() (read, written []byte, truncated bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	read = append([]byte(nil), c.read.buf.Bytes()...)
+	written = append([]byte(nil), c.written.buf.Bytes()...)
+
+	return read, written, c.read.truncated || c.written.truncated
+}
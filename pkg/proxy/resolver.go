@@ -0,0 +1,217 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dohContentType = "application/dns-message"
+
+	// maxDNSMessageSize is the maximum size of a DNS message over TCP.
+	maxDNSMessageSize = 65535
+)
+
+// NewResolver returns a resolver for upstream dialing that queries addr, which
+// is either the address of a DNS server (e.g. `10.0.0.1` or `10.0.0.1:5353`), or
+// the URL of a DNS-over-HTTPS endpoint (e.g. `https://1.1.1.1/dns-query`).
+// The DNS server of the OS isn't used, which is useful for environments with
+// split-horizon DNS. The hostname of a DNS-over-HTTPS URL is resolved with the
+// DNS server of the OS, unless it's an IP address.
+func NewResolver(addr string) (*net.Resolver, error) {
+	if strings.HasPrefix(addr, "https://") || strings.HasPrefix(addr, "http://") {
+		return newDoHResolver(addr)
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "53")
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return nil, fmt.Errorf("proxy: invalid DNS server address: %q", addr)
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+
+	return &net.Resolver{
+		PreferGo: true,
+		// The network is `udp`, or `tcp` for truncated responses.
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}, nil
+}
+
+func newDoHResolver(rawURL string) (*net.Resolver, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("proxy: invalid DNS-over-HTTPS URL: %q", rawURL)
+	}
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, client: client, url: u.String()}, nil
+		},
+	}, nil
+}
+
+// dohConn is a net.Conn that sends DNS queries as DNS-over-HTTPS (RFC 8484)
+// requests. It's a stream connection, so queries and responses are prefixed
+// with their length, like DNS over TCP.
+type dohConn struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+
+	mu       sync.Mutex
+	query    bytes.Buffer
+	response bytes.Buffer
+	deadline time.Time
+	closed   bool
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+
+	c.query.Write(b)
+
+	// Send the query once it's complete.
+	for c.query.Len() >= 2 {
+		size := int(binary.BigEndian.Uint16(c.query.Bytes()[:2]))
+		if c.query.Len() < 2+size {
+			break
+		}
+
+		query := make([]byte, 2+size)
+		c.query.Read(query)
+
+		res, err := c.exchange(query[2:])
+		if err != nil {
+			return 0, err
+		}
+
+		var length [2]byte
+		binary.BigEndian.PutUint16(length[:], uint16(len(res)))
+		c.response.Write(length[:])
+		c.response.Write(res)
+	}
+
+	return len(b), nil
+}
+
+func (c *dohConn) exchange(query []byte) ([]byte, error) {
+	ctx := c.ctx
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", dohContentType)
+	req.Header.Set("Accept", dohContentType)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: DNS-over-HTTPS request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy: DNS-over-HTTPS server responded with status %v", res.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxDNSMessageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("proxy: could not read DNS-over-HTTPS response: %w", err)
+	}
+
+	if len(body) > maxDNSMessageSize {
+		return nil, errors.New("proxy: DNS-over-HTTPS response is too large")
+	}
+
+	return body, nil
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+
+	return c.response.Read(b)
+}
+
+func (c *dohConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	return nil
+}
+
+func (c *dohConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deadline = t
+
+	return nil
+}
+
+func (c *dohConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *dohConn) SetWriteDeadline(t time.Time) error {
+	return c.SetDeadline(t)
+}
+
+func (c *dohConn) LocalAddr() net.Addr {
+	return dohAddr{}
+}
+
+func (c *dohConn) RemoteAddr() net.Addr {
+	return dohAddr{url: c.url}
+}
+
+type dohAddr struct {
+	url string
+}
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return a.url }
@@ -0,0 +1,133 @@
+package proxy_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+// dnsAnswer returns a response to query, that resolves A queries for
+// `example.test.` to 127.0.0.2.
+func dnsAnswer(t *testing.T, query []byte) []byte {
+	t.Helper()
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		t.Errorf("could not unpack DNS query: %v", err)
+		return nil
+	}
+
+	res := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: msg.ID, Response: true, RecursionAvailable: true},
+		Questions: msg.Questions,
+	}
+
+	for _, q := range msg.Questions {
+		if q.Type != dnsmessage.TypeA || q.Name.String() != "example.test." {
+			continue
+		}
+
+		res.Answers = append(res.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+			Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 2}},
+		})
+	}
+
+	b, err := res.Pack()
+	if err != nil {
+		t.Errorf("could not pack DNS response: %v", err)
+	}
+
+	return b
+}
+
+func TestNewResolver(t *testing.T) {
+	t.Parallel()
+
+	t.Run("DNS server", func(t *testing.T) {
+		t.Parallel()
+
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		go func() {
+			buf := make([]byte, 512)
+
+			for {
+				n, addr, err := conn.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+
+				conn.WriteTo(dnsAnswer(t, buf[:n]), addr)
+			}
+		}()
+
+		resolver, err := proxy.NewResolver(conn.LocalAddr().String())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		addrs, err := resolver.LookupHost(context.Background(), "example.test")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(addrs) != 1 || addrs[0] != "127.0.0.2" {
+			t.Fatalf("expected [127.0.0.2], got: %v", addrs)
+		}
+	})
+
+	t.Run("DNS-over-HTTPS", func(t *testing.T) {
+		t.Parallel()
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+				http.Error(w, "invalid request", http.StatusBadRequest)
+				return
+			}
+
+			query, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/dns-message")
+			w.Write(dnsAnswer(t, query))
+		}))
+		defer ts.Close()
+
+		resolver, err := proxy.NewResolver(ts.URL + "/dns-query")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		addrs, err := resolver.LookupHost(context.Background(), "example.test")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(addrs) != 1 || addrs[0] != "127.0.0.2" {
+			t.Fatalf("expected [127.0.0.2], got: %v", addrs)
+		}
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := proxy.NewResolver("https://"); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
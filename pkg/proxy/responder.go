@@ -0,0 +1,51 @@
+package proxy
+
+import "net/http"
+
+// Responder returns a response to a proxied request instead of the upstream
+// server, e.g. the contents of a local file, or nil to send the request
+// upstream. The request has the request modifiers applied, and the response
+// is passed to the response modifiers, so that it's logged like other
+// responses.
+type Responder func(req *http.Request) *http.Response
+
+// UseResponder registers responders. The response of the first responder that
+// returns one is used.
+func (p *Proxy) UseResponder(fn ...Responder) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	responders := make([]Responder, len(p.responders), len(p.responders)+len(fn))
+	copy(responders, p.responders)
+	p.responders = append(responders, fn...)
+}
+
+// respond returns the response of the first responder that returns one for
+// req, or nil.
+func (p *Proxy) respond(req *http.Request) (res *http.Response) {
+	p.mu.RLock()
+	responders := p.responders
+	p.mu.RUnlock()
+
+	for _, fn := range responders {
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					logPanic("responder", v)
+				}
+			}()
+
+			res = fn(req)
+		}()
+
+		if res != nil {
+			if res.Request == nil {
+				res.Request = req
+			}
+
+			return res
+		}
+	}
+
+	return nil
+}
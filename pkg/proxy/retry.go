@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// RetryConfig configures retries of upstream requests that failed because the
+// connection was reset, e.g. by a flaky target. Only GET and HEAD requests are
+// retried.
+type RetryConfig struct {
+	// Maximum number of retries of a request. Zero disables retries.
+	MaxRetries int
+	// Time to wait before the first retry, which doubles for every retry
+	// after that. Defaults to 100ms.
+	Backoff time.Duration
+}
+
+// RetryHandler is called when a proxied request was retried, after the last
+// attempt. The request has the request modifiers applied, so values set on its
+// context (e.g. `ReqLogIDKey`) can be used to correlate the retries.
+type RetryHandler func(req *http.Request, retries int)
+
+// OnRetry registers handlers that are called when a proxied request was
+// retried.
+func (p *Proxy) OnRetry(fn ...RetryHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	handlers := make([]RetryHandler, len(p.retryHandlers), len(p.retryHandlers)+len(fn))
+	copy(handlers, p.retryHandlers)
+	p.retryHandlers = append(handlers, fn...)
+}
+
+func (p *Proxy) handleRetry(req *http.Request, retries int) {
+	p.mu.RLock()
+	handlers := p.retryHandlers
+	p.mu.RUnlock()
+
+	for _, fn := range handlers {
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					logPanic("retry handler", v)
+				}
+			}()
+
+			fn(req, retries)
+		}()
+	}
+}
+
+// retryRoundTrip sends req with the upstream transport, and retries it with
+// exponential backoff if it's idempotent and the connection was reset.
+func (p *Proxy) retryRoundTrip(req *http.Request) (*http.Response, error) {
+	p.mu.RLock()
+	transport, cfg := p.transport, p.transportConfig.Retry
+	p.mu.RUnlock()
+
+	backoff := cfg.Backoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	retries := 0
+
+	for {
+		res, err := transport.RoundTrip(req)
+		if err == nil || retries >= cfg.MaxRetries || !isRetryable(req, err) {
+			if retries > 0 {
+				p.handleRetry(req, retries)
+			}
+
+			return res, err
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req.Body = body
+		}
+
+		retries++
+		backoff *= 2
+	}
+}
+
+// isRetryable returns true if req is a GET or HEAD request that can be resent,
+// and err is caused by a connection that was reset or closed by the server.
+func isRetryable(req *http.Request, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
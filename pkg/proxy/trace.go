@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/dstotijn/hetty/pkg/tracing"
+)
+
+// traceRoundTrip sends req with roundTrip, in a span of the request span.
+func traceRoundTrip(req *http.Request, roundTrip func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	ctx, span := tracing.Start(req.Context(), "proxy.upstream_round_trip",
+		tracing.String("http.request.method", req.Method),
+		tracing.String("server.address", req.URL.Host),
+	)
+	if span == nil {
+		return roundTrip(req)
+	}
+	defer span.End()
+
+	tracedReq := req.WithContext(ctx)
+
+	res, err := roundTrip(tracedReq)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Spans of response modifiers are children of the request span, rather
+	// than of the round trip span.
+	if res.Request == tracedReq {
+		res.Request = req
+	}
+
+	span.SetAttributes(tracing.Int("http.response.status_code", res.StatusCode))
+
+	return res, nil
+}
+
+// traceRequestModifier returns mw, recording a span of each call. Spans end
+// when the modifier calls the next one, so that they measure the work of the
+// modifier itself.
+func traceRequestModifier(index int, mw RequestModifyMiddleware) RequestModifyMiddleware {
+	return func(next RequestModifyFunc) RequestModifyFunc {
+		var span *tracing.Span
+
+		modify := mw(func(req *http.Request) {
+			span.End()
+			next(req)
+		})
+
+		return func(req *http.Request) {
+			_, span = tracing.Start(req.Context(), "proxy.request_modifier", tracing.Int("hetty.modifier.index", index))
+			defer span.End()
+
+			modify(req)
+		}
+	}
+}
+
+// traceResponseModifier is like traceRequestModifier, for response modifiers.
+func traceResponseModifier(index int, mw ResponseModifyMiddleware) ResponseModifyMiddleware {
+	return func(next ResponseModifyFunc) ResponseModifyFunc {
+		var span *tracing.Span
+
+		modify := mw(func(res *http.Response) error {
+			span.End()
+			return next(res)
+		})
+
+		return func(res *http.Response) error {
+			_, span = tracing.Start(res.Request.Context(), "proxy.response_modifier", tracing.Int("hetty.modifier.index", index))
+			defer span.End()
+
+			err := modify(res)
+			span.RecordError(err)
+
+			return err
+		}
+	}
+}
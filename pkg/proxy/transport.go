@@ -0,0 +1,335 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/ratelimit"
+)
+
+// TransportConfig configures the transport for upstream requests. Zero values
+// use the defaults of `http.DefaultTransport`.
+type TransportConfig struct {
+	// Maximum number of idle (keep-alive) connections, across all hosts.
+	MaxIdleConns int
+	// Maximum number of idle (keep-alive) connections per host. Defaults to
+	// `http.DefaultMaxIdleConnsPerHost` (2), which causes connection churn when
+	// many requests are sent to a single host, e.g. when fuzzing.
+	MaxIdleConnsPerHost int
+	// Maximum number of connections per host, including active connections.
+	// Zero means no limit.
+	MaxConnsPerHost int
+	// Time an idle connection is kept open.
+	IdleConnTimeout time.Duration
+	// Time to wait for a TCP connection to be established.
+	DialTimeout time.Duration
+	// Time to wait for a TLS handshake.
+	TLSHandshakeTimeout time.Duration
+	// Time to wait for response headers after a request is written. Zero means
+	// no timeout.
+	ResponseHeaderTimeout time.Duration
+	// Total time of a request, from dialing until the response body is read.
+	// Zero means no timeout.
+	RequestTimeout time.Duration
+	// Use a new connection for every request.
+	DisableKeepAlives bool
+	// Timeouts for specific hosts. The first match is used.
+	HostTimeouts []HostTimeouts
+	// IP versions of upstream connections. Defaults to `IPFamilyAny`.
+	IPFamily IPFamily
+	// Time to wait before racing a connection with the other IP version, when
+	// a host has both IPv4 and IPv6 addresses (happy eyeballs). Defaults to
+	// 300ms; a negative value disables racing.
+	FallbackDelay time.Duration
+	// Resolver for upstream hostnames, see `NewResolver`. Defaults to the
+	// resolver of the OS.
+	Resolver *net.Resolver
+	// Retries of idempotent requests that failed because the connection was
+	// reset. Retries are disabled by default.
+	Retry RetryConfig
+	// Limits the rate of upstream requests per host, including retries. Share
+	// it with other modules that send requests, so that their requests count
+	// towards the same limits. Optional.
+	RateLimiter *ratelimit.Limiter
+}
+
+// HostTimeouts overrides the timeouts of TransportConfig for requests to a
+// host. Zero values inherit the global timeouts.
+type HostTimeouts struct {
+	// Hostname, without port. A leading `*.` matches all subdomains, e.g.
+	// `*.example.com`.
+	Host                  string
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	RequestTimeout        time.Duration
+}
+
+// ParseHostTimeouts parses host timeouts in the format
+// `host:dial=5s,tls=5s,header=10s,request=30s`. Timeouts can be omitted.
+func ParseHostTimeouts(s string) (HostTimeouts, error) {
+	i := strings.Index(s, ":")
+	if i == -1 {
+		return HostTimeouts{}, fmt.Errorf("proxy: invalid host timeouts %q: missing timeouts", s)
+	}
+
+	ht := HostTimeouts{Host: strings.TrimSpace(s[:i])}
+	if ht.Host == "" {
+		return HostTimeouts{}, fmt.Errorf("proxy: invalid host timeouts %q: missing host", s)
+	}
+
+	for _, kv := range strings.Split(s[i+1:], ",") {
+		kv = strings.TrimSpace(kv)
+
+		j := strings.Index(kv, "=")
+		if j == -1 {
+			return HostTimeouts{}, fmt.Errorf("proxy: invalid host timeout %q", kv)
+		}
+
+		d, err := time.ParseDuration(kv[j+1:])
+		if err != nil || d < 0 {
+			return HostTimeouts{}, fmt.Errorf("proxy: invalid host timeout %q", kv)
+		}
+
+		switch kv[:j] {
+		case "dial":
+			ht.DialTimeout = d
+		case "tls":
+			ht.TLSHandshakeTimeout = d
+		case "header":
+			ht.ResponseHeaderTimeout = d
+		case "request":
+			ht.RequestTimeout = d
+		default:
+			return HostTimeouts{}, fmt.Errorf("proxy: unknown host timeout %q", kv[:j])
+		}
+	}
+
+	return ht, nil
+}
+
+func (ht HostTimeouts) match(hostname string) bool {
+	return matchHostPattern(ht.Host, hostname)
+}
+
+// matchHostPattern returns true if hostname matches pattern, where a leading
+// `*.` matches subdomains.
+func matchHostPattern(pattern, hostname string) bool {
+	pattern = strings.ToLower(pattern)
+
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(hostname, pattern[1:])
+	}
+
+	return pattern == hostname
+}
+
+// apply returns cfg with the timeouts of ht.
+func (ht HostTimeouts) apply(cfg TransportConfig) TransportConfig {
+	cfg.HostTimeouts = nil
+
+	if ht.DialTimeout > 0 {
+		cfg.DialTimeout = ht.DialTimeout
+	}
+
+	if ht.TLSHandshakeTimeout > 0 {
+		cfg.TLSHandshakeTimeout = ht.TLSHandshakeTimeout
+	}
+
+	if ht.ResponseHeaderTimeout > 0 {
+		cfg.ResponseHeaderTimeout = ht.ResponseHeaderTimeout
+	}
+
+	if ht.RequestTimeout > 0 {
+		cfg.RequestTimeout = ht.RequestTimeout
+	}
+
+	return cfg
+}
+
+// timeoutTransport sends upstream requests with the transport for the host of
+// the request, and applies the total request timeout. Hosts with timeout
+// overrides use a transport, and thus a connection pool, of their own.
+type timeoutTransport struct {
+	defaultTransport *http.Transport
+	requestTimeout   time.Duration
+	hosts            []hostTransport
+	rateLimiter      *ratelimit.Limiter
+}
+
+type hostTransport struct {
+	timeouts       HostTimeouts
+	transport      *http.Transport
+	requestTimeout time.Duration
+}
+
+func newDialer(cfg TransportConfig) *upstreamDialer {
+	dialer := &upstreamDialer{
+		Dialer: net.Dialer{
+			Timeout:       30 * time.Second,
+			KeepAlive:     30 * time.Second,
+			FallbackDelay: cfg.FallbackDelay,
+		},
+		family: cfg.IPFamily,
+	}
+
+	if cfg.DialTimeout > 0 {
+		dialer.Timeout = cfg.DialTimeout
+	}
+
+	if cfg.Resolver != nil {
+		dialer.Resolver = cfg.Resolver
+	}
+
+	return dialer
+}
+
+// newTimeoutTransport returns a transport for upstream requests, configured
+// with cfg, including its per host timeouts.
+func newTimeoutTransport(fp Fingerprint, cfg TransportConfig) *timeoutTransport {
+	t := &timeoutTransport{
+		defaultTransport: newUpstreamTransport(fp, cfg),
+		requestTimeout:   cfg.RequestTimeout,
+		rateLimiter:      cfg.RateLimiter,
+	}
+
+	for _, ht := range cfg.HostTimeouts {
+		hostCfg := ht.apply(cfg)
+
+		t.hosts = append(t.hosts, hostTransport{
+			timeouts:       ht,
+			transport:      newUpstreamTransport(fp, hostCfg),
+			requestTimeout: hostCfg.RequestTimeout,
+		})
+	}
+
+	return t
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, timeout := t.defaultTransport, t.requestTimeout
+	hostname := strings.ToLower(req.URL.Hostname())
+
+	if err := t.rateLimiter.Wait(req.Context(), hostname); err != nil {
+		return nil, err
+	}
+
+	for _, host := range t.hosts {
+		if host.timeouts.match(hostname) {
+			transport, timeout = host.transport, host.requestTimeout
+			break
+		}
+	}
+
+	if timeout <= 0 {
+		return transport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+
+	res, err := transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The timeout applies until the body is read, so the context is canceled
+	// when the body is closed.
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+
+	return res, nil
+}
+
+// CloseIdleConnections closes idle connections of all transports.
+func (t *timeoutTransport) CloseIdleConnections() {
+	t.defaultTransport.CloseIdleConnections()
+
+	for _, host := range t.hosts {
+		host.transport.CloseIdleConnections()
+	}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
+
+// newUpstreamTransport returns a transport like `http.DefaultTransport`,
+// configured with cfg, that dials TLS connections with the parameters of fp.
+func newUpstreamTransport(fp Fingerprint, cfg TransportConfig) *http.Transport {
+	dialer := newDialer(cfg)
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		DisableKeepAlives:     cfg.DisableKeepAlives,
+	}
+
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
+	}
+
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+
+	if cfg.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	}
+
+	if fp == FingerprintGo {
+		return transport
+	}
+
+	handshakeTimeout := transport.TLSHandshakeTimeout
+
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		hello, _ := ctx.Value(clientHelloKey{}).(*ClientHello)
+
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		// The transport doesn't apply its handshake timeout to custom TLS
+		// dialers.
+		ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+		defer cancel()
+
+		tlsConn := tls.Client(conn, fp.TLSConfig(host, hello))
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return tlsConn, nil
+	}
+
+	return transport
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewUpstreamTransport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		transport := newUpstreamTransport(FingerprintGo, TransportConfig{})
+
+		if transport.MaxIdleConns != 100 || transport.TLSHandshakeTimeout != 10*time.Second {
+			t.Fatalf("expected defaults of `http.DefaultTransport`, got: %+v", transport)
+		}
+
+		if transport.DialTLSContext != nil {
+			t.Fatal("expected Go TLS dialer")
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		t.Parallel()
+
+		transport := newUpstreamTransport(FingerprintChrome, TransportConfig{
+			MaxIdleConnsPerHost:   64,
+			TLSHandshakeTimeout:   time.Second,
+			ResponseHeaderTimeout: 5 * time.Second,
+			DisableKeepAlives:     true,
+		})
+
+		if transport.MaxIdleConnsPerHost != 64 ||
+			transport.TLSHandshakeTimeout != time.Second ||
+			transport.ResponseHeaderTimeout != 5*time.Second ||
+			!transport.DisableKeepAlives {
+			t.Fatalf("expected transport to be configured, got: %+v", transport)
+		}
+
+		if transport.DialTLSContext == nil {
+			t.Fatal("expected TLS dialer for fingerprint")
+		}
+	})
+}
+
+func TestParseHostTimeouts(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseHostTimeouts("*.example.com:dial=5s, request=1m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := HostTimeouts{
+		Host:           "*.example.com",
+		DialTimeout:    5 * time.Second,
+		RequestTimeout: time.Minute,
+	}
+
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("host timeouts not equal (-exp, +got):\n%v", diff)
+	}
+
+	for _, s := range []string{"example.com", ":request=1s", "example.com:request", "example.com:foo=1s", "example.com:dial=-1s"} {
+		if _, err := ParseHostTimeouts(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestTimeoutTransport(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+
+	transport := newTimeoutTransport(FingerprintGo, TransportConfig{
+		HostTimeouts: []HostTimeouts{
+			{Host: "example.com", RequestTimeout: time.Hour},
+			{Host: "127.0.0.1", RequestTimeout: 50 * time.Millisecond},
+		},
+	})
+	defer transport.CloseIdleConnections()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	// The request timeout applies while the body is read.
+	_, err = res.Body.Read(make([]byte, 1))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got: %v", err)
+	}
+}
+
+func TestHostTimeoutsMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		host     string
+		hostname string
+		exp      bool
+	}{
+		{host: "example.com", hostname: "example.com", exp: true},
+		{host: "Example.com", hostname: "example.com", exp: true},
+		{host: "example.com", hostname: "www.example.com", exp: false},
+		{host: "*.example.com", hostname: "www.example.com", exp: true},
+		{host: "*.example.com", hostname: "example.com", exp: false},
+		{host: "*.example.com", hostname: "badexample.com", exp: false},
+	}
+
+	for _, tt := range tests {
+		if got := (HostTimeouts{Host: tt.host}).match(tt.hostname); got != tt.exp {
+			t.Errorf("match(%q, %q): expected %v, got %v", tt.host, tt.hostname, tt.exp, got)
+		}
+	}
+}
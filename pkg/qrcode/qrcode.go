@@ -0,0 +1,580 @@
+package qrcode
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Only error correction level M (~15% recovery) and versions 1 to 10 are
+// supported, which is sufficient for URLs and short text (up to 213 bytes).
+const (
+	minVersion = 1
+	maxVersion = 10
+
+	// Format bits for error correction level M.
+	eclFormatBits = 0
+
+	quietZone = 4
+)
+
+var ErrDataTooLong = errors.New("qrcode: data too long")
+
+// blockSpec describes the error correction block structure of a version.
+type blockSpec struct {
+	ecPerBlock int
+	// Number of blocks and data codewords per block, for up to two groups.
+	groups [][2]int
+}
+
+// Block structure per version, for error correction level M.
+var blockSpecs = [maxVersion + 1]blockSpec{
+	1:  {10, [][2]int{{1, 16}}},
+	2:  {16, [][2]int{{1, 28}}},
+	3:  {26, [][2]int{{1, 44}}},
+	4:  {18, [][2]int{{2, 32}}},
+	5:  {24, [][2]int{{2, 43}}},
+	6:  {16, [][2]int{{4, 27}}},
+	7:  {18, [][2]int{{4, 31}}},
+	8:  {22, [][2]int{{2, 38}, {2, 39}}},
+	9:  {22, [][2]int{{3, 36}, {2, 37}}},
+	10: {26, [][2]int{{4, 43}, {1, 44}}},
+}
+
+// Alignment pattern center coordinates per version.
+var alignmentPositions = [maxVersion + 1][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// Code is a QR code symbol.
+type Code struct {
+	version    int
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+// Encode returns a QR code that encodes data in byte mode, using the smallest
+// version that fits.
+func Encode(data []byte) (*Code, error) {
+	version := 0
+
+	for v := minVersion; v <= maxVersion; v++ {
+		if len(data) <= dataCapacity(v) {
+			version = v
+			break
+		}
+	}
+
+	if version == 0 {
+		return nil, ErrDataTooLong
+	}
+
+	c := newCode(version)
+	c.drawFunctionPatterns()
+	c.drawCodewords(interleave(version, encodeData(version, data)))
+
+	// Use the mask with the lowest penalty score.
+	bestMask, bestPenalty := 0, -1
+
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+
+		if penalty := c.penalty(); bestPenalty == -1 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+
+		// Masks are XOR operations, so applying a mask again reverts it.
+		c.applyMask(mask)
+	}
+
+	c.applyMask(bestMask)
+	c.drawFormatBits(bestMask)
+
+	return c, nil
+}
+
+// Size returns the number of modules per side, excluding the quiet zone.
+func (c *Code) Size() int {
+	return c.size
+}
+
+// Version returns the version of the symbol (1 to 10).
+func (c *Code) Version() int {
+	return c.version
+}
+
+// Module reports whether the module at column x and row y is dark.
+func (c *Code) Module(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.size || y >= c.size {
+		return false
+	}
+
+	return c.modules[y][x]
+}
+
+// Image returns the symbol as an image, including a quiet zone, where each module
+// is scale pixels wide.
+func (c *Code) Image(scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+
+	width := (c.size + 2*quietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{color.White, color.Black})
+
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+quietZone)*scale+dx, (y+quietZone)*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// PNG writes the symbol as a PNG image. See Image.
+func (c *Code) PNG(w io.Writer, scale int) error {
+	return png.Encode(w, c.Image(scale))
+}
+
+func newCode(version int) *Code {
+	size := version*4 + 17
+
+	c := &Code{
+		version:    version,
+		size:       size,
+		modules:    make([][]bool, size),
+		isFunction: make([][]bool, size),
+	}
+
+	for i := range c.modules {
+		c.modules[i] = make([]bool, size)
+		c.isFunction[i] = make([]bool, size)
+	}
+
+	return c
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	// Timing patterns.
+	for i := 0; i < c.size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns, including separators.
+	c.drawFinderPattern(3, 3)
+	c.drawFinderPattern(c.size-4, 3)
+	c.drawFinderPattern(3, c.size-4)
+
+	positions := alignmentPositions[c.version]
+	last := len(positions) - 1
+
+	for i, y := range positions {
+		for j, x := range positions {
+			// Skip positions that overlap with finder patterns.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+
+			c.drawAlignmentPattern(x, y)
+		}
+	}
+
+	// Reserve the format bits area; actual bits are drawn once the mask is known.
+	c.drawFormatBits(0)
+	c.drawVersionBits()
+}
+
+func (c *Code) drawFinderPattern(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.size || y >= c.size {
+				continue
+			}
+
+			dist := maxInt(absInt(dx), absInt(dy))
+			c.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignmentPattern(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(cx+dx, cy+dy, maxInt(absInt(dx), absInt(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits draws both copies of the 15 bit format information, which is
+// the error correction level and mask, protected by a BCH code.
+func (c *Code) drawFormatBits(mask int) {
+	bits := formatBits(mask)
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.size-1-i, 8, bit(bits, i))
+	}
+
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.size-15+i, bit(bits, i))
+	}
+
+	// Dark module.
+	c.setFunction(8, c.size-8, true)
+}
+
+// drawVersionBits draws both copies of the 18 bit version information, which
+// is only present for version 7 and up.
+func (c *Code) drawVersionBits() {
+	if c.version < 7 {
+		return
+	}
+
+	rem := c.version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+
+	bits := c.version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		a, b := c.size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// drawCodewords places the codewords in the non-function modules, in two module
+// wide columns that zigzag up and down from the bottom right corner.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+
+	for right := c.size - 1; right >= 1; right -= 2 {
+		// Skip the vertical timing pattern.
+		if right == 6 {
+			right = 5
+		}
+
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+
+				if upward := (right+1)&2 == 0; upward {
+					y = c.size - 1 - vert
+				}
+
+				if c.isFunction[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+
+				c.modules[y][x] = bit(int(codewords[i>>3]), 7-(i&7))
+				i++
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.isFunction[y][x] {
+				continue
+			}
+
+			var invert bool
+
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+
+			if invert {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty returns the penalty score of the symbol, used for selecting a mask.
+func (c *Code) penalty() int {
+	penalty := 0
+
+	// Runs of five or more modules of the same color, and patterns similar to
+	// finder patterns, in rows and columns.
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	for _, horizontal := range []bool{true, false} {
+		get := func(i, j int) bool {
+			if horizontal {
+				return c.modules[i][j]
+			}
+
+			return c.modules[j][i]
+		}
+
+		for i := 0; i < c.size; i++ {
+			runLen := 1
+
+			for j := 1; j < c.size; j++ {
+				if get(i, j) == get(i, j-1) {
+					runLen++
+					continue
+				}
+
+				if runLen >= 5 {
+					penalty += 3 + runLen - 5
+				}
+
+				runLen = 1
+			}
+
+			if runLen >= 5 {
+				penalty += 3 + runLen - 5
+			}
+
+			for j := 0; j+11 <= c.size; j++ {
+				for _, pattern := range finderLike {
+					match := true
+
+					for k, dark := range pattern {
+						if get(i, j+k) != dark {
+							match = false
+							break
+						}
+					}
+
+					if match {
+						penalty += 40
+					}
+				}
+			}
+		}
+	}
+
+	// Blocks of 2x2 modules of the same color.
+	for y := 0; y < c.size-1; y++ {
+		for x := 0; x < c.size-1; x++ {
+			v := c.modules[y][x]
+			if v == c.modules[y][x+1] && v == c.modules[y+1][x] && v == c.modules[y+1][x+1] {
+				penalty += 3
+			}
+		}
+	}
+
+	// Proportion of dark modules, deviating from 50%.
+	dark := 0
+
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+		}
+	}
+
+	percent := dark * 100 / (c.size * c.size)
+	penalty += absInt(percent-50) / 5 * 10
+
+	return penalty
+}
+
+func formatBits(mask int) int {
+	data := eclFormatBits<<3 | mask
+
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// dataCapacity returns the number of data bytes that fit in a version, in byte mode.
+func dataCapacity(version int) int {
+	// Mode indicator (4 bits) and character count (8 or 16 bits).
+	overhead := 4 + charCountBits(version)
+
+	return (dataCodewords(version)*8 - overhead) / 8
+}
+
+func dataCodewords(version int) int {
+	n := 0
+	for _, group := range blockSpecs[version].groups {
+		n += group[0] * group[1]
+	}
+
+	return n
+}
+
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+
+	return 16
+}
+
+// encodeData returns the data codewords for data in byte mode, including
+// terminator and padding.
+func encodeData(version int, data []byte) []byte {
+	bb := &bitBuffer{}
+
+	bb.append(0x4, 4)
+	bb.append(len(data), charCountBits(version))
+
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+
+	capacityBits := dataCodewords(version) * 8
+
+	// Terminator of up to four zero bits, then pad to a byte boundary.
+	terminator := capacityBits - bb.len
+	if terminator > 4 {
+		terminator = 4
+	}
+
+	bb.append(0, terminator)
+	bb.append(0, (8-bb.len%8)%8)
+
+	// Pad bytes alternate between 0xEC and 0x11.
+	for pad := 0xEC; bb.len < capacityBits; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	return bb.bytes
+}
+
+// interleave splits data into blocks, computes the error correction codewords
+// per block, and returns all codewords in their final order.
+func interleave(version int, data []byte) []byte {
+	spec := blockSpecs[version]
+
+	var dataBlocks, ecBlocks [][]byte
+
+	for _, group := range spec.groups {
+		for i := 0; i < group[0]; i++ {
+			block := data[:group[1]]
+			data = data[group[1]:]
+
+			dataBlocks = append(dataBlocks, block)
+			ecBlocks = append(ecBlocks, reedSolomon(block, spec.ecPerBlock))
+		}
+	}
+
+	result := make([]byte, 0, dataCodewords(version)+len(ecBlocks)*spec.ecPerBlock)
+
+	// Blocks in the second group are one codeword longer.
+	maxLen := len(dataBlocks[len(dataBlocks)-1])
+
+	for i := 0; i < maxLen; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+
+	for i := 0; i < spec.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+
+	return result
+}
+
+type bitBuffer struct {
+	bytes []byte
+	len   int
+}
+
+func (bb *bitBuffer) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if bb.len%8 == 0 {
+			bb.bytes = append(bb.bytes, 0)
+		}
+
+		if bit(v, i) {
+			bb.bytes[len(bb.bytes)-1] |= 1 << (7 - uint(bb.len%8))
+		}
+
+		bb.len++
+	}
+}
+
+func bit(v, i int) bool {
+	return (v>>uint(i))&1 != 0
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
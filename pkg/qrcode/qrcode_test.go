@@ -0,0 +1,130 @@
+package qrcode_test
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/qrcode"
+)
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		dataLen    int
+		expVersion int
+		expTooLong bool
+	}{
+		{name: "version 1", dataLen: 14, expVersion: 1},
+		{name: "version 2", dataLen: 15, expVersion: 2},
+		{name: "version 7 (with version information)", dataLen: 110, expVersion: 7},
+		{name: "version 10", dataLen: 213, expVersion: 10},
+		{name: "too long", dataLen: 214, expTooLong: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, err := qrcode.Encode([]byte(strings.Repeat("a", tt.dataLen)))
+			if tt.expTooLong {
+				if !errors.Is(err, qrcode.ErrDataTooLong) {
+					t.Fatalf("expected `qrcode.ErrDataTooLong`, got: %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if code.Version() != tt.expVersion {
+				t.Errorf("incorrect version (expected: %v, got: %v)", tt.expVersion, code.Version())
+			}
+
+			if exp := tt.expVersion*4 + 17; code.Size() != exp {
+				t.Errorf("incorrect size (expected: %v, got: %v)", exp, code.Size())
+			}
+
+			assertFinderPatterns(t, code)
+			assertTimingPatterns(t, code)
+		})
+	}
+}
+
+func TestPNG(t *testing.T) {
+	t.Parallel()
+
+	code, err := qrcode.Encode([]byte("http://192.168.1.10:8080/hetty_cert.pem"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := code.PNG(buf, 4); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+
+	// Includes a quiet zone of 4 modules on each side.
+	if exp := (code.Size() + 8) * 4; img.Bounds().Dx() != exp || img.Bounds().Dy() != exp {
+		t.Errorf("incorrect image size (expected: %vx%v, got: %v)", exp, exp, img.Bounds())
+	}
+}
+
+func assertFinderPatterns(t *testing.T, code *qrcode.Code) {
+	t.Helper()
+
+	size := code.Size()
+
+	for _, corner := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := maxInt(absInt(dx-3), absInt(dy-3))
+				exp := ring != 2
+
+				if got := code.Module(corner[0]+dx, corner[1]+dy); got != exp {
+					t.Fatalf("incorrect finder pattern module at (%v, %v)", corner[0]+dx, corner[1]+dy)
+				}
+			}
+		}
+	}
+}
+
+func assertTimingPatterns(t *testing.T, code *qrcode.Code) {
+	t.Helper()
+
+	for i := 8; i < code.Size()-8; i++ {
+		if code.Module(i, 6) != (i%2 == 0) || code.Module(6, i) != (i%2 == 0) {
+			t.Fatalf("incorrect timing pattern module at index %v", i)
+		}
+	}
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
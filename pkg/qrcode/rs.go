@@ -0,0 +1,75 @@
+package qrcode
+
+// Exponent and logarithm tables for GF(2^8), with primitive polynomial
+// x^8 + x^4 + x^3 + x^2 + 1 (0x11D).
+var gfExp, gfLog = func() ([512]byte, [256]byte) {
+	var exp [512]byte
+	var log [256]byte
+
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// generatorPoly returns the coefficients (highest degree first, excluding the
+// leading 1) of the Reed-Solomon generator polynomial of degree n.
+func generatorPoly(n int) []byte {
+	poly := make([]byte, n)
+	poly[n-1] = 1
+
+	root := byte(1)
+
+	for i := 0; i < n; i++ {
+		// Multiply by (x - root).
+		for j := 0; j < n; j++ {
+			poly[j] = gfMul(poly[j], root)
+			if j+1 < n {
+				poly[j] ^= poly[j+1]
+			}
+		}
+
+		root = gfMul(root, 0x02)
+	}
+
+	return poly
+}
+
+// reedSolomon returns n error correction codewords for data.
+func reedSolomon(data []byte, n int) []byte {
+	gen := generatorPoly(n)
+	result := make([]byte, n)
+
+	for _, b := range data {
+		factor := b ^ result[0]
+
+		copy(result, result[1:])
+		result[n-1] = 0
+
+		for i := range result {
+			result[i] ^= gfMul(gen[i], factor)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,238 @@
+// Package ratelimit limits the rate of outbound requests per host pattern,
+// with token buckets. A single Limiter is shared by all modules that send
+// requests (the proxy, the sender and the active scanner, for example), so
+// that their combined rate doesn't exceed the limit of a target.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("ratelimit: invalid rule")
+
+// Rule limits the rate of requests to hosts that match Host. Requests to all
+// matching hosts share a single token bucket, e.g. a rule for `*.example.com`
+// limits the combined rate of requests to its subdomains.
+type Rule struct {
+	// Hostname, without port. A leading `*.` matches all subdomains, e.g.
+	// `*.example.com`, and `*` matches all hosts.
+	Host string
+	// Requests per second.
+	Rate float64
+	// Number of requests that can be sent at once, before the rate applies.
+	// Defaults to 1.
+	Burst int
+}
+
+// ParseRule parses a rule in the format `host:rate[,burst=n]`, where rate is
+// a number of requests per second, minute or hour, e.g. `10/s`, `100/m` or
+// `*.example.com:5/s,burst=10`.
+func ParseRule(s string) (Rule, error) {
+	i := strings.Index(s, ":")
+	if i == -1 {
+		return Rule{}, fmt.Errorf("%w %q: missing rate", ErrInvalidRule, s)
+	}
+
+	rule := Rule{Host: strings.ToLower(strings.TrimSpace(s[:i])), Burst: 1}
+	if rule.Host == "" {
+		return Rule{}, fmt.Errorf("%w %q: missing host", ErrInvalidRule, s)
+	}
+
+	parts := strings.Split(s[i+1:], ",")
+
+	rate, err := parseRate(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Rule{}, fmt.Errorf("%w %q: %v", ErrInvalidRule, s, err)
+	}
+
+	rule.Rate = rate
+
+	for _, kv := range parts[1:] {
+		kv = strings.TrimSpace(kv)
+
+		if !strings.HasPrefix(kv, "burst=") {
+			return Rule{}, fmt.Errorf("%w %q: unknown option %q", ErrInvalidRule, s, kv)
+		}
+
+		burst, err := strconv.Atoi(strings.TrimPrefix(kv, "burst="))
+		if err != nil || burst < 1 {
+			return Rule{}, fmt.Errorf("%w %q: burst must be a positive integer", ErrInvalidRule, s)
+		}
+
+		rule.Burst = burst
+	}
+
+	return rule, nil
+}
+
+func parseRate(s string) (float64, error) {
+	i := strings.Index(s, "/")
+	if i == -1 {
+		return 0, errors.New("rate must have a unit, e.g. 10/s")
+	}
+
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n <= 0 {
+		return 0, errors.New("rate must be a positive number")
+	}
+
+	switch s[i+1:] {
+	case "s":
+		return n, nil
+	case "m":
+		return n / 60, nil
+	case "h":
+		return n / 3600, nil
+	default:
+		return 0, fmt.Errorf("unknown rate unit %q, must be s, m or h", s[i+1:])
+	}
+}
+
+func (r Rule) String() string {
+	return r.Host + ":" + strconv.FormatFloat(r.Rate, 'f', -1, 64) + "/s,burst=" + strconv.Itoa(r.Burst)
+}
+
+func (r Rule) match(hostname string) bool {
+	switch {
+	case r.Host == "*":
+		return true
+	case strings.HasPrefix(r.Host, "*."):
+		return strings.HasSuffix(hostname, r.Host[1:])
+	default:
+		return r.Host == hostname
+	}
+}
+
+// Limiter limits requests by the first rule that matches their host. Requests
+// to hosts without a matching rule aren't limited. A nil *Limiter doesn't
+// limit requests.
+type Limiter struct {
+	buckets []*bucket
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+type bucket struct {
+	rule   Rule
+	tokens float64
+	last   time.Time
+}
+
+func NewLimiter(rules []Rule) *Limiter {
+	l := &Limiter{now: time.Now}
+	l.SetRules(rules)
+
+	return l
+}
+
+// SetRules replaces the rules of the limiter. Buckets start full.
+func (l *Limiter) SetRules(rules []Rule) {
+	buckets := make([]*bucket, len(rules))
+
+	for i, rule := range rules {
+		if rule.Burst < 1 {
+			rule.Burst = 1
+		}
+
+		rule.Host = strings.ToLower(rule.Host)
+		buckets[i] = &bucket{rule: rule, tokens: float64(rule.Burst)}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buckets = buckets
+}
+
+func (l *Limiter) Rules() []Rule {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rules := make([]Rule, len(l.buckets))
+	for i, b := range l.buckets {
+		rules[i] = b.rule
+	}
+
+	return rules
+}
+
+// Wait blocks until a request to hostname is allowed, or ctx is done.
+func (l *Limiter) Wait(ctx context.Context, hostname string) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		delay := l.reserve(strings.ToLower(hostname))
+		if delay <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token from the bucket of hostname, or returns the time
+// until a token is available.
+func (l *Limiter) reserve(hostname string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, b := range l.buckets {
+		if !b.rule.match(hostname) {
+			continue
+		}
+
+		now := l.now()
+
+		if !b.last.IsZero() {
+			b.tokens += now.Sub(b.last).Seconds() * b.rule.Rate
+			if max := float64(b.rule.Burst); b.tokens > max {
+				b.tokens = max
+			}
+		}
+
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			return 0
+		}
+
+		return time.Duration((1 - b.tokens) / b.rule.Rate * float64(time.Second))
+	}
+
+	return 0
+}
+
+// Transport returns a round tripper that waits for the limiter before each
+// request sent with rt.
+func (l *Limiter) Transport(rt http.RoundTripper) http.RoundTripper {
+	return transportFunc(func(req *http.Request) (*http.Response, error) {
+		if err := l.Wait(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+
+		return rt.RoundTrip(req)
+	})
+}
+
+type transportFunc func(*http.Request) (*http.Response, error)
+
+func (fn transportFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input  string
+		exp    Rule
+		expErr bool
+	}{
+		{input: "example.com:10/s", exp: Rule{Host: "example.com", Rate: 10, Burst: 1}},
+		{input: "*.Example.com: 120/m, burst=5", exp: Rule{Host: "*.example.com", Rate: 2, Burst: 5}},
+		{input: "*:3600/h", exp: Rule{Host: "*", Rate: 1, Burst: 1}},
+		{input: "example.com", expErr: true},
+		{input: ":10/s", expErr: true},
+		{input: "example.com:10", expErr: true},
+		{input: "example.com:0/s", expErr: true},
+		{input: "example.com:10/d", expErr: true},
+		{input: "example.com:10/s,burst=0", expErr: true},
+		{input: "example.com:10/s,size=5", expErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRule(tt.input)
+		if tt.expErr {
+			if !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("%q: expected invalid rule error, got: %v", tt.input, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.input, err)
+			continue
+		}
+
+		if got != tt.exp {
+			t.Errorf("%q: expected %+v, got: %+v", tt.input, tt.exp, got)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := NewLimiter([]Rule{
+		{Host: "*.example.com", Rate: 2, Burst: 2},
+		{Host: "*", Rate: 100, Burst: 100},
+	})
+	l.now = func() time.Time { return now }
+
+	// The burst is allowed at once, and the bucket is shared by subdomains.
+	for _, hostname := range []string{"a.example.com", "b.example.com"} {
+		if delay := l.reserve(hostname); delay != 0 {
+			t.Fatalf("expected request to %v to be allowed, got delay: %v", hostname, delay)
+		}
+	}
+
+	if delay := l.reserve("a.example.com"); delay != 500*time.Millisecond {
+		t.Fatalf("expected delay of 500ms after burst, got: %v", delay)
+	}
+
+	// Other hosts use the next matching rule.
+	if delay := l.reserve("other.test"); delay != 0 {
+		t.Fatalf("expected request to other host to be allowed, got delay: %v", delay)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+
+	if delay := l.reserve("a.example.com"); delay != 0 {
+		t.Fatalf("expected request to be allowed after refill, got delay: %v", delay)
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter([]Rule{{Host: "example.com", Rate: 0.001, Burst: 1}})
+	ctx := context.Background()
+
+	if err := l.Wait(ctx, "example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Hosts without a rule aren't limited.
+	if err := l.Wait(ctx, "other.test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx, "EXAMPLE.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got: %v", err)
+	}
+
+	// A nil limiter doesn't limit requests.
+	var nilLimiter *Limiter
+	if err := nilLimiter.Wait(ctx, "example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,531 @@
+// Package replay re-sends logged requests of a session in their original order,
+// and records the new responses next to the original ones, e.g. to compare the
+// behaviour of a staging environment with production.
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// JobType is the type of the jobs that run replays, see `job.Params`.
+const JobType = "replay"
+
+var (
+	ErrReplayNotFound  = errcode.New(errcode.NotFound, "replay: replay not found")
+	ErrNoRequests      = errcode.New(errcode.Invalid, "replay: no request logs selected")
+	ErrInvalidSpeed    = errcode.New(errcode.Invalid, "replay: speed must not be negative")
+	ErrProfileNotFound = errcode.New(errcode.NotFound, "replay: rewrite profile not found")
+
+	ErrSigningProfileNotFound = errcode.New(errcode.NotFound, "replay: signing profile not found")
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusFinished  Status = "finished"
+	StatusCancelled Status = "cancelled"
+)
+
+// Timing is the way requests of a replay are spaced.
+type Timing string
+
+const (
+	// Requests are sent one after the other, as fast as possible.
+	TimingNone Timing = "none"
+	// Requests are sent with the delays between the original requests,
+	// divided by the speed of the replay.
+	TimingOriginal Timing = "original"
+)
+
+// Service runs replays, which re-send an ordered set of request logs.
+type Service interface {
+	StartReplay(ctx context.Context, params ReplayParams) (Replay, error)
+	FindReplayByID(id ulid.ULID) (Replay, error)
+	FindReplays() []Replay
+	CancelReplay(id ulid.ULID) error
+}
+
+// RequestSigner signs replayed requests with a signing profile, see
+// `ReplayParams.SigningProfile`. It's implemented by `sender.Service`.
+type RequestSigner interface {
+	SigningProfiles() sender.SigningProfiles
+	SignHTTPRequest(profile sender.SigningProfile, req *http.Request, body []byte)
+}
+
+type service struct {
+	ids        idgen.Generator
+	jobs       job.Service
+	reqLogSvc  reqlog.Service
+	rewriter   *rewrite.Rewriter
+	signer     RequestSigner
+	httpClient *http.Client
+	replays    map[ulid.ULID]*replayState
+	mu         sync.RWMutex
+}
+
+type Config struct {
+	RequestLogService reqlog.Service
+	// Transport used for replayed requests. Typically the proxy itself, so
+	// that replayed requests are logged like proxied traffic.
+	Transport http.RoundTripper
+	// Generates the IDs of replays. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+	// Holds the rewrite profiles that `ReplayParams.RewriteProfile` refers
+	// to. Optional.
+	Rewriter *rewrite.Rewriter
+	// Holds the signing profiles that `ReplayParams.SigningProfile` refers
+	// to. Optional.
+	Signer RequestSigner
+	// Runs replays as jobs, so that they're queued, report progress and can
+	// be paused. Optional; without it, replays start right away.
+	Jobs job.Service
+}
+
+type ReplayParams struct {
+	// Request logs to replay. They are sent in order of their IDs, i.e. in
+	// the order they were logged.
+	Selection reqlog.Selection
+	// Defaults to `TimingOriginal`.
+	Timing Timing
+	// Factor that delays between requests are divided by with
+	// `TimingOriginal`, e.g. 2 to replay twice as fast. Defaults to 1.
+	Speed float64
+	// Name of a rewrite profile of the project that requests are rewritten
+	// with, e.g. to replay traffic of production against staging. Optional.
+	RewriteProfile string
+	// Rewrites of requests, applied after those of RewriteProfile. Its name
+	// is ignored.
+	Rewrite rewrite.Profile
+	// Name of a signing profile of the sender that requests are signed with
+	// after rewrites, e.g. for APIs that reject the original signatures once
+	// they expire. Its host pattern is ignored. Optional.
+	SigningProfile string
+}
+
+type Replay struct {
+	ID        ulid.ULID
+	Status    Status
+	Timing    Timing
+	Speed     float64
+	Total     int
+	Completed int
+	Results   []Result
+}
+
+// Result is a replayed request, with its new and original response.
+type Result struct {
+	// Request log that was replayed.
+	OriginalRequestLogID ulid.ULID
+	// Request log of the replayed request, if it was logged.
+	RequestLogID ulid.ULID
+	Method       string
+	// URL the request was sent to, after rewrites.
+	URL *url.URL
+	// Response of the original request, if it had one.
+	OriginalResponse *reqlog.ResponseLog
+	Response         *reqlog.ResponseLog
+	Duration         time.Duration
+	// Error of sending the request, if any.
+	Error string
+}
+
+// StatusCodeChanged returns true if the status code of the response differs
+// from the original response.
+func (result Result) StatusCodeChanged() bool {
+	return result.statusCode(result.OriginalResponse) != result.statusCode(result.Response)
+}
+
+// BodyChanged returns true if the body of the response differs from that of
+// the original response.
+func (result Result) BodyChanged() bool {
+	var orig, body []byte
+
+	if result.OriginalResponse != nil {
+		orig = result.OriginalResponse.Body
+	}
+
+	if result.Response != nil {
+		body = result.Response.Body
+	}
+
+	return !bytes.Equal(orig, body)
+}
+
+func (Result) statusCode(resLog *reqlog.ResponseLog) int {
+	if resLog == nil {
+		return 0
+	}
+
+	return resLog.StatusCode
+}
+
+type replayState struct {
+	replay Replay
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &service{
+		ids:       cfg.IDGenerator,
+		jobs:      cfg.Jobs,
+		reqLogSvc: cfg.RequestLogService,
+		rewriter:  cfg.Rewriter,
+		signer:    cfg.Signer,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   defaultTimeout,
+			// Redirects aren't followed, as their requests are replayed if
+			// they are part of the session.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		replays: make(map[ulid.ULID]*replayState),
+	}
+}
+
+// StartReplay looks up the selected request logs, and replays them in the
+// background.
+func (svc *service) StartReplay(ctx context.Context, params ReplayParams) (Replay, error) {
+	if params.Timing == "" {
+		params.Timing = TimingOriginal
+	}
+
+	if params.Speed < 0 {
+		return Replay{}, ErrInvalidSpeed
+	}
+
+	if params.Speed == 0 {
+		params.Speed = 1
+	}
+
+	// The profile is looked up once, so that changes of profiles don't affect
+	// a running replay.
+	var profiles []rewrite.Profile
+
+	if params.RewriteProfile != "" {
+		if svc.rewriter == nil {
+			return Replay{}, ErrProfileNotFound
+		}
+
+		profile, ok := svc.rewriter.Profiles().Find(params.RewriteProfile)
+		if !ok {
+			return Replay{}, ErrProfileNotFound
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	profiles = append(profiles, params.Rewrite)
+
+	var signing *sender.SigningProfile
+
+	if params.SigningProfile != "" {
+		if svc.signer == nil {
+			return Replay{}, ErrSigningProfileNotFound
+		}
+
+		profile, ok := svc.signer.SigningProfiles().Find(params.SigningProfile)
+		if !ok {
+			return Replay{}, ErrSigningProfileNotFound
+		}
+
+		signing = &profile
+	}
+
+	reqLogs, err := svc.reqLogSvc.FindSelectedRequests(ctx, params.Selection)
+	if err != nil {
+		return Replay{}, fmt.Errorf("replay: failed to find request logs: %w", err)
+	}
+
+	if len(reqLogs) == 0 {
+		return Replay{}, ErrNoRequests
+	}
+
+	sort.Slice(reqLogs, func(i, j int) bool {
+		return reqLogs[i].ID.Compare(reqLogs[j].ID) < 0
+	})
+
+	replayCtx, cancel := context.WithCancel(context.Background())
+
+	state := &replayState{
+		replay: Replay{
+			ID:      svc.ids.New(time.Now()),
+			Status:  StatusRunning,
+			Timing:  params.Timing,
+			Speed:   params.Speed,
+			Total:   len(reqLogs),
+			Results: make([]Result, 0, len(reqLogs)),
+		},
+		cancel: cancel,
+	}
+
+	svc.mu.Lock()
+	svc.replays[state.replay.ID] = state
+	svc.mu.Unlock()
+
+	if svc.jobs == nil {
+		go svc.run(replayCtx, state, reqLogs, params, profiles, signing, nil)
+		return state.snapshot(), nil
+	}
+
+	jobParams := job.Params{
+		ID:          state.replay.ID,
+		Type:        JobType,
+		Description: fmt.Sprintf("%v requests, %v timing", len(reqLogs), params.Timing),
+		Total:       len(reqLogs),
+	}
+
+	svc.jobs.StartJob(replayCtx, jobParams, func(ctx context.Context, progress *job.Progress) error {
+		return svc.run(ctx, state, reqLogs, params, profiles, signing, progress)
+	})
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindReplayByID(id ulid.ULID) (Replay, error) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	state, ok := svc.replays[id]
+	if !ok {
+		return Replay{}, ErrReplayNotFound
+	}
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindReplays() []Replay {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	replays := make([]Replay, 0, len(svc.replays))
+	for _, state := range svc.replays {
+		replays = append(replays, state.snapshot())
+	}
+
+	// Most recent replays first.
+	sort.Slice(replays, func(i, j int) bool {
+		return replays[i].ID.Compare(replays[j].ID) > 0
+	})
+
+	return replays
+}
+
+func (svc *service) CancelReplay(id ulid.ULID) error {
+	svc.mu.RLock()
+	state, ok := svc.replays[id]
+	svc.mu.RUnlock()
+
+	if !ok {
+		return ErrReplayNotFound
+	}
+
+	state.mu.Lock()
+	if state.replay.Status == StatusRunning {
+		state.replay.Status = StatusCancelled
+	}
+	state.mu.Unlock()
+
+	state.cancel()
+
+	return nil
+}
+
+func (svc *service) run(
+	ctx context.Context,
+	state *replayState,
+	reqLogs []reqlog.RequestLog,
+	params ReplayParams,
+	profiles []rewrite.Profile,
+	signing *sender.SigningProfile,
+	progress *job.Progress,
+) error {
+	defer state.cancel()
+
+	start := time.Now()
+	first := ulid.Time(reqLogs[0].ID.Time())
+
+	for _, reqLog := range reqLogs {
+		// Time spent paused doesn't count towards the original timing, so
+		// that requests aren't sent in a burst once the replay is resumed.
+		pausedAt := time.Now()
+		if err := progress.Wait(ctx); err != nil {
+			break
+		}
+		start = start.Add(time.Since(pausedAt))
+
+		if params.Timing == TimingOriginal {
+			offset := time.Duration(float64(ulid.Time(reqLog.ID.Time()).Sub(first)) / params.Speed)
+			timer := time.NewTimer(time.Until(start.Add(offset)))
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		state.addResult(svc.send(ctx, reqLog, profiles, signing))
+		progress.Add(1)
+	}
+
+	state.mu.Lock()
+	if state.replay.Status == StatusRunning {
+		state.replay.Status = StatusFinished
+		if ctx.Err() != nil {
+			state.replay.Status = StatusCancelled
+		}
+	}
+	state.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// send replays a request log, signed with signing if it's set.
+func (svc *service) send(
+	ctx context.Context,
+	reqLog reqlog.RequestLog,
+	profiles []rewrite.Profile,
+	signing *sender.SigningProfile,
+) Result {
+	result := Result{
+		OriginalRequestLogID: reqLog.ID,
+		Method:               reqLog.Method,
+		OriginalResponse:     reqLog.Response,
+	}
+
+	req, err := newRequest(ctx, reqLog, profiles)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.URL = req.URL
+
+	if signing != nil {
+		svc.signer.SignHTTPRequest(*signing, req, reqLog.Body)
+	}
+
+	sentAt := time.Now()
+
+	res, err := svc.httpClient.Do(req)
+	if err != nil {
+		result.Duration = time.Since(sentAt)
+		result.Error = err.Error()
+
+		return result
+	}
+	defer res.Body.Close()
+
+	resLog, err := reqlog.ParseHTTPResponse(res)
+	result.Duration = time.Since(sentAt)
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Response = &resLog
+
+	if res.Request != nil {
+		if reqLogID, ok := res.Request.Context().Value(proxy.ReqLogIDKey).(ulid.ULID); ok {
+			result.RequestLogID = reqLogID
+		}
+	}
+
+	return result
+}
+
+// newRequest returns the request of a request log, rewritten with profiles.
+func newRequest(ctx context.Context, reqLog reqlog.RequestLog, profiles []rewrite.Profile) (*http.Request, error) {
+	if reqLog.URL == nil || reqLog.URL.Host == "" {
+		return nil, fmt.Errorf("replay: request log (id: %v) has no absolute URL", reqLog.ID)
+	}
+
+	header := reqLog.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	u := reqLog.URL
+	for _, profile := range profiles {
+		u = profile.Apply(u, header)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, reqLog.Method, u.String(), bytes.NewReader(reqLog.Body))
+	if err != nil {
+		return nil, fmt.Errorf("replay: failed to create request: %w", err)
+	}
+
+	// Set by the HTTP client, for the rewritten request.
+	header.Del("Content-Length")
+	header.Del("Host")
+
+	req.Header = header
+
+	if len(reqLog.Body) == 0 {
+		req.Body = http.NoBody
+	}
+
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(reqLog.Body)), nil
+	}
+
+	return req, nil
+}
+
+func (state *replayState) addResult(result Result) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.replay.Completed++
+	state.replay.Results = append(state.replay.Results, result)
+}
+
+func (state *replayState) snapshot() Replay {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	replay := state.replay
+	replay.Results = make([]Result, len(state.replay.Results))
+	copy(replay.Results, state.replay.Results)
+
+	return replay
+}
@@ -0,0 +1,323 @@
+package replay_test
+
+//go:generate go run github.com/matryer/moq -out reqlog_mock_test.go -pkg replay_test ../reqlog Service:ReqLogServiceMock
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/replay"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func waitForReplay(t *testing.T, svc replay.Service, id ulid.ULID) replay.Replay {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		rep, err := svc.FindReplayByID(id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if rep.Status != replay.StatusRunning {
+			return rep
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("replay did not finish in time")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartReplay(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		received []*http.Request
+		sentAt   []time.Time
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r)
+		sentAt = append(sentAt, time.Now())
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte("welcome"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	firstID := ulid.MustNew(ulid.Timestamp(now.Add(-time.Hour)), nil)
+	secondID := ulid.MustNew(ulid.Timestamp(now.Add(-time.Hour+300*time.Millisecond)), nil)
+
+	reqLogs := []reqlog.RequestLog{
+		{
+			ID:     secondID,
+			Method: http.MethodGet,
+			URL:    &url.URL{Scheme: "https", Host: "prod.example.com", Path: "/account"},
+			Header: http.Header{"Authorization": []string{"Bearer prod"}},
+			Response: &reqlog.ResponseLog{
+				Proto:      "HTTP/1.1",
+				StatusCode: http.StatusOK,
+				Status:     "200 OK",
+				Body:       []byte("account"),
+			},
+		},
+		{
+			ID:     firstID,
+			Method: http.MethodPost,
+			URL:    &url.URL{Scheme: "https", Host: "prod.example.com", Path: "/login"},
+			Header: http.Header{"Authorization": []string{"Bearer prod"}, "X-Foo": []string{"bar"}},
+			Body:   []byte("user=foo"),
+			Response: &reqlog.ResponseLog{
+				Proto:      "HTTP/1.1",
+				StatusCode: http.StatusOK,
+				Status:     "200 OK",
+				Body:       []byte("welcome"),
+			},
+		},
+	}
+
+	reqLogSvc := &ReqLogServiceMock{
+		FindSelectedRequestsFunc: func(_ context.Context, _ reqlog.Selection) ([]reqlog.RequestLog, error) {
+			return reqLogs, nil
+		},
+	}
+
+	t.Run("replays requests in order, with rewrites", func(t *testing.T) {
+		rewriter := &rewrite.Rewriter{}
+		rewriter.SetProfiles(rewrite.Profiles{
+			Profiles: []rewrite.Profile{{
+				Name:  "staging",
+				Hosts: []rewrite.HostRule{{From: "PROD.example.com", To: tsURL.Host, Scheme: "http"}},
+			}},
+		})
+
+		svc := replay.NewService(replay.Config{RequestLogService: reqLogSvc, Rewriter: rewriter})
+
+		rep, err := svc.StartReplay(context.Background(), replay.ReplayParams{
+			Selection:      reqlog.Selection{IDs: []ulid.ULID{firstID, secondID}},
+			Timing:         replay.TimingOriginal,
+			Speed:          3,
+			RewriteProfile: "staging",
+			Rewrite: rewrite.Profile{
+				Headers: http.Header{
+					"authorization": []string{"Bearer staging"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if rep.Total != 2 {
+			t.Fatalf("incorrect total (expected: 2, got: %v)", rep.Total)
+		}
+
+		rep = waitForReplay(t, svc, rep.ID)
+
+		if rep.Status != replay.StatusFinished {
+			t.Fatalf("incorrect status (expected: %v, got: %v)", replay.StatusFinished, rep.Status)
+		}
+
+		if len(rep.Results) != 2 {
+			t.Fatalf("incorrect number of results (expected: 2, got: %v)", len(rep.Results))
+		}
+
+		login, account := rep.Results[0], rep.Results[1]
+
+		if login.OriginalRequestLogID != firstID || account.OriginalRequestLogID != secondID {
+			t.Fatalf("results not in order of request logs (got: %v, %v)",
+				login.OriginalRequestLogID, account.OriginalRequestLogID)
+		}
+
+		if got, exp := login.URL.String(), ts.URL+"/login"; got != exp {
+			t.Errorf("incorrect URL (expected: %v, got: %v)", exp, got)
+		}
+
+		if login.Error != "" || login.Response == nil {
+			t.Fatalf("expected response, got error: %v", login.Error)
+		}
+
+		if login.StatusCodeChanged() || login.BodyChanged() {
+			t.Errorf("expected unchanged response (got: %+v)", login.Response)
+		}
+
+		if account.Response == nil || account.Response.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected not found response (got: %+v)", account.Response)
+		}
+
+		if !account.StatusCodeChanged() || !account.BodyChanged() {
+			t.Error("expected changed response")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(received) != 2 {
+			t.Fatalf("incorrect number of received requests (expected: 2, got: %v)", len(received))
+		}
+
+		if got := received[0].Header.Get("Authorization"); got != "Bearer staging" {
+			t.Errorf("incorrect `Authorization` header (expected: `Bearer staging`, got: %q)", got)
+		}
+
+		if got := received[0].Header.Get("X-Foo"); got != "bar" {
+			t.Errorf("incorrect `X-Foo` header (expected: `bar`, got: %q)", got)
+		}
+
+		if got := received[0].Host; got != tsURL.Host {
+			t.Errorf("incorrect host (expected: %v, got: %v)", tsURL.Host, got)
+		}
+
+		// 300ms between the original requests, replayed 3 times as fast.
+		if gap := sentAt[1].Sub(sentAt[0]); gap < 90*time.Millisecond || gap > 290*time.Millisecond {
+			t.Errorf("incorrect delay between requests (expected: ~100ms, got: %v)", gap)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		svc := replay.NewService(replay.Config{RequestLogService: &ReqLogServiceMock{
+			FindSelectedRequestsFunc: func(_ context.Context, _ reqlog.Selection) ([]reqlog.RequestLog, error) {
+				return []reqlog.RequestLog{
+					{ID: ulid.MustNew(ulid.Timestamp(now), nil), Method: http.MethodGet, URL: tsURL},
+					{ID: ulid.MustNew(ulid.Timestamp(now.Add(time.Hour)), nil), Method: http.MethodGet, URL: tsURL},
+				}, nil
+			},
+		}})
+
+		rep, err := svc.StartReplay(context.Background(), replay.ReplayParams{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := svc.CancelReplay(rep.ID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		rep = waitForReplay(t, svc, rep.ID)
+
+		if rep.Status != replay.StatusCancelled {
+			t.Fatalf("incorrect status (expected: %v, got: %v)", replay.StatusCancelled, rep.Status)
+		}
+
+		if rep.Completed == rep.Total {
+			t.Errorf("expected cancelled replay to skip requests (completed: %v)", rep.Completed)
+		}
+
+		if err := svc.CancelReplay(ulid.ULID{}); !errors.Is(err, replay.ErrReplayNotFound) {
+			t.Errorf("expected `replay.ErrReplayNotFound`, got: %v", err)
+		}
+	})
+
+	t.Run("unknown rewrite profile", func(t *testing.T) {
+		svc := replay.NewService(replay.Config{RequestLogService: reqLogSvc, Rewriter: &rewrite.Rewriter{}})
+
+		_, err := svc.StartReplay(context.Background(), replay.ReplayParams{RewriteProfile: "foo"})
+		if !errors.Is(err, replay.ErrProfileNotFound) {
+			t.Fatalf("expected `replay.ErrProfileNotFound`, got: %v", err)
+		}
+	})
+
+	t.Run("signs requests with signing profile", func(t *testing.T) {
+		signed := make(chan string, 1)
+
+		signedTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signed <- r.Header.Get("X-Signature")
+		}))
+		defer signedTS.Close()
+
+		signedURL, err := url.Parse(signedTS.URL + "/orders")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		senderSvc := sender.NewService(sender.Config{Repository: memory.OpenDatabase()})
+		senderSvc.SetEnvironments(sender.Environments{
+			Environments: []sender.Environment{{Name: "dev", Variables: []sender.Variable{{Name: "key", Value: "s3cret"}}}},
+			Active:       "dev",
+		})
+		senderSvc.SetSigningProfiles(sender.SigningProfiles{{
+			Name:        "api",
+			HostPattern: `^api\.example\.com$`,
+			HMAC:        &sender.HMACSigning{Key: "{{key}}", Message: "{{request.method}} {{request.path}}", Header: "X-Signature"},
+		}})
+
+		svc := replay.NewService(replay.Config{
+			RequestLogService: &ReqLogServiceMock{
+				FindSelectedRequestsFunc: func(_ context.Context, _ reqlog.Selection) ([]reqlog.RequestLog, error) {
+					return []reqlog.RequestLog{{
+						ID:     firstID,
+						Method: http.MethodGet,
+						URL:    signedURL,
+						Header: http.Header{"X-Signature": []string{"expired"}},
+					}}, nil
+				},
+			},
+			Signer: senderSvc,
+		})
+
+		rep, err := svc.StartReplay(context.Background(), replay.ReplayParams{Timing: replay.TimingNone, SigningProfile: "api"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		waitForReplay(t, svc, rep.ID)
+
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte("GET /orders"))
+
+		// The host pattern of the profile doesn't have to match.
+		if got, exp := <-signed, hex.EncodeToString(mac.Sum(nil)); got != exp {
+			t.Fatalf("incorrect `X-Signature` header (expected: %v, got: %v)", exp, got)
+		}
+
+		_, err = svc.StartReplay(context.Background(), replay.ReplayParams{SigningProfile: "foo"})
+		if !errors.Is(err, replay.ErrSigningProfileNotFound) {
+			t.Fatalf("expected `replay.ErrSigningProfileNotFound`, got: %v", err)
+		}
+	})
+
+	t.Run("no request logs", func(t *testing.T) {
+		svc := replay.NewService(replay.Config{RequestLogService: &ReqLogServiceMock{
+			FindSelectedRequestsFunc: func(_ context.Context, _ reqlog.Selection) ([]reqlog.RequestLog, error) {
+				return nil, nil
+			},
+		}})
+
+		_, err := svc.StartReplay(context.Background(), replay.ReplayParams{})
+		if !errors.Is(err, replay.ErrNoRequests) {
+			t.Fatalf("expected `replay.ErrNoRequests`, got: %v", err)
+		}
+	})
+}
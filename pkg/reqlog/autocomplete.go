@@ -0,0 +1,92 @@
+package reqlog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/search"
+)
+
+// maxSearchValues is the maximum number of observed values per search key, see
+// `SearchMetadata`.
+const maxSearchValues = 100
+
+// FieldValue is an observed value of a request log field, with the number of
+// request logs that have it.
+type FieldValue struct {
+	Value string
+	Count int
+}
+
+// FieldValues are the observed values of the indexed fields of the request logs
+// of a project, in any order. Counts can be higher than the actual number of
+// request logs for responses that were stored more than once.
+type FieldValues struct {
+	Hosts        []FieldValue
+	Methods      []FieldValue
+	StatusCodes  []FieldValue
+	ContentTypes []FieldValue
+}
+
+// SearchMetadata describes the search expressions of request logs, for
+// autocompletion in clients.
+type SearchMetadata struct {
+	// Search keys, e.g. `req.method`, sorted.
+	Keys []string
+	// Prefixes of search keys that are followed by a header name or JSON path,
+	// e.g. `req.header.`.
+	KeyPrefixes []string
+	Operators   []string
+	// Observed values of the request logs of the active project, by search key,
+	// most common first.
+	Values map[string][]FieldValue
+}
+
+// SearchMetadata returns the search keys, operators and observed values of the
+// request logs of the active project.
+func (svc *service) SearchMetadata(ctx context.Context) (SearchMetadata, error) {
+	projectID := svc.ActiveProjectID()
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return SearchMetadata{}, ErrProjectIDMustBeSet
+	}
+
+	values, err := svc.repo.FindRequestLogFieldValues(ctx, projectID)
+	if err != nil {
+		return SearchMetadata{}, fmt.Errorf("reqlog: failed to find field values: %w", err)
+	}
+
+	return SearchMetadata{
+		Keys:        append([]string{}, reqLogAndResLogSearchKeys...),
+		KeyPrefixes: []string{reqHeaderKeyPrefix, reqJSONKeyPrefix, resHeaderKeyPrefix, resJSONKeyPrefix},
+		Operators:   search.Operators(),
+		Values: map[string][]FieldValue{
+			"req.host":                mostCommonValues(values.Hosts),
+			"req.method":              mostCommonValues(values.Methods),
+			"res.statusCode":          mostCommonValues(values.StatusCodes),
+			"res.header.Content-Type": mostCommonValues(values.ContentTypes),
+		},
+	}, nil
+}
+
+// mostCommonValues returns the `maxSearchValues` values with the highest count,
+// sorted by count and value.
+func mostCommonValues(values []FieldValue) []FieldValue {
+	sorted := append([]FieldValue{}, values...)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+
+		return sorted[i].Value < sorted[j].Value
+	})
+
+	if len(sorted) > maxSearchValues {
+		sorted = sorted[:maxSearchValues]
+	}
+
+	return sorted
+}
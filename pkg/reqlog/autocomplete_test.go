@@ -0,0 +1,91 @@
+package reqlog_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+func TestSearchMetadata(t *testing.T) {
+	t.Parallel()
+
+	t.Run("without active project", func(t *testing.T) {
+		t.Parallel()
+
+		svc := reqlog.NewService(reqlog.Config{Repository: &RepoMock{}})
+
+		_, err := svc.SearchMetadata(context.Background())
+		if !errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+			t.Fatalf("expected `reqlog.ErrProjectIDMustBeSet`, got: %v", err)
+		}
+	})
+
+	t.Run("most common values first", func(t *testing.T) {
+		t.Parallel()
+
+		projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+		hosts := make([]reqlog.FieldValue, 0, 150)
+		for i := 0; i < 150; i++ {
+			hosts = append(hosts, reqlog.FieldValue{Value: "host" + strconv.Itoa(i) + ".example.com", Count: i})
+		}
+
+		repoMock := &RepoMock{
+			FindRequestLogFieldValuesFunc: func(_ context.Context, id ulid.ULID) (reqlog.FieldValues, error) {
+				if id != projectID {
+					t.Errorf("expected project ID %v, got: %v", projectID, id)
+				}
+
+				return reqlog.FieldValues{
+					Hosts:       hosts,
+					Methods:     []reqlog.FieldValue{{Value: "POST", Count: 2}, {Value: "GET", Count: 5}, {Value: "DELETE", Count: 2}},
+					StatusCodes: []reqlog.FieldValue{{Value: "200", Count: 1}},
+				}, nil
+			},
+		}
+		svc := reqlog.NewService(reqlog.Config{Repository: repoMock})
+		svc.SetActiveProjectID(projectID)
+
+		got, err := svc.SearchMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expMethods := []reqlog.FieldValue{{Value: "GET", Count: 5}, {Value: "DELETE", Count: 2}, {Value: "POST", Count: 2}}
+		if diff := cmp.Diff(expMethods, got.Values["req.method"]); diff != "" {
+			t.Fatalf("methods not equal (-exp, +got):\n%v", diff)
+		}
+
+		if n := len(got.Values["req.host"]); n != 100 {
+			t.Fatalf("expected 100 hosts, got: %v", n)
+		}
+
+		if exp := hosts[149]; got.Values["req.host"][0] != exp {
+			t.Fatalf("expected most common host %+v, got: %+v", exp, got.Values["req.host"][0])
+		}
+
+		if n := len(got.Values["res.header.Content-Type"]); n != 0 {
+			t.Fatalf("expected no content types, got: %v", n)
+		}
+
+		// Every key is a search key.
+		for _, key := range got.Keys {
+			if _, err := reqlog.ParseSearchExpr(key + " = foo"); err != nil {
+				t.Fatalf("unexpected error parsing expression with key %q: %v", key, err)
+			}
+		}
+
+		for key := range got.Values {
+			if _, err := reqlog.ParseSearchExpr(key + " = foo"); err != nil {
+				t.Fatalf("unexpected error parsing expression with key %q: %v", key, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package reqlog
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// inScopeKey is set on the context of logged requests, to whether the request
+// matched the scope rules.
+const inScopeKey contextKey = 1
+
+// staticContentTypes are the media types of binary and static responses.
+var staticContentTypes = []string{
+	"image/*",
+	"font/*",
+	"video/*",
+	"audio/*",
+	"application/font-woff",
+	"application/vnd.ms-fontobject",
+	"application/x-font-ttf",
+}
+
+// BodyRules determine which response bodies are stored. For responses whose
+// body is omitted, only the status, headers and metadata are stored.
+type BodyRules struct {
+	// Omit bodies of responses to requests that don't match the scope rules.
+	OmitOutOfScope bool
+	// Omit bodies of images, fonts, video and audio.
+	OmitStatic bool
+	// Omit bodies of responses with these media types. A type ending with
+	// `/*` matches all subtypes, e.g. `image/*`.
+	OmitContentTypes []string
+	// Omit bodies larger than this number of bytes. Zero means no limit.
+	MaxSize int
+}
+
+// omitBody returns true when the rules omit the body of res.
+func (rules BodyRules) omitBody(res *http.Response, size int) bool {
+	if rules.MaxSize > 0 && size > rules.MaxSize {
+		return true
+	}
+
+	if rules.OmitOutOfScope && res.Request != nil {
+		if inScope, ok := res.Request.Context().Value(inScopeKey).(bool); ok && !inScope {
+			return true
+		}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	if rules.OmitStatic && matchMediaType(staticContentTypes, mediaType) {
+		return true
+	}
+
+	return matchMediaType(rules.OmitContentTypes, mediaType)
+}
+
+func matchMediaType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+		if strings.HasSuffix(pattern, "/*") {
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+
+			continue
+		}
+
+		if pattern == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
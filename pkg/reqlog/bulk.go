@@ -0,0 +1,151 @@
+package reqlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+var ErrInvalidSelection = errcode.New(errcode.Invalid, "reqlog: selection must have either IDs or a filter")
+
+// Selection is a set of request logs of the active project, for bulk
+// operations.
+type Selection struct {
+	// IDs of request logs. Request logs of other projects aren't selected.
+	IDs []ulid.ULID
+	// Filter is used when IDs is empty. Its project ID is overridden with the
+	// ID of the active project.
+	Filter *FindRequestsFilter
+}
+
+// FindSelectedRequests returns the request logs of a selection.
+func (svc *service) FindSelectedRequests(ctx context.Context, sel Selection) ([]RequestLog, error) {
+	projectID := svc.ActiveProjectID()
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, ErrProjectIDMustBeSet
+	}
+
+	switch {
+	case len(sel.IDs) > 0:
+		reqLogs := make([]RequestLog, 0, len(sel.IDs))
+
+		for _, id := range sel.IDs {
+			reqLog, err := svc.repo.FindRequestLogByID(ctx, id)
+			if errors.Is(err, ErrRequestNotFound) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("reqlog: failed to find request log: %w", err)
+			}
+
+			if reqLog.ProjectID.Compare(projectID) != 0 {
+				continue
+			}
+
+			reqLogs = append(reqLogs, reqLog)
+		}
+
+		return reqLogs, nil
+	case sel.Filter != nil:
+		filter := *sel.Filter
+		filter.ProjectID = projectID
+
+		return svc.repo.FindRequestLogs(ctx, filter, svc.scope)
+	default:
+		return nil, ErrInvalidSelection
+	}
+}
+
+// TagRequests adds and removes tags of the selected request logs, and returns
+// the number of request logs that were updated.
+func (svc *service) TagRequests(ctx context.Context, sel Selection, add, remove []string) (int, error) {
+	if svc.ReadOnly() {
+		return 0, ErrReadOnly
+	}
+
+	reqLogs, err := svc.FindSelectedRequests(ctx, sel)
+	if err != nil {
+		return 0, err
+	}
+
+	svc.updateMu.Lock()
+	defer svc.updateMu.Unlock()
+
+	for _, reqLog := range reqLogs {
+		reqLog.Tags = updateTags(reqLog.Tags, add, remove)
+		reqLog.Response = nil
+
+		if err := svc.repo.StoreRequestLog(ctx, reqLog); err != nil {
+			return 0, fmt.Errorf("reqlog: failed to store request log: %w", err)
+		}
+	}
+
+	return len(reqLogs), nil
+}
+
+// DeleteRequests deletes the selected request logs, and returns the number of
+// request logs that were deleted.
+func (svc *service) DeleteRequests(ctx context.Context, sel Selection) (int, error) {
+	if svc.ReadOnly() {
+		return 0, ErrReadOnly
+	}
+
+	reqLogs, err := svc.FindSelectedRequests(ctx, sel)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(reqLogs) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]ulid.ULID, len(reqLogs))
+	for i, reqLog := range reqLogs {
+		ids[i] = reqLog.ID
+	}
+
+	svc.updateMu.Lock()
+	defer svc.updateMu.Unlock()
+
+	if err := svc.repo.DeleteRequestLogs(ctx, svc.ActiveProjectID(), ids); err != nil {
+		return 0, fmt.Errorf("reqlog: failed to delete request logs: %w", err)
+	}
+
+	return len(ids), nil
+}
+
+// updateTags returns the sorted set of tags, with add added and remove removed.
+func updateTags(tags, add, remove []string) []string {
+	set := make(map[string]struct{}, len(tags)+len(add))
+
+	for _, tag := range tags {
+		set[tag] = struct{}{}
+	}
+
+	for _, tag := range add {
+		if tag != "" {
+			set[tag] = struct{}{}
+		}
+	}
+
+	for _, tag := range remove {
+		delete(set, tag)
+	}
+
+	if len(set) == 0 {
+		return nil
+	}
+
+	updated := make([]string, 0, len(set))
+	for tag := range set {
+		updated = append(updated, tag)
+	}
+
+	sort.Strings(updated)
+
+	return updated
+}
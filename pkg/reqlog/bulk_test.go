@@ -0,0 +1,356 @@
+package reqlog_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+func TestTagRequests(t *testing.T) {
+	t.Parallel()
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	otherProjectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	reqLogs := map[ulid.ULID]reqlog.RequestLog{}
+
+	for _, reqLog := range []reqlog.RequestLog{
+		{ProjectID: projectID, Tags: []string{"todo"}},
+		{ProjectID: projectID, Tags: []string{"auth", "todo"}},
+		{ProjectID: otherProjectID},
+	} {
+		reqLog.ID = ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+		reqLogs[reqLog.ID] = reqLog
+	}
+
+	repoMock := &RepoMock{
+		FindRequestLogByIDFunc: func(_ context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
+			reqLog, ok := reqLogs[id]
+			if !ok {
+				return reqlog.RequestLog{}, reqlog.ErrRequestNotFound
+			}
+
+			return reqLog, nil
+		},
+		StoreRequestLogFunc: func(_ context.Context, _ reqlog.RequestLog) error {
+			return nil
+		},
+	}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository: repoMock,
+		Scope:      &scope.Scope{},
+	})
+	svc.SetActiveProjectID(projectID)
+
+	ids := make([]ulid.ULID, 0, len(reqLogs)+1)
+	for id := range reqLogs {
+		ids = append(ids, id)
+	}
+
+	ids = append(ids, ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	n, err := svc.TagRequests(context.Background(), reqlog.Selection{IDs: ids}, []string{"idor"}, []string{"todo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if exp := 2; n != exp {
+		t.Fatalf("incorrect count (expected: %v, got: %v)", exp, n)
+	}
+
+	got := map[ulid.ULID][]string{}
+	for _, call := range repoMock.StoreRequestLogCalls() {
+		got[call.ReqLog.ID] = call.ReqLog.Tags
+	}
+
+	exp := map[ulid.ULID][]string{}
+
+	for id, reqLog := range reqLogs {
+		switch len(reqLog.Tags) {
+		case 1:
+			exp[id] = []string{"idor"}
+		case 2:
+			exp[id] = []string{"auth", "idor"}
+		}
+	}
+
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("stored tags not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestDeleteRequests(t *testing.T) {
+	t.Parallel()
+
+	t.Run("without selection", func(t *testing.T) {
+		t.Parallel()
+
+		svc := reqlog.NewService(reqlog.Config{Repository: &RepoMock{}})
+		svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+		_, err := svc.DeleteRequests(context.Background(), reqlog.Selection{})
+		if !errors.Is(err, reqlog.ErrInvalidSelection) {
+			t.Fatalf("expected `reqlog.ErrInvalidSelection`, got: %v", err)
+		}
+	})
+
+	t.Run("without active project", func(t *testing.T) {
+		t.Parallel()
+
+		svc := reqlog.NewService(reqlog.Config{Repository: &RepoMock{}})
+
+		_, err := svc.DeleteRequests(context.Background(), reqlog.Selection{Filter: &reqlog.FindRequestsFilter{}})
+		if !errors.Is(err, reqlog.ErrProjectIDMustBeSet) {
+			t.Fatalf("expected `reqlog.ErrProjectIDMustBeSet`, got: %v", err)
+		}
+	})
+
+	t.Run("deletes request logs matching filter", func(t *testing.T) {
+		t.Parallel()
+
+		projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+		reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+		repoMock := &RepoMock{
+			FindRequestLogsFunc: func(_ context.Context, filter reqlog.FindRequestsFilter, _ *scope.Scope) ([]reqlog.RequestLog, error) {
+				if filter.ProjectID != projectID || !filter.OnlyInScope {
+					return nil, nil
+				}
+
+				return []reqlog.RequestLog{{ID: reqLogID, ProjectID: projectID}}, nil
+			},
+			DeleteRequestLogsFunc: func(_ context.Context, _ ulid.ULID, _ []ulid.ULID) error {
+				return nil
+			},
+		}
+		svc := reqlog.NewService(reqlog.Config{Repository: repoMock})
+		svc.SetActiveProjectID(projectID)
+
+		sel := reqlog.Selection{Filter: &reqlog.FindRequestsFilter{OnlyInScope: true}}
+
+		n, err := svc.DeleteRequests(context.Background(), sel)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if exp := 1; n != exp {
+			t.Fatalf("incorrect count (expected: %v, got: %v)", exp, n)
+		}
+
+		calls := repoMock.DeleteRequestLogsCalls()
+		if len(calls) != 1 {
+			t.Fatalf("expected 1 `DeleteRequestLogs` call, got: %v", len(calls))
+		}
+
+		if calls[0].ProjectID != projectID {
+			t.Fatalf("incorrect project ID (expected: %v, got: %v)", projectID, calls[0].ProjectID)
+		}
+
+		if diff := cmp.Diff([]ulid.ULID{reqLogID}, calls[0].Ids); diff != "" {
+			t.Fatalf("deleted IDs not equal (-exp, +got):\n%v", diff)
+		}
+	})
+}
+
+func TestExportHAR(t *testing.T) {
+	t.Parallel()
+
+	reqLog := reqlog.RequestLog{
+		ID:       ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		URL:      &url.URL{Scheme: "https", Host: "example.com", Path: "/login", RawQuery: "next=%2F"},
+		Method:   http.MethodPost,
+		Proto:    "HTTP/1.1",
+		Header:   http.Header{"Content-Type": []string{"application/octet-stream"}},
+		Body:     []byte{0xff, 0xfe},
+		RemoteIP: "93.184.216.34",
+		Response: &reqlog.ResponseLog{
+			Proto:      "HTTP/1.1",
+			StatusCode: 302,
+			Status:     "302 Found",
+			Header:     http.Header{"Location": []string{"/"}},
+			Body:       []byte("redirecting"),
+		},
+	}
+
+	data, err := reqlog.ExportHAR([]reqlog.RequestLog{reqLog})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got struct {
+		Log struct {
+			Version string `json:"version"`
+			Entries []struct {
+				ServerIPAddress string `json:"serverIPAddress"`
+				Request         struct {
+					URL         string `json:"url"`
+					QueryString []struct {
+						Name  string `json:"name"`
+						Value string `json:"value"`
+					} `json:"queryString"`
+					PostData struct {
+						Text     string `json:"text"`
+						Encoding string `json:"encoding"`
+					} `json:"postData"`
+				} `json:"request"`
+				Response struct {
+					Status      int    `json:"status"`
+					StatusText  string `json:"statusText"`
+					RedirectURL string `json:"redirectURL"`
+					Content     struct {
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("failed to decode HAR: %v", err)
+	}
+
+	if got.Log.Version != "1.2" || len(got.Log.Entries) != 1 {
+		t.Fatalf("unexpected HAR log (version: %q, entries: %v)", got.Log.Version, len(got.Log.Entries))
+	}
+
+	entry := got.Log.Entries[0]
+
+	for _, tt := range []struct {
+		name string
+		exp  interface{}
+		got  interface{}
+	}{
+		{"url", "https://example.com/login?next=%2F", entry.Request.URL},
+		{"query string value", "/", entry.Request.QueryString[0].Value},
+		{"post data text", "//4=", entry.Request.PostData.Text},
+		{"post data encoding", "base64", entry.Request.PostData.Encoding},
+		{"server IP address", reqLog.RemoteIP, entry.ServerIPAddress},
+		{"status", 302, entry.Response.Status},
+		{"status text", "Found", entry.Response.StatusText},
+		{"redirect URL", "/", entry.Response.RedirectURL},
+		{"content text", "redirecting", entry.Response.Content.Text},
+	} {
+		if tt.exp != tt.got {
+			t.Errorf("incorrect %v (expected: %v, got: %v)", tt.name, tt.exp, tt.got)
+		}
+	}
+}
+
+func TestExportEvidence(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	reqLogs := []reqlog.RequestLog{
+		{
+			ID:     ulid.MustNew(ulid.Timestamp(now.Add(time.Second)), ulidEntropy),
+			URL:    &url.URL{Scheme: "https", Host: "example.com", Path: "/admin"},
+			Method: http.MethodGet,
+			Proto:  "HTTP/1.1",
+			Header: http.Header{"B": []string{"2"}, "A": []string{"1"}},
+			Response: &reqlog.ResponseLog{
+				Proto:      "HTTP/1.1",
+				StatusCode: 200,
+				Status:     "200 OK",
+				Header:     http.Header{},
+				Body:       []byte("secret"),
+			},
+		},
+		{
+			ID:     ulid.MustNew(ulid.Timestamp(now), ulidEntropy),
+			URL:    &url.URL{Scheme: "https", Host: "example.com", Path: "/"},
+			Method: http.MethodGet,
+			Proto:  "HTTP/1.1",
+			Header: http.Header{},
+		},
+	}
+
+	data, err := reqlog.ExportEvidence(reqLogs, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n, err := reqlog.VerifyEvidence(data); err != nil || n != 2 {
+		t.Fatalf("expected 2 verified entries, got: %v (error: %v)", n, err)
+	}
+
+	var doc struct {
+		Entries []map[string]interface{} `json:"entries"`
+	}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("failed to decode evidence: %v", err)
+	}
+
+	// Chained entries are ordered by capture time.
+	if doc.Entries[0]["id"] != reqLogs[1].ID.String() || doc.Entries[1]["previousSHA256"] != doc.Entries[0]["sha256"] {
+		t.Fatalf("expected entries to be chained in order of capture time, got: %v", doc.Entries)
+	}
+
+	expRequest := "GET https://example.com/admin HTTP/1.1\r\nA: 1\r\nB: 2\r\n\r\n"
+	if got := doc.Entries[1]["request"]; got != base64.StdEncoding.EncodeToString([]byte(expRequest)) {
+		t.Errorf("unexpected canonical request: %v", got)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(entries []interface{}) []interface{}
+	}{
+		{
+			name: "modified response",
+			tamper: func(entries []interface{}) []interface{} {
+				entry := entries[1].(map[string]interface{})
+				entry["response"] = base64.StdEncoding.EncodeToString([]byte("HTTP/1.1 200 OK\r\n\r\nforged"))
+				return entries
+			},
+		},
+		{
+			name: "modified timestamp",
+			tamper: func(entries []interface{}) []interface{} {
+				entry := entries[0].(map[string]interface{})
+				entry["capturedAt"] = now.Add(-time.Hour).UTC().Format(time.RFC3339Nano)
+				return entries
+			},
+		},
+		{
+			name: "removed entry",
+			tamper: func(entries []interface{}) []interface{} {
+				return entries[1:]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var doc map[string]interface{}
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatalf("failed to decode evidence: %v", err)
+			}
+
+			doc["entries"] = tt.tamper(doc["entries"].([]interface{}))
+
+			tampered, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatalf("failed to encode evidence: %v", err)
+			}
+
+			if _, err := reqlog.VerifyEvidence(tampered); !errors.Is(err, reqlog.ErrInvalidEvidence) {
+				t.Fatalf("expected `reqlog.ErrInvalidEvidence`, got: %v", err)
+			}
+		})
+	}
+}
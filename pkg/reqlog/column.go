@@ -0,0 +1,96 @@
+package reqlog
+
+import (
+	"fmt"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/search"
+)
+
+var ErrInvalidColumn = errcode.New(errcode.Invalid, "reqlog: invalid column")
+
+const (
+	// maxColumns is the maximum number of custom columns of a project.
+	maxColumns = 20
+	// maxColumnNameLength is the maximum length of the name of a column.
+	maxColumnNameLength = 100
+)
+
+// Column is a custom column of the request log table, whose values are
+// computed from a search expression, see `search.Value`. For example,
+// `req.header.Authorization` shows the value of a header, `res.json.user.id`
+// a field of a JSON response body and `req.url =~ "/users/([0-9]+)"` the first
+// submatch of a regular expression.
+type Column struct {
+	Name       string
+	Expression search.Expression
+}
+
+// ColumnValue is the value of a column for a request log.
+type ColumnValue struct {
+	Name  string
+	Value string
+}
+
+// ValidateColumns returns `ErrInvalidColumn` if there are too many columns, or
+// a column has an empty, duplicate or too long name, or no expression.
+func ValidateColumns(columns []Column) error {
+	if len(columns) > maxColumns {
+		return fmt.Errorf("%w: at most %v columns are allowed", ErrInvalidColumn, maxColumns)
+	}
+
+	names := make(map[string]struct{}, len(columns))
+
+	for _, column := range columns {
+		if column.Name == "" || len(column.Name) > maxColumnNameLength {
+			return fmt.Errorf("%w: name must be 1 to %v characters", ErrInvalidColumn, maxColumnNameLength)
+		}
+
+		if _, ok := names[column.Name]; ok {
+			return fmt.Errorf("%w: duplicate name %q", ErrInvalidColumn, column.Name)
+		}
+
+		names[column.Name] = struct{}{}
+
+		if column.Expression == nil {
+			return fmt.Errorf("%w: column %q has no expression", ErrInvalidColumn, column.Name)
+		}
+	}
+
+	return nil
+}
+
+// ColumnValues returns the values of columns for the request log, in order.
+// Columns whose expression can't be evaluated have an empty value.
+func (reqLog RequestLog) ColumnValues(columns []Column) []ColumnValue {
+	values := make([]ColumnValue, len(columns))
+
+	for i, column := range columns {
+		value, _ := search.Value(column.Expression, searchRecord{&reqLog})
+		values[i] = ColumnValue{Name: column.Name, Value: value}
+	}
+
+	return values
+}
+
+// SetColumns replaces the custom columns of the request log table.
+func (svc *service) SetColumns(columns []Column) error {
+	if err := ValidateColumns(columns); err != nil {
+		return err
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.columns = columns
+
+	return nil
+}
+
+// Columns returns the custom columns of the request log table.
+func (svc *service) Columns() []Column {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.columns
+}
@@ -0,0 +1,103 @@
+package reqlog
+
+import "strings"
+
+// DeviceType is the kind of client that sent a request, based on its
+// `User-Agent` header.
+type DeviceType string
+
+const (
+	DeviceTypeDesktop DeviceType = "desktop"
+	DeviceTypeMobile  DeviceType = "mobile"
+	DeviceTypeTablet  DeviceType = "tablet"
+	DeviceTypeBot     DeviceType = "bot"
+	// DeviceTypeCLI is a command line tool or HTTP library, e.g. `curl`.
+	DeviceTypeCLI     DeviceType = "cli"
+	DeviceTypeUnknown DeviceType = "unknown"
+)
+
+// Device classifies the client of a request, so that traffic of multiple test
+// devices that share the proxy can be told apart.
+type Device struct {
+	Type DeviceType
+	// Operating system, e.g. `iOS` or `Windows`. Empty if unknown.
+	OS string
+}
+
+// Substrings of `User-Agent` values, matched case-insensitively and in order.
+var (
+	deviceOSPatterns = []struct {
+		substr string
+		os     string
+	}{
+		{"windows phone", "Windows Phone"},
+		{"windows", "Windows"},
+		{"iphone", "iOS"},
+		{"ipad", "iPadOS"},
+		{"ipod", "iOS"},
+		{"android", "Android"},
+		{"cros", "ChromeOS"},
+		{"mac os x", "macOS"},
+		{"macintosh", "macOS"},
+		{"linux", "Linux"},
+	}
+	deviceBotPatterns = []string{"bot", "crawler", "spider", "slurp"}
+	deviceCLIPatterns = []string{
+		"curl/", "wget/", "httpie/", "python-requests/", "python-urllib/", "go-http-client/",
+		"okhttp/", "java/", "apache-httpclient/", "node-fetch/", "axios/", "postmanruntime/",
+	}
+)
+
+// ParseDevice classifies a client by its `User-Agent` header value.
+func ParseDevice(userAgent string) Device {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return Device{Type: DeviceTypeUnknown}
+	}
+
+	var device Device
+
+	for _, p := range deviceOSPatterns {
+		if strings.Contains(ua, p.substr) {
+			device.OS = p.os
+			break
+		}
+	}
+
+	switch {
+	case containsAny(ua, deviceBotPatterns):
+		device.Type = DeviceTypeBot
+	case containsAny(ua, deviceCLIPatterns):
+		device.Type = DeviceTypeCLI
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		device.Type = DeviceTypeTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		device.Type = DeviceTypeMobile
+	case device.OS != "":
+		device.Type = DeviceTypeDesktop
+	default:
+		device.Type = DeviceTypeUnknown
+	}
+
+	return device
+}
+
+// String returns the device type and OS, e.g. `mobile (iOS)`.
+func (d Device) String() string {
+	if d.OS == "" {
+		return string(d.Type)
+	}
+
+	return string(d.Type) + " (" + d.OS + ")"
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+package reqlog_test
+
+import (
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+func TestParseDevice(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		userAgent string
+		exp       reqlog.Device
+	}{
+		{
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/96.0.4664.45 Safari/537.36",
+			exp:       reqlog.Device{Type: reqlog.DeviceTypeDesktop, OS: "Windows"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.1 Safari/605.1.15",
+			exp:       reqlog.Device{Type: reqlog.DeviceTypeDesktop, OS: "macOS"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (Linux; Android 12; Pixel 6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/96.0.4664.45 Mobile Safari/537.36",
+			exp:       reqlog.Device{Type: reqlog.DeviceTypeMobile, OS: "Android"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (Linux; Android 11; SM-T870) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/96.0.4664.45 Safari/537.36",
+			exp:       reqlog.Device{Type: reqlog.DeviceTypeTablet, OS: "Android"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (iPad; CPU OS 15_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.1 Mobile/15E148 Safari/604.1",
+			exp:       reqlog.Device{Type: reqlog.DeviceTypeTablet, OS: "iPadOS"},
+		},
+		{
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			exp:       reqlog.Device{Type: reqlog.DeviceTypeBot},
+		},
+		{
+			userAgent: "curl/7.79.1",
+			exp:       reqlog.Device{Type: reqlog.DeviceTypeCLI},
+		},
+		{
+			userAgent: "",
+			exp:       reqlog.Device{Type: reqlog.DeviceTypeUnknown},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := reqlog.ParseDevice(tt.userAgent); got != tt.exp {
+			t.Errorf("unexpected device for %q (expected: %+v, got: %+v)", tt.userAgent, tt.exp, got)
+		}
+	}
+}
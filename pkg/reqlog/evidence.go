@@ -0,0 +1,224 @@
+package reqlog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/oklog/ulid"
+)
+
+// evidenceVersion identifies the format of evidence documents, and the
+// canonical serialization that their hashes are computed over.
+const evidenceVersion = "hetty-evidence/1"
+
+var ErrInvalidEvidence = errors.New("reqlog: invalid evidence")
+
+type (
+	evidenceDocument struct {
+		Version   string `json:"version"`
+		Algorithm string `json:"algorithm"`
+		// True if the hash of each entry includes the hash of the entry before
+		// it, so that entries can't be removed or reordered unnoticed.
+		Chained bool            `json:"chained"`
+		Entries []evidenceEntry `json:"entries"`
+	}
+	evidenceEntry struct {
+		ID         string `json:"id"`
+		CapturedAt string `json:"capturedAt"`
+		// Canonical serialization of the request, base64 encoded.
+		Request       string `json:"request"`
+		RequestSHA256 string `json:"requestSHA256"`
+		// Canonical serialization of the response, base64 encoded. Empty if
+		// there was no response.
+		Response            string `json:"response,omitempty"`
+		ResponseSHA256      string `json:"responseSHA256,omitempty"`
+		ResponseBodyOmitted bool   `json:"responseBodyOmitted,omitempty"`
+		PreviousSHA256      string `json:"previousSHA256,omitempty"`
+		SHA256              string `json:"sha256"`
+	}
+)
+
+// ExportEvidence encodes request logs as an evidence document, e.g. for
+// reports where the integrity of captured traffic may be questioned. Each
+// entry has a canonical serialization of the request and response, their
+// SHA-256 hashes, the capture timestamp, and a hash over all of these. When
+// chained, entries are ordered by capture time, and the hash of each entry
+// includes the hash of the entry before it. See `VerifyEvidence`.
+func ExportEvidence(reqLogs []RequestLog, chained bool) ([]byte, error) {
+	reqLogs = append([]RequestLog(nil), reqLogs...)
+
+	if chained {
+		sort.SliceStable(reqLogs, func(i, j int) bool {
+			return reqLogs[i].ID.Compare(reqLogs[j].ID) < 0
+		})
+	}
+
+	doc := evidenceDocument{
+		Version:   evidenceVersion,
+		Algorithm: "SHA-256",
+		Chained:   chained,
+		Entries:   make([]evidenceEntry, len(reqLogs)),
+	}
+
+	var prev string
+
+	for i, reqLog := range reqLogs {
+		req := canonicalRequest(reqLog)
+
+		entry := evidenceEntry{
+			ID:            reqLog.ID.String(),
+			CapturedAt:    ulid.Time(reqLog.ID.Time()).UTC().Format(time.RFC3339Nano),
+			Request:       base64.StdEncoding.EncodeToString(req),
+			RequestSHA256: sha256Hex(req),
+		}
+
+		if reqLog.Response != nil {
+			res := canonicalResponse(*reqLog.Response)
+			entry.Response = base64.StdEncoding.EncodeToString(res)
+			entry.ResponseSHA256 = sha256Hex(res)
+			entry.ResponseBodyOmitted = reqLog.Response.BodyOmitted
+		}
+
+		if chained {
+			entry.PreviousSHA256 = prev
+		}
+
+		entry.SHA256 = entry.hash()
+		prev = entry.SHA256
+
+		doc.Entries[i] = entry
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("reqlog: failed to encode evidence: %w", err)
+	}
+
+	return data, nil
+}
+
+// VerifyEvidence checks the hashes of an evidence document created with
+// `ExportEvidence`, and returns the number of entries. It returns
+// `ErrInvalidEvidence` if the document was modified.
+func VerifyEvidence(data []byte) (int, error) {
+	var doc evidenceDocument
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, fmt.Errorf("%w: could not decode document: %v", ErrInvalidEvidence, err)
+	}
+
+	if doc.Version != evidenceVersion {
+		return 0, fmt.Errorf("%w: unsupported version %q", ErrInvalidEvidence, doc.Version)
+	}
+
+	var prev string
+
+	for i, entry := range doc.Entries {
+		req, err := base64.StdEncoding.DecodeString(entry.Request)
+		if err != nil {
+			return 0, fmt.Errorf("%w: entry %v: could not decode request: %v", ErrInvalidEvidence, i, err)
+		}
+
+		if sha256Hex(req) != entry.RequestSHA256 {
+			return 0, fmt.Errorf("%w: entry %v: request hash mismatch", ErrInvalidEvidence, i)
+		}
+
+		res, err := base64.StdEncoding.DecodeString(entry.Response)
+		if err != nil {
+			return 0, fmt.Errorf("%w: entry %v: could not decode response: %v", ErrInvalidEvidence, i, err)
+		}
+
+		if entry.Response != "" && sha256Hex(res) != entry.ResponseSHA256 {
+			return 0, fmt.Errorf("%w: entry %v: response hash mismatch", ErrInvalidEvidence, i)
+		}
+
+		if doc.Chained && entry.PreviousSHA256 != prev {
+			return 0, fmt.Errorf("%w: entry %v: chain is broken", ErrInvalidEvidence, i)
+		}
+
+		if entry.hash() != entry.SHA256 {
+			return 0, fmt.Errorf("%w: entry %v: entry hash mismatch", ErrInvalidEvidence, i)
+		}
+
+		prev = entry.SHA256
+	}
+
+	return len(doc.Entries), nil
+}
+
+// hash returns the SHA-256 hash over the version, ID, capture timestamp, hashes
+// of the request and response, and the hash of the previous entry, separated
+// by newlines.
+func (entry evidenceEntry) hash() string {
+	var buf bytes.Buffer
+
+	for _, s := range []string{
+		evidenceVersion,
+		entry.ID,
+		entry.CapturedAt,
+		entry.RequestSHA256,
+		entry.ResponseSHA256,
+		strconv.FormatBool(entry.ResponseBodyOmitted),
+		entry.PreviousSHA256,
+	} {
+		buf.WriteString(s)
+		buf.WriteByte('\n')
+	}
+
+	return sha256Hex(buf.Bytes())
+}
+
+// canonicalRequest returns the request line, headers sorted by name and body
+// of reqLog, in HTTP/1.1 message format.
+func canonicalRequest(reqLog RequestLog) []byte {
+	var buf bytes.Buffer
+
+	var rawURL string
+	if reqLog.URL != nil {
+		rawURL = reqLog.URL.String()
+	}
+
+	fmt.Fprintf(&buf, "%v %v %v\r\n", reqLog.Method, rawURL, reqLog.Proto)
+	writeCanonicalMessage(&buf, reqLog.Header, reqLog.Body)
+
+	return buf.Bytes()
+}
+
+// canonicalResponse returns the status line, headers sorted by name and body
+// of resLog, in HTTP/1.1 message format.
+func canonicalResponse(resLog ResponseLog) []byte {
+	var buf bytes.Buffer
+
+	status := resLog.Status
+	if status == "" {
+		status = fmt.Sprintf("%v %v", resLog.StatusCode, http.StatusText(resLog.StatusCode))
+	}
+
+	fmt.Fprintf(&buf, "%v %v\r\n", resLog.Proto, status)
+	writeCanonicalMessage(&buf, resLog.Header, resLog.Body)
+
+	return buf.Bytes()
+}
+
+func writeCanonicalMessage(buf *bytes.Buffer, header http.Header, body []byte) {
+	for _, h := range harHeaders(header) {
+		fmt.Fprintf(buf, "%v: %v\r\n", h.Name, h.Value)
+	}
+
+	buf.WriteString("\r\n")
+	buf.Write(body)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
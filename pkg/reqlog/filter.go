@@ -0,0 +1,105 @@
+package reqlog
+
+import (
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/search"
+)
+
+// Hostname returns the hostname of the request URL in lowercase, without port.
+func (reqLog RequestLog) Hostname() string {
+	if reqLog.URL == nil {
+		return ""
+	}
+
+	return strings.ToLower(reqLog.URL.Hostname())
+}
+
+// MediaType returns the media type of the `Content-Type` header in lowercase,
+// without parameters, e.g. `text/html`.
+func (resLog ResponseLog) MediaType() string {
+	contentType := resLog.Header.Get("Content-Type")
+	if contentType == "" {
+		return ""
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Fall back to the value before any parameters, for malformed headers.
+		mediaType = strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	}
+
+	return strings.ToLower(mediaType)
+}
+
+// HasFields returns true if the host, status code or content type of the
+// filter is set.
+func (filter FindRequestsFilter) HasFields() bool {
+	return filter.Host != "" || filter.StatusCode != 0 || filter.ContentType != ""
+}
+
+// MatchFields returns true if reqLog matches the host, status code and content
+// type of the filter. Unset fields match any request log.
+func (filter FindRequestsFilter) MatchFields(reqLog RequestLog) bool {
+	if filter.Host != "" && !strings.EqualFold(filter.Host, reqLog.Hostname()) {
+		return false
+	}
+
+	if filter.StatusCode == 0 && filter.ContentType == "" {
+		return true
+	}
+
+	if reqLog.Response == nil {
+		return false
+	}
+
+	if filter.StatusCode != 0 && filter.StatusCode != reqLog.Response.StatusCode {
+		return false
+	}
+
+	if filter.ContentType != "" && !strings.EqualFold(filter.ContentType, reqLog.Response.MediaType()) {
+		return false
+	}
+
+	return true
+}
+
+// TimeRange returns the time range of request logs that can match the filter at
+// now: its `Since` and `Until`, narrowed to the `last` expressions and
+// `req.timestamp` comparisons of its search expression (see
+// `search.TimeRange`). Zero times are unbounded.
+func (filter FindRequestsFilter) TimeRange(now time.Time) (since, until time.Time) {
+	since, until = filter.Since, filter.Until
+
+	if filter.SearchExpr == nil {
+		return since, until
+	}
+
+	exprSince, exprUntil := search.TimeRange(filter.SearchExpr, "req.timestamp", now)
+
+	if exprSince.After(since) {
+		since = exprSince
+	}
+
+	if !exprUntil.IsZero() && (until.IsZero() || exprUntil.Before(until)) {
+		until = exprUntil
+	}
+
+	return since, until
+}
+
+// InTimeRange returns true if the time of the request log ID is at or after
+// since and before until. Zero times are unbounded.
+func InTimeRange(id ulid.ULID, since, until time.Time) bool {
+	t := ulid.Time(id.Time())
+
+	if !since.IsZero() && t.Before(since) {
+		return false
+	}
+
+	return until.IsZero() || t.Before(until)
+}
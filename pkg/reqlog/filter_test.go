@@ -0,0 +1,154 @@
+package reqlog_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+func TestFindRequestsFilterMatchFields(t *testing.T) {
+	t.Parallel()
+
+	reqLog := reqlog.RequestLog{
+		URL: &url.URL{Scheme: "https", Host: "Example.com:8443", Path: "/"},
+		Response: &reqlog.ResponseLog{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"Application/JSON; charset=utf-8"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter reqlog.FindRequestsFilter
+		reqLog reqlog.RequestLog
+		exp    bool
+	}{
+		{
+			name:   "no fields",
+			filter: reqlog.FindRequestsFilter{},
+			reqLog: reqlog.RequestLog{},
+			exp:    true,
+		},
+		{
+			name:   "all fields match",
+			filter: reqlog.FindRequestsFilter{Host: "example.com", StatusCode: 200, ContentType: "application/json"},
+			reqLog: reqLog,
+			exp:    true,
+		},
+		{
+			name:   "host doesn't match",
+			filter: reqlog.FindRequestsFilter{Host: "example.org"},
+			reqLog: reqLog,
+			exp:    false,
+		},
+		{
+			name:   "content type doesn't match",
+			filter: reqlog.FindRequestsFilter{ContentType: "text/html"},
+			reqLog: reqLog,
+			exp:    false,
+		},
+		{
+			name:   "status code without response",
+			filter: reqlog.FindRequestsFilter{StatusCode: 200},
+			reqLog: reqlog.RequestLog{URL: reqLog.URL},
+			exp:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.filter.MatchFields(tt.reqLog); got != tt.exp {
+				t.Fatalf("expected %v, got: %v", tt.exp, got)
+			}
+		})
+	}
+}
+
+func TestFindRequestsFilterTimeRange(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		filter     reqlog.FindRequestsFilter
+		query      string
+		expSince   time.Time
+		expUntil   time.Time
+		inRange    []time.Time
+		outOfRange []time.Time
+	}{
+		{
+			name:       "since and until",
+			filter:     reqlog.FindRequestsFilter{Since: since, Until: until},
+			expSince:   since,
+			expUntil:   until,
+			inRange:    []time.Time{since, until.Add(-time.Millisecond)},
+			outOfRange: []time.Time{since.Add(-time.Millisecond), until},
+		},
+		{
+			name:     "narrowed by search expression",
+			filter:   reqlog.FindRequestsFilter{Since: since, Until: until},
+			query:    `last 15h AND req.timestamp < "2024-01-01T12:00:00Z"`,
+			expSince: now.Add(-15 * time.Hour),
+			expUntil: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "not widened by search expression",
+			filter:   reqlog.FindRequestsFilter{Since: since, Until: until},
+			query:    "last 7d",
+			expSince: since,
+			expUntil: until,
+		},
+		{
+			name:     "search expression only",
+			query:    "last 15m",
+			expSince: now.Add(-15 * time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if tt.query != "" {
+				expr, err := reqlog.ParseSearchExpr(tt.query)
+				if err != nil {
+					t.Fatalf("unexpected error parsing search expression: %v", err)
+				}
+
+				tt.filter.SearchExpr = expr
+			}
+
+			gotSince, gotUntil := tt.filter.TimeRange(now)
+			if !gotSince.Equal(tt.expSince) || !gotUntil.Equal(tt.expUntil) {
+				t.Fatalf("expected range %v to %v, got: %v to %v", tt.expSince, tt.expUntil, gotSince, gotUntil)
+			}
+
+			for _, ts := range tt.inRange {
+				if id := ulid.MustNew(ulid.Timestamp(ts), ulidEntropy); !reqlog.InTimeRange(id, gotSince, gotUntil) {
+					t.Errorf("expected ID of %v to be in range", ts)
+				}
+			}
+
+			for _, ts := range tt.outOfRange {
+				if id := ulid.MustNew(ulid.Timestamp(ts), ulidEntropy); reqlog.InTimeRange(id, gotSince, gotUntil) {
+					t.Errorf("expected ID of %v to be out of range", ts)
+				}
+			}
+		})
+	}
+}
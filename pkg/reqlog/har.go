@@ -0,0 +1,197 @@
+package reqlog
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/oklog/ulid"
+)
+
+// HAR 1.2 types, see: http://www.softwareishard.com/blog/har-12-spec/
+type (
+	harLog struct {
+		Log harLogContent `json:"log"`
+	}
+	harLogContent struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            int         `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		Content     harContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Encoding string `json:"encoding,omitempty"`
+	}
+	harContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+	}
+	harTimings struct {
+		Send    int `json:"send"`
+		Wait    int `json:"wait"`
+		Receive int `json:"receive"`
+	}
+)
+
+// ExportHAR encodes request logs as a HAR (HTTP Archive) 1.2 document, e.g. for
+// importing them in other tools. Bodies that aren't valid UTF-8 are base64
+// encoded.
+func ExportHAR(reqLogs []RequestLog) ([]byte, error) {
+	entries := make([]harEntry, len(reqLogs))
+
+	for i, reqLog := range reqLogs {
+		entries[i] = harEntryFromRequestLog(reqLog)
+	}
+
+	data, err := json.MarshalIndent(harLog{
+		Log: harLogContent{
+			Version: "1.2",
+			Creator: harCreator{Name: "Hetty"},
+			Entries: entries,
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("reqlog: failed to encode HAR: %w", err)
+	}
+
+	return data, nil
+}
+
+func harEntryFromRequestLog(reqLog RequestLog) harEntry {
+	entry := harEntry{
+		StartedDateTime: ulid.Time(reqLog.ID.Time()).UTC().Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      reqLog.Method,
+			HTTPVersion: reqLog.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(reqLog.Header),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(reqLog.Body),
+		},
+		Response: harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		ServerIPAddress: reqLog.ServerIP(),
+		Comment:         reqLog.Error,
+	}
+
+	if reqLog.URL != nil {
+		entry.Request.URL = reqLog.URL.String()
+
+		for key, values := range reqLog.URL.Query() {
+			for _, value := range values {
+				entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: key, Value: value})
+			}
+		}
+
+		sort.SliceStable(entry.Request.QueryString, func(i, j int) bool {
+			return entry.Request.QueryString[i].Name < entry.Request.QueryString[j].Name
+		})
+	}
+
+	if len(reqLog.Body) > 0 {
+		text, encoding := harText(reqLog.Body)
+		entry.Request.PostData = &harPostData{
+			MimeType: reqLog.Header.Get("Content-Type"),
+			Text:     text,
+			Encoding: encoding,
+		}
+	}
+
+	if resLog := reqLog.Response; resLog != nil {
+		entry.Response.Status = resLog.StatusCode
+		entry.Response.StatusText = strings.TrimPrefix(resLog.Status, strconv.Itoa(resLog.StatusCode)+" ")
+		entry.Response.HTTPVersion = resLog.Proto
+		entry.Response.Headers = harHeaders(resLog.Header)
+		entry.Response.RedirectURL = resLog.Header.Get("Location")
+		entry.Response.BodySize = len(resLog.Body)
+		entry.Response.Content = harContent{
+			Size:     len(resLog.Body),
+			MimeType: resLog.Header.Get("Content-Type"),
+		}
+		entry.Response.Content.Text, entry.Response.Content.Encoding = harText(resLog.Body)
+	}
+
+	return entry
+}
+
+// harHeaders returns the headers of h, sorted by name.
+func harHeaders(h http.Header) []harNameValue {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	headers := make([]harNameValue, 0, len(h))
+
+	for _, key := range keys {
+		for _, value := range h[key] {
+			headers = append(headers, harNameValue{Name: key, Value: value})
+		}
+	}
+
+	return headers
+}
+
+func harText(body []byte) (text, encoding string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
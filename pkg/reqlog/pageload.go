@@ -0,0 +1,112 @@
+package reqlog
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid"
+)
+
+// Maximum duration between a request and the requests it triggers, e.g. for
+// images referenced by a document, for them to be in the same page load.
+const pageLoadTTL = 30 * time.Second
+
+type pendingPageLoad struct {
+	pageLoadID ulid.ULID
+	expires    time.Time
+}
+
+// isNavigation returns true if req loads a top-level document, which starts a
+// page load. Browsers send fetch metadata (`Sec-Fetch-Dest`); for clients that
+// don't, navigations are GET requests that accept HTML.
+func isNavigation(req *http.Request) bool {
+	if dest := req.Header.Get("Sec-Fetch-Dest"); dest != "" {
+		return dest == "document"
+	}
+
+	return req.Method == http.MethodGet && strings.Contains(req.Header.Get("Accept"), "text/html")
+}
+
+// pageLoadID returns the ID of the page load that reqLog is part of: its own
+// ID for navigations, or the page load of the request in its `Referer` header.
+// Requests that follow a redirect are in the page load of the redirect.
+func (svc *service) pageLoadID(req *http.Request, reqLog RequestLog, redirect pendingRedirect) ulid.ULID {
+	if redirect.pageLoadID.Compare(ulid.ULID{}) != 0 {
+		return redirect.pageLoadID
+	}
+
+	if isNavigation(req) {
+		return reqLog.ID
+	}
+
+	referer, err := url.Parse(req.Header.Get("Referer"))
+	if err != nil || referer.Host == "" {
+		return ulid.ULID{}
+	}
+
+	key := newRedirectKey(reqLog.ProjectID, referer)
+
+	svc.pageLoadsMu.Lock()
+	defer svc.pageLoadsMu.Unlock()
+
+	pageLoad, ok := svc.pageLoads[key]
+	if !ok || time.Now().After(pageLoad.expires) {
+		return ulid.ULID{}
+	}
+
+	return pageLoad.pageLoadID
+}
+
+// pushPageLoad remembers the URL of a request in a page load, so that requests
+// it triggers (e.g. fonts referenced by a stylesheet) can be linked to it.
+func (svc *service) pushPageLoad(reqLog RequestLog) {
+	if reqLog.PageLoadID.Compare(ulid.ULID{}) == 0 || reqLog.URL == nil {
+		return
+	}
+
+	now := time.Now()
+
+	svc.pageLoadsMu.Lock()
+	defer svc.pageLoadsMu.Unlock()
+
+	for key, pageLoad := range svc.pageLoads {
+		if now.After(pageLoad.expires) {
+			delete(svc.pageLoads, key)
+		}
+	}
+
+	svc.pageLoads[newRedirectKey(reqLog.ProjectID, reqLog.URL)] = pendingPageLoad{
+		pageLoadID: reqLog.PageLoadID,
+		expires:    now.Add(pageLoadTTL),
+	}
+}
+
+// FindPageLoad returns the request logs of the page load that the request log
+// with the given ID is part of, ordered from the first request to the last.
+// Request logs that aren't part of a page load are returned by themselves.
+func (svc *service) FindPageLoad(ctx context.Context, id ulid.ULID) ([]RequestLog, error) {
+	reqLog, err := svc.repo.FindRequestLogByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if reqLog.PageLoadID.Compare(ulid.ULID{}) == 0 {
+		return []RequestLog{reqLog}, nil
+	}
+
+	filter := FindRequestsFilter{
+		ProjectID:  reqLog.ProjectID,
+		PageLoadID: reqLog.PageLoadID,
+	}
+
+	return svc.repo.FindRequestLogs(ctx, filter, svc.scope)
+}
+
+// StartsPageLoad returns true if reqLog isn't part of a page load, or is the
+// navigation that started it.
+func (reqLog RequestLog) StartsPageLoad() bool {
+	return reqLog.PageLoadID.Compare(ulid.ULID{}) == 0 || reqLog.PageLoadID.Compare(reqLog.ID) == 0
+}
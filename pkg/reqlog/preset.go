@@ -0,0 +1,83 @@
+package reqlog
+
+import (
+	"fmt"
+
+	"github.com/dstotijn/hetty/pkg/search"
+)
+
+// FilterPresets are built-in filters that hide noisy request logs. Each preset
+// is a search expression, which is combined with the search expression of a
+// filter, see `FindRequestsFilter.SearchExpression`.
+type FilterPresets struct {
+	// Hide images, fonts, stylesheets and media, by extension of the URL path.
+	HideStaticAssets bool
+	// Hide requests to common analytics, advertising and tracking domains.
+	HideTracking bool
+	// Hide OCSP and CRL requests, which clients send to check certificates.
+	HideCertRevocation bool
+}
+
+var (
+	staticAssetsExpr = mustParsePreset(
+		`req.url =~ "(?i)^[^?#]*\.(png|jpe?g|gif|webp|avif|bmp|ico|svg|css|woff2?|ttf|otf|eot|mp3|mp4|webm|ogg|wav)([?#]|$)"`,
+	)
+	trackingExpr = mustParsePreset(
+		`req.url =~ "(?i)^[a-z]+://([^/?#]+\.)?(google-analytics\.com|analytics\.google\.com|googletagmanager\.com|` +
+			`doubleclick\.net|googlesyndication\.com|googleadservices\.com|facebook\.net|connect\.facebook\.com|` +
+			`hotjar\.com|segment\.io|segment\.com|mixpanel\.com|amplitude\.com|newrelic\.com|nr-data\.net|` +
+			`sentry\.io|clarity\.ms|bat\.bing\.com|scorecardresearch\.com|quantserve\.com|adnxs\.com|criteo\.com|` +
+			`taboola\.com|outbrain\.com|fullstory\.com|intercom\.io|optimizely\.com)(:[0-9]+)?([/?#]|$)"`,
+	)
+	certRevocationExpr = mustParsePreset(
+		`req.url =~ "(?i)^[a-z]+://(ocsp|crl)[^/?#]*\." OR req.url =~ "(?i)^[a-z]+://[^/?#]+\.o\.lencr\.org([:/?#]|$)" ` +
+			`OR req.url =~ "(?i)^[^?#]*\.crl([?#]|$)"`,
+	)
+)
+
+func mustParsePreset(query string) search.Expression {
+	expr, err := search.ParseQuery(query)
+	if err != nil {
+		panic(fmt.Sprintf("reqlog: invalid filter preset %q: %v", query, err))
+	}
+
+	return expr
+}
+
+// Expression returns a search expression that matches request logs that
+// aren't hidden by the enabled presets, or nil if none are enabled.
+func (presets FilterPresets) Expression() search.Expression {
+	var expr search.Expression
+
+	for _, preset := range []struct {
+		enabled bool
+		expr    search.Expression
+	}{
+		{presets.HideStaticAssets, staticAssetsExpr},
+		{presets.HideTracking, trackingExpr},
+		{presets.HideCertRevocation, certRevocationExpr},
+	} {
+		if preset.enabled {
+			expr = and(expr, search.PrefixExpression{Operator: search.TokOpNot, Right: preset.expr})
+		}
+	}
+
+	return expr
+}
+
+// SearchExpression returns the search expression of the filter, combined with
+// the expressions of its presets. Repositories use it to match request logs.
+func (filter FindRequestsFilter) SearchExpression() search.Expression {
+	return and(filter.SearchExpr, filter.Presets.Expression())
+}
+
+func and(left, right search.Expression) search.Expression {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	default:
+		return search.InfixExpression{Operator: search.TokOpAnd, Left: left, Right: right}
+	}
+}
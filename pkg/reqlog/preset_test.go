@@ -0,0 +1,75 @@
+package reqlog_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/search"
+)
+
+func TestFindRequestsFilterPresets(t *testing.T) {
+	t.Parallel()
+
+	all := reqlog.FilterPresets{HideStaticAssets: true, HideTracking: true, HideCertRevocation: true}
+
+	tests := []struct {
+		name       string
+		url        string
+		presets    reqlog.FilterPresets
+		query      string
+		expVisible bool
+	}{
+		{name: "page", url: "https://example.com/index.html", presets: all, expVisible: true},
+		{name: "image", url: "https://example.com/img/logo.PNG?v=2", presets: all, expVisible: false},
+		{name: "font", url: "https://example.com/font.woff2", presets: all, expVisible: false},
+		{name: "image without preset", url: "https://example.com/logo.png", expVisible: true},
+		{name: "extension in query", url: "https://example.com/api?file=logo.png", presets: all, expVisible: true},
+		{name: "analytics", url: "https://www.google-analytics.com/g/collect", presets: all, expVisible: false},
+		{name: "lookalike domain", url: "https://notgoogle-analytics.com/", presets: all, expVisible: true},
+		{name: "OCSP", url: "http://ocsp.digicert.com/", presets: all, expVisible: false},
+		{name: "Let's Encrypt OCSP", url: "http://r3.o.lencr.org/", presets: all, expVisible: false},
+		{name: "CRL", url: "http://crl3.digicert.com/ca.crl", presets: all, expVisible: false},
+		{
+			name:       "combined with search expression",
+			url:        "https://example.com/api",
+			presets:    all,
+			query:      "req.method = POST",
+			expVisible: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("unexpected error parsing URL: %v", err)
+			}
+
+			filter := reqlog.FindRequestsFilter{Presets: tt.presets}
+
+			if tt.query != "" {
+				if filter.SearchExpr, err = search.ParseQuery(tt.query); err != nil {
+					t.Fatalf("unexpected error parsing query: %v", err)
+				}
+			}
+
+			visible := true
+
+			if expr := filter.SearchExpression(); expr != nil {
+				visible, err = reqlog.RequestLog{URL: u, Method: "GET"}.Matches(expr)
+				if err != nil {
+					t.Fatalf("unexpected error matching: %v", err)
+				}
+			}
+
+			if visible != tt.expVisible {
+				t.Errorf("expected visible: %v, got: %v", tt.expVisible, visible)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package reqlog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+// Maximum duration between a redirect response and the request that follows
+// it, for the two to be linked.
+const redirectTTL = 30 * time.Second
+
+// Maximum number of hops in a redirect chain, to guard against cycles.
+const maxRedirectChainLen = 50
+
+type redirectKey struct {
+	projectID ulid.ULID
+	url       string
+}
+
+type pendingRedirect struct {
+	reqLogID      ulid.ULID
+	correlationID ulid.ULID
+	pageLoadID    ulid.ULID
+	expires       time.Time
+}
+
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusSeeOther,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+// pushRedirect remembers the target of a redirect response, so that the next
+// request for it can be linked to the request log that was redirected.
+func (svc *service) pushRedirect(projectID, reqLogID ulid.ULID, res *http.Response) {
+	if !isRedirect(res.StatusCode) || res.Request == nil || res.Request.URL == nil {
+		return
+	}
+
+	location := res.Header.Get("Location")
+	if location == "" {
+		return
+	}
+
+	target, err := res.Request.URL.Parse(location)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	svc.redirectsMu.Lock()
+	defer svc.redirectsMu.Unlock()
+
+	for key, redirect := range svc.redirects {
+		if now.After(redirect.expires) {
+			delete(svc.redirects, key)
+		}
+	}
+
+	correlationID, _ := res.Request.Context().Value(proxy.CorrelationIDKey).(ulid.ULID)
+	pageLoadID, _ := res.Request.Context().Value(pageLoadIDKey).(ulid.ULID)
+
+	svc.redirects[newRedirectKey(projectID, target)] = pendingRedirect{
+		reqLogID:      reqLogID,
+		correlationID: correlationID,
+		pageLoadID:    pageLoadID,
+		expires:       now.Add(redirectTTL),
+	}
+}
+
+// popRedirect returns the pending redirect to u, if any.
+func (svc *service) popRedirect(projectID ulid.ULID, u *url.URL) (pendingRedirect, bool) {
+	if u == nil {
+		return pendingRedirect{}, false
+	}
+
+	key := newRedirectKey(projectID, u)
+
+	svc.redirectsMu.Lock()
+	defer svc.redirectsMu.Unlock()
+
+	redirect, ok := svc.redirects[key]
+	if !ok {
+		return pendingRedirect{}, false
+	}
+
+	delete(svc.redirects, key)
+
+	if time.Now().After(redirect.expires) {
+		return pendingRedirect{}, false
+	}
+
+	return redirect, true
+}
+
+func newRedirectKey(projectID ulid.ULID, u *url.URL) redirectKey {
+	withoutFragment := *u
+	withoutFragment.Fragment = ""
+
+	return redirectKey{projectID: projectID, url: withoutFragment.String()}
+}
+
+// FindRedirectChain returns the redirect chain that contains the request log
+// with the given ID, ordered from the first request to the last.
+func (svc *service) FindRedirectChain(ctx context.Context, id ulid.ULID) ([]RequestLog, error) {
+	first, err := svc.repo.FindRequestLogByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < maxRedirectChainLen && first.RedirectFromID.Compare(ulid.ULID{}) != 0; i++ {
+		prev, err := svc.repo.FindRequestLogByID(ctx, first.RedirectFromID)
+		if err != nil {
+			// The start of the chain may have been deleted.
+			break
+		}
+
+		first = prev
+	}
+
+	chain := []RequestLog{first}
+
+	// Follow the chain forward by the redirect source of request logs, so only
+	// the request logs in the chain are read.
+	for len(chain) < maxRedirectChainLen {
+		ids, err := svc.repo.FindRedirectedRequestLogIDs(ctx, first.ProjectID, chain[len(chain)-1].ID)
+		if err != nil {
+			return nil, fmt.Errorf("reqlog: failed to find redirected request logs: %w", err)
+		}
+
+		if len(ids) == 0 {
+			break
+		}
+
+		reqLog, err := svc.repo.FindRequestLogByID(ctx, ids[0])
+		if err != nil {
+			return nil, fmt.Errorf("reqlog: failed to find request log: %w", err)
+		}
+
+		chain = append(chain, reqLog)
+	}
+
+	return chain, nil
+}
@@ -14,4 +14,7 @@ type Repository interface {
 	StoreRequestLog(ctx context.Context, reqLog RequestLog) error
 	StoreResponseLog(ctx context.Context, reqLogID ulid.ULID, resLog ResponseLog) error
 	ClearRequestLogs(ctx context.Context, projectID ulid.ULID) error
+	DeleteRequestLogs(ctx context.Context, projectID ulid.ULID, ids []ulid.ULID) error
+	FindRedirectedRequestLogIDs(ctx context.Context, projectID, redirectFromID ulid.ULID) ([]ulid.ULID, error)
+	FindRequestLogFieldValues(ctx context.Context, projectID ulid.ULID) (FieldValues, error)
 }
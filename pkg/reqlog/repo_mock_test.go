@@ -17,38 +17,56 @@ var _ reqlog.Repository = &RepoMock{}
 
 // RepoMock is a mock implementation of reqlog.Repository.
 //
-// 	func TestSomethingThatUsesRepository(t *testing.T) {
+//	func TestSomethingThatUsesRepository(t *testing.T) {
 //
-// 		// make and configure a mocked reqlog.Repository
-// 		mockedRepository := &RepoMock{
-// 			ClearRequestLogsFunc: func(ctx context.Context, projectID ulid.ULID) error {
-// 				panic("mock out the ClearRequestLogs method")
-// 			},
-// 			FindRequestLogByIDFunc: func(ctx context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
-// 				panic("mock out the FindRequestLogByID method")
-// 			},
-// 			FindRequestLogsFunc: func(ctx context.Context, filter reqlog.FindRequestsFilter, scopeMoqParam *scope.Scope) ([]reqlog.RequestLog, error) {
-// 				panic("mock out the FindRequestLogs method")
-// 			},
-// 			StoreRequestLogFunc: func(ctx context.Context, reqLog reqlog.RequestLog) error {
-// 				panic("mock out the StoreRequestLog method")
-// 			},
-// 			StoreResponseLogFunc: func(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error {
-// 				panic("mock out the StoreResponseLog method")
-// 			},
-// 		}
+//		// make and configure a mocked reqlog.Repository
+//		mockedRepository := &RepoMock{
+//			ClearRequestLogsFunc: func(ctx context.Context, projectID ulid.ULID) error {
+//				panic("mock out the ClearRequestLogs method")
+//			},
+//			DeleteRequestLogsFunc: func(ctx context.Context, projectID ulid.ULID, ids []ulid.ULID) error {
+//				panic("mock out the DeleteRequestLogs method")
+//			},
+//			FindRedirectedRequestLogIDsFunc: func(ctx context.Context, projectID ulid.ULID, redirectFromID ulid.ULID) ([]ulid.ULID, error) {
+//				panic("mock out the FindRedirectedRequestLogIDs method")
+//			},
+//			FindRequestLogByIDFunc: func(ctx context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
+//				panic("mock out the FindRequestLogByID method")
+//			},
+//			FindRequestLogFieldValuesFunc: func(ctx context.Context, projectID ulid.ULID) (reqlog.FieldValues, error) {
+//				panic("mock out the FindRequestLogFieldValues method")
+//			},
+//			FindRequestLogsFunc: func(ctx context.Context, filter reqlog.FindRequestsFilter, scopeMoqParam *scope.Scope) ([]reqlog.RequestLog, error) {
+//				panic("mock out the FindRequestLogs method")
+//			},
+//			StoreRequestLogFunc: func(ctx context.Context, reqLog reqlog.RequestLog) error {
+//				panic("mock out the StoreRequestLog method")
+//			},
+//			StoreResponseLogFunc: func(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error {
+//				panic("mock out the StoreResponseLog method")
+//			},
+//		}
 //
-// 		// use mockedRepository in code that requires reqlog.Repository
-// 		// and then make assertions.
+//		// use mockedRepository in code that requires reqlog.Repository
+//		// and then make assertions.
 //
-// 	}
+//	}
 type RepoMock struct {
 	// ClearRequestLogsFunc mocks the ClearRequestLogs method.
 	ClearRequestLogsFunc func(ctx context.Context, projectID ulid.ULID) error
 
+	// DeleteRequestLogsFunc mocks the DeleteRequestLogs method.
+	DeleteRequestLogsFunc func(ctx context.Context, projectID ulid.ULID, ids []ulid.ULID) error
+
+	// FindRedirectedRequestLogIDsFunc mocks the FindRedirectedRequestLogIDs method.
+	FindRedirectedRequestLogIDsFunc func(ctx context.Context, projectID ulid.ULID, redirectFromID ulid.ULID) ([]ulid.ULID, error)
+
 	// FindRequestLogByIDFunc mocks the FindRequestLogByID method.
 	FindRequestLogByIDFunc func(ctx context.Context, id ulid.ULID) (reqlog.RequestLog, error)
 
+	// FindRequestLogFieldValuesFunc mocks the FindRequestLogFieldValues method.
+	FindRequestLogFieldValuesFunc func(ctx context.Context, projectID ulid.ULID) (reqlog.FieldValues, error)
+
 	// FindRequestLogsFunc mocks the FindRequestLogs method.
 	FindRequestLogsFunc func(ctx context.Context, filter reqlog.FindRequestsFilter, scopeMoqParam *scope.Scope) ([]reqlog.RequestLog, error)
 
@@ -67,6 +85,24 @@ type RepoMock struct {
 			// ProjectID is the projectID argument value.
 			ProjectID ulid.ULID
 		}
+		// DeleteRequestLogs holds details about calls to the DeleteRequestLogs method.
+		DeleteRequestLogs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+			// Ids is the ids argument value.
+			Ids []ulid.ULID
+		}
+		// FindRedirectedRequestLogIDs holds details about calls to the FindRedirectedRequestLogIDs method.
+		FindRedirectedRequestLogIDs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+			// RedirectFromID is the redirectFromID argument value.
+			RedirectFromID ulid.ULID
+		}
 		// FindRequestLogByID holds details about calls to the FindRequestLogByID method.
 		FindRequestLogByID []struct {
 			// Ctx is the ctx argument value.
@@ -74,6 +110,13 @@ type RepoMock struct {
 			// ID is the id argument value.
 			ID ulid.ULID
 		}
+		// FindRequestLogFieldValues holds details about calls to the FindRequestLogFieldValues method.
+		FindRequestLogFieldValues []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+		}
 		// FindRequestLogs holds details about calls to the FindRequestLogs method.
 		FindRequestLogs []struct {
 			// Ctx is the ctx argument value.
@@ -100,11 +143,14 @@ type RepoMock struct {
 			ResLog reqlog.ResponseLog
 		}
 	}
-	lockClearRequestLogs   sync.RWMutex
-	lockFindRequestLogByID sync.RWMutex
-	lockFindRequestLogs    sync.RWMutex
-	lockStoreRequestLog    sync.RWMutex
-	lockStoreResponseLog   sync.RWMutex
+	lockClearRequestLogs            sync.RWMutex
+	lockDeleteRequestLogs           sync.RWMutex
+	lockFindRedirectedRequestLogIDs sync.RWMutex
+	lockFindRequestLogByID          sync.RWMutex
+	lockFindRequestLogFieldValues   sync.RWMutex
+	lockFindRequestLogs             sync.RWMutex
+	lockStoreRequestLog             sync.RWMutex
+	lockStoreResponseLog            sync.RWMutex
 }
 
 // ClearRequestLogs calls ClearRequestLogsFunc.
@@ -127,7 +173,8 @@ func (mock *RepoMock) ClearRequestLogs(ctx context.Context, projectID ulid.ULID)
 
 // ClearRequestLogsCalls gets all the calls that were made to ClearRequestLogs.
 // Check the length with:
-//     len(mockedRepository.ClearRequestLogsCalls())
+//
+//	len(mockedRepository.ClearRequestLogsCalls())
 func (mock *RepoMock) ClearRequestLogsCalls() []struct {
 	Ctx       context.Context
 	ProjectID ulid.ULID
@@ -142,6 +189,86 @@ func (mock *RepoMock) ClearRequestLogsCalls() []struct {
 	return calls
 }
 
+// DeleteRequestLogs calls DeleteRequestLogsFunc.
+func (mock *RepoMock) DeleteRequestLogs(ctx context.Context, projectID ulid.ULID, ids []ulid.ULID) error {
+	if mock.DeleteRequestLogsFunc == nil {
+		panic("RepoMock.DeleteRequestLogsFunc: method is nil but Repository.DeleteRequestLogs was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+		Ids       []ulid.ULID
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+		Ids:       ids,
+	}
+	mock.lockDeleteRequestLogs.Lock()
+	mock.calls.DeleteRequestLogs = append(mock.calls.DeleteRequestLogs, callInfo)
+	mock.lockDeleteRequestLogs.Unlock()
+	return mock.DeleteRequestLogsFunc(ctx, projectID, ids)
+}
+
+// DeleteRequestLogsCalls gets all the calls that were made to DeleteRequestLogs.
+// Check the length with:
+//
+//	len(mockedRepository.DeleteRequestLogsCalls())
+func (mock *RepoMock) DeleteRequestLogsCalls() []struct {
+	Ctx       context.Context
+	ProjectID ulid.ULID
+	Ids       []ulid.ULID
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+		Ids       []ulid.ULID
+	}
+	mock.lockDeleteRequestLogs.RLock()
+	calls = mock.calls.DeleteRequestLogs
+	mock.lockDeleteRequestLogs.RUnlock()
+	return calls
+}
+
+// FindRedirectedRequestLogIDs calls FindRedirectedRequestLogIDsFunc.
+func (mock *RepoMock) FindRedirectedRequestLogIDs(ctx context.Context, projectID ulid.ULID, redirectFromID ulid.ULID) ([]ulid.ULID, error) {
+	if mock.FindRedirectedRequestLogIDsFunc == nil {
+		panic("RepoMock.FindRedirectedRequestLogIDsFunc: method is nil but Repository.FindRedirectedRequestLogIDs was just called")
+	}
+	callInfo := struct {
+		Ctx            context.Context
+		ProjectID      ulid.ULID
+		RedirectFromID ulid.ULID
+	}{
+		Ctx:            ctx,
+		ProjectID:      projectID,
+		RedirectFromID: redirectFromID,
+	}
+	mock.lockFindRedirectedRequestLogIDs.Lock()
+	mock.calls.FindRedirectedRequestLogIDs = append(mock.calls.FindRedirectedRequestLogIDs, callInfo)
+	mock.lockFindRedirectedRequestLogIDs.Unlock()
+	return mock.FindRedirectedRequestLogIDsFunc(ctx, projectID, redirectFromID)
+}
+
+// FindRedirectedRequestLogIDsCalls gets all the calls that were made to FindRedirectedRequestLogIDs.
+// Check the length with:
+//
+//	len(mockedRepository.FindRedirectedRequestLogIDsCalls())
+func (mock *RepoMock) FindRedirectedRequestLogIDsCalls() []struct {
+	Ctx            context.Context
+	ProjectID      ulid.ULID
+	RedirectFromID ulid.ULID
+} {
+	var calls []struct {
+		Ctx            context.Context
+		ProjectID      ulid.ULID
+		RedirectFromID ulid.ULID
+	}
+	mock.lockFindRedirectedRequestLogIDs.RLock()
+	calls = mock.calls.FindRedirectedRequestLogIDs
+	mock.lockFindRedirectedRequestLogIDs.RUnlock()
+	return calls
+}
+
 // FindRequestLogByID calls FindRequestLogByIDFunc.
 func (mock *RepoMock) FindRequestLogByID(ctx context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
 	if mock.FindRequestLogByIDFunc == nil {
@@ -162,7 +289,8 @@ func (mock *RepoMock) FindRequestLogByID(ctx context.Context, id ulid.ULID) (req
 
 // FindRequestLogByIDCalls gets all the calls that were made to FindRequestLogByID.
 // Check the length with:
-//     len(mockedRepository.FindRequestLogByIDCalls())
+//
+//	len(mockedRepository.FindRequestLogByIDCalls())
 func (mock *RepoMock) FindRequestLogByIDCalls() []struct {
 	Ctx context.Context
 	ID  ulid.ULID
@@ -177,6 +305,42 @@ func (mock *RepoMock) FindRequestLogByIDCalls() []struct {
 	return calls
 }
 
+// FindRequestLogFieldValues calls FindRequestLogFieldValuesFunc.
+func (mock *RepoMock) FindRequestLogFieldValues(ctx context.Context, projectID ulid.ULID) (reqlog.FieldValues, error) {
+	if mock.FindRequestLogFieldValuesFunc == nil {
+		panic("RepoMock.FindRequestLogFieldValuesFunc: method is nil but Repository.FindRequestLogFieldValues was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+	}
+	mock.lockFindRequestLogFieldValues.Lock()
+	mock.calls.FindRequestLogFieldValues = append(mock.calls.FindRequestLogFieldValues, callInfo)
+	mock.lockFindRequestLogFieldValues.Unlock()
+	return mock.FindRequestLogFieldValuesFunc(ctx, projectID)
+}
+
+// FindRequestLogFieldValuesCalls gets all the calls that were made to FindRequestLogFieldValues.
+// Check the length with:
+//
+//	len(mockedRepository.FindRequestLogFieldValuesCalls())
+func (mock *RepoMock) FindRequestLogFieldValuesCalls() []struct {
+	Ctx       context.Context
+	ProjectID ulid.ULID
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}
+	mock.lockFindRequestLogFieldValues.RLock()
+	calls = mock.calls.FindRequestLogFieldValues
+	mock.lockFindRequestLogFieldValues.RUnlock()
+	return calls
+}
+
 // FindRequestLogs calls FindRequestLogsFunc.
 func (mock *RepoMock) FindRequestLogs(ctx context.Context, filter reqlog.FindRequestsFilter, scopeMoqParam *scope.Scope) ([]reqlog.RequestLog, error) {
 	if mock.FindRequestLogsFunc == nil {
@@ -199,7 +363,8 @@ func (mock *RepoMock) FindRequestLogs(ctx context.Context, filter reqlog.FindReq
 
 // FindRequestLogsCalls gets all the calls that were made to FindRequestLogs.
 // Check the length with:
-//     len(mockedRepository.FindRequestLogsCalls())
+//
+//	len(mockedRepository.FindRequestLogsCalls())
 func (mock *RepoMock) FindRequestLogsCalls() []struct {
 	Ctx           context.Context
 	Filter        reqlog.FindRequestsFilter
@@ -236,7 +401,8 @@ func (mock *RepoMock) StoreRequestLog(ctx context.Context, reqLog reqlog.Request
 
 // StoreRequestLogCalls gets all the calls that were made to StoreRequestLog.
 // Check the length with:
-//     len(mockedRepository.StoreRequestLogCalls())
+//
+//	len(mockedRepository.StoreRequestLogCalls())
 func (mock *RepoMock) StoreRequestLogCalls() []struct {
 	Ctx    context.Context
 	ReqLog reqlog.RequestLog
@@ -273,7 +439,8 @@ func (mock *RepoMock) StoreResponseLog(ctx context.Context, reqLogID ulid.ULID,
 
 // StoreResponseLogCalls gets all the calls that were made to StoreResponseLog.
 // Check the length with:
-//     len(mockedRepository.StoreResponseLogCalls())
+//
+//	len(mockedRepository.StoreResponseLogCalls())
 func (mock *RepoMock) StoreResponseLogCalls() []struct {
 	Ctx      context.Context
 	ReqLogID ulid.ULID
@@ -9,30 +9,41 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/oklog/ulid"
 
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/idgen"
 	"github.com/dstotijn/hetty/pkg/proxy"
 	"github.com/dstotijn/hetty/pkg/scope"
 	"github.com/dstotijn/hetty/pkg/search"
+	"github.com/dstotijn/hetty/pkg/tracing"
 )
 
 type contextKey int
 
 const LogBypassedKey contextKey = 0
 
+// ProjectIDKey is set on the context of logged requests, to the ID of the
+// project that the request was logged to. This is the active project, unless
+// the client was routed to another project, see `ClientRoute`.
+const ProjectIDKey contextKey = 2
+
+// pageLoadIDKey is set on the context of logged requests that are part of a
+// page load, to the ID of the page load.
+const pageLoadIDKey contextKey = 3
+
 var (
-	ErrRequestNotFound    = errors.New("reqlog: request not found")
+	ErrRequestNotFound    = errcode.New(errcode.NotFound, "reqlog: request not found")
 	ErrProjectIDMustBeSet = errors.New("reqlog: project ID must be set")
+	ErrReadOnly           = errors.New("reqlog: project is opened read-only")
 )
 
-//nolint:gosec
-var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
-
 type RequestLog struct {
 	ID        ulid.ULID
 	ProjectID ulid.ULID
@@ -43,6 +54,48 @@ type RequestLog struct {
 	Header http.Header
 	Body   []byte
 
+	// URL of the request before it was rewritten by a remote mapping, if any.
+	// `URL` is the URL that the request was sent to.
+	OriginalURL *url.URL
+
+	// Address (`ip:port`) of the client that sent the request to the proxy.
+	// Empty for requests sent by Hetty itself, e.g. via the sender.
+	ClientAddr string
+	// Client classification, based on the `User-Agent` header.
+	Device Device
+
+	// Tags set by the user, e.g. for triaging. Sorted and unique.
+	Tags []string
+	// Views of the bodies, set by the user. See `BodyView`.
+	BodyViews []BodyView
+
+	// ID of the request log whose redirect response led to this request.
+	RedirectFromID ulid.ULID
+	// ID that relates this request to the source that triggered it, e.g. a
+	// sender request.
+	CorrelationID ulid.ULID
+	// ID of the request log of the navigation that triggered this request,
+	// e.g. for images of a page. Equal to ID for the navigation itself. See
+	// pageload.go for the heuristics.
+	PageLoadID ulid.ULID
+
+	// Error of the upstream request (e.g. a DNS error, timeout or TLS failure),
+	// in which case there's no response.
+	Error string
+
+	// Raw bytes of the request and response, when raw capture is enabled.
+	Raw *proxy.RawExchange
+
+	// IP address of the upstream server (or HTTP proxy) that the request was
+	// sent to, if a connection was established but the request failed. For
+	// requests with a response, it's stored on the response log, see
+	// `ServerIP`.
+	RemoteIP string
+
+	// Number of times the upstream request was retried, after the connection
+	// was reset.
+	Retries int
+
 	Response *ResponseLog
 }
 
@@ -52,67 +105,258 @@ type ResponseLog struct {
 	Status     string
 	Header     http.Header
 	Body       []byte
+	// True when the body wasn't stored, because of the project's body rules.
+	BodyOmitted bool
+
+	// TLS connection metadata, for responses received over TLS.
+	TLS *TLSInfo
+
+	// IP address of the upstream server (or HTTP proxy) that the response was
+	// received from.
+	RemoteIP string
+}
+
+// ServerIP returns the IP address of the upstream server (or HTTP proxy) that
+// the request was sent to, or an empty string if unknown.
+func (reqLog RequestLog) ServerIP() string {
+	if reqLog.Response != nil && reqLog.Response.RemoteIP != "" {
+		return reqLog.Response.RemoteIP
+	}
+
+	return reqLog.RemoteIP
 }
 
 type Service interface {
 	FindRequests(ctx context.Context) ([]RequestLog, error)
 	FindRequestLogByID(ctx context.Context, id ulid.ULID) (RequestLog, error)
+	FindRedirectChain(ctx context.Context, id ulid.ULID) ([]RequestLog, error)
+	FindPageLoad(ctx context.Context, id ulid.ULID) ([]RequestLog, error)
+	FindCorrelatedRequests(ctx context.Context, correlationID ulid.ULID) ([]RequestLog, error)
 	ClearRequests(ctx context.Context, projectID ulid.ULID) error
+	FindSelectedRequests(ctx context.Context, sel Selection) ([]RequestLog, error)
+	TagRequests(ctx context.Context, sel Selection, add, remove []string) (int, error)
+	DeleteRequests(ctx context.Context, sel Selection) (int, error)
+	SetBodyView(ctx context.Context, reqLogID ulid.ULID, view BodyView) (BodyView, error)
+	DeleteBodyView(ctx context.Context, reqLogID ulid.ULID, name string) error
 	RequestModifier(next proxy.RequestModifyFunc) proxy.RequestModifyFunc
 	ResponseModifier(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc
+	RequestErrorHandler(req *http.Request, err error)
+	RawCaptureHandler(req *http.Request, raw proxy.RawExchange)
+	RetryHandler(req *http.Request, retries int)
 	SetActiveProjectID(id ulid.ULID)
 	ActiveProjectID() ulid.ULID
 	SetBypassOutOfScopeRequests(bool)
 	BypassOutOfScopeRequests() bool
+	SetReadOnly(readOnly bool)
+	ReadOnly() bool
+	SetCapturePaused(paused bool)
+	CapturePaused() bool
+	SetProjectCapturePaused(projectID ulid.ULID, paused bool)
+	ProjectCapturePaused(projectID ulid.ULID) bool
+	IsCapturePaused() bool
 	SetFindReqsFilter(filter FindRequestsFilter)
 	FindReqsFilter() FindRequestsFilter
+	SetBodyRules(rules BodyRules)
+	BodyRules() BodyRules
+	SetSampling(sampling Sampling) error
+	Sampling() Sampling
+	SetColumns(columns []Column) error
+	Columns() []Column
+	SearchMetadata(ctx context.Context) (SearchMetadata, error)
+	SetClientRoutes(routes []ClientRoute) error
+	ClientRoutes() []ClientRoute
+	Flush(ctx context.Context) error
+	Close()
+	StoreStats() StoreStats
 }
 
 type service struct {
+	// mu guards the settings below, which are changed at runtime.
+	mu                       sync.RWMutex
 	bypassOutOfScopeRequests bool
-	findReqsFilter           FindRequestsFilter
-	activeProjectID          ulid.ULID
-	scope                    *scope.Scope
-	repo                     Repository
+	readOnly                 bool
+	capturePaused            bool
+	// Projects for which capture is paused, see `SetProjectCapturePaused`.
+	pausedProjects  map[ulid.ULID]bool
+	findReqsFilter  FindRequestsFilter
+	bodyRules       BodyRules
+	columns         []Column
+	activeProjectID ulid.ULID
+	clientRoutes    []clientRoute
+	scope           *scope.Scope
+	repo            Repository
+	ids             idgen.Generator
+	events          *event.Bus
+
+	// Redirects that haven't been followed yet, by target URL.
+	redirects   map[redirectKey]pendingRedirect
+	redirectsMu sync.Mutex
+
+	// Page loads that requests can be linked to, by URL of their requests.
+	pageLoads   map[redirectKey]pendingPageLoad
+	pageLoadsMu sync.Mutex
+
+	// Serializes updates of stored request logs.
+	updateMu sync.Mutex
+
+	// Counts of proxied requests, for sampling which ones are logged.
+	sampler sampler
+
+	// Response logs that are queued or being stored.
+	storeQueue   chan storeJob
+	storeWorkers int
+	storeStats   storeStats
+	storeTimeout time.Duration
+	pending      *pendingWrites
+	pendingMu    sync.Mutex
+
+	// Context of background writes, cancelled by `Close`.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 type FindRequestsFilter struct {
 	ProjectID   ulid.ULID
 	OnlyInScope bool
 	SearchExpr  search.Expression
+	// Built-in filters, combined with SearchExpr.
+	Presets FilterPresets
+	// Only return the first request of a redirect chain.
+	CollapseRedirects bool
+	// Only return the first request of each page load.
+	CollapsePageLoads bool
+	// Only return requests with this correlation ID, when set.
+	CorrelationID ulid.ULID
+	// Only return requests of this page load, when set.
+	PageLoadID ulid.ULID
+	// Only return requests to this hostname, when set. Repositories maintain
+	// indexes for these fields, see `FindRequestsFilter.MatchFields`.
+	Host string
+	// Only return requests with a response of this status code, when set.
+	StatusCode int
+	// Only return requests with a response of this media type (e.g.
+	// `application/json`), when set.
+	ContentType string
+	// Only return requests logged at or after Since and before Until, when set.
+	// Request log IDs are ordered by time, so repositories only read request
+	// logs in this range, see `FindRequestsFilter.TimeRange`.
+	Since time.Time
+	Until time.Time
 }
 
 type Config struct {
 	Scope      *scope.Scope
 	Repository Repository
+	// Number of workers that store response logs, and the size of their queue.
+	// Defaults to 8 and 1024.
+	StoreWorkers   int
+	StoreQueueSize int
+	// Maximum duration of storing a response log or request log update in the
+	// background. Defaults to 30 seconds.
+	StoreTimeout time.Duration
+	// Bus for publishing stored request and response logs. Optional.
+	Events *event.Bus
+	// Generates the IDs of request logs. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
 }
 
 func NewService(cfg Config) Service {
-	return &service{
-		repo:  cfg.Repository,
-		scope: cfg.Scope,
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	if cfg.StoreWorkers <= 0 {
+		cfg.StoreWorkers = defaultStoreWorkers
+	}
+
+	if cfg.StoreQueueSize <= 0 {
+		cfg.StoreQueueSize = defaultStoreQueueSize
+	}
+
+	if cfg.StoreTimeout <= 0 {
+		cfg.StoreTimeout = defaultStoreTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	svc := &service{
+		ids:            cfg.IDGenerator,
+		repo:           cfg.Repository,
+		scope:          cfg.Scope,
+		events:         cfg.Events,
+		redirects:      make(map[redirectKey]pendingRedirect),
+		pageLoads:      make(map[redirectKey]pendingPageLoad),
+		pausedProjects: make(map[ulid.ULID]bool),
+		storeQueue:     make(chan storeJob, cfg.StoreQueueSize),
+		storeWorkers:   cfg.StoreWorkers,
+		storeTimeout:   cfg.StoreTimeout,
+		pending:        &pendingWrites{},
+		ctx:            ctx,
+		cancel:         cancel,
 	}
+
+	svc.startStoreWorkers(cfg.StoreWorkers)
+
+	return svc
 }
 
 func (svc *service) FindRequests(ctx context.Context) ([]RequestLog, error) {
-	return svc.repo.FindRequestLogs(ctx, svc.findReqsFilter, svc.scope)
+	return svc.repo.FindRequestLogs(ctx, svc.FindReqsFilter(), svc.scope)
 }
 
 func (svc *service) FindRequestLogByID(ctx context.Context, id ulid.ULID) (RequestLog, error) {
 	return svc.repo.FindRequestLogByID(ctx, id)
 }
 
+func (svc *service) FindCorrelatedRequests(ctx context.Context, correlationID ulid.ULID) ([]RequestLog, error) {
+	filter := FindRequestsFilter{
+		ProjectID:     svc.ActiveProjectID(),
+		CorrelationID: correlationID,
+	}
+
+	return svc.repo.FindRequestLogs(ctx, filter, svc.scope)
+}
+
 func (svc *service) ClearRequests(ctx context.Context, projectID ulid.ULID) error {
+	if svc.ReadOnly() {
+		return ErrReadOnly
+	}
+
 	return svc.repo.ClearRequestLogs(ctx, projectID)
 }
 
-func (svc *service) storeResponse(ctx context.Context, reqLogID ulid.ULID, res *http.Response) error {
+func (svc *service) storeResponse(ctx context.Context, projectID, reqLogID ulid.ULID, res *http.Response) error {
 	resLog, err := ParseHTTPResponse(res)
 	if err != nil {
 		return err
 	}
 
-	return svc.repo.StoreResponseLog(ctx, reqLogID, resLog)
+	if svc.BodyRules().omitBody(res, len(resLog.Body)) {
+		resLog.Body = nil
+		resLog.BodyOmitted = true
+	}
+
+	if ip := proxy.RemoteIP(res.Request.Context()); ip != nil {
+		resLog.RemoteIP = ip.String()
+	}
+
+	ctx, span := tracing.Start(ctx, "reqlog.store_response_log")
+	err = svc.repo.StoreResponseLog(ctx, reqLogID, resLog)
+	span.RecordError(err)
+	span.End()
+
+	if err != nil {
+		return err
+	}
+
+	svc.events.Publish(event.Event{
+		Type:      event.TypeResponseLogStored,
+		ProjectID: projectID,
+		ID:        reqLogID,
+		Data:      resLog,
+	})
+
+	return nil
 }
 
 func (svc *service) RequestModifier(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
@@ -137,17 +381,45 @@ func (svc *service) RequestModifier(next proxy.RequestModifyFunc) proxy.RequestM
 			clone.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 		}
 
-		// Bypass logging if no project is active.
-		if svc.activeProjectID.Compare(ulid.ULID{}) == 0 {
+		projectID, reqScope := svc.ActiveProjectID(), svc.scope
+
+		if route, ok := svc.matchClientRoute(req); ok {
+			projectID = route.ProjectID
+
+			if route.Scope != nil {
+				reqScope = route.Scope
+			}
+		}
+
+		// Bypass logging if no project is active, if the active project is
+		// opened read-only, or if capture is paused. Routed clients are still
+		// logged to their project, unless capture is paused globally.
+		if projectID.Compare(ulid.ULID{}) == 0 || svc.bypassProject(projectID) {
 			ctx := context.WithValue(req.Context(), LogBypassedKey, true)
 			*req = *req.WithContext(ctx)
 
 			return
 		}
 
+		bypassOutOfScope := svc.BypassOutOfScopeRequests()
+		omitOutOfScope := svc.BodyRules().OmitOutOfScope
+		inScope := true
+
+		if bypassOutOfScope || omitOutOfScope {
+			inScope = reqScope.Match(clone, body)
+		}
+
 		// Bypass logging if this setting is enabled and the incoming request
 		// doesn't match any scope rules.
-		if svc.bypassOutOfScopeRequests && !svc.scope.Match(clone, body) {
+		if bypassOutOfScope && !inScope {
+			ctx := context.WithValue(req.Context(), LogBypassedKey, true)
+			*req = *req.WithContext(ctx)
+
+			return
+		}
+
+		// Bypass logging of requests that aren't sampled, see `Sampling`.
+		if !svc.sampler.sample(clone) {
 			ctx := context.WithValue(req.Context(), LogBypassedKey, true)
 			*req = *req.WithContext(ctx)
 
@@ -155,22 +427,64 @@ func (svc *service) RequestModifier(next proxy.RequestModifyFunc) proxy.RequestM
 		}
 
 		reqLog := RequestLog{
-			ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
-			ProjectID: svc.activeProjectID,
-			Method:    clone.Method,
-			URL:       clone.URL,
-			Proto:     clone.Proto,
-			Header:    clone.Header,
-			Body:      body,
+			ID:         svc.ids.New(time.Now()),
+			ProjectID:  projectID,
+			Method:     clone.Method,
+			URL:        clone.URL,
+			Proto:      clone.Proto,
+			Header:     clone.Header,
+			Body:       body,
+			ClientAddr: clone.RemoteAddr,
+			Device:     ParseDevice(clone.Header.Get("User-Agent")),
+		}
+
+		reqLog.CorrelationID, _ = req.Context().Value(proxy.CorrelationIDKey).(ulid.ULID)
+		reqLog.OriginalURL, _ = req.Context().Value(proxy.OriginalURLKey).(*url.URL)
+
+		redirect, ok := svc.popRedirect(reqLog.ProjectID, clone.URL)
+		if ok {
+			reqLog.RedirectFromID = redirect.reqLogID
+
+			// Requests that follow a redirect inherit the correlation ID of
+			// the chain.
+			if reqLog.CorrelationID.Compare(ulid.ULID{}) == 0 {
+				reqLog.CorrelationID = redirect.correlationID
+			}
 		}
 
-		err := svc.repo.StoreRequestLog(req.Context(), reqLog)
+		reqLog.PageLoadID = svc.pageLoadID(clone, reqLog, redirect)
+		svc.pushPageLoad(reqLog)
+
+		storeCtx, span := tracing.Start(req.Context(), "reqlog.store_request_log")
+		err := svc.repo.StoreRequestLog(storeCtx, reqLog)
+		span.RecordError(err)
+		span.End()
+
 		if err != nil {
 			log.Printf("[ERROR] Could not store request log: %v", err)
 			return
 		}
 
+		svc.events.Publish(event.Event{
+			Type:      event.TypeRequestLogStored,
+			ProjectID: reqLog.ProjectID,
+			ID:        reqLog.ID,
+			Data:      reqLog,
+		})
+
 		ctx := context.WithValue(req.Context(), proxy.ReqLogIDKey, reqLog.ID)
+		ctx = context.WithValue(ctx, ProjectIDKey, reqLog.ProjectID)
+		if reqLog.PageLoadID.Compare(ulid.ULID{}) != 0 {
+			ctx = context.WithValue(ctx, pageLoadIDKey, reqLog.PageLoadID)
+		}
+		if omitOutOfScope {
+			ctx = context.WithValue(ctx, inScopeKey, inScope)
+		}
+
+		if reqLog.CorrelationID.Compare(ulid.ULID{}) != 0 {
+			ctx = context.WithValue(ctx, proxy.CorrelationIDKey, reqLog.CorrelationID)
+		}
+
 		*req = *req.WithContext(ctx)
 	}
 }
@@ -201,40 +515,205 @@ func (svc *service) ResponseModifier(next proxy.ResponseModifyFunc) proxy.Respon
 		res.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 		clone.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 
-		go func() {
-			if err := svc.storeResponse(context.Background(), reqLogID, &clone); err != nil {
-				log.Printf("[ERROR] Could not store response log: %v", err)
-			}
-		}()
+		projectID, _ := res.Request.Context().Value(ProjectIDKey).(ulid.ULID)
+
+		svc.pushRedirect(projectID, reqLogID, res)
+
+		svc.enqueueResponse(projectID, reqLogID, &clone)
+
+		return nil
+	}
+}
+
+// RequestErrorHandler stores the error of a logged request that failed
+// upstream on its request log. It's meant to be registered with
+// `proxy.OnRequestError`. The request log is updated in the background.
+func (svc *service) RequestErrorHandler(req *http.Request, reqErr error) {
+	svc.updateRequestLog(req, "request error", func(reqLog *RequestLog) {
+		reqLog.Error = reqErr.Error()
+
+		if ip := proxy.RemoteIP(req.Context()); ip != nil {
+			reqLog.RemoteIP = ip.String()
+		}
+	})
+}
+
+// RawCaptureHandler stores the raw bytes of a logged request on its request
+// log. It's meant to be registered with `proxy.OnRawCapture`. The request log
+// is updated in the background.
+func (svc *service) RawCaptureHandler(req *http.Request, raw proxy.RawExchange) {
+	svc.updateRequestLog(req, "raw capture", func(reqLog *RequestLog) {
+		reqLog.Raw = &raw
+	})
+}
+
+// RetryHandler stores the retry count of a logged request on its request log.
+// It's meant to be registered with `proxy.OnRetry`. The request log is updated
+// in the background.
+func (svc *service) RetryHandler(req *http.Request, retries int) {
+	svc.updateRequestLog(req, "retry count", func(reqLog *RequestLog) {
+		reqLog.Retries = retries
+	})
+}
+
+// updateRequestLog updates the stored request log of req with fn, in the
+// background. Updates are serialized, so that concurrent updates of a request
+// log aren't lost.
+func (svc *service) updateRequestLog(req *http.Request, desc string, fn func(reqLog *RequestLog)) {
+	if bypassed, _ := req.Context().Value(LogBypassedKey).(bool); bypassed {
+		return
+	}
+
+	reqLogID, ok := req.Context().Value(proxy.ReqLogIDKey).(ulid.ULID)
+	if !ok {
+		return
+	}
+
+	pending := svc.addPending()
+
+	go func() {
+		defer pending.wg.Done()
+
+		svc.updateMu.Lock()
+		defer svc.updateMu.Unlock()
+
+		ctx, cancel := svc.writeContext()
+		defer cancel()
+
+		reqLog, err := svc.repo.FindRequestLogByID(ctx, reqLogID)
+		if err != nil {
+			log.Printf("[ERROR] Could not store %v: failed to find request log: %v", desc, err)
+			return
+		}
+
+		fn(&reqLog)
+		reqLog.Response = nil
 
+		if err := svc.repo.StoreRequestLog(ctx, reqLog); err != nil {
+			log.Printf("[ERROR] Could not store %v: %v", desc, err)
+		}
+	}()
+}
+
+// Flush waits until response logs and request log updates that are pending
+// when it's called are stored, or ctx is done. Writes of traffic that arrives
+// after Flush is called aren't waited for.
+func (svc *service) Flush(ctx context.Context) error {
+	svc.pendingMu.Lock()
+	barrier := svc.pending
+	next := &pendingWrites{prev: barrier}
+	svc.pending = next
+	svc.pendingMu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		for p := barrier; p != nil; p = svc.prevPending(p) {
+			p.wg.Wait()
+		}
+
+		// All older generations are done, so they don't have to be waited for
+		// by later flushes.
+		svc.pendingMu.Lock()
+		next.prev = nil
+		svc.pendingMu.Unlock()
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
 		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("reqlog: failed to flush response logs: %w", ctx.Err())
 	}
 }
 
+func (svc *service) prevPending(p *pendingWrites) *pendingWrites {
+	svc.pendingMu.Lock()
+	defer svc.pendingMu.Unlock()
+
+	return p.prev
+}
+
+// Close cancels background writes that are in progress, e.g. after Flush
+// timed out on shutdown. Writes that are still queued fail right away.
+func (svc *service) Close() {
+	svc.cancel()
+}
+
 func (svc *service) SetActiveProjectID(id ulid.ULID) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
 	svc.activeProjectID = id
 }
 
 func (svc *service) ActiveProjectID() ulid.ULID {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
 	return svc.activeProjectID
 }
 
 func (svc *service) SetFindReqsFilter(filter FindRequestsFilter) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
 	svc.findReqsFilter = filter
 }
 
 func (svc *service) FindReqsFilter() FindRequestsFilter {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
 	return svc.findReqsFilter
 }
 
+func (svc *service) SetBodyRules(rules BodyRules) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.bodyRules = rules
+}
+
+func (svc *service) BodyRules() BodyRules {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.bodyRules
+}
+
 func (svc *service) SetBypassOutOfScopeRequests(bypass bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
 	svc.bypassOutOfScopeRequests = bypass
 }
 
 func (svc *service) BypassOutOfScopeRequests() bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
 	return svc.bypassOutOfScopeRequests
 }
 
+// SetReadOnly sets whether the active project is opened read-only. Requests
+// aren't logged for it, and mutating methods return `ErrReadOnly`.
+func (svc *service) SetReadOnly(readOnly bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.readOnly = readOnly
+}
+
+func (svc *service) ReadOnly() bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.readOnly
+}
+
 func ParseHTTPResponse(res *http.Response) (ResponseLog, error) {
 	if res.Header.Get("Content-Encoding") == "gzip" {
 		gzipReader, err := gzip.NewReader(res.Body)
@@ -257,11 +736,78 @@ func ParseHTTPResponse(res *http.Response) (ResponseLog, error) {
 		return ResponseLog{}, fmt.Errorf("reqlog: could not read body: %w", err)
 	}
 
-	return ResponseLog{
+	resLog := ResponseLog{
 		Proto:      res.Proto,
 		StatusCode: res.StatusCode,
 		Status:     res.Status,
 		Header:     res.Header,
 		Body:       body,
-	}, nil
+	}
+
+	if res.TLS != nil {
+		tlsInfo := ParseTLSConnectionState(*res.TLS)
+		resLog.TLS = &tlsInfo
+	}
+
+	return resLog, nil
+}
+
+// SetCapturePaused sets whether capture is paused globally. Proxied requests
+// are passed through, but nothing is logged for any project.
+func (svc *service) SetCapturePaused(paused bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.capturePaused = paused
+}
+
+func (svc *service) CapturePaused() bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.capturePaused
+}
+
+// SetProjectCapturePaused sets whether capture is paused for a project, i.e.
+// the active project or a project that clients are routed to. Requests for
+// other projects are still logged.
+func (svc *service) SetProjectCapturePaused(projectID ulid.ULID, paused bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if paused {
+		svc.pausedProjects[projectID] = true
+	} else {
+		delete(svc.pausedProjects, projectID)
+	}
+}
+
+func (svc *service) ProjectCapturePaused(projectID ulid.ULID) bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.pausedProjects[projectID]
+}
+
+// IsCapturePaused returns whether requests for the active project aren't
+// logged, because capture is paused globally or for the project.
+func (svc *service) IsCapturePaused() bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.capturePaused || svc.pausedProjects[svc.activeProjectID]
+}
+
+// bypassProject returns whether requests for a project aren't logged, because
+// capture is paused globally or for the project, or because it's the active
+// project and opened read-only.
+func (svc *service) bypassProject(projectID ulid.ULID) bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	if svc.capturePaused || svc.pausedProjects[projectID] {
+		return true
+	}
+
+	return svc.readOnly && projectID.Compare(svc.activeProjectID) == 0
 }
@@ -4,10 +4,12 @@ package reqlog_test
 
 import (
 	"context"
+	"errors"
 	"io"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -41,6 +43,7 @@ func TestRequestModifier(t *testing.T) {
 	}
 	reqModFn := svc.RequestModifier(next)
 	req := httptest.NewRequest("GET", "https://example.com/", strings.NewReader("bar"))
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 15_0 like Mac OS X) Mobile/15E148")
 
 	reqModFn(req)
 
@@ -51,13 +54,15 @@ func TestRequestModifier(t *testing.T) {
 		}
 
 		exp := reqlog.RequestLog{
-			ID:        ulid.ULID{}, // Empty value
-			ProjectID: svc.ActiveProjectID(),
-			Method:    req.Method,
-			URL:       req.URL,
-			Proto:     req.Proto,
-			Header:    req.Header,
-			Body:      []byte("modified body"),
+			ID:         ulid.ULID{}, // Empty value
+			ProjectID:  svc.ActiveProjectID(),
+			Method:     req.Method,
+			URL:        req.URL,
+			Proto:      req.Proto,
+			Header:     req.Header,
+			Body:       []byte("modified body"),
+			ClientAddr: req.RemoteAddr,
+			Device:     reqlog.Device{Type: reqlog.DeviceTypeMobile, OS: "iOS"},
 		}
 		got := repoMock.StoreRequestLogCalls()[0].ReqLog
 		got.ID = ulid.ULID{} // Override to empty value so we can compare against expected value.
@@ -68,6 +73,39 @@ func TestRequestModifier(t *testing.T) {
 	})
 }
 
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	repoMock := &RepoMock{}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository: repoMock,
+		Scope:      &scope.Scope{},
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+	svc.SetReadOnly(true)
+
+	req := httptest.NewRequest("GET", "https://example.com/", nil)
+	svc.RequestModifier(func(_ *http.Request) {})(req)
+
+	if bypassed, _ := req.Context().Value(reqlog.LogBypassedKey).(bool); !bypassed {
+		t.Fatal("expected logging to be bypassed")
+	}
+
+	sel := reqlog.Selection{IDs: []ulid.ULID{ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)}}
+
+	if _, err := svc.TagRequests(context.Background(), sel, []string{"foo"}, nil); !errors.Is(err, reqlog.ErrReadOnly) {
+		t.Fatalf("expected `reqlog.ErrReadOnly`, got: %v", err)
+	}
+
+	if _, err := svc.DeleteRequests(context.Background(), sel); !errors.Is(err, reqlog.ErrReadOnly) {
+		t.Fatalf("expected `reqlog.ErrReadOnly`, got: %v", err)
+	}
+
+	if err := svc.ClearRequests(context.Background(), svc.ActiveProjectID()); !errors.Is(err, reqlog.ErrReadOnly) {
+		t.Fatalf("expected `reqlog.ErrReadOnly`, got: %v", err)
+	}
+}
+
 //nolint:paralleltest
 func TestResponseModifier(t *testing.T) {
 	repoMock := &RepoMock{
@@ -100,8 +138,10 @@ func TestResponseModifier(t *testing.T) {
 	}
 
 	t.Run("request log was stored in repository", func(t *testing.T) {
-		// Dirty (but simple) wait for other goroutine to finish calling repository.
-		time.Sleep(10 * time.Millisecond)
+		if err := svc.Flush(context.Background()); err != nil {
+			t.Fatalf("unexpected error flushing response logs: %v", err)
+		}
+
 		got := len(repoMock.StoreResponseLogCalls())
 		if exp := 1; exp != got {
 			t.Fatalf("incorrect `proj.Service.AddResponseLog` calls (expected: %v, got: %v)", exp, got)
@@ -122,3 +162,703 @@ func TestResponseModifier(t *testing.T) {
 		})
 	})
 }
+
+func TestServerIP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		reqLog reqlog.RequestLog
+		exp    string
+	}{
+		{
+			name:   "IP address of response",
+			reqLog: reqlog.RequestLog{Response: &reqlog.ResponseLog{RemoteIP: "192.0.2.1"}},
+			exp:    "192.0.2.1",
+		},
+		{
+			name:   "IP address of failed request",
+			reqLog: reqlog.RequestLog{RemoteIP: "192.0.2.2"},
+			exp:    "192.0.2.2",
+		},
+		{
+			name:   "IP address stored on request log with response",
+			reqLog: reqlog.RequestLog{RemoteIP: "192.0.2.2", Response: &reqlog.ResponseLog{}},
+			exp:    "192.0.2.2",
+		},
+		{
+			name:   "no connection",
+			reqLog: reqlog.RequestLog{},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.reqLog.ServerIP(); got != tt.exp {
+			t.Errorf("%v: expected %q, got: %q", tt.name, tt.exp, got)
+		}
+	}
+}
+
+//nolint:paralleltest
+func TestRedirectLinking(t *testing.T) {
+	repoMock := &RepoMock{
+		StoreRequestLogFunc: func(_ context.Context, _ reqlog.RequestLog) error {
+			return nil
+		},
+		StoreResponseLogFunc: func(_ context.Context, _ ulid.ULID, _ reqlog.ResponseLog) error {
+			return nil
+		},
+	}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository: repoMock,
+		Scope:      &scope.Scope{},
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	reqModFn := svc.RequestModifier(func(_ *http.Request) {})
+	resModFn := svc.ResponseModifier(func(_ *http.Response) error { return nil })
+
+	correlationID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	req := httptest.NewRequest("POST", "https://example.com/login", strings.NewReader("foo=bar"))
+	req = req.WithContext(context.WithValue(req.Context(), proxy.CorrelationIDKey, correlationID))
+	reqModFn(req)
+
+	res := &http.Response{
+		StatusCode: http.StatusFound,
+		Header:     http.Header{"Location": []string{"/dashboard"}},
+		Request:    req,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}
+
+	if err := resModFn(res); err != nil {
+		t.Fatalf("unexpected error (expected: nil, got: %v)", err)
+	}
+
+	reqModFn(httptest.NewRequest("GET", "https://example.com/dashboard", nil))
+	reqModFn(httptest.NewRequest("GET", "https://example.com/dashboard", nil))
+
+	calls := repoMock.StoreRequestLogCalls()
+	if exp, got := 3, len(calls); exp != got {
+		t.Fatalf("incorrect `Repository.StoreRequestLog` calls (expected: %v, got: %v)", exp, got)
+	}
+
+	t.Run("request that follows redirect is linked", func(t *testing.T) {
+		if exp, got := calls[0].ReqLog.ID, calls[1].ReqLog.RedirectFromID; exp.Compare(got) != 0 {
+			t.Fatalf("incorrect `RequestLog.RedirectFromID` value (expected: %v, got: %v)", exp, got)
+		}
+	})
+
+	t.Run("request that follows redirect inherits correlation ID", func(t *testing.T) {
+		if got := calls[1].ReqLog.CorrelationID; correlationID.Compare(got) != 0 {
+			t.Fatalf("incorrect `RequestLog.CorrelationID` value (expected: %v, got: %v)", correlationID, got)
+		}
+	})
+
+	t.Run("redirect is only linked once", func(t *testing.T) {
+		if got := calls[2].ReqLog.RedirectFromID; got.Compare(ulid.ULID{}) != 0 {
+			t.Fatalf("expected empty `RequestLog.RedirectFromID` value, got: %v", got)
+		}
+	})
+}
+
+func TestFindRedirectChain(t *testing.T) {
+	t.Parallel()
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	first := reqlog.RequestLog{ID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy), ProjectID: projectID}
+	second := reqlog.RequestLog{ID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy), ProjectID: projectID, RedirectFromID: first.ID}
+	third := reqlog.RequestLog{ID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy), ProjectID: projectID, RedirectFromID: second.ID}
+	reqLogs := map[ulid.ULID]reqlog.RequestLog{first.ID: first, second.ID: second, third.ID: third}
+
+	repoMock := &RepoMock{
+		FindRequestLogByIDFunc: func(_ context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
+			reqLog, ok := reqLogs[id]
+			if !ok {
+				return reqlog.RequestLog{}, reqlog.ErrRequestNotFound
+			}
+
+			return reqLog, nil
+		},
+		FindRedirectedRequestLogIDsFunc: func(_ context.Context, _, redirectFromID ulid.ULID) ([]ulid.ULID, error) {
+			for _, reqLog := range reqLogs {
+				if reqLog.RedirectFromID.Compare(redirectFromID) == 0 {
+					return []ulid.ULID{reqLog.ID}, nil
+				}
+			}
+
+			return nil, nil
+		},
+	}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository: repoMock,
+		Scope:      &scope.Scope{},
+	})
+
+	chain, err := svc.FindRedirectChain(context.Background(), second.ID)
+	if err != nil {
+		t.Fatalf("unexpected error (expected: nil, got: %v)", err)
+	}
+
+	if len(chain) != 3 || chain[0].ID != first.ID || chain[1].ID != second.ID || chain[2].ID != third.ID {
+		t.Fatalf("incorrect redirect chain: %+v", chain)
+	}
+
+	if calls := repoMock.FindRequestLogsCalls(); len(calls) != 0 {
+		t.Fatalf("expected no `Repository.FindRequestLogs` calls, got: %v", len(calls))
+	}
+}
+
+//nolint:paralleltest
+func TestPageLoadGrouping(t *testing.T) {
+	repoMock := &RepoMock{
+		StoreRequestLogFunc: func(_ context.Context, _ reqlog.RequestLog) error {
+			return nil
+		},
+	}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository: repoMock,
+		Scope:      &scope.Scope{},
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	reqModFn := svc.RequestModifier(func(_ *http.Request) {})
+
+	newRequest := func(target, dest, referer string) *http.Request {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Sec-Fetch-Dest", dest)
+
+		if referer != "" {
+			req.Header.Set("Referer", referer)
+		}
+
+		return req
+	}
+
+	reqModFn(newRequest("https://example.com/", "document", ""))
+	reqModFn(newRequest("https://example.com/style.css", "style", "https://example.com/"))
+	reqModFn(newRequest("https://fonts.example.com/font.woff2", "font", "https://example.com/style.css"))
+	reqModFn(newRequest("https://example.com/api", "empty", "https://other.example.com/"))
+	reqModFn(newRequest("https://example.com/next", "document", "https://example.com/"))
+
+	calls := repoMock.StoreRequestLogCalls()
+	if exp, got := 5, len(calls); exp != got {
+		t.Fatalf("incorrect `Repository.StoreRequestLog` calls (expected: %v, got: %v)", exp, got)
+	}
+
+	navID := calls[0].ReqLog.ID
+
+	t.Run("navigation starts page load", func(t *testing.T) {
+		if got := calls[0].ReqLog.PageLoadID; navID.Compare(got) != 0 {
+			t.Fatalf("incorrect `RequestLog.PageLoadID` value (expected: %v, got: %v)", navID, got)
+		}
+	})
+
+	t.Run("requests triggered by page load are linked", func(t *testing.T) {
+		for _, call := range calls[1:3] {
+			if got := call.ReqLog.PageLoadID; navID.Compare(got) != 0 {
+				t.Fatalf("incorrect `RequestLog.PageLoadID` value (expected: %v, got: %v)", navID, got)
+			}
+		}
+	})
+
+	t.Run("request with unknown referer isn't linked", func(t *testing.T) {
+		if got := calls[3].ReqLog.PageLoadID; got.Compare(ulid.ULID{}) != 0 {
+			t.Fatalf("expected empty `RequestLog.PageLoadID` value, got: %v", got)
+		}
+	})
+
+	t.Run("next navigation starts new page load", func(t *testing.T) {
+		if exp, got := calls[4].ReqLog.ID, calls[4].ReqLog.PageLoadID; exp.Compare(got) != 0 {
+			t.Fatalf("incorrect `RequestLog.PageLoadID` value (expected: %v, got: %v)", exp, got)
+		}
+	})
+}
+
+//nolint:paralleltest
+func TestResponseModifierBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	repoMock := &RepoMock{
+		StoreResponseLogFunc: func(_ context.Context, _ ulid.ULID, _ reqlog.ResponseLog) error {
+			<-release
+			return nil
+		},
+	}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository:     repoMock,
+		StoreWorkers:   1,
+		StoreQueueSize: 1,
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	resModFn := svc.ResponseModifier(func(*http.Response) error { return nil })
+
+	modifyResponse := func() {
+		req := httptest.NewRequest("GET", "https://example.com/", nil)
+		reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+		req = req.WithContext(context.WithValue(req.Context(), proxy.ReqLogIDKey, reqLogID))
+
+		res := &http.Response{
+			Request: req,
+			Body:    io.NopCloser(strings.NewReader("foo")),
+		}
+
+		if err := resModFn(res); err != nil {
+			t.Errorf("unexpected error (expected: nil, got: %v)", err)
+		}
+	}
+
+	// The first response is taken by the worker, the second is queued and the
+	// third has to wait for room in the queue.
+	modifyResponse()
+
+	deadline := time.Now().Add(time.Second)
+	for svc.StoreStats().Queued != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected worker to take response from queue")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	modifyResponse()
+
+	go modifyResponse()
+
+	deadline = time.Now().Add(time.Second)
+	for svc.StoreStats().Blocked != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a blocked response, got stats: %+v", svc.StoreStats())
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+
+	if err := svc.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error flushing response logs: %v", err)
+	}
+
+	exp := reqlog.StoreStats{QueueSize: 1, Workers: 1, Stored: 3, Blocked: 1}
+	if diff := cmp.Diff(exp, svc.StoreStats()); diff != "" {
+		t.Fatalf("store stats not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+//nolint:paralleltest
+func TestResponseModifierBodyRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       reqlog.BodyRules
+		url         string
+		contentType string
+		body        string
+		expOmitted  bool
+	}{
+		{
+			name:        "without rules",
+			url:         "https://example.com/logo.png",
+			contentType: "image/png",
+			body:        "foobar",
+		},
+		{
+			name:        "static content",
+			rules:       reqlog.BodyRules{OmitStatic: true},
+			url:         "https://example.com/logo.png",
+			contentType: "image/png",
+			body:        "foobar",
+			expOmitted:  true,
+		},
+		{
+			name:        "content type pattern",
+			rules:       reqlog.BodyRules{OmitContentTypes: []string{"application/*"}},
+			url:         "https://example.com/app.js",
+			contentType: "application/javascript; charset=utf-8",
+			body:        "foobar",
+			expOmitted:  true,
+		},
+		{
+			name:        "larger than max size",
+			rules:       reqlog.BodyRules{MaxSize: 5},
+			url:         "https://example.com/",
+			contentType: "text/html",
+			body:        "foobar",
+			expOmitted:  true,
+		},
+		{
+			name:        "in scope",
+			rules:       reqlog.BodyRules{OmitOutOfScope: true},
+			url:         "https://example.com/api",
+			contentType: "application/json",
+			body:        "{}",
+		},
+		{
+			name:        "out of scope",
+			rules:       reqlog.BodyRules{OmitOutOfScope: true},
+			url:         "https://other.example/api",
+			contentType: "application/json",
+			body:        "{}",
+			expOmitted:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoMock := &RepoMock{
+				StoreRequestLogFunc: func(_ context.Context, _ reqlog.RequestLog) error {
+					return nil
+				},
+				StoreResponseLogFunc: func(_ context.Context, _ ulid.ULID, _ reqlog.ResponseLog) error {
+					return nil
+				},
+			}
+			reqScope := &scope.Scope{}
+			reqScope.SetRules([]scope.Rule{{URL: regexp.MustCompile(`^https://example\.com/`)}})
+
+			svc := reqlog.NewService(reqlog.Config{
+				Repository: repoMock,
+				Scope:      reqScope,
+			})
+			svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+			svc.SetBodyRules(tt.rules)
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			svc.RequestModifier(func(*http.Request) {})(req)
+
+			res := &http.Response{
+				Request: req,
+				Header:  http.Header{"Content-Type": []string{tt.contentType}},
+				Body:    io.NopCloser(strings.NewReader(tt.body)),
+			}
+
+			if err := svc.ResponseModifier(func(*http.Response) error { return nil })(res); err != nil {
+				t.Fatalf("unexpected error (expected: nil, got: %v)", err)
+			}
+
+			if err := svc.Flush(context.Background()); err != nil {
+				t.Fatalf("unexpected error flushing response logs: %v", err)
+			}
+
+			resLog := repoMock.StoreResponseLogCalls()[0].ResLog
+
+			if resLog.BodyOmitted != tt.expOmitted {
+				t.Fatalf("expected `ResponseLog.BodyOmitted` to be %v", tt.expOmitted)
+			}
+
+			expBody := tt.body
+			if tt.expOmitted {
+				expBody = ""
+			}
+
+			if got := string(resLog.Body); got != expBody {
+				t.Fatalf("incorrect `ResponseLog.Body` value (expected: %q, got: %q)", expBody, got)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest
+func TestRequestErrorHandler(t *testing.T) {
+	reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	reqLog := reqlog.RequestLog{
+		ID:     reqLogID,
+		Method: http.MethodGet,
+	}
+
+	repoMock := &RepoMock{
+		FindRequestLogByIDFunc: func(_ context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
+			return reqLog, nil
+		},
+		StoreRequestLogFunc: func(_ context.Context, _ reqlog.RequestLog) error {
+			return nil
+		},
+	}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository: repoMock,
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	req := httptest.NewRequest("GET", "https://example.com/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), proxy.ReqLogIDKey, reqLogID))
+
+	svc.RequestErrorHandler(req, errors.New("dial tcp: lookup example.com: no such host"))
+
+	bypassedReq := req.WithContext(context.WithValue(req.Context(), reqlog.LogBypassedKey, true))
+	svc.RequestErrorHandler(bypassedReq, errors.New("foobar"))
+
+	if err := svc.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+
+	if got := repoMock.FindRequestLogByIDCalls(); len(got) != 1 || got[0].ID.Compare(reqLogID) != 0 {
+		t.Fatalf("expected request log to be looked up once by ID (got: %+v)", got)
+	}
+
+	calls := repoMock.StoreRequestLogCalls()
+	if len(calls) != 1 {
+		t.Fatalf("incorrect `Repository.StoreRequestLog` calls (expected: 1, got: %v)", len(calls))
+	}
+
+	exp := reqLog
+	exp.Error = "dial tcp: lookup example.com: no such host"
+
+	if diff := cmp.Diff(exp, calls[0].ReqLog); diff != "" {
+		t.Fatalf("request log not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestFlushAndClose(t *testing.T) {
+	t.Parallel()
+
+	reqLogID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	// newBlockedService returns a service of which request log updates block
+	// until their context is done.
+	newBlockedService := func(storeTimeout time.Duration) (reqlog.Service, *http.Request, chan error) {
+		errs := make(chan error, 1)
+		repoMock := &RepoMock{
+			FindRequestLogByIDFunc: func(ctx context.Context, _ ulid.ULID) (reqlog.RequestLog, error) {
+				<-ctx.Done()
+				errs <- ctx.Err()
+
+				return reqlog.RequestLog{}, ctx.Err()
+			},
+		}
+		svc := reqlog.NewService(reqlog.Config{
+			Repository:   repoMock,
+			StoreTimeout: storeTimeout,
+		})
+
+		req := httptest.NewRequest("GET", "https://example.com/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), proxy.ReqLogIDKey, reqLogID))
+
+		return svc, req, errs
+	}
+
+	t.Run("flush times out on pending writes, close cancels them", func(t *testing.T) {
+		t.Parallel()
+
+		svc, req, errs := newBlockedService(0)
+		svc.RetryHandler(req, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if err := svc.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected `context.DeadlineExceeded`, got: %v", err)
+		}
+
+		svc.Close()
+
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected `context.Canceled`, got: %v", err)
+		}
+
+		if err := svc.Flush(context.Background()); err != nil {
+			t.Fatalf("unexpected error flushing: %v", err)
+		}
+	})
+
+	t.Run("writes time out", func(t *testing.T) {
+		t.Parallel()
+
+		svc, req, errs := newBlockedService(10 * time.Millisecond)
+		svc.RetryHandler(req, 1)
+
+		if err := svc.Flush(context.Background()); err != nil {
+			t.Fatalf("unexpected error flushing: %v", err)
+		}
+
+		if err := <-errs; !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected `context.DeadlineExceeded`, got: %v", err)
+		}
+	})
+}
+
+func TestClientRoutes(t *testing.T) {
+	t.Parallel()
+
+	activeProjectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	routedProjectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	repoMock := &RepoMock{
+		StoreRequestLogFunc: func(_ context.Context, _ reqlog.RequestLog) error {
+			return nil
+		},
+	}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository: repoMock,
+		Scope:      &scope.Scope{},
+	})
+	svc.SetActiveProjectID(activeProjectID)
+
+	err := svc.SetClientRoutes([]reqlog.ClientRoute{{ClientIP: "10.0.0.0/24", ProjectID: routedProjectID}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := svc.SetClientRoutes([]reqlog.ClientRoute{{ProjectID: routedProjectID}}); !errors.Is(err, reqlog.ErrInvalidClientRoute) {
+		t.Fatalf("expected `reqlog.ErrInvalidClientRoute`, got: %v", err)
+	}
+
+	reqModFn := svc.RequestModifier(func(req *http.Request) {})
+
+	for i, tt := range []struct {
+		remoteAddr string
+		exp        ulid.ULID
+	}{
+		{remoteAddr: "10.0.0.5:1234", exp: routedProjectID},
+		{remoteAddr: "192.0.2.1:1234", exp: activeProjectID},
+	} {
+		req := httptest.NewRequest("GET", "https://example.com/", nil)
+		req.RemoteAddr = tt.remoteAddr
+
+		reqModFn(req)
+
+		calls := repoMock.StoreRequestLogCalls()
+		if len(calls) != i+1 {
+			t.Fatalf("expected request log of %v to be stored", tt.remoteAddr)
+		}
+
+		if got := calls[i].ReqLog.ProjectID; got.Compare(tt.exp) != 0 {
+			t.Errorf("unexpected project ID for %v (expected: %v, got: %v)", tt.remoteAddr, tt.exp, got)
+		}
+	}
+}
+
+func TestCapturePaused(t *testing.T) {
+	t.Parallel()
+
+	activeProjectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	routedProjectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+
+	repoMock := &RepoMock{
+		StoreRequestLogFunc: func(_ context.Context, _ reqlog.RequestLog) error {
+			return nil
+		},
+	}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository: repoMock,
+		Scope:      &scope.Scope{},
+	})
+	svc.SetActiveProjectID(activeProjectID)
+
+	err := svc.SetClientRoutes([]reqlog.ClientRoute{{ClientIP: "10.0.0.0/24", ProjectID: routedProjectID}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reqModFn := svc.RequestModifier(func(req *http.Request) {})
+
+	bypassed := func(remoteAddr string) bool {
+		req := httptest.NewRequest("GET", "https://example.com/", nil)
+		req.RemoteAddr = remoteAddr
+
+		reqModFn(req)
+
+		bypassed, _ := req.Context().Value(reqlog.LogBypassedKey).(bool)
+
+		return bypassed
+	}
+
+	// Routed clients are still logged while capture is paused for the active
+	// project, but not while it's paused globally.
+	svc.SetProjectCapturePaused(activeProjectID, true)
+
+	if !svc.IsCapturePaused() || !bypassed("192.0.2.1:1234") || bypassed("10.0.0.5:1234") {
+		t.Fatal("expected only requests for the active project to be bypassed")
+	}
+
+	svc.SetProjectCapturePaused(activeProjectID, false)
+
+	// Capture can be paused for a routed project, without affecting the active
+	// project.
+	svc.SetProjectCapturePaused(routedProjectID, true)
+
+	if svc.IsCapturePaused() || bypassed("192.0.2.1:1234") || !bypassed("10.0.0.5:1234") {
+		t.Fatal("expected only requests for the routed project to be bypassed")
+	}
+
+	svc.SetProjectCapturePaused(routedProjectID, false)
+	svc.SetCapturePaused(true)
+
+	if !svc.IsCapturePaused() || !bypassed("192.0.2.1:1234") || !bypassed("10.0.0.5:1234") {
+		t.Fatal("expected all requests to be bypassed")
+	}
+
+	svc.SetCapturePaused(false)
+
+	if svc.IsCapturePaused() || bypassed("192.0.2.1:1234") || bypassed("10.0.0.5:1234") {
+		t.Fatal("expected requests to be logged after capture is resumed")
+	}
+
+	if calls := repoMock.StoreRequestLogCalls(); len(calls) != 4 {
+		t.Fatalf("expected 4 request logs to be stored, got: %v", len(calls))
+	}
+}
+
+func TestSampling(t *testing.T) {
+	t.Parallel()
+
+	repoMock := &RepoMock{
+		StoreRequestLogFunc: func(_ context.Context, _ reqlog.RequestLog) error {
+			return nil
+		},
+	}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository: repoMock,
+		Scope:      &scope.Scope{},
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	if err := svc.SetSampling(reqlog.Sampling{Rate: -1}); !errors.Is(err, reqlog.ErrInvalidSampling) {
+		t.Fatalf("expected `reqlog.ErrInvalidSampling`, got: %v", err)
+	}
+
+	reqModFn := svc.RequestModifier(func(req *http.Request) {})
+
+	logged := func(sampling reqlog.Sampling, urls ...string) []string {
+		if err := svc.SetSampling(sampling); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var got []string
+
+		for _, u := range urls {
+			req := httptest.NewRequest("GET", u, nil)
+			reqModFn(req)
+
+			if bypassed, _ := req.Context().Value(reqlog.LogBypassedKey).(bool); !bypassed {
+				got = append(got, u)
+			}
+		}
+
+		return got
+	}
+
+	urls := []string{
+		"https://example.com/foo?id=1",
+		"https://example.com/foo?id=2",
+		"https://example.com/bar",
+		"https://example.com/foo?id=3",
+		"https://example.com/bar",
+	}
+
+	tests := []struct {
+		name     string
+		sampling reqlog.Sampling
+		exp      []string
+	}{
+		{name: "disabled", sampling: reqlog.Sampling{}, exp: urls},
+		{name: "rate", sampling: reqlog.Sampling{Rate: 2}, exp: []string{urls[0], urls[2], urls[4]}},
+		{name: "per endpoint", sampling: reqlog.Sampling{PerEndpoint: 1}, exp: []string{urls[0], urls[2]}},
+		// Requests that exceed the limit per endpoint aren't counted for the rate.
+		{name: "both", sampling: reqlog.Sampling{Rate: 2, PerEndpoint: 1}, exp: []string{urls[0], urls[4]}},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.exp, logged(tt.sampling, urls...)); diff != "" {
+			t.Errorf("%v: logged requests not equal (-exp, +got):\n%v", tt.name, diff)
+		}
+	}
+}
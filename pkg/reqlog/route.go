@@ -0,0 +1,137 @@
+package reqlog
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/scope"
+)
+
+var ErrInvalidClientRoute = errcode.New(errcode.Invalid, "reqlog: invalid client route")
+
+// ClientRoute logs the requests of matching clients to a project other than
+// the active one, so that multiple testers can share the proxy without mixing
+// their logs. A route matches if all of its set criteria match.
+type ClientRoute struct {
+	// Client IP address or CIDR range, e.g. `10.0.0.5` or `10.0.0.0/24`.
+	ClientIP string
+	// Username of the client's proxy credentials, see `proxy.ClientUsername`.
+	Username  string
+	ProjectID ulid.ULID
+	// Scope of the project, which is used instead of the scope of the active
+	// project, e.g. for bypassing out of scope requests. Defaults to the scope
+	// of the active project.
+	Scope *scope.Scope
+}
+
+type clientRoute struct {
+	ClientRoute
+	ipNet *net.IPNet
+}
+
+// SetClientRoutes replaces the client routes. The first matching route is
+// used. Requests that don't match a route are logged to the active project.
+func (svc *service) SetClientRoutes(routes []ClientRoute) error {
+	parsed := make([]clientRoute, len(routes))
+
+	for i, route := range routes {
+		if route.ClientIP == "" && route.Username == "" {
+			return fmt.Errorf("%w: client IP or username must be set", ErrInvalidClientRoute)
+		}
+
+		if route.ProjectID.Compare(ulid.ULID{}) == 0 {
+			return fmt.Errorf("%w: project ID must be set", ErrInvalidClientRoute)
+		}
+
+		parsed[i] = clientRoute{ClientRoute: route}
+
+		if route.ClientIP != "" {
+			ipNet, err := parseIPNet(route.ClientIP)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidClientRoute, err)
+			}
+
+			parsed[i].ipNet = ipNet
+		}
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.clientRoutes = parsed
+
+	return nil
+}
+
+// ClientRoutes returns the client routes.
+func (svc *service) ClientRoutes() []ClientRoute {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	routes := make([]ClientRoute, len(svc.clientRoutes))
+	for i, route := range svc.clientRoutes {
+		routes[i] = route.ClientRoute
+	}
+
+	return routes
+}
+
+// matchClientRoute returns the first client route that matches the client of
+// req.
+func (svc *service) matchClientRoute(req *http.Request) (ClientRoute, bool) {
+	svc.mu.RLock()
+	routes := svc.clientRoutes
+	svc.mu.RUnlock()
+
+	if len(routes) == 0 {
+		return ClientRoute{}, false
+	}
+
+	var clientIP net.IP
+
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		clientIP = net.ParseIP(host)
+	}
+
+	username := proxy.ClientUsername(req.Context())
+
+	for _, route := range routes {
+		if route.ipNet != nil && (clientIP == nil || !route.ipNet.Contains(clientIP)) {
+			continue
+		}
+
+		if route.Username != "" && route.Username != username {
+			continue
+		}
+
+		return route.ClientRoute, true
+	}
+
+	return ClientRoute{}, false
+}
+
+// parseIPNet parses an IP address or CIDR range.
+func parseIPNet(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, ipNet, err := net.ParseCIDR(s)
+		return ipNet, err
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address: %q", s)
+	}
+
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
@@ -0,0 +1,119 @@
+package reqlog
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+var ErrInvalidSampling = errcode.New(errcode.Invalid, "reqlog: invalid sampling")
+
+// maxSampledEndpoints is the maximum number of endpoints whose requests are
+// counted for `Sampling.PerEndpoint`. Requests to other endpoints are sampled
+// by `Sampling.Rate` only.
+const maxSampledEndpoints = 100000
+
+// Sampling determines which proxied requests are logged, e.g. for load tests
+// where logging every request isn't needed. Requests that aren't sampled are
+// proxied without being logged. When both are set, a request is logged if it
+// passes both.
+type Sampling struct {
+	// Log 1 in N requests. Zero or one logs every request.
+	Rate int
+	// Log only the first K requests per endpoint (method, host and path). Zero
+	// means no limit.
+	PerEndpoint int
+}
+
+// Validate returns `ErrInvalidSampling` if the rate or number of requests per
+// endpoint is negative.
+func (s Sampling) Validate() error {
+	if s.Rate < 0 || s.PerEndpoint < 0 {
+		return fmt.Errorf("%w: rate and requests per endpoint must not be negative", ErrInvalidSampling)
+	}
+
+	return nil
+}
+
+func (s Sampling) enabled() bool {
+	return s.Rate > 1 || s.PerEndpoint > 0
+}
+
+type sampler struct {
+	mu       sync.Mutex
+	sampling Sampling
+	// Number of requests that were sampled by rate.
+	count int
+	// Number of logged requests, by endpoint.
+	endpoints map[string]int
+}
+
+// set replaces the sampling settings, and resets the counts.
+func (s *sampler) set(sampling Sampling) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sampling = sampling
+	s.count = 0
+	s.endpoints = nil
+}
+
+func (s *sampler) get() Sampling {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sampling
+}
+
+// sample returns whether req should be logged.
+func (s *sampler) sample(req *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.sampling.enabled() {
+		return true
+	}
+
+	key := req.Method + " " + req.URL.Host + req.URL.Path
+
+	n, tracked := s.endpoints[key]
+	if s.sampling.PerEndpoint > 0 && n >= s.sampling.PerEndpoint {
+		return false
+	}
+
+	if s.sampling.Rate > 1 {
+		s.count++
+
+		if (s.count-1)%s.sampling.Rate != 0 {
+			return false
+		}
+	}
+
+	if s.sampling.PerEndpoint > 0 && (tracked || len(s.endpoints) < maxSampledEndpoints) {
+		if s.endpoints == nil {
+			s.endpoints = make(map[string]int)
+		}
+
+		s.endpoints[key] = n + 1
+	}
+
+	return true
+}
+
+// SetSampling sets which proxied requests are logged. Counts of requests are
+// reset, e.g. the first requests per endpoint are logged again.
+func (svc *service) SetSampling(sampling Sampling) error {
+	if err := sampling.Validate(); err != nil {
+		return err
+	}
+
+	svc.sampler.set(sampling)
+
+	return nil
+}
+
+func (svc *service) Sampling() Sampling {
+	return svc.sampler.get()
+}
@@ -1,11 +1,13 @@
 package reqlog
 
 import (
-	"errors"
-	"fmt"
-	"regexp"
+	"encoding/json"
+	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/oklog/ulid"
 
@@ -22,9 +24,23 @@ var reqLogSearchKeyFns = map[string]func(rl RequestLog) string{
 		}
 		return rl.URL.String()
 	},
-	"req.method":    func(rl RequestLog) string { return rl.Method },
-	"req.body":      func(rl RequestLog) string { return string(rl.Body) },
-	"req.timestamp": func(rl RequestLog) string { return ulid.Time(rl.ID.Time()).String() },
+	"req.originalURL": func(rl RequestLog) string {
+		if rl.OriginalURL == nil {
+			return ""
+		}
+		return rl.OriginalURL.String()
+	},
+	"req.host":        func(rl RequestLog) string { return rl.Hostname() },
+	"req.method":      func(rl RequestLog) string { return rl.Method },
+	"req.body":        func(rl RequestLog) string { return string(rl.Body) },
+	"req.timestamp":   func(rl RequestLog) string { return ulid.Time(rl.ID.Time()).String() },
+	"req.error":       func(rl RequestLog) string { return rl.Error },
+	"req.remoteIP":    func(rl RequestLog) string { return rl.ServerIP() },
+	"req.clientAddr":  func(rl RequestLog) string { return rl.ClientAddr },
+	"req.device":      func(rl RequestLog) string { return rl.Device.String() },
+	"req.device.type": func(rl RequestLog) string { return string(rl.Device.Type) },
+	"req.device.os":   func(rl RequestLog) string { return rl.Device.OS },
+	"req.tags":        func(rl RequestLog) string { return strings.Join(rl.Tags, ",") },
 }
 
 var ResLogSearchKeyFns = map[string]func(rl ResponseLog) string{
@@ -32,159 +48,273 @@ var ResLogSearchKeyFns = map[string]func(rl ResponseLog) string{
 	"res.statusCode":   func(rl ResponseLog) string { return strconv.Itoa(rl.StatusCode) },
 	"res.statusReason": func(rl ResponseLog) string { return rl.Status },
 	"res.body":         func(rl ResponseLog) string { return string(rl.Body) },
+	"res.tls.version": func(rl ResponseLog) string {
+		if rl.TLS == nil {
+			return ""
+		}
+		return rl.TLS.VersionName()
+	},
+	"res.tls.cipherSuite": func(rl ResponseLog) string {
+		if rl.TLS == nil {
+			return ""
+		}
+		return rl.TLS.CipherSuiteName()
+	},
+	"res.tls.alpn": func(rl ResponseLog) string {
+		if rl.TLS == nil {
+			return ""
+		}
+		return rl.TLS.ALPN
+	},
+	"res.tls.weak": func(rl ResponseLog) string {
+		if rl.TLS == nil {
+			return ""
+		}
+		return strconv.FormatBool(rl.TLS.IsWeak())
+	},
 }
 
-// TODO: Request and response headers search key functions.
+// Prefixes of search keys for the values of a header (e.g. `req.header.Cookie`)
+// and of a field of a JSON body, by dot separated path (e.g.
+// `res.json.user.roles.0`).
+const (
+	reqHeaderKeyPrefix = "req.header."
+	resHeaderKeyPrefix = "res.header."
+	reqJSONKeyPrefix   = "req.json."
+	resJSONKeyPrefix   = "res.json."
+)
 
-// Matches returns true if the supplied search expression evaluates to true.
-func (reqLog RequestLog) Matches(expr search.Expression) (bool, error) {
-	switch e := expr.(type) {
-	case search.PrefixExpression:
-		return reqLog.matchPrefixExpr(e)
-	case search.InfixExpression:
-		return reqLog.matchInfixExpr(e)
-	case search.StringLiteral:
-		return reqLog.matchStringLiteral(e)
-	default:
-		return false, fmt.Errorf("expression type (%T) not supported", expr)
+var (
+	reqLogSearchKeys []string
+	// ResLogSearchKeys are the keys of `ResLogSearchKeyFns`, sorted.
+	ResLogSearchKeys          []string
+	reqLogAndResLogSearchKeys []string
+)
+
+func init() {
+	for key := range reqLogSearchKeyFns {
+		reqLogSearchKeys = append(reqLogSearchKeys, key)
 	}
+
+	for key := range ResLogSearchKeyFns {
+		ResLogSearchKeys = append(ResLogSearchKeys, key)
+	}
+
+	sort.Strings(reqLogSearchKeys)
+	sort.Strings(ResLogSearchKeys)
+
+	reqLogAndResLogSearchKeys = append(append([]string{}, reqLogSearchKeys...), ResLogSearchKeys...)
 }
 
-func (reqLog RequestLog) matchPrefixExpr(expr search.PrefixExpression) (bool, error) {
-	switch expr.Operator {
-	case search.TokOpNot:
-		match, err := reqLog.Matches(expr.Right)
-		if err != nil {
-			return false, err
-		}
+// ParseSearchExpr parses a search expression for request logs. Unknown `req.`
+// and `res.` keys are reported with suggestions, see `search.CheckKeys`.
+func ParseSearchExpr(input string) (search.Expression, error) {
+	expr, err := search.ParseQuery(input)
+	if err != nil {
+		return nil, err
+	}
 
-		return !match, nil
-	default:
-		return false, errors.New("operator is not supported")
+	if err := search.CheckKeys(input, reqLogAndResLogSearchKeys, isSearchKey); err != nil {
+		return nil, err
 	}
+
+	return expr, nil
 }
 
-func (reqLog RequestLog) matchInfixExpr(expr search.InfixExpression) (bool, error) {
-	switch expr.Operator {
-	case search.TokOpAnd:
-		left, err := reqLog.Matches(expr.Left)
-		if err != nil {
-			return false, err
-		}
+func isSearchKey(key string) bool {
+	if _, ok := reqLogSearchKeyFns[key]; ok {
+		return true
+	}
 
-		right, err := reqLog.Matches(expr.Right)
-		if err != nil {
-			return false, err
-		}
+	return isDynamicSearchKey(key, reqHeaderKeyPrefix, reqJSONKeyPrefix) || IsResponseSearchKey(key)
+}
 
-		return left && right, nil
-	case search.TokOpOr:
-		left, err := reqLog.Matches(expr.Left)
-		if err != nil {
-			return false, err
-		}
+// IsResponseSearchKey returns true if key is a `res.` search key, see
+// `ResponseSearchValue`.
+func IsResponseSearchKey(key string) bool {
+	if _, ok := ResLogSearchKeyFns[key]; ok {
+		return true
+	}
 
-		right, err := reqLog.Matches(expr.Right)
-		if err != nil {
-			return false, err
+	return isDynamicSearchKey(key, resHeaderKeyPrefix, resJSONKeyPrefix)
+}
+
+// isDynamicSearchKey returns true if key has one of prefixes, followed by e.g.
+// the name of a header.
+func isDynamicSearchKey(key string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			return true
 		}
+	}
 
-		return left || right, nil
+	return false
+}
+
+// ResponseSearchValue returns the value of a `res.` search key of a response
+// log. Keys of a missing response have an empty value.
+func ResponseSearchValue(resLog *ResponseLog, key string) (string, bool) {
+	if !strings.HasPrefix(key, "res.") {
+		return "", false
 	}
 
-	left, ok := expr.Left.(search.StringLiteral)
-	if !ok {
-		return false, errors.New("left operand must be a string literal")
+	if resLog == nil {
+		return "", true
 	}
 
-	leftVal := reqLog.getMappedStringLiteral(left.Value)
+	if fn, ok := ResLogSearchKeyFns[key]; ok {
+		return fn(*resLog), true
+	}
 
-	if expr.Operator == search.TokOpRe || expr.Operator == search.TokOpNotRe {
-		right, ok := expr.Right.(*regexp.Regexp)
-		if !ok {
-			return false, errors.New("right operand must be a regular expression")
-		}
+	if value, ok := headerSearchValue(resLog.Header, resHeaderKeyPrefix, key); ok {
+		return value, true
+	}
+
+	return jsonSearchValue(resLog.Body, resJSONKeyPrefix, key)
+}
+
+// headerSearchValue returns the values of the header named by key after prefix,
+// joined by commas. Missing headers have an empty value.
+func headerSearchValue(header http.Header, prefix, key string) (string, bool) {
+	if !strings.HasPrefix(key, prefix) || len(key) == len(prefix) {
+		return "", false
+	}
+
+	return strings.Join(header.Values(key[len(prefix):]), ", "), true
+}
+
+// jsonSearchValue returns the value of the field of a JSON body at the dot
+// separated path of key after prefix. Array elements are referred to by index.
+// Strings are returned as is, other values JSON encoded. Missing fields and
+// bodies that aren't JSON have an empty value.
+func jsonSearchValue(body []byte, prefix, key string) (string, bool) {
+	if !strings.HasPrefix(key, prefix) || len(key) == len(prefix) {
+		return "", false
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", true
+	}
+
+	for _, field := range strings.Split(key[len(prefix):], ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[field]
+		case []interface{}:
+			i, err := strconv.Atoi(field)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", true
+			}
 
-		switch expr.Operator {
-		case search.TokOpRe:
-			return right.MatchString(leftVal), nil
-		case search.TokOpNotRe:
-			return !right.MatchString(leftVal), nil
+			value = v[i]
+		default:
+			return "", true
 		}
 	}
 
-	right, ok := expr.Right.(search.StringLiteral)
-	if !ok {
-		return false, errors.New("right operand must be a string literal")
-	}
-
-	rightVal := reqLog.getMappedStringLiteral(right.Value)
-
-	switch expr.Operator {
-	case search.TokOpEq:
-		return leftVal == rightVal, nil
-	case search.TokOpNotEq:
-		return leftVal != rightVal, nil
-	case search.TokOpGt:
-		// TODO(?) attempt to parse as int.
-		return leftVal > rightVal, nil
-	case search.TokOpLt:
-		// TODO(?) attempt to parse as int.
-		return leftVal < rightVal, nil
-	case search.TokOpGtEq:
-		// TODO(?) attempt to parse as int.
-		return leftVal >= rightVal, nil
-	case search.TokOpLtEq:
-		// TODO(?) attempt to parse as int.
-		return leftVal <= rightVal, nil
+	switch v := value.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
 	default:
-		return false, errors.New("unsupported operator")
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", true
+		}
+
+		return string(encoded), true
 	}
 }
 
-func (reqLog RequestLog) getMappedStringLiteral(s string) string {
-	switch {
-	case strings.HasPrefix(s, "req."):
-		fn, ok := reqLogSearchKeyFns[s]
-		if ok {
-			return fn(reqLog)
-		}
-	case strings.HasPrefix(s, "res."):
-		if reqLog.Response == nil {
-			return ""
-		}
+// SearchExprFields describes which parts of a request log are needed to
+// evaluate a search expression. Repositories use it to only load stored
+// bodies and responses for records that can't be matched without them.
+type SearchExprFields struct {
+	RequestBody  bool
+	Response     bool
+	ResponseBody bool
+}
 
-		fn, ok := ResLogSearchKeyFns[s]
-		if ok {
-			return fn(*reqLog.Response)
-		}
+// FieldsForSearchExpr returns the parts of a request log that expr refers
+// to. A string literal without operator matches on all fields.
+func FieldsForSearchExpr(expr search.Expression) SearchExprFields {
+	keys, all := search.Keys(expr)
+	if all {
+		return SearchExprFields{RequestBody: true, Response: true, ResponseBody: true}
 	}
 
-	return s
+	var fields SearchExprFields
+
+	for _, key := range keys {
+		fields.addKey(key)
+	}
+
+	return fields
 }
 
-func (reqLog RequestLog) matchStringLiteral(strLiteral search.StringLiteral) (bool, error) {
-	for _, fn := range reqLogSearchKeyFns {
-		if strings.Contains(
-			strings.ToLower(fn(reqLog)),
-			strings.ToLower(strLiteral.Value),
-		) {
-			return true, nil
+func (f *SearchExprFields) addKey(key string) {
+	switch {
+	case key == "req.body":
+		f.RequestBody = true
+	case key == "req.remoteIP":
+		// The IP address is stored on the response log, if there is one.
+		f.Response = true
+	case key == "res.body":
+		f.Response = true
+		f.ResponseBody = true
+	case strings.HasPrefix(key, reqJSONKeyPrefix):
+		f.RequestBody = true
+	case strings.HasPrefix(key, resJSONKeyPrefix):
+		f.Response = true
+		f.ResponseBody = true
+	case strings.HasPrefix(key, resHeaderKeyPrefix):
+		f.Response = true
+	case strings.HasPrefix(key, "res."):
+		if _, ok := ResLogSearchKeyFns[key]; ok {
+			f.Response = true
 		}
 	}
+}
 
-	if reqLog.Response != nil {
-		for _, fn := range ResLogSearchKeyFns {
-			if strings.Contains(
-				strings.ToLower(fn(*reqLog.Response)),
-				strings.ToLower(strLiteral.Value),
-			) {
-				return true, nil
-			}
-		}
+// searchRecord evaluates search expressions for a request log.
+type searchRecord struct {
+	reqLog *RequestLog
+}
+
+func (rec searchRecord) SearchValue(key string) (string, bool) {
+	if fn, ok := reqLogSearchKeyFns[key]; ok {
+		return fn(*rec.reqLog), true
+	}
+
+	if value, ok := headerSearchValue(rec.reqLog.Header, reqHeaderKeyPrefix, key); ok {
+		return value, true
 	}
 
-	return false, nil
+	if value, ok := jsonSearchValue(rec.reqLog.Body, reqJSONKeyPrefix, key); ok {
+		return value, true
+	}
+
+	return ResponseSearchValue(rec.reqLog.Response, key)
+}
+
+func (rec searchRecord) SearchKeys() []string {
+	if rec.reqLog.Response == nil {
+		return reqLogSearchKeys
+	}
+
+	return reqLogAndResLogSearchKeys
+}
+
+// SearchTime returns the time of the request log ID, see `search.TimeRecord`.
+func (rec searchRecord) SearchTime() time.Time {
+	return ulid.Time(rec.reqLog.ID.Time())
+}
+
+// Matches returns true if the supplied search expression evaluates to true.
+func (reqLog RequestLog) Matches(expr search.Expression) (bool, error) {
+	return search.Match(expr, searchRecord{&reqLog})
 }
 
 func (reqLog RequestLog) MatchScope(s *scope.Scope) bool {
@@ -233,3 +363,65 @@ func (reqLog RequestLog) MatchScope(s *scope.Scope) bool {
 
 	return false
 }
+
+const (
+	// Maximum number of search hits per body.
+	maxSearchHits = 100
+	// Bytes of a body before and after a search hit, in its snippet.
+	searchHitContext = 40
+)
+
+// SearchHit is a match of a search expression in the request or response body
+// of a request log.
+type SearchHit struct {
+	// Search key of the body: `req.body` or `res.body`.
+	Key string
+	// Byte offsets of the match in the body.
+	Start, End int
+	// Part of the body around the match, and the byte offset of the match in
+	// it.
+	Snippet       string
+	SnippetOffset int
+}
+
+// SearchHits returns the matches of expr in the request and response body, so
+// that they can be highlighted without scanning the bodies again.
+func (reqLog RequestLog) SearchHits(expr search.Expression) []SearchHit {
+	rec := searchRecord{&reqLog}
+	hits := search.Hits(expr, rec, []string{"req.body", "res.body"}, maxSearchHits)
+	searchHits := make([]SearchHit, 0, len(hits))
+
+	for _, hit := range hits {
+		body, _ := rec.SearchValue(hit.Key)
+
+		start := snippetBoundary(body, hit.Start-searchHitContext)
+		end := snippetBoundary(body, hit.End+searchHitContext)
+
+		searchHits = append(searchHits, SearchHit{
+			Key:           hit.Key,
+			Start:         hit.Start,
+			End:           hit.End,
+			Snippet:       body[start:end],
+			SnippetOffset: hit.Start - start,
+		})
+	}
+
+	return searchHits
+}
+
+// snippetBoundary returns i, clamped to s and moved back to the start of a
+// UTF-8 encoded rune.
+func snippetBoundary(s string, i int) int {
+	switch {
+	case i <= 0:
+		return 0
+	case i >= len(s):
+		return len(s)
+	}
+
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+
+	return i
+}
@@ -1,8 +1,14 @@
 package reqlog_test
 
 import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	"github.com/dstotijn/hetty/pkg/reqlog"
 	"github.com/dstotijn/hetty/pkg/search"
 )
@@ -26,6 +32,28 @@ func TestRequestLogMatch(t *testing.T) {
 			expectedMatch: true,
 			expectedError: nil,
 		},
+		{
+			name:  "infix expression, equal operator, weak TLS",
+			query: "res.tls.weak = true",
+			requestLog: reqlog.RequestLog{
+				Response: &reqlog.ResponseLog{
+					TLS: &reqlog.TLSInfo{Version: tls.VersionTLS10, CipherSuite: tls.TLS_RSA_WITH_AES_128_CBC_SHA},
+				},
+			},
+			expectedMatch: true,
+			expectedError: nil,
+		},
+		{
+			name:  "infix expression, equal operator, strong TLS",
+			query: "res.tls.weak = true",
+			requestLog: reqlog.RequestLog{
+				Response: &reqlog.ResponseLog{
+					TLS: &reqlog.TLSInfo{Version: tls.VersionTLS13, CipherSuite: tls.TLS_AES_128_GCM_SHA256},
+				},
+			},
+			expectedMatch: false,
+			expectedError: nil,
+		},
 		{
 			name:  "infix expression, not equal operator, match",
 			query: "req.body != bar",
@@ -169,6 +197,26 @@ func TestRequestLogMatch(t *testing.T) {
 			expectedMatch: true,
 			expectedError: nil,
 		},
+		{
+			name:  "infix expression, equal operator, request header",
+			query: `req.header.x-api-key = "secret"`,
+			requestLog: reqlog.RequestLog{
+				Header: http.Header{"X-Api-Key": []string{"secret"}},
+			},
+			expectedMatch: true,
+			expectedError: nil,
+		},
+		{
+			name:  "infix expression, equal operator, JSON field of response body",
+			query: `res.json.user.roles.1 = "admin"`,
+			requestLog: reqlog.RequestLog{
+				Response: &reqlog.ResponseLog{
+					Body: []byte(`{"user": {"roles": ["user", "admin"]}}`),
+				},
+			},
+			expectedMatch: true,
+			expectedError: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -201,3 +249,152 @@ func assertError(t *testing.T, exp, got error) {
 		t.Fatalf("expected: %v, got: %v", exp.Error(), got.Error())
 	}
 }
+
+func TestFieldsForSearchExpr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		query string
+		exp   reqlog.SearchExprFields
+	}{
+		{
+			query: "req.method = GET",
+			exp:   reqlog.SearchExprFields{},
+		},
+		{
+			query: "req.body =~ foo",
+			exp:   reqlog.SearchExprFields{RequestBody: true},
+		},
+		{
+			query: "req.method = GET AND NOT (res.statusCode = 200)",
+			exp:   reqlog.SearchExprFields{Response: true},
+		},
+		{
+			query: "req.method = POST OR res.body =~ foo",
+			exp:   reqlog.SearchExprFields{Response: true, ResponseBody: true},
+		},
+		{
+			query: "req.header.Cookie =~ session AND req.json.id = 1",
+			exp:   reqlog.SearchExprFields{RequestBody: true},
+		},
+		{
+			query: "res.header.Server = nginx",
+			exp:   reqlog.SearchExprFields{Response: true},
+		},
+		{
+			query: "res.json.id = 1",
+			exp:   reqlog.SearchExprFields{Response: true, ResponseBody: true},
+		},
+		{
+			query: "foo",
+			exp:   reqlog.SearchExprFields{RequestBody: true, Response: true, ResponseBody: true},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+
+			searchExpr, err := search.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := reqlog.FieldsForSearchExpr(searchExpr); got != tt.exp {
+				t.Fatalf("expected %+v, got: %+v", tt.exp, got)
+			}
+		})
+	}
+}
+
+func TestRequestLogColumnValues(t *testing.T) {
+	t.Parallel()
+
+	reqLog := reqlog.RequestLog{
+		Header: http.Header{"Authorization": []string{"Bearer abc"}},
+		Response: &reqlog.ResponseLog{
+			StatusCode: 200,
+			Header:     http.Header{"Set-Cookie": []string{"a=1", "b=2"}},
+			Body:       []byte(`{"user": {"id": 42, "name": "alice"}}`),
+		},
+	}
+
+	var columns []reqlog.Column
+
+	for _, query := range []string{
+		"req.header.Authorization",
+		`req.header.Authorization =~ "^Bearer (.+)"`,
+		"res.header.Set-Cookie",
+		"res.json.user.id",
+		"res.json.user",
+		"res.json.missing",
+		"res.statusCode = 200",
+	} {
+		expr, err := search.ParseQuery(query)
+		if err != nil {
+			t.Fatalf("unexpected error parsing query %q: %v", query, err)
+		}
+
+		columns = append(columns, reqlog.Column{Name: query, Expression: expr})
+	}
+
+	if err := reqlog.ValidateColumns(columns); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []reqlog.ColumnValue{
+		{Name: columns[0].Name, Value: "Bearer abc"},
+		{Name: columns[1].Name, Value: "abc"},
+		{Name: columns[2].Name, Value: "a=1, b=2"},
+		{Name: columns[3].Name, Value: "42"},
+		{Name: columns[4].Name, Value: `{"id":42,"name":"alice"}`},
+		{Name: columns[5].Name, Value: ""},
+		{Name: columns[6].Name, Value: "true"},
+	}
+
+	if diff := cmp.Diff(exp, reqLog.ColumnValues(columns)); diff != "" {
+		t.Fatalf("column values not equal (-exp, +got):\n%v", diff)
+	}
+
+	if err := reqlog.ValidateColumns(append(columns, columns[0])); !errors.Is(err, reqlog.ErrInvalidColumn) {
+		t.Fatalf("expected invalid column error for duplicate name, got: %v", err)
+	}
+}
+
+func TestRequestLogSearchHits(t *testing.T) {
+	t.Parallel()
+
+	// The snippet boundaries are in the middle of the multibyte runes around
+	// the needle.
+	prefix := "é" + strings.Repeat("a", 39)
+	suffix := strings.Repeat("b", 39) + "é"
+
+	reqLog := reqlog.RequestLog{
+		Body: []byte("needle"),
+		Response: &reqlog.ResponseLog{
+			Body: []byte(prefix + "NEEDLE" + suffix),
+		},
+	}
+
+	searchExpr, err := search.ParseQuery("needle")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []reqlog.SearchHit{
+		{Key: "req.body", Start: 0, End: 6, Snippet: "needle", SnippetOffset: 0},
+		{
+			Key:           "res.body",
+			Start:         41,
+			End:           47,
+			Snippet:       prefix + "NEEDLE" + strings.Repeat("b", 39),
+			SnippetOffset: 41,
+		},
+	}
+
+	if diff := cmp.Diff(exp, reqLog.SearchHits(searchExpr)); diff != "" {
+		t.Fatalf("search hits not equal (-exp, +got):\n%v", diff)
+	}
+}
@@ -0,0 +1,132 @@
+package reqlog
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/tracing"
+)
+
+const (
+	defaultStoreWorkers   = 8
+	defaultStoreQueueSize = 1024
+	defaultStoreTimeout   = 30 * time.Second
+)
+
+// StoreStats are metrics of the queue of response logs that are stored in the
+// background.
+type StoreStats struct {
+	// Number of response logs waiting to be stored.
+	Queued int
+	// Capacity of the queue.
+	QueueSize int
+	Workers   int
+	Stored    uint64
+	Failed    uint64
+	// Number of responses that were delayed because the queue was full.
+	Blocked uint64
+}
+
+type storeJob struct {
+	projectID ulid.ULID
+	reqLogID  ulid.ULID
+	res       *http.Response
+	pending   *pendingWrites
+	// Span of the proxied request, so that the write is part of its trace.
+	span *tracing.Span
+}
+
+// pendingWrites is a generation of response logs and request log updates that
+// are being stored. Flush starts a new generation and waits for the previous
+// ones, so that it doesn't wait for writes of traffic that arrives after it
+// was called.
+type pendingWrites struct {
+	wg sync.WaitGroup
+	// prev is guarded by `service.pendingMu`.
+	prev *pendingWrites
+}
+
+// addPending adds a write to the current generation of pending writes. Call
+// `Done` on the returned generation when the write is done.
+func (svc *service) addPending() *pendingWrites {
+	svc.pendingMu.Lock()
+	defer svc.pendingMu.Unlock()
+
+	svc.pending.wg.Add(1)
+
+	return svc.pending
+}
+
+// writeContext returns the context of a background write, which is cancelled
+// by `Close`, or when the write takes longer than the store timeout.
+func (svc *service) writeContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(svc.ctx, svc.storeTimeout)
+}
+
+// storeStats holds the counters of StoreStats, for atomic access.
+type storeStats struct {
+	stored  uint64
+	failed  uint64
+	blocked uint64
+}
+
+func (svc *service) startStoreWorkers(n int) {
+	for i := 0; i < n; i++ {
+		go svc.storeWorker()
+	}
+}
+
+func (svc *service) storeWorker() {
+	for job := range svc.storeQueue {
+		ctx, cancel := svc.writeContext()
+		err := svc.storeResponse(tracing.ContextWithSpan(ctx, job.span), job.projectID, job.reqLogID, job.res)
+
+		cancel()
+
+		if err != nil {
+			atomic.AddUint64(&svc.storeStats.failed, 1)
+			log.Printf("[ERROR] Could not store response log: %v", err)
+		} else {
+			atomic.AddUint64(&svc.storeStats.stored, 1)
+		}
+
+		job.pending.wg.Done()
+	}
+}
+
+// enqueueResponse queues a response log to be stored. When the queue is full,
+// it blocks until a worker is available, which applies backpressure to the
+// proxy instead of buffering without bounds.
+func (svc *service) enqueueResponse(projectID, reqLogID ulid.ULID, res *http.Response) {
+	job := storeJob{
+		projectID: projectID,
+		reqLogID:  reqLogID,
+		res:       res,
+		pending:   svc.addPending(),
+		span:      tracing.SpanFromContext(res.Request.Context()),
+	}
+
+	select {
+	case svc.storeQueue <- job:
+	default:
+		atomic.AddUint64(&svc.storeStats.blocked, 1)
+		svc.storeQueue <- job
+	}
+}
+
+func (svc *service) StoreStats() StoreStats {
+	return StoreStats{
+		Queued:    len(svc.storeQueue),
+		QueueSize: cap(svc.storeQueue),
+		Workers:   svc.storeWorkers,
+		Stored:    atomic.LoadUint64(&svc.storeStats.stored),
+		Failed:    atomic.LoadUint64(&svc.storeStats.failed),
+		Blocked:   atomic.LoadUint64(&svc.storeStats.blocked),
+	}
+}
@@ -0,0 +1,77 @@
+package reqlog
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+)
+
+// TLSInfo is the metadata of the TLS connection a response was received on.
+type TLSInfo struct {
+	Version     uint16
+	CipherSuite uint16
+	// Negotiated application protocol (ALPN), e.g. `h2`.
+	ALPN       string
+	ServerName string
+	// Hex encoded SHA-256 fingerprints of the certificate chain presented by the
+	// server, leaf certificate first.
+	CertFingerprints []string
+}
+
+var tlsVersionNames = map[uint16]string{
+	tls.VersionSSL30: "SSL 3.0", //nolint:staticcheck
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// ParseTLSConnectionState returns the TLS metadata of a connection.
+func ParseTLSConnectionState(state tls.ConnectionState) TLSInfo {
+	info := TLSInfo{
+		Version:          state.Version,
+		CipherSuite:      state.CipherSuite,
+		ALPN:             state.NegotiatedProtocol,
+		ServerName:       state.ServerName,
+		CertFingerprints: make([]string, len(state.PeerCertificates)),
+	}
+
+	for i, cert := range state.PeerCertificates {
+		sum := sha256.Sum256(cert.Raw)
+		info.CertFingerprints[i] = hex.EncodeToString(sum[:])
+	}
+
+	return info
+}
+
+// VersionName returns the name of the TLS version, e.g. `TLS 1.3`.
+func (info TLSInfo) VersionName() string {
+	if name, ok := tlsVersionNames[info.Version]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("0x%04X", info.Version)
+}
+
+// CipherSuiteName returns the standard name of the cipher suite, e.g.
+// `TLS_AES_128_GCM_SHA256`.
+func (info TLSInfo) CipherSuiteName() string {
+	return tls.CipherSuiteName(info.CipherSuite)
+}
+
+// IsWeak returns true when the protocol version is older than TLS 1.2, or the
+// cipher suite has known security issues.
+func (info TLSInfo) IsWeak() bool {
+	if info.Version < tls.VersionTLS12 {
+		return true
+	}
+
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.ID == info.CipherSuite {
+			return true
+		}
+	}
+
+	return false
+}
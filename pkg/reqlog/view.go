@@ -0,0 +1,180 @@
+package reqlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/transform"
+)
+
+var (
+	ErrInvalidBodyView  = errcode.New(errcode.Invalid, "reqlog: invalid body view")
+	ErrBodyViewNotFound = errcode.New(errcode.NotFound, "reqlog: body view not found")
+)
+
+// maxBodyViewNameLength is the maximum length of the name of a body view.
+const maxBodyViewNameLength = 100
+
+type BodySource string
+
+const (
+	BodySourceRequest  BodySource = "request"
+	BodySourceResponse BodySource = "response"
+)
+
+// BodyView is a named transformation of (part of) the request or response body
+// of a request log, e.g. a decoded JWT or a decompressed payload. Views are
+// stored with the request log, so that analysis of its bodies is kept.
+type BodyView struct {
+	Name   string
+	Source BodySource
+	// Byte range of the body that is transformed, e.g. the value of a
+	// parameter. An end of 0 means the end of the body.
+	Start int
+	End   int
+	// Transforms that are applied in order, see `transform.Chain`.
+	Transforms []transform.Transform
+	// Output of the transforms, computed when the view is set.
+	Output    []byte
+	CreatedAt time.Time
+}
+
+// SetBodyView computes a view of a body of a request log of the active
+// project, and stores it with the request log. A view with the same name is
+// replaced.
+func (svc *service) SetBodyView(ctx context.Context, reqLogID ulid.ULID, view BodyView) (BodyView, error) {
+	if svc.ReadOnly() {
+		return BodyView{}, ErrReadOnly
+	}
+
+	view.Name = strings.TrimSpace(view.Name)
+	if view.Name == "" || len(view.Name) > maxBodyViewNameLength {
+		return BodyView{}, fmt.Errorf("%w: name must be 1 to %v characters", ErrInvalidBodyView, maxBodyViewNameLength)
+	}
+
+	svc.updateMu.Lock()
+	defer svc.updateMu.Unlock()
+
+	reqLog, err := svc.findActiveRequestLog(ctx, reqLogID)
+	if err != nil {
+		return BodyView{}, err
+	}
+
+	var body []byte
+
+	switch view.Source {
+	case BodySourceRequest:
+		body = reqLog.Body
+	case BodySourceResponse:
+		if reqLog.Response == nil {
+			return BodyView{}, fmt.Errorf("%w: request log has no response", ErrInvalidBodyView)
+		}
+
+		body = reqLog.Response.Body
+	default:
+		return BodyView{}, fmt.Errorf("%w: unknown source %q", ErrInvalidBodyView, view.Source)
+	}
+
+	end := view.End
+	if end == 0 {
+		end = len(body)
+	}
+
+	if view.Start < 0 || end < view.Start || end > len(body) {
+		return BodyView{}, fmt.Errorf("%w: range %v-%v is out of bounds of body (%v bytes)",
+			ErrInvalidBodyView, view.Start, view.End, len(body))
+	}
+
+	view.Output, err = transform.Chain(body[view.Start:end], view.Transforms...)
+	if err != nil {
+		return BodyView{}, fmt.Errorf("%w: %v", ErrInvalidBodyView, err)
+	}
+
+	view.CreatedAt = time.Now()
+
+	replaced := false
+
+	for i := range reqLog.BodyViews {
+		if reqLog.BodyViews[i].Name == view.Name {
+			reqLog.BodyViews[i] = view
+			replaced = true
+
+			break
+		}
+	}
+
+	if !replaced {
+		reqLog.BodyViews = append(reqLog.BodyViews, view)
+	}
+
+	reqLog.Response = nil
+
+	if err := svc.repo.StoreRequestLog(ctx, reqLog); err != nil {
+		return BodyView{}, fmt.Errorf("reqlog: failed to store request log: %w", err)
+	}
+
+	return view, nil
+}
+
+// DeleteBodyView deletes a view of a request log of the active project.
+func (svc *service) DeleteBodyView(ctx context.Context, reqLogID ulid.ULID, name string) error {
+	if svc.ReadOnly() {
+		return ErrReadOnly
+	}
+
+	svc.updateMu.Lock()
+	defer svc.updateMu.Unlock()
+
+	reqLog, err := svc.findActiveRequestLog(ctx, reqLogID)
+	if err != nil {
+		return err
+	}
+
+	views := make([]BodyView, 0, len(reqLog.BodyViews))
+
+	for _, view := range reqLog.BodyViews {
+		if view.Name != name {
+			views = append(views, view)
+		}
+	}
+
+	if len(views) == len(reqLog.BodyViews) {
+		return ErrBodyViewNotFound
+	}
+
+	reqLog.BodyViews = views
+	reqLog.Response = nil
+
+	if err := svc.repo.StoreRequestLog(ctx, reqLog); err != nil {
+		return fmt.Errorf("reqlog: failed to store request log: %w", err)
+	}
+
+	return nil
+}
+
+// findActiveRequestLog returns a request log of the active project.
+func (svc *service) findActiveRequestLog(ctx context.Context, id ulid.ULID) (RequestLog, error) {
+	projectID := svc.ActiveProjectID()
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return RequestLog{}, ErrProjectIDMustBeSet
+	}
+
+	reqLog, err := svc.repo.FindRequestLogByID(ctx, id)
+	if errors.Is(err, ErrRequestNotFound) {
+		return RequestLog{}, ErrRequestNotFound
+	} else if err != nil {
+		return RequestLog{}, fmt.Errorf("reqlog: failed to find request log: %w", err)
+	}
+
+	if reqLog.ProjectID.Compare(projectID) != 0 {
+		return RequestLog{}, ErrRequestNotFound
+	}
+
+	return reqLog, nil
+}
@@ -0,0 +1,108 @@
+package reqlog_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+	"github.com/dstotijn/hetty/pkg/transform"
+)
+
+func TestBodyViews(t *testing.T) {
+	t.Parallel()
+
+	projectID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: projectID,
+		Body:      []byte("token=aGVsbG8%3D&x=1"),
+		Response:  &reqlog.ResponseLog{Body: []byte("not gzip")},
+	}
+
+	repoMock := &RepoMock{
+		FindRequestLogByIDFunc: func(_ context.Context, id ulid.ULID) (reqlog.RequestLog, error) {
+			if id.Compare(reqLog.ID) != 0 {
+				return reqlog.RequestLog{}, reqlog.ErrRequestNotFound
+			}
+
+			return reqLog, nil
+		},
+		StoreRequestLogFunc: func(_ context.Context, stored reqlog.RequestLog) error {
+			stored.Response = reqLog.Response
+			reqLog = stored
+
+			return nil
+		},
+	}
+	svc := reqlog.NewService(reqlog.Config{
+		Repository: repoMock,
+		Scope:      &scope.Scope{},
+	})
+	svc.SetActiveProjectID(projectID)
+	ctx := context.Background()
+
+	view, err := svc.SetBodyView(ctx, reqLog.ID, reqlog.BodyView{
+		Name:       " token ",
+		Source:     reqlog.BodySourceRequest,
+		Start:      6,
+		End:        16,
+		Transforms: []transform.Transform{transform.URLDecode, transform.Base64Decode},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if view.Name != "token" || string(view.Output) != "hello" {
+		t.Fatalf("unexpected view: %+v (output: %q)", view, view.Output)
+	}
+
+	// Views are replaced by name.
+	_, err = svc.SetBodyView(ctx, reqLog.ID, reqlog.BodyView{Name: "token", Source: reqlog.BodySourceRequest})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(reqLog.BodyViews) != 1 || string(reqLog.BodyViews[0].Output) != string(reqLog.Body) {
+		t.Fatalf("expected replaced view of the whole body, got: %+v", reqLog.BodyViews)
+	}
+
+	for name, view := range map[string]reqlog.BodyView{
+		"empty name":     {Source: reqlog.BodySourceRequest},
+		"unknown source": {Name: "a", Source: "trailer"},
+		"out of bounds":  {Name: "a", Source: reqlog.BodySourceRequest, Start: 10, End: 100},
+		"failed transform": {
+			Name:       "a",
+			Source:     reqlog.BodySourceResponse,
+			Transforms: []transform.Transform{transform.GzipDecompress},
+		},
+	} {
+		if _, err := svc.SetBodyView(ctx, reqLog.ID, view); !errors.Is(err, reqlog.ErrInvalidBodyView) {
+			t.Errorf("%v: expected invalid body view error, got: %v", name, err)
+		}
+	}
+
+	if err := svc.DeleteBodyView(ctx, reqLog.ID, "token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(reqLog.BodyViews) != 0 {
+		t.Fatalf("expected no views after delete, got: %+v", reqLog.BodyViews)
+	}
+
+	if err := svc.DeleteBodyView(ctx, reqLog.ID, "token"); !errors.Is(err, reqlog.ErrBodyViewNotFound) {
+		t.Fatalf("expected body view not found error, got: %v", err)
+	}
+
+	// Request logs of other projects can't be changed.
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	_, err = svc.SetBodyView(ctx, reqLog.ID, reqlog.BodyView{Name: "a", Source: reqlog.BodySourceRequest})
+	if !errors.Is(err, reqlog.ErrRequestNotFound) {
+		t.Fatalf("expected request not found error, got: %v", err)
+	}
+}
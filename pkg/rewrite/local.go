@@ -0,0 +1,191 @@
+package rewrite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+var ErrInvalidLocalMappings = errcode.New(errcode.Invalid, "rewrite: invalid local mappings")
+
+// LocalMapping serves a local file or inline content as the response to
+// matching proxied requests ("map local"), instead of the upstream server, e.g.
+// to test frontend behavior against a modified script or config.
+type LocalMapping struct {
+	// Regular expression matched against the full URL of requests, e.g.
+	// `^https://example\.com/js/app\.js`.
+	URL string
+	// Path of the file to serve. It's read for every request, so that it can be
+	// edited while testing.
+	File string
+	// Content to serve, if `File` isn't set.
+	Content string
+	// Media type of the response. Defaults to the type of the file extension of
+	// `File` or of the URL path, or else it's detected from the content.
+	ContentType string
+	// Defaults to `200`.
+	StatusCode int
+}
+
+// LocalMappings are the local mappings of a project. The first matching
+// mapping is used.
+type LocalMappings []LocalMapping
+
+// Validate returns an error if a URL pattern is empty or invalid, if both a
+// file and content are set, or if a status code is invalid.
+func (mappings LocalMappings) Validate() error {
+	for _, mapping := range mappings {
+		if mapping.URL == "" {
+			return fmt.Errorf("%w: URL pattern must not be empty", ErrInvalidLocalMappings)
+		}
+
+		if _, err := regexp.Compile(mapping.URL); err != nil {
+			return fmt.Errorf("%w: invalid URL pattern: %v", ErrInvalidLocalMappings, err)
+		}
+
+		if mapping.File != "" && mapping.Content != "" {
+			return fmt.Errorf("%w: either a file or content must be set, not both", ErrInvalidLocalMappings)
+		}
+
+		if mapping.StatusCode != 0 && (mapping.StatusCode < 100 || mapping.StatusCode > 599) {
+			return fmt.Errorf("%w: invalid status code %v", ErrInvalidLocalMappings, mapping.StatusCode)
+		}
+	}
+
+	return nil
+}
+
+type localMapping struct {
+	LocalMapping
+	url *regexp.Regexp
+}
+
+// LocalMappings returns the local mappings of the active project.
+func (r *Rewriter) LocalMappings() LocalMappings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mappings := make(LocalMappings, len(r.localMappings))
+	for i, mapping := range r.localMappings {
+		mappings[i] = mapping.LocalMapping
+	}
+
+	return mappings
+}
+
+// SetLocalMappings replaces the local mappings. Mappings with an invalid URL
+// pattern are ignored, see `LocalMappings.Validate`.
+func (r *Rewriter) SetLocalMappings(mappings LocalMappings) {
+	compiled := make([]localMapping, 0, len(mappings))
+
+	for _, mapping := range mappings {
+		if mapping.URL == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(mapping.URL)
+		if err != nil {
+			continue
+		}
+
+		compiled = append(compiled, localMapping{LocalMapping: mapping, url: re})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.localMappings = compiled
+}
+
+// Responder returns the response of the first local mapping that matches req,
+// or nil. It's meant to be registered with `proxy.Proxy.UseResponder`. If the
+// file of a mapping can't be read, a `500 Internal Server Error` response is
+// returned, so that the request isn't sent upstream instead.
+func (r *Rewriter) Responder(req *http.Request) *http.Response {
+	r.mu.RLock()
+	mappings := r.localMappings
+	r.mu.RUnlock()
+
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	rawURL := req.URL.String()
+
+	for _, mapping := range mappings {
+		if mapping.url.MatchString(rawURL) {
+			return mapping.response(req)
+		}
+	}
+
+	return nil
+}
+
+func (mapping localMapping) response(req *http.Request) *http.Response {
+	statusCode := mapping.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	body := []byte(mapping.Content)
+
+	if mapping.File != "" {
+		var err error
+
+		body, err = ioutil.ReadFile(mapping.File)
+		if err != nil {
+			log.Printf("[ERROR] Could not read file of local mapping: %v", err)
+
+			return newResponse(req, http.StatusInternalServerError, "text/plain; charset=utf-8",
+				[]byte(fmt.Sprintf("Hetty could not read the file of a local mapping: %v\n", err)))
+		}
+	}
+
+	return newResponse(req, statusCode, mapping.contentType(req, body), body)
+}
+
+func (mapping localMapping) contentType(req *http.Request, body []byte) string {
+	if mapping.ContentType != "" {
+		return mapping.ContentType
+	}
+
+	ext := path.Ext(req.URL.Path)
+	if mapping.File != "" {
+		ext = filepath.Ext(mapping.File)
+	}
+
+	if contentType := mime.TypeByExtension(strings.ToLower(ext)); contentType != "" {
+		return contentType
+	}
+
+	return http.DetectContentType(body)
+}
+
+func newResponse(req *http.Request, statusCode int, contentType string, body []byte) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", contentType)
+	// Changes of the file or mapping take effect on the next request.
+	header.Set("Cache-Control", "no-store")
+
+	res := &http.Response{
+		Status:     fmt.Sprintf("%v %v", statusCode, http.StatusText(statusCode)),
+		StatusCode: statusCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Request:    req,
+	}
+
+	setBody(res, body)
+
+	return res
+}
@@ -0,0 +1,114 @@
+package rewrite_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/rewrite"
+)
+
+func TestLocalMappingsValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		mappings rewrite.LocalMappings
+		expErr   bool
+	}{
+		{name: "valid", mappings: rewrite.LocalMappings{{URL: `\.js$`, File: "app.js"}}},
+		{name: "empty URL", mappings: rewrite.LocalMappings{{Content: "foo"}}, expErr: true},
+		{name: "invalid URL", mappings: rewrite.LocalMappings{{URL: "("}}, expErr: true},
+		{name: "file and content", mappings: rewrite.LocalMappings{{URL: "foo", File: "foo", Content: "bar"}}, expErr: true},
+		{name: "invalid status code", mappings: rewrite.LocalMappings{{URL: "foo", StatusCode: 42}}, expErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.mappings.Validate()
+			if tt.expErr && !errors.Is(err, rewrite.ErrInvalidLocalMappings) {
+				t.Fatalf("expected `rewrite.ErrInvalidLocalMappings`, got: %v", err)
+			}
+
+			if !tt.expErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestResponder(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "app.js")
+	if err := os.WriteFile(path, []byte("console.log('mapped');"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &rewrite.Rewriter{}
+	r.SetLocalMappings(rewrite.LocalMappings{
+		{URL: `^https://example\.com/app\.js$`, File: path},
+		{URL: `/config$`, Content: `{"debug":true}`, ContentType: "application/json", StatusCode: http.StatusAccepted},
+		{URL: `/missing$`, File: filepath.Join(t.TempDir(), "missing")},
+	})
+
+	tests := []struct {
+		url            string
+		expStatusCode  int
+		expContentType string
+		expBody        string
+	}{
+		{
+			url:           "https://example.com/app.js",
+			expStatusCode: http.StatusOK,
+			// By file extension.
+			expContentType: mime.TypeByExtension(".js"),
+			expBody:        "console.log('mapped');",
+		},
+		{
+			url:            "https://example.com/api/config",
+			expStatusCode:  http.StatusAccepted,
+			expContentType: "application/json",
+			expBody:        `{"debug":true}`,
+		},
+		{
+			url:            "https://example.com/missing",
+			expStatusCode:  http.StatusInternalServerError,
+			expContentType: "text/plain; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		res := r.Responder(httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if res == nil {
+			t.Fatalf("%v: expected response", tt.url)
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.StatusCode != tt.expStatusCode || res.Header.Get("Content-Type") != tt.expContentType {
+			t.Errorf("%v: unexpected response (status code: %v, content type: %q)",
+				tt.url, res.StatusCode, res.Header.Get("Content-Type"))
+		}
+
+		if tt.expBody != "" && string(body) != tt.expBody {
+			t.Errorf("%v: expected body %q, got: %q", tt.url, tt.expBody, body)
+		}
+	}
+
+	if res := r.Responder(httptest.NewRequest(http.MethodGet, "https://example.com/app.js?v=2", nil)); res != nil {
+		t.Fatalf("expected no response for unmapped URL, got: %v", res.StatusCode)
+	}
+}
@@ -0,0 +1,143 @@
+package rewrite
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+var ErrInvalidProfiles = errcode.New(errcode.Invalid, "rewrite: invalid profiles")
+
+// Profile rewrites requests before they're sent with the sender or replayed,
+// e.g. to send requests captured in production to a staging environment, with
+// the credentials of staging.
+type Profile struct {
+	Name  string
+	Hosts []HostRule
+	// Headers that are set on each request, replacing existing values, e.g.
+	// `Authorization`.
+	Headers http.Header
+}
+
+// HostRule maps the host of requests to another host.
+type HostRule struct {
+	// Host (with port, if not the default port) to rewrite, e.g.
+	// `example.com`. Matched case-insensitively.
+	From string
+	// Host to send requests to instead, e.g. `staging.example.com:8443`.
+	To string
+	// Scheme of rewritten requests, e.g. `http`. Defaults to the scheme of the
+	// original request.
+	Scheme string
+}
+
+// Profiles are the rewrite profiles of a project.
+type Profiles struct {
+	Profiles []Profile
+	// Name of the profile that sender requests are rewritten with. Requests
+	// aren't rewritten if it's empty.
+	Active string
+}
+
+// Validate returns an error if names are empty or duplicate, if host rules are
+// incomplete, or if the active profile doesn't exist.
+func (profiles Profiles) Validate() error {
+	names := make(map[string]bool, len(profiles.Profiles))
+
+	for _, profile := range profiles.Profiles {
+		if profile.Name == "" {
+			return fmt.Errorf("%w: profile name must not be empty", ErrInvalidProfiles)
+		}
+
+		if names[profile.Name] {
+			return fmt.Errorf("%w: duplicate profile name %q", ErrInvalidProfiles, profile.Name)
+		}
+
+		names[profile.Name] = true
+
+		for _, rule := range profile.Hosts {
+			if rule.From == "" || rule.To == "" {
+				return fmt.Errorf("%w: host rules of profile %q must have a host to rewrite and a new host",
+					ErrInvalidProfiles, profile.Name)
+			}
+
+			if rule.Scheme != "" && rule.Scheme != "http" && rule.Scheme != "https" {
+				return fmt.Errorf("%w: unsupported scheme %q", ErrInvalidProfiles, rule.Scheme)
+			}
+		}
+	}
+
+	if profiles.Active != "" && !names[profiles.Active] {
+		return fmt.Errorf("%w: active profile %q doesn't exist", ErrInvalidProfiles, profiles.Active)
+	}
+
+	return nil
+}
+
+// Find returns the profile with name.
+func (profiles Profiles) Find(name string) (Profile, bool) {
+	for _, profile := range profiles.Profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+
+	return Profile{}, false
+}
+
+// ActiveProfile returns the active profile, if any.
+func (profiles Profiles) ActiveProfile() (Profile, bool) {
+	if profiles.Active == "" {
+		return Profile{}, false
+	}
+
+	return profiles.Find(profiles.Active)
+}
+
+// Apply returns u with the first matching host rule applied, and sets the
+// headers of the profile on header. The `Host` header is removed when the host
+// is rewritten, so that it doesn't point to the original host.
+func (profile Profile) Apply(u *url.URL, header http.Header) *url.URL {
+	for _, rule := range profile.Hosts {
+		if !strings.EqualFold(u.Host, rule.From) {
+			continue
+		}
+
+		rewritten := *u
+		rewritten.Host = rule.To
+
+		if rule.Scheme != "" {
+			rewritten.Scheme = rule.Scheme
+		}
+
+		u = &rewritten
+
+		header.Del("Host")
+
+		break
+	}
+
+	for key, values := range profile.Headers {
+		header[http.CanonicalHeaderKey(key)] = values
+	}
+
+	return u
+}
+
+// Profiles returns the rewrite profiles of the active project.
+func (r *Rewriter) Profiles() Profiles {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.profiles
+}
+
+func (r *Rewriter) SetProfiles(profiles Profiles) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.profiles = profiles
+}
@@ -0,0 +1,114 @@
+package rewrite_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dstotijn/hetty/pkg/rewrite"
+)
+
+func TestProfilesValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		profiles rewrite.Profiles
+		expErr   bool
+	}{
+		{
+			name: "valid",
+			profiles: rewrite.Profiles{
+				Profiles: []rewrite.Profile{{Name: "staging", Hosts: []rewrite.HostRule{{From: "a.com", To: "b.com"}}}},
+				Active:   "staging",
+			},
+		},
+		{
+			name:     "empty name",
+			profiles: rewrite.Profiles{Profiles: []rewrite.Profile{{}}},
+			expErr:   true,
+		},
+		{
+			name:     "duplicate name",
+			profiles: rewrite.Profiles{Profiles: []rewrite.Profile{{Name: "foo"}, {Name: "foo"}}},
+			expErr:   true,
+		},
+		{
+			name: "incomplete host rule",
+			profiles: rewrite.Profiles{
+				Profiles: []rewrite.Profile{{Name: "foo", Hosts: []rewrite.HostRule{{From: "a.com"}}}},
+			},
+			expErr: true,
+		},
+		{
+			name: "unsupported scheme",
+			profiles: rewrite.Profiles{
+				Profiles: []rewrite.Profile{{Name: "foo", Hosts: []rewrite.HostRule{{From: "a.com", To: "b.com", Scheme: "ftp"}}}},
+			},
+			expErr: true,
+		},
+		{
+			name:     "unknown active profile",
+			profiles: rewrite.Profiles{Profiles: []rewrite.Profile{{Name: "foo"}}, Active: "bar"},
+			expErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.profiles.Validate()
+			if tt.expErr && !errors.Is(err, rewrite.ErrInvalidProfiles) {
+				t.Fatalf("expected `rewrite.ErrInvalidProfiles`, got: %v", err)
+			}
+
+			if !tt.expErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestProfileApply(t *testing.T) {
+	t.Parallel()
+
+	profile := rewrite.Profile{
+		Hosts: []rewrite.HostRule{
+			{From: "EXAMPLE.com", To: "staging.example.com:8443"},
+			{From: "example.com", To: "other.example.com"},
+		},
+		Headers: http.Header{"authorization": []string{"Bearer staging"}},
+	}
+
+	u := &url.URL{Scheme: "https", Host: "example.com", Path: "/foo"}
+	header := http.Header{
+		"Authorization": []string{"Bearer prod"},
+		"Host":          []string{"example.com"},
+		"X-Foo":         []string{"bar"},
+	}
+
+	got := profile.Apply(u, header)
+
+	if exp := "https://staging.example.com:8443/foo"; got.String() != exp {
+		t.Errorf("incorrect URL (expected: %v, got: %v)", exp, got)
+	}
+
+	if u.Host != "example.com" {
+		t.Errorf("expected original URL to be unchanged (got: %v)", u)
+	}
+
+	expHeader := http.Header{
+		"Authorization": []string{"Bearer staging"},
+		"X-Foo":         []string{"bar"},
+	}
+
+	if diff := cmp.Diff(expHeader, header); diff != "" {
+		t.Fatalf("header not equal (-exp, +got):\n%v", diff)
+	}
+}
@@ -0,0 +1,187 @@
+package rewrite
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+var ErrInvalidRemoteMappings = errcode.New(errcode.Invalid, "rewrite: invalid remote mappings")
+
+// RemoteMapping sends matching proxied requests to another origin or path
+// ("map remote"), e.g. to test a production frontend against a local backend.
+// Both the original and the rewritten URL are logged. Empty fields keep the
+// value of the original request.
+type RemoteMapping struct {
+	// Regular expression matched against the full URL of requests, e.g.
+	// `^https://api\.example\.com/`.
+	URL string
+	// Scheme of rewritten requests: `http` or `https`.
+	Scheme string
+	// Hostname of rewritten requests, without port, e.g. `localhost`.
+	Host string
+	// Port of rewritten requests. Zero keeps the port of the original request,
+	// if any.
+	Port int
+	// Path of rewritten requests, replacing the original path. The query is kept.
+	Path string
+}
+
+// RemoteMappings are the remote mappings of a project. The first matching
+// mapping is used.
+type RemoteMappings []RemoteMapping
+
+// Validate returns an error if a URL pattern is empty or invalid, if a mapping
+// doesn't rewrite anything, or if a scheme or port is invalid.
+func (mappings RemoteMappings) Validate() error {
+	for _, mapping := range mappings {
+		if mapping.URL == "" {
+			return fmt.Errorf("%w: URL pattern must not be empty", ErrInvalidRemoteMappings)
+		}
+
+		if _, err := regexp.Compile(mapping.URL); err != nil {
+			return fmt.Errorf("%w: invalid URL pattern: %v", ErrInvalidRemoteMappings, err)
+		}
+
+		if mapping.Scheme == "" && mapping.Host == "" && mapping.Port == 0 && mapping.Path == "" {
+			return fmt.Errorf("%w: mapping for %q must set a scheme, host, port or path",
+				ErrInvalidRemoteMappings, mapping.URL)
+		}
+
+		if mapping.Scheme != "" && mapping.Scheme != "http" && mapping.Scheme != "https" {
+			return fmt.Errorf("%w: unsupported scheme %q", ErrInvalidRemoteMappings, mapping.Scheme)
+		}
+
+		if mapping.Port < 0 || mapping.Port > 65535 {
+			return fmt.Errorf("%w: invalid port %v", ErrInvalidRemoteMappings, mapping.Port)
+		}
+	}
+
+	return nil
+}
+
+type remoteMapping struct {
+	RemoteMapping
+	url *regexp.Regexp
+}
+
+// RemoteMappings returns the remote mappings of the active project.
+func (r *Rewriter) RemoteMappings() RemoteMappings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mappings := make(RemoteMappings, len(r.remoteMappings))
+	for i, mapping := range r.remoteMappings {
+		mappings[i] = mapping.RemoteMapping
+	}
+
+	return mappings
+}
+
+// SetRemoteMappings replaces the remote mappings. Mappings with an invalid URL
+// pattern are ignored, see `RemoteMappings.Validate`.
+func (r *Rewriter) SetRemoteMappings(mappings RemoteMappings) {
+	compiled := make([]remoteMapping, 0, len(mappings))
+
+	for _, mapping := range mappings {
+		if mapping.URL == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(mapping.URL)
+		if err != nil {
+			continue
+		}
+
+		compiled = append(compiled, remoteMapping{RemoteMapping: mapping, url: re})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.remoteMappings = compiled
+}
+
+// RequestModifier rewrites the URL of requests that match a remote mapping,
+// before next is called. The original URL is set on the request context, with
+// key `proxy.OriginalURLKey`. Register it after the request log modifier, so
+// that the rewritten request is logged.
+func (r *Rewriter) RequestModifier(next proxy.RequestModifyFunc) proxy.RequestModifyFunc {
+	return func(req *http.Request) {
+		r.mapRemote(req)
+		next(req)
+	}
+}
+
+func (r *Rewriter) mapRemote(req *http.Request) {
+	r.mu.RLock()
+	mappings := r.remoteMappings
+	r.mu.RUnlock()
+
+	if len(mappings) == 0 {
+		return
+	}
+
+	rawURL := req.URL.String()
+
+	for _, mapping := range mappings {
+		if !mapping.url.MatchString(rawURL) {
+			continue
+		}
+
+		original := *req.URL
+		req.URL = mapping.apply(req.URL)
+		// The `Host` header is set from the URL, so that it doesn't point to
+		// the original host.
+		req.Host = req.URL.Host
+
+		ctx := context.WithValue(req.Context(), proxy.OriginalURLKey, &original)
+		*req = *req.WithContext(ctx)
+
+		return
+	}
+}
+
+func (mapping remoteMapping) apply(u *url.URL) *url.URL {
+	rewritten := *u
+
+	if mapping.Scheme != "" {
+		rewritten.Scheme = mapping.Scheme
+	}
+
+	if mapping.Host != "" || mapping.Port != 0 {
+		hostname, port := u.Hostname(), u.Port()
+
+		if mapping.Host != "" {
+			hostname = mapping.Host
+		}
+
+		if mapping.Port != 0 {
+			port = strconv.Itoa(mapping.Port)
+		}
+
+		switch {
+		case port != "":
+			rewritten.Host = net.JoinHostPort(hostname, port)
+		case strings.Contains(hostname, ":"):
+			rewritten.Host = "[" + hostname + "]"
+		default:
+			rewritten.Host = hostname
+		}
+	}
+
+	if mapping.Path != "" {
+		rewritten.Path = mapping.Path
+		rewritten.RawPath = ""
+	}
+
+	return &rewritten
+}
@@ -0,0 +1,112 @@
+package rewrite_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/rewrite"
+)
+
+func TestRemoteMappingsValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		mappings rewrite.RemoteMappings
+		expErr   bool
+	}{
+		{name: "valid", mappings: rewrite.RemoteMappings{{URL: `^https://example\.com/`, Host: "localhost"}}},
+		{name: "empty URL", mappings: rewrite.RemoteMappings{{Host: "localhost"}}, expErr: true},
+		{name: "invalid URL", mappings: rewrite.RemoteMappings{{URL: "(", Host: "localhost"}}, expErr: true},
+		{name: "nothing rewritten", mappings: rewrite.RemoteMappings{{URL: "foo"}}, expErr: true},
+		{name: "invalid scheme", mappings: rewrite.RemoteMappings{{URL: "foo", Scheme: "ftp"}}, expErr: true},
+		{name: "invalid port", mappings: rewrite.RemoteMappings{{URL: "foo", Port: 70000}}, expErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.mappings.Validate()
+			if tt.expErr && !errors.Is(err, rewrite.ErrInvalidRemoteMappings) {
+				t.Fatalf("expected `rewrite.ErrInvalidRemoteMappings`, got: %v", err)
+			}
+
+			if !tt.expErr && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRemoteMappingRequestModifier(t *testing.T) {
+	t.Parallel()
+
+	r := &rewrite.Rewriter{}
+	r.SetRemoteMappings(rewrite.RemoteMappings{
+		{URL: `^https://api\.example\.com/`, Scheme: "http", Host: "localhost", Port: 8080},
+		{URL: `^https://example\.com:8443/old`, Path: "/new"},
+	})
+
+	tests := []struct {
+		name           string
+		url            string
+		expURL         string
+		expOriginalURL string
+	}{
+		{
+			name:           "origin",
+			url:            "https://api.example.com/v1/users?page=2",
+			expURL:         "http://localhost:8080/v1/users?page=2",
+			expOriginalURL: "https://api.example.com/v1/users?page=2",
+		},
+		{
+			name:           "path, keeping port",
+			url:            "https://example.com:8443/old?foo=bar",
+			expURL:         "https://example.com:8443/new?foo=bar",
+			expOriginalURL: "https://example.com:8443/old?foo=bar",
+		},
+		{
+			name:   "no match",
+			url:    "https://example.com/",
+			expURL: "https://example.com/",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+
+			var got *http.Request
+
+			r.RequestModifier(func(req *http.Request) { got = req })(req)
+
+			if got.URL.String() != tt.expURL {
+				t.Errorf("expected URL %q, got: %q", tt.expURL, got.URL)
+			}
+
+			if got.Host != got.URL.Host {
+				t.Errorf("expected host %q, got: %q", got.URL.Host, got.Host)
+			}
+
+			originalURL, _ := got.Context().Value(proxy.OriginalURLKey).(*url.URL)
+
+			switch {
+			case tt.expOriginalURL == "" && originalURL != nil:
+				t.Errorf("expected no original URL, got: %q", originalURL)
+			case tt.expOriginalURL != "" && (originalURL == nil || originalURL.String() != tt.expOriginalURL):
+				t.Errorf("expected original URL %q, got: %v", tt.expOriginalURL, originalURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package rewrite
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dstotijn/hetty/pkg/proxy"
+)
+
+// Presets are built-in response rewrites, toggleable per project.
+type Presets struct {
+	// JavaScript that is injected in HTML responses, as an inline `<script>`.
+	InjectScript string
+	// Remove `Content-Security-Policy` headers (including report only).
+	StripCSP bool
+	// Remove `Strict-Transport-Security` headers.
+	StripHSTS bool
+	// Remove the `Secure` attribute of `Set-Cookie` headers.
+	RemoveSecureCookieFlag bool
+}
+
+// Rewriter applies the presets of the active project to responses, serves its
+// local mappings (see `LocalMapping`), applies its remote mappings to requests
+// (see `RemoteMapping`), and holds its rewrite profiles for requests, see
+// `Profile`.
+type Rewriter struct {
+	presets        Presets
+	profiles       Profiles
+	localMappings  []localMapping
+	remoteMappings []remoteMapping
+	mw             proxy.ResponseModifyMiddleware
+	mu             sync.RWMutex
+}
+
+func (r *Rewriter) Presets() Presets {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.presets
+}
+
+func (r *Rewriter) SetPresets(presets Presets) {
+	var mws []proxy.ResponseModifyMiddleware
+
+	if presets.StripCSP {
+		mws = append(mws, StripHeaders("Content-Security-Policy", "Content-Security-Policy-Report-Only"))
+	}
+
+	if presets.StripHSTS {
+		mws = append(mws, StripHeaders("Strict-Transport-Security"))
+	}
+
+	if presets.RemoveSecureCookieFlag {
+		mws = append(mws, RemoveSecureCookieFlag)
+	}
+
+	if presets.InjectScript != "" {
+		mws = append(mws, InjectScript(presets.InjectScript))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.presets = presets
+	r.mw = Chain(mws...)
+}
+
+// ResponseModifier rewrites responses after next has been called. Register it
+// before the request log modifier, so that the original response is logged.
+func (r *Rewriter) ResponseModifier(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+	return func(res *http.Response) error {
+		if err := next(res); err != nil {
+			return err
+		}
+
+		r.mu.RLock()
+		mw := r.mw
+		r.mu.RUnlock()
+
+		if mw == nil {
+			return nil
+		}
+
+		return mw(func(*http.Response) error { return nil })(res)
+	}
+}
+
+// Chain composes middleware, in order.
+func Chain(mws ...proxy.ResponseModifyMiddleware) proxy.ResponseModifyMiddleware {
+	return func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+
+		return next
+	}
+}
+
+// StripHeaders returns middleware that removes response headers.
+func StripHeaders(keys ...string) proxy.ResponseModifyMiddleware {
+	return func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+		return func(res *http.Response) error {
+			if err := next(res); err != nil {
+				return err
+			}
+
+			cloneHeader(res)
+
+			for _, key := range keys {
+				res.Header.Del(key)
+			}
+
+			return nil
+		}
+	}
+}
+
+var secureAttrRegexp = regexp.MustCompile(`(?i);\s*secure\s*(;|$)`)
+
+// RemoveSecureCookieFlag is middleware that removes the `Secure` attribute of
+// cookies, so that they are also sent over plain HTTP.
+func RemoveSecureCookieFlag(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+	return func(res *http.Response) error {
+		if err := next(res); err != nil {
+			return err
+		}
+
+		cookies := res.Header.Values("Set-Cookie")
+		if len(cookies) == 0 {
+			return nil
+		}
+
+		cloneHeader(res)
+		res.Header.Del("Set-Cookie")
+
+		for _, cookie := range cookies {
+			res.Header.Add("Set-Cookie", secureAttrRegexp.ReplaceAllString(cookie, "$1"))
+		}
+
+		return nil
+	}
+}
+
+var headEndRegexp = regexp.MustCompile(`(?i)</head\s*>`)
+
+// InjectScript returns middleware that injects script in HTML responses, before
+// the end of the `<head>` element. When there is none, the script is prepended
+// to the document.
+func InjectScript(script string) proxy.ResponseModifyMiddleware {
+	tag := []byte("<script>" + script + "</script>")
+
+	return func(next proxy.ResponseModifyFunc) proxy.ResponseModifyFunc {
+		return func(res *http.Response) error {
+			if err := next(res); err != nil {
+				return err
+			}
+
+			if mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mediaType != "text/html" {
+				return nil
+			}
+
+			body, ok, err := readBody(res)
+			if err != nil {
+				return fmt.Errorf("rewrite: could not read response body: %w", err)
+			}
+
+			if !ok {
+				return nil
+			}
+
+			i := 0
+			if loc := headEndRegexp.FindIndex(body); loc != nil {
+				i = loc[0]
+			}
+
+			buf := bytes.NewBuffer(make([]byte, 0, len(body)+len(tag)))
+			buf.Write(body[:i])
+			buf.Write(tag)
+			buf.Write(body[i:])
+
+			setBody(res, buf.Bytes())
+
+			return nil
+		}
+	}
+}
+
+// readBody returns the decoded response body. It returns false when the body
+// has a content encoding that isn't supported.
+func readBody(res *http.Response) ([]byte, bool, error) {
+	if res.Body == nil {
+		return nil, true, nil
+	}
+
+	var r io.Reader = res.Body
+
+	switch strings.ToLower(res.Header.Get("Content-Encoding")) {
+	case "", "identity":
+	case "gzip":
+		gzipReader, err := gzip.NewReader(res.Body)
+		if err != nil {
+			return nil, false, err
+		}
+		defer gzipReader.Close()
+
+		r = gzipReader
+	default:
+		return nil, false, nil
+	}
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, false, err
+	}
+
+	res.Body.Close()
+
+	return body, true, nil
+}
+
+// setBody replaces the response body with an uncompressed body.
+func setBody(res *http.Response, body []byte) {
+	cloneHeader(res)
+	res.Header.Del("Content-Encoding")
+	res.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	res.ContentLength = int64(len(body))
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+}
+
+// cloneHeader replaces the response header with a copy before modifying it,
+// because modifiers that ran earlier (e.g. the request log) may still read it.
+func cloneHeader(res *http.Response) {
+	if res.Header == nil {
+		res.Header = make(http.Header)
+		return
+	}
+
+	res.Header = res.Header.Clone()
+}
@@ -0,0 +1,149 @@
+package rewrite_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/rewrite"
+)
+
+func nop(*http.Response) error { return nil }
+
+func newResponse(header http.Header, body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestInjectScript(t *testing.T) {
+	t.Parallel()
+
+	gzipped := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(gzipped)
+	_, _ = gzipWriter.Write([]byte("<html><head></head></html>"))
+	gzipWriter.Close()
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    string
+		expBody string
+	}{
+		{
+			name:    "before end of head",
+			header:  http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+			body:    "<html><head><title>foo</title></HEAD><body></body></html>",
+			expBody: "<html><head><title>foo</title><script>alert(1)</script></HEAD><body></body></html>",
+		},
+		{
+			name:    "without head",
+			header:  http.Header{"Content-Type": []string{"text/html"}},
+			body:    "<p>foo</p>",
+			expBody: "<script>alert(1)</script><p>foo</p>",
+		},
+		{
+			name:    "gzip encoded",
+			header:  http.Header{"Content-Type": []string{"text/html"}, "Content-Encoding": []string{"gzip"}},
+			body:    gzipped.String(),
+			expBody: "<html><head><script>alert(1)</script></head></html>",
+		},
+		{
+			name:    "not HTML",
+			header:  http.Header{"Content-Type": []string{"application/json"}},
+			body:    `{"foo":"bar"}`,
+			expBody: `{"foo":"bar"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			res := newResponse(tt.header, tt.body)
+
+			if err := rewrite.InjectScript("alert(1)")(nop)(res); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expBody, string(body)); diff != "" {
+				t.Fatalf("body not equal (-exp, +got):\n%v", diff)
+			}
+
+			if enc := res.Header.Get("Content-Encoding"); enc != "" {
+				t.Fatalf("expected empty `Content-Encoding` header, got: %v", enc)
+			}
+		})
+	}
+}
+
+func TestRemoveSecureCookieFlag(t *testing.T) {
+	t.Parallel()
+
+	res := newResponse(http.Header{
+		"Set-Cookie": []string{
+			"foo=bar; Secure; HttpOnly",
+			"baz=qux; Path=/; secure",
+			"secure=yes",
+		},
+	}, "")
+
+	if err := rewrite.RemoveSecureCookieFlag(nop)(res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []string{"foo=bar; HttpOnly", "baz=qux; Path=/", "secure=yes"}
+
+	if diff := cmp.Diff(exp, res.Header.Values("Set-Cookie")); diff != "" {
+		t.Fatalf("cookies not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestRewriter(t *testing.T) {
+	t.Parallel()
+
+	rewriter := &rewrite.Rewriter{}
+	rewriter.SetPresets(rewrite.Presets{StripCSP: true, StripHSTS: true})
+
+	header := http.Header{
+		"Content-Security-Policy":   []string{"default-src 'self'"},
+		"Strict-Transport-Security": []string{"max-age=31536000"},
+		"X-Foo":                     []string{"bar"},
+	}
+	res := newResponse(header, "")
+
+	// The next modifier (e.g. the request log) sees the original response.
+	var seen http.Header
+
+	next := proxy.ResponseModifyFunc(func(res *http.Response) error {
+		seen = res.Header
+		return nil
+	})
+
+	if err := rewriter.ResponseModifier(next)(res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff(http.Header{"X-Foo": []string{"bar"}}, res.Header); diff != "" {
+		t.Fatalf("header not equal (-exp, +got):\n%v", diff)
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("expected header seen by next modifier to be unmodified, got: %v", seen)
+	}
+}
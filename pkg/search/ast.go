@@ -4,6 +4,7 @@ import (
 	"encoding/gob"
 	"regexp"
 	"strings"
+	"time"
 )
 
 type Expression interface {
@@ -53,6 +54,16 @@ func (sl StringLiteral) String() string {
 	return sl.Value
 }
 
+// LastExpression matches records of the last duration, by the time of
+// `TimeRecord`, e.g. `last 15m`.
+type LastExpression struct {
+	Duration time.Duration
+}
+
+func (le LastExpression) String() string {
+	return "(last " + le.Duration.String() + ")"
+}
+
 type RegexpLiteral struct {
 	*regexp.Regexp
 }
@@ -76,5 +87,6 @@ func init() {
 	gob.Register(PrefixExpression{})
 	gob.Register(InfixExpression{})
 	gob.Register(StringLiteral{})
+	gob.Register(LastExpression{})
 	gob.Register(RegexpLiteral{})
 }
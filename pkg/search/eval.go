@@ -0,0 +1,354 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Record is a value that search expressions can be evaluated for, e.g. a
+// request log or sender request.
+type Record interface {
+	// SearchValue returns the value of a key, e.g. `req.url`, or false if the
+	// record has no such key. Unknown keys are compared as is.
+	SearchValue(key string) (string, bool)
+	// SearchKeys returns the keys whose values a string literal without
+	// operator is matched against.
+	SearchKeys() []string
+}
+
+// Match returns true if expr evaluates to true for rec.
+func Match(expr Expression, rec Record) (bool, error) {
+	switch e := expr.(type) {
+	case PrefixExpression:
+		return matchPrefixExpr(e, rec)
+	case InfixExpression:
+		return matchInfixExpr(e, rec)
+	case StringLiteral:
+		return matchStringLiteral(e, rec), nil
+	case LastExpression:
+		return matchLastExpr(e, rec), nil
+	default:
+		return false, fmt.Errorf("expression type (%T) not supported", expr)
+	}
+}
+
+// Keys returns the keys that expr compares, e.g. to find out which parts of
+// a record must be loaded to evaluate it. A string literal without operator
+// matches on all keys, in which case all is true.
+func Keys(expr Expression) (keys []string, all bool) {
+	switch e := expr.(type) {
+	case PrefixExpression:
+		return Keys(e.Right)
+	case InfixExpression:
+		if e.Operator == TokOpAnd || e.Operator == TokOpOr {
+			leftKeys, leftAll := Keys(e.Left)
+			rightKeys, rightAll := Keys(e.Right)
+
+			return append(leftKeys, rightKeys...), leftAll || rightAll
+		}
+
+		// Both operands of comparisons are mapped to values, see `mappedValue`.
+		for _, operand := range []Expression{e.Left, e.Right} {
+			if strLiteral, ok := operand.(StringLiteral); ok {
+				keys = append(keys, strLiteral.Value)
+			}
+		}
+
+		return keys, false
+	case StringLiteral:
+		return nil, true
+	default:
+		return nil, false
+	}
+}
+
+// Value returns the value of expr for rec, e.g. for showing it in a column:
+//   - the value of a key, for a string literal that is a key of rec;
+//   - the first submatch (or the match itself, if the regular expression has no
+//     groups) of a regular expression operator, or an empty string if it
+//     doesn't match;
+//   - otherwise, the result of `Match` as "true" or "false".
+func Value(expr Expression, rec Record) (string, error) {
+	switch e := expr.(type) {
+	case StringLiteral:
+		if value, ok := rec.SearchValue(e.Value); ok {
+			return value, nil
+		}
+	case InfixExpression:
+		left, ok := e.Left.(StringLiteral)
+		if !ok || e.Operator != TokOpRe {
+			break
+		}
+
+		var re *regexp.Regexp
+
+		switch right := e.Right.(type) {
+		case *regexp.Regexp:
+			re = right
+		case RegexpLiteral:
+			re = right.Regexp
+		default:
+			return "", errors.New("right operand must be a regular expression")
+		}
+
+		match := re.FindStringSubmatch(mappedValue(left.Value, rec))
+
+		switch {
+		case match == nil:
+			return "", nil
+		case len(match) > 1:
+			return match[1], nil
+		default:
+			return match[0], nil
+		}
+	}
+
+	match, err := Match(expr, rec)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatBool(match), nil
+}
+
+func matchPrefixExpr(expr PrefixExpression, rec Record) (bool, error) {
+	switch expr.Operator {
+	case TokOpNot:
+		match, err := Match(expr.Right, rec)
+		if err != nil {
+			return false, err
+		}
+
+		return !match, nil
+	default:
+		return false, errors.New("operator is not supported")
+	}
+}
+
+func matchInfixExpr(expr InfixExpression, rec Record) (bool, error) {
+	switch expr.Operator {
+	case TokOpAnd:
+		left, err := Match(expr.Left, rec)
+		if err != nil {
+			return false, err
+		}
+
+		right, err := Match(expr.Right, rec)
+		if err != nil {
+			return false, err
+		}
+
+		return left && right, nil
+	case TokOpOr:
+		left, err := Match(expr.Left, rec)
+		if err != nil {
+			return false, err
+		}
+
+		right, err := Match(expr.Right, rec)
+		if err != nil {
+			return false, err
+		}
+
+		return left || right, nil
+	}
+
+	left, ok := expr.Left.(StringLiteral)
+	if !ok {
+		return false, errors.New("left operand must be a string literal")
+	}
+
+	leftVal := mappedValue(left.Value, rec)
+
+	if expr.Operator == TokOpRe || expr.Operator == TokOpNotRe {
+		var right *regexp.Regexp
+
+		switch re := expr.Right.(type) {
+		case *regexp.Regexp:
+			right = re
+		case RegexpLiteral:
+			right = re.Regexp
+		default:
+			return false, errors.New("right operand must be a regular expression")
+		}
+
+		if expr.Operator == TokOpRe {
+			return right.MatchString(leftVal), nil
+		}
+
+		return !right.MatchString(leftVal), nil
+	}
+
+	right, ok := expr.Right.(StringLiteral)
+	if !ok {
+		return false, errors.New("right operand must be a string literal")
+	}
+
+	rightVal := mappedValue(right.Value, rec)
+
+	switch expr.Operator {
+	case TokOpEq:
+		return leftVal == rightVal, nil
+	case TokOpNotEq:
+		return leftVal != rightVal, nil
+	case TokOpGt:
+		return compareValues(leftVal, rightVal) > 0, nil
+	case TokOpLt:
+		return compareValues(leftVal, rightVal) < 0, nil
+	case TokOpGtEq:
+		return compareValues(leftVal, rightVal) >= 0, nil
+	case TokOpLtEq:
+		return compareValues(leftVal, rightVal) <= 0, nil
+	default:
+		return false, errors.New("unsupported operator")
+	}
+}
+
+// compareValues compares a and b as times if both are, see `parseTime`, and
+// otherwise as strings.
+func compareValues(a, b string) int {
+	// TODO(?) attempt to parse as int.
+	if aTime, ok := parseTime(a); ok {
+		if bTime, ok := parseTime(b); ok {
+			switch {
+			case aTime.Before(bTime):
+				return -1
+			case aTime.After(bTime):
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	return strings.Compare(a, b)
+}
+
+// mappedValue returns the value of s if it's a key of rec, otherwise s itself.
+func mappedValue(s string, rec Record) string {
+	if value, ok := rec.SearchValue(s); ok {
+		return value
+	}
+
+	return s
+}
+
+func matchStringLiteral(strLiteral StringLiteral, rec Record) bool {
+	needle := strings.ToLower(strLiteral.Value)
+
+	for _, key := range rec.SearchKeys() {
+		value, _ := rec.SearchValue(key)
+
+		if strings.Contains(strings.ToLower(value), needle) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Hit is the position of a match of a search expression in the value of a key.
+type Hit struct {
+	Key string
+	// Byte offsets of the match in the value.
+	Start, End int
+}
+
+// Hits returns the positions of matches of expr in the values of keys, e.g. to
+// highlight them, sorted by key (in the order of keys) and offset. Matches of
+// negated expressions aren't hits. At most max hits are returned per key.
+func Hits(expr Expression, rec Record, keys []string, max int) []Hit {
+	patterns := make(map[string][]*regexp.Regexp)
+	collectHitPatterns(expr, rec, patterns)
+
+	var hits []Hit
+
+	for _, key := range keys {
+		value, ok := rec.SearchValue(key)
+		if !ok || value == "" {
+			continue
+		}
+
+		var keyHits []Hit
+
+		for _, re := range append(patterns[key], patterns[""]...) {
+			for _, loc := range re.FindAllStringIndex(value, max) {
+				if loc[0] == loc[1] {
+					continue
+				}
+
+				keyHits = append(keyHits, Hit{Key: key, Start: loc[0], End: loc[1]})
+			}
+		}
+
+		sort.Slice(keyHits, func(i, j int) bool {
+			if keyHits[i].Start != keyHits[j].Start {
+				return keyHits[i].Start < keyHits[j].Start
+			}
+
+			return keyHits[i].End < keyHits[j].End
+		})
+
+		n := 0
+
+		for i, hit := range keyHits {
+			// Patterns of different sub-expressions can have the same match.
+			if i > 0 && hit == keyHits[i-1] {
+				continue
+			}
+
+			if n == max {
+				break
+			}
+
+			hits = append(hits, hit)
+			n++
+		}
+	}
+
+	return hits
+}
+
+// collectHitPatterns adds patterns for the matches of expr to patterns, by key.
+// Patterns of string literals without operator, which match on all keys, are
+// added for the empty key.
+func collectHitPatterns(expr Expression, rec Record, patterns map[string][]*regexp.Regexp) {
+	switch e := expr.(type) {
+	case InfixExpression:
+		switch e.Operator {
+		case TokOpAnd, TokOpOr:
+			collectHitPatterns(e.Left, rec, patterns)
+			collectHitPatterns(e.Right, rec, patterns)
+
+			return
+		}
+
+		left, ok := e.Left.(StringLiteral)
+		if !ok {
+			return
+		}
+
+		switch e.Operator {
+		case TokOpRe:
+			switch re := e.Right.(type) {
+			case *regexp.Regexp:
+				patterns[left.Value] = append(patterns[left.Value], re)
+			case RegexpLiteral:
+				patterns[left.Value] = append(patterns[left.Value], re.Regexp)
+			}
+		case TokOpEq:
+			if right, ok := e.Right.(StringLiteral); ok {
+				value := regexp.QuoteMeta(mappedValue(right.Value, rec))
+				patterns[left.Value] = append(patterns[left.Value], regexp.MustCompile("^"+value+"$"))
+			}
+		}
+	case StringLiteral:
+		if e.Value != "" {
+			patterns[""] = append(patterns[""], regexp.MustCompile("(?i)"+regexp.QuoteMeta(e.Value)))
+		}
+	}
+}
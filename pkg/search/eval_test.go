@@ -0,0 +1,265 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type mapRecord map[string]string
+
+func (rec mapRecord) SearchValue(key string) (string, bool) {
+	value, ok := rec[key]
+	return value, ok
+}
+
+func (rec mapRecord) SearchKeys() []string {
+	keys := make([]string, 0, len(rec))
+	for key := range rec {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	rec := mapRecord{
+		"req.method": "GET",
+		"req.body":   "foobar",
+		"res.body":   "Hello",
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedMatch bool
+		expectedError error
+	}{
+		{
+			name:          "equal operator, match",
+			query:         "req.method = GET",
+			expectedMatch: true,
+		},
+		{
+			name:          "equal operator, both operands mapped",
+			query:         "req.body = req.body",
+			expectedMatch: true,
+		},
+		{
+			name:          "regular expression operator, match",
+			query:         `req.body =~ "^foo"`,
+			expectedMatch: true,
+		},
+		{
+			name:          "and operator, no match",
+			query:         "req.method = GET AND req.body = bar",
+			expectedMatch: false,
+		},
+		{
+			name:          "not operator, match",
+			query:         "NOT (req.method = POST)",
+			expectedMatch: true,
+		},
+		{
+			name:          "string literal, case insensitive match",
+			query:         "hello",
+			expectedMatch: true,
+		},
+		{
+			name:          "string literal, no match",
+			query:         "baz",
+			expectedMatch: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error parsing query: %v", err)
+			}
+
+			got, err := Match(expr, rec)
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error: %v, got: %v", tt.expectedError, err)
+			}
+
+			if got != tt.expectedMatch {
+				t.Errorf("expected match result: %v, got: %v", tt.expectedMatch, got)
+			}
+		})
+	}
+}
+
+func TestKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedKeys []string
+		expectedAll  bool
+	}{
+		{
+			name:         "comparisons",
+			query:        `req.method = GET OR NOT (res.body =~ "foo")`,
+			expectedKeys: []string{"req.method", "GET", "res.body"},
+		},
+		{
+			name:         "string literal",
+			query:        "req.method = GET AND foo",
+			expectedKeys: []string{"req.method", "GET"},
+			expectedAll:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error parsing query: %v", err)
+			}
+
+			keys, all := Keys(expr)
+
+			if !reflect.DeepEqual(tt.expectedKeys, keys) {
+				t.Errorf("expected keys: %v, got: %v", tt.expectedKeys, keys)
+			}
+
+			if all != tt.expectedAll {
+				t.Errorf("expected all: %v, got: %v", tt.expectedAll, all)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	t.Parallel()
+
+	rec := mapRecord{
+		"req.method": "GET",
+		"req.url":    "https://example.com/users/42?tab=posts",
+	}
+
+	tests := []struct {
+		query         string
+		expectedValue string
+	}{
+		{query: "req.method", expectedValue: "GET"},
+		{query: `req.url =~ "users/([0-9]+)"`, expectedValue: "42"},
+		{query: `req.url =~ "tab=[a-z]+"`, expectedValue: "tab=posts"},
+		{query: `req.url =~ "^http:"`, expectedValue: ""},
+		{query: "req.method = GET", expectedValue: "true"},
+		{query: "example", expectedValue: "true"},
+		{query: "NOT example", expectedValue: "false"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error parsing query: %v", err)
+			}
+
+			value, err := Value(expr, rec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if value != tt.expectedValue {
+				t.Errorf("expected value: %q, got: %q", tt.expectedValue, value)
+			}
+		})
+	}
+}
+
+func TestHits(t *testing.T) {
+	t.Parallel()
+
+	rec := mapRecord{
+		"req.method": "GET",
+		"req.body":   "foo bar FOO",
+		"res.body":   "foobar",
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		max     int
+		expHits []Hit
+	}{
+		{
+			name:  "string literal, case insensitive",
+			query: "foo",
+			max:   10,
+			expHits: []Hit{
+				{Key: "req.body", Start: 0, End: 3},
+				{Key: "req.body", Start: 8, End: 11},
+				{Key: "res.body", Start: 0, End: 3},
+			},
+		},
+		{
+			name:    "regular expression on key",
+			query:   `req.method = GET AND res.body =~ "o+b"`,
+			max:     10,
+			expHits: []Hit{{Key: "res.body", Start: 1, End: 4}},
+		},
+		{
+			name:    "equal operator",
+			query:   "res.body = foobar",
+			max:     10,
+			expHits: []Hit{{Key: "res.body", Start: 0, End: 6}},
+		},
+		{
+			name:    "negated expression",
+			query:   "NOT (foo)",
+			max:     10,
+			expHits: nil,
+		},
+		{
+			name:  "duplicate matches and maximum",
+			query: "foo OR foo",
+			max:   1,
+			expHits: []Hit{
+				{Key: "req.body", Start: 0, End: 3},
+				{Key: "res.body", Start: 0, End: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error parsing query: %v", err)
+			}
+
+			got := Hits(expr, rec, []string{"req.body", "res.body"}, tt.max)
+
+			if !reflect.DeepEqual(tt.expHits, got) {
+				t.Errorf("expected hits: %v, got: %v", tt.expHits, got)
+			}
+		})
+	}
+}
@@ -11,6 +11,9 @@ type TokenType int
 type Token struct {
 	Type    TokenType
 	Literal string
+	// Byte offset of the token in the input, including the delimiter of a
+	// quoted string.
+	Pos int
 }
 
 const eof = 0
@@ -94,6 +97,23 @@ func (l *Lexer) Next() Token {
 	return <-l.tokens
 }
 
+// operators are the token types of operators, in order of `Operators`.
+var operators = []TokenType{
+	TokOpEq, TokOpNotEq, TokOpGt, TokOpLt, TokOpGtEq, TokOpLtEq, TokOpRe, TokOpNotRe,
+	TokOpNot, TokOpAnd, TokOpOr,
+}
+
+// Operators returns the comparison and boolean operators of search
+// expressions, e.g. for autocompletion.
+func Operators() []string {
+	ops := make([]string, len(operators))
+	for i, op := range operators {
+		ops[i] = op.String()
+	}
+
+	return ops
+}
+
 func (tt TokenType) String() string {
 	if typeString, ok := tokenTypeStrings[tt]; ok {
 		return typeString
@@ -122,9 +142,14 @@ func (l *Lexer) read() (r rune) {
 }
 
 func (l *Lexer) emit(tokenType TokenType) {
+	l.emitAt(tokenType, l.start)
+}
+
+func (l *Lexer) emitAt(tokenType TokenType, pos int) {
 	l.tokens <- Token{
 		Type:    tokenType,
 		Literal: l.input[l.start:l.pos],
+		Pos:     pos,
 	}
 
 	l.start = l.pos
@@ -143,10 +168,12 @@ func (l *Lexer) backup() {
 	l.pos -= l.width
 }
 
-func (l *Lexer) errorf(format string, args ...interface{}) stateFn {
+// errorf emits an invalid token, with the error message as literal, at pos.
+func (l *Lexer) errorf(pos int, format string, args ...interface{}) stateFn {
 	l.tokens <- Token{
 		Type:    TokInvalid,
 		Literal: fmt.Sprintf(format, args...),
+		Pos:     pos,
 	}
 
 	return nil
@@ -171,7 +198,7 @@ func begin(l *Lexer) stateFn {
 		case '~':
 			l.emit(TokOpNotRe)
 		default:
-			return l.errorf("invalid rune %v", r)
+			return l.errorf(l.start, `invalid operator "!", expected "!=" or "!~"`)
 		}
 
 		return begin
@@ -215,17 +242,19 @@ func begin(l *Lexer) stateFn {
 }
 
 func (l *Lexer) delimString(delim rune) stateFn {
+	start := l.start
+
 	// Ignore the start delimiter rune.
 	l.ignore()
 
 	for r := l.read(); r != delim; r = l.read() {
 		if r == eof {
-			return l.errorf("unexpected EOF, unclosed delimiter")
+			return l.errorf(start, "unexpected EOF, unclosed delimiter %q", delim)
 		}
 	}
 	// Don't include the end delimiter in emitted token.
 	l.backup()
-	l.emit(TokString)
+	l.emitAt(TokString, start)
 	// Skip end delimiter.
 	l.skip()
 
@@ -14,57 +14,57 @@ func TestNextToken(t *testing.T) {
 			name:  "unquoted string",
 			input: "foo bar",
 			expected: []Token{
-				{TokString, "foo"},
-				{TokString, "bar"},
-				{TokEOF, ""},
+				{Type: TokString, Literal: "foo"},
+				{Type: TokString, Literal: "bar"},
+				{Type: TokEOF, Literal: ""},
 			},
 		},
 		{
 			name:  "quoted string",
 			input: `"foo bar" "baz"`,
 			expected: []Token{
-				{TokString, "foo bar"},
-				{TokString, "baz"},
-				{TokEOF, ""},
+				{Type: TokString, Literal: "foo bar"},
+				{Type: TokString, Literal: "baz"},
+				{Type: TokEOF, Literal: ""},
 			},
 		},
 		{
 			name:  "boolean operator token types",
 			input: "NOT AND OR",
 			expected: []Token{
-				{TokOpNot, "NOT"},
-				{TokOpAnd, "AND"},
-				{TokOpOr, "OR"},
-				{TokEOF, ""},
+				{Type: TokOpNot, Literal: "NOT"},
+				{Type: TokOpAnd, Literal: "AND"},
+				{Type: TokOpOr, Literal: "OR"},
+				{Type: TokEOF, Literal: ""},
 			},
 		},
 		{
 			name:  "comparison operator token types",
 			input: `= != < > <= >= =~ !~`,
 			expected: []Token{
-				{TokOpEq, "="},
-				{TokOpNotEq, "!="},
-				{TokOpLt, "<"},
-				{TokOpGt, ">"},
-				{TokOpLtEq, "<="},
-				{TokOpGtEq, ">="},
-				{TokOpRe, "=~"},
-				{TokOpNotRe, "!~"},
-				{TokEOF, ""},
+				{Type: TokOpEq, Literal: "="},
+				{Type: TokOpNotEq, Literal: "!="},
+				{Type: TokOpLt, Literal: "<"},
+				{Type: TokOpGt, Literal: ">"},
+				{Type: TokOpLtEq, Literal: "<="},
+				{Type: TokOpGtEq, Literal: ">="},
+				{Type: TokOpRe, Literal: "=~"},
+				{Type: TokOpNotRe, Literal: "!~"},
+				{Type: TokEOF, Literal: ""},
 			},
 		},
 		{
 			name:  "with parentheses",
 			input: "(foo AND bar) OR baz",
 			expected: []Token{
-				{TokParenOpen, "("},
-				{TokString, "foo"},
-				{TokOpAnd, "AND"},
-				{TokString, "bar"},
-				{TokParenClose, ")"},
-				{TokOpOr, "OR"},
-				{TokString, "baz"},
-				{TokEOF, ""},
+				{Type: TokParenOpen, Literal: "("},
+				{Type: TokString, Literal: "foo"},
+				{Type: TokOpAnd, Literal: "AND"},
+				{Type: TokString, Literal: "bar"},
+				{Type: TokParenClose, Literal: ")"},
+				{Type: TokOpOr, Literal: "OR"},
+				{Type: TokString, Literal: "baz"},
+				{Type: TokEOF, Literal: ""},
 			},
 		},
 	}
@@ -89,3 +89,20 @@ func TestNextToken(t *testing.T) {
 		})
 	}
 }
+
+func TestOperators(t *testing.T) {
+	t.Parallel()
+
+	for _, op := range Operators() {
+		l := NewLexer("foo " + op + " bar")
+		l.Next()
+
+		tok := l.Next()
+		if tok.Literal != op {
+			t.Errorf("expected operator %q to be lexed, got: %+v", op, tok)
+		}
+
+		for range l.tokens {
+		}
+	}
+}
@@ -3,6 +3,10 @@ package search
 import (
 	"fmt"
 	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
 )
 
 type precedence int
@@ -68,6 +72,54 @@ func init() {
 	prefixParsers[TokParenOpen] = parseGroupedExpression
 }
 
+// expectedOperand are the tokens that an operand can start with.
+var expectedOperand = []string{"string", "NOT", `"("`}
+
+// ParseError is an error parsing a search expression, with its position in the
+// input, so that it can be pointed out to users.
+type ParseError struct {
+	// Byte offset in the input.
+	Pos int
+	Msg string
+	// Tokens that are valid at the position, if known.
+	Expected []string
+	// Keys that are similar to an unknown key at the position, see
+	// `CheckKeys`.
+	Suggestions []string
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("search: %v at offset %v", e.Msg, e.Pos)
+
+	if len(e.Expected) > 0 {
+		msg += fmt.Sprintf(", expected %v", strings.Join(e.Expected, ", "))
+	}
+
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf(" (did you mean %v?)", strings.Join(e.Suggestions, ", "))
+	}
+
+	return msg
+}
+
+// ErrorCode returns `errcode.Invalid`, see `errcode.Coder`.
+func (e *ParseError) ErrorCode() errcode.Code {
+	return errcode.Invalid
+}
+
+// unexpectedToken returns a parse error for tok. Invalid tokens have the error
+// of the lexer as literal.
+func unexpectedToken(tok Token, expected ...string) *ParseError {
+	switch tok.Type {
+	case TokInvalid:
+		return &ParseError{Pos: tok.Pos, Msg: tok.Literal}
+	case TokEOF:
+		return &ParseError{Pos: tok.Pos, Msg: "unexpected EOF", Expected: expected}
+	default:
+		return &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("unexpected %q", tok.Literal), Expected: expected}
+	}
+}
+
 type Parser struct {
 	l    *Lexer
 	cur  Token
@@ -82,13 +134,14 @@ func NewParser(l *Lexer) *Parser {
 	return p
 }
 
+// ParseQuery parses a search expression. Errors are of type `*ParseError`.
 func ParseQuery(input string) (expr Expression, err error) {
 	p := &Parser{l: NewLexer(input)}
 	p.nextToken()
 	p.nextToken()
 
 	if p.curTokenIs(TokEOF) {
-		return nil, fmt.Errorf("search: unexpected EOF")
+		return nil, unexpectedToken(p.cur, expectedOperand...)
 	}
 
 	for !p.curTokenIs(TokEOF) {
@@ -96,7 +149,7 @@ func ParseQuery(input string) (expr Expression, err error) {
 
 		switch {
 		case err != nil:
-			return nil, fmt.Errorf("search: could not parse expression: %w", err)
+			return nil, err
 		case expr == nil:
 			expr = right
 		default:
@@ -145,12 +198,12 @@ func (p *Parser) peekPrecedence() precedence {
 func (p *Parser) parseExpression(prec precedence) (Expression, error) {
 	prefixParser, ok := prefixParsers[p.cur.Type]
 	if !ok {
-		return nil, fmt.Errorf("no prefix parse function for %v found", p.cur.Type)
+		return nil, unexpectedToken(p.cur, expectedOperand...)
 	}
 
 	expr, err := prefixParser(p)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse expression prefix: %w", err)
+		return nil, err
 	}
 
 	for !p.peekTokenIs(eof) && prec < p.peekPrecedence() {
@@ -163,7 +216,7 @@ func (p *Parser) parseExpression(prec precedence) (Expression, error) {
 
 		expr, err = infixParser(p, expr)
 		if err != nil {
-			return nil, fmt.Errorf("could not parse infix expression: %w", err)
+			return nil, err
 		}
 	}
 
@@ -179,7 +232,7 @@ func parsePrefixExpression(p *Parser) (Expression, error) {
 
 	right, err := p.parseExpression(precPrefix)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse expression for right operand: %w", err)
+		return nil, err
 	}
 
 	expr.Right = right
@@ -196,16 +249,18 @@ func parseInfixExpression(p *Parser, left Expression) (Expression, error) {
 	prec := p.curPrecedence()
 	p.nextToken()
 
+	rightPos := p.cur.Pos
+
 	right, err := p.parseExpression(prec)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse expression for right operand: %w", err)
+		return nil, err
 	}
 
 	if expr.Operator == TokOpRe || expr.Operator == TokOpNotRe {
 		if rightStr, ok := right.(StringLiteral); ok {
 			re, err := regexp.Compile(rightStr.Value)
 			if err != nil {
-				return nil, fmt.Errorf("could not compile regular expression %q: %w", rightStr.Value, err)
+				return nil, &ParseError{Pos: rightPos, Msg: fmt.Sprintf("invalid regular expression: %v", err)}
 			}
 
 			right = re
@@ -217,26 +272,48 @@ func parseInfixExpression(p *Parser, left Expression) (Expression, error) {
 	return expr, nil
 }
 
+// parseStringLiteral parses a string, or a `last` expression if "last" is
+// followed by a positive duration. Otherwise, e.g. with `last 404` or `last
+// -1h`, "last" is a string like any other.
 func parseStringLiteral(p *Parser) (Expression, error) {
+	if p.cur.Literal == "last" && p.peekTokenIs(TokString) {
+		if d, err := parseDuration(p.peek.Literal); err == nil && d > 0 {
+			return parseLastExpression(p, d), nil
+		}
+	}
+
 	return StringLiteral{Value: p.cur.Literal}, nil
 }
 
+// parseLastExpression parses `last` followed by duration d, e.g. `last 15m`.
+func parseLastExpression(p *Parser, d time.Duration) Expression {
+	p.nextToken()
+
+	return LastExpression{Duration: d}
+}
+
 func parseGroupedExpression(p *Parser) (Expression, error) {
+	openPos := p.cur.Pos
+
 	p.nextToken()
 
 	expr, err := p.parseExpression(precLowest)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse grouped expression: %w", err)
+		return nil, err
 	}
 
 	for p.nextToken(); !p.curTokenIs(TokParenClose); p.nextToken() {
 		if p.curTokenIs(TokEOF) {
-			return nil, fmt.Errorf("unexpected EOF: unmatched parentheses")
+			return nil, &ParseError{
+				Pos:      p.cur.Pos,
+				Msg:      fmt.Sprintf("unexpected EOF, unclosed parenthesis at offset %v", openPos),
+				Expected: []string{`")"`},
+			}
 		}
 
 		right, err := p.parseExpression(precLowest)
 		if err != nil {
-			return nil, fmt.Errorf("could not parse expression: %w", err)
+			return nil, err
 		}
 
 		expr = InfixExpression{
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"regexp"
 	"testing"
+	"time"
 )
 
 func TestParseQuery(t *testing.T) {
@@ -20,7 +21,7 @@ func TestParseQuery(t *testing.T) {
 			name:               "empty query",
 			input:              "",
 			expectedExpression: nil,
-			expectedError:      errors.New("search: unexpected EOF"),
+			expectedError:      errors.New(`search: unexpected EOF at offset 0, expected string, NOT, "("`),
 		},
 		{
 			name:               "string literal expression",
@@ -220,6 +221,62 @@ func TestParseQuery(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			name:  "last expression",
+			input: "last 15m AND foo",
+			expectedExpression: InfixExpression{
+				Operator: TokOpAnd,
+				Left:     LastExpression{Duration: 15 * time.Minute},
+				Right:    StringLiteral{Value: "foo"},
+			},
+			expectedError: nil,
+		},
+		{
+			name:               "last expression with days",
+			input:              "NOT last 7d",
+			expectedExpression: PrefixExpression{Operator: TokOpNot, Right: LastExpression{Duration: 7 * 24 * time.Hour}},
+			expectedError:      nil,
+		},
+		{
+			name:  "last without duration",
+			input: "last week",
+			expectedExpression: InfixExpression{
+				Operator: TokOpAnd,
+				Left:     StringLiteral{Value: "last"},
+				Right:    StringLiteral{Value: "week"},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "last with number that isn't a duration",
+			input: "last 404",
+			expectedExpression: InfixExpression{
+				Operator: TokOpAnd,
+				Left:     StringLiteral{Value: "last"},
+				Right:    StringLiteral{Value: "404"},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "last with negative duration",
+			input: "last -1h",
+			expectedExpression: InfixExpression{
+				Operator: TokOpAnd,
+				Left:     StringLiteral{Value: "last"},
+				Right:    StringLiteral{Value: "-1h"},
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "last with zero duration",
+			input: "last 0m",
+			expectedExpression: InfixExpression{
+				Operator: TokOpAnd,
+				Left:     StringLiteral{Value: "last"},
+				Right:    StringLiteral{Value: "0m"},
+			},
+			expectedError: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -247,3 +304,44 @@ func assertError(t *testing.T, exp, got error) {
 		t.Fatalf("expected: %v, got: %v", exp.Error(), got.Error())
 	}
 }
+
+func TestParseQueryErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input            string
+		expectedPos      int
+		expectedExpected []string
+	}{
+		{input: "req.method =", expectedPos: 12, expectedExpected: expectedOperand},
+		{input: "req.method = = GET", expectedPos: 13, expectedExpected: expectedOperand},
+		{input: "(foo AND bar", expectedPos: 12, expectedExpected: []string{`")"`}},
+		{input: `foo = "bar`, expectedPos: 6},
+		{input: "foo ! bar", expectedPos: 4},
+		{input: `req.body =~ "(["`, expectedPos: 12},
+		{input: "foo )", expectedPos: 4, expectedExpected: expectedOperand},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseQuery(tt.input)
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected parse error, got: %v", err)
+			}
+
+			if parseErr.Pos != tt.expectedPos {
+				t.Errorf("expected position: %v, got: %v (%v)", tt.expectedPos, parseErr.Pos, parseErr)
+			}
+
+			if !reflect.DeepEqual(tt.expectedExpected, parseErr.Expected) {
+				t.Errorf("expected tokens: %v, got: %v", tt.expectedExpected, parseErr.Expected)
+			}
+		})
+	}
+}
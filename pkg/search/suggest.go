@@ -0,0 +1,146 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of suggested keys for an unknown key.
+const maxSuggestions = 3
+
+// CheckKeys returns a `*ParseError` for the first left operand of a comparison
+// in input that looks like a key, because it starts with the namespace of one of
+// keys (e.g. `req.`), but isn't a key by isKey. Such operands would otherwise
+// be compared as is, so that e.g. a misspelled key never matches. The error has
+// the keys that are most similar to the operand as suggestions.
+func CheckKeys(input string, keys []string, isKey func(key string) bool) error {
+	namespaces := make(map[string]struct{})
+
+	for _, key := range keys {
+		if i := strings.Index(key, "."); i > 0 {
+			namespaces[key[:i+1]] = struct{}{}
+		}
+	}
+
+	l := NewLexer(input)
+
+	// Drain the lexer, so that its goroutine exits.
+	defer func() {
+		for range l.tokens {
+		}
+	}()
+
+	var prev Token
+
+	for tok := l.Next(); ; prev, tok = tok, l.Next() {
+		if prev.Type == TokString && isComparison(tok.Type) && !isKey(prev.Literal) && inNamespace(prev.Literal, namespaces) {
+			return &ParseError{
+				Pos:         prev.Pos,
+				Msg:         fmt.Sprintf("unknown key %q", prev.Literal),
+				Suggestions: suggestKeys(prev.Literal, keys),
+			}
+		}
+
+		if tok.Type == TokEOF || tok.Type == TokInvalid {
+			return nil
+		}
+	}
+}
+
+func isComparison(tokenType TokenType) bool {
+	switch tokenType {
+	case TokOpEq, TokOpNotEq, TokOpGt, TokOpLt, TokOpGtEq, TokOpLtEq, TokOpRe, TokOpNotRe:
+		return true
+	default:
+		return false
+	}
+}
+
+func inNamespace(s string, namespaces map[string]struct{}) bool {
+	i := strings.Index(s, ".")
+	if i <= 0 {
+		return false
+	}
+
+	_, ok := namespaces[s[:i+1]]
+
+	return ok
+}
+
+// suggestKeys returns the keys that are most similar to s, by edit distance.
+// Keys that differ in more than a third of s aren't suggested.
+func suggestKeys(s string, keys []string) []string {
+	type candidate struct {
+		key      string
+		distance int
+	}
+
+	maxDistance := len(s) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	var candidates []candidate
+
+	for _, key := range keys {
+		if d := editDistance(strings.ToLower(s), strings.ToLower(key)); d <= maxDistance {
+			candidates = append(candidates, candidate{key, d})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+
+		return candidates[i].key < candidates[j].key
+	})
+
+	suggestions := make([]string, 0, maxSuggestions)
+
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].key)
+	}
+
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between the bytes of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+
+	if c < a {
+		a = c
+	}
+
+	return a
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckKeys(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{"req.method", "req.url", "res.body", "res.statusCode"}
+	isKey := func(key string) bool {
+		for _, k := range keys {
+			if k == key {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	tests := []struct {
+		input               string
+		expectedPos         int
+		expectedSuggestions []string
+		expectedOK          bool
+	}{
+		{input: `req.method = GET AND res.statusCode = 200`, expectedOK: true},
+		{input: `req.url = req.whatever`, expectedOK: true},
+		{input: `foo.bar = baz OR "req.meth"`, expectedOK: true},
+		{input: `req.method = GET AND res.statuscode = 200`, expectedPos: 21, expectedSuggestions: []string{"res.statusCode"}},
+		{input: `NOT ("req.metod" =~ "^P")`, expectedPos: 5, expectedSuggestions: []string{"req.method"}},
+		{input: `req.foobarbaz = 1`, expectedPos: 0, expectedSuggestions: []string{}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+
+			err := CheckKeys(tt.input, keys, isKey)
+			if tt.expectedOK {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected parse error, got: %v", err)
+			}
+
+			if parseErr.Pos != tt.expectedPos {
+				t.Errorf("expected position: %v, got: %v", tt.expectedPos, parseErr.Pos)
+			}
+
+			if !reflect.DeepEqual(tt.expectedSuggestions, parseErr.Suggestions) {
+				t.Errorf("expected suggestions: %v, got: %v", tt.expectedSuggestions, parseErr.Suggestions)
+			}
+		})
+	}
+}
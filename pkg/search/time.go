@@ -0,0 +1,138 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeRecord is a record with a time, e.g. of its creation, that `last`
+// expressions are matched against.
+type TimeRecord interface {
+	Record
+	SearchTime() time.Time
+}
+
+// timeLayouts are the layouts of times that values are compared as, in order.
+// The layout of `time.Time.String` is included, so that e.g. `req.timestamp` of
+// request logs can be compared with times in other layouts.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseTime parses s as a time in one of `timeLayouts`. Times without time
+// zone are in UTC.
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// parseDuration parses s as a duration of `time.ParseDuration`, or as a number
+// of days or weeks, e.g. `7d` or `2w`.
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if !strings.HasSuffix(s, suffix) {
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		return time.Duration(n) * unit, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return d, nil
+}
+
+func matchLastExpr(expr LastExpression, rec Record) bool {
+	timeRec, ok := rec.(TimeRecord)
+	if !ok {
+		return false
+	}
+
+	return !timeRec.SearchTime().Before(time.Now().Add(-expr.Duration))
+}
+
+// TimeRange returns the time range that records must be in to match expr, by
+// its `last` expressions, and comparisons of key (e.g. `req.timestamp`) with a
+// time, that every match must satisfy. Since is inclusive and until exclusive;
+// zero times are unbounded. The range can include records that don't match
+// expr, so it's meant for reading only records in the range (e.g. by ordered
+// IDs) before matching them.
+func TimeRange(expr Expression, key string, now time.Time) (since, until time.Time) {
+	switch e := expr.(type) {
+	case LastExpression:
+		return now.Add(-e.Duration), time.Time{}
+	case InfixExpression:
+		if e.Operator == TokOpAnd {
+			leftSince, leftUntil := TimeRange(e.Left, key, now)
+			rightSince, rightUntil := TimeRange(e.Right, key, now)
+
+			return laterTime(leftSince, rightSince), earlierTime(leftUntil, rightUntil)
+		}
+
+		left, ok := e.Left.(StringLiteral)
+		if !ok || left.Value != key {
+			return time.Time{}, time.Time{}
+		}
+
+		right, ok := e.Right.(StringLiteral)
+		if !ok {
+			return time.Time{}, time.Time{}
+		}
+
+		t, ok := parseTime(right.Value)
+		if !ok {
+			return time.Time{}, time.Time{}
+		}
+
+		switch e.Operator {
+		case TokOpGt, TokOpGtEq:
+			return t, time.Time{}
+		case TokOpLt:
+			return time.Time{}, t
+		case TokOpLtEq:
+			return time.Time{}, t.Add(time.Nanosecond)
+		}
+	}
+
+	return time.Time{}, time.Time{}
+}
+
+// laterTime returns the later of a and b, where zero times are earliest.
+func laterTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+
+	return a
+}
+
+// earlierTime returns the earlier of a and b, where zero times are latest.
+func earlierTime(a, b time.Time) time.Time {
+	switch {
+	case a.IsZero():
+		return b
+	case b.IsZero() || a.Before(b):
+		return a
+	default:
+		return b
+	}
+}
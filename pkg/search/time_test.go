@@ -0,0 +1,115 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+type timeRecord struct {
+	mapRecord
+	t time.Time
+}
+
+func (rec timeRecord) SearchTime() time.Time {
+	return rec.t
+}
+
+func TestMatchTime(t *testing.T) {
+	t.Parallel()
+
+	// Without monotonic clock reading, like the times of IDs.
+	recTime := time.Now().Add(-time.Hour).Round(0)
+	rec := timeRecord{
+		mapRecord: mapRecord{"req.timestamp": recTime.String()},
+		t:         recTime,
+	}
+	before := recTime.Add(-time.Minute).UTC().Format(time.RFC3339)
+	after := recTime.Add(time.Minute).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		query         string
+		rec           Record
+		expectedMatch bool
+	}{
+		{query: `req.timestamp > "` + before + `"`, rec: rec, expectedMatch: true},
+		{query: `req.timestamp > "` + after + `"`, rec: rec, expectedMatch: false},
+		{query: `req.timestamp <= "` + after + `"`, rec: rec, expectedMatch: true},
+		{query: `req.timestamp < "` + recTime.AddDate(0, 0, 1).Format("2006-01-02") + `"`, rec: rec, expectedMatch: true},
+		{query: "last 2h", rec: rec, expectedMatch: true},
+		{query: "last 30m", rec: rec, expectedMatch: false},
+		{query: "NOT last 30m", rec: rec, expectedMatch: true},
+		{query: "last 1d", rec: rec.mapRecord, expectedMatch: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error parsing query: %v", err)
+			}
+
+			got, err := Match(expr, tt.rec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tt.expectedMatch {
+				t.Errorf("expected match result: %v, got: %v", tt.expectedMatch, got)
+			}
+		})
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		query         string
+		expectedSince time.Time
+		expectedUntil time.Time
+	}{
+		{query: "last 15m", expectedSince: now.Add(-15 * time.Minute)},
+		{query: `req.timestamp >= "2024-01-01T00:00:00Z"`, expectedSince: day},
+		{query: `req.timestamp < 2024-01-01`, expectedUntil: day},
+		{query: `req.timestamp <= 2024-01-01`, expectedUntil: day.Add(time.Nanosecond)},
+		{
+			query:         `req.timestamp < 2024-01-01 AND (last 2d AND req.method = GET)`,
+			expectedSince: now.Add(-48 * time.Hour),
+			expectedUntil: day,
+		},
+		{query: `last 15m OR req.method = GET`},
+		{query: `NOT last 15m`},
+		{query: `res.timestamp > 2024-01-01`},
+		{query: `req.timestamp > yesterday`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error parsing query: %v", err)
+			}
+
+			since, until := TimeRange(expr, "req.timestamp", now)
+
+			if !since.Equal(tt.expectedSince) {
+				t.Errorf("expected since: %v, got: %v", tt.expectedSince, since)
+			}
+
+			if !until.Equal(tt.expectedUntil) {
+				t.Errorf("expected until: %v, got: %v", tt.expectedUntil, until)
+			}
+		})
+	}
+}
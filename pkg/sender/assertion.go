@@ -0,0 +1,174 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+var ErrInvalidAssertions = errcode.New(errcode.Invalid, "sender: invalid assertions")
+
+type AssertionType string
+
+const (
+	AssertionStatusEquals   AssertionType = "status_equals"
+	AssertionBodyContains   AssertionType = "body_contains"
+	AssertionJSONPathEquals AssertionType = "json_path_equals"
+)
+
+// Assertion is checked against the response of a sender request, so that
+// requests (e.g. of a previously found vulnerability) can be sent again as a
+// regression test.
+type Assertion struct {
+	Type AssertionType
+	// Dot separated path in the JSON response body, e.g. `$.user.roles.0`,
+	// for `AssertionJSONPathEquals`. The `$.` prefix is optional.
+	Path  string
+	Value string
+}
+
+// AssertionResult is the result of checking an assertion against a response.
+type AssertionResult struct {
+	Assertion Assertion
+	Passed    bool
+	// Value found in the response, if any.
+	Actual string
+	// Reason the assertion failed, if it did.
+	Message string
+}
+
+// ValidateAssertions returns an error if an assertion has an unsupported type,
+// or is missing its path or value.
+func ValidateAssertions(assertions []Assertion) error {
+	for i, assertion := range assertions {
+		switch assertion.Type {
+		case AssertionStatusEquals:
+			if code, err := strconv.Atoi(assertion.Value); err != nil || code < 100 || code > 999 {
+				return fmt.Errorf("%w: assertion %v: invalid status code %q", ErrInvalidAssertions, i+1, assertion.Value)
+			}
+		case AssertionBodyContains:
+			if assertion.Value == "" {
+				return fmt.Errorf("%w: assertion %v: value must not be empty", ErrInvalidAssertions, i+1)
+			}
+		case AssertionJSONPathEquals:
+			if jsonPath(assertion.Path) == "" {
+				return fmt.Errorf("%w: assertion %v: path must not be empty", ErrInvalidAssertions, i+1)
+			}
+		default:
+			return fmt.Errorf("%w: assertion %v: unsupported type %q", ErrInvalidAssertions, i+1, assertion.Type)
+		}
+	}
+
+	return nil
+}
+
+// Check checks the assertion against a response.
+func (assertion Assertion) Check(res reqlog.ResponseLog) AssertionResult {
+	result := AssertionResult{Assertion: assertion}
+
+	switch assertion.Type {
+	case AssertionStatusEquals:
+		result.Actual = strconv.Itoa(res.StatusCode)
+		result.Passed = result.Actual == assertion.Value
+	case AssertionBodyContains:
+		result.Passed = bytes.Contains(res.Body, []byte(assertion.Value))
+	case AssertionJSONPathEquals:
+		value, err := jsonPathValue(res.Body, jsonPath(assertion.Path))
+		if err != nil {
+			result.Message = err.Error()
+			return result
+		}
+
+		result.Actual = value
+		result.Passed = value == assertion.Value
+	default:
+		result.Message = fmt.Sprintf("unsupported assertion type %q", assertion.Type)
+		return result
+	}
+
+	if !result.Passed {
+		switch assertion.Type {
+		case AssertionBodyContains:
+			result.Message = fmt.Sprintf("response body doesn't contain %q", assertion.Value)
+		default:
+			result.Message = fmt.Sprintf("expected %q, got %q", assertion.Value, result.Actual)
+		}
+	}
+
+	return result
+}
+
+// CheckAssertions checks the assertions of the request against its last
+// response. It returns nil if the request hasn't been sent.
+func (req Request) CheckAssertions() []AssertionResult {
+	if req.Response == nil || len(req.Assertions) == 0 {
+		return nil
+	}
+
+	results := make([]AssertionResult, len(req.Assertions))
+	for i, assertion := range req.Assertions {
+		results[i] = assertion.Check(*req.Response)
+	}
+
+	return results
+}
+
+// Passed returns true if the request was sent and all its assertions passed.
+func (result CollectionRunResult) Passed() bool {
+	if result.Err != nil {
+		return false
+	}
+
+	for _, assertion := range result.Assertions {
+		if !assertion.Passed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RunAssertionSuite sends every request of the active project that has
+// assertions, one by one, and checks their assertions.
+func (svc *service) RunAssertionSuite(ctx context.Context) ([]CollectionRunResult, error) {
+	if svc.isReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	projectID := svc.activeProject()
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, ErrProjectIDMustBeSet
+	}
+
+	// The suite isn't affected by the filter of the requests list.
+	reqs, err := svc.repo.FindSenderRequests(ctx, FindRequestsFilter{ProjectID: projectID}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sender: failed to find requests: %w", err)
+	}
+
+	ids := make([]ulid.ULID, 0, len(reqs))
+
+	for _, req := range reqs {
+		if len(req.Assertions) > 0 {
+			ids = append(ids, req.ID)
+		}
+	}
+
+	return svc.runRequests(ctx, ids)
+}
+
+func jsonPath(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "$" {
+		return ""
+	}
+
+	return strings.TrimPrefix(path, "$.")
+}
@@ -0,0 +1,228 @@
+package sender_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func TestValidateAssertions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		assertions []sender.Assertion
+		expErr     bool
+	}{
+		{
+			name: "valid",
+			assertions: []sender.Assertion{
+				{Type: sender.AssertionStatusEquals, Value: "403"},
+				{Type: sender.AssertionBodyContains, Value: "denied"},
+				{Type: sender.AssertionJSONPathEquals, Path: "$.user.admin", Value: "false"},
+			},
+		},
+		{
+			name:       "invalid status code",
+			assertions: []sender.Assertion{{Type: sender.AssertionStatusEquals, Value: "forbidden"}},
+			expErr:     true,
+		},
+		{
+			name:       "empty body value",
+			assertions: []sender.Assertion{{Type: sender.AssertionBodyContains}},
+			expErr:     true,
+		},
+		{
+			name:       "empty JSON path",
+			assertions: []sender.Assertion{{Type: sender.AssertionJSONPathEquals, Path: "$", Value: "x"}},
+			expErr:     true,
+		},
+		{
+			name:       "unsupported type",
+			assertions: []sender.Assertion{{Type: "header_equals", Value: "x"}},
+			expErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := sender.ValidateAssertions(tt.assertions)
+			if tt.expErr && !errors.Is(err, sender.ErrInvalidAssertions) {
+				t.Fatalf("expected `sender.ErrInvalidAssertions`, got: %v", err)
+			}
+
+			if !tt.expErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestAssertionCheck(t *testing.T) {
+	t.Parallel()
+
+	res := reqlog.ResponseLog{
+		StatusCode: http.StatusForbidden,
+		Body:       []byte(`{"error": "access denied", "user": {"id": 42, "roles": ["viewer"]}}`),
+	}
+
+	tests := []struct {
+		name      string
+		assertion sender.Assertion
+		exp       sender.AssertionResult
+	}{
+		{
+			name:      "status equals",
+			assertion: sender.Assertion{Type: sender.AssertionStatusEquals, Value: "403"},
+			exp:       sender.AssertionResult{Passed: true, Actual: "403"},
+		},
+		{
+			name:      "status not equal",
+			assertion: sender.Assertion{Type: sender.AssertionStatusEquals, Value: "200"},
+			exp:       sender.AssertionResult{Actual: "403", Message: `expected "200", got "403"`},
+		},
+		{
+			name:      "body contains",
+			assertion: sender.Assertion{Type: sender.AssertionBodyContains, Value: "access denied"},
+			exp:       sender.AssertionResult{Passed: true},
+		},
+		{
+			name:      "body doesn't contain",
+			assertion: sender.Assertion{Type: sender.AssertionBodyContains, Value: "welcome"},
+			exp:       sender.AssertionResult{Message: `response body doesn't contain "welcome"`},
+		},
+		{
+			name:      "JSON path equals number",
+			assertion: sender.Assertion{Type: sender.AssertionJSONPathEquals, Path: "$.user.id", Value: "42"},
+			exp:       sender.AssertionResult{Passed: true, Actual: "42"},
+		},
+		{
+			name:      "JSON path equals array element",
+			assertion: sender.Assertion{Type: sender.AssertionJSONPathEquals, Path: "user.roles.0", Value: "viewer"},
+			exp:       sender.AssertionResult{Passed: true, Actual: "viewer"},
+		},
+		{
+			name:      "JSON path not found",
+			assertion: sender.Assertion{Type: sender.AssertionJSONPathEquals, Path: "$.user.admin", Value: "false"},
+			exp:       sender.AssertionResult{Message: `JSON path "user.admin" not found`},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.exp.Assertion = tt.assertion
+
+			if diff := cmp.Diff(tt.exp, tt.assertion.Check(res)); diff != "" {
+				t.Fatalf("assertion result not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestRunAssertionSuite(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+
+		w.Write([]byte(`{"path": "` + r.URL.Path + `"}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+		HTTPClient: &http.Client{},
+	})
+
+	if _, err := svc.RunAssertionSuite(ctx); !errors.Is(err, sender.ErrProjectIDMustBeSet) {
+		t.Fatalf("expected `sender.ErrProjectIDMustBeSet`, got: %v", err)
+	}
+
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	createRequest := func(path string, assertions ...sender.Assertion) sender.Request {
+		u, _ := url.Parse(upstream.URL + path)
+
+		req, err := svc.CreateOrUpdateRequest(ctx, sender.Request{
+			URL:        u,
+			Proto:      sender.HTTPProto1,
+			Assertions: assertions,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error creating request: %v", err)
+		}
+
+		return req
+	}
+
+	admin := createRequest("/admin",
+		sender.Assertion{Type: sender.AssertionStatusEquals, Value: "403"},
+		sender.Assertion{Type: sender.AssertionJSONPathEquals, Path: "$.path", Value: "/admin"},
+	)
+	// Regressed: the response no longer matches.
+	users := createRequest("/users", sender.Assertion{Type: sender.AssertionStatusEquals, Value: "401"})
+	// Requests without assertions aren't part of the suite.
+	createRequest("/health")
+
+	if _, err := svc.CreateOrUpdateRequest(ctx, sender.Request{
+		URL:        admin.URL,
+		Assertions: []sender.Assertion{{Type: sender.AssertionStatusEquals}},
+	}); !errors.Is(err, sender.ErrInvalidAssertions) {
+		t.Fatalf("expected `sender.ErrInvalidAssertions`, got: %v", err)
+	}
+
+	results, err := svc.RunAssertionSuite(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got: %v", len(results))
+	}
+
+	byID := make(map[ulid.ULID]sender.CollectionRunResult)
+	for _, result := range results {
+		byID[result.Request.ID] = result
+	}
+
+	if result := byID[admin.ID]; !result.Passed() || len(result.Assertions) != 2 {
+		t.Fatalf("expected admin request to pass, got: %+v", result.Assertions)
+	}
+
+	if result := byID[users.ID]; result.Passed() || result.Assertions[0].Actual != "200" {
+		t.Fatalf("expected users request to fail, got: %+v", result.Assertions)
+	}
+
+	// Assertions are checked against the stored response.
+	req, err := svc.FindRequestByID(ctx, users.ID)
+	if err != nil {
+		t.Fatalf("unexpected error finding request: %v", err)
+	}
+
+	if results := req.CheckAssertions(); len(results) != 1 || results[0].Passed {
+		t.Fatalf("expected failed assertion, got: %+v", results)
+	}
+}
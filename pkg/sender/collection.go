@@ -0,0 +1,320 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+var (
+	ErrCollectionNotFound    = errcode.New(errcode.NotFound, "sender: collection not found")
+	ErrInvalidCollectionName = errcode.New(errcode.Invalid, "sender: collection name must not be empty")
+)
+
+// Collection is a named, ordered group of sender requests of a project, e.g.
+// the requests of an authentication flow. A request is in at most one
+// collection.
+type Collection struct {
+	ID        ulid.ULID
+	ProjectID ulid.ULID
+	Name      string
+	// Position of the collection in the list of collections of the project.
+	Position   int
+	RequestIDs []ulid.ULID
+}
+
+// CollectionRunResult is the result of sending a request of a collection, see
+// `Service.RunCollection`.
+type CollectionRunResult struct {
+	Request Request
+	Err     error
+	// Results of the assertions of the request, if it was sent.
+	Assertions []AssertionResult
+}
+
+// FindCollections returns the collections of the active project, by position.
+func (svc *service) FindCollections(ctx context.Context) ([]Collection, error) {
+	projectID := svc.activeProject()
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, ErrProjectIDMustBeSet
+	}
+
+	collections, err := svc.repo.FindSenderCollections(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("sender: failed to find collections: %w", err)
+	}
+
+	sort.SliceStable(collections, func(i, j int) bool {
+		if collections[i].Position != collections[j].Position {
+			return collections[i].Position < collections[j].Position
+		}
+
+		return collections[i].ID.Compare(collections[j].ID) < 0
+	})
+
+	return collections, nil
+}
+
+func (svc *service) FindCollectionByID(ctx context.Context, id ulid.ULID) (Collection, error) {
+	collection, err := svc.repo.FindSenderCollectionByID(ctx, id)
+	if err != nil {
+		return Collection{}, fmt.Errorf("sender: failed to find collection: %w", err)
+	}
+
+	return collection, nil
+}
+
+// CreateCollection creates an empty collection, after the existing collections
+// of the active project.
+func (svc *service) CreateCollection(ctx context.Context, name string) (Collection, error) {
+	if svc.isReadOnly() {
+		return Collection{}, ErrReadOnly
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Collection{}, ErrInvalidCollectionName
+	}
+
+	svc.collectionsMu.Lock()
+	defer svc.collectionsMu.Unlock()
+
+	collections, err := svc.FindCollections(ctx)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	collection := Collection{
+		ID:        svc.ids.New(time.Now()),
+		ProjectID: svc.activeProject(),
+		Name:      name,
+	}
+
+	if n := len(collections); n > 0 {
+		collection.Position = collections[n-1].Position + 1
+	}
+
+	if err := svc.repo.StoreSenderCollection(ctx, collection); err != nil {
+		return Collection{}, fmt.Errorf("sender: failed to store collection: %w", err)
+	}
+
+	return collection, nil
+}
+
+func (svc *service) RenameCollection(ctx context.Context, id ulid.ULID, name string) (Collection, error) {
+	if svc.isReadOnly() {
+		return Collection{}, ErrReadOnly
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Collection{}, ErrInvalidCollectionName
+	}
+
+	svc.collectionsMu.Lock()
+	defer svc.collectionsMu.Unlock()
+
+	collection, err := svc.FindCollectionByID(ctx, id)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	collection.Name = name
+
+	if err := svc.repo.StoreSenderCollection(ctx, collection); err != nil {
+		return Collection{}, fmt.Errorf("sender: failed to store collection: %w", err)
+	}
+
+	return collection, nil
+}
+
+// DeleteCollection deletes a collection. Its requests aren't deleted.
+func (svc *service) DeleteCollection(ctx context.Context, id ulid.ULID) error {
+	if svc.isReadOnly() {
+		return ErrReadOnly
+	}
+
+	svc.collectionsMu.Lock()
+	defer svc.collectionsMu.Unlock()
+
+	if err := svc.repo.DeleteSenderCollection(ctx, id); err != nil {
+		return fmt.Errorf("sender: failed to delete collection: %w", err)
+	}
+
+	return nil
+}
+
+// ReorderCollections sets the order of the collections of the active project.
+// Collections that aren't in ids are placed after the others, in their current
+// order.
+func (svc *service) ReorderCollections(ctx context.Context, ids []ulid.ULID) error {
+	if svc.isReadOnly() {
+		return ErrReadOnly
+	}
+
+	svc.collectionsMu.Lock()
+	defer svc.collectionsMu.Unlock()
+
+	collections, err := svc.FindCollections(ctx)
+	if err != nil {
+		return err
+	}
+
+	positions := make(map[ulid.ULID]int, len(ids))
+	for i, id := range ids {
+		positions[id] = i
+	}
+
+	sort.SliceStable(collections, func(i, j int) bool {
+		pi, iok := positions[collections[i].ID]
+		pj, jok := positions[collections[j].ID]
+
+		if iok && jok {
+			return pi < pj
+		}
+
+		return iok && !jok
+	})
+
+	for i, collection := range collections {
+		if collection.Position == i {
+			continue
+		}
+
+		collection.Position = i
+
+		if err := svc.repo.StoreSenderCollection(ctx, collection); err != nil {
+			return fmt.Errorf("sender: failed to store collection: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// MoveRequest moves a sender request to index in a collection, removing it
+// from the collection it was in. If index is out of range, the request is
+// appended. A zero collectionID only removes the request from its collection.
+func (svc *service) MoveRequest(ctx context.Context, reqID, collectionID ulid.ULID, index int) error {
+	if svc.isReadOnly() {
+		return ErrReadOnly
+	}
+
+	req, err := svc.FindRequestByID(ctx, reqID)
+	if err != nil {
+		return err
+	}
+
+	if req.ProjectID.Compare(svc.activeProject()) != 0 {
+		return ErrRequestNotFound
+	}
+
+	svc.collectionsMu.Lock()
+	defer svc.collectionsMu.Unlock()
+
+	collections, err := svc.FindCollections(ctx)
+	if err != nil {
+		return err
+	}
+
+	found := collectionID.Compare(ulid.ULID{}) == 0
+
+	for _, collection := range collections {
+		isTarget := collection.ID.Compare(collectionID) == 0
+		requestIDs := removeID(collection.RequestIDs, reqID)
+
+		if isTarget {
+			found = true
+			requestIDs = insertID(requestIDs, reqID, index)
+		} else if len(requestIDs) == len(collection.RequestIDs) {
+			continue
+		}
+
+		collection.RequestIDs = requestIDs
+
+		if err := svc.repo.StoreSenderCollection(ctx, collection); err != nil {
+			return fmt.Errorf("sender: failed to store collection: %w", err)
+		}
+	}
+
+	if !found {
+		return ErrCollectionNotFound
+	}
+
+	return nil
+}
+
+// RunCollection sends the requests of a collection one by one, in order, so
+// that e.g. a login request is sent before requests that depend on it. A
+// request that fails doesn't stop the run; its error is in the result.
+func (svc *service) RunCollection(ctx context.Context, id ulid.ULID) ([]CollectionRunResult, error) {
+	if svc.isReadOnly() {
+		return nil, ErrReadOnly
+	}
+
+	collection, err := svc.FindCollectionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.runRequests(ctx, collection.RequestIDs)
+}
+
+// runRequests sends requests one by one, in order, and checks the assertions
+// of those that were sent.
+func (svc *service) runRequests(ctx context.Context, ids []ulid.ULID) ([]CollectionRunResult, error) {
+	results := make([]CollectionRunResult, 0, len(ids))
+
+	for _, reqID := range ids {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("sender: collection run interrupted: %w", err)
+		}
+
+		req, err := svc.SendRequest(ctx, reqID)
+		if err != nil {
+			// Report the request as stored, e.g. with the response of a
+			// previous run.
+			if req, _ = svc.FindRequestByID(ctx, reqID); req.ID.Compare(ulid.ULID{}) == 0 {
+				req.ID = reqID
+			}
+		}
+
+		result := CollectionRunResult{Request: req, Err: err}
+		if err == nil {
+			result.Assertions = req.CheckAssertions()
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func removeID(ids []ulid.ULID, id ulid.ULID) []ulid.ULID {
+	result := make([]ulid.ULID, 0, len(ids))
+
+	for _, v := range ids {
+		if v.Compare(id) != 0 {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+func insertID(ids []ulid.ULID, id ulid.ULID, index int) []ulid.ULID {
+	if index < 0 || index > len(ids) {
+		index = len(ids)
+	}
+
+	result := make([]ulid.ULID, 0, len(ids)+1)
+	result = append(result, ids[:index]...)
+	result = append(result, id)
+
+	return append(result, ids[index:]...)
+}
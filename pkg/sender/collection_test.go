@@ -0,0 +1,132 @@
+package sender_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func TestCollections(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	t.Cleanup(upstream.Close)
+
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+		HTTPClient: &http.Client{},
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	if _, err := svc.CreateCollection(ctx, " "); !errors.Is(err, sender.ErrInvalidCollectionName) {
+		t.Fatalf("expected `sender.ErrInvalidCollectionName`, got: %v", err)
+	}
+
+	auth, err := svc.CreateCollection(ctx, "auth flows")
+	if err != nil {
+		t.Fatalf("unexpected error creating collection: %v", err)
+	}
+
+	payments, err := svc.CreateCollection(ctx, "payments API")
+	if err != nil {
+		t.Fatalf("unexpected error creating collection: %v", err)
+	}
+
+	reqIDs := make([]ulid.ULID, 3)
+
+	for i, path := range []string{"/login", "/me", "/pay"} {
+		u, _ := url.Parse(upstream.URL + path)
+
+		req, err := svc.CreateOrUpdateRequest(ctx, sender.Request{URL: u, Proto: sender.HTTPProto1})
+		if err != nil {
+			t.Fatalf("unexpected error creating request: %v", err)
+		}
+
+		reqIDs[i] = req.ID
+	}
+
+	// Add `/pay` to the wrong collection first, then move it.
+	for _, move := range []struct {
+		reqID        ulid.ULID
+		collectionID ulid.ULID
+		index        int
+	}{
+		{reqIDs[1], auth.ID, -1},
+		{reqIDs[2], auth.ID, -1},
+		{reqIDs[0], auth.ID, 0},
+		{reqIDs[2], payments.ID, 0},
+	} {
+		if err := svc.MoveRequest(ctx, move.reqID, move.collectionID, move.index); err != nil {
+			t.Fatalf("unexpected error moving request: %v", err)
+		}
+	}
+
+	if err := svc.ReorderCollections(ctx, []ulid.ULID{payments.ID}); err != nil {
+		t.Fatalf("unexpected error reordering collections: %v", err)
+	}
+
+	got, err := svc.FindCollections(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error finding collections: %v", err)
+	}
+
+	exp := []sender.Collection{
+		{ID: payments.ID, ProjectID: payments.ProjectID, Name: "payments API", RequestIDs: []ulid.ULID{reqIDs[2]}},
+		{ID: auth.ID, ProjectID: auth.ProjectID, Name: "auth flows", Position: 1, RequestIDs: reqIDs[:2]},
+	}
+
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("collections not equal (-exp, +got):\n%v", diff)
+	}
+
+	results, err := svc.RunCollection(ctx, auth.ID)
+	if err != nil {
+		t.Fatalf("unexpected error running collection: %v", err)
+	}
+
+	bodies := make([]string, len(results))
+
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("unexpected error sending request: %v", result.Err)
+		}
+
+		bodies[i] = string(result.Request.Response.Body)
+	}
+
+	if diff := cmp.Diff([]string{"/login", "/me"}, bodies); diff != "" {
+		t.Fatalf("response bodies not equal (-exp, +got):\n%v", diff)
+	}
+
+	if err := svc.MoveRequest(ctx, reqIDs[0], ulid.ULID{}, 0); err != nil {
+		t.Fatalf("unexpected error removing request from collection: %v", err)
+	}
+
+	if auth, err = svc.FindCollectionByID(ctx, auth.ID); err != nil {
+		t.Fatalf("unexpected error finding collection: %v", err)
+	}
+
+	if diff := cmp.Diff(reqIDs[1:2], auth.RequestIDs); diff != "" {
+		t.Fatalf("request IDs not equal (-exp, +got):\n%v", diff)
+	}
+
+	svc.SetReadOnly(true)
+
+	if _, err := svc.RunCollection(ctx, auth.ID); !errors.Is(err, sender.ErrReadOnly) {
+		t.Fatalf("expected `sender.ErrReadOnly`, got: %v", err)
+	}
+}
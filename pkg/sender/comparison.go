@@ -0,0 +1,267 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+var ErrInvalidComparison = errcode.New(errcode.Invalid, "sender: invalid comparison")
+
+// Headers that differ between most responses, or follow from the body, and are
+// ignored in comparisons.
+var volatileHeaders = []string{"Age", "Content-Length", "Date", "Expires"}
+
+// CompareParams configures a comparison of the responses of two environments
+// to the same sender request, e.g. an old and a new API version.
+type CompareParams struct {
+	RequestID ulid.ULID
+	// Base URLs the request is sent to. Their scheme and host replace those of
+	// the request URL, and their path (if any) is prepended to its path, e.g.
+	// `https://staging.example.com/v2`.
+	BaseURLs [2]*url.URL
+	// Headers that aren't compared, in addition to `Age`, `Content-Length`,
+	// `Date` and `Expires`.
+	IgnoreHeaders []string
+	// Volatile fields of bodies that aren't compared, see
+	// `Schedule.IgnoreJSONPaths` and `Schedule.IgnorePatterns`.
+	IgnoreJSONPaths []string
+	IgnorePatterns  []string
+}
+
+// Comparison holds the responses of a sender request sent to two base URLs,
+// and their differences.
+type Comparison struct {
+	ID        ulid.ULID
+	ProjectID ulid.ULID
+	RequestID ulid.ULID
+	Targets   [2]ComparisonTarget
+	Diff      ResponseDiff
+}
+
+type ComparisonTarget struct {
+	BaseURL *url.URL
+	// URL the request was sent to.
+	URL      *url.URL
+	Response *reqlog.ResponseLog
+	// Error of sending the request, if any.
+	Err string
+}
+
+// ResponseDiff holds the differences of two responses. It's empty if either
+// request failed.
+type ResponseDiff struct {
+	// True if the status codes, headers and bodies are equal, ignoring
+	// volatile fields.
+	Equal             bool
+	StatusCodeChanged bool
+	// Headers with different values, sorted by key.
+	Headers []HeaderDiff
+	// Unified diff of the normalized bodies (indented, if JSON), if they
+	// differ.
+	Body string
+}
+
+// HeaderDiff is a header whose values differ between two responses. Values are
+// nil if a response doesn't have the header.
+type HeaderDiff struct {
+	Key     string
+	AValues []string
+	BValues []string
+}
+
+func (params CompareParams) validate() error {
+	for _, baseURL := range params.BaseURLs {
+		if baseURL == nil || baseURL.Host == "" || (baseURL.Scheme != "http" && baseURL.Scheme != "https") {
+			return fmt.Errorf("%w: base URLs must be absolute HTTP or HTTPS URLs", ErrInvalidComparison)
+		}
+	}
+
+	return nil
+}
+
+// CompareRequest sends a sender request to both base URLs of params, and
+// stores the comparison of their responses. Variables, the pre-request script
+// and signing profiles apply as when the request is sent, rewrite profiles
+// don't. Responses aren't stored as the response of the request, and
+// post-response scripts don't run.
+func (svc *service) CompareRequest(ctx context.Context, params CompareParams) (Comparison, error) {
+	if svc.isReadOnly() {
+		return Comparison{}, ErrReadOnly
+	}
+
+	if err := params.validate(); err != nil {
+		return Comparison{}, err
+	}
+
+	normalizer, err := newResponseNormalizer(params.IgnoreJSONPaths, params.IgnorePatterns)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("%w: %v", ErrInvalidComparison, err)
+	}
+
+	req, err := svc.repo.FindSenderRequestByID(ctx, params.RequestID)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("sender: failed to find request: %w", err)
+	}
+
+	if req.Raw != nil {
+		return Comparison{}, fmt.Errorf("%w: raw requests can't be compared", ErrInvalidComparison)
+	}
+
+	comparison := Comparison{
+		ID:        svc.ids.New(time.Now()),
+		ProjectID: req.ProjectID,
+		RequestID: req.ID,
+	}
+
+	for i, baseURL := range params.BaseURLs {
+		comparison.Targets[i], err = svc.sendToBaseURL(ctx, req, baseURL)
+		if err != nil {
+			return Comparison{}, err
+		}
+	}
+
+	a, b := comparison.Targets[0].Response, comparison.Targets[1].Response
+	if a != nil && b != nil {
+		comparison.Diff = diffResponses(*a, *b, normalizer, params.IgnoreHeaders)
+	}
+
+	if err := svc.repo.StoreSenderComparison(ctx, comparison); err != nil {
+		return Comparison{}, fmt.Errorf("sender: failed to store comparison: %w", err)
+	}
+
+	return comparison, nil
+}
+
+// FindComparisons returns the comparisons of a sender request, or of all
+// requests of the active project if requestID is zero, oldest first.
+func (svc *service) FindComparisons(ctx context.Context, requestID ulid.ULID) ([]Comparison, error) {
+	projectID := svc.activeProject()
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, ErrProjectIDMustBeSet
+	}
+
+	comparisons, err := svc.repo.FindSenderComparisons(ctx, projectID, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("sender: failed to find comparisons: %w", err)
+	}
+
+	return comparisons, nil
+}
+
+// sendToBaseURL sends req to baseURL. Errors of sending the request are
+// returned in the target, so that the comparison shows them.
+func (svc *service) sendToBaseURL(ctx context.Context, req Request, baseURL *url.URL) (ComparisonTarget, error) {
+	target := ComparisonTarget{BaseURL: baseURL}
+
+	sent, _, err := svc.prepareRequest(req, func(u *url.URL, header http.Header) *url.URL {
+		header.Del("Host")
+		return withBaseURL(u, baseURL)
+	})
+	if err != nil {
+		return ComparisonTarget{}, err
+	}
+
+	target.URL = sent.URL
+
+	httpReq, err := parseHTTPRequest(ctx, sent)
+	if err != nil {
+		return ComparisonTarget{}, fmt.Errorf("sender: failed to parse HTTP request: %w", err)
+	}
+
+	resLog, err := svc.sendHTTPRequest(httpReq)
+	if err != nil {
+		target.Err = err.Error()
+		return target, nil
+	}
+
+	target.Response = &resLog
+
+	return target, nil
+}
+
+// withBaseURL returns u with the scheme and host of baseURL, and the path of
+// baseURL prepended to its path.
+func withBaseURL(u, baseURL *url.URL) *url.URL {
+	rewritten := *u
+	rewritten.Scheme = baseURL.Scheme
+	rewritten.Host = baseURL.Host
+
+	if prefix := strings.TrimSuffix(baseURL.Path, "/"); prefix != "" {
+		rewritten.Path = prefix + u.Path
+		rewritten.RawPath = ""
+	}
+
+	return &rewritten
+}
+
+func diffResponses(a, b reqlog.ResponseLog, normalizer responseNormalizer, ignoreHeaders []string) ResponseDiff {
+	ignored := make(map[string]bool, len(volatileHeaders)+len(ignoreHeaders))
+
+	for _, key := range volatileHeaders {
+		ignored[key] = true
+	}
+
+	for _, key := range ignoreHeaders {
+		ignored[http.CanonicalHeaderKey(key)] = true
+	}
+
+	diff := ResponseDiff{
+		StatusCodeChanged: a.StatusCode != b.StatusCode,
+		Body:              lineDiff(normalizer.normalizeBody(a.Body), normalizer.normalizeBody(b.Body)),
+	}
+
+	keys := make(map[string]bool, len(a.Header)+len(b.Header))
+
+	for key := range a.Header {
+		keys[http.CanonicalHeaderKey(key)] = true
+	}
+
+	for key := range b.Header {
+		keys[http.CanonicalHeaderKey(key)] = true
+	}
+
+	for key := range keys {
+		if ignored[key] {
+			continue
+		}
+
+		aValues, bValues := a.Header.Values(key), b.Header.Values(key)
+		if equalStrings(aValues, bValues) {
+			continue
+		}
+
+		diff.Headers = append(diff.Headers, HeaderDiff{Key: key, AValues: aValues, BValues: bValues})
+	}
+
+	sort.Slice(diff.Headers, func(i, j int) bool {
+		return diff.Headers[i].Key < diff.Headers[j].Key
+	})
+
+	diff.Equal = !diff.StatusCodeChanged && len(diff.Headers) == 0 && diff.Body == ""
+
+	return diff
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,156 @@
+package sender_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func TestCompareRequest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	newUpstream := func(role string, statusCode int) *url.URL {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Date", time.Now().String())
+			w.Header().Set("X-Version", role)
+			w.WriteHeader(statusCode)
+			fmt.Fprintf(w, `{"path":%q,"role":%q,"ts":%v}`, r.URL.Path, role, time.Now().UnixNano())
+		}))
+		t.Cleanup(ts.Close)
+
+		u, _ := url.Parse(ts.URL)
+
+		return u
+	}
+
+	v1 := newUpstream("user", http.StatusOK)
+	v2 := newUpstream("admin", http.StatusForbidden)
+
+	// Comparisons are found in order of their IDs, which must sort in order of
+	// creation within the same millisecond.
+	ids, err := idgen.NewGenerator(idgen.Config{Strategy: idgen.StrategyMonotonic})
+	if err != nil {
+		t.Fatalf("unexpected error creating ID generator: %v", err)
+	}
+
+	svc := sender.NewService(sender.Config{
+		Repository:  memory.OpenDatabase(),
+		HTTPClient:  &http.Client{},
+		IDGenerator: ids,
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	req, err := svc.CreateOrUpdateRequest(ctx, sender.Request{
+		URL:   &url.URL{Scheme: "https", Host: "api.example.com", Path: "/users/1"},
+		Proto: sender.HTTPProto1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating request: %v", err)
+	}
+
+	t.Run("equal responses", func(t *testing.T) {
+		comparison, err := svc.CompareRequest(ctx, sender.CompareParams{
+			RequestID:       req.ID,
+			BaseURLs:        [2]*url.URL{v1, v1},
+			IgnoreJSONPaths: []string{"$.ts"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !comparison.Diff.Equal {
+			t.Fatalf("expected equal responses, got diff: %+v", comparison.Diff)
+		}
+	})
+
+	t.Run("different responses", func(t *testing.T) {
+		v2Prefixed := *v2
+		v2Prefixed.Path = "/v2/"
+
+		comparison, err := svc.CompareRequest(ctx, sender.CompareParams{
+			RequestID:       req.ID,
+			BaseURLs:        [2]*url.URL{v1, &v2Prefixed},
+			IgnoreJSONPaths: []string{"$.ts"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got, exp := comparison.Targets[1].URL.String(), v2.String()+"/v2/users/1"; got != exp {
+			t.Errorf("incorrect URL (expected: %v, got: %v)", exp, got)
+		}
+
+		expDiff := sender.ResponseDiff{
+			StatusCodeChanged: true,
+			Headers: []sender.HeaderDiff{
+				{Key: "X-Version", AValues: []string{"user"}, BValues: []string{"admin"}},
+			},
+			Body: "@@ -1,4 +1,4 @@\n" +
+				" {\n" +
+				"-  \"path\": \"/users/1\",\n" +
+				"-  \"role\": \"user\"\n" +
+				"+  \"path\": \"/v2/users/1\",\n" +
+				"+  \"role\": \"admin\"\n" +
+				" }\n",
+		}
+
+		if diff := cmp.Diff(expDiff, comparison.Diff); diff != "" {
+			t.Fatalf("response diff not equal (-exp, +got):\n%v", diff)
+		}
+
+		comparisons, err := svc.FindComparisons(ctx, req.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(comparisons) != 2 || comparisons[1].ID != comparison.ID {
+			t.Fatalf("expected comparison to be stored last (got: %v comparisons)", len(comparisons))
+		}
+	})
+
+	t.Run("failed request", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closedURL, _ := url.Parse(closed.URL)
+		closed.Close()
+
+		comparison, err := svc.CompareRequest(ctx, sender.CompareParams{
+			RequestID: req.ID,
+			BaseURLs:  [2]*url.URL{v1, closedURL},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if comparison.Targets[1].Err == "" || comparison.Targets[1].Response != nil {
+			t.Fatalf("expected error of second target (got: %+v)", comparison.Targets[1])
+		}
+
+		if comparison.Diff.Equal {
+			t.Fatal("expected responses to be unequal")
+		}
+	})
+
+	t.Run("invalid base URL", func(t *testing.T) {
+		_, err := svc.CompareRequest(ctx, sender.CompareParams{
+			RequestID: req.ID,
+			BaseURLs:  [2]*url.URL{v1, {Path: "/foo"}},
+		})
+		if !errors.Is(err, sender.ErrInvalidComparison) {
+			t.Fatalf("expected `sender.ErrInvalidComparison`, got: %v", err)
+		}
+	})
+}
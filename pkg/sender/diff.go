@@ -0,0 +1,149 @@
+package sender
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Lines of context around changed lines in a diff.
+	diffContextLines = 3
+	// Maximum size of the table used to find the longest common subsequence
+	// of lines. Larger inputs are diffed as if all lines changed.
+	maxDiffCells = 1 << 22
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	// Line numbers in a and b (zero based) before the operation.
+	aLine, bLine int
+}
+
+// lineDiff returns a unified diff of the lines of a and b, or an empty string
+// if they're equal.
+func lineDiff(a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := diffLines(strings.Split(a, "\n"), strings.Split(b, "\n"))
+
+	var sb strings.Builder
+
+	for start := 0; start < len(ops); {
+		// Find the next change, and the end of the hunk it's in: changes that
+		// are separated by less than twice the context are in the same hunk.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+
+		if first == len(ops) {
+			break
+		}
+
+		last := first
+
+		for i := first + 1; i < len(ops) && i-last <= 2*diffContextLines; i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			}
+		}
+
+		hunkStart := first - diffContextLines
+		if hunkStart < start {
+			hunkStart = start
+		}
+
+		hunkEnd := last + diffContextLines + 1
+		if hunkEnd > len(ops) {
+			hunkEnd = len(ops)
+		}
+
+		writeHunk(&sb, ops[hunkStart:hunkEnd])
+
+		start = hunkEnd
+	}
+
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []diffOp) {
+	var aCount, bCount int
+
+	for _, op := range ops {
+		if op.kind != '+' {
+			aCount++
+		}
+
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%v,%v +%v,%v @@\n", ops[0].aLine+1, aCount, ops[0].bLine+1, bCount)
+
+	for _, op := range ops {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// diffLines returns the operations that turn a into b, based on the longest
+// common subsequence of their lines.
+func diffLines(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for i, line := range a {
+			ops = append(ops, diffOp{kind: '-', line: line, aLine: i})
+		}
+
+		for j, line := range b {
+			ops = append(ops, diffOp{kind: '+', line: line, aLine: len(a), bLine: j})
+		}
+
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i], aLine: i, bLine: j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', line: a[i], aLine: i, bLine: j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j], aLine: i, bLine: j})
+			j++
+		}
+	}
+
+	return ops
+}
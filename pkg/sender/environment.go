@@ -0,0 +1,219 @@
+package sender
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+var ErrInvalidEnvironments = errcode.New(errcode.Invalid, "sender: invalid environments")
+
+var (
+	variableNameRegexp = regexp.MustCompile(`^[A-Za-z_][\w.-]*$`)
+	variableRefRegexp  = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w.-]*)\s*\}\}`)
+	// Undoes the escaping of braces by `url.URL.String`, so that references
+	// in URLs can be expanded.
+	urlBraceUnescaper = strings.NewReplacer("%7B%7B", "{{", "%7D%7D", "}}")
+)
+
+// Environments are the sets of variables of a project, e.g. for its dev,
+// staging and prod targets. References like `{{baseURL}}` in the URL, header
+// values and body of a sender request are replaced by the variables of the
+// active environment when the request is sent.
+type Environments struct {
+	Environments []Environment
+	// Name of the environment whose variables are used. No variables are
+	// replaced if it's empty.
+	Active string
+}
+
+type Environment struct {
+	Name      string
+	Variables []Variable
+}
+
+type Variable struct {
+	Name  string
+	Value string
+}
+
+// Validate returns an error if names are empty or duplicate, if variable names
+// can't be referenced, or if the active environment doesn't exist.
+func (envs Environments) Validate() error {
+	envNames := make(map[string]bool, len(envs.Environments))
+
+	for _, env := range envs.Environments {
+		if env.Name == "" {
+			return fmt.Errorf("%w: environment name must not be empty", ErrInvalidEnvironments)
+		}
+
+		if envNames[env.Name] {
+			return fmt.Errorf("%w: duplicate environment name %q", ErrInvalidEnvironments, env.Name)
+		}
+
+		envNames[env.Name] = true
+		varNames := make(map[string]bool, len(env.Variables))
+
+		for _, v := range env.Variables {
+			if !variableNameRegexp.MatchString(v.Name) {
+				return fmt.Errorf("%w: invalid variable name %q, must start with a letter or underscore, "+
+					"followed by letters, digits, underscores, dots or dashes", ErrInvalidEnvironments, v.Name)
+			}
+
+			if varNames[v.Name] {
+				return fmt.Errorf("%w: duplicate variable name %q in environment %q", ErrInvalidEnvironments, v.Name, env.Name)
+			}
+
+			varNames[v.Name] = true
+		}
+	}
+
+	if envs.Active != "" && !envNames[envs.Active] {
+		return fmt.Errorf("%w: active environment %q doesn't exist", ErrInvalidEnvironments, envs.Active)
+	}
+
+	return nil
+}
+
+// ActiveEnvironment returns the active environment, if any.
+func (envs Environments) ActiveEnvironment() (Environment, bool) {
+	if envs.Active == "" {
+		return Environment{}, false
+	}
+
+	for _, env := range envs.Environments {
+		if env.Name == envs.Active {
+			return env, true
+		}
+	}
+
+	return Environment{}, false
+}
+
+// withVariables returns a copy of envs, with vars added to (or replaced in)
+// the active environment. It returns false if there's no active environment.
+func (envs Environments) withVariables(vars []Variable) (Environments, bool) {
+	updated := Environments{
+		Environments: make([]Environment, len(envs.Environments)),
+		Active:       envs.Active,
+	}
+	copy(updated.Environments, envs.Environments)
+
+	for i, env := range updated.Environments {
+		if env.Name != envs.Active || envs.Active == "" {
+			continue
+		}
+
+		updated.Environments[i].Variables = mergeVariables(env.Variables, vars)
+
+		return updated, true
+	}
+
+	return envs, false
+}
+
+// mergeVariables returns a copy of variables, with vars added to (or replaced
+// in) it.
+func mergeVariables(variables, vars []Variable) []Variable {
+	merged := make([]Variable, len(variables), len(variables)+len(vars))
+	copy(merged, variables)
+
+	for _, v := range vars {
+		replaced := false
+
+		for i := range merged {
+			if merged[i].Name == v.Name {
+				merged[i].Value = v.Value
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			merged = append(merged, v)
+		}
+	}
+
+	return merged
+}
+
+// Expand replaces references to variables of env in s. References to
+// undefined variables are left as is, so that e.g. template injection
+// payloads like `{{config}}` can still be sent.
+func (env Environment) Expand(s string) string {
+	if len(env.Variables) == 0 {
+		return s
+	}
+
+	return expandVariables(s, env.lookup)
+}
+
+func (env Environment) lookup(name string) (string, bool) {
+	for _, v := range env.Variables {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// expandVariables replaces references in s to variables that lookup finds.
+func expandVariables(s string, lookup func(name string) (string, bool)) string {
+	return variableRefRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		if value, ok := lookup(variableRefRegexp.FindStringSubmatch(ref)[1]); ok {
+			return value
+		}
+
+		return ref
+	})
+}
+
+// expandRequest returns a copy of req to send, with references to variables
+// of env replaced.
+func (env Environment) expandRequest(req Request) (Request, error) {
+	if len(env.Variables) == 0 {
+		return req, nil
+	}
+
+	if req.URL != nil {
+		rawURL := urlBraceUnescaper.Replace(req.URL.String())
+
+		if expanded := env.Expand(rawURL); expanded != rawURL {
+			u, err := url.Parse(expanded)
+			if err != nil {
+				return Request{}, fmt.Errorf("invalid URL after replacing variables: %w", err)
+			}
+
+			req.URL = u
+		}
+	}
+
+	if req.Header != nil {
+		header := make(http.Header, len(req.Header))
+
+		for key, values := range req.Header {
+			expanded := make([]string, len(values))
+			for i, value := range values {
+				expanded[i] = env.Expand(value)
+			}
+
+			header[key] = expanded
+		}
+
+		req.Header = header
+	}
+
+	if req.Body != nil {
+		req.Body = []byte(env.Expand(string(req.Body)))
+	}
+
+	if req.Raw != nil {
+		req.Raw = []byte(env.Expand(string(req.Raw)))
+	}
+
+	return req, nil
+}
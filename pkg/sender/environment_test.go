@@ -0,0 +1,236 @@
+package sender_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func TestEnvironmentsValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		envs   sender.Environments
+		expErr bool
+	}{
+		{
+			name: "valid",
+			envs: sender.Environments{
+				Environments: []sender.Environment{
+					{Name: "dev", Variables: []sender.Variable{{Name: "baseURL"}, {Name: "api.token"}}},
+					{Name: "prod"},
+				},
+				Active: "prod",
+			},
+		},
+		{
+			name:   "empty environment name",
+			envs:   sender.Environments{Environments: []sender.Environment{{}}},
+			expErr: true,
+		},
+		{
+			name:   "duplicate environment name",
+			envs:   sender.Environments{Environments: []sender.Environment{{Name: "dev"}, {Name: "dev"}}},
+			expErr: true,
+		},
+		{
+			name: "invalid variable name",
+			envs: sender.Environments{Environments: []sender.Environment{
+				{Name: "dev", Variables: []sender.Variable{{Name: "base url"}}},
+			}},
+			expErr: true,
+		},
+		{
+			name: "duplicate variable name",
+			envs: sender.Environments{Environments: []sender.Environment{
+				{Name: "dev", Variables: []sender.Variable{{Name: "token"}, {Name: "token"}}},
+			}},
+			expErr: true,
+		},
+		{
+			name:   "unknown active environment",
+			envs:   sender.Environments{Environments: []sender.Environment{{Name: "dev"}}, Active: "prod"},
+			expErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.envs.Validate()
+			if tt.expErr && !errors.Is(err, sender.ErrInvalidEnvironments) {
+				t.Fatalf("expected `sender.ErrInvalidEnvironments`, got: %v", err)
+			}
+
+			if !tt.expErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestEnvironmentExpand(t *testing.T) {
+	t.Parallel()
+
+	env := sender.Environment{
+		Variables: []sender.Variable{
+			{Name: "baseURL", Value: "https://staging.example.com"},
+			{Name: "token", Value: "s3cret"},
+		},
+	}
+
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{input: "{{baseURL}}/login", exp: "https://staging.example.com/login"},
+		{input: "Bearer {{ token }}", exp: "Bearer s3cret"},
+		{input: "{{token}}{{token}}", exp: "s3crets3cret"},
+		{input: "{{config}} {{7*7}}", exp: "{{config}} {{7*7}}"},
+		{input: "{token}", exp: "{token}"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+
+			if got := env.Expand(tt.input); got != tt.exp {
+				t.Fatalf("expected %q, got: %q", tt.exp, got)
+			}
+		})
+	}
+}
+
+func TestSendRequestWithEnvironment(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.URL.Path + " " + r.Header.Get("Authorization") + " " + string(body)))
+	}))
+	t.Cleanup(upstream.Close)
+
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+		HTTPClient: &http.Client{},
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+	svc.SetEnvironments(sender.Environments{
+		Environments: []sender.Environment{
+			{
+				Name: "staging",
+				Variables: []sender.Variable{
+					{Name: "baseURL", Value: upstream.URL},
+					{Name: "token", Value: "s3cret"},
+					{Name: "id", Value: "42"},
+				},
+			},
+		},
+		Active: "staging",
+	})
+
+	u, err := url.Parse("{{baseURL}}/users/{{id}}")
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+
+	req, err := svc.CreateOrUpdateRequest(ctx, sender.Request{
+		URL:    u,
+		Proto:  sender.HTTPProto1,
+		Method: http.MethodPost,
+		Header: http.Header{"Authorization": []string{"Bearer {{token}}"}},
+		Body:   []byte(`{"id": {{id}}}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating request: %v", err)
+	}
+
+	req, err = svc.SendRequest(ctx, req.ID)
+	if err != nil {
+		t.Fatalf("unexpected error sending request: %v", err)
+	}
+
+	if exp, got := `/users/42 Bearer s3cret {"id": 42}`, string(req.Response.Body); got != exp {
+		t.Fatalf("expected response body %q, got: %q", exp, got)
+	}
+
+	// The stored request still references the variables.
+	stored, err := svc.FindRequestByID(ctx, req.ID)
+	if err != nil {
+		t.Fatalf("unexpected error finding request: %v", err)
+	}
+
+	if got := stored.Header.Get("Authorization"); got != "Bearer {{token}}" {
+		t.Fatalf("expected stored header to reference variable, got: %q", got)
+	}
+}
+
+func TestSendRequestWithRuntimeVariables(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(upstream.Close)
+
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+		HTTPClient: &http.Client{},
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	u, _ := url.Parse(upstream.URL)
+
+	req, err := svc.CreateOrUpdateRequest(ctx, sender.Request{
+		URL:    u,
+		Proto:  sender.HTTPProto1,
+		Header: http.Header{"Authorization": []string{"Bearer {{accessToken}}"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating request: %v", err)
+	}
+
+	// Runtime variables can be used without an active environment, and take
+	// precedence over its variables.
+	svc.SetRuntimeVariables([]sender.Variable{{Name: "accessToken", Value: "fresh"}})
+
+	for _, envToken := range []string{"", "stale"} {
+		if envToken != "" {
+			svc.SetEnvironments(sender.Environments{
+				Environments: []sender.Environment{
+					{Name: "dev", Variables: []sender.Variable{{Name: "accessToken", Value: envToken}}},
+				},
+				Active: "dev",
+			})
+		}
+
+		got, err := svc.SendRequest(ctx, req.ID)
+		if err != nil {
+			t.Fatalf("unexpected error sending request: %v", err)
+		}
+
+		if exp := "Bearer fresh"; string(got.Response.Body) != exp {
+			t.Fatalf("expected response body %q, got: %q", exp, got.Response.Body)
+		}
+	}
+}
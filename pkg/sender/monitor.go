@@ -0,0 +1,152 @@
+package sender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+// Maximum size of a response body in a snapshot. Changes beyond it aren't
+// detected.
+const maxSnapshotBodySize = 256 << 10
+
+// responseNormalizer removes volatile fields (e.g. of a schedule), such as
+// timestamps or CSRF tokens, from responses, so that only meaningful changes
+// are reported.
+type responseNormalizer struct {
+	jsonPaths [][]string
+	patterns  []*regexp.Regexp
+}
+
+func newResponseNormalizer(ignoreJSONPaths, ignorePatterns []string) (responseNormalizer, error) {
+	var n responseNormalizer
+
+	for _, path := range ignoreJSONPaths {
+		path = jsonPath(path)
+		if path == "" {
+			return responseNormalizer{}, fmt.Errorf("JSON path to ignore must not be empty")
+		}
+
+		n.jsonPaths = append(n.jsonPaths, strings.Split(path, "."))
+	}
+
+	for _, pattern := range ignorePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return responseNormalizer{}, fmt.Errorf("invalid pattern to ignore %q: %w", pattern, err)
+		}
+
+		n.patterns = append(n.patterns, re)
+	}
+
+	return n, nil
+}
+
+// snapshot returns the status line and normalized body of a response.
+func (n responseNormalizer) snapshot(res reqlog.ResponseLog) string {
+	return fmt.Sprintf("HTTP %v\n\n", res.StatusCode) + n.normalizeBody(res.Body)
+}
+
+// normalizeBody returns body without volatile fields. JSON bodies are
+// indented, so that a diff of normalized bodies shows changed fields.
+func (n responseNormalizer) normalizeBody(body []byte) string {
+	truncated := len(body) > maxSnapshotBodySize
+
+	if truncated {
+		body = body[:maxSnapshotBodySize]
+	} else if normalized, ok := n.normalizeJSON(body); ok {
+		body = normalized
+	}
+
+	for _, re := range n.patterns {
+		body = re.ReplaceAll(body, []byte("<ignored>"))
+	}
+
+	var sb strings.Builder
+
+	sb.Write(body)
+
+	if truncated {
+		sb.WriteString("\n<truncated>")
+	}
+
+	return sb.String()
+}
+
+func (n responseNormalizer) normalizeJSON(body []byte) ([]byte, bool) {
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return nil, false
+	}
+
+	for _, keys := range n.jsonPaths {
+		deleteJSONPath(v, keys)
+	}
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		return nil, false
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), true
+}
+
+// deleteJSONPath removes the value at a path from a decoded JSON document.
+// A `*` matches all elements of an array, or all fields of an object.
+func deleteJSONPath(v interface{}, keys []string) {
+	key, rest := keys[0], keys[1:]
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if key == "*" {
+			for field := range t {
+				deleteJSONField(t, field, rest)
+			}
+
+			return
+		}
+
+		deleteJSONField(t, key, rest)
+	case []interface{}:
+		if key == "*" {
+			for _, elem := range t {
+				if len(rest) > 0 {
+					deleteJSONPath(elem, rest)
+				}
+			}
+
+			return
+		}
+
+		// Array elements can't be removed without changing the indices of
+		// others, so only values within them are.
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(t) && len(rest) > 0 {
+			deleteJSONPath(t[i], rest)
+		}
+	}
+}
+
+func deleteJSONField(obj map[string]interface{}, field string, rest []string) {
+	if len(rest) == 0 {
+		delete(obj, field)
+		return
+	}
+
+	if value, ok := obj[field]; ok {
+		deleteJSONPath(value, rest)
+	}
+}
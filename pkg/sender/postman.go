@@ -0,0 +1,369 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+var ErrInvalidPostmanCollection = errcode.New(errcode.Invalid, "sender: invalid Postman collection")
+
+// Postman collection v2.1 types, see: https://schema.postman.com.
+type (
+	postmanCollection struct {
+		Info postmanInfo   `json:"info"`
+		Item []postmanItem `json:"item"`
+		Auth *postmanAuth  `json:"auth,omitempty"`
+	}
+	postmanInfo struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	}
+	// A request, or a folder of items.
+	postmanItem struct {
+		Name    string          `json:"name"`
+		Request *postmanRequest `json:"request,omitempty"`
+		Item    []postmanItem   `json:"item,omitempty"`
+		Auth    *postmanAuth    `json:"auth,omitempty"`
+	}
+	postmanRequest struct {
+		Method string       `json:"method"`
+		Header []postmanKV  `json:"header"`
+		URL    postmanURL   `json:"url"`
+		Body   *postmanBody `json:"body,omitempty"`
+		Auth   *postmanAuth `json:"auth,omitempty"`
+	}
+	// URL is either a string or an object, of which only `raw` is used.
+	postmanURL struct {
+		Raw string `json:"raw"`
+	}
+	postmanKV struct {
+		Key      string `json:"key"`
+		Value    string `json:"value"`
+		Type     string `json:"type,omitempty"`
+		Disabled bool   `json:"disabled,omitempty"`
+	}
+	postmanBody struct {
+		Mode       string          `json:"mode"`
+		Raw        string          `json:"raw,omitempty"`
+		URLEncoded []postmanKV     `json:"urlencoded,omitempty"`
+		FormData   []postmanKV     `json:"formdata,omitempty"`
+		GraphQL    *postmanGraphQL `json:"graphql,omitempty"`
+	}
+	postmanGraphQL struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables,omitempty"`
+	}
+	postmanAuth struct {
+		Type   string      `json:"type"`
+		Bearer []postmanKV `json:"bearer,omitempty"`
+		Basic  []postmanKV `json:"basic,omitempty"`
+		APIKey []postmanKV `json:"apikey,omitempty"`
+	}
+)
+
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &u.Raw)
+	}
+
+	var v struct {
+		Raw string `json:"raw"`
+	}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	u.Raw = v.Raw
+
+	return nil
+}
+
+// ParsePostmanCollection parses a Postman collection (v2.0 or v2.1). Requests
+// in folders are flattened, in order. `{{var}}` references are kept, so they
+// can be replaced by the variables of an environment. Collection variables,
+// scripts and file parameters of form data bodies aren't imported.
+func ParsePostmanCollection(data []byte) (name string, reqs []Request, err error) {
+	var collection postmanCollection
+
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidPostmanCollection, err)
+	}
+
+	if collection.Info.Name == "" {
+		return "", nil, fmt.Errorf("%w: collection has no name", ErrInvalidPostmanCollection)
+	}
+
+	if err := collectPostmanRequests(collection.Item, collection.Auth, &reqs); err != nil {
+		return "", nil, err
+	}
+
+	return collection.Info.Name, reqs, nil
+}
+
+func collectPostmanRequests(items []postmanItem, auth *postmanAuth, reqs *[]Request) error {
+	for _, item := range items {
+		// Auth is inherited by the items of a folder.
+		itemAuth := auth
+		if item.Auth != nil {
+			itemAuth = item.Auth
+		}
+
+		if item.Request == nil {
+			if err := collectPostmanRequests(item.Item, itemAuth, reqs); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if item.Request.Auth != nil {
+			itemAuth = item.Request.Auth
+		}
+
+		req, err := parsePostmanRequest(*item.Request, itemAuth)
+		if err != nil {
+			return fmt.Errorf("%w: request %q: %v", ErrInvalidPostmanCollection, item.Name, err)
+		}
+
+		*reqs = append(*reqs, req)
+	}
+
+	return nil
+}
+
+func parsePostmanRequest(pmReq postmanRequest, auth *postmanAuth) (Request, error) {
+	rawURL := strings.TrimSpace(pmReq.URL.Raw)
+	if rawURL == "" {
+		return Request{}, errors.New("URL must be set")
+	}
+
+	// Postman allows URLs without scheme.
+	if !strings.Contains(rawURL, "://") && !strings.HasPrefix(rawURL, "{{") {
+		rawURL = "http://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Request{}, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	req := Request{
+		URL:    u,
+		Method: strings.ToUpper(pmReq.Method),
+		Proto:  HTTPProto1,
+		Header: make(http.Header),
+	}
+
+	for _, kv := range pmReq.Header {
+		if !kv.Disabled {
+			req.Header.Add(kv.Key, kv.Value)
+		}
+	}
+
+	if auth != nil {
+		setPostmanAuth(req.Header, *auth)
+	}
+
+	if body := pmReq.Body; body != nil {
+		if err := setPostmanBody(&req, *body); err != nil {
+			return Request{}, err
+		}
+	}
+
+	return req, nil
+}
+
+func setPostmanAuth(header http.Header, auth postmanAuth) {
+	value := func(kvs []postmanKV, key string) string {
+		for _, kv := range kvs {
+			if kv.Key == key {
+				return kv.Value
+			}
+		}
+
+		return ""
+	}
+
+	switch auth.Type {
+	case "bearer":
+		header.Set("Authorization", "Bearer "+value(auth.Bearer, "token"))
+	case "basic":
+		credentials := value(auth.Basic, "username") + ":" + value(auth.Basic, "password")
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	case "apikey":
+		// API keys can also be sent as query parameter, which isn't supported.
+		if value(auth.APIKey, "in") != "query" {
+			header.Set(value(auth.APIKey, "key"), value(auth.APIKey, "value"))
+		}
+	}
+}
+
+func setPostmanBody(req *Request, body postmanBody) error {
+	setContentType := func(contentType string) {
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+	}
+
+	switch body.Mode {
+	case "raw":
+		if body.Raw != "" {
+			req.Body = []byte(body.Raw)
+		}
+	case "urlencoded":
+		form := url.Values{}
+		for _, kv := range body.URLEncoded {
+			if !kv.Disabled {
+				form.Add(kv.Key, kv.Value)
+			}
+		}
+
+		req.Body = []byte(form.Encode())
+		setContentType("application/x-www-form-urlencoded")
+	case "formdata":
+		var buf bytes.Buffer
+
+		w := multipart.NewWriter(&buf)
+
+		for _, kv := range body.FormData {
+			if kv.Disabled || kv.Type == "file" {
+				continue
+			}
+
+			if err := w.WriteField(kv.Key, kv.Value); err != nil {
+				return fmt.Errorf("failed to write form data: %w", err)
+			}
+		}
+
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("failed to write form data: %w", err)
+		}
+
+		req.Body = buf.Bytes()
+		// The boundary must match the body, so the header is always set.
+		req.Header.Set("Content-Type", w.FormDataContentType())
+	case "graphql":
+		if body.GraphQL == nil {
+			return nil
+		}
+
+		gqlBody := map[string]interface{}{"query": body.GraphQL.Query}
+
+		if vars := strings.TrimSpace(body.GraphQL.Variables); vars != "" {
+			gqlBody["variables"] = json.RawMessage(vars)
+		}
+
+		data, err := json.Marshal(gqlBody)
+		if err != nil {
+			return fmt.Errorf("invalid GraphQL variables: %w", err)
+		}
+
+		req.Body = data
+		setContentType("application/json")
+	}
+
+	return nil
+}
+
+// ExportPostmanCollection encodes sender requests as a Postman collection
+// v2.1. Raw requests can't be represented, and are skipped.
+func ExportPostmanCollection(name string, reqs []Request) ([]byte, error) {
+	collection := postmanCollection{
+		Info: postmanInfo{Name: name, Schema: postmanSchema},
+		Item: make([]postmanItem, 0, len(reqs)),
+	}
+
+	for _, req := range reqs {
+		if req.Raw != nil || req.URL == nil {
+			continue
+		}
+
+		// URLs escape braces, which Postman wouldn't recognize as variables.
+		rawURL := urlBraceUnescaper.Replace(req.URL.String())
+
+		pmReq := &postmanRequest{
+			Method: req.Method,
+			Header: []postmanKV{},
+			URL:    postmanURL{Raw: rawURL},
+		}
+
+		keys := make([]string, 0, len(req.Header))
+		for key := range req.Header {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			for _, value := range req.Header[key] {
+				pmReq.Header = append(pmReq.Header, postmanKV{Key: key, Value: value})
+			}
+		}
+
+		if len(req.Body) > 0 {
+			pmReq.Body = &postmanBody{Mode: "raw", Raw: string(req.Body)}
+		}
+
+		collection.Item = append(collection.Item, postmanItem{
+			Name:    req.Method + " " + rawURL,
+			Request: pmReq,
+		})
+	}
+
+	data, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("sender: failed to encode Postman collection: %w", err)
+	}
+
+	return data, nil
+}
+
+// ImportPostmanCollection creates a sender request for each request of a
+// Postman collection, in a new collection with the same name.
+func (svc *service) ImportPostmanCollection(ctx context.Context, data []byte) (Collection, error) {
+	if svc.isReadOnly() {
+		return Collection{}, ErrReadOnly
+	}
+
+	name, reqs, err := ParsePostmanCollection(data)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	collection, err := svc.CreateCollection(ctx, name)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	for _, req := range reqs {
+		req, err := svc.CreateOrUpdateRequest(ctx, req)
+		if err != nil {
+			return Collection{}, err
+		}
+
+		collection.RequestIDs = append(collection.RequestIDs, req.ID)
+	}
+
+	svc.collectionsMu.Lock()
+	defer svc.collectionsMu.Unlock()
+
+	if err := svc.repo.StoreSenderCollection(ctx, collection); err != nil {
+		return Collection{}, fmt.Errorf("sender: failed to store collection: %w", err)
+	}
+
+	return collection, nil
+}
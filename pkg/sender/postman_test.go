@@ -0,0 +1,182 @@
+package sender_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+const postmanCollection = `{
+  "info": {
+    "name": "Orders API",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "auth": {
+    "type": "bearer",
+    "bearer": [{"key": "token", "value": "{{accessToken}}"}]
+  },
+  "item": [
+    {
+      "name": "Login",
+      "request": {
+        "method": "post",
+        "auth": {"type": "noauth"},
+        "header": [
+          {"key": "X-Debug", "value": "1", "disabled": true}
+        ],
+        "url": "{{baseUrl}}/login",
+        "body": {
+          "mode": "urlencoded",
+          "urlencoded": [
+            {"key": "username", "value": "alice"},
+            {"key": "password", "value": "p4ss"},
+            {"key": "otp", "value": "123456", "disabled": true}
+          ]
+        }
+      }
+    },
+    {
+      "name": "Orders",
+      "item": [
+        {
+          "name": "List orders",
+          "request": {
+            "method": "GET",
+            "header": [{"key": "Accept", "value": "application/json"}],
+            "url": {"raw": "example.com/orders?limit=10", "host": ["example", "com"]}
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+func TestParsePostmanCollection(t *testing.T) {
+	t.Parallel()
+
+	name, reqs, err := sender.ParsePostmanCollection([]byte(postmanCollection))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if name != "Orders API" {
+		t.Fatalf("expected name %q, got: %q", "Orders API", name)
+	}
+
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 requests, got: %v", len(reqs))
+	}
+
+	type request struct {
+		Method string
+		URL    string
+		Header http.Header
+		Body   string
+	}
+
+	got := make([]request, len(reqs))
+	for i, req := range reqs {
+		got[i] = request{Method: req.Method, URL: req.URL.String(), Header: req.Header, Body: string(req.Body)}
+	}
+
+	exp := []request{
+		{
+			Method: http.MethodPost,
+			URL:    "%7B%7BbaseUrl%7D%7D/login",
+			Header: http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}},
+			Body:   "password=p4ss&username=alice",
+		},
+		{
+			Method: http.MethodGet,
+			URL:    "http://example.com/orders?limit=10",
+			Header: http.Header{
+				"Accept":        []string{"application/json"},
+				"Authorization": []string{"Bearer {{accessToken}}"},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("requests not equal (-exp, +got):\n%v", diff)
+	}
+
+	if _, _, err := sender.ParsePostmanCollection([]byte(`{"item": []}`)); !errors.Is(err, sender.ErrInvalidPostmanCollection) {
+		t.Fatalf("expected `sender.ErrInvalidPostmanCollection`, got: %v", err)
+	}
+}
+
+func TestExportPostmanCollection(t *testing.T) {
+	t.Parallel()
+
+	_, reqs, err := sender.ParsePostmanCollection([]byte(postmanCollection))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Raw requests can't be exported.
+	reqs = append(reqs, sender.Request{URL: reqs[0].URL, Raw: []byte("GET / HTTP/1.1\r\n\r\n")})
+
+	data, err := sender.ExportPostmanCollection("Export", reqs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	name, got, err := sender.ParsePostmanCollection(data)
+	if err != nil {
+		t.Fatalf("unexpected error parsing exported collection: %v", err)
+	}
+
+	if name != "Export" {
+		t.Fatalf("expected name %q, got: %q", "Export", name)
+	}
+
+	if diff := cmp.Diff(reqs[:2], got); diff != "" {
+		t.Fatalf("requests not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestImportPostmanCollection(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	collection, err := svc.ImportPostmanCollection(ctx, []byte(postmanCollection))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if collection.Name != "Orders API" || len(collection.RequestIDs) != 2 {
+		t.Fatalf("unexpected collection: %+v", collection)
+	}
+
+	got, err := svc.FindCollectionByID(ctx, collection.ID)
+	if err != nil {
+		t.Fatalf("unexpected error finding collection: %v", err)
+	}
+
+	if diff := cmp.Diff(collection.RequestIDs, got.RequestIDs); diff != "" {
+		t.Fatalf("request IDs not equal (-exp, +got):\n%v", diff)
+	}
+
+	req, err := svc.FindRequestByID(ctx, got.RequestIDs[1])
+	if err != nil {
+		t.Fatalf("unexpected error finding request: %v", err)
+	}
+
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") != "Bearer {{accessToken}}" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+}
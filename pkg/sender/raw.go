@@ -0,0 +1,146 @@
+package sender
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// DefaultRawTimeout is the time a raw request may take, from dialing until
+	// the response is read.
+	DefaultRawTimeout = 30 * time.Second
+
+	// maxRawResponseSize is the maximum number of bytes read for a raw request.
+	maxRawResponseSize = 10 << 20
+)
+
+// RawResponse holds the bytes received for a raw request.
+type RawResponse struct {
+	Raw []byte
+	// Time from writing the request until the response was read, the
+	// connection was closed by the server, or the timeout elapsed.
+	Duration time.Duration
+	// TimedOut is true if the server didn't send a complete response in time,
+	// e.g. because it's waiting for more bytes of a request body.
+	TimedOut bool
+}
+
+// SendRawRequest writes raw over a new connection to the host of u, bypassing
+// the normalization of `net/http`; e.g. duplicate headers, header casing and
+// conflicting framing headers are sent as is. For `https` URLs, a TLS
+// connection (HTTP/1.1 only) is used. The response is read until the first
+// HTTP response is complete, the server closes the connection, or timeout
+// elapses. Bytes that are received after the first response (e.g. for
+// pipelined requests) may be included. A timeout isn't considered an error.
+func SendRawRequest(ctx context.Context, u *url.URL, raw []byte, timeout time.Duration) (RawResponse, error) {
+	if timeout <= 0 {
+		timeout = DefaultRawTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := dialRaw(ctx, u)
+	if err != nil {
+		return RawResponse{}, err
+	}
+	defer conn.Close()
+
+	// Unblock reads and writes when ctx is canceled before the deadline.
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	start := time.Now()
+
+	if _, err := conn.Write(raw); err != nil {
+		return RawResponse{}, fmt.Errorf("failed to write raw request: %w", err)
+	}
+
+	buf := &bytes.Buffer{}
+	r := bufio.NewReader(io.TeeReader(io.LimitReader(conn, maxRawResponseSize), buf))
+
+	res, err := http.ReadResponse(r, nil)
+	if err == nil {
+		_, err = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+
+	rawRes := RawResponse{
+		Raw:      buf.Bytes(),
+		Duration: time.Since(start),
+	}
+
+	// Other errors mean that the server closed the connection, or that the
+	// bytes received aren't a valid HTTP response; they're returned as is.
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		if ctxErr := ctx.Err(); errors.Is(ctxErr, context.Canceled) {
+			return RawResponse{}, ctxErr
+		}
+
+		rawRes.TimedOut = true
+	}
+
+	return rawRes, nil
+}
+
+func dialRaw(ctx context.Context, u *url.URL) (net.Conn, error) {
+	if u == nil || u.Host == "" {
+		return nil, errors.New("raw request URL must have a host")
+	}
+
+	port := u.Port()
+
+	switch {
+	case port != "":
+	case u.Scheme == "https":
+		port = "443"
+	case u.Scheme == "http":
+		port = "80"
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme: %q", u.Scheme)
+	}
+
+	dialer := &net.Dialer{}
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+
+	if u.Scheme != "https" {
+		return conn, nil
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName: u.Hostname(),
+		NextProtos: []string{"http/1.1"},
+	})
+
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+
+	return tlsConn, nil
+}
@@ -0,0 +1,165 @@
+package sender_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+// newRawServer returns the URL of a TCP server that reads the request headers
+// of a single connection, sends them to received, and replies with response.
+func newRawServer(t *testing.T, response string) (*url.URL, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var head strings.Builder
+
+		r := bufio.NewReader(conn)
+
+		for {
+			line, err := r.ReadString('\n')
+			head.WriteString(line)
+
+			if err != nil || line == "\r\n" {
+				break
+			}
+		}
+
+		received <- head.String()
+
+		if response == "" {
+			// Wait for the client to give up.
+			r.ReadByte()
+			return
+		}
+
+		conn.Write([]byte(response))
+	}()
+
+	u, err := url.Parse("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u, received
+}
+
+func TestSendRawRequest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("request is sent as is", func(t *testing.T) {
+		t.Parallel()
+
+		response := "HTTP/1.1 200 OK\r\nContent-Length: 3\r\n\r\nfoo"
+		u, received := newRawServer(t, response)
+
+		raw := "GET / HTTP/1.1\r\nhost: example.com\r\nX-Foo: a\r\nx-foo: b\r\nX-Folded: a\r\n b\r\n\r\n"
+
+		got, err := sender.SendRawRequest(context.Background(), u, []byte(raw), time.Second)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if rcvd := <-received; rcvd != raw {
+			t.Fatalf("request not sent as is (expected: %q, got: %q)", raw, rcvd)
+		}
+
+		if string(got.Raw) != response {
+			t.Fatalf("unexpected response (expected: %q, got: %q)", response, got.Raw)
+		}
+
+		if got.TimedOut {
+			t.Fatal("expected response not to time out")
+		}
+	})
+
+	t.Run("incomplete response times out", func(t *testing.T) {
+		t.Parallel()
+
+		u, _ := newRawServer(t, "")
+
+		got, err := sender.SendRawRequest(context.Background(), u, []byte("GET / HTTP/1.1\r\n\r\n"), 50*time.Millisecond)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !got.TimedOut {
+			t.Fatal("expected response to time out")
+		}
+
+		if got.Duration < 50*time.Millisecond {
+			t.Fatalf("expected duration of at least the timeout, got: %v", got.Duration)
+		}
+	})
+}
+
+func TestSendRequestRaw(t *testing.T) {
+	t.Parallel()
+
+	u, _ := newRawServer(t, "HTTP/1.1 404 Not Found\r\nContent-Length: 3\r\n\r\nfoo")
+
+	reqID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	req := sender.Request{
+		ID:    reqID,
+		URL:   u,
+		Proto: sender.HTTPProto1,
+		Raw:   []byte("GET /foo HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+	}
+
+	repoMock := &RepoMock{
+		FindSenderRequestByIDFunc: func(ctx context.Context, id ulid.ULID) (sender.Request, error) {
+			return req, nil
+		},
+		StoreSenderRequestFunc: func(ctx context.Context, req sender.Request) error {
+			return nil
+		},
+		StoreResponseLogFunc: func(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error {
+			return nil
+		},
+	}
+	svc := sender.NewService(sender.Config{
+		Repository: repoMock,
+	})
+
+	got, err := svc.SendRequest(context.Background(), reqID)
+	if err != nil {
+		t.Fatalf("unexpected error sending request: %v", err)
+	}
+
+	if calls := repoMock.StoreSenderRequestCalls(); len(calls) != 1 || len(calls[0].Req.RawResponse) == 0 {
+		t.Fatal("expected sender request to be stored with raw response")
+	}
+
+	if got.Response == nil || got.Response.StatusCode != http.StatusNotFound || string(got.Response.Body) != "foo" {
+		t.Fatalf("unexpected response log: %+v", got.Response)
+	}
+
+	if len(repoMock.StoreResponseLogCalls()) != 1 {
+		t.Fatal("expected `svc.repo.StoreResponseLog()` to have been called 1 time")
+	}
+}
@@ -15,4 +15,16 @@ type Repository interface {
 	StoreSenderRequest(ctx context.Context, req Request) error
 	StoreResponseLog(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error
 	DeleteSenderRequests(ctx context.Context, projectID ulid.ULID) error
+	FindSenderCollectionByID(ctx context.Context, id ulid.ULID) (Collection, error)
+	FindSenderCollections(ctx context.Context, projectID ulid.ULID) ([]Collection, error)
+	StoreSenderCollection(ctx context.Context, collection Collection) error
+	DeleteSenderCollection(ctx context.Context, id ulid.ULID) error
+	// FindSenderScheduleRuns returns the runs of a schedule, or of all
+	// schedules of a project if name is empty, oldest first.
+	FindSenderScheduleRuns(ctx context.Context, projectID ulid.ULID, name string) ([]ScheduleRun, error)
+	StoreSenderScheduleRun(ctx context.Context, run ScheduleRun) error
+	// FindSenderComparisons returns the comparisons of a sender request, or of
+	// all requests of a project if requestID is zero, oldest first.
+	FindSenderComparisons(ctx context.Context, projectID, requestID ulid.ULID) ([]Comparison, error)
+	StoreSenderComparison(ctx context.Context, comparison Comparison) error
 }
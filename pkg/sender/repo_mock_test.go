@@ -18,49 +18,104 @@ var _ sender.Repository = &RepoMock{}
 
 // RepoMock is a mock implementation of sender.Repository.
 //
-// 	func TestSomethingThatUsesRepository(t *testing.T) {
+//	func TestSomethingThatUsesRepository(t *testing.T) {
 //
-// 		// make and configure a mocked sender.Repository
-// 		mockedRepository := &RepoMock{
-// 			DeleteSenderRequestsFunc: func(ctx context.Context, projectID ulid.ULID) error {
-// 				panic("mock out the DeleteSenderRequests method")
-// 			},
-// 			FindSenderRequestByIDFunc: func(ctx context.Context, id ulid.ULID) (sender.Request, error) {
-// 				panic("mock out the FindSenderRequestByID method")
-// 			},
-// 			FindSenderRequestsFunc: func(ctx context.Context, filter sender.FindRequestsFilter, scopeMoqParam *scope.Scope) ([]sender.Request, error) {
-// 				panic("mock out the FindSenderRequests method")
-// 			},
-// 			StoreResponseLogFunc: func(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error {
-// 				panic("mock out the StoreResponseLog method")
-// 			},
-// 			StoreSenderRequestFunc: func(ctx context.Context, req sender.Request) error {
-// 				panic("mock out the StoreSenderRequest method")
-// 			},
-// 		}
+//		// make and configure a mocked sender.Repository
+//		mockedRepository := &RepoMock{
+//			DeleteSenderCollectionFunc: func(ctx context.Context, id ulid.ULID) error {
+//				panic("mock out the DeleteSenderCollection method")
+//			},
+//			DeleteSenderRequestsFunc: func(ctx context.Context, projectID ulid.ULID) error {
+//				panic("mock out the DeleteSenderRequests method")
+//			},
+//			FindSenderCollectionByIDFunc: func(ctx context.Context, id ulid.ULID) (sender.Collection, error) {
+//				panic("mock out the FindSenderCollectionByID method")
+//			},
+//			FindSenderCollectionsFunc: func(ctx context.Context, projectID ulid.ULID) ([]sender.Collection, error) {
+//				panic("mock out the FindSenderCollections method")
+//			},
+//			FindSenderComparisonsFunc: func(ctx context.Context, projectID ulid.ULID, requestID ulid.ULID) ([]sender.Comparison, error) {
+//				panic("mock out the FindSenderComparisons method")
+//			},
+//			FindSenderRequestByIDFunc: func(ctx context.Context, id ulid.ULID) (sender.Request, error) {
+//				panic("mock out the FindSenderRequestByID method")
+//			},
+//			FindSenderRequestsFunc: func(ctx context.Context, filter sender.FindRequestsFilter, scopeMoqParam *scope.Scope) ([]sender.Request, error) {
+//				panic("mock out the FindSenderRequests method")
+//			},
+//			FindSenderScheduleRunsFunc: func(ctx context.Context, projectID ulid.ULID, name string) ([]sender.ScheduleRun, error) {
+//				panic("mock out the FindSenderScheduleRuns method")
+//			},
+//			StoreResponseLogFunc: func(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error {
+//				panic("mock out the StoreResponseLog method")
+//			},
+//			StoreSenderCollectionFunc: func(ctx context.Context, collection sender.Collection) error {
+//				panic("mock out the StoreSenderCollection method")
+//			},
+//			StoreSenderComparisonFunc: func(ctx context.Context, comparison sender.Comparison) error {
+//				panic("mock out the StoreSenderComparison method")
+//			},
+//			StoreSenderRequestFunc: func(ctx context.Context, req sender.Request) error {
+//				panic("mock out the StoreSenderRequest method")
+//			},
+//			StoreSenderScheduleRunFunc: func(ctx context.Context, run sender.ScheduleRun) error {
+//				panic("mock out the StoreSenderScheduleRun method")
+//			},
+//		}
 //
-// 		// use mockedRepository in code that requires sender.Repository
-// 		// and then make assertions.
+//		// use mockedRepository in code that requires sender.Repository
+//		// and then make assertions.
 //
-// 	}
+//	}
 type RepoMock struct {
+	// DeleteSenderCollectionFunc mocks the DeleteSenderCollection method.
+	DeleteSenderCollectionFunc func(ctx context.Context, id ulid.ULID) error
+
 	// DeleteSenderRequestsFunc mocks the DeleteSenderRequests method.
 	DeleteSenderRequestsFunc func(ctx context.Context, projectID ulid.ULID) error
 
+	// FindSenderCollectionByIDFunc mocks the FindSenderCollectionByID method.
+	FindSenderCollectionByIDFunc func(ctx context.Context, id ulid.ULID) (sender.Collection, error)
+
+	// FindSenderCollectionsFunc mocks the FindSenderCollections method.
+	FindSenderCollectionsFunc func(ctx context.Context, projectID ulid.ULID) ([]sender.Collection, error)
+
+	// FindSenderComparisonsFunc mocks the FindSenderComparisons method.
+	FindSenderComparisonsFunc func(ctx context.Context, projectID ulid.ULID, requestID ulid.ULID) ([]sender.Comparison, error)
+
 	// FindSenderRequestByIDFunc mocks the FindSenderRequestByID method.
 	FindSenderRequestByIDFunc func(ctx context.Context, id ulid.ULID) (sender.Request, error)
 
 	// FindSenderRequestsFunc mocks the FindSenderRequests method.
 	FindSenderRequestsFunc func(ctx context.Context, filter sender.FindRequestsFilter, scopeMoqParam *scope.Scope) ([]sender.Request, error)
 
+	// FindSenderScheduleRunsFunc mocks the FindSenderScheduleRuns method.
+	FindSenderScheduleRunsFunc func(ctx context.Context, projectID ulid.ULID, name string) ([]sender.ScheduleRun, error)
+
 	// StoreResponseLogFunc mocks the StoreResponseLog method.
 	StoreResponseLogFunc func(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error
 
+	// StoreSenderCollectionFunc mocks the StoreSenderCollection method.
+	StoreSenderCollectionFunc func(ctx context.Context, collection sender.Collection) error
+
+	// StoreSenderComparisonFunc mocks the StoreSenderComparison method.
+	StoreSenderComparisonFunc func(ctx context.Context, comparison sender.Comparison) error
+
 	// StoreSenderRequestFunc mocks the StoreSenderRequest method.
 	StoreSenderRequestFunc func(ctx context.Context, req sender.Request) error
 
+	// StoreSenderScheduleRunFunc mocks the StoreSenderScheduleRun method.
+	StoreSenderScheduleRunFunc func(ctx context.Context, run sender.ScheduleRun) error
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// DeleteSenderCollection holds details about calls to the DeleteSenderCollection method.
+		DeleteSenderCollection []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID ulid.ULID
+		}
 		// DeleteSenderRequests holds details about calls to the DeleteSenderRequests method.
 		DeleteSenderRequests []struct {
 			// Ctx is the ctx argument value.
@@ -68,6 +123,29 @@ type RepoMock struct {
 			// ProjectID is the projectID argument value.
 			ProjectID ulid.ULID
 		}
+		// FindSenderCollectionByID holds details about calls to the FindSenderCollectionByID method.
+		FindSenderCollectionByID []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID ulid.ULID
+		}
+		// FindSenderCollections holds details about calls to the FindSenderCollections method.
+		FindSenderCollections []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+		}
+		// FindSenderComparisons holds details about calls to the FindSenderComparisons method.
+		FindSenderComparisons []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+			// RequestID is the requestID argument value.
+			RequestID ulid.ULID
+		}
 		// FindSenderRequestByID holds details about calls to the FindSenderRequestByID method.
 		FindSenderRequestByID []struct {
 			// Ctx is the ctx argument value.
@@ -84,6 +162,15 @@ type RepoMock struct {
 			// ScopeMoqParam is the scopeMoqParam argument value.
 			ScopeMoqParam *scope.Scope
 		}
+		// FindSenderScheduleRuns holds details about calls to the FindSenderScheduleRuns method.
+		FindSenderScheduleRuns []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProjectID is the projectID argument value.
+			ProjectID ulid.ULID
+			// Name is the name argument value.
+			Name string
+		}
 		// StoreResponseLog holds details about calls to the StoreResponseLog method.
 		StoreResponseLog []struct {
 			// Ctx is the ctx argument value.
@@ -93,6 +180,20 @@ type RepoMock struct {
 			// ResLog is the resLog argument value.
 			ResLog reqlog.ResponseLog
 		}
+		// StoreSenderCollection holds details about calls to the StoreSenderCollection method.
+		StoreSenderCollection []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Collection is the collection argument value.
+			Collection sender.Collection
+		}
+		// StoreSenderComparison holds details about calls to the StoreSenderComparison method.
+		StoreSenderComparison []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Comparison is the comparison argument value.
+			Comparison sender.Comparison
+		}
 		// StoreSenderRequest holds details about calls to the StoreSenderRequest method.
 		StoreSenderRequest []struct {
 			// Ctx is the ctx argument value.
@@ -100,12 +201,63 @@ type RepoMock struct {
 			// Req is the req argument value.
 			Req sender.Request
 		}
+		// StoreSenderScheduleRun holds details about calls to the StoreSenderScheduleRun method.
+		StoreSenderScheduleRun []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Run is the run argument value.
+			Run sender.ScheduleRun
+		}
+	}
+	lockDeleteSenderCollection   sync.RWMutex
+	lockDeleteSenderRequests     sync.RWMutex
+	lockFindSenderCollectionByID sync.RWMutex
+	lockFindSenderCollections    sync.RWMutex
+	lockFindSenderComparisons    sync.RWMutex
+	lockFindSenderRequestByID    sync.RWMutex
+	lockFindSenderRequests       sync.RWMutex
+	lockFindSenderScheduleRuns   sync.RWMutex
+	lockStoreResponseLog         sync.RWMutex
+	lockStoreSenderCollection    sync.RWMutex
+	lockStoreSenderComparison    sync.RWMutex
+	lockStoreSenderRequest       sync.RWMutex
+	lockStoreSenderScheduleRun   sync.RWMutex
+}
+
+// DeleteSenderCollection calls DeleteSenderCollectionFunc.
+func (mock *RepoMock) DeleteSenderCollection(ctx context.Context, id ulid.ULID) error {
+	if mock.DeleteSenderCollectionFunc == nil {
+		panic("RepoMock.DeleteSenderCollectionFunc: method is nil but Repository.DeleteSenderCollection was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}{
+		Ctx: ctx,
+		ID:  id,
 	}
-	lockDeleteSenderRequests  sync.RWMutex
-	lockFindSenderRequestByID sync.RWMutex
-	lockFindSenderRequests    sync.RWMutex
-	lockStoreResponseLog      sync.RWMutex
-	lockStoreSenderRequest    sync.RWMutex
+	mock.lockDeleteSenderCollection.Lock()
+	mock.calls.DeleteSenderCollection = append(mock.calls.DeleteSenderCollection, callInfo)
+	mock.lockDeleteSenderCollection.Unlock()
+	return mock.DeleteSenderCollectionFunc(ctx, id)
+}
+
+// DeleteSenderCollectionCalls gets all the calls that were made to DeleteSenderCollection.
+// Check the length with:
+//
+//	len(mockedRepository.DeleteSenderCollectionCalls())
+func (mock *RepoMock) DeleteSenderCollectionCalls() []struct {
+	Ctx context.Context
+	ID  ulid.ULID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}
+	mock.lockDeleteSenderCollection.RLock()
+	calls = mock.calls.DeleteSenderCollection
+	mock.lockDeleteSenderCollection.RUnlock()
+	return calls
 }
 
 // DeleteSenderRequests calls DeleteSenderRequestsFunc.
@@ -128,7 +280,8 @@ func (mock *RepoMock) DeleteSenderRequests(ctx context.Context, projectID ulid.U
 
 // DeleteSenderRequestsCalls gets all the calls that were made to DeleteSenderRequests.
 // Check the length with:
-//     len(mockedRepository.DeleteSenderRequestsCalls())
+//
+//	len(mockedRepository.DeleteSenderRequestsCalls())
 func (mock *RepoMock) DeleteSenderRequestsCalls() []struct {
 	Ctx       context.Context
 	ProjectID ulid.ULID
@@ -143,6 +296,118 @@ func (mock *RepoMock) DeleteSenderRequestsCalls() []struct {
 	return calls
 }
 
+// FindSenderCollectionByID calls FindSenderCollectionByIDFunc.
+func (mock *RepoMock) FindSenderCollectionByID(ctx context.Context, id ulid.ULID) (sender.Collection, error) {
+	if mock.FindSenderCollectionByIDFunc == nil {
+		panic("RepoMock.FindSenderCollectionByIDFunc: method is nil but Repository.FindSenderCollectionByID was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockFindSenderCollectionByID.Lock()
+	mock.calls.FindSenderCollectionByID = append(mock.calls.FindSenderCollectionByID, callInfo)
+	mock.lockFindSenderCollectionByID.Unlock()
+	return mock.FindSenderCollectionByIDFunc(ctx, id)
+}
+
+// FindSenderCollectionByIDCalls gets all the calls that were made to FindSenderCollectionByID.
+// Check the length with:
+//
+//	len(mockedRepository.FindSenderCollectionByIDCalls())
+func (mock *RepoMock) FindSenderCollectionByIDCalls() []struct {
+	Ctx context.Context
+	ID  ulid.ULID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  ulid.ULID
+	}
+	mock.lockFindSenderCollectionByID.RLock()
+	calls = mock.calls.FindSenderCollectionByID
+	mock.lockFindSenderCollectionByID.RUnlock()
+	return calls
+}
+
+// FindSenderCollections calls FindSenderCollectionsFunc.
+func (mock *RepoMock) FindSenderCollections(ctx context.Context, projectID ulid.ULID) ([]sender.Collection, error) {
+	if mock.FindSenderCollectionsFunc == nil {
+		panic("RepoMock.FindSenderCollectionsFunc: method is nil but Repository.FindSenderCollections was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+	}
+	mock.lockFindSenderCollections.Lock()
+	mock.calls.FindSenderCollections = append(mock.calls.FindSenderCollections, callInfo)
+	mock.lockFindSenderCollections.Unlock()
+	return mock.FindSenderCollectionsFunc(ctx, projectID)
+}
+
+// FindSenderCollectionsCalls gets all the calls that were made to FindSenderCollections.
+// Check the length with:
+//
+//	len(mockedRepository.FindSenderCollectionsCalls())
+func (mock *RepoMock) FindSenderCollectionsCalls() []struct {
+	Ctx       context.Context
+	ProjectID ulid.ULID
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+	}
+	mock.lockFindSenderCollections.RLock()
+	calls = mock.calls.FindSenderCollections
+	mock.lockFindSenderCollections.RUnlock()
+	return calls
+}
+
+// FindSenderComparisons calls FindSenderComparisonsFunc.
+func (mock *RepoMock) FindSenderComparisons(ctx context.Context, projectID ulid.ULID, requestID ulid.ULID) ([]sender.Comparison, error) {
+	if mock.FindSenderComparisonsFunc == nil {
+		panic("RepoMock.FindSenderComparisonsFunc: method is nil but Repository.FindSenderComparisons was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+		RequestID ulid.ULID
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+		RequestID: requestID,
+	}
+	mock.lockFindSenderComparisons.Lock()
+	mock.calls.FindSenderComparisons = append(mock.calls.FindSenderComparisons, callInfo)
+	mock.lockFindSenderComparisons.Unlock()
+	return mock.FindSenderComparisonsFunc(ctx, projectID, requestID)
+}
+
+// FindSenderComparisonsCalls gets all the calls that were made to FindSenderComparisons.
+// Check the length with:
+//
+//	len(mockedRepository.FindSenderComparisonsCalls())
+func (mock *RepoMock) FindSenderComparisonsCalls() []struct {
+	Ctx       context.Context
+	ProjectID ulid.ULID
+	RequestID ulid.ULID
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+		RequestID ulid.ULID
+	}
+	mock.lockFindSenderComparisons.RLock()
+	calls = mock.calls.FindSenderComparisons
+	mock.lockFindSenderComparisons.RUnlock()
+	return calls
+}
+
 // FindSenderRequestByID calls FindSenderRequestByIDFunc.
 func (mock *RepoMock) FindSenderRequestByID(ctx context.Context, id ulid.ULID) (sender.Request, error) {
 	if mock.FindSenderRequestByIDFunc == nil {
@@ -163,7 +428,8 @@ func (mock *RepoMock) FindSenderRequestByID(ctx context.Context, id ulid.ULID) (
 
 // FindSenderRequestByIDCalls gets all the calls that were made to FindSenderRequestByID.
 // Check the length with:
-//     len(mockedRepository.FindSenderRequestByIDCalls())
+//
+//	len(mockedRepository.FindSenderRequestByIDCalls())
 func (mock *RepoMock) FindSenderRequestByIDCalls() []struct {
 	Ctx context.Context
 	ID  ulid.ULID
@@ -200,7 +466,8 @@ func (mock *RepoMock) FindSenderRequests(ctx context.Context, filter sender.Find
 
 // FindSenderRequestsCalls gets all the calls that were made to FindSenderRequests.
 // Check the length with:
-//     len(mockedRepository.FindSenderRequestsCalls())
+//
+//	len(mockedRepository.FindSenderRequestsCalls())
 func (mock *RepoMock) FindSenderRequestsCalls() []struct {
 	Ctx           context.Context
 	Filter        sender.FindRequestsFilter
@@ -217,6 +484,46 @@ func (mock *RepoMock) FindSenderRequestsCalls() []struct {
 	return calls
 }
 
+// FindSenderScheduleRuns calls FindSenderScheduleRunsFunc.
+func (mock *RepoMock) FindSenderScheduleRuns(ctx context.Context, projectID ulid.ULID, name string) ([]sender.ScheduleRun, error) {
+	if mock.FindSenderScheduleRunsFunc == nil {
+		panic("RepoMock.FindSenderScheduleRunsFunc: method is nil but Repository.FindSenderScheduleRuns was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+		Name      string
+	}{
+		Ctx:       ctx,
+		ProjectID: projectID,
+		Name:      name,
+	}
+	mock.lockFindSenderScheduleRuns.Lock()
+	mock.calls.FindSenderScheduleRuns = append(mock.calls.FindSenderScheduleRuns, callInfo)
+	mock.lockFindSenderScheduleRuns.Unlock()
+	return mock.FindSenderScheduleRunsFunc(ctx, projectID, name)
+}
+
+// FindSenderScheduleRunsCalls gets all the calls that were made to FindSenderScheduleRuns.
+// Check the length with:
+//
+//	len(mockedRepository.FindSenderScheduleRunsCalls())
+func (mock *RepoMock) FindSenderScheduleRunsCalls() []struct {
+	Ctx       context.Context
+	ProjectID ulid.ULID
+	Name      string
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ProjectID ulid.ULID
+		Name      string
+	}
+	mock.lockFindSenderScheduleRuns.RLock()
+	calls = mock.calls.FindSenderScheduleRuns
+	mock.lockFindSenderScheduleRuns.RUnlock()
+	return calls
+}
+
 // StoreResponseLog calls StoreResponseLogFunc.
 func (mock *RepoMock) StoreResponseLog(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error {
 	if mock.StoreResponseLogFunc == nil {
@@ -239,7 +546,8 @@ func (mock *RepoMock) StoreResponseLog(ctx context.Context, reqLogID ulid.ULID,
 
 // StoreResponseLogCalls gets all the calls that were made to StoreResponseLog.
 // Check the length with:
-//     len(mockedRepository.StoreResponseLogCalls())
+//
+//	len(mockedRepository.StoreResponseLogCalls())
 func (mock *RepoMock) StoreResponseLogCalls() []struct {
 	Ctx      context.Context
 	ReqLogID ulid.ULID
@@ -256,6 +564,78 @@ func (mock *RepoMock) StoreResponseLogCalls() []struct {
 	return calls
 }
 
+// StoreSenderCollection calls StoreSenderCollectionFunc.
+func (mock *RepoMock) StoreSenderCollection(ctx context.Context, collection sender.Collection) error {
+	if mock.StoreSenderCollectionFunc == nil {
+		panic("RepoMock.StoreSenderCollectionFunc: method is nil but Repository.StoreSenderCollection was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		Collection sender.Collection
+	}{
+		Ctx:        ctx,
+		Collection: collection,
+	}
+	mock.lockStoreSenderCollection.Lock()
+	mock.calls.StoreSenderCollection = append(mock.calls.StoreSenderCollection, callInfo)
+	mock.lockStoreSenderCollection.Unlock()
+	return mock.StoreSenderCollectionFunc(ctx, collection)
+}
+
+// StoreSenderCollectionCalls gets all the calls that were made to StoreSenderCollection.
+// Check the length with:
+//
+//	len(mockedRepository.StoreSenderCollectionCalls())
+func (mock *RepoMock) StoreSenderCollectionCalls() []struct {
+	Ctx        context.Context
+	Collection sender.Collection
+} {
+	var calls []struct {
+		Ctx        context.Context
+		Collection sender.Collection
+	}
+	mock.lockStoreSenderCollection.RLock()
+	calls = mock.calls.StoreSenderCollection
+	mock.lockStoreSenderCollection.RUnlock()
+	return calls
+}
+
+// StoreSenderComparison calls StoreSenderComparisonFunc.
+func (mock *RepoMock) StoreSenderComparison(ctx context.Context, comparison sender.Comparison) error {
+	if mock.StoreSenderComparisonFunc == nil {
+		panic("RepoMock.StoreSenderComparisonFunc: method is nil but Repository.StoreSenderComparison was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		Comparison sender.Comparison
+	}{
+		Ctx:        ctx,
+		Comparison: comparison,
+	}
+	mock.lockStoreSenderComparison.Lock()
+	mock.calls.StoreSenderComparison = append(mock.calls.StoreSenderComparison, callInfo)
+	mock.lockStoreSenderComparison.Unlock()
+	return mock.StoreSenderComparisonFunc(ctx, comparison)
+}
+
+// StoreSenderComparisonCalls gets all the calls that were made to StoreSenderComparison.
+// Check the length with:
+//
+//	len(mockedRepository.StoreSenderComparisonCalls())
+func (mock *RepoMock) StoreSenderComparisonCalls() []struct {
+	Ctx        context.Context
+	Comparison sender.Comparison
+} {
+	var calls []struct {
+		Ctx        context.Context
+		Comparison sender.Comparison
+	}
+	mock.lockStoreSenderComparison.RLock()
+	calls = mock.calls.StoreSenderComparison
+	mock.lockStoreSenderComparison.RUnlock()
+	return calls
+}
+
 // StoreSenderRequest calls StoreSenderRequestFunc.
 func (mock *RepoMock) StoreSenderRequest(ctx context.Context, req sender.Request) error {
 	if mock.StoreSenderRequestFunc == nil {
@@ -276,7 +656,8 @@ func (mock *RepoMock) StoreSenderRequest(ctx context.Context, req sender.Request
 
 // StoreSenderRequestCalls gets all the calls that were made to StoreSenderRequest.
 // Check the length with:
-//     len(mockedRepository.StoreSenderRequestCalls())
+//
+//	len(mockedRepository.StoreSenderRequestCalls())
 func (mock *RepoMock) StoreSenderRequestCalls() []struct {
 	Ctx context.Context
 	Req sender.Request
@@ -290,3 +671,39 @@ func (mock *RepoMock) StoreSenderRequestCalls() []struct {
 	mock.lockStoreSenderRequest.RUnlock()
 	return calls
 }
+
+// StoreSenderScheduleRun calls StoreSenderScheduleRunFunc.
+func (mock *RepoMock) StoreSenderScheduleRun(ctx context.Context, run sender.ScheduleRun) error {
+	if mock.StoreSenderScheduleRunFunc == nil {
+		panic("RepoMock.StoreSenderScheduleRunFunc: method is nil but Repository.StoreSenderScheduleRun was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Run sender.ScheduleRun
+	}{
+		Ctx: ctx,
+		Run: run,
+	}
+	mock.lockStoreSenderScheduleRun.Lock()
+	mock.calls.StoreSenderScheduleRun = append(mock.calls.StoreSenderScheduleRun, callInfo)
+	mock.lockStoreSenderScheduleRun.Unlock()
+	return mock.StoreSenderScheduleRunFunc(ctx, run)
+}
+
+// StoreSenderScheduleRunCalls gets all the calls that were made to StoreSenderScheduleRun.
+// Check the length with:
+//
+//	len(mockedRepository.StoreSenderScheduleRunCalls())
+func (mock *RepoMock) StoreSenderScheduleRunCalls() []struct {
+	Ctx context.Context
+	Run sender.ScheduleRun
+} {
+	var calls []struct {
+		Ctx context.Context
+		Run sender.ScheduleRun
+	}
+	mock.lockStoreSenderScheduleRun.RLock()
+	calls = mock.calls.StoreSenderScheduleRun
+	mock.lockStoreSenderScheduleRun.RUnlock()
+	return calls
+}
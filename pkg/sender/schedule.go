@@ -0,0 +1,408 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+var defaultWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+var ErrInvalidSchedules = errcode.New(errcode.Invalid, "sender: invalid schedules")
+
+// Schedule sends requests on an interval and checks their assertions, so that
+// a target can be monitored for regressions during an engagement. Requests
+// are selected by ID, by collection, or else all requests with assertions
+// (see `Service.RunAssertionSuite`) are sent.
+type Schedule struct {
+	Name         string
+	RequestIDs   []ulid.ULID
+	CollectionID ulid.ULID
+	Interval     time.Duration
+	// URL that a JSON summary of a run is posted to when an assertion fails,
+	// or, if AlertOnChange is true, when a response changed since the
+	// previous run. Optional.
+	WebhookURL    string
+	AlertOnChange bool
+	// Volatile fields that are ignored when responses are compared: JSON
+	// paths (e.g. `$.items.*.updatedAt`), and patterns that are replaced in
+	// the body (e.g. `csrf_token=\w+`).
+	IgnoreJSONPaths []string
+	IgnorePatterns  []string
+	// Paused schedules aren't run, but are kept so that they can be resumed.
+	Paused bool
+}
+
+// ScheduleRun is the stored result of a run of a schedule.
+type ScheduleRun struct {
+	ID        ulid.ULID
+	ProjectID ulid.ULID
+	Schedule  string
+	Results   []ScheduleRunResult
+	Passed    int
+	Failed    int
+	Changed   int
+}
+
+// ScheduleRunResult is the result of sending a request in a schedule run.
+// Responses themselves aren't stored with the run, only the last response is
+// stored with the request.
+type ScheduleRunResult struct {
+	RequestID  ulid.ULID
+	StatusCode int
+	// SHA-256 hash of the snapshot of the response, used to detect changes.
+	SnapshotHash string
+	// Status and body of the response, without volatile fields. Only stored
+	// on the first run, and when the response changed.
+	Snapshot   string
+	Err        string
+	Assertions []AssertionResult
+	Passed     bool
+	// True if the snapshot differs from the one of the previous response.
+	Changed bool
+	// Unified diff of the previous and current snapshot, if changed.
+	Diff string
+}
+
+// scheduleState is the state of a schedule between its runs.
+type scheduleState struct {
+	normalizer responseNormalizer
+	// Last result with a snapshot of each request; nil until loaded from the
+	// stored runs.
+	snapshots map[ulid.ULID]ScheduleRunResult
+}
+
+// Payload of webhook alerts.
+type scheduleAlert struct {
+	Schedule  string              `json:"schedule"`
+	RunID     string              `json:"runID"`
+	Timestamp time.Time           `json:"timestamp"`
+	Passed    int                 `json:"passed"`
+	Failed    int                 `json:"failed"`
+	Changed   int                 `json:"changed"`
+	Results   []scheduleAlertItem `json:"results"`
+}
+
+type scheduleAlertItem struct {
+	RequestID  string   `json:"requestID"`
+	StatusCode int      `json:"statusCode,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	Passed     bool     `json:"passed"`
+	Changed    bool     `json:"changed"`
+	Failures   []string `json:"failures,omitempty"`
+	Diff       string   `json:"diff,omitempty"`
+}
+
+// ValidateSchedules returns an error if schedules have an empty or duplicate
+// name, no positive interval, both request IDs and a collection, an invalid
+// webhook URL, or invalid volatile fields.
+func ValidateSchedules(schedules []Schedule) error {
+	names := make(map[string]bool, len(schedules))
+
+	for _, schedule := range schedules {
+		if schedule.Name == "" {
+			return fmt.Errorf("%w: name must not be empty", ErrInvalidSchedules)
+		}
+
+		if names[schedule.Name] {
+			return fmt.Errorf("%w: duplicate name %q", ErrInvalidSchedules, schedule.Name)
+		}
+
+		names[schedule.Name] = true
+
+		if schedule.Interval <= 0 {
+			return fmt.Errorf("%w: interval of schedule %q must be positive", ErrInvalidSchedules, schedule.Name)
+		}
+
+		if len(schedule.RequestIDs) > 0 && schedule.CollectionID.Compare(ulid.ULID{}) != 0 {
+			return fmt.Errorf("%w: schedule %q must not have both request IDs and a collection",
+				ErrInvalidSchedules, schedule.Name)
+		}
+
+		if schedule.WebhookURL != "" {
+			u, err := url.Parse(schedule.WebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: webhook URL of schedule %q must be an absolute HTTP(S) URL",
+					ErrInvalidSchedules, schedule.Name)
+			}
+		}
+
+		if _, err := newResponseNormalizer(schedule.IgnoreJSONPaths, schedule.IgnorePatterns); err != nil {
+			return fmt.Errorf("%w: schedule %q: %v", ErrInvalidSchedules, schedule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// SetSchedules replaces the schedules of the active project. Each schedule that
+// isn't paused is first run after its interval.
+func (svc *service) SetSchedules(schedules []Schedule) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	svc.mu.Lock()
+
+	if svc.cancelSchedules != nil {
+		svc.cancelSchedules()
+	}
+
+	svc.schedules = schedules
+	svc.cancelSchedules = cancel
+
+	svc.mu.Unlock()
+
+	for _, schedule := range schedules {
+		if schedule.Paused {
+			continue
+		}
+
+		go svc.runSchedule(ctx, schedule)
+	}
+}
+
+func (svc *service) Schedules() []Schedule {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.schedules
+}
+
+// FindScheduleRuns returns the runs of a schedule of the active project, or
+// of all its schedules if name is empty, oldest first.
+func (svc *service) FindScheduleRuns(ctx context.Context, name string) ([]ScheduleRun, error) {
+	projectID := svc.activeProject()
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return nil, ErrProjectIDMustBeSet
+	}
+
+	runs, err := svc.repo.FindSenderScheduleRuns(ctx, projectID, name)
+	if err != nil {
+		return nil, fmt.Errorf("sender: failed to find schedule runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// runSchedule runs schedule on its interval, until ctx is done.
+func (svc *service) runSchedule(ctx context.Context, schedule Schedule) {
+	normalizer, err := newResponseNormalizer(schedule.IgnoreJSONPaths, schedule.IgnorePatterns)
+	if err != nil {
+		log.Printf("[ERROR] Invalid sender schedule (name: %v): %v", schedule.Name, err)
+		return
+	}
+
+	state := &scheduleState{normalizer: normalizer}
+
+	ticker := time.NewTicker(schedule.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := svc.runScheduleOnce(ctx, schedule, state); err != nil && ctx.Err() == nil {
+			log.Printf("[ERROR] Could not run sender schedule (name: %v): %v", schedule.Name, err)
+		}
+	}
+}
+
+func (svc *service) runScheduleOnce(ctx context.Context, schedule Schedule, state *scheduleState) error {
+	projectID := svc.activeProject()
+	if projectID.Compare(ulid.ULID{}) == 0 {
+		return ErrProjectIDMustBeSet
+	}
+
+	// Runs aren't stored for read-only projects.
+	if svc.isReadOnly() {
+		return nil
+	}
+
+	var (
+		results []CollectionRunResult
+		err     error
+	)
+
+	switch {
+	case len(schedule.RequestIDs) > 0:
+		results, err = svc.runRequests(ctx, schedule.RequestIDs)
+	case schedule.CollectionID.Compare(ulid.ULID{}) != 0:
+		results, err = svc.RunCollection(ctx, schedule.CollectionID)
+	default:
+		results, err = svc.RunAssertionSuite(ctx)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Discard runs that were interrupted, e.g. because the project was closed.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if state.snapshots == nil {
+		if err := state.loadSnapshots(ctx, svc.repo, projectID, schedule.Name); err != nil {
+			return err
+		}
+	}
+
+	run := state.newRun(svc.ids.New(time.Now()), projectID, schedule.Name, results)
+
+	if err := svc.repo.StoreSenderScheduleRun(ctx, run); err != nil {
+		return fmt.Errorf("sender: failed to store schedule run: %w", err)
+	}
+
+	if schedule.WebhookURL != "" && (run.Failed > 0 || (schedule.AlertOnChange && run.Changed > 0)) {
+		if err := svc.sendScheduleAlert(ctx, schedule.WebhookURL, run); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadSnapshots loads the last snapshot of each request from the stored runs
+// of a schedule, so that responses are compared across restarts.
+func (state *scheduleState) loadSnapshots(ctx context.Context, repo Repository, projectID ulid.ULID, name string) error {
+	runs, err := repo.FindSenderScheduleRuns(ctx, projectID, name)
+	if err != nil {
+		return fmt.Errorf("sender: failed to find schedule runs: %w", err)
+	}
+
+	state.snapshots = make(map[ulid.ULID]ScheduleRunResult)
+
+	for _, run := range runs {
+		for _, result := range run.Results {
+			if result.Snapshot != "" {
+				state.snapshots[result.RequestID] = result
+			}
+		}
+	}
+
+	return nil
+}
+
+// newRun summarizes the results of a run. Responses are compared with the
+// last snapshot of their request, if any.
+func (state *scheduleState) newRun(id, projectID ulid.ULID, name string, results []CollectionRunResult) ScheduleRun {
+	run := ScheduleRun{
+		ID:        id,
+		ProjectID: projectID,
+		Schedule:  name,
+		Results:   make([]ScheduleRunResult, len(results)),
+	}
+
+	for i, result := range results {
+		runResult := ScheduleRunResult{
+			RequestID:  result.Request.ID,
+			Assertions: result.Assertions,
+			Passed:     result.Passed(),
+		}
+
+		// Requests that couldn't be sent are failures, not changes.
+		if result.Err != nil {
+			runResult.Err = result.Err.Error()
+		} else if res := result.Request.Response; res != nil {
+			snapshot := state.normalizer.snapshot(*res)
+			hash := sha256.Sum256([]byte(snapshot))
+
+			runResult.StatusCode = res.StatusCode
+			runResult.SnapshotHash = hex.EncodeToString(hash[:])
+
+			prev, ok := state.snapshots[runResult.RequestID]
+
+			if !ok || prev.SnapshotHash != runResult.SnapshotHash {
+				runResult.Snapshot = snapshot
+				state.snapshots[runResult.RequestID] = runResult
+			}
+
+			if ok && prev.SnapshotHash != runResult.SnapshotHash {
+				runResult.Changed = true
+				runResult.Diff = lineDiff(prev.Snapshot, snapshot)
+			}
+		}
+
+		if runResult.Passed {
+			run.Passed++
+		} else {
+			run.Failed++
+		}
+
+		if runResult.Changed {
+			run.Changed++
+		}
+
+		run.Results[i] = runResult
+	}
+
+	return run
+}
+
+func (svc *service) sendScheduleAlert(ctx context.Context, webhookURL string, run ScheduleRun) error {
+	alert := scheduleAlert{
+		Schedule:  run.Schedule,
+		RunID:     run.ID.String(),
+		Timestamp: ulid.Time(run.ID.Time()),
+		Passed:    run.Passed,
+		Failed:    run.Failed,
+		Changed:   run.Changed,
+		Results:   make([]scheduleAlertItem, len(run.Results)),
+	}
+
+	for i, result := range run.Results {
+		item := scheduleAlertItem{
+			RequestID:  result.RequestID.String(),
+			StatusCode: result.StatusCode,
+			Error:      result.Err,
+			Passed:     result.Passed,
+			Changed:    result.Changed,
+			Diff:       result.Diff,
+		}
+
+		for _, assertion := range result.Assertions {
+			if !assertion.Passed {
+				item.Failures = append(item.Failures, assertion.Message)
+			}
+		}
+
+		alert.Results[i] = item
+	}
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("sender: failed to encode webhook alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sender: failed to create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := svc.webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sender: failed to send webhook alert: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("sender: webhook returned unexpected status code: %v", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package sender_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func TestValidateSchedules(t *testing.T) {
+	t.Parallel()
+
+	valid := sender.Schedule{Name: "nightly", Interval: time.Hour}
+
+	tests := []struct {
+		name      string
+		schedules func() []sender.Schedule
+	}{
+		{
+			name:      "duplicate name",
+			schedules: func() []sender.Schedule { return []sender.Schedule{valid, valid} },
+		},
+		{
+			name: "no interval",
+			schedules: func() []sender.Schedule {
+				s := valid
+				s.Interval = 0
+				return []sender.Schedule{s}
+			},
+		},
+		{
+			name: "request IDs and collection",
+			schedules: func() []sender.Schedule {
+				s := valid
+				s.RequestIDs = []ulid.ULID{ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)}
+				s.CollectionID = ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+				return []sender.Schedule{s}
+			},
+		},
+		{
+			name: "relative webhook URL",
+			schedules: func() []sender.Schedule {
+				s := valid
+				s.WebhookURL = "/alerts"
+				return []sender.Schedule{s}
+			},
+		},
+		{
+			name: "invalid pattern to ignore",
+			schedules: func() []sender.Schedule {
+				s := valid
+				s.IgnorePatterns = []string{"("}
+				return []sender.Schedule{s}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := sender.ValidateSchedules(tt.schedules()); !errors.Is(err, sender.ErrInvalidSchedules) {
+				t.Fatalf("expected `sender.ErrInvalidSchedules`, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var hits int32
+
+	// The timestamp changes on every request, the version after the second.
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+
+		version := 1
+		if n > 2 {
+			version = 2
+		}
+
+		fmt.Fprintf(w, `{"version": %v, "timestamp": %v}`, version, n)
+	}))
+	t.Cleanup(upstream.Close)
+
+	type alert struct {
+		Schedule string `json:"schedule"`
+		Failed   int    `json:"failed"`
+		Changed  int    `json:"changed"`
+	}
+
+	alerts := make(chan alert, 10)
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case alerts <- a:
+		default:
+		}
+	}))
+	t.Cleanup(webhook.Close)
+
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+		HTTPClient: &http.Client{},
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	u, _ := url.Parse(upstream.URL)
+
+	req, err := svc.CreateOrUpdateRequest(ctx, sender.Request{
+		URL:        u,
+		Proto:      sender.HTTPProto1,
+		Assertions: []sender.Assertion{{Type: sender.AssertionStatusEquals, Value: "200"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating request: %v", err)
+	}
+
+	svc.SetSchedules([]sender.Schedule{
+		{
+			Name:            "monitor",
+			RequestIDs:      []ulid.ULID{req.ID},
+			Interval:        10 * time.Millisecond,
+			WebhookURL:      webhook.URL,
+			AlertOnChange:   true,
+			IgnoreJSONPaths: []string{"$.timestamp"},
+		},
+	})
+	t.Cleanup(func() { svc.SetSchedules(nil) })
+
+	select {
+	case got := <-alerts:
+		if got.Schedule != "monitor" || got.Changed != 1 || got.Failed != 0 {
+			t.Fatalf("unexpected alert: %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected webhook alert for changed response")
+	}
+
+	svc.SetSchedules(nil)
+
+	runs, err := svc.FindScheduleRuns(ctx, "monitor")
+	if err != nil {
+		t.Fatalf("unexpected error finding schedule runs: %v", err)
+	}
+
+	if len(runs) < 3 {
+		t.Fatalf("expected at least 3 runs, got: %v", len(runs))
+	}
+
+	first, second, third := runs[0].Results[0], runs[1].Results[0], runs[2].Results[0]
+
+	if !first.Passed || first.Changed || first.StatusCode != http.StatusOK || first.Snapshot == "" {
+		t.Fatalf("unexpected result of first run: %+v", first)
+	}
+
+	// Only the ignored timestamp changed.
+	if second.Changed || second.Snapshot != "" || second.SnapshotHash != first.SnapshotHash {
+		t.Fatalf("unexpected result of second run: %+v", second)
+	}
+
+	expDiff := "@@ -1,5 +1,5 @@\n HTTP 200\n \n {\n-  \"version\": 1\n+  \"version\": 2\n }\n"
+
+	if !third.Changed || third.Diff != expDiff {
+		t.Fatalf("unexpected result of third run: %+v", third)
+	}
+
+	if runs, err := svc.FindScheduleRuns(ctx, "other"); err != nil || len(runs) != 0 {
+		t.Fatalf("expected no runs of other schedule, got: %v (error: %v)", len(runs), err)
+	}
+}
@@ -0,0 +1,645 @@
+package sender
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+)
+
+var (
+	ErrInvalidScript = errcode.New(errcode.Invalid, "sender: invalid script")
+	ErrScriptFailed  = errors.New("sender: script failed")
+)
+
+// Scripts are small programs attached to a sender request, with one command
+// per line. Arguments are separated by whitespace, and can be double quoted
+// (with Go escape sequences). Lines starting with `#` are comments.
+//
+// References like `{{name}}` in arguments are replaced by variables set by
+// earlier commands, the variables of the active environment, and these
+// built-in variables: `request.method`, `request.url`, `request.host`,
+// `request.path`, `request.query`, `request.body`, `timestamp` (Unix time),
+// `timestamp.ms` and `date.iso` (RFC 3339, UTC). In post-response scripts,
+// `response.status` and `response.body` are set too.
+//
+// Commands of both pre-request and post-response scripts:
+//
+//	set NAME VALUE
+//	hash NAME ALG DATA [ENCODING]
+//	hmac NAME ALG KEY DATA [ENCODING]
+//
+// Where ALG is `md5`, `sha1`, `sha256` or `sha512`, and ENCODING is `hex`
+// (default) or `base64`. Commands of pre-request scripts, which run before the
+// request is sent:
+//
+//	header NAME VALUE
+//	sigv4 REGION SERVICE ACCESS_KEY_ID SECRET_ACCESS_KEY [SESSION_TOKEN]
+//
+// Where `sigv4` signs the request with AWS Signature Version 4, so it's
+// typically the last command. Commands of post-response scripts, which run
+// after the response is stored:
+//
+//	extract NAME json PATH
+//	extract NAME header KEY
+//	extract NAME regexp PATTERN
+//
+// Where `extract` stores a value of the response as variable of the active
+// environment, e.g. a session token for the next requests. PATH is a dot
+// separated list of object keys and array indices (e.g. `data.items.0.id`). For
+// `regexp`, the first submatch is used, or the whole match if the pattern has
+// no groups.
+
+type scriptPhase int
+
+const (
+	anyPhase scriptPhase = iota
+	preRequestPhase
+	postResponsePhase
+)
+
+type scriptCommandSpec struct {
+	minArgs int
+	maxArgs int
+	// Phase the command is restricted to, if any.
+	phase scriptPhase
+}
+
+var (
+	scriptCommandSpecs = map[string]scriptCommandSpec{
+		"set":     {minArgs: 2, maxArgs: 2},
+		"hash":    {minArgs: 3, maxArgs: 4},
+		"hmac":    {minArgs: 4, maxArgs: 5},
+		"header":  {minArgs: 2, maxArgs: 2, phase: preRequestPhase},
+		"sigv4":   {minArgs: 4, maxArgs: 5, phase: preRequestPhase},
+		"extract": {minArgs: 3, maxArgs: 3, phase: postResponsePhase},
+	}
+
+	hashFuncs = map[string]func() hash.Hash{
+		"md5":    md5.New,
+		"sha1":   sha1.New,
+		"sha256": sha256.New,
+		"sha512": sha512.New,
+	}
+)
+
+type scriptCommand struct {
+	line int
+	name string
+	args []string
+	// Compiled pattern of `extract NAME regexp PATTERN`.
+	re *regexp.Regexp
+}
+
+// scriptRun holds the variables of a script run.
+type scriptRun struct {
+	env      Environment
+	hasEnv   bool
+	vars     map[string]string
+	exported []Variable
+}
+
+func (phase scriptPhase) String() string {
+	if phase == postResponsePhase {
+		return "post-response"
+	}
+
+	return "pre-request"
+}
+
+func validateScripts(req Request) error {
+	if req.PreRequestScript == "" && req.PostResponseScript == "" {
+		return nil
+	}
+
+	if req.Raw != nil {
+		return fmt.Errorf("%w: scripts aren't supported for raw requests", ErrInvalidScript)
+	}
+
+	if _, err := parseScript(req.PreRequestScript, preRequestPhase); err != nil {
+		return err
+	}
+
+	if _, err := parseScript(req.PostResponseScript, postResponsePhase); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func parseScript(script string, phase scriptPhase) ([]scriptCommand, error) {
+	var cmds []scriptCommand
+
+	for i, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cmd, err := parseScriptLine(line, phase)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v script: line %v: %v", ErrInvalidScript, phase, i+1, err)
+		}
+
+		cmd.line = i + 1
+		cmds = append(cmds, cmd)
+	}
+
+	return cmds, nil
+}
+
+func parseScriptLine(line string, phase scriptPhase) (scriptCommand, error) {
+	args, err := splitScriptLine(line)
+	if err != nil {
+		return scriptCommand{}, err
+	}
+
+	cmd := scriptCommand{name: args[0], args: args[1:]}
+
+	spec, ok := scriptCommandSpecs[cmd.name]
+	if !ok {
+		return scriptCommand{}, fmt.Errorf("unknown command %q", cmd.name)
+	}
+
+	if spec.phase != anyPhase && spec.phase != phase {
+		return scriptCommand{}, fmt.Errorf("command %q is only supported in %v scripts", cmd.name, spec.phase)
+	}
+
+	if n := len(cmd.args); n < spec.minArgs || n > spec.maxArgs {
+		return scriptCommand{}, fmt.Errorf("invalid number of arguments for command %q", cmd.name)
+	}
+
+	switch cmd.name {
+	case "set", "hash", "hmac", "extract":
+		if !variableNameRegexp.MatchString(cmd.args[0]) {
+			return scriptCommand{}, fmt.Errorf("invalid variable name %q", cmd.args[0])
+		}
+	}
+
+	switch cmd.name {
+	case "hash", "hmac":
+		if _, ok := hashFuncs[cmd.args[1]]; !ok {
+			return scriptCommand{}, fmt.Errorf("unsupported hash algorithm %q", cmd.args[1])
+		}
+
+		if n := len(cmd.args); n == spec.maxArgs && cmd.args[n-1] != "hex" && cmd.args[n-1] != "base64" {
+			return scriptCommand{}, fmt.Errorf("unsupported encoding %q", cmd.args[n-1])
+		}
+	case "extract":
+		switch cmd.args[1] {
+		case "json", "header":
+		case "regexp":
+			if cmd.re, err = regexp.Compile(cmd.args[2]); err != nil {
+				return scriptCommand{}, fmt.Errorf("invalid pattern: %w", err)
+			}
+		default:
+			return scriptCommand{}, fmt.Errorf("unsupported source %q, must be `json`, `header` or `regexp`", cmd.args[1])
+		}
+	}
+
+	return cmd, nil
+}
+
+// splitScriptLine splits a line into whitespace separated arguments, which can
+// be double quoted.
+func splitScriptLine(line string) ([]string, error) {
+	var args []string
+
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return args, nil
+		}
+
+		if line[0] != '"' {
+			end := strings.IndexAny(line, " \t")
+			if end == -1 {
+				end = len(line)
+			}
+
+			args = append(args, line[:end])
+			line = line[end:]
+
+			continue
+		}
+
+		end := 1
+		for ; end < len(line) && line[end] != '"'; end++ {
+			if line[end] == '\\' {
+				end++
+			}
+		}
+
+		if end >= len(line) {
+			return nil, errors.New("unterminated quoted argument")
+		}
+
+		arg, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted argument %v", line[:end+1])
+		}
+
+		args = append(args, arg)
+		line = line[end+1:]
+
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			return nil, errors.New("quoted argument must be followed by whitespace")
+		}
+	}
+}
+
+// newScriptRun returns a run with the variables of env, which is the active
+// environment if hasEnv is true.
+func newScriptRun(env Environment, hasEnv bool, req Request, now time.Time) *scriptRun {
+	run := &scriptRun{
+		env:    env,
+		hasEnv: hasEnv,
+		vars: map[string]string{
+			"request.method": req.Method,
+			"request.body":   string(req.Body),
+			"timestamp":      strconv.FormatInt(now.Unix(), 10),
+			"timestamp.ms":   strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10),
+			"date.iso":       now.UTC().Format(time.RFC3339),
+		},
+	}
+
+	if req.URL != nil {
+		run.vars["request.url"] = req.URL.String()
+		run.vars["request.host"] = req.URL.Host
+		run.vars["request.path"] = req.URL.EscapedPath()
+		run.vars["request.query"] = req.URL.RawQuery
+	}
+
+	return run
+}
+
+func (run *scriptRun) lookup(name string) (string, bool) {
+	if value, ok := run.vars[name]; ok {
+		return value, true
+	}
+
+	return run.env.lookup(name)
+}
+
+func (run *scriptRun) expand(s string) string {
+	return expandVariables(s, run.lookup)
+}
+
+// runPreRequest runs a pre-request script, changing req before it's sent. The
+// header of req must not be shared with the stored request.
+func (run *scriptRun) runPreRequest(script string, req *Request, now time.Time) error {
+	cmds, err := parseScript(script, preRequestPhase)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range cmds {
+		args := run.expandArgs(cmd)
+
+		switch cmd.name {
+		case "header":
+			req.Header.Set(args[0], args[1])
+		case "sigv4":
+			var sessionToken string
+			if len(args) == 5 {
+				sessionToken = args[4]
+			}
+
+			signAWSV4(req, awsCredentials{
+				region:          args[0],
+				service:         args[1],
+				accessKeyID:     args[2],
+				secretAccessKey: args[3],
+				sessionToken:    sessionToken,
+			}, now)
+		default:
+			if err := run.runCommand(cmd, args); err != nil {
+				return fmt.Errorf("%w: pre-request script: line %v: %v", ErrScriptFailed, cmd.line, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// runPostResponse runs a post-response script. Extracted values are stored in
+// run.exported.
+func (run *scriptRun) runPostResponse(script string, res reqlog.ResponseLog) error {
+	cmds, err := parseScript(script, postResponsePhase)
+	if err != nil {
+		return err
+	}
+
+	run.vars["response.status"] = strconv.Itoa(res.StatusCode)
+	run.vars["response.body"] = string(res.Body)
+
+	for _, cmd := range cmds {
+		args := run.expandArgs(cmd)
+
+		if cmd.name == "extract" {
+			err = run.extract(cmd, args, res)
+		} else {
+			err = run.runCommand(cmd, args)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: post-response script: line %v: %v", ErrScriptFailed, cmd.line, err)
+		}
+	}
+
+	return nil
+}
+
+// expandArgs replaces variable references in the arguments of cmd, except for
+// variable names, algorithms and encodings.
+func (run *scriptRun) expandArgs(cmd scriptCommand) []string {
+	args := make([]string, len(cmd.args))
+	copy(args, cmd.args)
+
+	from, to := 0, len(args)
+
+	switch cmd.name {
+	case "set":
+		from = 1
+	case "hash", "hmac":
+		from, to = 2, scriptCommandSpecs[cmd.name].minArgs
+	case "extract":
+		// Patterns may contain braces, e.g. `\d{2}`.
+		if cmd.args[1] == "regexp" {
+			return args
+		}
+
+		from = 2
+	}
+
+	for i := from; i < to; i++ {
+		args[i] = run.expand(args[i])
+	}
+
+	return args
+}
+
+func (run *scriptRun) runCommand(cmd scriptCommand, args []string) error {
+	switch cmd.name {
+	case "set":
+		run.vars[args[0]] = args[1]
+	case "hash":
+		h := hashFuncs[args[1]]()
+		h.Write([]byte(args[2]))
+		run.vars[args[0]] = encodeDigest(h.Sum(nil), args[3:])
+	case "hmac":
+		mac := hmac.New(hashFuncs[args[1]], []byte(args[2]))
+		mac.Write([]byte(args[3]))
+		run.vars[args[0]] = encodeDigest(mac.Sum(nil), args[4:])
+	default:
+		return fmt.Errorf("unsupported command %q", cmd.name)
+	}
+
+	return nil
+}
+
+func (run *scriptRun) extract(cmd scriptCommand, args []string, res reqlog.ResponseLog) error {
+	if !run.hasEnv {
+		return errors.New("no active environment to store extracted value in")
+	}
+
+	var value string
+
+	switch args[1] {
+	case "json":
+		v, err := jsonPathValue(res.Body, args[2])
+		if err != nil {
+			return err
+		}
+
+		value = v
+	case "header":
+		values, ok := res.Header[http.CanonicalHeaderKey(args[2])]
+		if !ok {
+			return fmt.Errorf("response has no %q header", args[2])
+		}
+
+		value = strings.Join(values, ", ")
+	case "regexp":
+		match := cmd.re.FindSubmatch(res.Body)
+		if match == nil {
+			return fmt.Errorf("pattern %q doesn't match response body", args[2])
+		}
+
+		value = string(match[0])
+		if len(match) > 1 {
+			value = string(match[1])
+		}
+	}
+
+	run.vars[args[0]] = value
+	run.exported = append(run.exported, Variable{Name: args[0], Value: value})
+
+	return nil
+}
+
+func encodeDigest(sum []byte, encoding []string) string {
+	if len(encoding) > 0 && encoding[0] == "base64" {
+		return base64.StdEncoding.EncodeToString(sum)
+	}
+
+	return hex.EncodeToString(sum)
+}
+
+// jsonPathValue returns the value at path in a JSON document. Strings are
+// returned as is, other values as JSON.
+func jsonPathValue(data []byte, path string) (string, error) {
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("response body isn't valid JSON: %w", err)
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			value, ok := t[key]
+			if !ok {
+				return "", fmt.Errorf("JSON path %q not found", path)
+			}
+
+			v = value
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(t) {
+				return "", fmt.Errorf("JSON path %q not found", path)
+			}
+
+			v = t[i]
+		default:
+			return "", fmt.Errorf("JSON path %q not found", path)
+		}
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JSON value: %w", err)
+	}
+
+	return string(b), nil
+}
+
+type awsCredentials struct {
+	region          string
+	service         string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+const amzDateFormat = "20060102T150405Z"
+
+// signAWSV4 sets the `Authorization` header of req to an AWS Signature Version
+// 4 signature, see: https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html.
+// The `X-Amz-Date` header of req is used as signing time if it's set, so that
+// requests can be replayed.
+func signAWSV4(req *Request, creds awsCredentials, now time.Time) {
+	signTime, err := time.Parse(amzDateFormat, req.Header.Get("X-Amz-Date"))
+	if err != nil {
+		signTime = now.UTC()
+		req.Header.Set("X-Amz-Date", signTime.Format(amzDateFormat))
+	}
+
+	payloadHash := sha256.Sum256(req.Body)
+	payloadHashHex := hex.EncodeToString(payloadHash[:])
+
+	if creds.service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHashHex)
+	}
+
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	req.Header.Del("Authorization")
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+
+		headers[strings.ToLower(key)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	// Path segments are URI encoded twice, except for S3.
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	if creds.service != "s3" {
+		path = awsURIEncode(path, false)
+	}
+
+	query := req.URL.Query()
+	queryPairs := make([]string, 0, len(query))
+
+	for key, values := range query {
+		for _, value := range values {
+			queryPairs = append(queryPairs, awsURIEncode(key, true)+"="+awsURIEncode(value, true))
+		}
+	}
+
+	sort.Strings(queryPairs)
+
+	canonicalReq := strings.Join([]string{
+		req.Method,
+		path,
+		strings.Join(queryPairs, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHashHex,
+	}, "\n")
+
+	date := signTime.Format("20060102")
+	credentialScope := strings.Join([]string{date, creds.region, creds.service, "aws4_request"}, "/")
+	canonicalReqHash := sha256.Sum256([]byte(canonicalReq))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		signTime.Format(amzDateFormat),
+		credentialScope,
+		hex.EncodeToString(canonicalReqHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + creds.secretAccessKey)
+	for _, data := range []string{date, creds.region, creds.service, "aws4_request"} {
+		key = hmacSHA256(key, data)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		creds.accessKeyID, credentialScope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+// awsURIEncode encodes all bytes of s except unreserved characters (RFC 3986)
+// and, unless encodeSlash is true, slashes.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
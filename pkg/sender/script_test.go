@@ -0,0 +1,242 @@
+package sender_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func TestScripts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+
+		if r.Header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("X-Request-Id", "abc")
+		w.Write([]byte(`{"data": {"sessions": [{"token": "t0k3n", "ttl": 3600}]}}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	events := event.NewBus()
+	published := make(chan sender.Environments, 1)
+
+	events.Subscribe(func(e event.Event) {
+		published <- e.Data.(sender.Environments)
+	}, event.TypeSenderEnvironmentsChanged)
+
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+		HTTPClient: &http.Client{},
+		Events:     events,
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+	svc.SetEnvironments(sender.Environments{
+		Environments: []sender.Environment{
+			{Name: "dev", Variables: []sender.Variable{{Name: "secret", Value: "s3cret"}, {Name: "token"}}},
+		},
+		Active: "dev",
+	})
+
+	u, _ := url.Parse(upstream.URL + "/login")
+
+	req, err := svc.CreateOrUpdateRequest(ctx, sender.Request{
+		URL:    u,
+		Proto:  sender.HTTPProto1,
+		Method: http.MethodPost,
+		Body:   []byte(`{"user": "alice"}`),
+		PreRequestScript: strings.Join([]string{
+			"# Sign the body.",
+			"hmac signature sha256 {{secret}} {{request.body}}",
+			`header "X-Signature" {{signature}}`,
+		}, "\n"),
+		PostResponseScript: strings.Join([]string{
+			"extract token json data.sessions.0.token",
+			"extract ttl json data.sessions.0.ttl",
+			"extract requestID header x-request-id",
+			`extract quoted regexp "\"(t0k\\d)"`,
+		}, "\n"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating request: %v", err)
+	}
+
+	req, err = svc.SendRequest(ctx, req.ID)
+	if err != nil {
+		t.Fatalf("unexpected error sending request: %v", err)
+	}
+
+	if req.Response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code 200, got: %v", req.Response.StatusCode)
+	}
+
+	exp := sender.Environments{
+		Environments: []sender.Environment{
+			{
+				Name: "dev",
+				Variables: []sender.Variable{
+					{Name: "secret", Value: "s3cret"},
+					{Name: "token", Value: "t0k3n"},
+					{Name: "ttl", Value: "3600"},
+					{Name: "requestID", Value: "abc"},
+					{Name: "quoted", Value: "t0k3"},
+				},
+			},
+		},
+		Active: "dev",
+	}
+
+	if diff := cmp.Diff(exp, svc.Environments()); diff != "" {
+		t.Fatalf("environments not equal (-exp, +got):\n%v", diff)
+	}
+
+	if diff := cmp.Diff(exp, <-published); diff != "" {
+		t.Fatalf("published environments not equal (-exp, +got):\n%v", diff)
+	}
+
+	// The stored request doesn't have the headers set by the script.
+	stored, err := svc.FindRequestByID(ctx, req.ID)
+	if err != nil {
+		t.Fatalf("unexpected error finding request: %v", err)
+	}
+
+	if got := stored.Header.Get("X-Signature"); got != "" {
+		t.Fatalf("expected stored request to have no signature header, got: %q", got)
+	}
+}
+
+func TestScriptsAWSV4(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	authorization := make(chan string, 1)
+
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+		HTTPClient: &http.Client{
+			Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				authorization <- r.Header.Get("Authorization")
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Proto:      "HTTP/1.1",
+					ProtoMajor: 1,
+					ProtoMinor: 1,
+					Header:     make(http.Header),
+					Body:       io.NopCloser(strings.NewReader("")),
+				}, nil
+			}),
+		},
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	// Test vector `get-vanilla` of the AWS Signature Version 4 test suite.
+	u, _ := url.Parse("https://example.amazonaws.com/")
+
+	req, err := svc.CreateOrUpdateRequest(ctx, sender.Request{
+		URL:              u,
+		Proto:            sender.HTTPProto1,
+		Header:           http.Header{"X-Amz-Date": []string{"20150830T123600Z"}},
+		PreRequestScript: "sigv4 us-east-1 service AKIDEXAMPLE wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating request: %v", err)
+	}
+
+	if _, err := svc.SendRequest(ctx, req.ID); err != nil {
+		t.Fatalf("unexpected error sending request: %v", err)
+	}
+
+	exp := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+
+	if got := <-authorization; got != exp {
+		t.Fatalf("expected authorization header %q, got: %q", exp, got)
+	}
+}
+
+func TestScriptsInvalid(t *testing.T) {
+	t.Parallel()
+
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	u, _ := url.Parse("https://example.com/")
+
+	tests := []struct {
+		name string
+		req  sender.Request
+	}{
+		{
+			name: "unknown command",
+			req:  sender.Request{PreRequestScript: "eval 1+1"},
+		},
+		{
+			name: "command of other phase",
+			req:  sender.Request{PreRequestScript: "extract token json token"},
+		},
+		{
+			name: "missing argument",
+			req:  sender.Request{PostResponseScript: "set token"},
+		},
+		{
+			name: "unterminated quote",
+			req:  sender.Request{PreRequestScript: `header X-Foo "bar`},
+		},
+		{
+			name: "unsupported hash algorithm",
+			req:  sender.Request{PreRequestScript: "hash digest crc32 {{request.body}}"},
+		},
+		{
+			name: "invalid pattern",
+			req:  sender.Request{PostResponseScript: "extract token regexp ("},
+		},
+		{
+			name: "raw request",
+			req:  sender.Request{Raw: []byte("GET / HTTP/1.1\r\n\r\n"), PreRequestScript: "set foo bar"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.req.URL = u
+
+			_, err := svc.CreateOrUpdateRequest(context.Background(), tt.req)
+			if !errors.Is(err, sender.ErrInvalidScript) {
+				t.Fatalf("expected `sender.ErrInvalidScript`, got: %v", err)
+			}
+		})
+	}
+}
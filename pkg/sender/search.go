@@ -1,10 +1,8 @@
 package sender
 
 import (
-	"errors"
-	"fmt"
-	"regexp"
-	"strings"
+	"sort"
+	"time"
 
 	"github.com/oklog/ulid"
 
@@ -29,155 +27,71 @@ var senderReqSearchKeyFns = map[string]func(req Request) string{
 
 // TODO: Request and response headers search key functions.
 
-// Matches returns true if the supplied search expression evaluates to true.
-func (req Request) Matches(expr search.Expression) (bool, error) {
-	switch e := expr.(type) {
-	case search.PrefixExpression:
-		return req.matchPrefixExpr(e)
-	case search.InfixExpression:
-		return req.matchInfixExpr(e)
-	case search.StringLiteral:
-		return req.matchStringLiteral(e)
-	default:
-		return false, fmt.Errorf("expression type (%T) not supported", expr)
-	}
-}
-
-func (req Request) matchPrefixExpr(expr search.PrefixExpression) (bool, error) {
-	switch expr.Operator {
-	case search.TokOpNot:
-		match, err := req.Matches(expr.Right)
-		if err != nil {
-			return false, err
-		}
+var (
+	senderReqSearchKeys          []string
+	senderReqAndResLogSearchKeys []string
+)
 
-		return !match, nil
-	default:
-		return false, errors.New("operator is not supported")
+func init() {
+	for key := range senderReqSearchKeyFns {
+		senderReqSearchKeys = append(senderReqSearchKeys, key)
 	}
-}
-
-func (req Request) matchInfixExpr(expr search.InfixExpression) (bool, error) {
-	switch expr.Operator {
-	case search.TokOpAnd:
-		left, err := req.Matches(expr.Left)
-		if err != nil {
-			return false, err
-		}
 
-		right, err := req.Matches(expr.Right)
-		if err != nil {
-			return false, err
-		}
+	sort.Strings(senderReqSearchKeys)
 
-		return left && right, nil
-	case search.TokOpOr:
-		left, err := req.Matches(expr.Left)
-		if err != nil {
-			return false, err
-		}
-
-		right, err := req.Matches(expr.Right)
-		if err != nil {
-			return false, err
-		}
-
-		return left || right, nil
-	}
+	senderReqAndResLogSearchKeys = append(append([]string{}, senderReqSearchKeys...), reqlog.ResLogSearchKeys...)
+}
 
-	left, ok := expr.Left.(search.StringLiteral)
-	if !ok {
-		return false, errors.New("left operand must be a string literal")
+// ParseSearchExpr parses a search expression for sender requests. Unknown
+// `req.` and `res.` keys are reported with suggestions, see `search.CheckKeys`.
+func ParseSearchExpr(input string) (search.Expression, error) {
+	expr, err := search.ParseQuery(input)
+	if err != nil {
+		return nil, err
 	}
 
-	leftVal := req.getMappedStringLiteral(left.Value)
-
-	if expr.Operator == search.TokOpRe || expr.Operator == search.TokOpNotRe {
-		right, ok := expr.Right.(*regexp.Regexp)
-		if !ok {
-			return false, errors.New("right operand must be a regular expression")
-		}
-
-		switch expr.Operator {
-		case search.TokOpRe:
-			return right.MatchString(leftVal), nil
-		case search.TokOpNotRe:
-			return !right.MatchString(leftVal), nil
-		}
+	isKey := func(key string) bool {
+		_, ok := senderReqSearchKeyFns[key]
+		return ok || reqlog.IsResponseSearchKey(key)
 	}
 
-	right, ok := expr.Right.(search.StringLiteral)
-	if !ok {
-		return false, errors.New("right operand must be a string literal")
+	if err := search.CheckKeys(input, senderReqAndResLogSearchKeys, isKey); err != nil {
+		return nil, err
 	}
 
-	rightVal := req.getMappedStringLiteral(right.Value)
-
-	switch expr.Operator {
-	case search.TokOpEq:
-		return leftVal == rightVal, nil
-	case search.TokOpNotEq:
-		return leftVal != rightVal, nil
-	case search.TokOpGt:
-		// TODO(?) attempt to parse as int.
-		return leftVal > rightVal, nil
-	case search.TokOpLt:
-		// TODO(?) attempt to parse as int.
-		return leftVal < rightVal, nil
-	case search.TokOpGtEq:
-		// TODO(?) attempt to parse as int.
-		return leftVal >= rightVal, nil
-	case search.TokOpLtEq:
-		// TODO(?) attempt to parse as int.
-		return leftVal <= rightVal, nil
-	default:
-		return false, errors.New("unsupported operator")
-	}
+	return expr, nil
 }
 
-func (req Request) getMappedStringLiteral(s string) string {
-	switch {
-	case strings.HasPrefix(s, "req."):
-		fn, ok := senderReqSearchKeyFns[s]
-		if ok {
-			return fn(req)
-		}
-	case strings.HasPrefix(s, "res."):
-		if req.Response == nil {
-			return ""
-		}
+// searchRecord evaluates search expressions for a sender request and its
+// response, with the same keys as request logs.
+type searchRecord struct {
+	req *Request
+}
 
-		fn, ok := reqlog.ResLogSearchKeyFns[s]
-		if ok {
-			return fn(*req.Response)
-		}
+func (rec searchRecord) SearchValue(key string) (string, bool) {
+	if fn, ok := senderReqSearchKeyFns[key]; ok {
+		return fn(*rec.req), true
 	}
 
-	return s
+	return reqlog.ResponseSearchValue(rec.req.Response, key)
 }
 
-func (req Request) matchStringLiteral(strLiteral search.StringLiteral) (bool, error) {
-	for _, fn := range senderReqSearchKeyFns {
-		if strings.Contains(
-			strings.ToLower(fn(req)),
-			strings.ToLower(strLiteral.Value),
-		) {
-			return true, nil
-		}
+func (rec searchRecord) SearchKeys() []string {
+	if rec.req.Response == nil {
+		return senderReqSearchKeys
 	}
 
-	if req.Response != nil {
-		for _, fn := range reqlog.ResLogSearchKeyFns {
-			if strings.Contains(
-				strings.ToLower(fn(*req.Response)),
-				strings.ToLower(strLiteral.Value),
-			) {
-				return true, nil
-			}
-		}
-	}
+	return senderReqAndResLogSearchKeys
+}
 
-	return false, nil
+// SearchTime returns the time of the request ID, see `search.TimeRecord`.
+func (rec searchRecord) SearchTime() time.Time {
+	return ulid.Time(rec.req.ID.Time())
+}
+
+// Matches returns true if the supplied search expression evaluates to true.
+func (req Request) Matches(expr search.Expression) (bool, error) {
+	return search.Match(expr, searchRecord{&req})
 }
 
 func (req Request) MatchScope(s *scope.Scope) bool {
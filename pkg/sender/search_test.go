@@ -141,6 +141,39 @@ func TestRequestLogMatch(t *testing.T) {
 			expectedMatch: true,
 			expectedError: nil,
 		},
+		{
+			name:  "infix expression, response status code, match",
+			query: "req.method = POST AND res.statusCode = 201",
+			senderReq: sender.Request{
+				Method: "POST",
+				Response: &reqlog.ResponseLog{
+					StatusCode: 201,
+				},
+			},
+			expectedMatch: true,
+			expectedError: nil,
+		},
+		{
+			name:  "infix expression, response regular expression, match",
+			query: `res.statusReason =~ "Not Found$"`,
+			senderReq: sender.Request{
+				Response: &reqlog.ResponseLog{
+					StatusCode: 404,
+					Status:     "404 Not Found",
+				},
+			},
+			expectedMatch: true,
+			expectedError: nil,
+		},
+		{
+			name:  "infix expression, response field without response, no match",
+			query: "res.statusCode = 200",
+			senderReq: sender.Request{
+				Body: []byte("foo"),
+			},
+			expectedMatch: false,
+			expectedError: nil,
+		},
 		{
 			name:  "string literal expression, match in request log",
 			query: "foo",
@@ -1,25 +1,29 @@
 package sender
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/oklog/ulid"
 
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/proxy"
+	"github.com/dstotijn/hetty/pkg/ratelimit"
 	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
 	"github.com/dstotijn/hetty/pkg/scope"
 	"github.com/dstotijn/hetty/pkg/search"
 )
 
-//nolint:gosec
-var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
-
 var defaultHTTPClient = &http.Client{
 	Transport: &HTTPTransport{},
 	Timeout:   30 * time.Second,
@@ -27,7 +31,8 @@ var defaultHTTPClient = &http.Client{
 
 var (
 	ErrProjectIDMustBeSet = errors.New("sender: project ID must be set")
-	ErrRequestNotFound    = errors.New("sender: request not found")
+	ErrRequestNotFound    = errcode.New(errcode.NotFound, "sender: request not found")
+	ErrReadOnly           = errors.New("sender: project is opened read-only")
 )
 
 type Service interface {
@@ -38,17 +43,56 @@ type Service interface {
 	DeleteRequests(ctx context.Context, projectID ulid.ULID) error
 	SendRequest(ctx context.Context, id ulid.ULID) (Request, error)
 	SetActiveProjectID(ulid.ULID)
+	SetReadOnly(readOnly bool)
 	SetFindReqsFilter(filter FindRequestsFilter)
 	FindReqsFilter() FindRequestsFilter
+	SetEnvironments(envs Environments)
+	Environments() Environments
+	SetRuntimeVariables(vars []Variable)
+	SetSigningProfiles(profiles SigningProfiles)
+	SigningProfiles() SigningProfiles
+	SignHTTPRequest(profile SigningProfile, req *http.Request, body []byte)
+	FindCollections(ctx context.Context) ([]Collection, error)
+	FindCollectionByID(ctx context.Context, id ulid.ULID) (Collection, error)
+	CreateCollection(ctx context.Context, name string) (Collection, error)
+	RenameCollection(ctx context.Context, id ulid.ULID, name string) (Collection, error)
+	DeleteCollection(ctx context.Context, id ulid.ULID) error
+	ReorderCollections(ctx context.Context, ids []ulid.ULID) error
+	MoveRequest(ctx context.Context, reqID, collectionID ulid.ULID, index int) error
+	RunCollection(ctx context.Context, id ulid.ULID) ([]CollectionRunResult, error)
+	ImportPostmanCollection(ctx context.Context, data []byte) (Collection, error)
+	RunAssertionSuite(ctx context.Context) ([]CollectionRunResult, error)
+	SetSchedules(schedules []Schedule)
+	Schedules() []Schedule
+	FindScheduleRuns(ctx context.Context, name string) ([]ScheduleRun, error)
+	CompareRequest(ctx context.Context, params CompareParams) (Comparison, error)
+	FindComparisons(ctx context.Context, requestID ulid.ULID) ([]Comparison, error)
 }
 
 type service struct {
+	// mu guards the settings below, which are changed at runtime.
+	mu              sync.RWMutex
 	activeProjectID ulid.ULID
+	readOnly        bool
 	findReqsFilter  FindRequestsFilter
-	scope           *scope.Scope
-	repo            Repository
-	reqLogSvc       reqlog.Service
-	httpClient      *http.Client
+	environments    Environments
+	signingProfiles SigningProfiles
+	runtimeVars     []Variable
+	schedules       []Schedule
+	cancelSchedules context.CancelFunc
+
+	scope         *scope.Scope
+	repo          Repository
+	ids           idgen.Generator
+	reqLogSvc     reqlog.Service
+	rewriter      *rewrite.Rewriter
+	httpClient    *http.Client
+	webhookClient *http.Client
+	events        *event.Bus
+
+	// Serializes updates of collections, which are read before they're
+	// changed.
+	collectionsMu sync.Mutex
 }
 
 type FindRequestsFilter struct {
@@ -62,6 +106,23 @@ type Config struct {
 	Repository    Repository
 	ReqLogService reqlog.Service
 	HTTPClient    *http.Client
+	// Transport used for requests that follow a redirect, when the default HTTP
+	// client is used.
+	RedirectTransport http.RoundTripper
+	// Bus that `event.TypeSenderEnvironmentsChanged` is published on, when
+	// post-response scripts extract variables.
+	Events *event.Bus
+	// Client for webhook alerts of schedules. Defaults to a client that
+	// doesn't use the proxy.
+	WebhookClient *http.Client
+	// Generates the IDs of sender requests, collections and schedule runs. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+	// Holds the rewrite profiles of the active project. Requests are rewritten
+	// with the active profile when they're sent. Optional.
+	Rewriter *rewrite.Rewriter
+	// Limits the rate of sent requests per host, when the default HTTP client
+	// is used. Optional.
+	RateLimiter *ratelimit.Limiter
 }
 
 type SendError struct {
@@ -69,15 +130,32 @@ type SendError struct {
 }
 
 func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
 	svc := &service{
-		repo:       cfg.Repository,
-		reqLogSvc:  cfg.ReqLogService,
-		httpClient: defaultHTTPClient,
-		scope:      cfg.Scope,
+		ids:           cfg.IDGenerator,
+		repo:          cfg.Repository,
+		reqLogSvc:     cfg.ReqLogService,
+		rewriter:      cfg.Rewriter,
+		httpClient:    defaultHTTPClient,
+		webhookClient: defaultWebhookClient,
+		scope:         cfg.Scope,
+		events:        cfg.Events,
+	}
+
+	if cfg.WebhookClient != nil {
+		svc.webhookClient = cfg.WebhookClient
 	}
 
 	if cfg.HTTPClient != nil {
 		svc.httpClient = cfg.HTTPClient
+	} else if cfg.RedirectTransport != nil || cfg.RateLimiter != nil {
+		svc.httpClient = &http.Client{
+			Transport: &HTTPTransport{RedirectTransport: cfg.RedirectTransport, RateLimiter: cfg.RateLimiter},
+			Timeout:   defaultHTTPClient.Timeout,
+		}
 	}
 
 	return svc
@@ -94,6 +172,21 @@ type Request struct {
 	Header http.Header
 	Body   []byte
 
+	// Raw request bytes. When set, they're sent as is to the host of URL (see
+	// `SendRawRequest`), instead of a request built from the fields above.
+	Raw []byte
+	// Bytes received for the last raw request that was sent.
+	RawResponse []byte
+
+	// Scripts that run before the request is sent (e.g. to sign it) and after
+	// the response is received (e.g. to extract a token). See script.go for
+	// their commands.
+	PreRequestScript   string
+	PostResponseScript string
+	// Checked against the response when the request is run in a collection
+	// or assertion suite.
+	Assertions []Assertion
+
 	Response *reqlog.ResponseLog
 }
 
@@ -107,19 +200,24 @@ func (svc *service) FindRequestByID(ctx context.Context, id ulid.ULID) (Request,
 }
 
 func (svc *service) FindRequests(ctx context.Context) ([]Request, error) {
-	return svc.repo.FindSenderRequests(ctx, svc.findReqsFilter, svc.scope)
+	return svc.repo.FindSenderRequests(ctx, svc.FindReqsFilter(), svc.scope)
 }
 
 func (svc *service) CreateOrUpdateRequest(ctx context.Context, req Request) (Request, error) {
-	if svc.activeProjectID.Compare(ulid.ULID{}) == 0 {
+	if svc.isReadOnly() {
+		return Request{}, ErrReadOnly
+	}
+
+	projectID := svc.activeProject()
+	if projectID.Compare(ulid.ULID{}) == 0 {
 		return Request{}, ErrProjectIDMustBeSet
 	}
 
 	if req.ID.Compare(ulid.ULID{}) == 0 {
-		req.ID = ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+		req.ID = svc.ids.New(time.Now())
 	}
 
-	req.ProjectID = svc.activeProjectID
+	req.ProjectID = projectID
 
 	if req.Method == "" {
 		req.Method = http.MethodGet
@@ -133,6 +231,14 @@ func (svc *service) CreateOrUpdateRequest(ctx context.Context, req Request) (Req
 		return Request{}, fmt.Errorf("sender: unsupported HTTP protocol: %v", req.Proto)
 	}
 
+	if err := validateScripts(req); err != nil {
+		return Request{}, err
+	}
+
+	if err := ValidateAssertions(req.Assertions); err != nil {
+		return Request{}, err
+	}
+
 	err := svc.repo.StoreSenderRequest(ctx, req)
 	if err != nil {
 		return Request{}, fmt.Errorf("sender: failed to store request: %w", err)
@@ -142,7 +248,12 @@ func (svc *service) CreateOrUpdateRequest(ctx context.Context, req Request) (Req
 }
 
 func (svc *service) CloneFromRequestLog(ctx context.Context, reqLogID ulid.ULID) (Request, error) {
-	if svc.activeProjectID.Compare(ulid.ULID{}) == 0 {
+	if svc.isReadOnly() {
+		return Request{}, ErrReadOnly
+	}
+
+	projectID := svc.activeProject()
+	if projectID.Compare(ulid.ULID{}) == 0 {
 		return Request{}, ErrProjectIDMustBeSet
 	}
 
@@ -152,8 +263,8 @@ func (svc *service) CloneFromRequestLog(ctx context.Context, reqLogID ulid.ULID)
 	}
 
 	req := Request{
-		ID:                 ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
-		ProjectID:          svc.activeProjectID,
+		ID:                 svc.ids.New(time.Now()),
+		ProjectID:          projectID,
 		SourceRequestLogID: reqLogID,
 		Method:             reqLog.Method,
 		URL:                reqLog.URL,
@@ -171,20 +282,43 @@ func (svc *service) CloneFromRequestLog(ctx context.Context, reqLogID ulid.ULID)
 }
 
 func (svc *service) SetFindReqsFilter(filter FindRequestsFilter) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
 	svc.findReqsFilter = filter
 }
 
 func (svc *service) FindReqsFilter() FindRequestsFilter {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
 	return svc.findReqsFilter
 }
 
 func (svc *service) SendRequest(ctx context.Context, id ulid.ULID) (Request, error) {
+	if svc.isReadOnly() {
+		return Request{}, ErrReadOnly
+	}
+
 	req, err := svc.repo.FindSenderRequestByID(ctx, id)
 	if err != nil {
 		return Request{}, fmt.Errorf("sender: failed to find request: %w", err)
 	}
 
-	httpReq, err := parseHTTPRequest(ctx, req)
+	sent, run, err := svc.prepareRequest(req, svc.activeRewrite())
+	if err != nil {
+		return Request{}, err
+	}
+
+	if req.Raw != nil {
+		return svc.sendRawRequest(ctx, req, sent)
+	}
+
+	// The sender request ID is used as correlation ID for traffic triggered by
+	// the request, such as redirects that are followed via the proxy.
+	ctx = context.WithValue(ctx, proxy.CorrelationIDKey, req.ID)
+
+	httpReq, err := parseHTTPRequest(ctx, sent)
 	if err != nil {
 		return Request{}, fmt.Errorf("sender: failed to parse HTTP request: %w", err)
 	}
@@ -201,6 +335,134 @@ func (svc *service) SendRequest(ctx context.Context, id ulid.ULID) (Request, err
 
 	req.Response = &resLog
 
+	if err := run.runPostResponse(req.PostResponseScript, resLog); err != nil {
+		return Request{}, err
+	}
+
+	if len(run.exported) > 0 {
+		svc.storeVariables(req.ProjectID, run.exported)
+	}
+
+	return req, nil
+}
+
+// prepareRequest returns a copy of req as it's sent: with the variables of the
+// active environment replaced, rewritten with rewriteURL (if set), and with the
+// pre-request script and signing profile applied. Raw requests only have their
+// variables replaced, and the returned script run is nil.
+func (svc *service) prepareRequest(
+	req Request,
+	rewriteURL func(u *url.URL, header http.Header) *url.URL,
+) (Request, *scriptRun, error) {
+	// Variables are replaced in a copy, so that the stored request still
+	// references them.
+	envs := svc.Environments()
+	env, hasEnv := envs.ActiveEnvironment()
+	env.Variables = mergeVariables(env.Variables, svc.runtimeVariables())
+
+	sent, err := env.expandRequest(req)
+	if err != nil {
+		return Request{}, nil, fmt.Errorf("sender: failed to replace variables: %w", err)
+	}
+
+	if req.Raw != nil {
+		return sent, nil, nil
+	}
+
+	sent.Header = sent.Header.Clone()
+	if sent.Header == nil {
+		sent.Header = make(http.Header)
+	}
+
+	// Rewrites apply before the pre-request script and signing, which see the
+	// request as it's sent.
+	if rewriteURL != nil && sent.URL != nil {
+		sent.URL = rewriteURL(sent.URL, sent.Header)
+	}
+
+	now := time.Now()
+	run := newScriptRun(env, hasEnv, sent, now)
+
+	if err := run.runPreRequest(req.PreRequestScript, &sent, now); err != nil {
+		return Request{}, nil, err
+	}
+
+	if profile, ok := svc.SigningProfiles().match(sent.URL.Hostname()); ok {
+		profile.sign(&sent, run, now)
+	}
+
+	return sent, run, nil
+}
+
+// activeRewrite returns a func that rewrites requests with the active rewrite
+// profile, or nil if there's none.
+func (svc *service) activeRewrite() func(u *url.URL, header http.Header) *url.URL {
+	if svc.rewriter == nil {
+		return nil
+	}
+
+	profile, ok := svc.rewriter.Profiles().ActiveProfile()
+	if !ok {
+		return nil
+	}
+
+	return profile.Apply
+}
+
+// storeVariables stores variables extracted by a post-response script in the
+// active environment.
+func (svc *service) storeVariables(projectID ulid.ULID, vars []Variable) {
+	svc.mu.Lock()
+
+	envs, ok := svc.environments.withVariables(vars)
+	if ok {
+		svc.environments = envs
+	}
+
+	svc.mu.Unlock()
+
+	if ok {
+		svc.events.Publish(event.Event{
+			Type:      event.TypeSenderEnvironmentsChanged,
+			ProjectID: projectID,
+			Data:      envs,
+		})
+	}
+}
+
+// sendRawRequest sends the raw bytes of sent (req with variables replaced),
+// and stores the bytes received on req. When they start with a valid HTTP
+// response, it's stored as the response log of the request.
+func (svc *service) sendRawRequest(ctx context.Context, req, sent Request) (Request, error) {
+	rawRes, err := SendRawRequest(ctx, sent.URL, sent.Raw, DefaultRawTimeout)
+	if err != nil {
+		return Request{}, fmt.Errorf("sender: could not send raw request: %w", &SendError{err})
+	}
+
+	req.RawResponse = rawRes.Raw
+	req.Response = nil
+
+	if err := svc.repo.StoreSenderRequest(ctx, req); err != nil {
+		return Request{}, fmt.Errorf("sender: failed to store request: %w", err)
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rawRes.Raw)), nil)
+	if err != nil {
+		return req, nil
+	}
+	defer res.Body.Close()
+
+	resLog, err := reqlog.ParseHTTPResponse(res)
+	if err != nil {
+		return req, nil
+	}
+
+	if err := svc.repo.StoreResponseLog(ctx, req.ID, resLog); err != nil {
+		return Request{}, fmt.Errorf("sender: failed to store sender response log: %w", err)
+	}
+
+	req.Response = &resLog
+
 	return req, nil
 }
 
@@ -234,11 +496,90 @@ func (svc *service) sendHTTPRequest(httpReq *http.Request) (reqlog.ResponseLog,
 	return resLog, err
 }
 
+// SetEnvironments sets the environments of the active project. The variables
+// of the active environment are replaced when requests are sent.
+func (svc *service) SetEnvironments(envs Environments) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.environments = envs
+}
+
+func (svc *service) Environments() Environments {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.environments
+}
+
+// SetRuntimeVariables sets variables that aren't stored with the project, e.g.
+// OAuth 2.0 access tokens. They take precedence over the variables of the
+// active environment, and can be used without one.
+func (svc *service) SetRuntimeVariables(vars []Variable) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.runtimeVars = vars
+}
+
+func (svc *service) runtimeVariables() []Variable {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.runtimeVars
+}
+
+// SetSigningProfiles sets the signing profiles of the active project. Requests
+// to hosts that match a profile are signed when they're sent.
+func (svc *service) SetSigningProfiles(profiles SigningProfiles) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.signingProfiles = profiles
+}
+
+func (svc *service) SigningProfiles() SigningProfiles {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.signingProfiles
+}
+
 func (svc *service) SetActiveProjectID(id ulid.ULID) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
 	svc.activeProjectID = id
 }
 
+// SetReadOnly sets whether the active project is opened read-only, in which
+// case requests can't be created, sent or deleted.
+func (svc *service) SetReadOnly(readOnly bool) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	svc.readOnly = readOnly
+}
+
+func (svc *service) isReadOnly() bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.readOnly
+}
+
+func (svc *service) activeProject() ulid.ULID {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	return svc.activeProjectID
+}
+
 func (svc *service) DeleteRequests(ctx context.Context, projectID ulid.ULID) error {
+	if svc.isReadOnly() {
+		return ErrReadOnly
+	}
+
 	return svc.repo.DeleteSenderRequests(ctx, projectID)
 }
 
@@ -249,3 +590,7 @@ func (e SendError) Error() string {
 func (e SendError) Unwrap() error {
 	return e.err
 }
+
+func (e SendError) ErrorCode() errcode.Code {
+	return errcode.Upstream
+}
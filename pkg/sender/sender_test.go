@@ -17,7 +17,10 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/oklog/ulid"
 
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/proxy"
 	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/rewrite"
 	"github.com/dstotijn/hetty/pkg/sender"
 )
 
@@ -287,3 +290,129 @@ func TestSendRequest(t *testing.T) {
 		t.Fatalf("returned response log value and persisted value not equal (-exp, +got):\n%v", diff)
 	}
 }
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestSendRequestFollowsRedirectsWithCorrelationID(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.Redirect(w, r, "/dashboard", http.StatusFound)
+			return
+		}
+
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+
+	tsURL, _ := url.Parse(ts.URL + "/login")
+
+	reqID := ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy)
+	req := sender.Request{
+		ID:     reqID,
+		URL:    tsURL,
+		Method: http.MethodGet,
+		Proto:  sender.HTTPProto1,
+	}
+
+	repoMock := &RepoMock{
+		FindSenderRequestByIDFunc: func(ctx context.Context, id ulid.ULID) (sender.Request, error) {
+			return req, nil
+		},
+		StoreResponseLogFunc: func(ctx context.Context, reqLogID ulid.ULID, resLog reqlog.ResponseLog) error {
+			return nil
+		},
+	}
+
+	var redirects []*http.Request
+
+	svc := sender.NewService(sender.Config{
+		Repository: repoMock,
+		RedirectTransport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			redirects = append(redirects, req)
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	})
+
+	got, err := svc.SendRequest(context.Background(), reqID)
+	if err != nil {
+		t.Fatalf("unexpected error sending request: %v", err)
+	}
+
+	if got.Response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code of redirect target, got: %v", got.Response.StatusCode)
+	}
+
+	if len(redirects) != 1 {
+		t.Fatalf("expected redirect transport to be used 1 time, got: %v", len(redirects))
+	}
+
+	if exp := "/dashboard"; redirects[0].URL.Path != exp {
+		t.Fatalf("incorrect redirect URL path (expected: %v, got: %v)", exp, redirects[0].URL.Path)
+	}
+
+	correlationID, _ := redirects[0].Context().Value(proxy.CorrelationIDKey).(ulid.ULID)
+	if correlationID.Compare(reqID) != 0 {
+		t.Fatalf("incorrect correlation ID (expected: %v, got: %v)", reqID, correlationID)
+	}
+}
+
+func TestSendRequestWithRewriteProfile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer staging" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	upstreamURL, _ := url.Parse(upstream.URL)
+
+	rewriter := &rewrite.Rewriter{}
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+		HTTPClient: &http.Client{},
+		Rewriter:   rewriter,
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+
+	req, err := svc.CreateOrUpdateRequest(ctx, sender.Request{
+		URL:    &url.URL{Scheme: "https", Host: "prod.example.com", Path: "/orders"},
+		Proto:  sender.HTTPProto1,
+		Header: http.Header{"Authorization": []string{"Bearer prod"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating request: %v", err)
+	}
+
+	rewriter.SetProfiles(rewrite.Profiles{
+		Profiles: []rewrite.Profile{{
+			Name:    "staging",
+			Hosts:   []rewrite.HostRule{{From: "prod.example.com", To: upstreamURL.Host, Scheme: "http"}},
+			Headers: http.Header{"Authorization": []string{"Bearer staging"}},
+		}},
+		Active: "staging",
+	})
+
+	got, err := svc.SendRequest(ctx, req.ID)
+	if err != nil {
+		t.Fatalf("unexpected error sending request: %v", err)
+	}
+
+	if got.Response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code %v, got: %v", http.StatusOK, got.Response.StatusCode)
+	}
+
+	// The stored request isn't rewritten.
+	if got.URL.Host != "prod.example.com" || got.Header.Get("Authorization") != "Bearer prod" {
+		t.Fatalf("expected stored request to be unchanged (got: %v, %v)", got.URL, got.Header)
+	}
+}
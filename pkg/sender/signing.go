@@ -0,0 +1,193 @@
+package sender
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+)
+
+var ErrInvalidSigningProfiles = errcode.New(errcode.Invalid, "sender: invalid signing profiles")
+
+// SigningProfile signs sender requests to matching hosts when they're sent, so
+// that modified requests to signed APIs can be replayed without recomputing
+// signatures by hand. Exactly one of AWSV4 and HMAC is set. References like
+// `{{secret}}` in values are replaced by variables of the active environment
+// and variables set by the pre-request script, which runs first.
+type SigningProfile struct {
+	Name string
+	// Regular expression that hosts of requests to sign must match, e.g.
+	// `\.amazonaws\.com$`.
+	HostPattern string
+	AWSV4       *AWSV4Signing
+	HMAC        *HMACSigning
+}
+
+// AWSV4Signing signs requests with AWS Signature Version 4.
+type AWSV4Signing struct {
+	Region          string
+	Service         string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// HMACSigning sets a header to the HMAC of a message built from the request,
+// a common scheme of signed APIs.
+type HMACSigning struct {
+	// One of `md5`, `sha1`, `sha256` (default) or `sha512`.
+	Algorithm string
+	Key       string
+	// Template of the signed message, using the built-in variables of
+	// scripts, e.g. `{{request.method}}\n{{request.path}}\n{{timestamp}}`.
+	Message string
+	// One of `hex` (default) or `base64`.
+	Encoding string
+	// Header that is set to HeaderValue.
+	Header string
+	// Template of the header value; `{{signature}}` is replaced by the
+	// signature. Defaults to `{{signature}}`.
+	HeaderValue string
+	// Header that is set to the Unix time used in the message, if any, e.g.
+	// `X-Timestamp`.
+	TimestampHeader string
+}
+
+type SigningProfiles []SigningProfile
+
+// Validate returns an error if names are empty or duplicate, if host patterns
+// are invalid, or if profiles don't have exactly one signing method.
+func (profiles SigningProfiles) Validate() error {
+	names := make(map[string]bool, len(profiles))
+
+	for _, profile := range profiles {
+		if profile.Name == "" {
+			return fmt.Errorf("%w: profile name must not be empty", ErrInvalidSigningProfiles)
+		}
+
+		if names[profile.Name] {
+			return fmt.Errorf("%w: duplicate profile name %q", ErrInvalidSigningProfiles, profile.Name)
+		}
+
+		names[profile.Name] = true
+
+		if _, err := regexp.Compile(profile.HostPattern); err != nil {
+			return fmt.Errorf("%w: invalid host pattern of profile %q: %v", ErrInvalidSigningProfiles, profile.Name, err)
+		}
+
+		if (profile.AWSV4 == nil) == (profile.HMAC == nil) {
+			return fmt.Errorf("%w: profile %q must have exactly one signing method", ErrInvalidSigningProfiles, profile.Name)
+		}
+
+		if aws := profile.AWSV4; aws != nil && (aws.Region == "" || aws.Service == "" || aws.AccessKeyID == "") {
+			return fmt.Errorf("%w: profile %q must have a region, service and access key ID",
+				ErrInvalidSigningProfiles, profile.Name)
+		}
+
+		if h := profile.HMAC; h != nil {
+			if h.Header == "" {
+				return fmt.Errorf("%w: profile %q must have a header", ErrInvalidSigningProfiles, profile.Name)
+			}
+
+			if _, ok := hashFuncs[h.Algorithm]; h.Algorithm != "" && !ok {
+				return fmt.Errorf("%w: unsupported hash algorithm %q", ErrInvalidSigningProfiles, h.Algorithm)
+			}
+
+			if h.Encoding != "" && h.Encoding != "hex" && h.Encoding != "base64" {
+				return fmt.Errorf("%w: unsupported encoding %q", ErrInvalidSigningProfiles, h.Encoding)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Find returns the profile with the given name.
+func (profiles SigningProfiles) Find(name string) (SigningProfile, bool) {
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+
+	return SigningProfile{}, false
+}
+
+// match returns the first profile whose host pattern matches host.
+func (profiles SigningProfiles) match(host string) (SigningProfile, bool) {
+	for _, profile := range profiles {
+		re, err := regexp.Compile(profile.HostPattern)
+		if err == nil && re.MatchString(host) {
+			return profile, true
+		}
+	}
+
+	return SigningProfile{}, false
+}
+
+// SignHTTPRequest signs req with profile, regardless of its host pattern, e.g.
+// for requests that are replayed. References in the profile are replaced by
+// variables of the active environment. body is the body of req, which some
+// signing methods sign as well.
+func (svc *service) SignHTTPRequest(profile SigningProfile, req *http.Request, body []byte) {
+	env, hasEnv := svc.Environments().ActiveEnvironment()
+	env.Variables = mergeVariables(env.Variables, svc.runtimeVariables())
+
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	// The header of signed is req's header, so signing sets it on req.
+	signed := Request{URL: req.URL, Method: req.Method, Header: req.Header, Body: body}
+	now := time.Now()
+
+	profile.sign(&signed, newScriptRun(env, hasEnv, signed, now), now)
+}
+
+// sign signs req, using the variables of run for references in the profile.
+func (profile SigningProfile) sign(req *Request, run *scriptRun, now time.Time) {
+	if aws := profile.AWSV4; aws != nil {
+		signAWSV4(req, awsCredentials{
+			region:          run.expand(aws.Region),
+			service:         run.expand(aws.Service),
+			accessKeyID:     run.expand(aws.AccessKeyID),
+			secretAccessKey: run.expand(aws.SecretAccessKey),
+			sessionToken:    run.expand(aws.SessionToken),
+		}, now)
+
+		return
+	}
+
+	h := profile.HMAC
+
+	algorithm := h.Algorithm
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+
+	mac := hmac.New(hashFuncs[algorithm], []byte(run.expand(h.Key)))
+	mac.Write([]byte(run.expand(h.Message)))
+	signature := encodeDigest(mac.Sum(nil), []string{h.Encoding})
+
+	headerValue := h.HeaderValue
+	if headerValue == "" {
+		headerValue = "{{signature}}"
+	}
+
+	headerValue = expandVariables(headerValue, func(name string) (string, bool) {
+		if name == "signature" {
+			return signature, true
+		}
+
+		return run.lookup(name)
+	})
+
+	req.Header.Set(h.Header, headerValue)
+
+	if h.TimestampHeader != "" {
+		req.Header.Set(h.TimestampHeader, run.vars["timestamp"])
+	}
+}
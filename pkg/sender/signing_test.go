@@ -0,0 +1,157 @@
+package sender_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/db/memory"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+func TestSigningProfilesValidate(t *testing.T) {
+	t.Parallel()
+
+	hmacSigning := &sender.HMACSigning{Key: "secret", Header: "X-Signature"}
+	awsSigning := &sender.AWSV4Signing{Region: "eu-west-1", Service: "execute-api", AccessKeyID: "AKID"}
+
+	tests := []struct {
+		name     string
+		profiles sender.SigningProfiles
+		expErr   bool
+	}{
+		{
+			name: "valid",
+			profiles: sender.SigningProfiles{
+				{Name: "api", HostPattern: `^api\.example\.com$`, HMAC: hmacSigning},
+				{Name: "aws", HostPattern: `\.amazonaws\.com$`, AWSV4: awsSigning},
+			},
+		},
+		{
+			name:     "empty name",
+			profiles: sender.SigningProfiles{{HMAC: hmacSigning}},
+			expErr:   true,
+		},
+		{
+			name:     "duplicate name",
+			profiles: sender.SigningProfiles{{Name: "api", HMAC: hmacSigning}, {Name: "api", HMAC: hmacSigning}},
+			expErr:   true,
+		},
+		{
+			name:     "invalid host pattern",
+			profiles: sender.SigningProfiles{{Name: "api", HostPattern: "(", HMAC: hmacSigning}},
+			expErr:   true,
+		},
+		{
+			name:     "multiple signing methods",
+			profiles: sender.SigningProfiles{{Name: "api", HMAC: hmacSigning, AWSV4: awsSigning}},
+			expErr:   true,
+		},
+		{
+			name:     "no signing method",
+			profiles: sender.SigningProfiles{{Name: "api"}},
+			expErr:   true,
+		},
+		{
+			name: "unsupported algorithm",
+			profiles: sender.SigningProfiles{
+				{Name: "api", HMAC: &sender.HMACSigning{Algorithm: "crc32", Header: "X-Signature"}},
+			},
+			expErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.profiles.Validate()
+			if tt.expErr && !errors.Is(err, sender.ErrInvalidSigningProfiles) {
+				t.Fatalf("expected `sender.ErrInvalidSigningProfiles`, got: %v", err)
+			}
+
+			if !tt.expErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSigningProfiles(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(r.Method + "\n" + r.URL.Path + "\n" + r.Header.Get("X-Timestamp")))
+
+		if r.Header.Get("Authorization") != "HMAC "+base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	svc := sender.NewService(sender.Config{
+		Repository: memory.OpenDatabase(),
+		HTTPClient: &http.Client{},
+	})
+	svc.SetActiveProjectID(ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy))
+	svc.SetEnvironments(sender.Environments{
+		Environments: []sender.Environment{{Name: "dev", Variables: []sender.Variable{{Name: "secret", Value: "s3cret"}}}},
+		Active:       "dev",
+	})
+
+	u, _ := url.Parse(upstream.URL + "/orders")
+
+	req, err := svc.CreateOrUpdateRequest(ctx, sender.Request{URL: u, Proto: sender.HTTPProto1})
+	if err != nil {
+		t.Fatalf("unexpected error creating request: %v", err)
+	}
+
+	tests := []struct {
+		hostPattern   string
+		expStatusCode int
+	}{
+		{hostPattern: `^127\.0\.0\.1$`, expStatusCode: http.StatusOK},
+		{hostPattern: `^example\.com$`, expStatusCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		svc.SetSigningProfiles(sender.SigningProfiles{
+			{
+				Name:        "api",
+				HostPattern: tt.hostPattern,
+				HMAC: &sender.HMACSigning{
+					Key:             "{{secret}}",
+					Message:         "{{request.method}}\n{{request.path}}\n{{timestamp}}",
+					Encoding:        "base64",
+					Header:          "Authorization",
+					HeaderValue:     "HMAC {{signature}}",
+					TimestampHeader: "X-Timestamp",
+				},
+			},
+		})
+
+		got, err := svc.SendRequest(ctx, req.ID)
+		if err != nil {
+			t.Fatalf("unexpected error sending request: %v", err)
+		}
+
+		if got.Response.StatusCode != tt.expStatusCode {
+			t.Fatalf("host pattern %q: expected status code %v, got: %v",
+				tt.hostPattern, tt.expStatusCode, got.Response.StatusCode)
+		}
+	}
+}
@@ -5,9 +5,19 @@ import (
 	"net"
 	"net/http"
 	"time"
+
+	"github.com/dstotijn/hetty/pkg/ratelimit"
 )
 
-type HTTPTransport struct{}
+type HTTPTransport struct {
+	// Transport used for requests that follow a redirect. When set (typically
+	// to the proxy), redirect hops are logged with the sender request's
+	// correlation ID.
+	RedirectTransport http.RoundTripper
+	// Limits the rate of requests per host. Redirects aren't limited by it; the
+	// redirect transport (e.g. the proxy) limits them. Optional.
+	RateLimiter *ratelimit.Limiter
+}
 
 type protoCtxKey struct{}
 
@@ -36,6 +46,15 @@ var h1OnlyTransport = &http.Transport{
 // HTTP request, it switches between using `http.DefaultTransport` (which attempts
 // HTTP/2) and a HTTP/1.1 only transport that's based off `http.DefaultTransport`.
 func (t *HTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The `Response` field is only set for requests that follow a redirect.
+	if req.Response != nil && t.RedirectTransport != nil {
+		return t.RedirectTransport.RoundTrip(req)
+	}
+
+	if err := t.RateLimiter.Wait(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+
 	proto, ok := req.Context().Value(protoCtxKey{}).(string)
 
 	if ok && proto == HTTPProto1 {
@@ -0,0 +1,533 @@
+package smuggle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/event"
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/idgen"
+	"github.com/dstotijn/hetty/pkg/job"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+	"github.com/dstotijn/hetty/pkg/sender"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// JobType is the type of the jobs that run tests, see `job.Params`.
+const JobType = "smugglingTest"
+
+var (
+	ErrTestNotFound = errcode.New(errcode.NotFound, "smuggle: test not found")
+	ErrOutOfScope   = errcode.New(errcode.Invalid, "smuggle: request is out of scope")
+)
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusFinished  Status = "finished"
+	StatusCancelled Status = "cancelled"
+	StatusFailed    Status = "failed"
+)
+
+// Technique is the way front-end and back-end servers disagree on the length
+// of a request body.
+type Technique string
+
+const (
+	// The front-end uses `Content-Length`, the back-end `Transfer-Encoding`.
+	TechniqueCLTE Technique = "cl_te"
+	// The front-end uses `Transfer-Encoding`, the back-end `Content-Length`.
+	TechniqueTECL Technique = "te_cl"
+)
+
+// teVariant is a way to send the `Transfer-Encoding` header. Obfuscated
+// variants detect TE.TE desyncs, where one of the servers ignores the header.
+type teVariant struct {
+	name   string
+	header string
+}
+
+var teVariants = []teVariant{
+	{name: "plain", header: "Transfer-Encoding: chunked"},
+	{name: "space before colon", header: "Transfer-Encoding : chunked"},
+	{name: "tab separator", header: "Transfer-Encoding:\tchunked"},
+	{name: "lowercase", header: "transfer-encoding: chunked"},
+	{name: "duplicate header", header: "Transfer-Encoding: chunked\r\nTransfer-Encoding: x"},
+	{name: "obs-fold", header: "Transfer-Encoding:\r\n chunked"},
+	{name: "quoted value", header: "Transfer-Encoding: \"chunked\""},
+}
+
+// Service runs request smuggling tests, which send timing based CL.TE, TE.CL
+// and TE.TE probes for a logged request, over raw connections. Probes are
+// designed to make a vulnerable back-end wait for bytes that never arrive,
+// instead of prefixing the requests of other users. Vulnerabilities are stored
+// as findings.
+type Service interface {
+	StartTest(ctx context.Context, params TestParams) (Test, error)
+	FindTestByID(id ulid.ULID) (Test, error)
+	FindTests() []Test
+	CancelTest(id ulid.ULID) error
+}
+
+type service struct {
+	scope       *scope.Scope
+	ids         idgen.Generator
+	jobs        job.Service
+	reqLogSvc   reqlog.Service
+	findingRepo finding.Repository
+	events      *event.Bus
+	tests       map[ulid.ULID]*testState
+	mu          sync.RWMutex
+}
+
+type Config struct {
+	Scope             *scope.Scope
+	RequestLogService reqlog.Service
+	FindingRepository finding.Repository
+	// Bus for publishing created findings. Optional.
+	Events *event.Bus
+	// Generates the IDs of tests and findings. Defaults to `idgen.Default()`.
+	IDGenerator idgen.Generator
+	// Runs tests as jobs, so that they're queued, report progress and can be
+	// paused. Optional; without it, tests start right away.
+	Jobs job.Service
+}
+
+type TestParams struct {
+	RequestLogID ulid.ULID
+	// Time to wait for a response to a probe. A probe that times out, while
+	// the baseline request doesn't, indicates a desync. Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+type Test struct {
+	ID           ulid.ULID
+	RequestLogID ulid.ULID
+	URL          *url.URL
+	Status       Status
+	// Duration of a well-formed request, to compare probes with.
+	BaselineDuration time.Duration
+	// Error that caused the test to fail, if any.
+	Error   string
+	Results []Result
+}
+
+// Result is the response evidence of a probe.
+type Result struct {
+	Technique Technique
+	// Variant of the `Transfer-Encoding` header.
+	Variant  string
+	Duration time.Duration
+	TimedOut bool
+	// Status code of the response, if a valid response was received.
+	StatusCode int
+	// Vulnerable is true if the probe timed out on two attempts, while the
+	// baseline request didn't.
+	Vulnerable bool
+}
+
+type testState struct {
+	test   Test
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+func NewService(cfg Config) Service {
+	if cfg.IDGenerator == nil {
+		cfg.IDGenerator = idgen.Default()
+	}
+
+	return &service{
+		ids:         cfg.IDGenerator,
+		scope:       cfg.Scope,
+		jobs:        cfg.Jobs,
+		reqLogSvc:   cfg.RequestLogService,
+		findingRepo: cfg.FindingRepository,
+		events:      cfg.Events,
+		tests:       make(map[ulid.ULID]*testState),
+	}
+}
+
+// StartTest starts a test of a logged request in the background. The request
+// must match the project scope.
+func (svc *service) StartTest(ctx context.Context, params TestParams) (Test, error) {
+	reqLog, err := svc.reqLogSvc.FindRequestLogByID(ctx, params.RequestLogID)
+	if err != nil {
+		return Test{}, fmt.Errorf("smuggle: failed to find request log: %w", err)
+	}
+
+	if reqLog.URL == nil || reqLog.URL.Host == "" {
+		return Test{}, errcode.New(errcode.Invalid, "smuggle: request log URL must be absolute")
+	}
+
+	if svc.scope != nil && !svc.scope.Match(&http.Request{URL: reqLog.URL, Header: reqLog.Header}, reqLog.Body) {
+		return Test{}, ErrOutOfScope
+	}
+
+	if params.Timeout <= 0 {
+		params.Timeout = defaultTimeout
+	}
+
+	testCtx, cancel := context.WithCancel(context.Background())
+
+	state := &testState{
+		test: Test{
+			ID:           svc.ids.New(time.Now()),
+			RequestLogID: reqLog.ID,
+			URL:          reqLog.URL,
+			Status:       StatusRunning,
+			Results:      make([]Result, 0),
+		},
+		cancel: cancel,
+	}
+
+	svc.mu.Lock()
+	svc.tests[state.test.ID] = state
+	svc.mu.Unlock()
+
+	if svc.jobs == nil {
+		go svc.run(testCtx, state, reqLog, params.Timeout, nil)
+		return state.snapshot(), nil
+	}
+
+	jobParams := job.Params{
+		ID:          state.test.ID,
+		Type:        JobType,
+		Description: reqLog.URL.String(),
+		Total:       2 * len(teVariants),
+	}
+
+	svc.jobs.StartJob(testCtx, jobParams, func(ctx context.Context, progress *job.Progress) error {
+		return svc.run(ctx, state, reqLog, params.Timeout, progress)
+	})
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindTestByID(id ulid.ULID) (Test, error) {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	state, ok := svc.tests[id]
+	if !ok {
+		return Test{}, ErrTestNotFound
+	}
+
+	return state.snapshot(), nil
+}
+
+func (svc *service) FindTests() []Test {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	tests := make([]Test, 0, len(svc.tests))
+	for _, state := range svc.tests {
+		tests = append(tests, state.snapshot())
+	}
+
+	// Most recent tests first.
+	sort.Slice(tests, func(i, j int) bool {
+		return tests[i].ID.Compare(tests[j].ID) > 0
+	})
+
+	return tests
+}
+
+func (svc *service) CancelTest(id ulid.ULID) error {
+	svc.mu.RLock()
+	state, ok := svc.tests[id]
+	svc.mu.RUnlock()
+
+	if !ok {
+		return ErrTestNotFound
+	}
+
+	state.mu.Lock()
+	if state.test.Status == StatusRunning {
+		state.test.Status = StatusCancelled
+	}
+	state.mu.Unlock()
+
+	state.cancel()
+
+	return nil
+}
+
+// run sends the baseline request and the probes of a test. Progress is
+// reported as probes out of a CL.TE and a TE.CL probe per variant.
+func (svc *service) run(
+	ctx context.Context,
+	state *testState,
+	reqLog reqlog.RequestLog,
+	timeout time.Duration,
+	progress *job.Progress,
+) error {
+	defer state.cancel()
+
+	baseline, err := sender.SendRawRequest(ctx, reqLog.URL, buildRequest(reqLog, "Content-Length: 1", "X"), timeout)
+	if err == nil && baseline.TimedOut {
+		err = errors.New("baseline request timed out")
+	}
+
+	if ctx.Err() != nil {
+		state.finish(ctx)
+		return ctx.Err()
+	}
+
+	if err != nil {
+		state.fail(err)
+		return err
+	}
+
+	state.mu.Lock()
+	state.test.BaselineDuration = baseline.Duration
+	state.mu.Unlock()
+
+	vulnerable := make(map[Technique]Result)
+
+	for _, variant := range teVariants {
+		if err := progress.Wait(ctx); err != nil {
+			break
+		}
+
+		result, ok := svc.probe(ctx, reqLog, TechniqueCLTE, variant, timeout)
+		if !ok {
+			break
+		}
+
+		state.addResult(result)
+		progress.Add(1)
+
+		if result.Vulnerable {
+			if _, ok := vulnerable[TechniqueCLTE]; !ok {
+				vulnerable[TechniqueCLTE] = result
+			}
+
+			// A TE.CL probe desyncs a CL.TE vulnerable server, which would
+			// prefix the next request on the connection, so it's skipped.
+			progress.Add(1)
+
+			continue
+		}
+
+		result, ok = svc.probe(ctx, reqLog, TechniqueTECL, variant, timeout)
+		if !ok {
+			break
+		}
+
+		state.addResult(result)
+		progress.Add(1)
+
+		if _, ok := vulnerable[TechniqueTECL]; !ok && result.Vulnerable {
+			vulnerable[TechniqueTECL] = result
+		}
+	}
+
+	for _, technique := range []Technique{TechniqueCLTE, TechniqueTECL} {
+		if result, ok := vulnerable[technique]; ok {
+			svc.storeFinding(reqLog, result, baseline.Duration)
+		}
+	}
+
+	state.finish(ctx)
+
+	return ctx.Err()
+}
+
+// probe sends a probe, and resends it when it times out, to rule out a slow
+// response. It returns false when ctx is done.
+func (svc *service) probe(
+	ctx context.Context,
+	reqLog reqlog.RequestLog,
+	technique Technique,
+	variant teVariant,
+	timeout time.Duration,
+) (Result, bool) {
+	raw := buildProbe(reqLog, technique, variant)
+	result := Result{
+		Technique: technique,
+		Variant:   variant.name,
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		res, err := sender.SendRawRequest(ctx, reqLog.URL, raw, timeout)
+		if ctx.Err() != nil {
+			return Result{}, false
+		}
+
+		if err != nil {
+			return result, true
+		}
+
+		result.Duration = res.Duration
+		result.TimedOut = res.TimedOut
+		result.StatusCode = statusCode(res.Raw)
+
+		if !res.TimedOut {
+			return result, true
+		}
+	}
+
+	result.Vulnerable = true
+
+	return result, true
+}
+
+func (svc *service) storeFinding(reqLog reqlog.RequestLog, result Result, baseline time.Duration) {
+	check := finding.CheckRequestSmugglingCLTE
+	if result.Technique == TechniqueTECL {
+		check = finding.CheckRequestSmugglingTECL
+	}
+
+	f := finding.Finding{
+		ID:           svc.ids.New(time.Now()),
+		ProjectID:    reqLog.ProjectID,
+		RequestLogID: reqLog.ID,
+		Check:        check,
+		Severity:     finding.SeverityHigh,
+		Description: fmt.Sprintf(
+			"Possible %v request smuggling (Transfer-Encoding variant: %v): probe timed out after %v twice, "+
+				"while a well-formed request took %v.",
+			strings.ToUpper(strings.Replace(string(result.Technique), "_", ".", 1)),
+			result.Variant, result.Duration.Round(time.Millisecond), baseline.Round(time.Millisecond),
+		),
+		HeaderKey: "Transfer-Encoding",
+	}
+
+	if err := svc.findingRepo.StoreFinding(context.Background(), f); err != nil {
+		log.Printf("[ERROR] Could not store request smuggling finding: %v", err)
+		return
+	}
+
+	svc.events.Publish(event.Event{
+		Type:      event.TypeFindingCreated,
+		ProjectID: f.ProjectID,
+		ID:        f.ID,
+		Data:      f,
+	})
+}
+
+// buildProbe returns a probe that makes a back-end that uses a different body
+// length than the front-end wait for more bytes.
+func buildProbe(reqLog reqlog.RequestLog, technique Technique, variant teVariant) []byte {
+	switch technique {
+	case TechniqueCLTE:
+		// The front-end forwards 4 bytes, a chunk without its terminating
+		// chunk; the back-end waits for the next chunk.
+		return buildRequest(reqLog, variant.header+"\r\nContent-Length: 4", "1\r\nA\r\nX")
+	default:
+		// The front-end forwards the terminating chunk only; the back-end
+		// waits for the remaining 6 content bytes.
+		return buildRequest(reqLog, variant.header+"\r\nContent-Length: 6", "0\r\n\r\nX")
+	}
+}
+
+// buildRequest returns a POST request for the URL of reqLog, with its headers,
+// except those that determine the body length, and with framing headers and
+// body.
+func buildRequest(reqLog reqlog.RequestLog, framing, body string) []byte {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "POST %v HTTP/1.1\r\n", reqLog.URL.RequestURI())
+	fmt.Fprintf(buf, "Host: %v\r\n", reqLog.URL.Host)
+
+	keys := make([]string, 0, len(reqLog.Header))
+
+	for key := range reqLog.Header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Host", "Content-Length", "Transfer-Encoding", "Connection":
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range reqLog.Header[key] {
+			fmt.Fprintf(buf, "%v: %v\r\n", key, value)
+		}
+	}
+
+	if reqLog.Header.Get("Content-Type") == "" {
+		buf.WriteString("Content-Type: application/x-www-form-urlencoded\r\n")
+	}
+
+	buf.WriteString(framing + "\r\n\r\n")
+	buf.WriteString(body)
+
+	return buf.Bytes()
+}
+
+// statusCode returns the status code of a raw response, or 0 if it doesn't
+// start with a status line.
+func statusCode(raw []byte) int {
+	var (
+		proto string
+		code  int
+	)
+
+	if _, err := fmt.Sscanf(string(raw), "%s %d", &proto, &code); err != nil || !strings.HasPrefix(proto, "HTTP/") {
+		return 0
+	}
+
+	return code
+}
+
+func (state *testState) addResult(result Result) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.test.Results = append(state.test.Results, result)
+}
+
+// finish sets the status of a running test to finished, or to cancelled if ctx
+// is done.
+func (state *testState) finish(ctx context.Context) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.test.Status == StatusRunning {
+		state.test.Status = StatusFinished
+		if ctx.Err() != nil {
+			state.test.Status = StatusCancelled
+		}
+	}
+}
+
+func (state *testState) fail(err error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.test.Status == StatusRunning {
+		state.test.Status = StatusFailed
+		state.test.Error = err.Error()
+	}
+}
+
+func (state *testState) snapshot() Test {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	test := state.test
+	test.Results = make([]Result, len(state.test.Results))
+	copy(test.Results, state.test.Results)
+
+	return test
+}
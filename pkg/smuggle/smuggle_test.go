@@ -0,0 +1,204 @@
+package smuggle_test
+
+//go:generate go run github.com/matryer/moq -out reqlog_mock_test.go -pkg smuggle_test ../reqlog Service:ReqLogServiceMock
+//go:generate go run github.com/matryer/moq -out finding_repo_mock_test.go -pkg smuggle_test ../finding Repository:FindingRepoMock
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/oklog/ulid"
+
+	"github.com/dstotijn/hetty/pkg/finding"
+	"github.com/dstotijn/hetty/pkg/reqlog"
+	"github.com/dstotijn/hetty/pkg/scope"
+	"github.com/dstotijn/hetty/pkg/smuggle"
+)
+
+//nolint:gosec
+var ulidEntropy = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// newCLTEServer returns the URL of a server that only recognizes
+// `Transfer-Encoding: chunked` exactly (as a back-end behind a front-end that
+// uses `Content-Length`), which makes it vulnerable to CL.TE probes.
+func newCLTEServer(t *testing.T) *url.URL {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go handleCLTEConn(conn)
+		}
+	}()
+
+	u, err := url.Parse("http://" + ln.Addr().String() + "/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+func handleCLTEConn(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+
+	var (
+		chunked       bool
+		contentLength int
+	)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		if line == "\r\n" {
+			break
+		}
+
+		if line == "Transfer-Encoding: chunked\r\n" {
+			chunked = true
+		}
+
+		if strings.HasPrefix(line, "Content-Length: ") {
+			contentLength, _ = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length: ")))
+		}
+	}
+
+	var body io.Reader = io.LimitReader(r, int64(contentLength))
+	if chunked {
+		body = httputil.NewChunkedReader(r)
+	}
+
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return
+	}
+
+	conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+}
+
+func TestStartTest(t *testing.T) {
+	t.Parallel()
+
+	u := newCLTEServer(t)
+
+	reqLog := reqlog.RequestLog{
+		ID:        ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		ProjectID: ulid.MustNew(ulid.Timestamp(time.Now()), ulidEntropy),
+		URL:       u,
+		Method:    http.MethodGet,
+		Header:    http.Header{"Cookie": []string{"foo=bar"}},
+	}
+
+	reqLogSvc := &ReqLogServiceMock{
+		FindRequestLogByIDFunc: func(_ context.Context, _ ulid.ULID) (reqlog.RequestLog, error) {
+			return reqLog, nil
+		},
+	}
+
+	findingRepo := &FindingRepoMock{
+		StoreFindingFunc: func(_ context.Context, _ finding.Finding) error {
+			return nil
+		},
+	}
+
+	t.Run("out of scope", func(t *testing.T) {
+		t.Parallel()
+
+		svc := smuggle.NewService(smuggle.Config{
+			Scope:             &scope.Scope{},
+			RequestLogService: reqLogSvc,
+			FindingRepository: findingRepo,
+		})
+
+		_, err := svc.StartTest(context.Background(), smuggle.TestParams{RequestLogID: reqLog.ID})
+		if !errors.Is(err, smuggle.ErrOutOfScope) {
+			t.Fatalf("expected `smuggle.ErrOutOfScope`, got: %v", err)
+		}
+	})
+
+	t.Run("detects CL.TE desync", func(t *testing.T) {
+		t.Parallel()
+
+		s := &scope.Scope{}
+		s.SetRules([]scope.Rule{{URL: regexp.MustCompile(regexp.QuoteMeta(u.Host))}})
+
+		svc := smuggle.NewService(smuggle.Config{
+			Scope:             s,
+			RequestLogService: reqLogSvc,
+			FindingRepository: findingRepo,
+		})
+
+		test, err := svc.StartTest(context.Background(), smuggle.TestParams{
+			RequestLogID: reqLog.ID,
+			Timeout:      100 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+
+		for test.Status == smuggle.StatusRunning && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+
+			test, err = svc.FindTestByID(test.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		if test.Status != smuggle.StatusFinished {
+			t.Fatalf("expected test to finish, got status: %v (error: %v)", test.Status, test.Error)
+		}
+
+		var vulnerable []string
+
+		for _, result := range test.Results {
+			if result.Vulnerable {
+				vulnerable = append(vulnerable, string(result.Technique)+" "+result.Variant)
+			}
+		}
+
+		// The server recognizes the exact header in both variants.
+		if exp := []string{"cl_te plain", "cl_te duplicate header"}; !cmp.Equal(exp, vulnerable) {
+			t.Fatalf("expected vulnerable probes %v, got: %+v", exp, test.Results)
+		}
+
+		calls := findingRepo.StoreFindingCalls()
+		if len(calls) != 1 || calls[0].FindingMoqParam.Check != finding.CheckRequestSmugglingCLTE {
+			t.Fatalf("expected CL.TE finding to be stored, got: %+v", calls)
+		}
+
+		if got := calls[0].FindingMoqParam; got.RequestLogID != reqLog.ID || got.ProjectID != reqLog.ProjectID {
+			t.Fatalf("expected finding for request log, got: %+v", got)
+		}
+	})
+}
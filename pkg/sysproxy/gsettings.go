@@ -0,0 +1,36 @@
+package sysproxy
+
+import (
+	"strings"
+)
+
+// GNOME proxy settings that are changed on Linux, in the form "schema key".
+var gnomeKeys = []string{
+	"org.gnome.system.proxy mode",
+	"org.gnome.system.proxy.http host",
+	"org.gnome.system.proxy.http port",
+	"org.gnome.system.proxy.https host",
+	"org.gnome.system.proxy.https port",
+}
+
+func gnomeGetCommand(key string) []string {
+	return append([]string{"gsettings", "get"}, strings.Fields(key)...)
+}
+
+// gnomeSetCommand returns the command that sets key to value, which is either
+// a plain value or, for restores, the output of `gsettings get`.
+func gnomeSetCommand(key, value string) []string {
+	return append(append([]string{"gsettings", "set"}, strings.Fields(key)...), value)
+}
+
+// gnomeProxyValues returns the values of gnomeKeys that configure a manual
+// proxy at host and port.
+func gnomeProxyValues(host, port string) map[string]string {
+	return map[string]string{
+		"org.gnome.system.proxy mode":       "manual",
+		"org.gnome.system.proxy.http host":  host,
+		"org.gnome.system.proxy.http port":  port,
+		"org.gnome.system.proxy.https host": host,
+		"org.gnome.system.proxy.https port": port,
+	}
+}
@@ -0,0 +1,87 @@
+package sysproxy
+
+import (
+	"strings"
+)
+
+// Proxy kinds of `networksetup` that are configured on macOS: the web (HTTP)
+// and secure web (HTTPS) proxy.
+var darwinProxyKinds = []string{"webproxy", "securewebproxy"}
+
+type darwinProxy struct {
+	enabled bool
+	server  string
+	port    string
+}
+
+func listNetworkServicesCommand() []string {
+	return []string{"networksetup", "-listallnetworkservices"}
+}
+
+func getProxyCommand(service, kind string) []string {
+	return []string{"networksetup", "-get" + kind, service}
+}
+
+func setProxyCommand(service, kind, host, port string) []string {
+	return []string{"networksetup", "-set" + kind, service, host, port}
+}
+
+// restoreProxyCommand returns the command that restores proxy p of a service,
+// as parsed by parseProxy before it was changed.
+func restoreProxyCommand(service, kind string, p darwinProxy) []string {
+	if p.enabled {
+		return setProxyCommand(service, kind, p.server, p.port)
+	}
+
+	return []string{"networksetup", "-set" + kind + "state", service, "off"}
+}
+
+// parseNetworkServices parses the output of `networksetup
+// -listallnetworkservices`, and returns the enabled services.
+func parseNetworkServices(out string) []string {
+	var services []string
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+
+		// The first line is an informational message, and disabled services
+		// are prefixed with an asterisk.
+		if line == "" || strings.HasPrefix(line, "*") || strings.HasPrefix(line, "An asterisk") {
+			continue
+		}
+
+		services = append(services, line)
+	}
+
+	return services
+}
+
+// parseProxy parses the output of `networksetup -getwebproxy`, e.g.:
+//
+//	Enabled: Yes
+//	Server: 127.0.0.1
+//	Port: 8080
+//	Authenticated Proxy Enabled: 0
+func parseProxy(out string) darwinProxy {
+	var p darwinProxy
+
+	for _, line := range strings.Split(out, "\n") {
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		value := strings.TrimSpace(kv[1])
+
+		switch strings.TrimSpace(kv[0]) {
+		case "Enabled":
+			p.enabled = value == "Yes"
+		case "Server":
+			p.server = value
+		case "Port":
+			p.port = value
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,65 @@
+package sysproxy
+
+import (
+	"strings"
+)
+
+// Registry key of the WinINet proxy settings of the current user on Windows.
+const internetSettingsKey = `HKCU\Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+
+// regValue is the data of a registry value, as formatted by `reg query` (e.g.
+// `0x1` for DWORD values). ok is false if the value doesn't exist.
+type regValue struct {
+	data string
+	ok   bool
+}
+
+func regQueryCommand(name string) []string {
+	return []string{"reg", "query", internetSettingsKey, "/v", name}
+}
+
+func regAddCommand(name, typ, data string) []string {
+	return []string{"reg", "add", internetSettingsKey, "/v", name, "/t", typ, "/d", data, "/f"}
+}
+
+func regDeleteCommand(name string) []string {
+	return []string{"reg", "delete", internetSettingsKey, "/v", name, "/f"}
+}
+
+// windowsEnableCommands returns the commands that enable the proxy at host and
+// port, in order.
+func windowsEnableCommands(host, port string) [][]string {
+	return [][]string{
+		regAddCommand("ProxyServer", "REG_SZ", host+":"+port),
+		regAddCommand("ProxyEnable", "REG_DWORD", "1"),
+	}
+}
+
+// windowsRestoreCommands returns the commands that restore the previous values
+// of `ProxyEnable` and `ProxyServer`, in order. A missing `ProxyEnable` value
+// disables the proxy, and a missing `ProxyServer` value is deleted.
+func windowsRestoreCommands(prevEnable, prevServer regValue) [][]string {
+	enable := regAddCommand("ProxyEnable", "REG_DWORD", "0")
+	if prevEnable.ok {
+		enable = regAddCommand("ProxyEnable", "REG_DWORD", prevEnable.data)
+	}
+
+	server := regDeleteCommand("ProxyServer")
+	if prevServer.ok {
+		server = regAddCommand("ProxyServer", "REG_SZ", prevServer.data)
+	}
+
+	return [][]string{enable, server}
+}
+
+// parseRegValue parses the output of `reg query` for the value name.
+func parseRegValue(out, name string) regValue {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && strings.EqualFold(fields[0], name) {
+			return regValue{data: strings.Join(fields[2:], " "), ok: true}
+		}
+	}
+
+	return regValue{}
+}
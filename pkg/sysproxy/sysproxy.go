@@ -0,0 +1,59 @@
+package sysproxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+var ErrUnsupported = errors.New("sysproxy: system proxy configuration is not supported on this platform")
+
+// RestoreFunc restores the system proxy settings that were in place before
+// they were changed.
+type RestoreFunc func() error
+
+// Enable configures the system proxy settings for HTTP and HTTPS to use the
+// proxy listening on addr. It returns a function to restore the previous
+// settings, which should be called before Hetty exits.
+func Enable(addr string) (RestoreFunc, error) {
+	host, port, err := splitAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	restore, err := enable(host, port)
+	if err != nil {
+		return nil, fmt.Errorf("sysproxy: failed to configure system proxy: %w", err)
+	}
+
+	return restore, nil
+}
+
+// splitAddr splits a listen address, and uses the loopback address if it
+// doesn't have a host.
+func splitAddr(addr string) (string, string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", "", fmt.Errorf("sysproxy: invalid address: %w", err)
+	}
+
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+
+	return host, port, nil
+}
+
+// run runs a command line, of which the first element is the name of the
+// program. Commands are built by pure functions, so that they can be tested on
+// all platforms.
+func run(cmd []string) (string, error) {
+	out, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v: %w: %s", strings.Join(cmd, " "), err, strings.TrimSpace(string(out)))
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,49 @@
+package sysproxy
+
+// enable configures the web (HTTP) and secure web (HTTPS) proxy of all enabled
+// network services via `networksetup`.
+func enable(host, port string) (RestoreFunc, error) {
+	out, err := run(listNetworkServicesCommand())
+	if err != nil {
+		return nil, err
+	}
+
+	type prevSettings struct {
+		service string
+		kind    string
+		proxy   darwinProxy
+	}
+
+	var prev []prevSettings
+
+	restore := func() error {
+		var firstErr error
+
+		for _, p := range prev {
+			if _, err := run(restoreProxyCommand(p.service, p.kind, p.proxy)); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		return firstErr
+	}
+
+	for _, service := range parseNetworkServices(out) {
+		for _, kind := range darwinProxyKinds {
+			out, err := run(getProxyCommand(service, kind))
+			if err != nil {
+				_ = restore()
+				return nil, err
+			}
+
+			if _, err := run(setProxyCommand(service, kind, host, port)); err != nil {
+				_ = restore()
+				return nil, err
+			}
+
+			prev = append(prev, prevSettings{service: service, kind: kind, proxy: parseProxy(out)})
+		}
+	}
+
+	return restore, nil
+}
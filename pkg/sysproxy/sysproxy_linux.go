@@ -0,0 +1,47 @@
+package sysproxy
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// enable configures the GNOME proxy settings via `gsettings`.
+func enable(host, port string) (RestoreFunc, error) {
+	if _, err := exec.LookPath("gsettings"); err != nil {
+		return nil, ErrUnsupported
+	}
+
+	prev := make(map[string]string, len(gnomeKeys))
+
+	for _, key := range gnomeKeys {
+		out, err := run(gnomeGetCommand(key))
+		if err != nil {
+			return nil, err
+		}
+
+		prev[key] = strings.TrimSpace(out)
+	}
+
+	restore := func() error {
+		var firstErr error
+
+		for _, key := range gnomeKeys {
+			if _, err := run(gnomeSetCommand(key, prev[key])); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		return firstErr
+	}
+
+	values := gnomeProxyValues(host, port)
+
+	for _, key := range gnomeKeys {
+		if _, err := run(gnomeSetCommand(key, values[key])); err != nil {
+			_ = restore()
+			return nil, err
+		}
+	}
+
+	return restore, nil
+}
@@ -0,0 +1,8 @@
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package sysproxy
+
+func enable(host, port string) (RestoreFunc, error) {
+	return nil, ErrUnsupported
+}
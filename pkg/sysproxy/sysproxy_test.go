@@ -0,0 +1,196 @@
+package sysproxy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitAddr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		addr    string
+		expHost string
+		expPort string
+		expErr  bool
+	}{
+		{addr: ":8080", expHost: "127.0.0.1", expPort: "8080"},
+		{addr: "0.0.0.0:8080", expHost: "127.0.0.1", expPort: "8080"},
+		{addr: "[::]:8080", expHost: "127.0.0.1", expPort: "8080"},
+		{addr: "192.168.1.10:8080", expHost: "192.168.1.10", expPort: "8080"},
+		{addr: "8080", expErr: true},
+	}
+
+	for _, tt := range tests {
+		host, port, err := splitAddr(tt.addr)
+		if tt.expErr {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.addr)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.addr, err)
+			continue
+		}
+
+		if host != tt.expHost || port != tt.expPort {
+			t.Errorf("%q: expected %v %v, got: %v %v", tt.addr, tt.expHost, tt.expPort, host, port)
+		}
+	}
+}
+
+func TestDarwinCommands(t *testing.T) {
+	t.Parallel()
+
+	services := parseNetworkServices("An asterisk (*) denotes that a network service is disabled.\n" +
+		"Wi-Fi\n*Bluetooth PAN\nThunderbolt Bridge\n")
+	if exp := []string{"Wi-Fi", "Thunderbolt Bridge"}; !cmp.Equal(exp, services) {
+		t.Fatalf("expected services %v, got: %v", exp, services)
+	}
+
+	exp := []string{"networksetup", "-setsecurewebproxy", "Wi-Fi", "127.0.0.1", "8080"}
+	if got := setProxyCommand("Wi-Fi", "securewebproxy", "127.0.0.1", "8080"); !cmp.Equal(exp, got) {
+		t.Errorf("expected set command %v, got: %v", exp, got)
+	}
+
+	exp = []string{"networksetup", "-getwebproxy", "Wi-Fi"}
+	if got := getProxyCommand("Wi-Fi", "webproxy"); !cmp.Equal(exp, got) {
+		t.Errorf("expected get command %v, got: %v", exp, got)
+	}
+
+	// Restores of the output of the get command.
+	tests := []struct {
+		name string
+		out  string
+		exp  []string
+	}{
+		{
+			name: "enabled proxy",
+			out:  "Enabled: Yes\nServer: proxy.example.com\nPort: 3128\nAuthenticated Proxy Enabled: 0\n",
+			exp:  []string{"networksetup", "-setwebproxy", "Wi-Fi", "proxy.example.com", "3128"},
+		},
+		{
+			name: "disabled proxy",
+			out:  "Enabled: No\nServer: \nPort: 0\nAuthenticated Proxy Enabled: 0\n",
+			exp:  []string{"networksetup", "-setwebproxystate", "Wi-Fi", "off"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := restoreProxyCommand("Wi-Fi", "webproxy", parseProxy(tt.out))
+		if !cmp.Equal(tt.exp, got) {
+			t.Errorf("%v: expected restore command %v, got: %v", tt.name, tt.exp, got)
+		}
+	}
+}
+
+func TestGNOMECommands(t *testing.T) {
+	t.Parallel()
+
+	exp := []string{"gsettings", "get", "org.gnome.system.proxy.http", "port"}
+	if got := gnomeGetCommand("org.gnome.system.proxy.http port"); !cmp.Equal(exp, got) {
+		t.Errorf("expected get command %v, got: %v", exp, got)
+	}
+
+	values := gnomeProxyValues("127.0.0.1", "8080")
+
+	var got [][]string
+	for _, key := range gnomeKeys {
+		got = append(got, gnomeSetCommand(key, values[key]))
+	}
+
+	expEnable := [][]string{
+		{"gsettings", "set", "org.gnome.system.proxy", "mode", "manual"},
+		{"gsettings", "set", "org.gnome.system.proxy.http", "host", "127.0.0.1"},
+		{"gsettings", "set", "org.gnome.system.proxy.http", "port", "8080"},
+		{"gsettings", "set", "org.gnome.system.proxy.https", "host", "127.0.0.1"},
+		{"gsettings", "set", "org.gnome.system.proxy.https", "port", "8080"},
+	}
+	if diff := cmp.Diff(expEnable, got); diff != "" {
+		t.Errorf("enable commands not equal (-exp, +got):\n%v", diff)
+	}
+
+	// The output of `gsettings get` is a serialized value, which is set as is
+	// when the settings are restored.
+	prev := map[string]string{
+		"org.gnome.system.proxy mode":       "'none'\n",
+		"org.gnome.system.proxy.http host":  "''\n",
+		"org.gnome.system.proxy.http port":  "0\n",
+		"org.gnome.system.proxy.https host": "'proxy.example.com'\n",
+		"org.gnome.system.proxy.https port": "3128\n",
+	}
+
+	got = nil
+	for _, key := range gnomeKeys {
+		got = append(got, gnomeSetCommand(key, strings.TrimSpace(prev[key])))
+	}
+
+	expRestore := [][]string{
+		{"gsettings", "set", "org.gnome.system.proxy", "mode", "'none'"},
+		{"gsettings", "set", "org.gnome.system.proxy.http", "host", "''"},
+		{"gsettings", "set", "org.gnome.system.proxy.http", "port", "0"},
+		{"gsettings", "set", "org.gnome.system.proxy.https", "host", "'proxy.example.com'"},
+		{"gsettings", "set", "org.gnome.system.proxy.https", "port", "3128"},
+	}
+	if diff := cmp.Diff(expRestore, got); diff != "" {
+		t.Errorf("restore commands not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestWindowsCommands(t *testing.T) {
+	t.Parallel()
+
+	expEnable := [][]string{
+		{"reg", "add", internetSettingsKey, "/v", "ProxyServer", "/t", "REG_SZ", "/d", "127.0.0.1:8080", "/f"},
+		{"reg", "add", internetSettingsKey, "/v", "ProxyEnable", "/t", "REG_DWORD", "/d", "1", "/f"},
+	}
+	if diff := cmp.Diff(expEnable, windowsEnableCommands("127.0.0.1", "8080")); diff != "" {
+		t.Errorf("enable commands not equal (-exp, +got):\n%v", diff)
+	}
+
+	// Restores of the output of `reg query`.
+	tests := []struct {
+		name      string
+		enableOut string
+		serverOut string
+		exp       [][]string
+	}{
+		{
+			name: "previous proxy",
+			enableOut: "\r\nHKEY_CURRENT_USER\\Software\\Microsoft\\Windows\\CurrentVersion\\Internet Settings\r\n" +
+				"    ProxyEnable    REG_DWORD    0x1\r\n\r\n",
+			serverOut: "\r\nHKEY_CURRENT_USER\\Software\\Microsoft\\Windows\\CurrentVersion\\Internet Settings\r\n" +
+				"    ProxyServer    REG_SZ    proxy.example.com:3128\r\n\r\n",
+			exp: [][]string{
+				{"reg", "add", internetSettingsKey, "/v", "ProxyEnable", "/t", "REG_DWORD", "/d", "0x1", "/f"},
+				{"reg", "add", internetSettingsKey, "/v", "ProxyServer", "/t", "REG_SZ", "/d", "proxy.example.com:3128", "/f"},
+			},
+		},
+		{
+			name: "no previous proxy",
+			exp: [][]string{
+				{"reg", "add", internetSettingsKey, "/v", "ProxyEnable", "/t", "REG_DWORD", "/d", "0", "/f"},
+				{"reg", "delete", internetSettingsKey, "/v", "ProxyServer", "/f"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		prevEnable := parseRegValue(tt.enableOut, "ProxyEnable")
+		prevServer := parseRegValue(tt.serverOut, "ProxyServer")
+
+		if diff := cmp.Diff(tt.exp, windowsRestoreCommands(prevEnable, prevServer)); diff != "" {
+			t.Errorf("%v: restore commands not equal (-exp, +got):\n%v", tt.name, diff)
+		}
+	}
+
+	exp := []string{"reg", "query", internetSettingsKey, "/v", "ProxyServer"}
+	if got := regQueryCommand("ProxyServer"); !cmp.Equal(exp, got) {
+		t.Errorf("expected query command %v, got: %v", exp, got)
+	}
+}
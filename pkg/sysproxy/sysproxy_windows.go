@@ -0,0 +1,62 @@
+package sysproxy
+
+import (
+	"syscall"
+)
+
+// WinINet options to notify running applications of changed settings.
+const (
+	internetOptionRefresh         = 37
+	internetOptionSettingsChanged = 39
+)
+
+var procInternetSetOption = syscall.NewLazyDLL("wininet.dll").NewProc("InternetSetOptionW")
+
+// enable configures the WinINet proxy settings of the current user, which are
+// used by most Windows applications.
+func enable(host, port string) (RestoreFunc, error) {
+	prevEnable := queryValue("ProxyEnable")
+	prevServer := queryValue("ProxyServer")
+
+	restore := func() error {
+		err := runAll(windowsRestoreCommands(prevEnable, prevServer))
+		notifySettingsChanged()
+
+		return err
+	}
+
+	if err := runAll(windowsEnableCommands(host, port)); err != nil {
+		_ = restore()
+		return nil, err
+	}
+
+	notifySettingsChanged()
+
+	return restore, nil
+}
+
+// queryValue returns the data of a value of the Internet Settings key.
+func queryValue(name string) regValue {
+	out, err := run(regQueryCommand(name))
+	if err != nil {
+		return regValue{}
+	}
+
+	return parseRegValue(out, name)
+}
+
+// runAll runs cmds in order, until one fails.
+func runAll(cmds [][]string) error {
+	for _, cmd := range cmds {
+		if _, err := run(cmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func notifySettingsChanged() {
+	_, _, _ = procInternetSetOption.Call(0, internetOptionSettingsChanged, 0, 0)
+	_, _, _ = procInternetSetOption.Call(0, internetOptionRefresh, 0, 0)
+}
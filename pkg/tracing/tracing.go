@@ -0,0 +1,389 @@
+// Package tracing records spans of the request path (e.g. tunnel setup,
+// upstream round trips and storage), and exports them in batches, e.g. to an
+// OpenTelemetry collector with `OTLPExporter`.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultQueueSize     = 2048
+	defaultBatchSize     = 512
+	defaultFlushInterval = 5 * time.Second
+)
+
+var ErrShutdown = errors.New("tracing: tracer is shut down")
+
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Attribute is a key-value pair of a span. Values are strings, ints, int64s,
+// float64s or bools.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanData is a finished span, as passed to exporters.
+type SpanData struct {
+	Name    string
+	TraceID TraceID
+	SpanID  SpanID
+	// Zero for root spans.
+	ParentSpanID SpanID
+	Start        time.Time
+	End          time.Time
+	Attributes   []Attribute
+	// Error message of a failed operation, see `Span.RecordError`.
+	Err string
+}
+
+// Exporter exports batches of finished spans. It's called by a single goroutine.
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+}
+
+type Config struct {
+	Exporter Exporter
+	// Maximum number of finished spans waiting to be exported. Spans that
+	// finish when the queue is full are dropped, so that tracing doesn't slow
+	// down the proxy. Defaults to 2048.
+	QueueSize int
+	// Maximum number of spans per export. Defaults to 512.
+	BatchSize int
+	// Interval of exporting spans, if fewer than `BatchSize` are queued.
+	// Defaults to 5 seconds.
+	FlushInterval time.Duration
+}
+
+// Tracer starts root spans, and exports finished spans in the background.
+// A nil *Tracer is valid and doesn't record spans.
+type Tracer struct {
+	exporter      Exporter
+	batchSize     int
+	flushInterval time.Duration
+
+	queue   chan SpanData
+	flushCh chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	// mu guards rand and shutdown.
+	mu       sync.Mutex
+	rand     *rand.Rand
+	shutdown bool
+}
+
+// NewTracer returns a Tracer, and starts exporting spans.
+func NewTracer(cfg Config) *Tracer {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+
+	t := &Tracer{
+		exporter:      cfg.Exporter,
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		queue:         make(chan SpanData, cfg.QueueSize),
+		flushCh:       make(chan chan struct{}),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+		//nolint:gosec
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	go t.export()
+
+	return t
+}
+
+// Start starts a span, as child of the span in ctx, or else as root span of a
+// new trace. The returned context holds the span, so that spans started with
+// it (also with the package-level `Start`) are its children. End the span when
+// the operation is done.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	if parent := SpanFromContext(ctx); parent != nil {
+		return parent.tracer.start(ctx, name, parent, attrs)
+	}
+
+	if t == nil {
+		return ctx, nil
+	}
+
+	return t.start(ctx, name, nil, attrs)
+}
+
+// Start starts a child span of the span in ctx. If ctx has no span, no span is
+// recorded and the returned span is nil, which is safe to use.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	return parent.tracer.start(ctx, name, parent, attrs)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, parent *Span, attrs []Attribute) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:       name,
+			Start:      time.Now(),
+			Attributes: append([]Attribute(nil), attrs...),
+		},
+	}
+
+	t.mu.Lock()
+
+	if parent != nil {
+		span.data.TraceID = parent.data.TraceID
+		span.data.ParentSpanID = parent.data.SpanID
+	} else {
+		t.rand.Read(span.data.TraceID[:])
+	}
+
+	t.rand.Read(span.data.SpanID[:])
+	t.mu.Unlock()
+
+	return ContextWithSpan(ctx, span), span
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.shutdown {
+		return
+	}
+
+	select {
+	case t.queue <- data:
+	default:
+	}
+}
+
+func (t *Tracer) export() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, t.batchSize)
+
+	exportBatch := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if t.exporter != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), t.flushInterval)
+			if err := t.exporter.ExportSpans(ctx, batch); err != nil {
+				log.Printf("[ERROR] Could not export %v spans: %v", len(batch), err)
+			}
+			cancel()
+		}
+
+		batch = make([]SpanData, 0, t.batchSize)
+	}
+
+	// drain adds queued spans to the batch, exporting full batches.
+	drain := func() {
+		for {
+			select {
+			case data := <-t.queue:
+				if batch = append(batch, data); len(batch) == t.batchSize {
+					exportBatch()
+				}
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case data := <-t.queue:
+			if batch = append(batch, data); len(batch) == t.batchSize {
+				exportBatch()
+			}
+		case <-ticker.C:
+			exportBatch()
+		case flushed := <-t.flushCh:
+			drain()
+			exportBatch()
+			close(flushed)
+		case <-t.done:
+			drain()
+			exportBatch()
+
+			return
+		}
+	}
+}
+
+// Flush exports spans that are finished, or returns when ctx is done.
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+
+	select {
+	case t.flushCh <- flushed:
+	case <-t.stopped:
+		return ErrShutdown
+	case <-ctx.Done():
+		return fmt.Errorf("tracing: failed to flush spans: %w", ctx.Err())
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("tracing: failed to flush spans: %w", ctx.Err())
+	}
+}
+
+// Shutdown exports finished spans and stops the tracer, or returns when ctx is
+// done. Spans that finish afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	if !t.shutdown {
+		t.shutdown = true
+		close(t.done)
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("tracing: failed to shut down: %w", ctx.Err())
+	}
+}
+
+// Span is an operation of a trace. A nil *Span is valid and isn't recorded.
+// Spans are safe for concurrent use.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// RecordError marks the span as failed, if err is non-nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Err = err.Error()
+}
+
+// End finishes the span. Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.enqueue(data)
+}
+
+// TraceID returns the ID of the trace of the span.
+func (s *Span) TraceID() TraceID {
+	if s == nil {
+		return TraceID{}
+	}
+
+	return s.data.TraceID
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx with span, e.g. to start child spans of
+// a request span in a background goroutine.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span of ctx, or nil if it has none.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
@@ -0,0 +1,149 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/dstotijn/hetty/pkg/tracing"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, spans...)
+
+	return nil
+}
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("child spans and flush", func(t *testing.T) {
+		t.Parallel()
+
+		exporter := &recordingExporter{}
+		tracer := tracing.NewTracer(tracing.Config{Exporter: exporter})
+
+		ctx, root := tracer.Start(context.Background(), "root", tracing.String("foo", "bar"))
+		_, child := tracing.Start(ctx, "child")
+		child.RecordError(errors.New("oops"))
+		child.End()
+		root.End()
+		root.End()
+
+		if err := tracer.Flush(context.Background()); err != nil {
+			t.Fatalf("unexpected error flushing: %v", err)
+		}
+
+		exporter.mu.Lock()
+		spans := exporter.spans
+		exporter.mu.Unlock()
+
+		if len(spans) != 2 {
+			t.Fatalf("expected 2 spans, got: %+v", spans)
+		}
+
+		childData, rootData := spans[0], spans[1]
+
+		if rootData.Name != "root" || rootData.ParentSpanID != (tracing.SpanID{}) {
+			t.Errorf("unexpected root span: %+v", rootData)
+		}
+
+		if len(rootData.Attributes) != 1 || rootData.Attributes[0] != tracing.String("foo", "bar") {
+			t.Errorf("unexpected root span attributes: %+v", rootData.Attributes)
+		}
+
+		if childData.TraceID != rootData.TraceID || childData.ParentSpanID != rootData.SpanID {
+			t.Errorf("expected child of root span, got: %+v", childData)
+		}
+
+		if childData.Err != "oops" {
+			t.Errorf("expected error of child span, got: %q", childData.Err)
+		}
+
+		if err := tracer.Shutdown(context.Background()); err != nil {
+			t.Fatalf("unexpected error shutting down: %v", err)
+		}
+
+		if err := tracer.Flush(context.Background()); !errors.Is(err, tracing.ErrShutdown) {
+			t.Fatalf("expected `tracing.ErrShutdown`, got: %v", err)
+		}
+	})
+
+	t.Run("without tracer", func(t *testing.T) {
+		t.Parallel()
+
+		var tracer *tracing.Tracer
+
+		ctx, span := tracer.Start(context.Background(), "root")
+		if span != nil {
+			t.Fatalf("expected nil span, got: %+v", span)
+		}
+
+		_, child := tracing.Start(ctx, "child")
+		child.SetAttributes(tracing.Bool("foo", true))
+		child.End()
+
+		if err := tracer.Shutdown(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestOTLPExporter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gotPath, gotAuth string
+		gotBody          map[string]interface{}
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Errorf("failed to decode export request: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	exporter := tracing.NewOTLPExporter(tracing.OTLPConfig{
+		Endpoint: ts.URL + "/",
+		Headers:  http.Header{"Authorization": []string{"Bearer foo"}},
+	})
+	tracer := tracing.NewTracer(tracing.Config{Exporter: exporter})
+
+	_, span := tracer.Start(context.Background(), "proxy.request", tracing.Int("http.response.status_code", 200))
+	span.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error shutting down: %v", err)
+	}
+
+	if gotPath != "/v1/traces" || gotAuth != "Bearer foo" {
+		t.Fatalf("unexpected export request (path: %q, authorization: %q)", gotPath, gotAuth)
+	}
+
+	resourceSpans := gotBody["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	otlpSpan := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+
+	if otlpSpan["name"] != "proxy.request" || otlpSpan["traceId"] != span.TraceID().String() {
+		t.Errorf("unexpected span: %v", otlpSpan)
+	}
+
+	attr := otlpSpan["attributes"].([]interface{})[0].(map[string]interface{})
+	if value := attr["value"].(map[string]interface{})["intValue"]; value != "200" {
+		t.Errorf("expected int attribute encoded as string, got: %v", attr)
+	}
+}
@@ -0,0 +1,227 @@
+package transform
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/dstotijn/hetty/pkg/errcode"
+	"github.com/dstotijn/hetty/pkg/jwt"
+)
+
+// Transform defines a type for a single encoding, decoding or hashing operation
+// that can be applied to a byte slice.
+type Transform int
+
+// Transforms.
+const (
+	Invalid Transform = iota
+	Base64Encode
+	Base64Decode
+	Base64URLEncode
+	Base64URLDecode
+	URLEncode
+	URLDecode
+	HexEncode
+	HexDecode
+	GzipCompress
+	GzipDecompress
+	HTMLEncode
+	HTMLDecode
+	JWTDecode
+	MD5
+	SHA1
+	SHA256
+	SHA512
+)
+
+var ErrInvalidTransform = errcode.New(errcode.Invalid, "transform: invalid transform")
+
+var transformStrings = map[Transform]string{
+	Base64Encode:    "base64_encode",
+	Base64Decode:    "base64_decode",
+	Base64URLEncode: "base64url_encode",
+	Base64URLDecode: "base64url_decode",
+	URLEncode:       "url_encode",
+	URLDecode:       "url_decode",
+	HexEncode:       "hex_encode",
+	HexDecode:       "hex_decode",
+	GzipCompress:    "gzip_compress",
+	GzipDecompress:  "gzip_decompress",
+	HTMLEncode:      "html_encode",
+	HTMLDecode:      "html_decode",
+	JWTDecode:       "jwt_decode",
+	MD5:             "md5",
+	SHA1:            "sha1",
+	SHA256:          "sha256",
+	SHA512:          "sha512",
+}
+
+var transformFns = map[Transform]func([]byte) ([]byte, error){
+	Base64Encode:    base64Encode(base64.StdEncoding),
+	Base64Decode:    base64Decode(base64.StdEncoding),
+	Base64URLEncode: base64Encode(base64.URLEncoding),
+	Base64URLDecode: base64Decode(base64.URLEncoding),
+	URLEncode: func(b []byte) ([]byte, error) {
+		return []byte(url.QueryEscape(string(b))), nil
+	},
+	URLDecode: func(b []byte) ([]byte, error) {
+		s, err := url.QueryUnescape(string(b))
+		return []byte(s), err
+	},
+	HexEncode: func(b []byte) ([]byte, error) {
+		return []byte(hex.EncodeToString(b)), nil
+	},
+	HexDecode: func(b []byte) ([]byte, error) {
+		return hex.DecodeString(strings.TrimSpace(string(b)))
+	},
+	GzipCompress:   gzipCompress,
+	GzipDecompress: gzipDecompress,
+	HTMLEncode: func(b []byte) ([]byte, error) {
+		return []byte(html.EscapeString(string(b))), nil
+	},
+	HTMLDecode: func(b []byte) ([]byte, error) {
+		return []byte(html.UnescapeString(string(b))), nil
+	},
+	JWTDecode: jwtDecode,
+	MD5:       hashFn(func(b []byte) []byte { sum := md5.Sum(b); return sum[:] }),  //nolint:gosec
+	SHA1:      hashFn(func(b []byte) []byte { sum := sha1.Sum(b); return sum[:] }), //nolint:gosec
+	SHA256:    hashFn(func(b []byte) []byte { sum := sha256.Sum256(b); return sum[:] }),
+	SHA512:    hashFn(func(b []byte) []byte { sum := sha512.Sum512(b); return sum[:] }),
+}
+
+// Transforms returns all valid transforms.
+func Transforms() []Transform {
+	transforms := make([]Transform, 0, len(transformStrings))
+	for t := Base64Encode; t <= SHA512; t++ {
+		transforms = append(transforms, t)
+	}
+
+	return transforms
+}
+
+// Parse returns the transform for its string representation, e.g. `base64_decode`.
+func Parse(s string) (Transform, error) {
+	for t, str := range transformStrings {
+		if strings.EqualFold(s, str) {
+			return t, nil
+		}
+	}
+
+	return Invalid, fmt.Errorf("%w: %q", ErrInvalidTransform, s)
+}
+
+func (t Transform) String() string {
+	if s, ok := transformStrings[t]; ok {
+		return s
+	}
+
+	return "<invalid>"
+}
+
+// Apply runs the transform on input and returns its output.
+func (t Transform) Apply(input []byte) ([]byte, error) {
+	fn, ok := transformFns[t]
+	if !ok {
+		return nil, ErrInvalidTransform
+	}
+
+	output, err := fn(input)
+	if err != nil {
+		return nil, fmt.Errorf("transform: %v failed: %w", t, err)
+	}
+
+	return output, nil
+}
+
+// Chain applies transforms in order, where the output of each transform is
+// used as the input of the next one.
+func Chain(input []byte, transforms ...Transform) ([]byte, error) {
+	output := input
+
+	for i, t := range transforms {
+		var err error
+
+		output, err = t.Apply(output)
+		if err != nil {
+			return nil, fmt.Errorf("transform: step %v: %w", i+1, err)
+		}
+	}
+
+	return output, nil
+}
+
+func base64Encode(enc *base64.Encoding) func([]byte) ([]byte, error) {
+	return func(b []byte) ([]byte, error) {
+		return []byte(enc.EncodeToString(b)), nil
+	}
+}
+
+// base64Decode returns a decode function that accepts input with or without
+// padding, as unpadded values are common in URLs and tokens.
+func base64Decode(enc *base64.Encoding) func([]byte) ([]byte, error) {
+	return func(b []byte) ([]byte, error) {
+		s := strings.TrimSpace(string(b))
+		if strings.HasSuffix(s, "=") {
+			return enc.DecodeString(s)
+		}
+
+		return enc.WithPadding(base64.NoPadding).DecodeString(s)
+	}
+}
+
+func gzipCompress(b []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+
+	if _, err := gzipWriter.Write(b); err != nil {
+		return nil, err
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func gzipDecompress(b []byte) ([]byte, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	return io.ReadAll(gzipReader)
+}
+
+// jwtDecode decodes the header and payload of a JSON Web Token, and returns
+// both as an indented JSON object. The signature is not verified.
+func jwtDecode(b []byte) ([]byte, error) {
+	token, err := jwt.Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(struct {
+		Header  json.RawMessage `json:"header"`
+		Payload json.RawMessage `json:"payload"`
+	}{token.RawHeader, token.RawClaims}, "", "  ")
+}
+
+func hashFn(sum func([]byte) []byte) func([]byte) ([]byte, error) {
+	return func(b []byte) ([]byte, error) {
+		return []byte(hex.EncodeToString(sum(b))), nil
+	}
+}
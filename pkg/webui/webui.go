@@ -0,0 +1,203 @@
+// Package webui serves the embedded admin interface, optionally under a base
+// path, e.g. `/hetty` when Hetty is reverse-proxied on a shared host.
+package webui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidBasePath = errors.New("webui: invalid base path")
+
+// ParseBasePath parses and normalizes a base path, e.g. `/hetty/` to `/hetty`.
+// An empty base path (or `/`) is returned as an empty string.
+func ParseBasePath(s string) (string, error) {
+	if s == "" || s == "/" {
+		return "", nil
+	}
+
+	if !strings.HasPrefix(s, "/") {
+		return "", fmt.Errorf("%w: must start with a slash, got %q", ErrInvalidBasePath, s)
+	}
+
+	if strings.ContainsAny(s, "?#\"'<> ") {
+		return "", fmt.Errorf("%w: contains invalid characters: %q", ErrInvalidBasePath, s)
+	}
+
+	cleaned := path.Clean(s)
+	if cleaned != strings.TrimSuffix(s, "/") {
+		return "", fmt.Errorf("%w: must be a clean path, got %q", ErrInvalidBasePath, s)
+	}
+
+	return cleaned, nil
+}
+
+// BasePathHandler serves h under basePath: the base path is stripped from the
+// request path, and added to the `Location` header of redirects. Requests
+// outside the base path are not found, and the base path itself is redirected
+// to the base path with a trailing slash.
+func BasePathHandler(basePath string, h http.Handler) http.Handler {
+	if basePath == "" {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == basePath {
+			target := basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+
+			return
+		}
+
+		if !strings.HasPrefix(r.URL.Path, basePath+"/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = strings.TrimPrefix(r.URL.Path, basePath)
+		r2.URL.RawPath = ""
+
+		h.ServeHTTP(&redirectWriter{ResponseWriter: w, basePath: basePath}, r2)
+	})
+}
+
+// redirectWriter adds the base path to root-relative `Location` headers.
+type redirectWriter struct {
+	http.ResponseWriter
+	basePath    string
+	wroteHeader bool
+}
+
+func (w *redirectWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+
+		if loc := w.Header().Get("Location"); strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") {
+			w.Header().Set("Location", w.basePath+loc)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *redirectWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// Handler returns a handler that serves the admin interface files of fsys.
+// Because the interface is built for the root path, references to its assets
+// (`/_next/...`) in HTML and JavaScript files are rewritten to include the
+// base path, and HTML pages get a `hetty-base-path` meta tag, which the
+// interface uses for API requests. Serve the handler with BasePathHandler.
+func Handler(fsys fs.FS, basePath string) http.Handler {
+	fileServer := http.FileServer(http.FS(fsys))
+
+	if basePath == "" {
+		return fileServer
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if name == "" || strings.HasSuffix(name, "/") {
+			name += "index.html"
+		}
+
+		// Other files (and directory redirects) are served as is.
+		ext := path.Ext(name)
+		if ext != ".html" && ext != ".js" {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		data = rewriteAssetPaths(data, basePath, ext == ".html")
+
+		contentType := "text/html; charset=utf-8"
+		if ext == ".js" {
+			contentType = "text/javascript; charset=utf-8"
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+
+		if r.Method != http.MethodHead {
+			io.Copy(w, bytes.NewReader(data))
+		}
+	})
+}
+
+// rewriteAssetPaths adds basePath to references to assets. In HTML files, all
+// root-relative `href` and `src` attributes are rewritten, so that links to
+// other pages keep the base path as well.
+func rewriteAssetPaths(data []byte, basePath string, isHTML bool) []byte {
+	if isHTML {
+		for _, attr := range []string{`href="/`, `src="/`} {
+			data = rewriteAttr(data, []byte(attr), basePath)
+		}
+	}
+
+	data = bytes.ReplaceAll(data, []byte(`"/_next/`), []byte(`"`+basePath+`/_next/`))
+
+	if !isHTML {
+		return data
+	}
+
+	meta := []byte(`<meta name="hetty-base-path" content="` + html.EscapeString(basePath) + `">`)
+
+	if i := bytes.Index(data, []byte("<head>")); i != -1 {
+		i += len("<head>")
+		return append(data[:i:i], append(meta, data[i:]...)...)
+	}
+
+	return append(meta, data...)
+}
+
+// rewriteAttr adds basePath to attribute values that start with a slash, e.g.
+// `href="/"`. Protocol-relative URLs (`//host/...`) are left as is.
+func rewriteAttr(data, attr []byte, basePath string) []byte {
+	var buf bytes.Buffer
+
+	for {
+		i := bytes.Index(data, attr)
+		if i == -1 {
+			buf.Write(data)
+			return buf.Bytes()
+		}
+
+		end := i + len(attr)
+		buf.Write(data[:end-1])
+
+		if !bytes.HasPrefix(data[end:], []byte("/")) {
+			buf.WriteString(basePath)
+		}
+
+		buf.WriteByte('/')
+		data = data[end:]
+	}
+}
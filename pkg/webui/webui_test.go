@@ -0,0 +1,119 @@
+package webui_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dstotijn/hetty/pkg/webui"
+)
+
+func TestParseBasePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input  string
+		exp    string
+		expErr error
+	}{
+		{input: "", exp: ""},
+		{input: "/", exp: ""},
+		{input: "/hetty", exp: "/hetty"},
+		{input: "/tools/hetty/", exp: "/tools/hetty"},
+		{input: "hetty", expErr: webui.ErrInvalidBasePath},
+		{input: "/hetty/../admin", expErr: webui.ErrInvalidBasePath},
+		{input: "/hetty?x=1", expErr: webui.ErrInvalidBasePath},
+	}
+
+	for _, tt := range tests {
+		got, err := webui.ParseBasePath(tt.input)
+		if !errors.Is(err, tt.expErr) {
+			t.Errorf("%q: expected error %v, got: %v", tt.input, tt.expErr, err)
+			continue
+		}
+
+		if got != tt.exp {
+			t.Errorf("%q: expected %q, got: %q", tt.input, tt.exp, got)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte(`<html><head><script src="/_next/static/main.js"></script></head>` +
+			`<body><a href="/proxy/logs/">Logs</a><a href="//example.com/">Ext</a></body></html>`)},
+		"_next/static/main.js": {Data: []byte(`__webpack_require__.p="/_next/";`)},
+		"favicon.ico":          {Data: []byte("icon")},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", webui.Handler(fsys, "/hetty"))
+	mux.Handle("/api/", http.RedirectHandler("/api/graphql/", http.StatusMovedPermanently))
+
+	ts := httptest.NewServer(webui.BasePathHandler("/hetty", mux))
+	defer ts.Close()
+
+	client := ts.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return resp, string(body)
+	}
+
+	resp, body := get("/hetty/")
+	for _, exp := range []string{
+		`<head><meta name="hetty-base-path" content="/hetty">`,
+		`src="/hetty/_next/static/main.js"`,
+		`href="/hetty/proxy/logs/"`,
+		`href="//example.com/"`,
+	} {
+		if !strings.Contains(body, exp) {
+			t.Errorf("expected index to contain %q, got: %v", exp, body)
+		}
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("unexpected content type: %v", ct)
+	}
+
+	if _, body := get("/hetty/_next/static/main.js"); body != `__webpack_require__.p="/hetty/_next/";` {
+		t.Errorf("unexpected script: %v", body)
+	}
+
+	if _, body := get("/hetty/favicon.ico"); body != "icon" {
+		t.Errorf("unexpected favicon: %v", body)
+	}
+
+	if resp, _ := get("/hetty"); resp.Header.Get("Location") != "/hetty/" {
+		t.Errorf("expected redirect to base path with trailing slash, got: %v", resp.Header.Get("Location"))
+	}
+
+	if resp, _ := get("/hetty/api/"); resp.Header.Get("Location") != "/hetty/api/graphql/" {
+		t.Errorf("expected redirect within base path, got: %v", resp.Header.Get("Location"))
+	}
+
+	if resp, _ := get("/"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status not found outside base path, got: %v", resp.StatusCode)
+	}
+}